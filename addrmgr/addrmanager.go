@@ -10,6 +10,7 @@ import (
 	crand "crypto/rand" // for seeding
 	"encoding/base32"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -47,6 +49,14 @@ type AddrManager struct {
 	lamtx          sync.Mutex
 	localAddresses map[string]*localAddress
 	version        int
+
+	// asmap is the ASN map the addresses are grouped by, or nil to group
+	// them by their /16 instead.
+	asmap *ASMap
+
+	// triedCollisions are the good new addresses that are waiting for the
+	// tried addresses they'd evict to be tested, by their address keys.
+	triedCollisions map[string]*KnownAddress
 }
 
 type serializedKnownAddress struct {
@@ -67,6 +77,10 @@ type serializedAddrManager struct {
 	Addresses    []*serializedKnownAddress
 	NewBuckets   [newBucketCount][]string // string is NetAddressKey
 	TriedBuckets [triedBucketCount][]string
+
+	// ASMapVersion is the hex encoded version of the ASN map the addresses
+	// were bucketed with, or empty when they were bucketed by their /16.
+	ASMapVersion string
 }
 
 type localAddress struct {
@@ -159,6 +173,19 @@ const (
 
 	// serialisationVersion is the current version of the on-disk format.
 	serialisationVersion = 2
+
+	// maxTriedCollisions is the most good new addresses that wait for the
+	// tried addresses they'd evict to be tested.
+	maxTriedCollisions = 10
+
+	// triedReplacementWindow is how long ago a tried address has to have
+	// last been connected to for a good new address to replace it, and how
+	// recent a failed attempt to connect to it has to be for that.
+	triedReplacementWindow = time.Hour * 4
+
+	// triedCollisionTimeout is how long a good new address waits for the
+	// tried address it'd evict to be tested before it evicts it anyway.
+	triedCollisionTimeout = time.Minute * 40
 )
 
 // updateAddress is a helper function to either update an address already known
@@ -170,6 +197,12 @@ func (a *AddrManager) updateAddress(netAddr, srcAddr *wire.NetAddress) {
 		return
 	}
 
+	// I2P addresses can only be dialed when the destination behind them is
+	// known.
+	if IsI2P(netAddr) && I2PHost(netAddr.IP) == "" {
+		return
+	}
+
 	addr := NetAddressKey(netAddr)
 	ka := a.find(netAddr)
 	if ka != nil {
@@ -300,8 +333,8 @@ func (a *AddrManager) getNewBucket(netAddr, srcAddr *wire.NetAddress) int {
 
 	data1 := []byte{}
 	data1 = append(data1, a.key[:]...)
-	data1 = append(data1, []byte(GroupKey(netAddr))...)
-	data1 = append(data1, []byte(GroupKey(srcAddr))...)
+	data1 = append(data1, []byte(a.GroupKey(netAddr))...)
+	data1 = append(data1, []byte(a.GroupKey(srcAddr))...)
 	hash1 := chainhash.DoubleHashB(data1)
 	hash64 := binary.LittleEndian.Uint64(hash1)
 	hash64 %= newBucketsPerGroup
@@ -309,7 +342,7 @@ func (a *AddrManager) getNewBucket(netAddr, srcAddr *wire.NetAddress) int {
 	binary.LittleEndian.PutUint64(hashbuf[:], hash64)
 	data2 := []byte{}
 	data2 = append(data2, a.key[:]...)
-	data2 = append(data2, GroupKey(srcAddr)...)
+	data2 = append(data2, a.GroupKey(srcAddr)...)
 	data2 = append(data2, hashbuf[:]...)
 
 	hash2 := chainhash.DoubleHashB(data2)
@@ -329,7 +362,7 @@ func (a *AddrManager) getTriedBucket(netAddr *wire.NetAddress) int {
 	binary.LittleEndian.PutUint64(hashbuf[:], hash64)
 	data2 := []byte{}
 	data2 = append(data2, a.key[:]...)
-	data2 = append(data2, GroupKey(netAddr)...)
+	data2 = append(data2, a.GroupKey(netAddr)...)
 	data2 = append(data2, hashbuf[:]...)
 
 	hash2 := chainhash.DoubleHashB(data2)
@@ -367,6 +400,7 @@ func (a *AddrManager) savePeers() {
 	sam := new(serializedAddrManager)
 	sam.Version = a.version
 	copy(sam.Key[:], a.key[:])
+	sam.ASMapVersion = a.asmapVersion()
 
 	sam.Addresses = make([]*serializedKnownAddress, len(a.addrIndex))
 	i := 0
@@ -543,9 +577,65 @@ func (a *AddrManager) deserializePeers(filePath string) error {
 		}
 	}
 
+	// The addresses are in the buckets of other groups when the ASN map
+	// changed since they were saved.
+	if sam.ASMapVersion != a.asmapVersion() {
+		log.Infof("Rebucketing %d addresses for the changed ASN map",
+			len(a.addrIndex))
+		a.rebucket()
+	}
+
 	return nil
 }
 
+// asmapVersion returns the hex encoded version of the ASN map, or an empty
+// string when there is none.
+func (a *AddrManager) asmapVersion() string {
+	if a.asmap == nil {
+		return ""
+	}
+	version := a.asmap.Version()
+	return hex.EncodeToString(version[:])
+}
+
+// rebucket puts all the addresses back into the buckets of their groups.  The
+// tried addresses that don't fit in their tried bucket anymore become new ones,
+// and the new addresses that don't fit in their new bucket are forgotten.
+//
+// This function MUST be called with the address manager lock held (for
+// writes).
+func (a *AddrManager) rebucket() {
+	for i := range a.addrNew {
+		a.addrNew[i] = make(map[string]*KnownAddress)
+	}
+	for i := range a.addrTried {
+		a.addrTried[i] = list.New()
+	}
+	a.nNew, a.nTried = 0, 0
+
+	for key, ka := range a.addrIndex {
+		ka.refs = 0
+		if ka.tried {
+			bucket := a.getTriedBucket(ka.na)
+			if a.addrTried[bucket].Len() < triedBucketSize {
+				a.addrTried[bucket].PushBack(ka)
+				a.nTried++
+				continue
+			}
+			ka.tried = false
+		}
+
+		bucket := a.getNewBucket(ka.na, ka.srcAddr)
+		if len(a.addrNew[bucket]) >= newBucketSize {
+			delete(a.addrIndex, key)
+			continue
+		}
+		ka.refs = 1
+		a.addrNew[bucket][key] = ka
+		a.nNew++
+	}
+}
+
 // DeserializeNetAddress converts a given address string to a *wire.NetAddress.
 func (a *AddrManager) DeserializeNetAddress(addr string,
 	services wire.ServiceFlag) (*wire.NetAddress, error) {
@@ -665,6 +755,16 @@ func (a *AddrManager) NeedMoreAddresses() bool {
 func (a *AddrManager) AddressCache() []*wire.NetAddress {
 	allAddr := a.getAddresses()
 
+	// I2P addresses can't be told apart from unique local addresses by
+	// the peers they would be sent to.
+	relayable := allAddr[:0]
+	for _, na := range allAddr {
+		if !IsI2P(na) {
+			relayable = append(relayable, na)
+		}
+	}
+	allAddr = relayable
+
 	numAddresses := len(allAddr) * getAddrPercent / 100
 	if numAddresses > getAddrMax {
 		numAddresses = getAddrMax
@@ -706,6 +806,7 @@ func (a *AddrManager) getAddresses() []*wire.NetAddress {
 func (a *AddrManager) reset() {
 
 	a.addrIndex = make(map[string]*KnownAddress)
+	a.triedCollisions = make(map[string]*KnownAddress)
 
 	// fill key with bytes from a good random source.
 	io.ReadFull(crand.Reader, a.key[:])
@@ -718,8 +819,9 @@ func (a *AddrManager) reset() {
 }
 
 // HostToNetAddress returns a netaddress given a host address.  If the address
-// is a Tor .onion address this will be taken care of.  Else if the host is
-// not an IP address it will be resolved (via Tor if required).
+// is a Tor .onion address or an I2P .b32.i2p address this will be taken care
+// of.  Else if the host is not an IP address it will be resolved (via Tor if
+// required).
 func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.ServiceFlag) (*wire.NetAddress, error) {
 	// Tor address is 16 char base32 + ".onion"
 	var ip net.IP
@@ -734,6 +836,12 @@ func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.S
 		}
 		prefix := []byte{0xfd, 0x87, 0xd8, 0x7e, 0xeb, 0x43}
 		ip = net.IP(append(prefix, data...))
+	} else if IsI2PHost(host) {
+		var err error
+		ip, err = i2pHostToIP(host)
+		if err != nil {
+			return nil, err
+		}
 	} else if ip = net.ParseIP(host); ip == nil {
 		ips, err := a.lookupFunc(host)
 		if err != nil {
@@ -750,13 +858,17 @@ func (a *AddrManager) HostToNetAddress(host string, port uint16, services wire.S
 
 // ipString returns a string for the ip from the provided NetAddress. If the
 // ip is in the range used for Tor addresses then it will be transformed into
-// the relevant .onion address.
+// the relevant .onion address, and likewise into the .b32.i2p address of the
+// destination behind it for I2P addresses.
 func ipString(na *wire.NetAddress) string {
 	if IsOnionCatTor(na) {
 		// We know now that na.IP is long enough.
 		base32 := base32.StdEncoding.EncodeToString(na.IP[6:])
 		return strings.ToLower(base32) + ".onion"
 	}
+	if host := I2PHost(na.IP); host != "" {
+		return host
+	}
 
 	return na.IP.String()
 }
@@ -811,32 +923,53 @@ func (a *AddrManager) GetAddress() *KnownAddress {
 		}
 	} else {
 		// new node.
-		// XXX use a closure/function to avoid repeating this.
-		large := 1 << 30
-		factor := 1.0
-		for {
-			// Pick a random bucket.
-			bucket := a.rand.Intn(len(a.addrNew))
-			if len(a.addrNew[bucket]) == 0 {
-				continue
-			}
-			// Then, a random entry in it.
-			var ka *KnownAddress
-			nth := a.rand.Intn(len(a.addrNew[bucket]))
-			for _, value := range a.addrNew[bucket] {
-				if nth == 0 {
-					ka = value
-				}
-				nth--
-			}
-			randval := a.rand.Intn(large)
-			if float64(randval) < (factor * ka.chance() * float64(large)) {
-				log.Tracef("Selected %v from new bucket",
-					NetAddressKey(ka.na))
-				return ka
+		return a.pickNew()
+	}
+}
+
+// GetNewAddress returns a random address that hasn't been connected to yet,
+// for a feeler connection to check that there's a node at, or nil when there
+// aren't any.
+func (a *AddrManager) GetNewAddress() *KnownAddress {
+	// Protect concurrent access.
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.nNew == 0 {
+		return nil
+	}
+	return a.pickNew()
+}
+
+// pickNew picks a random address from the new buckets, with preference given
+// to ones that have not been used recently.  There must be new addresses.
+//
+// This function MUST be called with the address manager lock held (for writes).
+func (a *AddrManager) pickNew() *KnownAddress {
+	large := 1 << 30
+	factor := 1.0
+	for {
+		// Pick a random bucket.
+		bucket := a.rand.Intn(len(a.addrNew))
+		if len(a.addrNew[bucket]) == 0 {
+			continue
+		}
+		// Then, a random entry in it.
+		var ka *KnownAddress
+		nth := a.rand.Intn(len(a.addrNew[bucket]))
+		for _, value := range a.addrNew[bucket] {
+			if nth == 0 {
+				ka = value
 			}
-			factor *= 1.2
+			nth--
 		}
+		randval := a.rand.Intn(large)
+		if float64(randval) < (factor * ka.chance() * float64(large)) {
+			log.Tracef("Selected %v from new bucket",
+				NetAddressKey(ka.na))
+			return ka
+		}
+		factor *= 1.2
 	}
 }
 
@@ -887,6 +1020,10 @@ func (a *AddrManager) Connected(addr *wire.NetAddress) {
 // Good marks the given address as good.  To be called after a successful
 // connection and version exchange.  If the address is unknown to the address
 // manager it will be ignored.
+//
+// A new address is only moved to the tried addresses right away when there's
+// room in its tried bucket.  Otherwise it waits for the tried address it'd
+// evict to be tested, which ResolveCollisions settles.
 func (a *AddrManager) Good(addr *wire.NetAddress) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
@@ -908,11 +1045,31 @@ func (a *AddrManager) Good(addr *wire.NetAddress) {
 		return
 	}
 
-	// ok, need to move it to tried.
+	// Test before evicting a tried address.
+	bucket := a.getTriedBucket(ka.na)
+	if a.addrTried[bucket].Len() >= triedBucketSize {
+		addrKey := NetAddressKey(ka.na)
+		if _, ok := a.triedCollisions[addrKey]; !ok &&
+			len(a.triedCollisions) < maxTriedCollisions {
+
+			log.Tracef("Waiting for the tried bucket of %s to be "+
+				"tested", addrKey)
+			a.triedCollisions[addrKey] = ka
+		}
+		return
+	}
+
+	a.moveToTried(ka)
+}
 
+// moveToTried moves the new address to its tried bucket, and evicts the eldest
+// address of the bucket into the new addresses when it's full.
+//
+// This function MUST be called with the address manager lock held (for writes).
+func (a *AddrManager) moveToTried(ka *KnownAddress) {
 	// remove from all new buckets.
 	// record one of the buckets in question and call it the `first'
-	addrKey := NetAddressKey(addr)
+	addrKey := NetAddressKey(ka.na)
 	oldBucket := -1
 	for i := range a.addrNew {
 		// we check for existence so we can record the first one
@@ -973,6 +1130,73 @@ func (a *AddrManager) Good(addr *wire.NetAddress) {
 	a.addrNew[newBucket][rmkey] = rmka
 }
 
+// ResolveCollisions settles the good new addresses that are waiting for the
+// tried addresses they'd evict to be tested.  A tried address that was
+// connected to lately is kept, while one that failed to be connected to lately,
+// or that wasn't tested in time, is evicted by the new address.
+func (a *AddrManager) ResolveCollisions() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := time.Now()
+	for addrKey, ka := range a.triedCollisions {
+		// Forget the addresses that were removed or made tried since.
+		if a.addrIndex[addrKey] != ka || ka.tried {
+			delete(a.triedCollisions, addrKey)
+			continue
+		}
+
+		bucket := a.getTriedBucket(ka.na)
+		if a.addrTried[bucket].Len() < triedBucketSize {
+			a.moveToTried(ka)
+			delete(a.triedCollisions, addrKey)
+			continue
+		}
+
+		old := a.pickTried(bucket).Value.(*KnownAddress)
+		switch {
+		// The tried address still works.
+		case now.Sub(old.lastsuccess) < triedReplacementWindow:
+			log.Tracef("Keeping %s in tried over %s",
+				NetAddressKey(old.na), addrKey)
+			delete(a.triedCollisions, addrKey)
+
+		// The tried address was attempted lately without success, unless
+		// the attempt is still going on.
+		case now.Sub(old.lastattempt) < triedReplacementWindow:
+			if now.Sub(old.lastattempt) > time.Minute {
+				a.moveToTried(ka)
+				delete(a.triedCollisions, addrKey)
+			}
+
+		// The tried address wasn't tested in time.
+		case now.Sub(ka.lastsuccess) > triedCollisionTimeout:
+			a.moveToTried(ka)
+			delete(a.triedCollisions, addrKey)
+		}
+	}
+}
+
+// SelectTriedCollision returns a tried address that a good new address would
+// evict, which is to be tested with a feeler connection, or nil when there
+// aren't any.
+func (a *AddrManager) SelectTriedCollision() *KnownAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for addrKey, ka := range a.triedCollisions {
+		if a.addrIndex[addrKey] != ka || ka.tried {
+			continue
+		}
+		bucket := a.getTriedBucket(ka.na)
+		if a.addrTried[bucket].Len() < triedBucketSize {
+			continue
+		}
+		return a.pickTried(bucket).Value.(*KnownAddress)
+	}
+	return nil
+}
+
 // SetServices sets the services for the giiven address to the provided value.
 func (a *AddrManager) SetServices(addr *wire.NetAddress, services wire.ServiceFlag) {
 	a.mtx.Lock()
@@ -1017,6 +1241,29 @@ func (a *AddrManager) AddLocalAddress(na *wire.NetAddress, priority AddressPrior
 	return nil
 }
 
+// LocalAddress is a known local address that is advertised to peers, along
+// with the priority it was added with.
+type LocalAddress struct {
+	NA    *wire.NetAddress
+	Score AddressPriority
+}
+
+// LocalAddresses returns the known local addresses that are advertised to
+// peers, with those of the highest priority first.
+func (a *AddrManager) LocalAddresses() []LocalAddress {
+	a.lamtx.Lock()
+	defer a.lamtx.Unlock()
+
+	addrs := make([]LocalAddress, 0, len(a.localAddresses))
+	for _, la := range a.localAddresses {
+		addrs = append(addrs, LocalAddress{NA: la.na, Score: la.score})
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Score > addrs[j].Score
+	})
+	return addrs
+}
+
 // getReachabilityFrom returns the relative reachability of the provided local
 // address to the provided remote address.
 func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
@@ -1046,6 +1293,13 @@ func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
 		return Default
 	}
 
+	if IsI2P(remoteAddr) {
+		if IsI2P(localAddr) {
+			return Private
+		}
+		return Unreachable
+	}
+
 	if IsRFC4380(remoteAddr) {
 		if !IsRoutable(localAddr) {
 			return Default
@@ -1149,3 +1403,28 @@ func New(dataDir string, lookupFunc func(string) ([]net.IP, error)) *AddrManager
 	am.reset()
 	return &am
 }
+
+// SetASMap makes the address manager group the addresses by the autonomous
+// system that announces them according to the ASN map, rather than by their
+// /16.  It must be called before the address manager is started, and the saved
+// addresses are put into the buckets of their new groups once they're loaded.
+func (a *AddrManager) SetASMap(asmap *ASMap) {
+	a.mtx.Lock()
+	a.asmap = asmap
+	a.mtx.Unlock()
+}
+
+// GroupKey returns a string representing the network group an address is part
+// of.  With an ASN map, the IP addresses it maps are grouped by their AS number
+// as the string "as:number", and all other addresses are grouped the same way
+// as the GroupKey function groups them.
+//
+// This function is safe for concurrent access.
+func (a *AddrManager) GroupKey(na *wire.NetAddress) string {
+	if a.asmap != nil {
+		if asn := a.asmap.ASN(na); asn != 0 {
+			return fmt.Sprintf("as:%d", asn)
+		}
+	}
+	return GroupKey(na)
+}
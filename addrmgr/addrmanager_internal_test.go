@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/utreexo/utreexod/wire"
 )
@@ -209,3 +210,94 @@ func TestAddrManagerV1ToV2(t *testing.T) {
 	addrMgr.loadPeers()
 	assertAddrs(t, addrMgr, expectedAddrs)
 }
+
+// TestTriedCollisions ensures that a good new address waits for the tried
+// address it'd evict to be tested, and that it only evicts it when the tried
+// address failed or wasn't tested in time.
+func TestTriedCollisions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	tests := []struct {
+		name        string
+		lastSuccess time.Time
+		lastAttempt time.Time
+		newSuccess  time.Time
+		evicted     bool
+	}{
+		{
+			name:        "tried address works",
+			lastSuccess: now.Add(-time.Hour),
+			lastAttempt: now.Add(-time.Hour),
+			newSuccess:  now,
+			evicted:     false,
+		},
+		{
+			name:        "tried address failed",
+			lastSuccess: now.Add(-time.Hour * 5),
+			lastAttempt: now.Add(-time.Minute * 2),
+			newSuccess:  now,
+			evicted:     true,
+		},
+		{
+			name:        "tried address being tested",
+			lastSuccess: now.Add(-time.Hour * 5),
+			lastAttempt: now.Add(-time.Second * 10),
+			newSuccess:  now,
+			evicted:     false,
+		},
+		{
+			name:        "tried address not tested in time",
+			lastSuccess: now.Add(-time.Hour * 5),
+			lastAttempt: now.Add(-time.Hour * 5),
+			newSuccess:  now.Add(-time.Hour),
+			evicted:     true,
+		},
+	}
+
+	for _, test := range tests {
+		addrMgr := New(t.TempDir(), nil)
+
+		// Fill the tried bucket of the new address, with the first
+		// address being the eldest that it'd evict.
+		addr := routableRandAddr(t)
+		addrMgr.AddAddress(addr, routableRandAddr(t))
+		bucket := addrMgr.getTriedBucket(addr)
+		var old *KnownAddress
+		for i := 0; i < triedBucketSize; i++ {
+			na := *routableRandAddr(t)
+			na.Timestamp = now.Add(time.Duration(i) * time.Second)
+			ka := &KnownAddress{na: &na, srcAddr: &na, tried: true,
+				lastsuccess: now, lastattempt: now}
+			addrMgr.addrIndex[NetAddressKey(&na)] = ka
+			addrMgr.addrTried[bucket].PushBack(ka)
+			addrMgr.nTried++
+			if old == nil {
+				old = ka
+			}
+		}
+
+		addrMgr.Good(addr)
+		ka := addrMgr.find(addr)
+		if ka.tried {
+			t.Fatalf("%s: address was made tried without testing "+
+				"the one it evicts", test.name)
+		}
+		if got := addrMgr.SelectTriedCollision(); got != old {
+			t.Fatalf("%s: got tried collision %v, want %v",
+				test.name, got, old)
+		}
+
+		old.lastsuccess = test.lastSuccess
+		old.lastattempt = test.lastAttempt
+		ka.lastsuccess = test.newSuccess
+		addrMgr.ResolveCollisions()
+		if ka.tried != test.evicted || old.tried == test.evicted {
+			t.Fatalf("%s: got new address tried %v and old tried %v",
+				test.name, ka.tried, old.tried)
+		}
+		if test.evicted && addrMgr.SelectTriedCollision() != nil {
+			t.Fatalf("%s: collision wasn't resolved", test.name)
+		}
+	}
+}
@@ -5,10 +5,13 @@
 package addrmgr_test
 
 import (
+	"crypto/sha256"
+	"encoding/base32"
 	"errors"
 	"fmt"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -470,3 +473,49 @@ func TestNetAddressKey(t *testing.T) {
 	}
 
 }
+
+// TestI2PAddress ensures that I2P destinations are kept in the I2P range, that
+// they keep their names, and that they're never relayed to peers.
+func TestI2PAddress(t *testing.T) {
+	const host = "ukeu3k5oycgaauneqgtnvselmt4yemvoilkln7jpvamvfx7dnkdq.b32.i2p"
+	n := addrmgr.New("testi2paddress", lookupFunc)
+	na, err := n.HostToNetAddress(host, 8333, wire.SFNodeNetwork)
+	if err != nil {
+		t.Fatalf("unable to parse i2p destination: %v", err)
+	}
+	if !addrmgr.IsI2P(na) || !addrmgr.IsRoutable(na) {
+		t.Fatalf("%s isn't a routable i2p address", na.IP)
+	}
+	if key := addrmgr.NetAddressKey(na); key != host+":8333" {
+		t.Fatalf("got key %s, want %s", key, host+":8333")
+	}
+	if _, err := n.HostToNetAddress("abcd.b32.i2p", 8333, 0); err == nil {
+		t.Fatal("parsed a truncated i2p destination")
+	}
+
+	// Addresses in the I2P range are only added when their destination
+	// is known, which isn't the case for those peers send.
+	unknown := wire.NewNetAddressIPPort(net.ParseIP("fd60:db4d:ddb5::1"),
+		8333, wire.SFNodeNetwork)
+	n.AddAddresses([]*wire.NetAddress{na, unknown}, na)
+	if n.NumAddresses() != 1 {
+		t.Fatalf("got %d addresses, want 1", n.NumAddresses())
+	}
+	if ka := n.GetAddress(); ka == nil || addrmgr.NetAddressKey(ka.NetAddress()) != host+":8333" {
+		t.Fatalf("got address %v, want %s", ka, host)
+	}
+
+	for i := 0; i < 20; i++ {
+		hash := sha256.Sum256([]byte{byte(i)})
+		name := strings.ToLower(base32.StdEncoding.WithPadding(
+			base32.NoPadding).EncodeToString(hash[:]))
+		na, err := n.HostToNetAddress(name+".b32.i2p", 8333, 0)
+		if err != nil {
+			t.Fatalf("unable to parse i2p destination: %v", err)
+		}
+		n.AddAddress(na, na)
+	}
+	if addrs := n.AddressCache(); len(addrs) != 0 {
+		t.Fatalf("i2p addresses %v would be relayed", addrs)
+	}
+}
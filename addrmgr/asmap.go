@@ -0,0 +1,350 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package addrmgr
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net"
+	"os"
+
+	"github.com/utreexo/utreexod/wire"
+)
+
+// asmapInvalid is returned when decoding a value of the ASN map runs past its
+// end.
+const asmapInvalid = 0xffffffff
+
+// asmapInstruction is an instruction of the program the ASN map is made of.
+type asmapInstruction uint32
+
+const (
+	asmapReturn asmapInstruction = iota
+	asmapJump
+	asmapMatch
+	asmapDefault
+)
+
+var (
+	// asmapTypeBitSizes, asmapASNBitSizes, asmapMatchBitSizes and
+	// asmapJumpBitSizes are the sizes of the mantissa classes the values of
+	// the ASN map are encoded with.
+	asmapTypeBitSizes  = []uint8{0, 0, 1}
+	asmapASNBitSizes   = []uint8{15, 16, 17, 18, 19, 20, 21, 22, 23, 24}
+	asmapMatchBitSizes = []uint8{1, 2, 3, 4, 5, 6, 7, 8}
+	asmapJumpBitSizes  = []uint8{5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30}
+)
+
+// ASMap maps IP addresses to the autonomous system (AS) that announces them,
+// which the address manager groups addresses by instead of their /16 when one
+// is set.  The map is in the compact format of Bitcoin Core, which is a program
+// that walks the bits of an IPv6 address, with IPv4 addresses mapped into IPv6.
+type ASMap struct {
+	bits    []bool
+	version [sha256.Size]byte
+}
+
+// NewASMap returns the ASN map encoded in the data, which is checked to be a
+// well formed program that can't read past its end.
+func NewASMap(data []byte) (*ASMap, error) {
+	m := &ASMap{
+		bits:    make([]bool, 0, len(data)*8),
+		version: sha256.Sum256(data),
+	}
+	for _, b := range data {
+		for bit := 0; bit < 8; bit++ {
+			m.bits = append(m.bits, (b>>bit)&1 == 1)
+		}
+	}
+	if !m.sane(128) {
+		return nil, errors.New("malformed ASN map")
+	}
+	return m, nil
+}
+
+// LoadASMap returns the ASN map in the file at path.
+func LoadASMap(path string) (*ASMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := NewASMap(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// Version returns the hash of the ASN map, which tells different maps apart.
+func (m *ASMap) Version() [sha256.Size]byte {
+	return m.version
+}
+
+// decodeBits decodes a value encoded as an exponent of continuation bits
+// followed by a mantissa of the size of its class, starting at the position,
+// and returns it along with the position past it.
+func (m *ASMap) decodeBits(pos int, minVal uint32, sizes []uint8) (uint32, int) {
+	val := minVal
+	for i, size := range sizes {
+		var bit bool
+		if i+1 != len(sizes) {
+			if pos == len(m.bits) {
+				break
+			}
+			bit = m.bits[pos]
+			pos++
+		}
+		if bit {
+			val += 1 << size
+			continue
+		}
+
+		for b := uint8(0); b < size; b++ {
+			if pos == len(m.bits) {
+				return asmapInvalid, pos
+			}
+			if m.bits[pos] {
+				val += 1 << (size - 1 - b)
+			}
+			pos++
+		}
+		return val, pos
+	}
+	return asmapInvalid, pos
+}
+
+// decodeType decodes the instruction at the position.
+func (m *ASMap) decodeType(pos int) (asmapInstruction, int) {
+	val, pos := m.decodeBits(pos, 0, asmapTypeBitSizes)
+	return asmapInstruction(val), pos
+}
+
+// decodeASN decodes the AS number at the position.
+func (m *ASMap) decodeASN(pos int) (uint32, int) {
+	return m.decodeBits(pos, 1, asmapASNBitSizes)
+}
+
+// decodeMatch decodes the bits to match at the position.
+func (m *ASMap) decodeMatch(pos int) (uint32, int) {
+	return m.decodeBits(pos, 2, asmapMatchBitSizes)
+}
+
+// decodeJump decodes the jump offset at the position.
+func (m *ASMap) decodeJump(pos int) (uint32, int) {
+	return m.decodeBits(pos, 17, asmapJumpBitSizes)
+}
+
+// sane returns whether the program of the ASN map always returns an AS number
+// for addresses of the number of bits without reading past its end.
+func (m *ASMap) sane(numBits int) bool {
+	type jumpTarget struct {
+		offset int
+		bits   int
+	}
+
+	var jumps []jumpTarget
+	prevOpcode := asmapJump
+	hadIncompleteMatch := false
+	pos := 0
+	for pos != len(m.bits) {
+		if len(jumps) > 0 && pos >= jumps[len(jumps)-1].offset {
+			// There was a jump into the middle of the previous
+			// instruction.
+			return false
+		}
+
+		var opcode asmapInstruction
+		opcode, pos = m.decodeType(pos)
+		switch opcode {
+		case asmapReturn:
+			// A return right after a default could just be a return.
+			if prevOpcode == asmapDefault {
+				return false
+			}
+			var asn uint32
+			asn, pos = m.decodeASN(pos)
+			if asn == asmapInvalid {
+				return false
+			}
+			if len(jumps) == 0 {
+				// Only up to 7 bits of zero padding may be left.
+				if len(m.bits)-pos > 7 {
+					return false
+				}
+				for ; pos != len(m.bits); pos++ {
+					if m.bits[pos] {
+						return false
+					}
+				}
+				return true
+			}
+
+			// Continue as if the last jump was taken, which has to
+			// land right here.
+			last := jumps[len(jumps)-1]
+			if pos != last.offset {
+				return false
+			}
+			numBits = last.bits
+			jumps = jumps[:len(jumps)-1]
+			prevOpcode = asmapJump
+
+		case asmapJump:
+			var jump uint32
+			jump, pos = m.decodeJump(pos)
+			if jump == asmapInvalid || int64(jump) > int64(len(m.bits)-pos) {
+				return false
+			}
+			if numBits == 0 {
+				return false
+			}
+			numBits--
+			offset := pos + int(jump)
+			if len(jumps) > 0 && offset >= jumps[len(jumps)-1].offset {
+				// The jumps intersect.
+				return false
+			}
+			jumps = append(jumps, jumpTarget{offset: offset, bits: numBits})
+			prevOpcode = asmapJump
+
+		case asmapMatch:
+			var match uint32
+			match, pos = m.decodeMatch(pos)
+			if match == asmapInvalid {
+				return false
+			}
+			matchLen := bits.Len32(match) - 1
+			if prevOpcode != asmapMatch {
+				hadIncompleteMatch = false
+			}
+
+			// Only one match of a sequence of them may be shorter
+			// than 8 bits.
+			if matchLen < 8 && hadIncompleteMatch {
+				return false
+			}
+			hadIncompleteMatch = matchLen < 8
+			if numBits < matchLen {
+				return false
+			}
+			numBits -= matchLen
+			prevOpcode = asmapMatch
+
+		case asmapDefault:
+			if prevOpcode == asmapDefault {
+				return false
+			}
+			var asn uint32
+			asn, pos = m.decodeASN(pos)
+			if asn == asmapInvalid {
+				return false
+			}
+			prevOpcode = asmapDefault
+
+		default:
+			// The instruction runs past the end.
+			return false
+		}
+	}
+
+	// The end was reached without a return.
+	return false
+}
+
+// interpret runs the program of the ASN map on the bits of the IPv6 address
+// and returns the AS number it maps to, or 0 when it isn't mapped.
+func (m *ASMap) interpret(ip net.IP) uint32 {
+	ipBit := func(i int) bool {
+		return ip[i/8]>>(7-i%8)&1 == 1
+	}
+
+	numBits := len(ip) * 8
+	var defaultASN uint32
+	pos := 0
+	for pos != len(m.bits) {
+		var opcode asmapInstruction
+		opcode, pos = m.decodeType(pos)
+		switch opcode {
+		case asmapReturn:
+			asn, _ := m.decodeASN(pos)
+			if asn == asmapInvalid {
+				return 0
+			}
+			return asn
+
+		case asmapJump:
+			var jump uint32
+			jump, pos = m.decodeJump(pos)
+			if jump == asmapInvalid || numBits == 0 ||
+				int64(jump) >= int64(len(m.bits)-pos) {
+
+				return 0
+			}
+			if ipBit(len(ip)*8 - numBits) {
+				pos += int(jump)
+			}
+			numBits--
+
+		case asmapMatch:
+			var match uint32
+			match, pos = m.decodeMatch(pos)
+			if match == asmapInvalid {
+				return 0
+			}
+			matchLen := bits.Len32(match) - 1
+			if numBits < matchLen {
+				return 0
+			}
+			for bit := 0; bit < matchLen; bit++ {
+				want := (match>>(matchLen-1-bit))&1 == 1
+				if ipBit(len(ip)*8-numBits) != want {
+					return defaultASN
+				}
+				numBits--
+			}
+
+		case asmapDefault:
+			defaultASN, pos = m.decodeASN(pos)
+			if defaultASN == asmapInvalid {
+				return 0
+			}
+
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// ASN returns the AS number of the address, or 0 when the address isn't in the
+// map or isn't an IP address on the internet.  The IPv4 addresses embedded in
+// IPv6 ones, such as those of 6to4 and Teredo, are mapped by the IPv4 address.
+func (m *ASMap) ASN(na *wire.NetAddress) uint32 {
+	if IsLocal(na) || !IsRoutable(na) || IsOnionCatTor(na) || IsI2P(na) {
+		return 0
+	}
+
+	ip := na.IP.To16()
+	switch {
+	case IsIPv4(na):
+	case IsRFC6145(na) || IsRFC6052(na):
+		ip = net.IP(na.IP[12:16]).To16()
+	case IsRFC3964(na):
+		ip = net.IP(na.IP[2:6]).To16()
+	case IsRFC4380(na):
+		ipv4 := make(net.IP, 4)
+		for i, b := range na.IP[12:16] {
+			ipv4[i] = b ^ 0xff
+		}
+		ip = ipv4.To16()
+	}
+	if ip == nil {
+		return 0
+	}
+	return m.interpret(ip)
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package addrmgr
+
+import (
+	"net"
+	"testing"
+
+	"github.com/utreexo/utreexod/wire"
+)
+
+// asmapWriter encodes the instructions of an ASN map for the tests.
+type asmapWriter struct {
+	bits []bool
+}
+
+// encode appends the value the way decodeBits decodes it.
+func (w *asmapWriter) encode(val, minVal uint32, sizes []uint8) {
+	val -= minVal
+	for i, size := range sizes {
+		last := i+1 == len(sizes)
+		if !last && val >= 1<<size {
+			w.bits = append(w.bits, true)
+			val -= 1 << size
+			continue
+		}
+		if !last {
+			w.bits = append(w.bits, false)
+		}
+		for b := int(size) - 1; b >= 0; b-- {
+			w.bits = append(w.bits, (val>>b)&1 == 1)
+		}
+		return
+	}
+}
+
+func (w *asmapWriter) ret(asn uint32) *asmapWriter {
+	w.encode(uint32(asmapReturn), 0, asmapTypeBitSizes)
+	w.encode(asn, 1, asmapASNBitSizes)
+	return w
+}
+
+func (w *asmapWriter) defaultASN(asn uint32) *asmapWriter {
+	w.encode(uint32(asmapDefault), 0, asmapTypeBitSizes)
+	w.encode(asn, 1, asmapASNBitSizes)
+	return w
+}
+
+// match appends a match of the lowest n bits of the value.
+func (w *asmapWriter) match(value uint32, n int) *asmapWriter {
+	w.encode(uint32(asmapMatch), 0, asmapTypeBitSizes)
+	w.encode(1<<n|value, 2, asmapMatchBitSizes)
+	return w
+}
+
+// jump appends a jump on the next bit of the address to the program for a set
+// bit, with the program for a clear bit right after it.
+func (w *asmapWriter) jump(zero, one *asmapWriter) *asmapWriter {
+	w.encode(uint32(asmapJump), 0, asmapTypeBitSizes)
+	w.encode(uint32(len(zero.bits)), 17, asmapJumpBitSizes)
+	w.bits = append(w.bits, zero.bits...)
+	w.bits = append(w.bits, one.bits...)
+	return w
+}
+
+func (w *asmapWriter) bytes() []byte {
+	data := make([]byte, (len(w.bits)+7)/8)
+	for i, bit := range w.bits {
+		if bit {
+			data[i/8] |= 1 << (i % 8)
+		}
+	}
+	return data
+}
+
+// testASMap returns an ASN map of 1.0.0.0/8 to AS 100, 193.0.0.0/8 to AS 200
+// and the rest of 128.0.0.0/1 to AS 300.
+func testASMap(t *testing.T) *ASMap {
+	t.Helper()
+
+	w := &asmapWriter{}
+	for i := 0; i < 10; i++ {
+		w.match(0x00, 8)
+	}
+	w.match(0xff, 8).match(0xff, 8)
+	w.jump((&asmapWriter{}).match(0x01, 7).ret(100),
+		(&asmapWriter{}).defaultASN(300).match(0x41, 7).ret(200))
+
+	m, err := NewASMap(w.bytes())
+	if err != nil {
+		t.Fatalf("unable to decode the ASN map: %v", err)
+	}
+	return m
+}
+
+// TestASMap ensures that the ASN map maps the IP addresses to their AS number
+// and that malformed maps are rejected.
+func TestASMap(t *testing.T) {
+	t.Parallel()
+
+	m := testASMap(t)
+	tests := []struct {
+		ip  string
+		asn uint32
+	}{
+		{ip: "1.2.3.4", asn: 100},
+		{ip: "1.200.0.1", asn: 100},
+		{ip: "193.0.0.1", asn: 200},
+		{ip: "200.1.1.1", asn: 300},
+		{ip: "5.6.7.8", asn: 0},
+		{ip: "2002:102:304::1", asn: 100},
+		{ip: "2a00::1", asn: 0},
+		{ip: "10.0.0.1", asn: 0},
+		{ip: "127.0.0.1", asn: 0},
+	}
+	for _, test := range tests {
+		na := wire.NewNetAddressIPPort(net.ParseIP(test.ip), 8333, 0)
+		if asn := m.ASN(na); asn != test.asn {
+			t.Errorf("%s: got AS %d, want %d", test.ip, asn, test.asn)
+		}
+	}
+
+	// Maps that end early or don't return are rejected.
+	w := (&asmapWriter{}).match(0x00, 8)
+	if _, err := NewASMap(w.bytes()); err == nil {
+		t.Fatalf("expected a map without a return to be rejected")
+	}
+	w = (&asmapWriter{}).ret(100)
+	if _, err := NewASMap(w.bytes()[:1]); err == nil {
+		t.Fatalf("expected a truncated map to be rejected")
+	}
+	if _, err := NewASMap(nil); err == nil {
+		t.Fatalf("expected an empty map to be rejected")
+	}
+}
+
+// TestASMapGroupKey ensures that the addresses are grouped by their AS with an
+// ASN map, and that the saved addresses are rebucketed when it changes.
+func TestASMapGroupKey(t *testing.T) {
+	t.Parallel()
+
+	addrMgr := New(t.TempDir(), nil)
+	addrMgr.SetASMap(testASMap(t))
+
+	sameAS := []*wire.NetAddress{
+		wire.NewNetAddressIPPort(net.ParseIP("1.2.3.4"), 8333, 0),
+		wire.NewNetAddressIPPort(net.ParseIP("1.200.0.1"), 8333, 0),
+	}
+	for _, na := range sameAS {
+		if key := addrMgr.GroupKey(na); key != "as:100" {
+			t.Fatalf("%v: got group %s, want as:100", na.IP, key)
+		}
+	}
+	unmapped := wire.NewNetAddressIPPort(net.ParseIP("5.6.7.8"), 8333, 0)
+	if key := addrMgr.GroupKey(unmapped); key != GroupKey(unmapped) {
+		t.Fatalf("%v: got group %s, want %s", unmapped.IP, key,
+			GroupKey(unmapped))
+	}
+
+	// Save addresses bucketed by their /16 and load them with the ASN map.
+	tempDir := t.TempDir()
+	addrMgr = New(tempDir, nil)
+	const numAddrs = 50
+	for i := 0; i < numAddrs; i++ {
+		addr := routableRandAddr(t)
+		addrMgr.AddAddress(addr, routableRandAddr(t))
+		if i%2 == 0 {
+			addrMgr.Good(addr)
+		}
+	}
+	want := addrMgr.numAddresses()
+	addrMgr.savePeers()
+
+	addrMgr = New(tempDir, nil)
+	addrMgr.SetASMap(testASMap(t))
+	addrMgr.loadPeers()
+	if got := addrMgr.numAddresses(); got != want {
+		t.Fatalf("got %d addresses after rebucketing, want %d", got, want)
+	}
+	for key, ka := range addrMgr.addrIndex {
+		if ka.tried {
+			bucket := addrMgr.getTriedBucket(ka.na)
+			var found bool
+			for e := addrMgr.addrTried[bucket].Front(); e != nil; e = e.Next() {
+				found = found || e.Value.(*KnownAddress) == ka
+			}
+			if !found {
+				t.Fatalf("%s isn't in its tried bucket", key)
+			}
+			continue
+		}
+		bucket := addrMgr.getNewBucket(ka.na, ka.srcAddr)
+		if addrMgr.addrNew[bucket][key] != ka || ka.refs != 1 {
+			t.Fatalf("%s isn't in its new bucket", key)
+		}
+	}
+
+	// Saving and loading them again with the same map keeps them as is.
+	addrMgr.savePeers()
+	addrMgr = New(tempDir, nil)
+	addrMgr.SetASMap(testASMap(t))
+	addrMgr.loadPeers()
+	if got := addrMgr.numAddresses(); got != want {
+		t.Fatalf("got %d addresses after reloading, want %d", got, want)
+	}
+}
@@ -34,5 +34,10 @@ provided by the caller about connected, known good, and attempted addresses to
 periodically purge peers which no longer appear to be good peers as well as
 bias the selection toward known good peers.  The general idea is to make a best
 effort at only providing usable addresses.
+
+Known good addresses don't evict the tried addresses in their way right away.
+The caller is expected to test those with short-lived feeler connections, which
+are also used to check the addresses that were never connected to, and the
+tried addresses are only evicted when they don't work.
 */
 package addrmgr
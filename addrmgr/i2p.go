@@ -0,0 +1,71 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package addrmgr
+
+import (
+	"encoding/base32"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// i2pHostSuffix is the suffix of the names of I2P destinations, which are the
+// base32 encoded SHA256 hash of the destination.
+const i2pHostSuffix = ".b32.i2p"
+
+// i2pEncoding is the base32 encoding of the names of I2P destinations.
+var i2pEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// i2pHosts maps the addresses I2P destinations are kept at to their names.  The
+// address is derived from the name, so the map is shared between the address
+// managers.
+var i2pHosts = struct {
+	sync.RWMutex
+	names map[string]string
+}{names: make(map[string]string)}
+
+// IsI2PHost returns whether or not the host is the name of an I2P destination.
+func IsI2PHost(host string) bool {
+	return strings.HasSuffix(host, i2pHostSuffix)
+}
+
+// i2pHostToIP returns the address in the I2P range the destination with the
+// passed .b32.i2p name is kept at and remembers the name of the address.
+func i2pHostToIP(host string) (net.IP, error) {
+	name := strings.ToLower(host)
+	hash, err := i2pEncoding.DecodeString(
+		strings.ToUpper(strings.TrimSuffix(name, i2pHostSuffix)))
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("invalid i2p destination %s", host)
+	}
+
+	ip := make(net.IP, 0, net.IPv6len)
+	ip = append(ip, i2pNet.IP[:6]...)
+	ip = append(ip, hash[:10]...)
+
+	i2pHosts.Lock()
+	i2pHosts.names[string(ip)] = name
+	i2pHosts.Unlock()
+	return ip, nil
+}
+
+// I2PHost returns the .b32.i2p name of the I2P destination kept at the address.
+// It returns an empty string when the address isn't in the I2P range or when
+// the destination behind it isn't known, which is the case for the addresses
+// in the range that were received from peers.
+func I2PHost(ip net.IP) string {
+	ip = ip.To16()
+	if ip == nil || !i2pNet.Contains(ip) {
+		return ""
+	}
+
+	i2pHosts.RLock()
+	defer i2pHosts.RUnlock()
+	return i2pHosts.names[string(ip)]
+}
@@ -84,6 +84,15 @@ var (
 	// { magic 6 bytes, 10 bytes base32 decode of key hash }
 	onionCatNet = ipNet("fd87:d87e:eb43::", 48, 128)
 
+	// i2pNet defines the IPv6 address block that I2P destinations are kept
+	// in.  Like with onionCatNet, the first 10 bytes of the hash of the
+	// destination follow the first 6 bytes of the block.  The hash doesn't
+	// fit in the address, so the .b32.i2p names of the destinations are
+	// looked up by their address with I2PHost.  The block is part of the
+	// RFC4193 unique local IPv6 range, so its addresses aren't relayed to
+	// peers, which couldn't tell the destination from it anyway.
+	i2pNet = ipNet("fd60:db4d:ddb5::", 48, 128)
+
 	// zero4Net defines the IPv4 address block for address staring with 0
 	// (0.0.0.0/8).
 	zero4Net = ipNet("0.0.0.0", 8, 32)
@@ -117,6 +126,12 @@ func IsOnionCatTor(na *wire.NetAddress) bool {
 	return onionCatNet.Contains(na.IP)
 }
 
+// IsI2P returns whether or not the passed address is in the IPv6 range that I2P
+// destinations are kept in (fd60:db4d:ddb5::/48).
+func IsI2P(na *wire.NetAddress) bool {
+	return i2pNet.Contains(na.IP)
+}
+
 // IsRFC1918 returns whether or not the passed address is part of the IPv4
 // private network address space as defined by RFC1918 (10.0.0.0/8,
 // 172.16.0.0/12, or 192.168.0.0/16).
@@ -226,14 +241,15 @@ func IsRoutable(na *wire.NetAddress) bool {
 	return IsValid(na) && !(IsRFC1918(na) || IsRFC2544(na) ||
 		IsRFC3927(na) || IsRFC4862(na) || IsRFC3849(na) ||
 		IsRFC4843(na) || IsRFC5737(na) || IsRFC6598(na) ||
-		IsLocal(na) || (IsRFC4193(na) && !IsOnionCatTor(na)))
+		IsLocal(na) || (IsRFC4193(na) && !IsOnionCatTor(na) && !IsI2P(na)))
 }
 
 // GroupKey returns a string representing the network group an address is part
 // of.  This is the /16 for IPv4, the /32 (/36 for he.net) for IPv6, the string
 // "local" for a local address, the string "tor:key" where key is the /4 of the
-// onion address for Tor address, and the string "unroutable" for an unroutable
-// address.
+// onion address for Tor address, the string "i2p:key" where key is the /4 of
+// the hash of the destination for I2P addresses, and the string "unroutable"
+// for an unroutable address.
 func GroupKey(na *wire.NetAddress) string {
 	if IsLocal(na) {
 		return "local"
@@ -268,6 +284,9 @@ func GroupKey(na *wire.NetAddress) string {
 		// group is keyed off the first 4 bits of the actual onion key.
 		return fmt.Sprintf("tor:%d", na.IP[6]&((1<<4)-1))
 	}
+	if IsI2P(na) {
+		return fmt.Sprintf("i2p:%d", na.IP[6]&((1<<4)-1))
+	}
 
 	// OK, so now we know ourselves to be a IPv6 address.
 	// bitcoind uses /32 for everything, except for Hurricane Electric's
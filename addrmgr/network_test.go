@@ -183,6 +183,10 @@ func TestGroupKey(t *testing.T) {
 		{name: "ipv6 tor onioncat 2", ip: "fd87:d87e:eb43:1245::6789", expected: "tor:2"},
 		{name: "ipv6 tor onioncat 3", ip: "fd87:d87e:eb43:1345::6789", expected: "tor:3"},
 
+		// I2P.
+		{name: "ipv6 i2p", ip: "fd60:db4d:ddb5:1234::5678", expected: "i2p:2"},
+		{name: "ipv6 i2p 2", ip: "fd60:db4d:ddb5:1345::6789", expected: "i2p:3"},
+
 		// IPv6 normal.
 		{name: "ipv6 normal", ip: "2602:100::1", expected: "2602:100::"},
 		{name: "ipv6 normal 2", ip: "2602:0100::1234", expected: "2602:100::"},
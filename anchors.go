@@ -0,0 +1,64 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+const (
+	// maxBlockRelayPeers is the most automatic outbound peers that are only
+	// used to relay blocks.  Those peers don't relay transactions or
+	// addresses, which makes them harder to find for an attacker, and they
+	// are saved as the anchors of the node on shutdown.
+	maxBlockRelayPeers = 2
+
+	// anchorsFileName is the name of the file in the data directory that
+	// the addresses of the block-relay-only peers are saved to on shutdown
+	// so that they're connected to first on the next start.
+	anchorsFileName = "anchors.json"
+)
+
+// saveAnchors saves the addresses of the anchors to the file at the path,
+// replacing it.
+func saveAnchors(path string, addrs []string) error {
+	data, err := json.Marshal(addrs)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".new"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// loadAnchors returns the addresses of the anchors saved to the file at the
+// path, which is removed so that a node that keeps crashing doesn't keep
+// connecting to the same anchors.  There are no anchors when there's no file.
+func loadAnchors(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	if err := json.Unmarshal(data, &addrs); err != nil {
+		return nil, err
+	}
+	if len(addrs) > maxBlockRelayPeers {
+		addrs = addrs[:maxBlockRelayPeers]
+	}
+	return addrs, nil
+}
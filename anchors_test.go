@@ -0,0 +1,43 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestAnchors ensures that the anchors are loaded once after being saved and
+// that no more of them than there are block-relay-only peers are loaded.
+func TestAnchors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), anchorsFileName)
+	addrs, err := loadAnchors(path)
+	if err != nil || addrs != nil {
+		t.Fatalf("got anchors %v (%v) without a file", addrs, err)
+	}
+
+	want := []string{"1.2.3.4:8333", "[2001:db8::1]:8333"}
+	if err := saveAnchors(path, append(want, "5.6.7.8:8333")); err != nil {
+		t.Fatalf("unable to save anchors: %v", err)
+	}
+	addrs, err = loadAnchors(path)
+	if err != nil {
+		t.Fatalf("unable to load anchors: %v", err)
+	}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("got anchors %v, want %v", addrs, want)
+	}
+
+	// The anchors are forgotten once they're loaded.
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("anchors file wasn't removed: %v", err)
+	}
+	addrs, err = loadAnchors(path)
+	if err != nil || addrs != nil {
+		t.Fatalf("got anchors %v (%v) after loading them", addrs, err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/blockchain/indexers"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+)
+
+// dataDirBackup describes a backup of the data directory made by
+// backupDataDir.
+type dataDirBackup struct {
+	height int32
+	hash   chainhash.Hash
+}
+
+// backupDataDir writes a copy of the block database and of the state that the
+// utreexo proof indexes keep outside of it to the passed directory, which must
+// not exist, without stopping the node.  No block is connected or disconnected
+// while the copy is made, so all of its parts are at the same block, and it is
+// a data directory the node can be started with.  The passed indexes are nil
+// when they're disabled.  The peer addresses and the wallets aren't copied, and
+// the wallets are backed up with backupwallet instead.
+func backupDataDir(chain *blockchain.BlockChain, db database.DB,
+	utreexoProofIndex *indexers.UtreexoProofIndex,
+	flatUtreexoProofIndex *indexers.FlatUtreexoProofIndex,
+	dir string) (*dataDirBackup, error) {
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	srvrLog.Infof("Backing up the data directory to %s", dir)
+	start := time.Now()
+	var backup dataDirBackup
+	err := chain.Quiesce(func() error {
+		best := chain.BestSnapshot()
+		backup.height, backup.hash = best.Height, best.Hash
+
+		dbPath := filepath.Join(dir, blockDbName(db.Type()))
+		if err := db.Backup(dbPath); err != nil {
+			return err
+		}
+		if utreexoProofIndex != nil {
+			if err := utreexoProofIndex.Backup(dir); err != nil {
+				return err
+			}
+		}
+		if flatUtreexoProofIndex != nil {
+			if err := flatUtreexoProofIndex.Backup(dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	srvrLog.Infof("Backed up the data directory at height %d (%v) in %v",
+		backup.height, backup.hash, time.Since(start).Round(time.Millisecond))
+	return &backup, nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btclog"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestBackupDataDir ensures that a backup of the data directory holds the
+// chain it was made at, and that a backup isn't written over an existing
+// directory.
+func TestBackupDataDir(t *testing.T) {
+	oldLog := srvrLog
+	srvrLog = btclog.Disabled
+	blockchain.UseLogger(btclog.Disabled)
+	defer func() {
+		srvrLog = oldLog
+		blockchain.UseLogger(chanLog)
+	}()
+
+	dir := t.TempDir()
+	writeImportTestFile(t, filepath.Join(dir, "blk00000.dat"), nil,
+		loadImportTestBlocks(t), nil)
+
+	// The test blocks spend the coinbases of the blocks before them.
+	params := chaincfg.MainNetParams
+	params.CoinbaseMaturity = 1
+	newChain := func(db database.DB) *blockchain.BlockChain {
+		chain, err := blockchain.New(&blockchain.Config{
+			DB:          db,
+			ChainParams: &params,
+			TimeSource:  blockchain.NewMedianTime(),
+		})
+		if err != nil {
+			t.Fatalf("unable to create the chain: %v", err)
+		}
+		return chain
+	}
+
+	db, err := database.Create("ffldb", filepath.Join(t.TempDir(), "db"),
+		wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to create the database: %v", err)
+	}
+	defer db.Close()
+	chain := newChain(db)
+	if err := importBlockFiles(chain, wire.MainNet, []string{dir}, nil); err != nil {
+		t.Fatalf("importBlockFiles: unexpected error: %v", err)
+	}
+
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	backup, err := backupDataDir(chain, db, nil, nil, backupDir)
+	if err != nil {
+		t.Fatalf("backupDataDir: unexpected error: %v", err)
+	}
+	best := chain.BestSnapshot()
+	if backup.height != best.Height || backup.hash != best.Hash {
+		t.Fatalf("got backup at %v height %d, want %v height %d",
+			backup.hash, backup.height, best.Hash, best.Height)
+	}
+	if _, err := backupDataDir(chain, db, nil, nil, backupDir); err == nil {
+		t.Fatalf("expected a backup over an existing directory to fail")
+	}
+
+	backupDb, err := database.Open("ffldb", filepath.Join(backupDir,
+		blockDbName("ffldb")), wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to open the backup: %v", err)
+	}
+	defer backupDb.Close()
+	got := newChain(backupDb).BestSnapshot()
+	if got.Height != best.Height || got.Hash != best.Hash {
+		t.Fatalf("got backup tip %v at height %d, want %v at height %d",
+			got.Hash, got.Height, best.Hash, best.Height)
+	}
+}
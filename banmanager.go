@@ -0,0 +1,252 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/utreexo/utreexod/addrmgr"
+)
+
+const (
+	// banListFileName is the name of the file in the data directory that
+	// the bans are kept in, so that banned peers stay banned across
+	// restarts.
+	banListFileName = "banlist.json"
+
+	// banReasonMisbehaving is the reason of the bans of the peers whose ban
+	// score went over the ban threshold.
+	banReasonMisbehaving = "node misbehaving"
+
+	// banReasonManual is the reason of the bans made with the setban RPC.
+	banReasonManual = "manually added"
+)
+
+// errNotBanned is returned when unbanning an IP address or a subnet that isn't
+// banned.
+var errNotBanned = errors.New("the IP address or subnet isn't banned")
+
+// banEntry is the ban of an IP address, a subnet or a Tor or I2P host.
+type banEntry struct {
+	Subnet  string `json:"subnet"`
+	Created int64  `json:"created"`
+	Until   int64  `json:"until"`
+	Reason  string `json:"reason"`
+
+	// ipNet is the subnet that is banned, or nil when the ban is of a Tor
+	// or I2P host.
+	ipNet *net.IPNet
+}
+
+// matches returns whether the host is banned by the entry.
+func (e *banEntry) matches(host string) bool {
+	if e.ipNet == nil {
+		return strings.EqualFold(e.Subnet, host)
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && e.ipNet.Contains(ip)
+}
+
+// parseBanSubnet returns the canonical form of the IP address, subnet in CIDR
+// notation, or Tor or I2P host to ban, along with the banned subnet for the
+// IP addresses and subnets.  Single IP addresses are banned as the subnet of
+// just them.
+func parseBanSubnet(subnet string) (string, *net.IPNet, error) {
+	if strings.Contains(subnet, "/") {
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid subnet %q", subnet)
+		}
+		return ipNet.String(), ipNet, nil
+	}
+
+	if ip := net.ParseIP(subnet); ip != nil {
+		bits := 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		ipNet := &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		return ipNet.String(), ipNet, nil
+	}
+
+	host := strings.ToLower(subnet)
+	if strings.HasSuffix(host, ".onion") || addrmgr.IsI2PHost(host) {
+		return host, nil, nil
+	}
+	return "", nil, fmt.Errorf("invalid IP address or subnet %q", subnet)
+}
+
+// banManager keeps the bans of the node and saves them to the ban list file
+// whenever they change.  It is safe for concurrent access.
+type banManager struct {
+	mtx  sync.Mutex
+	path string
+	bans map[string]*banEntry
+}
+
+// newBanManager returns a ban manager with the bans of the ban list file at
+// the path, which doesn't have to exist yet.  A ban manager without any bans is
+// returned along with the error when the file can't be read.
+func newBanManager(path string) (*banManager, error) {
+	bm := &banManager{
+		path: path,
+		bans: make(map[string]*banEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return bm, nil
+	}
+	if err != nil {
+		return bm, err
+	}
+	var entries []*banEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return bm, fmt.Errorf("malformed ban list %s: %v", path, err)
+	}
+	now := time.Now().Unix()
+	for _, entry := range entries {
+		subnet, ipNet, err := parseBanSubnet(entry.Subnet)
+		if err != nil {
+			return bm, fmt.Errorf("malformed ban list %s: %v", path, err)
+		}
+		if entry.Until <= now {
+			continue
+		}
+		entry.Subnet, entry.ipNet = subnet, ipNet
+		bm.bans[subnet] = entry
+	}
+	return bm, nil
+}
+
+// save writes the bans to the ban list file, replacing it only once all of
+// them are written.  It must be called with the mutex held.
+func (bm *banManager) save() error {
+	entries := make([]*banEntry, 0, len(bm.bans))
+	for _, entry := range bm.bans {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Subnet < entries[j].Subnet
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := bm.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, bm.path)
+}
+
+// sweep removes the bans that expired by the passed time, saving the rest when
+// any were removed.  It must be called with the mutex held.
+func (bm *banManager) sweep(now time.Time) {
+	var expired bool
+	for subnet, entry := range bm.bans {
+		if entry.Until <= now.Unix() {
+			srvrLog.Infof("Ban of %s expired", subnet)
+			delete(bm.bans, subnet)
+			expired = true
+		}
+	}
+	if !expired {
+		return
+	}
+	if err := bm.save(); err != nil {
+		srvrLog.Errorf("Unable to save the ban list: %v", err)
+	}
+}
+
+// ban bans the IP address, subnet, or Tor or I2P host until the passed time for
+// the passed reason, replacing any ban of it there already is.
+func (bm *banManager) ban(subnet string, until time.Time, reason string) error {
+	subnet, ipNet, err := parseBanSubnet(subnet)
+	if err != nil {
+		return err
+	}
+
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	bm.bans[subnet] = &banEntry{
+		Subnet:  subnet,
+		Created: time.Now().Unix(),
+		Until:   until.Unix(),
+		Reason:  reason,
+		ipNet:   ipNet,
+	}
+	return bm.save()
+}
+
+// unban removes the ban of the IP address, subnet, or Tor or I2P host.
+func (bm *banManager) unban(subnet string) error {
+	subnet, _, err := parseBanSubnet(subnet)
+	if err != nil {
+		return err
+	}
+
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	if _, exists := bm.bans[subnet]; !exists {
+		return errNotBanned
+	}
+	delete(bm.bans, subnet)
+	return bm.save()
+}
+
+// clear removes all the bans.
+func (bm *banManager) clear() error {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	bm.bans = make(map[string]*banEntry)
+	return bm.save()
+}
+
+// isBanned returns whether the host of a peer is banned along with when the
+// ban ends.  The host is banned by the bans of the subnets it's in.
+func (bm *banManager) isBanned(host string) (time.Time, bool) {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	now := time.Now()
+	bm.sweep(now)
+
+	var until int64
+	for _, entry := range bm.bans {
+		if entry.matches(host) && entry.Until > until {
+			until = entry.Until
+		}
+	}
+	return time.Unix(until, 0), until != 0
+}
+
+// list returns the bans that haven't expired yet ordered by what they ban.
+func (bm *banManager) list() []banEntry {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	bm.sweep(time.Now())
+	entries := make([]banEntry, 0, len(bm.bans))
+	for _, entry := range bm.bans {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Subnet < entries[j].Subnet
+	})
+	return entries
+}
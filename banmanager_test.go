@@ -0,0 +1,128 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// TestParseBanSubnet ensures that the IP addresses, subnets and Tor hosts to
+// ban are put in their canonical form and that anything else is rejected.
+func TestParseBanSubnet(t *testing.T) {
+	tests := []struct {
+		subnet string
+		want   string
+		valid  bool
+	}{
+		{subnet: "1.2.3.4", want: "1.2.3.4/32", valid: true},
+		{subnet: "1.2.3.4/24", want: "1.2.3.0/24", valid: true},
+		{subnet: "::ffff:1.2.3.4", want: "1.2.3.4/32", valid: true},
+		{subnet: "2001:db8::1", want: "2001:db8::1/128", valid: true},
+		{subnet: "2001:db8::/32", want: "2001:db8::/32", valid: true},
+		{subnet: "EXAMPLEONIONADDRESS.onion", want: "exampleonionaddress.onion", valid: true},
+		{subnet: "1.2.3.4/33"},
+		{subnet: "1.2.3.4:8333"},
+		{subnet: "example.com"},
+	}
+
+	for _, test := range tests {
+		got, _, err := parseBanSubnet(test.subnet)
+		if test.valid != (err == nil) {
+			t.Errorf("%s: unexpected error %v", test.subnet, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.subnet, got, test.want)
+		}
+	}
+}
+
+// TestBanManager ensures that the bans cover the hosts of their subnets, that
+// they're kept across restarts and that they expire.
+func TestBanManager(t *testing.T) {
+	// There's no log rotator to write the expired bans to.
+	logger := srvrLog
+	srvrLog = btclog.Disabled
+	defer func() { srvrLog = logger }()
+
+	path := filepath.Join(t.TempDir(), banListFileName)
+	bm, err := newBanManager(path)
+	if err != nil {
+		t.Fatalf("unable to create the ban manager: %v", err)
+	}
+
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := bm.ban("10.0.0.0/8", until, banReasonManual); err != nil {
+		t.Fatalf("unable to ban the subnet: %v", err)
+	}
+	if err := bm.ban("192.168.1.1", until, banReasonMisbehaving); err != nil {
+		t.Fatalf("unable to ban the IP address: %v", err)
+	}
+	if err := bm.ban("172.16.0.1", time.Now().Add(-time.Second),
+		banReasonManual); err != nil {
+
+		t.Fatalf("unable to ban the IP address: %v", err)
+	}
+
+	// Restarting keeps the bans that haven't expired.
+	bm, err = newBanManager(path)
+	if err != nil {
+		t.Fatalf("unable to load the ban list: %v", err)
+	}
+	tests := []struct {
+		host   string
+		banned bool
+	}{
+		{host: "10.1.2.3", banned: true},
+		{host: "192.168.1.1", banned: true},
+		{host: "192.168.1.2", banned: false},
+		{host: "172.16.0.1", banned: false},
+		{host: "11.0.0.1", banned: false},
+	}
+	for _, test := range tests {
+		banEnd, banned := bm.isBanned(test.host)
+		if banned != test.banned {
+			t.Errorf("%s: got banned %v, want %v", test.host, banned,
+				test.banned)
+		}
+		if banned && !banEnd.Equal(until) {
+			t.Errorf("%s: got ban end %v, want %v", test.host, banEnd,
+				until)
+		}
+	}
+
+	bans := bm.list()
+	if len(bans) != 2 || bans[0].Subnet != "10.0.0.0/8" ||
+		bans[1].Reason != banReasonMisbehaving {
+
+		t.Fatalf("unexpected bans %v", bans)
+	}
+
+	// Unbanning has to be done with what was banned.
+	if err := bm.unban("10.1.2.3"); err != errNotBanned {
+		t.Fatalf("got error %v unbanning a host that isn't banned", err)
+	}
+	if err := bm.unban("10.0.0.0/8"); err != nil {
+		t.Fatalf("unable to unban the subnet: %v", err)
+	}
+	if _, banned := bm.isBanned("10.1.2.3"); banned {
+		t.Fatalf("expected the subnet to be unbanned")
+	}
+
+	if err := bm.clear(); err != nil {
+		t.Fatalf("unable to clear the bans: %v", err)
+	}
+	bm, err = newBanManager(path)
+	if err != nil {
+		t.Fatalf("unable to load the ban list: %v", err)
+	}
+	if bans := bm.list(); len(bans) != 0 {
+		t.Fatalf("expected no bans after clearing them, got %v", bans)
+	}
+}
@@ -0,0 +1,469 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// backupVersion is the version of the backup format written.
+	backupVersion = 1
+
+	// The scrypt parameters the key a backup is encrypted with is derived
+	// from the passphrase with.
+	backupScryptN = 1 << 15
+	backupScryptR = 8
+	backupScryptP = 1
+
+	backupSaltLen = 16
+)
+
+var (
+	// backupMagic starts every wallet backup file.
+	backupMagic = []byte("UTXWBKUP")
+
+	ErrNoBackupPassphrase = errors.New("a passphrase is needed to encrypt and decrypt wallet backups")
+	ErrBadBackup          = errors.New("wrong passphrase or corrupted wallet backup")
+)
+
+// importSuffixRegexp matches the suffixes the files of the imported descriptors
+// have after the name of the wallet file.
+var importSuffixRegexp = regexp.MustCompile(`^\.import\.[0-9]+$`)
+
+// optionalFileSuffixes are the suffixes of the files that a wallet only has
+// once it's used the features they're for.
+var optionalFileSuffixes = []string{silentPaymentFileSuffix, labelsFileSuffix, lockedFileSuffix}
+
+// isOptionalFileSuffix returns whether the suffix is one of the optional files
+// of a wallet.
+func isOptionalFileSuffix(suffix string) bool {
+	for _, optional := range optionalFileSuffixes {
+		if suffix == optional {
+			return true
+		}
+	}
+	return false
+}
+
+// walletFiles returns the suffixes of the files of the wallet stored at the
+// path: the wallet file itself, with an empty suffix, followed by the files of
+// the imported descriptors and the optional files that the wallet has.
+func walletFiles(dbPath string) ([]string, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	}
+	suffixes := []string{""}
+	for i := 0; ; i++ {
+		suffix := ".import." + strconv.Itoa(i)
+		if _, err := os.Stat(dbPath + suffix); err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			break
+		}
+		suffixes = append(suffixes, suffix)
+	}
+	for _, suffix := range optionalFileSuffixes {
+		if _, err := os.Stat(dbPath + suffix); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		suffixes = append(suffixes, suffix)
+	}
+	return suffixes, nil
+}
+
+// backupKey derives the key a backup is encrypted with from the passphrase.
+func backupKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, backupScryptN, backupScryptR,
+		backupScryptP, 32)
+}
+
+// encryptBackup returns the backup of the wallet files, which are keyed by
+// their suffixes, encrypted with the passphrase.
+//
+// The backup is the magic, the version, the salt of the key and the nonce
+// followed by the files encrypted with AES-256-GCM.  The magic, the version and
+// the salt are authenticated with the files.
+func encryptBackup(files map[string][]byte, suffixes []string, passphrase string) ([]byte, error) {
+	var plaintext bytes.Buffer
+	for _, suffix := range suffixes {
+		data := files[suffix]
+		plaintext.WriteByte(byte(len(suffix)))
+		plaintext.WriteString(suffix)
+		var size [8]byte
+		binary.LittleEndian.PutUint64(size[:], uint64(len(data)))
+		plaintext.Write(size[:])
+		plaintext.Write(data)
+	}
+
+	header := make([]byte, 0, len(backupMagic)+1+backupSaltLen)
+	header = append(header, backupMagic...)
+	header = append(header, backupVersion)
+	salt := make([]byte, backupSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	header = append(header, salt...)
+
+	aead, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	backup := append(header, nonce...)
+	return aead.Seal(backup, nonce, plaintext.Bytes(), header), nil
+}
+
+// decryptBackup returns the wallet files of the backup, keyed by their
+// suffixes, after decrypting it with the passphrase.
+func decryptBackup(backup []byte, passphrase string) (map[string][]byte, error) {
+	headerLen := len(backupMagic) + 1 + backupSaltLen
+	if len(backup) < headerLen || !bytes.Equal(backup[:len(backupMagic)], backupMagic) {
+		return nil, errors.New("not a wallet backup")
+	}
+	if version := backup[len(backupMagic)]; version != backupVersion {
+		return nil, fmt.Errorf("unsupported wallet backup version %d", version)
+	}
+	header := backup[:headerLen]
+	salt := header[len(backupMagic)+1:]
+
+	aead, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(backup) < headerLen+aead.NonceSize() {
+		return nil, ErrBadBackup
+	}
+	nonce := backup[headerLen : headerLen+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, backup[headerLen+aead.NonceSize():], header)
+	if err != nil {
+		return nil, ErrBadBackup
+	}
+
+	files := make(map[string][]byte)
+	r := bytes.NewReader(plaintext)
+	for r.Len() > 0 {
+		suffixLen, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrBadBackup
+		}
+		suffix := make([]byte, suffixLen)
+		if _, err := io.ReadFull(r, suffix); err != nil {
+			return nil, ErrBadBackup
+		}
+		var size uint64
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil ||
+			size > uint64(r.Len()) {
+
+			return nil, ErrBadBackup
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, ErrBadBackup
+		}
+		if len(suffix) != 0 && !importSuffixRegexp.Match(suffix) &&
+			!isOptionalFileSuffix(string(suffix)) {
+
+			return nil, ErrBadBackup
+		}
+		files[string(suffix)] = data
+	}
+	if _, ok := files[""]; !ok {
+		return nil, ErrBadBackup
+	}
+	return files, nil
+}
+
+// backupCipher returns the AES-256-GCM cipher of the key derived from the
+// passphrase.
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, ErrNoBackupPassphrase
+	}
+	key, err := backupKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFileAtomic writes the data to a temporary file that is then renamed to
+// the path so that an interrupted write doesn't leave a partial file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Backup writes an encrypted backup of the wallet, of its imported descriptors
+// and of its silent payments and labels to the destination.  The passphrase configured for the
+// automatic backups is used when the passphrase is empty.
+func (m *Manager) Backup(destination, passphrase string) error {
+	if passphrase == "" {
+		passphrase = m.config.BackupPassphrase
+	}
+	if passphrase == "" {
+		return ErrNoBackupPassphrase
+	}
+
+	dbPath := m.dbPath()
+
+	// Hold the mutex so that no blocks are written to the wallet while its
+	// files are read.
+	m.mtx.Lock()
+	suffixes, err := walletFiles(dbPath)
+	if err != nil {
+		m.mtx.Unlock()
+		return err
+	}
+	files := make(map[string][]byte, len(suffixes))
+	for _, suffix := range suffixes {
+		if files[suffix], err = os.ReadFile(dbPath + suffix); err != nil {
+			m.mtx.Unlock()
+			return err
+		}
+	}
+	m.mtx.Unlock()
+
+	backup, err := encryptBackup(files, suffixes, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(destination, backup)
+}
+
+// Restore restores the encrypted backup at the source as the named wallet and
+// loads it.  The passphrase configured for the automatic backups is used when
+// the passphrase is empty.  The name must not be taken by an existing wallet.
+func (w *Wallets) Restore(name, source, passphrase string) (*Manager, error) {
+	if err := checkWalletName(name); err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		passphrase = w.config.BackupPassphrase
+	}
+	backup, err := os.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+	files, err := decryptBackup(backup, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if _, ok := w.named[name]; ok {
+		return nil, fmt.Errorf("%w: %q", ErrWalletLoaded, name)
+	}
+	exists, err := w.exists(name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: %q", ErrWalletExists, name)
+	}
+
+	// The wallet file is written last so that the wallet doesn't exist
+	// until all of the files of its imported descriptors are in place.
+	dbPath := filepath.Join(WalletDir(w.config.DataDir), walletFileName(name))
+	var imports int
+	for suffix := range files {
+		if importSuffixRegexp.MatchString(suffix) {
+			imports++
+		}
+	}
+	for _, suffix := range optionalFileSuffixes {
+		if _, ok := files[suffix]; ok {
+			continue
+		}
+		err := os.Remove(dbPath + suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	for i := imports; ; i++ {
+		// Remove the files of the imported descriptors a removed wallet
+		// of the same name left behind.
+		err := os.Remove(dbPath + ".import." + strconv.Itoa(i))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for suffix, data := range files {
+		if suffix == "" {
+			continue
+		}
+		if err := writeFileAtomic(dbPath+suffix, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeFileAtomic(dbPath, files[""]); err != nil {
+		return nil, err
+	}
+
+	config := w.config
+	config.WalletName = name
+	config.WatchOnlyDescriptor = ""
+	return w.load(config)
+}
+
+// backupLabel returns the name the backups of the wallet are written under.
+func backupLabel(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+// backupFileName returns the name of the automatic backup of the wallet made
+// at the time.
+func backupFileName(name string, t time.Time) string {
+	return backupLabel(name) + "-" + t.UTC().Format("20060102-150405") + ".bak"
+}
+
+// rotateBackups removes the oldest automatic backups of the wallet in the
+// directory so that only the newest keep of them are left.
+func rotateBackups(dir, name string, keep int) error {
+	pattern, err := regexp.Compile(`^` + regexp.QuoteMeta(backupLabel(name)) +
+		`-[0-9]{8}-[0-9]{6}\.bak$`)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var backups []string
+	for _, entry := range entries {
+		if !entry.IsDir() && pattern.MatchString(entry.Name()) {
+			backups = append(backups, entry.Name())
+		}
+	}
+
+	// The timestamps in the names sort the backups from the oldest to the
+	// newest.
+	sort.Strings(backups)
+	for len(backups) > keep {
+		if err := os.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// autoBackup writes a backup of every loaded wallet to the backup directory
+// and removes the oldest ones beyond the number of backups kept.
+func (w *Wallets) autoBackup() {
+	dir := w.config.BackupDir
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Errorf("Unable to create the wallet backup directory. %v", err)
+		return
+	}
+
+	now := time.Now()
+	managers := []*Manager{w.Default}
+	w.mtx.RLock()
+	for _, manager := range w.named {
+		managers = append(managers, manager)
+	}
+	w.mtx.RUnlock()
+
+	for _, manager := range managers {
+		name := manager.Name()
+		path := filepath.Join(dir, backupFileName(name, now))
+		if err := manager.Backup(path, ""); err != nil {
+			log.Errorf("Unable to back up the wallet %s. %v",
+				backupLabel(name), err)
+			continue
+		}
+		log.Debugf("Backed up the wallet %s to %s", backupLabel(name), path)
+
+		if w.config.BackupKeep > 0 {
+			if err := rotateBackups(dir, name, w.config.BackupKeep); err != nil {
+				log.Errorf("Unable to remove the old backups of the wallet %s. %v",
+					backupLabel(name), err)
+			}
+		}
+	}
+}
+
+// backupHandler backs up the loaded wallets on the configured interval until
+// the wallets are stopped.
+//
+// This function MUST be run as a goroutine.
+func (w *Wallets) backupHandler() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.config.BackupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.autoBackup()
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// Start starts backing up the loaded wallets if automatic backups are
+// configured.
+func (w *Wallets) Start() {
+	if w.config.BackupInterval <= 0 {
+		return
+	}
+	log.Infof("Backing up the BDK wallets to %s every %v",
+		w.config.BackupDir, w.config.BackupInterval)
+	w.wg.Add(1)
+	go w.backupHandler()
+}
+
+// Stop stops the automatic backups.
+func (w *Wallets) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.quit)
+	})
+	w.wg.Wait()
+}
+
+// defaultBackupDir returns the directory the automatic backups are written to
+// when none is configured.
+func defaultBackupDir(dataDir string) string {
+	return filepath.Join(WalletDir(dataDir), "backups")
+}
@@ -0,0 +1,107 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestBackup ensures that the files of a wallet are backed up encrypted and
+// that they're only recovered with the right passphrase.
+func TestBackup(t *testing.T) {
+	dataDir := t.TempDir()
+	walletDir := WalletDir(dataDir)
+	if err := os.MkdirAll(walletDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	contents := map[string][]byte{
+		"":          []byte("wallet changesets"),
+		".import.0": []byte("first import"),
+		".import.1": []byte("second import"),
+		".sp":       []byte("silent payments"),
+		".labels":   []byte("labels"),
+		".locked":   []byte("locked outputs"),
+	}
+	dbPath := filepath.Join(walletDir, walletFileName("savings"))
+	for suffix, data := range contents {
+		if err := os.WriteFile(dbPath+suffix, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &Manager{config: ManagerConfig{DataDir: dataDir, WalletName: "savings"}}
+	destination := filepath.Join(dataDir, "savings.bak")
+	if err := m.Backup(destination, ""); !errors.Is(err, ErrNoBackupPassphrase) {
+		t.Fatalf("got error %v, want %v", err, ErrNoBackupPassphrase)
+	}
+	if err := m.Backup(destination, "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(backup, contents[""]) {
+		t.Fatal("the backup isn't encrypted")
+	}
+
+	files, err := decryptBackup(backup, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(contents) {
+		t.Fatalf("got %d files, want %d", len(files), len(contents))
+	}
+	for suffix, data := range contents {
+		if !bytes.Equal(files[suffix], data) {
+			t.Fatalf("file %q: got %q, want %q", suffix, files[suffix], data)
+		}
+	}
+
+	if _, err := decryptBackup(backup, "hunter3"); !errors.Is(err, ErrBadBackup) {
+		t.Fatalf("got error %v, want %v", err, ErrBadBackup)
+	}
+	tampered := append([]byte(nil), backup...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := decryptBackup(tampered, "hunter2"); !errors.Is(err, ErrBadBackup) {
+		t.Fatalf("got error %v, want %v", err, ErrBadBackup)
+	}
+	future := append([]byte(nil), backup...)
+	future[len(backupMagic)] = backupVersion + 1
+	if _, err := decryptBackup(future, "hunter2"); err == nil {
+		t.Fatal("expected an error for an unknown backup version")
+	}
+}
+
+// TestRotateBackups ensures that only the newest automatic backups of a wallet
+// are kept.
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var names []string
+	for i := 0; i < 4; i++ {
+		for _, wallet := range []string{"", "default-2"} {
+			name := backupFileName(wallet, start.Add(time.Duration(i)*time.Hour))
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, name)
+		}
+	}
+
+	if err := rotateBackups(dir, "", 2); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range names {
+		_, err := os.Stat(filepath.Join(dir, name))
+		// Only the two oldest backups of the default wallet are removed.
+		removed := i%2 == 0 && i < 4
+		if removed != os.IsNotExist(err) {
+			t.Fatalf("%s: removed %v, want %v", name, os.IsNotExist(err), removed)
+		}
+	}
+}
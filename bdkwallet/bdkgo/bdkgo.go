@@ -481,6 +481,30 @@ func uniffiCheckChecksums() {
 	}
 }
 
+type FfiConverterInt32 struct{}
+
+var FfiConverterInt32INSTANCE = FfiConverterInt32{}
+
+func (FfiConverterInt32) Lower(value int32) C.int32_t {
+	return C.int32_t(value)
+}
+
+func (FfiConverterInt32) Write(writer io.Writer, value int32) {
+	writeInt32(writer, value)
+}
+
+func (FfiConverterInt32) Lift(value C.int32_t) int32 {
+	return int32(value)
+}
+
+func (FfiConverterInt32) Read(reader io.Reader) int32 {
+	return readInt32(reader)
+}
+
+type FfiDestroyerInt32 struct{}
+
+func (FfiDestroyerInt32) Destroy(_ int32) {}
+
 type FfiConverterUint32 struct{}
 
 var FfiConverterUint32INSTANCE = FfiConverterUint32{}
@@ -748,6 +772,17 @@ func WalletCreateNew(dbPath string, network string, genesisHash []byte) (*Wallet
 		return FfiConverterWalletINSTANCE.Lift(_uniffiRV), _uniffiErr
 	}
 }
+func WalletCreateWatchOnly(dbPath string, network string, genesisHash []byte, descriptor string, birthday uint32) (*Wallet, error) {
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypeCreateNewError{}, func(_uniffiStatus *C.RustCallStatus) unsafe.Pointer {
+		return C.uniffi_bdkgo_fn_constructor_wallet_create_watch_only(FfiConverterStringINSTANCE.Lower(dbPath), FfiConverterStringINSTANCE.Lower(network), FfiConverterBytesINSTANCE.Lower(genesisHash), FfiConverterStringINSTANCE.Lower(descriptor), FfiConverterUint32INSTANCE.Lower(birthday), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue *Wallet
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterWalletINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
 func WalletLoad(dbPath string) (*Wallet, error) {
 	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypeLoadError{}, func(_uniffiStatus *C.RustCallStatus) unsafe.Pointer {
 		return C.uniffi_bdkgo_fn_constructor_wallet_load(FfiConverterStringINSTANCE.Lower(dbPath), _uniffiStatus)
@@ -775,6 +810,21 @@ func (_self *Wallet) ApplyBlock(height uint32, blockBytes []byte) (ApplyResult,
 	}
 }
 
+func (_self *Wallet) ApplyBlockToImports(height uint32, blockBytes []byte) (ApplyResult, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypeApplyBlockError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_apply_block_to_imports(
+			_pointer, FfiConverterUint32INSTANCE.Lower(height), FfiConverterBytesINSTANCE.Lower(blockBytes), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue ApplyResult
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterTypeApplyResultINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
 func (_self *Wallet) ApplyMempool(txs []MempoolTx) (ApplyResult, error) {
 	_pointer := _self.ffiObject.incrementPointer("*Wallet")
 	defer _self.ffiObject.decrementPointer()
@@ -799,12 +849,81 @@ func (_self *Wallet) Balance() Balance {
 	}))
 }
 
-func (_self *Wallet) CreateTx(feerate float32, recipients []Recipient) ([]byte, error) {
+func (_self *Wallet) Birthday() uint32 {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	return FfiConverterUint32INSTANCE.Lift(rustCall(func(_uniffiStatus *C.RustCallStatus) C.uint32_t {
+		return C.uniffi_bdkgo_fn_method_wallet_birthday(
+			_pointer, _uniffiStatus)
+	}))
+}
+
+func (_self *Wallet) BumpFee(txid []byte, feerate float32) (FeeBump, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypeBumpFeeError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_bump_fee(
+			_pointer, FfiConverterBytesINSTANCE.Lower(txid), FfiConverterFloat32INSTANCE.Lower(feerate), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue FeeBump
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterTypeFeeBumpINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
+func (_self *Wallet) CreateCpfp(txid []byte, feerate float32) (FeeBump, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypeBumpFeeError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_create_cpfp(
+			_pointer, FfiConverterBytesINSTANCE.Lower(txid), FfiConverterFloat32INSTANCE.Lower(feerate), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue FeeBump
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterTypeFeeBumpINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
+func (_self *Wallet) CreatePsbt(inputs []PsbtInput, outputs []PsbtOutput, locktime uint32, feerate float32, replaceable bool, addInputs bool, coinSelection CoinSelection) (FundedPsbt, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypePsbtError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_create_psbt(
+			_pointer, FfiConverterSequenceTypePsbtInputINSTANCE.Lower(inputs), FfiConverterSequenceTypePsbtOutputINSTANCE.Lower(outputs), FfiConverterUint32INSTANCE.Lower(locktime), FfiConverterFloat32INSTANCE.Lower(feerate), FfiConverterBoolINSTANCE.Lower(replaceable), FfiConverterBoolINSTANCE.Lower(addInputs), FfiConverterTypeCoinSelectionINSTANCE.Lower(coinSelection), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue FundedPsbt
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterTypeFundedPsbtINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
+func (_self *Wallet) CreateSweep(feerate float32, scriptPubkey []byte, inputs []PsbtInput) (FundedPsbt, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypePsbtError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_create_sweep(
+			_pointer, FfiConverterFloat32INSTANCE.Lower(feerate), FfiConverterBytesINSTANCE.Lower(scriptPubkey), FfiConverterSequenceTypePsbtInputINSTANCE.Lower(inputs), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue FundedPsbt
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterTypeFundedPsbtINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
+func (_self *Wallet) CreateTx(feerate float32, recipients []Recipient, inputs []PsbtInput, coinSelection CoinSelection) ([]byte, error) {
 	_pointer := _self.ffiObject.incrementPointer("*Wallet")
 	defer _self.ffiObject.decrementPointer()
 	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypeCreateTxError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
 		return C.uniffi_bdkgo_fn_method_wallet_create_tx(
-			_pointer, FfiConverterFloat32INSTANCE.Lower(feerate), FfiConverterSequenceTypeRecipientINSTANCE.Lower(recipients), _uniffiStatus)
+			_pointer, FfiConverterFloat32INSTANCE.Lower(feerate), FfiConverterSequenceTypeRecipientINSTANCE.Lower(recipients), FfiConverterSequenceTypePsbtInputINSTANCE.Lower(inputs), FfiConverterTypeCoinSelectionINSTANCE.Lower(coinSelection), _uniffiStatus)
 	})
 	if _uniffiErr != nil {
 		var _uniffiDefaultValue []byte
@@ -814,6 +933,30 @@ func (_self *Wallet) CreateTx(feerate float32, recipients []Recipient) ([]byte,
 	}
 }
 
+func (_self *Wallet) Descriptors(includePrivate bool) []DescriptorInfo {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	return FfiConverterSequenceTypeDescriptorInfoINSTANCE.Lift(rustCall(func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_descriptors(
+			_pointer, FfiConverterBoolINSTANCE.Lower(includePrivate), _uniffiStatus)
+	}))
+}
+
+func (_self *Wallet) FinalizePsbt(psbt []byte) (FinalizedPsbt, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypePsbtError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_finalize_psbt(
+			_pointer, FfiConverterBytesINSTANCE.Lower(psbt), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue FinalizedPsbt
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterTypeFinalizedPsbtINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
 func (_self *Wallet) FreshAddress() (AddressInfo, error) {
 	_pointer := _self.ffiObject.incrementPointer("*Wallet")
 	defer _self.ffiObject.decrementPointer()
@@ -838,6 +981,26 @@ func (_self *Wallet) GenesisHash() []byte {
 	}))
 }
 
+func (_self *Wallet) ImportDescriptor(descriptor string, birthday uint32) error {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_, _uniffiErr := rustCallWithError(FfiConverterTypeImportDescriptorError{}, func(_uniffiStatus *C.RustCallStatus) bool {
+		C.uniffi_bdkgo_fn_method_wallet_import_descriptor(
+			_pointer, FfiConverterStringINSTANCE.Lower(descriptor), FfiConverterUint32INSTANCE.Lower(birthday), _uniffiStatus)
+		return false
+	})
+	return _uniffiErr
+}
+
+func (_self *Wallet) ImportsHeight() uint32 {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	return FfiConverterUint32INSTANCE.Lift(rustCall(func(_uniffiStatus *C.RustCallStatus) C.uint32_t {
+		return C.uniffi_bdkgo_fn_method_wallet_imports_height(
+			_pointer, _uniffiStatus)
+	}))
+}
+
 func (_self *Wallet) IncrementReferenceCounter() {
 	_pointer := _self.ffiObject.incrementPointer("*Wallet")
 	defer _self.ffiObject.decrementPointer()
@@ -887,6 +1050,51 @@ func (_self *Wallet) PeekAddress(index uint32) (AddressInfo, error) {
 	}
 }
 
+func (_self *Wallet) ProcessPsbt(psbt []byte, sign bool) (ProcessedPsbt, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypePsbtError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_process_psbt(
+			_pointer, FfiConverterBytesINSTANCE.Lower(psbt), FfiConverterBoolINSTANCE.Lower(sign), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue ProcessedPsbt
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterTypeProcessedPsbtINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
+func (_self *Wallet) PsbtInputs(psbt []byte) ([]PsbtInput, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypePsbtError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_psbt_inputs(
+			_pointer, FfiConverterBytesINSTANCE.Lower(psbt), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue []PsbtInput
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterSequenceTypePsbtInputINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
+func (_self *Wallet) PsbtOutputs(psbt []byte) ([]PsbtOutput, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypePsbtError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_psbt_outputs(
+			_pointer, FfiConverterBytesINSTANCE.Lower(psbt), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue []PsbtOutput
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterSequenceTypePsbtOutputINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
 func (_self *Wallet) RecentBlocks(count uint32) []BlockId {
 	_pointer := _self.ffiObject.incrementPointer("*Wallet")
 	defer _self.ffiObject.decrementPointer()
@@ -896,6 +1104,40 @@ func (_self *Wallet) RecentBlocks(count uint32) []BlockId {
 	}))
 }
 
+func (_self *Wallet) Scripts() [][]byte {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	return FfiConverterSequenceBytesINSTANCE.Lift(rustCall(func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_scripts(
+			_pointer, _uniffiStatus)
+	}))
+}
+
+func (_self *Wallet) SetLockedUtxos(outpoints []PsbtInput) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	rustCall(func(_uniffiStatus *C.RustCallStatus) bool {
+		C.uniffi_bdkgo_fn_method_wallet_set_locked_utxos(
+			_pointer, FfiConverterSequenceTypePsbtInputINSTANCE.Lower(outpoints), _uniffiStatus)
+		return false
+	})
+}
+
+func (_self *Wallet) SilentPaymentPsbt(psbt []byte, outputs []SilentPaymentOutput) (SilentPaymentPsbt, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypePsbtError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_silent_payment_psbt(
+			_pointer, FfiConverterBytesINSTANCE.Lower(psbt), FfiConverterSequenceTypeSilentPaymentOutputINSTANCE.Lower(outputs), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue SilentPaymentPsbt
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterTypeSilentPaymentPsbtINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
 func (_self *Wallet) Transactions() []TxInfo {
 	_pointer := _self.ffiObject.incrementPointer("*Wallet")
 	defer _self.ffiObject.decrementPointer()
@@ -905,6 +1147,21 @@ func (_self *Wallet) Transactions() []TxInfo {
 	}))
 }
 
+func (_self *Wallet) UpdatePsbt(psbt []byte, prevTxs [][]byte) ([]byte, error) {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	_uniffiRV, _uniffiErr := rustCallWithError(FfiConverterTypePsbtError{}, func(_uniffiStatus *C.RustCallStatus) RustBufferI {
+		return C.uniffi_bdkgo_fn_method_wallet_update_psbt(
+			_pointer, FfiConverterBytesINSTANCE.Lower(psbt), FfiConverterSequenceBytesINSTANCE.Lower(prevTxs), _uniffiStatus)
+	})
+	if _uniffiErr != nil {
+		var _uniffiDefaultValue []byte
+		return _uniffiDefaultValue, _uniffiErr
+	} else {
+		return FfiConverterBytesINSTANCE.Lift(_uniffiRV), _uniffiErr
+	}
+}
+
 func (_self *Wallet) Utxos() []UtxoInfo {
 	_pointer := _self.ffiObject.incrementPointer("*Wallet")
 	defer _self.ffiObject.decrementPointer()
@@ -914,6 +1171,15 @@ func (_self *Wallet) Utxos() []UtxoInfo {
 	}))
 }
 
+func (_self *Wallet) WatchOnly() bool {
+	_pointer := _self.ffiObject.incrementPointer("*Wallet")
+	defer _self.ffiObject.decrementPointer()
+	return FfiConverterBoolINSTANCE.Lift(rustCall(func(_uniffiStatus *C.RustCallStatus) C.int8_t {
+		return C.uniffi_bdkgo_fn_method_wallet_watch_only(
+			_pointer, _uniffiStatus)
+	}))
+}
+
 func (object *Wallet) Destroy() {
 	runtime.SetFinalizer(object, nil)
 	object.ffiObject.destroy()
@@ -1122,839 +1388,1973 @@ func (_ FfiDestroyerTypeBlockId) Destroy(value BlockId) {
 	value.Destroy()
 }
 
-type MempoolTx struct {
-	Tx        []byte
-	AddedUnix uint64
+type DescriptorInfo struct {
+	Descriptor string
+	Internal   bool
+	Birthday   uint32
+	NextIndex  uint32
 }
 
-func (r *MempoolTx) Destroy() {
-	FfiDestroyerBytes{}.Destroy(r.Tx)
-	FfiDestroyerUint64{}.Destroy(r.AddedUnix)
+func (r *DescriptorInfo) Destroy() {
+	FfiDestroyerString{}.Destroy(r.Descriptor)
+	FfiDestroyerBool{}.Destroy(r.Internal)
+	FfiDestroyerUint32{}.Destroy(r.Birthday)
+	FfiDestroyerUint32{}.Destroy(r.NextIndex)
 }
 
-type FfiConverterTypeMempoolTx struct{}
+type FfiConverterTypeDescriptorInfo struct{}
 
-var FfiConverterTypeMempoolTxINSTANCE = FfiConverterTypeMempoolTx{}
+var FfiConverterTypeDescriptorInfoINSTANCE = FfiConverterTypeDescriptorInfo{}
 
-func (c FfiConverterTypeMempoolTx) Lift(rb RustBufferI) MempoolTx {
-	return LiftFromRustBuffer[MempoolTx](c, rb)
+func (c FfiConverterTypeDescriptorInfo) Lift(rb RustBufferI) DescriptorInfo {
+	return LiftFromRustBuffer[DescriptorInfo](c, rb)
 }
 
-func (c FfiConverterTypeMempoolTx) Read(reader io.Reader) MempoolTx {
-	return MempoolTx{
-		FfiConverterBytesINSTANCE.Read(reader),
-		FfiConverterUint64INSTANCE.Read(reader),
+func (c FfiConverterTypeDescriptorInfo) Read(reader io.Reader) DescriptorInfo {
+	return DescriptorInfo{
+		FfiConverterStringINSTANCE.Read(reader),
+		FfiConverterBoolINSTANCE.Read(reader),
+		FfiConverterUint32INSTANCE.Read(reader),
+		FfiConverterUint32INSTANCE.Read(reader),
 	}
 }
 
-func (c FfiConverterTypeMempoolTx) Lower(value MempoolTx) RustBuffer {
-	return LowerIntoRustBuffer[MempoolTx](c, value)
+func (c FfiConverterTypeDescriptorInfo) Lower(value DescriptorInfo) RustBuffer {
+	return LowerIntoRustBuffer[DescriptorInfo](c, value)
 }
 
-func (c FfiConverterTypeMempoolTx) Write(writer io.Writer, value MempoolTx) {
-	FfiConverterBytesINSTANCE.Write(writer, value.Tx)
-	FfiConverterUint64INSTANCE.Write(writer, value.AddedUnix)
+func (c FfiConverterTypeDescriptorInfo) Write(writer io.Writer, value DescriptorInfo) {
+	FfiConverterStringINSTANCE.Write(writer, value.Descriptor)
+	FfiConverterBoolINSTANCE.Write(writer, value.Internal)
+	FfiConverterUint32INSTANCE.Write(writer, value.Birthday)
+	FfiConverterUint32INSTANCE.Write(writer, value.NextIndex)
 }
 
-type FfiDestroyerTypeMempoolTx struct{}
+type FfiDestroyerTypeDescriptorInfo struct{}
 
-func (_ FfiDestroyerTypeMempoolTx) Destroy(value MempoolTx) {
+func (_ FfiDestroyerTypeDescriptorInfo) Destroy(value DescriptorInfo) {
 	value.Destroy()
 }
 
-type Recipient struct {
-	Address string
-	Amount  uint64
+type EcdhShare struct {
+	ScanKey []byte
+	Share   []byte
 }
 
-func (r *Recipient) Destroy() {
-	FfiDestroyerString{}.Destroy(r.Address)
-	FfiDestroyerUint64{}.Destroy(r.Amount)
+func (r *EcdhShare) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.ScanKey)
+	FfiDestroyerBytes{}.Destroy(r.Share)
 }
 
-type FfiConverterTypeRecipient struct{}
+type FfiConverterTypeEcdhShare struct{}
 
-var FfiConverterTypeRecipientINSTANCE = FfiConverterTypeRecipient{}
+var FfiConverterTypeEcdhShareINSTANCE = FfiConverterTypeEcdhShare{}
 
-func (c FfiConverterTypeRecipient) Lift(rb RustBufferI) Recipient {
-	return LiftFromRustBuffer[Recipient](c, rb)
+func (c FfiConverterTypeEcdhShare) Lift(rb RustBufferI) EcdhShare {
+	return LiftFromRustBuffer[EcdhShare](c, rb)
 }
 
-func (c FfiConverterTypeRecipient) Read(reader io.Reader) Recipient {
-	return Recipient{
-		FfiConverterStringINSTANCE.Read(reader),
-		FfiConverterUint64INSTANCE.Read(reader),
+func (c FfiConverterTypeEcdhShare) Read(reader io.Reader) EcdhShare {
+	return EcdhShare{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterBytesINSTANCE.Read(reader),
 	}
 }
 
-func (c FfiConverterTypeRecipient) Lower(value Recipient) RustBuffer {
-	return LowerIntoRustBuffer[Recipient](c, value)
+func (c FfiConverterTypeEcdhShare) Lower(value EcdhShare) RustBuffer {
+	return LowerIntoRustBuffer[EcdhShare](c, value)
 }
 
-func (c FfiConverterTypeRecipient) Write(writer io.Writer, value Recipient) {
-	FfiConverterStringINSTANCE.Write(writer, value.Address)
-	FfiConverterUint64INSTANCE.Write(writer, value.Amount)
+func (c FfiConverterTypeEcdhShare) Write(writer io.Writer, value EcdhShare) {
+	FfiConverterBytesINSTANCE.Write(writer, value.ScanKey)
+	FfiConverterBytesINSTANCE.Write(writer, value.Share)
 }
 
-type FfiDestroyerTypeRecipient struct{}
+type FfiDestroyerTypeEcdhShare struct{}
 
-func (_ FfiDestroyerTypeRecipient) Destroy(value Recipient) {
+func (_ FfiDestroyerTypeEcdhShare) Destroy(value EcdhShare) {
 	value.Destroy()
 }
 
-type TxInfo struct {
-	Txid          []byte
-	Tx            []byte
-	Spent         uint64
-	Received      uint64
-	Confirmations uint32
+type FeeBump struct {
+	Psbt        []byte
+	OriginalFee uint64
+	Fee         uint64
 }
 
-func (r *TxInfo) Destroy() {
-	FfiDestroyerBytes{}.Destroy(r.Txid)
-	FfiDestroyerBytes{}.Destroy(r.Tx)
-	FfiDestroyerUint64{}.Destroy(r.Spent)
-	FfiDestroyerUint64{}.Destroy(r.Received)
-	FfiDestroyerUint32{}.Destroy(r.Confirmations)
+func (r *FeeBump) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Psbt)
+	FfiDestroyerUint64{}.Destroy(r.OriginalFee)
+	FfiDestroyerUint64{}.Destroy(r.Fee)
 }
 
-type FfiConverterTypeTxInfo struct{}
+type FfiConverterTypeFeeBump struct{}
 
-var FfiConverterTypeTxInfoINSTANCE = FfiConverterTypeTxInfo{}
+var FfiConverterTypeFeeBumpINSTANCE = FfiConverterTypeFeeBump{}
 
-func (c FfiConverterTypeTxInfo) Lift(rb RustBufferI) TxInfo {
-	return LiftFromRustBuffer[TxInfo](c, rb)
+func (c FfiConverterTypeFeeBump) Lift(rb RustBufferI) FeeBump {
+	return LiftFromRustBuffer[FeeBump](c, rb)
 }
 
-func (c FfiConverterTypeTxInfo) Read(reader io.Reader) TxInfo {
-	return TxInfo{
-		FfiConverterBytesINSTANCE.Read(reader),
+func (c FfiConverterTypeFeeBump) Read(reader io.Reader) FeeBump {
+	return FeeBump{
 		FfiConverterBytesINSTANCE.Read(reader),
 		FfiConverterUint64INSTANCE.Read(reader),
 		FfiConverterUint64INSTANCE.Read(reader),
-		FfiConverterUint32INSTANCE.Read(reader),
 	}
 }
 
-func (c FfiConverterTypeTxInfo) Lower(value TxInfo) RustBuffer {
-	return LowerIntoRustBuffer[TxInfo](c, value)
+func (c FfiConverterTypeFeeBump) Lower(value FeeBump) RustBuffer {
+	return LowerIntoRustBuffer[FeeBump](c, value)
 }
 
-func (c FfiConverterTypeTxInfo) Write(writer io.Writer, value TxInfo) {
-	FfiConverterBytesINSTANCE.Write(writer, value.Txid)
-	FfiConverterBytesINSTANCE.Write(writer, value.Tx)
-	FfiConverterUint64INSTANCE.Write(writer, value.Spent)
-	FfiConverterUint64INSTANCE.Write(writer, value.Received)
-	FfiConverterUint32INSTANCE.Write(writer, value.Confirmations)
+func (c FfiConverterTypeFeeBump) Write(writer io.Writer, value FeeBump) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Psbt)
+	FfiConverterUint64INSTANCE.Write(writer, value.OriginalFee)
+	FfiConverterUint64INSTANCE.Write(writer, value.Fee)
 }
 
-type FfiDestroyerTypeTxInfo struct{}
+type FfiDestroyerTypeFeeBump struct{}
 
-func (_ FfiDestroyerTypeTxInfo) Destroy(value TxInfo) {
+func (_ FfiDestroyerTypeFeeBump) Destroy(value FeeBump) {
 	value.Destroy()
 }
 
-type UtxoInfo struct {
-	Txid            []byte
-	Vout            uint32
-	Amount          uint64
-	ScriptPubkey    []byte
-	IsChange        bool
-	DerivationIndex uint32
-	Confirmations   uint32
+type FinalizedPsbt struct {
+	Psbt     []byte
+	Tx       []byte
+	Complete bool
 }
 
-func (r *UtxoInfo) Destroy() {
-	FfiDestroyerBytes{}.Destroy(r.Txid)
+func (r *FinalizedPsbt) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Psbt)
+	FfiDestroyerBytes{}.Destroy(r.Tx)
+	FfiDestroyerBool{}.Destroy(r.Complete)
+}
+
+type FfiConverterTypeFinalizedPsbt struct{}
+
+var FfiConverterTypeFinalizedPsbtINSTANCE = FfiConverterTypeFinalizedPsbt{}
+
+func (c FfiConverterTypeFinalizedPsbt) Lift(rb RustBufferI) FinalizedPsbt {
+	return LiftFromRustBuffer[FinalizedPsbt](c, rb)
+}
+
+func (c FfiConverterTypeFinalizedPsbt) Read(reader io.Reader) FinalizedPsbt {
+	return FinalizedPsbt{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterBoolINSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeFinalizedPsbt) Lower(value FinalizedPsbt) RustBuffer {
+	return LowerIntoRustBuffer[FinalizedPsbt](c, value)
+}
+
+func (c FfiConverterTypeFinalizedPsbt) Write(writer io.Writer, value FinalizedPsbt) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Psbt)
+	FfiConverterBytesINSTANCE.Write(writer, value.Tx)
+	FfiConverterBoolINSTANCE.Write(writer, value.Complete)
+}
+
+type FfiDestroyerTypeFinalizedPsbt struct{}
+
+func (_ FfiDestroyerTypeFinalizedPsbt) Destroy(value FinalizedPsbt) {
+	value.Destroy()
+}
+
+type FundedPsbt struct {
+	Psbt      []byte
+	Fee       uint64
+	ChangePos int32
+}
+
+func (r *FundedPsbt) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Psbt)
+	FfiDestroyerUint64{}.Destroy(r.Fee)
+	FfiDestroyerInt32{}.Destroy(r.ChangePos)
+}
+
+type FfiConverterTypeFundedPsbt struct{}
+
+var FfiConverterTypeFundedPsbtINSTANCE = FfiConverterTypeFundedPsbt{}
+
+func (c FfiConverterTypeFundedPsbt) Lift(rb RustBufferI) FundedPsbt {
+	return LiftFromRustBuffer[FundedPsbt](c, rb)
+}
+
+func (c FfiConverterTypeFundedPsbt) Read(reader io.Reader) FundedPsbt {
+	return FundedPsbt{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterUint64INSTANCE.Read(reader),
+		FfiConverterInt32INSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeFundedPsbt) Lower(value FundedPsbt) RustBuffer {
+	return LowerIntoRustBuffer[FundedPsbt](c, value)
+}
+
+func (c FfiConverterTypeFundedPsbt) Write(writer io.Writer, value FundedPsbt) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Psbt)
+	FfiConverterUint64INSTANCE.Write(writer, value.Fee)
+	FfiConverterInt32INSTANCE.Write(writer, value.ChangePos)
+}
+
+type FfiDestroyerTypeFundedPsbt struct{}
+
+func (_ FfiDestroyerTypeFundedPsbt) Destroy(value FundedPsbt) {
+	value.Destroy()
+}
+
+type MempoolTx struct {
+	Tx        []byte
+	AddedUnix uint64
+}
+
+func (r *MempoolTx) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Tx)
+	FfiDestroyerUint64{}.Destroy(r.AddedUnix)
+}
+
+type FfiConverterTypeMempoolTx struct{}
+
+var FfiConverterTypeMempoolTxINSTANCE = FfiConverterTypeMempoolTx{}
+
+func (c FfiConverterTypeMempoolTx) Lift(rb RustBufferI) MempoolTx {
+	return LiftFromRustBuffer[MempoolTx](c, rb)
+}
+
+func (c FfiConverterTypeMempoolTx) Read(reader io.Reader) MempoolTx {
+	return MempoolTx{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterUint64INSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeMempoolTx) Lower(value MempoolTx) RustBuffer {
+	return LowerIntoRustBuffer[MempoolTx](c, value)
+}
+
+func (c FfiConverterTypeMempoolTx) Write(writer io.Writer, value MempoolTx) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Tx)
+	FfiConverterUint64INSTANCE.Write(writer, value.AddedUnix)
+}
+
+type FfiDestroyerTypeMempoolTx struct{}
+
+func (_ FfiDestroyerTypeMempoolTx) Destroy(value MempoolTx) {
+	value.Destroy()
+}
+
+type ProcessedPsbt struct {
+	Psbt     []byte
+	Complete bool
+}
+
+func (r *ProcessedPsbt) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Psbt)
+	FfiDestroyerBool{}.Destroy(r.Complete)
+}
+
+type FfiConverterTypeProcessedPsbt struct{}
+
+var FfiConverterTypeProcessedPsbtINSTANCE = FfiConverterTypeProcessedPsbt{}
+
+func (c FfiConverterTypeProcessedPsbt) Lift(rb RustBufferI) ProcessedPsbt {
+	return LiftFromRustBuffer[ProcessedPsbt](c, rb)
+}
+
+func (c FfiConverterTypeProcessedPsbt) Read(reader io.Reader) ProcessedPsbt {
+	return ProcessedPsbt{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterBoolINSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeProcessedPsbt) Lower(value ProcessedPsbt) RustBuffer {
+	return LowerIntoRustBuffer[ProcessedPsbt](c, value)
+}
+
+func (c FfiConverterTypeProcessedPsbt) Write(writer io.Writer, value ProcessedPsbt) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Psbt)
+	FfiConverterBoolINSTANCE.Write(writer, value.Complete)
+}
+
+type FfiDestroyerTypeProcessedPsbt struct{}
+
+func (_ FfiDestroyerTypeProcessedPsbt) Destroy(value ProcessedPsbt) {
+	value.Destroy()
+}
+
+type PsbtInput struct {
+	Txid []byte
+	Vout uint32
+}
+
+func (r *PsbtInput) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Txid)
 	FfiDestroyerUint32{}.Destroy(r.Vout)
+}
+
+type FfiConverterTypePsbtInput struct{}
+
+var FfiConverterTypePsbtInputINSTANCE = FfiConverterTypePsbtInput{}
+
+func (c FfiConverterTypePsbtInput) Lift(rb RustBufferI) PsbtInput {
+	return LiftFromRustBuffer[PsbtInput](c, rb)
+}
+
+func (c FfiConverterTypePsbtInput) Read(reader io.Reader) PsbtInput {
+	return PsbtInput{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterUint32INSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypePsbtInput) Lower(value PsbtInput) RustBuffer {
+	return LowerIntoRustBuffer[PsbtInput](c, value)
+}
+
+func (c FfiConverterTypePsbtInput) Write(writer io.Writer, value PsbtInput) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Txid)
+	FfiConverterUint32INSTANCE.Write(writer, value.Vout)
+}
+
+type FfiDestroyerTypePsbtInput struct{}
+
+func (_ FfiDestroyerTypePsbtInput) Destroy(value PsbtInput) {
+	value.Destroy()
+}
+
+type PsbtOutput struct {
+	ScriptPubkey []byte
+	Amount       uint64
+}
+
+func (r *PsbtOutput) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.ScriptPubkey)
+	FfiDestroyerUint64{}.Destroy(r.Amount)
+}
+
+type FfiConverterTypePsbtOutput struct{}
+
+var FfiConverterTypePsbtOutputINSTANCE = FfiConverterTypePsbtOutput{}
+
+func (c FfiConverterTypePsbtOutput) Lift(rb RustBufferI) PsbtOutput {
+	return LiftFromRustBuffer[PsbtOutput](c, rb)
+}
+
+func (c FfiConverterTypePsbtOutput) Read(reader io.Reader) PsbtOutput {
+	return PsbtOutput{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterUint64INSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypePsbtOutput) Lower(value PsbtOutput) RustBuffer {
+	return LowerIntoRustBuffer[PsbtOutput](c, value)
+}
+
+func (c FfiConverterTypePsbtOutput) Write(writer io.Writer, value PsbtOutput) {
+	FfiConverterBytesINSTANCE.Write(writer, value.ScriptPubkey)
+	FfiConverterUint64INSTANCE.Write(writer, value.Amount)
+}
+
+type FfiDestroyerTypePsbtOutput struct{}
+
+func (_ FfiDestroyerTypePsbtOutput) Destroy(value PsbtOutput) {
+	value.Destroy()
+}
+
+type Recipient struct {
+	Address string
+	Amount  uint64
+}
+
+func (r *Recipient) Destroy() {
+	FfiDestroyerString{}.Destroy(r.Address)
 	FfiDestroyerUint64{}.Destroy(r.Amount)
+}
+
+type FfiConverterTypeRecipient struct{}
+
+var FfiConverterTypeRecipientINSTANCE = FfiConverterTypeRecipient{}
+
+func (c FfiConverterTypeRecipient) Lift(rb RustBufferI) Recipient {
+	return LiftFromRustBuffer[Recipient](c, rb)
+}
+
+func (c FfiConverterTypeRecipient) Read(reader io.Reader) Recipient {
+	return Recipient{
+		FfiConverterStringINSTANCE.Read(reader),
+		FfiConverterUint64INSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeRecipient) Lower(value Recipient) RustBuffer {
+	return LowerIntoRustBuffer[Recipient](c, value)
+}
+
+func (c FfiConverterTypeRecipient) Write(writer io.Writer, value Recipient) {
+	FfiConverterStringINSTANCE.Write(writer, value.Address)
+	FfiConverterUint64INSTANCE.Write(writer, value.Amount)
+}
+
+type FfiDestroyerTypeRecipient struct{}
+
+func (_ FfiDestroyerTypeRecipient) Destroy(value Recipient) {
+	value.Destroy()
+}
+
+type SilentPaymentInput struct {
+	Txid       []byte
+	Vout       uint32
+	PublicKey  []byte
+	EcdhShares []EcdhShare
+}
+
+func (r *SilentPaymentInput) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Txid)
+	FfiDestroyerUint32{}.Destroy(r.Vout)
+	FfiDestroyerBytes{}.Destroy(r.PublicKey)
+	FfiDestroyerSequenceTypeEcdhShare{}.Destroy(r.EcdhShares)
+}
+
+type FfiConverterTypeSilentPaymentInput struct{}
+
+var FfiConverterTypeSilentPaymentInputINSTANCE = FfiConverterTypeSilentPaymentInput{}
+
+func (c FfiConverterTypeSilentPaymentInput) Lift(rb RustBufferI) SilentPaymentInput {
+	return LiftFromRustBuffer[SilentPaymentInput](c, rb)
+}
+
+func (c FfiConverterTypeSilentPaymentInput) Read(reader io.Reader) SilentPaymentInput {
+	return SilentPaymentInput{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterUint32INSTANCE.Read(reader),
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterSequenceTypeEcdhShareINSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeSilentPaymentInput) Lower(value SilentPaymentInput) RustBuffer {
+	return LowerIntoRustBuffer[SilentPaymentInput](c, value)
+}
+
+func (c FfiConverterTypeSilentPaymentInput) Write(writer io.Writer, value SilentPaymentInput) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Txid)
+	FfiConverterUint32INSTANCE.Write(writer, value.Vout)
+	FfiConverterBytesINSTANCE.Write(writer, value.PublicKey)
+	FfiConverterSequenceTypeEcdhShareINSTANCE.Write(writer, value.EcdhShares)
+}
+
+type FfiDestroyerTypeSilentPaymentInput struct{}
+
+func (_ FfiDestroyerTypeSilentPaymentInput) Destroy(value SilentPaymentInput) {
+	value.Destroy()
+}
+
+type SilentPaymentOutput struct {
+	Index        uint32
+	ScanKey      []byte
+	SpendKey     []byte
+	ScriptPubkey []byte
+}
+
+func (r *SilentPaymentOutput) Destroy() {
+	FfiDestroyerUint32{}.Destroy(r.Index)
+	FfiDestroyerBytes{}.Destroy(r.ScanKey)
+	FfiDestroyerBytes{}.Destroy(r.SpendKey)
 	FfiDestroyerBytes{}.Destroy(r.ScriptPubkey)
-	FfiDestroyerBool{}.Destroy(r.IsChange)
-	FfiDestroyerUint32{}.Destroy(r.DerivationIndex)
-	FfiDestroyerUint32{}.Destroy(r.Confirmations)
 }
 
-type FfiConverterTypeUtxoInfo struct{}
+type FfiConverterTypeSilentPaymentOutput struct{}
+
+var FfiConverterTypeSilentPaymentOutputINSTANCE = FfiConverterTypeSilentPaymentOutput{}
+
+func (c FfiConverterTypeSilentPaymentOutput) Lift(rb RustBufferI) SilentPaymentOutput {
+	return LiftFromRustBuffer[SilentPaymentOutput](c, rb)
+}
+
+func (c FfiConverterTypeSilentPaymentOutput) Read(reader io.Reader) SilentPaymentOutput {
+	return SilentPaymentOutput{
+		FfiConverterUint32INSTANCE.Read(reader),
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterBytesINSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeSilentPaymentOutput) Lower(value SilentPaymentOutput) RustBuffer {
+	return LowerIntoRustBuffer[SilentPaymentOutput](c, value)
+}
+
+func (c FfiConverterTypeSilentPaymentOutput) Write(writer io.Writer, value SilentPaymentOutput) {
+	FfiConverterUint32INSTANCE.Write(writer, value.Index)
+	FfiConverterBytesINSTANCE.Write(writer, value.ScanKey)
+	FfiConverterBytesINSTANCE.Write(writer, value.SpendKey)
+	FfiConverterBytesINSTANCE.Write(writer, value.ScriptPubkey)
+}
+
+type FfiDestroyerTypeSilentPaymentOutput struct{}
+
+func (_ FfiDestroyerTypeSilentPaymentOutput) Destroy(value SilentPaymentOutput) {
+	value.Destroy()
+}
+
+type SilentPaymentPsbt struct {
+	Psbt    []byte
+	Inputs  []SilentPaymentInput
+	Outputs []SilentPaymentOutput
+}
+
+func (r *SilentPaymentPsbt) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Psbt)
+	FfiDestroyerSequenceTypeSilentPaymentInput{}.Destroy(r.Inputs)
+	FfiDestroyerSequenceTypeSilentPaymentOutput{}.Destroy(r.Outputs)
+}
+
+type FfiConverterTypeSilentPaymentPsbt struct{}
+
+var FfiConverterTypeSilentPaymentPsbtINSTANCE = FfiConverterTypeSilentPaymentPsbt{}
+
+func (c FfiConverterTypeSilentPaymentPsbt) Lift(rb RustBufferI) SilentPaymentPsbt {
+	return LiftFromRustBuffer[SilentPaymentPsbt](c, rb)
+}
+
+func (c FfiConverterTypeSilentPaymentPsbt) Read(reader io.Reader) SilentPaymentPsbt {
+	return SilentPaymentPsbt{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterSequenceTypeSilentPaymentInputINSTANCE.Read(reader),
+		FfiConverterSequenceTypeSilentPaymentOutputINSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeSilentPaymentPsbt) Lower(value SilentPaymentPsbt) RustBuffer {
+	return LowerIntoRustBuffer[SilentPaymentPsbt](c, value)
+}
+
+func (c FfiConverterTypeSilentPaymentPsbt) Write(writer io.Writer, value SilentPaymentPsbt) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Psbt)
+	FfiConverterSequenceTypeSilentPaymentInputINSTANCE.Write(writer, value.Inputs)
+	FfiConverterSequenceTypeSilentPaymentOutputINSTANCE.Write(writer, value.Outputs)
+}
+
+type FfiDestroyerTypeSilentPaymentPsbt struct{}
+
+func (_ FfiDestroyerTypeSilentPaymentPsbt) Destroy(value SilentPaymentPsbt) {
+	value.Destroy()
+}
+
+type TxInfo struct {
+	Txid          []byte
+	Tx            []byte
+	Spent         uint64
+	Received      uint64
+	Confirmations uint32
+}
+
+func (r *TxInfo) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Txid)
+	FfiDestroyerBytes{}.Destroy(r.Tx)
+	FfiDestroyerUint64{}.Destroy(r.Spent)
+	FfiDestroyerUint64{}.Destroy(r.Received)
+	FfiDestroyerUint32{}.Destroy(r.Confirmations)
+}
+
+type FfiConverterTypeTxInfo struct{}
+
+var FfiConverterTypeTxInfoINSTANCE = FfiConverterTypeTxInfo{}
+
+func (c FfiConverterTypeTxInfo) Lift(rb RustBufferI) TxInfo {
+	return LiftFromRustBuffer[TxInfo](c, rb)
+}
+
+func (c FfiConverterTypeTxInfo) Read(reader io.Reader) TxInfo {
+	return TxInfo{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterUint64INSTANCE.Read(reader),
+		FfiConverterUint64INSTANCE.Read(reader),
+		FfiConverterUint32INSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeTxInfo) Lower(value TxInfo) RustBuffer {
+	return LowerIntoRustBuffer[TxInfo](c, value)
+}
+
+func (c FfiConverterTypeTxInfo) Write(writer io.Writer, value TxInfo) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Txid)
+	FfiConverterBytesINSTANCE.Write(writer, value.Tx)
+	FfiConverterUint64INSTANCE.Write(writer, value.Spent)
+	FfiConverterUint64INSTANCE.Write(writer, value.Received)
+	FfiConverterUint32INSTANCE.Write(writer, value.Confirmations)
+}
+
+type FfiDestroyerTypeTxInfo struct{}
+
+func (_ FfiDestroyerTypeTxInfo) Destroy(value TxInfo) {
+	value.Destroy()
+}
+
+type UtxoInfo struct {
+	Txid            []byte
+	Vout            uint32
+	Amount          uint64
+	ScriptPubkey    []byte
+	IsChange        bool
+	DerivationIndex uint32
+	Confirmations   uint32
+}
+
+func (r *UtxoInfo) Destroy() {
+	FfiDestroyerBytes{}.Destroy(r.Txid)
+	FfiDestroyerUint32{}.Destroy(r.Vout)
+	FfiDestroyerUint64{}.Destroy(r.Amount)
+	FfiDestroyerBytes{}.Destroy(r.ScriptPubkey)
+	FfiDestroyerBool{}.Destroy(r.IsChange)
+	FfiDestroyerUint32{}.Destroy(r.DerivationIndex)
+	FfiDestroyerUint32{}.Destroy(r.Confirmations)
+}
+
+type FfiConverterTypeUtxoInfo struct{}
+
+var FfiConverterTypeUtxoInfoINSTANCE = FfiConverterTypeUtxoInfo{}
+
+func (c FfiConverterTypeUtxoInfo) Lift(rb RustBufferI) UtxoInfo {
+	return LiftFromRustBuffer[UtxoInfo](c, rb)
+}
+
+func (c FfiConverterTypeUtxoInfo) Read(reader io.Reader) UtxoInfo {
+	return UtxoInfo{
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterUint32INSTANCE.Read(reader),
+		FfiConverterUint64INSTANCE.Read(reader),
+		FfiConverterBytesINSTANCE.Read(reader),
+		FfiConverterBoolINSTANCE.Read(reader),
+		FfiConverterUint32INSTANCE.Read(reader),
+		FfiConverterUint32INSTANCE.Read(reader),
+	}
+}
+
+func (c FfiConverterTypeUtxoInfo) Lower(value UtxoInfo) RustBuffer {
+	return LowerIntoRustBuffer[UtxoInfo](c, value)
+}
+
+func (c FfiConverterTypeUtxoInfo) Write(writer io.Writer, value UtxoInfo) {
+	FfiConverterBytesINSTANCE.Write(writer, value.Txid)
+	FfiConverterUint32INSTANCE.Write(writer, value.Vout)
+	FfiConverterUint64INSTANCE.Write(writer, value.Amount)
+	FfiConverterBytesINSTANCE.Write(writer, value.ScriptPubkey)
+	FfiConverterBoolINSTANCE.Write(writer, value.IsChange)
+	FfiConverterUint32INSTANCE.Write(writer, value.DerivationIndex)
+	FfiConverterUint32INSTANCE.Write(writer, value.Confirmations)
+}
+
+type FfiDestroyerTypeUtxoInfo struct{}
+
+func (_ FfiDestroyerTypeUtxoInfo) Destroy(value UtxoInfo) {
+	value.Destroy()
+}
+
+type ApplyBlockError struct {
+	err error
+}
+
+func (err ApplyBlockError) Error() string {
+	return fmt.Sprintf("ApplyBlockError: %s", err.err.Error())
+}
+
+func (err ApplyBlockError) Unwrap() error {
+	return err.err
+}
+
+// Err* are used for checking error type with `errors.Is`
+var ErrApplyBlockErrorDecodeBlock = fmt.Errorf("ApplyBlockErrorDecodeBlock")
+var ErrApplyBlockErrorCannotConnect = fmt.Errorf("ApplyBlockErrorCannotConnect")
+var ErrApplyBlockErrorDatabase = fmt.Errorf("ApplyBlockErrorDatabase")
+
+// Variant structs
+type ApplyBlockErrorDecodeBlock struct {
+	message string
+}
+
+func NewApplyBlockErrorDecodeBlock() *ApplyBlockError {
+	return &ApplyBlockError{
+		err: &ApplyBlockErrorDecodeBlock{},
+	}
+}
+
+func (err ApplyBlockErrorDecodeBlock) Error() string {
+	return fmt.Sprintf("DecodeBlock: %s", err.message)
+}
+
+func (self ApplyBlockErrorDecodeBlock) Is(target error) bool {
+	return target == ErrApplyBlockErrorDecodeBlock
+}
+
+type ApplyBlockErrorCannotConnect struct {
+	message string
+}
+
+func NewApplyBlockErrorCannotConnect() *ApplyBlockError {
+	return &ApplyBlockError{
+		err: &ApplyBlockErrorCannotConnect{},
+	}
+}
+
+func (err ApplyBlockErrorCannotConnect) Error() string {
+	return fmt.Sprintf("CannotConnect: %s", err.message)
+}
+
+func (self ApplyBlockErrorCannotConnect) Is(target error) bool {
+	return target == ErrApplyBlockErrorCannotConnect
+}
+
+type ApplyBlockErrorDatabase struct {
+	message string
+}
+
+func NewApplyBlockErrorDatabase() *ApplyBlockError {
+	return &ApplyBlockError{
+		err: &ApplyBlockErrorDatabase{},
+	}
+}
+
+func (err ApplyBlockErrorDatabase) Error() string {
+	return fmt.Sprintf("Database: %s", err.message)
+}
+
+func (self ApplyBlockErrorDatabase) Is(target error) bool {
+	return target == ErrApplyBlockErrorDatabase
+}
+
+type FfiConverterTypeApplyBlockError struct{}
+
+var FfiConverterTypeApplyBlockErrorINSTANCE = FfiConverterTypeApplyBlockError{}
+
+func (c FfiConverterTypeApplyBlockError) Lift(eb RustBufferI) error {
+	return LiftFromRustBuffer[error](c, eb)
+}
+
+func (c FfiConverterTypeApplyBlockError) Lower(value *ApplyBlockError) RustBuffer {
+	return LowerIntoRustBuffer[*ApplyBlockError](c, value)
+}
+
+func (c FfiConverterTypeApplyBlockError) Read(reader io.Reader) error {
+	errorID := readUint32(reader)
+
+	message := FfiConverterStringINSTANCE.Read(reader)
+	switch errorID {
+	case 1:
+		return &ApplyBlockError{&ApplyBlockErrorDecodeBlock{message}}
+	case 2:
+		return &ApplyBlockError{&ApplyBlockErrorCannotConnect{message}}
+	case 3:
+		return &ApplyBlockError{&ApplyBlockErrorDatabase{message}}
+	default:
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeApplyBlockError.Read()", errorID))
+	}
+
+}
+
+func (c FfiConverterTypeApplyBlockError) Write(writer io.Writer, value *ApplyBlockError) {
+	switch variantValue := value.err.(type) {
+	case *ApplyBlockErrorDecodeBlock:
+		writeInt32(writer, 1)
+	case *ApplyBlockErrorCannotConnect:
+		writeInt32(writer, 2)
+	case *ApplyBlockErrorDatabase:
+		writeInt32(writer, 3)
+	default:
+		_ = variantValue
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeApplyBlockError.Write", value))
+	}
+}
+
+type ApplyMempoolError struct {
+	err error
+}
+
+func (err ApplyMempoolError) Error() string {
+	return fmt.Sprintf("ApplyMempoolError: %s", err.err.Error())
+}
+
+func (err ApplyMempoolError) Unwrap() error {
+	return err.err
+}
+
+// Err* are used for checking error type with `errors.Is`
+var ErrApplyMempoolErrorDatabase = fmt.Errorf("ApplyMempoolErrorDatabase")
+
+// Variant structs
+type ApplyMempoolErrorDatabase struct {
+	message string
+}
+
+func NewApplyMempoolErrorDatabase() *ApplyMempoolError {
+	return &ApplyMempoolError{
+		err: &ApplyMempoolErrorDatabase{},
+	}
+}
+
+func (err ApplyMempoolErrorDatabase) Error() string {
+	return fmt.Sprintf("Database: %s", err.message)
+}
+
+func (self ApplyMempoolErrorDatabase) Is(target error) bool {
+	return target == ErrApplyMempoolErrorDatabase
+}
+
+type FfiConverterTypeApplyMempoolError struct{}
+
+var FfiConverterTypeApplyMempoolErrorINSTANCE = FfiConverterTypeApplyMempoolError{}
+
+func (c FfiConverterTypeApplyMempoolError) Lift(eb RustBufferI) error {
+	return LiftFromRustBuffer[error](c, eb)
+}
+
+func (c FfiConverterTypeApplyMempoolError) Lower(value *ApplyMempoolError) RustBuffer {
+	return LowerIntoRustBuffer[*ApplyMempoolError](c, value)
+}
+
+func (c FfiConverterTypeApplyMempoolError) Read(reader io.Reader) error {
+	errorID := readUint32(reader)
+
+	message := FfiConverterStringINSTANCE.Read(reader)
+	switch errorID {
+	case 1:
+		return &ApplyMempoolError{&ApplyMempoolErrorDatabase{message}}
+	default:
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeApplyMempoolError.Read()", errorID))
+	}
+
+}
+
+func (c FfiConverterTypeApplyMempoolError) Write(writer io.Writer, value *ApplyMempoolError) {
+	switch variantValue := value.err.(type) {
+	case *ApplyMempoolErrorDatabase:
+		writeInt32(writer, 1)
+	default:
+		_ = variantValue
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeApplyMempoolError.Write", value))
+	}
+}
+
+type BumpFeeError struct {
+	err error
+}
+
+func (err BumpFeeError) Error() string {
+	return fmt.Sprintf("BumpFeeError: %s", err.err.Error())
+}
+
+func (err BumpFeeError) Unwrap() error {
+	return err.err
+}
+
+// Err* are used for checking error type with `errors.Is`
+var ErrBumpFeeErrorUnknownTx = fmt.Errorf("BumpFeeErrorUnknownTx")
+var ErrBumpFeeErrorConfirmed = fmt.Errorf("BumpFeeErrorConfirmed")
+var ErrBumpFeeErrorNoChange = fmt.Errorf("BumpFeeErrorNoChange")
+var ErrBumpFeeErrorFeeTooLow = fmt.Errorf("BumpFeeErrorFeeTooLow")
+var ErrBumpFeeErrorBuildFeeBump = fmt.Errorf("BumpFeeErrorBuildFeeBump")
+var ErrBumpFeeErrorCreateTx = fmt.Errorf("BumpFeeErrorCreateTx")
+var ErrBumpFeeErrorDatabase = fmt.Errorf("BumpFeeErrorDatabase")
+
+// Variant structs
+type BumpFeeErrorUnknownTx struct {
+	message string
+}
+
+func NewBumpFeeErrorUnknownTx() *BumpFeeError {
+	return &BumpFeeError{
+		err: &BumpFeeErrorUnknownTx{},
+	}
+}
+
+func (err BumpFeeErrorUnknownTx) Error() string {
+	return fmt.Sprintf("UnknownTx: %s", err.message)
+}
+
+func (self BumpFeeErrorUnknownTx) Is(target error) bool {
+	return target == ErrBumpFeeErrorUnknownTx
+}
+
+type BumpFeeErrorConfirmed struct {
+	message string
+}
+
+func NewBumpFeeErrorConfirmed() *BumpFeeError {
+	return &BumpFeeError{
+		err: &BumpFeeErrorConfirmed{},
+	}
+}
+
+func (err BumpFeeErrorConfirmed) Error() string {
+	return fmt.Sprintf("Confirmed: %s", err.message)
+}
+
+func (self BumpFeeErrorConfirmed) Is(target error) bool {
+	return target == ErrBumpFeeErrorConfirmed
+}
+
+type BumpFeeErrorNoChange struct {
+	message string
+}
+
+func NewBumpFeeErrorNoChange() *BumpFeeError {
+	return &BumpFeeError{
+		err: &BumpFeeErrorNoChange{},
+	}
+}
+
+func (err BumpFeeErrorNoChange) Error() string {
+	return fmt.Sprintf("NoChange: %s", err.message)
+}
+
+func (self BumpFeeErrorNoChange) Is(target error) bool {
+	return target == ErrBumpFeeErrorNoChange
+}
+
+type BumpFeeErrorFeeTooLow struct {
+	message string
+}
+
+func NewBumpFeeErrorFeeTooLow() *BumpFeeError {
+	return &BumpFeeError{
+		err: &BumpFeeErrorFeeTooLow{},
+	}
+}
+
+func (err BumpFeeErrorFeeTooLow) Error() string {
+	return fmt.Sprintf("FeeTooLow: %s", err.message)
+}
+
+func (self BumpFeeErrorFeeTooLow) Is(target error) bool {
+	return target == ErrBumpFeeErrorFeeTooLow
+}
+
+type BumpFeeErrorBuildFeeBump struct {
+	message string
+}
+
+func NewBumpFeeErrorBuildFeeBump() *BumpFeeError {
+	return &BumpFeeError{
+		err: &BumpFeeErrorBuildFeeBump{},
+	}
+}
+
+func (err BumpFeeErrorBuildFeeBump) Error() string {
+	return fmt.Sprintf("BuildFeeBump: %s", err.message)
+}
+
+func (self BumpFeeErrorBuildFeeBump) Is(target error) bool {
+	return target == ErrBumpFeeErrorBuildFeeBump
+}
+
+type BumpFeeErrorCreateTx struct {
+	message string
+}
+
+func NewBumpFeeErrorCreateTx() *BumpFeeError {
+	return &BumpFeeError{
+		err: &BumpFeeErrorCreateTx{},
+	}
+}
+
+func (err BumpFeeErrorCreateTx) Error() string {
+	return fmt.Sprintf("CreateTx: %s", err.message)
+}
+
+func (self BumpFeeErrorCreateTx) Is(target error) bool {
+	return target == ErrBumpFeeErrorCreateTx
+}
+
+type BumpFeeErrorDatabase struct {
+	message string
+}
+
+func NewBumpFeeErrorDatabase() *BumpFeeError {
+	return &BumpFeeError{
+		err: &BumpFeeErrorDatabase{},
+	}
+}
+
+func (err BumpFeeErrorDatabase) Error() string {
+	return fmt.Sprintf("Database: %s", err.message)
+}
+
+func (self BumpFeeErrorDatabase) Is(target error) bool {
+	return target == ErrBumpFeeErrorDatabase
+}
+
+type FfiConverterTypeBumpFeeError struct{}
+
+var FfiConverterTypeBumpFeeErrorINSTANCE = FfiConverterTypeBumpFeeError{}
+
+func (c FfiConverterTypeBumpFeeError) Lift(eb RustBufferI) error {
+	return LiftFromRustBuffer[error](c, eb)
+}
+
+func (c FfiConverterTypeBumpFeeError) Lower(value *BumpFeeError) RustBuffer {
+	return LowerIntoRustBuffer[*BumpFeeError](c, value)
+}
+
+func (c FfiConverterTypeBumpFeeError) Read(reader io.Reader) error {
+	errorID := readUint32(reader)
+
+	message := FfiConverterStringINSTANCE.Read(reader)
+	switch errorID {
+	case 1:
+		return &BumpFeeError{&BumpFeeErrorUnknownTx{message}}
+	case 2:
+		return &BumpFeeError{&BumpFeeErrorConfirmed{message}}
+	case 3:
+		return &BumpFeeError{&BumpFeeErrorNoChange{message}}
+	case 4:
+		return &BumpFeeError{&BumpFeeErrorFeeTooLow{message}}
+	case 5:
+		return &BumpFeeError{&BumpFeeErrorBuildFeeBump{message}}
+	case 6:
+		return &BumpFeeError{&BumpFeeErrorCreateTx{message}}
+	case 7:
+		return &BumpFeeError{&BumpFeeErrorDatabase{message}}
+	default:
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeBumpFeeError.Read()", errorID))
+	}
+
+}
+
+func (c FfiConverterTypeBumpFeeError) Write(writer io.Writer, value *BumpFeeError) {
+	switch variantValue := value.err.(type) {
+	case *BumpFeeErrorUnknownTx:
+		writeInt32(writer, 1)
+	case *BumpFeeErrorConfirmed:
+		writeInt32(writer, 2)
+	case *BumpFeeErrorNoChange:
+		writeInt32(writer, 3)
+	case *BumpFeeErrorFeeTooLow:
+		writeInt32(writer, 4)
+	case *BumpFeeErrorBuildFeeBump:
+		writeInt32(writer, 5)
+	case *BumpFeeErrorCreateTx:
+		writeInt32(writer, 6)
+	case *BumpFeeErrorDatabase:
+		writeInt32(writer, 7)
+	default:
+		_ = variantValue
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeBumpFeeError.Write", value))
+	}
+}
+
+type CoinSelection uint
+
+const (
+	CoinSelectionBranchAndBound     CoinSelection = 1
+	CoinSelectionKnapsack           CoinSelection = 2
+	CoinSelectionAvoidPartialSpends CoinSelection = 3
+)
+
+type FfiConverterTypeCoinSelection struct{}
+
+var FfiConverterTypeCoinSelectionINSTANCE = FfiConverterTypeCoinSelection{}
+
+func (c FfiConverterTypeCoinSelection) Lift(rb RustBufferI) CoinSelection {
+	return LiftFromRustBuffer[CoinSelection](c, rb)
+}
+
+func (c FfiConverterTypeCoinSelection) Lower(value CoinSelection) RustBuffer {
+	return LowerIntoRustBuffer[CoinSelection](c, value)
+}
+func (FfiConverterTypeCoinSelection) Read(reader io.Reader) CoinSelection {
+	id := readInt32(reader)
+	return CoinSelection(id)
+}
+
+func (FfiConverterTypeCoinSelection) Write(writer io.Writer, value CoinSelection) {
+	writeInt32(writer, int32(value))
+}
 
-var FfiConverterTypeUtxoInfoINSTANCE = FfiConverterTypeUtxoInfo{}
+type FfiDestroyerTypeCoinSelection struct{}
 
-func (c FfiConverterTypeUtxoInfo) Lift(rb RustBufferI) UtxoInfo {
-	return LiftFromRustBuffer[UtxoInfo](c, rb)
+func (_ FfiDestroyerTypeCoinSelection) Destroy(value CoinSelection) {
 }
 
-func (c FfiConverterTypeUtxoInfo) Read(reader io.Reader) UtxoInfo {
-	return UtxoInfo{
-		FfiConverterBytesINSTANCE.Read(reader),
-		FfiConverterUint32INSTANCE.Read(reader),
-		FfiConverterUint64INSTANCE.Read(reader),
-		FfiConverterBytesINSTANCE.Read(reader),
-		FfiConverterBoolINSTANCE.Read(reader),
-		FfiConverterUint32INSTANCE.Read(reader),
-		FfiConverterUint32INSTANCE.Read(reader),
+type CreateNewError struct {
+	err error
+}
+
+func (err CreateNewError) Error() string {
+	return fmt.Sprintf("CreateNewError: %s", err.err.Error())
+}
+
+func (err CreateNewError) Unwrap() error {
+	return err.err
+}
+
+// Err* are used for checking error type with `errors.Is`
+var ErrCreateNewErrorParseNetwork = fmt.Errorf("CreateNewErrorParseNetwork")
+var ErrCreateNewErrorParseGenesisHash = fmt.Errorf("CreateNewErrorParseGenesisHash")
+var ErrCreateNewErrorDatabase = fmt.Errorf("CreateNewErrorDatabase")
+var ErrCreateNewErrorWallet = fmt.Errorf("CreateNewErrorWallet")
+var ErrCreateNewErrorDescriptor = fmt.Errorf("CreateNewErrorDescriptor")
+
+// Variant structs
+type CreateNewErrorParseNetwork struct {
+	message string
+}
+
+func NewCreateNewErrorParseNetwork() *CreateNewError {
+	return &CreateNewError{
+		err: &CreateNewErrorParseNetwork{},
 	}
 }
 
-func (c FfiConverterTypeUtxoInfo) Lower(value UtxoInfo) RustBuffer {
-	return LowerIntoRustBuffer[UtxoInfo](c, value)
+func (err CreateNewErrorParseNetwork) Error() string {
+	return fmt.Sprintf("ParseNetwork: %s", err.message)
 }
 
-func (c FfiConverterTypeUtxoInfo) Write(writer io.Writer, value UtxoInfo) {
-	FfiConverterBytesINSTANCE.Write(writer, value.Txid)
-	FfiConverterUint32INSTANCE.Write(writer, value.Vout)
-	FfiConverterUint64INSTANCE.Write(writer, value.Amount)
-	FfiConverterBytesINSTANCE.Write(writer, value.ScriptPubkey)
-	FfiConverterBoolINSTANCE.Write(writer, value.IsChange)
-	FfiConverterUint32INSTANCE.Write(writer, value.DerivationIndex)
-	FfiConverterUint32INSTANCE.Write(writer, value.Confirmations)
+func (self CreateNewErrorParseNetwork) Is(target error) bool {
+	return target == ErrCreateNewErrorParseNetwork
 }
 
-type FfiDestroyerTypeUtxoInfo struct{}
+type CreateNewErrorParseGenesisHash struct {
+	message string
+}
 
-func (_ FfiDestroyerTypeUtxoInfo) Destroy(value UtxoInfo) {
-	value.Destroy()
+func NewCreateNewErrorParseGenesisHash() *CreateNewError {
+	return &CreateNewError{
+		err: &CreateNewErrorParseGenesisHash{},
+	}
 }
 
-type ApplyBlockError struct {
+func (err CreateNewErrorParseGenesisHash) Error() string {
+	return fmt.Sprintf("ParseGenesisHash: %s", err.message)
+}
+
+func (self CreateNewErrorParseGenesisHash) Is(target error) bool {
+	return target == ErrCreateNewErrorParseGenesisHash
+}
+
+type CreateNewErrorDatabase struct {
+	message string
+}
+
+func NewCreateNewErrorDatabase() *CreateNewError {
+	return &CreateNewError{
+		err: &CreateNewErrorDatabase{},
+	}
+}
+
+func (err CreateNewErrorDatabase) Error() string {
+	return fmt.Sprintf("Database: %s", err.message)
+}
+
+func (self CreateNewErrorDatabase) Is(target error) bool {
+	return target == ErrCreateNewErrorDatabase
+}
+
+type CreateNewErrorWallet struct {
+	message string
+}
+
+func NewCreateNewErrorWallet() *CreateNewError {
+	return &CreateNewError{
+		err: &CreateNewErrorWallet{},
+	}
+}
+
+func (err CreateNewErrorWallet) Error() string {
+	return fmt.Sprintf("Wallet: %s", err.message)
+}
+
+func (self CreateNewErrorWallet) Is(target error) bool {
+	return target == ErrCreateNewErrorWallet
+}
+
+type CreateNewErrorDescriptor struct {
+	message string
+}
+
+func NewCreateNewErrorDescriptor() *CreateNewError {
+	return &CreateNewError{
+		err: &CreateNewErrorDescriptor{},
+	}
+}
+
+func (err CreateNewErrorDescriptor) Error() string {
+	return fmt.Sprintf("Descriptor: %s", err.message)
+}
+
+func (self CreateNewErrorDescriptor) Is(target error) bool {
+	return target == ErrCreateNewErrorDescriptor
+}
+
+type FfiConverterTypeCreateNewError struct{}
+
+var FfiConverterTypeCreateNewErrorINSTANCE = FfiConverterTypeCreateNewError{}
+
+func (c FfiConverterTypeCreateNewError) Lift(eb RustBufferI) error {
+	return LiftFromRustBuffer[error](c, eb)
+}
+
+func (c FfiConverterTypeCreateNewError) Lower(value *CreateNewError) RustBuffer {
+	return LowerIntoRustBuffer[*CreateNewError](c, value)
+}
+
+func (c FfiConverterTypeCreateNewError) Read(reader io.Reader) error {
+	errorID := readUint32(reader)
+
+	message := FfiConverterStringINSTANCE.Read(reader)
+	switch errorID {
+	case 1:
+		return &CreateNewError{&CreateNewErrorParseNetwork{message}}
+	case 2:
+		return &CreateNewError{&CreateNewErrorParseGenesisHash{message}}
+	case 3:
+		return &CreateNewError{&CreateNewErrorDatabase{message}}
+	case 4:
+		return &CreateNewError{&CreateNewErrorWallet{message}}
+	case 5:
+		return &CreateNewError{&CreateNewErrorDescriptor{message}}
+	default:
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeCreateNewError.Read()", errorID))
+	}
+
+}
+
+func (c FfiConverterTypeCreateNewError) Write(writer io.Writer, value *CreateNewError) {
+	switch variantValue := value.err.(type) {
+	case *CreateNewErrorParseNetwork:
+		writeInt32(writer, 1)
+	case *CreateNewErrorParseGenesisHash:
+		writeInt32(writer, 2)
+	case *CreateNewErrorDatabase:
+		writeInt32(writer, 3)
+	case *CreateNewErrorWallet:
+		writeInt32(writer, 4)
+	case *CreateNewErrorDescriptor:
+		writeInt32(writer, 5)
+	default:
+		_ = variantValue
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeCreateNewError.Write", value))
+	}
+}
+
+type CreateTxError struct {
 	err error
 }
 
-func (err ApplyBlockError) Error() string {
-	return fmt.Sprintf("ApplyBlockError: %s", err.err.Error())
+func (err CreateTxError) Error() string {
+	return fmt.Sprintf("CreateTxError: %s", err.err.Error())
 }
 
-func (err ApplyBlockError) Unwrap() error {
+func (err CreateTxError) Unwrap() error {
 	return err.err
 }
 
 // Err* are used for checking error type with `errors.Is`
-var ErrApplyBlockErrorDecodeBlock = fmt.Errorf("ApplyBlockErrorDecodeBlock")
-var ErrApplyBlockErrorCannotConnect = fmt.Errorf("ApplyBlockErrorCannotConnect")
-var ErrApplyBlockErrorDatabase = fmt.Errorf("ApplyBlockErrorDatabase")
+var ErrCreateTxErrorInvalidAddress = fmt.Errorf("CreateTxErrorInvalidAddress")
+var ErrCreateTxErrorCreateTx = fmt.Errorf("CreateTxErrorCreateTx")
+var ErrCreateTxErrorSignTx = fmt.Errorf("CreateTxErrorSignTx")
+var ErrCreateTxErrorWatchOnly = fmt.Errorf("CreateTxErrorWatchOnly")
 
 // Variant structs
-type ApplyBlockErrorDecodeBlock struct {
+type CreateTxErrorInvalidAddress struct {
 	message string
 }
 
-func NewApplyBlockErrorDecodeBlock() *ApplyBlockError {
-	return &ApplyBlockError{
-		err: &ApplyBlockErrorDecodeBlock{},
+func NewCreateTxErrorInvalidAddress() *CreateTxError {
+	return &CreateTxError{
+		err: &CreateTxErrorInvalidAddress{},
 	}
 }
 
-func (err ApplyBlockErrorDecodeBlock) Error() string {
-	return fmt.Sprintf("DecodeBlock: %s", err.message)
+func (err CreateTxErrorInvalidAddress) Error() string {
+	return fmt.Sprintf("InvalidAddress: %s", err.message)
 }
 
-func (self ApplyBlockErrorDecodeBlock) Is(target error) bool {
-	return target == ErrApplyBlockErrorDecodeBlock
+func (self CreateTxErrorInvalidAddress) Is(target error) bool {
+	return target == ErrCreateTxErrorInvalidAddress
 }
 
-type ApplyBlockErrorCannotConnect struct {
+type CreateTxErrorCreateTx struct {
 	message string
 }
 
-func NewApplyBlockErrorCannotConnect() *ApplyBlockError {
-	return &ApplyBlockError{
-		err: &ApplyBlockErrorCannotConnect{},
+func NewCreateTxErrorCreateTx() *CreateTxError {
+	return &CreateTxError{
+		err: &CreateTxErrorCreateTx{},
 	}
 }
 
-func (err ApplyBlockErrorCannotConnect) Error() string {
-	return fmt.Sprintf("CannotConnect: %s", err.message)
+func (err CreateTxErrorCreateTx) Error() string {
+	return fmt.Sprintf("CreateTx: %s", err.message)
 }
 
-func (self ApplyBlockErrorCannotConnect) Is(target error) bool {
-	return target == ErrApplyBlockErrorCannotConnect
+func (self CreateTxErrorCreateTx) Is(target error) bool {
+	return target == ErrCreateTxErrorCreateTx
 }
 
-type ApplyBlockErrorDatabase struct {
+type CreateTxErrorSignTx struct {
 	message string
 }
 
-func NewApplyBlockErrorDatabase() *ApplyBlockError {
-	return &ApplyBlockError{
-		err: &ApplyBlockErrorDatabase{},
+func NewCreateTxErrorSignTx() *CreateTxError {
+	return &CreateTxError{
+		err: &CreateTxErrorSignTx{},
 	}
 }
 
-func (err ApplyBlockErrorDatabase) Error() string {
-	return fmt.Sprintf("Database: %s", err.message)
+func (err CreateTxErrorSignTx) Error() string {
+	return fmt.Sprintf("SignTx: %s", err.message)
 }
 
-func (self ApplyBlockErrorDatabase) Is(target error) bool {
-	return target == ErrApplyBlockErrorDatabase
+func (self CreateTxErrorSignTx) Is(target error) bool {
+	return target == ErrCreateTxErrorSignTx
 }
 
-type FfiConverterTypeApplyBlockError struct{}
+type CreateTxErrorWatchOnly struct {
+	message string
+}
 
-var FfiConverterTypeApplyBlockErrorINSTANCE = FfiConverterTypeApplyBlockError{}
+func NewCreateTxErrorWatchOnly() *CreateTxError {
+	return &CreateTxError{
+		err: &CreateTxErrorWatchOnly{},
+	}
+}
 
-func (c FfiConverterTypeApplyBlockError) Lift(eb RustBufferI) error {
+func (err CreateTxErrorWatchOnly) Error() string {
+	return fmt.Sprintf("WatchOnly: %s", err.message)
+}
+
+func (self CreateTxErrorWatchOnly) Is(target error) bool {
+	return target == ErrCreateTxErrorWatchOnly
+}
+
+type FfiConverterTypeCreateTxError struct{}
+
+var FfiConverterTypeCreateTxErrorINSTANCE = FfiConverterTypeCreateTxError{}
+
+func (c FfiConverterTypeCreateTxError) Lift(eb RustBufferI) error {
 	return LiftFromRustBuffer[error](c, eb)
 }
 
-func (c FfiConverterTypeApplyBlockError) Lower(value *ApplyBlockError) RustBuffer {
-	return LowerIntoRustBuffer[*ApplyBlockError](c, value)
+func (c FfiConverterTypeCreateTxError) Lower(value *CreateTxError) RustBuffer {
+	return LowerIntoRustBuffer[*CreateTxError](c, value)
 }
 
-func (c FfiConverterTypeApplyBlockError) Read(reader io.Reader) error {
+func (c FfiConverterTypeCreateTxError) Read(reader io.Reader) error {
 	errorID := readUint32(reader)
 
 	message := FfiConverterStringINSTANCE.Read(reader)
 	switch errorID {
 	case 1:
-		return &ApplyBlockError{&ApplyBlockErrorDecodeBlock{message}}
+		return &CreateTxError{&CreateTxErrorInvalidAddress{message}}
 	case 2:
-		return &ApplyBlockError{&ApplyBlockErrorCannotConnect{message}}
+		return &CreateTxError{&CreateTxErrorCreateTx{message}}
 	case 3:
-		return &ApplyBlockError{&ApplyBlockErrorDatabase{message}}
+		return &CreateTxError{&CreateTxErrorSignTx{message}}
+	case 4:
+		return &CreateTxError{&CreateTxErrorWatchOnly{message}}
 	default:
-		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeApplyBlockError.Read()", errorID))
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeCreateTxError.Read()", errorID))
 	}
 
 }
 
-func (c FfiConverterTypeApplyBlockError) Write(writer io.Writer, value *ApplyBlockError) {
+func (c FfiConverterTypeCreateTxError) Write(writer io.Writer, value *CreateTxError) {
 	switch variantValue := value.err.(type) {
-	case *ApplyBlockErrorDecodeBlock:
+	case *CreateTxErrorInvalidAddress:
 		writeInt32(writer, 1)
-	case *ApplyBlockErrorCannotConnect:
+	case *CreateTxErrorCreateTx:
 		writeInt32(writer, 2)
-	case *ApplyBlockErrorDatabase:
+	case *CreateTxErrorSignTx:
 		writeInt32(writer, 3)
+	case *CreateTxErrorWatchOnly:
+		writeInt32(writer, 4)
 	default:
 		_ = variantValue
-		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeApplyBlockError.Write", value))
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeCreateTxError.Write", value))
 	}
 }
 
-type ApplyMempoolError struct {
+type DatabaseError struct {
 	err error
 }
 
-func (err ApplyMempoolError) Error() string {
-	return fmt.Sprintf("ApplyMempoolError: %s", err.err.Error())
+func (err DatabaseError) Error() string {
+	return fmt.Sprintf("DatabaseError: %s", err.err.Error())
 }
 
-func (err ApplyMempoolError) Unwrap() error {
+func (err DatabaseError) Unwrap() error {
 	return err.err
 }
 
 // Err* are used for checking error type with `errors.Is`
-var ErrApplyMempoolErrorDatabase = fmt.Errorf("ApplyMempoolErrorDatabase")
+var ErrDatabaseErrorWrite = fmt.Errorf("DatabaseErrorWrite")
 
 // Variant structs
-type ApplyMempoolErrorDatabase struct {
+type DatabaseErrorWrite struct {
 	message string
 }
 
-func NewApplyMempoolErrorDatabase() *ApplyMempoolError {
-	return &ApplyMempoolError{
-		err: &ApplyMempoolErrorDatabase{},
+func NewDatabaseErrorWrite() *DatabaseError {
+	return &DatabaseError{
+		err: &DatabaseErrorWrite{},
 	}
 }
 
-func (err ApplyMempoolErrorDatabase) Error() string {
-	return fmt.Sprintf("Database: %s", err.message)
+func (err DatabaseErrorWrite) Error() string {
+	return fmt.Sprintf("Write: %s", err.message)
 }
 
-func (self ApplyMempoolErrorDatabase) Is(target error) bool {
-	return target == ErrApplyMempoolErrorDatabase
+func (self DatabaseErrorWrite) Is(target error) bool {
+	return target == ErrDatabaseErrorWrite
 }
 
-type FfiConverterTypeApplyMempoolError struct{}
+type FfiConverterTypeDatabaseError struct{}
 
-var FfiConverterTypeApplyMempoolErrorINSTANCE = FfiConverterTypeApplyMempoolError{}
+var FfiConverterTypeDatabaseErrorINSTANCE = FfiConverterTypeDatabaseError{}
 
-func (c FfiConverterTypeApplyMempoolError) Lift(eb RustBufferI) error {
+func (c FfiConverterTypeDatabaseError) Lift(eb RustBufferI) error {
 	return LiftFromRustBuffer[error](c, eb)
 }
 
-func (c FfiConverterTypeApplyMempoolError) Lower(value *ApplyMempoolError) RustBuffer {
-	return LowerIntoRustBuffer[*ApplyMempoolError](c, value)
+func (c FfiConverterTypeDatabaseError) Lower(value *DatabaseError) RustBuffer {
+	return LowerIntoRustBuffer[*DatabaseError](c, value)
 }
 
-func (c FfiConverterTypeApplyMempoolError) Read(reader io.Reader) error {
+func (c FfiConverterTypeDatabaseError) Read(reader io.Reader) error {
 	errorID := readUint32(reader)
 
 	message := FfiConverterStringINSTANCE.Read(reader)
 	switch errorID {
 	case 1:
-		return &ApplyMempoolError{&ApplyMempoolErrorDatabase{message}}
+		return &DatabaseError{&DatabaseErrorWrite{message}}
 	default:
-		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeApplyMempoolError.Read()", errorID))
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeDatabaseError.Read()", errorID))
 	}
 
 }
 
-func (c FfiConverterTypeApplyMempoolError) Write(writer io.Writer, value *ApplyMempoolError) {
+func (c FfiConverterTypeDatabaseError) Write(writer io.Writer, value *DatabaseError) {
 	switch variantValue := value.err.(type) {
-	case *ApplyMempoolErrorDatabase:
+	case *DatabaseErrorWrite:
 		writeInt32(writer, 1)
 	default:
 		_ = variantValue
-		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeApplyMempoolError.Write", value))
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeDatabaseError.Write", value))
 	}
 }
 
-type CreateNewError struct {
+type ImportDescriptorError struct {
 	err error
 }
 
-func (err CreateNewError) Error() string {
-	return fmt.Sprintf("CreateNewError: %s", err.err.Error())
+func (err ImportDescriptorError) Error() string {
+	return fmt.Sprintf("ImportDescriptorError: %s", err.err.Error())
 }
 
-func (err CreateNewError) Unwrap() error {
+func (err ImportDescriptorError) Unwrap() error {
 	return err.err
 }
 
 // Err* are used for checking error type with `errors.Is`
-var ErrCreateNewErrorParseNetwork = fmt.Errorf("CreateNewErrorParseNetwork")
-var ErrCreateNewErrorParseGenesisHash = fmt.Errorf("CreateNewErrorParseGenesisHash")
-var ErrCreateNewErrorDatabase = fmt.Errorf("CreateNewErrorDatabase")
-var ErrCreateNewErrorWallet = fmt.Errorf("CreateNewErrorWallet")
+var ErrImportDescriptorErrorParseDescriptor = fmt.Errorf("ImportDescriptorErrorParseDescriptor")
+var ErrImportDescriptorErrorUnsupportedDescriptor = fmt.Errorf("ImportDescriptorErrorUnsupportedDescriptor")
+var ErrImportDescriptorErrorDatabase = fmt.Errorf("ImportDescriptorErrorDatabase")
+var ErrImportDescriptorErrorWallet = fmt.Errorf("ImportDescriptorErrorWallet")
 
 // Variant structs
-type CreateNewErrorParseNetwork struct {
+type ImportDescriptorErrorParseDescriptor struct {
 	message string
 }
 
-func NewCreateNewErrorParseNetwork() *CreateNewError {
-	return &CreateNewError{
-		err: &CreateNewErrorParseNetwork{},
+func NewImportDescriptorErrorParseDescriptor() *ImportDescriptorError {
+	return &ImportDescriptorError{
+		err: &ImportDescriptorErrorParseDescriptor{},
 	}
 }
 
-func (err CreateNewErrorParseNetwork) Error() string {
-	return fmt.Sprintf("ParseNetwork: %s", err.message)
+func (err ImportDescriptorErrorParseDescriptor) Error() string {
+	return fmt.Sprintf("ParseDescriptor: %s", err.message)
 }
 
-func (self CreateNewErrorParseNetwork) Is(target error) bool {
-	return target == ErrCreateNewErrorParseNetwork
+func (self ImportDescriptorErrorParseDescriptor) Is(target error) bool {
+	return target == ErrImportDescriptorErrorParseDescriptor
 }
 
-type CreateNewErrorParseGenesisHash struct {
+type ImportDescriptorErrorUnsupportedDescriptor struct {
 	message string
 }
 
-func NewCreateNewErrorParseGenesisHash() *CreateNewError {
-	return &CreateNewError{
-		err: &CreateNewErrorParseGenesisHash{},
+func NewImportDescriptorErrorUnsupportedDescriptor() *ImportDescriptorError {
+	return &ImportDescriptorError{
+		err: &ImportDescriptorErrorUnsupportedDescriptor{},
 	}
 }
 
-func (err CreateNewErrorParseGenesisHash) Error() string {
-	return fmt.Sprintf("ParseGenesisHash: %s", err.message)
+func (err ImportDescriptorErrorUnsupportedDescriptor) Error() string {
+	return fmt.Sprintf("UnsupportedDescriptor: %s", err.message)
 }
 
-func (self CreateNewErrorParseGenesisHash) Is(target error) bool {
-	return target == ErrCreateNewErrorParseGenesisHash
+func (self ImportDescriptorErrorUnsupportedDescriptor) Is(target error) bool {
+	return target == ErrImportDescriptorErrorUnsupportedDescriptor
 }
 
-type CreateNewErrorDatabase struct {
+type ImportDescriptorErrorDatabase struct {
 	message string
 }
 
-func NewCreateNewErrorDatabase() *CreateNewError {
-	return &CreateNewError{
-		err: &CreateNewErrorDatabase{},
+func NewImportDescriptorErrorDatabase() *ImportDescriptorError {
+	return &ImportDescriptorError{
+		err: &ImportDescriptorErrorDatabase{},
 	}
 }
 
-func (err CreateNewErrorDatabase) Error() string {
+func (err ImportDescriptorErrorDatabase) Error() string {
 	return fmt.Sprintf("Database: %s", err.message)
 }
 
-func (self CreateNewErrorDatabase) Is(target error) bool {
-	return target == ErrCreateNewErrorDatabase
+func (self ImportDescriptorErrorDatabase) Is(target error) bool {
+	return target == ErrImportDescriptorErrorDatabase
 }
 
-type CreateNewErrorWallet struct {
+type ImportDescriptorErrorWallet struct {
 	message string
 }
 
-func NewCreateNewErrorWallet() *CreateNewError {
-	return &CreateNewError{
-		err: &CreateNewErrorWallet{},
+func NewImportDescriptorErrorWallet() *ImportDescriptorError {
+	return &ImportDescriptorError{
+		err: &ImportDescriptorErrorWallet{},
 	}
 }
 
-func (err CreateNewErrorWallet) Error() string {
+func (err ImportDescriptorErrorWallet) Error() string {
 	return fmt.Sprintf("Wallet: %s", err.message)
 }
 
-func (self CreateNewErrorWallet) Is(target error) bool {
-	return target == ErrCreateNewErrorWallet
+func (self ImportDescriptorErrorWallet) Is(target error) bool {
+	return target == ErrImportDescriptorErrorWallet
 }
 
-type FfiConverterTypeCreateNewError struct{}
+type FfiConverterTypeImportDescriptorError struct{}
 
-var FfiConverterTypeCreateNewErrorINSTANCE = FfiConverterTypeCreateNewError{}
+var FfiConverterTypeImportDescriptorErrorINSTANCE = FfiConverterTypeImportDescriptorError{}
 
-func (c FfiConverterTypeCreateNewError) Lift(eb RustBufferI) error {
+func (c FfiConverterTypeImportDescriptorError) Lift(eb RustBufferI) error {
 	return LiftFromRustBuffer[error](c, eb)
 }
 
-func (c FfiConverterTypeCreateNewError) Lower(value *CreateNewError) RustBuffer {
-	return LowerIntoRustBuffer[*CreateNewError](c, value)
+func (c FfiConverterTypeImportDescriptorError) Lower(value *ImportDescriptorError) RustBuffer {
+	return LowerIntoRustBuffer[*ImportDescriptorError](c, value)
 }
 
-func (c FfiConverterTypeCreateNewError) Read(reader io.Reader) error {
+func (c FfiConverterTypeImportDescriptorError) Read(reader io.Reader) error {
 	errorID := readUint32(reader)
 
 	message := FfiConverterStringINSTANCE.Read(reader)
 	switch errorID {
 	case 1:
-		return &CreateNewError{&CreateNewErrorParseNetwork{message}}
+		return &ImportDescriptorError{&ImportDescriptorErrorParseDescriptor{message}}
 	case 2:
-		return &CreateNewError{&CreateNewErrorParseGenesisHash{message}}
+		return &ImportDescriptorError{&ImportDescriptorErrorUnsupportedDescriptor{message}}
 	case 3:
-		return &CreateNewError{&CreateNewErrorDatabase{message}}
+		return &ImportDescriptorError{&ImportDescriptorErrorDatabase{message}}
 	case 4:
-		return &CreateNewError{&CreateNewErrorWallet{message}}
+		return &ImportDescriptorError{&ImportDescriptorErrorWallet{message}}
 	default:
-		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeCreateNewError.Read()", errorID))
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeImportDescriptorError.Read()", errorID))
 	}
 
 }
 
-func (c FfiConverterTypeCreateNewError) Write(writer io.Writer, value *CreateNewError) {
+func (c FfiConverterTypeImportDescriptorError) Write(writer io.Writer, value *ImportDescriptorError) {
 	switch variantValue := value.err.(type) {
-	case *CreateNewErrorParseNetwork:
+	case *ImportDescriptorErrorParseDescriptor:
 		writeInt32(writer, 1)
-	case *CreateNewErrorParseGenesisHash:
+	case *ImportDescriptorErrorUnsupportedDescriptor:
 		writeInt32(writer, 2)
-	case *CreateNewErrorDatabase:
+	case *ImportDescriptorErrorDatabase:
 		writeInt32(writer, 3)
-	case *CreateNewErrorWallet:
+	case *ImportDescriptorErrorWallet:
 		writeInt32(writer, 4)
 	default:
 		_ = variantValue
-		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeCreateNewError.Write", value))
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeImportDescriptorError.Write", value))
 	}
 }
 
-type CreateTxError struct {
+type LoadError struct {
 	err error
 }
 
-func (err CreateTxError) Error() string {
-	return fmt.Sprintf("CreateTxError: %s", err.err.Error())
+func (err LoadError) Error() string {
+	return fmt.Sprintf("LoadError: %s", err.err.Error())
 }
 
-func (err CreateTxError) Unwrap() error {
+func (err LoadError) Unwrap() error {
 	return err.err
 }
 
 // Err* are used for checking error type with `errors.Is`
-var ErrCreateTxErrorInvalidAddress = fmt.Errorf("CreateTxErrorInvalidAddress")
-var ErrCreateTxErrorCreateTx = fmt.Errorf("CreateTxErrorCreateTx")
-var ErrCreateTxErrorSignTx = fmt.Errorf("CreateTxErrorSignTx")
+var ErrLoadErrorDatabase = fmt.Errorf("LoadErrorDatabase")
+var ErrLoadErrorParseHeader = fmt.Errorf("LoadErrorParseHeader")
+var ErrLoadErrorHeaderVersion = fmt.Errorf("LoadErrorHeaderVersion")
+var ErrLoadErrorWallet = fmt.Errorf("LoadErrorWallet")
 
 // Variant structs
-type CreateTxErrorInvalidAddress struct {
+type LoadErrorDatabase struct {
 	message string
 }
 
-func NewCreateTxErrorInvalidAddress() *CreateTxError {
-	return &CreateTxError{
-		err: &CreateTxErrorInvalidAddress{},
+func NewLoadErrorDatabase() *LoadError {
+	return &LoadError{
+		err: &LoadErrorDatabase{},
 	}
 }
 
-func (err CreateTxErrorInvalidAddress) Error() string {
-	return fmt.Sprintf("InvalidAddress: %s", err.message)
+func (err LoadErrorDatabase) Error() string {
+	return fmt.Sprintf("Database: %s", err.message)
 }
 
-func (self CreateTxErrorInvalidAddress) Is(target error) bool {
-	return target == ErrCreateTxErrorInvalidAddress
+func (self LoadErrorDatabase) Is(target error) bool {
+	return target == ErrLoadErrorDatabase
 }
 
-type CreateTxErrorCreateTx struct {
+type LoadErrorParseHeader struct {
 	message string
 }
 
-func NewCreateTxErrorCreateTx() *CreateTxError {
-	return &CreateTxError{
-		err: &CreateTxErrorCreateTx{},
+func NewLoadErrorParseHeader() *LoadError {
+	return &LoadError{
+		err: &LoadErrorParseHeader{},
 	}
 }
 
-func (err CreateTxErrorCreateTx) Error() string {
-	return fmt.Sprintf("CreateTx: %s", err.message)
+func (err LoadErrorParseHeader) Error() string {
+	return fmt.Sprintf("ParseHeader: %s", err.message)
 }
 
-func (self CreateTxErrorCreateTx) Is(target error) bool {
-	return target == ErrCreateTxErrorCreateTx
+func (self LoadErrorParseHeader) Is(target error) bool {
+	return target == ErrLoadErrorParseHeader
 }
 
-type CreateTxErrorSignTx struct {
+type LoadErrorHeaderVersion struct {
 	message string
 }
 
-func NewCreateTxErrorSignTx() *CreateTxError {
-	return &CreateTxError{
-		err: &CreateTxErrorSignTx{},
+func NewLoadErrorHeaderVersion() *LoadError {
+	return &LoadError{
+		err: &LoadErrorHeaderVersion{},
 	}
 }
 
-func (err CreateTxErrorSignTx) Error() string {
-	return fmt.Sprintf("SignTx: %s", err.message)
+func (err LoadErrorHeaderVersion) Error() string {
+	return fmt.Sprintf("HeaderVersion: %s", err.message)
 }
 
-func (self CreateTxErrorSignTx) Is(target error) bool {
-	return target == ErrCreateTxErrorSignTx
+func (self LoadErrorHeaderVersion) Is(target error) bool {
+	return target == ErrLoadErrorHeaderVersion
 }
 
-type FfiConverterTypeCreateTxError struct{}
+type LoadErrorWallet struct {
+	message string
+}
 
-var FfiConverterTypeCreateTxErrorINSTANCE = FfiConverterTypeCreateTxError{}
+func NewLoadErrorWallet() *LoadError {
+	return &LoadError{
+		err: &LoadErrorWallet{},
+	}
+}
 
-func (c FfiConverterTypeCreateTxError) Lift(eb RustBufferI) error {
+func (err LoadErrorWallet) Error() string {
+	return fmt.Sprintf("Wallet: %s", err.message)
+}
+
+func (self LoadErrorWallet) Is(target error) bool {
+	return target == ErrLoadErrorWallet
+}
+
+type FfiConverterTypeLoadError struct{}
+
+var FfiConverterTypeLoadErrorINSTANCE = FfiConverterTypeLoadError{}
+
+func (c FfiConverterTypeLoadError) Lift(eb RustBufferI) error {
 	return LiftFromRustBuffer[error](c, eb)
 }
 
-func (c FfiConverterTypeCreateTxError) Lower(value *CreateTxError) RustBuffer {
-	return LowerIntoRustBuffer[*CreateTxError](c, value)
+func (c FfiConverterTypeLoadError) Lower(value *LoadError) RustBuffer {
+	return LowerIntoRustBuffer[*LoadError](c, value)
 }
 
-func (c FfiConverterTypeCreateTxError) Read(reader io.Reader) error {
+func (c FfiConverterTypeLoadError) Read(reader io.Reader) error {
 	errorID := readUint32(reader)
 
 	message := FfiConverterStringINSTANCE.Read(reader)
 	switch errorID {
 	case 1:
-		return &CreateTxError{&CreateTxErrorInvalidAddress{message}}
+		return &LoadError{&LoadErrorDatabase{message}}
 	case 2:
-		return &CreateTxError{&CreateTxErrorCreateTx{message}}
+		return &LoadError{&LoadErrorParseHeader{message}}
 	case 3:
-		return &CreateTxError{&CreateTxErrorSignTx{message}}
+		return &LoadError{&LoadErrorHeaderVersion{message}}
+	case 4:
+		return &LoadError{&LoadErrorWallet{message}}
 	default:
-		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeCreateTxError.Read()", errorID))
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeLoadError.Read()", errorID))
 	}
 
 }
 
-func (c FfiConverterTypeCreateTxError) Write(writer io.Writer, value *CreateTxError) {
+func (c FfiConverterTypeLoadError) Write(writer io.Writer, value *LoadError) {
 	switch variantValue := value.err.(type) {
-	case *CreateTxErrorInvalidAddress:
+	case *LoadErrorDatabase:
 		writeInt32(writer, 1)
-	case *CreateTxErrorCreateTx:
+	case *LoadErrorParseHeader:
 		writeInt32(writer, 2)
-	case *CreateTxErrorSignTx:
+	case *LoadErrorHeaderVersion:
 		writeInt32(writer, 3)
+	case *LoadErrorWallet:
+		writeInt32(writer, 4)
 	default:
 		_ = variantValue
-		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeCreateTxError.Write", value))
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeLoadError.Write", value))
 	}
 }
 
-type DatabaseError struct {
+type PsbtError struct {
 	err error
 }
 
-func (err DatabaseError) Error() string {
-	return fmt.Sprintf("DatabaseError: %s", err.err.Error())
+func (err PsbtError) Error() string {
+	return fmt.Sprintf("PsbtError: %s", err.err.Error())
 }
 
-func (err DatabaseError) Unwrap() error {
+func (err PsbtError) Unwrap() error {
 	return err.err
 }
 
 // Err* are used for checking error type with `errors.Is`
-var ErrDatabaseErrorWrite = fmt.Errorf("DatabaseErrorWrite")
+var ErrPsbtErrorDecodePsbt = fmt.Errorf("PsbtErrorDecodePsbt")
+var ErrPsbtErrorDecodeTx = fmt.Errorf("PsbtErrorDecodeTx")
+var ErrPsbtErrorUnknownInput = fmt.Errorf("PsbtErrorUnknownInput")
+var ErrPsbtErrorCreateTx = fmt.Errorf("PsbtErrorCreateTx")
+var ErrPsbtErrorSignTx = fmt.Errorf("PsbtErrorSignTx")
+var ErrPsbtErrorUnknownOutput = fmt.Errorf("PsbtErrorUnknownOutput")
+var ErrPsbtErrorUnknownInputKey = fmt.Errorf("PsbtErrorUnknownInputKey")
+var ErrPsbtErrorIneligibleInput = fmt.Errorf("PsbtErrorIneligibleInput")
 
 // Variant structs
-type DatabaseErrorWrite struct {
+type PsbtErrorDecodePsbt struct {
 	message string
 }
 
-func NewDatabaseErrorWrite() *DatabaseError {
-	return &DatabaseError{
-		err: &DatabaseErrorWrite{},
+func NewPsbtErrorDecodePsbt() *PsbtError {
+	return &PsbtError{
+		err: &PsbtErrorDecodePsbt{},
 	}
 }
 
-func (err DatabaseErrorWrite) Error() string {
-	return fmt.Sprintf("Write: %s", err.message)
+func (err PsbtErrorDecodePsbt) Error() string {
+	return fmt.Sprintf("DecodePsbt: %s", err.message)
 }
 
-func (self DatabaseErrorWrite) Is(target error) bool {
-	return target == ErrDatabaseErrorWrite
+func (self PsbtErrorDecodePsbt) Is(target error) bool {
+	return target == ErrPsbtErrorDecodePsbt
 }
 
-type FfiConverterTypeDatabaseError struct{}
-
-var FfiConverterTypeDatabaseErrorINSTANCE = FfiConverterTypeDatabaseError{}
+type PsbtErrorDecodeTx struct {
+	message string
+}
 
-func (c FfiConverterTypeDatabaseError) Lift(eb RustBufferI) error {
-	return LiftFromRustBuffer[error](c, eb)
+func NewPsbtErrorDecodeTx() *PsbtError {
+	return &PsbtError{
+		err: &PsbtErrorDecodeTx{},
+	}
+}
+
+func (err PsbtErrorDecodeTx) Error() string {
+	return fmt.Sprintf("DecodeTx: %s", err.message)
 }
 
-func (c FfiConverterTypeDatabaseError) Lower(value *DatabaseError) RustBuffer {
-	return LowerIntoRustBuffer[*DatabaseError](c, value)
+func (self PsbtErrorDecodeTx) Is(target error) bool {
+	return target == ErrPsbtErrorDecodeTx
 }
 
-func (c FfiConverterTypeDatabaseError) Read(reader io.Reader) error {
-	errorID := readUint32(reader)
+type PsbtErrorUnknownInput struct {
+	message string
+}
 
-	message := FfiConverterStringINSTANCE.Read(reader)
-	switch errorID {
-	case 1:
-		return &DatabaseError{&DatabaseErrorWrite{message}}
-	default:
-		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeDatabaseError.Read()", errorID))
+func NewPsbtErrorUnknownInput() *PsbtError {
+	return &PsbtError{
+		err: &PsbtErrorUnknownInput{},
 	}
+}
 
+func (err PsbtErrorUnknownInput) Error() string {
+	return fmt.Sprintf("UnknownInput: %s", err.message)
 }
 
-func (c FfiConverterTypeDatabaseError) Write(writer io.Writer, value *DatabaseError) {
-	switch variantValue := value.err.(type) {
-	case *DatabaseErrorWrite:
-		writeInt32(writer, 1)
-	default:
-		_ = variantValue
-		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeDatabaseError.Write", value))
-	}
+func (self PsbtErrorUnknownInput) Is(target error) bool {
+	return target == ErrPsbtErrorUnknownInput
 }
 
-type LoadError struct {
-	err error
+type PsbtErrorCreateTx struct {
+	message string
 }
 
-func (err LoadError) Error() string {
-	return fmt.Sprintf("LoadError: %s", err.err.Error())
+func NewPsbtErrorCreateTx() *PsbtError {
+	return &PsbtError{
+		err: &PsbtErrorCreateTx{},
+	}
 }
 
-func (err LoadError) Unwrap() error {
-	return err.err
+func (err PsbtErrorCreateTx) Error() string {
+	return fmt.Sprintf("CreateTx: %s", err.message)
 }
 
-// Err* are used for checking error type with `errors.Is`
-var ErrLoadErrorDatabase = fmt.Errorf("LoadErrorDatabase")
-var ErrLoadErrorParseHeader = fmt.Errorf("LoadErrorParseHeader")
-var ErrLoadErrorHeaderVersion = fmt.Errorf("LoadErrorHeaderVersion")
-var ErrLoadErrorWallet = fmt.Errorf("LoadErrorWallet")
+func (self PsbtErrorCreateTx) Is(target error) bool {
+	return target == ErrPsbtErrorCreateTx
+}
 
-// Variant structs
-type LoadErrorDatabase struct {
+type PsbtErrorSignTx struct {
 	message string
 }
 
-func NewLoadErrorDatabase() *LoadError {
-	return &LoadError{
-		err: &LoadErrorDatabase{},
+func NewPsbtErrorSignTx() *PsbtError {
+	return &PsbtError{
+		err: &PsbtErrorSignTx{},
 	}
 }
 
-func (err LoadErrorDatabase) Error() string {
-	return fmt.Sprintf("Database: %s", err.message)
+func (err PsbtErrorSignTx) Error() string {
+	return fmt.Sprintf("SignTx: %s", err.message)
 }
 
-func (self LoadErrorDatabase) Is(target error) bool {
-	return target == ErrLoadErrorDatabase
+func (self PsbtErrorSignTx) Is(target error) bool {
+	return target == ErrPsbtErrorSignTx
 }
 
-type LoadErrorParseHeader struct {
+type PsbtErrorUnknownOutput struct {
 	message string
 }
 
-func NewLoadErrorParseHeader() *LoadError {
-	return &LoadError{
-		err: &LoadErrorParseHeader{},
+func NewPsbtErrorUnknownOutput() *PsbtError {
+	return &PsbtError{
+		err: &PsbtErrorUnknownOutput{},
 	}
 }
 
-func (err LoadErrorParseHeader) Error() string {
-	return fmt.Sprintf("ParseHeader: %s", err.message)
+func (err PsbtErrorUnknownOutput) Error() string {
+	return fmt.Sprintf("UnknownOutput: %s", err.message)
 }
 
-func (self LoadErrorParseHeader) Is(target error) bool {
-	return target == ErrLoadErrorParseHeader
+func (self PsbtErrorUnknownOutput) Is(target error) bool {
+	return target == ErrPsbtErrorUnknownOutput
 }
 
-type LoadErrorHeaderVersion struct {
+type PsbtErrorUnknownInputKey struct {
 	message string
 }
 
-func NewLoadErrorHeaderVersion() *LoadError {
-	return &LoadError{
-		err: &LoadErrorHeaderVersion{},
+func NewPsbtErrorUnknownInputKey() *PsbtError {
+	return &PsbtError{
+		err: &PsbtErrorUnknownInputKey{},
 	}
 }
 
-func (err LoadErrorHeaderVersion) Error() string {
-	return fmt.Sprintf("HeaderVersion: %s", err.message)
+func (err PsbtErrorUnknownInputKey) Error() string {
+	return fmt.Sprintf("UnknownInputKey: %s", err.message)
 }
 
-func (self LoadErrorHeaderVersion) Is(target error) bool {
-	return target == ErrLoadErrorHeaderVersion
+func (self PsbtErrorUnknownInputKey) Is(target error) bool {
+	return target == ErrPsbtErrorUnknownInputKey
 }
 
-type LoadErrorWallet struct {
+type PsbtErrorIneligibleInput struct {
 	message string
 }
 
-func NewLoadErrorWallet() *LoadError {
-	return &LoadError{
-		err: &LoadErrorWallet{},
+func NewPsbtErrorIneligibleInput() *PsbtError {
+	return &PsbtError{
+		err: &PsbtErrorIneligibleInput{},
 	}
 }
 
-func (err LoadErrorWallet) Error() string {
-	return fmt.Sprintf("Wallet: %s", err.message)
+func (err PsbtErrorIneligibleInput) Error() string {
+	return fmt.Sprintf("IneligibleInput: %s", err.message)
 }
 
-func (self LoadErrorWallet) Is(target error) bool {
-	return target == ErrLoadErrorWallet
+func (self PsbtErrorIneligibleInput) Is(target error) bool {
+	return target == ErrPsbtErrorIneligibleInput
 }
 
-type FfiConverterTypeLoadError struct{}
+type FfiConverterTypePsbtError struct{}
 
-var FfiConverterTypeLoadErrorINSTANCE = FfiConverterTypeLoadError{}
+var FfiConverterTypePsbtErrorINSTANCE = FfiConverterTypePsbtError{}
 
-func (c FfiConverterTypeLoadError) Lift(eb RustBufferI) error {
+func (c FfiConverterTypePsbtError) Lift(eb RustBufferI) error {
 	return LiftFromRustBuffer[error](c, eb)
 }
 
-func (c FfiConverterTypeLoadError) Lower(value *LoadError) RustBuffer {
-	return LowerIntoRustBuffer[*LoadError](c, value)
+func (c FfiConverterTypePsbtError) Lower(value *PsbtError) RustBuffer {
+	return LowerIntoRustBuffer[*PsbtError](c, value)
 }
 
-func (c FfiConverterTypeLoadError) Read(reader io.Reader) error {
+func (c FfiConverterTypePsbtError) Read(reader io.Reader) error {
 	errorID := readUint32(reader)
 
 	message := FfiConverterStringINSTANCE.Read(reader)
 	switch errorID {
 	case 1:
-		return &LoadError{&LoadErrorDatabase{message}}
+		return &PsbtError{&PsbtErrorDecodePsbt{message}}
 	case 2:
-		return &LoadError{&LoadErrorParseHeader{message}}
+		return &PsbtError{&PsbtErrorDecodeTx{message}}
 	case 3:
-		return &LoadError{&LoadErrorHeaderVersion{message}}
+		return &PsbtError{&PsbtErrorUnknownInput{message}}
 	case 4:
-		return &LoadError{&LoadErrorWallet{message}}
+		return &PsbtError{&PsbtErrorCreateTx{message}}
+	case 5:
+		return &PsbtError{&PsbtErrorSignTx{message}}
+	case 6:
+		return &PsbtError{&PsbtErrorUnknownOutput{message}}
+	case 7:
+		return &PsbtError{&PsbtErrorUnknownInputKey{message}}
+	case 8:
+		return &PsbtError{&PsbtErrorIneligibleInput{message}}
 	default:
-		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypeLoadError.Read()", errorID))
+		panic(fmt.Sprintf("Unknown error code %d in FfiConverterTypePsbtError.Read()", errorID))
 	}
 
 }
 
-func (c FfiConverterTypeLoadError) Write(writer io.Writer, value *LoadError) {
+func (c FfiConverterTypePsbtError) Write(writer io.Writer, value *PsbtError) {
 	switch variantValue := value.err.(type) {
-	case *LoadErrorDatabase:
+	case *PsbtErrorDecodePsbt:
 		writeInt32(writer, 1)
-	case *LoadErrorParseHeader:
+	case *PsbtErrorDecodeTx:
 		writeInt32(writer, 2)
-	case *LoadErrorHeaderVersion:
+	case *PsbtErrorUnknownInput:
 		writeInt32(writer, 3)
-	case *LoadErrorWallet:
+	case *PsbtErrorCreateTx:
 		writeInt32(writer, 4)
+	case *PsbtErrorSignTx:
+		writeInt32(writer, 5)
+	case *PsbtErrorUnknownOutput:
+		writeInt32(writer, 6)
+	case *PsbtErrorUnknownInputKey:
+		writeInt32(writer, 7)
+	case *PsbtErrorIneligibleInput:
+		writeInt32(writer, 8)
 	default:
 		_ = variantValue
-		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypeLoadError.Write", value))
+		panic(fmt.Sprintf("invalid error value `%v` in FfiConverterTypePsbtError.Write", value))
 	}
 }
 
@@ -2087,6 +3487,92 @@ func (FfiDestroyerSequenceTypeBlockId) Destroy(sequence []BlockId) {
 	}
 }
 
+type FfiConverterSequenceTypeDescriptorInfo struct{}
+
+var FfiConverterSequenceTypeDescriptorInfoINSTANCE = FfiConverterSequenceTypeDescriptorInfo{}
+
+func (c FfiConverterSequenceTypeDescriptorInfo) Lift(rb RustBufferI) []DescriptorInfo {
+	return LiftFromRustBuffer[[]DescriptorInfo](c, rb)
+}
+
+func (c FfiConverterSequenceTypeDescriptorInfo) Read(reader io.Reader) []DescriptorInfo {
+	length := readInt32(reader)
+	if length == 0 {
+		return nil
+	}
+	result := make([]DescriptorInfo, 0, length)
+	for i := int32(0); i < length; i++ {
+		result = append(result, FfiConverterTypeDescriptorInfoINSTANCE.Read(reader))
+	}
+	return result
+}
+
+func (c FfiConverterSequenceTypeDescriptorInfo) Lower(value []DescriptorInfo) RustBuffer {
+	return LowerIntoRustBuffer[[]DescriptorInfo](c, value)
+}
+
+func (c FfiConverterSequenceTypeDescriptorInfo) Write(writer io.Writer, value []DescriptorInfo) {
+	if len(value) > math.MaxInt32 {
+		panic("[]DescriptorInfo is too large to fit into Int32")
+	}
+
+	writeInt32(writer, int32(len(value)))
+	for _, item := range value {
+		FfiConverterTypeDescriptorInfoINSTANCE.Write(writer, item)
+	}
+}
+
+type FfiDestroyerSequenceTypeDescriptorInfo struct{}
+
+func (FfiDestroyerSequenceTypeDescriptorInfo) Destroy(sequence []DescriptorInfo) {
+	for _, value := range sequence {
+		FfiDestroyerTypeDescriptorInfo{}.Destroy(value)
+	}
+}
+
+type FfiConverterSequenceTypeEcdhShare struct{}
+
+var FfiConverterSequenceTypeEcdhShareINSTANCE = FfiConverterSequenceTypeEcdhShare{}
+
+func (c FfiConverterSequenceTypeEcdhShare) Lift(rb RustBufferI) []EcdhShare {
+	return LiftFromRustBuffer[[]EcdhShare](c, rb)
+}
+
+func (c FfiConverterSequenceTypeEcdhShare) Read(reader io.Reader) []EcdhShare {
+	length := readInt32(reader)
+	if length == 0 {
+		return nil
+	}
+	result := make([]EcdhShare, 0, length)
+	for i := int32(0); i < length; i++ {
+		result = append(result, FfiConverterTypeEcdhShareINSTANCE.Read(reader))
+	}
+	return result
+}
+
+func (c FfiConverterSequenceTypeEcdhShare) Lower(value []EcdhShare) RustBuffer {
+	return LowerIntoRustBuffer[[]EcdhShare](c, value)
+}
+
+func (c FfiConverterSequenceTypeEcdhShare) Write(writer io.Writer, value []EcdhShare) {
+	if len(value) > math.MaxInt32 {
+		panic("[]EcdhShare is too large to fit into Int32")
+	}
+
+	writeInt32(writer, int32(len(value)))
+	for _, item := range value {
+		FfiConverterTypeEcdhShareINSTANCE.Write(writer, item)
+	}
+}
+
+type FfiDestroyerSequenceTypeEcdhShare struct{}
+
+func (FfiDestroyerSequenceTypeEcdhShare) Destroy(sequence []EcdhShare) {
+	for _, value := range sequence {
+		FfiDestroyerTypeEcdhShare{}.Destroy(value)
+	}
+}
+
 type FfiConverterSequenceTypeMempoolTx struct{}
 
 var FfiConverterSequenceTypeMempoolTxINSTANCE = FfiConverterSequenceTypeMempoolTx{}
@@ -2130,6 +3616,92 @@ func (FfiDestroyerSequenceTypeMempoolTx) Destroy(sequence []MempoolTx) {
 	}
 }
 
+type FfiConverterSequenceTypePsbtInput struct{}
+
+var FfiConverterSequenceTypePsbtInputINSTANCE = FfiConverterSequenceTypePsbtInput{}
+
+func (c FfiConverterSequenceTypePsbtInput) Lift(rb RustBufferI) []PsbtInput {
+	return LiftFromRustBuffer[[]PsbtInput](c, rb)
+}
+
+func (c FfiConverterSequenceTypePsbtInput) Read(reader io.Reader) []PsbtInput {
+	length := readInt32(reader)
+	if length == 0 {
+		return nil
+	}
+	result := make([]PsbtInput, 0, length)
+	for i := int32(0); i < length; i++ {
+		result = append(result, FfiConverterTypePsbtInputINSTANCE.Read(reader))
+	}
+	return result
+}
+
+func (c FfiConverterSequenceTypePsbtInput) Lower(value []PsbtInput) RustBuffer {
+	return LowerIntoRustBuffer[[]PsbtInput](c, value)
+}
+
+func (c FfiConverterSequenceTypePsbtInput) Write(writer io.Writer, value []PsbtInput) {
+	if len(value) > math.MaxInt32 {
+		panic("[]PsbtInput is too large to fit into Int32")
+	}
+
+	writeInt32(writer, int32(len(value)))
+	for _, item := range value {
+		FfiConverterTypePsbtInputINSTANCE.Write(writer, item)
+	}
+}
+
+type FfiDestroyerSequenceTypePsbtInput struct{}
+
+func (FfiDestroyerSequenceTypePsbtInput) Destroy(sequence []PsbtInput) {
+	for _, value := range sequence {
+		FfiDestroyerTypePsbtInput{}.Destroy(value)
+	}
+}
+
+type FfiConverterSequenceTypePsbtOutput struct{}
+
+var FfiConverterSequenceTypePsbtOutputINSTANCE = FfiConverterSequenceTypePsbtOutput{}
+
+func (c FfiConverterSequenceTypePsbtOutput) Lift(rb RustBufferI) []PsbtOutput {
+	return LiftFromRustBuffer[[]PsbtOutput](c, rb)
+}
+
+func (c FfiConverterSequenceTypePsbtOutput) Read(reader io.Reader) []PsbtOutput {
+	length := readInt32(reader)
+	if length == 0 {
+		return nil
+	}
+	result := make([]PsbtOutput, 0, length)
+	for i := int32(0); i < length; i++ {
+		result = append(result, FfiConverterTypePsbtOutputINSTANCE.Read(reader))
+	}
+	return result
+}
+
+func (c FfiConverterSequenceTypePsbtOutput) Lower(value []PsbtOutput) RustBuffer {
+	return LowerIntoRustBuffer[[]PsbtOutput](c, value)
+}
+
+func (c FfiConverterSequenceTypePsbtOutput) Write(writer io.Writer, value []PsbtOutput) {
+	if len(value) > math.MaxInt32 {
+		panic("[]PsbtOutput is too large to fit into Int32")
+	}
+
+	writeInt32(writer, int32(len(value)))
+	for _, item := range value {
+		FfiConverterTypePsbtOutputINSTANCE.Write(writer, item)
+	}
+}
+
+type FfiDestroyerSequenceTypePsbtOutput struct{}
+
+func (FfiDestroyerSequenceTypePsbtOutput) Destroy(sequence []PsbtOutput) {
+	for _, value := range sequence {
+		FfiDestroyerTypePsbtOutput{}.Destroy(value)
+	}
+}
+
 type FfiConverterSequenceTypeRecipient struct{}
 
 var FfiConverterSequenceTypeRecipientINSTANCE = FfiConverterSequenceTypeRecipient{}
@@ -2173,6 +3745,92 @@ func (FfiDestroyerSequenceTypeRecipient) Destroy(sequence []Recipient) {
 	}
 }
 
+type FfiConverterSequenceTypeSilentPaymentInput struct{}
+
+var FfiConverterSequenceTypeSilentPaymentInputINSTANCE = FfiConverterSequenceTypeSilentPaymentInput{}
+
+func (c FfiConverterSequenceTypeSilentPaymentInput) Lift(rb RustBufferI) []SilentPaymentInput {
+	return LiftFromRustBuffer[[]SilentPaymentInput](c, rb)
+}
+
+func (c FfiConverterSequenceTypeSilentPaymentInput) Read(reader io.Reader) []SilentPaymentInput {
+	length := readInt32(reader)
+	if length == 0 {
+		return nil
+	}
+	result := make([]SilentPaymentInput, 0, length)
+	for i := int32(0); i < length; i++ {
+		result = append(result, FfiConverterTypeSilentPaymentInputINSTANCE.Read(reader))
+	}
+	return result
+}
+
+func (c FfiConverterSequenceTypeSilentPaymentInput) Lower(value []SilentPaymentInput) RustBuffer {
+	return LowerIntoRustBuffer[[]SilentPaymentInput](c, value)
+}
+
+func (c FfiConverterSequenceTypeSilentPaymentInput) Write(writer io.Writer, value []SilentPaymentInput) {
+	if len(value) > math.MaxInt32 {
+		panic("[]SilentPaymentInput is too large to fit into Int32")
+	}
+
+	writeInt32(writer, int32(len(value)))
+	for _, item := range value {
+		FfiConverterTypeSilentPaymentInputINSTANCE.Write(writer, item)
+	}
+}
+
+type FfiDestroyerSequenceTypeSilentPaymentInput struct{}
+
+func (FfiDestroyerSequenceTypeSilentPaymentInput) Destroy(sequence []SilentPaymentInput) {
+	for _, value := range sequence {
+		FfiDestroyerTypeSilentPaymentInput{}.Destroy(value)
+	}
+}
+
+type FfiConverterSequenceTypeSilentPaymentOutput struct{}
+
+var FfiConverterSequenceTypeSilentPaymentOutputINSTANCE = FfiConverterSequenceTypeSilentPaymentOutput{}
+
+func (c FfiConverterSequenceTypeSilentPaymentOutput) Lift(rb RustBufferI) []SilentPaymentOutput {
+	return LiftFromRustBuffer[[]SilentPaymentOutput](c, rb)
+}
+
+func (c FfiConverterSequenceTypeSilentPaymentOutput) Read(reader io.Reader) []SilentPaymentOutput {
+	length := readInt32(reader)
+	if length == 0 {
+		return nil
+	}
+	result := make([]SilentPaymentOutput, 0, length)
+	for i := int32(0); i < length; i++ {
+		result = append(result, FfiConverterTypeSilentPaymentOutputINSTANCE.Read(reader))
+	}
+	return result
+}
+
+func (c FfiConverterSequenceTypeSilentPaymentOutput) Lower(value []SilentPaymentOutput) RustBuffer {
+	return LowerIntoRustBuffer[[]SilentPaymentOutput](c, value)
+}
+
+func (c FfiConverterSequenceTypeSilentPaymentOutput) Write(writer io.Writer, value []SilentPaymentOutput) {
+	if len(value) > math.MaxInt32 {
+		panic("[]SilentPaymentOutput is too large to fit into Int32")
+	}
+
+	writeInt32(writer, int32(len(value)))
+	for _, item := range value {
+		FfiConverterTypeSilentPaymentOutputINSTANCE.Write(writer, item)
+	}
+}
+
+type FfiDestroyerSequenceTypeSilentPaymentOutput struct{}
+
+func (FfiDestroyerSequenceTypeSilentPaymentOutput) Destroy(sequence []SilentPaymentOutput) {
+	for _, value := range sequence {
+		FfiDestroyerTypeSilentPaymentOutput{}.Destroy(value)
+	}
+}
+
 type FfiConverterSequenceTypeTxInfo struct{}
 
 var FfiConverterSequenceTypeTxInfoINSTANCE = FfiConverterSequenceTypeTxInfo{}
@@ -80,6 +80,15 @@ void* uniffi_bdkgo_fn_constructor_wallet_create_new(
 	RustCallStatus* out_status
 );
 
+void* uniffi_bdkgo_fn_constructor_wallet_create_watch_only(
+	RustBuffer db_path,
+	RustBuffer network,
+	RustBuffer genesis_hash,
+	RustBuffer descriptor,
+	uint32_t birthday,
+	RustCallStatus* out_status
+);
+
 void* uniffi_bdkgo_fn_constructor_wallet_load(
 	RustBuffer db_path,
 	RustCallStatus* out_status
@@ -92,6 +101,13 @@ RustBuffer uniffi_bdkgo_fn_method_wallet_apply_block(
 	RustCallStatus* out_status
 );
 
+RustBuffer uniffi_bdkgo_fn_method_wallet_apply_block_to_imports(
+	void* ptr,
+	uint32_t height,
+	RustBuffer block_bytes,
+	RustCallStatus* out_status
+);
+
 RustBuffer uniffi_bdkgo_fn_method_wallet_apply_mempool(
 	void* ptr,
 	RustBuffer txs,
@@ -103,10 +119,63 @@ RustBuffer uniffi_bdkgo_fn_method_wallet_balance(
 	RustCallStatus* out_status
 );
 
+uint32_t uniffi_bdkgo_fn_method_wallet_birthday(
+	void* ptr,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_bump_fee(
+	void* ptr,
+	RustBuffer txid,
+	float feerate,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_create_cpfp(
+	void* ptr,
+	RustBuffer txid,
+	float feerate,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_create_psbt(
+	void* ptr,
+	RustBuffer inputs,
+	RustBuffer outputs,
+	uint32_t locktime,
+	float feerate,
+	int8_t replaceable,
+	int8_t add_inputs,
+	RustBuffer coin_selection,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_create_sweep(
+	void* ptr,
+	float feerate,
+	RustBuffer script_pubkey,
+	RustBuffer inputs,
+	RustCallStatus* out_status
+);
+
 RustBuffer uniffi_bdkgo_fn_method_wallet_create_tx(
 	void* ptr,
 	float feerate,
 	RustBuffer recipients,
+	RustBuffer inputs,
+	RustBuffer coin_selection,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_descriptors(
+	void* ptr,
+	int8_t include_private,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_finalize_psbt(
+	void* ptr,
+	RustBuffer psbt,
 	RustCallStatus* out_status
 );
 
@@ -120,6 +189,18 @@ RustBuffer uniffi_bdkgo_fn_method_wallet_genesis_hash(
 	RustCallStatus* out_status
 );
 
+void uniffi_bdkgo_fn_method_wallet_import_descriptor(
+	void* ptr,
+	RustBuffer descriptor,
+	uint32_t birthday,
+	RustCallStatus* out_status
+);
+
+uint32_t uniffi_bdkgo_fn_method_wallet_imports_height(
+	void* ptr,
+	RustCallStatus* out_status
+);
+
 void uniffi_bdkgo_fn_method_wallet_increment_reference_counter(
 	void* ptr,
 	RustCallStatus* out_status
@@ -141,22 +222,71 @@ RustBuffer uniffi_bdkgo_fn_method_wallet_peek_address(
 	RustCallStatus* out_status
 );
 
+RustBuffer uniffi_bdkgo_fn_method_wallet_process_psbt(
+	void* ptr,
+	RustBuffer psbt,
+	int8_t sign,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_psbt_inputs(
+	void* ptr,
+	RustBuffer psbt,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_psbt_outputs(
+	void* ptr,
+	RustBuffer psbt,
+	RustCallStatus* out_status
+);
+
 RustBuffer uniffi_bdkgo_fn_method_wallet_recent_blocks(
 	void* ptr,
 	uint32_t count,
 	RustCallStatus* out_status
 );
 
+RustBuffer uniffi_bdkgo_fn_method_wallet_scripts(
+	void* ptr,
+	RustCallStatus* out_status
+);
+
+void uniffi_bdkgo_fn_method_wallet_set_locked_utxos(
+	void* ptr,
+	RustBuffer outpoints,
+	RustCallStatus* out_status
+);
+
+RustBuffer uniffi_bdkgo_fn_method_wallet_silent_payment_psbt(
+	void* ptr,
+	RustBuffer psbt,
+	RustBuffer outputs,
+	RustCallStatus* out_status
+);
+
 RustBuffer uniffi_bdkgo_fn_method_wallet_transactions(
 	void* ptr,
 	RustCallStatus* out_status
 );
 
+RustBuffer uniffi_bdkgo_fn_method_wallet_update_psbt(
+	void* ptr,
+	RustBuffer psbt,
+	RustBuffer prev_txs,
+	RustCallStatus* out_status
+);
+
 RustBuffer uniffi_bdkgo_fn_method_wallet_utxos(
 	void* ptr,
 	RustCallStatus* out_status
 );
 
+int8_t uniffi_bdkgo_fn_method_wallet_watch_only(
+	void* ptr,
+	RustCallStatus* out_status
+);
+
 RustBuffer ffi_bdkgo_rustbuffer_alloc(
 	int32_t size,
 	RustCallStatus* out_status
@@ -460,6 +590,10 @@ uint16_t uniffi_bdkgo_checksum_method_wallet_apply_block(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_method_wallet_apply_block_to_imports(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_method_wallet_apply_mempool(
 	RustCallStatus* out_status
 );
@@ -468,10 +602,38 @@ uint16_t uniffi_bdkgo_checksum_method_wallet_balance(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_method_wallet_birthday(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_bump_fee(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_create_cpfp(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_create_psbt(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_create_sweep(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_method_wallet_create_tx(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_method_wallet_descriptors(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_finalize_psbt(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_method_wallet_fresh_address(
 	RustCallStatus* out_status
 );
@@ -480,6 +642,14 @@ uint16_t uniffi_bdkgo_checksum_method_wallet_genesis_hash(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_method_wallet_import_descriptor(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_imports_height(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_method_wallet_increment_reference_counter(
 	RustCallStatus* out_status
 );
@@ -496,22 +666,58 @@ uint16_t uniffi_bdkgo_checksum_method_wallet_peek_address(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_method_wallet_process_psbt(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_psbt_inputs(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_psbt_outputs(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_method_wallet_recent_blocks(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_method_wallet_scripts(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_set_locked_utxos(
+	RustCallStatus* out_status
+);
+
+uint16_t uniffi_bdkgo_checksum_method_wallet_silent_payment_psbt(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_method_wallet_transactions(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_method_wallet_update_psbt(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_method_wallet_utxos(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_method_wallet_watch_only(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_constructor_wallet_create_new(
 	RustCallStatus* out_status
 );
 
+uint16_t uniffi_bdkgo_checksum_constructor_wallet_create_watch_only(
+	RustCallStatus* out_status
+);
+
 uint16_t uniffi_bdkgo_checksum_constructor_wallet_load(
 	RustCallStatus* out_status
 );
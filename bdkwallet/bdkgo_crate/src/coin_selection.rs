@@ -0,0 +1,270 @@
+use std::{
+    cmp::Reverse,
+    collections::{HashMap, HashSet},
+};
+
+use bdk::{
+    bitcoin::{consensus::serialize, Script, ScriptBuf},
+    wallet::coin_selection::{
+        decide_change, BranchAndBoundCoinSelection, CoinSelectionAlgorithm, CoinSelectionResult,
+        Error,
+    },
+    FeeRate, WeightedUtxo,
+};
+use rand::Rng;
+
+/// Weight of a txin without its script and witness, the same as bdk uses to compute the fee of
+/// spending an unspent output.
+const TXIN_BASE_WEIGHT: usize = (32 + 4 + 4) * 4;
+
+/// Number of random subsets the knapsack solver tries before settling on the best one.
+const KNAPSACK_ITERATIONS: usize = 1000;
+
+/// The strategy used to select the unspent outputs that fund a transaction.
+#[derive(Debug, Clone, Copy)]
+pub enum CoinSelection {
+    /// Searches for a set of unspent outputs that needs no change output, falling back to a
+    /// random selection when there is none.
+    BranchAndBound,
+    /// Picks the set of unspent outputs whose value is the closest to the amount needed while
+    /// leaving either no change or enough change to be worth an output.
+    Knapsack,
+    /// Selects the unspent outputs of the same script together like the knapsack solver would,
+    /// so that the addresses of the wallet are never left partially spent.
+    AvoidPartialSpends,
+}
+
+impl CoinSelectionAlgorithm for CoinSelection {
+    fn coin_select(
+        &self,
+        required_utxos: Vec<WeightedUtxo>,
+        optional_utxos: Vec<WeightedUtxo>,
+        fee_rate: FeeRate,
+        target_amount: u64,
+        drain_script: &Script,
+    ) -> Result<CoinSelectionResult, Error> {
+        match self {
+            CoinSelection::BranchAndBound => BranchAndBoundCoinSelection::default().coin_select(
+                required_utxos,
+                optional_utxos,
+                fee_rate,
+                target_amount,
+                drain_script,
+            ),
+            CoinSelection::Knapsack => {
+                let groups = optional_utxos.into_iter().map(|utxo| vec![utxo]).collect();
+                knapsack(required_utxos, groups, fee_rate, target_amount, drain_script)
+            }
+            CoinSelection::AvoidPartialSpends => {
+                let mut groups = Vec::<Vec<WeightedUtxo>>::new();
+                let mut group_of_script = HashMap::<ScriptBuf, usize>::new();
+                for utxo in optional_utxos {
+                    let script = utxo.utxo.txout().script_pubkey.clone();
+                    match group_of_script.get(&script) {
+                        Some(&i) => groups[i].push(utxo),
+                        None => {
+                            group_of_script.insert(script, groups.len());
+                            groups.push(vec![utxo]);
+                        }
+                    }
+                }
+
+                // The scripts of the required unspent outputs have the rest of their unspent
+                // outputs spent as well.
+                let required_scripts = required_utxos
+                    .iter()
+                    .map(|utxo| utxo.utxo.txout().script_pubkey.clone())
+                    .collect::<HashSet<_>>();
+                let (forced, groups): (Vec<_>, Vec<_>) = groups.into_iter().partition(|group| {
+                    required_scripts.contains(&group[0].utxo.txout().script_pubkey)
+                });
+                let mut required_utxos = required_utxos;
+                required_utxos.extend(forced.into_iter().flatten());
+                knapsack(required_utxos, groups, fee_rate, target_amount, drain_script)
+            }
+        }
+    }
+}
+
+/// Unspent outputs that are selected together along with their total value and the fee of
+/// spending them.
+struct OutputGroup {
+    utxos: Vec<WeightedUtxo>,
+    value: u64,
+    fee: u64,
+}
+
+impl OutputGroup {
+    fn new(utxos: Vec<WeightedUtxo>, fee_rate: FeeRate) -> Self {
+        let value = utxos.iter().map(|utxo| utxo.utxo.txout().value).sum();
+        let fee = utxos
+            .iter()
+            .map(|utxo| fee_rate.fee_wu(TXIN_BASE_WEIGHT + utxo.satisfaction_weight))
+            .sum();
+        Self { utxos, value, fee }
+    }
+
+    /// The value the group adds to the transaction once the fee of spending it is paid.
+    fn effective_value(&self) -> u64 {
+        self.value.saturating_sub(self.fee)
+    }
+}
+
+/// Selects the groups of unspent outputs with the knapsack solver on top of the required unspent
+/// outputs.
+fn knapsack(
+    required_utxos: Vec<WeightedUtxo>,
+    groups: Vec<Vec<WeightedUtxo>>,
+    fee_rate: FeeRate,
+    target_amount: u64,
+    drain_script: &Script,
+) -> Result<CoinSelectionResult, Error> {
+    let required = OutputGroup::new(required_utxos, fee_rate);
+    let groups = groups
+        .into_iter()
+        .map(|utxos| OutputGroup::new(utxos, fee_rate))
+        .filter(|group| group.effective_value() > 0)
+        .collect::<Vec<_>>();
+
+    // Change smaller than the fee of its output plus the dust limit goes to the fee, so the
+    // solver either avoids change or leaves at least that much.
+    let change_fee = fee_rate.fee_vb(serialize(drain_script).len() + 8);
+    let min_change = change_fee + drain_script.dust_value().to_sat();
+
+    let needed = (target_amount + required.fee).saturating_sub(required.value);
+    let selected = if needed == 0 {
+        Vec::new()
+    } else {
+        knapsack_select(&groups, needed, min_change).ok_or_else(|| {
+            Error::InsufficientFunds {
+                needed: target_amount
+                    + required.fee
+                    + groups.iter().map(|group| group.fee).sum::<u64>(),
+                available: required.value + groups.iter().map(|group| group.value).sum::<u64>(),
+            }
+        })?
+    };
+
+    let mut groups = groups.into_iter().map(Some).collect::<Vec<_>>();
+    let mut selected_amount = required.value;
+    let mut fee_amount = required.fee;
+    let mut utxos = required.utxos;
+    for i in selected {
+        let group = groups[i].take().expect("groups are selected once");
+        selected_amount += group.value;
+        fee_amount += group.fee;
+        utxos.extend(group.utxos);
+    }
+
+    let remaining_amount = selected_amount - target_amount - fee_amount;
+    Ok(CoinSelectionResult {
+        selected: utxos.into_iter().map(|utxo| utxo.utxo).collect(),
+        fee_amount,
+        excess: decide_change(remaining_amount, fee_rate, drain_script),
+    })
+}
+
+/// Returns the indexes of the groups whose effective values add up to at least the target the way
+/// the knapsack solver of Bitcoin Core does. A group that matches the target exactly is picked
+/// right away. Otherwise the smallest group that leaves `min_change` competes with the best random
+/// subset of the smaller groups. None is returned if the groups don't add up to the target.
+fn knapsack_select(groups: &[OutputGroup], target: u64, min_change: u64) -> Option<Vec<usize>> {
+    let mut applicable = Vec::new();
+    let mut total_lower = 0;
+    let mut lowest_larger: Option<usize> = None;
+    for (i, group) in groups.iter().enumerate() {
+        let value = group.effective_value();
+        if value == target {
+            return Some(vec![i]);
+        }
+        if value < target + min_change {
+            applicable.push(i);
+            total_lower += value;
+        } else if lowest_larger.map_or(true, |j| value < groups[j].effective_value()) {
+            lowest_larger = Some(i);
+        }
+    }
+
+    if total_lower == target {
+        return Some(applicable);
+    }
+    if total_lower < target {
+        return lowest_larger.map(|i| vec![i]);
+    }
+
+    applicable.sort_unstable_by_key(|&i| Reverse(groups[i].effective_value()));
+    let values = applicable
+        .iter()
+        .map(|&i| groups[i].effective_value())
+        .collect::<Vec<_>>();
+    let mut rng = rand::thread_rng();
+    let (mut best, mut best_value) = approximate_best_subset(&mut rng, &values, total_lower, target);
+    if best_value != target && total_lower >= target + min_change {
+        (best, best_value) =
+            approximate_best_subset(&mut rng, &values, total_lower, target + min_change);
+    }
+
+    // The smallest larger group is better unless the subset is both smaller and either exact or
+    // leaves enough change.
+    if let Some(i) = lowest_larger {
+        if (best_value != target && best_value < target + min_change)
+            || groups[i].effective_value() <= best_value
+        {
+            return Some(vec![i]);
+        }
+    }
+    Some(
+        applicable
+            .into_iter()
+            .zip(best)
+            .filter_map(|(i, included)| included.then_some(i))
+            .collect(),
+    )
+}
+
+/// Searches random subsets of the values for the smallest total that reaches the target. The
+/// values must be sorted in descending order and add up to `total`.
+fn approximate_best_subset<R: Rng>(
+    rng: &mut R,
+    values: &[u64],
+    total: u64,
+    target: u64,
+) -> (Vec<bool>, u64) {
+    let mut best = vec![true; values.len()];
+    let mut best_value = total;
+    for _ in 0..KNAPSACK_ITERATIONS {
+        if best_value == target {
+            break;
+        }
+
+        // The first pass includes the values at random while the second one includes the rest
+        // until the target is reached. Whenever the target is reached, the last value is taken out
+        // again to look for a smaller total.
+        let mut included = vec![false; values.len()];
+        let mut subset_value = 0;
+        let mut reached_target = false;
+        for pass in 0..2 {
+            if reached_target {
+                break;
+            }
+            for (i, &value) in values.iter().enumerate() {
+                let include = if pass == 0 { rng.gen_bool(0.5) } else { !included[i] };
+                if !include {
+                    continue;
+                }
+                subset_value += value;
+                included[i] = true;
+                if subset_value >= target {
+                    reached_target = true;
+                    if subset_value < best_value {
+                        best_value = subset_value;
+                        best = included.clone();
+                    }
+                    subset_value -= value;
+                    included[i] = false;
+                }
+            }
+        }
+    }
+    (best, best_value)
+}
@@ -1,6 +1,8 @@
 use std::{
     cmp::Reverse,
+    collections::HashMap,
     io::Read,
+    path::Path,
     str::FromStr,
     sync::{Arc, Mutex},
 };
@@ -9,12 +11,21 @@ pub use bdk::wallet::Balance;
 use bdk::{
     bitcoin::{
         self,
+        bip32::DerivationPath,
         consensus::{Decodable, Encodable},
-        hashes::Hash,
+        hashes::{hash160, Hash},
+        key::TapTweak,
         network::constants::ParseNetworkError,
-        Address, BlockHash, Network, Transaction,
+        psbt::{self, raw, PartiallySignedTransaction as Psbt},
+        secp256k1::{All, KeyPair, Parity, PublicKey, Scalar, Secp256k1, SecretKey, XOnlyPublicKey},
+        absolute, Address, BlockHash, Network, OutPoint, ScriptBuf, Transaction, TxIn, TxOut, Txid,
     },
     keys::{DerivableKey, ExtendedKey},
+    miniscript::{
+        descriptor::{DescriptorPublicKey, DescriptorSecretKey, DescriptorType, KeyMap},
+        psbt::PsbtExt,
+        Descriptor,
+    },
     template::Bip86,
     wallet::AddressIndex,
     FeeRate, KeychainKind, SignOptions,
@@ -23,12 +34,29 @@ use bincode::Options;
 use rand::RngCore;
 use uniffi::deps::bytes::Buf;
 
+mod coin_selection;
+
+pub use coin_selection::CoinSelection;
+
 uniffi::include_scaffolding!("bdkgo");
 
 const DB_MAGIC: &str = "utreexod.bdk.345e94cf";
 const DB_MAGIC_LEN: usize = DB_MAGIC.len();
+const WATCH_ONLY_DB_MAGIC: &str = "utreexod.bdk.wo.7c2d1"; // same length as DB_MAGIC
 const ENTROPY_LEN: usize = 16; // 12 words
 
+/// Type of the BIP375 output field with the scan and spend keys of the silent payment address the
+/// output pays to.
+const PSBT_OUT_SP_V0_INFO: u8 = 0x09;
+/// Type of the BIP375 input field with the ECDH share of the input for the scan key of its key.
+const PSBT_IN_SP_ECDH_SHARE: u8 = 0x1d;
+/// The x coordinate of the point with an unknown discrete logarithm that BIP341 suggests as the
+/// internal key of taproot outputs without a key path.
+const TAPROOT_NUMS_KEY: [u8; 32] = [
+    0x50, 0x92, 0x9b, 0x74, 0xc1, 0xa0, 0x49, 0x54, 0xb7, 0x8b, 0x4b, 0x60, 0x35, 0xe9, 0x7a, 0x5e,
+    0x07, 0x8a, 0x5a, 0x0f, 0x28, 0xec, 0x96, 0xd5, 0x47, 0xbf, 0xee, 0x9a, 0xce, 0x80, 0x3a, 0xc0,
+];
+
 type BdkWallet = bdk::Wallet<bdk_file_store::Store<bdk::wallet::ChangeSet>>;
 
 fn bincode_config() -> impl bincode::Options {
@@ -45,6 +73,8 @@ pub enum CreateNewError {
     Database(bdk_file_store::FileError),
     #[error("failed to init wallet: {0}")]
     Wallet(bdk::wallet::NewError<std::io::Error>),
+    #[error("invalid watch-only descriptor: {0}")]
+    Descriptor(ImportDescriptorError),
 }
 
 #[derive(Debug, thiserror::Error)]
@@ -81,6 +111,18 @@ pub enum ApplyMempoolError {
     Database(std::io::Error),
 }
 
+#[derive(Debug, thiserror::Error)]
+pub enum ImportDescriptorError {
+    #[error("failed to parse descriptor: {0}")]
+    ParseDescriptor(bdk::miniscript::Error),
+    #[error("descriptor must be a ranged wpkh or tr descriptor with at most two paths")]
+    UnsupportedDescriptor,
+    #[error("failed to create new db file: {0}")]
+    Database(bdk_file_store::FileError),
+    #[error("failed to init wallet: {0}")]
+    Wallet(bdk::wallet::NewError<std::io::Error>),
+}
+
 #[derive(Debug, thiserror::Error)]
 pub enum CreateTxError {
     #[error("recipient address is invalid: {0}")]
@@ -89,6 +131,46 @@ pub enum CreateTxError {
     CreateTx(bdk::wallet::error::CreateTxError<std::io::Error>),
     #[error("failed to sign tx: {0}")]
     SignTx(bdk::wallet::signer::SignerError),
+    #[error("watch-only wallet cannot sign txs")]
+    WatchOnly,
+}
+
+#[derive(Debug, thiserror::Error)]
+pub enum PsbtError {
+    #[error("failed to decode psbt: {0}")]
+    DecodePsbt(bdk::bitcoin::psbt::Error),
+    #[error("failed to decode tx: {0}")]
+    DecodeTx(bdk::bitcoin::consensus::encode::Error),
+    #[error("input is not an unspent output of the wallet")]
+    UnknownInput,
+    #[error("failed to create psbt: {0}")]
+    CreateTx(bdk::wallet::error::CreateTxError<std::io::Error>),
+    #[error("failed to sign psbt: {0}")]
+    SignTx(bdk::wallet::signer::SignerError),
+    #[error("psbt has no output at the index")]
+    UnknownOutput,
+    #[error("public key of an input spent along with silent payment outputs is unknown")]
+    UnknownInputKey,
+    #[error("outputs of witness versions above 1 can't be spent along with silent payment outputs")]
+    IneligibleInput,
+}
+
+#[derive(Debug, thiserror::Error)]
+pub enum BumpFeeError {
+    #[error("transaction is not of the wallet")]
+    UnknownTx,
+    #[error("transaction is already confirmed")]
+    Confirmed,
+    #[error("transaction has no unspent output of the wallet to spend")]
+    NoChange,
+    #[error("fee rate is too low to bump the fee of the transaction")]
+    FeeTooLow,
+    #[error("failed to bump the fee: {0}")]
+    BuildFeeBump(bdk::wallet::error::BuildFeeBumpError),
+    #[error("failed to create psbt: {0}")]
+    CreateTx(bdk::wallet::error::CreateTxError<std::io::Error>),
+    #[error("failed to write to db: {0}")]
+    Database(std::io::Error),
 }
 
 pub struct AddressInfo {
@@ -125,15 +207,8 @@ impl WalletHeader {
         l.into_iter().chain(b).collect::<Vec<u8>>()
     }
 
-    pub fn decode<R: Read>(mut r: R) -> Result<(Self, Vec<u8>), LoadError> {
-        let mut l_buf = [0_u8; 4];
-        r.read_exact(&mut l_buf)
-            .map_err(|err| LoadError::Database(bdk_file_store::FileError::Io(err)))?;
-        let l = u32::from_le_bytes(l_buf);
-        let mut b = vec![0; l as usize];
-        r.read_exact(&mut b)
-            .map_err(|err| LoadError::Database(bdk_file_store::FileError::Io(err)))?;
-
+    pub fn decode<R: Read>(r: R) -> Result<(Self, Vec<u8>), LoadError> {
+        let (b, header_b) = read_header(r)?;
         let header = bincode_config()
             .deserialize::<WalletHeader>(&b)
             .map_err(LoadError::ParseHeader)?;
@@ -141,7 +216,6 @@ impl WalletHeader {
             return Err(LoadError::HeaderVersion);
         }
 
-        let header_b = l_buf.into_iter().chain(b).collect::<Vec<u8>>();
         Ok((header, header_b))
     }
 
@@ -165,9 +239,589 @@ impl WalletHeader {
     }
 }
 
+/// The header of the db file of a watch-only wallet. Its keychains are the public descriptors it
+/// was created with, which are tracked from the birthday on.
+#[derive(Debug, serde::Serialize, serde::Deserialize)]
+pub struct WatchOnlyHeader {
+    pub version: [u8; DB_MAGIC_LEN],
+    pub descriptor: String,
+    pub change_descriptor: Option<String>,
+    pub birthday: u32,
+}
+
+impl WatchOnlyHeader {
+    pub fn new(descriptor: String, change_descriptor: Option<String>, birthday: u32) -> Self {
+        let mut version = [0_u8; DB_MAGIC_LEN];
+        version.copy_from_slice(WATCH_ONLY_DB_MAGIC.as_bytes());
+        Self {
+            version,
+            descriptor,
+            change_descriptor,
+            birthday,
+        }
+    }
+
+    pub fn encode(&mut self) -> Vec<u8> {
+        self.version.copy_from_slice(WATCH_ONLY_DB_MAGIC.as_bytes());
+        let b = bincode_config()
+            .serialize(&self)
+            .expect("bincode must serialize");
+        let l = (b.len() as u32).to_le_bytes();
+        l.into_iter().chain(b).collect::<Vec<u8>>()
+    }
+
+    pub fn decode<R: Read>(r: R) -> Result<(Self, Vec<u8>), LoadError> {
+        let (b, header_b) = read_header(r)?;
+        let header = bincode_config()
+            .deserialize::<WatchOnlyHeader>(&b)
+            .map_err(LoadError::ParseHeader)?;
+        if header.version != WATCH_ONLY_DB_MAGIC.as_bytes() {
+            return Err(LoadError::HeaderVersion);
+        }
+
+        Ok((header, header_b))
+    }
+}
+
+/// The keys the keychains of the wallet come from.
+pub enum WalletKeys {
+    /// The keychains are derived from the mnemonic of the wallet.
+    Mnemonic(WalletHeader),
+    /// The wallet is watch-only and its keychains are public descriptors.
+    WatchOnly(WatchOnlyHeader),
+}
+
+impl WalletKeys {
+    pub fn mnemonic_words(&self) -> Vec<String> {
+        match self {
+            WalletKeys::Mnemonic(header) => header.mnemonic_words(),
+            WalletKeys::WatchOnly(_) => Vec::new(),
+        }
+    }
+
+    /// Returns the height the wallet is tracked from, which is the genesis block unless the
+    /// wallet is watch-only.
+    pub fn birthday(&self) -> u32 {
+        match self {
+            WalletKeys::Mnemonic(_) => 0,
+            WalletKeys::WatchOnly(header) => header.birthday,
+        }
+    }
+}
+
+/// Reads a length-prefixed header from the start of a db file. It returns the header along with
+/// the raw bytes the db file was created with.
+fn read_header<R: Read>(mut r: R) -> Result<(Vec<u8>, Vec<u8>), LoadError> {
+    let mut l_buf = [0_u8; 4];
+    r.read_exact(&mut l_buf)
+        .map_err(|err| LoadError::Database(bdk_file_store::FileError::Io(err)))?;
+    let l = u32::from_le_bytes(l_buf);
+    let mut b = vec![0; l as usize];
+    r.read_exact(&mut b)
+        .map_err(|err| LoadError::Database(bdk_file_store::FileError::Io(err)))?;
+
+    let header_b = l_buf.iter().copied().chain(b.iter().copied()).collect::<Vec<u8>>();
+    Ok((b, header_b))
+}
+
+/// The header of the db file of an imported descriptor. The descriptors are kept with their
+/// secret keys (if any) so that they can be exported and signed with.
+#[derive(Debug, serde::Serialize, serde::Deserialize)]
+pub struct ImportHeader {
+    pub version: [u8; DB_MAGIC_LEN],
+    pub descriptor: String,
+    pub change_descriptor: Option<String>,
+    pub birthday: u32,
+}
+
+impl ImportHeader {
+    pub fn new(descriptor: String, change_descriptor: Option<String>, birthday: u32) -> Self {
+        let mut version = [0_u8; DB_MAGIC_LEN];
+        version.copy_from_slice(DB_MAGIC.as_bytes());
+        Self {
+            version,
+            descriptor,
+            change_descriptor,
+            birthday,
+        }
+    }
+
+    pub fn encode(&mut self) -> Vec<u8> {
+        self.version.copy_from_slice(DB_MAGIC.as_bytes());
+        let b = bincode_config()
+            .serialize(&self)
+            .expect("bincode must serialize");
+        let l = (b.len() as u32).to_le_bytes();
+        l.into_iter().chain(b).collect::<Vec<u8>>()
+    }
+
+    pub fn decode<R: Read>(r: R) -> Result<(Self, Vec<u8>), LoadError> {
+        let (b, header_b) = read_header(r)?;
+        let header = bincode_config()
+            .deserialize::<ImportHeader>(&b)
+            .map_err(LoadError::ParseHeader)?;
+        if header.version != DB_MAGIC.as_bytes() {
+            return Err(LoadError::HeaderVersion);
+        }
+
+        Ok((header, header_b))
+    }
+}
+
+/// A descriptor imported into the wallet. It's tracked by its own bdk wallet as the keychains of
+/// the main wallet are derived from its mnemonic.
+pub struct ImportedWallet {
+    header: ImportHeader,
+    wallet: BdkWallet,
+}
+
+/// Returns the path of the db file of the imported descriptor at the index.
+fn import_db_path(db_path: &str, index: usize) -> String {
+    format!("{}.import.{}", db_path, index)
+}
+
+/// Loads the imported descriptors of the wallet at the db path.
+fn load_imports(db_path: &str) -> Result<Vec<ImportedWallet>, LoadError> {
+    let mut imports = Vec::new();
+    loop {
+        let path = import_db_path(db_path, imports.len());
+        if !Path::new(&path).exists() {
+            return Ok(imports);
+        }
+
+        let file = std::fs::File::open(&path)
+            .map_err(|err| LoadError::Database(bdk_file_store::FileError::Io(err)))?;
+        let (header, header_bytes) = ImportHeader::decode(file)?;
+        let db = bdk_file_store::Store::open(&header_bytes, &path).map_err(LoadError::Database)?;
+        let wallet = bdk::Wallet::load(
+            header.descriptor.as_str(),
+            header.change_descriptor.as_deref(),
+            db,
+        )
+        .map_err(LoadError::Wallet)?;
+        imports.push(ImportedWallet { header, wallet });
+    }
+}
+
+/// Parses a ranged wpkh or tr descriptor with at most two paths into the descriptors of the
+/// external keychain and of the change keychain (if any). The secret keys are only kept if
+/// `include_private` is set.
+fn split_descriptor(
+    secp: &Secp256k1<All>,
+    descriptor: &str,
+    include_private: bool,
+) -> Result<(String, Option<String>), ImportDescriptorError> {
+    let (descriptor, key_map) =
+        Descriptor::<DescriptorPublicKey>::parse_descriptor(secp, descriptor)
+            .map_err(ImportDescriptorError::ParseDescriptor)?;
+    if !matches!(descriptor.desc_type(), DescriptorType::Wpkh | DescriptorType::Tr)
+        || !descriptor.has_wildcard()
+    {
+        return Err(ImportDescriptorError::UnsupportedDescriptor);
+    }
+    let descriptors = descriptor
+        .into_single_descriptors()
+        .map_err(ImportDescriptorError::ParseDescriptor)?;
+    if descriptors.len() > 2 {
+        return Err(ImportDescriptorError::UnsupportedDescriptor);
+    }
+    let key_maps = split_key_map(&key_map, descriptors.len());
+    let mut descriptors = descriptors.iter().zip(&key_maps).map(|(descriptor, key_map)| {
+        if include_private {
+            descriptor.to_string_with_secret(key_map)
+        } else {
+            descriptor.to_string()
+        }
+    });
+
+    let external = descriptors.next().expect("must have a descriptor");
+    Ok((external, descriptors.next()))
+}
+
+/// Splits a multi-path key map into the key maps of each of the paths.
+fn split_key_map(key_map: &KeyMap, paths: usize) -> Vec<KeyMap> {
+    let mut key_maps = vec![KeyMap::new(); paths];
+    for (public, secret) in key_map {
+        let publics = public.clone().into_single_keys();
+        let secrets = secret.clone().into_single_keys();
+        for (i, (public, secret)) in publics.into_iter().zip(secrets).enumerate() {
+            if let Some(key_map) = key_maps.get_mut(i) {
+                key_map.insert(public, secret);
+            }
+        }
+    }
+    key_maps
+}
+
+/// Applies the block to the wallet unless the wallet already has it. It returns whether or not
+/// the block was applied.
+///
+/// Rescans skip the blocks whose compact filters don't match the scripts of the wallet, so the
+/// wallet has no checkpoints at the heights of the blocks it skipped. A block below the tip at such
+/// a height was skipped by a rescan and a block further above the tip connects to the tip.
+fn apply_block_to(
+    wallet: &mut BdkWallet,
+    block: &bitcoin::Block,
+    height: u32,
+) -> Result<bool, ApplyBlockError> {
+    let tip = wallet.latest_checkpoint();
+    if tip.height() >= height {
+        let hash = block.block_hash();
+        match tip.iter().find(|cp| cp.height() <= height) {
+            Some(cp) if cp.height() < height || cp.hash() == hash => return Ok(false),
+            _ => {}
+        }
+    }
+
+    if tip.height() + 1 < height {
+        wallet
+            .apply_block_connected_to(block, height, tip.block_id())
+            .map_err(|err| match err {
+                bdk::chain::local_chain::ApplyHeaderError::InconsistentBlocks => {
+                    unreachable!("cannot happen")
+                }
+                bdk::chain::local_chain::ApplyHeaderError::CannotConnect(err) => {
+                    ApplyBlockError::CannotConnect(err)
+                }
+            })?;
+    } else {
+        wallet
+            .apply_block(block, height)
+            .map_err(ApplyBlockError::CannotConnect)?;
+    }
+    Ok(true)
+}
+
+/// Applies the block to the imported descriptors whose birthday isn't after it.
+fn apply_block_to_imports(
+    imports: &mut [ImportedWallet],
+    block: &bitcoin::Block,
+    height: u32,
+) -> Result<ApplyResult, ApplyBlockError> {
+    let mut res = ApplyResult {
+        relevant_txids: Vec::new(),
+    };
+    for import in imports.iter_mut() {
+        if height < import.header.birthday {
+            continue;
+        }
+        if apply_block_to(&mut import.wallet, block, height)? {
+            res.relevant_txids
+                .extend(ApplyResult::new(&import.wallet).relevant_txids);
+            import.wallet.commit().map_err(ApplyBlockError::Database)?;
+        }
+    }
+    Ok(res)
+}
+
+/// Returns the descriptors of the keychains of the wallet.
+fn wallet_descriptors(
+    wallet: &BdkWallet,
+    birthday: u32,
+    include_private: bool,
+) -> Vec<DescriptorInfo> {
+    [KeychainKind::External, KeychainKind::Internal]
+        .into_iter()
+        .filter_map(|keychain| {
+            let descriptor = wallet.public_descriptor(keychain)?;
+            let descriptor = if include_private {
+                let key_map = wallet.get_signers(keychain).as_key_map(wallet.secp_ctx());
+                descriptor.to_string_with_secret(&key_map)
+            } else {
+                descriptor.to_string()
+            };
+            let next_index = wallet
+                .spk_index()
+                .last_revealed_index(&keychain)
+                .map_or(0, |index| index + 1);
+            Some(DescriptorInfo {
+                descriptor,
+                internal: keychain == KeychainKind::Internal,
+                birthday,
+                next_index,
+            })
+        })
+        .collect()
+}
+
+/// Adds what the wallet knows of the outputs it owns to the inputs of the psbt spending them.
+fn update_psbt_from(wallet: &BdkWallet, psbt: &mut Psbt) -> Result<(), PsbtError> {
+    for (txin, input) in psbt.unsigned_tx.input.iter().zip(psbt.inputs.iter_mut()) {
+        if let Some(utxo) = wallet.get_utxo(txin.previous_output) {
+            let wallet_input = wallet
+                .get_psbt_input(utxo, input.sighash_type, false)
+                .map_err(PsbtError::CreateTx)?;
+            input.combine(wallet_input);
+        }
+    }
+    Ok(())
+}
+
+/// Returns the psbt input spending the unspent output of the imported descriptors at the outpoint
+/// along with the weight of the witness that satisfies its descriptor, so that the wallet can spend
+/// it as a foreign utxo. The input of a taproot descriptor with a script tree has the leaf scripts
+/// and their control blocks for the inputs to be signed and finalized through the script paths.
+fn import_foreign_utxo(
+    imports: &[ImportedWallet],
+    outpoint: OutPoint,
+) -> Result<Option<(psbt::Input, usize)>, PsbtError> {
+    for import in imports {
+        let Some(utxo) = import.wallet.get_utxo(outpoint) else {
+            continue;
+        };
+        let satisfaction_weight = import
+            .wallet
+            .get_descriptor_for_keychain(utxo.keychain)
+            .max_weight_to_satisfy()
+            .map_err(|_| PsbtError::UnknownInput)?;
+        let input = import
+            .wallet
+            .get_psbt_input(utxo, None, false)
+            .map_err(PsbtError::CreateTx)?;
+        return Ok(Some((input, satisfaction_weight)));
+    }
+    Ok(None)
+}
+
+/// Returns the fee paid by the psbt if it has the outputs spent by all of its inputs.
+fn psbt_fee(psbt: &Psbt) -> Option<u64> {
+    let spent = psbt
+        .unsigned_tx
+        .input
+        .iter()
+        .zip(&psbt.inputs)
+        .map(|(txin, input)| psbt_spent_output(txin, input).map(|txout| txout.value))
+        .sum::<Option<u64>>()?;
+    let sent: u64 = psbt.unsigned_tx.output.iter().map(|txout| txout.value).sum();
+    spent.checked_sub(sent)
+}
+
+/// Finalizes the inputs of the psbt that have all of the signatures they need. It returns whether
+/// all of the inputs are finalized.
+fn finalize_inputs(psbt: &mut Psbt) -> bool {
+    let secp = Secp256k1::verification_only();
+    (0..psbt.inputs.len()).fold(true, |complete, index| {
+        let input = &psbt.inputs[index];
+        let finalized = input.final_script_sig.is_some()
+            || input.final_script_witness.is_some()
+            || psbt.finalize_inp_mut(&secp, index).is_ok();
+        complete && finalized
+    })
+}
+
+/// Returns the output spent by the input of the psbt if the psbt has it.
+fn psbt_spent_output<'a>(txin: &TxIn, input: &'a psbt::Input) -> Option<&'a TxOut> {
+    input.witness_utxo.as_ref().or_else(|| {
+        input
+            .non_witness_utxo
+            .as_ref()
+            .and_then(|tx| tx.output.get(txin.previous_output.vout as usize))
+    })
+}
+
+/// Returns the public key that BIP352 takes from the input of the psbt, or None if the input isn't
+/// eligible for silent payments. The key of taproot inputs is the output key with an even y.
+fn silent_payment_input_key(
+    txin: &TxIn,
+    input: &psbt::Input,
+) -> Result<Option<PublicKey>, PsbtError> {
+    let spk = &psbt_spent_output(txin, input)
+        .ok_or(PsbtError::UnknownInputKey)?
+        .script_pubkey;
+    if spk.is_v1_p2tr() {
+        // Outputs without a key path can only be spent through the script path.
+        if input
+            .tap_internal_key
+            .map_or(false, |key| key.serialize() == TAPROOT_NUMS_KEY)
+        {
+            return Ok(None);
+        }
+        let key = XOnlyPublicKey::from_slice(&spk.as_bytes()[2..34])
+            .map_err(|_| PsbtError::UnknownInputKey)?;
+        return Ok(Some(key.public_key(Parity::Even)));
+    }
+    if spk
+        .witness_version()
+        .map_or(false, |version| version.to_num() > 1)
+    {
+        return Err(PsbtError::IneligibleInput);
+    }
+
+    let hash = match &input.redeem_script {
+        _ if spk.is_v0_p2wpkh() => &spk.as_bytes()[2..22],
+        _ if spk.is_p2pkh() => &spk.as_bytes()[3..23],
+        Some(redeem_script) if spk.is_p2sh() && redeem_script.is_v0_p2wpkh() => {
+            &redeem_script.as_bytes()[2..22]
+        }
+        _ => return Ok(None),
+    };
+    input
+        .bip32_derivation
+        .keys()
+        .copied()
+        .chain(
+            input
+                .partial_sigs
+                .keys()
+                .filter(|key| key.compressed)
+                .map(|key| key.inner),
+        )
+        .find(|key| &hash160::Hash::hash(&key.serialize()).as_byte_array()[..] == hash)
+        .map(Some)
+        .ok_or(PsbtError::UnknownInputKey)
+}
+
+/// Returns the secret key of the public key that BIP352 takes from the input of the psbt if one
+/// of the key maps has the extended key it's derived from. The secret key of taproot inputs is
+/// tweaked and negated the same way as their public key.
+fn silent_payment_secret_key(
+    secp: &Secp256k1<All>,
+    key_maps: &[KeyMap],
+    input: &psbt::Input,
+    public_key: &PublicKey,
+) -> Option<SecretKey> {
+    let taproot = input.tap_internal_key.is_some();
+    let sources = input
+        .tap_key_origins
+        .values()
+        .filter(|(leaf_hashes, _)| leaf_hashes.is_empty())
+        .map(|(_, source)| source)
+        .chain(input.bip32_derivation.values());
+    for source in sources {
+        for secret in key_maps.iter().flat_map(|key_map| key_map.values()) {
+            let xkey = match secret {
+                DescriptorSecretKey::XPrv(xkey) if xkey.matches(source, secp).is_some() => xkey,
+                _ => continue,
+            };
+            let path = match &xkey.origin {
+                Some((_, origin_path)) => {
+                    DerivationPath::from(&source.1.as_ref()[origin_path.as_ref().len()..])
+                }
+                None => source.1.clone(),
+            };
+            let mut secret_key = match xkey.xkey.derive_priv(secp, &path) {
+                Ok(derived) => derived.private_key,
+                Err(_) => continue,
+            };
+            if taproot {
+                let keypair = KeyPair::from_secret_key(secp, &secret_key)
+                    .tap_tweak(secp, input.tap_merkle_root)
+                    .to_inner();
+                secret_key = match keypair.x_only_public_key().1 {
+                    Parity::Even => keypair.secret_key(),
+                    Parity::Odd => keypair.secret_key().negate(),
+                };
+            }
+            if PublicKey::from_secret_key(secp, &secret_key) == *public_key {
+                return Some(secret_key);
+            }
+        }
+    }
+    None
+}
+
+/// Returns the outputs of the psbt with the silent payment info field of BIP375.
+fn silent_payment_outputs(psbt: &Psbt) -> Vec<SilentPaymentOutput> {
+    let info_key = raw::Key {
+        type_value: PSBT_OUT_SP_V0_INFO,
+        key: Vec::new(),
+    };
+    psbt.unsigned_tx
+        .output
+        .iter()
+        .zip(&psbt.outputs)
+        .enumerate()
+        .filter_map(|(index, (txout, output))| {
+            let info = output.unknown.get(&info_key)?;
+            if info.len() != 66 {
+                return None;
+            }
+            let (scan_key, spend_key) = info.split_at(33);
+            PublicKey::from_slice(scan_key).ok()?;
+            PublicKey::from_slice(spend_key).ok()?;
+            Some(SilentPaymentOutput {
+                index: index as u32,
+                scan_key: scan_key.to_vec(),
+                spend_key: spend_key.to_vec(),
+                script_pubkey: txout.script_pubkey.to_bytes(),
+            })
+        })
+        .collect()
+}
+
+/// Returns the unconfirmed transaction of the wallet with the txid along with its fee.
+fn unconfirmed_wallet_tx(
+    wallet: &BdkWallet,
+    txid: &[u8],
+) -> Result<(Transaction, u64), BumpFeeError> {
+    let txid = Txid::from_slice(txid).map_err(|_| BumpFeeError::UnknownTx)?;
+    let ctx = wallet.get_tx(txid).ok_or(BumpFeeError::UnknownTx)?;
+    if ctx.chain_position.is_confirmed() {
+        return Err(BumpFeeError::Confirmed);
+    }
+    let tx = ctx.tx_node.tx.clone();
+    let fee = wallet
+        .calculate_fee(&tx)
+        .map_err(|_| BumpFeeError::UnknownTx)?;
+    Ok((tx, fee))
+}
+
+/// Returns the transactions of the wallet.wallet: &BdkWallet) -> Vec<TxInfo> {
+    let height = wallet.latest_checkpoint().height();
+    wallet
+        .transactions()
+        .map(|ctx| {
+            let txid = ctx.tx_node.txid.to_byte_array().to_vec();
+            let mut tx = Vec::<u8>::new();
+            ctx.tx_node
+                .tx
+                .consensus_encode(&mut tx)
+                .expect("must encode");
+            let (spent, received) = wallet.sent_and_received(ctx.tx_node.tx);
+            let confirmations = ctx
+                .chain_position
+                .confirmation_height_upper_bound()
+                .map_or(0, |conf_height| (1 + height).saturating_sub(conf_height));
+            TxInfo {
+                txid,
+                tx,
+                spent,
+                received,
+                confirmations,
+            }
+        })
+        .collect()
+}
+
+/// Returns the unspent outputs of the wallet.
+fn wallet_utxos(wallet: &BdkWallet) -> Vec<UtxoInfo> {
+    let wallet_height = wallet.latest_checkpoint().height();
+    wallet
+        .list_unspent()
+        .map(|utxo| UtxoInfo {
+            txid: utxo.outpoint.txid.to_byte_array().to_vec(),
+            vout: utxo.outpoint.vout,
+            amount: utxo.txout.value,
+            script_pubkey: utxo.txout.script_pubkey.to_bytes(),
+            is_change: utxo.keychain == KeychainKind::Internal,
+            derivation_index: utxo.derivation_index,
+            confirmations: match utxo.confirmation_time {
+                bdk::chain::ConfirmationTime::Confirmed { height, .. } => {
+                    (1 + wallet_height).saturating_sub(height)
+                }
+                bdk::chain::ConfirmationTime::Unconfirmed { .. } => 0,
+            },
+        })
+        .collect()
+}
+
 pub struct Wallet {
     inner: Mutex<BdkWallet>,
-    header: Mutex<WalletHeader>,
+    keys: Mutex<WalletKeys>,
+    imports: Mutex<Vec<ImportedWallet>>,
+    /// The unspent outputs that aren't spent unless they're explicitly picked as inputs.
+    locked: Mutex<Vec<OutPoint>>,
+    db_path: String,
 }
 
 impl Wallet {
@@ -208,26 +862,118 @@ impl Wallet {
         };
 
         let inner = Mutex::new(bdk_wallet);
-        let header = Mutex::new(header);
-        Ok(Self { inner, header })
+        let keys = Mutex::new(WalletKeys::Mnemonic(header));
+        let imports = Mutex::new(Vec::new());
+        Ok(Self {
+            inner,
+            keys,
+            imports,
+            locked: Mutex::new(Vec::new()),
+            db_path,
+        })
+    }
+
+    /// Creates a watch-only wallet tracking the descriptor, which is either a ranged wpkh or tr
+    /// descriptor with at most two paths or an extended public key that is tracked as a BIP86
+    /// wallet. Only the public keys are kept. The blocks from the birthday on have to be applied
+    /// to the wallet to find its history.
+    pub fn create_watch_only(
+        db_path: String,
+        network: String,
+        genesis_hash: Vec<u8>,
+        descriptor: String,
+        birthday: u32,
+    ) -> Result<Self, CreateNewError> {
+        let network = Network::from_str(&network).map_err(CreateNewError::ParseNetwork)?;
+        let genesis_hash =
+            BlockHash::from_slice(&genesis_hash).map_err(CreateNewError::ParseGenesisHash)?;
+
+        let descriptor = if descriptor.contains('(') {
+            descriptor
+        } else {
+            format!("tr({}/<0;1>/*)", descriptor)
+        };
+        let (external, internal) = split_descriptor(&Secp256k1::new(), &descriptor, false)
+            .map_err(CreateNewError::Descriptor)?;
+
+        let mut header = WatchOnlyHeader::new(external, internal, birthday);
+        let header_bytes = header.encode();
+        let db = bdk_file_store::Store::create_new(&header_bytes, &db_path)
+            .map_err(CreateNewError::Database)?;
+        let bdk_wallet = match bdk::Wallet::new_with_genesis_hash(
+            header.descriptor.as_str(),
+            header.change_descriptor.as_deref(),
+            db,
+            network,
+            genesis_hash,
+        ) {
+            Ok(w) => w,
+            Err(err) => {
+                let _ = std::fs::remove_file(db_path);
+                return Err(CreateNewError::Wallet(err));
+            }
+        };
+
+        let inner = Mutex::new(bdk_wallet);
+        let keys = Mutex::new(WalletKeys::WatchOnly(header));
+        let imports = Mutex::new(Vec::new());
+        Ok(Self {
+            inner,
+            keys,
+            imports,
+            locked: Mutex::new(Vec::new()),
+            db_path,
+        })
     }
 
     pub fn load(db_path: String) -> Result<Self, LoadError> {
         let file = std::fs::File::open(&db_path)
             .map_err(|err| LoadError::Database(bdk_file_store::FileError::Io(err)))?;
-        let (header, header_bytes) = WalletHeader::decode(file)?;
+        let (b, header_bytes) = read_header(file)?;
         let db =
-            bdk_file_store::Store::open(&header_bytes, db_path).map_err(LoadError::Database)?;
-        let bdk_wallet = bdk::Wallet::load(
-            header.descriptor(KeychainKind::External),
-            Some(header.descriptor(KeychainKind::Internal)),
-            db,
-        )
-        .map_err(LoadError::Wallet)?;
+            bdk_file_store::Store::open(&header_bytes, &db_path).map_err(LoadError::Database)?;
+        let (bdk_wallet, keys) = if b.starts_with(WATCH_ONLY_DB_MAGIC.as_bytes()) {
+            let (header, _) = WatchOnlyHeader::decode(header_bytes.as_slice())?;
+            let bdk_wallet = bdk::Wallet::load(
+                header.descriptor.as_str(),
+                header.change_descriptor.as_deref(),
+                db,
+            )
+            .map_err(LoadError::Wallet)?;
+            (bdk_wallet, WalletKeys::WatchOnly(header))
+        } else {
+            let (header, _) = WalletHeader::decode(header_bytes.as_slice())?;
+            let bdk_wallet = bdk::Wallet::load(
+                header.descriptor(KeychainKind::External),
+                Some(header.descriptor(KeychainKind::Internal)),
+                db,
+            )
+            .map_err(LoadError::Wallet)?;
+            (bdk_wallet, WalletKeys::Mnemonic(header))
+        };
 
         let inner = Mutex::new(bdk_wallet);
-        let header = Mutex::new(header);
-        Ok(Self { inner, header })
+        let keys = Mutex::new(keys);
+        let imports = Mutex::new(load_imports(&db_path)?);
+        Ok(Self {
+            inner,
+            keys,
+            imports,
+            locked: Mutex::new(Vec::new()),
+            db_path,
+        })
+    }
+
+    /// Returns whether the wallet is watch-only.
+    pub fn watch_only(self: Arc<Self>) -> bool {
+        self.increment_reference_counter();
+        matches!(*self.keys.lock().unwrap(), WalletKeys::WatchOnly(_))
+    }
+
+    /// Returns the height the wallet is tracked from.
+    pub fn birthday(self: Arc<Self>) -> u32 {
+        self.increment_reference_counter();
+        self.keys.lock().unwrap().birthday()
     }
 
     fn address(self: Arc<Self>, index: AddressIndex) -> Result<AddressInfo, DatabaseError> {
@@ -260,7 +1006,16 @@ impl Wallet {
     pub fn balance(self: Arc<Self>) -> bdk::wallet::Balance {
         self.increment_reference_counter();
         let wallet = self.inner.lock().unwrap();
-        wallet.get_balance()
+        let imports = self.imports.lock().unwrap();
+        imports
+            .iter()
+            .map(|import| import.wallet.get_balance())
+            .fold(wallet.get_balance(), |total, balance| bdk::wallet::Balance {
+                immature: total.immature + balance.immature,
+                trusted_pending: total.trusted_pending + balance.trusted_pending,
+                untrusted_pending: total.untrusted_pending + balance.untrusted_pending,
+                confirmed: total.confirmed + balance.confirmed,
+            })
     }
 
     pub fn genesis_hash(self: Arc<Self>) -> Vec<u8> {
@@ -299,29 +1054,75 @@ impl Wallet {
         let block = bitcoin::Block::consensus_decode_from_finite_reader(&mut reader)
             .map_err(ApplyBlockError::DecodeBlock)?;
 
-        let tip = wallet.latest_checkpoint();
-
-        if tip.height() == 0 {
-            wallet
-                .apply_block_connected_to(&block, height, tip.block_id())
-                .map_err(|err| match err {
-                    bdk::chain::local_chain::ApplyHeaderError::InconsistentBlocks => {
-                        unreachable!("cannot happen")
-                    }
-                    bdk::chain::local_chain::ApplyHeaderError::CannotConnect(err) => {
-                        ApplyBlockError::CannotConnect(err)
-                    }
-                })?;
-        } else {
-            wallet
-                .apply_block(&block, height)
-                .map_err(ApplyBlockError::CannotConnect)?;
-        }
-        let res = ApplyResult::new(&wallet);
+        apply_block_to(&mut wallet, &block, height)?;
+        let mut res = ApplyResult::new(&wallet);
         wallet.commit().map_err(ApplyBlockError::Database)?;
+
+        let mut imports = self.imports.lock().unwrap();
+        res.relevant_txids
+            .extend(apply_block_to_imports(&mut imports, &block, height)?.relevant_txids);
         Ok(res)
     }
 
+    /// Applies a block to the imported descriptors only. This is used to rescan the blocks of
+    /// the main chain from the birthdays of the descriptors.
+    pub fn apply_block_to_imports(
+        self: Arc<Self>,
+        height: u32,
+        block_bytes: &[u8],
+    ) -> Result<ApplyResult, ApplyBlockError> {
+        self.increment_reference_counter();
+
+        let mut reader = block_bytes.reader();
+        let block = bitcoin::Block::consensus_decode_from_finite_reader(&mut reader)
+            .map_err(ApplyBlockError::DecodeBlock)?;
+
+        let mut imports = self.imports.lock().unwrap();
+        apply_block_to_imports(&mut imports, &block, height)
+    }
+
+    /// Returns the height up to which all of the imported descriptors have been synced. It's the
+    /// tip of the wallet when there are no imported descriptors.
+    pub fn imports_height(self: Arc<Self>) -> u32 {
+        self.increment_reference_counter();
+        let height = self.inner.lock().unwrap().latest_checkpoint().height();
+        let imports = self.imports.lock().unwrap();
+        imports
+            .iter()
+            .map(|import| {
+                let tip = import.wallet.latest_checkpoint().height();
+                tip.max(import.header.birthday.saturating_sub(1))
+            })
+            .min()
+            .unwrap_or(height)
+    }
+
+    /// Returns the scripts of the wallet and of the imported descriptors, including the scripts of
+    /// the lookahead that haven't been revealed yet, so that the blocks paying to or spending from
+    /// them can be found with compact filters.
+    pub fn scripts(self: Arc<Self>) -> Vec<Vec<u8>> {
+        self.increment_reference_counter();
+        let wallet = self.inner.lock().unwrap();
+        let mut scripts = wallet
+            .spk_index()
+            .all_spks()
+            .values()
+            .map(|spk| spk.to_bytes())
+            .collect::<Vec<_>>();
+        let imports = self.imports.lock().unwrap();
+        for import in imports.iter() {
+            scripts.extend(
+                import
+                    .wallet
+                    .spk_index()
+                    .all_spks()
+                    .values()
+                    .map(|spk| spk.to_bytes()),
+            );
+        }
+        scripts
+    }
+
     pub fn apply_mempool(
         self: Arc<Self>,
         txs: Vec<MempoolTx>,
@@ -340,17 +1141,99 @@ impl Wallet {
             .collect::<Vec<_>>();
         wallet.apply_unconfirmed_txs(txs.iter().map(|(tx, added)| (tx, *added)));
 
-        let res = ApplyResult::new(&wallet);
+        let mut res = ApplyResult::new(&wallet);
         wallet.commit().map_err(ApplyMempoolError::Database)?;
+
+        let mut imports = self.imports.lock().unwrap();
+        for import in imports.iter_mut() {
+            import
+                .wallet
+                .apply_unconfirmed_txs(txs.iter().map(|(tx, added)| (tx, *added)));
+            res.relevant_txids
+                .extend(ApplyResult::new(&import.wallet).relevant_txids);
+            import.wallet.commit().map_err(ApplyMempoolError::Database)?;
+        }
         Ok(res)
     }
 
+    /// Imports a ranged wpkh or tr descriptor. A tr descriptor may have a script tree, whose leaves
+    /// are signed for when the wallet has their keys. A multi-path descriptor with two paths is
+    /// imported with the second path as its change keychain. The blocks from the birthday on have to be
+    /// applied to the new descriptor with `apply_block_to_imports`.
+    pub fn import_descriptor(
+        self: Arc<Self>,
+        descriptor: String,
+        birthday: u32,
+    ) -> Result<(), ImportDescriptorError> {
+        self.increment_reference_counter();
+        let wallet = self.inner.lock().unwrap();
+        let mut imports = self.imports.lock().unwrap();
+
+        let (external, internal) = split_descriptor(wallet.secp_ctx(), &descriptor, true)?;
+        let mut header = ImportHeader::new(external, internal, birthday);
+        let header_bytes = header.encode();
+        let path = import_db_path(&self.db_path, imports.len());
+        let db = bdk_file_store::Store::create_new(&header_bytes, &path)
+            .map_err(ImportDescriptorError::Database)?;
+        let import_wallet = match bdk::Wallet::new_with_genesis_hash(
+            header.descriptor.as_str(),
+            header.change_descriptor.as_deref(),
+            db,
+            wallet.network(),
+            wallet.local_chain().genesis_hash(),
+        ) {
+            Ok(w) => w,
+            Err(err) => {
+                let _ = std::fs::remove_file(path);
+                return Err(ImportDescriptorError::Wallet(err));
+            }
+        };
+
+        imports.push(ImportedWallet {
+            header,
+            wallet: import_wallet,
+        });
+        Ok(())
+    }
+
+    /// Returns the descriptors of the keychains of the wallet followed by the imported ones.
+    pub fn descriptors(self: Arc<Self>, include_private: bool) -> Vec<DescriptorInfo> {
+        self.increment_reference_counter();
+        let wallet = self.inner.lock().unwrap();
+        let imports = self.imports.lock().unwrap();
+        let birthday = self.keys.lock().unwrap().birthday();
+        let mut descriptors = wallet_descriptors(&wallet, birthday, include_private);
+        for import in imports.iter() {
+            descriptors.extend(wallet_descriptors(
+                &import.wallet,
+                import.header.birthday,
+                include_private,
+            ));
+        }
+        descriptors
+    }
+
+    /// Sets the unspent outputs that are locked. The locked outputs aren't picked by the coin
+    /// selection, so they're only spent when they're given as inputs.
+    pub fn set_locked_utxos(self: Arc<Self>, outpoints: Vec<PsbtInput>) {
+        self.increment_reference_counter();
+        *self.locked.lock().unwrap() = outpoints.iter().map(PsbtInput::outpoint).collect();
+    }
+
+    /// Creates and signs a transaction paying the recipients. When inputs are given, exactly
+    /// those outputs of the wallet are spent. Otherwise the coin selection strategy picks them
+    /// from the unlocked outputs.
     pub fn create_tx(
         self: Arc<Self>,
         feerate: f32,
         recipients: Vec<Recipient>,
+        inputs: Vec<PsbtInput>,
+        coin_selection: CoinSelection,
     ) -> Result<Vec<u8>, CreateTxError> {
         self.increment_reference_counter();
+        if matches!(*self.keys.lock().unwrap(), WalletKeys::WatchOnly(_)) {
+            return Err(CreateTxError::WatchOnly);
+        }
         let mut wallet = self.inner.lock().unwrap();
         let recipients = recipients
             .into_iter()
@@ -363,14 +1246,23 @@ impl Wallet {
             })
             .collect::<Result<Vec<_>, _>>()?;
 
-        let mut psbt = wallet
-            .build_tx()
+        let locked = self.locked.lock().unwrap().clone();
+        let mut builder = wallet.build_tx().coin_selection(coin_selection);
+        builder
             .set_recipients(recipients)
             .fee_rate(FeeRate::from_sat_per_vb(feerate))
-            .enable_rbf()
-            .clone()
-            .finish()
-            .map_err(CreateTxError::CreateTx)?;
+            .unspendable(locked)
+            .enable_rbf();
+        if !inputs.is_empty() {
+            let outpoints = inputs.iter().map(PsbtInput::outpoint).collect::<Vec<_>>();
+            builder
+                .add_utxos(&outpoints)
+                .map_err(|_| {
+                    CreateTxError::CreateTx(bdk::wallet::error::CreateTxError::UnknownUtxo)
+                })?
+                .manually_selected_only();
+        }
+        let mut psbt = builder.finish().map_err(CreateTxError::CreateTx)?;
         let is_finalized = wallet
             .sign(&mut psbt, SignOptions::default())
             .map_err(CreateTxError::SignTx)?;
@@ -383,38 +1275,518 @@ impl Wallet {
         Ok(raw_bytes)
     }
 
+    /// Creates a psbt paying to the outputs that is funded by the wallet. The inputs are always
+    /// spent while more unspent outputs of the wallet are added as needed with the coin selection
+    /// strategy. The inputs may be unspent outputs of the imported descriptors, which are spent
+    /// through the script paths of taproot descriptors the wallet only has the keys of a leaf of.
+    /// Only the inputs are spent when `add_inputs` isn't set, and the locked outputs are never
+    /// added. A zero locktime lets the wallet pick it.
+    #[allow(clippy::too_many_arguments)]
+    pub fn create_psbt(
+        self: Arc<Self>,
+        inputs: Vec<PsbtInput>,
+        outputs: Vec<PsbtOutput>,
+        locktime: u32,
+        feerate: f32,
+        replaceable: bool,
+        add_inputs: bool,
+        coin_selection: CoinSelection,
+    ) -> Result<FundedPsbt, PsbtError> {
+        self.increment_reference_counter();
+        let mut wallet = self.inner.lock().unwrap();
+        let imports = self.imports.lock().unwrap();
+        let recipients = outputs
+            .into_iter()
+            .map(|output| (ScriptBuf::from_bytes(output.script_pubkey), output.amount))
+            .collect::<Vec<_>>();
+
+        // The outputs of the imported descriptors are added as foreign utxos since the wallet
+        // doesn't know them.
+        let mut own_utxos = Vec::new();
+        let mut foreign_utxos = Vec::new();
+        for input in inputs {
+            let outpoint = input.outpoint();
+            if wallet.get_utxo(outpoint).is_some() {
+                own_utxos.push(outpoint);
+                continue;
+            }
+            let (psbt_input, satisfaction_weight) =
+                import_foreign_utxo(&imports, outpoint)?.ok_or(PsbtError::UnknownInput)?;
+            foreign_utxos.push((outpoint, psbt_input, satisfaction_weight));
+        }
+
+        let locked = self.locked.lock().unwrap().clone();
+        let mut builder = wallet.build_tx().coin_selection(coin_selection);
+        builder
+            .set_recipients(recipients)
+            .fee_rate(FeeRate::from_sat_per_vb(feerate))
+            .unspendable(locked);
+        if !add_inputs {
+            builder.manually_selected_only();
+        }
+        for outpoint in own_utxos {
+            builder
+                .add_utxo(outpoint)
+                .map_err(|_| PsbtError::UnknownInput)?;
+        }
+        for (outpoint, psbt_input, satisfaction_weight) in foreign_utxos {
+            builder
+                .add_foreign_utxo(outpoint, psbt_input, satisfaction_weight)
+                .map_err(|_| PsbtError::UnknownInput)?;
+        }
+        if locktime != 0 {
+            builder.nlocktime(absolute::LockTime::from_consensus(locktime));
+        }
+        if replaceable {
+            builder.enable_rbf();
+        }
+        let psbt = builder.finish().map_err(PsbtError::CreateTx)?;
+
+        let fee = wallet
+            .calculate_fee(&psbt.unsigned_tx)
+            .ok()
+            .or_else(|| psbt_fee(&psbt))
+            .expect("inputs must be of the wallet or of the imports");
+        let change_pos = psbt
+            .unsigned_tx
+            .output
+            .iter()
+            .position(|txout| {
+                wallet
+                    .spk_index()
+                    .index_of_spk(&txout.script_pubkey)
+                    .filter(|index| index.0 == KeychainKind::Internal)
+                    .is_some()
+            })
+            .map_or(-1, |pos| pos as i32);
+        Ok(FundedPsbt {
+            psbt: psbt.serialize(),
+            fee,
+            change_pos,
+        })
+    }
+
+    /// Creates a psbt sweeping the unspent outputs of the wallet to the script. The psbt has no
+    /// change output and its only output is what the inputs hold minus the fee at the fee rate.
+    /// All of the unlocked outputs are swept unless inputs are given, in which case exactly those
+    /// are spent.
+    pub fn create_sweep(
+        self: Arc<Self>,
+        feerate: f32,
+        script_pubkey: Vec<u8>,
+        inputs: Vec<PsbtInput>,
+    ) -> Result<FundedPsbt, PsbtError> {
+        self.increment_reference_counter();
+        let mut wallet = self.inner.lock().unwrap();
+        let locked = self.locked.lock().unwrap().clone();
+        let mut builder = wallet.build_tx();
+        builder
+            .drain_to(ScriptBuf::from_bytes(script_pubkey))
+            .fee_rate(FeeRate::from_sat_per_vb(feerate))
+            .unspendable(locked)
+            .enable_rbf();
+        if inputs.is_empty() {
+            builder.drain_wallet();
+        } else {
+            let outpoints = inputs.iter().map(PsbtInput::outpoint).collect::<Vec<_>>();
+            builder
+                .add_utxos(&outpoints)
+                .map_err(|_| PsbtError::UnknownInput)?
+                .manually_selected_only();
+        }
+        let psbt = builder.finish().map_err(PsbtError::CreateTx)?;
+
+        let fee = wallet
+            .calculate_fee(&psbt.unsigned_tx)
+            .expect("inputs must be of the wallet");
+        Ok(FundedPsbt {
+            psbt: psbt.serialize(),
+            fee,
+            change_pos: -1,
+        })
+    }
+
+    /// Creates a psbt replacing the unconfirmed transaction of the wallet with one that pays the
+    /// fee rate as BIP125 allows. The higher fee is taken out of the change output, with more
+    /// confirmed unspent outputs of the wallet added when the change doesn't cover it.
+    pub fn bump_fee(self: Arc<Self>, txid: &[u8], feerate: f32) -> Result<FeeBump, BumpFeeError> {
+        self.increment_reference_counter();
+        let mut wallet = self.inner.lock().unwrap();
+        let (original, original_fee) = unconfirmed_wallet_tx(&wallet, txid)?;
+
+        // The replacement may only spend the unconfirmed outputs that the original spends, and
+        // the locked outputs aren't added to it.
+        let mut unspendable = self.locked.lock().unwrap().clone();
+        let unconfirmed = wallet
+            .list_unspent()
+            .filter(|utxo| {
+                matches!(
+                    utxo.confirmation_time,
+                    bdk::chain::ConfirmationTime::Unconfirmed { .. }
+                )
+            })
+            .map(|utxo| utxo.outpoint);
+        unspendable.extend(unconfirmed);
+        let mut builder = wallet
+            .build_fee_bump(original.txid())
+            .map_err(BumpFeeError::BuildFeeBump)?;
+        builder
+            .fee_rate(FeeRate::from_sat_per_vb(feerate))
+            .unspendable(unspendable)
+            .enable_rbf();
+        let psbt = builder.finish().map_err(BumpFeeError::CreateTx)?;
+
+        // On top of the fee of the original, the replacement pays for its own relay at the
+        // incremental relay fee rate of 1 sat/vB.
+        let fee = wallet
+            .calculate_fee(&psbt.unsigned_tx)
+            .expect("inputs must be of the wallet");
+        let vsize = fee as f32 / feerate;
+        if (fee as f32) < original_fee as f32 + vsize {
+            return Err(BumpFeeError::FeeTooLow);
+        }
+        Ok(FeeBump {
+            psbt: psbt.serialize(),
+            original_fee,
+            fee,
+        })
+    }
+
+    /// Creates a psbt spending an output of the wallet of the unconfirmed transaction back to the
+    /// wallet with a fee high enough for the transaction and the child to pay the fee rate
+    /// together. The change output of the transaction is spent when it has one.
+    pub fn create_cpfp(
+        self: Arc<Self>,
+        txid: &[u8],
+        feerate: f32,
+    ) -> Result<FeeBump, BumpFeeError> {
+        self.increment_reference_counter();
+        let mut wallet = self.inner.lock().unwrap();
+        let (parent, parent_fee) = unconfirmed_wallet_tx(&wallet, txid)?;
+        let parent_txid = parent.txid();
+        let outpoint = (0..parent.output.len() as u32)
+            .filter_map(|vout| wallet.get_utxo(OutPoint::new(parent_txid, vout)))
+            .max_by_key(|utxo| utxo.keychain == KeychainKind::Internal)
+            .map(|utxo| utxo.outpoint)
+            .ok_or(BumpFeeError::NoChange)?;
+        let drain_script = wallet
+            .try_get_internal_address(AddressIndex::LastUnused)
+            .map_err(BumpFeeError::Database)?
+            .address
+            .script_pubkey();
+
+        // The child is built at the fee rate first to find out its size. It spends no other
+        // locked outputs.
+        let locked = self.locked.lock().unwrap().clone();
+        let mut builder = wallet.build_tx();
+        builder
+            .add_utxo(outpoint)
+            .map_err(|_| BumpFeeError::NoChange)?
+            .unspendable(locked.clone())
+            .drain_to(drain_script.clone())
+            .fee_rate(FeeRate::from_sat_per_vb(feerate))
+            .enable_rbf();
+        let psbt = builder.finish().map_err(BumpFeeError::CreateTx)?;
+        let child_fee = wallet
+            .calculate_fee(&psbt.unsigned_tx)
+            .expect("inputs must be of the wallet");
+        let child_vsize = child_fee as f32 / feerate;
+
+        let package_fee = (feerate * (parent.vsize() as f32 + child_vsize)).ceil() as u64;
+        if package_fee <= parent_fee + child_fee {
+            // The transaction already pays the fee rate by itself.
+            return Err(BumpFeeError::FeeTooLow);
+        }
+        let fee = package_fee - parent_fee;
+        let mut builder = wallet.build_tx();
+        builder
+            .add_utxo(outpoint)
+            .map_err(|_| BumpFeeError::NoChange)?
+            .unspendable(locked)
+            .drain_to(drain_script)
+            .fee_absolute(fee)
+            .enable_rbf();
+        let psbt = builder.finish().map_err(BumpFeeError::CreateTx)?;
+        Ok(FeeBump {
+            psbt: psbt.serialize(),
+            original_fee: parent_fee,
+            fee,
+        })
+    }
+
+    /// Adds what the wallet and the imported descriptors know of the inputs to the psbt and signs
+    /// the ones they can sign if `sign` is set. The inputs that have all of their signatures are
+    /// finalized.
+    pub fn process_psbt(
+        self: Arc<Self>,
+        psbt: &[u8],
+        sign: bool,
+    ) -> Result<ProcessedPsbt, PsbtError> {
+        self.increment_reference_counter();
+        let wallet = self.inner.lock().unwrap();
+        let imports = self.imports.lock().unwrap();
+        let mut psbt = Psbt::deserialize(psbt).map_err(PsbtError::DecodePsbt)?;
+
+        let wallets = std::iter::once(&*wallet).chain(imports.iter().map(|import| &import.wallet));
+        for wallet in wallets {
+            update_psbt_from(wallet, &mut psbt)?;
+            if sign {
+                // The inputs are finalized below since not all of them may be of this wallet.
+                let sign_options = SignOptions {
+                    trust_witness_utxo: true,
+                    try_finalize: false,
+                    ..Default::default()
+                };
+                wallet
+                    .sign(&mut psbt, sign_options)
+                    .map_err(PsbtError::SignTx)?;
+            }
+        }
+
+        let complete = finalize_inputs(&mut psbt);
+        Ok(ProcessedPsbt {
+            psbt: psbt.serialize(),
+            complete,
+        })
+    }
+
+    /// Finalizes the inputs of the psbt that have all of their signatures. The transaction is
+    /// extracted when all of the inputs are finalized.
+    pub fn finalize_psbt(self: Arc<Self>, psbt: &[u8]) -> Result<FinalizedPsbt, PsbtError> {
+        self.increment_reference_counter();
+        let mut psbt = Psbt::deserialize(psbt).map_err(PsbtError::DecodePsbt)?;
+
+        let complete = finalize_inputs(&mut psbt);
+        let mut tx = Vec::<u8>::new();
+        if complete {
+            psbt.clone()
+                .extract_tx()
+                .consensus_encode(&mut tx)
+                .expect("must encode tx");
+        }
+        Ok(FinalizedPsbt {
+            psbt: psbt.serialize(),
+            tx,
+            complete,
+        })
+    }
+
+    /// Returns the outputs of the psbt.
+    pub fn psbt_outputs(self: Arc<Self>, psbt: &[u8]) -> Result<Vec<PsbtOutput>, PsbtError> {
+        self.increment_reference_counter();
+        let psbt = Psbt::deserialize(psbt).map_err(PsbtError::DecodePsbt)?;
+        Ok(psbt
+            .unsigned_tx
+            .output
+            .iter()
+            .map(|txout| PsbtOutput {
+                script_pubkey: txout.script_pubkey.to_bytes(),
+                amount: txout.value,
+            })
+            .collect())
+    }
+
+    /// Adds the silent payment outputs to the psbt the way BIP375 does, with the scan and spend
+    /// keys of the address each of them pays to, and sets their script pubkeys when given. The
+    /// ECDH shares of the inputs whose keys the wallet or the imported descriptors have are then
+    /// added for the scan keys of all of the silent payment outputs of the psbt. The keys and the
+    /// ECDH shares of the inputs are returned along with the silent payment outputs so that the
+    /// output keys can be derived once every eligible input has its shares. DLEQ proofs of the
+    /// shares are neither added nor checked.
+    pub fn silent_payment_psbt(
+        self: Arc<Self>,
+        psbt: &[u8],
+        outputs: Vec<SilentPaymentOutput>,
+    ) -> Result<SilentPaymentPsbt, PsbtError> {
+        self.increment_reference_counter();
+        let wallet = self.inner.lock().unwrap();
+        let imports = self.imports.lock().unwrap();
+        let mut psbt = Psbt::deserialize(psbt).map_err(PsbtError::DecodePsbt)?;
+
+        for output in outputs {
+            let index = output.index as usize;
+            let (txout, psbt_output) = match (
+                psbt.unsigned_tx.output.get_mut(index),
+                psbt.outputs.get_mut(index),
+            ) {
+                (Some(txout), Some(psbt_output)) => (txout, psbt_output),
+                _ => return Err(PsbtError::UnknownOutput),
+            };
+            let info_key = raw::Key {
+                type_value: PSBT_OUT_SP_V0_INFO,
+                key: Vec::new(),
+            };
+            psbt_output
+                .unknown
+                .insert(info_key, [output.scan_key, output.spend_key].concat());
+            if !output.script_pubkey.is_empty() {
+                txout.script_pubkey = ScriptBuf::from_bytes(output.script_pubkey);
+            }
+        }
+        let outputs = silent_payment_outputs(&psbt);
+        if outputs.is_empty() {
+            return Ok(SilentPaymentPsbt {
+                psbt: psbt.serialize(),
+                inputs: Vec::new(),
+                outputs,
+            });
+        }
+        let mut scan_keys = outputs
+            .iter()
+            .map(|output| PublicKey::from_slice(&output.scan_key).expect("must be a valid key"))
+            .collect::<Vec<_>>();
+        scan_keys.sort_unstable();
+        scan_keys.dedup();
+
+        let wallets = std::iter::once(&*wallet)
+            .chain(imports.iter().map(|import| &import.wallet))
+            .collect::<Vec<_>>();
+        let mut key_maps = Vec::new();
+        for wallet in &wallets {
+            update_psbt_from(wallet, &mut psbt)?;
+            for keychain in [KeychainKind::External, KeychainKind::Internal] {
+                key_maps.push(wallet.get_signers(keychain).as_key_map(wallet.secp_ctx()));
+            }
+        }
+
+        let secp = Secp256k1::new();
+        let mut inputs = Vec::with_capacity(psbt.inputs.len());
+        for (txin, input) in psbt.unsigned_tx.input.iter().zip(psbt.inputs.iter_mut()) {
+            let public_key = silent_payment_input_key(txin, input)?;
+            let mut ecdh_shares = Vec::new();
+            if let Some(public_key) = &public_key {
+                let share_keys = scan_keys
+                    .iter()
+                    .map(|scan_key| raw::Key {
+                        type_value: PSBT_IN_SP_ECDH_SHARE,
+                        key: scan_key.serialize().to_vec(),
+                    })
+                    .collect::<Vec<_>>();
+                let secret_key = if share_keys.iter().any(|key| !input.unknown.contains_key(key)) {
+                    silent_payment_secret_key(&secp, &key_maps, input, public_key)
+                } else {
+                    None
+                };
+                for (scan_key, share_key) in scan_keys.iter().zip(share_keys) {
+                    if let (Some(secret_key), false) =
+                        (secret_key, input.unknown.contains_key(&share_key))
+                    {
+                        let share = scan_key
+                            .mul_tweak(&secp, &Scalar::from(secret_key))
+                            .expect("secret key must be valid");
+                        input
+                            .unknown
+                            .insert(share_key.clone(), share.serialize().to_vec());
+                    }
+                    if let Some(share) = input.unknown.get(&share_key) {
+                        ecdh_shares.push(EcdhShare {
+                            scan_key: share_key.key,
+                            share: share.clone(),
+                        });
+                    }
+                }
+            }
+            inputs.push(SilentPaymentInput {
+                txid: txin.previous_output.txid.to_byte_array().to_vec(),
+                vout: txin.previous_output.vout,
+                public_key: public_key.map_or(Vec::new(), |key| key.serialize().to_vec()),
+                ecdh_shares,
+            });
+        }
+
+        Ok(SilentPaymentPsbt {
+            psbt: psbt.serialize(),
+            inputs,
+            outputs,
+        })
+    }
+
+    /// Returns the outputs spent by the psbt.
+    pub fn psbt_inputs(self: Arc<Self>, psbt: &[u8]) -> Result<Vec<PsbtInput>, PsbtError> {
+        self.increment_reference_counter();
+        let psbt = Psbt::deserialize(psbt).map_err(PsbtError::DecodePsbt)?;
+        Ok(psbt
+            .unsigned_tx
+            .input
+            .iter()
+            .map(|txin| PsbtInput {
+                txid: txin.previous_output.txid.to_byte_array().to_vec(),
+                vout: txin.previous_output.vout,
+            })
+            .collect())
+    }
+
+    /// Adds the outputs spent by the psbt to its inputs, along with the transactions of the
+    /// outputs that aren't taproot ones. The transactions that are missing from `prev_txs` are
+    /// skipped.
+    pub fn update_psbt(
+        self: Arc<Self>,
+        psbt: &[u8],
+        prev_txs: Vec<Vec<u8>>,
+    ) -> Result<Vec<u8>, PsbtError> {
+        self.increment_reference_counter();
+        let mut psbt = Psbt::deserialize(psbt).map_err(PsbtError::DecodePsbt)?;
+        let prev_txs = prev_txs
+            .iter()
+            .map(|tx| {
+                Transaction::consensus_decode_from_finite_reader(&mut tx.reader())
+                    .map(|tx| (tx.txid(), tx))
+            })
+            .collect::<Result<HashMap<_, _>, _>>()
+            .map_err(PsbtError::DecodeTx)?;
+
+        for (txin, input) in psbt.unsigned_tx.input.iter().zip(psbt.inputs.iter_mut()) {
+            let outpoint = txin.previous_output;
+            let prev_tx = match prev_txs.get(&outpoint.txid) {
+                Some(prev_tx) => prev_tx,
+                None => continue,
+            };
+            let txout = match prev_tx.output.get(outpoint.vout as usize) {
+                Some(txout) => txout,
+                None => continue,
+            };
+            if txout.script_pubkey.is_witness_program() {
+                input.witness_utxo = Some(txout.clone());
+            }
+            if !txout.script_pubkey.is_v1_p2tr() {
+                input.non_witness_utxo = Some(prev_tx.clone());
+            }
+        }
+        Ok(psbt.serialize())
+    }
+
     pub fn mnemonic_words(self: Arc<Self>) -> Vec<String> {
         self.increment_reference_counter();
-        self.header.lock().unwrap().mnemonic_words()
+        self.keys.lock().unwrap().mnemonic_words()
     }
 
     pub fn transactions(self: Arc<Self>) -> Vec<TxInfo> {
         self.increment_reference_counter();
         let wallet = self.inner.lock().unwrap();
-        let height = wallet.latest_checkpoint().height();
-        let mut txs = wallet
-            .transactions()
-            .map(|ctx| {
-                let txid = ctx.tx_node.txid.to_byte_array().to_vec();
-                let mut tx = Vec::<u8>::new();
-                ctx.tx_node
-                    .tx
-                    .consensus_encode(&mut tx)
-                    .expect("must encode");
-                let (spent, received) = wallet.sent_and_received(ctx.tx_node.tx);
-                let confirmations = ctx
-                    .chain_position
-                    .confirmation_height_upper_bound()
-                    .map_or(0, |conf_height| (1 + height).saturating_sub(conf_height));
-                TxInfo {
-                    txid,
-                    tx,
-                    spent,
-                    received,
-                    confirmations,
+        let imports = self.imports.lock().unwrap();
+        let mut txs = wallet_transactions(&wallet);
+
+        // A transaction may involve both the wallet and the imported descriptors, so the amounts
+        // of the transactions that are already listed are added up.
+        let mut positions = txs
+            .iter()
+            .enumerate()
+            .map(|(i, tx)| (tx.txid.clone(), i))
+            .collect::<HashMap<_, _>>();
+        for import in imports.iter() {
+            for tx in wallet_transactions(&import.wallet) {
+                match positions.get(&tx.txid) {
+                    Some(&i) => {
+                        txs[i].spent += tx.spent;
+                        txs[i].received += tx.received;
+                    }
+                    None => {
+                        positions.insert(tx.txid.clone(), txs.len());
+                        txs.push(tx);
+                    }
                 }
-            })
-            .collect::<Vec<_>>();
+            }
+        }
         txs.sort_unstable_by_key(|tx| Reverse(tx.confirmations));
         txs
     }
@@ -422,24 +1794,11 @@ impl Wallet {
     pub fn utxos(self: Arc<Self>) -> Vec<UtxoInfo> {
         self.increment_reference_counter();
         let wallet = self.inner.lock().unwrap();
-        let wallet_height = wallet.latest_checkpoint().height();
-        let mut utxos = wallet
-            .list_unspent()
-            .map(|utxo| UtxoInfo {
-                txid: utxo.outpoint.txid.to_byte_array().to_vec(),
-                vout: utxo.outpoint.vout,
-                amount: utxo.txout.value,
-                script_pubkey: utxo.txout.script_pubkey.to_bytes(),
-                is_change: utxo.keychain == KeychainKind::Internal,
-                derivation_index: utxo.derivation_index,
-                confirmations: match utxo.confirmation_time {
-                    bdk::chain::ConfirmationTime::Confirmed { height, .. } => {
-                        (1 + wallet_height).saturating_sub(height)
-                    }
-                    bdk::chain::ConfirmationTime::Unconfirmed { .. } => 0,
-                },
-            })
-            .collect::<Vec<_>>();
+        let imports = self.imports.lock().unwrap();
+        let mut utxos = wallet_utxos(&wallet);
+        for import in imports.iter() {
+            utxos.extend(wallet_utxos(&import.wallet));
+        }
         utxos.sort_unstable_by_key(|utxo| Reverse(utxo.confirmations));
         utxos
     }
@@ -455,6 +1814,85 @@ pub struct BlockId {
     pub hash: Vec<u8>,
 }
 
+pub struct PsbtInput {
+    pub txid: Vec<u8>,
+    pub vout: u32,
+}
+
+impl PsbtInput {
+    fn outpoint(&self) -> OutPoint {
+        OutPoint {
+            txid: Txid::from_slice(&self.txid).expect("txid must be 32 bytes"),
+            vout: self.vout,
+        }
+    }
+}
+
+pub struct PsbtOutput {
+    pub script_pubkey: Vec<u8>,
+    pub amount: u64,
+}
+
+pub struct FundedPsbt {
+    pub psbt: Vec<u8>,
+    /// Fee paid by the psbt in satoshis.
+    pub fee: u64,
+    /// Index of the change output, or -1 if there is none.
+    pub change_pos: i32,
+}
+
+pub struct FeeBump {
+    pub psbt: Vec<u8>,
+    /// Fee paid by the transaction whose fee is bumped in satoshis.
+    pub original_fee: u64,
+    /// Fee paid by the psbt in satoshis.
+    pub fee: u64,
+}
+
+pub struct ProcessedPsbt {
+    pub psbt: Vec<u8>,
+    /// Whether all of the inputs are finalized.
+    pub complete: bool,
+}
+
+pub struct FinalizedPsbt {
+    pub psbt: Vec<u8>,
+    /// The extracted transaction, which is empty unless the psbt is complete.
+    pub tx: Vec<u8>,
+    /// Whether all of the inputs are finalized.
+    pub complete: bool,
+}
+
+pub struct EcdhShare {
+    /// Scan key the share is of.
+    pub scan_key: Vec<u8>,
+    /// The secret key of the input multiplied by the scan key.
+    pub share: Vec<u8>,
+}
+
+pub struct SilentPaymentInput {
+    pub txid: Vec<u8>,
+    pub vout: u32,
+    /// The public key BIP352 takes from the input, which is empty if the input isn't eligible.
+    pub public_key: Vec<u8>,
+    /// ECDH shares of the input for the scan keys of the silent payment outputs it has them for.
+    pub ecdh_shares: Vec<EcdhShare>,
+}
+
+pub struct SilentPaymentOutput {
+    pub index: u32,
+    pub scan_key: Vec<u8>,
+    pub spend_key: Vec<u8>,
+    /// Script pubkey of the output, which is left as it is when empty.
+    pub script_pubkey: Vec<u8>,
+}
+
+pub struct SilentPaymentPsbt {
+    pub psbt: Vec<u8>,
+    pub inputs: Vec<SilentPaymentInput>,
+    pub outputs: Vec<SilentPaymentOutput>,
+}
+
 pub struct TxInfo {
     pub txid: Vec<u8>,
     pub tx: Vec<u8>,
@@ -476,6 +1914,16 @@ pub struct UtxoInfo {
     pub confirmations: u32,
 }
 
+pub struct DescriptorInfo {
+    pub descriptor: String,
+    /// Whether this is the descriptor of a change keychain.
+    pub internal: bool,
+    /// The height the descriptor is tracked from.
+    pub birthday: u32,
+    /// The index of the next address to be revealed.
+    pub next_index: u32,
+}
+
 pub struct MempoolTx {
     pub tx: Vec<u8>,
     pub added_unix: u64,
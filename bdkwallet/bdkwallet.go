@@ -7,7 +7,9 @@ import "C"
 
 import (
 	"bytes"
+	"fmt"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/utreexo/utreexod/bdkwallet/bdkgo"
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg"
@@ -22,19 +24,26 @@ func init() {
 
 type BDKWalletFactory struct{}
 
-func (*BDKWalletFactory) Create(dbPath string, chainParams *chaincfg.Params) (Wallet, error) {
+// bdkNetwork returns the name of the network of the chain params the way bdk
+// takes it.
+func bdkNetwork(chainParams *chaincfg.Params) string {
 	// used for the address format
 	// this is parsed as `bitcoin::Network` in rust
 	// supported strings: bitcoin, testnet, signet, regtest
 	// https://docs.rs/bitcoin/latest/bitcoin/network/enum.Network.html
 	network := chainParams.Name
-	log.Infof("Creating wallet with network: %v", network)
 	switch network {
 	case "mainnet":
 		network = "bitcoin"
 	case "testnet3":
 		network = "testnet"
 	}
+	return network
+}
+
+func (*BDKWalletFactory) Create(dbPath string, chainParams *chaincfg.Params) (Wallet, error) {
+	network := bdkNetwork(chainParams)
+	log.Infof("Creating wallet with network: %v", network)
 
 	genesisHash := chainParams.GenesisHash.CloneBytes()
 
@@ -50,6 +59,27 @@ func (*BDKWalletFactory) Create(dbPath string, chainParams *chaincfg.Params) (Wa
 	return &BDKWallet{*inner}, nil
 }
 
+func (*BDKWalletFactory) CreateWatchOnly(dbPath string, chainParams *chaincfg.Params,
+	descriptor string, birthday uint32) (Wallet, error) {
+
+	network := bdkNetwork(chainParams)
+	log.Infof("Creating watch-only wallet with network: %v", network)
+
+	genesisHash := chainParams.GenesisHash.CloneBytes()
+
+	inner, err := bdkgo.WalletCreateWatchOnly(dbPath, network, genesisHash,
+		descriptor, birthday)
+	if err != nil {
+		return nil, err
+	}
+
+	// This increments the reference count of the Arc pointer in rust. We are
+	// doing this due to a bug with uniffi-bindgen-go's generated code
+	// decrementing this count too aggressively.
+	inner.IncrementReferenceCounter()
+	return &BDKWallet{*inner}, nil
+}
+
 func (*BDKWalletFactory) Load(dbPath string) (Wallet, error) {
 	inner, err := bdkgo.WalletLoad(dbPath)
 	if err != nil {
@@ -146,17 +176,50 @@ func (w *BDKWallet) ApplyBlock(block *btcutil.Block) error {
 		return err
 	}
 
+	return logRelevantTxids(res, block)
+}
+
+// ApplyBlockToImports updates only the imported descriptors with the given
+// block. This is used to rescan the blocks from the birthdays of the
+// descriptors.
+func (w *BDKWallet) ApplyBlockToImports(block *btcutil.Block) error {
+	var b bytes.Buffer
+	if err := block.MsgBlock().BtcEncode(&b, wire.FeeFilterVersion, wire.WitnessEncoding); err != nil {
+		return err
+	}
+	res, err := w.inner.ApplyBlockToImports(uint32(block.Height()), b.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return logRelevantTxids(res, block)
+}
+
+// logRelevantTxids logs the relevant txs the wallet found in the block.
+func logRelevantTxids(res bdkgo.ApplyResult, block *btcutil.Block) error {
 	for _, txid := range res.RelevantTxids {
 		hash, err := chainhash.NewHash(txid)
 		if err != nil {
 			return err
 		}
 		log.Infof("Found relevant tx %v in block %v:%v.",
-			hash.String(), bheight, block.Hash().String())
+			hash.String(), block.Height(), block.Hash().String())
 	}
 	return nil
 }
 
+// ImportsHeight returns the block height up to which all the imported
+// descriptors have been updated.
+func (w *BDKWallet) ImportsHeight() uint {
+	return uint(w.inner.ImportsHeight())
+}
+
+// Scripts returns the scripts of the wallet and of its imported descriptors,
+// including the ones of the addresses that haven't been revealed yet.
+func (w *BDKWallet) Scripts() [][]byte {
+	return w.inner.Scripts()
+}
+
 // ApplyMempoolTransactions updates the wallet with the given mempool transactions.
 func (w *BDKWallet) ApplyMempoolTransactions(txns []*mempool.TxDesc) error {
 	if len(txns) == 0 {
@@ -187,8 +250,49 @@ func (w *BDKWallet) ApplyMempoolTransactions(txns []*mempool.TxDesc) error {
 	return nil
 }
 
-// CreateTx creates and signs a transaction spending from the wallet.
-func (w *BDKWallet) CreateTx(feerate float32, recipients []Recipient) ([]byte, error) {
+// genCoinSelection maps the coin selection strategies to the ones of bdkgo.
+var genCoinSelection = map[CoinSelection]bdkgo.CoinSelection{
+	CoinSelectionBranchAndBound:     bdkgo.CoinSelectionBranchAndBound,
+	CoinSelectionKnapsack:           bdkgo.CoinSelectionKnapsack,
+	CoinSelectionAvoidPartialSpends: bdkgo.CoinSelectionAvoidPartialSpends,
+}
+
+// WatchOnly returns whether the wallet only has the public keys of its
+// keychains.
+func (w *BDKWallet) WatchOnly() bool {
+	return w.inner.WatchOnly()
+}
+
+// Birthday returns the block height the wallet is tracked from. It's zero
+// unless the wallet is watch-only.
+func (w *BDKWallet) Birthday() uint {
+	return uint(w.inner.Birthday())
+}
+
+// genOutPoints converts the outpoints to the generated psbt inputs.
+func genOutPoints(outpoints []wire.OutPoint) []bdkgo.PsbtInput {
+	genInputs := make([]bdkgo.PsbtInput, 0, len(outpoints))
+	for _, op := range outpoints {
+		genInputs = append(genInputs, bdkgo.PsbtInput{
+			Txid: op.Hash.CloneBytes(),
+			Vout: op.Index,
+		})
+	}
+	return genInputs
+}
+
+// SetLockedUTXOs sets the unspent outputs of the wallet that the coin
+// selection doesn't pick.
+func (w *BDKWallet) SetLockedUTXOs(outpoints []wire.OutPoint) {
+	w.inner.SetLockedUtxos(genOutPoints(outpoints))
+}
+
+// CreateTx creates and signs a transaction spending from the wallet. Exactly
+// the inputs are spent when they're given. Otherwise the unlocked outputs of
+// the wallet that fund it are picked with the coin selection strategy.
+func (w *BDKWallet) CreateTx(feerate float32, recipients []Recipient,
+	inputs []wire.OutPoint, coinSelection CoinSelection) ([]byte, error) {
+
 	genRecipients := make([]bdkgo.Recipient, 0, len(recipients))
 	for _, r := range recipients {
 		genRecipients = append(genRecipients, bdkgo.Recipient{
@@ -196,7 +300,254 @@ func (w *BDKWallet) CreateTx(feerate float32, recipients []Recipient) ([]byte, e
 			Amount:  uint64(r.Amount),
 		})
 	}
-	return w.inner.CreateTx(feerate, genRecipients)
+	return w.inner.CreateTx(feerate, genRecipients, genOutPoints(inputs),
+		genCoinSelection[coinSelection])
+}
+
+// CreatePsbt creates a psbt paying to the outputs that is funded by the
+// wallet. The inputs are always spent and, when addInputs is set, more unlocked
+// outputs of the wallet are added as needed with the coin selection strategy.
+// The inputs may be outputs of the imported descriptors, including taproot
+// descriptors spent through a leaf of their script tree. A zero locktime lets
+// the wallet pick it.
+func (w *BDKWallet) CreatePsbt(inputs []wire.OutPoint, outputs []*wire.TxOut,
+	locktime uint32, feerate float32, replaceable, addInputs bool,
+	coinSelection CoinSelection) (FundedPsbt, error) {
+
+	genInputs := genOutPoints(inputs)
+	genOutputs := make([]bdkgo.PsbtOutput, 0, len(outputs))
+	for _, txOut := range outputs {
+		genOutputs = append(genOutputs, bdkgo.PsbtOutput{
+			ScriptPubkey: txOut.PkScript,
+			Amount:       uint64(txOut.Value),
+		})
+	}
+
+	res, err := w.inner.CreatePsbt(genInputs, genOutputs, locktime, feerate,
+		replaceable, addInputs, genCoinSelection[coinSelection])
+	if err != nil {
+		return FundedPsbt{}, err
+	}
+	return FundedPsbt{
+		Psbt:      res.Psbt,
+		Fee:       btcutil.Amount(res.Fee),
+		ChangePos: int(res.ChangePos),
+	}, nil
+}
+
+// CreateSweep creates a psbt sweeping the unspent outputs of the wallet to the
+// script with no change output.  All of the unlocked outputs are swept unless
+// the inputs are given, in which case exactly those are spent.
+func (w *BDKWallet) CreateSweep(feerate float32, pkScript []byte,
+	inputs []wire.OutPoint) (FundedPsbt, error) {
+
+	res, err := w.inner.CreateSweep(feerate, pkScript, genOutPoints(inputs))
+	if err != nil {
+		return FundedPsbt{}, err
+	}
+	return FundedPsbt{
+		Psbt:      res.Psbt,
+		Fee:       btcutil.Amount(res.Fee),
+		ChangePos: int(res.ChangePos),
+	}, nil
+}
+
+// BumpFee creates a psbt replacing the unconfirmed transaction of the wallet
+// with one that pays the fee rate as BIP125 allows.
+func (w *BDKWallet) BumpFee(txid chainhash.Hash, feerate float32) (FeeBump, error) {
+	res, err := w.inner.BumpFee(txid[:], feerate)
+	if err != nil {
+		return FeeBump{}, err
+	}
+	return FeeBump{
+		Psbt:        res.Psbt,
+		OriginalFee: btcutil.Amount(res.OriginalFee),
+		Fee:         btcutil.Amount(res.Fee),
+	}, nil
+}
+
+// CreateCpfp creates a psbt spending an output of the wallet of the
+// unconfirmed transaction so that the transaction and the child pay the fee
+// rate together.
+func (w *BDKWallet) CreateCpfp(txid chainhash.Hash, feerate float32) (FeeBump, error) {
+	res, err := w.inner.CreateCpfp(txid[:], feerate)
+	if err != nil {
+		return FeeBump{}, err
+	}
+	return FeeBump{
+		Psbt:        res.Psbt,
+		OriginalFee: btcutil.Amount(res.OriginalFee),
+		Fee:         btcutil.Amount(res.Fee),
+	}, nil
+}
+
+// ProcessPsbt adds what the wallet knows of the inputs of the psbt to it and
+// signs the inputs it can if sign is set. It returns the psbt along with
+// whether all of its inputs are finalized.
+func (w *BDKWallet) ProcessPsbt(psbt []byte, sign bool) ([]byte, bool, error) {
+	res, err := w.inner.ProcessPsbt(psbt, sign)
+	if err != nil {
+		return nil, false, err
+	}
+	return res.Psbt, res.Complete, nil
+}
+
+// FinalizePsbt finalizes the inputs of the psbt that have all of their
+// signatures. The returned transaction is nil unless all of the inputs are
+// finalized.
+func (w *BDKWallet) FinalizePsbt(psbt []byte) ([]byte, *btcutil.Tx, error) {
+	res, err := w.inner.FinalizePsbt(psbt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !res.Complete {
+		return res.Psbt, nil, nil
+	}
+	tx, err := btcutil.NewTxFromBytes(res.Tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.Psbt, tx, nil
+}
+
+// PsbtInputs returns the outpoints spent by the psbt.
+func (w *BDKWallet) PsbtInputs(psbt []byte) ([]wire.OutPoint, error) {
+	genOut, err := w.inner.PsbtInputs(psbt)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]wire.OutPoint, 0, len(genOut))
+	for _, input := range genOut {
+		out = append(out, wire.OutPoint{
+			Hash:  hashFromBytes(input.Txid),
+			Index: input.Vout,
+		})
+	}
+	return out, nil
+}
+
+// PsbtOutputs returns the outputs of the psbt.
+func (w *BDKWallet) PsbtOutputs(psbt []byte) ([]*wire.TxOut, error) {
+	genOut, err := w.inner.PsbtOutputs(psbt)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*wire.TxOut, 0, len(genOut))
+	for _, output := range genOut {
+		out = append(out, wire.NewTxOut(int64(output.Amount), output.ScriptPubkey))
+	}
+	return out, nil
+}
+
+// SilentPaymentPsbt adds the silent payment outputs to the psbt along with the
+// ECDH shares of the inputs the wallet has the keys of. It returns the psbt
+// along with its silent payment outputs and the keys and ECDH shares of its
+// inputs.
+func (w *BDKWallet) SilentPaymentPsbt(psbt []byte,
+	outputs []SilentPaymentOutput) (SilentPaymentPsbt, error) {
+
+	genOutputs := make([]bdkgo.SilentPaymentOutput, 0, len(outputs))
+	for _, output := range outputs {
+		genOutputs = append(genOutputs, bdkgo.SilentPaymentOutput{
+			Index:        uint32(output.Index),
+			ScanKey:      output.ScanKey.SerializeCompressed(),
+			SpendKey:     output.SpendKey.SerializeCompressed(),
+			ScriptPubkey: output.PkScript,
+		})
+	}
+
+	res, err := w.inner.SilentPaymentPsbt(psbt, genOutputs)
+	if err != nil {
+		return SilentPaymentPsbt{}, err
+	}
+	sp := SilentPaymentPsbt{
+		Psbt:    res.Psbt,
+		Inputs:  make([]SilentPaymentInput, 0, len(res.Inputs)),
+		Outputs: make([]SilentPaymentOutput, 0, len(res.Outputs)),
+	}
+	for _, input := range res.Inputs {
+		spInput := SilentPaymentInput{
+			OutPoint: wire.OutPoint{
+				Hash:  hashFromBytes(input.Txid),
+				Index: input.Vout,
+			},
+			ECDHShares: make(map[[btcec.PubKeyBytesLenCompressed]byte]*btcec.PublicKey,
+				len(input.EcdhShares)),
+		}
+		if len(input.PublicKey) > 0 {
+			spInput.PublicKey, err = btcec.ParsePubKey(input.PublicKey)
+			if err != nil {
+				return SilentPaymentPsbt{}, err
+			}
+		}
+		for _, share := range input.EcdhShares {
+			var scanKey [btcec.PubKeyBytesLenCompressed]byte
+			copy(scanKey[:], share.ScanKey)
+			spInput.ECDHShares[scanKey], err = btcec.ParsePubKey(share.Share)
+			if err != nil {
+				return SilentPaymentPsbt{}, fmt.Errorf("invalid ECDH share "+
+					"of input %v: %v", spInput.OutPoint, err)
+			}
+		}
+		sp.Inputs = append(sp.Inputs, spInput)
+	}
+	for _, output := range res.Outputs {
+		scanKey, err := btcec.ParsePubKey(output.ScanKey)
+		if err != nil {
+			return SilentPaymentPsbt{}, err
+		}
+		spendKey, err := btcec.ParsePubKey(output.SpendKey)
+		if err != nil {
+			return SilentPaymentPsbt{}, err
+		}
+		sp.Outputs = append(sp.Outputs, SilentPaymentOutput{
+			Index:    int(output.Index),
+			ScanKey:  scanKey,
+			SpendKey: spendKey,
+			PkScript: output.ScriptPubkey,
+		})
+	}
+	return sp, nil
+}
+
+// UpdatePsbt adds the outputs spent by the psbt to its inputs, along with the
+// transactions of the outputs that aren't taproot ones. The inputs spending
+// from transactions missing from prevTxs are left as they are.
+func (w *BDKWallet) UpdatePsbt(psbt []byte, prevTxs []*btcutil.Tx) ([]byte, error) {
+	genTxs := make([][]byte, 0, len(prevTxs))
+	for _, tx := range prevTxs {
+		var buf bytes.Buffer
+		err := tx.MsgTx().Serialize(&buf)
+		if err != nil {
+			return nil, err
+		}
+		genTxs = append(genTxs, buf.Bytes())
+	}
+	return w.inner.UpdatePsbt(psbt, genTxs)
+}
+
+// ImportDescriptor imports a ranged wpkh or tr output descriptor to be tracked
+// from the birthday height on. A multi-path descriptor with two paths is
+// imported with the second path as its change keychain.
+func (w *BDKWallet) ImportDescriptor(descriptor string, birthday uint32) error {
+	return w.inner.ImportDescriptor(descriptor, birthday)
+}
+
+// Descriptors returns the descriptors of the wallet followed by the imported
+// ones. The secret keys of the descriptors are included if includePrivate is
+// set.
+func (w *BDKWallet) Descriptors(includePrivate bool) []DescriptorInfo {
+	genOut := w.inner.Descriptors(includePrivate)
+	out := make([]DescriptorInfo, 0, len(genOut))
+	for _, info := range genOut {
+		out = append(out, DescriptorInfo{
+			Descriptor: info.Descriptor,
+			Internal:   info.Internal,
+			Birthday:   uint(info.Birthday),
+			NextIndex:  uint(info.NextIndex),
+		})
+	}
+	return out
 }
 
 // MnemonicWords returns the mnemonic words to backup the wallet.
@@ -4,6 +4,7 @@ package bdkwallet
 
 import (
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/utreexo/utreexod/chaincfg"
@@ -72,3 +73,102 @@ func TestCreateAndLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateWatchOnlyAndLoad(t *testing.T) {
+	factory, err := factory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	wallet, err := factory.Create(filepath.Join(dir, "bdk.db"), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	descriptors := wallet.Descriptors(false)
+	if len(descriptors) == 0 {
+		t.Fatal("wallet should have descriptors")
+	}
+
+	// track the external keychain of the wallet with a watch-only wallet
+	dbPath := filepath.Join(dir, "watchonly.db")
+	{
+		watchOnly, err := factory.CreateWatchOnly(dbPath, &chaincfg.MainNetParams,
+			descriptors[0].Descriptor, 10)
+		if err != nil {
+			t.Fatalf("failed to create watch-only db: %v", err)
+		}
+		if !watchOnly.WatchOnly() {
+			t.Fatal("wallet should be watch-only")
+		}
+		if watchOnly.Birthday() != 10 {
+			t.Fatalf("birthday should be 10: %v", watchOnly.Birthday())
+		}
+		if words := watchOnly.MnemonicWords(); len(words) != 0 {
+			t.Fatalf("watch-only wallet should have no mnemonic: %v", words)
+		}
+
+		for i := uint32(0); i < 5; i++ {
+			_, addr, err := wallet.PeekAddress(i)
+			if err != nil {
+				t.Fatalf("failed to peek addr %v: %v", i, err)
+			}
+			_, watchOnlyAddr, err := watchOnly.PeekAddress(i)
+			if err != nil {
+				t.Fatalf("failed to peek watch-only addr %v: %v", i, err)
+			}
+			if addr.String() != watchOnlyAddr.String() {
+				t.Fatalf("addr %v should be the same: %v != %v", i, addr,
+					watchOnlyAddr)
+			}
+		}
+	}
+
+	// load watch-only wallet and make sure it stays watch-only
+	{
+		watchOnly, err := factory.Load(dbPath)
+		if err != nil {
+			t.Fatalf("failed to load watch-only wallet: %v", err)
+		}
+		if !watchOnly.WatchOnly() || watchOnly.Birthday() != 10 {
+			t.Fatal("loaded wallet should be watch-only with a birthday of 10")
+		}
+	}
+}
+
+func TestImportScriptTreeDescriptor(t *testing.T) {
+	factory, err := factory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wallet, err := factory.Create(filepath.Join(t.TempDir(), "bdk.db"), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("failed to create db: %v", err)
+	}
+	descriptors := wallet.Descriptors(false)
+	if len(descriptors) < 2 {
+		t.Fatal("wallet should have an external and an internal descriptor")
+	}
+
+	// spend the outputs of the keys of the wallet through the leaves of a
+	// tree under an internal key without a known discrete logarithm
+	keys := make([]string, 2)
+	for i := range keys {
+		key := strings.TrimPrefix(descriptors[i].Descriptor, "tr(")
+		keys[i] = key[:strings.Index(key, ")")]
+	}
+	descriptor := "tr(50929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0," +
+		"{pk(" + keys[0] + "),pk(" + keys[1] + ")})"
+	if err := wallet.ImportDescriptor(descriptor, 0); err != nil {
+		t.Fatalf("failed to import script tree descriptor: %v", err)
+	}
+
+	imported := wallet.Descriptors(false)
+	if len(imported) != len(descriptors)+1 {
+		t.Fatalf("got %d descriptors, want %d", len(imported), len(descriptors)+1)
+	}
+	if got := imported[len(descriptors)].Descriptor; !strings.HasPrefix(got, descriptor) {
+		t.Fatalf("imported descriptor %s should be %s", got, descriptor)
+	}
+}
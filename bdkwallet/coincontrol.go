@@ -0,0 +1,186 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/utreexo/utreexod/wire"
+)
+
+// lockedFileSuffix is the suffix the file of the locked unspent outputs of a
+// wallet has after the name of the wallet file.
+const lockedFileSuffix = ".locked"
+
+var (
+	// ErrNotUnspent is returned when an output that isn't an unspent
+	// output of the wallet is locked.
+	ErrNotUnspent = errors.New("not an unspent output of the wallet")
+
+	// ErrAlreadyLocked is returned when a locked output is locked again.
+	ErrAlreadyLocked = errors.New("output already locked")
+
+	// ErrNotLocked is returned when an output that isn't locked is
+	// unlocked.
+	ErrNotLocked = errors.New("output not locked")
+)
+
+// loadLocked loads the locked outputs of the wallet stored at the path.  A
+// wallet that never had any locked outputs has no file of them.
+func loadLocked(path string) (map[wire.OutPoint]struct{}, error) {
+	locked := make(map[wire.OutPoint]struct{})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return locked, nil
+		}
+		return nil, err
+	}
+	var outpoints []wire.OutPoint
+	if err := json.Unmarshal(data, &outpoints); err != nil {
+		return nil, err
+	}
+	for _, op := range outpoints {
+		locked[op] = struct{}{}
+	}
+	return locked, nil
+}
+
+// sortedLocked returns the locked outputs ordered by their outpoints.
+//
+// This function MUST be called with the locked mutex held.
+func (m *Manager) sortedLocked() []wire.OutPoint {
+	outpoints := make([]wire.OutPoint, 0, len(m.locked))
+	for op := range m.locked {
+		outpoints = append(outpoints, op)
+	}
+	sort.Slice(outpoints, func(i, j int) bool {
+		if cmp := bytes.Compare(outpoints[i].Hash[:], outpoints[j].Hash[:]); cmp != 0 {
+			return cmp < 0
+		}
+		return outpoints[i].Index < outpoints[j].Index
+	})
+	return outpoints
+}
+
+// updateLocked writes the locked outputs of the wallet to their file and
+// keeps the wallet from picking them in the coin selection.
+//
+// This function MUST be called with the locked mutex held.
+func (m *Manager) updateLocked() error {
+	outpoints := m.sortedLocked()
+	data, err := json.Marshal(outpoints)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(m.dbPath()+lockedFileSuffix, data); err != nil {
+		return err
+	}
+	m.Wallet.SetLockedUTXOs(outpoints)
+	return nil
+}
+
+// LockUnspent locks the unspent outputs of the wallet at the outpoints so that
+// they're only spent when they're explicitly given as inputs, or unlocks them
+// when unlock is set.  Either all of the outputs are locked or unlocked or none
+// of them are.
+func (m *Manager) LockUnspent(unlock bool, outpoints []wire.OutPoint) error {
+	unspent := make(map[wire.OutPoint]struct{})
+	if !unlock {
+		for _, utxo := range m.UTXOs() {
+			unspent[wire.OutPoint{Hash: utxo.Txid, Index: uint32(utxo.Vout)}] = struct{}{}
+		}
+	}
+
+	m.lockedMtx.Lock()
+	defer m.lockedMtx.Unlock()
+
+	for _, op := range outpoints {
+		_, locked := m.locked[op]
+		switch {
+		case unlock && !locked:
+			return fmt.Errorf("%v: %w", op, ErrNotLocked)
+		case !unlock && locked:
+			return fmt.Errorf("%v: %w", op, ErrAlreadyLocked)
+		}
+		if _, ok := unspent[op]; !unlock && !ok {
+			return fmt.Errorf("%v: %w", op, ErrNotUnspent)
+		}
+	}
+	for _, op := range outpoints {
+		if unlock {
+			delete(m.locked, op)
+		} else {
+			m.locked[op] = struct{}{}
+		}
+	}
+	if err := m.updateLocked(); err != nil {
+		for _, op := range outpoints {
+			if unlock {
+				m.locked[op] = struct{}{}
+			} else {
+				delete(m.locked, op)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// LockInputs locks the outpoints that are unspent outputs of the wallet and
+// aren't locked yet, such as the inputs of a psbt the wallet has funded, and
+// skips the others.
+func (m *Manager) LockInputs(outpoints []wire.OutPoint) error {
+	unspent := make(map[wire.OutPoint]struct{})
+	for _, utxo := range m.UTXOs() {
+		unspent[wire.OutPoint{Hash: utxo.Txid, Index: uint32(utxo.Vout)}] = struct{}{}
+	}
+
+	m.lockedMtx.Lock()
+	defer m.lockedMtx.Unlock()
+
+	var added []wire.OutPoint
+	for _, op := range outpoints {
+		_, ok := unspent[op]
+		if _, locked := m.locked[op]; !ok || locked {
+			continue
+		}
+		m.locked[op] = struct{}{}
+		added = append(added, op)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if err := m.updateLocked(); err != nil {
+		for _, op := range added {
+			delete(m.locked, op)
+		}
+		return err
+	}
+	return nil
+}
+
+// UnlockAllUnspent unlocks all of the locked outputs of the wallet.
+func (m *Manager) UnlockAllUnspent() error {
+	m.lockedMtx.Lock()
+	defer m.lockedMtx.Unlock()
+
+	previous := m.locked
+	m.locked = make(map[wire.OutPoint]struct{})
+	if err := m.updateLocked(); err != nil {
+		m.locked = previous
+		return err
+	}
+	return nil
+}
+
+// LockedUnspent returns the locked outputs of the wallet ordered by their
+// outpoints.
+func (m *Manager) LockedUnspent() []wire.OutPoint {
+	m.lockedMtx.Lock()
+	defer m.lockedMtx.Unlock()
+	return m.sortedLocked()
+}
@@ -0,0 +1,92 @@
+package bdkwallet
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// lockWallet is a wallet with fixed unspent outputs that records the outputs
+// it's told are locked.
+type lockWallet struct {
+	Wallet
+	utxos  []UTXOInfo
+	locked []wire.OutPoint
+}
+
+func (w *lockWallet) UTXOs() []UTXOInfo                  { return w.utxos }
+func (w *lockWallet) SetLockedUTXOs(ops []wire.OutPoint) { w.locked = ops }
+
+// TestLockUnspent ensures that the locked outputs are persisted in their file
+// and passed on to the wallet and that invalid locks change nothing.
+func TestLockUnspent(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := os.MkdirAll(WalletDir(dataDir), 0700); err != nil {
+		t.Fatal(err)
+	}
+	txid := chainhash.HashH([]byte("tx"))
+	wallet := &lockWallet{utxos: []UTXOInfo{
+		{Txid: txid, Vout: 0}, {Txid: txid, Vout: 1},
+	}}
+	m := &Manager{
+		config: ManagerConfig{DataDir: dataDir, ChainParams: &chaincfg.RegressionNetParams},
+		Wallet: wallet,
+		locked: make(map[wire.OutPoint]struct{}),
+	}
+
+	first, second := wire.OutPoint{Hash: txid, Index: 0}, wire.OutPoint{Hash: txid, Index: 1}
+	if err := m.LockUnspent(false, []wire.OutPoint{second, first}); err != nil {
+		t.Fatal(err)
+	}
+	want := []wire.OutPoint{first, second}
+	if got := m.LockedUnspent(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got locked %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(wallet.locked, want) {
+		t.Fatalf("got wallet locked %v, want %v", wallet.locked, want)
+	}
+
+	// Locking an output that is locked or isn't unspent fails without
+	// locking any of the other outputs.
+	unknown := wire.OutPoint{Hash: txid, Index: 2}
+	if err := m.LockUnspent(false, []wire.OutPoint{unknown}); !errors.Is(err, ErrNotUnspent) {
+		t.Fatalf("got %v, want %v", err, ErrNotUnspent)
+	}
+	if err := m.LockUnspent(true, []wire.OutPoint{first, unknown}); !errors.Is(err, ErrNotLocked) {
+		t.Fatalf("got %v, want %v", err, ErrNotLocked)
+	}
+	if got := m.LockedUnspent(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got locked %v after failed locks, want %v", got, want)
+	}
+
+	if err := m.LockUnspent(true, []wire.OutPoint{first}); err != nil {
+		t.Fatal(err)
+	}
+	locked, err := loadLocked(m.dbPath() + lockedFileSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(locked, map[wire.OutPoint]struct{}{second: {}}) {
+		t.Fatalf("got stored locked %v, want %v", locked, second)
+	}
+
+	// Locking the inputs of a psbt skips the locked and unknown outputs.
+	if err := m.LockInputs([]wire.OutPoint{first, second, unknown}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.LockedUnspent(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got locked %v after locking inputs, want %v", got, want)
+	}
+
+	if err := m.UnlockAllUnspent(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.LockedUnspent()) != 0 || len(wallet.locked) != 0 {
+		t.Fatal("outputs are still locked after unlocking all of them")
+	}
+}
@@ -0,0 +1,176 @@
+package bdkwallet
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/txscript"
+)
+
+// HistoryEntry is a transaction of the wallet along with what accounting tools
+// need to know about it.  The amounts are in satoshis and carry no fiat
+// values.
+type HistoryEntry struct {
+	Txid          chainhash.Hash
+	Height        int32     // height of the block of the tx, 0 while unconfirmed
+	Time          time.Time // time of the block of the tx, zero while unconfirmed
+	Confirmations uint
+	Received      btcutil.Amount // sum of owned outputs
+	Spent         btcutil.Amount // sum of owned inputs
+	Fee           btcutil.Amount // fee of the tx when FeeKnown is set
+	FeeKnown      bool           // whether all of the inputs are known to the wallet
+	Label         string
+	ReceivedBy    []string // addresses of the wallet the tx pays to
+	SentTo        []string // addresses outside of the wallet the tx pays to
+}
+
+// Net returns how much the transaction changed the balance of the wallet by.
+func (e *HistoryEntry) Net() btcutil.Amount {
+	return e.Received - e.Spent
+}
+
+// History returns the transactions of the wallet oldest first, ending with the
+// unconfirmed ones.  The fee of a transaction is only known when the wallet
+// has the transactions of all of its inputs, which it does for the
+// transactions it funded.
+func (m *Manager) History() ([]HistoryEntry, error) {
+	txs, err := m.Transactions()
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make(map[string]struct{})
+	for _, script := range m.Wallet.Scripts() {
+		owned[string(script)] = struct{}{}
+	}
+	m.spMtx.RLock()
+	if m.silentPayments != nil {
+		for _, output := range m.silentPayments.Outputs {
+			owned[string(output.PkScript)] = struct{}{}
+		}
+	}
+	m.spMtx.RUnlock()
+
+	byTxid := make(map[chainhash.Hash]*TxInfo, len(txs))
+	for i := range txs {
+		byTxid[txs[i].Txid] = &txs[i]
+	}
+
+	var best int32
+	if m.config.Chain != nil {
+		best = m.config.Chain.BestSnapshot().Height
+	}
+	entries := make([]HistoryEntry, 0, len(txs))
+	for i := range txs {
+		msgTx := txs[i].Tx.MsgTx()
+		entry := HistoryEntry{
+			Txid:          txs[i].Txid,
+			Confirmations: txs[i].Confirmations,
+			Received:      txs[i].Received,
+			Spent:         txs[i].Spent,
+			Label:         m.TxLabel(txs[i].Txid),
+		}
+		if entry.Confirmations > 0 && m.config.Chain != nil {
+			entry.Height = best - int32(entry.Confirmations) + 1
+			hash, err := m.config.Chain.BlockHashByHeight(entry.Height)
+			if err != nil {
+				return nil, err
+			}
+			header, err := m.config.Chain.HeaderByHash(hash)
+			if err != nil {
+				return nil, err
+			}
+			entry.Time = header.Timestamp
+		}
+
+		var in, out int64
+		entry.FeeKnown = true
+		for _, txIn := range msgTx.TxIn {
+			prev, ok := byTxid[txIn.PreviousOutPoint.Hash]
+			if !ok || int(txIn.PreviousOutPoint.Index) >= len(prev.Tx.MsgTx().TxOut) {
+				entry.FeeKnown = false
+				break
+			}
+			in += prev.Tx.MsgTx().TxOut[txIn.PreviousOutPoint.Index].Value
+		}
+		for _, txOut := range msgTx.TxOut {
+			out += txOut.Value
+
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+				txOut.PkScript, m.config.ChainParams)
+			if err != nil || len(addrs) != 1 {
+				continue
+			}
+			if _, ok := owned[string(txOut.PkScript)]; ok {
+				entry.ReceivedBy = append(entry.ReceivedBy, addrs[0].EncodeAddress())
+			} else {
+				entry.SentTo = append(entry.SentTo, addrs[0].EncodeAddress())
+			}
+		}
+		if entry.FeeKnown {
+			entry.Fee = btcutil.Amount(in - out)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		hi, hj := entries[i].Height, entries[j].Height
+		if (hi == 0) != (hj == 0) {
+			return hj == 0
+		}
+		return hi < hj
+	})
+	return entries, nil
+}
+
+// historyCSVHeader is the header row of the CSV export of the history.
+var historyCSVHeader = []string{
+	"txid", "height", "time", "confirmations", "received", "spent", "net",
+	"fee", "label", "receivedby", "sentto",
+}
+
+// WriteHistoryCSV writes the history as CSV with a header row.  The times are
+// in RFC 3339 format in UTC, the amounts are in satoshis and the addresses of a
+// transaction are separated by spaces.  The fields that aren't known are left
+// empty.
+func WriteHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(historyCSVHeader); err != nil {
+		return err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		var height, timestamp, fee string
+		if entry.Height > 0 {
+			height = strconv.FormatInt(int64(entry.Height), 10)
+			timestamp = entry.Time.UTC().Format(time.RFC3339)
+		}
+		if entry.FeeKnown {
+			fee = strconv.FormatInt(int64(entry.Fee), 10)
+		}
+		err := writer.Write([]string{
+			entry.Txid.String(),
+			height,
+			timestamp,
+			strconv.FormatUint(uint64(entry.Confirmations), 10),
+			strconv.FormatInt(int64(entry.Received), 10),
+			strconv.FormatInt(int64(entry.Spent), 10),
+			strconv.FormatInt(int64(entry.Net()), 10),
+			fee,
+			entry.Label,
+			strings.Join(entry.ReceivedBy, " "),
+			strings.Join(entry.SentTo, " "),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,108 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// historyWallet is a wallet with fixed transactions and scripts.
+type historyWallet struct {
+	Wallet
+	txs     []TxInfo
+	scripts [][]byte
+}
+
+func (w *historyWallet) Transactions() ([]TxInfo, error) { return w.txs, nil }
+func (w *historyWallet) Scripts() [][]byte               { return w.scripts }
+
+// TestHistory ensures that the history tells the addresses of the wallet apart
+// from the others, that the fees are only known for the transactions the
+// wallet funded and that it's exported as CSV.
+func TestHistory(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	addrScript := func(b byte) (string, []byte) {
+		addr, err := btcutil.NewAddressWitnessPubKeyHash(bytes.Repeat([]byte{b}, 20), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr.EncodeAddress(), script
+	}
+	ownAddr, ownScript := addrScript(1)
+	changeAddr, changeScript := addrScript(2)
+	otherAddr, otherScript := addrScript(3)
+
+	funding := wire.NewMsgTx(wire.TxVersion)
+	funding.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 5}, nil, nil))
+	funding.AddTxOut(wire.NewTxOut(10000, ownScript))
+	spend := wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: funding.TxHash()}, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(6000, otherScript))
+	spend.AddTxOut(wire.NewTxOut(3500, changeScript))
+
+	wallet := &historyWallet{
+		txs: []TxInfo{
+			{Txid: spend.TxHash(), Tx: *btcutil.NewTx(spend), Spent: 10000, Received: 3500},
+			{Txid: funding.TxHash(), Tx: *btcutil.NewTx(funding), Received: 10000},
+		},
+		scripts: [][]byte{ownScript, changeScript},
+	}
+	m := &Manager{
+		config: ManagerConfig{ChainParams: params},
+		Wallet: wallet,
+		labels: make(map[labelKey]string),
+	}
+	m.labels[labelKey{Type: LabelTx, Ref: spend.TxHash().String()}] = "rent"
+
+	entries, err := m.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HistoryEntry{
+		{
+			Txid: spend.TxHash(), Received: 3500, Spent: 10000, Fee: 500,
+			FeeKnown: true, Label: "rent", ReceivedBy: []string{changeAddr},
+			SentTo: []string{otherAddr},
+		},
+		{
+			Txid: funding.TxHash(), Received: 10000,
+			ReceivedBy: []string{ownAddr},
+		},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("got history %+v, want %+v", entries, want)
+	}
+
+	entries[1].Height = 100
+	entries[1].Time = time.Unix(1700000000, 0)
+	entries[1].Confirmations = 1
+	var buf bytes.Buffer
+	if err := WriteHistoryCSV(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRecords := [][]string{
+		historyCSVHeader,
+		{spend.TxHash().String(), "", "", "0", "3500", "10000", "-6500", "500",
+			"rent", changeAddr, otherAddr},
+		{funding.TxHash().String(), "100", "2023-11-14T22:13:20Z", "1", "10000",
+			"0", "10000", "", "", ownAddr, ""},
+	}
+	if !reflect.DeepEqual(records, wantRecords) {
+		t.Fatalf("got CSV %v, want %v", records, wantRecords)
+	}
+}
@@ -0,0 +1,232 @@
+package bdkwallet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/txscript"
+)
+
+// labelsFileSuffix is the suffix the file of the labels of a wallet has after
+// the name of the wallet file.  The labels are stored in the BIP-329 format so
+// that they can be moved to other wallets.
+const labelsFileSuffix = ".labels"
+
+// LabelType is the type of what a label is attached to, as named by BIP-329.
+type LabelType string
+
+const (
+	// LabelTx is the type of the labels of transactions, which are
+	// referenced by their txids.
+	LabelTx LabelType = "tx"
+
+	// LabelAddress is the type of the labels of addresses.
+	LabelAddress LabelType = "addr"
+)
+
+// labelKey is what a label is attached to.
+type labelKey struct {
+	Type LabelType
+	Ref  string
+}
+
+// labelRecord is a line of a BIP-329 labels file.
+type labelRecord struct {
+	Type  LabelType `json:"type"`
+	Ref   string    `json:"ref"`
+	Label string    `json:"label"`
+}
+
+// loadLabels loads the labels of the wallet stored at the path.  A wallet that
+// has never been labeled has no labels file.
+func loadLabels(path string) (map[labelKey]string, error) {
+	labels := make(map[labelKey]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return labels, nil
+		}
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record labelRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid label on line %d: %v", line, err)
+		}
+		labels[labelKey{Type: record.Type, Ref: record.Ref}] = record.Label
+	}
+	return labels, scanner.Err()
+}
+
+// saveLabels writes the labels of the wallet to its labels file, sorted so
+// that the file only changes with the labels.
+//
+// This function MUST be called with the labels mutex held.
+func (m *Manager) saveLabels() error {
+	records := make([]labelRecord, 0, len(m.labels))
+	for key, label := range m.labels {
+		records = append(records, labelRecord{Type: key.Type, Ref: key.Ref, Label: label})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Type != records[j].Type {
+			return records[i].Type < records[j].Type
+		}
+		return records[i].Ref < records[j].Ref
+	})
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(m.dbPath()+labelsFileSuffix, buf.Bytes())
+}
+
+// setLabel attaches the label to the reference, removing its label when the
+// label is empty.
+func (m *Manager) setLabel(key labelKey, label string) error {
+	m.labelsMtx.Lock()
+	defer m.labelsMtx.Unlock()
+
+	previous, ok := m.labels[key]
+	if label == "" {
+		delete(m.labels, key)
+	} else {
+		m.labels[key] = label
+	}
+	if err := m.saveLabels(); err != nil {
+		if ok {
+			m.labels[key] = previous
+		} else {
+			delete(m.labels, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// label returns the label attached to the reference or an empty string if it
+// has none.
+func (m *Manager) label(key labelKey) string {
+	m.labelsMtx.RLock()
+	defer m.labelsMtx.RUnlock()
+	return m.labels[key]
+}
+
+// SetAddressLabel labels the address.  An empty label removes the label of the
+// address.
+func (m *Manager) SetAddressLabel(addr btcutil.Address, label string) error {
+	return m.setLabel(labelKey{Type: LabelAddress, Ref: addr.EncodeAddress()}, label)
+}
+
+// AddressLabel returns the label of the address.
+func (m *Manager) AddressLabel(addr btcutil.Address) string {
+	return m.label(labelKey{Type: LabelAddress, Ref: addr.EncodeAddress()})
+}
+
+// SetTxLabel labels the transaction.  An empty label removes the label of the
+// transaction.
+func (m *Manager) SetTxLabel(txid chainhash.Hash, label string) error {
+	return m.setLabel(labelKey{Type: LabelTx, Ref: txid.String()}, label)
+}
+
+// TxLabel returns the label of the transaction.
+func (m *Manager) TxLabel(txid chainhash.Hash) string {
+	return m.label(labelKey{Type: LabelTx, Ref: txid.String()})
+}
+
+// ScriptLabel returns the label of the address the script pays to, or an empty
+// string if the script doesn't pay to an address.
+func (m *Manager) ScriptLabel(pkScript []byte) string {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, m.config.ChainParams)
+	if err != nil || len(addrs) != 1 {
+		return ""
+	}
+	return m.AddressLabel(addrs[0])
+}
+
+// ReceivedByAddress is what an address of the wallet has received.
+type ReceivedByAddress struct {
+	Address       btcutil.Address
+	Label         string
+	Amount        btcutil.Amount   // sum of the outputs paying to the address
+	Confirmations uint             // confirmations of the most recent transaction
+	Txids         []chainhash.Hash // transactions paying to the address
+}
+
+// ReceivedByAddress returns what the revealed addresses of the receive
+// keychain of the wallet have received in the transactions with at least
+// minConf confirmations.  The addresses that haven't received anything are
+// only included when includeEmpty is set.
+func (m *Manager) ReceivedByAddress(minConf uint, includeEmpty bool) ([]ReceivedByAddress, error) {
+	var revealed uint
+	for _, info := range m.Wallet.Descriptors(false) {
+		if !info.Internal {
+			revealed = info.NextIndex
+			break
+		}
+	}
+
+	received := make([]ReceivedByAddress, revealed)
+	scripts := make(map[string]int, revealed)
+	for i := range received {
+		_, addr, err := m.Wallet.PeekAddress(uint32(i))
+		if err != nil {
+			return nil, err
+		}
+		pkScript, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		received[i] = ReceivedByAddress{Address: addr, Label: m.AddressLabel(addr)}
+		scripts[string(pkScript)] = i
+	}
+
+	txs, err := m.Wallet.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range txs {
+		if tx.Confirmations < minConf {
+			continue
+		}
+		for _, txOut := range tx.Tx.MsgTx().TxOut {
+			i, ok := scripts[string(txOut.PkScript)]
+			if !ok {
+				continue
+			}
+			entry := &received[i]
+			if len(entry.Txids) == 0 || tx.Confirmations < entry.Confirmations {
+				entry.Confirmations = tx.Confirmations
+			}
+			if len(entry.Txids) == 0 || entry.Txids[len(entry.Txids)-1] != tx.Txid {
+				entry.Txids = append(entry.Txids, tx.Txid)
+			}
+			entry.Amount += btcutil.Amount(txOut.Value)
+		}
+	}
+
+	if includeEmpty {
+		return received, nil
+	}
+	nonEmpty := received[:0]
+	for _, entry := range received {
+		if len(entry.Txids) > 0 {
+			nonEmpty = append(nonEmpty, entry)
+		}
+	}
+	return nonEmpty, nil
+}
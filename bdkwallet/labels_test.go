@@ -0,0 +1,72 @@
+package bdkwallet
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/txscript"
+)
+
+// TestLabels ensures that the labels of addresses and transactions are
+// persisted in the labels file and that empty labels remove them.
+func TestLabels(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := os.MkdirAll(WalletDir(dataDir), 0700); err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{
+		config: ManagerConfig{DataDir: dataDir, ChainParams: &chaincfg.RegressionNetParams},
+		labels: make(map[labelKey]string),
+	}
+
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(make([]byte, 20),
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := chainhash.HashH([]byte("tx"))
+	if err := m.SetAddressLabel(addr, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetTxLabel(txid, "rent"); err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.ScriptLabel(pkScript); got != "alice" {
+		t.Fatalf("got script label %q, want %q", got, "alice")
+	}
+
+	labels, err := loadLabels(m.dbPath() + labelsFileSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[labelKey]string{
+		{Type: LabelAddress, Ref: addr.EncodeAddress()}: "alice",
+		{Type: LabelTx, Ref: txid.String()}:             "rent",
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("got labels %v, want %v", labels, want)
+	}
+
+	if err := m.SetTxLabel(txid, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.TxLabel(txid); got != "" {
+		t.Fatalf("got tx label %q after removing it", got)
+	}
+	labels, err = loadLabels(m.dbPath() + labelsFileSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(want, labelKey{Type: LabelTx, Ref: txid.String()})
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("got labels %v after removing one, want %v", labels, want)
+	}
+}
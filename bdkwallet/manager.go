@@ -1,13 +1,22 @@
 package bdkwallet
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/blockchain/indexers"
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
 	"github.com/utreexo/utreexod/mempool"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
 )
 
 var defaultWalletPath = "bdkwallet"
@@ -19,6 +28,57 @@ type ManagerConfig struct {
 	TxMemPool   *mempool.TxPool
 	ChainParams *chaincfg.Params
 	DataDir     string
+
+	// SilentPaymentIndex is the index the silent payment tweaks of the
+	// blocks are fetched from when it's set.  Otherwise the tweaks are
+	// computed from the spend journals of the blocks.
+	SilentPaymentIndex *indexers.SilentPaymentIndex
+
+	// CfIndex is the compact filter index that the rescans of the wallet
+	// look up the blocks relevant to it with when it's set.  Otherwise
+	// every block is rescanned.
+	CfIndex *indexers.CfIndex
+
+	// WalletName is the name of the wallet the manager handles.  The
+	// default wallet has an empty name.
+	WalletName string
+
+	// WatchOnlyDescriptor is the xpub or output descriptor that a new
+	// wallet is created as a watch-only wallet of.  The wallet is created
+	// from a fresh mnemonic when it's empty.
+	WatchOnlyDescriptor string
+
+	// WatchOnlyBirthday is the block height a new watch-only wallet is
+	// rescanned from.
+	WatchOnlyBirthday int32
+
+	// SignerCommand is the HWI compatible command that reaches the
+	// external signer the transactions of the wallet are signed with.  A
+	// new wallet is created as a watch-only wallet of the descriptors of
+	// the signer unless WatchOnlyDescriptor is set.
+	SignerCommand string
+
+	// BackupPassphrase is the passphrase the wallet backups are encrypted
+	// with when no other passphrase is given.
+	BackupPassphrase string
+
+	// BackupInterval is how often the loaded wallets are backed up to
+	// BackupDir.  The wallets aren't backed up automatically when it's
+	// zero.
+	BackupInterval time.Duration
+
+	// BackupDir is the directory the automatic backups are written to.
+	BackupDir string
+
+	// BackupKeep is the number of automatic backups of each wallet that
+	// are kept.  All of them are kept when it's zero.
+	BackupKeep int
+
+	// NotifyConfirmations is the number of confirmations at which the
+	// subscribers of the wallet are notified that one of its transactions
+	// is confirmed.  They're notified at the first confirmation when it's
+	// zero.
+	NotifyConfirmations uint
 }
 
 // Manager handles the configuration and handling data in between the utreexo node
@@ -29,6 +89,39 @@ type Manager struct {
 	// Wallet is the underlying wallet that calls out to the
 	// bdk rust library.
 	Wallet Wallet // wallet does not need a mutex as it's done in Rust
+
+	// mtx serializes applying blocks to the wallet so that the blocks
+	// connected while the imported descriptors are rescanned are applied
+	// after them.
+	mtx sync.Mutex
+
+	// closed is set once the wallet is unloaded so that it stops being
+	// updated with the blocks the chain still notifies the manager of.
+	closed bool
+
+	// silentPayments are the silent payment keys of the wallet and the
+	// outputs paying to them, or nil if the wallet has none.  spMtx
+	// protects them from being read while blocks are applied to them.
+	silentPayments *silentPayments
+	spMtx          sync.RWMutex
+
+	// labels are the BIP-329 labels of the addresses and transactions of
+	// the wallet.
+	labels    map[labelKey]string
+	labelsMtx sync.RWMutex
+
+	// locked are the unspent outputs of the wallet that are locked so that
+	// the coin selection doesn't spend them.
+	locked    map[wire.OutPoint]struct{}
+	lockedMtx sync.Mutex
+
+	// notifications are the callbacks of the subscribers to the events of
+	// the wallet.  txConfs are the confirmations of the transactions of
+	// the wallet when the events were last looked for, which the new
+	// payments and confirmations are found from.
+	notifications []NotificationCallback
+	txConfs       map[chainhash.Hash]uint
+	ntfnMtx       sync.Mutex
 }
 
 func WalletDir(dataDir string) string {
@@ -46,6 +139,15 @@ func DoesWalletDirExist(dataDir string) (bool, error) {
 	return true, nil
 }
 
+// walletFileName returns the name of the file the wallet with the name is
+// stored in.
+func walletFileName(name string) string {
+	if name == "" {
+		return defaultWalletFileName
+	}
+	return name + ".dat"
+}
+
 func NewManager(config ManagerConfig) (*Manager, error) {
 	factory, err := factory()
 	if err != nil {
@@ -57,13 +159,28 @@ func NewManager(config ManagerConfig) (*Manager, error) {
 		return nil, err
 	}
 
-	dbPath := filepath.Join(walletDir, defaultWalletFileName)
+	dbPath := filepath.Join(walletDir, walletFileName(config.WalletName))
 	var wallet Wallet
 	if _, err := os.Stat(dbPath); err != nil {
 		if !os.IsNotExist(err) {
 			return nil, err
 		}
-		if wallet, err = factory.Create(dbPath, config.ChainParams); err != nil {
+		descriptor := config.WatchOnlyDescriptor
+		if descriptor == "" && config.SignerCommand != "" {
+			if descriptor, err = signerDescriptor(config); err != nil {
+				return nil, err
+			}
+		}
+		if descriptor != "" {
+			if err := checkRescanBlocks(config.Chain, config.WatchOnlyBirthday); err != nil {
+				return nil, err
+			}
+			wallet, err = factory.CreateWatchOnly(dbPath, config.ChainParams,
+				descriptor, uint32(config.WatchOnlyBirthday))
+			if err != nil {
+				return nil, err
+			}
+		} else if wallet, err = factory.Create(dbPath, config.ChainParams); err != nil {
 			return nil, err
 		}
 	} else {
@@ -71,18 +188,479 @@ func NewManager(config ManagerConfig) (*Manager, error) {
 			return nil, err
 		}
 	}
+	if config.SignerCommand != "" && !wallet.WatchOnly() {
+		// Named wallets that hold their own keys are signed with them.
+		if config.WalletName == "" {
+			return nil, errors.New("an external signer can only be used " +
+				"with a watch-only wallet")
+		}
+		config.SignerCommand = ""
+	}
 
-	m := Manager{
+	m := &Manager{
 		config: config,
 		Wallet: wallet,
 	}
+	m.silentPayments, err = loadSilentPayments(m.dbPath() + silentPaymentFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if m.labels, err = loadLabels(m.dbPath() + labelsFileSuffix); err != nil {
+		return nil, err
+	}
+	if m.locked, err = loadLocked(m.dbPath() + lockedFileSuffix); err != nil {
+		return nil, err
+	}
+	m.Wallet.SetLockedUTXOs(m.sortedLocked())
 	if config.Chain != nil {
 		// Subscribe to new blocks/reorged blocks.
 		config.Chain.Subscribe(m.handleBlockchainNotification)
+
+		// Finish the rescans of the watch-only wallet and of the imported
+		// descriptors that were interrupted.
+		m.mtx.Lock()
+		err := m.rescanWallet()
+		if err != nil {
+			log.Errorf("Failed to rescan the watch-only wallet. %v", err)
+		}
+		err = m.rescanImports()
+		if err != nil {
+			log.Errorf("Failed to rescan the imported descriptors. %v", err)
+		}
+		err = m.rescanSilentPayments(config.Chain.BestSnapshot().Height)
+		m.mtx.Unlock()
+		if err != nil {
+			log.Errorf("Failed to rescan the silent payments. %v", err)
+		}
+	}
+
+	if config.WalletName == "" {
+		log.Info("Started the BDK wallet manager.")
+	} else {
+		log.Infof("Loaded the BDK wallet %s.", config.WalletName)
+	}
+	return m, nil
+}
+
+// dbPath returns the path of the file the wallet is stored in, which the other
+// files of the wallet are named after.
+func (m *Manager) dbPath() string {
+	return filepath.Join(WalletDir(m.config.DataDir), walletFileName(m.config.WalletName))
+}
+
+// Name returns the name of the wallet.  The default wallet has an empty name.
+func (m *Manager) Name() string {
+	return m.config.WalletName
+}
+
+// close stops the wallet from being updated with new blocks.  The chain has no
+// way to unsubscribe the manager so the notifications keep being ignored.
+func (m *Manager) close() {
+	m.mtx.Lock()
+	m.closed = true
+	m.mtx.Unlock()
+}
+
+// signerDescriptor returns the descriptor of the first account of the only
+// external signer connected that a new wallet is created as a watch-only
+// wallet of.
+func signerDescriptor(config ManagerConfig) (string, error) {
+	signer, err := FindSigner(config.SignerCommand, config.ChainParams, "")
+	if err != nil {
+		return "", err
+	}
+	receive, internal, err := signer.Descriptors(0)
+	if err != nil {
+		return "", err
+	}
+	descriptor, err := SignerDescriptor(receive, internal)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("Creating a watch-only wallet of the external signer %s (%s)",
+		signer.Name, signer.Fingerprint)
+	return descriptor, nil
+}
+
+// signer returns the connected external signer that holds the keys of the
+// wallet or nil if the wallet isn't signed with an external signer.
+func (m *Manager) signer() (*ExternalSigner, error) {
+	if m.config.SignerCommand == "" {
+		return nil, nil
+	}
+	var fingerprint string
+	for _, info := range m.Wallet.Descriptors(false) {
+		if fingerprint = descriptorFingerprint(info.Descriptor); fingerprint != "" {
+			break
+		}
+	}
+	if fingerprint == "" {
+		return nil, errors.New("the descriptors of the wallet have no " +
+			"key origins to find the external signer with")
+	}
+	return FindSigner(m.config.SignerCommand, m.config.ChainParams, fingerprint)
+}
+
+// EnumerateSigners returns the external signers that are connected.
+func (m *Manager) EnumerateSigners() ([]ExternalSigner, error) {
+	if m.config.SignerCommand == "" {
+		return nil, errors.New("no external signer command is configured " +
+			"(--bdksigner)")
+	}
+	return EnumerateSigners(m.config.SignerCommand, m.config.ChainParams)
+}
+
+// ProcessPsbt updates the psbt with the information of the wallet and signs
+// its inputs if sign is set.  The inputs are signed by the external signer
+// when one is configured, with the wallet providing the derivation paths of
+// the keys.  The processed psbt is returned along with whether all of its
+// inputs are finalized.
+//
+// The ECDH shares of the inputs the wallet has the keys of are added for the
+// silent payment outputs of the psbt, whose scripts are derived once all of the
+// shares are there.  The psbt isn't signed before then since the signatures
+// commit to the scripts.
+func (m *Manager) ProcessPsbt(psbt []byte, sign bool) ([]byte, bool, error) {
+	psbt, derived, err := m.completeSilentPayments(psbt, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if !sign {
+		return m.Wallet.ProcessPsbt(psbt, false)
+	}
+	if !derived {
+		return nil, false, ErrSilentPaymentShares
+	}
+	signer, err := m.signer()
+	if err != nil {
+		return nil, false, err
+	}
+	if signer == nil {
+		return m.Wallet.ProcessPsbt(psbt, true)
+	}
+
+	updated, _, err := m.Wallet.ProcessPsbt(psbt, false)
+	if err != nil {
+		return nil, false, err
+	}
+	signed, err := signer.SignPsbt(updated)
+	if err != nil {
+		return nil, false, err
+	}
+	return m.Wallet.ProcessPsbt(signed, false)
+}
+
+// CreateTx creates a signed transaction paying the recipients.  The
+// transaction is funded by the wallet and signed by the external signer when
+// one is configured.  Exactly the inputs are spent when they're given and the
+// unlocked outputs of the wallet are picked with the coin selection strategy
+// otherwise.  The recipients may be silent payment addresses, whose outputs
+// are derived from the keys of the inputs the wallet funds the transaction
+// with.  The fee is split between the recipients that have SubtractFee set
+// and taken out of their amounts.
+func (m *Manager) CreateTx(feerate float32, recipients []Recipient,
+	inputs []wire.OutPoint, coinSelection CoinSelection) ([]byte, error) {
+
+	var silentPayment bool
+	var subtractFeeFrom []int
+	for i, recipient := range recipients {
+		if IsSilentPaymentAddress(recipient.Address, m.config.ChainParams) {
+			silentPayment = true
+		}
+		if recipient.SubtractFee {
+			subtractFeeFrom = append(subtractFeeFrom, i)
+		}
+	}
+	if m.config.SignerCommand == "" && !silentPayment && len(subtractFeeFrom) == 0 {
+		return m.Wallet.CreateTx(feerate, recipients, inputs, coinSelection)
+	}
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipient
+	}
+
+	outputs := make([]*wire.TxOut, len(recipients))
+	spAddrs := make(map[int]*SilentPaymentAddress)
+	for i, recipient := range recipients {
+		if IsSilentPaymentAddress(recipient.Address, m.config.ChainParams) {
+			addr, err := DecodeSilentPaymentAddress(recipient.Address,
+				m.config.ChainParams)
+			if err != nil {
+				return nil, err
+			}
+			spAddrs[i] = addr
+			outputs[i] = wire.NewTxOut(int64(recipient.Amount), nil)
+			continue
+		}
+
+		addr, err := btcutil.DecodeAddress(recipient.Address, m.config.ChainParams)
+		if err != nil {
+			return nil, err
+		}
+		pkScript, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = wire.NewTxOut(int64(recipient.Amount), pkScript)
+	}
+	funded, err := m.CreatePsbt(inputs, outputs, spAddrs, subtractFeeFrom,
+		0, feerate, true, len(inputs) == 0, coinSelection)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := m.signPsbt(funded.Psbt)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tx.MsgTx().Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BumpFee creates a signed transaction replacing the unconfirmed transaction
+// of the wallet with one that pays the fee rate in sat/vB.  The replacement is
+// returned along with the psbt it was signed from.
+func (m *Manager) BumpFee(txid chainhash.Hash, feerate float32) (*btcutil.Tx, FeeBump, error) {
+	bump, err := m.Wallet.BumpFee(txid, feerate)
+	if err != nil {
+		return nil, FeeBump{}, err
+	}
+	tx, err := m.signPsbt(bump.Psbt)
+	if err != nil {
+		return nil, FeeBump{}, err
+	}
+	return tx, bump, nil
+}
+
+// CreateCpfp creates a signed transaction spending an output of the wallet of
+// the unconfirmed transaction so that both of them pay the fee rate in sat/vB
+// together.  The child is returned along with the psbt it was signed from.
+func (m *Manager) CreateCpfp(txid chainhash.Hash, feerate float32) (*btcutil.Tx, FeeBump, error) {
+	bump, err := m.Wallet.CreateCpfp(txid, feerate)
+	if err != nil {
+		return nil, FeeBump{}, err
+	}
+	tx, err := m.signPsbt(bump.Psbt)
+	if err != nil {
+		return nil, FeeBump{}, err
+	}
+	return tx, bump, nil
+}
+
+// SendAll creates a signed transaction sweeping the unspent outputs of the
+// wallet to the address at the fee rate in sat/vB.  The transaction has no
+// change output, so the address receives everything the inputs hold but the
+// fee, which is returned along with it.  All of the unlocked outputs are swept
+// unless the inputs are given, in which case exactly those are spent.  The
+// outputs received through silent payments aren't swept.
+func (m *Manager) SendAll(feerate float32, addr btcutil.Address,
+	inputs []wire.OutPoint) (*btcutil.Tx, btcutil.Amount, error) {
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	funded, err := m.Wallet.CreateSweep(feerate, pkScript, inputs)
+	if err != nil {
+		return nil, 0, err
+	}
+	tx, err := m.signPsbt(funded.Psbt)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tx, funded.Fee, nil
+}
+
+// signPsbt signs all of the inputs of the psbt funded by the wallet, with the
+// external signer when one is configured, and extracts the transaction.
+func (m *Manager) signPsbt(psbt []byte) (*btcutil.Tx, error) {
+	processed, complete, err := m.ProcessPsbt(psbt, true)
+	if err != nil {
+		return nil, err
+	}
+	if !complete {
+		return nil, errors.New("not all of the inputs of the " +
+			"transaction were signed")
+	}
+	_, tx, err := m.Wallet.FinalizePsbt(processed)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Balance returns the balance of the wallet, including the confirmed silent
+// payments that it received.
+func (m *Manager) Balance() Balance {
+	balance := m.Wallet.Balance()
+
+	m.spMtx.RLock()
+	defer m.spMtx.RUnlock()
+	if m.silentPayments == nil {
+		return balance
+	}
+	for _, output := range m.silentPayments.Outputs {
+		if output.SpendingTx == nil {
+			balance.Confirmed += btcutil.Amount(output.Amount)
+		}
+	}
+	return balance
+}
+
+// confirmations returns the number of confirmations of a block at the height.
+func (m *Manager) confirmations(height int32) uint {
+	best := m.silentPayments.Height
+	if m.config.Chain != nil {
+		best = m.config.Chain.BestSnapshot().Height
+	}
+	if height > best {
+		return 0
+	}
+	return uint(best - height + 1)
+}
+
+// Transactions returns the transactions of the wallet, including the ones that
+// created and spent the silent payments that it received.
+func (m *Manager) Transactions() ([]TxInfo, error) {
+	txs, err := m.Wallet.Transactions()
+	if err != nil {
+		return nil, err
 	}
 
-	log.Info("Started the BDK wallet manager.")
-	return &m, nil
+	m.spMtx.RLock()
+	defer m.spMtx.RUnlock()
+	if m.silentPayments == nil {
+		return txs, nil
+	}
+	txIndexes := make(map[chainhash.Hash]int, len(txs))
+	for i := range txs {
+		txIndexes[txs[i].Txid] = i
+	}
+	txInfo := func(serialized []byte, height int32) (*TxInfo, error) {
+		tx, err := btcutil.NewTxFromBytes(serialized)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := txIndexes[*tx.Hash()]
+		if !ok {
+			i = len(txs)
+			txIndexes[*tx.Hash()] = i
+			txs = append(txs, TxInfo{
+				Txid:          *tx.Hash(),
+				Tx:            *tx,
+				Confirmations: m.confirmations(height),
+			})
+		}
+		return &txs[i], nil
+	}
+	for _, output := range m.silentPayments.Outputs {
+		info, err := txInfo(output.Tx, output.Height)
+		if err != nil {
+			return nil, err
+		}
+		info.Received += btcutil.Amount(output.Amount)
+		if output.SpendingTx == nil {
+			continue
+		}
+		if info, err = txInfo(output.SpendingTx, output.SpentHeight); err != nil {
+			return nil, err
+		}
+		info.Spent += btcutil.Amount(output.Amount)
+	}
+	return txs, nil
+}
+
+// UTXOs returns the unspent outputs of the wallet, including the silent
+// payments that it received.
+func (m *Manager) UTXOs() []UTXOInfo {
+	utxos := m.Wallet.UTXOs()
+
+	m.spMtx.RLock()
+	defer m.spMtx.RUnlock()
+	if m.silentPayments == nil {
+		return utxos
+	}
+	for _, output := range m.silentPayments.Outputs {
+		if output.SpendingTx != nil {
+			continue
+		}
+		utxos = append(utxos, UTXOInfo{
+			Txid:               output.OutPoint.Hash,
+			Vout:               uint(output.OutPoint.Index),
+			Amount:             btcutil.Amount(output.Amount),
+			ScriptPubKey:       output.PkScript,
+			Confirmations:      m.confirmations(output.Height),
+			SilentPaymentTweak: output.Tweak,
+		})
+	}
+	return utxos
+}
+
+// ImportDescriptor imports the ranged output descriptor into the wallet and
+// rescans the blocks of the main chain from the birthday height for it.
+func (m *Manager) ImportDescriptor(descriptor string, birthday int32) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	// Make sure the blocks to rescan are there before the descriptor is
+	// imported.
+	if err := checkRescanBlocks(m.config.Chain, birthday); err != nil {
+		return err
+	}
+
+	if err := m.Wallet.ImportDescriptor(descriptor, uint32(birthday)); err != nil {
+		return err
+	}
+	if m.config.Chain == nil {
+		return nil
+	}
+	if err := m.rescanImports(); err != nil {
+		return err
+	}
+	m.notifyTxEvents()
+	return nil
+}
+
+// checkRescanBlocks returns an error if the blocks of the main chain from the
+// birthday height on aren't there to be rescanned.
+func checkRescanBlocks(chain *blockchain.BlockChain, birthday int32) error {
+	if chain != nil && birthday <= chain.BestSnapshot().Height {
+		if _, err := chain.BlockByHeight(birthday); err != nil {
+			return fmt.Errorf("unable to rescan from height %d: %v",
+				birthday, err)
+		}
+	}
+	return nil
+}
+
+// rescanWallet applies the blocks of the main chain from the birthday of a
+// watch-only wallet that it hasn't been updated with yet to it.  Wallets
+// created from a mnemonic have no history to rescan.
+//
+// This function MUST be called with the manager mutex held.
+func (m *Manager) rescanWallet() error {
+	if !m.Wallet.WatchOnly() {
+		return nil
+	}
+
+	start := int32(m.Wallet.Birthday())
+	if recent := m.Wallet.RecentBlocks(1); len(recent) > 0 &&
+		int32(recent[0].Height) >= start {
+
+		start = int32(recent[0].Height) + 1
+	}
+	return m.rescanBlocks(start, m.Wallet.ApplyBlock)
+}
+
+// rescanImports applies the blocks of the main chain the imported descriptors
+// haven't been updated with yet to them.
+//
+// This function MUST be called with the manager mutex held.
+func (m *Manager) rescanImports() error {
+	return m.rescanBlocks(int32(m.Wallet.ImportsHeight())+1,
+		m.Wallet.ApplyBlockToImports)
 }
 
 func (m *Manager) NotifyNewTransactions(txns []*mempool.TxDesc) {
@@ -92,7 +670,9 @@ func (m *Manager) NotifyNewTransactions(txns []*mempool.TxDesc) {
 
 	if err := m.Wallet.ApplyMempoolTransactions(txns); err != nil {
 		log.Errorf("Failed to apply mempool txs to the wallet. %v", err)
+		return
 	}
+	m.notifyTxEvents()
 }
 
 func (m *Manager) handleBlockchainNotification(notification *blockchain.Notification) {
@@ -108,9 +688,22 @@ func (m *Manager) handleBlockchainNotification(notification *blockchain.Notifica
 			log.Warnf("Chain connected notification is not a block.")
 			return
 		}
+		m.mtx.Lock()
+		if m.closed {
+			m.mtx.Unlock()
+			return
+		}
 		err := m.Wallet.ApplyBlock(block)
 		if err != nil {
+			m.mtx.Unlock()
 			log.Criticalf("Couldn't apply block to the wallet. %v", err)
+			return
+		}
+		err = m.applyBlockToSilentPayments(block)
+		m.mtx.Unlock()
+		if err != nil {
+			log.Errorf("Couldn't scan block for silent payments. %v", err)
 		}
+		m.notifyTxEvents()
 	}
 }
@@ -0,0 +1,185 @@
+package bdkwallet
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+)
+
+// NotificationType represents the type of a notification message.
+type NotificationType int
+
+// NotificationCallback is used for a caller to provide a callback for
+// notifications about the events of the wallet.
+type NotificationCallback func(*Notification)
+
+// Constants for the type of a notification message.
+const (
+	// NTPaymentReceived indicates the associated transaction, which pays
+	// more to the wallet than it spends from it, was added to the wallet.
+	NTPaymentReceived NotificationType = iota
+
+	// NTTxConfirmed indicates the associated transaction of the wallet
+	// reached the number of confirmations the subscribers are notified at.
+	NTTxConfirmed
+
+	// NTFeeBumped indicates the associated transaction was broadcast to
+	// bump the fee of another transaction of the wallet, either by
+	// replacing it or by spending one of its outputs.
+	NTFeeBumped
+)
+
+// notificationTypeStrings is a map of notification types back to their constant
+// names for pretty printing.
+var notificationTypeStrings = map[NotificationType]string{
+	NTPaymentReceived: "NTPaymentReceived",
+	NTTxConfirmed:     "NTTxConfirmed",
+	NTFeeBumped:       "NTFeeBumped",
+}
+
+// String returns the NotificationType in human-readable form.
+func (n NotificationType) String() string {
+	if s, ok := notificationTypeStrings[n]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Notification Type (%d)", int(n))
+}
+
+// Notification defines the notification that is sent to the subscribers of the
+// wallet whenever it receives a payment, one of its transactions reaches the
+// confirmations they're notified at or the fee of one of its transactions is
+// bumped.
+//
+// BumpedTxid, Fee, OriginalFee and Cpfp are only set for NTFeeBumped
+// notifications.
+type Notification struct {
+	Type          NotificationType
+	Wallet        string // name of the wallet, empty for the default wallet
+	Txid          chainhash.Hash
+	Received      btcutil.Amount // sum of owned outputs
+	Spent         btcutil.Amount // sum of owned inputs
+	Confirmations uint
+
+	BumpedTxid  chainhash.Hash // transaction whose fee was bumped
+	Fee         btcutil.Amount // fee paid by the transaction
+	OriginalFee btcutil.Amount // fee paid by the transaction whose fee was bumped
+	Cpfp        bool           // whether the fee was bumped by spending an output
+}
+
+// defaultNotifyConfirmations is the number of confirmations the subscribers
+// are notified at when the configuration doesn't set it.
+const defaultNotifyConfirmations = 1
+
+// Subscribe to wallet notifications.  Registers a callback to be executed when
+// the wallet receives a payment, when one of its transactions reaches the
+// confirmations of the configuration and when the fee of one of its
+// transactions is bumped.  Only the events that happen after the first
+// subscription are notified.
+//
+// The callbacks are executed in the order the events happen in and should
+// hand the notifications off rather than block.
+func (m *Manager) Subscribe(callback NotificationCallback) {
+	m.ntfnMtx.Lock()
+	defer m.ntfnMtx.Unlock()
+
+	if m.txConfs == nil {
+		txConfs, err := m.txConfirmations()
+		if err != nil {
+			log.Errorf("Failed to fetch the transactions of the wallet. %v", err)
+		}
+		m.txConfs = txConfs
+	}
+	m.notifications = append(m.notifications, callback)
+}
+
+// txConfirmations returns the confirmations of the transactions of the
+// wallet, which the later payments and confirmations are found from.
+func (m *Manager) txConfirmations() (map[chainhash.Hash]uint, error) {
+	txConfs := make(map[chainhash.Hash]uint)
+	txs, err := m.Transactions()
+	if err != nil {
+		return txConfs, err
+	}
+	for _, tx := range txs {
+		txConfs[tx.Txid] = tx.Confirmations
+	}
+	return txConfs, nil
+}
+
+// sendNotification sends the notification of the wallet to all the
+// subscribers.
+//
+// This function MUST be called with the notifications mutex held.
+func (m *Manager) sendNotification(n *Notification) {
+	n.Wallet = m.config.WalletName
+	for _, callback := range m.notifications {
+		callback(n)
+	}
+}
+
+// notifyTxEvents notifies the subscribers of the payments the wallet received
+// and of the transactions that reached the confirmations they're notified at
+// since the wallet was last updated.
+func (m *Manager) notifyTxEvents() {
+	m.ntfnMtx.Lock()
+	defer m.ntfnMtx.Unlock()
+	if len(m.notifications) == 0 {
+		return
+	}
+
+	txs, err := m.Transactions()
+	if err != nil {
+		log.Errorf("Failed to fetch the transactions of the wallet. %v", err)
+		return
+	}
+	threshold := m.config.NotifyConfirmations
+	if threshold == 0 {
+		threshold = defaultNotifyConfirmations
+	}
+
+	txConfs := make(map[chainhash.Hash]uint, len(txs))
+	for _, tx := range txs {
+		txConfs[tx.Txid] = tx.Confirmations
+		previous, seen := m.txConfs[tx.Txid]
+		notify := func(typ NotificationType) {
+			m.sendNotification(&Notification{
+				Type:          typ,
+				Txid:          tx.Txid,
+				Received:      tx.Received,
+				Spent:         tx.Spent,
+				Confirmations: tx.Confirmations,
+			})
+		}
+		if !seen && tx.Received > tx.Spent {
+			notify(NTPaymentReceived)
+		}
+		if (!seen || previous < threshold) && tx.Confirmations >= threshold {
+			notify(NTTxConfirmed)
+		}
+	}
+	m.txConfs = txConfs
+}
+
+// NotifyFeeBump notifies the subscribers that the transaction bumping the fee
+// of the transaction of the wallet with the txid was broadcast.  The fee was
+// bumped by spending one of its outputs when cpfp is set and by replacing it
+// otherwise.
+func (m *Manager) NotifyFeeBump(tx *btcutil.Tx, bumped chainhash.Hash,
+	bump FeeBump, cpfp bool) {
+
+	m.ntfnMtx.Lock()
+	defer m.ntfnMtx.Unlock()
+	if len(m.notifications) == 0 {
+		return
+	}
+
+	m.sendNotification(&Notification{
+		Type:        NTFeeBumped,
+		Txid:        *tx.Hash(),
+		BumpedTxid:  bumped,
+		Fee:         bump.Fee,
+		OriginalFee: bump.OriginalFee,
+		Cpfp:        cpfp,
+	})
+}
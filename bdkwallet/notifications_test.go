@@ -0,0 +1,82 @@
+package bdkwallet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestNotifications ensures that the subscribers are notified once of the
+// payments the wallet receives and of its transactions reaching the
+// confirmations of the configuration, and of the fee bumps.
+func TestNotifications(t *testing.T) {
+	existing := chainhash.HashH([]byte("existing"))
+	payment := chainhash.HashH([]byte("payment"))
+	spend := chainhash.HashH([]byte("spend"))
+
+	wallet := &historyWallet{
+		txs: []TxInfo{{Txid: existing, Received: 1000, Confirmations: 3}},
+	}
+	m := &Manager{
+		config: ManagerConfig{WalletName: "savings", NotifyConfirmations: 2},
+		Wallet: wallet,
+	}
+	var got []Notification
+	m.Subscribe(func(n *Notification) { got = append(got, *n) })
+
+	// The transactions the wallet had before the subscription aren't
+	// notified.
+	m.notifyTxEvents()
+	if len(got) != 0 {
+		t.Fatalf("got notifications %v for the existing transactions", got)
+	}
+
+	wallet.txs = append(wallet.txs,
+		TxInfo{Txid: payment, Received: 5000},
+		TxInfo{Txid: spend, Received: 1000, Spent: 3000},
+	)
+	m.notifyTxEvents()
+	want := []Notification{
+		{Type: NTPaymentReceived, Wallet: "savings", Txid: payment, Received: 5000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got notifications %v, want %v", got, want)
+	}
+
+	wallet.txs[1].Confirmations = 1
+	wallet.txs[2].Confirmations = 1
+	m.notifyTxEvents()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got notifications %v before reaching the confirmations, want %v", got, want)
+	}
+
+	wallet.txs[0].Confirmations = 4
+	wallet.txs[1].Confirmations = 2
+	wallet.txs[2].Confirmations = 2
+	m.notifyTxEvents()
+	m.notifyTxEvents()
+	want = append(want,
+		Notification{Type: NTTxConfirmed, Wallet: "savings", Txid: payment,
+			Received: 5000, Confirmations: 2},
+		Notification{Type: NTTxConfirmed, Wallet: "savings", Txid: spend,
+			Received: 1000, Spent: 3000, Confirmations: 2},
+	)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got notifications %v, want %v", got, want)
+	}
+
+	bump := wire.NewMsgTx(wire.TxVersion)
+	bump.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: spend}, nil, nil))
+	m.NotifyFeeBump(btcutil.NewTx(bump), spend,
+		FeeBump{Fee: 800, OriginalFee: 200}, true)
+	want = append(want, Notification{
+		Type: NTFeeBumped, Wallet: "savings", Txid: bump.TxHash(),
+		BumpedTxid: spend, Fee: 800, OriginalFee: 200, Cpfp: true,
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got notifications %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package bdkwallet
+
+import (
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/btcutil/gcs"
+	"github.com/utreexo/utreexod/btcutil/gcs/builder"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// rescanTipDepth is the number of blocks below the tip of the main chain that
+// a rescan always applies to the wallet, even when their filters don't match
+// it.  The wallet keeps a checkpoint of every block it's applied, so the blocks
+// of a reorg connect to the checkpoints of these blocks.
+const rescanTipDepth = 100
+
+// rescanBlocks applies the blocks of the main chain from the start height up to
+// the tip with apply.  When the compact filter index is enabled, the blocks
+// whose filters don't match any of the scripts of the wallet are skipped.  The
+// scripts are fetched again after every block that is applied as the wallet
+// reveals more of its addresses once they're used.
+//
+// This function MUST be called with the manager mutex held.
+func (m *Manager) rescanBlocks(start int32, apply func(*btcutil.Block) error) error {
+	cfIndex := m.config.CfIndex
+	var scripts [][]byte
+	if cfIndex != nil {
+		scripts = m.Wallet.Scripts()
+	}
+
+	best := m.config.Chain.BestSnapshot().Height
+	var skipped int32
+	for height := start; height <= best; height++ {
+		if cfIndex != nil && height <= best-rescanTipDepth {
+			hash, err := m.config.Chain.BlockHashByHeight(height)
+			if err != nil {
+				return err
+			}
+			filter, err := cfIndex.FilterByBlockHash(hash, wire.GCSFilterRegular)
+			if err != nil {
+				return err
+			}
+			match, err := filterMatches(filter, hash, scripts)
+			if err != nil {
+				return err
+			}
+			if !match {
+				skipped++
+				continue
+			}
+		}
+
+		block, err := m.config.Chain.BlockByHeight(height)
+		if err != nil {
+			return err
+		}
+		if err := apply(block); err != nil {
+			return err
+		}
+		if cfIndex != nil {
+			scripts = m.Wallet.Scripts()
+		}
+	}
+	if skipped > 0 {
+		log.Infof("Skipped %d blocks not matching the wallet while rescanning "+
+			"from height %d.", skipped, start)
+	}
+	return nil
+}
+
+// filterMatches returns whether the serialized basic filter of the block with
+// the hash matches any of the scripts.  A block without a filter is treated as
+// matching so that it's applied.
+func filterMatches(filterBytes []byte, hash *chainhash.Hash, scripts [][]byte) (bool, error) {
+	if len(filterBytes) == 0 {
+		return true, nil
+	}
+	filter, err := gcs.FromNBytes(builder.DefaultP, builder.DefaultM, filterBytes)
+	if err != nil {
+		return false, err
+	}
+	if filter.N() == 0 || len(scripts) == 0 {
+		return false, nil
+	}
+	return filter.MatchAny(builder.DeriveKey(hash), scripts)
+}
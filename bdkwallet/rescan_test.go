@@ -0,0 +1,53 @@
+package bdkwallet
+
+import (
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil/gcs/builder"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestFilterMatches ensures that the blocks are matched by the scripts they pay
+// to and spend from, and that blocks without a filter are always matched.
+func TestFilterMatches(t *testing.T) {
+	paid := []byte{0x00, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09,
+		0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14}
+	spent := []byte{0x51, 0x20, 0xaa, 0xbb}
+	other := []byte{0x6a, 0x01, 0x02}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, paid))
+	block := testBlock(10, tx)
+
+	filter, err := builder.BuildBasicFilter(block.MsgBlock(), [][]byte{spent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterBytes, err := filter.NBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		filter  []byte
+		scripts [][]byte
+		want    bool
+	}{
+		{"paid script", filterBytes, [][]byte{other, paid}, true},
+		{"spent script", filterBytes, [][]byte{spent}, true},
+		{"unrelated script", filterBytes, [][]byte{other}, false},
+		{"no scripts", filterBytes, nil, false},
+		{"no filter", nil, [][]byte{other}, true},
+	}
+	for _, test := range tests {
+		got, err := filterMatches(test.filter, block.Hash(), test.scripts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/utreexo/utreexod/chaincfg"
+)
+
+var (
+	ErrNoSigner        = errors.New("no external signer found")
+	ErrMultipleSigners = errors.New("more than one external signer found, connect only one at a time")
+)
+
+// ExternalSigner is a device such as a hardware wallet that is reached through a command
+// implementing the HWI interface, like HWI itself.
+type ExternalSigner struct {
+	Command     string // command that is run to reach the device
+	Fingerprint string // fingerprint of the master key of the device
+	Name        string // model of the device
+	chain       string // network of the device the way HWI names it
+}
+
+// hwiChain returns the name HWI uses for the network of the chain params.
+func hwiChain(chainParams *chaincfg.Params) string {
+	switch chainParams.Name {
+	case "mainnet":
+		return "main"
+	case "testnet3":
+		return "test"
+	case "signet":
+		return "signet"
+	default:
+		return "regtest"
+	}
+}
+
+// runSigner runs the signer command with the arguments and decodes its JSON output into result.
+// The input is passed on stdin unless it's empty.
+func runSigner(command string, args []string, input string, result interface{}) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return errors.New("external signer command is empty")
+	}
+	cmd := exec.Command(fields[0], append(fields[1:], args...)...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("external signer command failed: %v %s", err,
+			strings.TrimSpace(stderr.String()))
+	}
+
+	// HWI reports errors as an object with an error field.
+	var signerErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(out, &signerErr) == nil && signerErr.Error != "" {
+		return fmt.Errorf("external signer: %s", signerErr.Error)
+	}
+	if err := json.Unmarshal(out, result); err != nil {
+		return fmt.Errorf("unable to decode external signer output: %v", err)
+	}
+	return nil
+}
+
+// EnumerateSigners returns the devices the signer command finds.
+func EnumerateSigners(command string, chainParams *chaincfg.Params) ([]ExternalSigner, error) {
+	chain := hwiChain(chainParams)
+	var devices []struct {
+		Fingerprint string `json:"fingerprint"`
+		Model       string `json:"model"`
+		Error       string `json:"error"`
+	}
+	err := runSigner(command, []string{"--chain", chain, "enumerate"}, "", &devices)
+	if err != nil {
+		return nil, err
+	}
+
+	signers := make([]ExternalSigner, 0, len(devices))
+	seen := make(map[string]struct{}, len(devices))
+	for _, device := range devices {
+		if device.Error != "" {
+			return nil, fmt.Errorf("external signer %s: %s", device.Model,
+				device.Error)
+		}
+		// The same device may be listed more than once.
+		if _, ok := seen[device.Fingerprint]; ok {
+			continue
+		}
+		seen[device.Fingerprint] = struct{}{}
+		signers = append(signers, ExternalSigner{
+			Command:     command,
+			Fingerprint: device.Fingerprint,
+			Name:        device.Model,
+			chain:       chain,
+		})
+	}
+	return signers, nil
+}
+
+// FindSigner returns the device of the signer command with the fingerprint. The only device found
+// is returned when the fingerprint is empty.
+func FindSigner(command string, chainParams *chaincfg.Params, fingerprint string) (*ExternalSigner, error) {
+	signers, err := EnumerateSigners(command, chainParams)
+	if err != nil {
+		return nil, err
+	}
+	if fingerprint == "" {
+		switch len(signers) {
+		case 0:
+			return nil, ErrNoSigner
+		case 1:
+			return &signers[0], nil
+		default:
+			return nil, ErrMultipleSigners
+		}
+	}
+	for i := range signers {
+		if strings.EqualFold(signers[i].Fingerprint, fingerprint) {
+			return &signers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w with fingerprint %s", ErrNoSigner, fingerprint)
+}
+
+// Descriptors returns the descriptors the device offers for the receive and the change keychains
+// of the account.
+func (s *ExternalSigner) Descriptors(account uint32) ([]string, []string, error) {
+	var res struct {
+		Receive  []string `json:"receive"`
+		Internal []string `json:"internal"`
+	}
+	args := []string{"--fingerprint", s.Fingerprint, "--chain", s.chain,
+		"getdescriptors", "--account", strconv.FormatUint(uint64(account), 10)}
+	if err := runSigner(s.Command, args, "", &res); err != nil {
+		return nil, nil, err
+	}
+	return res.Receive, res.Internal, nil
+}
+
+// SignPsbt has the device sign the inputs of the psbt it holds the keys of. The device finds its
+// keys through the derivation paths of the psbt.
+func (s *ExternalSigner) SignPsbt(psbt []byte) ([]byte, error) {
+	var res struct {
+		Psbt string `json:"psbt"`
+	}
+	args := []string{"--stdin", "--fingerprint", s.Fingerprint, "--chain", s.chain}
+	input := "signtx " + base64.StdEncoding.EncodeToString(psbt) + "\n"
+	if err := runSigner(s.Command, args, input, &res); err != nil {
+		return nil, err
+	}
+	signed, err := base64.StdEncoding.DecodeString(res.Psbt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode psbt signed by the external signer: %v", err)
+	}
+	return signed, nil
+}
+
+// SignerDescriptor returns the taproot descriptor with both the receive and the change paths
+// out of the descriptors of a device. The wallet is created as a watch-only wallet of it.
+func SignerDescriptor(receive, internal []string) (string, error) {
+	stripChecksum := func(descriptor string) string {
+		if i := strings.IndexByte(descriptor, '#'); i >= 0 {
+			return descriptor[:i]
+		}
+		return descriptor
+	}
+	for _, r := range receive {
+		r = stripChecksum(r)
+		if !strings.HasPrefix(r, "tr(") || !strings.HasSuffix(r, "/0/*)") {
+			continue
+		}
+		multipath := strings.TrimSuffix(r, "/0/*)") + "/<0;1>/*)"
+		for _, i := range internal {
+			i = stripChecksum(i)
+			if strings.HasSuffix(i, "/1/*)") &&
+				strings.TrimSuffix(i, "/1/*)") == strings.TrimSuffix(r, "/0/*)") {
+
+				return multipath, nil
+			}
+		}
+	}
+	return "", errors.New("external signer has no taproot descriptors")
+}
+
+// fingerprintRegexp matches the fingerprint of the key origin of a descriptor.
+var fingerprintRegexp = regexp.MustCompile(`\[([0-9a-fA-F]{8})[/\]]`)
+
+// descriptorFingerprint returns the fingerprint of the first key origin of the descriptor or an
+// empty string if it has none.
+func descriptorFingerprint(descriptor string) string {
+	match := fingerprintRegexp.FindStringSubmatch(descriptor)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,86 @@
+package bdkwallet
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg"
+)
+
+// TestSignerDescriptor ensures that the receive and change descriptors of an external signer are
+// combined into the multi-path taproot descriptor the wallet is created with.
+func TestSignerDescriptor(t *testing.T) {
+	receive := []string{
+		"pkh([d34db33f/44h/1h/0h]tpubA/0/*)#aaaaaaaa",
+		"tr([d34db33f/86h/1h/0h]tpubB/0/*)#bbbbbbbb",
+	}
+	internal := []string{
+		"pkh([d34db33f/44h/1h/0h]tpubA/1/*)#cccccccc",
+		"tr([d34db33f/86h/1h/0h]tpubB/1/*)#dddddddd",
+	}
+	descriptor, err := SignerDescriptor(receive, internal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "tr([d34db33f/86h/1h/0h]tpubB/<0;1>/*)"
+	if descriptor != want {
+		t.Fatalf("got %s, want %s", descriptor, want)
+	}
+	if fingerprint := descriptorFingerprint(descriptor); fingerprint != "d34db33f" {
+		t.Fatalf("got fingerprint %s, want d34db33f", fingerprint)
+	}
+
+	if _, err := SignerDescriptor(receive[:1], internal[:1]); err == nil {
+		t.Fatal("expected an error for a signer without taproot descriptors")
+	}
+}
+
+// TestExternalSigner ensures that the devices are enumerated and the psbts are signed through the
+// HWI interface of the signer command.
+func TestExternalSigner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake signer is a shell script")
+	}
+
+	// The fake signer lists a device twice and signs the psbt into "signed".
+	script := `#!/bin/sh
+case "$*" in
+*enumerate*)
+	echo '[{"fingerprint":"d34db33f","model":"trezor_t"},{"fingerprint":"d34db33f","model":"trezor_t"}]' ;;
+*--stdin*)
+	read cmd psbt
+	[ "$cmd" = "signtx" ] && [ "$psbt" = "cHNidA==" ] && echo '{"psbt":"c2lnbmVk"}' || echo '{"error":"bad input"}' ;;
+*)
+	echo '{"error":"unknown command"}' ;;
+esac
+`
+	command := filepath.Join(t.TempDir(), "signer")
+	if err := os.WriteFile(command, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := FindSigner(command, &chaincfg.RegressionNetParams, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Fingerprint != "d34db33f" || signer.Name != "trezor_t" || signer.chain != "regtest" {
+		t.Fatalf("unexpected signer %+v", signer)
+	}
+	if _, err := FindSigner(command, &chaincfg.RegressionNetParams, "0badf00d"); err == nil {
+		t.Fatal("expected an error for a fingerprint that isn't connected")
+	}
+
+	signed, err := signer.SignPsbt([]byte("psbt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(signed) != "signed" {
+		t.Fatalf("got psbt %q, want %q", signed, "signed")
+	}
+
+	if _, _, err := signer.Descriptors(0); err == nil {
+		t.Fatal("expected the error reported by the signer")
+	}
+}
@@ -0,0 +1,378 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/btcutil/bech32"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+var (
+	ErrNoSilentPaymentInputs = errors.New("no input is eligible for silent payments")
+	ErrSilentPaymentShares   = errors.New("the ECDH shares of all of the eligible inputs " +
+		"are needed to derive the silent payment outputs")
+)
+
+var (
+	// silentPaymentInputsTag is the tag of the hash that commits to the
+	// inputs of a transaction as defined in BIP-352.
+	silentPaymentInputsTag = []byte("BIP0352/Inputs")
+
+	// silentPaymentSharedSecretTag is the tag of the hash that derives the
+	// tweak of an output from the shared secret as defined in BIP-352.
+	silentPaymentSharedSecretTag = []byte("BIP0352/SharedSecret")
+)
+
+// silentPaymentKeysLen is the length of the scan and spend keys of a silent
+// payment address.
+const silentPaymentKeysLen = 2 * btcec.PubKeyBytesLenCompressed
+
+// SilentPaymentAddress is a BIP-352 silent payment address.  The outputs
+// paying to it are derived from its keys and from the keys of the inputs of
+// the transaction.
+type SilentPaymentAddress struct {
+	ScanKey  *btcec.PublicKey
+	SpendKey *btcec.PublicKey
+}
+
+// silentPaymentHRP returns the human-readable part of the silent payment
+// addresses of the network.
+func silentPaymentHRP(chainParams *chaincfg.Params) string {
+	if chainParams.Net == wire.MainNet {
+		return "sp"
+	}
+	return "tsp"
+}
+
+// IsSilentPaymentAddress returns whether the address is meant to be a silent
+// payment address of the network.  The address isn't validated.
+func IsSilentPaymentAddress(addr string, chainParams *chaincfg.Params) bool {
+	return strings.HasPrefix(strings.ToLower(addr), silentPaymentHRP(chainParams)+"1")
+}
+
+// DecodeSilentPaymentAddress decodes the silent payment address of the
+// network.  The data that addresses of versions above 0 may have after the
+// keys is ignored.
+func DecodeSilentPaymentAddress(addr string,
+	chainParams *chaincfg.Params) (*SilentPaymentAddress, error) {
+
+	// Silent payment addresses are longer than the limit of segwit
+	// addresses.
+	hrp, data, err := bech32.DecodeNoLimit(addr)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != silentPaymentHRP(chainParams) {
+		return nil, fmt.Errorf("silent payment address %s is not for %s",
+			addr, chainParams.Name)
+	}
+
+	// The data is only encoded back to the address with the checksum it
+	// was encoded with.
+	encoded, err := bech32.EncodeM(hrp, data)
+	if err != nil || encoded != strings.ToLower(addr) {
+		return nil, errors.New("silent payment addresses must be encoded with bech32m")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("silent payment address has no version")
+	}
+	version := data[0]
+	if version == 31 {
+		return nil, fmt.Errorf("unsupported silent payment address version %d",
+			version)
+	}
+	keys, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) < silentPaymentKeysLen ||
+		(version == 0 && len(keys) != silentPaymentKeysLen) {
+
+		return nil, fmt.Errorf("invalid silent payment address length %d",
+			len(keys))
+	}
+
+	scanKey, err := btcec.ParsePubKey(keys[:btcec.PubKeyBytesLenCompressed])
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan key: %v", err)
+	}
+	spendKey, err := btcec.ParsePubKey(
+		keys[btcec.PubKeyBytesLenCompressed:silentPaymentKeysLen])
+	if err != nil {
+		return nil, fmt.Errorf("invalid spend key: %v", err)
+	}
+	return &SilentPaymentAddress{ScanKey: scanKey, SpendKey: spendKey}, nil
+}
+
+// EncodeAddress returns the version 0 encoding of the silent payment address
+// for the network.
+func (a *SilentPaymentAddress) EncodeAddress(chainParams *chaincfg.Params) (string, error) {
+	keys := append(a.ScanKey.SerializeCompressed(), a.SpendKey.SerializeCompressed()...)
+	data, err := bech32.ConvertBits(keys, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32.EncodeM(silentPaymentHRP(chainParams), append([]byte{0}, data...))
+}
+
+// taprootScript returns the script of the taproot output with the key.
+func taprootScript(key *btcec.PublicKey, chainParams *chaincfg.Params) ([]byte, error) {
+	addr, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(key), chainParams)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+// silentPaymentOutputKey returns the output key of the k-th output paying to
+// the spend key that is derived from the shared secret, along with the tweak
+// that was added to the spend key.
+func silentPaymentOutputKey(sharedSecret []byte, k uint32,
+	spendKey *btcec.PublicKey) (*btcec.PublicKey, *btcec.ModNScalar, error) {
+
+	var serializedK [4]byte
+	binary.BigEndian.PutUint32(serializedK[:], k)
+	tweak := chainhash.TaggedHash(
+		silentPaymentSharedSecretTag, sharedSecret, serializedK[:],
+	)
+	var tweakScalar btcec.ModNScalar
+	if overflow := tweakScalar.SetByteSlice(tweak[:]); overflow {
+		return nil, nil, errors.New("output tweak is not a valid scalar")
+	}
+
+	var tweakPoint, spendPoint, outputPoint btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(&tweakScalar, &tweakPoint)
+	spendKey.AsJacobian(&spendPoint)
+	btcec.AddNonConst(&spendPoint, &tweakPoint, &outputPoint)
+	outputPoint.ToAffine()
+	return btcec.NewPublicKey(&outputPoint.X, &outputPoint.Y), &tweakScalar, nil
+}
+
+// deriveSilentPaymentScripts sets the scripts of the silent payment outputs to
+// the ones BIP-352 derives from the keys and the ECDH shares of the inputs.
+// False is returned when an eligible input is missing the share of one of the
+// scan keys, in which case none of the scripts are set.
+func deriveSilentPaymentScripts(inputs []SilentPaymentInput,
+	outputs []SilentPaymentOutput, chainParams *chaincfg.Params) (bool, error) {
+
+	var (
+		sum         btcec.JacobianPoint
+		numKeys     int
+		smallestOp  [chainhash.HashSize + 4]byte
+		serializeOp [chainhash.HashSize + 4]byte
+	)
+	for i, input := range inputs {
+		copy(serializeOp[:], input.OutPoint.Hash[:])
+		binary.LittleEndian.PutUint32(serializeOp[chainhash.HashSize:],
+			input.OutPoint.Index)
+		if i == 0 || bytes.Compare(serializeOp[:], smallestOp[:]) < 0 {
+			smallestOp = serializeOp
+		}
+
+		if input.PublicKey == nil {
+			continue
+		}
+		var point, next btcec.JacobianPoint
+		input.PublicKey.AsJacobian(&point)
+		btcec.AddNonConst(&sum, &point, &next)
+		sum = next
+		numKeys++
+	}
+	if numKeys == 0 {
+		return false, ErrNoSilentPaymentInputs
+	}
+	if (sum.X.IsZero() && sum.Y.IsZero()) || sum.Z.IsZero() {
+		return false, errors.New("the keys of the inputs cancel each other out")
+	}
+	sum.ToAffine()
+	sumKey := btcec.NewPublicKey(&sum.X, &sum.Y).SerializeCompressed()
+
+	inputHash := chainhash.TaggedHash(
+		silentPaymentInputsTag, smallestOp[:], sumKey,
+	)
+	var inputHashScalar btcec.ModNScalar
+	if overflow := inputHashScalar.SetByteSlice(inputHash[:]); overflow {
+		return false, errors.New("input hash is not a valid scalar")
+	}
+
+	// Every scan key has its own shared secret, which the outputs paying to
+	// it are derived from in the order they're in.
+	type scanKeyGroup struct {
+		shareSum btcec.JacobianPoint
+		outputs  []int
+	}
+	var scanKeys [][btcec.PubKeyBytesLenCompressed]byte
+	groups := make(map[[btcec.PubKeyBytesLenCompressed]byte]*scanKeyGroup)
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].Index < outputs[j].Index
+	})
+	for i, output := range outputs {
+		var scanKey [btcec.PubKeyBytesLenCompressed]byte
+		copy(scanKey[:], output.ScanKey.SerializeCompressed())
+		group, ok := groups[scanKey]
+		if !ok {
+			group = &scanKeyGroup{}
+			groups[scanKey] = group
+			scanKeys = append(scanKeys, scanKey)
+		}
+		group.outputs = append(group.outputs, i)
+	}
+	for _, scanKey := range scanKeys {
+		group := groups[scanKey]
+		for _, input := range inputs {
+			if input.PublicKey == nil {
+				continue
+			}
+			share, ok := input.ECDHShares[scanKey]
+			if !ok {
+				return false, nil
+			}
+			var point, next btcec.JacobianPoint
+			share.AsJacobian(&point)
+			btcec.AddNonConst(&group.shareSum, &point, &next)
+			group.shareSum = next
+		}
+	}
+
+	for _, scanKey := range scanKeys {
+		group := groups[scanKey]
+		var secret btcec.JacobianPoint
+		btcec.ScalarMultNonConst(&inputHashScalar, &group.shareSum, &secret)
+		secret.ToAffine()
+		sharedSecret := btcec.NewPublicKey(&secret.X, &secret.Y).SerializeCompressed()
+
+		for k, i := range group.outputs {
+			outputKey, _, err := silentPaymentOutputKey(sharedSecret,
+				uint32(k), outputs[i].SpendKey)
+			if err != nil {
+				return false, err
+			}
+			pkScript, err := taprootScript(outputKey, chainParams)
+			if err != nil {
+				return false, err
+			}
+			outputs[i].PkScript = pkScript
+		}
+	}
+	return true, nil
+}
+
+// completeSilentPayments adds the silent payment outputs to the psbt along with
+// the ECDH shares of the inputs the wallet has the keys of.  The scripts of all
+// of the silent payment outputs of the psbt are then derived if every eligible
+// input has its shares, which is returned.  A psbt without silent payment
+// outputs is complete.
+func (m *Manager) completeSilentPayments(psbt []byte,
+	outputs []SilentPaymentOutput) ([]byte, bool, error) {
+
+	sp, err := m.Wallet.SilentPaymentPsbt(psbt, outputs)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(sp.Outputs) == 0 {
+		return sp.Psbt, true, nil
+	}
+	derived, err := deriveSilentPaymentScripts(sp.Inputs, sp.Outputs,
+		m.config.ChainParams)
+	if err != nil || !derived {
+		return sp.Psbt, false, err
+	}
+	sp, err = m.Wallet.SilentPaymentPsbt(sp.Psbt, sp.Outputs)
+	if err != nil {
+		return nil, false, err
+	}
+	return sp.Psbt, true, nil
+}
+
+// CreatePsbt creates a psbt paying to the outputs that is funded by the wallet
+// the same way as Wallet.CreatePsbt.  The outputs at the indexes of spAddrs pay
+// to the silent payment addresses and their scripts are ignored.  Their output
+// keys are derived from the keys of the inputs once every eligible input has
+// its ECDH shares, which the wallet adds for the inputs it has the keys of and
+// other signers add to the psbt as BIP-375 has it.  The fee is taken out of
+// the outputs at the indexes of subtractFeeFrom when there are any.
+func (m *Manager) CreatePsbt(inputs []wire.OutPoint, outputs []*wire.TxOut,
+	spAddrs map[int]*SilentPaymentAddress, subtractFeeFrom []int,
+	locktime uint32, feerate float32, replaceable, addInputs bool,
+	coinSelection CoinSelection) (FundedPsbt, error) {
+
+	// The silent payment outputs pay to their spend keys until their
+	// output keys are derived so that the fee is estimated for a taproot
+	// output.
+	indexes := make([]int, 0, len(spAddrs))
+	placeholders := make([]*wire.TxOut, len(outputs))
+	copy(placeholders, outputs)
+	for index, addr := range spAddrs {
+		if index < 0 || index >= len(outputs) {
+			return FundedPsbt{}, fmt.Errorf("no output at index %d", index)
+		}
+		pkScript, err := taprootScript(addr.SpendKey, m.config.ChainParams)
+		if err != nil {
+			return FundedPsbt{}, err
+		}
+		placeholders[index] = wire.NewTxOut(outputs[index].Value, pkScript)
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var funded FundedPsbt
+	var err error
+	if len(subtractFeeFrom) == 0 {
+		funded, err = m.Wallet.CreatePsbt(inputs, placeholders, locktime,
+			feerate, replaceable, addInputs, coinSelection)
+	} else {
+		funded, placeholders, err = m.createPsbtSubtractingFee(inputs,
+			placeholders, subtractFeeFrom, locktime, feerate,
+			replaceable, addInputs, coinSelection)
+	}
+	if err != nil || len(spAddrs) == 0 {
+		return funded, err
+	}
+
+	// The wallet shuffles the outputs, so the silent payment outputs are
+	// found by their placeholders.
+	txOuts, err := m.Wallet.PsbtOutputs(funded.Psbt)
+	if err != nil {
+		return FundedPsbt{}, err
+	}
+	found := make([]bool, len(txOuts))
+	spOutputs := make([]SilentPaymentOutput, 0, len(spAddrs))
+	for _, index := range indexes {
+		placeholder := placeholders[index]
+		for i, txOut := range txOuts {
+			if found[i] || txOut.Value != placeholder.Value ||
+				!bytes.Equal(txOut.PkScript, placeholder.PkScript) {
+
+				continue
+			}
+			found[i] = true
+			spOutputs = append(spOutputs, SilentPaymentOutput{
+				Index:    i,
+				ScanKey:  spAddrs[index].ScanKey,
+				SpendKey: spAddrs[index].SpendKey,
+			})
+			break
+		}
+	}
+	if len(spOutputs) != len(spAddrs) {
+		return FundedPsbt{}, errors.New("unable to find the silent " +
+			"payment outputs of the psbt")
+	}
+
+	funded.Psbt, _, err = m.completeSilentPayments(funded.Psbt, spOutputs)
+	if err != nil {
+		return FundedPsbt{}, err
+	}
+	return funded, nil
+}
@@ -0,0 +1,247 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/utreexo/utreexod/btcutil/bech32"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// spTestAddress is the silent payment address of the BIP-352 test vectors.
+const spTestAddress = "sp1qqgste7k9hx0qftg6qmwlkqtwuy6cycyavzmzj85c6qdfhjdpdjtdgqjuexzk6" +
+	"murw56suy3e0rd2cgqvycxttddwsvgxe2usfpxumr70xc9pkqwv"
+
+// encodeSilentPaymentAddress encodes the keys as a silent payment address of
+// the version with the extra data appended to them.
+func encodeSilentPaymentAddress(t *testing.T, hrp string, version byte,
+	scanKey, spendKey *btcec.PublicKey, extra []byte) string {
+
+	payload := append(scanKey.SerializeCompressed(), spendKey.SerializeCompressed()...)
+	payload = append(payload, extra...)
+	data, err := bech32.ConvertBits(payload, 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := bech32.EncodeM(hrp, append([]byte{version}, data...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+// testKey returns the private key with the hex encoded scalar.
+func testKey(t *testing.T, scalar string) *btcec.PrivateKey {
+	b, err := hex.DecodeString(scalar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := btcec.PrivKeyFromBytes(b)
+	return key
+}
+
+// ecdhShare returns the ECDH share of the private key for the scan key.
+func ecdhShare(key *btcec.PrivateKey, scanKey *btcec.PublicKey) *btcec.PublicKey {
+	var point, result btcec.JacobianPoint
+	scanKey.AsJacobian(&point)
+	btcec.ScalarMultNonConst(&key.Key, &point, &result)
+	result.ToAffine()
+	return btcec.NewPublicKey(&result.X, &result.Y)
+}
+
+// testTweak returns the tweak input_hash*A of a transaction spending the
+// outpoint with the key.
+func testTweak(inputKey *btcec.PrivateKey, op wire.OutPoint) *btcec.PublicKey {
+	var serializedOp [chainhash.HashSize + 4]byte
+	copy(serializedOp[:], op.Hash[:])
+	binary.LittleEndian.PutUint32(serializedOp[chainhash.HashSize:], op.Index)
+	inputHash := chainhash.TaggedHash(silentPaymentInputsTag, serializedOp[:],
+		inputKey.PubKey().SerializeCompressed())
+	var inputHashScalar btcec.ModNScalar
+	inputHashScalar.SetByteSlice(inputHash[:])
+	return ecdhShare(&btcec.PrivateKey{Key: inputHashScalar}, inputKey.PubKey())
+}
+
+// TestDecodeSilentPaymentAddress ensures that silent payment addresses are
+// only decoded for their network and with the bech32m checksum.
+func TestDecodeSilentPaymentAddress(t *testing.T) {
+	addr, err := DecodeSilentPaymentAddress(spTestAddress, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantScan := "0220bcfac5b99e04ad1a06ddfb016ee13582609d60b6291e98d01a9bc9a16c96d4"
+	wantSpend := "025cc9856d6f8375350e123978daac200c260cb5b5ae83106cab90484dcd8fcf36"
+	if got := hex.EncodeToString(addr.ScanKey.SerializeCompressed()); got != wantScan {
+		t.Fatalf("got scan key %s, want %s", got, wantScan)
+	}
+	if got := hex.EncodeToString(addr.SpendKey.SerializeCompressed()); got != wantSpend {
+		t.Fatalf("got spend key %s, want %s", got, wantSpend)
+	}
+
+	scanKey, spendKey := addr.ScanKey, addr.SpendKey
+	tests := []struct {
+		name  string
+		addr  string
+		valid bool
+	}{
+		{"testnet", encodeSilentPaymentAddress(t, "tsp", 0, scanKey, spendKey, nil), true},
+		{"future version", encodeSilentPaymentAddress(t, "tsp", 1, scanKey, spendKey, []byte{1, 2}), true},
+		{"mainnet", encodeSilentPaymentAddress(t, "sp", 0, scanKey, spendKey, nil), false},
+		{"extra data", encodeSilentPaymentAddress(t, "tsp", 0, scanKey, spendKey, []byte{1}), false},
+		{"version 31", encodeSilentPaymentAddress(t, "tsp", 31, scanKey, spendKey, nil), false},
+		{"bech32", func() string {
+			data, _ := bech32.ConvertBits(append(scanKey.SerializeCompressed(),
+				spendKey.SerializeCompressed()...), 8, 5, true)
+			addr, _ := bech32.Encode("tsp", append([]byte{0}, data...))
+			return addr
+		}(), false},
+	}
+	for _, test := range tests {
+		if !IsSilentPaymentAddress(test.addr, &chaincfg.TestNet3Params) && test.valid {
+			t.Errorf("%s: not recognized as a silent payment address", test.name)
+			continue
+		}
+		addr, err := DecodeSilentPaymentAddress(test.addr, &chaincfg.TestNet3Params)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !addr.ScanKey.IsEqual(scanKey) || !addr.SpendKey.IsEqual(spendKey) {
+			t.Errorf("%s: got different keys", test.name)
+		}
+	}
+}
+
+// TestDeriveSilentPaymentScripts ensures that the silent payment outputs are
+// derived from the ECDH shares of the inputs as BIP-352 has it.
+func TestDeriveSilentPaymentScripts(t *testing.T) {
+	// The "Simple send: two inputs" case of the BIP-352 test vectors.
+	addr, err := DecodeSilentPaymentAddress(spTestAddress, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []*btcec.PrivateKey{
+		testKey(t, "eadc78165ff1f8ea94ad7cfdc54990738a4c53f6e0507b42154201b8e5dff3b1"),
+		testKey(t, "93f5ed907ad5b2bdbbdcb5d9116ebc0a4e1f92f910d5260237fa45a9408aad16"),
+	}
+	txids := []string{
+		"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+		"a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d",
+	}
+	var scanKey [btcec.PubKeyBytesLenCompressed]byte
+	copy(scanKey[:], addr.ScanKey.SerializeCompressed())
+	inputs := make([]SilentPaymentInput, len(keys))
+	for i, key := range keys {
+		hash, err := chainhash.NewHashFromStr(txids[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[i] = SilentPaymentInput{
+			OutPoint:  *wire.NewOutPoint(hash, 0),
+			PublicKey: key.PubKey(),
+			ECDHShares: map[[btcec.PubKeyBytesLenCompressed]byte]*btcec.PublicKey{
+				scanKey: ecdhShare(key, addr.ScanKey),
+			},
+		}
+	}
+	outputs := []SilentPaymentOutput{{
+		Index:    0,
+		ScanKey:  addr.ScanKey,
+		SpendKey: addr.SpendKey,
+	}}
+
+	derived, err := deriveSilentPaymentScripts(inputs, outputs, &chaincfg.MainNetParams)
+	if err != nil || !derived {
+		t.Fatalf("got %v, %v", derived, err)
+	}
+	want := "51203e9fce73d4e77a4809908e3c3a2e54ee147b9312dc5044a193d1fc85de46e3c1"
+	if got := hex.EncodeToString(outputs[0].PkScript); got != want {
+		t.Fatalf("got script %s, want %s", got, want)
+	}
+
+	// Nothing is derived until every eligible input has its share.
+	outputs[0].PkScript = nil
+	delete(inputs[1].ECDHShares, scanKey)
+	derived, err = deriveSilentPaymentScripts(inputs, outputs, &chaincfg.MainNetParams)
+	if err != nil || derived || outputs[0].PkScript != nil {
+		t.Fatalf("got %v, %v with a missing share", derived, err)
+	}
+
+	// Inputs that aren't eligible need no share.
+	inputs[1].PublicKey = nil
+	derived, err = deriveSilentPaymentScripts(inputs, outputs, &chaincfg.MainNetParams)
+	if err != nil || !derived {
+		t.Fatalf("got %v, %v with an ineligible input", derived, err)
+	}
+
+	inputs[0].PublicKey = nil
+	_, err = deriveSilentPaymentScripts(inputs, outputs, &chaincfg.MainNetParams)
+	if !errors.Is(err, ErrNoSilentPaymentInputs) {
+		t.Fatalf("got error %v, want %v", err, ErrNoSilentPaymentInputs)
+	}
+}
+
+// TestSilentPaymentReceive ensures that the outputs paying to the same scan
+// key are found by the receiver from the tweak of the transaction.
+func TestSilentPaymentReceive(t *testing.T) {
+	scanKey := testKey(t, "0f694e068028a717f8af6b9411f9a133dd3565258714cc226594b34db90c1f2c")
+	spendKeys := []*btcec.PrivateKey{
+		testKey(t, "9d6ad855ce3417ef84e836892e5a56392bfba05fa5d97ccea30e266f540e08b3"),
+		testKey(t, "3a8d8c2a8e1d35b0b2c0fc8c5b3f4f5bd64b5f9d2b0e2f0c1e1f6a7b8c9d0e1f"),
+	}
+	var serializedScan [btcec.PubKeyBytesLenCompressed]byte
+	copy(serializedScan[:], scanKey.PubKey().SerializeCompressed())
+
+	inputKey := testKey(t, "1cd5e8f6b3f29505ed1da7a5806291ebab6491c6a172467e44debe255428a192")
+	inputs := []SilentPaymentInput{{
+		OutPoint:  wire.OutPoint{Index: 1},
+		PublicKey: inputKey.PubKey(),
+		ECDHShares: map[[btcec.PubKeyBytesLenCompressed]byte]*btcec.PublicKey{
+			serializedScan: ecdhShare(inputKey, scanKey.PubKey()),
+		},
+	}}
+	outputs := []SilentPaymentOutput{
+		{Index: 2, ScanKey: scanKey.PubKey(), SpendKey: spendKeys[1].PubKey()},
+		{Index: 0, ScanKey: scanKey.PubKey(), SpendKey: spendKeys[0].PubKey()},
+	}
+	derived, err := deriveSilentPaymentScripts(inputs, outputs, &chaincfg.RegressionNetParams)
+	if err != nil || !derived {
+		t.Fatalf("got %v, %v", derived, err)
+	}
+
+	// The receiver computes the shared secret from the tweak
+	// input_hash*A with its scan key.
+	tweak := testTweak(inputKey, inputs[0].OutPoint)
+	secret := ecdhShare(scanKey, tweak).SerializeCompressed()
+
+	// The outputs are derived in the order of their indexes.
+	for k, output := range outputs {
+		var serializedK [4]byte
+		binary.BigEndian.PutUint32(serializedK[:], uint32(k))
+		outputTweak := chainhash.TaggedHash(silentPaymentSharedSecretTag,
+			secret, serializedK[:])
+		var tweakScalar btcec.ModNScalar
+		tweakScalar.SetByteSlice(outputTweak[:])
+		outputKey := (&btcec.PrivateKey{Key: *tweakScalar.Add(&spendKeys[k].Key)}).PubKey()
+		want, err := taprootScript(outputKey, &chaincfg.RegressionNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output.PkScript, want) {
+			t.Fatalf("output %d: got script %x, want %x", output.Index,
+				output.PkScript, want)
+		}
+	}
+}
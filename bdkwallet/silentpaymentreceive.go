@@ -0,0 +1,403 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/utreexo/utreexod/blockchain/indexers"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// silentPaymentFileSuffix is the suffix the file of the silent payment keys of
+// a wallet and of the outputs paying to them has after the name of the wallet
+// file.
+const silentPaymentFileSuffix = ".sp"
+
+var ErrSilentPaymentKeys = errors.New("the wallet already has different silent payment keys")
+
+// silentPaymentUtxo is an output paying to the silent payment keys of the
+// wallet.
+type silentPaymentUtxo struct {
+	OutPoint wire.OutPoint `json:"outpoint"`
+	Amount   int64         `json:"amount"`
+	PkScript []byte        `json:"pkscript"`
+
+	// Tweak is the scalar added to the spend key to get the key of the
+	// output.
+	Tweak []byte `json:"tweak"`
+
+	// Height is the height of the block the output was created in and Tx
+	// the serialized transaction that created it.
+	Height int32  `json:"height"`
+	Tx     []byte `json:"tx"`
+
+	// SpentHeight is the height of the block the output was spent in and
+	// SpendingTx the serialized transaction that spent it.  SpendingTx is
+	// nil while the output is unspent.
+	SpentHeight int32  `json:"spentheight,omitempty"`
+	SpendingTx  []byte `json:"spendingtx,omitempty"`
+}
+
+// silentPayments holds the silent payment keys of a wallet and the outputs of
+// the blocks of the main chain that pay to them.  The blocks are scanned with
+// the tweaks of the silent payment index when the node runs it and with the
+// tweaks computed from their spend journals otherwise.
+type silentPayments struct {
+	ScanKey  []byte `json:"scankey"`
+	SpendKey []byte `json:"spendkey"`
+
+	// Birthday is the height of the first block scanned and Height the
+	// height of the last one.
+	Birthday int32 `json:"birthday"`
+	Height   int32 `json:"height"`
+
+	Outputs []*silentPaymentUtxo `json:"outputs"`
+
+	scanKey  *btcec.PrivateKey
+	spendKey *btcec.PublicKey
+}
+
+// parseKeys parses the keys the silent payments were stored with.
+func (sp *silentPayments) parseKeys() error {
+	if len(sp.ScanKey) != btcec.PrivKeyBytesLen {
+		return errors.New("invalid silent payment scan key")
+	}
+	sp.scanKey, _ = btcec.PrivKeyFromBytes(sp.ScanKey)
+	spendKey, err := btcec.ParsePubKey(sp.SpendKey)
+	if err != nil {
+		return err
+	}
+	sp.spendKey = spendKey
+	return nil
+}
+
+// address returns the silent payment address of the keys.
+func (sp *silentPayments) address() *SilentPaymentAddress {
+	return &SilentPaymentAddress{
+		ScanKey:  sp.scanKey.PubKey(),
+		SpendKey: sp.spendKey,
+	}
+}
+
+// rollback forgets the outputs created and spent in the blocks above the
+// height, which are no longer in the main chain.
+func (sp *silentPayments) rollback(height int32) {
+	outputs := sp.Outputs[:0]
+	for _, output := range sp.Outputs {
+		if output.Height > height {
+			continue
+		}
+		if output.SpendingTx != nil && output.SpentHeight > height {
+			output.SpentHeight = 0
+			output.SpendingTx = nil
+		}
+		outputs = append(outputs, output)
+	}
+	sp.Outputs = outputs
+	sp.Height = height
+}
+
+// applyBlock adds the outputs of the block that pay to the silent payment keys
+// and marks the ones that it spends as spent.  The outputs are found from the
+// tweaks of the transactions of the block.
+func (sp *silentPayments) applyBlock(block *btcutil.Block,
+	tweaks []indexers.SilentPaymentTweak) error {
+
+	tweakByTx := make(map[chainhash.Hash]*indexers.SilentPaymentTweak, len(tweaks))
+	for i := range tweaks {
+		tweakByTx[tweaks[i].TxHash] = &tweaks[i]
+	}
+	unspent := make(map[wire.OutPoint]*silentPaymentUtxo)
+	for _, output := range sp.Outputs {
+		if output.SpendingTx == nil {
+			unspent[output.OutPoint] = output
+		}
+	}
+
+	height := block.Height()
+	for _, tx := range block.Transactions() {
+		var serialized []byte
+		serializeTx := func() ([]byte, error) {
+			if serialized == nil {
+				var buf bytes.Buffer
+				if err := tx.MsgTx().Serialize(&buf); err != nil {
+					return nil, err
+				}
+				serialized = buf.Bytes()
+			}
+			return serialized, nil
+		}
+
+		for _, txIn := range tx.MsgTx().TxIn {
+			output, ok := unspent[txIn.PreviousOutPoint]
+			if !ok {
+				continue
+			}
+			spendingTx, err := serializeTx()
+			if err != nil {
+				return err
+			}
+			output.SpentHeight = height
+			output.SpendingTx = spendingTx
+			delete(unspent, txIn.PreviousOutPoint)
+		}
+
+		tweak, ok := tweakByTx[*tx.Hash()]
+		if !ok {
+			continue
+		}
+		indexes, tweakScalars, err := findSilentPayments(tx.MsgTx(),
+			tweak.Tweak[:], sp.scanKey, sp.spendKey)
+		if err != nil {
+			return err
+		}
+		for i, index := range indexes {
+			serializedTx, err := serializeTx()
+			if err != nil {
+				return err
+			}
+			txOut := tx.MsgTx().TxOut[index]
+			tweakBytes := tweakScalars[i].Bytes()
+			output := &silentPaymentUtxo{
+				OutPoint: *wire.NewOutPoint(tx.Hash(), uint32(index)),
+				Amount:   txOut.Value,
+				PkScript: txOut.PkScript,
+				Tweak:    tweakBytes[:],
+				Height:   height,
+				Tx:       serializedTx,
+			}
+			sp.Outputs = append(sp.Outputs, output)
+			unspent[output.OutPoint] = output
+		}
+	}
+	sp.Height = height
+	return nil
+}
+
+// findSilentPayments returns the indexes of the outputs of the transaction
+// that pay to the spend key, along with the tweaks added to the spend key to
+// get their keys.  The shared secret is derived from the compressed tweak of
+// the transaction with the scan key.  The outputs are looked for in the order
+// BIP-352 derives them in until one isn't in the transaction.
+func findSilentPayments(tx *wire.MsgTx, txTweak []byte, scanKey *btcec.PrivateKey,
+	spendKey *btcec.PublicKey) ([]int, []*btcec.ModNScalar, error) {
+
+	tweakKey, err := btcec.ParsePubKey(txTweak)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tweakPoint, secret btcec.JacobianPoint
+	tweakKey.AsJacobian(&tweakPoint)
+	btcec.ScalarMultNonConst(&scanKey.Key, &tweakPoint, &secret)
+	secret.ToAffine()
+	sharedSecret := btcec.NewPublicKey(&secret.X, &secret.Y).SerializeCompressed()
+
+	taprootOutputs := make(map[[32]byte]int)
+	for i, txOut := range tx.TxOut {
+		if !txscript.IsPayToTaproot(txOut.PkScript) {
+			continue
+		}
+		var key [32]byte
+		copy(key[:], txOut.PkScript[2:])
+		if _, ok := taprootOutputs[key]; !ok {
+			taprootOutputs[key] = i
+		}
+	}
+
+	var (
+		indexes []int
+		tweaks  []*btcec.ModNScalar
+	)
+	for k := uint32(0); len(taprootOutputs) > 0; k++ {
+		outputKey, tweak, err := silentPaymentOutputKey(sharedSecret, k, spendKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		var key [32]byte
+		copy(key[:], schnorr.SerializePubKey(outputKey))
+		index, ok := taprootOutputs[key]
+		if !ok {
+			break
+		}
+		delete(taprootOutputs, key)
+		indexes = append(indexes, index)
+		tweaks = append(tweaks, tweak)
+	}
+	return indexes, tweaks, nil
+}
+
+// loadSilentPayments loads the silent payments of the wallet stored at the
+// path.  Nil is returned when the wallet has no silent payment keys.
+func loadSilentPayments(path string) (*silentPayments, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sp silentPayments
+	if err := json.Unmarshal(data, &sp); err != nil {
+		return nil, err
+	}
+	if err := sp.parseKeys(); err != nil {
+		return nil, err
+	}
+	return &sp, nil
+}
+
+// saveSilentPayments writes the silent payments of the wallet to its file.
+//
+// This function MUST be called with the silent payments mutex held for reads.
+func (m *Manager) saveSilentPayments() error {
+	data, err := json.Marshal(m.silentPayments)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(m.dbPath()+silentPaymentFileSuffix, data)
+}
+
+// ImportSilentPaymentKeys registers the scan key and the spend key of a silent
+// payment address with the wallet and scans the blocks of the main chain from
+// the birthday height for the outputs paying to it.  The outputs are included
+// in the balance, transactions and unspent outputs of the wallet, and are spent
+// with the spend key plus the tweak of the output.  A wallet has a single pair
+// of silent payment keys.  The address of the keys is returned.
+func (m *Manager) ImportSilentPaymentKeys(scanKey *btcec.PrivateKey,
+	spendKey *btcec.PublicKey, birthday int32) (*SilentPaymentAddress, error) {
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.spMtx.RLock()
+	existing := m.silentPayments
+	m.spMtx.RUnlock()
+	if existing != nil {
+		if !existing.scanKey.PubKey().IsEqual(scanKey.PubKey()) ||
+			!existing.spendKey.IsEqual(spendKey) {
+
+			return nil, ErrSilentPaymentKeys
+		}
+		return existing.address(), nil
+	}
+
+	if err := checkRescanBlocks(m.config.Chain, birthday); err != nil {
+		return nil, err
+	}
+	sp := &silentPayments{
+		ScanKey:  scanKey.Serialize(),
+		SpendKey: spendKey.SerializeCompressed(),
+		Birthday: birthday,
+		Height:   birthday - 1,
+		scanKey:  scanKey,
+		spendKey: spendKey,
+	}
+	m.spMtx.Lock()
+	m.silentPayments = sp
+	err := m.saveSilentPayments()
+	m.spMtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if m.config.Chain == nil {
+		return sp.address(), nil
+	}
+	return sp.address(), m.rescanSilentPayments(m.config.Chain.BestSnapshot().Height)
+}
+
+// blockSilentPaymentTweaks returns the silent payment tweaks of the block,
+// either from the silent payment index or, when the node doesn't run it or it
+// hasn't indexed the block, computed from the spend journal of the block.
+func (m *Manager) blockSilentPaymentTweaks(block *btcutil.Block) (
+	[]indexers.SilentPaymentTweak, error) {
+
+	if m.config.SilentPaymentIndex != nil {
+		tweaks, err := m.config.SilentPaymentIndex.FetchBlockTweaks(block.Hash())
+		if err != nil || tweaks != nil {
+			return tweaks, err
+		}
+	}
+
+	// The chain lock is held while the connected blocks are notified, and
+	// the spend journals of the blocks of the main chain don't change, so
+	// the journal is fetched without the lock.
+	stxos, err := m.config.Chain.FetchSpendJournalUnsafe(block)
+	if err != nil {
+		return nil, err
+	}
+	return indexers.BlockSilentPaymentTweaks(block, stxos)
+}
+
+// scanSilentPaymentBlock applies the block of the main chain to the silent
+// payments, forgetting the ones of the blocks it replaces first.
+//
+// This function MUST be called with the manager mutex held.
+func (m *Manager) scanSilentPaymentBlock(block *btcutil.Block) error {
+	tweaks, err := m.blockSilentPaymentTweaks(block)
+	if err != nil {
+		return err
+	}
+
+	m.spMtx.Lock()
+	defer m.spMtx.Unlock()
+	if block.Height() <= m.silentPayments.Height {
+		m.silentPayments.rollback(block.Height() - 1)
+	}
+	return m.silentPayments.applyBlock(block, tweaks)
+}
+
+// rescanSilentPayments scans the blocks of the main chain up to the height
+// that the silent payments haven't been scanned for yet.
+//
+// This function MUST be called with the manager mutex held.
+func (m *Manager) rescanSilentPayments(height int32) error {
+	m.spMtx.RLock()
+	sp := m.silentPayments
+	m.spMtx.RUnlock()
+	if sp == nil || sp.Height >= height {
+		return nil
+	}
+
+	for next := sp.Height + 1; next <= height; next++ {
+		block, err := m.config.Chain.BlockByHeight(next)
+		if err != nil {
+			return err
+		}
+		if err := m.scanSilentPaymentBlock(block); err != nil {
+			return err
+		}
+	}
+	m.spMtx.RLock()
+	defer m.spMtx.RUnlock()
+	return m.saveSilentPayments()
+}
+
+// applyBlockToSilentPayments scans the newly connected block for silent
+// payments after the blocks before it that haven't been scanned yet.
+//
+// This function MUST be called with the manager mutex held.
+func (m *Manager) applyBlockToSilentPayments(block *btcutil.Block) error {
+	m.spMtx.RLock()
+	sp := m.silentPayments
+	m.spMtx.RUnlock()
+	if sp == nil || block.Height() < sp.Birthday {
+		return nil
+	}
+
+	if err := m.rescanSilentPayments(block.Height() - 1); err != nil {
+		return err
+	}
+	if err := m.scanSilentPaymentBlock(block); err != nil {
+		return err
+	}
+	m.spMtx.RLock()
+	defer m.spMtx.RUnlock()
+	return m.saveSilentPayments()
+}
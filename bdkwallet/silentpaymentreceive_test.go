@@ -0,0 +1,118 @@
+package bdkwallet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/utreexo/utreexod/blockchain/indexers"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// testBlock returns the block at the height with a coinbase followed by the
+// transactions.
+func testBlock(height int32, txs ...*wire.MsgTx) *btcutil.Block {
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		[]byte{byte(height)}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(50, []byte{0x51}))
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: append([]*wire.MsgTx{coinbase}, txs...),
+	})
+	block.SetHeight(height)
+	return block
+}
+
+// TestSilentPaymentsApplyBlock ensures that the outputs of a block paying to the
+// silent payment keys are found from the tweaks of its transactions, that they
+// are spent by the blocks spending them and that they're forgotten once their
+// blocks are no longer in the main chain.
+func TestSilentPaymentsApplyBlock(t *testing.T) {
+	scanKey := testKey(t, "0f694e068028a717f8af6b9411f9a133dd3565258714cc226594b34db90c1f2c")
+	spendKey := testKey(t, "9d6ad855ce3417ef84e836892e5a56392bfba05fa5d97ccea30e266f540e08b3")
+	inputKey := testKey(t, "1cd5e8f6b3f29505ed1da7a5806291ebab6491c6a172467e44debe255428a192")
+	var serializedScan [btcec.PubKeyBytesLenCompressed]byte
+	copy(serializedScan[:], scanKey.PubKey().SerializeCompressed())
+
+	// Pay the keys twice in a transaction that also pays to another
+	// taproot output.
+	prevOut := wire.OutPoint{Index: 1}
+	inputs := []SilentPaymentInput{{
+		OutPoint:  prevOut,
+		PublicKey: inputKey.PubKey(),
+		ECDHShares: map[[btcec.PubKeyBytesLenCompressed]byte]*btcec.PublicKey{
+			serializedScan: ecdhShare(inputKey, scanKey.PubKey()),
+		},
+	}}
+	outputs := []SilentPaymentOutput{
+		{Index: 1, ScanKey: scanKey.PubKey(), SpendKey: spendKey.PubKey()},
+		{Index: 2, ScanKey: scanKey.PubKey(), SpendKey: spendKey.PubKey()},
+	}
+	derived, err := deriveSilentPaymentScripts(inputs, outputs, &chaincfg.RegressionNetParams)
+	if err != nil || !derived {
+		t.Fatalf("got %v, %v", derived, err)
+	}
+	other, err := taprootScript(inputKey.PubKey(), &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment := wire.NewMsgTx(wire.TxVersion)
+	payment.AddTxIn(wire.NewTxIn(&prevOut, nil, nil))
+	payment.AddTxOut(wire.NewTxOut(1000, other))
+	payment.AddTxOut(wire.NewTxOut(2000, outputs[0].PkScript))
+	payment.AddTxOut(wire.NewTxOut(3000, outputs[1].PkScript))
+
+	var tweak indexers.SilentPaymentTweak
+	tweak.TxHash = payment.TxHash()
+	copy(tweak.Tweak[:], testTweak(inputKey, prevOut).SerializeCompressed())
+
+	sp := &silentPayments{Height: 9, scanKey: scanKey, spendKey: spendKey.PubKey()}
+	err = sp.applyBlock(testBlock(10, payment), []indexers.SilentPaymentTweak{tweak})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp.Outputs) != 2 || sp.Height != 10 {
+		t.Fatalf("got %d outputs at height %d, want 2 at height 10",
+			len(sp.Outputs), sp.Height)
+	}
+	for i, output := range sp.Outputs {
+		if output.OutPoint.Hash != tweak.TxHash || output.OutPoint.Index != uint32(i+1) ||
+			output.Amount != int64(1000*(i+2)) {
+
+			t.Fatalf("output %d: got %v of %d", i, output.OutPoint, output.Amount)
+		}
+
+		// The output is spent with the spend key plus the tweak.
+		var tweakScalar btcec.ModNScalar
+		tweakScalar.SetByteSlice(output.Tweak)
+		outputKey := (&btcec.PrivateKey{Key: *tweakScalar.Add(&spendKey.Key)}).PubKey()
+		if !bytes.Equal(schnorr.SerializePubKey(outputKey), output.PkScript[2:]) {
+			t.Fatalf("output %d: the tweak doesn't match the output key", i)
+		}
+	}
+
+	// A transaction without a tweak has no silent payments.
+	spend := wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(&sp.Outputs[0].OutPoint, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(1500, outputs[1].PkScript))
+	if err := sp.applyBlock(testBlock(11, spend), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(sp.Outputs) != 2 || sp.Outputs[0].SpentHeight != 11 ||
+		sp.Outputs[0].SpendingTx == nil || sp.Outputs[1].SpendingTx != nil {
+
+		t.Fatal("the spent output isn't marked as spent")
+	}
+
+	sp.rollback(10)
+	if len(sp.Outputs) != 2 || sp.Outputs[0].SpendingTx != nil {
+		t.Fatal("the spend of the disconnected block wasn't forgotten")
+	}
+	sp.rollback(9)
+	if len(sp.Outputs) != 0 || sp.Height != 9 {
+		t.Fatal("the outputs of the disconnected block weren't forgotten")
+	}
+}
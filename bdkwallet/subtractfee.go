@@ -0,0 +1,91 @@
+package bdkwallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// subtractFeePasses is the most times a psbt the fee is subtracted from the
+// outputs of is rebuilt to settle on its fee.  The fee only changes between
+// the passes when the change output is added or removed.
+const subtractFeePasses = 3
+
+// ErrSubtractFeeOutput is returned when the fee is subtracted from an output
+// that doesn't exist or that was picked more than once.
+var ErrSubtractFeeOutput = errors.New("invalid output to subtract the fee from")
+
+// subtractFee returns a copy of the outputs with the fee taken out of the
+// outputs at the indexes.  The fee is split evenly between them and the first
+// of them pays what doesn't divide evenly.
+func subtractFee(outputs []*wire.TxOut, indexes []int, fee btcutil.Amount) ([]*wire.TxOut, error) {
+	reduced := make([]*wire.TxOut, len(outputs))
+	copy(reduced, outputs)
+
+	share := int64(fee) / int64(len(indexes))
+	remainder := int64(fee) % int64(len(indexes))
+	for i, index := range indexes {
+		value := outputs[index].Value - share
+		if i == 0 {
+			value -= remainder
+		}
+		if value <= 0 {
+			return nil, fmt.Errorf("output %d of %v can't pay its share "+
+				"of the fee of %v", index,
+				btcutil.Amount(outputs[index].Value), fee)
+		}
+		reduced[index] = wire.NewTxOut(value, outputs[index].PkScript)
+	}
+	return reduced, nil
+}
+
+// createPsbtSubtractingFee creates a psbt like Wallet.CreatePsbt does with the
+// fee taken out of the outputs at the indexes instead of being paid on top of
+// them.  The psbt is funded as usual first to pick its inputs, so the wallet
+// needs to hold the fee on top of the amounts.  It's then rebuilt from the same
+// inputs with the outputs paying the fee until the fee stays the same.  The
+// outputs the psbt was funded for are returned along with it.
+func (m *Manager) createPsbtSubtractingFee(inputs []wire.OutPoint,
+	outputs []*wire.TxOut, subtractFeeFrom []int, locktime uint32,
+	feerate float32, replaceable, addInputs bool,
+	coinSelection CoinSelection) (FundedPsbt, []*wire.TxOut, error) {
+
+	picked := make(map[int]struct{}, len(subtractFeeFrom))
+	for _, index := range subtractFeeFrom {
+		if _, ok := picked[index]; ok || index < 0 || index >= len(outputs) {
+			return FundedPsbt{}, nil, fmt.Errorf("%w: %d",
+				ErrSubtractFeeOutput, index)
+		}
+		picked[index] = struct{}{}
+	}
+
+	funded, err := m.Wallet.CreatePsbt(inputs, outputs, locktime, feerate,
+		replaceable, addInputs, coinSelection)
+	if err != nil {
+		return FundedPsbt{}, nil, err
+	}
+	spent, err := m.Wallet.PsbtInputs(funded.Psbt)
+	if err != nil {
+		return FundedPsbt{}, nil, err
+	}
+
+	var reduced []*wire.TxOut
+	for pass := 0; pass < subtractFeePasses; pass++ {
+		fee := funded.Fee
+		reduced, err = subtractFee(outputs, subtractFeeFrom, fee)
+		if err != nil {
+			return FundedPsbt{}, nil, err
+		}
+		funded, err = m.Wallet.CreatePsbt(spent, reduced, locktime,
+			feerate, replaceable, false, coinSelection)
+		if err != nil {
+			return FundedPsbt{}, nil, err
+		}
+		if funded.Fee == fee {
+			break
+		}
+	}
+	return funded, reduced, nil
+}
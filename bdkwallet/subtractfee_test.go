@@ -0,0 +1,106 @@
+package bdkwallet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// feeWallet is a wallet that funds psbts with a fixed input and the fees it's
+// given, one for each time a psbt is created with the last one repeated.
+type feeWallet struct {
+	Wallet
+	input   wire.OutPoint
+	fees    []int64
+	outputs [][]*wire.TxOut
+}
+
+func (w *feeWallet) CreatePsbt(inputs []wire.OutPoint, outputs []*wire.TxOut,
+	locktime uint32, feerate float32, replaceable, addInputs bool,
+	coinSelection CoinSelection) (FundedPsbt, error) {
+
+	if len(w.outputs) > 0 && (addInputs || !reflect.DeepEqual(inputs, []wire.OutPoint{w.input})) {
+		return FundedPsbt{}, errors.New("psbt rebuilt from other inputs")
+	}
+	fee := w.fees[len(w.fees)-1]
+	if len(w.outputs) < len(w.fees) {
+		fee = w.fees[len(w.outputs)]
+	}
+	w.outputs = append(w.outputs, outputs)
+	return FundedPsbt{Fee: btcutil.Amount(fee), ChangePos: -1}, nil
+}
+
+func (w *feeWallet) PsbtInputs(psbt []byte) ([]wire.OutPoint, error) {
+	return []wire.OutPoint{w.input}, nil
+}
+
+// TestSubtractFee ensures that the fee is split between the outputs it's
+// subtracted from and that the psbt is rebuilt from the same inputs until its
+// fee settles.
+func TestSubtractFee(t *testing.T) {
+	outputs := []*wire.TxOut{
+		wire.NewTxOut(5000, []byte{1}),
+		wire.NewTxOut(3000, []byte{2}),
+		wire.NewTxOut(2000, []byte{3}),
+	}
+	input := wire.OutPoint{Hash: chainhash.HashH([]byte("input"))}
+
+	// The fee drops once the psbt is rebuilt, which the outputs pay
+	// instead of the first fee.
+	wallet := &feeWallet{input: input, fees: []int64{1001, 901}}
+	m := &Manager{Wallet: wallet}
+	funded, reduced, err := m.createPsbtSubtractingFee(nil, outputs,
+		[]int{2, 0}, 0, 2, true, true, CoinSelectionBranchAndBound)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if funded.Fee != 901 {
+		t.Fatalf("got fee %v, want %v", funded.Fee, btcutil.Amount(901))
+	}
+	want := []*wire.TxOut{
+		wire.NewTxOut(4550, []byte{1}),
+		wire.NewTxOut(3000, []byte{2}),
+		wire.NewTxOut(1549, []byte{3}),
+	}
+	if !reflect.DeepEqual(reduced, want) {
+		t.Fatalf("got outputs %v, want %v", reduced, want)
+	}
+	if len(wallet.outputs) != 3 {
+		t.Fatalf("psbt created %d times, want 3", len(wallet.outputs))
+	}
+	if outputs[0].Value != 5000 || outputs[2].Value != 2000 {
+		t.Fatal("outputs the fee is subtracted from were modified")
+	}
+
+	tests := []struct {
+		name    string
+		indexes []int
+	}{
+		{name: "out of range", indexes: []int{3}},
+		{name: "negative", indexes: []int{-1}},
+		{name: "duplicate", indexes: []int{1, 1}},
+	}
+	for _, test := range tests {
+		wallet := &feeWallet{input: input, fees: []int64{1000}}
+		m := &Manager{Wallet: wallet}
+		_, _, err := m.createPsbtSubtractingFee(nil, outputs, test.indexes,
+			0, 2, true, true, CoinSelectionBranchAndBound)
+		if !errors.Is(err, ErrSubtractFeeOutput) {
+			t.Fatalf("%s: got error %v, want %v", test.name, err,
+				ErrSubtractFeeOutput)
+		}
+	}
+
+	// An output smaller than its share of the fee can't pay it.
+	wallet = &feeWallet{input: input, fees: []int64{2000}}
+	m = &Manager{Wallet: wallet}
+	_, _, err = m.createPsbtSubtractingFee(nil, outputs, []int{2},
+		0, 2, true, true, CoinSelectionBranchAndBound)
+	if err == nil {
+		t.Fatal("fee subtracted from an output smaller than it")
+	}
+}
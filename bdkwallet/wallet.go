@@ -2,11 +2,14 @@ package bdkwallet
 
 import (
 	"errors"
+	"fmt"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
 	"github.com/utreexo/utreexod/mempool"
+	"github.com/utreexo/utreexod/wire"
 )
 
 var (
@@ -28,6 +31,7 @@ func factory() (WalletFactory, error) {
 // WalletFactory creates wallets.
 type WalletFactory interface {
 	Create(dbPath string, chainParams *chaincfg.Params) (Wallet, error)
+	CreateWatchOnly(dbPath string, chainParams *chaincfg.Params, descriptor string, birthday uint32) (Wallet, error)
 	Load(dbPath string) (Wallet, error)
 }
 
@@ -40,8 +44,26 @@ type Wallet interface {
 	Balance() Balance
 	RecentBlocks(count uint32) []BlockId
 	ApplyBlock(block *btcutil.Block) error
+	ApplyBlockToImports(block *btcutil.Block) error
+	ImportsHeight() uint
+	Scripts() [][]byte
+	WatchOnly() bool
+	Birthday() uint
 	ApplyMempoolTransactions(txns []*mempool.TxDesc) error
-	CreateTx(feerate float32, recipients []Recipient) ([]byte, error)
+	SetLockedUTXOs(outpoints []wire.OutPoint)
+	CreateTx(feerate float32, recipients []Recipient, inputs []wire.OutPoint, coinSelection CoinSelection) ([]byte, error)
+	CreatePsbt(inputs []wire.OutPoint, outputs []*wire.TxOut, locktime uint32, feerate float32, replaceable, addInputs bool, coinSelection CoinSelection) (FundedPsbt, error)
+	CreateSweep(feerate float32, pkScript []byte, inputs []wire.OutPoint) (FundedPsbt, error)
+	BumpFee(txid chainhash.Hash, feerate float32) (FeeBump, error)
+	CreateCpfp(txid chainhash.Hash, feerate float32) (FeeBump, error)
+	ProcessPsbt(psbt []byte, sign bool) ([]byte, bool, error)
+	FinalizePsbt(psbt []byte) ([]byte, *btcutil.Tx, error)
+	PsbtInputs(psbt []byte) ([]wire.OutPoint, error)
+	PsbtOutputs(psbt []byte) ([]*wire.TxOut, error)
+	SilentPaymentPsbt(psbt []byte, outputs []SilentPaymentOutput) (SilentPaymentPsbt, error)
+	UpdatePsbt(psbt []byte, prevTxs []*btcutil.Tx) ([]byte, error)
+	ImportDescriptor(descriptor string, birthday uint32) error
+	Descriptors(includePrivate bool) []DescriptorInfo
 	MnemonicWords() []string
 	Transactions() ([]TxInfo, error)
 	UTXOs() []UTXOInfo
@@ -73,8 +95,93 @@ type BlockId struct {
 
 // Recipient specifies the intended amount and destination address for a transaction output.
 type Recipient struct {
-	Amount  btcutil.Amount // amount to send
-	Address string         // recipient address to send to (in human-readable form)
+	Amount      btcutil.Amount // amount to send
+	Address     string         // recipient address to send to (in human-readable form)
+	SubtractFee bool           // whether the fee is taken out of the amount
+}
+
+// CoinSelection is the strategy used to select the unspent outputs that fund a transaction.
+type CoinSelection uint8
+
+const (
+	// CoinSelectionBranchAndBound searches for a set of unspent outputs that needs no change
+	// output and falls back to a random selection when there is none.
+	CoinSelectionBranchAndBound CoinSelection = iota
+
+	// CoinSelectionKnapsack picks the set of unspent outputs whose value is the closest to the
+	// amount needed while leaving either no change or enough change to be worth an output.
+	CoinSelectionKnapsack
+
+	// CoinSelectionAvoidPartialSpends selects the unspent outputs of an address together so that
+	// no address is left partially spent.
+	CoinSelectionAvoidPartialSpends
+)
+
+// coinSelectionStrings maps the coin selection strategies to their names.
+var coinSelectionStrings = map[CoinSelection]string{
+	CoinSelectionBranchAndBound:     "branchandbound",
+	CoinSelectionKnapsack:           "knapsack",
+	CoinSelectionAvoidPartialSpends: "avoidpartialspends",
+}
+
+// String returns the name of the coin selection strategy.
+func (c CoinSelection) String() string {
+	if s, ok := coinSelectionStrings[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown CoinSelection (%d)", uint8(c))
+}
+
+// ParseCoinSelection returns the coin selection strategy with the given name.
+func ParseCoinSelection(name string) (CoinSelection, error) {
+	for c, s := range coinSelectionStrings {
+		if s == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown coin selection strategy %q", name)
+}
+
+// FundedPsbt is a psbt funded by the wallet.
+type FundedPsbt struct {
+	Psbt      []byte         // serialized psbt
+	Fee       btcutil.Amount // fee paid by the psbt
+	ChangePos int            // index of the change output or -1 if there is none
+}
+
+// FeeBump is a psbt that bumps the fee of an unconfirmed transaction of the wallet, either by
+// replacing it or by spending one of its outputs.
+type FeeBump struct {
+	Psbt        []byte         // serialized psbt
+	OriginalFee btcutil.Amount // fee paid by the transaction whose fee is bumped
+	Fee         btcutil.Amount // fee paid by the psbt
+}
+
+// SilentPaymentInput is an input of a psbt paying to silent payment addresses.
+type SilentPaymentInput struct {
+	OutPoint wire.OutPoint
+
+	// PublicKey is the key BIP352 takes from the input or nil if the input isn't eligible.
+	PublicKey *btcec.PublicKey
+
+	// ECDHShares are the ECDH shares of the input keyed by their compressed scan keys.
+	ECDHShares map[[btcec.PubKeyBytesLenCompressed]byte]*btcec.PublicKey
+}
+
+// SilentPaymentOutput is an output of a psbt paying to a silent payment address.
+type SilentPaymentOutput struct {
+	Index    int              // index of the output in the psbt
+	ScanKey  *btcec.PublicKey // scan key of the address
+	SpendKey *btcec.PublicKey // spend key of the address
+	PkScript []byte           // script of the output, which is left as it is when nil
+}
+
+// SilentPaymentPsbt is a psbt along with what the wallet knows of its silent payment outputs and
+// of the inputs their output keys are derived from.
+type SilentPaymentPsbt struct {
+	Psbt    []byte // serialized psbt
+	Inputs  []SilentPaymentInput
+	Outputs []SilentPaymentOutput
 }
 
 // TxInfo is information on a given transaction.
@@ -86,6 +193,14 @@ type TxInfo struct {
 	Confirmations uint           // number of confirmations for this tx
 }
 
+// DescriptorInfo is information on an output descriptor tracked by the wallet.
+type DescriptorInfo struct {
+	Descriptor string // descriptor along with its checksum
+	Internal   bool   // whether the descriptor is of a change keychain
+	Birthday   uint   // block height the descriptor is tracked from
+	NextIndex  uint   // derivation index of the next address to be revealed
+}
+
 // UtxoInfo is information on a given transaction.
 type UTXOInfo struct {
 	Txid            chainhash.Hash
@@ -95,6 +210,11 @@ type UTXOInfo struct {
 	IsChange        bool
 	DerivationIndex uint
 	Confirmations   uint // number of confirmations for this utxo
+
+	// SilentPaymentTweak is the tweak added to the spend key of the
+	// silent payment keys of the wallet to spend the utxo, or nil if the
+	// utxo isn't a silent payment.
+	SilentPaymentTweak []byte
 }
 
 func hashFromBytes(b []byte) chainhash.Hash {
@@ -0,0 +1,240 @@
+package bdkwallet
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/utreexo/utreexod/mempool"
+)
+
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrWalletNotLoaded     = errors.New("wallet is not loaded")
+	ErrWalletLoaded        = errors.New("wallet is already loaded")
+	ErrWalletExists        = errors.New("wallet already exists")
+	ErrInvalidWalletName   = errors.New("wallet names may only have letters, digits, '.', '-' and '_'")
+	ErrUnloadDefaultWallet = errors.New("the default wallet can't be unloaded")
+)
+
+// walletNameRegexp matches the names that named wallets can be given.  The
+// names are used as file names in the wallet directory.
+var walletNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// checkWalletName returns an error if the name can't be given to a named
+// wallet.
+func checkWalletName(name string) error {
+	if !walletNameRegexp.MatchString(name) ||
+		name+".dat" == defaultWalletFileName {
+
+		return fmt.Errorf("%w: %q", ErrInvalidWalletName, name)
+	}
+	return nil
+}
+
+// Wallets keeps track of the wallets that are loaded.  The default wallet is
+// loaded when the node starts and stays loaded while named wallets are
+// created, loaded and unloaded at runtime.
+type Wallets struct {
+	config ManagerConfig
+
+	// Default is the manager of the default wallet.
+	Default *Manager
+
+	mtx   sync.RWMutex
+	named map[string]*Manager
+
+	// notifications are the callbacks of the subscribers to the events of
+	// all the loaded wallets, which the wallets loaded later are
+	// subscribed to as well.
+	notifications []NotificationCallback
+
+	quit     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewWallets loads the default wallet, creating it if it doesn't exist yet.
+func NewWallets(config ManagerConfig) (*Wallets, error) {
+	config.WalletName = ""
+	if config.BackupDir == "" {
+		config.BackupDir = defaultBackupDir(config.DataDir)
+	}
+	manager, err := NewManager(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallets{
+		config:  config,
+		Default: manager,
+		named:   make(map[string]*Manager),
+		quit:    make(chan struct{}),
+	}, nil
+}
+
+// Get returns the manager of the loaded wallet with the name.  The default
+// wallet has an empty name.
+func (w *Wallets) Get(name string) (*Manager, error) {
+	if name == "" {
+		return w.Default, nil
+	}
+	w.mtx.RLock()
+	manager, ok := w.named[name]
+	w.mtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrWalletNotLoaded, name)
+	}
+	return manager, nil
+}
+
+// List returns the names of the loaded wallets, starting with the default
+// wallet.
+func (w *Wallets) List() []string {
+	w.mtx.RLock()
+	names := make([]string, 0, len(w.named))
+	for name := range w.named {
+		names = append(names, name)
+	}
+	w.mtx.RUnlock()
+	sort.Strings(names)
+	return append([]string{""}, names...)
+}
+
+// Create creates the named wallet and loads it.  The wallet is created from a
+// fresh mnemonic unless watchOnly is set, in which case it's created as a
+// watch-only wallet of the external signer.
+func (w *Wallets) Create(name string, watchOnly bool) (*Manager, error) {
+	if err := checkWalletName(name); err != nil {
+		return nil, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if _, ok := w.named[name]; ok {
+		return nil, fmt.Errorf("%w: %q", ErrWalletLoaded, name)
+	}
+	exists, err := w.exists(name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: %q", ErrWalletExists, name)
+	}
+
+	config := w.config
+	config.WalletName = name
+	config.WatchOnlyDescriptor = ""
+	if watchOnly {
+		if config.SignerCommand == "" {
+			return nil, errors.New("watch-only wallets can only be " +
+				"created from an external signer (--bdksigner)")
+		}
+	} else {
+		config.SignerCommand = ""
+	}
+	return w.load(config)
+}
+
+// Load loads the named wallet that was created before.
+func (w *Wallets) Load(name string) (*Manager, error) {
+	if err := checkWalletName(name); err != nil {
+		return nil, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if _, ok := w.named[name]; ok {
+		return nil, fmt.Errorf("%w: %q", ErrWalletLoaded, name)
+	}
+	exists, err := w.exists(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %q", ErrWalletNotFound, name)
+	}
+
+	config := w.config
+	config.WalletName = name
+	config.WatchOnlyDescriptor = ""
+	return w.load(config)
+}
+
+// load starts the manager of the named wallet and keeps track of it.
+//
+// This function MUST be called with the wallets mutex held.
+func (w *Wallets) load(config ManagerConfig) (*Manager, error) {
+	manager, err := NewManager(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, callback := range w.notifications {
+		manager.Subscribe(callback)
+	}
+	w.named[config.WalletName] = manager
+	return manager, nil
+}
+
+// exists returns whether the file of the named wallet exists.
+func (w *Wallets) exists(name string) (bool, error) {
+	dbPath := filepath.Join(WalletDir(w.config.DataDir), walletFileName(name))
+	if _, err := os.Stat(dbPath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Unload unloads the named wallet.  The wallet stays on disk and can be
+// loaded again.
+func (w *Wallets) Unload(name string) error {
+	if name == "" {
+		return ErrUnloadDefaultWallet
+	}
+
+	w.mtx.Lock()
+	manager, ok := w.named[name]
+	delete(w.named, name)
+	w.mtx.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrWalletNotLoaded, name)
+	}
+
+	manager.close()
+	log.Infof("Unloaded the BDK wallet %s.", name)
+	return nil
+}
+
+// Subscribe registers the callback to be executed on the events of all the
+// loaded wallets, including the ones that are loaded later.  The notifications
+// tell which wallet the events are of.
+func (w *Wallets) Subscribe(callback NotificationCallback) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.notifications = append(w.notifications, callback)
+	w.Default.Subscribe(callback)
+	for _, manager := range w.named {
+		manager.Subscribe(callback)
+	}
+}
+
+// NotifyNewTransactions applies the mempool transactions to all the loaded
+// wallets.
+func (w *Wallets) NotifyNewTransactions(txns []*mempool.TxDesc) {
+	w.Default.NotifyNewTransactions(txns)
+
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+	for _, manager := range w.named {
+		manager.NotifyNewTransactions(txns)
+	}
+}
@@ -0,0 +1,94 @@
+package bdkwallet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCheckWalletName ensures that only names that are safe to use as file
+// names in the wallet directory are accepted for named wallets.
+func TestCheckWalletName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"savings", true},
+		{"cold-storage_2", true},
+		{"v1.0", true},
+		{"", false},
+		{"default", false},
+		{".hidden", false},
+		{"../escape", false},
+		{"a/b", false},
+		{"with space", false},
+	}
+	for _, test := range tests {
+		err := checkWalletName(test.name)
+		if test.valid && err != nil {
+			t.Errorf("%q: unexpected error %v", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidWalletName) {
+			t.Errorf("%q: got error %v, want %v", test.name, err,
+				ErrInvalidWalletName)
+		}
+	}
+}
+
+// TestWallets ensures that the named wallets are looked up, loaded and
+// unloaded by their names.
+func TestWallets(t *testing.T) {
+	dataDir := t.TempDir()
+	walletDir := WalletDir(dataDir)
+	if err := os.MkdirAll(walletDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(walletDir, "savings.dat"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	def := &Manager{}
+	w := &Wallets{
+		config:  ManagerConfig{DataDir: dataDir},
+		Default: def,
+		named:   map[string]*Manager{"spending": {}},
+	}
+
+	if manager, err := w.Get(""); err != nil || manager != def {
+		t.Fatalf("got %v, %v for the default wallet", manager, err)
+	}
+	if _, err := w.Get("savings"); !errors.Is(err, ErrWalletNotLoaded) {
+		t.Fatalf("got error %v, want %v", err, ErrWalletNotLoaded)
+	}
+	if _, err := w.Load("missing"); !errors.Is(err, ErrWalletNotFound) {
+		t.Fatalf("got error %v, want %v", err, ErrWalletNotFound)
+	}
+	if _, err := w.Load("spending"); !errors.Is(err, ErrWalletLoaded) {
+		t.Fatalf("got error %v, want %v", err, ErrWalletLoaded)
+	}
+	if _, err := w.Create("savings", false); !errors.Is(err, ErrWalletExists) {
+		t.Fatalf("got error %v, want %v", err, ErrWalletExists)
+	}
+	if _, err := w.Create("cold", true); err == nil {
+		t.Fatal("expected an error for a watch-only wallet without a signer")
+	}
+
+	names := w.List()
+	if len(names) != 2 || names[0] != "" || names[1] != "spending" {
+		t.Fatalf("got wallets %q", names)
+	}
+
+	if err := w.Unload(""); !errors.Is(err, ErrUnloadDefaultWallet) {
+		t.Fatalf("got error %v, want %v", err, ErrUnloadDefaultWallet)
+	}
+	if err := w.Unload("spending"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Unload("spending"); !errors.Is(err, ErrWalletNotLoaded) {
+		t.Fatalf("got error %v, want %v", err, ErrWalletNotLoaded)
+	}
+	if names := w.List(); len(names) != 1 {
+		t.Fatalf("got wallets %q", names)
+	}
+}
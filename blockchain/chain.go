@@ -11,7 +11,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/utreexo/utreexo"
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
@@ -134,6 +133,12 @@ func (b *BlockChain) SetNewBestStateFromAssumedUtreexoPoint() {
 // follow all rules, orphan handling, checkpoint handling, and best chain
 // selection with reorganization.
 type BlockChain struct {
+	// The following fields are used to account for the utreexo proofs
+	// served to peers.  They must be accessed atomically and are placed
+	// first for 64-bit alignment.
+	proofsServed     uint64
+	proofBytesServed uint64
+
 	// The following fields are set when the instance is created and can't
 	// be changed afterwards, so there is no need to protect them with a
 	// separate mutex.
@@ -188,6 +193,19 @@ type BlockChain struct {
 	// from peers.
 	utreexoView *UtreexoViewpoint
 
+	// rememberPolicy decides which of the leaves added to the utreexoView
+	// are cached.
+	rememberPolicy RememberPolicy
+
+	// leafHashCache memoizes the hashes of the leaves spent by mempool
+	// transactions.  It's nil if the utreexoView is nil.
+	leafHashCache *leafHashCache
+
+	// rootCheckpoints are the known good accumulator states keyed by
+	// height.  They're checked on startup and whenever a block at one of
+	// the heights is connected.
+	rootCheckpoints map[int32]*UtreexoRootCheckpoint
+
 	// These fields are related to handling of orphan blocks.  They are
 	// protected by a combination of the chain lock and the orphan lock.
 	orphanLock   sync.RWMutex
@@ -641,6 +659,17 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 		}
 	}
 
+	// Check the accumulator of the utreexo proof index against the
+	// utreexo root checkpoint before the index connects the block.  The
+	// accumulator of utreexo nodes is checked before it's modified when
+	// the block is validated.
+	if b.utreexoView == nil {
+		err := b.checkIndexRootCheckpoint(node, block, stxos)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Write any block status changes to DB before updating best state.
 	err := b.index.flushToDB()
 	if err != nil {
@@ -676,7 +705,22 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 			// NODE_NETWORK_LIMITED service bit requires that the last 288 blocks.
 			// Since we just saved block with `node.height`, the minimum block height
 			// we need to keep is `node.height-287`.
-			earliestKeptBlockHeight, err := dbTx.PruneBlocks(b.pruneTarget, node.height-287)
+			keepHeight := node.height - 287
+
+			// The indexes may need earlier blocks to be kept as well.
+			if b.indexManager != nil {
+				indexKeepHeight, err := b.indexManager.KeepHeight(dbTx)
+				if err != nil {
+					return err
+				}
+				if indexKeepHeight >= 0 &&
+					(keepHeight <= 0 || indexKeepHeight < keepHeight) {
+
+					keepHeight = indexKeepHeight
+				}
+			}
+
+			earliestKeptBlockHeight, err := dbTx.PruneBlocks(b.pruneTarget, keepHeight)
 			if err != nil {
 				log.Warnf("Prune failed on block height %d, hash %s. Error %v",
 					node.height, node.hash.String(), err)
@@ -719,6 +763,13 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 			return err
 		}
 
+		// Add the total number of transactions in the main chain up
+		// to the block.
+		err = dbPutChainTxCount(dbTx, node.height, state.TotalTxns)
+		if err != nil {
+			return err
+		}
+
 		// Update the transaction spend journal by adding a record for
 		// the block that contains all txos spent by it.
 		err = dbPutSpendJournalEntry(dbTx, block.Hash(), stxos)
@@ -851,6 +902,11 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view
 			return err
 		}
 
+		err = dbRemoveChainTxCount(dbTx, node.height)
+		if err != nil {
+			return err
+		}
+
 		if b.utxoCache != nil {
 			// Flush the cache on every disconnect. Since the code for
 			// reorganization modifies the database directly, the cache
@@ -1007,10 +1063,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 			if err != nil {
 				return err
 			}
-			delHashes := make([]utreexo.Hash, len(dels))
-			for i := range delHashes {
-				delHashes[i] = dels[i].LeafHash()
-			}
+			delHashes := wire.LeafHashes(dels)
 
 			// Generate the adds.
 			adds := BlockToAddLeaves(block, outskip, nil, outCount)
@@ -1068,9 +1121,15 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 				return err
 			}
 		} else {
+			err := b.checkUtreexoViewRootCheckpoint(b.utreexoView, n, block)
+			if err != nil {
+				return err
+			}
+
 			// Check that the block txOuts are valid by checking the utreexo proof and
 			// extra data and then update the accumulator.
-			err := b.utreexoView.ProcessUData(block, b.bestChain, block.MsgBlock().UData)
+			err = b.utreexoView.ProcessUData(block, b.bestChain, block.MsgBlock().UData,
+				b.rememberPolicy, b.leafHashCache)
 			if err != nil {
 				return fmt.Errorf("reorganizeChain fail while attaching "+
 					"block %s. Error: %v", block.Hash().String(), err)
@@ -1226,7 +1285,8 @@ func (b *BlockChain) verifyReorganizationValidity(detachNodes, attachNodes *list
 			// block.  The added data here is needed to undo utreexo
 			// proofs.
 			copyUView := prevUView.CopyWithRoots()
-			err = copyUView.ProcessUData(block, b.bestChain, block.MsgBlock().UData)
+			err = copyUView.ProcessUData(block, b.bestChain, block.MsgBlock().UData,
+				b.rememberPolicy, nil)
 			if err != nil {
 				return nil, nil, nil,
 					fmt.Errorf("verifyReorganizationValidity fail "+
@@ -1304,7 +1364,7 @@ func (b *BlockChain) verifyReorganizationValidity(detachNodes, attachNodes *list
 
 			// Reconstruct the utreexo data as it's stored in the compact state.
 			_, _, inskip, _ := DedupeBlock(block)
-			_, err := reconstructUData(block.MsgBlock().UData, block, b.bestChain, inskip)
+			_, err := reconstructUData(block.MsgBlock().UData, block, b.bestChain, inskip, nil)
 			if err != nil {
 				return nil, nil, nil, fmt.Errorf("verifyReorganizationValidity fail "+
 					"while reconstructing udata. Error: %v", err)
@@ -1329,7 +1389,8 @@ func (b *BlockChain) verifyReorganizationValidity(detachNodes, attachNodes *list
 			} else {
 				// Check that the block txOuts are valid by checking the utreexo proof and
 				// extra data and then update the accumulator.
-				err := utreexoView.ProcessUData(block, b.bestChain, block.MsgBlock().UData)
+				err := utreexoView.ProcessUData(block, b.bestChain, block.MsgBlock().UData,
+					b.rememberPolicy, nil)
 				if err != nil {
 					return nil, nil, nil,
 						fmt.Errorf("verifyReorganizationValidity fail "+
@@ -1444,9 +1505,22 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *btcutil.Block, fla
 		stxos := make([]SpentTxOut, 0, countSpentOutputs(block))
 		if b.utreexoView != nil {
 			if fastAdd {
+				err := b.checkUtreexoViewRootCheckpoint(b.utreexoView,
+					node, block)
+				if err != nil {
+					if _, ok := err.(RuleError); ok {
+						b.index.SetStatusFlags(
+							node, statusValidateFailed,
+						)
+						flushIndexState()
+					}
+					return false, err
+				}
+
 				// Check that the block txOuts are valid by checking the utreexo proof and
 				// extra data and then update the accumulator.
-				err := b.utreexoView.ProcessUData(block, b.bestChain, block.MsgBlock().UData)
+				err = b.utreexoView.ProcessUData(block, b.bestChain, block.MsgBlock().UData,
+					b.rememberPolicy, b.leafHashCache)
 				if err != nil {
 					return false, fmt.Errorf("connectBestChain fail on block %s. "+
 						"Error: %v", block.Hash().String(), err)
@@ -1595,6 +1669,14 @@ func (b *BlockChain) BestSnapshot() *BestState {
 	return snapshot
 }
 
+// IsPruneEnabled returns whether the chain deletes old blocks to keep the
+// database under the prune target.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsPruneEnabled() bool {
+	return b.pruneTarget != 0
+}
+
 // TipStatus is the status of a chain tip.
 type TipStatus byte
 
@@ -1804,6 +1886,20 @@ func (b *BlockChain) BlockHashByHeight(blockHeight int32) (*chainhash.Hash, erro
 	return &node.hash, nil
 }
 
+// MedianTimeByHeight returns the median time of the block at the given height
+// in the main chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) MedianTimeByHeight(blockHeight int32) (time.Time, error) {
+	node := b.bestChain.NodeByHeight(blockHeight)
+	if node == nil {
+		str := fmt.Sprintf("no block at height %d exists", blockHeight)
+		return time.Time{}, errNotInMainChain(str)
+	}
+
+	return node.CalcPastMedianTime(), nil
+}
+
 // HeightRange returns a range of block hashes for the given start and end
 // heights.  It is inclusive of the start height and exclusive of the end
 // height.  The end height will be limited to the current main chain height.
@@ -2328,6 +2424,11 @@ type IndexManager interface {
 	// PruneBlock is invoked when an older block is deleted after it's been
 	// processed. This lowers the storage requirement for a node.
 	PruneBlocks(database.Tx, int32, func(int32) (*chainhash.Hash, error)) error
+
+	// KeepHeight returns the height of the earliest block that the indexes
+	// need to stay on disk or -1 if they don't need any of them.  Blocks at
+	// and after the returned height aren't pruned.
+	KeepHeight(database.Tx) (int32, error)
 }
 
 // Config is a descriptor which specifies the blockchain instance configuration.
@@ -2408,6 +2509,21 @@ type Config struct {
 	// This field can be nil as being a utreexo node is optional.
 	UtreexoView *UtreexoViewpoint
 
+	// RememberPolicy decides which of the leaves added to the UtreexoView
+	// are cached.
+	//
+	// This field can be nil in which case the leaves the bridge marked to
+	// be remembered are cached.
+	RememberPolicy RememberPolicy
+
+	// UtreexoRootCheckpoints are known good states of the utreexo
+	// accumulator.  The accumulator of the node is checked against them on
+	// startup and while syncing.
+	//
+	// This field can be nil if the caller doesn't want to check the
+	// accumulator.
+	UtreexoRootCheckpoints []UtreexoRootCheckpoint
+
 	// Prune specifies the target database usage (in bytes) the database will target for with
 	// block and spend journal files.  Prune at 0 specifies that no blocks will be deleted.
 	Prune uint64
@@ -2444,6 +2560,15 @@ func New(config *Config) (*BlockChain, error) {
 		}
 	}
 
+	var utreexoRootCheckpoints map[int32]*UtreexoRootCheckpoint
+	if len(config.UtreexoRootCheckpoints) > 0 {
+		utreexoRootCheckpoints = make(map[int32]*UtreexoRootCheckpoint)
+		for i := range config.UtreexoRootCheckpoints {
+			checkpoint := &config.UtreexoRootCheckpoints[i]
+			utreexoRootCheckpoints[checkpoint.Height] = checkpoint
+		}
+	}
+
 	// UtreexoView replaces utxo caches.  Only make them when UtreexoView is
 	// not set.
 	var utxoCache *utxoCache
@@ -2473,6 +2598,7 @@ func New(config *Config) (*BlockChain, error) {
 		index:               newBlockIndex(config.DB, params),
 		utxoCache:           utxoCache,
 		utreexoView:         config.UtreexoView,
+		rememberPolicy:      config.RememberPolicy,
 		hashCache:           config.HashCache,
 		bestChain:           newChainView(nil),
 		orphans:             make(map[chainhash.Hash]*orphanBlock),
@@ -2480,6 +2606,10 @@ func New(config *Config) (*BlockChain, error) {
 		warningCaches:       newThresholdCaches(vbNumBits),
 		deploymentCaches:    newThresholdCaches(chaincfg.DefinedDeployments),
 		pruneTarget:         config.Prune,
+		rootCheckpoints:     utreexoRootCheckpoints,
+	}
+	if config.UtreexoView != nil {
+		b.leafHashCache = newLeafHashCache()
 	}
 
 	// Ensure all the deployments are synchronized with our clock if
@@ -2496,6 +2626,12 @@ func New(config *Config) (*BlockChain, error) {
 		}
 	}
 
+	// Perform any upgrades to the various chain-specific buckets as needed
+	// before they're loaded.
+	if err := b.maybeUpgradeDbBuckets(config.Interrupt); err != nil {
+		return nil, err
+	}
+
 	// Initialize the chain state from the passed database.  When the db
 	// does not yet contain any chain state, both it and the chain state
 	// will be initialized to contain only the genesis block.
@@ -2503,8 +2639,9 @@ func New(config *Config) (*BlockChain, error) {
 		return nil, err
 	}
 
-	// Perform any upgrades to the various chain-specific buckets as needed.
-	if err := b.maybeUpgradeDbBuckets(config.Interrupt); err != nil {
+	// Build the chain transaction counts of chain states created before
+	// they were kept.
+	if err := b.initChainTxCounts(); err != nil {
 		return nil, err
 	}
 
@@ -2528,6 +2665,11 @@ func New(config *Config) (*BlockChain, error) {
 		}
 	}
 
+	// Make sure the accumulator wasn't corrupted while the node was down.
+	if err := b.verifyUtreexoRootCheckpoints(); err != nil {
+		return nil, err
+	}
+
 	// Initialize rule change threshold state caches.
 	if err := b.initThresholdCaches(); err != nil {
 		return nil, err
@@ -24,10 +24,6 @@ const (
 	// constant from wire and is only provided here for convenience since
 	// wire.MaxBlockHeaderPayload is quite long.
 	blockHdrSize = wire.MaxBlockHeaderPayload
-
-	// latestUtxoSetBucketVersion is the current version of the utxo set
-	// bucket that is used to track all unspent outputs.
-	latestUtxoSetBucketVersion = 2
 )
 
 var (
@@ -701,6 +697,13 @@ func deserializeUtxoEntry(serialized []byte) (*UtxoEntry, error) {
 	return entry, nil
 }
 
+// DeserializeUtxoEntry decodes a utxo entry from the passed serialized byte
+// slice.  The serialization format is the same as the one used by Bitcoin Core
+// for the coins in its chainstate so it can be used to decode those as well.
+func DeserializeUtxoEntry(serialized []byte) (*UtxoEntry, error) {
+	return deserializeUtxoEntry(serialized)
+}
+
 // dbFetchUtxoEntryByHash attempts to find and fetch a utxo for the given hash.
 // It uses a cursor and seek to try and do this as efficiently as possible.
 //
@@ -1167,6 +1170,25 @@ func dbFetchUtreexoView(dbTx database.Tx, blockHash *chainhash.Hash) (*UtreexoVi
 	return uView, err
 }
 
+// DBFetchUtreexoRoots uses an existing database transaction to fetch the roots
+// and the number of leaves of the utreexo viewpoint stored for the block with
+// the given hash.  The boolean is false if no viewpoint is stored for the block,
+// which is always the case for nodes that don't keep the utreexo compact state.
+func DBFetchUtreexoRoots(dbTx database.Tx, blockHash *chainhash.Hash) (
+	[]*chainhash.Hash, uint64, bool, error) {
+
+	if dbTx.Metadata().Bucket(utreexoStateBucketName) == nil {
+		return nil, 0, false, nil
+	}
+
+	uView, err := dbFetchUtreexoView(dbTx, blockHash)
+	if err != nil || uView == nil {
+		return nil, 0, false, err
+	}
+
+	return uView.GetRoots(), uView.NumLeaves(), true, nil
+}
+
 // dbRemoveUtreexoView deletes the utreexoViewpoint at the given hash from the database.
 func dbRemoveUtreexoView(dbTx database.Tx, blockHash chainhash.Hash) error {
 	utreexoBucket := dbTx.Metadata().Bucket(utreexoStateBucketName)
@@ -1221,12 +1243,26 @@ func (b *BlockChain) createChainState() error {
 			return err
 		}
 
-		err = dbPutVersion(dbTx, utxoSetVersionKeyName,
-			latestUtxoSetBucketVersion)
+		// Create the bucket that houses the chain transaction counts
+		// and store its version.
+		_, err = meta.CreateBucket(chainTxCountBucketName)
+		if err != nil {
+			return err
+		}
+
+		err = dbPutVersion(dbTx, chainTxCountVersionKeyName, 1)
 		if err != nil {
 			return err
 		}
 
+		// Store the latest versions of the block index, chain state
+		// and utxo set.
+		for _, schema := range chainDbSchemas {
+			if err := PutLatestDbSchemaVersion(dbTx, schema); err != nil {
+				return err
+			}
+		}
+
 		// Create the bucket that houses the utxo set and store its
 		// version.  Note that the genesis block coinbase transaction is
 		// intentionally not inserted here since it is not spendable by
@@ -1249,6 +1285,13 @@ func (b *BlockChain) createChainState() error {
 			return err
 		}
 
+		// Add the genesis block transactions to the chain transaction
+		// counts.
+		err = dbPutChainTxCount(dbTx, node.height, numTxns)
+		if err != nil {
+			return err
+		}
+
 		// Store the current best chain state into the database.
 		err = dbPutBestState(dbTx, b.stateSnapshot, node.workSum)
 		if err != nil {
@@ -0,0 +1,201 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// chainTxCountBatchSize is the number of blocks whose transaction
+	// counts are written in a single database transaction when building the
+	// chain transaction count index of an existing chain.
+	chainTxCountBatchSize = 10000
+)
+
+var (
+	// chainTxCountBucketName is the name of the db bucket used to house the
+	// block height -> number of transactions in the chain up to and
+	// including the block index.
+	chainTxCountBucketName = []byte("chaintxcountidx")
+
+	// chainTxCountVersionKeyName is the name of the db key used to store
+	// the version of the chain transaction count index.  It's only stored
+	// once the index covers every block of the main chain that's stored.
+	chainTxCountVersionKeyName = []byte("chaintxcountversion")
+)
+
+// -----------------------------------------------------------------------------
+// The chain transaction count index keeps the total number of transactions in
+// the main chain up to and including each block so that transaction rates over
+// any window of blocks are cheap to compute.
+//
+// The serialized key format is:
+//
+//   <block height>
+//
+//   Field          Type      Size
+//   block height   uint32    4 bytes (big endian)
+//
+// The serialized value format is:
+//
+//   <chain tx count>
+//
+//   Field          Type      Size
+//   chain tx count uint64    8 bytes
+// -----------------------------------------------------------------------------
+
+// chainTxCountKey returns the key of the chain transaction count of the block
+// at the height.
+func chainTxCountKey(height int32) []byte {
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], uint32(height))
+	return key[:]
+}
+
+// dbPutChainTxCount uses an existing database transaction to store the number
+// of transactions in the main chain up to and including the block at the
+// height.
+func dbPutChainTxCount(dbTx database.Tx, height int32, count uint64) error {
+	var serialized [8]byte
+	byteOrder.PutUint64(serialized[:], count)
+
+	bucket := dbTx.Metadata().Bucket(chainTxCountBucketName)
+	return bucket.Put(chainTxCountKey(height), serialized[:])
+}
+
+// dbRemoveChainTxCount uses an existing database transaction to remove the
+// number of transactions in the main chain of the block at the height.
+func dbRemoveChainTxCount(dbTx database.Tx, height int32) error {
+	bucket := dbTx.Metadata().Bucket(chainTxCountBucketName)
+	return bucket.Delete(chainTxCountKey(height))
+}
+
+// dbFetchChainTxCount uses an existing database transaction to fetch the number
+// of transactions in the main chain up to and including the block at the
+// height.  False is returned when the count isn't known.
+func dbFetchChainTxCount(dbTx database.Tx, height int32) (uint64, bool) {
+	bucket := dbTx.Metadata().Bucket(chainTxCountBucketName)
+	serialized := bucket.Get(chainTxCountKey(height))
+	if len(serialized) != 8 {
+		return 0, false
+	}
+	return byteOrder.Uint64(serialized), true
+}
+
+// dbFetchBlockTxCount uses an existing database transaction to read the number
+// of transactions of a stored block without fetching the whole block.
+func dbFetchBlockTxCount(dbTx database.Tx, node *blockNode) (uint64, error) {
+	region, err := dbTx.FetchBlockRegion(&database.BlockRegion{
+		Hash:   &node.hash,
+		Offset: blockHdrSize,
+		Len:    wire.MaxVarIntPayload,
+	})
+	if err != nil {
+		// The region may extend past the end of a small block so
+		// fall back to reading the whole block.
+		blockBytes, err := dbTx.FetchBlock(&node.hash)
+		if err != nil {
+			return 0, err
+		}
+		region = blockBytes[blockHdrSize:]
+	}
+
+	return wire.ReadVarInt(bytes.NewReader(region), 0)
+}
+
+// initChainTxCounts creates the chain transaction count index of a chain state
+// created before the index existed.  The counts are derived backwards from the
+// total number of transactions of the best chain so that they're known for all
+// the stored blocks even when older blocks were pruned.
+func (b *BlockChain) initChainTxCounts() error {
+	var initialized bool
+	err := b.db.View(func(dbTx database.Tx) error {
+		initialized = dbTx.Metadata().Get(chainTxCountVersionKeyName) != nil
+		return nil
+	})
+	if err != nil || initialized {
+		return err
+	}
+
+	log.Infof("Building the chain transaction count index.  This might " +
+		"take a while...")
+
+	node := b.bestChain.Tip()
+	count := b.stateSnapshot.TotalTxns
+	done := false
+	for !done {
+		err := b.db.Update(func(dbTx database.Tx) error {
+			meta := dbTx.Metadata()
+			if meta.Bucket(chainTxCountBucketName) == nil {
+				_, err := meta.CreateBucket(chainTxCountBucketName)
+				if err != nil {
+					return err
+				}
+			}
+
+			for i := 0; i < chainTxCountBatchSize; i++ {
+				err := dbPutChainTxCount(dbTx, node.height, count)
+				if err != nil {
+					return err
+				}
+
+				// The count of the parent is only known when the
+				// block is stored.  The counts of the chain states
+				// loaded from a UTXO snapshot don't include the
+				// blocks before the snapshot.
+				hasBlock, err := dbTx.HasBlock(&node.hash)
+				if err != nil {
+					return err
+				}
+				if node.parent == nil || !hasBlock {
+					done = true
+					break
+				}
+				numTxns, err := dbFetchBlockTxCount(dbTx, node)
+				if err != nil {
+					return err
+				}
+				if numTxns > count {
+					done = true
+					break
+				}
+				count -= numTxns
+				node = node.parent
+			}
+
+			if done {
+				return dbPutVersion(dbTx, chainTxCountVersionKeyName, 1)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Done building the chain transaction count index")
+	return nil
+}
+
+// ChainTxCount returns the number of transactions in the main chain up to and
+// including the block at the height.  False is returned when the count isn't
+// known, which is the case for blocks that were pruned before the count was
+// kept and for blocks that aren't in the main chain.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ChainTxCount(height int32) (uint64, bool, error) {
+	var count uint64
+	var ok bool
+	err := b.db.View(func(dbTx database.Tx) error {
+		count, ok = dbFetchChainTxCount(dbTx, height)
+		return nil
+	})
+	return count, ok, err
+}
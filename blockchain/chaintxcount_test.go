@@ -0,0 +1,115 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/database"
+)
+
+// TestChainTxCounts ensures the chain transaction counts follow the main chain
+// through reorganizations and are rebuilt for chains created without them.
+func TestChainTxCounts(t *testing.T) {
+	// (genesis block) -> 1 -> 2 -> 3 -> 4
+	//                          \-> 3a -> 4a -> 5a
+	testFiles := []string{
+		"blk_0_to_4.dat.bz2",
+		"blk_3A.dat.bz2",
+		"blk_4A.dat.bz2",
+		"blk_5A.dat.bz2",
+	}
+	var blocks []*btcutil.Block
+	for _, file := range testFiles {
+		blockTmp, err := loadBlocks(file)
+		if err != nil {
+			t.Fatalf("Error loading file: %v", err)
+		}
+		blocks = append(blocks, blockTmp...)
+	}
+
+	chain, teardownFunc, err := ChainSetup("chaintxcounts",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+	chain.TstSetCoinbaseMaturity(1)
+
+	// assertCounts checks the counts of the main chain against the number
+	// of transactions of its blocks.
+	assertCounts := func(desc string) {
+		t.Helper()
+
+		snapshot := chain.BestSnapshot()
+		var want uint64
+		for height := int32(0); height <= snapshot.Height; height++ {
+			block, err := chain.BlockByHeight(height)
+			if err != nil {
+				t.Fatalf("%s: %v", desc, err)
+			}
+			want += uint64(len(block.Transactions()))
+
+			count, ok, err := chain.ChainTxCount(height)
+			if err != nil {
+				t.Fatalf("%s: %v", desc, err)
+			}
+			if !ok || count != want {
+				t.Fatalf("%s: expected a count of %d at height %d, "+
+					"got %d (known %v)", desc, want, height, count,
+					ok)
+			}
+		}
+		if want != snapshot.TotalTxns {
+			t.Fatalf("%s: expected %d total transactions, got %d",
+				desc, snapshot.TotalTxns, want)
+		}
+
+		_, ok, err := chain.ChainTxCount(snapshot.Height + 1)
+		if err != nil {
+			t.Fatalf("%s: %v", desc, err)
+		}
+		if ok {
+			t.Fatalf("%s: unexpected count past the tip", desc)
+		}
+	}
+
+	for i := 1; i < 5; i++ {
+		if _, _, err := chain.ProcessBlock(blocks[i], BFNone); err != nil {
+			t.Fatalf("ProcessBlock fail on block %v: %v", i, err)
+		}
+	}
+	assertCounts("main chain")
+
+	// Reorganize to the longer side chain.
+	for i := 5; i < len(blocks); i++ {
+		if _, _, err := chain.ProcessBlock(blocks[i], BFNone); err != nil {
+			t.Fatalf("ProcessBlock fail on block %v: %v", i, err)
+		}
+	}
+	if chain.BestSnapshot().Hash != *blocks[len(blocks)-1].Hash() {
+		t.Fatalf("expected a reorganization to the side chain")
+	}
+	assertCounts("after reorganization")
+
+	// Drop the counts as if the chain was created before they were kept.
+	err = chain.db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		err := meta.DeleteBucket(chainTxCountBucketName)
+		if err != nil {
+			return err
+		}
+		return meta.Delete(chainTxCountVersionKeyName)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.initChainTxCounts(); err != nil {
+		t.Fatal(err)
+	}
+	assertCounts("after rebuild")
+}
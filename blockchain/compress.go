@@ -105,6 +105,13 @@ func deserializeVLQ(serialized []byte) (uint64, int) {
 	return n, size
 }
 
+// DeserializeVLQ deserializes the provided variable-length quantity according
+// to the format described above.  It also returns the number of bytes
+// deserialized.  The format is the same as the VARINT used by Bitcoin Core.
+func DeserializeVLQ(serialized []byte) (uint64, int) {
+	return deserializeVLQ(serialized)
+}
+
 // -----------------------------------------------------------------------------
 // In order to reduce the size of stored scripts, a domain specific compression
 // algorithm is used which recognizes standard scripts and stores them using
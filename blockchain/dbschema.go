@@ -0,0 +1,149 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexod/database"
+)
+
+var (
+	// blockIndexVersionKeyName is the name of the db key used to store the
+	// version of the block index currently in the database.
+	blockIndexVersionKeyName = []byte("blockidxversion")
+
+	// chainStateVersionKeyName is the name of the db key used to store the
+	// version of the chain state currently in the database.
+	chainStateVersionKeyName = []byte("chainstateversion")
+)
+
+// DbMigration is an upgrade of a versioned part of the database from the
+// previous version to the version of the migration.
+type DbMigration struct {
+	// Version is the version the data is at once the migration is done.
+	Version uint32
+
+	// Description is a human-readable description of the migration that's
+	// logged when it's applied.
+	Description string
+
+	// Migrate performs the migration.  It must leave the data at the
+	// previous version when it returns an error, or be able to pick up
+	// where it left off when it's run again, since the version is only
+	// bumped once it succeeds.
+	Migrate func(db database.DB, interrupt <-chan struct{}) error
+}
+
+// DbSchema is a part of the database whose layout is versioned, such as the
+// block index, the utxo set or an index.  Its version is kept under a key of
+// the metadata bucket and is brought up to the latest version by running its
+// migrations in order.  The version of data written before it was versioned is
+// 1, so the migrations start at version 2.
+type DbSchema struct {
+	// Name is the human-readable name of the data.
+	Name string
+
+	// VersionKey is the key of the metadata bucket the version is kept
+	// under.
+	VersionKey []byte
+
+	// Migrations are the upgrades of the data ordered by the version they
+	// upgrade to.
+	Migrations []DbMigration
+}
+
+// LatestVersion returns the version the data is at once all of the migrations
+// are applied.
+func (s *DbSchema) LatestVersion() uint32 {
+	if len(s.Migrations) == 0 {
+		return 1
+	}
+	return s.Migrations[len(s.Migrations)-1].Version
+}
+
+// PutLatestDbSchemaVersion sets the version of the schema to its latest
+// version, which is done when the data is created.
+func PutLatestDbSchemaVersion(dbTx database.Tx, schema *DbSchema) error {
+	return dbPutVersion(dbTx, schema.VersionKey, schema.LatestVersion())
+}
+
+// UpgradeDbSchema brings the data of the schema up to its latest version by
+// running the migrations it's missing in order.  An error is returned when the
+// data is at a newer version than the latest one, which means that it was
+// written by a newer release of the software, since downgrades aren't
+// supported.
+func UpgradeDbSchema(db database.DB, schema *DbSchema, interrupt <-chan struct{}) error {
+	var version uint32
+	err := db.Update(func(dbTx database.Tx) error {
+		var err error
+		version, err = dbFetchOrCreateVersion(dbTx, schema.VersionKey, 1)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	latest := schema.LatestVersion()
+	if version > latest {
+		return fmt.Errorf("the %s in the database is at version %d, but "+
+			"this release only supports up to version %d -- it was "+
+			"written by a newer release and downgrading isn't "+
+			"supported, so run that release again or start over "+
+			"with an empty data directory", schema.Name, version,
+			latest)
+	}
+
+	for _, migration := range schema.Migrations {
+		if migration.Version <= version {
+			continue
+		}
+		if interruptRequested(interrupt) {
+			return errInterruptRequested
+		}
+
+		log.Infof("Upgrading the %s to version %d: %s", schema.Name,
+			migration.Version, migration.Description)
+		err := migration.Migrate(db, interrupt)
+		if err == errInterruptRequested {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to upgrade the %s to version "+
+				"%d: %v", schema.Name, migration.Version, err)
+		}
+		err = db.Update(func(dbTx database.Tx) error {
+			return dbPutVersion(dbTx, schema.VersionKey,
+				migration.Version)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chainDbSchemas are the versioned parts of the database that this package
+// keeps.  The indexes keep schemas of their own.
+var chainDbSchemas = []*DbSchema{
+	{
+		Name:       "block index",
+		VersionKey: blockIndexVersionKeyName,
+	},
+	{
+		Name:       "chain state",
+		VersionKey: chainStateVersionKeyName,
+	},
+	{
+		Name:       "utxo set",
+		VersionKey: utxoSetVersionKeyName,
+		Migrations: []DbMigration{{
+			Version:     2,
+			Description: "store an entry per output rather than per transaction",
+			Migrate:     upgradeUtxoSetToV2,
+		}},
+	},
+}
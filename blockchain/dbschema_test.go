@@ -0,0 +1,92 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/utreexo/utreexod/database"
+	_ "github.com/utreexo/utreexod/database/ffldb"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestUpgradeDbSchema ensures that the migrations a schema is missing are run
+// in order, that a failed migration leaves the version where it was, and that
+// data written by a newer release is refused.
+func TestUpgradeDbSchema(t *testing.T) {
+	db, err := database.Create("ffldb", t.TempDir(), wire.SimNet)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer db.Close()
+
+	var ran []uint32
+	var failV3 bool
+	migration := func(version uint32) DbMigration {
+		return DbMigration{
+			Version:     version,
+			Description: "test migration",
+			Migrate: func(database.DB, <-chan struct{}) error {
+				if version == 3 && failV3 {
+					return errors.New("migration failed")
+				}
+				ran = append(ran, version)
+				return nil
+			},
+		}
+	}
+	schema := &DbSchema{
+		Name:       "test data",
+		VersionKey: []byte("testversion"),
+		Migrations: []DbMigration{migration(2), migration(3)},
+	}
+	fetchVersion := func() uint32 {
+		var version uint32
+		db.View(func(dbTx database.Tx) error {
+			version = dbFetchVersion(dbTx, schema.VersionKey)
+			return nil
+		})
+		return version
+	}
+
+	// Data without a version is at version 1, so both migrations run.
+	// The version stays at 2 when the migration to 3 fails.
+	failV3 = true
+	if err := UpgradeDbSchema(db, schema, nil); err == nil {
+		t.Fatalf("expected the failed migration to be returned")
+	}
+	if fetchVersion() != 2 || len(ran) != 1 || ran[0] != 2 {
+		t.Fatalf("got version %d after running %v, want 2 after [2]",
+			fetchVersion(), ran)
+	}
+
+	failV3 = false
+	if err := UpgradeDbSchema(db, schema, nil); err != nil {
+		t.Fatalf("UpgradeDbSchema: unexpected error: %v", err)
+	}
+	if fetchVersion() != 3 || len(ran) != 2 || ran[1] != 3 {
+		t.Fatalf("got version %d after running %v, want 3 after [2 3]",
+			fetchVersion(), ran)
+	}
+
+	// Nothing runs once the data is at the latest version.
+	if err := UpgradeDbSchema(db, schema, nil); err != nil || len(ran) != 2 {
+		t.Fatalf("got error %v after running %v, want none", err, ran)
+	}
+
+	// Data written by a newer release is refused.
+	err = db.Update(func(dbTx database.Tx) error {
+		return dbPutVersion(dbTx, schema.VersionKey, 4)
+	})
+	if err != nil {
+		t.Fatalf("unable to store version: %v", err)
+	}
+	err = UpgradeDbSchema(db, schema, nil)
+	if err == nil || !strings.Contains(err.Error(), "downgrading") {
+		t.Fatalf("got error %v, want a refused downgrade", err)
+	}
+}
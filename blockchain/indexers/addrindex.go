@@ -7,6 +7,8 @@ package indexers
 import (
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
 
 	"github.com/utreexo/utreexod/blockchain"
@@ -145,6 +147,10 @@ var (
 // serialized block ID to an associated block hash.
 type fetchBlockHashFunc func(serializedID []byte) (*chainhash.Hash, error)
 
+// fetchBlockHeightFunc defines a callback function to use in order to convert a
+// serialized block ID to the height of the associated block.
+type fetchBlockHeightFunc func(serializedID []byte) (int32, error)
+
 // serializeAddrIndexEntry serializes the provided block id and transaction
 // location according to the format described in detail above.
 func serializeAddrIndexEntry(blockID uint32, txLoc wire.TxLoc) []byte {
@@ -289,6 +295,82 @@ func dbFetchAddrIndexEntries(bucket internalBucket, addrKey [addrKeySize]byte,
 		level++
 	}
 
+	return selectAddrIndexEntries(serialized, addrKey, numToSkip,
+		numRequested, reverse, fetchBlockHash)
+}
+
+// dbFetchAddrIndexEntriesInRange returns block regions for transactions
+// referenced by the given address key like dbFetchAddrIndexEntries, except that
+// only the transactions in blocks with heights from minHeight to maxHeight
+// inclusive are considered.  The entries to skip and the requested entries are
+// counted from the start of the range, or from its end when the reverse flag
+// is set.
+func dbFetchAddrIndexEntriesInRange(bucket internalBucket,
+	addrKey [addrKeySize]byte, minHeight, maxHeight int32, numToSkip,
+	numRequested uint32, reverse bool, fetchBlockHash fetchBlockHashFunc,
+	fetchBlockHeight fetchBlockHeightFunc) ([]database.BlockRegion, uint32, error) {
+
+	// All levels need to be fetched since the range may be anywhere in
+	// the entries.  Higher levels contain older transactions, so prepend
+	// them.
+	var serialized []byte
+	for level := uint8(0); ; level++ {
+		curLevelKey := keyForLevel(addrKey, level)
+		levelData := bucket.Get(curLevelKey[:])
+		if levelData == nil {
+			break
+		}
+		prepended := make([]byte, len(serialized)+len(levelData))
+		copy(prepended, levelData)
+		copy(prepended[len(levelData):], serialized)
+		serialized = prepended
+	}
+
+	// The entries are ordered by the height of the blocks the transactions
+	// are in, so search for the entries that bound the range.
+	var searchErr error
+	numEntries := len(serialized) / txEntrySize
+	searchHeight := func(height int32) int {
+		return sort.Search(numEntries, func(i int) bool {
+			if searchErr != nil {
+				return true
+			}
+			entryHeight, err := fetchBlockHeight(
+				serialized[i*txEntrySize : i*txEntrySize+4])
+			if err != nil {
+				searchErr = err
+				return true
+			}
+			return entryHeight >= height
+		})
+	}
+	start := searchHeight(minHeight)
+	end := numEntries
+	if maxHeight < math.MaxInt32 {
+		end = searchHeight(maxHeight + 1)
+	}
+	if searchErr != nil {
+		return nil, 0, searchErr
+	}
+	if end < start {
+		end = start
+	}
+
+	return selectAddrIndexEntries(
+		serialized[start*txEntrySize:end*txEntrySize], addrKey,
+		numToSkip, numRequested, reverse, fetchBlockHash)
+}
+
+// selectAddrIndexEntries returns the block regions of the passed serialized
+// entries of the given address key according to the number of entries to skip,
+// the number requested and whether the entries are selected from the newest
+// ones, along with the number of entries skipped since it could have been less
+// in the case where there are less entries than the requested number of entries
+// to skip.
+func selectAddrIndexEntries(serialized []byte, addrKey [addrKeySize]byte,
+	numToSkip, numRequested uint32, reverse bool,
+	fetchBlockHash fetchBlockHashFunc) ([]database.BlockRegion, uint32, error) {
+
 	// When the requested number of entries to skip is larger than the
 	// number available, skip them all and return now with the actual number
 	// skipped.
@@ -609,6 +691,10 @@ type AddrIndex struct {
 	db          database.DB
 	chainParams *chaincfg.Params
 
+	// chain is used to look up the heights of the blocks of the indexed
+	// transactions.  It is set when the index is initialized.
+	chain *blockchain.BlockChain
+
 	// The following fields are used to quickly link transactions and
 	// addresses that have not been included into a block yet when an
 	// address index is being maintained.  The are protected by the
@@ -641,12 +727,35 @@ func (idx *AddrIndex) NeedsInputs() bool {
 	return true
 }
 
+// Ensure the AddrIndex type implements the BackgroundCatchUpper interface.
+var _ BackgroundCatchUpper = (*AddrIndex)(nil)
+
+// BackgroundCatchUp signals that the index can be caught up in the background
+// since no other part of the node depends on it.
+//
+// This implements the BackgroundCatchUpper interface.
+func (idx *AddrIndex) BackgroundCatchUp() bool {
+	return true
+}
+
+// Ensure the AddrIndex type implements the PruneBlocker interface.
+var _ PruneBlocker = (*AddrIndex)(nil)
+
+// BlocksPrune signals that the index keeps the blocks it has indexed from
+// being pruned since its entries are only the locations of the transactions
+// within them.
+//
+// This implements the PruneBlocker interface.
+func (idx *AddrIndex) BlocksPrune() bool {
+	return true
+}
+
 // Init is only provided to satisfy the Indexer interface as there is nothing to
 // initialize for this index.
 //
 // This is part of the Indexer interface.
-func (idx *AddrIndex) Init(_ *blockchain.BlockChain) error {
-	// Nothing to do.
+func (idx *AddrIndex) Init(chain *blockchain.BlockChain) error {
+	idx.chain = chain
 	return nil
 }
 
@@ -811,9 +920,8 @@ func (idx *AddrIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
 
 // PruneBlock is invoked when an older block is deleted after it's been
 // processed.
-// NOTE: For AddrIndex, it's a no-op as AddrIndex isn't allowed to be enabled
-// with pruning. This may be visited at a later date so that AddrIndex is
-// supported with pruning.
+// NOTE: For AddrIndex, it's a no-op as the index keeps the blocks it has
+// indexed from being pruned.
 //
 // This is part of the Indexer interface.
 func (idx *AddrIndex) PruneBlock(dbTx database.Tx, blockHash *chainhash.Hash) error {
@@ -860,6 +968,42 @@ func (idx *AddrIndex) TxRegionsForAddress(dbTx database.Tx, addr btcutil.Address
 	return regions, skipped, err
 }
 
+// TxRegionsForAddressInRange returns a slice of block regions which identify
+// each transaction that involves the passed address like TxRegionsForAddress,
+// except that only the transactions confirmed in the main chain blocks with
+// heights from minHeight to maxHeight inclusive are considered.  The number to
+// skip and the number requested are counted from the start of the range, or
+// from its end when the results are reversed.
+//
+// This function is safe for concurrent access.
+func (idx *AddrIndex) TxRegionsForAddressInRange(dbTx database.Tx,
+	addr btcutil.Address, minHeight, maxHeight int32, numToSkip,
+	numRequested uint32, reverse bool) ([]database.BlockRegion, uint32, error) {
+
+	addrKey, err := addrToKey(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Create closures to lookup the block hash and height given the ID
+	// using the database transaction.
+	fetchBlockHash := func(id []byte) (*chainhash.Hash, error) {
+		return dbFetchBlockHashBySerializedID(dbTx, id)
+	}
+	fetchBlockHeight := func(id []byte) (int32, error) {
+		hash, err := fetchBlockHash(id)
+		if err != nil {
+			return 0, err
+		}
+		return idx.chain.BlockHeightByHash(hash)
+	}
+
+	addrIdxBucket := dbTx.Metadata().Bucket(addrIndexKey)
+	return dbFetchAddrIndexEntriesInRange(addrIdxBucket, addrKey, minHeight,
+		maxHeight, numToSkip, numRequested, reverse, fetchBlockHash,
+		fetchBlockHeight)
+}
+
 // indexUnconfirmedAddresses modifies the unconfirmed (memory-only) address
 // index to include mappings for the addresses encoded by the passed public key
 // script to the transaction.
@@ -7,8 +7,11 @@ package indexers
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"reflect"
 	"testing"
 
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
 	"github.com/utreexo/utreexod/wire"
 )
 
@@ -274,3 +277,122 @@ nextTest:
 		}
 	}
 }
+
+// TestAddrIndexEntriesInRange ensures that fetching the entries of an address
+// restricted to a range of heights returns the entries of the transactions in
+// blocks within the range while skipping and limiting them from either end of
+// the range.
+func TestAddrIndexEntriesInRange(t *testing.T) {
+	t.Parallel()
+
+	// Insert three transactions at each of the heights from 0 to 9, where
+	// the block ID of each entry is its index so that the transactions can
+	// be told apart by the offsets of the regions.
+	var key [addrKeySize]byte
+	bucket := &addrIndexBucket{
+		levels: make(map[[levelKeySize]byte][]byte),
+	}
+	const txnsPerBlock = 3
+	for i := 0; i < 10*txnsPerBlock; i++ {
+		txLoc := wire.TxLoc{TxStart: i}
+		err := dbPutAddrIndexEntry(bucket, key, uint32(i), txLoc)
+		if err != nil {
+			t.Fatalf("dbPutAddrIndexEntry #%d - unexpected error: %v",
+				i, err)
+		}
+	}
+	fetchBlockHash := func(id []byte) (*chainhash.Hash, error) {
+		return &chainhash.Hash{}, nil
+	}
+	fetchBlockHeight := func(id []byte) (int32, error) {
+		return int32(byteOrder.Uint32(id)) / txnsPerBlock, nil
+	}
+
+	tests := []struct {
+		name         string
+		minHeight    int32
+		maxHeight    int32
+		numToSkip    uint32
+		numRequested uint32
+		reverse      bool
+		wantOffsets  []uint32
+		wantSkipped  uint32
+	}{
+		{
+			name:         "single height",
+			minHeight:    2,
+			maxHeight:    2,
+			numRequested: 10,
+			wantOffsets:  []uint32{6, 7, 8},
+		},
+		{
+			name:         "skip and limit from the start",
+			minHeight:    2,
+			maxHeight:    4,
+			numToSkip:    2,
+			numRequested: 3,
+			wantOffsets:  []uint32{8, 9, 10},
+			wantSkipped:  2,
+		},
+		{
+			name:         "skip and limit from the end",
+			minHeight:    2,
+			maxHeight:    4,
+			numToSkip:    1,
+			numRequested: 2,
+			reverse:      true,
+			wantOffsets:  []uint32{13, 12},
+			wantSkipped:  1,
+		},
+		{
+			name:         "open ended",
+			minHeight:    9,
+			maxHeight:    math.MaxInt32,
+			numRequested: 10,
+			wantOffsets:  []uint32{27, 28, 29},
+		},
+		{
+			name:         "skip all",
+			minHeight:    0,
+			maxHeight:    0,
+			numToSkip:    5,
+			numRequested: 10,
+			wantSkipped:  3,
+		},
+		{
+			name:         "empty range",
+			minHeight:    5,
+			maxHeight:    4,
+			numRequested: 10,
+		},
+		{
+			name:         "beyond the entries",
+			minHeight:    10,
+			maxHeight:    20,
+			numRequested: 10,
+		},
+	}
+
+	for _, test := range tests {
+		regions, skipped, err := dbFetchAddrIndexEntriesInRange(bucket,
+			key, test.minHeight, test.maxHeight, test.numToSkip,
+			test.numRequested, test.reverse, fetchBlockHash,
+			fetchBlockHeight)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if skipped != test.wantSkipped {
+			t.Errorf("%s: skipped %d entries, want %d", test.name,
+				skipped, test.wantSkipped)
+		}
+		var offsets []uint32
+		for _, region := range regions {
+			offsets = append(offsets, region.Offset)
+		}
+		if !reflect.DeepEqual(offsets, test.wantOffsets) {
+			t.Errorf("%s: got offsets %v, want %v", test.name,
+				offsets, test.wantOffsets)
+		}
+	}
+}
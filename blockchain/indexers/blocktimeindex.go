@@ -0,0 +1,243 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+)
+
+const (
+	// blockTimeIndexName is the human-readable name for the index.
+	blockTimeIndexName = "block time index"
+
+	// blockTimeKeySize is the size of a block time index key.  It consists
+	// of the 4 bytes timestamp + 4 bytes block height.
+	blockTimeKeySize = 4 + 4
+)
+
+var (
+	// blockTimeIndexKey is the key of the block time index and the db
+	// bucket used to house it.
+	blockTimeIndexKey = []byte("blockbytimeidx")
+)
+
+// -----------------------------------------------------------------------------
+// The block time index maps the timestamps of the blocks in the main chain to
+// the blocks, so that the blocks within a time range can be iterated without
+// going through the whole chain.  Block timestamps don't strictly increase
+// with the height, so the key includes the height as well to keep the blocks
+// with the same timestamp apart.
+//
+// The serialized key format is:
+//
+//   <timestamp><block height>
+//
+//   Field           Type              Size
+//   timestamp       uint32            4 bytes
+//   block height    uint32            4 bytes
+//
+// Both fields are big endian so that the keys are ordered by the timestamp
+// and then by the height.
+//
+// The serialized value format is:
+//
+//   <block hash>
+//
+//   Field           Type              Size
+//   block hash      chainhash.Hash    32 bytes
+//   -----
+//   Total: 32 bytes
+// -----------------------------------------------------------------------------
+
+// BlockTimeEntry describes a block in the main chain by its timestamp.
+type BlockTimeEntry struct {
+	// Hash is the hash of the block.
+	Hash chainhash.Hash
+
+	// Height is the height of the block.
+	Height int32
+
+	// Timestamp is the timestamp of the block header.
+	Timestamp time.Time
+}
+
+// blockTimeKey returns the block time index key for the passed timestamp and
+// height.
+func blockTimeKey(timestamp uint32, height int32) []byte {
+	key := make([]byte, blockTimeKeySize)
+	binary.BigEndian.PutUint32(key, timestamp)
+	binary.BigEndian.PutUint32(key[4:], uint32(height))
+	return key
+}
+
+// dbFetchBlockTimeEntries returns the entries of the blocks with a timestamp
+// at or after start and before end, ordered by timestamp and then by height.
+func dbFetchBlockTimeEntries(dbTx database.Tx, start, end uint32) ([]BlockTimeEntry, error) {
+	var entries []BlockTimeEntry
+	endKey := blockTimeKey(end, 0)
+	cursor := dbTx.Metadata().Bucket(blockTimeIndexKey).Cursor()
+	for ok := cursor.Seek(blockTimeKey(start, 0)); ok; ok = cursor.Next() {
+		key := cursor.Key()
+		if bytes.Compare(key, endKey) >= 0 {
+			break
+		}
+
+		value := cursor.Value()
+		if len(key) < blockTimeKeySize || len(value) < chainhash.HashSize {
+			return nil, database.Error{
+				ErrorCode: database.ErrCorruption,
+				Description: fmt.Sprintf("corrupt block time "+
+					"index entry %x", key),
+			}
+		}
+
+		var entry BlockTimeEntry
+		copy(entry.Hash[:], value)
+		entry.Height = int32(binary.BigEndian.Uint32(key[4:]))
+		entry.Timestamp = time.Unix(int64(binary.BigEndian.Uint32(key)), 0)
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// BlockTimeIndex implements an index of the blocks in the main chain by their
+// timestamps.
+type BlockTimeIndex struct {
+	db database.DB
+}
+
+// Ensure the BlockTimeIndex type implements the Indexer interface.
+var _ Indexer = (*BlockTimeIndex)(nil)
+
+// Ensure the BlockTimeIndex type implements the BackgroundCatchUpper
+// interface.
+var _ BackgroundCatchUpper = (*BlockTimeIndex)(nil)
+
+// BackgroundCatchUp signals that the index can be caught up in the background
+// since no other part of the node depends on it.
+//
+// This implements the BackgroundCatchUpper interface.
+func (idx *BlockTimeIndex) BackgroundCatchUp() bool {
+	return true
+}
+
+// Init initializes the block time index.
+//
+// This is part of the Indexer interface.
+func (idx *BlockTimeIndex) Init(_ *blockchain.BlockChain) error {
+	return nil // Nothing to do.
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *BlockTimeIndex) Key() []byte {
+	return blockTimeIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *BlockTimeIndex) Name() string {
+	return blockTimeIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the bucket for the block time
+// index.
+//
+// This is part of the Indexer interface.
+func (idx *BlockTimeIndex) Create(dbTx database.Tx) error {
+	_, err := dbTx.Metadata().CreateBucket(blockTimeIndexKey)
+	return err
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer adds the block under its
+// timestamp.
+//
+// This is part of the Indexer interface.
+func (idx *BlockTimeIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	timestamp := uint32(block.MsgBlock().Header.Timestamp.Unix())
+	key := blockTimeKey(timestamp, block.Height())
+	return dbTx.Metadata().Bucket(blockTimeIndexKey).Put(key, block.Hash()[:])
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the block from
+// under its timestamp.
+//
+// This is part of the Indexer interface.
+func (idx *BlockTimeIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	timestamp := uint32(block.MsgBlock().Header.Timestamp.Unix())
+	key := blockTimeKey(timestamp, block.Height())
+	return dbTx.Metadata().Bucket(blockTimeIndexKey).Delete(key)
+}
+
+// PruneBlock is invoked when an older block is deleted after it's been
+// processed.
+// NOTE: For BlockTimeIndex, it's a no-op as BlockTimeIndex isn't allowed to be
+// enabled with pruning since it can't be caught up without the blocks.
+//
+// This is part of the Indexer interface.
+func (idx *BlockTimeIndex) PruneBlock(dbTx database.Tx, blockHash *chainhash.Hash) error {
+	return nil
+}
+
+// FetchBlocksByTime returns the blocks in the main chain with a timestamp at or
+// after start and before end, ordered by timestamp and then by height.
+//
+// This function is safe for concurrent access.
+func (idx *BlockTimeIndex) FetchBlocksByTime(start, end time.Time) ([]BlockTimeEntry, error) {
+	// Block timestamps are stored as uint32s, so clamp the range to
+	// them.
+	clamp := func(t time.Time) uint32 {
+		switch {
+		case t.Unix() < 0:
+			return 0
+		case t.Unix() > int64(^uint32(0)):
+			return ^uint32(0)
+		}
+		return uint32(t.Unix())
+	}
+
+	var entries []BlockTimeEntry
+	err := idx.db.View(func(dbTx database.Tx) error {
+		var err error
+		entries, err = dbFetchBlockTimeEntries(dbTx, clamp(start), clamp(end))
+		return err
+	})
+	return entries, err
+}
+
+// NewBlockTimeIndex returns a new instance of an indexer that is used to create
+// a mapping of the timestamps of all the blocks in the main chain to the
+// blocks.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewBlockTimeIndex(db database.DB) *BlockTimeIndex {
+	return &BlockTimeIndex{db: db}
+}
+
+// DropBlockTimeIndex drops the block time index from the provided database if
+// it exists.
+func DropBlockTimeIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, blockTimeIndexKey, blockTimeIndexName, interrupt)
+}
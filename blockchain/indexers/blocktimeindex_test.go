@@ -0,0 +1,105 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestBlockTimeIndex ensures that the block time index returns the blocks
+// within a time range ordered by timestamp, including the ones whose timestamp
+// is earlier than the one of the block before them.
+func TestBlockTimeIndex(t *testing.T) {
+	db, dbPath, err := createDB("blocktimeindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewBlockTimeIndex(db)
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.Create(dbTx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The third block has a timestamp earlier than the second one.
+	timestamps := []int64{1000, 2000, 1500, 3000}
+	var blocks []*btcutil.Block
+	for i, timestamp := range timestamps {
+		block := btcutil.NewBlock(&wire.MsgBlock{
+			Header: wire.BlockHeader{
+				Timestamp: time.Unix(timestamp, 0),
+			},
+		})
+		block.SetHeight(int32(i + 1))
+		blocks = append(blocks, block)
+	}
+	err = db.Update(func(dbTx database.Tx) error {
+		for _, block := range blocks {
+			err := idx.ConnectBlock(dbTx, block, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ConnectBlock: unexpected error: %v", err)
+	}
+
+	entry := func(i int) BlockTimeEntry {
+		return BlockTimeEntry{
+			Hash:      *blocks[i].Hash(),
+			Height:    blocks[i].Height(),
+			Timestamp: time.Unix(timestamps[i], 0),
+		}
+	}
+	tests := []struct {
+		start, end int64
+		want       []BlockTimeEntry
+	}{
+		{start: 0, end: 1000, want: nil},
+		{start: 1000, end: 2000, want: []BlockTimeEntry{entry(0), entry(2)}},
+		{start: 1001, end: 3001, want: []BlockTimeEntry{entry(2), entry(1), entry(3)}},
+		{start: -1, end: 1 << 40, want: []BlockTimeEntry{entry(0), entry(2), entry(1), entry(3)}},
+	}
+	for _, test := range tests {
+		entries, err := idx.FetchBlocksByTime(time.Unix(test.start, 0),
+			time.Unix(test.end, 0))
+		if err != nil {
+			t.Fatalf("FetchBlocksByTime(%d, %d): unexpected error: %v",
+				test.start, test.end, err)
+		}
+		if !reflect.DeepEqual(entries, test.want) {
+			t.Fatalf("FetchBlocksByTime(%d, %d): got %v, want %v",
+				test.start, test.end, entries, test.want)
+		}
+	}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.DisconnectBlock(dbTx, blocks[3], nil)
+	})
+	if err != nil {
+		t.Fatalf("DisconnectBlock: unexpected error: %v", err)
+	}
+	entries, err := idx.FetchBlocksByTime(time.Unix(3000, 0), time.Unix(4000, 0))
+	if err != nil {
+		t.Fatalf("FetchBlocksByTime: unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("FetchBlocksByTime: got %v after the block was "+
+			"disconnected", entries)
+	}
+}
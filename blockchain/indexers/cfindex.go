@@ -94,6 +94,18 @@ func (idx *CfIndex) NeedsInputs() bool {
 	return true
 }
 
+// Ensure the CfIndex type implements the BackgroundCatchUpper interface.
+var _ BackgroundCatchUpper = (*CfIndex)(nil)
+
+// BackgroundCatchUp signals that the index can be caught up in the background
+// since the filters of the blocks it hasn't caught up to yet are just not
+// served until it does.
+//
+// This implements the BackgroundCatchUpper interface.
+func (idx *CfIndex) BackgroundCatchUp() bool {
+	return true
+}
+
 // Init initializes the hash-based cf index. This is part of the Indexer
 // interface.
 func (idx *CfIndex) Init(_ *blockchain.BlockChain) error {
@@ -33,6 +33,29 @@ type NeedsInputser interface {
 	NeedsInputs() bool
 }
 
+// BackgroundCatchUpper provides a generic interface for an indexer to specify
+// that it can be caught up to the main chain in the background while the node
+// keeps running instead of before the node starts up.
+type BackgroundCatchUpper interface {
+	BackgroundCatchUp() bool
+}
+
+// PruneBlocker provides a generic interface for an indexer to specify that its
+// entries refer to the blocks on disk.  Rather than having its entries pruned
+// along with the blocks, such an index keeps the blocks it has indexed from
+// being pruned.
+type PruneBlocker interface {
+	BlocksPrune() bool
+}
+
+// Migrator provides a generic interface for an indexer to specify the upgrades
+// of the layout of its data.  Every index is versioned and the index manager
+// runs the migrations an index is missing before the index is initialized.
+// The indexes that don't implement the interface stay at version 1.
+type Migrator interface {
+	Migrations() []blockchain.DbMigration
+}
+
 // Indexer provides a generic interface for an indexer that is managed by an
 // index manager such as the Manager type provided by this package.
 type Indexer interface {
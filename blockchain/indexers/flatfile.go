@@ -7,7 +7,10 @@ package indexers
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -28,10 +31,101 @@ const (
 )
 
 var (
-	// magicBytes are the bytes prepended to any entry in the dataFiles.
+	// magicBytes are the bytes prepended to the entries in the dataFiles
+	// that were written without a checksum.  The magic is followed by the
+	// size of the data.
 	magicBytes = []byte{0xaa, 0xff, 0xaa, 0xff}
+
+	// checksumMagicBytes are the bytes prepended to the entries in the
+	// dataFiles that have a checksum.  The magic is followed by the size
+	// of the data and the Castagnoli CRC-32 checksum of the data.
+	checksumMagicBytes = []byte{0xaa, 0xff, 0xaa, 0xfe}
+
+	// castagnoli houses the Castagnoli polynomial used for CRC-32 checksums.
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+	// errFlatFileReadOnly is returned when data is stored to or
+	// disconnected from a FlatFileState that was initialized read-only.
+	errFlatFileReadOnly = errors.New("the flat file state is read-only")
 )
 
+// flatFileEntryHeader is the header that precedes the data of an entry in a
+// dataFile.
+type flatFileEntryHeader struct {
+	// size is the size of the data.
+	size uint32
+
+	// checksum is the checksum of the data.  It's only set when
+	// hasChecksum is, which is for the entries written by the releases
+	// that checksum them.
+	checksum    uint32
+	hasChecksum bool
+}
+
+// len returns the size of the header.
+func (h *flatFileEntryHeader) len() int64 {
+	if h.hasChecksum {
+		return 12
+	}
+	return 8
+}
+
+// readEntryHeader reads the header of the entry at the passed offset of the
+// dataFile.
+func (ff *FlatFileState) readEntryHeader(offset int64) (*flatFileEntryHeader, error) {
+	buf := make([]byte, 12)
+	n, err := ff.dataFile.ReadAt(buf, offset)
+	if err != nil && (err != io.EOF || n < 8) {
+		return nil, err
+	}
+
+	switch {
+	case bytes.Equal(buf[:4], checksumMagicBytes):
+		if n < 12 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return &flatFileEntryHeader{
+			size:        binary.BigEndian.Uint32(buf[4:8]),
+			checksum:    binary.BigEndian.Uint32(buf[8:12]),
+			hasChecksum: true,
+		}, nil
+
+	case bytes.Equal(buf[:4], magicBytes):
+		return &flatFileEntryHeader{
+			size: binary.BigEndian.Uint32(buf[4:8]),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("Read wrong magic bytes. Expect %x or %x "+
+			"but got %x", checksumMagicBytes, magicBytes, buf[:4])
+	}
+}
+
+// readEntry reads the data of the entry at the passed offset of the dataFile
+// and checks it against its checksum when it has one.
+func (ff *FlatFileState) readEntry(offset int64) ([]byte, error) {
+	header, err := ff.readEntryHeader(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, header.size)
+	_, err = ff.dataFile.ReadAt(data, offset+header.len())
+	if err != nil {
+		return nil, err
+	}
+	if header.hasChecksum {
+		checksum := crc32.Checksum(data, castagnoli)
+		if checksum != header.checksum {
+			return nil, fmt.Errorf("data at offset %d doesn't match "+
+				"its checksum - got %d, want %d", offset,
+				checksum, header.checksum)
+		}
+	}
+
+	return data, nil
+}
+
 // FlatFileState is the shared state for storing flatfiles.  It is specifically designed
 // for the utreexo proofs and stores data as a [key-value] of [height-data].
 type FlatFileState struct {
@@ -57,6 +151,10 @@ type FlatFileState struct {
 	// NOTE Since we account for the genesis block in the offsets, to fetch data for
 	// height x, you'd do 'offsets[x]' and not 'offsets[x-1]'.
 	offsets []int64
+
+	// readOnly is set when the FlatFileState was initialized with
+	// InitReadOnly, in which case the files are never written to.
+	readOnly bool
 }
 
 // Init initializes the FlatFileState.  If resuming, it loads the offsets onto memory.
@@ -70,14 +168,32 @@ func (ff *FlatFileState) Init(path, dataName string) error {
 		return err
 	}
 
+	return ff.init(path, dataName, os.O_CREATE|os.O_RDWR)
+}
+
+// InitReadOnly initializes the FlatFileState for reading the existing files at
+// the passed path without ever writing to them, which allows a FlatFileState
+// that's in use by a running node to be read from another process.  The data
+// that's stored after it's initialized isn't seen, and the entries at the end
+// that are still being written are left out rather than truncated.  Storing or
+// disconnecting data returns an error.
+func (ff *FlatFileState) InitReadOnly(path, dataName string) error {
+	ff.readOnly = true
+	return ff.init(path, dataName, os.O_RDONLY)
+}
+
+// init opens the files of the FlatFileState with the passed flags and loads the
+// offsets onto memory.
+func (ff *FlatFileState) init(path, dataName string, flag int) error {
+	var err error
 	offsetPath := filepath.Join(path, offsetFileName)
-	ff.offsetFile, err = os.OpenFile(offsetPath, os.O_CREATE|os.O_RDWR, 0600)
+	ff.offsetFile, err = os.OpenFile(offsetPath, flag, 0600)
 	if err != nil {
 		return err
 	}
 
 	dataPath := filepath.Join(path, dataName+dataFileSuffix)
-	ff.dataFile, err = os.OpenFile(dataPath, os.O_CREATE|os.O_RDWR, 0600)
+	ff.dataFile, err = os.OpenFile(dataPath, flag, 0600)
 	if err != nil {
 		return err
 	}
@@ -88,10 +204,20 @@ func (ff *FlatFileState) Init(path, dataName string) error {
 		return err
 	}
 
-	// Offsets are always 8 bytes each.
+	// Offsets are always 8 bytes each, so a partially written offset is
+	// left at the end when the writing of an entry was cut off.  It's
+	// only ignored when read-only since it's most likely still being
+	// written.
 	if offsetFileSize%8 != 0 {
-		return fmt.Errorf("FlatFileState.Init(): Corrupt FlatFileState. " +
-			"offsetFile not mulitple of 8 bytes")
+		offsetFileSize -= offsetFileSize % 8
+		if !ff.readOnly {
+			log.Warnf("Truncating the partially written offset at "+
+				"the end of %s", offsetPath)
+			err := ff.offsetFile.Truncate(offsetFileSize)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// If the file size is bigger than 0, we're resuming and will read all
@@ -117,12 +243,15 @@ func (ff *FlatFileState) Init(path, dataName string) error {
 			ff.offsets[i] = ff.currentOffset
 		}
 
-		// Set the currentOffset to the end of the data file.
-		ff.currentOffset, err = ff.dataFile.Seek(0, 2)
+		// Drop the entries at the end that weren't entirely written
+		// and set the currentOffset to the end of the last entry.
+		err = ff.truncateTornEntries(dataPath)
 		if err != nil {
 			return err
 		}
 
+	} else if ff.readOnly {
+		ff.offsets = make([]int64, 1)
 	} else {
 		// We don't save block 0 with utreexo proof index.  Just append
 		// 0s since we don't keep it.
@@ -155,6 +284,10 @@ func (ff *FlatFileState) StoreData(height int32, data []byte) error {
 	ff.mtx.Lock()
 	defer ff.mtx.Unlock()
 
+	if ff.readOnly {
+		return errFlatFileReadOnly
+	}
+
 	// We only accept the next block in seqence.
 	if height != ff.currentHeight+1 || height <= 0 {
 		return fmt.Errorf("Passed in height not the next block in sequence. "+
@@ -162,7 +295,7 @@ func (ff *FlatFileState) StoreData(height int32, data []byte) error {
 	}
 
 	// Pre-allocate the needed buffer.
-	buf := make([]byte, len(data)+8)
+	buf := make([]byte, len(data)+12)
 
 	// Slice the buffer to 8 bytes and encode the offset to it.
 	buf = buf[:8]
@@ -176,21 +309,24 @@ func (ff *FlatFileState) StoreData(height int32, data []byte) error {
 	}
 
 	// Re-slice the buffer to the total length.
-	buf = buf[:len(data)+8]
+	buf = buf[:len(data)+12]
 
-	// Add the magic bytes, size, and the data to the buffer to be written.
-	copy(buf[:4], magicBytes)
+	// Add the magic bytes, size, checksum, and the data to the buffer to
+	// be written.
+	copy(buf[:4], checksumMagicBytes)
 	binary.BigEndian.PutUint32(buf[4:8], uint32(len(data)))
-	copy(buf[8:], data)
+	binary.BigEndian.PutUint32(buf[8:12], crc32.Checksum(data, castagnoli))
+	copy(buf[12:], data)
 
-	// Write the magic+size+data to the dataFile.
+	// Write the magic+size+checksum+data to the dataFile.
 	_, err = ff.dataFile.WriteAt(buf, ff.currentOffset)
 	if err != nil {
 		return err
 	}
 
-	// Increment the current offset.  +8 to account for the magic bytes and size.
-	ff.currentOffset += int64(len(data)) + 8
+	// Increment the current offset.  +12 to account for the magic bytes,
+	// size, and checksum.
+	ff.currentOffset += int64(len(data)) + 12
 
 	// Finally, increment the currentHeight.
 	ff.currentHeight++
@@ -213,34 +349,8 @@ func (ff *FlatFileState) FetchData(height int32) ([]byte, error) {
 		return nil, nil
 	}
 
-	// Grab the offset for where the data is in the dataFile.
-	offset := ff.offsets[height]
-
-	// Read from the dataFile.  This read will grab the magic bytes and the
-	// size bytes.
-	buf := make([]byte, 8)
-	_, err := ff.dataFile.ReadAt(buf, offset)
-	if err != nil {
-		return nil, err
-	}
-
-	// Sanity check.  If wrong magic was read, then error out.
-	if !bytes.Equal(buf[:4], magicBytes) {
-		return nil, fmt.Errorf("Read wrong magic bytes. Expect %x but got %x",
-			magicBytes, buf[:4])
-	}
-
-	// Size of the actual data we want to fetch.
-	size := binary.BigEndian.Uint32(buf[4:])
-
-	// Now do the actual read of the data from the dataFile.
-	dataBuf := make([]byte, size)
-	_, err = ff.dataFile.ReadAt(dataBuf, offset+8)
-	if err != nil {
-		return nil, err
-	}
-
-	return dataBuf, nil
+	// Grab the offset for where the data is in the dataFile and read it.
+	return ff.readEntry(ff.offsets[height])
 }
 
 // DisconnectBlock is used during reorganizations and it deletes the last data
@@ -252,32 +362,23 @@ func (ff *FlatFileState) DisconnectBlock(height int32) error {
 	ff.mtx.Lock()
 	defer ff.mtx.Unlock()
 
+	if ff.readOnly {
+		return errFlatFileReadOnly
+	}
+
 	if height != ff.currentHeight {
 		return fmt.Errorf("FlatFileState: Lastest block saved is %d but was asked to disconnect height %d",
 			ff.currentHeight, height)
 	}
 
+	// Sanity check that the entry is there before it's cut off.
 	offset := ff.offsets[height]
-	buf := make([]byte, 8)
-
-	// Read from the dataFile to get the size of the data.
-	_, err := ff.dataFile.ReadAt(buf, offset)
-	if err != nil {
-		return err
-	}
-
-	if !bytes.Equal(buf[:4], magicBytes) {
-		return fmt.Errorf("read wrong magic of %x", buf[:4])
-	}
-
-	size := int64(binary.BigEndian.Uint32(buf[4:]))
-
-	dataFileSize, err := ff.dataFile.Seek(0, 2)
+	_, err := ff.readEntryHeader(offset)
 	if err != nil {
 		return err
 	}
 
-	err = ff.dataFile.Truncate(dataFileSize - (size + 8))
+	err = ff.dataFile.Truncate(offset)
 	if err != nil {
 		return err
 	}
@@ -305,6 +406,118 @@ func (ff *FlatFileState) DisconnectBlock(height int32) error {
 	return nil
 }
 
+// truncateTornEntries drops the entries at the end of the FlatFileState that
+// weren't entirely written, which happens when the node stops while an entry
+// is being stored, such as on a power loss.  The offset of an entry is written
+// before its data, so it's dropped when its data is cut off by the end of the
+// dataFile, has the wrong magic, or doesn't match its checksum.  The partially
+// written data after the last entry is removed as well.  Only the entries at
+// the end are checked, from the last one back to the first one that's intact.
+// When the FlatFileState is read-only, the entries are only dropped from memory
+// since they're most likely still being written.
+func (ff *FlatFileState) truncateTornEntries(dataPath string) error {
+	dataFileSize, err := ff.dataFile.Seek(0, 2)
+	if err != nil {
+		return err
+	}
+
+	end := int64(0)
+	height := ff.currentHeight
+	for ; height > 0; height-- {
+		offset := ff.offsets[height]
+		if offset > dataFileSize {
+			continue
+		}
+		header, err := ff.readEntryHeader(offset)
+		if err != nil {
+			continue
+		}
+		entryEnd := offset + header.len() + int64(header.size)
+		if entryEnd > dataFileSize {
+			continue
+		}
+		if header.hasChecksum {
+			if _, err := ff.readEntry(offset); err != nil {
+				continue
+			}
+		}
+		end = entryEnd
+		break
+	}
+
+	dropped := ff.currentHeight - height
+	if ff.readOnly {
+		ff.offsets = ff.offsets[:height+1]
+		ff.currentHeight = height
+		ff.currentOffset = end
+		return nil
+	}
+	if dropped > 0 {
+		log.Warnf("Truncating the %d partially written entries at the "+
+			"end of %s from height %d to %d", dropped, dataPath,
+			ff.currentHeight, height)
+		err := ff.offsetFile.Truncate(int64(height+1) * 8)
+		if err != nil {
+			return err
+		}
+		ff.offsets = ff.offsets[:height+1]
+		ff.currentHeight = height
+	}
+	if end < dataFileSize {
+		if dropped == 0 {
+			log.Warnf("Truncating %d partially written bytes at the "+
+				"end of %s", dataFileSize-end, dataPath)
+		}
+		if err := ff.dataFile.Truncate(end); err != nil {
+			return err
+		}
+	}
+	ff.currentOffset = end
+
+	return nil
+}
+
+// backup copies the files of the FlatFileState to the passed directory, which
+// is created if needed.  The files are copied up to the end of the last entry
+// rather than hard-linked since disconnecting a block truncates them.  Storing
+// and disconnecting data waits for the copy to be made.
+//
+// This function is safe for concurrent access.
+func (ff *FlatFileState) backup(path string) error {
+	ff.mtx.RLock()
+	defer ff.mtx.RUnlock()
+
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return err
+	}
+	offsetPath := filepath.Join(path, filepath.Base(ff.offsetFile.Name()))
+	err := copyFileSection(offsetPath, ff.offsetFile,
+		int64(ff.currentHeight+1)*8)
+	if err != nil {
+		return err
+	}
+	dataPath := filepath.Join(path, filepath.Base(ff.dataFile.Name()))
+	return copyFileSection(dataPath, ff.dataFile, ff.currentOffset)
+}
+
+// copyFileSection copies the first size bytes of the passed file to a new file
+// at the passed path.
+func copyFileSection(path string, file io.ReaderAt, size int64) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, io.NewSectionReader(file, 0, size))
+	if err == nil {
+		err = out.Sync()
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
 // deleteFileFile removes the flat file state directory and all the contents
 // in it.
 func deleteFlatFile(path string) error {
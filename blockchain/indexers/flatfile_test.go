@@ -321,16 +321,16 @@ func tryToDisconnectUnallowed(ff *FlatFileState, height int32) error {
 func getAfterSizes(ff *FlatFileState, height int32) (int64, int64, error) {
 	// Get the size of the data to be disconnected.
 	offset := ff.offsets[height]
-	buf := make([]byte, 8)
+	buf := make([]byte, 12)
 
 	_, err := ff.dataFile.ReadAt(buf, offset)
 	if err != nil {
 		return 0, 0, err
 	}
-	if !bytes.Equal(buf[:4], magicBytes) {
+	if !bytes.Equal(buf[:4], checksumMagicBytes) {
 		return 0, 0, fmt.Errorf("read wrong magic of %x", buf[:4])
 	}
-	dataSize := binary.BigEndian.Uint32(buf[4:])
+	dataSize := binary.BigEndian.Uint32(buf[4:8])
 
 	// Get data file size.
 	dataFileSize, err := ff.dataFile.Seek(0, 2)
@@ -344,7 +344,7 @@ func getAfterSizes(ff *FlatFileState, height int32) (int64, int64, error) {
 		return 0, 0, err
 	}
 
-	return dataFileSize - int64(dataSize+8), offsetSize - 8, nil
+	return dataFileSize - int64(dataSize+12), offsetSize - 8, nil
 }
 
 func getSizes(ff *FlatFileState) (int64, int64, error) {
@@ -649,3 +649,223 @@ func TestMultipleFetchData(t *testing.T) {
 
 	wg.Wait()
 }
+
+// TestTornWrites ensures that the entries at the end of a FlatFileState that
+// weren't entirely written are dropped on restart and that the entries before
+// them and the ones written without a checksum can still be fetched.
+func TestTornWrites(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := os.MkdirTemp("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Write the first two entries without a checksum, as the releases
+	// before checksums did.
+	dir := "TestTornWrites"
+	ffPath := filepath.Join(tmpDir, dir)
+	if err := os.MkdirAll(ffPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	storedData := map[int32][]byte{
+		1: []byte("legacy entry 1"),
+		2: []byte("legacy entry 2"),
+	}
+	offsets := make([]byte, 3*8)
+	var data []byte
+	for height := int32(1); height <= 2; height++ {
+		binary.BigEndian.PutUint64(offsets[height*8:], uint64(len(data)))
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(storedData[height])))
+		data = append(data, magicBytes...)
+		data = append(data, size[:]...)
+		data = append(data, storedData[height]...)
+	}
+	err = os.WriteFile(filepath.Join(ffPath, offsetFileName), offsets, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataPath := filepath.Join(ffPath, "data"+dataFileSuffix)
+	if err := os.WriteFile(dataPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+
+		// tear damages the files after the entry of height 4 was
+		// written.
+		tear func(ff *FlatFileState) error
+
+		// wantHeight is the height after a restart.
+		wantHeight int32
+	}{
+		{
+			name: "partially written offset",
+			tear: func(ff *FlatFileState) error {
+				_, err := ff.offsetFile.WriteAt([]byte{0, 0, 0}, 5*8)
+				return err
+			},
+			wantHeight: 4,
+		},
+		{
+			name: "data cut off",
+			tear: func(ff *FlatFileState) error {
+				return ff.dataFile.Truncate(ff.currentOffset - 3)
+			},
+			wantHeight: 3,
+		},
+		{
+			name: "data not written",
+			tear: func(ff *FlatFileState) error {
+				return ff.dataFile.Truncate(ff.offsets[4])
+			},
+			wantHeight: 3,
+		},
+		{
+			name: "data doesn't match its checksum",
+			tear: func(ff *FlatFileState) error {
+				_, err := ff.dataFile.WriteAt([]byte{0xff},
+					ff.currentOffset-1)
+				return err
+			},
+			wantHeight: 3,
+		},
+		{
+			name: "partially written data after the last entry",
+			tear: func(ff *FlatFileState) error {
+				_, err := ff.dataFile.WriteAt(checksumMagicBytes,
+					ff.currentOffset)
+				return err
+			},
+			wantHeight: 4,
+		},
+	}
+
+	for _, test := range tests {
+		ff, err := restartFF(tmpDir, dir)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for height := ff.currentHeight + 1; height <= 4; height++ {
+			storedData[height] = []byte(fmt.Sprintf("entry %d", height))
+			err := ff.StoreData(height, storedData[height])
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		if err := test.tear(ff); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if _, _, _, err := closeFF(ff); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		ff, err = restartFF(tmpDir, dir)
+		if err != nil {
+			t.Fatalf("%s: unexpected error on restart: %v", test.name, err)
+		}
+		if ff.currentHeight != test.wantHeight {
+			t.Fatalf("%s: got height %d after restart, want %d",
+				test.name, ff.currentHeight, test.wantHeight)
+		}
+		err = checkDataStillFetches(ff.currentHeight+1, ff, storedData)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		dataFileSize, err := ff.dataFile.Seek(0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ff.currentOffset != dataFileSize {
+			t.Fatalf("%s: got currentOffset %d, want the data file "+
+				"size of %d", test.name, ff.currentOffset,
+				dataFileSize)
+		}
+		if _, _, _, err := closeFF(ff); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
+
+// TestReadOnly ensures that a FlatFileState that's initialized read-only reads
+// the data of a FlatFileState that's in use, leaves out the entry that's still
+// being written without modifying the files, and refuses to write.
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := os.MkdirTemp("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir := "TestReadOnly"
+	ffPath := filepath.Join(tmpDir, dir)
+	ro := NewFlatFileState()
+	if err := ro.InitReadOnly(ffPath, "data"); err == nil {
+		t.Fatalf("expected an error for missing files")
+	}
+	if _, err := os.Stat(ffPath); !os.IsNotExist(err) {
+		t.Fatalf("the missing files were created")
+	}
+
+	ff, err := restartFF(tmpDir, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFF(ff)
+	storedData := make(map[int32][]byte)
+	for height := int32(1); height <= 3; height++ {
+		storedData[height] = []byte(fmt.Sprintf("entry %d", height))
+		if err := ff.StoreData(height, storedData[height]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Leave the entry of height 4 half written, as it is while it's being
+	// stored.
+	var offset [8]byte
+	binary.BigEndian.PutUint64(offset[:], uint64(ff.currentOffset))
+	if _, err := ff.offsetFile.WriteAt(offset[:], 4*8); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ff.dataFile.WriteAt(checksumMagicBytes, ff.currentOffset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataPath := filepath.Join(ffPath, "data"+dataFileSuffix)
+	before, err := os.Stat(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ro = NewFlatFileState()
+	if err := ro.InitReadOnly(ffPath, "data"); err != nil {
+		t.Fatalf("InitReadOnly: unexpected error: %v", err)
+	}
+	defer closeFF(ro)
+	if ro.currentHeight != 3 {
+		t.Fatalf("got height %d, want 3", ro.currentHeight)
+	}
+	if err := checkDataStillFetches(4, ro, storedData); err != nil {
+		t.Fatal(err)
+	}
+	if err := ro.StoreData(4, []byte("entry 4")); err == nil {
+		t.Fatalf("expected StoreData to be refused")
+	}
+	if err := ro.DisconnectBlock(3); err == nil {
+		t.Fatalf("expected DisconnectBlock to be refused")
+	}
+
+	after, err := os.Stat(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() != before.Size() {
+		t.Fatalf("the data file changed from %d to %d bytes",
+			before.Size(), after.Size())
+	}
+}
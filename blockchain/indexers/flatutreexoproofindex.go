@@ -238,6 +238,28 @@ func (idx *FlatUtreexoProofIndex) Key() []byte {
 	return flatUtreexoBucketKey
 }
 
+// extraSize returns the size of the flat files and of the utreexo state.
+//
+// This is part of the extraSizer interface.
+func (idx *FlatUtreexoProofIndex) extraSize(dbTx database.Tx) (int64, error) {
+	names := []string{flatUtreexoProofName, flatUtreexoUndoName,
+		flatRememberIdxName, flatUtreexoProofStatsName, flatUtreexoRootsName}
+	paths := []string{utreexoBasePath(idx.utreexoState.config)}
+	for _, name := range names {
+		paths = append(paths, flatFilePath(idx.dataDir, name))
+	}
+
+	var size int64
+	for _, path := range paths {
+		pathBytes, err := pathSize(path)
+		if err != nil {
+			return 0, err
+		}
+		size += pathBytes
+	}
+	return size, nil
+}
+
 // Create is invoked when the indexer manager determines the index needs
 // to be created for the first time.
 //
@@ -279,10 +301,7 @@ func (idx *FlatUtreexoProofIndex) ConnectBlock(dbTx database.Tx, block *btcutil.
 		return err
 	}
 
-	delHashes := make([]utreexo.Hash, 0, len(ud.LeafDatas))
-	for _, ld := range ud.LeafDatas {
-		delHashes = append(delHashes, ld.LeafHash())
-	}
+	delHashes := wire.LeafHashes(ud.LeafDatas)
 	// For pruned nodes and for multi-block proofs, we need to save the
 	// undo block in order to undo a block on reorgs. If we have all the
 	// proofs block by block, that data can be used for reorgs but these
@@ -464,10 +483,7 @@ func (idx *FlatUtreexoProofIndex) attachBlock(blk *btcutil.Block, stxos []blockc
 		return err
 	}
 
-	delHashes := make([]utreexo.Hash, len(dels))
-	for i, del := range dels {
-		delHashes[i] = del.LeafHash()
-	}
+	delHashes := wire.LeafHashes(dels)
 
 	err = idx.utreexoState.state.Modify(adds, delHashes, ud.AccProof)
 	if err != nil {
@@ -664,10 +680,7 @@ func (idx *FlatUtreexoProofIndex) MakeMultiBlockProof(currentHeight, proveHeight
 		panic(err)
 	}
 
-	delHashes := make([]utreexo.Hash, 0, len(delsToProve))
-	for _, del := range delsToProve {
-		delHashes = append(delHashes, del.LeafHash())
-	}
+	delHashes := wire.LeafHashes(delsToProve)
 
 	// Store the proof that we have created.
 	err = idx.storeMultiBlockProof(currentHeight, currentUD, ud, delHashes)
@@ -1148,3 +1148,250 @@ func TestBridgeNodePruneUndoDataGen(t *testing.T) {
 		}
 	}
 }
+
+// TestBackgroundCatchUp ensures that the indexes that can be caught up in the
+// background are skipped when blocks are connected while they're behind and
+// that they're kept up to date once they have caught up.
+func TestBackgroundCatchUp(t *testing.T) {
+	// Always remove the root on return.
+	defer os.RemoveAll(testDbRoot)
+
+	chain, _, params, indexManager, tearDown := indexersTestChain("TestBackgroundCatchUp", 1)
+	defer tearDown()
+
+	nextBlock := btcutil.NewBlock(params.GenesisBlock)
+	var blocks []*btcutil.Block
+	for i := 0; i < 20; i++ {
+		newBlock, _, err := blockchain.AddBlock(chain, nextBlock, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, newBlock)
+		nextBlock = newBlock
+	}
+
+	// Enable the tx index on the existing chain.
+	db := indexManager.db
+	txIndex := NewTxIndex(db)
+	manager := NewManager(db, []Indexer{txIndex})
+	if err := manager.Init(chain, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The block connected while the index may still be catching up gets
+	// indexed either way.
+	newBlock, _, err := blockchain.AddBlock(chain, nextBlock, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks = append(blocks, newBlock)
+	err = db.Update(func(dbTx database.Tx) error {
+		return manager.ConnectBlock(dbTx, newBlock, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the index to catch up.
+	for i := 0; ; i++ {
+		manager.mtx.Lock()
+		catchingUp := manager.catchingUp[0]
+		manager.mtx.Unlock()
+		if !catchingUp {
+			break
+		}
+		if i == 100 {
+			t.Fatal("the tx index didn't catch up in the background")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for _, block := range blocks {
+		for _, tx := range block.Transactions() {
+			region, err := txIndex.TxBlockRegion(tx.Hash())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if region == nil || !region.Hash.IsEqual(block.Hash()) {
+				t.Fatalf("tx %v of block %v isn't indexed",
+					tx.Hash(), block.Hash())
+			}
+		}
+	}
+}
+
+// TestPruneBlocker ensures that the indexes that block pruning keep the blocks
+// they have indexed while the entries of the other indexes are pruned along
+// with the blocks.
+func TestPruneBlocker(t *testing.T) {
+	db, dbPath, err := createDB("pruneblocker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	txIndex := NewTxIndex(db)
+	cfIndex := NewCfIndex(db, &chaincfg.RegressionNetParams)
+	tests := []struct {
+		indexes    []Indexer
+		keepHeight int32
+	}{
+		{indexes: []Indexer{cfIndex}, keepHeight: -1},
+		{indexes: []Indexer{cfIndex, txIndex}, keepHeight: 0},
+	}
+
+	fetchHash := func(height int32) (*chainhash.Hash, error) {
+		return &chainhash.Hash{byte(height)}, nil
+	}
+	for _, test := range tests {
+		manager := NewManager(db, test.indexes)
+		err = db.Update(func(dbTx database.Tx) error {
+			meta := dbTx.Metadata()
+			_, err := meta.CreateBucketIfNotExists(indexTipsBucketName)
+			if err != nil {
+				return err
+			}
+			_, err = meta.CreateBucketIfNotExists(indexEarliestBucketName)
+			if err != nil {
+				return err
+			}
+			return manager.maybeCreateIndexes(dbTx)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.Update(func(dbTx database.Tx) error {
+			keepHeight, err := manager.KeepHeight(dbTx)
+			if err != nil {
+				return err
+			}
+			if keepHeight != test.keepHeight {
+				return fmt.Errorf("KeepHeight: got %d, want %d",
+					keepHeight, test.keepHeight)
+			}
+
+			return manager.PruneBlocks(dbTx, 10, fetchHash)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the entries of the cf index were pruned.
+	err = db.View(func(dbTx database.Tx) error {
+		_, height, err := dbFetchIndexerEarliest(dbTx, cfIndex.Key())
+		if err != nil {
+			return err
+		}
+		if height < 11 {
+			return fmt.Errorf("cf index earliest height: got %d, "+
+				"want at least 11", height)
+		}
+
+		_, height, err = dbFetchIndexerEarliest(dbTx, txIndex.Key())
+		if err != nil {
+			return err
+		}
+		if height != -1 {
+			return fmt.Errorf("tx index earliest height: got %d, "+
+				"want -1", height)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testDependentIndex is an index that can only be connected to a block once
+// another index has been.
+type testDependentIndex struct {
+	Indexer
+	dep Indexer
+}
+
+func (idx *testDependentIndex) dependency() Indexer {
+	return idx.dep
+}
+
+func (idx *testDependentIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	_, height, err := dbFetchIndexerTip(dbTx, idx.dep.Key())
+	if err != nil {
+		return err
+	}
+	if height < block.Height() {
+		return fmt.Errorf("%s is behind the block at height %d",
+			idx.dep.Name(), block.Height())
+	}
+	return idx.Indexer.ConnectBlock(dbTx, block, stxos)
+}
+
+// TestParallelCatchUp ensures that the indexes that are behind are caught up
+// together and that the indexes that depend on another index aren't connected
+// to a block before it.
+func TestParallelCatchUp(t *testing.T) {
+	// Always remove the root on return.
+	defer os.RemoveAll(testDbRoot)
+
+	chain, _, params, indexManager, tearDown := indexersTestChain("TestParallelCatchUp", 1)
+	defer tearDown()
+
+	nextBlock := btcutil.NewBlock(params.GenesisBlock)
+	var blocks []*btcutil.Block
+	for i := 0; i < 40; i++ {
+		newBlock, _, err := blockchain.AddBlock(chain, nextBlock, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, newBlock)
+		nextBlock = newBlock
+	}
+
+	// Enable the indexes on the existing chain.  They're wrapped so that
+	// they're caught up while the manager is initialized rather than in
+	// the background.
+	db := indexManager.db
+	txIndex := struct{ Indexer }{NewTxIndex(db)}
+	blockTimeIndex := NewBlockTimeIndex(db)
+	indexes := []Indexer{
+		txIndex,
+		&testDependentIndex{Indexer: blockTimeIndex, dep: txIndex},
+	}
+	manager := NewManager(db, indexes)
+	if err := manager.Init(chain, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.View(func(dbTx database.Tx) error {
+		for _, indexer := range indexes {
+			hash, height, err := dbFetchIndexerTip(dbTx, indexer.Key())
+			if err != nil {
+				return err
+			}
+			tip := blocks[len(blocks)-1]
+			if height != tip.Height() || !hash.IsEqual(tip.Hash()) {
+				return fmt.Errorf("%s tip: got %v (height %d), "+
+					"want %v (height %d)", indexer.Name(), hash,
+					height, tip.Hash(), tip.Height())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := blockTimeIndex.FetchBlocksByTime(time.Unix(0, 0),
+		time.Unix(1<<32-1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(blocks)+1 {
+		t.Fatalf("FetchBlocksByTime: got %d blocks, want %d",
+			len(entries), len(blocks)+1)
+	}
+}
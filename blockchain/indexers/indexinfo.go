@@ -0,0 +1,127 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+)
+
+// IndexInfo describes the state of an index.
+type IndexInfo struct {
+	// BestHash and BestHeight are the block the index is caught up to.
+	BestHash   chainhash.Hash
+	BestHeight int32
+
+	// SizeOnDisk is the number of bytes of the data of the index.  For the
+	// data kept in the block database, it's the size of the keys and values
+	// before compression.
+	SizeOnDisk int64
+}
+
+// extraSizer is implemented by the indexes that keep data outside of the
+// bucket of their key.
+type extraSizer interface {
+	// extraSize returns the number of bytes of the data of the index that
+	// isn't kept in the bucket of its key.
+	extraSize(dbTx database.Tx) (int64, error)
+}
+
+// FetchIndexInfo returns the tip and the size on disk of the index.  The size
+// is computed by walking through every entry of the index so this may take a
+// while for large indexes.
+func FetchIndexInfo(db database.DB, indexer Indexer) (*IndexInfo, error) {
+	var info IndexInfo
+	err := db.View(func(dbTx database.Tx) error {
+		hash, height, err := dbFetchIndexerTip(dbTx, indexer.Key())
+		if err != nil {
+			return err
+		}
+		info.BestHash = *hash
+		info.BestHeight = height
+
+		bucket := dbTx.Metadata().Bucket(indexer.Key())
+		if bucket != nil {
+			info.SizeOnDisk, err = bucketSize(bucket)
+			if err != nil {
+				return err
+			}
+		}
+
+		if sizer, ok := indexer.(extraSizer); ok {
+			size, err := sizer.extraSize(dbTx)
+			if err != nil {
+				return err
+			}
+			info.SizeOnDisk += size
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// bucketSize returns the number of bytes of the keys and values of the bucket
+// and all of its nested buckets.
+func bucketSize(bucket database.Bucket) (int64, error) {
+	var size int64
+	err := bucket.ForEach(func(k, v []byte) error {
+		size += int64(len(k) + len(v))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = bucket.ForEachBucket(func(k []byte) error {
+		nestedSize, err := bucketSize(bucket.Bucket(k))
+		if err != nil {
+			return err
+		}
+		size += nestedSize
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// pathSize returns the number of bytes of the file at the path, or of all the
+// files below it if it's a directory.  A path that doesn't exist has no size.
+func pathSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
@@ -7,6 +7,8 @@ package indexers
 import (
 	"bytes"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/utreexo/utreexod/blockchain"
 	"github.com/utreexo/utreexod/btcutil"
@@ -166,11 +168,45 @@ func dbIndexDisconnectBlock(dbTx database.Tx, indexer Indexer, block *btcutil.Bl
 type Manager struct {
 	db             database.DB
 	enabledIndexes []Indexer
+
+	// mtx protects the fields below along with the tips of the indexes
+	// that are caught up in the background.
+	mtx sync.Mutex
+
+	// catchingUp marks the enabled indexes that are still being caught up
+	// in the background.  They're skipped when blocks are connected to and
+	// disconnected from the main chain, except for disconnecting the block
+	// at their tip.
+	catchingUp []bool
+
+	// tip is the hash of the last block connected to the main chain.
+	tip chainhash.Hash
+
+	// disconnects is the number of blocks disconnected from the main chain
+	// so that the background catch up notices the reorganizations that
+	// happen while it fetches the next block.
+	disconnects uint64
 }
 
 // Ensure the Manager type implements the blockchain.IndexManager interface.
 var _ blockchain.IndexManager = (*Manager)(nil)
 
+// Ensure the Manager type implements the blockchain.UtreexoStatser interface.
+var _ blockchain.UtreexoStatser = (*Manager)(nil)
+
+// Ensure the Manager type implements the blockchain.UtreexoRootsFetcher
+// interface.
+var _ blockchain.UtreexoRootsFetcher = (*Manager)(nil)
+
+// utreexoRootsFetcher is implemented by the indexes that keep a utreexo
+// accumulator.
+type utreexoRootsFetcher interface {
+	FetchCurrentUtreexoState() ([]*chainhash.Hash, uint64)
+	fetchUtreexoRoots(height int32) ([]*chainhash.Hash, uint64, error)
+	utreexoRootsAfter(block *btcutil.Block, stxos []blockchain.SpentTxOut) (
+		[]*chainhash.Hash, uint64, error)
+}
+
 // indexDropKey returns the key for an index which indicates it is in the
 // process of being dropped.
 func indexDropKey(idxKey []byte) []byte {
@@ -180,6 +216,24 @@ func indexDropKey(idxKey []byte) []byte {
 	return dropKey
 }
 
+// indexVersionKey returns the key of the metadata bucket the version of the
+// layout of the index with the passed key is stored under.
+func indexVersionKey(idxKey []byte) []byte {
+	return append([]byte("idxversion-"), idxKey...)
+}
+
+// indexDbSchema returns the versioned layout of the data of the indexer.
+func indexDbSchema(indexer Indexer) *blockchain.DbSchema {
+	schema := &blockchain.DbSchema{
+		Name:       indexer.Name(),
+		VersionKey: indexVersionKey(indexer.Key()),
+	}
+	if migrator, ok := indexer.(Migrator); ok {
+		schema.Migrations = migrator.Migrations()
+	}
+	return schema
+}
+
 // maybeFinishDrops determines if each of the enabled indexes are in the middle
 // of being dropped and finishes dropping them when the are.  This is necessary
 // because dropping and index has to be done in several atomic steps rather than
@@ -255,6 +309,12 @@ func (m *Manager) maybeCreateIndexes(dbTx database.Tx) error {
 			return err
 		}
 
+		// The new index is created at the latest version.
+		err = blockchain.PutLatestDbSchemaVersion(dbTx, indexDbSchema(indexer))
+		if err != nil {
+			return err
+		}
+
 		// Set the earliest for the index to values which represent an
 		// uninitialized index.
 		err = dbPutIndexerEarliest(dbTx, idxKey, &chainhash.Hash{}, -1)
@@ -273,6 +333,11 @@ func (m *Manager) maybeCreateIndexes(dbTx database.Tx) error {
 // time new blocks are being downloaded would lead to an overall longer time to
 // catch up due to the I/O contention.
 //
+// The indexes that implement the BackgroundCatchUpper interface are the
+// exception when the node doesn't prune blocks.  As nothing else depends on
+// them, they are caught up in the background instead so that enabling one
+// of them on an existing node doesn't keep it from starting up.
+//
 // This is part of the blockchain.IndexManager interface.
 func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{}) error {
 	// Nothing to do when no indexes are enabled.
@@ -309,6 +374,19 @@ func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{})
 		return err
 	}
 
+	// Upgrade the layouts of the indexes that were written by older
+	// releases before they're initialized.
+	for _, indexer := range m.enabledIndexes {
+		err := blockchain.UpgradeDbSchema(m.db, indexDbSchema(indexer),
+			interrupt)
+		if err != nil {
+			if interruptRequested(interrupt) {
+				return errInterruptRequested
+			}
+			return err
+		}
+	}
+
 	// Initialize each of the enabled indexes.
 	for _, indexer := range m.enabledIndexes {
 		if err := indexer.Init(chain); err != nil {
@@ -410,10 +488,13 @@ func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{})
 	// Fetch the current tip heights for each index along with tracking the
 	// lowest one so the catchup code only needs to start at the earliest
 	// block and is able to skip connecting the block for the indexes that
-	// don't need it.
-	bestHeight := chain.BestSnapshot().Height
+	// don't need it.  The indexes that are behind and can be caught up in
+	// the background are left out.
+	best := chain.BestSnapshot()
+	bestHeight := best.Height
 	lowestHeight := bestHeight
 	indexerHeights := make([]int32, len(m.enabledIndexes))
+	var catchUpInBackground bool
 	err = m.db.View(func(dbTx database.Tx) error {
 		for i, indexer := range m.enabledIndexes {
 			idxKey := indexer.Key()
@@ -425,6 +506,15 @@ func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{})
 			log.Debugf("Current %s tip (height %d, hash %v)",
 				indexer.Name(), height, hash)
 			indexerHeights[i] = height
+			if height < bestHeight && !chain.IsPruneEnabled() &&
+				indexCatchesUpInBackground(indexer) {
+
+				log.Infof("Catching up %s from height %d in "+
+					"the background", indexer.Name(), height)
+				m.catchingUp[i] = true
+				catchUpInBackground = true
+				continue
+			}
 			if height < lowestHeight {
 				lowestHeight = height
 			}
@@ -435,8 +525,16 @@ func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{})
 		return err
 	}
 
+	// Keep track of the tip of the main chain so that the indexes caught
+	// up in the background know when they're done.  They only start being
+	// caught up once the other indexes are.
+	m.tip = best.Hash
+
 	// Nothing to index if all of the indexes are caught up.
 	if lowestHeight == bestHeight {
+		if catchUpInBackground {
+			go m.backgroundCatchUp(chain, interrupt)
+		}
 		return nil
 	}
 
@@ -451,97 +549,397 @@ func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{})
 			lowestHeight+1, bestHeight, err)
 	}
 
-	// Create a progress logger for the indexing process below.
-	progressLogger := newBlockProgressLogger("Indexed", log)
-
 	// At this point, one or more indexes are behind the current best chain
-	// tip and need to be caught up, so log the details and loop through
-	// each block that needs to be indexed.
+	// tip and need to be caught up, so log the details and connect each
+	// block that needs to be indexed to them.
 	log.Infof("Catching up indexes from height %d to %d", lowestHeight,
 		bestHeight)
 
+	err = m.catchUpIndexes(chain, indexerHeights, lowestHeight, bestHeight,
+		interrupt)
+	if err == errInterruptRequested {
+		for _, indexer := range m.enabledIndexes {
+			switch idxType := indexer.(type) {
+			case *UtreexoProofIndex:
+				err := idxType.FlushUtreexoState()
+				if err != nil {
+					log.Errorf("Error while flushing utreexo state: %v", err)
+				}
+			case *FlatUtreexoProofIndex:
+				err := idxType.FlushUtreexoState()
+				if err != nil {
+					log.Errorf("Error while flushing utreexo state for flat utreexo proof index: %v", err)
+				}
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Indexes caught up to height %d", bestHeight)
+	if catchUpInBackground {
+		go m.backgroundCatchUp(chain, interrupt)
+	}
+	return nil
+}
+
+// catchUpQueueSize is the number of blocks that are loaded ahead of the index
+// that is the furthest behind while catching up the indexes.
+const catchUpQueueSize = 16
+
+// catchUpBlock is a block being caught up along with the txouts it spends.
+type catchUpBlock struct {
+	block     *btcutil.Block
+	spentTxos []blockchain.SpentTxOut
+}
+
+// catchUpIndexes connects the blocks after lowestHeight up to bestHeight to
+// the indexes that are behind them and aren't caught up in the background.
+// The blocks are loaded only once and fed to a worker for each index that
+// connects them to the index in its own database transactions, so that the
+// indexes don't wait on each other except for the ones that depend on another
+// index being connected to a block first.
+func (m *Manager) catchUpIndexes(chain *blockchain.BlockChain, indexerHeights []int32,
+	lowestHeight, bestHeight int32, interrupt <-chan struct{}) error {
+
+	// heights tracks the tips of the indexes while they're caught up so
+	// that the workers of the indexes that depend on another one can wait
+	// for it.  The first error stops all of the workers.
+	var (
+		mtx      sync.Mutex
+		cond     = sync.NewCond(&mtx)
+		heights  = make([]int32, len(indexerHeights))
+		firstErr error
+		quit     = make(chan struct{})
+	)
+	copy(heights, indexerHeights)
+	fail := func(err error) {
+		mtx.Lock()
+		if firstErr == nil {
+			firstErr = err
+			close(quit)
+		}
+		cond.Broadcast()
+		mtx.Unlock()
+	}
+
+	// Start a worker for each index that needs to be caught up.
+	type worker struct {
+		idx        int
+		dependency int
+		blocks     chan *catchUpBlock
+	}
+	var workers []*worker
+	workerIdx := make(map[Indexer]int)
+	for i, indexer := range m.enabledIndexes {
+		if m.catchingUp[i] || indexerHeights[i] >= bestHeight {
+			continue
+		}
+
+		// Only the dependencies that are caught up along with the index
+		// are waited for.  The others are already past the blocks.
+		dependency := -1
+		if dep := indexDependency(indexer); dep != nil {
+			if j, ok := workerIdx[dep]; ok {
+				dependency = j
+			}
+		}
+		workerIdx[indexer] = i
+		workers = append(workers, &worker{
+			idx:        i,
+			dependency: dependency,
+			blocks:     make(chan *catchUpBlock, catchUpQueueSize),
+		})
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+
+			indexer := m.enabledIndexes[w.idx]
+			for b := range w.blocks {
+				height := b.block.Height()
+				if w.dependency >= 0 {
+					mtx.Lock()
+					for heights[w.dependency] < height && firstErr == nil {
+						cond.Wait()
+					}
+					mtx.Unlock()
+				}
+
+				if interruptRequested(interrupt) {
+					fail(errInterruptRequested)
+				}
+				select {
+				case <-quit:
+					return
+				default:
+				}
+
+				err := m.db.Update(func(dbTx database.Tx) error {
+					return dbIndexConnectBlock(
+						dbTx, indexer, b.block, b.spentTxos,
+					)
+				})
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				mtx.Lock()
+				heights[w.idx] = height
+				cond.Broadcast()
+				mtx.Unlock()
+			}
+		}(w)
+	}
+
+	// Create a progress logger for the indexing process below.
+	progressLogger := newBlockProgressLogger("Indexed", log)
+
+	// Load each block that needs to be indexed and feed it to the workers
+	// of the indexes that need it.
+out:
 	for height := lowestHeight + 1; height <= bestHeight; height++ {
+		if interruptRequested(interrupt) {
+			fail(errInterruptRequested)
+			break
+		}
+
 		// Load the block for the height since it is required to index
 		// it.
 		block, err := chain.BlockByHeight(height)
 		if err != nil {
-			return err
+			fail(err)
+			break
 		}
 
-		if interruptRequested(interrupt) {
-			for _, indexer := range m.enabledIndexes {
-				switch idxType := indexer.(type) {
-				case *UtreexoProofIndex:
-					err := idxType.FlushUtreexoState()
-					if err != nil {
-						log.Errorf("Error while flushing utreexo state: %v", err)
-					}
-				case *FlatUtreexoProofIndex:
-					err := idxType.FlushUtreexoState()
-					if err != nil {
-						log.Errorf("Error while flushing utreexo state for flat utreexo proof index: %v", err)
-					}
-				}
+		// When an index requires all of the referenced txouts, they
+		// need to be retrieved from the spend journal.
+		var spentTxos []blockchain.SpentTxOut
+		for _, w := range workers {
+			if indexerHeights[w.idx] < height &&
+				indexNeedsInputs(m.enabledIndexes[w.idx]) {
+
+				spentTxos, err = chain.FetchSpendJournal(block)
+				break
 			}
-			return errInterruptRequested
+		}
+		if err != nil {
+			fail(err)
+			break
 		}
 
-		// Connect the block for all indexes that need it.
-		var spentTxos []blockchain.SpentTxOut
-		for i, indexer := range m.enabledIndexes {
+		b := &catchUpBlock{block: block, spentTxos: spentTxos}
+		for _, w := range workers {
 			// Skip indexes that don't need to be updated with this
 			// block.
-			if indexerHeights[i] >= height {
+			if indexerHeights[w.idx] >= height {
 				continue
 			}
 
-			// When the index requires all of the referenced txouts
-			// and they haven't been loaded yet, they need to be
-			// retrieved from the spend journal.
-			if spentTxos == nil && indexNeedsInputs(indexer) {
-				spentTxos, err = chain.FetchSpendJournal(block)
-				if err != nil {
-					return err
-				}
+			select {
+			case w.blocks <- b:
+			case <-quit:
+				break out
 			}
+		}
 
-			err := m.db.Update(func(dbTx database.Tx) error {
-				return dbIndexConnectBlock(
-					dbTx, indexer, block, spentTxos,
-				)
-			})
+		// Log indexing progress.
+		progressLogger.LogBlockHeight(block)
+	}
+
+	// Wait for the workers to connect the blocks fed to them.
+	for _, w := range workers {
+		close(w.blocks)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// backgroundCatchUp catches up the indexes marked as catching up to the main
+// chain one block at a time while blocks keep being connected.  Once the tip of
+// an index is the tip of the main chain, it's kept up to date along with the
+// other indexes from then on.
+//
+// This MUST be run as a goroutine.
+func (m *Manager) backgroundCatchUp(chain *blockchain.BlockChain, interrupt <-chan struct{}) {
+	progressLogger := newBlockProgressLogger("Indexed", log)
+	for !interruptRequested(interrupt) {
+		block, done, err := m.backgroundCatchUpBlock(chain)
+		if err != nil {
+			if !interruptRequested(interrupt) {
+				log.Errorf("Unable to catch up indexes in the "+
+					"background: %v", err)
+			}
+			return
+		}
+		if done {
+			return
+		}
+
+		// Wait for the chain to catch up with the block that was just
+		// connected when there's no next block yet.
+		if block == nil {
+			time.Sleep(time.Millisecond * 100)
+			continue
+		}
+		progressLogger.LogBlockHeight(block)
+	}
+}
+
+// backgroundCatchUpBlock connects the next block to the indexes that are caught
+// up in the background and returns it.  No block is returned if the next block
+// isn't available yet or the chain was reorganized in the meantime.  It returns
+// true once there are no more indexes to catch up.
+func (m *Manager) backgroundCatchUpBlock(chain *blockchain.BlockChain) (
+	*btcutil.Block, bool, error) {
+
+	// Stop catching up the indexes whose tip is the tip of the main chain
+	// and find the lowest tip of the remaining ones.
+	m.mtx.Lock()
+	disconnects := m.disconnects
+	lowestHeight := int32(-1)
+	var catchingUp, needsInputs bool
+	err := m.db.View(func(dbTx database.Tx) error {
+		for i, indexer := range m.enabledIndexes {
+			if !m.catchingUp[i] {
+				continue
+			}
+
+			hash, height, err := dbFetchIndexerTip(dbTx, indexer.Key())
 			if err != nil {
 				return err
 			}
-			indexerHeights[i] = height
+			if hash.IsEqual(&m.tip) {
+				log.Infof("Caught up %s to height %d",
+					indexer.Name(), height)
+				m.catchingUp[i] = false
+				continue
+			}
+
+			if !catchingUp || height < lowestHeight {
+				lowestHeight = height
+			}
+			catchingUp = true
+			needsInputs = needsInputs || indexNeedsInputs(indexer)
+		}
+		return nil
+	})
+	m.mtx.Unlock()
+	if err != nil || !catchingUp {
+		return nil, true, err
+	}
+
+	// The chain may not have caught up with the block that was just
+	// connected yet.
+	if lowestHeight >= chain.BestSnapshot().Height {
+		return nil, false, nil
+	}
+	block, err := chain.BlockByHeight(lowestHeight + 1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var spentTxos []blockchain.SpentTxOut
+	if needsInputs {
+		spentTxos, err = chain.FetchSpendJournal(block)
+		if err != nil {
+			return nil, false, err
 		}
+	}
 
-		// Log indexing progress.
-		progressLogger.LogBlockHeight(block)
+	var connected bool
+	err = m.db.Update(func(dbTx database.Tx) error {
+		// The lock is only taken inside the transaction since the
+		// blocks are connected to the other indexes with the database
+		// locked for writes.
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
 
-		if interruptRequested(interrupt) {
-			for _, indexer := range m.enabledIndexes {
-				switch idxType := indexer.(type) {
-				case *UtreexoProofIndex:
-					err := idxType.FlushUtreexoState()
-					if err != nil {
-						log.Errorf("Error while flushing utreexo state: %v", err)
-					}
-				case *FlatUtreexoProofIndex:
-					err := idxType.FlushUtreexoState()
-					if err != nil {
-						log.Errorf("Error while flushing utreexo state for flat utreexo proof index: %v", err)
-					}
-				}
+		// The block may have been disconnected while it was fetched.
+		if m.disconnects != disconnects {
+			return nil
+		}
+
+		prevHash := &block.MsgBlock().Header.PrevBlock
+		for i, indexer := range m.enabledIndexes {
+			if !m.catchingUp[i] {
+				continue
 			}
-			return errInterruptRequested
+
+			hash, _, err := dbFetchIndexerTip(dbTx, indexer.Key())
+			if err != nil {
+				return err
+			}
+			if !hash.IsEqual(prevHash) {
+				continue
+			}
+
+			err = dbIndexConnectBlock(dbTx, indexer, block, spentTxos)
+			if err != nil {
+				return err
+			}
+			connected = true
+		}
+
+		// The lowest index has to be connected to the block unless the
+		// chain was reorganized.
+		if !connected {
+			return AssertError(fmt.Sprintf("no index caught up in the "+
+				"background extends to block %v", block.Hash()))
 		}
+		return nil
+	})
+	if err != nil || !connected {
+		return nil, false, err
+	}
+
+	return block, false, nil
+}
+
+// indexCatchesUpInBackground returns whether or not the index can be caught up
+// in the background.
+func indexCatchesUpInBackground(index Indexer) bool {
+	if idx, ok := index.(BackgroundCatchUpper); ok {
+		return idx.BackgroundCatchUp()
+	}
+
+	return false
+}
+
+// dependentIndex is implemented by the indexes that can only be connected to a
+// block once another index has been.
+type dependentIndex interface {
+	// dependency returns the index that has to be connected to the blocks
+	// first, if any.
+	dependency() Indexer
+}
+
+// indexDependency returns the index that has to be connected to the blocks
+// before the passed index, if any.
+func indexDependency(index Indexer) Indexer {
+	if idx, ok := index.(dependentIndex); ok {
+		return idx.dependency()
 	}
 
-	log.Infof("Indexes caught up to height %d", bestHeight)
 	return nil
 }
 
+// indexBlocksPrune returns whether or not the index keeps the blocks it has
+// indexed from being pruned.
+func indexBlocksPrune(index Indexer) bool {
+	if idx, ok := index.(PruneBlocker); ok {
+		return idx.BlocksPrune()
+	}
+
+	return false
+}
+
 // indexNeedsInputs returns whether or not the index needs access to the txouts
 // referenced by the transaction inputs being indexed.
 func indexNeedsInputs(index Indexer) bool {
@@ -588,14 +986,23 @@ func dbFetchTx(dbTx database.Tx, hash *chainhash.Hash) (*wire.MsgTx, error) {
 func (m *Manager) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
 	stxos []blockchain.SpentTxOut) error {
 
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
 	// Call each of the currently active optional indexes with the block
-	// being connected so they can update accordingly.
-	for _, index := range m.enabledIndexes {
+	// being connected so they can update accordingly.  The indexes that
+	// are still being caught up in the background get to the block later.
+	for i, index := range m.enabledIndexes {
+		if m.catchingUp[i] {
+			continue
+		}
+
 		err := dbIndexConnectBlock(dbTx, index, block, stxos)
 		if err != nil {
 			return err
 		}
 	}
+	m.tip = *block.Hash()
 	return nil
 }
 
@@ -608,17 +1015,65 @@ func (m *Manager) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
 func (m *Manager) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
 	stxo []blockchain.SpentTxOut) error {
 
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
 	// Call each of the currently active optional indexes with the block
-	// being disconnected so they can update accordingly.
-	for _, index := range m.enabledIndexes {
+	// being disconnected so they can update accordingly.  The indexes that
+	// are still being caught up in the background only need to be updated
+	// if they already got to the block.
+	for i, index := range m.enabledIndexes {
+		if m.catchingUp[i] {
+			tipHash, _, err := dbFetchIndexerTip(dbTx, index.Key())
+			if err != nil {
+				return err
+			}
+			if !tipHash.IsEqual(block.Hash()) {
+				continue
+			}
+		}
+
 		err := dbIndexDisconnectBlock(dbTx, index, block, stxo)
 		if err != nil {
 			return err
 		}
 	}
+	m.tip = block.MsgBlock().Header.PrevBlock
+	m.disconnects++
 	return nil
 }
 
+// KeepHeight returns the height of the earliest block that has to be kept on
+// disk for the enabled indexes.  -1 is returned when none of them need the
+// blocks to be kept.
+//
+// The indexes that implement the PruneBlocker interface need every block from
+// the earliest one they have indexed while all the other indexes prune their
+// entries along with the blocks.
+//
+// This is part of the blockchain.IndexManager interface.
+func (m *Manager) KeepHeight(dbTx database.Tx) (int32, error) {
+	keepHeight := int32(-1)
+	for _, index := range m.enabledIndexes {
+		if !indexBlocksPrune(index) {
+			continue
+		}
+
+		_, height, err := dbFetchIndexerEarliest(dbTx, index.Key())
+		if err != nil {
+			return 0, err
+		}
+		if height < 0 {
+			height = 0
+		}
+		if keepHeight == -1 || height < keepHeight {
+			keepHeight = height
+		}
+	}
+
+	return keepHeight, nil
+}
+
 // PruneBlock is invoked when an older block is deleted after it's been
 // processed.
 //
@@ -627,8 +1082,14 @@ func (m *Manager) PruneBlocks(dbTx database.Tx, lastKeptHeight int32,
 	fetchHashFunc func(blockHeight int32) (*chainhash.Hash, error)) error {
 
 	// Call each of the currently active optional indexes with the block
-	// being disconnected so they can update accordingly.
+	// being disconnected so they can update accordingly.  The indexes that
+	// block pruning are skipped as none of the blocks they've indexed get
+	// pruned.
 	for _, index := range m.enabledIndexes {
+		if indexBlocksPrune(index) {
+			continue
+		}
+
 		idxKey := index.Key()
 		_, height, err := dbFetchIndexerEarliest(dbTx, idxKey)
 		if err != nil {
@@ -667,6 +1128,54 @@ func (m *Manager) PruneBlocks(dbTx database.Tx, lastKeptHeight int32,
 	return nil
 }
 
+// UtreexoStats returns the statistics of the accumulator kept by the first
+// enabled index that keeps one.
+//
+// This is part of the blockchain.UtreexoStatser interface.
+func (m *Manager) UtreexoStats() (blockchain.UtreexoStats, bool) {
+	for _, index := range m.enabledIndexes {
+		if statser, ok := index.(blockchain.UtreexoStatser); ok {
+			return statser.UtreexoStats()
+		}
+	}
+
+	return blockchain.UtreexoStats{}, false
+}
+
+// FetchUtreexoRoots returns the roots and number of leaves of the accumulator
+// kept by the first enabled index that keeps one after the block at the given
+// height was connected.
+//
+// This is part of the blockchain.UtreexoRootsFetcher interface.
+func (m *Manager) FetchUtreexoRoots(height int32) ([]*chainhash.Hash, uint64, bool, error) {
+	for _, index := range m.enabledIndexes {
+		if fetcher, ok := index.(utreexoRootsFetcher); ok {
+			roots, numLeaves, err := fetcher.fetchUtreexoRoots(height)
+			return roots, numLeaves, true, err
+		}
+	}
+
+	return nil, 0, false, nil
+}
+
+// UtreexoRootsAfter returns the roots and number of leaves the accumulator kept
+// by the first enabled index that keeps one will have once the block is
+// connected.  The accumulator isn't modified.
+//
+// This is part of the blockchain.UtreexoRootsFetcher interface.
+func (m *Manager) UtreexoRootsAfter(block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) ([]*chainhash.Hash, uint64, bool, error) {
+
+	for _, index := range m.enabledIndexes {
+		if fetcher, ok := index.(utreexoRootsFetcher); ok {
+			roots, numLeaves, err := fetcher.utreexoRootsAfter(block, stxos)
+			return roots, numLeaves, true, err
+		}
+	}
+
+	return nil, 0, false, nil
+}
+
 // NewManager returns a new index manager with the provided indexes enabled.
 //
 // The manager returned satisfies the blockchain.IndexManager interface and thus
@@ -675,6 +1184,7 @@ func NewManager(db database.DB, enabledIndexes []Indexer) *Manager {
 	return &Manager{
 		db:             db,
 		enabledIndexes: enabledIndexes,
+		catchingUp:     make([]bool, len(enabledIndexes)),
 	}
 }
 
@@ -811,14 +1321,17 @@ func dropIndex(db database.DB, idxKey []byte, idxName string, interrupt <-chan s
 		}
 	}
 
-	// Remove the index tip, index bucket, and in-progress drop flag now
-	// that all index entries have been removed.
+	// Remove the index tip, index version, index bucket, and in-progress
+	// drop flag now that all index entries have been removed.
 	err = db.Update(func(dbTx database.Tx) error {
 		meta := dbTx.Metadata()
 		indexesBucket := meta.Bucket(indexTipsBucketName)
 		if err := indexesBucket.Delete(idxKey); err != nil {
 			return err
 		}
+		if err := meta.Delete(indexVersionKey(idxKey)); err != nil {
+			return err
+		}
 
 		return indexesBucket.Delete(indexDropKey(idxKey))
 	})
@@ -0,0 +1,456 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"fmt"
+	"hash"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/txscript"
+)
+
+const (
+	// scriptBalanceIndexName is the human-readable name for the index.
+	scriptBalanceIndexName = "script balance index"
+)
+
+var (
+	// scriptBalanceIndexKey is the key of the script balance index and the
+	// db bucket used to house it.  The rest of the buckets live below this
+	// bucket.
+	scriptBalanceIndexKey = []byte("scriptbalanceidx")
+
+	// scriptBalanceBucketName is the name of the db bucket used to house
+	// the balances and histories of the scripts.
+	scriptBalanceBucketName = []byte("scriptbalances")
+
+	// scriptBalanceUndoBucketName is the name of the db bucket used to
+	// house the entries the blocks replaced so they can be disconnected.
+	scriptBalanceUndoBucketName = []byte("scriptbalanceundo")
+
+	// historyStateSize is the size of the serialized sha256 state that
+	// the history digests are kept in.
+	historyStateSize = func() int {
+		state, err := sha256.New().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			panic(err)
+		}
+		return len(state)
+	}()
+
+	// scriptBalanceEntrySize is the size of a script balance entry.  It
+	// consists of the 8 bytes balance + 4 bytes transaction count + the
+	// serialized sha256 state of the history.
+	scriptBalanceEntrySize = 8 + 4 + historyStateSize
+
+	// scriptBalanceUndoSize is the size of an undo record of the script
+	// balance index.  It consists of the 32 bytes script hash + the entry
+	// the block replaced.
+	scriptBalanceUndoSize = chainhash.HashSize + scriptBalanceEntrySize
+)
+
+// -----------------------------------------------------------------------------
+// The script balance index keeps the confirmed balance and a digest of the
+// confirmed history of every script in the main chain, so that an Electrum
+// compatible frontend can answer balance requests and status subscriptions
+// without scanning the history of the script.
+//
+// The scripts are keyed by their Electrum script hash, which is the sha256 of
+// the script.  The Electrum status of a script is the sha256 of the
+// concatenation of "<txid>:<height>:" for every transaction involving it in
+// the order of the chain.  As the history is only ever appended to, the sha256
+// state is kept instead of the status so that it can be extended with the
+// transactions of every new block.
+//
+// The serialized key format is:
+//
+//   <script hash>
+//
+//   Field           Type              Size
+//   script hash     chainhash.Hash    32 bytes
+//
+// The serialized value format is:
+//
+//   <balance><tx count><history state>
+//
+//   Field           Type              Size
+//   balance         int64             8 bytes
+//   tx count        uint32            4 bytes
+//   history state   sha256 state      108 bytes
+//   -----
+//   Total: 120 bytes
+//
+// Every block stores the entries it replaced in the undo bucket under its
+// hash so that they can be restored when it's disconnected:
+//
+//   <script hash><entry>...
+//
+//   Field           Type              Size
+//   script hash     chainhash.Hash    32 bytes
+//   entry           value format      120 bytes
+//
+// An entry of all zeros is stored for the scripts that weren't indexed before
+// the block.
+// -----------------------------------------------------------------------------
+
+// ScriptHash returns the Electrum script hash of the passed script, which is
+// the key it's indexed under.
+func ScriptHash(pkScript []byte) chainhash.Hash {
+	return chainhash.Hash(sha256.Sum256(pkScript))
+}
+
+// ScriptBalance describes the confirmed balance and history of a script.
+type ScriptBalance struct {
+	// Balance is the sum of the values of the unspent outputs paying to
+	// the script in satoshis.
+	Balance int64
+
+	// TxCount is the number of transactions in the main chain that involve
+	// the script.
+	TxCount uint32
+
+	// Status is the Electrum status of the confirmed history of the
+	// script.
+	Status [sha256.Size]byte
+}
+
+// scriptBalanceEntry is the deserialized form of a script balance index
+// entry.
+type scriptBalanceEntry struct {
+	balance int64
+	txCount uint32
+	history hash.Hash
+}
+
+// serializeScriptBalanceEntry serializes the passed entry into the passed
+// slice of scriptBalanceEntrySize bytes.
+func serializeScriptBalanceEntry(target []byte, entry *scriptBalanceEntry) error {
+	state, err := entry.history.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return err
+	}
+	byteOrder.PutUint64(target, uint64(entry.balance))
+	byteOrder.PutUint32(target[8:], entry.txCount)
+	copy(target[12:], state)
+	return nil
+}
+
+// deserializeScriptBalanceEntry deserializes the passed entry.  A fresh entry
+// is returned for a nil value.
+func deserializeScriptBalanceEntry(serialized []byte) (*scriptBalanceEntry, error) {
+	entry := &scriptBalanceEntry{history: sha256.New()}
+	if serialized == nil {
+		return entry, nil
+	}
+
+	if len(serialized) < scriptBalanceEntrySize {
+		return nil, errDeserialize("unexpected end of data")
+	}
+	entry.balance = int64(byteOrder.Uint64(serialized))
+	entry.txCount = byteOrder.Uint32(serialized[8:])
+	unmarshaler := entry.history.(encoding.BinaryUnmarshaler)
+	err := unmarshaler.UnmarshalBinary(serialized[12:scriptBalanceEntrySize])
+	if err != nil {
+		return nil, errDeserialize(err.Error())
+	}
+	return entry, nil
+}
+
+// dbFetchScriptBalanceEntry returns the entry of the script with the passed
+// script hash.  Nil is returned for both the entry and the error when the
+// script isn't indexed.
+func dbFetchScriptBalanceEntry(bucket internalBucket, scriptHash *chainhash.Hash) (*scriptBalanceEntry, error) {
+	serialized := bucket.Get(scriptHash[:])
+	if serialized == nil {
+		return nil, nil
+	}
+
+	entry, err := deserializeScriptBalanceEntry(serialized)
+	if err != nil {
+		return nil, database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: fmt.Sprintf("corrupt script balance index "+
+				"entry for %v: %v", scriptHash, err),
+		}
+	}
+	return entry, nil
+}
+
+// ScriptBalanceIndex implements an index of the confirmed balances and
+// histories of all the scripts in the main chain.
+type ScriptBalanceIndex struct {
+	db database.DB
+}
+
+// Ensure the ScriptBalanceIndex type implements the Indexer interface.
+var _ Indexer = (*ScriptBalanceIndex)(nil)
+
+// Ensure the ScriptBalanceIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*ScriptBalanceIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *ScriptBalanceIndex) NeedsInputs() bool {
+	return true
+}
+
+// Ensure the ScriptBalanceIndex type implements the BackgroundCatchUpper
+// interface.
+var _ BackgroundCatchUpper = (*ScriptBalanceIndex)(nil)
+
+// BackgroundCatchUp signals that the index can be caught up in the background
+// since no other part of the node depends on it.
+//
+// This implements the BackgroundCatchUpper interface.
+func (idx *ScriptBalanceIndex) BackgroundCatchUp() bool {
+	return true
+}
+
+// Init initializes the script balance index.
+//
+// This is part of the Indexer interface.
+func (idx *ScriptBalanceIndex) Init(_ *blockchain.BlockChain) error {
+	return nil // Nothing to do.
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *ScriptBalanceIndex) Key() []byte {
+	return scriptBalanceIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *ScriptBalanceIndex) Name() string {
+	return scriptBalanceIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the buckets for the script
+// balances and the undo data of the blocks.
+//
+// This is part of the Indexer interface.
+func (idx *ScriptBalanceIndex) Create(dbTx database.Tx) error {
+	bucket, err := dbTx.Metadata().CreateBucket(scriptBalanceIndexKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = bucket.CreateBucket(scriptBalanceBucketName)
+	if err != nil {
+		return err
+	}
+
+	_, err = bucket.CreateBucket(scriptBalanceUndoBucketName)
+	return err
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer updates the balance of every
+// script the block pays to or spends from and adds the transactions involving
+// them to their histories.
+//
+// This is part of the Indexer interface.
+func (idx *ScriptBalanceIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	bucket := dbTx.Metadata().Bucket(scriptBalanceIndexKey)
+	balances := bucket.Bucket(scriptBalanceBucketName)
+
+	// Load the entries of the scripts as they're first involved in the
+	// block while keeping the ones they had before for the undo data.
+	entries := make(map[chainhash.Hash]*scriptBalanceEntry)
+	var scriptHashes []chainhash.Hash
+	var undo []byte
+	fetchEntry := func(scriptHash chainhash.Hash) (*scriptBalanceEntry, error) {
+		if entry, ok := entries[scriptHash]; ok {
+			return entry, nil
+		}
+
+		serialized := balances.Get(scriptHash[:])
+		record := make([]byte, scriptBalanceUndoSize)
+		copy(record, scriptHash[:])
+		copy(record[chainhash.HashSize:], serialized)
+		undo = append(undo, record...)
+
+		entry, err := deserializeScriptBalanceEntry(serialized)
+		if err != nil {
+			return nil, database.Error{
+				ErrorCode: database.ErrCorruption,
+				Description: fmt.Sprintf("corrupt script balance "+
+					"index entry for %v: %v", scriptHash, err),
+			}
+		}
+		entries[scriptHash] = entry
+		scriptHashes = append(scriptHashes, scriptHash)
+		return entry, nil
+	}
+
+	var stxoIndex int
+	for txIdx, tx := range block.Transactions() {
+		involved := make(map[chainhash.Hash]*scriptBalanceEntry)
+
+		// Coinbases do not reference any inputs.
+		if txIdx != 0 {
+			for range tx.MsgTx().TxIn {
+				stxo := &stxos[stxoIndex]
+				stxoIndex++
+
+				scriptHash := ScriptHash(stxo.PkScript)
+				entry, err := fetchEntry(scriptHash)
+				if err != nil {
+					return err
+				}
+				entry.balance -= stxo.Amount
+				involved[scriptHash] = entry
+			}
+		}
+
+		// Unspendable outputs can never be spent so they aren't indexed.
+		for _, txOut := range tx.MsgTx().TxOut {
+			if txscript.IsUnspendable(txOut.PkScript) {
+				continue
+			}
+
+			scriptHash := ScriptHash(txOut.PkScript)
+			entry, err := fetchEntry(scriptHash)
+			if err != nil {
+				return err
+			}
+			entry.balance += txOut.Value
+			involved[scriptHash] = entry
+		}
+
+		// Every transaction is in the history of a script once.
+		historyItem := fmt.Sprintf("%v:%d:", tx.Hash(), block.Height())
+		for _, entry := range involved {
+			entry.history.Write([]byte(historyItem))
+			entry.txCount++
+		}
+	}
+
+	// As an optimization, serialize the entries into a single slice since
+	// the database contract prohibits modifying them.
+	serialized := make([]byte, len(scriptHashes)*scriptBalanceEntrySize)
+	for i, scriptHash := range scriptHashes {
+		value := serialized[i*scriptBalanceEntrySize : (i+1)*scriptBalanceEntrySize]
+		err := serializeScriptBalanceEntry(value, entries[scriptHash])
+		if err != nil {
+			return err
+		}
+		if err := balances.Put(scriptHashes[i][:], value); err != nil {
+			return err
+		}
+	}
+
+	undoBucket := bucket.Bucket(scriptBalanceUndoBucketName)
+	return undoBucket.Put(block.Hash()[:], undo)
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer restores the entries of the
+// scripts the block involves to the ones they had before it.
+//
+// This is part of the Indexer interface.
+func (idx *ScriptBalanceIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	bucket := dbTx.Metadata().Bucket(scriptBalanceIndexKey)
+	balances := bucket.Bucket(scriptBalanceBucketName)
+	undoBucket := bucket.Bucket(scriptBalanceUndoBucketName)
+
+	undo := undoBucket.Get(block.Hash()[:])
+	if undo == nil || len(undo)%scriptBalanceUndoSize != 0 {
+		return database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: fmt.Sprintf("missing or corrupt script "+
+				"balance index undo data for block %v", block.Hash()),
+		}
+	}
+
+	for offset := 0; offset < len(undo); offset += scriptBalanceUndoSize {
+		record := undo[offset : offset+scriptBalanceUndoSize]
+		scriptHash := record[:chainhash.HashSize]
+		value := record[chainhash.HashSize:]
+
+		// The scripts that weren't indexed before the block didn't have
+		// any transactions.
+		var err error
+		if byteOrder.Uint32(value[8:]) == 0 {
+			err = balances.Delete(scriptHash)
+		} else {
+			err = balances.Put(scriptHash, value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return undoBucket.Delete(block.Hash()[:])
+}
+
+// PruneBlock is invoked when an older block is deleted after it's been
+// processed.
+// NOTE: For ScriptBalanceIndex, it's a no-op as ScriptBalanceIndex isn't
+// allowed to be enabled with pruning since it can't be caught up without the
+// blocks.
+//
+// This is part of the Indexer interface.
+func (idx *ScriptBalanceIndex) PruneBlock(dbTx database.Tx, blockHash *chainhash.Hash) error {
+	return nil
+}
+
+// FetchScriptBalance returns the confirmed balance and history status of the
+// script with the passed Electrum script hash.  When the script isn't involved
+// in any transaction of the main chain, nil is returned for both the balance
+// and the error.
+//
+// This function is safe for concurrent access.
+func (idx *ScriptBalanceIndex) FetchScriptBalance(scriptHash *chainhash.Hash) (*ScriptBalance, error) {
+	var balance *ScriptBalance
+	err := idx.db.View(func(dbTx database.Tx) error {
+		balances := dbTx.Metadata().Bucket(scriptBalanceIndexKey).
+			Bucket(scriptBalanceBucketName)
+		entry, err := dbFetchScriptBalanceEntry(balances, scriptHash)
+		if err != nil || entry == nil {
+			return err
+		}
+
+		balance = &ScriptBalance{
+			Balance: entry.balance,
+			TxCount: entry.txCount,
+		}
+		copy(balance.Status[:], entry.history.Sum(nil))
+		return nil
+	})
+	return balance, err
+}
+
+// NewScriptBalanceIndex returns a new instance of an indexer that is used to
+// keep the confirmed balances and history digests of all the scripts in the
+// main chain.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewScriptBalanceIndex(db database.DB) *ScriptBalanceIndex {
+	return &ScriptBalanceIndex{db: db}
+}
+
+// DropScriptBalanceIndex drops the script balance index from the provided
+// database if it exists.
+func DropScriptBalanceIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, scriptBalanceIndexKey, scriptBalanceIndexName, interrupt)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestScriptBalanceIndex ensures that the script balance index keeps the
+// confirmed balances and Electrum statuses of the scripts of the connected
+// blocks and restores them when the blocks are disconnected.
+func TestScriptBalanceIndex(t *testing.T) {
+	db, dbPath, err := createDB("scriptbalanceindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewScriptBalanceIndex(db)
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.Create(dbTx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scriptA := []byte{txscript.OP_TRUE}
+	scriptB := []byte{txscript.OP_2}
+	newCoinbase := func(extraNonce byte) *wire.MsgTx {
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex},
+			[]byte{extraNonce}, nil))
+		tx.AddTxOut(wire.NewTxOut(5000, scriptA))
+		tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+		return tx
+	}
+
+	// The first block pays to script A while the second one spends it to
+	// script B with the change going back to script A.
+	coinbase1 := newCoinbase(1)
+	block1 := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{coinbase1},
+	})
+	block1.SetHeight(1)
+
+	coinbase2 := newCoinbase(2)
+	spend := wire.NewMsgTx(1)
+	spend.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: coinbase1.TxHash()}, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(3000, scriptB))
+	spend.AddTxOut(wire.NewTxOut(1900, scriptA))
+	block2 := btcutil.NewBlock(&wire.MsgBlock{
+		Header:       wire.BlockHeader{PrevBlock: *block1.Hash()},
+		Transactions: []*wire.MsgTx{coinbase2, spend},
+	})
+	block2.SetHeight(2)
+	stxos := []blockchain.SpentTxOut{{Amount: 5000, PkScript: scriptA, Height: 1}}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		err := idx.ConnectBlock(dbTx, block1, nil)
+		if err != nil {
+			return err
+		}
+		return idx.ConnectBlock(dbTx, block2, stxos)
+	})
+	if err != nil {
+		t.Fatalf("ConnectBlock: unexpected error: %v", err)
+	}
+
+	status := func(history string) [sha256.Size]byte {
+		return sha256.Sum256([]byte(history))
+	}
+	historyA := fmt.Sprintf("%v:1:%v:2:%v:2:", coinbase1.TxHash(),
+		coinbase2.TxHash(), spend.TxHash())
+	historyB := fmt.Sprintf("%v:2:", spend.TxHash())
+	tests := []struct {
+		script []byte
+		want   *ScriptBalance
+	}{
+		{scriptA, &ScriptBalance{Balance: 6900, TxCount: 3, Status: status(historyA)}},
+		{scriptB, &ScriptBalance{Balance: 3000, TxCount: 1, Status: status(historyB)}},
+		{[]byte{txscript.OP_RETURN}, nil},
+	}
+	checkBalances := func() {
+		t.Helper()
+		for _, test := range tests {
+			scriptHash := ScriptHash(test.script)
+			balance, err := idx.FetchScriptBalance(&scriptHash)
+			if err != nil {
+				t.Fatalf("FetchScriptBalance(%x): unexpected error: %v",
+					test.script, err)
+			}
+			if (balance == nil) != (test.want == nil) ||
+				(balance != nil && *balance != *test.want) {
+
+				t.Fatalf("FetchScriptBalance(%x): got %v, want %v",
+					test.script, balance, test.want)
+			}
+		}
+	}
+	checkBalances()
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.DisconnectBlock(dbTx, block2, stxos)
+	})
+	if err != nil {
+		t.Fatalf("DisconnectBlock: unexpected error: %v", err)
+	}
+	tests[0].want = &ScriptBalance{
+		Balance: 5000,
+		TxCount: 1,
+		Status:  status(fmt.Sprintf("%v:1:", coinbase1.TxHash())),
+	}
+	tests[1].want = nil
+	checkBalances()
+
+	// The script hashes are displayed the way Electrum does.  This is the
+	// example of the Electrum protocol documentation.
+	pkScript, err := hex.DecodeString("76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161"
+	if scriptHash := ScriptHash(pkScript); scriptHash.String() != want {
+		t.Fatalf("ScriptHash: got %v, want %v", scriptHash, want)
+	}
+}
@@ -0,0 +1,468 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// silentPaymentIndexName is the human-readable name for the index.
+	silentPaymentIndexName = "silent payment index"
+
+	// silentPaymentEntrySize is the size of a tweak entry of a block.  It
+	// consists of the 32 bytes hash of the transaction + 33 bytes
+	// compressed tweak.
+	silentPaymentEntrySize = chainhash.HashSize + btcec.PubKeyBytesLenCompressed
+)
+
+var (
+	// silentPaymentIndexKey is the key of the silent payment index and the
+	// db bucket used to house it.
+	silentPaymentIndexKey = []byte("silentpaymenttweakbyhashidx")
+
+	// silentPaymentInputsTag is the tag of the hash that commits to the
+	// inputs of a transaction as defined in BIP-352.
+	silentPaymentInputsTag = []byte("BIP0352/Inputs")
+
+	// taprootNUMSKey is the x coordinate of the point with an unknown
+	// discrete logarithm that BIP-341 suggests as the internal key of
+	// taproot outputs without a key path.  Inputs that spend such outputs
+	// through the script path aren't eligible for silent payments.
+	taprootNUMSKey, _ = hex.DecodeString(
+		"50929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0",
+	)
+)
+
+// -----------------------------------------------------------------------------
+// The silent payment index maps every block to the BIP-352 tweaks of the
+// transactions in it that may contain silent payments.  A light wallet fetches
+// the tweaks of a block and only has to derive its outputs from them instead
+// of fetching and scanning every transaction along with its prevouts.
+//
+// The tweak of a transaction is input_hash*A, where A is the sum of the public
+// keys of its eligible inputs and input_hash commits to the smallest outpoint
+// it spends and A.  A wallet with the scan key b_scan computes the shared
+// secret as b_scan*tweak.
+//
+// The serialized key format is:
+//
+//   <block hash>
+//
+//   Field           Type              Size
+//   block hash      chainhash.Hash    32 bytes
+//
+// The serialized value format is:
+//
+//   <num entries>[<txid><tweak>,...]
+//
+//   Field           Type              Size
+//   num entries     VARINT            variable
+//   txid            chainhash.Hash    32 bytes
+//   tweak           compressed point  33 bytes
+// -----------------------------------------------------------------------------
+
+// SilentPaymentTweak is the BIP-352 tweak of a transaction.
+type SilentPaymentTweak struct {
+	// TxHash is the hash of the transaction.
+	TxHash chainhash.Hash
+
+	// Tweak is the compressed serialization of the tweak.
+	Tweak [btcec.PubKeyBytesLenCompressed]byte
+}
+
+// silentPaymentInputKey returns the public key that the passed input of a
+// transaction contributes to its silent payment tweak, or nil when the input
+// isn't eligible.
+func silentPaymentInputKey(txIn *wire.TxIn, pkScript []byte) *btcec.PublicKey {
+	witness := txIn.Witness
+	switch {
+	case txscript.IsPayToPubKeyHash(pkScript):
+		// The signature script may be malleated, so look for the last
+		// compressed public key that matches the hash.
+		sigScript := txIn.SignatureScript
+		for i := len(sigScript); i >= btcec.PubKeyBytesLenCompressed; i-- {
+			pubKey := sigScript[i-btcec.PubKeyBytesLenCompressed : i]
+			if !bytes.Equal(btcutil.Hash160(pubKey), pkScript[3:23]) {
+				continue
+			}
+			key, err := btcec.ParsePubKey(pubKey)
+			if err != nil {
+				return nil
+			}
+			return key
+		}
+		return nil
+
+	case txscript.IsPayToScriptHash(pkScript):
+		// Only nested pay-to-witness-pubkey-hash inputs are eligible.
+		sigScript := txIn.SignatureScript
+		if len(sigScript) != 23 || sigScript[0] != txscript.OP_DATA_22 ||
+			!txscript.IsPayToWitnessPubKeyHash(sigScript[1:]) {
+
+			return nil
+		}
+		fallthrough
+
+	case txscript.IsPayToWitnessPubKeyHash(pkScript):
+		if len(witness) == 0 {
+			return nil
+		}
+		pubKey := witness[len(witness)-1]
+		if len(pubKey) != btcec.PubKeyBytesLenCompressed {
+			return nil
+		}
+		key, err := btcec.ParsePubKey(pubKey)
+		if err != nil {
+			return nil
+		}
+		return key
+
+	case txscript.IsPayToTaproot(pkScript):
+		// Strip the annex before looking at the control block.
+		if len(witness) > 1 {
+			last := witness[len(witness)-1]
+			if len(last) > 0 && last[0] == txscript.TaprootAnnexTag {
+				witness = witness[:len(witness)-1]
+			}
+		}
+		if len(witness) > 1 {
+			ctrlBlock := witness[len(witness)-1]
+			if len(ctrlBlock) >= txscript.ControlBlockBaseSize &&
+				bytes.Equal(ctrlBlock[1:33], taprootNUMSKey) {
+
+				return nil
+			}
+		}
+		key, err := schnorr.ParsePubKey(pkScript[2:34])
+		if err != nil {
+			return nil
+		}
+		return key
+	}
+
+	return nil
+}
+
+// computeSilentPaymentTweak returns the compressed BIP-352 tweak of the passed
+// transaction that spends the passed previous outputs.  False is returned when
+// the transaction can't contain silent payments.
+func computeSilentPaymentTweak(tx *wire.MsgTx,
+	prevOuts []*wire.TxOut) ([btcec.PubKeyBytesLenCompressed]byte, bool, error) {
+
+	var tweak [btcec.PubKeyBytesLenCompressed]byte
+
+	// Silent payments are always taproot outputs.
+	var hasTaprootOutput bool
+	for _, txOut := range tx.TxOut {
+		if txscript.IsPayToTaproot(txOut.PkScript) {
+			hasTaprootOutput = true
+			break
+		}
+	}
+	if !hasTaprootOutput {
+		return tweak, false, nil
+	}
+
+	var (
+		sum         btcec.JacobianPoint
+		numKeys     int
+		smallestOp  [chainhash.HashSize + 4]byte
+		serializeOp [chainhash.HashSize + 4]byte
+	)
+	for i, txIn := range tx.TxIn {
+		pkScript := prevOuts[i].PkScript
+
+		// Transactions that spend outputs of future witness versions
+		// are left for upgrades of the protocol.
+		if txscript.IsWitnessProgram(pkScript) {
+			version, _, err := txscript.ExtractWitnessProgramInfo(pkScript)
+			if err != nil {
+				return tweak, false, err
+			}
+			if version > 1 {
+				return tweak, false, nil
+			}
+		}
+
+		copy(serializeOp[:], txIn.PreviousOutPoint.Hash[:])
+		byteOrder.PutUint32(serializeOp[chainhash.HashSize:],
+			txIn.PreviousOutPoint.Index)
+		if i == 0 || bytes.Compare(serializeOp[:], smallestOp[:]) < 0 {
+			smallestOp = serializeOp
+		}
+
+		key := silentPaymentInputKey(txIn, pkScript)
+		if key == nil {
+			continue
+		}
+		var point, next btcec.JacobianPoint
+		key.AsJacobian(&point)
+		btcec.AddNonConst(&sum, &point, &next)
+		sum = next
+		numKeys++
+	}
+	if numKeys == 0 {
+		return tweak, false, nil
+	}
+
+	// The keys may cancel each other out, which leaves no shared secret to
+	// derive.
+	if (sum.X.IsZero() && sum.Y.IsZero()) || sum.Z.IsZero() {
+		return tweak, false, nil
+	}
+	sum.ToAffine()
+	sumKey := btcec.NewPublicKey(&sum.X, &sum.Y).SerializeCompressed()
+
+	inputHash := chainhash.TaggedHash(
+		silentPaymentInputsTag, smallestOp[:], sumKey,
+	)
+	var scalar btcec.ModNScalar
+	if overflow := scalar.SetByteSlice(inputHash[:]); overflow {
+		return tweak, false, nil
+	}
+
+	var result btcec.JacobianPoint
+	btcec.ScalarMultNonConst(&scalar, &sum, &result)
+	result.ToAffine()
+	copy(tweak[:], btcec.NewPublicKey(&result.X, &result.Y).SerializeCompressed())
+	return tweak, true, nil
+}
+
+// BlockSilentPaymentTweaks returns the silent payment tweaks of the eligible
+// transactions of the passed block, computed from the outputs it spends in the
+// order of its spend journal.  It allows wallets to scan the blocks of nodes
+// that don't run the silent payment index.
+func BlockSilentPaymentTweaks(block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) ([]SilentPaymentTweak, error) {
+
+	var (
+		tweaks     []SilentPaymentTweak
+		stxoIndex  int
+		prevOuts   []*wire.TxOut
+		totalStxos = len(stxos)
+	)
+	for _, tx := range block.Transactions()[1:] {
+		msgTx := tx.MsgTx()
+		if stxoIndex+len(msgTx.TxIn) > totalStxos {
+			return nil, fmt.Errorf("missing spent outputs for block %v",
+				block.Hash())
+		}
+
+		prevOuts = prevOuts[:0]
+		for i := range msgTx.TxIn {
+			stxo := &stxos[stxoIndex+i]
+			prevOuts = append(prevOuts, wire.NewTxOut(stxo.Amount,
+				stxo.PkScript))
+		}
+		stxoIndex += len(msgTx.TxIn)
+
+		tweak, ok, err := computeSilentPaymentTweak(msgTx, prevOuts)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		tweaks = append(tweaks, SilentPaymentTweak{
+			TxHash: *tx.Hash(),
+			Tweak:  tweak,
+		})
+	}
+	return tweaks, nil
+}
+
+// serializeSilentPaymentTweaks returns the tweaks of the eligible transactions
+// of the passed block serialized according to the format described in detail
+// above.
+func serializeSilentPaymentTweaks(block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) ([]byte, error) {
+
+	tweaks, err := BlockSilentPaymentTweaks(block, stxos)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(wire.VarIntSerializeSize(uint64(len(tweaks))) +
+		len(tweaks)*silentPaymentEntrySize)
+	err = wire.WriteVarInt(&buf, 0, uint64(len(tweaks)))
+	if err != nil {
+		return nil, err
+	}
+	for _, tweak := range tweaks {
+		buf.Write(tweak.TxHash[:])
+		buf.Write(tweak.Tweak[:])
+	}
+	return buf.Bytes(), nil
+}
+
+// deserializeSilentPaymentTweaks decodes the passed tweaks of a block that were
+// serialized according to the format described in detail above.
+func deserializeSilentPaymentTweaks(serialized []byte) ([]SilentPaymentTweak, error) {
+	r := bytes.NewReader(serialized)
+	numEntries, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(r.Len()) != numEntries*silentPaymentEntrySize {
+		return nil, fmt.Errorf("unexpected size of %d bytes for %d "+
+			"entries", r.Len(), numEntries)
+	}
+
+	tweaks := make([]SilentPaymentTweak, numEntries)
+	entries := serialized[len(serialized)-r.Len():]
+	for i := range tweaks {
+		entry := entries[i*silentPaymentEntrySize:]
+		copy(tweaks[i].TxHash[:], entry[:chainhash.HashSize])
+		copy(tweaks[i].Tweak[:], entry[chainhash.HashSize:silentPaymentEntrySize])
+	}
+	return tweaks, nil
+}
+
+// SilentPaymentIndex implements an index of the BIP-352 silent payment tweaks
+// of the transactions in every block.
+type SilentPaymentIndex struct {
+	db database.DB
+}
+
+// Ensure the SilentPaymentIndex type implements the Indexer interface.
+var _ Indexer = (*SilentPaymentIndex)(nil)
+
+// Ensure the SilentPaymentIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*SilentPaymentIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *SilentPaymentIndex) NeedsInputs() bool {
+	return true
+}
+
+// Init initializes the silent payment index.
+//
+// This is part of the Indexer interface.
+func (idx *SilentPaymentIndex) Init(_ *blockchain.BlockChain) error {
+	return nil // Nothing to do.
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *SilentPaymentIndex) Key() []byte {
+	return silentPaymentIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *SilentPaymentIndex) Name() string {
+	return silentPaymentIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the bucket for the silent
+// payment index.
+//
+// This is part of the Indexer interface.
+func (idx *SilentPaymentIndex) Create(dbTx database.Tx) error {
+	_, err := dbTx.Metadata().CreateBucket(silentPaymentIndexKey)
+	return err
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer stores the tweaks of the eligible
+// transactions in the block.
+//
+// This is part of the Indexer interface.
+func (idx *SilentPaymentIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	serialized, err := serializeSilentPaymentTweaks(block, stxos)
+	if err != nil {
+		return err
+	}
+	bucket := dbTx.Metadata().Bucket(silentPaymentIndexKey)
+	return bucket.Put(block.Hash()[:], serialized)
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the tweaks of the
+// block.
+//
+// This is part of the Indexer interface.
+func (idx *SilentPaymentIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	bucket := dbTx.Metadata().Bucket(silentPaymentIndexKey)
+	return bucket.Delete(block.Hash()[:])
+}
+
+// PruneBlock is invoked when an older block is deleted after it's been
+// processed.
+// NOTE: For SilentPaymentIndex, it's a no-op as SilentPaymentIndex isn't allowed
+// to be enabled with pruning since it can't be caught up without the blocks.
+//
+// This is part of the Indexer interface.
+func (idx *SilentPaymentIndex) PruneBlock(dbTx database.Tx, blockHash *chainhash.Hash) error {
+	return nil
+}
+
+// FetchBlockTweaks returns the silent payment tweaks of the eligible
+// transactions in the block with the passed hash.  Nil is returned for both
+// the tweaks and the error when the block isn't indexed.
+//
+// This function is safe for concurrent access.
+func (idx *SilentPaymentIndex) FetchBlockTweaks(blockHash *chainhash.Hash) ([]SilentPaymentTweak, error) {
+	var tweaks []SilentPaymentTweak
+	err := idx.db.View(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(silentPaymentIndexKey)
+		serialized := bucket.Get(blockHash[:])
+		if serialized == nil {
+			return nil
+		}
+
+		var err error
+		tweaks, err = deserializeSilentPaymentTweaks(serialized)
+		if err != nil {
+			return database.Error{
+				ErrorCode: database.ErrCorruption,
+				Description: fmt.Sprintf("corrupt silent payment "+
+					"index entry for %v: %v", blockHash, err),
+			}
+		}
+		return nil
+	})
+	return tweaks, err
+}
+
+// NewSilentPaymentIndex returns a new instance of an indexer that is used to
+// create a mapping of the blocks in the main chain to the BIP-352 silent
+// payment tweaks of their transactions.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewSilentPaymentIndex(db database.DB) *SilentPaymentIndex {
+	return &SilentPaymentIndex{db: db}
+}
+
+// DropSilentPaymentIndex drops the silent payment index from the provided
+// database if it exists.
+func DropSilentPaymentIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, silentPaymentIndexKey, silentPaymentIndexName, interrupt)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestSilentPaymentIndex ensures that the silent payment index computes the
+// BIP-352 tweaks of the eligible transactions of a block from the keys of their
+// eligible inputs and forgets them once the block is disconnected.
+func TestSilentPaymentIndex(t *testing.T) {
+	db, dbPath, err := createDB("silentpaymentindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewSilentPaymentIndex(db)
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.Create(dbTx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var privKeys []*btcec.PrivateKey
+	for i := byte(1); i <= 3; i++ {
+		privKey, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{i}, 32))
+		privKeys = append(privKeys, privKey)
+	}
+	pubKey := func(i int) []byte {
+		return privKeys[i].PubKey().SerializeCompressed()
+	}
+	sig := bytes.Repeat([]byte{0x30}, 71)
+
+	p2pkh := append([]byte{0x76, 0xa9, 0x14},
+		append(btcutil.Hash160(pubKey(0)), 0x88, 0xac)...)
+	p2wpkh := append([]byte{0x00, 0x14}, btcutil.Hash160(pubKey(1))...)
+	p2tr := append([]byte{0x51, 0x20}, schnorr.SerializePubKey(
+		privKeys[2].PubKey())...)
+	p2wsh := append([]byte{0x00, 0x20}, make([]byte, 32)...)
+	witnessV2 := append([]byte{0x52, 0x20}, make([]byte, 32)...)
+
+	// The transaction spends one input of each eligible type along with an
+	// ineligible one.  The smallest outpoint is the one of the last input.
+	p2pkhIn := wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{0x03}},
+		append(append([]byte{byte(len(sig))}, sig...),
+			append([]byte{byte(len(pubKey(0)))}, pubKey(0)...)...), nil)
+	p2wpkhIn := wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{0x02}, Index: 1},
+		nil, wire.TxWitness{sig, pubKey(1)})
+	p2trIn := wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{0x02}},
+		nil, wire.TxWitness{bytes.Repeat([]byte{0x01}, 64)})
+	p2wshIn := wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 5},
+		nil, wire.TxWitness{sig, {0x51}})
+	eligible := wire.NewMsgTx(2)
+	eligible.AddTxIn(p2pkhIn)
+	eligible.AddTxIn(p2wpkhIn)
+	eligible.AddTxIn(p2trIn)
+	eligible.AddTxIn(p2wshIn)
+	eligible.AddTxOut(wire.NewTxOut(1000, p2tr))
+
+	// The expected tweak is derived from the private keys, where the key of
+	// the taproot input is negated when its public key has an odd y.
+	var sum btcec.ModNScalar
+	sum.Add(&privKeys[0].Key).Add(&privKeys[1].Key)
+	taprootKey := privKeys[2].Key
+	if privKeys[2].PubKey().SerializeCompressed()[0] == 0x03 {
+		taprootKey.Negate()
+	}
+	sum.Add(&taprootKey)
+	outpoint := make([]byte, chainhash.HashSize+4)
+	copy(outpoint, p2wshIn.PreviousOutPoint.Hash[:])
+	byteOrder.PutUint32(outpoint[chainhash.HashSize:], 5)
+	sumKey := btcec.PrivKeyFromScalar(&sum).PubKey().SerializeCompressed()
+	inputHash := chainhash.TaggedHash(silentPaymentInputsTag, outpoint, sumKey)
+	var tweakKey btcec.ModNScalar
+	tweakKey.SetByteSlice(inputHash[:])
+	tweakKey.Mul(&sum)
+	var wantTweak [btcec.PubKeyBytesLenCompressed]byte
+	copy(wantTweak[:], btcec.PrivKeyFromScalar(&tweakKey).PubKey().
+		SerializeCompressed())
+
+	// Transactions without taproot outputs, without eligible inputs or that
+	// spend future witness versions don't get a tweak.
+	noTaprootOutput := wire.NewMsgTx(2)
+	noTaprootOutput.AddTxIn(p2wpkhIn)
+	noTaprootOutput.AddTxOut(wire.NewTxOut(1000, p2wpkh))
+	noEligibleInput := wire.NewMsgTx(2)
+	noEligibleInput.AddTxIn(p2wshIn)
+	noEligibleInput.AddTxOut(wire.NewTxOut(1000, p2tr))
+	futureVersion := wire.NewMsgTx(2)
+	futureVersion.AddTxIn(p2wpkhIn)
+	futureVersion.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 7}, nil, nil))
+	futureVersion.AddTxOut(wire.NewTxOut(1000, p2tr))
+
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		nil, nil))
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			coinbase, noTaprootOutput, eligible, noEligibleInput,
+			futureVersion,
+		},
+	})
+	stxos := []blockchain.SpentTxOut{
+		{PkScript: p2wpkh},
+		{PkScript: p2pkh}, {PkScript: p2wpkh}, {PkScript: p2tr},
+		{PkScript: p2wsh},
+		{PkScript: p2wsh},
+		{PkScript: p2wpkh}, {PkScript: witnessV2},
+	}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.ConnectBlock(dbTx, block, stxos)
+	})
+	if err != nil {
+		t.Fatalf("ConnectBlock: unexpected error: %v", err)
+	}
+	tweaks, err := idx.FetchBlockTweaks(block.Hash())
+	if err != nil {
+		t.Fatalf("FetchBlockTweaks: unexpected error: %v", err)
+	}
+	want := SilentPaymentTweak{TxHash: eligible.TxHash(), Tweak: wantTweak}
+	if len(tweaks) != 1 || tweaks[0] != want {
+		t.Fatalf("FetchBlockTweaks: got %x, want %x", tweaks, want)
+	}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.DisconnectBlock(dbTx, block, stxos)
+	})
+	if err != nil {
+		t.Fatalf("DisconnectBlock: unexpected error: %v", err)
+	}
+	tweaks, err = idx.FetchBlockTweaks(block.Hash())
+	if err != nil {
+		t.Fatalf("FetchBlockTweaks: unexpected error: %v", err)
+	}
+	if tweaks != nil {
+		t.Fatalf("FetchBlockTweaks: got %x after the block was "+
+			"disconnected", tweaks)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// spendIndexName is the human-readable name for the index.
+	spendIndexName = "spend index"
+
+	// spendEntrySize is the size of a spend index entry.  It consists of
+	// the 32 bytes hash of the spending transaction + 4 bytes input index +
+	// 4 bytes block height.
+	spendEntrySize = chainhash.HashSize + 4 + 4
+)
+
+var (
+	// spendIndexKey is the key of the spend index and the db bucket used
+	// to house it.
+	spendIndexKey = []byte("spenderbyoutpointidx")
+)
+
+// -----------------------------------------------------------------------------
+// The spend index maps every output that was spent in the main chain to the
+// input that spends it, so that the spender of an output can be looked up
+// without scanning the blocks after it.
+//
+// The serialized key format is the same as the one of the ttl index:
+//
+//   <txid><output index>
+//
+//   Field           Type              Size
+//   txid            chainhash.Hash    32 bytes
+//   output index    VARINT            variable
+//
+// The serialized value format is:
+//
+//   <spending txid><input index><block height>
+//
+//   Field           Type              Size
+//   spending txid   chainhash.Hash    32 bytes
+//   input index     uint32            4 bytes
+//   block height    uint32            4 bytes
+//   -----
+//   Total: 40 bytes
+// -----------------------------------------------------------------------------
+
+// Spender describes the input of a transaction in the main chain that spends
+// an output.
+type Spender struct {
+	// TxHash is the hash of the spending transaction.
+	TxHash chainhash.Hash
+
+	// InputIndex is the index of the input of the spending transaction
+	// that spends the output.
+	InputIndex uint32
+
+	// Height is the height of the block of the spending transaction.
+	Height int32
+}
+
+// dbPutSpendEntries stores the spenders of all the outputs that the passed
+// block spends.
+func dbPutSpendEntries(bucket internalBucket, block *btcutil.Block) error {
+	// As an optimization, serialize the values of the block into a single
+	// slice since the database contract prohibits modifying them.
+	var numInputs int
+	for _, tx := range block.Transactions()[1:] {
+		numInputs += len(tx.MsgTx().TxIn)
+	}
+	serialized := make([]byte, numInputs*spendEntrySize)
+
+	var offset int
+	for _, tx := range block.Transactions()[1:] {
+		for i, txIn := range tx.MsgTx().TxIn {
+			value := serialized[offset : offset+spendEntrySize]
+			copy(value, tx.Hash()[:])
+			byteOrder.PutUint32(value[chainhash.HashSize:], uint32(i))
+			byteOrder.PutUint32(value[chainhash.HashSize+4:],
+				uint32(block.Height()))
+			offset += spendEntrySize
+
+			key := blockchain.OutpointKey(txIn.PreviousOutPoint)
+			if err := bucket.Put(*key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dbRemoveSpendEntries removes the spenders of all the outputs that the passed
+// block spends.
+func dbRemoveSpendEntries(bucket internalBucket, block *btcutil.Block) error {
+	for _, tx := range block.Transactions()[1:] {
+		for _, txIn := range tx.MsgTx().TxIn {
+			key := blockchain.OutpointKey(txIn.PreviousOutPoint)
+			err := bucket.Delete(*key)
+			blockchain.RecycleOutpointKey(key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dbFetchSpendEntry returns the spender of the passed output.  When the output
+// wasn't spent in the main chain, nil is returned for both the spender and the
+// error.
+func dbFetchSpendEntry(dbTx database.Tx, op *wire.OutPoint) (*Spender, error) {
+	key := blockchain.OutpointKey(*op)
+	serialized := dbTx.Metadata().Bucket(spendIndexKey).Get(*key)
+	blockchain.RecycleOutpointKey(key)
+	if serialized == nil {
+		return nil, nil
+	}
+
+	if len(serialized) < spendEntrySize {
+		return nil, database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: fmt.Sprintf("corrupt spend index entry "+
+				"for %v", op),
+		}
+	}
+
+	var spender Spender
+	copy(spender.TxHash[:], serialized[:chainhash.HashSize])
+	spender.InputIndex = byteOrder.Uint32(serialized[chainhash.HashSize:])
+	spender.Height = int32(byteOrder.Uint32(serialized[chainhash.HashSize+4:]))
+	return &spender, nil
+}
+
+// SpendIndex implements an index of the spenders of all the outputs spent in
+// the main chain.
+type SpendIndex struct {
+	db database.DB
+}
+
+// Ensure the SpendIndex type implements the Indexer interface.
+var _ Indexer = (*SpendIndex)(nil)
+
+// Init initializes the spend index.
+//
+// This is part of the Indexer interface.
+func (idx *SpendIndex) Init(_ *blockchain.BlockChain) error {
+	return nil // Nothing to do.
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *SpendIndex) Key() []byte {
+	return spendIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *SpendIndex) Name() string {
+	return spendIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the bucket for the spend
+// index.
+//
+// This is part of the Indexer interface.
+func (idx *SpendIndex) Create(dbTx database.Tx) error {
+	_, err := dbTx.Metadata().CreateBucket(spendIndexKey)
+	return err
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer adds the spender of every output
+// that the block spends.
+//
+// This is part of the Indexer interface.
+func (idx *SpendIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	return dbPutSpendEntries(dbTx.Metadata().Bucket(spendIndexKey), block)
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the spender of every
+// output that the block spends.
+//
+// This is part of the Indexer interface.
+func (idx *SpendIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	return dbRemoveSpendEntries(dbTx.Metadata().Bucket(spendIndexKey), block)
+}
+
+// PruneBlock is invoked when an older block is deleted after it's been
+// processed.
+// NOTE: For SpendIndex, it's a no-op as SpendIndex isn't allowed to be enabled
+// with pruning since it can't be caught up without the blocks.
+//
+// This is part of the Indexer interface.
+func (idx *SpendIndex) PruneBlock(dbTx database.Tx, blockHash *chainhash.Hash) error {
+	return nil
+}
+
+// FetchSpender returns the input of the transaction in the main chain that
+// spends the passed output.  When the output wasn't spent in the main chain,
+// nil is returned for both the spender and the error.
+//
+// This function is safe for concurrent access.
+func (idx *SpendIndex) FetchSpender(op *wire.OutPoint) (*Spender, error) {
+	var spender *Spender
+	err := idx.db.View(func(dbTx database.Tx) error {
+		var err error
+		spender, err = dbFetchSpendEntry(dbTx, op)
+		return err
+	})
+	return spender, err
+}
+
+// NewSpendIndex returns a new instance of an indexer that is used to create a
+// mapping of all the outputs spent in the main chain to the inputs that spend
+// them.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewSpendIndex(db database.DB) *SpendIndex {
+	return &SpendIndex{db: db}
+}
+
+// DropSpendIndex drops the spend index from the provided database if it
+// exists.
+func DropSpendIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, spendIndexKey, spendIndexName, interrupt)
+}
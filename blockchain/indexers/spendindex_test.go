@@ -0,0 +1,96 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"os"
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestSpendIndex ensures that the spend index returns the inputs that spend the
+// outputs spent by a connected block and forgets them once the block is
+// disconnected.
+func TestSpendIndex(t *testing.T) {
+	db, dbPath, err := createDB("spendindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewSpendIndex(db)
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.Create(dbTx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op1 := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 0}
+	op2 := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 300}
+	unspent := wire.OutPoint{Hash: chainhash.Hash{0x02}, Index: 0}
+
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		nil, nil))
+	spend := wire.NewMsgTx(1)
+	spend.AddTxIn(wire.NewTxIn(&op2, nil, nil))
+	spend.AddTxIn(wire.NewTxIn(&op1, nil, nil))
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{coinbase, spend},
+	})
+	block.SetHeight(100)
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.ConnectBlock(dbTx, block, nil)
+	})
+	if err != nil {
+		t.Fatalf("ConnectBlock: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		op   wire.OutPoint
+		want *Spender
+	}{
+		{op1, &Spender{TxHash: spend.TxHash(), InputIndex: 1, Height: 100}},
+		{op2, &Spender{TxHash: spend.TxHash(), InputIndex: 0, Height: 100}},
+		{unspent, nil},
+	}
+	for _, test := range tests {
+		spender, err := idx.FetchSpender(&test.op)
+		if err != nil {
+			t.Fatalf("FetchSpender(%v): unexpected error: %v",
+				test.op, err)
+		}
+		if (spender == nil) != (test.want == nil) ||
+			(spender != nil && *spender != *test.want) {
+
+			t.Fatalf("FetchSpender(%v): got %v, want %v", test.op,
+				spender, test.want)
+		}
+	}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.DisconnectBlock(dbTx, block, nil)
+	})
+	if err != nil {
+		t.Fatalf("DisconnectBlock: unexpected error: %v", err)
+	}
+	for _, op := range []wire.OutPoint{op1, op2} {
+		spender, err := idx.FetchSpender(&op)
+		if err != nil {
+			t.Fatalf("FetchSpender(%v): unexpected error: %v", op, err)
+		}
+		if spender != nil {
+			t.Fatalf("FetchSpender(%v): got %v after the block was "+
+				"disconnected", op, spender)
+		}
+	}
+}
@@ -115,6 +115,81 @@ func (idx *TTLIndex) GetTTL(op *wire.OutPoint) *int32 {
 	return ttl
 }
 
+// LeafTTL is the time to live of a leaf that a block added to the utreexo
+// accumulator.
+type LeafTTL struct {
+	// OutPoint is the output of the leaf.
+	OutPoint wire.OutPoint
+
+	// Index is the index of the output among all the outputs of the block,
+	// which is how the remember indexes of utreexo proofs refer to the leaf.
+	Index uint32
+
+	// TTL is the number of blocks after which the leaf is spent, or -1 if
+	// it's unspent.
+	TTL int32
+}
+
+// FetchLeafTTLs returns the time to live of every leaf the passed block added
+// to the utreexo accumulator in the order they were added.  The outputs spent in
+// the same block and the unspendable outputs aren't leaves and are left out.
+//
+// This function is safe for concurrent access.
+func (idx *TTLIndex) FetchLeafTTLs(block *btcutil.Block) []LeafTTL {
+	_, outCount, _, outskip := blockchain.DedupeBlock(block)
+	leafTTLs := make([]LeafTTL, 0, outCount-len(outskip))
+	idx.db.View(func(dbTx database.Tx) error {
+		var txoNum uint32
+		for _, tx := range block.Transactions() {
+			for outIdx, txOut := range tx.MsgTx().TxOut {
+				if blockchain.IsUnspendable(txOut) {
+					txoNum++
+					continue
+				}
+				if len(outskip) > 0 && outskip[0] == txoNum {
+					outskip = outskip[1:]
+					txoNum++
+					continue
+				}
+
+				leafTTL := LeafTTL{
+					OutPoint: wire.OutPoint{
+						Hash:  *tx.Hash(),
+						Index: uint32(outIdx),
+					},
+					Index: txoNum,
+					TTL:   -1,
+				}
+				ttl := dbFetchTTLEntry(dbTx, &leafTTL.OutPoint)
+				if ttl != nil {
+					leafTTL.TTL = *ttl
+				}
+				leafTTLs = append(leafTTLs, leafTTL)
+				txoNum++
+			}
+		}
+		return nil
+	})
+
+	return leafTTLs
+}
+
+// Remembers returns the remember indexes of the leaves that the passed block
+// added to the utreexo accumulator and that are spent at most maxTTL blocks
+// later.  Compact state nodes with the ttl remember policy cache these leaves
+// so that they don't need a proof when they're spent.
+//
+// This function is safe for concurrent access.
+func (idx *TTLIndex) Remembers(block *btcutil.Block, maxTTL int32) []uint32 {
+	var remembers []uint32
+	for _, leafTTL := range idx.FetchLeafTTLs(block) {
+		if leafTTL.TTL >= 0 && leafTTL.TTL <= maxTTL {
+			remembers = append(remembers, leafTTL.Index)
+		}
+	}
+	return remembers
+}
+
 // -----------------------------------------------------------------------------
 // Each TTL entry is stored on disk with a <key><value> of:
 //
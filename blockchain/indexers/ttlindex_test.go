@@ -0,0 +1,118 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestTTLIndexLeafTTLs ensures that the time to live of the leaves a block adds
+// to the accumulator are returned along with their remember indexes.
+func TestTTLIndexLeafTTLs(t *testing.T) {
+	db, dbPath, err := createDB("ttlindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	idx := NewTTLIndex(db, &chaincfg.RegressionNetParams)
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.Create(dbTx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkScript := []byte{txscript.OP_TRUE}
+	newCoinbase := func(extraNonce byte) *wire.MsgTx {
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex},
+			[]byte{extraNonce}, nil))
+		tx.AddTxOut(wire.NewTxOut(5000, pkScript))
+		return tx
+	}
+
+	// The first block creates an unspendable output and an output that is
+	// spent in the same block, neither of which are leaves.
+	coinbase := newCoinbase(1)
+	coinbase.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+	parent := wire.NewMsgTx(1)
+	parent.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{0x01}},
+		nil, nil))
+	parent.AddTxOut(wire.NewTxOut(1000, pkScript))
+	parent.AddTxOut(wire.NewTxOut(1000, pkScript))
+	child := wire.NewMsgTx(1)
+	child.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: parent.TxHash()}, nil, nil))
+	child.AddTxOut(wire.NewTxOut(900, pkScript))
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{coinbase, parent, child},
+	})
+	block.SetHeight(100)
+	stxos := []blockchain.SpentTxOut{
+		{PkScript: pkScript, Height: 90},
+		{PkScript: pkScript, Height: 100},
+	}
+
+	// The later block spends the outputs of the coinbase and the child.
+	spend := wire.NewMsgTx(1)
+	spend.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: coinbase.TxHash()}, nil, nil))
+	spend.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: child.TxHash()}, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(4000, pkScript))
+	laterBlock := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{newCoinbase(2), spend},
+	})
+	laterBlock.SetHeight(105)
+	laterStxos := []blockchain.SpentTxOut{
+		{PkScript: pkScript, Height: 100},
+		{PkScript: pkScript, Height: 100},
+	}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		err := idx.ConnectBlock(dbTx, block, stxos)
+		if err != nil {
+			return err
+		}
+		return idx.ConnectBlock(dbTx, laterBlock, laterStxos)
+	})
+	if err != nil {
+		t.Fatalf("ConnectBlock: unexpected error: %v", err)
+	}
+
+	want := []LeafTTL{
+		{OutPoint: wire.OutPoint{Hash: coinbase.TxHash()}, Index: 0, TTL: 5},
+		{OutPoint: wire.OutPoint{Hash: parent.TxHash(), Index: 1}, Index: 3, TTL: -1},
+		{OutPoint: wire.OutPoint{Hash: child.TxHash()}, Index: 4, TTL: 5},
+	}
+	leafTTLs := idx.FetchLeafTTLs(block)
+	if !reflect.DeepEqual(leafTTLs, want) {
+		t.Fatalf("FetchLeafTTLs: got %v, want %v", leafTTLs, want)
+	}
+
+	tests := []struct {
+		maxTTL int32
+		want   []uint32
+	}{
+		{maxTTL: 4, want: nil},
+		{maxTTL: 5, want: []uint32{0, 4}},
+	}
+	for _, test := range tests {
+		remembers := idx.Remembers(block, test.maxTTL)
+		if !reflect.DeepEqual(remembers, test.want) {
+			t.Fatalf("Remembers(%d): got %v, want %v", test.maxTTL,
+				remembers, test.want)
+		}
+	}
+}
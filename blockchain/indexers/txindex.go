@@ -289,6 +289,29 @@ type TxIndex struct {
 // Ensure the TxIndex type implements the Indexer interface.
 var _ Indexer = (*TxIndex)(nil)
 
+// Ensure the TxIndex type implements the BackgroundCatchUpper interface.
+var _ BackgroundCatchUpper = (*TxIndex)(nil)
+
+// BackgroundCatchUp signals that the index can be caught up in the background
+// since no other part of the node depends on it.
+//
+// This implements the BackgroundCatchUpper interface.
+func (idx *TxIndex) BackgroundCatchUp() bool {
+	return true
+}
+
+// Ensure the TxIndex type implements the PruneBlocker interface.
+var _ PruneBlocker = (*TxIndex)(nil)
+
+// BlocksPrune signals that the index keeps the blocks it has indexed from
+// being pruned since its entries are only the locations of the transactions
+// within them.
+//
+// This implements the PruneBlocker interface.
+func (idx *TxIndex) BlocksPrune() bool {
+	return true
+}
+
 // Init initializes the hash-based transaction index.  In particular, it finds
 // the highest used block ID and stores it for later use when connecting or
 // disconnecting blocks.
@@ -366,6 +389,21 @@ func (idx *TxIndex) Name() string {
 	return txIndexName
 }
 
+// extraSize returns the size of the internal block ID indexes.
+//
+// This is part of the extraSizer interface.
+func (idx *TxIndex) extraSize(dbTx database.Tx) (int64, error) {
+	var size int64
+	for _, name := range [][]byte{idByHashIndexBucketName, hashByIDIndexBucketName} {
+		bucketBytes, err := bucketSize(dbTx.Metadata().Bucket(name))
+		if err != nil {
+			return 0, err
+		}
+		size += bucketBytes
+	}
+	return size, nil
+}
+
 // Create is invoked when the indexer manager determines the index needs
 // to be created for the first time.  It creates the buckets for the hash-based
 // transaction index and the internal block ID indexes.
@@ -432,9 +470,8 @@ func (idx *TxIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
 
 // PruneBlock is invoked when an older block is deleted after it's been
 // processed.
-// NOTE: For TxIndex, it's a no-op as TxIndex isn't allowed to be enabled
-// with pruning. This may be visited at a later date so that TxIndex is
-// supported with pruning.
+// NOTE: For TxIndex, it's a no-op as the index keeps the blocks it has
+// indexed from being pruned.
 //
 // This is part of the Indexer interface.
 func (idx *TxIndex) PruneBlock(dbTx database.Tx, blockHash *chainhash.Hash) error {
@@ -6,16 +6,19 @@ package indexers
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
+	"math/bits"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/utreexo/utreexo"
 	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
 	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
 )
 
 const (
@@ -53,6 +56,11 @@ type UtreexoState struct {
 	config *UtreexoConfig
 	state  utreexo.Utreexo
 
+	// nodesDB and cachedLeavesDB are the databases backing the state.
+	// They're only used for reporting statistics.
+	nodesDB        *blockchain.NodesBackEnd
+	cachedLeavesDB *blockchain.CachedLeavesBackEnd
+
 	closeDB func() error
 }
 
@@ -158,46 +166,364 @@ func (idx *FlatUtreexoProofIndex) FetchUtreexoState(blockHeight int32) ([]*chain
 	return chainhashRoots, stump.NumLeaves, nil
 }
 
-// FlushUtreexoState saves the utreexo state to disk.
-func (idx *UtreexoProofIndex) FlushUtreexoState() error {
-	basePath := utreexoBasePath(idx.utreexoState.config)
-	if _, err := os.Stat(basePath); err != nil {
-		os.MkdirAll(basePath, os.ModePerm)
+// fetchUtreexoRoots returns the utreexo state after the block at the given
+// height of the main chain was connected.  The index stores the state from
+// before a block is connected under that block so it's the state stored under
+// the next block or the current state for the tip.
+func (idx *UtreexoProofIndex) fetchUtreexoRoots(height int32) ([]*chainhash.Hash, uint64, error) {
+	if height == idx.chain.BestSnapshot().Height {
+		roots, numLeaves := idx.FetchCurrentUtreexoState()
+		return roots, numLeaves, nil
 	}
-	forestFilePath := filepath.Join(basePath, defaultUtreexoFileName)
-	forestFile, err := os.OpenFile(forestFilePath, os.O_RDWR|os.O_CREATE, 0666)
+
+	blockHash, err := idx.chain.BlockHashByHeight(height + 1)
 	if err != nil {
+		return nil, 0, err
+	}
+
+	var roots []*chainhash.Hash
+	var numLeaves uint64
+	err = idx.db.View(func(dbTx database.Tx) error {
+		var err error
+		roots, numLeaves, err = idx.FetchUtreexoState(dbTx, blockHash)
 		return err
+	})
+	return roots, numLeaves, err
+}
+
+// fetchUtreexoRoots returns the utreexo state after the block at the given
+// height of the main chain was connected.  The index stores the state from
+// before a block is connected under that block so it's the state stored under
+// the next block or the current state for the tip.
+func (idx *FlatUtreexoProofIndex) fetchUtreexoRoots(height int32) ([]*chainhash.Hash, uint64, error) {
+	if height == idx.chain.BestSnapshot().Height {
+		roots, numLeaves := idx.FetchCurrentUtreexoState()
+		return roots, numLeaves, nil
+	}
+
+	return idx.FetchUtreexoState(height + 1)
+}
+
+// utreexoRootsAfterBlock returns the roots and number of leaves the accumulator
+// will have once the block, which spends the passed in stxos, is connected to
+// it.  The block is applied to a stump of the accumulator so that the
+// accumulator itself is left untouched.
+func utreexoRootsAfterBlock(chain *blockchain.BlockChain, state utreexo.Utreexo,
+	mtx *sync.RWMutex, block *btcutil.Block, stxos []blockchain.SpentTxOut) (
+	[]*chainhash.Hash, uint64, error) {
+
+	// The genesis block isn't connected to the accumulator.
+	if block.Height() == 0 {
+		mtx.RLock()
+		defer mtx.RUnlock()
+		return chainhashRoots(state.GetRoots()), state.GetNumLeaves(), nil
 	}
-	var buf [8]byte
-	binary.LittleEndian.PutUint64(buf[:], idx.utreexoState.state.GetNumLeaves())
-	_, err = forestFile.Write(buf[:])
+
+	_, outCount, inskip, outskip := blockchain.DedupeBlock(block)
+	dels, _, err := blockchain.BlockToDelLeaves(stxos, chain, block, inskip, -1)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
+	adds := blockchain.BlockToAddLeaves(block, outskip, nil, outCount)
+
+	mtx.RLock()
+	ud, err := wire.GenerateUData(dels, state)
+	stump := utreexo.Stump{
+		Roots:     state.GetRoots(),
+		NumLeaves: state.GetNumLeaves(),
+	}
+	mtx.RUnlock()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	addHashes := make([]utreexo.Hash, 0, len(adds))
+	for _, add := range adds {
+		addHashes = append(addHashes, add.Hash)
+	}
+	_, err = stump.Update(wire.LeafHashes(ud.LeafDatas), addHashes, ud.AccProof)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return chainhashRoots(stump.Roots), stump.NumLeaves, nil
+}
+
+// chainhashRoots returns the roots of an accumulator as chainhash hashes.
+func chainhashRoots(roots []utreexo.Hash) []*chainhash.Hash {
+	hashes := make([]*chainhash.Hash, len(roots))
+	for i := range roots {
+		root := chainhash.Hash(roots[i])
+		hashes[i] = &root
+	}
+	return hashes
+}
+
+// utreexoRootsAfter returns the utreexo state the index will have once the
+// block is connected to it.
+//
+// This is part of the utreexoRootsFetcher interface.
+func (idx *UtreexoProofIndex) utreexoRootsAfter(block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) ([]*chainhash.Hash, uint64, error) {
 
-	return idx.utreexoState.closeDB()
+	return utreexoRootsAfterBlock(idx.chain, idx.utreexoState.state, idx.mtx,
+		block, stxos)
+}
+
+// utreexoRootsAfter returns the utreexo state the index will have once the
+// block is connected to it.
+//
+// This is part of the utreexoRootsFetcher interface.
+func (idx *FlatUtreexoProofIndex) utreexoRootsAfter(block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) ([]*chainhash.Hash, uint64, error) {
+
+	return utreexoRootsAfterBlock(idx.chain, idx.utreexoState.state, idx.mtx,
+		block, stxos)
+}
+
+// FlushUtreexoState saves the utreexo state to disk.
+func (idx *UtreexoProofIndex) FlushUtreexoState() error {
+	return idx.utreexoState.flush()
 }
 
 // FlushUtreexoState saves the utreexo state to disk.
 func (idx *FlatUtreexoProofIndex) FlushUtreexoState() error {
-	basePath := utreexoBasePath(idx.utreexoState.config)
-	if _, err := os.Stat(basePath); err != nil {
-		os.MkdirAll(basePath, os.ModePerm)
+	return idx.utreexoState.flush()
+}
+
+// Backup writes a copy of the utreexo state of the index to the passed data
+// directory, where it's found when the node is started with that data
+// directory.  It must be called while no block is connected or disconnected,
+// such as from the function passed to BlockChain.Quiesce, for the copy to be at
+// the same block as the database.
+func (idx *UtreexoProofIndex) Backup(dataDir string) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	return idx.utreexoState.backup(dataDir)
+}
+
+// Backup writes a copy of the utreexo state and the flat files of the index to
+// the passed data directory, where they're found when the node is started with
+// that data directory.  It must be called while no block is connected or
+// disconnected, such as from the function passed to BlockChain.Quiesce, for
+// the copy to be at the same block as the database.
+func (idx *FlatUtreexoProofIndex) Backup(dataDir string) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	if err := idx.utreexoState.backup(dataDir); err != nil {
+		return err
+	}
+
+	states := []struct {
+		name  string
+		state *FlatFileState
+	}{
+		{flatUtreexoProofName, &idx.proofState},
+		{flatUtreexoUndoName, &idx.undoState},
+		{flatRememberIdxName, &idx.rememberIdxState},
+		{flatUtreexoProofStatsName, &idx.proofStatsState},
+		{flatUtreexoRootsName, &idx.rootsState},
 	}
-	forestFilePath := filepath.Join(basePath, defaultUtreexoFileName)
-	forestFile, err := os.OpenFile(forestFilePath, os.O_RDWR|os.O_CREATE, 0666)
+	for _, s := range states {
+		// The proofs aren't kept by pruned nodes.
+		if s.state.dataFile == nil {
+			continue
+		}
+		if err := s.state.backup(flatFilePath(dataDir, s.name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flush writes the forest file for the current utreexoStateVersion and closes
+// the underlying databases.
+func (us *UtreexoState) flush() error {
+	basePath := utreexoBasePath(us.config)
+	payload := serializeForestState(forestState{numLeaves: us.state.GetNumLeaves()})
+	err := writeForestFile(basePath, payload)
 	if err != nil {
 		return err
 	}
-	var buf [8]byte
-	binary.LittleEndian.PutUint64(buf[:], idx.utreexoState.state.GetNumLeaves())
-	_, err = forestFile.Write(buf[:])
+
+	return us.closeDB()
+}
+
+// backup writes a copy of the utreexo state to the utreexo state directory of
+// the passed data directory.  The nodes and cached leaves are written to new
+// databases there from the cache and the databases of the state, so it has to
+// be called while the state isn't modified.
+func (us *UtreexoState) backup(dataDir string) error {
+	p, ok := us.state.(*utreexo.MapPollard)
+	if !ok {
+		return fmt.Errorf("unable to back up a utreexo state of type %T",
+			us.state)
+	}
+
+	basePath := utreexoBasePath(&UtreexoConfig{DataDir: dataDir,
+		Name: us.config.Name})
+	if err := os.MkdirAll(basePath, 0700); err != nil {
+		return err
+	}
+
+	nodesDB, err := blockchain.InitNodesBackEnd(
+		filepath.Join(basePath, nodesDBDirName), 0)
 	if err != nil {
 		return err
 	}
+	err = p.Nodes.ForEach(func(k uint64, v utreexo.Leaf) error {
+		nodesDB.Put(k, v)
+		return nil
+	})
+	if closeErr := nodesDB.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	cachedLeavesDB, err := blockchain.InitCachedLeavesBackEnd(
+		filepath.Join(basePath, cachedLeavesDBDirName), 0)
+	if err != nil {
+		return err
+	}
+	err = p.CachedLeaves.ForEach(func(k utreexo.Hash, v uint64) error {
+		cachedLeavesDB.Put(k, v)
+		return nil
+	})
+	if closeErr := cachedLeavesDB.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// The forest file is written last since the state isn't resumed from
+	// without it.
+	payload := serializeForestState(forestState{numLeaves: us.state.GetNumLeaves()})
+	return writeForestFile(basePath, payload)
+}
+
+// NumLeaves returns the number of leaves that were ever added to the
+// accumulator.
+func (us *UtreexoState) NumLeaves() uint64 {
+	return us.state.GetNumLeaves()
+}
+
+// LeafPosition returns the position of the given leaf hash in the accumulator
+// and a boolean for whether or not the leaf exists in it.
+func (us *UtreexoState) LeafPosition(hash utreexo.Hash) (uint64, bool) {
+	return us.state.GetLeafPosition(hash)
+}
+
+// LeafCount returns the number of leaves that are currently in the accumulator.
+func (us *UtreexoState) LeafCount() int {
+	p, ok := us.state.(*utreexo.MapPollard)
+	if !ok {
+		return 0
+	}
+	return p.CachedLeaves.Length()
+}
+
+// stats returns the statistics of the utreexo state other than its size.  The
+// caller must hold the lock protecting the state.
+func (us *UtreexoState) stats() blockchain.UtreexoStats {
+	// There's a root for every bit set in the number of leaves.  Fetching
+	// the roots would look up the nodes and count towards the cache
+	// statistics.
+	numLeaves := us.state.GetNumLeaves()
+	stats := blockchain.UtreexoStats{
+		NumLeaves: numLeaves,
+		NumRoots:  bits.OnesCount64(numLeaves),
+	}
+	stats.NodesCacheHits, stats.NodesCacheMisses = us.nodesDB.CacheStats()
+	stats.CachedLeavesCacheHits, stats.CachedLeavesCacheMisses =
+		us.cachedLeavesDB.CacheStats()
+
+	return stats
+}
+
+// size returns the number of bytes the utreexo state takes up on disk.  It
+// walks the directory of the state so it doesn't require the lock protecting
+// the state to be held and is best effort as files may be created and removed
+// by the databases while walking it.
+func (us *UtreexoState) size() int64 {
+	var size int64
+	filepath.Walk(utreexoBasePath(us.config), func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size
+}
+
+// UtreexoStats returns the statistics of the accumulator kept by the index.
+//
+// This implements the blockchain.UtreexoStatser interface.
+func (idx *UtreexoProofIndex) UtreexoStats() (blockchain.UtreexoStats, bool) {
+	idx.mtx.RLock()
+	stats := idx.utreexoState.stats()
+	idx.mtx.RUnlock()
+
+	// Walk the directory of the state without holding the lock so the
+	// blocks being connected aren't held up by the disk.
+	stats.StateSize = idx.utreexoState.size()
+	return stats, true
+}
+
+// UtreexoStats returns the statistics of the accumulator kept by the index.
+//
+// This implements the blockchain.UtreexoStatser interface.
+func (idx *FlatUtreexoProofIndex) UtreexoStats() (blockchain.UtreexoStats, bool) {
+	idx.mtx.RLock()
+	stats := idx.utreexoState.stats()
+	idx.mtx.RUnlock()
+
+	// Walk the directory of the state without holding the lock so the
+	// blocks being connected aren't held up by the disk.
+	stats.StateSize = idx.utreexoState.size()
+	return stats, true
+}
+
+// Close closes the underlying databases without writing out the forest file.
+// It's meant for callers that only read the utreexo state.
+func (us *UtreexoState) Close() error {
+	return us.closeDB()
+}
+
+// FetchUtreexoStateTip returns the hash and height of the block the utreexo
+// state of the utreexo proof index, or of the flat utreexo proof index if flat
+// is true, is at according to the index tips in the given block database.  It's
+// meant for callers that only read the utreexo state of a stopped node.
+func FetchUtreexoStateTip(db database.DB, flat bool) (*chainhash.Hash, int32, error) {
+	idxKey, idxName := utreexoParentBucketKey, utreexoProofIndexName
+	if flat {
+		idxKey, idxName = flatUtreexoBucketKey, flatUtreexoProofIndexName
+	}
+
+	var hash *chainhash.Hash
+	var height int32
+	err := db.View(func(dbTx database.Tx) error {
+		indexesBucket := dbTx.Metadata().Bucket(indexTipsBucketName)
+		if indexesBucket == nil || indexesBucket.Get(idxKey) == nil {
+			return fmt.Errorf("the %s isn't enabled in the block "+
+				"database", idxName)
+		}
+
+		var err error
+		hash, height, err = dbFetchIndexerTip(dbTx, idxKey)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return idx.utreexoState.closeDB()
+	return hash, height, nil
 }
 
 // serializeUndoBlock serializes all the data that's needed for undoing a full utreexo state
@@ -319,6 +645,16 @@ func deserializeUndoBlock(serialized []byte) (uint64, []uint64, []utreexo.Hash,
 func initUtreexoState(cfg *UtreexoConfig, maxMemoryUsage int64, basePath string) (*UtreexoState, error) {
 	p := utreexo.NewMapPollard(true)
 
+	// Read the forest file first as any migrations need to be applied
+	// before the databases are opened.
+	if checkUtreexoExists(cfg, basePath) {
+		fState, err := readForestFile(basePath)
+		if err != nil {
+			return nil, err
+		}
+		p.NumLeaves = fState.numLeaves
+	}
+
 	// 60% of the memory for the nodes map, 40% for the cache leaves map.
 	// TODO Totally arbitrary, it there's something better than change it to that.
 	maxNodesMem := maxMemoryUsage * 6 / 10
@@ -336,20 +672,6 @@ func initUtreexoState(cfg *UtreexoConfig, maxMemoryUsage int64, basePath string)
 		return nil, err
 	}
 
-	if checkUtreexoExists(cfg, basePath) {
-		forestFilePath := filepath.Join(basePath, defaultUtreexoFileName)
-		file, err := os.OpenFile(forestFilePath, os.O_RDWR, 0400)
-		if err != nil {
-			return nil, err
-		}
-		var buf [8]byte
-		_, err = file.Read(buf[:])
-		if err != nil {
-			return nil, err
-		}
-		p.NumLeaves = binary.LittleEndian.Uint64(buf[:])
-	}
-
 	var closeDB func() error
 	if maxMemoryUsage >= 0 {
 		p.Nodes = nodesDB
@@ -423,9 +745,11 @@ func initUtreexoState(cfg *UtreexoConfig, maxMemoryUsage int64, basePath string)
 	}
 
 	uState := &UtreexoState{
-		config:  cfg,
-		state:   &p,
-		closeDB: closeDB,
+		config:         cfg,
+		state:          &p,
+		nodesDB:        nodesDB,
+		cachedLeavesDB: cachedLeavesDB,
+		closeDB:        closeDB,
 	}
 
 	return uState, err
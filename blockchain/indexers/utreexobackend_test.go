@@ -0,0 +1,58 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"os"
+	"testing"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+)
+
+// TestFetchUtreexoStateTip ensures that the tips of the utreexo states are
+// fetched from the block database and that an error is returned for a block
+// database without them.
+func TestFetchUtreexoStateTip(t *testing.T) {
+	// Always remove the root on return.
+	defer os.RemoveAll(testDbRoot)
+
+	chain, _, params, indexManager, tearDown := indexersTestChain(
+		"TestFetchUtreexoStateTip", 1)
+	defer tearDown()
+
+	tip := btcutil.NewBlock(params.GenesisBlock)
+	var spends []*blockchain.SpendableOut
+	for i := 0; i < 3; i++ {
+		var err error
+		tip, spends, err = blockchain.AddBlock(chain, tip, spends)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	best := chain.BestSnapshot()
+	for _, flat := range []bool{false, true} {
+		hash, height, err := FetchUtreexoStateTip(indexManager.db, flat)
+		if err != nil {
+			t.Fatalf("flat %v: unexpected error: %v", flat, err)
+		}
+		if *hash != best.Hash || height != best.Height {
+			t.Fatalf("flat %v: got tip %v (height %d), want %v "+
+				"(height %d)", flat, hash, height, best.Hash,
+				best.Height)
+		}
+	}
+
+	db, _, err := createDB("TestFetchUtreexoStateTip-NoIndexes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err := FetchUtreexoStateTip(db, false); err == nil {
+		t.Fatalf("expected an error for a block database without " +
+			"the utreexo proof index")
+	}
+}
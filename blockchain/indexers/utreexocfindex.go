@@ -0,0 +1,375 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/btcutil/gcs/builder"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+)
+
+const (
+	// utreexoCFIndexName is the human-readable name for the index.
+	utreexoCFIndexName = "utreexo committed filter header index"
+
+	// utreexoCFEntrySize is the size of a utreexo committed filter header
+	// index entry.  It consists of the 32 bytes header + 32 bytes filter
+	// hash + 32 bytes roots hash.
+	utreexoCFEntrySize = chainhash.HashSize * 3
+)
+
+var (
+	// utreexoCFIndexKey is the key of the utreexo committed filter header
+	// index and the db bucket used to house it.
+	utreexoCFIndexKey = []byte("utreexocfheaderbyhashidx")
+)
+
+// -----------------------------------------------------------------------------
+// The utreexo committed filter header index keeps a chain of filter headers
+// that commit to both the BIP-158 basic filter and the utreexo roots of every
+// block in the main chain.  A light client that already follows the regular
+// filter header chain is then also able to verify the accumulator state
+// transition of every block it fetches the filter for.
+//
+// The header of a block is computed as:
+//
+//   double-SHA256(<filter hash><roots hash><previous header>)
+//
+// where the filter hash is the same one committed to by the BIP-157 filter
+// headers, the roots hash is the double-SHA256 of the utreexo roots after the
+// block was connected as serialized by blockchain.SerializeUtreexoRoots and the
+// previous header is the header of the parent block or all zeros for the
+// genesis block.
+//
+// The serialized key format is:
+//
+//   <block hash>
+//
+//   Field           Type              Size
+//   block hash      chainhash.Hash    32 bytes
+//
+// The serialized value format is:
+//
+//   <header><filter hash><roots hash>
+//
+//   Field           Type              Size
+//   header          chainhash.Hash    32 bytes
+//   filter hash     chainhash.Hash    32 bytes
+//   roots hash      chainhash.Hash    32 bytes
+//   -----
+//   Total: 96 bytes
+// -----------------------------------------------------------------------------
+
+// UtreexoCFHeader is the entry of the utreexo committed filter header index for
+// a block.
+type UtreexoCFHeader struct {
+	// Header is the filter header which commits to the filter hash, the
+	// roots hash and the header of the previous block.
+	Header chainhash.Hash
+
+	// FilterHash is the hash of the BIP-158 basic filter of the block.
+	FilterHash chainhash.Hash
+
+	// RootsHash is the hash of the utreexo roots after the block was
+	// connected.
+	RootsHash chainhash.Hash
+}
+
+// UtreexoRootsHash returns the hash committed to by the utreexo committed
+// filter headers for the given number of leaves and roots of the accumulator.
+func UtreexoRootsHash(numLeaves uint64, roots []*chainhash.Hash) (chainhash.Hash, error) {
+	uRoots := make([]utreexo.Hash, len(roots))
+	for i, root := range roots {
+		uRoots[i] = utreexo.Hash(*root)
+	}
+	serialized, err := blockchain.SerializeUtreexoRoots(numLeaves, uRoots)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	return chainhash.DoubleHashH(serialized), nil
+}
+
+// MakeUtreexoCFHeader returns the utreexo committed filter header for the given
+// filter hash, roots hash and header of the previous block.
+func MakeUtreexoCFHeader(filterHash, rootsHash, prevHeader *chainhash.Hash) chainhash.Hash {
+	var preimage [chainhash.HashSize * 3]byte
+	copy(preimage[:], filterHash[:])
+	copy(preimage[chainhash.HashSize:], rootsHash[:])
+	copy(preimage[chainhash.HashSize*2:], prevHeader[:])
+	return chainhash.DoubleHashH(preimage[:])
+}
+
+// dbFetchUtreexoCFEntry returns the utreexo committed filter header index
+// entry of the block with the given hash.  When the block isn't indexed, nil is
+// returned for both the entry and the error.
+func dbFetchUtreexoCFEntry(dbTx database.Tx, hash *chainhash.Hash) (*UtreexoCFHeader, error) {
+	serialized := dbTx.Metadata().Bucket(utreexoCFIndexKey).Get(hash[:])
+	if serialized == nil {
+		return nil, nil
+	}
+
+	if len(serialized) < utreexoCFEntrySize {
+		return nil, database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: fmt.Sprintf("corrupt utreexo committed filter "+
+				"header index entry for %v", hash),
+		}
+	}
+
+	var entry UtreexoCFHeader
+	copy(entry.Header[:], serialized[:chainhash.HashSize])
+	copy(entry.FilterHash[:], serialized[chainhash.HashSize:])
+	copy(entry.RootsHash[:], serialized[chainhash.HashSize*2:])
+	return &entry, nil
+}
+
+// dbPutUtreexoCFEntry stores the utreexo committed filter header index entry of
+// the block with the given hash.
+func dbPutUtreexoCFEntry(dbTx database.Tx, hash *chainhash.Hash, entry *UtreexoCFHeader) error {
+	serialized := make([]byte, utreexoCFEntrySize)
+	copy(serialized, entry.Header[:])
+	copy(serialized[chainhash.HashSize:], entry.FilterHash[:])
+	copy(serialized[chainhash.HashSize*2:], entry.RootsHash[:])
+	return dbTx.Metadata().Bucket(utreexoCFIndexKey).Put(hash[:], serialized)
+}
+
+// utreexoRootsIndex is an index that keeps a utreexo accumulator.
+type utreexoRootsIndex interface {
+	Indexer
+	utreexoRootsFetcher
+}
+
+// UtreexoCFIndex implements an index of filter headers that commit to the
+// BIP-158 basic filters and the utreexo roots of all the blocks in the main
+// chain.
+type UtreexoCFIndex struct {
+	db database.DB
+
+	// rootsIndex is the utreexo proof index the roots are fetched from.
+	// It's nil for compact state nodes which fetch the roots from the
+	// utreexo viewpoints stored by the chain instead.
+	rootsIndex utreexoRootsIndex
+}
+
+// Ensure the UtreexoCFIndex type implements the Indexer interface.
+var _ Indexer = (*UtreexoCFIndex)(nil)
+
+// Ensure the UtreexoCFIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*UtreexoCFIndex)(nil)
+
+// Ensure the UtreexoCFIndex type implements the dependentIndex interface.
+var _ dependentIndex = (*UtreexoCFIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the basic filters.
+//
+// This implements the NeedsInputser interface.
+func (idx *UtreexoCFIndex) NeedsInputs() bool {
+	return true
+}
+
+// dependency returns the utreexo proof index the roots are fetched from since
+// it has to be connected to a block before this index can be.
+//
+// This implements the dependentIndex interface.
+func (idx *UtreexoCFIndex) dependency() Indexer {
+	if idx.rootsIndex == nil {
+		return nil
+	}
+	return idx.rootsIndex
+}
+
+// Init initializes the utreexo committed filter header index.
+//
+// This is part of the Indexer interface.
+func (idx *UtreexoCFIndex) Init(_ *blockchain.BlockChain) error {
+	return nil // Nothing to do.
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *UtreexoCFIndex) Key() []byte {
+	return utreexoCFIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *UtreexoCFIndex) Name() string {
+	return utreexoCFIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the bucket for the utreexo
+// committed filter header index.
+//
+// This is part of the Indexer interface.
+func (idx *UtreexoCFIndex) Create(dbTx database.Tx) error {
+	_, err := dbTx.Metadata().CreateBucket(utreexoCFIndexKey)
+	return err
+}
+
+// fetchUtreexoRoots returns the roots and the number of leaves of the
+// accumulator after the passed block was connected.
+func (idx *UtreexoCFIndex) fetchUtreexoRoots(dbTx database.Tx, block *btcutil.Block) (
+	[]*chainhash.Hash, uint64, error) {
+
+	if idx.rootsIndex == nil {
+		roots, numLeaves, ok, err := blockchain.DBFetchUtreexoRoots(
+			dbTx, block.Hash())
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			return nil, 0, fmt.Errorf("the utreexo roots of block %v "+
+				"(height %d) aren't stored", block.Hash(),
+				block.Height())
+		}
+		return roots, numLeaves, nil
+	}
+
+	// The utreexo proof index comes before this index so it has already
+	// been connected to the block when its tip is at the block.  Otherwise
+	// it's ahead and the roots are fetched from its stored states.
+	_, tipHeight, err := dbFetchIndexerTip(dbTx, idx.rootsIndex.Key())
+	if err != nil {
+		return nil, 0, err
+	}
+	if tipHeight == block.Height() {
+		roots, numLeaves := idx.rootsIndex.FetchCurrentUtreexoState()
+		return roots, numLeaves, nil
+	}
+	if tipHeight < block.Height() {
+		return nil, 0, fmt.Errorf("%s is behind the block %v (height %d)",
+			idx.rootsIndex.Name(), block.Hash(), block.Height())
+	}
+
+	return idx.rootsIndex.fetchUtreexoRoots(block.Height())
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer adds the filter header of the
+// block, committing to its basic filter and the utreexo roots after it.
+//
+// This is part of the Indexer interface.
+func (idx *UtreexoCFIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	prevScripts := make([][]byte, len(stxos))
+	for i, stxo := range stxos {
+		prevScripts[i] = stxo.PkScript
+	}
+
+	f, err := builder.BuildBasicFilter(block.MsgBlock(), prevScripts)
+	if err != nil {
+		return err
+	}
+	filterHash, err := builder.GetFilterHash(f)
+	if err != nil {
+		return err
+	}
+
+	roots, numLeaves, err := idx.fetchUtreexoRoots(dbTx, block)
+	if err != nil {
+		return err
+	}
+	rootsHash, err := UtreexoRootsHash(numLeaves, roots)
+	if err != nil {
+		return err
+	}
+
+	// The genesis block has no previous header so all zeros are used
+	// instead like it's done for the BIP-157 filter headers.
+	var prevHeader chainhash.Hash
+	prevBlock := &block.MsgBlock().Header.PrevBlock
+	if *prevBlock != (chainhash.Hash{}) {
+		prevEntry, err := dbFetchUtreexoCFEntry(dbTx, prevBlock)
+		if err != nil {
+			return err
+		}
+		if prevEntry == nil {
+			return fmt.Errorf("the utreexo committed filter header of "+
+				"the previous block %v is missing", prevBlock)
+		}
+		prevHeader = prevEntry.Header
+	}
+
+	return dbPutUtreexoCFEntry(dbTx, block.Hash(), &UtreexoCFHeader{
+		Header:     MakeUtreexoCFHeader(&filterHash, &rootsHash, &prevHeader),
+		FilterHash: filterHash,
+		RootsHash:  rootsHash,
+	})
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the filter header of
+// the block.
+//
+// This is part of the Indexer interface.
+func (idx *UtreexoCFIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	_ []blockchain.SpentTxOut) error {
+
+	return dbTx.Metadata().Bucket(utreexoCFIndexKey).Delete(block.Hash()[:])
+}
+
+// PruneBlock is invoked when an older block is deleted after it's been
+// processed.
+// NOTE: For UtreexoCFIndex, it's a no-op as UtreexoCFIndex isn't allowed to be
+// enabled with pruning since it can't be caught up without the blocks.
+//
+// This is part of the Indexer interface.
+func (idx *UtreexoCFIndex) PruneBlock(dbTx database.Tx, blockHash *chainhash.Hash) error {
+	return nil
+}
+
+// FetchHeader returns the utreexo committed filter header index entry of the
+// block with the given hash.  When the block isn't indexed, nil is returned for
+// both the entry and the error.
+//
+// This function is safe for concurrent access.
+func (idx *UtreexoCFIndex) FetchHeader(hash *chainhash.Hash) (*UtreexoCFHeader, error) {
+	var entry *UtreexoCFHeader
+	err := idx.db.View(func(dbTx database.Tx) error {
+		var err error
+		entry, err = dbFetchUtreexoCFEntry(dbTx, hash)
+		return err
+	})
+	return entry, err
+}
+
+// NewUtreexoCFIndex returns a new instance of an indexer that is used to create
+// a chain of filter headers committing to the basic filters and the utreexo
+// roots of all the blocks in the main chain.  The roots are fetched from the
+// passed utreexo proof index or, if both are nil, from the utreexo viewpoints
+// stored by compact state nodes.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewUtreexoCFIndex(db database.DB, utreexoProofIndex *UtreexoProofIndex,
+	flatUtreexoProofIndex *FlatUtreexoProofIndex) *UtreexoCFIndex {
+
+	idx := &UtreexoCFIndex{db: db}
+	switch {
+	case utreexoProofIndex != nil:
+		idx.rootsIndex = utreexoProofIndex
+	case flatUtreexoProofIndex != nil:
+		idx.rootsIndex = flatUtreexoProofIndex
+	}
+	return idx
+}
+
+// DropUtreexoCFIndex drops the utreexo committed filter header index from the
+// provided database if it exists.
+func DropUtreexoCFIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, utreexoCFIndexKey, utreexoCFIndexName, interrupt)
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"os"
+	"testing"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/btcutil/gcs/builder"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// testRootsIndex is a utreexo roots index that returns canned roots for the
+// heights of the blocks.
+type testRootsIndex struct {
+	SpendIndex
+	roots map[int32][]*chainhash.Hash
+	tip   int32
+}
+
+func (idx *testRootsIndex) FetchCurrentUtreexoState() ([]*chainhash.Hash, uint64) {
+	return idx.roots[idx.tip], uint64(len(idx.roots[idx.tip]))
+}
+
+func (idx *testRootsIndex) fetchUtreexoRoots(height int32) ([]*chainhash.Hash, uint64, error) {
+	return idx.roots[height], uint64(len(idx.roots[height])), nil
+}
+
+func (idx *testRootsIndex) utreexoRootsAfter(block *btcutil.Block,
+	_ []blockchain.SpentTxOut) ([]*chainhash.Hash, uint64, error) {
+
+	return idx.fetchUtreexoRoots(block.Height())
+}
+
+// TestUtreexoCFIndex ensures that the utreexo committed filter headers commit
+// to the basic filter, the utreexo roots and the previous header of a block.
+func TestUtreexoCFIndex(t *testing.T) {
+	db, dbPath, err := createDB("utreexocfindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	rootsIndex := &testRootsIndex{
+		roots: map[int32][]*chainhash.Hash{
+			0: nil,
+			1: {{0x01}},
+		},
+	}
+	idx := &UtreexoCFIndex{db: db, rootsIndex: rootsIndex}
+	err = db.Update(func(dbTx database.Tx) error {
+		_, err := dbTx.Metadata().CreateBucket(indexTipsBucketName)
+		if err != nil {
+			return err
+		}
+		return idx.Create(dbTx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newBlock := func(prevBlock *chainhash.Hash, height int32) *btcutil.Block {
+		coinbase := wire.NewMsgTx(1)
+		coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex},
+			[]byte{byte(height)}, nil))
+		coinbase.AddTxOut(wire.NewTxOut(5000, []byte{txscript.OP_TRUE}))
+		block := btcutil.NewBlock(&wire.MsgBlock{
+			Header:       wire.BlockHeader{PrevBlock: *prevBlock},
+			Transactions: []*wire.MsgTx{coinbase},
+		})
+		block.SetHeight(height)
+		return block
+	}
+	genesis := newBlock(&chainhash.Hash{}, 0)
+	block := newBlock(genesis.Hash(), 1)
+
+	// The genesis block is connected right after the roots index while the
+	// next block is connected after the roots index has moved past it.
+	for _, test := range []struct {
+		block *btcutil.Block
+		tip   int32
+	}{
+		{genesis, 0},
+		{block, 2},
+	} {
+		rootsIndex.tip = test.tip
+		err = db.Update(func(dbTx database.Tx) error {
+			err := dbPutIndexerTip(dbTx, rootsIndex.Key(),
+				&chainhash.Hash{}, test.tip)
+			if err != nil {
+				return err
+			}
+			return idx.ConnectBlock(dbTx, test.block, nil)
+		})
+		if err != nil {
+			t.Fatalf("ConnectBlock: unexpected error: %v", err)
+		}
+	}
+
+	var prevHeader chainhash.Hash
+	for _, b := range []*btcutil.Block{genesis, block} {
+		f, err := builder.BuildBasicFilter(b.MsgBlock(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filterHash, err := builder.GetFilterHash(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots := rootsIndex.roots[b.Height()]
+		rootsHash, err := UtreexoRootsHash(uint64(len(roots)), roots)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := UtreexoCFHeader{
+			Header:     MakeUtreexoCFHeader(&filterHash, &rootsHash, &prevHeader),
+			FilterHash: filterHash,
+			RootsHash:  rootsHash,
+		}
+
+		entry, err := idx.FetchHeader(b.Hash())
+		if err != nil {
+			t.Fatalf("FetchHeader: unexpected error: %v", err)
+		}
+		if entry == nil || *entry != want {
+			t.Fatalf("FetchHeader(%v): got %v, want %v", b.Hash(),
+				entry, want)
+		}
+		prevHeader = want.Header
+	}
+
+	// Blocks can't be connected when the roots index is behind them.
+	err = db.Update(func(dbTx database.Tx) error {
+		err := dbPutIndexerTip(dbTx, rootsIndex.Key(), &chainhash.Hash{}, 1)
+		if err != nil {
+			return err
+		}
+		return idx.ConnectBlock(dbTx, newBlock(block.Hash(), 2), nil)
+	})
+	if err == nil {
+		t.Fatal("ConnectBlock: expected an error when the roots index " +
+			"is behind")
+	}
+
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.DisconnectBlock(dbTx, block, nil)
+	})
+	if err != nil {
+		t.Fatalf("DisconnectBlock: unexpected error: %v", err)
+	}
+	entry, err := idx.FetchHeader(block.Hash())
+	if err != nil {
+		t.Fatalf("FetchHeader: unexpected error: %v", err)
+	}
+	if entry != nil {
+		t.Fatalf("FetchHeader: got %v after the block was disconnected",
+			entry)
+	}
+}
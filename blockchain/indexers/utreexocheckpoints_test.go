@@ -0,0 +1,205 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/txscript"
+)
+
+// checkpointTestChain creates a bridge chain, or a chain using the compact
+// utreexo state if csn is true, which checks the accumulator against the
+// utreexo root checkpoints.
+func checkpointTestChain(testName string, csn bool, params *chaincfg.Params,
+	checkpoints []blockchain.UtreexoRootCheckpoint) (
+	*blockchain.BlockChain, *UtreexoProofIndex, func(), error) {
+
+	db, dbPath, err := createDB(testName)
+	tearDown := func() {
+		db.Close()
+		os.RemoveAll(dbPath)
+	}
+	if err != nil {
+		return nil, nil, tearDown, err
+	}
+
+	config := blockchain.Config{
+		DB:                     db,
+		ChainParams:            params,
+		TimeSource:             blockchain.NewMedianTime(),
+		SigCache:               txscript.NewSigCache(1000),
+		UtxoCacheMaxSize:       10 * 1024 * 1024,
+		UtreexoRootCheckpoints: checkpoints,
+	}
+	var indexManager *Manager
+	var proofIndex *UtreexoProofIndex
+	if csn {
+		config.UtreexoView = blockchain.NewUtreexoViewpoint()
+	} else {
+		var indexes []Indexer
+		indexManager, indexes, err = initIndexes(1, dbPath, &db, params)
+		if err != nil {
+			return nil, nil, tearDown, err
+		}
+		config.IndexManager = indexManager
+		proofIndex = indexes[0].(*UtreexoProofIndex)
+	}
+
+	chain, err := blockchain.New(&config)
+	if err != nil {
+		return nil, nil, tearDown, err
+	}
+	if indexManager != nil {
+		err = indexManager.Init(chain, nil)
+		if err != nil {
+			return nil, nil, tearDown, err
+		}
+	}
+
+	return chain, proofIndex, tearDown, nil
+}
+
+// TestUtreexoRootCheckpoints ensures that the blocks which lead to an
+// accumulator not matching the utreexo root checkpoint at their height are
+// rejected with a rule error before the accumulator is modified.
+func TestUtreexoRootCheckpoints(t *testing.T) {
+	// Always remove the root on return.
+	defer os.RemoveAll(testDbRoot)
+
+	source, indexes, params, _, tearDown := indexersTestChain(
+		"TestUtreexoRootCheckpoints", 1)
+	defer tearDown()
+
+	// Create a chain with 10 blocks where each block spends the outputs
+	// of the previous one.
+	tip := btcutil.NewBlock(params.GenesisBlock)
+	var spends []*blockchain.SpendableOut
+	for i := 0; i < 10; i++ {
+		var err error
+		tip, spends, err = blockchain.AddBlock(source, tip, spends)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const checkpointHeight = 5
+	sourceIndex := indexes[0].(*UtreexoProofIndex)
+	fetchRoots := func(height int32) ([]*chainhash.Hash, uint64) {
+		roots, numLeaves, err := sourceIndex.fetchUtreexoRoots(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return roots, numLeaves
+	}
+	roots, numLeaves := fetchRoots(checkpointHeight)
+	blockHash, err := source.BlockHashByHeight(checkpointHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := blockchain.UtreexoRootCheckpoint{
+		Height:    checkpointHeight,
+		BlockHash: *blockHash,
+		NumLeaves: numLeaves,
+	}
+	for _, root := range roots {
+		good.Roots = append(good.Roots, *root)
+	}
+	bad := good
+	bad.Roots = append([]chainhash.Hash{{1}}, good.Roots[1:]...)
+
+	tests := []struct {
+		name       string
+		csn        bool
+		checkpoint blockchain.UtreexoRootCheckpoint
+		valid      bool
+	}{
+		{"bridge matching", false, good, true},
+		{"bridge mismatching", false, bad, false},
+		{"csn matching", true, good, true},
+		{"csn mismatching", true, bad, false},
+	}
+
+	for _, test := range tests {
+		chain, proofIndex, tearDown, err := checkpointTestChain(
+			"TestUtreexoRootCheckpoints-"+test.name, test.csn, params,
+			[]blockchain.UtreexoRootCheckpoint{test.checkpoint})
+		if err != nil {
+			tearDown()
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		currentRoots := func() ([]*chainhash.Hash, uint64) {
+			if test.csn {
+				uview := chain.GetUtreexoView()
+				return uview.GetRoots(), uview.NumLeaves()
+			}
+			return proofIndex.FetchCurrentUtreexoState()
+		}
+
+		for height := int32(1); height <= 10; height++ {
+			block, err := source.BlockByHeight(height)
+			if err != nil {
+				tearDown()
+				t.Fatal(err)
+			}
+			if test.csn {
+				block.MsgBlock().UData, err = sourceIndex.FetchUtreexoProof(block.Hash())
+				if err != nil {
+					tearDown()
+					t.Fatal(err)
+				}
+			}
+
+			_, _, err = chain.ProcessBlock(btcutil.NewBlock(block.MsgBlock()),
+				blockchain.BFNone)
+			if test.valid || height < checkpointHeight {
+				if err != nil {
+					tearDown()
+					t.Fatalf("%s: ProcessBlock at height %d: "+
+						"unexpected error: %v", test.name, height, err)
+				}
+				continue
+			}
+
+			rErr, ok := err.(blockchain.RuleError)
+			if !ok || rErr.ErrorCode != blockchain.ErrBadCheckpoint {
+				tearDown()
+				t.Fatalf("%s: ProcessBlock at height %d: got error %v, "+
+					"want %v", test.name, height, err,
+					blockchain.ErrBadCheckpoint)
+			}
+
+			// The accumulator must be left at the state of the
+			// parent of the rejected block.
+			wantRoots, wantNumLeaves := fetchRoots(height - 1)
+			gotRoots, gotNumLeaves := currentRoots()
+			if !reflect.DeepEqual(gotRoots, wantRoots) ||
+				gotNumLeaves != wantNumLeaves {
+				tearDown()
+				t.Fatalf("%s: the accumulator was modified by the "+
+					"rejected block at height %d", test.name, height)
+			}
+			break
+		}
+
+		if test.valid {
+			wantRoots, wantNumLeaves := fetchRoots(10)
+			gotRoots, gotNumLeaves := currentRoots()
+			if !reflect.DeepEqual(gotRoots, wantRoots) ||
+				gotNumLeaves != wantNumLeaves {
+				tearDown()
+				t.Fatalf("%s: got an accumulator with %d leaves, "+
+					"want %d", test.name, gotNumLeaves, wantNumLeaves)
+			}
+		}
+		tearDown()
+	}
+}
@@ -204,6 +204,14 @@ func (idx *UtreexoProofIndex) Key() []byte {
 	return utreexoParentBucketKey
 }
 
+// extraSize returns the size of the utreexo state kept outside of the block
+// database.
+//
+// This is part of the extraSizer interface.
+func (idx *UtreexoProofIndex) extraSize(dbTx database.Tx) (int64, error) {
+	return pathSize(utreexoBasePath(idx.utreexoState.config))
+}
+
 // Create is invoked when the indexer manager determines the index needs
 // to be created for the first time.  It creates the bucket for the utreexo proof
 // index.
@@ -279,10 +287,7 @@ func (idx *UtreexoProofIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Bloc
 		return err
 	}
 
-	delHashes := make([]utreexo.Hash, len(ud.LeafDatas))
-	for i := range delHashes {
-		delHashes[i] = ud.LeafDatas[i].LeafHash()
-	}
+	delHashes := wire.LeafHashes(ud.LeafDatas)
 
 	// For pruned nodes, the undo data is necessary for reorgs.
 	if idx.pruned {
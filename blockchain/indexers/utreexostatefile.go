@@ -0,0 +1,216 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// utreexoStateVersion is the current version of the on-disk utreexo
+	// state.  It must be bumped whenever the format of the forest file or
+	// the nodes and cached leaves databases changes and a matching
+	// migration must be added to utreexoStateMigrations.
+	utreexoStateVersion = 1
+
+	// legacyForestFileSize is the size of a forest file that was written
+	// before the version header was introduced.  These files only contain
+	// the numLeaves serialized as a little endian uint64.
+	legacyForestFileSize = 8
+
+	// forestFileHeaderSize is the size of the header that's prefixed to
+	// every versioned forest file.  It's the magic bytes followed by the
+	// version as a little endian uint32.
+	forestFileHeaderSize = len(forestFileMagic) + 4
+)
+
+// forestFileMagic are the bytes that every versioned forest file starts with.
+// It allows a versioned file to be told apart from a legacy one.
+var forestFileMagic = [4]byte{'u', 't', 'x', 'f'}
+
+// forestState is the data that's stored in the forest file.
+type forestState struct {
+	// numLeaves is the number of leaves that were ever added to the
+	// accumulator.
+	numLeaves uint64
+}
+
+// utreexoStateMigration describes an upgrade of the on-disk utreexo state from
+// the previous version to the version specified in the migration.
+type utreexoStateMigration struct {
+	// version is the version the utreexo state will be at after the
+	// migration is applied.
+	version uint32
+
+	// description is a human-readable description of the migration that's
+	// logged when the migration is applied.
+	description string
+
+	// migrate performs the migration.  It's passed in the base path of the
+	// utreexo state along with the payload of the forest file, that is the
+	// forest file without the header, and returns the payload for the new
+	// version.  Migrations are run before the nodes and the cached leaves
+	// databases are opened so they're free to rewrite those as well.
+	migrate func(basePath string, payload []byte) ([]byte, error)
+}
+
+// utreexoStateMigrations are all the migrations of the utreexo state, ordered
+// by the version they upgrade to.
+var utreexoStateMigrations = []utreexoStateMigration{
+	{
+		version:     1,
+		description: "add a version header to the forest file",
+		migrate: func(_ string, payload []byte) ([]byte, error) {
+			// The payload is unchanged.  The header is added when
+			// the migrated file is written out.
+			if len(payload) != legacyForestFileSize {
+				return nil, fmt.Errorf("expected a legacy forest "+
+					"file of %d bytes but got %d bytes",
+					legacyForestFileSize, len(payload))
+			}
+			return payload, nil
+		},
+	},
+}
+
+// serializeForestState serializes the forest state into the payload of the
+// forest file for the current utreexoStateVersion.
+func serializeForestState(state forestState) []byte {
+	var buf [8]byte
+	byteOrder.PutUint64(buf[:], state.numLeaves)
+	return buf[:]
+}
+
+// deserializeForestState deserializes the payload of the forest file for the
+// current utreexoStateVersion.
+func deserializeForestState(payload []byte) (forestState, error) {
+	if len(payload) < 8 {
+		return forestState{}, fmt.Errorf("forest file payload is %d "+
+			"bytes, too short to contain numLeaves", len(payload))
+	}
+
+	return forestState{numLeaves: byteOrder.Uint64(payload[:8])}, nil
+}
+
+// parseForestFile returns the version and the payload of the serialized forest
+// file.  Forest files without a version header are reported as version 0.
+func parseForestFile(serialized []byte) (uint32, []byte, error) {
+	if len(serialized) == legacyForestFileSize {
+		return 0, serialized, nil
+	}
+
+	if len(serialized) < forestFileHeaderSize ||
+		!bytes.Equal(serialized[:len(forestFileMagic)], forestFileMagic[:]) {
+
+		return 0, nil, fmt.Errorf("forest file of %d bytes is neither "+
+			"a legacy nor a versioned forest file", len(serialized))
+	}
+
+	version := byteOrder.Uint32(serialized[len(forestFileMagic):forestFileHeaderSize])
+	return version, serialized[forestFileHeaderSize:], nil
+}
+
+// writeForestFile writes the header for the current utreexoStateVersion and the
+// given payload to the forest file under the basePath.  The file is first
+// written to a temporary file and then renamed so that a crash mid-write never
+// leaves behind a partially written forest file.
+func writeForestFile(basePath string, payload []byte) error {
+	err := os.MkdirAll(basePath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, forestFileHeaderSize, forestFileHeaderSize+len(payload))
+	copy(buf, forestFileMagic[:])
+	byteOrder.PutUint32(buf[len(forestFileMagic):], utreexoStateVersion)
+	buf = append(buf, payload...)
+
+	forestFilePath := filepath.Join(basePath, defaultUtreexoFileName)
+	tmpPath := forestFilePath + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(buf)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, forestFilePath)
+}
+
+// migrateUtreexoState upgrades the utreexo state under the basePath to the
+// current utreexoStateVersion.  It returns the payload of the forest file at
+// the current version.  An error is returned if the utreexo state was written
+// by a newer version of the software.
+func migrateUtreexoState(basePath string, serialized []byte) ([]byte, error) {
+	version, payload, err := parseForestFile(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > utreexoStateVersion {
+		return nil, fmt.Errorf("the utreexo state at %s is at version "+
+			"%d which is newer than the latest supported version %d. "+
+			"Downgrading is not supported", basePath, version,
+			utreexoStateVersion)
+	}
+
+	if version == utreexoStateVersion {
+		return payload, nil
+	}
+
+	for _, migration := range utreexoStateMigrations {
+		if migration.version <= version {
+			continue
+		}
+
+		log.Infof("Upgrading the utreexo state at %s to version %d: %s",
+			basePath, migration.version, migration.description)
+		payload, err = migration.migrate(basePath, payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade the utreexo "+
+				"state to version %d: %v", migration.version, err)
+		}
+	}
+
+	err = writeForestFile(basePath, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// readForestFile reads the forest state from the forest file under the
+// basePath, applying any migrations that are needed to bring it up to the
+// current utreexoStateVersion.
+func readForestFile(basePath string) (forestState, error) {
+	forestFilePath := filepath.Join(basePath, defaultUtreexoFileName)
+	serialized, err := os.ReadFile(forestFilePath)
+	if err != nil {
+		return forestState{}, err
+	}
+
+	payload, err := migrateUtreexoState(basePath, serialized)
+	if err != nil {
+		return forestState{}, err
+	}
+
+	return deserializeForestState(payload)
+}
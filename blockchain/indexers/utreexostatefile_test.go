@@ -0,0 +1,83 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestForestFileMigration(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	forestFilePath := filepath.Join(tmpDir, defaultUtreexoFileName)
+
+	// Write a legacy forest file that only has the numLeaves.
+	var legacy [legacyForestFileSize]byte
+	byteOrder.PutUint64(legacy[:], 1234)
+	err := os.WriteFile(forestFilePath, legacy[:], 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fState, err := readForestFile(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fState.numLeaves != 1234 {
+		t.Fatalf("expected numLeaves of %d but got %d", 1234, fState.numLeaves)
+	}
+
+	// The file should've been rewritten with the version header.
+	serialized, err := os.ReadFile(forestFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, payload, err := parseForestFile(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != utreexoStateVersion {
+		t.Fatalf("expected version %d but got %d", utreexoStateVersion, version)
+	}
+	if len(payload) != legacyForestFileSize {
+		t.Fatalf("expected payload of %d bytes but got %d",
+			legacyForestFileSize, len(payload))
+	}
+
+	// Reading it again shouldn't change anything.
+	fState, err = readForestFile(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fState.numLeaves != 1234 {
+		t.Fatalf("expected numLeaves of %d but got %d", 1234, fState.numLeaves)
+	}
+
+	// A state from a newer version must be refused.
+	serialized = make([]byte, forestFileHeaderSize+8)
+	copy(serialized, forestFileMagic[:])
+	byteOrder.PutUint32(serialized[len(forestFileMagic):], utreexoStateVersion+1)
+	err = os.WriteFile(forestFilePath, serialized, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = readForestFile(tmpDir)
+	if err == nil {
+		t.Fatalf("expected an error for a forest file from a newer version")
+	}
+
+	// Garbage must be refused as well.
+	err = os.WriteFile(forestFilePath, []byte{1, 2, 3}, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = readForestFile(tmpDir)
+	if err == nil {
+		t.Fatalf("expected an error for a malformed forest file")
+	}
+}
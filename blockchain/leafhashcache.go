@@ -0,0 +1,119 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// maxLeafHashCacheEntries is the maximum number of leaf hashes that are kept by
+// the leaf hash cache.  An entry is around 150 bytes so the cache stays below
+// 20MB.
+const maxLeafHashCacheEntries = 125000
+
+// leafHashEntry is a leaf along with its hash.
+type leafHashEntry struct {
+	leaf wire.LeafData
+	hash utreexo.Hash
+}
+
+// leafHashCache memoizes the leaf hashes of the utxos that are spent by the
+// transactions in the mempool.  The hashes are computed when the proofs of the
+// transactions are verified and are reused when the block that includes them
+// is connected instead of serializing and hashing the leaves again.
+//
+// A hash is only reused if the leaf it was computed for is identical to the
+// leaf that's being hashed.  The leaves of a block come from the peer that sent
+// it so they could differ from the ones of the mempool transactions.
+type leafHashCache struct {
+	mtx     sync.Mutex
+	entries map[wire.OutPoint]leafHashEntry
+}
+
+// newLeafHashCache returns an empty leaf hash cache.
+func newLeafHashCache() *leafHashCache {
+	return &leafHashCache{
+		entries: make(map[wire.OutPoint]leafHashEntry),
+	}
+}
+
+// leafDataEqual returns true if both leaves commit to the same hash.
+func leafDataEqual(a, b *wire.LeafData) bool {
+	return a.BlockHash == b.BlockHash &&
+		a.OutPoint == b.OutPoint &&
+		a.Height == b.Height &&
+		a.IsCoinBase == b.IsCoinBase &&
+		a.Amount == b.Amount &&
+		bytes.Equal(a.PkScript, b.PkScript)
+}
+
+// add memoizes the hash of the leaf.  An arbitrary entry is evicted if the
+// cache is full.
+//
+// This function is safe for concurrent access.
+func (c *leafHashCache) add(leaf *wire.LeafData, hash utreexo.Hash) {
+	if c == nil {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if _, exists := c.entries[leaf.OutPoint]; !exists &&
+		len(c.entries) >= maxLeafHashCacheEntries {
+
+		for op := range c.entries {
+			delete(c.entries, op)
+			break
+		}
+	}
+
+	entry := leafHashEntry{leaf: *leaf, hash: hash}
+	entry.leaf.PkScript = append([]byte(nil), leaf.PkScript...)
+	c.entries[leaf.OutPoint] = entry
+}
+
+// leafHashes returns the leaf hashes of the passed in leaves.  The memoized
+// hashes are used for the leaves that are in the cache and the rest are hashed.
+// The leaves are removed from the cache if remove is true, which is the case
+// once they're spent or no longer referenced by the mempool.
+//
+// A nil cache hashes all the leaves.
+//
+// This function is safe for concurrent access.
+func (c *leafHashCache) leafHashes(leaves []wire.LeafData, remove bool) []utreexo.Hash {
+	if c == nil {
+		return wire.LeafHashes(leaves)
+	}
+
+	hashes := make([]utreexo.Hash, len(leaves))
+	var missIdxs []int
+	var misses []wire.LeafData
+
+	c.mtx.Lock()
+	for i := range leaves {
+		entry, found := c.entries[leaves[i].OutPoint]
+		if found && remove {
+			delete(c.entries, leaves[i].OutPoint)
+		}
+		if !found || !leafDataEqual(&entry.leaf, &leaves[i]) {
+			missIdxs = append(missIdxs, i)
+			misses = append(misses, leaves[i])
+			continue
+		}
+		hashes[i] = entry.hash
+	}
+	c.mtx.Unlock()
+
+	for i, hash := range wire.LeafHashes(misses) {
+		hashes[missIdxs[i]] = hash
+	}
+
+	return hashes
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+func TestLeafHashCache(t *testing.T) {
+	leaves := make([]wire.LeafData, 3)
+	for i := range leaves {
+		leaves[i] = wire.LeafData{
+			BlockHash: chainhash.Hash{1},
+			OutPoint:  wire.OutPoint{Hash: chainhash.Hash{2}, Index: uint32(i)},
+			Height:    10,
+			Amount:    int64(i + 1),
+			PkScript:  []byte{0x51},
+		}
+	}
+
+	// Memoize bogus hashes so that a hit can be told apart from a hash
+	// that was computed.
+	cache := newLeafHashCache()
+	bogus := utreexo.Hash{0xff}
+	cache.add(&leaves[0], bogus)
+	cache.add(&leaves[1], bogus)
+
+	// A leaf that's different from the memoized one must be hashed.
+	tampered := leaves[1]
+	tampered.Amount++
+
+	hashes := cache.leafHashes([]wire.LeafData{leaves[0], tampered, leaves[2]}, false)
+	if hashes[0] != bogus {
+		t.Fatalf("expected the memoized hash for the first leaf")
+	}
+	if hashes[1] != tampered.LeafHash() {
+		t.Fatalf("expected the hash of the tampered leaf to be computed")
+	}
+	if hashes[2] != leaves[2].LeafHash() {
+		t.Fatalf("expected the hash of the third leaf to be computed")
+	}
+
+	// The hashes must stay in the cache unless they're removed.
+	hashes = cache.leafHashes(leaves[:1], true)
+	if hashes[0] != bogus {
+		t.Fatalf("expected the memoized hash for the first leaf")
+	}
+	hashes = cache.leafHashes(leaves[:1], true)
+	if hashes[0] != leaves[0].LeafHash() {
+		t.Fatalf("expected the first leaf to be removed from the cache")
+	}
+
+	// A nil cache hashes all the leaves.
+	var nilCache *leafHashCache
+	nilCache.add(&leaves[0], bogus)
+	hashes = nilCache.leafHashes(leaves, true)
+	for i := range leaves {
+		if hashes[i] != leaves[i].LeafHash() {
+			t.Fatalf("expected the hash of leaf %d to be computed", i)
+		}
+	}
+}
+
+func TestLeafHashCacheEviction(t *testing.T) {
+	cache := newLeafHashCache()
+	for i := 0; i < maxLeafHashCacheEntries+10; i++ {
+		leaf := wire.LeafData{
+			BlockHash: chainhash.Hash{1},
+			OutPoint:  wire.OutPoint{Index: uint32(i)},
+		}
+		cache.add(&leaf, utreexo.Hash{})
+	}
+
+	if len(cache.entries) != maxLeafHashCacheEntries {
+		t.Fatalf("expected %d entries but got %d",
+			maxLeafHashCacheEntries, len(cache.entries))
+	}
+}
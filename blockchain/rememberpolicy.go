@@ -0,0 +1,126 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/utreexo/utreexod/wire"
+)
+
+// RememberPolicy decides which of the leaves that are added to the accumulator
+// of a compact state node are cached.  Cached leaves don't need a proof when
+// they're spent, so caching more leaves uses more memory but less bandwidth.
+type RememberPolicy interface {
+	// Remember returns true if the leaf created in the block with the given
+	// header should be cached.  bridgeRemember is true if the bridge that
+	// sent the block marked the leaf to be remembered.
+	Remember(leaf *wire.LeafData, header *wire.BlockHeader, bridgeRemember bool) bool
+}
+
+// AlwaysRememberPolicy caches every leaf.
+type AlwaysRememberPolicy struct{}
+
+// Remember returns true for every leaf.
+//
+// This is part of the RememberPolicy interface.
+func (AlwaysRememberPolicy) Remember(*wire.LeafData, *wire.BlockHeader, bool) bool {
+	return true
+}
+
+// AgeRememberPolicy caches the leaves created in blocks that are at most MaxAge
+// old.  This keeps the cache empty during the initial block download and only
+// caches leaves once the node is close to the tip, where the proofs for the
+// transactions relayed by peers are needed.
+type AgeRememberPolicy struct {
+	MaxAge time.Duration
+}
+
+// Remember returns true if the block the leaf was created in isn't older than
+// MaxAge.
+//
+// This is part of the RememberPolicy interface.
+func (p AgeRememberPolicy) Remember(_ *wire.LeafData, header *wire.BlockHeader, _ bool) bool {
+	return time.Since(header.Timestamp) <= p.MaxAge
+}
+
+// AmountRememberPolicy caches the leaves that are worth at least MinAmount
+// satoshis.
+type AmountRememberPolicy struct {
+	MinAmount int64
+}
+
+// Remember returns true if the leaf is worth at least MinAmount.
+//
+// This is part of the RememberPolicy interface.
+func (p AmountRememberPolicy) Remember(leaf *wire.LeafData, _ *wire.BlockHeader, _ bool) bool {
+	return leaf.Amount >= p.MinAmount
+}
+
+// TTLRememberPolicy caches the leaves that the bridge marked to be remembered.
+// As the bridge knows when every leaf is going to be spent, these are the leaves
+// that are spent soon after they're created.  It's the default policy.
+type TTLRememberPolicy struct{}
+
+// Remember returns true if the bridge marked the leaf to be remembered.
+//
+// This is part of the RememberPolicy interface.
+func (TTLRememberPolicy) Remember(_ *wire.LeafData, _ *wire.BlockHeader, bridgeRemember bool) bool {
+	return bridgeRemember
+}
+
+// SpentRememberPolicy caches the leaves whose outputs IsSpent reports as spent
+// by unconfirmed transactions along with the leaves the wrapped policy caches.
+// The proofs of the mempool transactions that spend the outputs of a connected
+// block are then kept up to date by the accumulator as it's modified instead of
+// having to be reproven.  The TTL policy is wrapped if RememberPolicy is nil.
+type SpentRememberPolicy struct {
+	RememberPolicy
+
+	// IsSpent returns true if the passed output is spent by an unconfirmed
+	// transaction.  It's called while the chain lock is held.
+	IsSpent func(op *wire.OutPoint) bool
+}
+
+// Remember returns true if the leaf is spent by an unconfirmed transaction or if
+// the wrapped policy caches it.
+//
+// This is part of the RememberPolicy interface.
+func (p SpentRememberPolicy) Remember(leaf *wire.LeafData, header *wire.BlockHeader, bridgeRemember bool) bool {
+	if p.IsSpent != nil && p.IsSpent(&leaf.OutPoint) {
+		return true
+	}
+	if p.RememberPolicy == nil {
+		return bridgeRemember
+	}
+	return p.RememberPolicy.Remember(leaf, header, bridgeRemember)
+}
+
+// Names of the built-in remember policies.
+const (
+	RememberPolicyAlways = "always"
+	RememberPolicyAge    = "age"
+	RememberPolicyAmount = "amount"
+	RememberPolicyTTL    = "ttl"
+)
+
+// NewRememberPolicy returns the built-in remember policy with the given name.
+// maxAge is only used by the age policy and minAmount only by the amount
+// policy.
+func NewRememberPolicy(name string, maxAge time.Duration, minAmount int64) (RememberPolicy, error) {
+	switch name {
+	case RememberPolicyAlways:
+		return AlwaysRememberPolicy{}, nil
+	case RememberPolicyAge:
+		return AgeRememberPolicy{MaxAge: maxAge}, nil
+	case RememberPolicyAmount:
+		return AmountRememberPolicy{MinAmount: minAmount}, nil
+	case RememberPolicyTTL:
+		return TTLRememberPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown remember policy %q", name)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/wire"
+)
+
+func TestRememberPolicies(t *testing.T) {
+	now := time.Now()
+	oldHeader := &wire.BlockHeader{Timestamp: now.Add(-48 * time.Hour)}
+	newHeader := &wire.BlockHeader{Timestamp: now.Add(-time.Hour)}
+	small := &wire.LeafData{Amount: 1000}
+	big := &wire.LeafData{Amount: 100000000}
+
+	tests := []struct {
+		name           string
+		leaf           *wire.LeafData
+		header         *wire.BlockHeader
+		bridgeRemember bool
+		want           bool
+	}{
+		{RememberPolicyAlways, small, oldHeader, false, true},
+		{RememberPolicyAlways, big, newHeader, true, true},
+		{RememberPolicyAge, big, oldHeader, true, false},
+		{RememberPolicyAge, small, newHeader, false, true},
+		{RememberPolicyAmount, small, newHeader, true, false},
+		{RememberPolicyAmount, big, oldHeader, false, true},
+		{RememberPolicyTTL, big, newHeader, false, false},
+		{RememberPolicyTTL, small, oldHeader, true, true},
+	}
+
+	for _, test := range tests {
+		policy, err := NewRememberPolicy(test.name, 24*time.Hour, 100000)
+		if err != nil {
+			t.Fatalf("NewRememberPolicy(%s) failed: %v", test.name, err)
+		}
+
+		got := policy.Remember(test.leaf, test.header, test.bridgeRemember)
+		if got != test.want {
+			t.Errorf("%s: expected %v for amount %d, time %v and bridge "+
+				"remember %v but got %v", test.name, test.want,
+				test.leaf.Amount, test.header.Timestamp,
+				test.bridgeRemember, got)
+		}
+	}
+
+	_, err := NewRememberPolicy("never", 0, 0)
+	if err == nil {
+		t.Fatalf("expected an error for an unknown policy")
+	}
+}
+
+func TestSpentRememberPolicy(t *testing.T) {
+	header := &wire.BlockHeader{Timestamp: time.Now()}
+	spentOp := wire.OutPoint{Index: 1}
+	spent := &wire.LeafData{OutPoint: spentOp}
+	unspent := &wire.LeafData{OutPoint: wire.OutPoint{Index: 2}}
+
+	policy := SpentRememberPolicy{
+		IsSpent: func(op *wire.OutPoint) bool { return *op == spentOp },
+	}
+	if !policy.Remember(spent, header, false) {
+		t.Fatalf("expected the spent leaf to be remembered")
+	}
+	if policy.Remember(unspent, header, false) {
+		t.Fatalf("expected the unspent leaf to not be remembered")
+	}
+	if !policy.Remember(unspent, header, true) {
+		t.Fatalf("expected the leaf the bridge marked to be remembered")
+	}
+
+	policy.RememberPolicy = AlwaysRememberPolicy{}
+	if !policy.Remember(unspent, header, false) {
+		t.Fatalf("expected the wrapped policy to remember the leaf")
+	}
+}
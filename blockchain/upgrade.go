@@ -576,27 +576,24 @@ func upgradeUtxoSetToV2(db database.DB, interrupt <-chan struct{}) error {
 
 // maybeUpgradeDbBuckets checks the database version of the buckets used by this
 // package and performs any needed upgrades to bring them to the latest version.
+// It refuses to go on when any of them was written by a newer release.  Nothing
+// is done for a new database since its chain state is created at the latest
+// versions.
 //
 // All buckets used by this package are guaranteed to be the latest version if
 // this function returns without error.
 func (b *BlockChain) maybeUpgradeDbBuckets(interrupt <-chan struct{}) error {
-	// Load or create bucket versions as needed.
-	var utxoSetVersion uint32
-	err := b.db.Update(func(dbTx database.Tx) error {
-		// Load the utxo set version from the database or create it and
-		// initialize it to version 1 if it doesn't exist.
-		var err error
-		utxoSetVersion, err = dbFetchOrCreateVersion(dbTx,
-			utxoSetVersionKeyName, 1)
-		return err
+	var exists bool
+	err := b.db.View(func(dbTx database.Tx) error {
+		exists = dbTx.Metadata().Get(chainStateKeyName) != nil
+		return nil
 	})
-	if err != nil {
+	if err != nil || !exists {
 		return err
 	}
 
-	// Update the utxo set to v2 if needed.
-	if utxoSetVersion < 2 {
-		if err := upgradeUtxoSetToV2(b.db, interrupt); err != nil {
+	for _, schema := range chainDbSchemas {
+		if err := UpgradeDbSchema(b.db, schema, interrupt); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,305 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+)
+
+// UtreexoRootCheckpoint is a known good state of the utreexo accumulator after
+// the block at Height was connected.
+type UtreexoRootCheckpoint struct {
+	Height    int32
+	BlockHash chainhash.Hash
+	NumLeaves uint64
+	Roots     []chainhash.Hash
+}
+
+// utreexoRootCheckpointJSON is how a utreexo root checkpoint is stored in a
+// checkpoint file.  The roots are in the same format as the ones returned by
+// the getutreexoroots RPC.
+type utreexoRootCheckpointJSON struct {
+	Height    int32    `json:"height"`
+	BlockHash string   `json:"hash"`
+	NumLeaves uint64   `json:"numleaves"`
+	Roots     []string `json:"roots"`
+}
+
+// ParseUtreexoRootCheckpointsHash parses the sha256 of a utreexo root
+// checkpoint file from its hex encoding as printed by sha256sum.  Unlike block
+// hashes, the bytes aren't reversed.
+func ParseUtreexoRootCheckpointsHash(s string) (*[sha256.Size]byte, error) {
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != sha256.Size {
+		return nil, fmt.Errorf("expected %d bytes but got %d",
+			sha256.Size, len(decoded))
+	}
+
+	var fileHash [sha256.Size]byte
+	copy(fileHash[:], decoded)
+	return &fileHash, nil
+}
+
+// LoadUtreexoRootCheckpoints reads the utreexo root checkpoints from the JSON
+// file at the given path.  If fileHash isn't nil, the sha256 of the file must
+// match it so that only a known good file is accepted.
+func LoadUtreexoRootCheckpoints(path string, fileHash *[sha256.Size]byte) (
+	[]UtreexoRootCheckpoint, error) {
+
+	serialized, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fileHash != nil {
+		sum := sha256.Sum256(serialized)
+		if sum != *fileHash {
+			return nil, fmt.Errorf("utreexo root checkpoint file %s has "+
+				"hash %x but expected %x", path, sum, *fileHash)
+		}
+	}
+
+	return parseUtreexoRootCheckpoints(serialized)
+}
+
+// parseUtreexoRootCheckpoints parses the serialized checkpoint file.  The
+// returned checkpoints are sorted by height.
+func parseUtreexoRootCheckpoints(serialized []byte) ([]UtreexoRootCheckpoint, error) {
+	var entries []utreexoRootCheckpointJSON
+	dec := json.NewDecoder(bytes.NewReader(serialized))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("invalid utreexo root checkpoint file: %v", err)
+	}
+
+	checkpoints := make([]UtreexoRootCheckpoint, 0, len(entries))
+	for _, entry := range entries {
+		blockHash, err := chainhash.NewHashFromStr(entry.BlockHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block hash for the utreexo "+
+				"root checkpoint at height %d: %v", entry.Height, err)
+		}
+
+		roots := make([]chainhash.Hash, len(entry.Roots))
+		for i, root := range entry.Roots {
+			decoded, err := hex.DecodeString(root)
+			if err != nil || len(decoded) != chainhash.HashSize {
+				return nil, fmt.Errorf("invalid root %q for the utreexo "+
+					"root checkpoint at height %d", root, entry.Height)
+			}
+			copy(roots[i][:], decoded)
+		}
+
+		checkpoints = append(checkpoints, UtreexoRootCheckpoint{
+			Height:    entry.Height,
+			BlockHash: *blockHash,
+			NumLeaves: entry.NumLeaves,
+			Roots:     roots,
+		})
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Height < checkpoints[j].Height
+	})
+	for i := 1; i < len(checkpoints); i++ {
+		if checkpoints[i].Height == checkpoints[i-1].Height {
+			return nil, fmt.Errorf("duplicate utreexo root checkpoint "+
+				"at height %d", checkpoints[i].Height)
+		}
+	}
+
+	return checkpoints, nil
+}
+
+// UtreexoRootsFetcher is implemented by index managers that manage an index
+// which keeps a utreexo accumulator.  It allows the chain to check the
+// accumulators of bridge nodes against the utreexo root checkpoints.
+type UtreexoRootsFetcher interface {
+	// UtreexoRootsAfter returns the roots and number of leaves the
+	// accumulator kept by the managed indexes will have once the block,
+	// which spends the passed in stxos, is connected.  The accumulator
+	// isn't modified.  The boolean is false if none of the managed
+	// indexes keep an accumulator.
+	UtreexoRootsAfter(block *btcutil.Block, stxos []SpentTxOut) (
+		[]*chainhash.Hash, uint64, bool, error)
+
+	// FetchUtreexoRoots returns the roots and number of leaves of the
+	// accumulator after the block at the given height of the main chain
+	// was connected.  The boolean is false if none of the managed indexes
+	// keep an accumulator.
+	FetchUtreexoRoots(height int32) ([]*chainhash.Hash, uint64, bool, error)
+}
+
+// checkUtreexoRoots returns an error if the passed in accumulator state doesn't
+// match the checkpoint.
+func checkUtreexoRoots(checkpoint *UtreexoRootCheckpoint, roots []*chainhash.Hash,
+	numLeaves uint64) error {
+
+	var reason string
+	switch {
+	case numLeaves != checkpoint.NumLeaves:
+		reason = fmt.Sprintf("has %d leaves instead of %d", numLeaves,
+			checkpoint.NumLeaves)
+
+	case len(roots) != len(checkpoint.Roots):
+		reason = fmt.Sprintf("has %d roots instead of %d", len(roots),
+			len(checkpoint.Roots))
+
+	default:
+		for i := range roots {
+			if !roots[i].IsEqual(&checkpoint.Roots[i]) {
+				reason = fmt.Sprintf("has root %x instead of %x at "+
+					"index %d", roots[i][:], checkpoint.Roots[i][:], i)
+				break
+			}
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+
+	str := fmt.Sprintf("the utreexo accumulator at height %d %s.  The "+
+		"local state is corrupted or the node is being fed a bogus chain",
+		checkpoint.Height, reason)
+	return ruleError(ErrBadCheckpoint, str)
+}
+
+// checkUtreexoRootCheckpointHash returns an error if there's a utreexo root
+// checkpoint at the given height for a block other than the passed in one.
+func (b *BlockChain) checkUtreexoRootCheckpointHash(height int32, hash *chainhash.Hash) error {
+	checkpoint, exists := b.rootCheckpoints[height]
+	if !exists || checkpoint.BlockHash.IsEqual(hash) {
+		return nil
+	}
+
+	str := fmt.Sprintf("block %v at height %d doesn't match the utreexo "+
+		"root checkpoint block %v", hash, height, checkpoint.BlockHash)
+	return ruleError(ErrBadCheckpoint, str)
+}
+
+// checkUtreexoViewRootCheckpoint checks the accumulator the viewpoint will have
+// once the block is connected to it against the utreexo root checkpoint at the
+// height of the block if there is one.  The utreexo data of the block is
+// processed on a copy so the viewpoint is left untouched when it doesn't match.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) checkUtreexoViewRootCheckpoint(uview *UtreexoViewpoint,
+	node *blockNode, block *btcutil.Block) error {
+
+	checkpoint, exists := b.rootCheckpoints[node.height]
+	if !exists {
+		return nil
+	}
+
+	copyUView := uview.CopyWithRoots()
+	err := copyUView.ProcessUData(block, b.bestChain, block.MsgBlock().UData,
+		b.rememberPolicy, nil)
+	if err != nil {
+		return err
+	}
+	err = checkUtreexoRoots(checkpoint, copyUView.GetRoots(),
+		copyUView.NumLeaves())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Verified the utreexo accumulator against the utreexo root "+
+		"checkpoint at height %d", node.height)
+	return nil
+}
+
+// checkIndexRootCheckpoint checks the accumulator of the utreexo proof index
+// once the block, which spends the passed in stxos, is connected to it against
+// the utreexo root checkpoint at the height of the block if there is one.  The
+// index is asked for the state it would have, so it's left untouched when it
+// doesn't match.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) checkIndexRootCheckpoint(node *blockNode, block *btcutil.Block,
+	stxos []SpentTxOut) error {
+
+	checkpoint, exists := b.rootCheckpoints[node.height]
+	if !exists {
+		return nil
+	}
+	fetcher, ok := b.indexManager.(UtreexoRootsFetcher)
+	if !ok {
+		return nil
+	}
+
+	roots, numLeaves, found, err := fetcher.UtreexoRootsAfter(block, stxos)
+	if err != nil || !found {
+		return err
+	}
+	err = checkUtreexoRoots(checkpoint, roots, numLeaves)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Verified the utreexo accumulator against the utreexo root "+
+		"checkpoint at height %d", node.height)
+	return nil
+}
+
+// verifyUtreexoRootCheckpoints checks the main chain against all the utreexo
+// root checkpoints at or below the tip and the accumulator against the latest
+// one of them.  It's called on startup to detect a corrupted local state
+// before the node starts syncing.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) verifyUtreexoRootCheckpoints() error {
+	tip := b.bestChain.Tip()
+
+	var latest *UtreexoRootCheckpoint
+	for height, checkpoint := range b.rootCheckpoints {
+		if height > tip.height {
+			continue
+		}
+		node := b.bestChain.NodeByHeight(height)
+		if node == nil || node.hash != checkpoint.BlockHash {
+			return fmt.Errorf("the main chain doesn't include the "+
+				"utreexo root checkpoint block %v at height %d",
+				checkpoint.BlockHash, height)
+		}
+		if latest == nil || height > latest.Height {
+			latest = checkpoint
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+
+	roots, numLeaves, found, err := b.FetchUtreexoRoots(latest.Height)
+	if err != nil {
+		return err
+	}
+	if !found {
+		log.Warnf("Couldn't find the utreexo accumulator at height %d to "+
+			"verify against the utreexo root checkpoint", latest.Height)
+		return nil
+	}
+
+	err = checkUtreexoRoots(latest, roots, numLeaves)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Verified the utreexo accumulator against the utreexo root "+
+		"checkpoint at height %d", latest.Height)
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+)
+
+const testRootCheckpoints = `[
+  {
+    "height": 20,
+    "hash": "0000000000000000000000000000000000000000000000000000000000000014",
+    "numleaves": 3,
+    "roots": [
+      "0100000000000000000000000000000000000000000000000000000000000000",
+      "0200000000000000000000000000000000000000000000000000000000000000"
+    ]
+  },
+  {
+    "height": 10,
+    "hash": "000000000000000000000000000000000000000000000000000000000000000a",
+    "numleaves": 1,
+    "roots": ["0300000000000000000000000000000000000000000000000000000000000000"]
+  }
+]`
+
+func TestLoadUtreexoRootCheckpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roots.json")
+	err := os.WriteFile(path, []byte(testRootCheckpoints), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileHash := sha256.Sum256([]byte(testRootCheckpoints))
+	checkpoints, err := LoadUtreexoRootCheckpoints(path, &fileHash)
+	if err != nil {
+		t.Fatalf("LoadUtreexoRootCheckpoints failed: %v", err)
+	}
+	if len(checkpoints) != 2 || checkpoints[0].Height != 10 ||
+		checkpoints[1].Height != 20 {
+
+		t.Fatalf("expected checkpoints at heights 10 and 20 sorted by "+
+			"height but got %v", checkpoints)
+	}
+	if checkpoints[1].Roots[1][0] != 2 {
+		t.Fatalf("expected the roots in the getutreexoroots format")
+	}
+
+	// A file that doesn't match the expected hash must be rejected.
+	var wrongHash [sha256.Size]byte
+	_, err = LoadUtreexoRootCheckpoints(path, &wrongHash)
+	if err == nil {
+		t.Fatalf("expected an error for a mismatching file hash")
+	}
+
+	// Duplicate heights must be rejected.
+	_, err = parseUtreexoRootCheckpoints([]byte(`[
+		{"height": 1, "hash": "01", "numleaves": 0, "roots": []},
+		{"height": 1, "hash": "02", "numleaves": 0, "roots": []}
+	]`))
+	if err == nil {
+		t.Fatalf("expected an error for duplicate heights")
+	}
+}
+
+// TestUtreexoRootCheckpointsHash ensures that the hash of a checkpoint file is
+// accepted in the format sha256sum prints it in, and that the byte-reversed
+// format of block hashes and invalid hashes are rejected.
+func TestUtreexoRootCheckpointsHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roots.json")
+	err := os.WriteFile(path, []byte(testRootCheckpoints), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The output of sha256sum for the file.
+	sum := sha256.Sum256([]byte(testRootCheckpoints))
+	sha256sum := hex.EncodeToString(sum[:])
+
+	fileHash, err := ParseUtreexoRootCheckpointsHash(sha256sum)
+	if err != nil {
+		t.Fatalf("ParseUtreexoRootCheckpointsHash failed: %v", err)
+	}
+	if _, err := LoadUtreexoRootCheckpoints(path, fileHash); err != nil {
+		t.Fatalf("LoadUtreexoRootCheckpoints failed for the sha256sum "+
+			"of the file: %v", err)
+	}
+
+	reversed := chainhash.Hash(sum).String()
+	fileHash, err = ParseUtreexoRootCheckpointsHash(reversed)
+	if err != nil {
+		t.Fatalf("ParseUtreexoRootCheckpointsHash failed: %v", err)
+	}
+	if _, err := LoadUtreexoRootCheckpoints(path, fileHash); err == nil {
+		t.Fatalf("expected an error for the byte-reversed file hash")
+	}
+
+	for _, invalid := range []string{"", "zz", sha256sum[2:], sha256sum + "00"} {
+		if _, err := ParseUtreexoRootCheckpointsHash(invalid); err == nil {
+			t.Errorf("expected an error for file hash %q", invalid)
+		}
+	}
+}
+
+func TestCheckUtreexoRoots(t *testing.T) {
+	checkpoint := &UtreexoRootCheckpoint{
+		Height:    10,
+		NumLeaves: 3,
+		Roots:     []chainhash.Hash{{1}, {2}},
+	}
+
+	tests := []struct {
+		name      string
+		roots     []*chainhash.Hash
+		numLeaves uint64
+		valid     bool
+	}{
+		{"match", []*chainhash.Hash{{1}, {2}}, 3, true},
+		{"numleaves", []*chainhash.Hash{{1}, {2}}, 4, false},
+		{"missing root", []*chainhash.Hash{{1}}, 3, false},
+		{"wrong root", []*chainhash.Hash{{1}, {3}}, 3, false},
+	}
+
+	for _, test := range tests {
+		err := checkUtreexoRoots(checkpoint, test.roots, test.numLeaves)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v but got error %v",
+				test.name, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package utreexodiff
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/utreexo/utreexo"
+)
+
+// Accumulator is an accumulator implementation that's checked by the harness.
+type Accumulator interface {
+	// Name returns a human-readable name of the implementation.
+	Name() string
+
+	// Modify applies the deletions and then the additions of the step.
+	Modify(step *Step) error
+
+	// State returns the roots and the number of leaves of the accumulator.
+	State() ([]utreexo.Hash, uint64, error)
+}
+
+// mapPollard is the MapPollard of the utreexo library.  It's the accumulator
+// used by bridge nodes.
+type mapPollard struct {
+	p utreexo.MapPollard
+}
+
+// NewMapPollard returns an Accumulator backed by a full MapPollard.
+func NewMapPollard() Accumulator {
+	return &mapPollard{p: utreexo.NewMapPollard(true)}
+}
+
+// Name returns the name of the implementation.
+func (m *mapPollard) Name() string { return "mappollard" }
+
+// Modify applies the step to the MapPollard.
+func (m *mapPollard) Modify(step *Step) error {
+	return m.p.Modify(toLeaves(step.Adds), toHashes(step.Dels), step.proof())
+}
+
+// State returns the roots and the number of leaves of the MapPollard.
+func (m *mapPollard) State() ([]utreexo.Hash, uint64, error) {
+	return m.p.GetRoots(), m.p.GetNumLeaves(), nil
+}
+
+// pollard is the pointer based Pollard of the utreexo library.
+type pollard struct {
+	p utreexo.Pollard
+}
+
+// NewPollard returns an Accumulator backed by a Pollard.
+func NewPollard() Accumulator {
+	return &pollard{p: utreexo.NewAccumulator()}
+}
+
+// Name returns the name of the implementation.
+func (p *pollard) Name() string { return "pollard" }
+
+// Modify applies the step to the Pollard.
+func (p *pollard) Modify(step *Step) error {
+	return p.p.Modify(toLeaves(step.Adds), toHashes(step.Dels), step.proof())
+}
+
+// State returns the roots and the number of leaves of the Pollard.
+func (p *pollard) State() ([]utreexo.Hash, uint64, error) {
+	return p.p.GetRoots(), p.p.GetNumLeaves(), nil
+}
+
+// stump is the Stump of the utreexo library.  It's the accumulator used by
+// compact state nodes.
+type stump struct {
+	s utreexo.Stump
+}
+
+// NewStump returns an Accumulator backed by a Stump.
+func NewStump() Accumulator {
+	return &stump{}
+}
+
+// Name returns the name of the implementation.
+func (s *stump) Name() string { return "stump" }
+
+// Modify verifies the proof of the step and applies it to the Stump.
+func (s *stump) Modify(step *Step) error {
+	_, err := s.s.Update(toHashes(step.Dels), toHashes(step.Adds), step.proof())
+	return err
+}
+
+// State returns the roots and the number of leaves of the Stump.
+func (s *stump) State() ([]utreexo.Hash, uint64, error) {
+	return s.s.Roots, s.s.NumLeaves, nil
+}
+
+// toLeaves converts the hashes into leaves that are all remembered.
+func toLeaves(hashes []Hash) []utreexo.Leaf {
+	leaves := make([]utreexo.Leaf, len(hashes))
+	for i := range hashes {
+		leaves[i] = utreexo.Leaf{Hash: utreexo.Hash(hashes[i]), Remember: true}
+	}
+	return leaves
+}
+
+// externalRequest is a step as it's sent to an external implementation.
+type externalRequest struct {
+	Adds    []Hash   `json:"adds"`
+	Dels    []Hash   `json:"dels"`
+	Targets []uint64 `json:"targets"`
+	Proof   []Hash   `json:"proof"`
+}
+
+// externalResponse is the state an external implementation replies with.
+type externalResponse struct {
+	Roots     []Hash `json:"roots"`
+	NumLeaves uint64 `json:"numleaves"`
+	Error     string `json:"error,omitempty"`
+}
+
+// External is an accumulator implementation that runs in a separate process.
+//
+// The process reads one JSON encoded externalRequest per line from stdin,
+// applies it to its accumulator and writes one JSON encoded externalResponse
+// per line to stdout with the state after the modification.  This allows
+// implementations in other languages, such as rustreexo, to be checked without
+// linking them into the node.
+type External struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+
+	roots     []utreexo.Hash
+	numLeaves uint64
+}
+
+// NewExternal starts the given command and returns an Accumulator that forwards
+// every step to it.  Close must be called to stop the process.
+func NewExternal(name string, path string, args ...string) (*External, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, 64*1024*1024)
+	return &External{
+		name:   name,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: scanner,
+	}, nil
+}
+
+// Name returns the name of the implementation.
+func (e *External) Name() string { return e.name }
+
+// Modify sends the step to the external process and waits for the state after
+// the modification.
+func (e *External) Modify(step *Step) error {
+	req, err := json.Marshal(externalRequest{
+		Adds:    step.Adds,
+		Dels:    step.Dels,
+		Targets: step.Targets,
+		Proof:   step.Proof,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = e.stdin.Write(append(req, '\n'))
+	if err != nil {
+		return err
+	}
+
+	if !e.stdout.Scan() {
+		if err := e.stdout.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s exited before replying", e.name)
+	}
+
+	var resp externalResponse
+	err = json.Unmarshal(e.stdout.Bytes(), &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s: %s", e.name, resp.Error)
+	}
+
+	e.roots = toHashes(resp.Roots)
+	e.numLeaves = resp.NumLeaves
+	return nil
+}
+
+// State returns the state the external process replied with for the last step.
+func (e *External) State() ([]utreexo.Hash, uint64, error) {
+	return e.roots, e.numLeaves, nil
+}
+
+// Close stops the external process.
+func (e *External) Close() error {
+	e.stdin.Close()
+	return e.cmd.Wait()
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package utreexodiff implements a differential test harness for utreexo
+// accumulator implementations.
+//
+// A Vector is a sequence of additions and deletions along with the proofs for
+// the deletions and the state the accumulator is expected to be in after every
+// step.  Vectors are created with Generate and can be written out as JSON to
+// be used as a test corpus by other implementations such as rustreexo.  Run
+// feeds a vector to any number of implementations, including ones that run in
+// a separate process, and reports every step at which they diverge.
+package utreexodiff
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexo"
+)
+
+// Divergence describes a step at which an implementation didn't end up in the
+// expected state.
+type Divergence struct {
+	// Implementation is the name of the diverging implementation.
+	Implementation string
+
+	// Step is the index of the step in the vector.
+	Step int
+
+	// Reason describes how the implementation diverged.
+	Reason string
+}
+
+// String returns the divergence in a human-readable form.
+func (d Divergence) String() string {
+	return fmt.Sprintf("%s diverged at step %d: %s",
+		d.Implementation, d.Step, d.Reason)
+}
+
+// Run applies every step of the vector to each of the accumulators and checks
+// the resulting state against the expected state of the vector.  Once an
+// accumulator diverges it's not fed any further steps since every state after
+// that would diverge as well.
+func Run(vector *Vector, accs ...Accumulator) []Divergence {
+	var divergences []Divergence
+	diverged := make([]bool, len(accs))
+	for i := range vector.Steps {
+		step := &vector.Steps[i]
+		for j, acc := range accs {
+			if diverged[j] {
+				continue
+			}
+			reason := check(acc, step)
+			if reason == "" {
+				continue
+			}
+			diverged[j] = true
+			divergences = append(divergences, Divergence{
+				Implementation: acc.Name(),
+				Step:           i,
+				Reason:         reason,
+			})
+		}
+	}
+
+	return divergences
+}
+
+// check applies the step to the accumulator and returns the reason why the
+// accumulator diverged from the expected state.  An empty string is returned
+// if it didn't diverge.
+func check(acc Accumulator, step *Step) string {
+	err := acc.Modify(step)
+	if err != nil {
+		return fmt.Sprintf("modify failed: %v", err)
+	}
+
+	roots, numLeaves, err := acc.State()
+	if err != nil {
+		return fmt.Sprintf("fetching the state failed: %v", err)
+	}
+	if numLeaves != step.NumLeaves {
+		return fmt.Sprintf("expected %d leaves but got %d",
+			step.NumLeaves, numLeaves)
+	}
+
+	expected := toHashes(step.Roots)
+	roots = nonEmptyRoots(roots)
+	if len(roots) != len(expected) {
+		return fmt.Sprintf("expected %d roots but got %d",
+			len(expected), len(roots))
+	}
+	for i := range roots {
+		if roots[i] != expected[i] {
+			return fmt.Sprintf("expected root %d to be %x but got %x",
+				i, expected[i], roots[i])
+		}
+	}
+
+	return ""
+}
+
+// nonEmptyRoots returns the roots without the empty ones.  Some implementations
+// keep an empty hash in place of a root that was deleted while others drop it.
+func nonEmptyRoots(roots []utreexo.Hash) []utreexo.Hash {
+	filtered := make([]utreexo.Hash, 0, len(roots))
+	for _, root := range roots {
+		if root == (utreexo.Hash{}) {
+			continue
+		}
+		filtered = append(filtered, root)
+	}
+	return filtered
+}
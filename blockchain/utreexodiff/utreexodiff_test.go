@@ -0,0 +1,98 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package utreexodiff
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// extCmdEnv is the environment variable that holds the command of an external
+// implementation to check against, e.g. a rustreexo test binary.
+const extCmdEnv = "UTREEXO_DIFF_CMD"
+
+func testVectors(t *testing.T) []Vector {
+	configs := []GenerateConfig{
+		{Seed: 1, NumSteps: 50, MaxAdds: 8, MaxDels: 4},
+		{Seed: 2, NumSteps: 100, MaxAdds: 20, MaxDels: 20},
+		{Seed: 3, NumSteps: 30, MaxAdds: 1, MaxDels: 1},
+		{Seed: 4, NumSteps: 20, MaxAdds: 64, MaxDels: 0},
+	}
+
+	vectors := make([]Vector, 0, len(configs))
+	for _, cfg := range configs {
+		vector, err := Generate(cfg)
+		if err != nil {
+			t.Fatalf("Generate(%+v) failed: %v", cfg, err)
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors
+}
+
+func TestRun(t *testing.T) {
+	for _, vector := range testVectors(t) {
+		accs := []Accumulator{NewMapPollard(), NewPollard(), NewStump()}
+
+		cmd := os.Getenv(extCmdEnv)
+		if cmd != "" {
+			fields := strings.Fields(cmd)
+			ext, err := NewExternal(fields[0], fields[0], fields[1:]...)
+			if err != nil {
+				t.Fatalf("failed to start %s: %v", cmd, err)
+			}
+			defer ext.Close()
+			accs = append(accs, ext)
+		}
+
+		for _, div := range Run(&vector, accs...) {
+			t.Errorf("%s: %v", vector.Name, div)
+		}
+	}
+}
+
+func TestRunDivergence(t *testing.T) {
+	vector, err := Generate(GenerateConfig{
+		Seed: 5, NumSteps: 10, MaxAdds: 8, MaxDels: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tamper with the expected state of a step and make sure every
+	// implementation is flagged at that step.
+	vector.Steps[6].Roots[0][0] ^= 0xff
+
+	divs := Run(&vector, NewMapPollard(), NewPollard(), NewStump())
+	if len(divs) != 3 {
+		t.Fatalf("expected 3 divergences but got %d: %v", len(divs), divs)
+	}
+	for _, div := range divs {
+		if div.Step != 6 {
+			t.Fatalf("expected a divergence at step 6 but got %v", div)
+		}
+	}
+}
+
+func TestVectorsRoundTrip(t *testing.T) {
+	vectors := testVectors(t)
+
+	var buf bytes.Buffer
+	err := WriteVectors(&buf, vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadVectors(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(vectors, read) {
+		t.Fatalf("vectors changed after a round trip")
+	}
+}
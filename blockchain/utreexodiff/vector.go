@@ -0,0 +1,193 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package utreexodiff
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+
+	"github.com/utreexo/utreexo"
+)
+
+// Hash is a utreexo hash that's encoded as a hex string in JSON.
+type Hash utreexo.Hash
+
+// MarshalJSON encodes the hash as a hex string.
+func (h Hash) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(h[:]))
+}
+
+// UnmarshalJSON decodes the hash from a hex string.
+func (h *Hash) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(str)
+	if err != nil {
+		return err
+	}
+	if len(decoded) != len(h) {
+		return fmt.Errorf("expected a hash of %d bytes but got %d bytes",
+			len(h), len(decoded))
+	}
+	copy(h[:], decoded)
+	return nil
+}
+
+// toHashes converts the hashes to utreexo hashes.
+func toHashes(hashes []Hash) []utreexo.Hash {
+	converted := make([]utreexo.Hash, len(hashes))
+	for i := range hashes {
+		converted[i] = utreexo.Hash(hashes[i])
+	}
+	return converted
+}
+
+// fromHashes converts the utreexo hashes to hashes.
+func fromHashes(hashes []utreexo.Hash) []Hash {
+	converted := make([]Hash, len(hashes))
+	for i := range hashes {
+		converted[i] = Hash(hashes[i])
+	}
+	return converted
+}
+
+// Step is a single modification of the accumulator.  The deletions are applied
+// before the additions, just like a block does.
+type Step struct {
+	// Adds are the leaf hashes that are added to the accumulator.
+	Adds []Hash `json:"adds"`
+
+	// Dels are the leaf hashes that are deleted from the accumulator.
+	Dels []Hash `json:"dels"`
+
+	// Targets and Proof are the accumulator proof for the deletions with
+	// respect to the state before this step.
+	Targets []uint64 `json:"targets"`
+	Proof   []Hash   `json:"proof"`
+
+	// Roots and NumLeaves are the expected state after this step.
+	Roots     []Hash `json:"roots"`
+	NumLeaves uint64 `json:"numleaves"`
+}
+
+// proof returns the accumulator proof of the step.
+func (s *Step) proof() utreexo.Proof {
+	return utreexo.Proof{Targets: s.Targets, Proof: toHashes(s.Proof)}
+}
+
+// Vector is a sequence of modifications starting from an empty accumulator.
+type Vector struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// ReadVectors reads a JSON array of vectors from the reader.
+func ReadVectors(r io.Reader) ([]Vector, error) {
+	var vectors []Vector
+	err := json.NewDecoder(r).Decode(&vectors)
+	if err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+// WriteVectors writes the vectors to the writer as a JSON array.  The output
+// can be consumed by other accumulator implementations as a test corpus.
+func WriteVectors(w io.Writer, vectors []Vector) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vectors)
+}
+
+// GenerateConfig describes the shape of the vectors created by Generate.
+type GenerateConfig struct {
+	// Seed makes the generated vector deterministic.
+	Seed int64
+
+	// NumSteps is the number of modifications in the vector.
+	NumSteps int
+
+	// MaxAdds and MaxDels are the maximum number of leaves that are added
+	// and deleted in a single step.
+	MaxAdds int
+	MaxDels int
+}
+
+// Generate creates a vector of random additions and deletions.  The proofs and
+// the expected states are computed with the MapPollard of the utreexo library,
+// which is the accumulator the node uses.
+func Generate(cfg GenerateConfig) (Vector, error) {
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	p := utreexo.NewMapPollard(true)
+
+	vector := Vector{
+		Name:  fmt.Sprintf("random-seed%d-steps%d", cfg.Seed, cfg.NumSteps),
+		Steps: make([]Step, 0, cfg.NumSteps),
+	}
+
+	var leafCount uint64
+	var leaves []utreexo.Hash
+	for i := 0; i < cfg.NumSteps; i++ {
+		// Pick random leaves to delete.
+		numDels := 0
+		if cfg.MaxDels > 0 && len(leaves) > 0 {
+			numDels = rnd.Intn(cfg.MaxDels + 1)
+			if numDels > len(leaves) {
+				numDels = len(leaves)
+			}
+		}
+		rnd.Shuffle(len(leaves), func(a, b int) {
+			leaves[a], leaves[b] = leaves[b], leaves[a]
+		})
+		dels := append([]utreexo.Hash(nil), leaves[:numDels]...)
+		leaves = leaves[numDels:]
+
+		proof, err := p.Prove(dels)
+		if err != nil {
+			return Vector{}, fmt.Errorf("step %d: %v", i, err)
+		}
+
+		// Create new unique leaves.
+		numAdds := 0
+		if cfg.MaxAdds > 0 {
+			numAdds = rnd.Intn(cfg.MaxAdds + 1)
+		}
+		adds := make([]utreexo.Leaf, numAdds)
+		addHashes := make([]utreexo.Hash, numAdds)
+		for j := range adds {
+			var buf [16]byte
+			binary.LittleEndian.PutUint64(buf[:8], uint64(cfg.Seed))
+			binary.LittleEndian.PutUint64(buf[8:], leafCount)
+			leafCount++
+
+			addHashes[j] = sha256.Sum256(buf[:])
+			adds[j] = utreexo.Leaf{Hash: addHashes[j], Remember: true}
+		}
+		leaves = append(leaves, addHashes...)
+
+		err = p.Modify(adds, dels, proof)
+		if err != nil {
+			return Vector{}, fmt.Errorf("step %d: %v", i, err)
+		}
+
+		vector.Steps = append(vector.Steps, Step{
+			Adds:      fromHashes(addHashes),
+			Dels:      fromHashes(dels),
+			Targets:   proof.Targets,
+			Proof:     fromHashes(proof.Proof),
+			Roots:     fromHashes(nonEmptyRoots(p.GetRoots())),
+			NumLeaves: p.GetNumLeaves(),
+		})
+	}
+
+	return vector, nil
+}
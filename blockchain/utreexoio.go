@@ -6,6 +6,7 @@ package blockchain
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/utreexo/utreexo"
@@ -43,6 +44,12 @@ var _ utreexo.NodesInterface = (*NodesBackEnd)(nil)
 
 // NodesBackEnd implements the NodesInterface interface.
 type NodesBackEnd struct {
+	// cacheHits and cacheMisses count the lookups that were and weren't
+	// served from the cache.  They must be accessed atomically and are
+	// placed first for 64-bit alignment.
+	cacheHits   uint64
+	cacheMisses uint64
+
 	db           *leveldb.DB
 	maxCacheElem int64
 	cache        utreexobackends.NodesMapSlice
@@ -126,8 +133,10 @@ func (m *NodesBackEnd) Get(k uint64) (utreexo.Leaf, bool) {
 		}
 
 		// If we found it, return here.
+		atomic.AddUint64(&m.cacheHits, 1)
 		return cLeaf.Leaf, true
 	}
+	atomic.AddUint64(&m.cacheMisses, 1)
 
 	// Since it's not in the cache, look it up in the database.
 	leaf, found := m.dbGet(k)
@@ -256,6 +265,12 @@ func (m *NodesBackEnd) ForEach(fn func(uint64, utreexo.Leaf) error) error {
 	return iter.Error()
 }
 
+// CacheStats returns the number of lookups that were and weren't served from
+// the cache.
+func (m *NodesBackEnd) CacheStats() (uint64, uint64) {
+	return atomic.LoadUint64(&m.cacheHits), atomic.LoadUint64(&m.cacheMisses)
+}
+
 // flush saves all the cached entries to disk and resets the cache map.
 func (m *NodesBackEnd) flush() {
 	if m.maxCacheElem == 0 {
@@ -291,6 +306,12 @@ var _ utreexo.CachedLeavesInterface = (*CachedLeavesBackEnd)(nil)
 // CachedLeavesBackEnd implements the CachedLeavesInterface interface. The cache assumes
 // that anything in the cache doesn't exist in the db and vise-versa.
 type CachedLeavesBackEnd struct {
+	// cacheHits and cacheMisses count the lookups that were and weren't
+	// served from the cache.  They must be accessed atomically and are
+	// placed first for 64-bit alignment.
+	cacheHits   uint64
+	cacheMisses uint64
+
 	db           *leveldb.DB
 	maxCacheElem int64
 	cache        utreexobackends.CachedLeavesMapSlice
@@ -335,8 +356,10 @@ func (m *CachedLeavesBackEnd) Get(k utreexo.Hash) (uint64, bool) {
 
 	pos, found := m.cache.Get(k)
 	if !found {
+		atomic.AddUint64(&m.cacheMisses, 1)
 		return m.dbGet(k)
 	}
+	atomic.AddUint64(&m.cacheHits, 1)
 
 	return pos, found
 }
@@ -402,6 +425,12 @@ func (m *CachedLeavesBackEnd) ForEach(fn func(utreexo.Hash, uint64) error) error
 	return iter.Error()
 }
 
+// CacheStats returns the number of lookups that were and weren't served from
+// the cache.
+func (m *CachedLeavesBackEnd) CacheStats() (uint64, uint64) {
+	return atomic.LoadUint64(&m.cacheHits), atomic.LoadUint64(&m.cacheMisses)
+}
+
 // Flush resets the cache and saves all the key values onto the database.
 func (m *CachedLeavesBackEnd) flush() {
 	m.cache.ForEach(func(k utreexo.Hash, v uint64) {
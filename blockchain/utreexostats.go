@@ -0,0 +1,121 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"sync/atomic"
+
+	"github.com/utreexo/utreexod/wire"
+)
+
+// UtreexoStats are statistics about the utreexo accumulator a node keeps and
+// about the proofs it serves to its peers.
+type UtreexoStats struct {
+	// NumLeaves is the number of leaves that were ever added to the
+	// accumulator.
+	NumLeaves uint64
+
+	// NumRoots is the number of roots the accumulator currently has.
+	NumRoots int
+
+	// StateSize is the size in bytes of the files the accumulator is
+	// stored in.  It's 0 for compact state nodes as they only store the
+	// roots in the block database.
+	StateSize int64
+
+	// NodesCacheHits and NodesCacheMisses are the number of lookups of
+	// accumulator nodes that were and weren't served from the cache.
+	NodesCacheHits   uint64
+	NodesCacheMisses uint64
+
+	// CachedLeavesCacheHits and CachedLeavesCacheMisses are the number of
+	// lookups of leaf positions that were and weren't served from the
+	// cache.
+	CachedLeavesCacheHits   uint64
+	CachedLeavesCacheMisses uint64
+
+	// ProofsServed is the number of proofs that were sent to peers and
+	// ProofBytesServed is their total serialized size.
+	ProofsServed     uint64
+	ProofBytesServed uint64
+}
+
+// AvgProofSize returns the average serialized size of the proofs that were
+// served.
+func (s *UtreexoStats) AvgProofSize() float64 {
+	if s.ProofsServed == 0 {
+		return 0
+	}
+	return float64(s.ProofBytesServed) / float64(s.ProofsServed)
+}
+
+// NodesCacheHitRate returns the ratio of the accumulator node lookups that were
+// served from the cache.
+func (s *UtreexoStats) NodesCacheHitRate() float64 {
+	return hitRate(s.NodesCacheHits, s.NodesCacheMisses)
+}
+
+// CachedLeavesCacheHitRate returns the ratio of the leaf position lookups that
+// were served from the cache.
+func (s *UtreexoStats) CachedLeavesCacheHitRate() float64 {
+	return hitRate(s.CachedLeavesCacheHits, s.CachedLeavesCacheMisses)
+}
+
+// hitRate returns the ratio of hits out of all the lookups.
+func hitRate(hits, misses uint64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// UtreexoStatser is implemented by index managers that manage an index which
+// keeps a utreexo accumulator.  It allows the chain to report the statistics of
+// the accumulators of bridge nodes.
+type UtreexoStatser interface {
+	// UtreexoStats returns the statistics of the accumulator kept by the
+	// managed indexes.  The boolean is false if none of the managed
+	// indexes keep an accumulator.
+	UtreexoStats() (UtreexoStats, bool)
+}
+
+// RecordProofServed records that the passed in utreexo data was served to a
+// peer so that it's accounted for in the statistics returned by UtreexoStats.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) RecordProofServed(ud *wire.UData) {
+	if ud == nil {
+		return
+	}
+	atomic.AddUint64(&b.proofsServed, 1)
+	atomic.AddUint64(&b.proofBytesServed, uint64(ud.SerializeSizeCompact(false)))
+}
+
+// UtreexoStats returns the statistics of the utreexo accumulator of the node.
+// For bridge nodes these are the statistics of the accumulator of the utreexo
+// proof index and for compact state nodes the statistics of the utreexo
+// viewpoint.  The boolean is false if the node doesn't keep an accumulator.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) UtreexoStats() (UtreexoStats, bool) {
+	var stats UtreexoStats
+	found := false
+	if statser, ok := b.indexManager.(UtreexoStatser); ok {
+		stats, found = statser.UtreexoStats()
+	}
+
+	if !found && b.utreexoView != nil {
+		b.chainLock.RLock()
+		stats.NumLeaves = b.utreexoView.NumLeaves()
+		stats.NumRoots = len(b.utreexoView.accumulator.GetRoots())
+		b.chainLock.RUnlock()
+		found = true
+	}
+
+	stats.ProofsServed = atomic.LoadUint64(&b.proofsServed)
+	stats.ProofBytesServed = atomic.LoadUint64(&b.proofBytesServed)
+
+	return stats, found
+}
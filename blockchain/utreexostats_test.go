@@ -0,0 +1,203 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestUtreexoStatsRates ensures that the averages and the hit rates are derived
+// from the counters and that no counts result in zeros.
+func TestUtreexoStatsRates(t *testing.T) {
+	tests := []struct {
+		name                string
+		stats               UtreexoStats
+		avgProofSize        float64
+		nodesHitRate        float64
+		cachedLeavesHitRate float64
+	}{
+		{
+			name: "no counts",
+		},
+		{
+			name: "counts",
+			stats: UtreexoStats{
+				NodesCacheHits:          3,
+				NodesCacheMisses:        1,
+				CachedLeavesCacheHits:   0,
+				CachedLeavesCacheMisses: 5,
+				ProofsServed:            4,
+				ProofBytesServed:        1000,
+			},
+			avgProofSize:        250,
+			nodesHitRate:        0.75,
+			cachedLeavesHitRate: 0,
+		},
+		{
+			name: "only hits",
+			stats: UtreexoStats{
+				NodesCacheHits:        7,
+				CachedLeavesCacheHits: 2,
+				ProofsServed:          3,
+				ProofBytesServed:      1,
+			},
+			avgProofSize:        1.0 / 3,
+			nodesHitRate:        1,
+			cachedLeavesHitRate: 1,
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.stats.AvgProofSize(); math.Abs(got-test.avgProofSize) > 1e-9 {
+			t.Errorf("%s: got average proof size %v, want %v",
+				test.name, got, test.avgProofSize)
+		}
+		if got := test.stats.NodesCacheHitRate(); got != test.nodesHitRate {
+			t.Errorf("%s: got nodes hit rate %v, want %v",
+				test.name, got, test.nodesHitRate)
+		}
+		if got := test.stats.CachedLeavesCacheHitRate(); got != test.cachedLeavesHitRate {
+			t.Errorf("%s: got cached leaves hit rate %v, want %v",
+				test.name, got, test.cachedLeavesHitRate)
+		}
+	}
+}
+
+// TestBackEndCacheStats ensures that the lookups of the utreexo backends are
+// counted as hits when they're served from the cache and as misses otherwise,
+// and that nothing is counted when there's no cache.
+func TestBackEndCacheStats(t *testing.T) {
+	leafHash := func(i uint64) utreexo.Hash {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], i)
+		return sha256.Sum256(buf[:])
+	}
+
+	tests := []struct {
+		name        string
+		maxMemUsage int64
+		hits        uint64
+		misses      uint64
+	}{
+		{name: "no cache", maxMemUsage: 0, hits: 0, misses: 0},
+		{name: "cache", maxMemUsage: 1024 * 1024, hits: 2, misses: 1},
+	}
+
+	for _, test := range tests {
+		nodesBackEnd, err := InitNodesBackEnd(
+			filepath.Join(t.TempDir(), "nodes"), test.maxMemUsage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cachedLeavesBackEnd, err := InitCachedLeavesBackEnd(
+			filepath.Join(t.TempDir(), "cachedleaves"), test.maxMemUsage)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := uint64(0); i < 2; i++ {
+			nodesBackEnd.Put(i, utreexo.Leaf{Hash: leafHash(i)})
+			cachedLeavesBackEnd.Put(leafHash(i), i)
+		}
+		for i := uint64(0); i < 3; i++ {
+			nodesBackEnd.Get(i)
+			cachedLeavesBackEnd.Get(leafHash(i))
+		}
+
+		hits, misses := nodesBackEnd.CacheStats()
+		if hits != test.hits || misses != test.misses {
+			t.Errorf("%s: got %d nodes hits and %d misses, want "+
+				"%d and %d", test.name, hits, misses, test.hits,
+				test.misses)
+		}
+		hits, misses = cachedLeavesBackEnd.CacheStats()
+		if hits != test.hits || misses != test.misses {
+			t.Errorf("%s: got %d cached leaves hits and %d misses, "+
+				"want %d and %d", test.name, hits, misses,
+				test.hits, test.misses)
+		}
+
+		nodesBackEnd.Close()
+		cachedLeavesBackEnd.Close()
+	}
+}
+
+// testStatsIndexManager is an index manager that reports fixed utreexo
+// statistics.  The methods of the IndexManager interface aren't implemented.
+type testStatsIndexManager struct {
+	IndexManager
+	stats UtreexoStats
+	found bool
+}
+
+// UtreexoStats returns the fixed statistics of the index manager.
+//
+// This is part of the UtreexoStatser interface.
+func (m *testStatsIndexManager) UtreexoStats() (UtreexoStats, bool) {
+	return m.stats, m.found
+}
+
+// TestChainUtreexoStats ensures that the chain reports the statistics of the
+// index manager along with the proofs it was told were served, and that it
+// reports that there's no accumulator when neither an index nor a viewpoint
+// keeps one.
+func TestChainUtreexoStats(t *testing.T) {
+	ud := &wire.UData{
+		AccProof: utreexo.Proof{
+			Targets: []uint64{1, 2},
+			Proof:   []utreexo.Hash{{1}, {2}, {3}},
+		},
+	}
+	proofSize := uint64(ud.SerializeSizeCompact(false))
+
+	// A chain without an index that keeps an accumulator still counts
+	// the proofs.
+	chain := &BlockChain{}
+	chain.RecordProofServed(nil)
+	chain.RecordProofServed(ud)
+	stats, found := chain.UtreexoStats()
+	if found {
+		t.Fatalf("got an accumulator for a chain without one")
+	}
+	if stats.ProofsServed != 1 || stats.ProofBytesServed != proofSize {
+		t.Fatalf("got %d proofs of %d bytes served, want 1 of %d bytes",
+			stats.ProofsServed, stats.ProofBytesServed, proofSize)
+	}
+
+	// An index manager that doesn't manage an index with an accumulator.
+	chain.indexManager = &testStatsIndexManager{}
+	if _, found := chain.UtreexoStats(); found {
+		t.Fatalf("got an accumulator for an index manager without one")
+	}
+
+	indexStats := UtreexoStats{
+		NumLeaves:               100,
+		NumRoots:                3,
+		StateSize:               4096,
+		NodesCacheHits:          10,
+		NodesCacheMisses:        2,
+		CachedLeavesCacheHits:   8,
+		CachedLeavesCacheMisses: 4,
+	}
+	chain.indexManager = &testStatsIndexManager{stats: indexStats, found: true}
+	chain.RecordProofServed(ud)
+	stats, found = chain.UtreexoStats()
+	if !found {
+		t.Fatalf("got no accumulator for an index manager with one")
+	}
+	want := indexStats
+	want.ProofsServed = 2
+	want.ProofBytesServed = 2 * proofSize
+	if stats != want {
+		t.Fatalf("got stats %+v, want %+v", stats, want)
+	}
+}
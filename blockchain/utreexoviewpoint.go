@@ -48,12 +48,18 @@ func (uview *UtreexoViewpoint) CopyWithRoots() *UtreexoViewpoint {
 
 // ProcessUData checks that the accumulator proof and the utxo data included in the UData
 // passes consensus and then it updates the underlying accumulator.
+//
+// The remember policy decides which of the added leaves are cached.  The leaves
+// the bridge marked to be remembered are cached if it's nil.  The hashes of the
+// deleted leaves are taken from the hash cache if they're in it.
 func (uview *UtreexoViewpoint) ProcessUData(block *btcutil.Block,
-	bestChain *chainView, ud *wire.UData) error {
+	bestChain *chainView, ud *wire.UData, policy RememberPolicy,
+	hashCache *leafHashCache) error {
 
 	// Extracts the block into additions and deletions that will be processed.
 	// Adds correspond to newly created UTXOs and dels correspond to STXOs.
-	adds, dels, err := ExtractAccumulatorAddDels(block, bestChain, ud.RememberIdx)
+	adds, dels, err := ExtractAccumulatorAddDels(block, bestChain,
+		ud.RememberIdx, policy, hashCache)
 	if err != nil {
 		return err
 	}
@@ -232,8 +238,12 @@ func DedupeBlock(blk *btcutil.Block) (inCount, outCount int, inskip []uint32, ou
 }
 
 // ExtractAccumulatorAddDels extracts the additions and the deletions that will be
-// used to modify the utreexo accumulator.
-func ExtractAccumulatorAddDels(block *btcutil.Block, bestChain *chainView, remembers []uint32) (
+// used to modify the utreexo accumulator.  The remember policy decides which of
+// the additions are cached and the additions marked in remembers are cached if
+// it's nil.  The hashes of the deletions are taken from the hash cache if
+// they're in it.
+func ExtractAccumulatorAddDels(block *btcutil.Block, bestChain *chainView,
+	remembers []uint32, policy RememberPolicy, hashCache *leafHashCache) (
 	[]utreexo.Leaf, []utreexo.Hash, error) {
 
 	// Check that UData field isn't nil before doing anything else.
@@ -250,7 +260,7 @@ func ExtractAccumulatorAddDels(block *btcutil.Block, bestChain *chainView, remem
 
 	// Make the now verified utxos into 32 byte leaves ready to be added into the
 	// utreexo accumulator.
-	leaves := BlockToAddLeaves(block, outskip, remembers, outCount)
+	leaves := blockToAddLeaves(block, outskip, remembers, outCount, policy)
 
 	// Make slice of hashes from the LeafDatas. These are the hash commitments
 	// to be proven.
@@ -260,7 +270,7 @@ func ExtractAccumulatorAddDels(block *btcutil.Block, bestChain *chainView, remem
 	var delHashes []utreexo.Hash
 	if len(ud.LeafDatas) > 0 {
 		var err error
-		delHashes, err = reconstructUData(ud, block, bestChain, inskip)
+		delHashes, err = reconstructUData(ud, block, bestChain, inskip, hashCache)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -319,7 +329,7 @@ func (b *BlockChain) ReconstructUData(ud *wire.UData, blockHash chainhash.Hash)
 	}
 
 	_, _, inskip, _ := DedupeBlock(block)
-	delHashes, err := reconstructUData(ud, block, b.bestChain, inskip)
+	delHashes, err := reconstructUData(ud, block, b.bestChain, inskip, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -329,11 +339,12 @@ func (b *BlockChain) ReconstructUData(ud *wire.UData, blockHash chainhash.Hash)
 
 // reconstructUData adds in missing information to the passed in compact UData and
 // makes it full. The hashes returned are the hashes of the individual leaf data
-// that were commited into the accumulator.
+// that were commited into the accumulator.  The hashes memoized in the hash
+// cache are reused and removed from it as the leaves are spent by the block.
 //
 // This function is safe for concurrent access.
 func reconstructUData(ud *wire.UData, block *btcutil.Block, chainView *chainView,
-	inskip []uint32) ([]utreexo.Hash, error) {
+	inskip []uint32, hashCache *leafHashCache) ([]utreexo.Hash, error) {
 	if chainView == nil {
 		return nil, fmt.Errorf("Passed in chainView is nil. Cannot make compact udata to full")
 	}
@@ -342,7 +353,6 @@ func reconstructUData(ud *wire.UData, block *btcutil.Block, chainView *chainView
 	// as a separate idx for the LeafDatas.  We need both of them because
 	// LeafDatas have already been deduped while the transactions are not.
 	var blockInIdx, ldIdx uint32
-	delHashes := make([]utreexo.Hash, 0, len(ud.LeafDatas))
 	for idx, tx := range block.Transactions() {
 		if idx == 0 {
 			// coinbase can have many inputs
@@ -399,14 +409,14 @@ func reconstructUData(ud *wire.UData, block *btcutil.Block, chainView *chainView
 				ld.PkScript = scriptToUse
 			}
 
-			delHashes = append(delHashes, ld.LeafHash())
-
 			blockInIdx++
 			ldIdx++
 		}
 	}
 
-	return delHashes, nil
+	// Hash the leaves after they've all been reconstructed so that the
+	// leaves of large blocks can be hashed concurrently.
+	return hashCache.leafHashes(ud.LeafDatas[:ldIdx], true), nil
 }
 
 // IsUnspendable determines whether a tx is spendable or not.
@@ -432,12 +442,27 @@ func IsUnspendable(o *wire.TxOut) bool {
 func BlockToAddLeaves(block *btcutil.Block, skiplist []uint32, remembers []uint32,
 	outCount int) []utreexo.Leaf {
 
+	return blockToAddLeaves(block, skiplist, remembers, outCount, nil)
+}
+
+// blockToAddLeaves is BlockToAddLeaves with the remember bit of each leaf
+// decided by the passed in remember policy.  The leaves in remembers are
+// remembered if the policy is nil.
+func blockToAddLeaves(block *btcutil.Block, skiplist []uint32, remembers []uint32,
+	outCount int, policy RememberPolicy) []utreexo.Leaf {
+
+	if policy == nil {
+		policy = TTLRememberPolicy{}
+	}
+	header := &block.MsgBlock().Header
+
 	// Sort first as the below loop expects the remembers to be in order.
 	sortUint32s(remembers)
 
 	// We're overallocating a little bit since all the unspendables
 	// won't be appended. It's ok though for the pre-allocation savings.
-	leaves := make([]utreexo.Leaf, 0, outCount-len(skiplist))
+	leafDatas := make([]wire.LeafData, 0, outCount-len(skiplist))
+	rememberBits := make([]bool, 0, outCount-len(skiplist))
 
 	var txonum uint32
 	for coinbase, tx := range block.Transactions() {
@@ -469,23 +494,32 @@ func BlockToAddLeaves(block *btcutil.Block, skiplist []uint32, remembers []uint3
 			}
 
 			// Set remember to be true if the current UTXO corresponds
-			// to an index that should be remembered.
+			// to an index that should be remembered and let the policy
+			// make the final decision.
 			remember := false
 			if len(remembers) > 0 && remembers[0] == txonum {
 				remembers = remembers[1:]
 				remember = true
 			}
+			remember = policy.Remember(&leaf, header, remember)
 
-			uleaf := utreexo.Leaf{
-				Hash:     leaf.LeafHash(),
-				Remember: remember,
-			}
-
-			leaves = append(leaves, uleaf)
+			leafDatas = append(leafDatas, leaf)
+			rememberBits = append(rememberBits, remember)
 			txonum++
 		}
 	}
 
+	// Hash all the leaves at once so that the leaves of large blocks are
+	// hashed concurrently.
+	hashes := wire.LeafHashes(leafDatas)
+	leaves := make([]utreexo.Leaf, len(hashes))
+	for i := range hashes {
+		leaves[i] = utreexo.Leaf{
+			Hash:     hashes[i],
+			Remember: rememberBits[i],
+		}
+	}
+
 	return leaves
 }
 
@@ -895,9 +929,9 @@ func (b *BlockChain) VerifyUData(ud *wire.UData, txIns []*wire.TxIn, remember bo
 		return fmt.Errorf(str)
 	}
 
-	// Make a slice of hashes from LeafDatas. These are the hash commitments
-	// to be proven.
-	delHashes := make([]utreexo.Hash, 0, len(ud.LeafDatas))
+	// Make a slice of the confirmed LeafDatas. Their hashes are the hash
+	// commitments to be proven.
+	dels := make([]wire.LeafData, 0, len(ud.LeafDatas))
 	for i, txIn := range txIns {
 		ld := &ud.LeafDatas[i]
 
@@ -944,9 +978,10 @@ func (b *BlockChain) VerifyUData(ud *wire.UData, txIns []*wire.TxIn, remember bo
 				ld.PkScript = scriptToUse
 			}
 
-			delHashes = append(delHashes, ld.LeafHash())
+			dels = append(dels, *ld)
 		}
 	}
+	delHashes := b.leafHashCache.leafHashes(dels, false)
 
 	// Acquire read lock before accessing the accumulator state.
 	b.chainLock.RLock()
@@ -970,6 +1005,12 @@ func (b *BlockChain) VerifyUData(ud *wire.UData, txIns []*wire.TxIn, remember bo
 		log.Debugf("cached hashes: %v", delHashes)
 	}
 
+	// Memoize the verified hashes so that they don't have to be computed
+	// again when the transaction is included in a block.
+	for i := range dels {
+		b.leafHashCache.add(&dels[i], delHashes[i])
+	}
+
 	return nil
 }
 
@@ -1032,7 +1073,7 @@ func (b *BlockChain) PruneFromAccumulator(leaves []wire.LeafData) error {
 	b.chainLock.Lock()
 	defer b.chainLock.Unlock()
 
-	hashes := make([]utreexo.Hash, 0, len(leaves))
+	confirmed := make([]wire.LeafData, 0, len(leaves))
 	for i := range leaves {
 		// Unconfirmed leaves aren't present in the accumulator.
 		if leaves[i].IsUnconfirmed() {
@@ -1044,9 +1085,13 @@ func (b *BlockChain) PruneFromAccumulator(leaves []wire.LeafData) error {
 				"the leafdata is compact")
 		}
 
-		hashes = append(hashes, leaves[i].LeafHash())
+		confirmed = append(confirmed, leaves[i])
 	}
 
+	// The leaves are no longer referenced by the mempool so they're removed
+	// from the leaf hash cache as well.
+	hashes := b.leafHashCache.leafHashes(confirmed, true)
+
 	log.Debugf("uncaching hashes: %v", hashes)
 
 	err := b.utreexoView.accumulator.Prune(hashes)
@@ -1105,6 +1150,42 @@ func (b *BlockChain) GetNeededPositions(packedPositions []chainhash.Hash) []chai
 	return chainhash.Uint64sToPackedHashes(missing)
 }
 
+// FetchUtreexoRoots returns the roots and the number of leaves of the utreexo
+// accumulator after the block at the given height of the main chain was
+// connected.  For bridge nodes that's the accumulator of the utreexo proof index
+// and for compact state nodes the stored utreexo viewpoint.  The boolean is
+// false if the node doesn't keep an accumulator or if the accumulator at that
+// height isn't stored.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FetchUtreexoRoots(height int32) ([]*chainhash.Hash, uint64, bool, error) {
+	node := b.bestChain.NodeByHeight(height)
+	if node == nil {
+		return nil, 0, false, fmt.Errorf("no block at height %d exists "+
+			"in the main chain", height)
+	}
+
+	if b.utreexoView == nil {
+		fetcher, ok := b.indexManager.(UtreexoRootsFetcher)
+		if !ok {
+			return nil, 0, false, nil
+		}
+		return fetcher.FetchUtreexoRoots(height)
+	}
+
+	var view *UtreexoViewpoint
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		view, err = dbFetchUtreexoView(dbTx, &node.hash)
+		return err
+	})
+	if err != nil || view == nil {
+		return nil, 0, false, err
+	}
+
+	return view.GetRoots(), view.NumLeaves(), true, nil
+}
+
 // FetchUtreexoViewpoint returns the utreexo viewpoint at the given block hash.
 // returns nil if it wasn't found.
 //
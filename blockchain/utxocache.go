@@ -595,6 +595,29 @@ func (b *BlockChain) FlushUtxoCache(mode FlushMode) error {
 	})
 }
 
+// Quiesce flushes the UTXO state to the database and calls the passed function
+// while no block can be connected to or disconnected from the chain, so the
+// database and the state the indexes keep outside of it stay at the same block
+// for as long as it runs, such as to back them up.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) Quiesce(fn func() error) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	// Utreexo nodes don't have a utxo cache.
+	if b.utxoCache != nil {
+		err := b.db.Update(func(dbTx database.Tx) error {
+			return b.utxoCache.flush(dbTx, FlushRequired, b.BestSnapshot())
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return fn()
+}
+
 // InitConsistentState checks the consistency status of the utxo state and
 // replays blocks if it lags behind the best state of the blockchain.
 //
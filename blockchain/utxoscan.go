@@ -0,0 +1,117 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// ScannedUtxo is an unspent transaction output found by ScanUtxoSet.
+type ScannedUtxo struct {
+	OutPoint wire.OutPoint
+	Entry    *UtxoEntry
+}
+
+// UtxoSetScan houses the unspent transaction outputs of the main chain that pay
+// to the scripts of a scan of the utxo set.
+type UtxoSetScan struct {
+	// Height and Hash are of the block the utxo set was scanned at.
+	Height int32
+	Hash   chainhash.Hash
+
+	// TxOuts is the number of unspent outputs that were scanned.
+	TxOuts int64
+
+	// Utxos are the unspent outputs that pay to one of the scripts, sorted
+	// by their outpoints.
+	Utxos []ScannedUtxo
+}
+
+// ScanUtxoSet returns the unspent transaction outputs that pay to any of the
+// scripts as of the current best block of the main chain.  The utxo cache is
+// flushed to the database first, after which the set is read without blocking
+// the chain from processing new blocks.  When progress isn't nil, it's called
+// with the percentage of the set that was scanned whenever it increases.  An
+// error is returned for nodes that don't keep a utxo set, since compact state
+// nodes only keep the utreexo accumulator.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ScanUtxoSet(scripts map[string]struct{}, progress func(int),
+	interrupt <-chan struct{}) (*UtxoSetScan, error) {
+
+	if b.utxoCache == nil {
+		return nil, fmt.Errorf("the utxo set isn't kept by compact " +
+			"state nodes")
+	}
+
+	// Flush the cache and start reading the database while holding the
+	// chain lock so that the set is consistent with the best block.
+	b.chainLock.Lock()
+	best := b.BestSnapshot()
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return b.utxoCache.flush(dbTx, FlushRequired, best)
+	})
+	if err != nil {
+		b.chainLock.Unlock()
+		return nil, err
+	}
+	dbTx, err := b.db.Begin(false)
+	b.chainLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	scan := &UtxoSetScan{
+		Height: best.Height,
+		Hash:   best.Hash,
+	}
+	var percent int
+	cursor := dbTx.Metadata().Bucket(utxoSetBucketName).Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		if interruptRequested(interrupt) {
+			return nil, errInterruptRequested
+		}
+
+		key := cursor.Key()
+		if len(key) <= chainhash.HashSize {
+			return nil, AssertError(fmt.Sprintf("utxo set contains "+
+				"invalid key %x", key))
+		}
+		entry, err := deserializeUtxoEntry(cursor.Value())
+		if err != nil {
+			return nil, err
+		}
+		scan.TxOuts++
+
+		// The outpoints are sorted by their transaction hash, so the
+		// progress is estimated from its first two bytes.
+		if progress != nil {
+			scanned := (int(key[0])<<8 | int(key[1])) * 100 / (1 << 16)
+			if scanned > percent {
+				percent = scanned
+				progress(percent)
+			}
+		}
+
+		if _, ok := scripts[string(entry.PkScript())]; !ok {
+			continue
+		}
+		var outpoint wire.OutPoint
+		copy(outpoint.Hash[:], key[:chainhash.HashSize])
+		index, _ := deserializeVLQ(key[chainhash.HashSize:])
+		outpoint.Index = uint32(index)
+		scan.Utxos = append(scan.Utxos, ScannedUtxo{
+			OutPoint: outpoint,
+			Entry:    entry,
+		})
+	}
+
+	return scan, nil
+}
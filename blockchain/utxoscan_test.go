@@ -0,0 +1,77 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestScanUtxoSet ensures that scanning the utxo set finds the unspent outputs
+// paying to the scripts, including the entries in the utxo cache.
+func TestScanUtxoSet(t *testing.T) {
+	chain, params, tearDown := utxoCacheTestChain("TestScanUtxoSet")
+	defer tearDown()
+	cache := chain.utxoCache
+
+	// Add the outputs of two transactions to different scripts without
+	// flushing the cache.
+	script := getValidP2PKHScript()
+	otherScript := []byte{0x51}
+	for _, txHash := range []chainhash.Hash{{0xff}, {1}} {
+		for i := uint32(0); i < 2; i++ {
+			op := wire.OutPoint{Hash: txHash, Index: i}
+			pkScript := script
+			if i == 1 {
+				pkScript = otherScript
+			}
+			txOut := wire.TxOut{Value: 10000, PkScript: pkScript}
+			cache.addTxOut(op, &txOut, false, 1)
+		}
+	}
+
+	var progress []int
+	scripts := map[string]struct{}{string(script): {}}
+	scan, err := chain.ScanUtxoSet(scripts, func(percent int) {
+		progress = append(progress, percent)
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Height != 0 || scan.Hash != *params.GenesisHash ||
+		scan.TxOuts != 4 {
+
+		t.Fatalf("unexpected scan %+v", scan)
+	}
+	if len(scan.Utxos) != 2 {
+		t.Fatalf("expected 2 utxos, got %d", len(scan.Utxos))
+	}
+	want := []wire.OutPoint{
+		{Hash: chainhash.Hash{1}, Index: 0},
+		{Hash: chainhash.Hash{0xff}, Index: 0},
+	}
+	for i, utxo := range scan.Utxos {
+		if utxo.OutPoint != want[i] {
+			t.Fatalf("expected outpoint %v, got %v", want[i],
+				utxo.OutPoint)
+		}
+		if utxo.Entry.Amount() != 10000 || utxo.Entry.BlockHeight() != 1 {
+			t.Fatalf("unexpected entry %v", utxo.Entry)
+		}
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 99 {
+		t.Fatalf("unexpected progress %v", progress)
+	}
+
+	// Interrupting the scan returns an error.
+	interrupt := make(chan struct{})
+	close(interrupt)
+	_, err = chain.ScanUtxoSet(scripts, nil, interrupt)
+	if err != errInterruptRequested {
+		t.Fatalf("expected an interrupt error, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sort"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// UtxoSetStats houses statistics about the unspent transaction output set of
+// the main chain.
+type UtxoSetStats struct {
+	// Height and Hash are of the block the statistics are for.
+	Height int32
+	Hash   chainhash.Hash
+
+	// Transactions is the number of transactions that have unspent outputs
+	// and TxOuts is the number of unspent outputs.
+	Transactions int64
+	TxOuts       int64
+
+	// BogoSize is a database-independent metric of the size of the set,
+	// computed the same way as the bogosize of Bitcoin Core.
+	BogoSize int64
+
+	// HashSerialized is the hash of the serialized set, computed the same
+	// way as the hash_serialized_2 of Bitcoin Core.
+	HashSerialized chainhash.Hash
+
+	// DiskSize is the total size of the serialized keys and values of the
+	// set in the database.
+	DiskSize int64
+
+	// TotalAmount is the total amount of the unspent outputs in satoshi.
+	TotalAmount int64
+}
+
+// utxoSetHasher accumulates the statistics and the serialized hash of the utxo
+// set.  The outputs have to be added sorted by their outpoints so that the
+// outputs of each transaction are added together.
+type utxoSetHasher struct {
+	stats   *UtxoSetStats
+	hash    hash.Hash
+	txHash  chainhash.Hash
+	entries map[uint32]*UtxoEntry
+}
+
+// add adds the unspent output to the statistics.
+func (h *utxoSetHasher) add(outpoint wire.OutPoint, entry *UtxoEntry) {
+	if len(h.entries) > 0 && outpoint.Hash != h.txHash {
+		h.flushTx()
+	}
+	h.txHash = outpoint.Hash
+	h.entries[outpoint.Index] = entry
+}
+
+// flushTx serializes the outputs of the current transaction into the hashed
+// data the same way Bitcoin Core does for the hash_serialized_2.
+func (h *utxoSetHasher) flushTx() {
+	indexes := make([]uint32, 0, len(h.entries))
+	for index := range h.entries {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	var vlq [9]byte
+	writeVLQ := func(n uint64) {
+		h.hash.Write(vlq[:putVLQ(vlq[:], n)])
+	}
+
+	// Bitcoin Core commits to the height and coinbase flag of the first
+	// output as "height*2 + coinbase ? 1 : 0", which due to operator
+	// precedence is 1 for all but the genesis outputs.  Replicate it so
+	// that the hashes match.
+	first := h.entries[indexes[0]]
+	var heightCode uint64
+	if first.BlockHeight() != 0 || first.IsCoinBase() {
+		heightCode = 1
+	}
+	h.hash.Write(h.txHash[:])
+	writeVLQ(heightCode)
+
+	h.stats.Transactions++
+	for _, index := range indexes {
+		entry := h.entries[index]
+		writeVLQ(uint64(index) + 1)
+		wire.WriteVarBytes(h.hash, 0, entry.PkScript())
+		writeVLQ(uint64(entry.Amount()))
+
+		h.stats.TxOuts++
+		h.stats.TotalAmount += entry.Amount()
+		h.stats.BogoSize += 32 + 4 + 4 + 8 + 2 + int64(len(entry.PkScript()))
+		delete(h.entries, index)
+	}
+	writeVLQ(0)
+}
+
+// FetchUtxoSetStats returns statistics about the unspent transaction output set
+// as of the current best block of the main chain.  The utxo cache is flushed to
+// the database first, after which the set is read without blocking the chain
+// from processing new blocks.  An error is returned for nodes that don't keep
+// a utxo set, since compact state nodes only keep the utreexo accumulator.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FetchUtxoSetStats(interrupt <-chan struct{}) (*UtxoSetStats, error) {
+	if b.utxoCache == nil {
+		return nil, fmt.Errorf("the utxo set isn't kept by compact " +
+			"state nodes")
+	}
+
+	// Flush the cache and start reading the database while holding the
+	// chain lock so that the set is consistent with the best block.
+	b.chainLock.Lock()
+	best := b.BestSnapshot()
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return b.utxoCache.flush(dbTx, FlushRequired, best)
+	})
+	if err != nil {
+		b.chainLock.Unlock()
+		return nil, err
+	}
+	dbTx, err := b.db.Begin(false)
+	b.chainLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	stats := &UtxoSetStats{
+		Height: best.Height,
+		Hash:   best.Hash,
+	}
+	hasher := utxoSetHasher{
+		stats:   stats,
+		hash:    sha256.New(),
+		entries: make(map[uint32]*UtxoEntry),
+	}
+	hasher.hash.Write(best.Hash[:])
+
+	cursor := dbTx.Metadata().Bucket(utxoSetBucketName).Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		if interruptRequested(interrupt) {
+			return nil, errInterruptRequested
+		}
+
+		key := cursor.Key()
+		if len(key) <= chainhash.HashSize {
+			return nil, AssertError(fmt.Sprintf("utxo set contains "+
+				"invalid key %x", key))
+		}
+		var outpoint wire.OutPoint
+		copy(outpoint.Hash[:], key[:chainhash.HashSize])
+		index, _ := deserializeVLQ(key[chainhash.HashSize:])
+		outpoint.Index = uint32(index)
+
+		entry, err := deserializeUtxoEntry(cursor.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		stats.DiskSize += int64(len(key) + len(cursor.Value()))
+		hasher.add(outpoint, entry)
+	}
+	if len(hasher.entries) > 0 {
+		hasher.flushTx()
+	}
+	stats.HashSerialized = chainhash.HashH(hasher.hash.Sum(nil))
+
+	return stats, nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestFetchUtxoSetStats ensures that the statistics of the utxo set include the
+// entries in the utxo cache and that the serialized hash commits to the set.
+func TestFetchUtxoSetStats(t *testing.T) {
+	chain, params, tearDown := utxoCacheTestChain("TestFetchUtxoSetStats")
+	defer tearDown()
+	cache := chain.utxoCache
+
+	// Only the genesis block is connected and its coinbase isn't in the
+	// utxo set.
+	stats, err := chain.FetchUtxoSetStats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Height != 0 || stats.Hash != *params.GenesisHash ||
+		stats.TxOuts != 0 || stats.Transactions != 0 {
+
+		t.Fatalf("unexpected stats for the genesis block %+v", stats)
+	}
+	emptyHash := chainhash.DoubleHashH(params.GenesisHash[:])
+	if stats.HashSerialized != emptyHash {
+		t.Fatalf("expected the hash of the empty set to be %v, got %v",
+			emptyHash, stats.HashSerialized)
+	}
+
+	// Add a transaction with two outputs without flushing the cache.
+	script := getValidP2PKHScript()
+	txHash := chainhash.Hash{1}
+	for i := uint32(0); i < 2; i++ {
+		op := wire.OutPoint{Hash: txHash, Index: i}
+		txOut := wire.TxOut{Value: 10000, PkScript: script}
+		cache.addTxOut(op, &txOut, false, 1)
+	}
+
+	stats, err = chain.FetchUtxoSetStats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Transactions != 1 || stats.TxOuts != 2 ||
+		stats.TotalAmount != 20000 {
+
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.BogoSize != 2*(50+int64(len(script))) {
+		t.Fatalf("unexpected bogosize %d", stats.BogoSize)
+	}
+	if stats.DiskSize == 0 {
+		t.Fatalf("expected a disk size")
+	}
+	err = assertNbEntriesOnDisk(chain, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The outputs are serialized the same way as the hash_serialized_2 of
+	// Bitcoin Core.
+	var buf bytes.Buffer
+	buf.Write(params.GenesisHash[:])
+	buf.Write(txHash[:])
+	buf.WriteByte(1)
+	for i := byte(0); i < 2; i++ {
+		buf.WriteByte(i + 1)
+		wire.WriteVarBytes(&buf, 0, script)
+		buf.Write([]byte{0xcd, 0x10})
+	}
+	buf.WriteByte(0)
+	want := chainhash.DoubleHashH(buf.Bytes())
+	if stats.HashSerialized != want {
+		t.Fatalf("expected the serialized hash %v, got %v", want,
+			stats.HashSerialized)
+	}
+
+	// Spending an output changes the statistics and the hash.
+	spent := wire.OutPoint{Hash: txHash, Index: 1}
+	cache.addTxIn(&wire.TxIn{PreviousOutPoint: spent}, nil)
+	oldHash := stats.HashSerialized
+	stats, err = chain.FetchUtxoSetStats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TxOuts != 1 || stats.TotalAmount != 10000 {
+		t.Fatalf("unexpected stats %+v after spending", stats)
+	}
+	if stats.HashSerialized == oldHash {
+		t.Fatalf("expected the serialized hash to change")
+	}
+
+	// Interrupting the scan returns an error.
+	interrupt := make(chan struct{})
+	close(interrupt)
+	_, err = chain.FetchUtxoSetStats(interrupt)
+	if err != errInterruptRequested {
+		t.Fatalf("expected an interrupt error, got %v", err)
+	}
+}
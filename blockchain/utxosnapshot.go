@@ -0,0 +1,569 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// utxoSnapshotVersion is the version of the utxo set snapshot format
+	// that is written and read.  It's the same format as the one used by
+	// the dumptxoutset and loadtxoutset RPCs of Bitcoin Core.
+	utxoSnapshotVersion = 2
+
+	// utxoSnapshotBatchSize is the number of unspent outputs of a snapshot
+	// that are written to the database in a single transaction.
+	utxoSnapshotBatchSize = 100000
+)
+
+// utxoSnapshotMagic are the bytes that every utxo set snapshot starts with.
+var utxoSnapshotMagic = [5]byte{'u', 't', 'x', 'o', 0xff}
+
+// UtxoSnapshotInfo describes a snapshot of the unspent transaction output set.
+type UtxoSnapshotInfo struct {
+	// Height and Hash are of the block the snapshot is for.
+	Height int32
+	Hash   chainhash.Hash
+
+	// Coins is the number of unspent outputs in the snapshot.
+	Coins uint64
+
+	// HashSerialized is the hash of the serialized set, computed the same
+	// way as the hash_serialized_2 of Bitcoin Core.
+	HashSerialized chainhash.Hash
+}
+
+// writeUtxoSnapshotHeader writes the metadata a utxo set snapshot starts with.
+//
+// The serialized format is:
+//
+//	<magic><version><network magic><base block hash><coin count>
+//
+//	Field              Type              Size
+//	magic              [5]byte           5
+//	version            uint16            2
+//	network magic      uint32            4
+//	base block hash    chainhash.Hash    chainhash.HashSize
+//	coin count         uint64            8
+//
+// The integers are little endian.
+func writeUtxoSnapshotHeader(w io.Writer, net wire.BitcoinNet,
+	hash *chainhash.Hash, coins uint64) error {
+
+	var header [5 + 2 + 4 + chainhash.HashSize + 8]byte
+	copy(header[:], utxoSnapshotMagic[:])
+	binary.LittleEndian.PutUint16(header[5:], utxoSnapshotVersion)
+	binary.LittleEndian.PutUint32(header[7:], uint32(net))
+	copy(header[11:], hash[:])
+	binary.LittleEndian.PutUint64(header[11+chainhash.HashSize:], coins)
+	_, err := w.Write(header[:])
+	return err
+}
+
+// readUtxoSnapshotHeader reads and checks the metadata a utxo set snapshot
+// starts with and returns the base block hash and the number of coins.
+func readUtxoSnapshotHeader(r io.Reader, net wire.BitcoinNet) (
+	chainhash.Hash, uint64, error) {
+
+	var header [5 + 2 + 4 + chainhash.HashSize + 8]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return chainhash.Hash{}, 0, fmt.Errorf("unable to read the "+
+			"snapshot metadata: %v", err)
+	}
+	if !bytes.Equal(header[:5], utxoSnapshotMagic[:]) {
+		return chainhash.Hash{}, 0, fmt.Errorf("invalid snapshot magic "+
+			"bytes %x", header[:5])
+	}
+	version := binary.LittleEndian.Uint16(header[5:])
+	if version != utxoSnapshotVersion {
+		return chainhash.Hash{}, 0, fmt.Errorf("unsupported snapshot "+
+			"version %d", version)
+	}
+	snapshotNet := wire.BitcoinNet(binary.LittleEndian.Uint32(header[7:]))
+	if snapshotNet != net {
+		return chainhash.Hash{}, 0, fmt.Errorf("the snapshot is for "+
+			"network %v instead of %v", snapshotNet, net)
+	}
+
+	var hash chainhash.Hash
+	copy(hash[:], header[11:])
+	coins := binary.LittleEndian.Uint64(header[11+chainhash.HashSize:])
+	return hash, coins, nil
+}
+
+// readSnapshotVLQ reads a variable length quantity in the format described in
+// compress.go from the reader.  The serialized bytes are appended to buf.
+func readSnapshotVLQ(r io.ByteReader, buf []byte) (uint64, []byte, error) {
+	var n uint64
+	for i := 0; ; i++ {
+		if i == 9 {
+			return 0, buf, errDeserialize("variable length quantity " +
+				"is too large")
+		}
+		val, err := r.ReadByte()
+		if err != nil {
+			return 0, buf, err
+		}
+		buf = append(buf, val)
+		n = (n << 7) | uint64(val&0x7f)
+		if val&0x80 != 0x80 {
+			return n, buf, nil
+		}
+		n++
+	}
+}
+
+// readSnapshotCoin reads an unspent output of a snapshot that is serialized
+// the same way as in the database and returns the serialized bytes.
+func readSnapshotCoin(r *bufio.Reader) ([]byte, error) {
+	// Read the header code and the compressed amount.
+	_, serialized, err := readSnapshotVLQ(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, serialized, err = readSnapshotVLQ(r, serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the script size and then the rest of the compressed script.
+	scriptStart := len(serialized)
+	scriptCode, serialized, err := readSnapshotVLQ(r, serialized)
+	if err != nil {
+		return nil, err
+	}
+	if scriptCode >= numSpecialScripts &&
+		scriptCode-numSpecialScripts > txscript.MaxScriptSize {
+
+		return nil, errDeserialize(fmt.Sprintf("script size of %d "+
+			"is over the maximum", scriptCode-numSpecialScripts))
+	}
+	size := decodeCompressedScriptSize(serialized[scriptStart:])
+	remaining := size - (len(serialized) - scriptStart)
+	serialized = append(serialized, make([]byte, remaining)...)
+	_, err = io.ReadFull(r, serialized[len(serialized)-remaining:])
+	if err != nil {
+		return nil, err
+	}
+
+	return serialized, nil
+}
+
+// DumpUtxoSnapshot writes a snapshot of the unspent transaction output set as
+// of the current best block of the main chain to w.  The snapshot has the same
+// format as the ones of the dumptxoutset RPC of Bitcoin Core.  The utxo cache
+// is flushed to the database first, after which the set is read without
+// blocking the chain from processing new blocks.  An error is returned for
+// nodes that don't keep a utxo set.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) DumpUtxoSnapshot(w io.Writer, interrupt <-chan struct{}) (
+	*UtxoSnapshotInfo, error) {
+
+	if b.utxoCache == nil {
+		return nil, fmt.Errorf("the utxo set isn't kept by compact " +
+			"state nodes")
+	}
+
+	// Flush the cache and start reading the database while holding the
+	// chain lock so that the set is consistent with the best block.
+	b.chainLock.Lock()
+	best := b.BestSnapshot()
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return b.utxoCache.flush(dbTx, FlushRequired, best)
+	})
+	if err != nil {
+		b.chainLock.Unlock()
+		return nil, err
+	}
+	dbTx, err := b.db.Begin(false)
+	b.chainLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	// The number of coins comes before them, so count them first.
+	info := &UtxoSnapshotInfo{
+		Height: best.Height,
+		Hash:   best.Hash,
+	}
+	bucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+	cursor := bucket.Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		if interruptRequested(interrupt) {
+			return nil, errInterruptRequested
+		}
+		info.Coins++
+	}
+
+	bw := bufio.NewWriter(w)
+	err = writeUtxoSnapshotHeader(bw, b.chainParams.Net, &best.Hash,
+		info.Coins)
+	if err != nil {
+		return nil, err
+	}
+
+	// The coins are written grouped by the transaction they're from, so
+	// they're collected until the next transaction is reached.
+	hasher := utxoSetHasher{
+		stats:   &UtxoSetStats{},
+		hash:    sha256.New(),
+		entries: make(map[uint32]*UtxoEntry),
+	}
+	hasher.hash.Write(best.Hash[:])
+	var txHash chainhash.Hash
+	var indexes []uint32
+	var coins [][]byte
+	writeTx := func() error {
+		_, err := bw.Write(txHash[:])
+		if err != nil {
+			return err
+		}
+		err = wire.WriteVarInt(bw, 0, uint64(len(coins)))
+		if err != nil {
+			return err
+		}
+		for i, coin := range coins {
+			err = wire.WriteVarInt(bw, 0, uint64(indexes[i]))
+			if err != nil {
+				return err
+			}
+			_, err = bw.Write(coin)
+			if err != nil {
+				return err
+			}
+		}
+		indexes = indexes[:0]
+		coins = coins[:0]
+		return nil
+	}
+
+	var written uint64
+	cursor = bucket.Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		if interruptRequested(interrupt) {
+			return nil, errInterruptRequested
+		}
+
+		key := cursor.Key()
+		if len(key) <= chainhash.HashSize {
+			return nil, AssertError(fmt.Sprintf("utxo set contains "+
+				"invalid key %x", key))
+		}
+		var outpoint wire.OutPoint
+		copy(outpoint.Hash[:], key[:chainhash.HashSize])
+		index, _ := deserializeVLQ(key[chainhash.HashSize:])
+		outpoint.Index = uint32(index)
+
+		entry, err := deserializeUtxoEntry(cursor.Value())
+		if err != nil {
+			return nil, err
+		}
+		hasher.add(outpoint, entry)
+
+		if len(coins) > 0 && outpoint.Hash != txHash {
+			err = writeTx()
+			if err != nil {
+				return nil, err
+			}
+		}
+		txHash = outpoint.Hash
+		indexes = append(indexes, outpoint.Index)
+		coins = append(coins, append([]byte(nil), cursor.Value()...))
+		written++
+	}
+	if len(coins) > 0 {
+		err = writeTx()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(hasher.entries) > 0 {
+		hasher.flushTx()
+	}
+	if written != info.Coins {
+		return nil, AssertError(fmt.Sprintf("counted %d coins but "+
+			"wrote %d", info.Coins, written))
+	}
+	info.HashSerialized = chainhash.HashH(hasher.hash.Sum(nil))
+
+	return info, bw.Flush()
+}
+
+// LoadUtxoSnapshot loads a snapshot of the unspent transaction output set in
+// the format written by DumpUtxoSnapshot and makes the base block of the
+// snapshot the best block of the main chain.  The blocks up to the base block
+// are assumed to be valid and aren't downloaded, so the snapshot has to come
+// from a trusted source.  The utxo set of the best block is returned so that
+// it can be compared against the one of a trusted node.
+//
+// Since the snapshot doesn't contain any headers, the header of the base block
+// already has to be in the block index.  The snapshot can only be loaded by
+// nodes that keep a utxo set and have no indexes enabled, since the indexes
+// need every block, and only while the best block is the genesis block.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) LoadUtxoSnapshot(r io.Reader, interrupt <-chan struct{}) (
+	*UtxoSnapshotInfo, error) {
+
+	if b.utxoCache == nil {
+		return nil, fmt.Errorf("the utxo set isn't kept by compact " +
+			"state nodes")
+	}
+	if b.indexManager != nil {
+		return nil, fmt.Errorf("a snapshot can't be loaded with indexes " +
+			"enabled")
+	}
+
+	br := bufio.NewReader(r)
+	baseHash, coinCount, err := readUtxoSnapshotHeader(br, b.chainParams.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	if b.bestChain.Height() != 0 {
+		return nil, fmt.Errorf("a snapshot can only be loaded before " +
+			"any blocks are connected")
+	}
+	node := b.index.LookupNode(&baseHash)
+	if node == nil {
+		return nil, fmt.Errorf("the header of the snapshot base block %v "+
+			"isn't known", baseHash)
+	}
+	if b.index.NodeStatus(node).KnownInvalid() {
+		return nil, fmt.Errorf("the snapshot base block %v is invalid",
+			baseHash)
+	}
+
+	// The utxo set of the genesis block is empty, make sure it is so that
+	// it can be cleared again if the snapshot turns out to be invalid.
+	err = b.db.View(func(dbTx database.Tx) error {
+		cursor := dbTx.Metadata().Bucket(utxoSetBucketName).Cursor()
+		if cursor.First() || b.utxoCache.cachedEntries.length() != 0 {
+			return fmt.Errorf("the utxo set isn't empty")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := b.loadUtxoSnapshotCoins(br, node, coinCount, interrupt)
+	if err != nil {
+		// Remove the coins that were written so far.
+		clearErr := b.db.Update(func(dbTx database.Tx) error {
+			meta := dbTx.Metadata()
+			err := meta.DeleteBucket(utxoSetBucketName)
+			if err != nil {
+				return err
+			}
+			_, err = meta.CreateBucket(utxoSetBucketName)
+			return err
+		})
+		if clearErr != nil {
+			log.Errorf("Unable to clear the partially loaded utxo "+
+				"set: %v", clearErr)
+		}
+		return nil, err
+	}
+
+	// Mark the blocks up to the base block as valid since they're assumed
+	// to be valid by the snapshot.
+	for iterNode := node; iterNode != nil; iterNode = iterNode.parent {
+		if !iterNode.status.KnownValid() {
+			b.index.SetStatusFlags(iterNode, statusValid)
+		}
+	}
+
+	// The size and transactions of the base block aren't known without
+	// the block itself.
+	state := newBestState(node, 0, 0, 0, 0, node.CalcPastMedianTime())
+	err = b.index.flushToDB()
+	if err != nil {
+		return nil, err
+	}
+	err = b.db.Update(func(dbTx database.Tx) error {
+		err := dbPutUtxoStateConsistency(dbTx, &node.hash)
+		if err != nil {
+			return err
+		}
+
+		// The chain transaction counts of the blocks after the base
+		// block are counted from it.
+		err = dbPutChainTxCount(dbTx, node.height, 0)
+		if err != nil {
+			return err
+		}
+		return dbPutBestState(dbTx, state, node.workSum)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.bestChain.SetTip(node)
+	b.utxoCache.lastFlushHash = node.hash
+	b.stateLock.Lock()
+	b.stateSnapshot = state
+	b.stateLock.Unlock()
+
+	log.Infof("Loaded a utxo set snapshot of %d coins at block %v "+
+		"(height %d)", info.Coins, node.hash, node.height)
+
+	return info, nil
+}
+
+// loadUtxoSnapshotCoins writes the coins of the snapshot for the passed base
+// block to the database in batches.
+//
+// This function MUST be called with the chain lock held (for writes).
+func (b *BlockChain) loadUtxoSnapshotCoins(r *bufio.Reader, node *blockNode,
+	coinCount uint64, interrupt <-chan struct{}) (*UtxoSnapshotInfo, error) {
+
+	info := &UtxoSnapshotInfo{
+		Height: node.height,
+		Hash:   node.hash,
+	}
+	hasher := utxoSetHasher{
+		stats:   &UtxoSetStats{},
+		hash:    sha256.New(),
+		entries: make(map[uint32]*UtxoEntry),
+	}
+	hasher.hash.Write(node.hash[:])
+
+	type snapshotCoin struct {
+		key   []byte
+		value []byte
+	}
+	batch := make([]snapshotCoin, 0, utxoSnapshotBatchSize)
+	writeBatch := func() error {
+		err := b.db.Update(func(dbTx database.Tx) error {
+			bucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+			for _, coin := range batch {
+				err := bucket.Put(coin.key, coin.value)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		batch = batch[:0]
+		return err
+	}
+
+	var prevOutpoint *wire.OutPoint
+	for info.Coins < coinCount {
+		if interruptRequested(interrupt) {
+			return nil, errInterruptRequested
+		}
+
+		var txHash chainhash.Hash
+		_, err := io.ReadFull(r, txHash[:])
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the snapshot "+
+				"coins: %v", err)
+		}
+		count, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the snapshot "+
+				"coins: %v", err)
+		}
+		if count == 0 || count > coinCount-info.Coins {
+			return nil, fmt.Errorf("invalid number of coins %d for "+
+				"transaction %v", count, txHash)
+		}
+
+		for i := uint64(0); i < count; i++ {
+			index, err := wire.ReadVarInt(r, 0)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read the "+
+					"snapshot coins: %v", err)
+			}
+			if index > uint64(^uint32(0)) {
+				return nil, fmt.Errorf("invalid output index %d "+
+					"for transaction %v", index, txHash)
+			}
+			outpoint := wire.OutPoint{Hash: txHash, Index: uint32(index)}
+
+			// The coins have to be sorted the same way as in the
+			// database so that each one is only loaded once.
+			if prevOutpoint != nil && !outpointLess(prevOutpoint, &outpoint) {
+				return nil, fmt.Errorf("coin %v isn't sorted "+
+					"after coin %v", outpoint, prevOutpoint)
+			}
+			prevOutpoint = &outpoint
+
+			value, err := readSnapshotCoin(r)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read the "+
+					"snapshot coin %v: %v", outpoint, err)
+			}
+			entry, err := deserializeUtxoEntry(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid snapshot coin "+
+					"%v: %v", outpoint, err)
+			}
+			if entry.BlockHeight() > node.height {
+				return nil, fmt.Errorf("snapshot coin %v has a "+
+					"height of %d which is after the base "+
+					"block", outpoint, entry.BlockHeight())
+			}
+			hasher.add(outpoint, entry)
+
+			key := make([]byte, chainhash.HashSize+serializeSizeVLQ(index))
+			copy(key, txHash[:])
+			putVLQ(key[chainhash.HashSize:], index)
+			batch = append(batch, snapshotCoin{key: key, value: value})
+			if len(batch) == utxoSnapshotBatchSize {
+				err := writeBatch()
+				if err != nil {
+					return nil, err
+				}
+			}
+			info.Coins++
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("the snapshot contains more than %d "+
+			"coins", coinCount)
+	}
+	if len(batch) > 0 {
+		err := writeBatch()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(hasher.entries) > 0 {
+		hasher.flushTx()
+	}
+	info.HashSerialized = chainhash.HashH(hasher.hash.Sum(nil))
+
+	return info, nil
+}
+
+// outpointLess returns whether the outpoint a is sorted before the outpoint b
+// in the utxo set of the database.
+func outpointLess(a, b *wire.OutPoint) bool {
+	cmp := bytes.Compare(a.Hash[:], b.Hash[:])
+	if cmp != 0 {
+		return cmp < 0
+	}
+	return a.Index < b.Index
+}
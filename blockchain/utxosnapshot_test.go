@@ -0,0 +1,132 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// dumpTestUtxoSnapshot dumps a snapshot of a chain with the outputs of two
+// transactions and returns it along with the stats of the dumped utxo set.
+func dumpTestUtxoSnapshot(t *testing.T) ([]byte, *UtxoSnapshotInfo, *UtxoSetStats) {
+	chain, params, tearDown := utxoCacheTestChain("TestUtxoSnapshotDump")
+	defer tearDown()
+
+	// Add the outputs of two transactions without flushing the cache.
+	script := getValidP2PKHScript()
+	for _, txHash := range []chainhash.Hash{{2}, {1}} {
+		for i := uint32(0); i < 3; i++ {
+			op := wire.OutPoint{Hash: txHash, Index: i}
+			txOut := wire.TxOut{Value: 10000, PkScript: script}
+			chain.utxoCache.addTxOut(op, &txOut, i == 0, 0)
+		}
+	}
+
+	var snapshot bytes.Buffer
+	info, err := chain.DumpUtxoSnapshot(&snapshot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := chain.FetchUtxoSetStats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Coins != 6 || info.Hash != *params.GenesisHash ||
+		info.HashSerialized != stats.HashSerialized {
+
+		t.Fatalf("unexpected snapshot info %+v", info)
+	}
+
+	// The snapshot starts with the metadata followed by the coins of the
+	// first transaction.
+	serialized := snapshot.Bytes()
+	var want bytes.Buffer
+	want.Write([]byte{'u', 't', 'x', 'o', 0xff, 0x02, 0x00})
+	binary.Write(&want, binary.LittleEndian, uint32(params.Net))
+	want.Write(params.GenesisHash[:])
+	binary.Write(&want, binary.LittleEndian, uint64(6))
+	firstTx := chainhash.Hash{1}
+	want.Write(firstTx[:])
+	want.WriteByte(3)
+	want.WriteByte(0)
+	if !bytes.HasPrefix(serialized, want.Bytes()) {
+		t.Fatalf("unexpected snapshot prefix %x", serialized[:want.Len()])
+	}
+
+	return serialized, info, stats
+}
+
+// TestUtxoSnapshot ensures that a dumped utxo set snapshot has the format of
+// Bitcoin Core and that loading it restores the same utxo set.
+func TestUtxoSnapshot(t *testing.T) {
+	serialized, info, stats := dumpTestUtxoSnapshot(t)
+
+	// Loading the snapshot into a fresh chain restores the same set.
+	loaded, _, tearDown := utxoCacheTestChain("TestUtxoSnapshot")
+	defer tearDown()
+
+	loadInfo, err := loaded.LoadUtxoSnapshot(bytes.NewReader(serialized), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loadInfo != *info {
+		t.Fatalf("expected the loaded snapshot %+v, got %+v", info,
+			loadInfo)
+	}
+	loadedStats, err := loaded.FetchUtxoSetStats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loadedStats != *stats {
+		t.Fatalf("expected the loaded stats %+v, got %+v", stats,
+			loadedStats)
+	}
+
+	// A snapshot can't be loaded over an existing utxo set.
+	_, err = loaded.LoadUtxoSnapshot(bytes.NewReader(serialized), nil)
+	if err == nil {
+		t.Fatalf("expected an error loading over an existing set")
+	}
+}
+
+// TestLoadInvalidUtxoSnapshot ensures that invalid snapshots are rejected and
+// leave the utxo set empty.
+func TestLoadInvalidUtxoSnapshot(t *testing.T) {
+	serialized, _, _ := dumpTestUtxoSnapshot(t)
+
+	invalid, _, tearDown := utxoCacheTestChain("TestLoadInvalidUtxoSnapshot")
+	defer tearDown()
+
+	trailing := append(append([]byte(nil), serialized...), 0)
+	unknownBase := append([]byte(nil), serialized...)
+	unknownBase[11] ^= 0xff
+	wrongNet := append([]byte(nil), serialized...)
+	wrongNet[7] ^= 0xff
+	tests := []struct {
+		name     string
+		snapshot []byte
+	}{
+		{name: "truncated", snapshot: serialized[:len(serialized)-1]},
+		{name: "trailing data", snapshot: trailing},
+		{name: "unknown base block", snapshot: unknownBase},
+		{name: "wrong network", snapshot: wrongNet},
+		{name: "wrong magic", snapshot: serialized[1:]},
+	}
+	for _, test := range tests {
+		_, err := invalid.LoadUtxoSnapshot(bytes.NewReader(test.snapshot), nil)
+		if err == nil {
+			t.Fatalf("%s: expected an error", test.name)
+		}
+		err = assertNbEntriesOnDisk(invalid, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
@@ -710,6 +710,12 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 		return ruleError(ErrBadCheckpoint, str)
 	}
 
+	// Ensure the block matches the utreexo root checkpoint at its height.
+	err := b.checkUtreexoRootCheckpointHash(blockHeight, &blockHash)
+	if err != nil {
+		return err
+	}
+
 	// Find the previous checkpoint and prevent blocks which fork the main
 	// chain before it.  This prevents storage of new, otherwise valid,
 	// blocks which build off of old blocks that are likely at a much easier
@@ -1075,7 +1081,13 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block,
 	// If utreexo accumulators are enabled, then check that the accumulator
 	// proof is ok.  Then convert the msgBlock.UData into UtxoViewpoint.
 	if utreexoView != nil {
-		err := utreexoView.ProcessUData(block, b.bestChain, block.MsgBlock().UData)
+		err := b.checkUtreexoViewRootCheckpoint(utreexoView, node, block)
+		if err != nil {
+			return err
+		}
+
+		err = utreexoView.ProcessUData(block, b.bestChain, block.MsgBlock().UData,
+			b.rememberPolicy, b.leafHashCache)
 		if err != nil {
 			return fmt.Errorf("checkConnectBlock fail. error: %v", err)
 		}
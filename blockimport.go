@@ -0,0 +1,425 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// blockImportLogInterval is how often the progress of a block import
+	// is logged.
+	blockImportLogInterval = 10 * time.Second
+
+	// coreXorKeyFileName is the name of the file Bitcoin Core keeps the key
+	// its block files are obfuscated with in, next to the block files.
+	coreXorKeyFileName = "xor.dat"
+)
+
+// blockImportFile is a file of serialized blocks to import.  Each block is
+// preceded by the network magic and its length, which is the format of both
+// bootstrap.dat and the blk*.dat files of Bitcoin Core.
+type blockImportFile struct {
+	path string
+
+	// xorKey is the key the file is obfuscated with, which is applied
+	// repeatedly from the start of the file.  It's empty when the file
+	// isn't obfuscated.
+	xorKey []byte
+}
+
+// blockImportPos is the position of a block in one of the import files.
+type blockImportPos struct {
+	file *blockImportFile
+
+	// offset is the offset of the length that precedes the block.
+	offset int64
+}
+
+// blockImportFiles returns the files to import for the passed --importblocks
+// paths in order.  A directory stands for the blk*.dat files in it, as in the
+// blocks directory of Bitcoin Core.  The block files are deobfuscated with the
+// key in the xor.dat file next to them when there's one.
+func blockImportFiles(paths []string) ([]*blockImportFile, error) {
+	var files []*blockImportFile
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		dir, names := filepath.Dir(path), []string{filepath.Base(path)}
+		if fi.IsDir() {
+			dir = path
+			names, err = filepath.Glob(filepath.Join(dir, "blk*.dat"))
+			if err != nil {
+				return nil, err
+			}
+			if len(names) == 0 {
+				return nil, fmt.Errorf("no blk*.dat files in %s", dir)
+			}
+			for i := range names {
+				names[i] = filepath.Base(names[i])
+			}
+			sort.Strings(names)
+		}
+
+		var xorKey []byte
+		if matched, _ := filepath.Match("blk*.dat", names[0]); matched {
+			xorKey, err = readCoreXorKey(dir)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range names {
+			files = append(files, &blockImportFile{
+				path:   filepath.Join(dir, name),
+				xorKey: xorKey,
+			})
+		}
+	}
+
+	return files, nil
+}
+
+// readCoreXorKey returns the key the block files of Bitcoin Core in the passed
+// directory are obfuscated with.  No key is returned when there's no key file,
+// which is the case for the files written before Bitcoin Core 28.0, or when the
+// key is all zeros.
+func readCoreXorKey(dir string) ([]byte, error) {
+	key, err := os.ReadFile(filepath.Join(dir, coreXorKeyFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("the key in %s is empty",
+			filepath.Join(dir, coreXorKeyFileName))
+	}
+	for _, b := range key {
+		if b != 0 {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// xorReader deobfuscates what's read from an obfuscated block file.
+type xorReader struct {
+	r   io.Reader
+	key []byte
+
+	// offset is the offset in the file of the next byte that's read.
+	offset int64
+}
+
+// Read reads from the underlying reader and deobfuscates what it read.
+func (x *xorReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	if len(x.key) != 0 {
+		for i := 0; i < n; i++ {
+			p[i] ^= x.key[(x.offset+int64(i))%int64(len(x.key))]
+		}
+	}
+	x.offset += int64(n)
+	return n, err
+}
+
+// readImportBlock reads a block at the offset of its length with the passed
+// reader.  Nil is returned when the block is cut off by the end of the file,
+// which happens when the writer of the file stopped while writing it.  An
+// error is returned when the length isn't that of a block.
+func readImportBlock(r *xorReader) (*btcutil.Block, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	blockLen := binary.LittleEndian.Uint32(lenBuf[:])
+	if blockLen < wire.MaxBlockHeaderPayload || blockLen > wire.MaxBlockPayload {
+		return nil, fmt.Errorf("block length of %d bytes is out of range",
+			blockLen)
+	}
+
+	serializedBlock := make([]byte, blockLen)
+	if _, err := io.ReadFull(r, serializedBlock); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return btcutil.NewBlockFromBytes(serializedBlock)
+}
+
+// blockImporter imports the blocks of local files into the chain with the same
+// validation as blocks downloaded from peers, which builds the indexes and the
+// utreexo accumulators of a bridge just the same.
+type blockImporter struct {
+	chain     *blockchain.BlockChain
+	net       wire.BitcoinNet
+	interrupt <-chan struct{}
+
+	// pending are the positions of the blocks whose parent isn't known
+	// yet by the hash of the parent.  Bitcoin Core writes the blocks in
+	// the order they're downloaded in, so a block can be written before
+	// its parent.
+	pending      map[chainhash.Hash][]blockImportPos
+	pendingCount int
+
+	imported    int
+	known       int
+	rejected    int
+	logImported int
+	lastLogTime time.Time
+}
+
+// importBlockFiles imports the blocks of the files passed with --importblocks
+// into the chain.  The files are read in order, and the blocks that come before
+// their parent are imported once the parent is.  Already known blocks are
+// skipped and blocks that break the rules of the chain are logged and left
+// out, so that the import can be repeated with the same files and the stale
+// blocks in the files of Bitcoin Core don't stop it.  The import stops with no
+// error when the passed channel is closed.
+func importBlockFiles(chain *blockchain.BlockChain, net wire.BitcoinNet,
+	paths []string, interrupt <-chan struct{}) error {
+
+	files, err := blockImportFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	bi := &blockImporter{
+		chain:       chain,
+		net:         net,
+		interrupt:   interrupt,
+		pending:     make(map[chainhash.Hash][]blockImportPos),
+		lastLogTime: time.Now(),
+	}
+	start := time.Now()
+	for _, file := range files {
+		if interruptRequested(interrupt) {
+			break
+		}
+		srvrLog.Infof("Importing blocks from %s", file.path)
+		if err := bi.importFile(file); err != nil {
+			return fmt.Errorf("unable to import the blocks from %s: %v",
+				file.path, err)
+		}
+	}
+
+	// The changes to the utxo set are flushed so that nothing is lost
+	// when the import was interrupted.
+	if err := chain.FlushUtxoCache(blockchain.FlushRequired); err != nil {
+		return err
+	}
+	if interruptRequested(interrupt) {
+		srvrLog.Infof("Block import interrupted after importing %d "+
+			"blocks", bi.imported)
+		return nil
+	}
+
+	best := chain.BestSnapshot()
+	srvrLog.Infof("Imported %d blocks in %v (%d already known, %d "+
+		"rejected, height %d)", bi.imported,
+		time.Since(start).Round(time.Second), bi.known, bi.rejected,
+		best.Height)
+	if bi.pendingCount > 0 {
+		srvrLog.Warnf("Skipped %d blocks whose parent isn't in the "+
+			"imported files", bi.pendingCount)
+	}
+	return nil
+}
+
+// importFile imports the blocks of the passed file.  Each block is found by
+// looking for the network magic, which skips the zeros Bitcoin Core
+// preallocates the end of its files with.
+func (bi *blockImporter) importFile(file *blockImportFile) error {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := &xorReader{r: bufio.NewReader(f), key: file.xorKey}
+	var magic, window [4]byte
+	binary.LittleEndian.PutUint32(magic[:], uint32(bi.net))
+	var b [1]byte
+	for !interruptRequested(bi.interrupt) {
+		// Look for the magic that precedes the next block.
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		copy(window[:], window[1:])
+		window[3] = b[0]
+		if window != magic {
+			continue
+		}
+		window = [4]byte{}
+
+		pos := blockImportPos{file: file, offset: r.offset}
+		block, err := readImportBlock(r)
+		if err != nil {
+			srvrLog.Debugf("Skipping the data at offset %d of %s: %v",
+				pos.offset, file.path, err)
+			continue
+		}
+		if block == nil {
+			return nil
+		}
+		if err := bi.importBlock(block, pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readBlockAt reads the block at the passed position again.
+func readBlockAt(pos blockImportPos) (*btcutil.Block, error) {
+	f, err := os.Open(pos.file.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r := &xorReader{r: bufio.NewReader(f), key: pos.file.xorKey,
+		offset: pos.offset}
+	block, err := readImportBlock(r)
+	if err == nil && block == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return block, err
+}
+
+// importBlock processes the passed block, which was read at the passed
+// position, unless it's already known, and then the blocks that are waiting
+// for it.  The block waits for its parent when the parent isn't known yet.
+func (bi *blockImporter) importBlock(block *btcutil.Block, pos blockImportPos) error {
+	exists, err := bi.chain.HaveBlock(block.Hash())
+	if err != nil {
+		return err
+	}
+	if exists {
+		bi.known++
+		return nil
+	}
+
+	prevHash := block.MsgBlock().Header.PrevBlock
+	exists, err = bi.chain.HaveBlock(&prevHash)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		bi.pending[prevHash] = append(bi.pending[prevHash], pos)
+		bi.pendingCount++
+		return nil
+	}
+
+	queue := []*btcutil.Block{block}
+	for len(queue) > 0 && !interruptRequested(bi.interrupt) {
+		block := queue[0]
+		queue = queue[1:]
+
+		accepted, err := bi.processBlock(block)
+		if err != nil {
+			return err
+		}
+
+		// The children of a rejected block keep waiting since a block
+		// with a malformed body has the hash of the valid one, which
+		// may come later.
+		if !accepted {
+			continue
+		}
+		children := bi.pending[*block.Hash()]
+		delete(bi.pending, *block.Hash())
+		bi.pendingCount -= len(children)
+		for _, pos := range children {
+			child, err := readBlockAt(pos)
+			if err != nil {
+				return fmt.Errorf("unable to read the block at "+
+					"offset %d of %s again: %v", pos.offset,
+					pos.file.path, err)
+			}
+			queue = append(queue, child)
+		}
+	}
+
+	return nil
+}
+
+// processBlock runs the passed block through the chain, which fully validates
+// it, and returns whether it's in the chain afterwards.
+func (bi *blockImporter) processBlock(block *btcutil.Block) (bool, error) {
+	exists, err := bi.chain.HaveBlock(block.Hash())
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		bi.known++
+		return true, nil
+	}
+
+	_, _, err = bi.chain.ProcessBlock(block, blockchain.BFNone)
+	if err != nil {
+		if _, ok := err.(blockchain.RuleError); !ok {
+			return false, err
+		}
+		srvrLog.Warnf("Rejected imported block %v: %v", block.Hash(), err)
+		bi.rejected++
+		return false, nil
+	}
+	if err := bi.chain.FlushUtxoCache(blockchain.FlushPeriodic); err != nil {
+		return false, err
+	}
+
+	bi.imported++
+	bi.logImported++
+	bi.logProgress(block)
+	return true, nil
+}
+
+// logProgress logs how many blocks were imported since the last time, along
+// with the last imported block, at most once every blockImportLogInterval.
+func (bi *blockImporter) logProgress(block *btcutil.Block) {
+	now := time.Now()
+	duration := now.Sub(bi.lastLogTime)
+	if duration < blockImportLogInterval {
+		return
+	}
+
+	blockStr := "blocks"
+	if bi.logImported == 1 {
+		blockStr = "block"
+	}
+	srvrLog.Infof("Imported %d %s in the last %s (height %d, %s)",
+		bi.logImported, blockStr, duration.Round(10*time.Millisecond),
+		block.Height(), block.MsgBlock().Header.Timestamp)
+
+	bi.logImported = 0
+	bi.lastLogTime = now
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btclog"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// loadImportTestBlocks returns the serialized main network blocks 0 to 4 of the
+// blockchain test data.
+func loadImportTestBlocks(t *testing.T) [][]byte {
+	f, err := os.Open(filepath.Join("blockchain", "testdata",
+		"blk_0_to_4.dat.bz2"))
+	if err != nil {
+		t.Fatalf("unable to open the test blocks: %v", err)
+	}
+	defer f.Close()
+
+	var blocks [][]byte
+	r := bzip2.NewReader(f)
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+			return blocks
+		} else if err != nil {
+			t.Fatalf("unable to read the test blocks: %v", err)
+		}
+		block := make([]byte, binary.LittleEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(r, block); err != nil {
+			t.Fatalf("unable to read the test blocks: %v", err)
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+// writeImportTestFile writes the passed blocks to a block file obfuscated with
+// the passed key, followed by the passed trailing bytes.
+func writeImportTestFile(t *testing.T, path string, key []byte,
+	blocks [][]byte, trailer []byte) {
+
+	var buf bytes.Buffer
+	for _, block := range blocks {
+		binary.Write(&buf, binary.LittleEndian, uint32(wire.MainNet))
+		binary.Write(&buf, binary.LittleEndian, uint32(len(block)))
+		buf.Write(block)
+	}
+	buf.Write(trailer)
+
+	data := buf.Bytes()
+	for i := range data {
+		if len(key) != 0 {
+			data[i] ^= key[i%len(key)]
+		}
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("unable to write %s: %v", path, err)
+	}
+}
+
+// TestImportBlockFiles ensures that the blocks of an obfuscated Bitcoin Core
+// blocks directory are imported when they come before their parents, that
+// invalid blocks and the preallocated and cut off ends of the files are
+// skipped, and that importing the files again changes nothing.
+func TestImportBlockFiles(t *testing.T) {
+	oldLog := srvrLog
+	srvrLog = btclog.Disabled
+	blockchain.UseLogger(btclog.Disabled)
+	defer func() {
+		srvrLog = oldLog
+		blockchain.UseLogger(chanLog)
+	}()
+
+	blocks := loadImportTestBlocks(t)
+	if len(blocks) != 5 {
+		t.Fatalf("got %d test blocks, want 5", len(blocks))
+	}
+
+	// A copy of block 3 with a different coinbase breaks the merkle root.
+	badBlock := make([]byte, len(blocks[3]))
+	copy(badBlock, blocks[3])
+	badBlock[len(badBlock)-10] ^= 0xff
+
+	dir := t.TempDir()
+	key := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	if err := os.WriteFile(filepath.Join(dir, coreXorKeyFileName), key, 0600); err != nil {
+		t.Fatalf("unable to write the key: %v", err)
+	}
+	writeImportTestFile(t, filepath.Join(dir, "blk00000.dat"), key,
+		[][]byte{blocks[0], blocks[2], blocks[1]}, make([]byte, 1000))
+	writeImportTestFile(t, filepath.Join(dir, "blk00001.dat"), key,
+		[][]byte{blocks[4], badBlock, blocks[3]},
+		[]byte{0xf9, 0xbe, 0xb4, 0xd9, 0xff, 0x00})
+
+	db, err := database.Create("ffldb", filepath.Join(t.TempDir(), "db"),
+		wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to create the database: %v", err)
+	}
+	defer db.Close()
+	// The test blocks spend the coinbases of the blocks before them.
+	params := chaincfg.MainNetParams
+	params.CoinbaseMaturity = 1
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  blockchain.NewMedianTime(),
+	})
+	if err != nil {
+		t.Fatalf("unable to create the chain: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		err := importBlockFiles(chain, wire.MainNet, []string{dir}, nil)
+		if err != nil {
+			t.Fatalf("importBlockFiles #%d: unexpected error: %v", i, err)
+		}
+
+		best := chain.BestSnapshot()
+		want, _ := btcutil.NewBlockFromBytes(blocks[4])
+		if best.Height != 4 || best.Hash != *want.Hash() {
+			t.Fatalf("importBlockFiles #%d: got tip %v at height %d, "+
+				"want %v at height 4", i, best.Hash, best.Height,
+				want.Hash())
+		}
+	}
+}
@@ -16,6 +16,19 @@ import (
 	"github.com/utreexo/utreexod/wire"
 )
 
+// AbandonTransactionCmd defines the abandontransaction JSON-RPC command.
+type AbandonTransactionCmd struct {
+	TxID string
+}
+
+// NewAbandonTransactionCmd returns a new instance which can be used to issue an
+// abandontransaction JSON-RPC command.
+func NewAbandonTransactionCmd(txID string) *AbandonTransactionCmd {
+	return &AbandonTransactionCmd{
+		TxID: txID,
+	}
+}
+
 // AddNodeSubCmd defines the type used in the addnode JSON-RPC command for the
 // sub command field.
 type AddNodeSubCmd string
@@ -48,6 +61,19 @@ func NewAddNodeCmd(addr string, subCmd AddNodeSubCmd) *AddNodeCmd {
 	}
 }
 
+// BackupDataDirCmd defines the backupdatadir JSON-RPC command.
+type BackupDataDirCmd struct {
+	Destination string
+}
+
+// NewBackupDataDirCmd returns a new instance which can be used to issue a
+// backupdatadir JSON-RPC command.
+func NewBackupDataDirCmd(destination string) *BackupDataDirCmd {
+	return &BackupDataDirCmd{
+		Destination: destination,
+	}
+}
+
 // BalanceCmd defines the balance JSON-RPC command.
 type BalanceCmd struct{}
 
@@ -57,6 +83,24 @@ func NewBalanceCmd() *BalanceCmd {
 	return &BalanceCmd{}
 }
 
+// ClearBannedCmd defines the clearbanned JSON-RPC command.
+type ClearBannedCmd struct{}
+
+// NewClearBannedCmd returns a new instance which can be used to issue a
+// clearbanned JSON-RPC command.
+func NewClearBannedCmd() *ClearBannedCmd {
+	return &ClearBannedCmd{}
+}
+
+// CompactDBCmd defines the compactdb JSON-RPC command.
+type CompactDBCmd struct{}
+
+// NewCompactDBCmd returns a new instance which can be used to issue a compactdb
+// JSON-RPC command.
+func NewCompactDBCmd() *CompactDBCmd {
+	return &CompactDBCmd{}
+}
+
 // TransactionInput represents the inputs to a transaction.  Specifically a
 // transaction hash and output number pair.
 type TransactionInput struct {
@@ -66,22 +110,53 @@ type TransactionInput struct {
 
 // Recipient is the recipient information needed to create a transaction from the bdk wallet.
 type Recipient struct {
-	Amount  int64  `json:"amount"`
-	Address string `json:"address"`
+	Amount      int64  `json:"amount"`
+	Address     string `json:"address"`
+	SubtractFee bool   `json:"subtractfee,omitempty"`
+}
+
+// CpfpBDKTransactionCmd defines the cpfpbdktransaction JSON-RPC command.
+type CpfpBDKTransactionCmd struct {
+	TxID    string
+	FeeRate *float64 // In sat/vB
+}
+
+// NewCpfpBDKTransactionCmd returns a new instance which can be used to issue a
+// cpfpbdktransaction JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewCpfpBDKTransactionCmd(txID string, feeRate *float64) *CpfpBDKTransactionCmd {
+	return &CpfpBDKTransactionCmd{
+		TxID:    txID,
+		FeeRate: feeRate,
+	}
 }
 
 // CreateTransactionFromBDKWalletCmd defines the createtransactionfrombdkwallet JSON-RPC command.
 type CreateTransactionFromBDKWalletCmd struct {
-	FeeRate    float32
-	Recipients []Recipient
+	FeeRate       float32
+	Recipients    []Recipient
+	CoinSelection *string `jsonrpcdefault:"\"branchandbound\""`
+	Inputs        *[]TransactionInput
+	ConfTarget    *int64 `jsonrpcdefault:"6"`
 }
 
 // NewCreateTransactionFromBDKWalletCmd returns a new instance which can be used to issue
 // a createtransactionfrombdkwallet command.
-func NewCreateTransactionFromBDKWalletCmd(feeRate float32, recipients []Recipient) *CreateTransactionFromBDKWalletCmd {
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewCreateTransactionFromBDKWalletCmd(feeRate float32, recipients []Recipient,
+	coinSelection *string, inputs *[]TransactionInput,
+	confTarget *int64) *CreateTransactionFromBDKWalletCmd {
+
 	return &CreateTransactionFromBDKWalletCmd{
-		FeeRate:    feeRate,
-		Recipients: recipients,
+		FeeRate:       feeRate,
+		Recipients:    recipients,
+		CoinSelection: coinSelection,
+		Inputs:        inputs,
+		ConfTarget:    confTarget,
 	}
 }
 
@@ -152,6 +227,62 @@ func NewDeriveAddressesCmd(descriptor string, descriptorRange *DescriptorRange)
 	}
 }
 
+// DumpTxOutSetCmd defines the dumptxoutset JSON-RPC command.
+type DumpTxOutSetCmd struct {
+	Path string
+}
+
+// NewDumpTxOutSetCmd returns a new instance which can be used to issue a
+// dumptxoutset JSON-RPC command.
+func NewDumpTxOutSetCmd(path string) *DumpTxOutSetCmd {
+	return &DumpTxOutSetCmd{
+		Path: path,
+	}
+}
+
+// EnumerateSignersCmd defines the enumeratesigners JSON-RPC command.
+type EnumerateSignersCmd struct{}
+
+// NewEnumerateSignersCmd returns a new instance which can be used to issue an
+// enumeratesigners JSON-RPC command.
+func NewEnumerateSignersCmd() *EnumerateSignersCmd {
+	return &EnumerateSignersCmd{}
+}
+
+// ExportBDKTransactionsCmd defines the exportbdktransactions JSON-RPC command.
+type ExportBDKTransactionsCmd struct {
+	Format *string `jsonrpcdefault:"\"json\""`
+}
+
+// NewExportBDKTransactionsCmd returns a new instance which can be used to issue
+// an exportbdktransactions JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewExportBDKTransactionsCmd(format *string) *ExportBDKTransactionsCmd {
+	return &ExportBDKTransactionsCmd{
+		Format: format,
+	}
+}
+
+// EstimateRawFeeCmd defines the estimaterawfee JSON-RPC command.
+type EstimateRawFeeCmd struct {
+	ConfTarget int64
+	Threshold  *float64 `jsonrpcdefault:"0.95"`
+}
+
+// NewEstimateRawFeeCmd returns a new instance which can be used to issue a
+// estimaterawfee JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewEstimateRawFeeCmd(confTarget int64, threshold *float64) *EstimateRawFeeCmd {
+	return &EstimateRawFeeCmd{
+		ConfTarget: confTarget,
+		Threshold:  threshold,
+	}
+}
+
 // ChangeType defines the different output types to use for the change address
 // of a transaction built by the node.
 type ChangeType string
@@ -179,13 +310,38 @@ type FundRawTransactionOpts struct {
 	EstimateMode           *EstimateSmartFeeMode `json:"estimate_mode,omitempty"`
 }
 
+// FinalizePsbtCmd defines the finalizepsbt JSON-RPC command.
+type FinalizePsbtCmd struct {
+	Psbt    string
+	Extract *bool `jsonrpcdefault:"true"`
+}
+
+// NewFinalizePsbtCmd returns a new instance which can be used to issue a
+// finalizepsbt JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewFinalizePsbtCmd(psbt string, extract *bool) *FinalizePsbtCmd {
+	return &FinalizePsbtCmd{
+		Psbt:    psbt,
+		Extract: extract,
+	}
+}
+
 // FreshAddressCmd defines the freshaddress JSON-RPC command
-type FreshAddressCmd struct{}
+type FreshAddressCmd struct {
+	Label *string
+}
 
 // NewFreshAddressCmd returns a new instance which can be used to issue a
 // freshaddress JSON-RPC command.
-func NewFreshAddressCmd() *FreshAddressCmd {
-	return &FreshAddressCmd{}
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewFreshAddressCmd(label *string) *FreshAddressCmd {
+	return &FreshAddressCmd{
+		Label: label,
+	}
 }
 
 // FundRawTransactionCmd defines the fundrawtransaction JSON-RPC command
@@ -332,6 +488,21 @@ func NewGetBlockHeaderCmd(hash string, verbose *bool) *GetBlockHeaderCmd {
 	}
 }
 
+// GetBlocksByTimeCmd defines the getblocksbytime JSON-RPC command.
+type GetBlocksByTimeCmd struct {
+	StartTime int64
+	EndTime   int64
+}
+
+// NewGetBlocksByTimeCmd returns a new instance which can be used to issue a
+// getblocksbytime JSON-RPC command.
+func NewGetBlocksByTimeCmd(startTime, endTime int64) *GetBlocksByTimeCmd {
+	return &GetBlocksByTimeCmd{
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+}
+
 // HashOrHeight defines a type that can be used as hash_or_height value in JSON-RPC commands.
 type HashOrHeight struct {
 	Value interface{}
@@ -376,6 +547,19 @@ func NewGetBlockStatsCmd(hashOrHeight HashOrHeight, stats *[]string) *GetBlockSt
 	}
 }
 
+// GetBlockTTLsCmd defines the getblockttls JSON-RPC command.
+type GetBlockTTLsCmd struct {
+	BlockHash string
+}
+
+// NewGetBlockTTLsCmd returns a new instance which can be used to issue a
+// getblockttls JSON-RPC command.
+func NewGetBlockTTLsCmd(blockHash string) *GetBlockTTLsCmd {
+	return &GetBlockTTLsCmd{
+		BlockHash: blockHash,
+	}
+}
+
 // TemplateRequest is a request object as defined in BIP22
 // (https://en.bitcoin.it/wiki/BIP_0022), it is optionally provided as an
 // pointer argument to GetBlockTemplateCmd.
@@ -403,6 +587,14 @@ type TemplateRequest struct {
 	// list of supported softfork deployments, by name
 	// Ref: https://en.bitcoin.it/wiki/BIP_0009#getblocktemplate_changes.
 	Rules []string `json:"rules,omitempty"`
+
+	// Optional overrides of the mining policy of the template.
+	BlockMinTxFee  *float64 `json:"blockmintxfee,omitempty"`
+	BlockMaxWeight *uint32  `json:"blockmaxweight,omitempty"`
+
+	// Optional long poll ID of a previously returned template to return
+	// the template as a delta against.
+	BaseTemplate string `json:"basetemplate,omitempty"`
 }
 
 // convertTemplateRequestField potentially converts the provided value as
@@ -535,6 +727,15 @@ func NewGetConnectionCountCmd() *GetConnectionCountCmd {
 	return &GetConnectionCountCmd{}
 }
 
+// GetDBCompactionInfoCmd defines the getdbcompactioninfo JSON-RPC command.
+type GetDBCompactionInfoCmd struct{}
+
+// NewGetDBCompactionInfoCmd returns a new instance which can be used to issue a
+// getdbcompactioninfo JSON-RPC command.
+func NewGetDBCompactionInfoCmd() *GetDBCompactionInfoCmd {
+	return &GetDBCompactionInfoCmd{}
+}
+
 // GetDescriptorInfoCmd defines the getdescriptorinfo JSON-RPC command.
 type GetDescriptorInfoCmd struct {
 	Descriptor string
@@ -575,6 +776,22 @@ func NewGetHashesPerSecCmd() *GetHashesPerSecCmd {
 	return &GetHashesPerSecCmd{}
 }
 
+// GetIndexInfoCmd defines the getindexinfo JSON-RPC command.
+type GetIndexInfoCmd struct {
+	IndexName *string
+}
+
+// NewGetIndexInfoCmd returns a new instance which can be used to issue a
+// getindexinfo JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetIndexInfoCmd(indexName *string) *GetIndexInfoCmd {
+	return &GetIndexInfoCmd{
+		IndexName: indexName,
+	}
+}
+
 // GetInfoCmd defines the getinfo JSON-RPC command.
 type GetInfoCmd struct{}
 
@@ -584,6 +801,42 @@ func NewGetInfoCmd() *GetInfoCmd {
 	return &GetInfoCmd{}
 }
 
+// GetMempoolAncestorsCmd defines the getmempoolancestors JSON-RPC command.
+type GetMempoolAncestorsCmd struct {
+	TxID    string
+	Verbose *bool `jsonrpcdefault:"false"`
+}
+
+// NewGetMempoolAncestorsCmd returns a new instance which can be used to issue
+// a getmempoolancestors JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetMempoolAncestorsCmd(txHash string, verbose *bool) *GetMempoolAncestorsCmd {
+	return &GetMempoolAncestorsCmd{
+		TxID:    txHash,
+		Verbose: verbose,
+	}
+}
+
+// GetMempoolDescendantsCmd defines the getmempooldescendants JSON-RPC command.
+type GetMempoolDescendantsCmd struct {
+	TxID    string
+	Verbose *bool `jsonrpcdefault:"false"`
+}
+
+// NewGetMempoolDescendantsCmd returns a new instance which can be used to
+// issue a getmempooldescendants JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetMempoolDescendantsCmd(txHash string, verbose *bool) *GetMempoolDescendantsCmd {
+	return &GetMempoolDescendantsCmd{
+		TxID:    txHash,
+		Verbose: verbose,
+	}
+}
+
 // GetMempoolEntryCmd defines the getmempoolentry JSON-RPC command.
 type GetMempoolEntryCmd struct {
 	TxID string
@@ -731,6 +984,33 @@ func NewGetRawTransactionCmd(txHash string, verbose *int) *GetRawTransactionCmd
 	}
 }
 
+// GetScriptBalanceCmd defines the getscriptbalance JSON-RPC command.
+type GetScriptBalanceCmd struct {
+	ScriptHash string
+}
+
+// NewGetScriptBalanceCmd returns a new instance which can be used to issue a
+// getscriptbalance JSON-RPC command.
+func NewGetScriptBalanceCmd(scriptHash string) *GetScriptBalanceCmd {
+	return &GetScriptBalanceCmd{
+		ScriptHash: scriptHash,
+	}
+}
+
+// GetSilentPaymentTweaksCmd defines the getsilentpaymenttweaks JSON-RPC
+// command.
+type GetSilentPaymentTweaksCmd struct {
+	BlockHash string
+}
+
+// NewGetSilentPaymentTweaksCmd returns a new instance which can be used to
+// issue a getsilentpaymenttweaks JSON-RPC command.
+func NewGetSilentPaymentTweaksCmd(blockHash string) *GetSilentPaymentTweaksCmd {
+	return &GetSilentPaymentTweaksCmd{
+		BlockHash: blockHash,
+	}
+}
+
 // GetTTLCmd defines the getttl JSON-RPC command.
 type GetTTLCmd struct {
 	Txid string
@@ -787,6 +1067,19 @@ func NewGetTxOutProofCmd(txIDs []string, blockHash *string) *GetTxOutProofCmd {
 	}
 }
 
+// GetTxSpendingPrevOutCmd defines the gettxspendingprevout JSON-RPC command.
+type GetTxSpendingPrevOutCmd struct {
+	Outputs []TransactionInput
+}
+
+// NewGetTxSpendingPrevOutCmd returns a new instance which can be used to issue
+// a gettxspendingprevout JSON-RPC command.
+func NewGetTxSpendingPrevOutCmd(outputs []TransactionInput) *GetTxSpendingPrevOutCmd {
+	return &GetTxSpendingPrevOutCmd{
+		Outputs: outputs,
+	}
+}
+
 // GetTxOutSetInfoCmd defines the gettxoutsetinfo JSON-RPC command.
 type GetTxOutSetInfoCmd struct{}
 
@@ -796,6 +1089,19 @@ func NewGetTxOutSetInfoCmd() *GetTxOutSetInfoCmd {
 	return &GetTxOutSetInfoCmd{}
 }
 
+// GetUtreexoCFHeaderCmd defines the getutreexocfheader JSON-RPC command.
+type GetUtreexoCFHeaderCmd struct {
+	BlockHash string
+}
+
+// NewGetUtreexoCFHeaderCmd returns a new instance which can be used to issue a
+// getutreexocfheader JSON-RPC command.
+func NewGetUtreexoCFHeaderCmd(blockHash string) *GetUtreexoCFHeaderCmd {
+	return &GetUtreexoCFHeaderCmd{
+		BlockHash: blockHash,
+	}
+}
+
 // GetUtreexoProofCmd defines the getutreexoproof JSON-RPC command.
 type GetUtreexoProofCmd struct {
 	BlockHash string
@@ -811,19 +1117,50 @@ func NewGetUtreexoProofCmd(blockhash string, verbosity *int) *GetUtreexoProofCmd
 	}
 }
 
+// GetUtxoProofCmd defines the getutxoproof JSON-RPC command.
+type GetUtxoProofCmd struct {
+	Outpoints []TransactionInput
+	BlockHash *string
+}
+
+// NewGetUtxoProofCmd returns a new instance which can be used to issue a
+// getutxoproof JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetUtxoProofCmd(outpoints []TransactionInput, blockHash *string) *GetUtxoProofCmd {
+	return &GetUtxoProofCmd{
+		Outpoints: outpoints,
+		BlockHash: blockHash,
+	}
+}
+
 // GetUtreexoRootsCmd defines the getutreexoroots JSON-RPC command.
 type GetUtreexoRootsCmd struct {
-	BlockHash string
+	HashOrHeight *HashOrHeight
 }
 
-// NewGetUtreexoRootsCmd returns a new instance which can be used
-// to issue a getutreexoroots JSON-RPC command.
-func NewGetUtreexoRootsCmd(blockhash string) *GetUtreexoRootsCmd {
+// NewGetUtreexoRootsCmd returns a new instance which can be used to issue a
+// getutreexoroots JSON-RPC command.  Either a block hash or a block height can
+// be passed in.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetUtreexoRootsCmd(hashOrHeight *HashOrHeight) *GetUtreexoRootsCmd {
 	return &GetUtreexoRootsCmd{
-		BlockHash: blockhash,
+		HashOrHeight: hashOrHeight,
 	}
 }
 
+// GetUtreexoStatsCmd defines the getutreexostats JSON-RPC command.
+type GetUtreexoStatsCmd struct{}
+
+// NewGetUtreexoStatsCmd returns a new instance which can be used to issue a
+// getutreexostats JSON-RPC command.
+func NewGetUtreexoStatsCmd() *GetUtreexoStatsCmd {
+	return &GetUtreexoStatsCmd{}
+}
+
 // GetWorkCmd defines the getwork JSON-RPC command.
 type GetWorkCmd struct {
 	Data *string
@@ -865,6 +1202,61 @@ func NewHelpCmd(command *string) *HelpCmd {
 	}
 }
 
+// ImportBDKDescriptorCmd defines the importbdkdescriptor JSON-RPC command.
+type ImportBDKDescriptorCmd struct {
+	Descriptor string
+	Birthday   *int32 `jsonrpcdefault:"0"`
+}
+
+// NewImportBDKDescriptorCmd returns a new instance which can be used to issue an
+// importbdkdescriptor JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewImportBDKDescriptorCmd(descriptor string, birthday *int32) *ImportBDKDescriptorCmd {
+	return &ImportBDKDescriptorCmd{
+		Descriptor: descriptor,
+		Birthday:   birthday,
+	}
+}
+
+// ImportSilentPaymentKeyCmd defines the importsilentpaymentkey JSON-RPC
+// command.
+type ImportSilentPaymentKeyCmd struct {
+	ScanKey  string
+	SpendKey string
+	Birthday *int32 `jsonrpcdefault:"0"`
+}
+
+// NewImportSilentPaymentKeyCmd returns a new instance which can be used to
+// issue an importsilentpaymentkey JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewImportSilentPaymentKeyCmd(scanKey, spendKey string, birthday *int32) *ImportSilentPaymentKeyCmd {
+	return &ImportSilentPaymentKeyCmd{
+		ScanKey:  scanKey,
+		SpendKey: spendKey,
+		Birthday: birthday,
+	}
+}
+
+// ListBDKDescriptorsCmd defines the listbdkdescriptors JSON-RPC command.
+type ListBDKDescriptorsCmd struct {
+	IncludePrivate *bool `jsonrpcdefault:"false"`
+}
+
+// NewListBDKDescriptorsCmd returns a new instance which can be used to issue a
+// listbdkdescriptors JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewListBDKDescriptorsCmd(includePrivate *bool) *ListBDKDescriptorsCmd {
+	return &ListBDKDescriptorsCmd{
+		IncludePrivate: includePrivate,
+	}
+}
+
 // ListBDKTransactionsCmd defines the listbdktransactions JSON-RPC command.
 type ListBDKTransactionsCmd struct{}
 
@@ -883,6 +1275,28 @@ func NewListBDKUTXOsCmd() *ListBDKUTXOsCmd {
 	return &ListBDKUTXOsCmd{}
 }
 
+// ListBannedCmd defines the listbanned JSON-RPC command.
+type ListBannedCmd struct{}
+
+// NewListBannedCmd returns a new instance which can be used to issue a
+// listbanned JSON-RPC command.
+func NewListBannedCmd() *ListBannedCmd {
+	return &ListBannedCmd{}
+}
+
+// LoadTxOutSetCmd defines the loadtxoutset JSON-RPC command.
+type LoadTxOutSetCmd struct {
+	Path string
+}
+
+// NewLoadTxOutSetCmd returns a new instance which can be used to issue a
+// loadtxoutset JSON-RPC command.
+func NewLoadTxOutSetCmd(path string) *LoadTxOutSetCmd {
+	return &LoadTxOutSetCmd{
+		Path: path,
+	}
+}
+
 // InvalidateBlockCmd defines the invalidateblock JSON-RPC command.
 type InvalidateBlockCmd struct {
 	BlockHash string
@@ -996,6 +1410,129 @@ func NewRebroadcastUnconfirmedBDKTxsCmd() *RebroadcastUnconfirmedBDKTxsCmd {
 	return &RebroadcastUnconfirmedBDKTxsCmd{}
 }
 
+// ScanBlocksAction defines the action of the scanblocks JSON-RPC command.
+type ScanBlocksAction string
+
+const (
+	// ScanBlocksActionStart starts a scan.
+	ScanBlocksActionStart ScanBlocksAction = "start"
+
+	// ScanBlocksActionStatus returns the progress of the scan in progress.
+	ScanBlocksActionStatus ScanBlocksAction = "status"
+
+	// ScanBlocksActionAbort aborts the scan in progress.
+	ScanBlocksActionAbort ScanBlocksAction = "abort"
+)
+
+// ScanBlocksOptions houses the options of the scanblocks JSON-RPC command.
+type ScanBlocksOptions struct {
+	// FilterFalsePositives removes the blocks that only match the filters
+	// by chance by checking the scripts of the blocks.
+	FilterFalsePositives bool `json:"filter_false_positives"`
+}
+
+// ScanBlocksCmd defines the scanblocks JSON-RPC command.
+type ScanBlocksCmd struct {
+	Action      ScanBlocksAction
+	ScanObjects *[]string
+	StartHeight *int32 `jsonrpcdefault:"0"`
+	StopHeight  *int32
+	FilterType  *FilterTypeName
+	Options     *ScanBlocksOptions
+}
+
+// NewScanBlocksCmd returns a new instance which can be used to issue a
+// scanblocks JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewScanBlocksCmd(action ScanBlocksAction, scanObjects *[]string,
+	startHeight, stopHeight *int32, filterType *FilterTypeName,
+	options *ScanBlocksOptions) *ScanBlocksCmd {
+
+	return &ScanBlocksCmd{
+		Action:      action,
+		ScanObjects: scanObjects,
+		StartHeight: startHeight,
+		StopHeight:  stopHeight,
+		FilterType:  filterType,
+		Options:     options,
+	}
+}
+
+// ScanTxOutSetAction defines the action of the scantxoutset JSON-RPC command.
+type ScanTxOutSetAction string
+
+const (
+	// ScanTxOutSetActionStart starts a scan.
+	ScanTxOutSetActionStart ScanTxOutSetAction = "start"
+
+	// ScanTxOutSetActionStatus returns the progress of the scan in
+	// progress.
+	ScanTxOutSetActionStatus ScanTxOutSetAction = "status"
+
+	// ScanTxOutSetActionAbort aborts the scan in progress.
+	ScanTxOutSetActionAbort ScanTxOutSetAction = "abort"
+)
+
+// ScanObject defines an output descriptor to scan for along with the range of
+// child indexes to derive when it's ranged.  It's either given as just the
+// descriptor or as an object with the descriptor and the range.
+type ScanObject struct {
+	Desc  string           `json:"desc"`
+	Range *DescriptorRange `json:"range,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (o ScanObject) MarshalJSON() ([]byte, error) {
+	if o.Range == nil {
+		return json.Marshal(o.Desc)
+	}
+
+	type scanObject ScanObject
+	return json.Marshal(scanObject(o))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (o *ScanObject) UnmarshalJSON(data []byte) error {
+	var desc string
+	if err := json.Unmarshal(data, &desc); err == nil {
+		*o = ScanObject{Desc: desc}
+		return nil
+	}
+
+	type scanObject ScanObject
+	var obj scanObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*o = ScanObject(obj)
+
+	return nil
+}
+
+// ScanTxOutSetCmd defines the scantxoutset JSON-RPC command.
+type ScanTxOutSetCmd struct {
+	Action       ScanTxOutSetAction
+	ScanObjects  *[]ScanObject
+	IncludeProof *bool `jsonrpcdefault:"false"`
+}
+
+// NewScanTxOutSetCmd returns a new instance which can be used to issue a
+// scantxoutset JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewScanTxOutSetCmd(action ScanTxOutSetAction, scanObjects *[]ScanObject,
+	includeProof *bool) *ScanTxOutSetCmd {
+
+	return &ScanTxOutSetCmd{
+		Action:       action,
+		ScanObjects:  scanObjects,
+		IncludeProof: includeProof,
+	}
+}
+
 // SearchRawTransactionsCmd defines the searchrawtransactions JSON-RPC command.
 type SearchRawTransactionsCmd struct {
 	Address     string
@@ -1005,6 +1542,8 @@ type SearchRawTransactionsCmd struct {
 	VinExtra    *int  `jsonrpcdefault:"0"`
 	Reverse     *bool `jsonrpcdefault:"false"`
 	FilterAddrs *[]string
+	MinHeight   *int32
+	MaxHeight   *int32
 }
 
 // NewSearchRawTransactionsCmd returns a new instance which can be used to issue a
@@ -1012,7 +1551,7 @@ type SearchRawTransactionsCmd struct {
 //
 // The parameters which are pointers indicate they are optional.  Passing nil
 // for optional parameters will use the default value.
-func NewSearchRawTransactionsCmd(address string, verbose, skip, count *int, vinExtra *int, reverse *bool, filterAddrs *[]string) *SearchRawTransactionsCmd {
+func NewSearchRawTransactionsCmd(address string, verbose, skip, count *int, vinExtra *int, reverse *bool, filterAddrs *[]string, minHeight, maxHeight *int32) *SearchRawTransactionsCmd {
 	return &SearchRawTransactionsCmd{
 		Address:     address,
 		Verbose:     verbose,
@@ -1021,6 +1560,8 @@ func NewSearchRawTransactionsCmd(address string, verbose, skip, count *int, vinE
 		VinExtra:    vinExtra,
 		Reverse:     reverse,
 		FilterAddrs: filterAddrs,
+		MinHeight:   minHeight,
+		MaxHeight:   maxHeight,
 	}
 }
 
@@ -1125,6 +1666,58 @@ func NewSetGenerateCmd(generate bool, genProcLimit *int) *SetGenerateCmd {
 	}
 }
 
+// SetBanSubCmd defines the type used in the setban JSON-RPC command for the
+// sub command field.
+type SetBanSubCmd string
+
+const (
+	// SBAdd indicates the specified IP address or subnet should be banned.
+	SBAdd SetBanSubCmd = "add"
+
+	// SBRemove indicates the ban of the specified IP address or subnet
+	// should be removed.
+	SBRemove SetBanSubCmd = "remove"
+)
+
+// SetBanCmd defines the setban JSON-RPC command.
+type SetBanCmd struct {
+	Subnet   string
+	SubCmd   SetBanSubCmd `jsonrpcusage:"\"add|remove\""`
+	BanTime  *int64       `jsonrpcdefault:"0"`
+	Absolute *bool        `jsonrpcdefault:"false"`
+}
+
+// NewSetBanCmd returns a new instance which can be used to issue a setban
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSetBanCmd(subnet string, subCmd SetBanSubCmd, banTime *int64,
+	absolute *bool) *SetBanCmd {
+
+	return &SetBanCmd{
+		Subnet:   subnet,
+		SubCmd:   subCmd,
+		BanTime:  banTime,
+		Absolute: absolute,
+	}
+}
+
+// SetLabelCmd defines the setlabel JSON-RPC command.
+type SetLabelCmd struct {
+	Reference string
+	Label     string
+}
+
+// NewSetLabelCmd returns a new instance which can be used to issue a setlabel
+// JSON-RPC command.  The reference is an address or a txid of the wallet.
+func NewSetLabelCmd(reference, label string) *SetLabelCmd {
+	return &SetLabelCmd{
+		Reference: reference,
+		Label:     label,
+	}
+}
+
 // SignMessageWithPrivKeyCmd defines the signmessagewithprivkey JSON-RPC command.
 type SignMessageWithPrivKeyCmd struct {
 	PrivKey string // base 58 Wallet Import format private key
@@ -1177,6 +1770,44 @@ func NewSubmitBlockCmd(hexBlock string, options *SubmitBlockOptions) *SubmitBloc
 	}
 }
 
+// SubmitPackageCmd defines the submitpackage JSON-RPC command.
+type SubmitPackageCmd struct {
+	// RawTxs are the hex-encoded transactions of the package, which is a
+	// child with all of its unconfirmed parents where the child comes
+	// last.
+	RawTxs []string
+}
+
+// NewSubmitPackageCmd returns a new instance which can be used to issue a
+// submitpackage JSON-RPC command.
+func NewSubmitPackageCmd(rawTxs []string) *SubmitPackageCmd {
+	return &SubmitPackageCmd{
+		RawTxs: rawTxs,
+	}
+}
+
+// TestMempoolAcceptCmd defines the testmempoolaccept JSON-RPC command.
+type TestMempoolAcceptCmd struct {
+	// RawTxs are the hex-encoded transactions to check.
+	RawTxs []string
+
+	// MaxFeeRate is the fee rate in BTC/kvB above which transactions are
+	// rejected.  A 0 rate accepts any fee rate.
+	MaxFeeRate *float64 `jsonrpcdefault:"0.10"`
+}
+
+// NewTestMempoolAcceptCmd returns a new instance which can be used to issue a
+// testmempoolaccept JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewTestMempoolAcceptCmd(rawTxs []string, maxFeeRate *float64) *TestMempoolAcceptCmd {
+	return &TestMempoolAcceptCmd{
+		RawTxs:     rawTxs,
+		MaxFeeRate: maxFeeRate,
+	}
+}
+
 // UnusedAddressCmd defines the unusedaddress JSON-RPC command.
 type UnusedAddressCmd struct{}
 
@@ -1194,6 +1825,19 @@ func NewUptimeCmd() *UptimeCmd {
 	return &UptimeCmd{}
 }
 
+// UtxoUpdatePsbtCmd defines the utxoupdatepsbt JSON-RPC command.
+type UtxoUpdatePsbtCmd struct {
+	Psbt string
+}
+
+// NewUtxoUpdatePsbtCmd returns a new instance which can be used to issue a
+// utxoupdatepsbt JSON-RPC command.
+func NewUtxoUpdatePsbtCmd(psbt string) *UtxoUpdatePsbtCmd {
+	return &UtxoUpdatePsbtCmd{
+		Psbt: psbt,
+	}
+}
+
 // ValidateAddressCmd defines the validateaddress JSON-RPC command.
 type ValidateAddressCmd struct {
 	Address string
@@ -1271,13 +1915,23 @@ func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
 
+	MustRegisterCmd("abandontransaction", (*AbandonTransactionCmd)(nil), flags)
 	MustRegisterCmd("addnode", (*AddNodeCmd)(nil), flags)
+	MustRegisterCmd("backupdatadir", (*BackupDataDirCmd)(nil), flags)
 	MustRegisterCmd("balance", (*BalanceCmd)(nil), flags)
+	MustRegisterCmd("clearbanned", (*ClearBannedCmd)(nil), flags)
+	MustRegisterCmd("compactdb", (*CompactDBCmd)(nil), flags)
+	MustRegisterCmd("cpfpbdktransaction", (*CpfpBDKTransactionCmd)(nil), flags)
 	MustRegisterCmd("createtransactionfrombdkwallet", (*CreateTransactionFromBDKWalletCmd)(nil), flags)
 	MustRegisterCmd("createrawtransaction", (*CreateRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decoderawtransaction", (*DecodeRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decodescript", (*DecodeScriptCmd)(nil), flags)
 	MustRegisterCmd("deriveaddresses", (*DeriveAddressesCmd)(nil), flags)
+	MustRegisterCmd("dumptxoutset", (*DumpTxOutSetCmd)(nil), flags)
+	MustRegisterCmd("enumeratesigners", (*EnumerateSignersCmd)(nil), flags)
+	MustRegisterCmd("estimaterawfee", (*EstimateRawFeeCmd)(nil), flags)
+	MustRegisterCmd("exportbdktransactions", (*ExportBDKTransactionsCmd)(nil), flags)
+	MustRegisterCmd("finalizepsbt", (*FinalizePsbtCmd)(nil), flags)
 	MustRegisterCmd("freshaddress", (*FreshAddressCmd)(nil), flags)
 	MustRegisterCmd("fundrawtransaction", (*FundRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("getaddednodeinfo", (*GetAddedNodeInfoCmd)(nil), flags)
@@ -1289,18 +1943,24 @@ func init() {
 	MustRegisterCmd("getblockfilter", (*GetBlockFilterCmd)(nil), flags)
 	MustRegisterCmd("getblockhash", (*GetBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblockheader", (*GetBlockHeaderCmd)(nil), flags)
+	MustRegisterCmd("getblocksbytime", (*GetBlocksByTimeCmd)(nil), flags)
 	MustRegisterCmd("getblockstats", (*GetBlockStatsCmd)(nil), flags)
+	MustRegisterCmd("getblockttls", (*GetBlockTTLsCmd)(nil), flags)
 	MustRegisterCmd("getblocktemplate", (*GetBlockTemplateCmd)(nil), flags)
 	MustRegisterCmd("getcfilter", (*GetCFilterCmd)(nil), flags)
 	MustRegisterCmd("getcfilterheader", (*GetCFilterHeaderCmd)(nil), flags)
 	MustRegisterCmd("getchaintips", (*GetChainTipsCmd)(nil), flags)
 	MustRegisterCmd("getchaintxstats", (*GetChainTxStatsCmd)(nil), flags)
 	MustRegisterCmd("getconnectioncount", (*GetConnectionCountCmd)(nil), flags)
+	MustRegisterCmd("getdbcompactioninfo", (*GetDBCompactionInfoCmd)(nil), flags)
 	MustRegisterCmd("getdescriptorinfo", (*GetDescriptorInfoCmd)(nil), flags)
 	MustRegisterCmd("getdifficulty", (*GetDifficultyCmd)(nil), flags)
 	MustRegisterCmd("getgenerate", (*GetGenerateCmd)(nil), flags)
 	MustRegisterCmd("gethashespersec", (*GetHashesPerSecCmd)(nil), flags)
+	MustRegisterCmd("getindexinfo", (*GetIndexInfoCmd)(nil), flags)
 	MustRegisterCmd("getinfo", (*GetInfoCmd)(nil), flags)
+	MustRegisterCmd("getmempoolancestors", (*GetMempoolAncestorsCmd)(nil), flags)
+	MustRegisterCmd("getmempooldescendants", (*GetMempoolDescendantsCmd)(nil), flags)
 	MustRegisterCmd("getmempoolentry", (*GetMempoolEntryCmd)(nil), flags)
 	MustRegisterCmd("getmempoolinfo", (*GetMempoolInfoCmd)(nil), flags)
 	MustRegisterCmd("getmininginfo", (*GetMiningInfoCmd)(nil), flags)
@@ -1313,18 +1973,29 @@ func init() {
 	MustRegisterCmd("getpeerinfo", (*GetPeerInfoCmd)(nil), flags)
 	MustRegisterCmd("getrawmempool", (*GetRawMempoolCmd)(nil), flags)
 	MustRegisterCmd("getrawtransaction", (*GetRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("getscriptbalance", (*GetScriptBalanceCmd)(nil), flags)
+	MustRegisterCmd("getsilentpaymenttweaks", (*GetSilentPaymentTweaksCmd)(nil), flags)
 	MustRegisterCmd("getttl", (*GetTTLCmd)(nil), flags)
 	MustRegisterCmd("gettxout", (*GetTxOutCmd)(nil), flags)
 	MustRegisterCmd("gettxoutproof", (*GetTxOutProofCmd)(nil), flags)
 	MustRegisterCmd("gettxoutsetinfo", (*GetTxOutSetInfoCmd)(nil), flags)
+	MustRegisterCmd("gettxspendingprevout", (*GetTxSpendingPrevOutCmd)(nil), flags)
+	MustRegisterCmd("getutreexocfheader", (*GetUtreexoCFHeaderCmd)(nil), flags)
 	MustRegisterCmd("getutreexoproof", (*GetUtreexoProofCmd)(nil), flags)
 	MustRegisterCmd("getutreexoroots", (*GetUtreexoRootsCmd)(nil), flags)
+	MustRegisterCmd("getutreexostats", (*GetUtreexoStatsCmd)(nil), flags)
+	MustRegisterCmd("getutxoproof", (*GetUtxoProofCmd)(nil), flags)
 	MustRegisterCmd("getwork", (*GetWorkCmd)(nil), flags)
 	MustRegisterCmd("getwatchonlybalance", (*GetWatchOnlyBalanceCmd)(nil), flags)
 	MustRegisterCmd("help", (*HelpCmd)(nil), flags)
+	MustRegisterCmd("importbdkdescriptor", (*ImportBDKDescriptorCmd)(nil), flags)
+	MustRegisterCmd("importsilentpaymentkey", (*ImportSilentPaymentKeyCmd)(nil), flags)
+	MustRegisterCmd("listbdkdescriptors", (*ListBDKDescriptorsCmd)(nil), flags)
 	MustRegisterCmd("listbdktransactions", (*ListBDKTransactionsCmd)(nil), flags)
 	MustRegisterCmd("listbdkutxos", (*ListBDKUTXOsCmd)(nil), flags)
+	MustRegisterCmd("listbanned", (*ListBannedCmd)(nil), flags)
 	MustRegisterCmd("invalidateblock", (*InvalidateBlockCmd)(nil), flags)
+	MustRegisterCmd("loadtxoutset", (*LoadTxOutSetCmd)(nil), flags)
 	MustRegisterCmd("peekaddress", (*PeekAddressCmd)(nil), flags)
 	MustRegisterCmd("ping", (*PingCmd)(nil), flags)
 	MustRegisterCmd("preciousblock", (*PreciousBlockCmd)(nil), flags)
@@ -1333,14 +2004,21 @@ func init() {
 	MustRegisterCmd("registeraddressestowatchonlywallet", (*RegisterAddressesToWatchOnlyWalletCmd)(nil), flags)
 	MustRegisterCmd("rebroadcastunconfirmedbdktxs", (*RebroadcastUnconfirmedBDKTxsCmd)(nil), flags)
 	MustRegisterCmd("reconsiderblock", (*ReconsiderBlockCmd)(nil), flags)
+	MustRegisterCmd("scanblocks", (*ScanBlocksCmd)(nil), flags)
+	MustRegisterCmd("scantxoutset", (*ScanTxOutSetCmd)(nil), flags)
 	MustRegisterCmd("searchrawtransactions", (*SearchRawTransactionsCmd)(nil), flags)
 	MustRegisterCmd("sendrawtransaction", (*SendRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("setban", (*SetBanCmd)(nil), flags)
 	MustRegisterCmd("setgenerate", (*SetGenerateCmd)(nil), flags)
+	MustRegisterCmd("setlabel", (*SetLabelCmd)(nil), flags)
 	MustRegisterCmd("signmessagewithprivkey", (*SignMessageWithPrivKeyCmd)(nil), flags)
 	MustRegisterCmd("stop", (*StopCmd)(nil), flags)
 	MustRegisterCmd("submitblock", (*SubmitBlockCmd)(nil), flags)
+	MustRegisterCmd("submitpackage", (*SubmitPackageCmd)(nil), flags)
+	MustRegisterCmd("testmempoolaccept", (*TestMempoolAcceptCmd)(nil), flags)
 	MustRegisterCmd("unusedaddress", (*UnusedAddressCmd)(nil), flags)
 	MustRegisterCmd("uptime", (*UptimeCmd)(nil), flags)
+	MustRegisterCmd("utxoupdatepsbt", (*UtxoUpdatePsbtCmd)(nil), flags)
 	MustRegisterCmd("validateaddress", (*ValidateAddressCmd)(nil), flags)
 	MustRegisterCmd("verifychain", (*VerifyChainCmd)(nil), flags)
 	MustRegisterCmd("verifymessage", (*VerifyMessageCmd)(nil), flags)
@@ -31,6 +31,19 @@ func TestChainSvrCmds(t *testing.T) {
 		marshalled   string
 		unmarshalled interface{}
 	}{
+		{
+			name: "abandontransaction",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("abandontransaction", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewAbandonTransactionCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"abandontransaction","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.AbandonTransactionCmd{
+				TxID: "123",
+			},
+		},
 		{
 			name: "addnode",
 			newCmd: func() (interface{}, error) {
@@ -42,6 +55,114 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"addnode","params":["127.0.0.1","remove"],"id":1}`,
 			unmarshalled: &btcjson.AddNodeCmd{Addr: "127.0.0.1", SubCmd: btcjson.ANRemove},
 		},
+		{
+			name: "backupdatadir",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("backupdatadir", "backup")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBackupDataDirCmd("backup")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"backupdatadir","params":["backup"],"id":1}`,
+			unmarshalled: &btcjson.BackupDataDirCmd{Destination: "backup"},
+		},
+		{
+			name: "cpfpbdktransaction",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("cpfpbdktransaction", "123", 10.5)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewCpfpBDKTransactionCmd("123", btcjson.Float64(10.5))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"cpfpbdktransaction","params":["123",10.5],"id":1}`,
+			unmarshalled: &btcjson.CpfpBDKTransactionCmd{
+				TxID:    "123",
+				FeeRate: btcjson.Float64(10.5),
+			},
+		},
+		{
+			name: "createtransactionfrombdkwallet",
+			newCmd: func() (interface{}, error) {
+				recipients := []btcjson.Recipient{{Amount: 10000, Address: "1Address"}}
+				return btcjson.NewCmd("createtransactionfrombdkwallet", float32(2),
+					recipients)
+			},
+			staticCmd: func() interface{} {
+				recipients := []btcjson.Recipient{{Amount: 10000, Address: "1Address"}}
+				return btcjson.NewCreateTransactionFromBDKWalletCmd(2, recipients, nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createtransactionfrombdkwallet","params":[2,[{"amount":10000,"address":"1Address"}]],"id":1}`,
+			unmarshalled: &btcjson.CreateTransactionFromBDKWalletCmd{
+				FeeRate:       2,
+				Recipients:    []btcjson.Recipient{{Amount: 10000, Address: "1Address"}},
+				CoinSelection: btcjson.String("branchandbound"),
+				ConfTarget:    btcjson.Int64(6),
+			},
+		},
+		{
+			name: "createtransactionfrombdkwallet optional",
+			newCmd: func() (interface{}, error) {
+				recipients := []btcjson.Recipient{{Amount: 10000, Address: "1Address"}}
+				return btcjson.NewCmd("createtransactionfrombdkwallet", float32(2),
+					recipients, "knapsack")
+			},
+			staticCmd: func() interface{} {
+				recipients := []btcjson.Recipient{{Amount: 10000, Address: "1Address"}}
+				return btcjson.NewCreateTransactionFromBDKWalletCmd(2, recipients,
+					btcjson.String("knapsack"), nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createtransactionfrombdkwallet","params":[2,[{"amount":10000,"address":"1Address"}],"knapsack"],"id":1}`,
+			unmarshalled: &btcjson.CreateTransactionFromBDKWalletCmd{
+				FeeRate:       2,
+				Recipients:    []btcjson.Recipient{{Amount: 10000, Address: "1Address"}},
+				CoinSelection: btcjson.String("knapsack"),
+				ConfTarget:    btcjson.Int64(6),
+			},
+		},
+		{
+			name: "createtransactionfrombdkwallet inputs",
+			newCmd: func() (interface{}, error) {
+				recipients := []btcjson.Recipient{{Amount: 10000, Address: "1Address"}}
+				return btcjson.NewCmd("createtransactionfrombdkwallet", float32(2),
+					recipients, "knapsack", `[{"txid":"123","vout":1}]`)
+			},
+			staticCmd: func() interface{} {
+				recipients := []btcjson.Recipient{{Amount: 10000, Address: "1Address"}}
+				inputs := []btcjson.TransactionInput{{Txid: "123", Vout: 1}}
+				return btcjson.NewCreateTransactionFromBDKWalletCmd(2, recipients,
+					btcjson.String("knapsack"), &inputs, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createtransactionfrombdkwallet","params":[2,[{"amount":10000,"address":"1Address"}],"knapsack",[{"txid":"123","vout":1}]],"id":1}`,
+			unmarshalled: &btcjson.CreateTransactionFromBDKWalletCmd{
+				FeeRate:       2,
+				Recipients:    []btcjson.Recipient{{Amount: 10000, Address: "1Address"}},
+				CoinSelection: btcjson.String("knapsack"),
+				Inputs:        &[]btcjson.TransactionInput{{Txid: "123", Vout: 1}},
+				ConfTarget:    btcjson.Int64(6),
+			},
+		},
+		{
+			name: "createtransactionfrombdkwallet estimated",
+			newCmd: func() (interface{}, error) {
+				recipients := []btcjson.Recipient{{Amount: 10000, Address: "1Address", SubtractFee: true}}
+				return btcjson.NewCmd("createtransactionfrombdkwallet", float32(0),
+					recipients, "knapsack", `[]`, 2)
+			},
+			staticCmd: func() interface{} {
+				recipients := []btcjson.Recipient{{Amount: 10000, Address: "1Address", SubtractFee: true}}
+				return btcjson.NewCreateTransactionFromBDKWalletCmd(0, recipients,
+					btcjson.String("knapsack"), &[]btcjson.TransactionInput{},
+					btcjson.Int64(2))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createtransactionfrombdkwallet","params":[0,[{"amount":10000,"address":"1Address","subtractfee":true}],"knapsack",[],2],"id":1}`,
+			unmarshalled: &btcjson.CreateTransactionFromBDKWalletCmd{
+				FeeRate:       0,
+				Recipients:    []btcjson.Recipient{{Amount: 10000, Address: "1Address", SubtractFee: true}},
+				CoinSelection: btcjson.String("knapsack"),
+				Inputs:        &[]btcjson.TransactionInput{},
+				ConfTarget:    btcjson.Int64(2),
+			},
+		},
 		{
 			name: "createrawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -96,6 +217,58 @@ func TestChainSvrCmds(t *testing.T) {
 				LockTime: btcjson.Int64(12312333333),
 			},
 		},
+		{
+			name: "finalizepsbt",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("finalizepsbt", "cHNidP8B")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewFinalizePsbtCmd("cHNidP8B", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"finalizepsbt","params":["cHNidP8B"],"id":1}`,
+			unmarshalled: &btcjson.FinalizePsbtCmd{
+				Psbt:    "cHNidP8B",
+				Extract: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "finalizepsbt optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("finalizepsbt", "cHNidP8B", false)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewFinalizePsbtCmd("cHNidP8B", btcjson.Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"finalizepsbt","params":["cHNidP8B",false],"id":1}`,
+			unmarshalled: &btcjson.FinalizePsbtCmd{
+				Psbt:    "cHNidP8B",
+				Extract: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "freshaddress",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("freshaddress")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewFreshAddressCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"freshaddress","params":[],"id":1}`,
+			unmarshalled: &btcjson.FreshAddressCmd{},
+		},
+		{
+			name: "freshaddress optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("freshaddress", "alice")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewFreshAddressCmd(btcjson.String("alice"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"freshaddress","params":["alice"],"id":1}`,
+			unmarshalled: &btcjson.FreshAddressCmd{
+				Label: btcjson.String("alice"),
+			},
+		},
 		{
 			name: "fundrawtransaction - empty opts",
 			newCmd: func() (i interface{}, e error) {
@@ -265,6 +438,137 @@ func TestChainSvrCmds(t *testing.T) {
 				Range:      &btcjson.DescriptorRange{Value: []int{0, 2}},
 			},
 		},
+		{
+			name: "dumptxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("dumptxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDumpTxOutSetCmd("utxo.dat")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"dumptxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.DumpTxOutSetCmd{Path: "utxo.dat"},
+		},
+		{
+			name: "enumeratesigners",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("enumeratesigners")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewEnumerateSignersCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"enumeratesigners","params":[],"id":1}`,
+			unmarshalled: &btcjson.EnumerateSignersCmd{},
+		},
+		{
+			name: "exportbdktransactions",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("exportbdktransactions")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewExportBDKTransactionsCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"exportbdktransactions","params":[],"id":1}`,
+			unmarshalled: &btcjson.ExportBDKTransactionsCmd{
+				Format: btcjson.String("json"),
+			},
+		},
+		{
+			name: "exportbdktransactions csv",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("exportbdktransactions", "csv")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewExportBDKTransactionsCmd(btcjson.String("csv"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"exportbdktransactions","params":["csv"],"id":1}`,
+			unmarshalled: &btcjson.ExportBDKTransactionsCmd{
+				Format: btcjson.String("csv"),
+			},
+		},
+		{
+			name: "estimaterawfee",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("estimaterawfee", 6)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewEstimateRawFeeCmd(6, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimaterawfee","params":[6],"id":1}`,
+			unmarshalled: &btcjson.EstimateRawFeeCmd{
+				ConfTarget: 6,
+				Threshold:  btcjson.Float64(0.95),
+			},
+		},
+		{
+			name: "estimaterawfee optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("estimaterawfee", 6, 0.5)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewEstimateRawFeeCmd(6, btcjson.Float64(0.5))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimaterawfee","params":[6,0.5],"id":1}`,
+			unmarshalled: &btcjson.EstimateRawFeeCmd{
+				ConfTarget: 6,
+				Threshold:  btcjson.Float64(0.5),
+			},
+		},
+		{
+			name: "clearbanned",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("clearbanned")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewClearBannedCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"clearbanned","params":[],"id":1}`,
+			unmarshalled: &btcjson.ClearBannedCmd{},
+		},
+		{
+			name: "compactdb",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("compactdb")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewCompactDBCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"compactdb","params":[],"id":1}`,
+			unmarshalled: &btcjson.CompactDBCmd{},
+		},
+		{
+			name: "getdbcompactioninfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getdbcompactioninfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetDBCompactionInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getdbcompactioninfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetDBCompactionInfoCmd{},
+		},
+		{
+			name: "listbanned",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listbanned")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListBannedCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"listbanned","params":[],"id":1}`,
+			unmarshalled: &btcjson.ListBannedCmd{},
+		},
+		{
+			name: "loadtxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadtxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewLoadTxOutSetCmd("utxo.dat")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"loadtxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.LoadTxOutSetCmd{Path: "utxo.dat"},
+		},
 		{
 			name: "getaddednodeinfo",
 			newCmd: func() (interface{}, error) {
@@ -426,6 +730,20 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: btcjson.Bool(true),
 			},
 		},
+		{
+			name: "getblocksbytime",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblocksbytime", 1709251200, 1711929600)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlocksByTimeCmd(1709251200, 1711929600)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblocksbytime","params":[1709251200,1711929600],"id":1}`,
+			unmarshalled: &btcjson.GetBlocksByTimeCmd{
+				StartTime: 1709251200,
+				EndTime:   1711929600,
+			},
+		},
 		{
 			name: "getblockstats height",
 			newCmd: func() (interface{}, error) {
@@ -480,6 +798,19 @@ func TestChainSvrCmds(t *testing.T) {
 				Stats:        &[]string{"avgfee", "maxfee"},
 			},
 		},
+		{
+			name: "getblockttls",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblockttls", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetBlockTTLsCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblockttls","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetBlockTTLsCmd{
+				BlockHash: "123",
+			},
+		},
 		{
 			name: "getblocktemplate",
 			newCmd: func() (interface{}, error) {
@@ -563,6 +894,32 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "getblocktemplate optional - template request with policy and base template",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getblocktemplate", `{"mode":"template","capabilities":["longpoll","utreexoproof"],"blockmintxfee":0.0001,"blockmaxweight":2000000,"basetemplate":"abc-123"}`)
+			},
+			staticCmd: func() interface{} {
+				template := btcjson.TemplateRequest{
+					Mode:           "template",
+					Capabilities:   []string{"longpoll", "utreexoproof"},
+					BlockMinTxFee:  btcjson.Float64(0.0001),
+					BlockMaxWeight: btcjson.Uint32(2000000),
+					BaseTemplate:   "abc-123",
+				}
+				return btcjson.NewGetBlockTemplateCmd(&template)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getblocktemplate","params":[{"mode":"template","capabilities":["longpoll","utreexoproof"],"blockmintxfee":0.0001,"blockmaxweight":2000000,"basetemplate":"abc-123"}],"id":1}`,
+			unmarshalled: &btcjson.GetBlockTemplateCmd{
+				Request: &btcjson.TemplateRequest{
+					Mode:           "template",
+					Capabilities:   []string{"longpoll", "utreexoproof"},
+					BlockMinTxFee:  btcjson.Float64(0.0001),
+					BlockMaxWeight: btcjson.Uint32(2000000),
+					BaseTemplate:   "abc-123",
+				},
+			},
+		},
 		{
 			name: "getcfilter",
 			newCmd: func() (interface{}, error) {
@@ -688,6 +1045,28 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"gethashespersec","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetHashesPerSecCmd{},
 		},
+		{
+			name: "getindexinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getindexinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetIndexInfoCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getindexinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetIndexInfoCmd{IndexName: nil},
+		},
+		{
+			name: "getindexinfo optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getindexinfo", "txindex")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetIndexInfoCmd(btcjson.String("txindex"))
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getindexinfo","params":["txindex"],"id":1}`,
+			unmarshalled: &btcjson.GetIndexInfoCmd{IndexName: btcjson.String("txindex")},
+		},
 		{
 			name: "getinfo",
 			newCmd: func() (interface{}, error) {
@@ -699,6 +1078,62 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getinfo","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetInfoCmd{},
 		},
+		{
+			name: "getmempoolancestors",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempoolancestors", "txhash")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolAncestorsCmd("txhash", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempoolancestors","params":["txhash"],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolAncestorsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getmempoolancestors optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempoolancestors", "txhash", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolAncestorsCmd("txhash", btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempoolancestors","params":["txhash",true],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolAncestorsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "getmempooldescendants",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempooldescendants", "txhash")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolDescendantsCmd("txhash", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempooldescendants","params":["txhash"],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolDescendantsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getmempooldescendants optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempooldescendants", "txhash", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolDescendantsCmd("txhash", btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempooldescendants","params":["txhash",true],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolDescendantsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(true),
+			},
+		},
 		{
 			name: "getmempoolentry",
 			newCmd: func() (interface{}, error) {
@@ -889,6 +1324,32 @@ func TestChainSvrCmds(t *testing.T) {
 				Verbose: btcjson.Int(1),
 			},
 		},
+		{
+			name: "getscriptbalance",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getscriptbalance", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetScriptBalanceCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getscriptbalance","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetScriptBalanceCmd{
+				ScriptHash: "123",
+			},
+		},
+		{
+			name: "getsilentpaymenttweaks",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getsilentpaymenttweaks", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetSilentPaymentTweaksCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getsilentpaymenttweaks","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetSilentPaymentTweaksCmd{
+				BlockHash: "123",
+			},
+		},
 		{
 			name: "gettxout",
 			newCmd: func() (interface{}, error) {
@@ -960,6 +1421,107 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetTxOutSetInfoCmd{},
 		},
+		{
+			name: "gettxspendingprevout",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("gettxspendingprevout", `[{"txid":"123","vout":1}]`)
+			},
+			staticCmd: func() interface{} {
+				outputs := []btcjson.TransactionInput{
+					{Txid: "123", Vout: 1},
+				}
+				return btcjson.NewGetTxSpendingPrevOutCmd(outputs)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"gettxspendingprevout","params":[[{"txid":"123","vout":1}]],"id":1}`,
+			unmarshalled: &btcjson.GetTxSpendingPrevOutCmd{
+				Outputs: []btcjson.TransactionInput{
+					{Txid: "123", Vout: 1},
+				},
+			},
+		},
+		{
+			name: "getutreexocfheader",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getutreexocfheader", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetUtreexoCFHeaderCmd("123")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getutreexocfheader","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetUtreexoCFHeaderCmd{
+				BlockHash: "123",
+			},
+		},
+		{
+			name: "getutreexoroots",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getutreexoroots")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetUtreexoRootsCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getutreexoroots","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetUtreexoRootsCmd{},
+		},
+		{
+			name: "getutreexoroots height",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getutreexoroots", btcjson.HashOrHeight{Value: 123})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetUtreexoRootsCmd(&btcjson.HashOrHeight{Value: 123})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getutreexoroots","params":[123],"id":1}`,
+			unmarshalled: &btcjson.GetUtreexoRootsCmd{
+				HashOrHeight: &btcjson.HashOrHeight{Value: 123},
+			},
+		},
+		{
+			name: "getutreexoroots hash",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getutreexoroots", btcjson.HashOrHeight{Value: "deadbeef"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetUtreexoRootsCmd(&btcjson.HashOrHeight{Value: "deadbeef"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getutreexoroots","params":["deadbeef"],"id":1}`,
+			unmarshalled: &btcjson.GetUtreexoRootsCmd{
+				HashOrHeight: &btcjson.HashOrHeight{Value: "deadbeef"},
+			},
+		},
+		{
+			name: "getutxoproof",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getutxoproof", `[{"txid":"123","vout":1}]`)
+			},
+			staticCmd: func() interface{} {
+				txInputs := []btcjson.TransactionInput{
+					{Txid: "123", Vout: 1},
+				}
+				return btcjson.NewGetUtxoProofCmd(txInputs, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getutxoproof","params":[[{"txid":"123","vout":1}]],"id":1}`,
+			unmarshalled: &btcjson.GetUtxoProofCmd{
+				Outpoints: []btcjson.TransactionInput{{Txid: "123", Vout: 1}},
+			},
+		},
+		{
+			name: "getutxoproof optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getutxoproof", `[{"txid":"123","vout":1}]`, "456")
+			},
+			staticCmd: func() interface{} {
+				txInputs := []btcjson.TransactionInput{
+					{Txid: "123", Vout: 1},
+				}
+				return btcjson.NewGetUtxoProofCmd(txInputs, btcjson.String("456"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getutxoproof","params":[[{"txid":"123","vout":1}],"456"],"id":1}`,
+			unmarshalled: &btcjson.GetUtxoProofCmd{
+				Outpoints: []btcjson.TransactionInput{{Txid: "123", Vout: 1}},
+				BlockHash: btcjson.String("456"),
+			},
+		},
 		{
 			name: "getwork",
 			newCmd: func() (interface{}, error) {
@@ -1012,6 +1574,77 @@ func TestChainSvrCmds(t *testing.T) {
 				Command: btcjson.String("getblock"),
 			},
 		},
+		{
+			name: "importbdkdescriptor",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("importbdkdescriptor", "wpkh(xpub/0/*)")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewImportBDKDescriptorCmd("wpkh(xpub/0/*)", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importbdkdescriptor","params":["wpkh(xpub/0/*)"],"id":1}`,
+			unmarshalled: &btcjson.ImportBDKDescriptorCmd{
+				Descriptor: "wpkh(xpub/0/*)",
+				Birthday:   btcjson.Int32(0),
+			},
+		},
+		{
+			name: "importbdkdescriptor optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("importbdkdescriptor", "wpkh(xpub/0/*)", 100)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewImportBDKDescriptorCmd("wpkh(xpub/0/*)", btcjson.Int32(100))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importbdkdescriptor","params":["wpkh(xpub/0/*)",100],"id":1}`,
+			unmarshalled: &btcjson.ImportBDKDescriptorCmd{
+				Descriptor: "wpkh(xpub/0/*)",
+				Birthday:   btcjson.Int32(100),
+			},
+		},
+		{
+			name: "importsilentpaymentkey",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("importsilentpaymentkey", "scankey", "spendkey")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewImportSilentPaymentKeyCmd("scankey", "spendkey", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importsilentpaymentkey","params":["scankey","spendkey"],"id":1}`,
+			unmarshalled: &btcjson.ImportSilentPaymentKeyCmd{
+				ScanKey:  "scankey",
+				SpendKey: "spendkey",
+				Birthday: btcjson.Int32(0),
+			},
+		},
+		{
+			name: "importsilentpaymentkey optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("importsilentpaymentkey", "scankey", "spendkey", 100)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewImportSilentPaymentKeyCmd("scankey", "spendkey", btcjson.Int32(100))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importsilentpaymentkey","params":["scankey","spendkey",100],"id":1}`,
+			unmarshalled: &btcjson.ImportSilentPaymentKeyCmd{
+				ScanKey:  "scankey",
+				SpendKey: "spendkey",
+				Birthday: btcjson.Int32(100),
+			},
+		},
+		{
+			name: "listbdkdescriptors",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listbdkdescriptors")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListBDKDescriptorsCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listbdkdescriptors","params":[],"id":1}`,
+			unmarshalled: &btcjson.ListBDKDescriptorsCmd{
+				IncludePrivate: btcjson.Bool(false),
+			},
+		},
 		{
 			name: "invalidateblock",
 			newCmd: func() (interface{}, error) {
@@ -1092,13 +1725,97 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "scanblocks status",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("scanblocks", btcjson.ScanBlocksActionStatus)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewScanBlocksCmd(btcjson.ScanBlocksActionStatus,
+					nil, nil, nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scanblocks","params":["status"],"id":1}`,
+			unmarshalled: &btcjson.ScanBlocksCmd{
+				Action:      btcjson.ScanBlocksActionStatus,
+				StartHeight: btcjson.Int32(0),
+			},
+		},
+		{
+			name: "scanblocks start",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("scanblocks", btcjson.ScanBlocksActionStart,
+					[]string{"raw(0014aa)"}, 10, 20, btcjson.FilterTypeBasic,
+					btcjson.ScanBlocksOptions{FilterFalsePositives: true})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewScanBlocksCmd(btcjson.ScanBlocksActionStart,
+					&[]string{"raw(0014aa)"}, btcjson.Int32(10),
+					btcjson.Int32(20),
+					btcjson.NewFilterTypeName(btcjson.FilterTypeBasic),
+					&btcjson.ScanBlocksOptions{FilterFalsePositives: true})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scanblocks","params":["start",["raw(0014aa)"],10,20,"basic",{"filter_false_positives":true}],"id":1}`,
+			unmarshalled: &btcjson.ScanBlocksCmd{
+				Action:      btcjson.ScanBlocksActionStart,
+				ScanObjects: &[]string{"raw(0014aa)"},
+				StartHeight: btcjson.Int32(10),
+				StopHeight:  btcjson.Int32(20),
+				FilterType:  btcjson.NewFilterTypeName(btcjson.FilterTypeBasic),
+				Options:     &btcjson.ScanBlocksOptions{FilterFalsePositives: true},
+			},
+		},
+		{
+			name: "scantxoutset status",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("scantxoutset", btcjson.ScanTxOutSetActionStatus)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewScanTxOutSetCmd(btcjson.ScanTxOutSetActionStatus,
+					nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scantxoutset","params":["status"],"id":1}`,
+			unmarshalled: &btcjson.ScanTxOutSetCmd{
+				Action:       btcjson.ScanTxOutSetActionStatus,
+				IncludeProof: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "scantxoutset start",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("scantxoutset", btcjson.ScanTxOutSetActionStart,
+					`["raw(0014aa)",{"desc":"pkh(xpub/0/*)","range":[5,10]}]`,
+					true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewScanTxOutSetCmd(btcjson.ScanTxOutSetActionStart,
+					&[]btcjson.ScanObject{
+						{Desc: "raw(0014aa)"},
+						{
+							Desc:  "pkh(xpub/0/*)",
+							Range: &btcjson.DescriptorRange{Value: []int{5, 10}},
+						},
+					}, btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scantxoutset","params":["start",["raw(0014aa)",{"desc":"pkh(xpub/0/*)","range":[5,10]}],true],"id":1}`,
+			unmarshalled: &btcjson.ScanTxOutSetCmd{
+				Action: btcjson.ScanTxOutSetActionStart,
+				ScanObjects: &[]btcjson.ScanObject{
+					{Desc: "raw(0014aa)"},
+					{
+						Desc:  "pkh(xpub/0/*)",
+						Range: &btcjson.DescriptorRange{Value: []int{5, 10}},
+					},
+				},
+				IncludeProof: btcjson.Bool(true),
+			},
+		},
 		{
 			name: "searchrawtransactions",
 			newCmd: func() (interface{}, error) {
 				return btcjson.NewCmd("searchrawtransactions", "1Address")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewSearchRawTransactionsCmd("1Address", nil, nil, nil, nil, nil, nil)
+				return btcjson.NewSearchRawTransactionsCmd("1Address", nil, nil, nil, nil, nil, nil, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address"],"id":1}`,
 			unmarshalled: &btcjson.SearchRawTransactionsCmd{
@@ -1118,7 +1835,7 @@ func TestChainSvrCmds(t *testing.T) {
 			},
 			staticCmd: func() interface{} {
 				return btcjson.NewSearchRawTransactionsCmd("1Address",
-					btcjson.Int(0), nil, nil, nil, nil, nil)
+					btcjson.Int(0), nil, nil, nil, nil, nil, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address",0],"id":1}`,
 			unmarshalled: &btcjson.SearchRawTransactionsCmd{
@@ -1138,7 +1855,7 @@ func TestChainSvrCmds(t *testing.T) {
 			},
 			staticCmd: func() interface{} {
 				return btcjson.NewSearchRawTransactionsCmd("1Address",
-					btcjson.Int(0), btcjson.Int(5), nil, nil, nil, nil)
+					btcjson.Int(0), btcjson.Int(5), nil, nil, nil, nil, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address",0,5],"id":1}`,
 			unmarshalled: &btcjson.SearchRawTransactionsCmd{
@@ -1158,7 +1875,7 @@ func TestChainSvrCmds(t *testing.T) {
 			},
 			staticCmd: func() interface{} {
 				return btcjson.NewSearchRawTransactionsCmd("1Address",
-					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), nil, nil, nil)
+					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), nil, nil, nil, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address",0,5,10],"id":1}`,
 			unmarshalled: &btcjson.SearchRawTransactionsCmd{
@@ -1178,7 +1895,7 @@ func TestChainSvrCmds(t *testing.T) {
 			},
 			staticCmd: func() interface{} {
 				return btcjson.NewSearchRawTransactionsCmd("1Address",
-					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), btcjson.Int(1), nil, nil)
+					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), btcjson.Int(1), nil, nil, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address",0,5,10,1],"id":1}`,
 			unmarshalled: &btcjson.SearchRawTransactionsCmd{
@@ -1198,7 +1915,7 @@ func TestChainSvrCmds(t *testing.T) {
 			},
 			staticCmd: func() interface{} {
 				return btcjson.NewSearchRawTransactionsCmd("1Address",
-					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), btcjson.Int(1), btcjson.Bool(true), nil)
+					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), btcjson.Int(1), btcjson.Bool(true), nil, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address",0,5,10,1,true],"id":1}`,
 			unmarshalled: &btcjson.SearchRawTransactionsCmd{
@@ -1218,7 +1935,7 @@ func TestChainSvrCmds(t *testing.T) {
 			},
 			staticCmd: func() interface{} {
 				return btcjson.NewSearchRawTransactionsCmd("1Address",
-					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), btcjson.Int(1), btcjson.Bool(true), &[]string{"1Address"})
+					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), btcjson.Int(1), btcjson.Bool(true), &[]string{"1Address"}, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address",0,5,10,1,true,["1Address"]],"id":1}`,
 			unmarshalled: &btcjson.SearchRawTransactionsCmd{
@@ -1238,7 +1955,7 @@ func TestChainSvrCmds(t *testing.T) {
 			},
 			staticCmd: func() interface{} {
 				return btcjson.NewSearchRawTransactionsCmd("1Address",
-					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), nil, btcjson.Bool(true), &[]string{"1Address"})
+					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), nil, btcjson.Bool(true), &[]string{"1Address"}, nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address",0,5,10,null,true,["1Address"]],"id":1}`,
 			unmarshalled: &btcjson.SearchRawTransactionsCmd{
@@ -1251,6 +1968,28 @@ func TestChainSvrCmds(t *testing.T) {
 				FilterAddrs: &[]string{"1Address"},
 			},
 		},
+		{
+			name: "searchrawtransactions",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("searchrawtransactions", "1Address", 0, 5, 10, 1, true, []string{"1Address"}, 100, 200)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSearchRawTransactionsCmd("1Address",
+					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10), btcjson.Int(1), btcjson.Bool(true), &[]string{"1Address"}, btcjson.Int32(100), btcjson.Int32(200))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"searchrawtransactions","params":["1Address",0,5,10,1,true,["1Address"],100,200],"id":1}`,
+			unmarshalled: &btcjson.SearchRawTransactionsCmd{
+				Address:     "1Address",
+				Verbose:     btcjson.Int(0),
+				Skip:        btcjson.Int(5),
+				Count:       btcjson.Int(10),
+				VinExtra:    btcjson.Int(1),
+				Reverse:     btcjson.Bool(true),
+				FilterAddrs: &[]string{"1Address"},
+				MinHeight:   btcjson.Int32(100),
+				MaxHeight:   btcjson.Int32(200),
+			},
+		},
 		{
 			name: "sendrawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -1299,6 +2038,39 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "setban",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setban", "1.2.3.0/24", btcjson.SBAdd)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSetBanCmd("1.2.3.0/24", btcjson.SBAdd, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setban","params":["1.2.3.0/24","add"],"id":1}`,
+			unmarshalled: &btcjson.SetBanCmd{
+				Subnet:   "1.2.3.0/24",
+				SubCmd:   btcjson.SBAdd,
+				BanTime:  btcjson.Int64(0),
+				Absolute: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "setban optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setban", "1.2.3.4", btcjson.SBAdd, 1700000000, true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSetBanCmd("1.2.3.4", btcjson.SBAdd,
+					btcjson.Int64(1700000000), btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setban","params":["1.2.3.4","add",1700000000,true],"id":1}`,
+			unmarshalled: &btcjson.SetBanCmd{
+				Subnet:   "1.2.3.4",
+				SubCmd:   btcjson.SBAdd,
+				BanTime:  btcjson.Int64(1700000000),
+				Absolute: btcjson.Bool(true),
+			},
+		},
 		{
 			name: "setgenerate",
 			newCmd: func() (interface{}, error) {
@@ -1327,6 +2099,20 @@ func TestChainSvrCmds(t *testing.T) {
 				GenProcLimit: btcjson.Int(6),
 			},
 		},
+		{
+			name: "setlabel",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("setlabel", "bcrt1qaddress", "alice")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSetLabelCmd("bcrt1qaddress", "alice")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"setlabel","params":["bcrt1qaddress","alice"],"id":1}`,
+			unmarshalled: &btcjson.SetLabelCmd{
+				Reference: "bcrt1qaddress",
+				Label:     "alice",
+			},
+		},
 		{
 			name: "signmessagewithprivkey",
 			newCmd: func() (interface{}, error) {
@@ -1385,6 +2171,48 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "submitpackage",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("submitpackage", []string{"1122", "3344"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSubmitPackageCmd([]string{"1122", "3344"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"submitpackage","params":[["1122","3344"]],"id":1}`,
+			unmarshalled: &btcjson.SubmitPackageCmd{
+				RawTxs: []string{"1122", "3344"},
+			},
+		},
+		{
+			name: "testmempoolaccept",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("testmempoolaccept", []string{"1122"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewTestMempoolAcceptCmd([]string{"1122"}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["1122"]],"id":1}`,
+			unmarshalled: &btcjson.TestMempoolAcceptCmd{
+				RawTxs:     []string{"1122"},
+				MaxFeeRate: btcjson.Float64(0.10),
+			},
+		},
+		{
+			name: "testmempoolaccept optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("testmempoolaccept", []string{"1122", "3344"}, 0.25)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewTestMempoolAcceptCmd([]string{"1122", "3344"},
+					btcjson.Float64(0.25))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["1122","3344"],0.25],"id":1}`,
+			unmarshalled: &btcjson.TestMempoolAcceptCmd{
+				RawTxs:     []string{"1122", "3344"},
+				MaxFeeRate: btcjson.Float64(0.25),
+			},
+		},
 		{
 			name: "uptime",
 			newCmd: func() (interface{}, error) {
@@ -1396,6 +2224,19 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"uptime","params":[],"id":1}`,
 			unmarshalled: &btcjson.UptimeCmd{},
 		},
+		{
+			name: "utxoupdatepsbt",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("utxoupdatepsbt", "cHNidP8B")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewUtxoUpdatePsbtCmd("cHNidP8B")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"utxoupdatepsbt","params":["cHNidP8B"],"id":1}`,
+			unmarshalled: &btcjson.UtxoUpdatePsbtCmd{
+				Psbt: "cHNidP8B",
+			},
+		},
 		{
 			name: "validateaddress",
 			newCmd: func() (interface{}, error) {
@@ -27,13 +27,56 @@ type BalanceResult struct {
 	Confirmed int64 `json:"confirmed"`
 }
 
+// EnumerateSignersResult models the data from the enumeratesigners command.
+type EnumerateSignersResult struct {
+	Signers []SignerResult `json:"signers"`
+}
+
+// SignerResult models an external signer of the enumeratesigners command.
+type SignerResult struct {
+	Fingerprint string `json:"fingerprint"`
+	Name        string `json:"name"`
+}
+
+// ExportBDKTransactionsResult models a transaction of the exportbdktransactions
+// command.
+type ExportBDKTransactionsResult struct {
+	Txid          string   `json:"txid"`
+	Height        int32    `json:"height,omitempty"`
+	Time          int64    `json:"time,omitempty"`
+	Confirmations uint     `json:"confirmations"`
+	Received      int64    `json:"received"`
+	Spent         int64    `json:"spent"`
+	Net           int64    `json:"net"`
+	Fee           *int64   `json:"fee,omitempty"`
+	Label         string   `json:"label,omitempty"`
+	ReceivedBy    []string `json:"receivedby,omitempty"`
+	SentTo        []string `json:"sentto,omitempty"`
+}
+
+// FinalizePsbtResult models the data from the finalizepsbt command.
+type FinalizePsbtResult struct {
+	Psbt     string `json:"psbt,omitempty"`
+	Hex      string `json:"hex,omitempty"`
+	Complete bool   `json:"complete"`
+}
+
+// ListBDKDescriptorsResult models the data from the listbdkdescriptors command.
+type ListBDKDescriptorsResult struct {
+	Descriptor string `json:"descriptor"`
+	Internal   bool   `json:"internal"`
+	Birthday   uint   `json:"birthday"`
+	NextIndex  uint   `json:"nextindex"`
+}
+
 // ListBDKTransactionsResult models the data from the listbdktransactions command.
 type ListBDKTransactionsResult struct {
-	Txid          string `json:"txid"`          // txid of the tx
-	RawBytes      string `json:"rawbytes"`      // hex encoded raw tx
-	Spent         int64  `json:"spent"`         // sum of owned inputs
-	Received      int64  `json:"received"`      // sum of owned outputs
-	Confirmations uint   `json:"confirmations"` // number of confirmations for this tx
+	Txid          string `json:"txid"`            // txid of the tx
+	RawBytes      string `json:"rawbytes"`        // hex encoded raw tx
+	Spent         int64  `json:"spent"`           // sum of owned inputs
+	Received      int64  `json:"received"`        // sum of owned outputs
+	Confirmations uint   `json:"confirmations"`   // number of confirmations for this tx
+	Label         string `json:"label,omitempty"` // label of the tx
 }
 
 // ListBDKUTXOsResult models the data from the listbdkutxos command.
@@ -45,6 +88,13 @@ type ListBDKUTXOsResult struct {
 	IsChange        bool   `json:"ischange"`
 	DerivationIndex uint   `json:"derivationindex"`
 	Confirmations   uint   `json:"confirmations"`
+
+	// SilentPaymentTweak is the tweak added to the spend key to spend a
+	// silent payment.
+	SilentPaymentTweak string `json:"silentpaymenttweak,omitempty"`
+
+	// Label is the label of the address the utxo pays to.
+	Label string `json:"label,omitempty"`
 }
 
 // GetBlockHeaderVerboseResult models the data from the getblockheader command when
@@ -97,6 +147,24 @@ type GetBlockStatsResult struct {
 	UTXOSizeIncrease   int64   `json:"utxo_size_inc"`
 }
 
+// GetBlockTTLResult models the data of a leaf from the getblockttls command.
+// The spend height is left out for unspent leaves.
+type GetBlockTTLResult struct {
+	Txid        string `json:"txid"`
+	Vout        uint32 `json:"vout"`
+	Index       uint32 `json:"index"`
+	TTL         int32  `json:"ttl"`
+	SpendHeight *int32 `json:"spendheight,omitempty"`
+}
+
+// GetBlocksByTimeResult models the data of a block from the getblocksbytime
+// command.
+type GetBlocksByTimeResult struct {
+	Hash   string `json:"hash"`
+	Height int32  `json:"height"`
+	Time   int64  `json:"time"`
+}
+
 // GetBlockVerboseResult models the data from the getblock command when the
 // verbose flag is set to 1.  When the verbose flag is set to 0, getblock returns a
 // hex-encoded string. When the verbose flag is set to 1, getblock returns an object
@@ -280,6 +348,7 @@ type GetBlockChainInfoResult struct {
 	PruneHeight          int32   `json:"pruneheight,omitempty"`
 	ChainWork            string  `json:"chainwork,omitempty"`
 	SizeOnDisk           int64   `json:"size_on_disk,omitempty"`
+	Warnings             string  `json:"warnings"`
 	*SoftForks
 	*UnifiedSoftForks
 }
@@ -294,7 +363,7 @@ type GetBlockFilterResult struct {
 // GetBlockTemplateResultTx models the transactions field of the
 // getblocktemplate command.
 type GetBlockTemplateResultTx struct {
-	Data    string  `json:"data"`
+	Data    string  `json:"data,omitempty"`
 	Hash    string  `json:"hash"`
 	TxID    string  `json:"txid"`
 	Depends []int64 `json:"depends"`
@@ -331,6 +400,14 @@ type GetBlockTemplateResult struct {
 	// Witness commitment defined in BIP 0141.
 	DefaultWitnessCommitment string `json:"default_witness_commitment,omitempty"`
 
+	// Utreexo proof of the inputs of the transactions.  Only provided when
+	// the utreexoproof capability is requested.
+	UtreexoProof string `json:"utreexoproof,omitempty"`
+
+	// Long poll ID of the template the result is a delta against.  The
+	// data of the transactions that are in that template is omitted.
+	BaseTemplate string `json:"basetemplate,omitempty"`
+
 	// Optional long polling from BIP 0022.
 	LongPollID  string `json:"longpollid,omitempty"`
 	LongPollURI string `json:"longpolluri,omitempty"`
@@ -351,9 +428,8 @@ type GetBlockTemplateResult struct {
 	RejectReasion string   `json:"reject-reason,omitempty"`
 }
 
-// GetMempoolEntryResult models the data returned from the getmempoolentry's
-// fee field
-
+// MempoolFees models the fees field of the data returned from the
+// getmempoolentry command.
 type MempoolFees struct {
 	Base       float64 `json:"base"`
 	Modified   float64 `json:"modified"`
@@ -361,32 +437,67 @@ type MempoolFees struct {
 	Descendant float64 `json:"descendant"`
 }
 
+// GetIndexInfoResult models the data of an index returned from the
+// getindexinfo command.
+type GetIndexInfoResult struct {
+	Synced          bool   `json:"synced"`
+	BestBlockHeight int32  `json:"best_block_height"`
+	BestBlockHash   string `json:"best_block_hash"`
+	SizeOnDisk      int64  `json:"size_on_disk"`
+}
+
+// BackupDataDirResult models the data returned from the backupdatadir command.
+type BackupDataDirResult struct {
+	Destination string `json:"destination"`
+	Height      int32  `json:"height"`
+	Hash        string `json:"hash"`
+}
+
+// GetDBCompactionInfoResult models the data returned from the
+// getdbcompactioninfo command.
+type GetDBCompactionInfoResult struct {
+	Running       bool    `json:"running"`
+	Progress      float64 `json:"progress"`
+	RangesDone    int     `json:"ranges_done"`
+	RangesTotal   int     `json:"ranges_total"`
+	LastStarted   int64   `json:"last_started,omitempty"`
+	LastFinished  int64   `json:"last_finished,omitempty"`
+	LastError     string  `json:"last_error,omitempty"`
+	NextScheduled int64   `json:"next_scheduled,omitempty"`
+}
+
 // GetMempoolEntryResult models the data returned from the getmempoolentry
 // command.
 type GetMempoolEntryResult struct {
-	VSize           int32       `json:"vsize"`
-	Size            int32       `json:"size"`
-	Weight          int64       `json:"weight"`
-	Fee             float64     `json:"fee"`
-	ModifiedFee     float64     `json:"modifiedfee"`
-	Time            int64       `json:"time"`
-	Height          int64       `json:"height"`
-	DescendantCount int64       `json:"descendantcount"`
-	DescendantSize  int64       `json:"descendantsize"`
-	DescendantFees  float64     `json:"descendantfees"`
-	AncestorCount   int64       `json:"ancestorcount"`
-	AncestorSize    int64       `json:"ancestorsize"`
-	AncestorFees    float64     `json:"ancestorfees"`
-	WTxId           string      `json:"wtxid"`
-	Fees            MempoolFees `json:"fees"`
-	Depends         []string    `json:"depends"`
+	VSize             int32       `json:"vsize"`
+	Size              int32       `json:"size"`
+	Weight            int64       `json:"weight"`
+	Fee               float64     `json:"fee"`
+	ModifiedFee       float64     `json:"modifiedfee"`
+	Time              int64       `json:"time"`
+	Height            int64       `json:"height"`
+	DescendantCount   int64       `json:"descendantcount"`
+	DescendantSize    int64       `json:"descendantsize"`
+	DescendantFees    float64     `json:"descendantfees"`
+	AncestorCount     int64       `json:"ancestorcount"`
+	AncestorSize      int64       `json:"ancestorsize"`
+	AncestorFees      float64     `json:"ancestorfees"`
+	WTxId             string      `json:"wtxid"`
+	Fees              MempoolFees `json:"fees"`
+	Depends           []string    `json:"depends"`
+	SpentBy           []string    `json:"spentby"`
+	BIP125Replaceable bool        `json:"bip125-replaceable"`
 }
 
 // GetMempoolInfoResult models the data returned from the getmempoolinfo
 // command.
 type GetMempoolInfoResult struct {
-	Size  int64 `json:"size"`
-	Bytes int64 `json:"bytes"`
+	Size          int64   `json:"size"`
+	Bytes         int64   `json:"bytes"`
+	MaxMempool    int64   `json:"maxmempool"`
+	MempoolMinFee float64 `json:"mempoolminfee"`
+	MinRelayTxFee float64 `json:"minrelaytxfee"`
+	MempoolExpiry int64   `json:"mempoolexpiry"`
 }
 
 // NetworksResult models the networks data from the getnetworkinfo command.
@@ -406,6 +517,16 @@ type LocalAddressesResult struct {
 	Score   int32  `json:"score"`
 }
 
+// PortMappingResult models the portmapping data from the getnetworkinfo
+// command.
+type PortMappingResult struct {
+	Protocol        string `json:"protocol"`
+	ExternalAddress string `json:"externaladdress,omitempty"`
+	ExternalPort    uint16 `json:"externalport,omitempty"`
+	LastRenewal     int64  `json:"lastrenewal,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
 // GetNetworkInfoResult models the data returned from the getnetworkinfo
 // command.
 type GetNetworkInfoResult struct {
@@ -421,6 +542,7 @@ type GetNetworkInfoResult struct {
 	RelayFee        float64                `json:"relayfee"`
 	IncrementalFee  float64                `json:"incrementalfee"`
 	LocalAddresses  []LocalAddressesResult `json:"localaddresses"`
+	PortMapping     *PortMappingResult     `json:"portmapping,omitempty"`
 	Warnings        string                 `json:"warnings"`
 }
 
@@ -434,29 +556,52 @@ type GetNodeAddressesResult struct {
 	Port     uint16 `json:"port"`     // The port of the node
 }
 
+// ListBannedResult models the data returned from the listbanned command.
+type ListBannedResult struct {
+	Address       string `json:"address"`
+	BanCreated    int64  `json:"ban_created"`
+	BannedUntil   int64  `json:"banned_until"`
+	BanDuration   int64  `json:"ban_duration"`
+	TimeRemaining int64  `json:"time_remaining"`
+	BanReason     string `json:"ban_reason"`
+}
+
 // GetPeerInfoResult models the data returned from the getpeerinfo command.
 type GetPeerInfoResult struct {
-	ID             int32   `json:"id"`
-	Addr           string  `json:"addr"`
-	AddrLocal      string  `json:"addrlocal,omitempty"`
-	Services       string  `json:"services"`
-	RelayTxes      bool    `json:"relaytxes"`
-	LastSend       int64   `json:"lastsend"`
-	LastRecv       int64   `json:"lastrecv"`
-	BytesSent      uint64  `json:"bytessent"`
-	BytesRecv      uint64  `json:"bytesrecv"`
-	ConnTime       int64   `json:"conntime"`
-	TimeOffset     int64   `json:"timeoffset"`
-	PingTime       float64 `json:"pingtime"`
-	PingWait       float64 `json:"pingwait,omitempty"`
-	Version        uint32  `json:"version"`
-	SubVer         string  `json:"subver"`
-	Inbound        bool    `json:"inbound"`
-	StartingHeight int32   `json:"startingheight"`
-	CurrentHeight  int32   `json:"currentheight,omitempty"`
-	BanScore       int32   `json:"banscore"`
-	FeeFilter      int64   `json:"feefilter"`
-	SyncNode       bool    `json:"syncnode"`
+	ID             int32                     `json:"id"`
+	Addr           string                    `json:"addr"`
+	AddrLocal      string                    `json:"addrlocal,omitempty"`
+	Services       string                    `json:"services"`
+	RelayTxes      bool                      `json:"relaytxes"`
+	LastSend       int64                     `json:"lastsend"`
+	LastRecv       int64                     `json:"lastrecv"`
+	BytesSent      uint64                    `json:"bytessent"`
+	BytesRecv      uint64                    `json:"bytesrecv"`
+	ConnTime       int64                     `json:"conntime"`
+	TimeOffset     int64                     `json:"timeoffset"`
+	PingTime       float64                   `json:"pingtime"`
+	PingWait       float64                   `json:"pingwait,omitempty"`
+	Version        uint32                    `json:"version"`
+	SubVer         string                    `json:"subver"`
+	Inbound        bool                      `json:"inbound"`
+	StartingHeight int32                     `json:"startingheight"`
+	CurrentHeight  int32                     `json:"currentheight,omitempty"`
+	BanScore       int32                     `json:"banscore"`
+	FeeFilter      int64                     `json:"feefilter"`
+	SyncNode       bool                      `json:"syncnode"`
+	BlockRelayOnly bool                      `json:"blockrelayonly"`
+	Permissions    []string                  `json:"permissions"`
+	Utreexo        *GetPeerInfoUtreexoResult `json:"utreexo,omitempty"`
+}
+
+// GetPeerInfoUtreexoResult models the utreexo data of a peer from the
+// getpeerinfo command.
+type GetPeerInfoUtreexoResult struct {
+	NodeType             string `json:"nodetype"`
+	ProofVersion         uint32 `json:"proofversion"`
+	ProofBytesSent       uint64 `json:"proofbytessent"`
+	ProofBytesRecv       uint64 `json:"proofbytesrecv"`
+	ProofRequestFailures uint64 `json:"proofrequestfailures"`
 }
 
 // GetRawMempoolVerboseResult models the data returned from the getrawmempool
@@ -484,6 +629,21 @@ type ScriptPubKeyResult struct {
 	Addresses []string `json:"addresses,omitempty"`
 }
 
+// GetScriptBalanceResult models the data from the getscriptbalance command.
+// The status is left out for scripts without any confirmed transactions.
+type GetScriptBalanceResult struct {
+	Confirmed int64  `json:"confirmed"`
+	TxCount   uint32 `json:"txcount"`
+	Status    string `json:"status,omitempty"`
+}
+
+// GetSilentPaymentTweakResult models the data of a transaction from the
+// getsilentpaymenttweaks command.
+type GetSilentPaymentTweakResult struct {
+	Txid  string `json:"txid"`
+	Tweak string `json:"tweak"`
+}
+
 // GetTTLResult models the data from the getttl command.
 type GetTTLResult struct {
 	TTL int32 `json:"ttl"`
@@ -498,16 +658,92 @@ type GetTxOutResult struct {
 	Coinbase      bool               `json:"coinbase"`
 }
 
+// These constants define the modes of the data returned from the
+// gettxoutsetinfo command.
+const (
+	// TxOutSetModeUtxoSet is the mode of nodes that keep the full utxo set,
+	// which includes bridge nodes.  All of the fields are available.
+	TxOutSetModeUtxoSet = "utxoset"
+
+	// TxOutSetModeAccumulator is the mode of compact state nodes, which
+	// only keep the utreexo accumulator.  Only the height, best block, and
+	// utreexo fields are available.
+	TxOutSetModeAccumulator = "accumulator"
+)
+
+// GetTxOutSetInfoUtreexoResult models the utreexo accumulator data from the
+// gettxoutsetinfo command.
+type GetTxOutSetInfoUtreexoResult struct {
+	NumLeaves uint64   `json:"numleaves"`
+	Roots     []string `json:"roots"`
+}
+
+// GetTxSpendingPrevOutResult models the data of an output from the
+// gettxspendingprevout command.  The spending transaction is left out when the
+// output isn't known to be spent and the block hash is only set for spending
+// transactions that are in a block.
+type GetTxSpendingPrevOutResult struct {
+	Txid         string `json:"txid"`
+	Vout         uint32 `json:"vout"`
+	SpendingTxid string `json:"spendingtxid,omitempty"`
+	BlockHash    string `json:"blockhash,omitempty"`
+}
+
 // GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
 type GetTxOutSetInfoResult struct {
-	Height         int64          `json:"height"`
-	BestBlock      chainhash.Hash `json:"bestblock"`
-	Transactions   int64          `json:"transactions"`
-	TxOuts         int64          `json:"txouts"`
-	BogoSize       int64          `json:"bogosize"`
-	HashSerialized chainhash.Hash `json:"hash_serialized_2"`
-	DiskSize       int64          `json:"disk_size"`
-	TotalAmount    btcutil.Amount `json:"total_amount"`
+	Height         int64                         `json:"height"`
+	BestBlock      chainhash.Hash                `json:"bestblock"`
+	Mode           string                        `json:"mode,omitempty"`
+	Transactions   int64                         `json:"transactions"`
+	TxOuts         int64                         `json:"txouts"`
+	BogoSize       int64                         `json:"bogosize"`
+	HashSerialized chainhash.Hash                `json:"hash_serialized_2"`
+	DiskSize       int64                         `json:"disk_size"`
+	TotalAmount    btcutil.Amount                `json:"total_amount"`
+	Utreexo        *GetTxOutSetInfoUtreexoResult `json:"utreexo,omitempty"`
+}
+
+// MarshalJSON marshals the result of the gettxoutsetinfo JSON-RPC call.  The
+// total amount is marshalled in BTC and only the fields that are available in
+// the mode of the result are included.
+func (g GetTxOutSetInfoResult) MarshalJSON() ([]byte, error) {
+	if g.Mode == TxOutSetModeAccumulator {
+		return json.Marshal(&struct {
+			Height    int64                         `json:"height"`
+			BestBlock chainhash.Hash                `json:"bestblock"`
+			Mode      string                        `json:"mode"`
+			Utreexo   *GetTxOutSetInfoUtreexoResult `json:"utreexo,omitempty"`
+		}{
+			Height:    g.Height,
+			BestBlock: g.BestBlock,
+			Mode:      g.Mode,
+			Utreexo:   g.Utreexo,
+		})
+	}
+
+	return json.Marshal(&struct {
+		Height         int64                         `json:"height"`
+		BestBlock      chainhash.Hash                `json:"bestblock"`
+		Mode           string                        `json:"mode,omitempty"`
+		Transactions   int64                         `json:"transactions"`
+		TxOuts         int64                         `json:"txouts"`
+		BogoSize       int64                         `json:"bogosize"`
+		HashSerialized chainhash.Hash                `json:"hash_serialized_2"`
+		DiskSize       int64                         `json:"disk_size"`
+		TotalAmount    float64                       `json:"total_amount"`
+		Utreexo        *GetTxOutSetInfoUtreexoResult `json:"utreexo,omitempty"`
+	}{
+		Height:         g.Height,
+		BestBlock:      g.BestBlock,
+		Mode:           g.Mode,
+		Transactions:   g.Transactions,
+		TxOuts:         g.TxOuts,
+		BogoSize:       g.BogoSize,
+		HashSerialized: g.HashSerialized,
+		DiskSize:       g.DiskSize,
+		TotalAmount:    g.TotalAmount.ToBTC(),
+		Utreexo:        g.Utreexo,
+	})
 }
 
 // UnmarshalJSON unmarshals the result of the gettxoutsetinfo JSON-RPC call
@@ -539,12 +775,15 @@ func (g *GetTxOutSetInfoResult) UnmarshalJSON(data []byte) error {
 
 	g.BestBlock = *blockHash
 
-	serializedHash, err := chainhash.NewHashFromStr(aux.HashSerialized)
-	if err != nil {
-		return err
-	}
+	// The serialized hash isn't available in the accumulator mode.
+	if aux.HashSerialized != "" {
+		serializedHash, err := chainhash.NewHashFromStr(aux.HashSerialized)
+		if err != nil {
+			return err
+		}
 
-	g.HashSerialized = *serializedHash
+		g.HashSerialized = *serializedHash
+	}
 
 	amount, err := btcutil.NewAmount(aux.TotalAmount)
 	if err != nil {
@@ -556,11 +795,119 @@ func (g *GetTxOutSetInfoResult) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// DumpTxOutSetResult models the data from the dumptxoutset command.
+type DumpTxOutSetResult struct {
+	CoinsWritten   uint64 `json:"coins_written"`
+	BaseHash       string `json:"base_hash"`
+	BaseHeight     int32  `json:"base_height"`
+	Path           string `json:"path"`
+	HashSerialized string `json:"hash_serialized_2"`
+}
+
+// EstimateRawFeeBucketResult models the statistics of a range of fee rate
+// buckets returned by the estimaterawfee command.
+type EstimateRawFeeBucketResult struct {
+	StartRange     float64 `json:"startrange"`
+	EndRange       float64 `json:"endrange"`
+	WithinTarget   float64 `json:"withintarget"`
+	TotalConfirmed float64 `json:"totalconfirmed"`
+	InMempool      float64 `json:"inmempool"`
+	LeftMempool    float64 `json:"leftmempool"`
+}
+
+// EstimateRawFeeHorizonResult models the estimate of a horizon returned by the
+// estimaterawfee command.
+type EstimateRawFeeHorizonResult struct {
+	FeeRate *float64                    `json:"feerate,omitempty"`
+	Decay   float64                     `json:"decay"`
+	Scale   uint32                      `json:"scale"`
+	Pass    *EstimateRawFeeBucketResult `json:"pass,omitempty"`
+	Fail    *EstimateRawFeeBucketResult `json:"fail,omitempty"`
+	Errors  []string                    `json:"errors,omitempty"`
+}
+
+// EstimateRawFeeResult models the data from the estimaterawfee command.  The
+// horizons that don't track the confirmation target are omitted.
+type EstimateRawFeeResult struct {
+	Short  *EstimateRawFeeHorizonResult `json:"short,omitempty"`
+	Medium *EstimateRawFeeHorizonResult `json:"medium,omitempty"`
+	Long   *EstimateRawFeeHorizonResult `json:"long,omitempty"`
+}
+
+// LoadTxOutSetResult models the data from the loadtxoutset command.
+type LoadTxOutSetResult struct {
+	CoinsLoaded    uint64 `json:"coins_loaded"`
+	TipHash        string `json:"tip_hash"`
+	BaseHeight     int32  `json:"base_height"`
+	Path           string `json:"path"`
+	HashSerialized string `json:"hash_serialized_2"`
+}
+
+// ScanBlocksResult models the data from the scanblocks command when a scan is
+// started.
+type ScanBlocksResult struct {
+	FromHeight     int32    `json:"from_height"`
+	ToHeight       int32    `json:"to_height"`
+	RelevantBlocks []string `json:"relevant_blocks"`
+	Completed      bool     `json:"completed"`
+}
+
+// ScanBlocksStatusResult models the data from the scanblocks command when the
+// status of the scan in progress is requested.
+type ScanBlocksStatusResult struct {
+	Progress      int   `json:"progress"`
+	CurrentHeight int32 `json:"current_height"`
+}
+
+// ScanTxOutSetUnspent models an unspent output found by the scantxoutset
+// command.
+type ScanTxOutSetUnspent struct {
+	TxID          string  `json:"txid"`
+	Vout          uint32  `json:"vout"`
+	ScriptPubKey  string  `json:"scriptPubKey"`
+	Desc          string  `json:"desc"`
+	Amount        float64 `json:"amount"`
+	Coinbase      bool    `json:"coinbase"`
+	Height        int32   `json:"height"`
+	BlockHash     string  `json:"blockhash"`
+	Confirmations int64   `json:"confirmations"`
+}
+
+// ScanTxOutSetResult models the data from the scantxoutset command when a scan
+// is started.
+type ScanTxOutSetResult struct {
+	Success     bool                  `json:"success"`
+	TxOuts      int64                 `json:"txouts"`
+	Height      int32                 `json:"height"`
+	BestBlock   string                `json:"bestblock"`
+	Unspents    []ScanTxOutSetUnspent `json:"unspents"`
+	TotalAmount float64               `json:"total_amount"`
+	Proof       string                `json:"proof,omitempty"`
+}
+
+// ScanTxOutSetStatusResult models the data from the scantxoutset command when
+// the status of the scan in progress is requested.
+type ScanTxOutSetStatusResult struct {
+	Progress int `json:"progress"`
+}
+
+// UploadTargetResult models the uploadtarget data from the getnettotals
+// command.
+type UploadTargetResult struct {
+	TimeFrame             int64  `json:"timeframe"`
+	Target                uint64 `json:"target"`
+	TargetReached         bool   `json:"target_reached"`
+	ServeHistoricalBlocks bool   `json:"serve_historical_blocks"`
+	BytesLeftInCycle      uint64 `json:"bytes_left_in_cycle"`
+	TimeLeftInCycle       int64  `json:"time_left_in_cycle"`
+}
+
 // GetNetTotalsResult models the data returned from the getnettotals command.
 type GetNetTotalsResult struct {
-	TotalBytesRecv uint64 `json:"totalbytesrecv"`
-	TotalBytesSent uint64 `json:"totalbytessent"`
-	TimeMillis     int64  `json:"timemillis"`
+	TotalBytesRecv uint64              `json:"totalbytesrecv"`
+	TotalBytesSent uint64              `json:"totalbytessent"`
+	TimeMillis     int64               `json:"timemillis"`
+	UploadTarget   *UploadTargetResult `json:"uploadtarget,omitempty"`
 }
 
 // GetTxTotalsResult models the data returned from the gettxtotals command.
@@ -586,12 +933,13 @@ type ScriptSig struct {
 // getrawtransaction, decoderawtransaction, and searchrawtransaction use the
 // same structure.
 type Vin struct {
-	Coinbase  string     `json:"coinbase"`
-	Txid      string     `json:"txid"`
-	Vout      uint32     `json:"vout"`
-	ScriptSig *ScriptSig `json:"scriptSig"`
-	Sequence  uint32     `json:"sequence"`
-	Witness   []string   `json:"txinwitness"`
+	Coinbase  string         `json:"coinbase"`
+	Txid      string         `json:"txid"`
+	Vout      uint32         `json:"vout"`
+	ScriptSig *ScriptSig     `json:"scriptSig"`
+	Sequence  uint32         `json:"sequence"`
+	Witness   []string       `json:"txinwitness"`
+	PrevOut   *PrevOutResult `json:"prevout,omitempty"`
 }
 
 // IsCoinBase returns a bool to show if a Vin is a Coinbase one or not.
@@ -622,35 +970,48 @@ func (v *Vin) MarshalJSON() ([]byte, error) {
 
 	if v.HasWitness() {
 		txStruct := struct {
-			Txid      string     `json:"txid"`
-			Vout      uint32     `json:"vout"`
-			ScriptSig *ScriptSig `json:"scriptSig"`
-			Witness   []string   `json:"txinwitness"`
-			Sequence  uint32     `json:"sequence"`
+			Txid      string         `json:"txid"`
+			Vout      uint32         `json:"vout"`
+			ScriptSig *ScriptSig     `json:"scriptSig"`
+			Witness   []string       `json:"txinwitness"`
+			PrevOut   *PrevOutResult `json:"prevout,omitempty"`
+			Sequence  uint32         `json:"sequence"`
 		}{
 			Txid:      v.Txid,
 			Vout:      v.Vout,
 			ScriptSig: v.ScriptSig,
 			Witness:   v.Witness,
+			PrevOut:   v.PrevOut,
 			Sequence:  v.Sequence,
 		}
 		return json.Marshal(txStruct)
 	}
 
 	txStruct := struct {
-		Txid      string     `json:"txid"`
-		Vout      uint32     `json:"vout"`
-		ScriptSig *ScriptSig `json:"scriptSig"`
-		Sequence  uint32     `json:"sequence"`
+		Txid      string         `json:"txid"`
+		Vout      uint32         `json:"vout"`
+		ScriptSig *ScriptSig     `json:"scriptSig"`
+		PrevOut   *PrevOutResult `json:"prevout,omitempty"`
+		Sequence  uint32         `json:"sequence"`
 	}{
 		Txid:      v.Txid,
 		Vout:      v.Vout,
 		ScriptSig: v.ScriptSig,
+		PrevOut:   v.PrevOut,
 		Sequence:  v.Sequence,
 	}
 	return json.Marshal(txStruct)
 }
 
+// PrevOutResult models the output spent by an input.  It is only returned
+// by getblock with verbosity 3.
+type PrevOutResult struct {
+	Generated    bool               `json:"generated"`
+	Height       int32              `json:"height"`
+	Value        float64            `json:"value"`
+	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
 // PrevOut represents previous output for an input Vin.
 type PrevOut struct {
 	Addresses []string `json:"addresses,omitempty"`
@@ -819,6 +1180,50 @@ type TxRawDecodeResult struct {
 	Vout     []Vout `json:"vout"`
 }
 
+// SubmitPackageFeesResult models the fees of a transaction returned by the
+// submitpackage command.
+type SubmitPackageFeesResult struct {
+	Base              float64  `json:"base"`
+	EffectiveFeeRate  *float64 `json:"effective-feerate,omitempty"`
+	EffectiveIncludes []string `json:"effective-includes,omitempty"`
+}
+
+// SubmitPackageTxResult models the result of a transaction returned by the
+// submitpackage command.
+type SubmitPackageTxResult struct {
+	Txid  string                   `json:"txid"`
+	Vsize int64                    `json:"vsize,omitempty"`
+	Fees  *SubmitPackageFeesResult `json:"fees,omitempty"`
+	Error string                   `json:"error,omitempty"`
+}
+
+// SubmitPackageResult models the data returned by the submitpackage command.
+// The transaction results are keyed by wtxid.
+type SubmitPackageResult struct {
+	PackageMsg           string                           `json:"package_msg"`
+	TxResults            map[string]SubmitPackageTxResult `json:"tx-results"`
+	ReplacedTransactions []string                         `json:"replaced-transactions"`
+}
+
+// TestMempoolAcceptFeesResult models the fees of a transaction returned by the
+// testmempoolaccept command.
+type TestMempoolAcceptFeesResult struct {
+	Base              float64  `json:"base"`
+	EffectiveFeeRate  float64  `json:"effective-feerate"`
+	EffectiveIncludes []string `json:"effective-includes"`
+}
+
+// TestMempoolAcceptResult models the result of a transaction returned by the
+// testmempoolaccept command.
+type TestMempoolAcceptResult struct {
+	Txid         string                       `json:"txid"`
+	Wtxid        string                       `json:"wtxid"`
+	Allowed      bool                         `json:"allowed"`
+	Vsize        int64                        `json:"vsize,omitempty"`
+	Fees         *TestMempoolAcceptFeesResult `json:"fees,omitempty"`
+	RejectReason string                       `json:"reject-reason,omitempty"`
+}
+
 // ValidateAddressChainResult models the data returned by the chain server
 // validateaddress command.
 //
@@ -924,6 +1329,15 @@ type ProveUtxoChainTipInclusionVerboseResult struct {
 	Hex          string   `json:"hex"`
 }
 
+// GetUtreexoCFHeaderResult models the data from the getutreexocfheader
+// command.
+type GetUtreexoCFHeaderResult struct {
+	Header     string `json:"header"`
+	PrevHeader string `json:"prevheader"`
+	FilterHash string `json:"filterhash"`
+	RootsHash  string `json:"rootshash"`
+}
+
 // GetUtreexoProofVerboseResult models the data from the
 // getutreexoproof when the verbose flag is set.  When the
 // verbose flag is not set, just the hex-encoded string of the entire proof
@@ -936,12 +1350,44 @@ type GetUtreexoProofVerboseResult struct {
 	ProofTargets    []uint64 `json:"prooftargets"`
 }
 
+// GetUtxoProofResult models the data from the getutxoproof command.
+type GetUtxoProofResult struct {
+	ProvedAtHash string   `json:"provedathash"`
+	ProofHashes  []string `json:"proofhashes"`
+	ProofTargets []uint64 `json:"prooftargets"`
+	HashesProven []string `json:"hashesproven"`
+	Hex          string   `json:"hex"`
+}
+
 // GetUtreexoRootsResult models the data from the getutreexoroots command.
 type GetUtreexoRootsResult struct {
+	BlockHash string   `json:"blockhash"`
+	Height    int32    `json:"height"`
 	Roots     []string `json:"roots"`
 	NumLeaves uint64   `json:"numleaves"`
 }
 
+// GetUtreexoStatsResult models the data from the getutreexostats command.
+type GetUtreexoStatsResult struct {
+	NumLeaves                uint64  `json:"numleaves"`
+	NumRoots                 int     `json:"numroots"`
+	StateSize                int64   `json:"statesize"`
+	NodesCacheHits           uint64  `json:"nodescachehits"`
+	NodesCacheMisses         uint64  `json:"nodescachemisses"`
+	NodesCacheHitRate        float64 `json:"nodescachehitrate"`
+	CachedLeavesCacheHits    uint64  `json:"cachedleavescachehits"`
+	CachedLeavesCacheMisses  uint64  `json:"cachedleavescachemisses"`
+	CachedLeavesCacheHitRate float64 `json:"cachedleavescachehitrate"`
+	ProofsServed             uint64  `json:"proofsserved"`
+	ProofBytesServed         uint64  `json:"proofbytesserved"`
+	AvgProofSize             float64 `json:"avgproofsize"`
+	ProofOrphans             int     `json:"prooforphans"`
+	ProofOrphansTotal        uint64  `json:"prooforphanstotal"`
+	ProofRequests            uint64  `json:"proofrequests"`
+	ProofOrphansResolved     uint64  `json:"prooforphansresolved"`
+	ProofOrphansDropped      uint64  `json:"prooforphansdropped"`
+}
+
 // ProveWatchOnlyChainTipInclusionVerboseResult models the data from the
 // provewatchonlychaintipinclusion command when the verbose flag is set.  When the
 // verbose flag is not set, just the hex-encoded string of the entire proof
@@ -961,6 +1407,15 @@ type BDKAddressResult struct {
 	Address string `json:"address"`
 }
 
+// CpfpBDKTransactionResult models the data from the cpfpbdktransaction
+// command.
+type CpfpBDKTransactionResult struct {
+	TxHash    string `json:"txhash"`
+	RawBytes  string `json:"rawbytes"`
+	ParentFee int64  `json:"parentfee"`
+	Fee       int64  `json:"fee"`
+}
+
 // CreateTransactionFromBDKWalletResult models the data from the
 // createtransactionfrombdkwallet command.
 type CreateTransactionFromBDKWalletResult struct {
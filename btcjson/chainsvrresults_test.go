@@ -47,6 +47,29 @@ func TestChainSvrCustomResults(t *testing.T) {
 			},
 			expected: `{"txid":"123","vout":1,"scriptSig":{"asm":"0","hex":"00"},"sequence":4294967295}`,
 		},
+		{
+			name: "custom vin marshal with prevout",
+			result: &btcjson.Vin{
+				Txid: "123",
+				Vout: 1,
+				ScriptSig: &btcjson.ScriptSig{
+					Asm: "0",
+					Hex: "00",
+				},
+				PrevOut: &btcjson.PrevOutResult{
+					Generated: true,
+					Height:    100,
+					Value:     50,
+					ScriptPubKey: btcjson.ScriptPubKeyResult{
+						Asm:  "OP_TRUE",
+						Hex:  "51",
+						Type: "nonstandard",
+					},
+				},
+				Sequence: 4294967295,
+			},
+			expected: `{"txid":"123","vout":1,"scriptSig":{"asm":"0","hex":"00"},"prevout":{"generated":true,"height":100,"value":50,"scriptPubKey":{"asm":"OP_TRUE","hex":"51","type":"nonstandard"}},"sequence":4294967295}`,
+		},
 		{
 			name: "custom vinprevout marshal with coinbase",
 			result: &btcjson.VinPrevOut{
@@ -136,6 +159,26 @@ func TestGetTxOutSetInfoResult(t *testing.T) {
 				}(),
 			},
 		},
+		{
+			name:   "GetTxOutSetInfoResult - accumulator",
+			result: `{"height":123,"bestblock":"000000000000005f94116250e2407310463c0a7cf950f1af9ebe935b1c0687ab","mode":"accumulator","utreexo":{"numleaves":3,"roots":["9a0a561203ff052182993bc5d0cb2c620880bfafdbd80331f65fd9546c3e5c3e"]}}`,
+			want: btcjson.GetTxOutSetInfoResult{
+				Height: 123,
+				BestBlock: func() chainhash.Hash {
+					h, err := chainhash.NewHashFromStr("000000000000005f94116250e2407310463c0a7cf950f1af9ebe935b1c0687ab")
+					if err != nil {
+						panic(err)
+					}
+
+					return *h
+				}(),
+				Mode: btcjson.TxOutSetModeAccumulator,
+				Utreexo: &btcjson.GetTxOutSetInfoUtreexoResult{
+					NumLeaves: 3,
+					Roots:     []string{"9a0a561203ff052182993bc5d0cb2c620880bfafdbd80331f65fd9546c3e5c3e"},
+				},
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -154,6 +197,20 @@ func TestGetTxOutSetInfoResult(t *testing.T) {
 				spew.Sdump(test.want))
 			continue
 		}
+
+		// Marshalling the result gives back the same data.
+		marshalled, err := json.Marshal(&out)
+		if err != nil {
+			t.Errorf("Test #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+		if string(marshalled) != test.result {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.result)
+			continue
+		}
 	}
 }
 
@@ -41,6 +41,63 @@ func NewStopNotifyBlocksCmd() *StopNotifyBlocksCmd {
 	return &StopNotifyBlocksCmd{}
 }
 
+// NotifyUtreexoRootsCmd defines the notifyutreexoroots JSON-RPC command.
+type NotifyUtreexoRootsCmd struct{}
+
+// NewNotifyUtreexoRootsCmd returns a new instance which can be used to issue a
+// notifyutreexoroots JSON-RPC command.
+func NewNotifyUtreexoRootsCmd() *NotifyUtreexoRootsCmd {
+	return &NotifyUtreexoRootsCmd{}
+}
+
+// StopNotifyUtreexoRootsCmd defines the stopnotifyutreexoroots JSON-RPC
+// command.
+type StopNotifyUtreexoRootsCmd struct{}
+
+// NewStopNotifyUtreexoRootsCmd returns a new instance which can be used to
+// issue a stopnotifyutreexoroots JSON-RPC command.
+func NewStopNotifyUtreexoRootsCmd() *StopNotifyUtreexoRootsCmd {
+	return &StopNotifyUtreexoRootsCmd{}
+}
+
+// NotifyMempoolEventsCmd defines the notifymempoolevents JSON-RPC command.
+type NotifyMempoolEventsCmd struct{}
+
+// NewNotifyMempoolEventsCmd returns a new instance which can be used to issue a
+// notifymempoolevents JSON-RPC command.
+func NewNotifyMempoolEventsCmd() *NotifyMempoolEventsCmd {
+	return &NotifyMempoolEventsCmd{}
+}
+
+// StopNotifyMempoolEventsCmd defines the stopnotifymempoolevents JSON-RPC
+// command.
+type StopNotifyMempoolEventsCmd struct{}
+
+// NewStopNotifyMempoolEventsCmd returns a new instance which can be used to
+// issue a stopnotifymempoolevents JSON-RPC command.
+func NewStopNotifyMempoolEventsCmd() *StopNotifyMempoolEventsCmd {
+	return &StopNotifyMempoolEventsCmd{}
+}
+
+// NotifyWalletEventsCmd defines the notifywalletevents JSON-RPC command.
+type NotifyWalletEventsCmd struct{}
+
+// NewNotifyWalletEventsCmd returns a new instance which can be used to issue a
+// notifywalletevents JSON-RPC command.
+func NewNotifyWalletEventsCmd() *NotifyWalletEventsCmd {
+	return &NotifyWalletEventsCmd{}
+}
+
+// StopNotifyWalletEventsCmd defines the stopnotifywalletevents JSON-RPC
+// command.
+type StopNotifyWalletEventsCmd struct{}
+
+// NewStopNotifyWalletEventsCmd returns a new instance which can be used to
+// issue a stopnotifywalletevents JSON-RPC command.
+func NewStopNotifyWalletEventsCmd() *StopNotifyWalletEventsCmd {
+	return &StopNotifyWalletEventsCmd{}
+}
+
 // NotifyNewTransactionsCmd defines the notifynewtransactions JSON-RPC command.
 type NotifyNewTransactionsCmd struct {
 	Verbose *bool `jsonrpcdefault:"false"`
@@ -228,14 +285,20 @@ func init() {
 	MustRegisterCmd("authenticate", (*AuthenticateCmd)(nil), flags)
 	MustRegisterCmd("loadtxfilter", (*LoadTxFilterCmd)(nil), flags)
 	MustRegisterCmd("notifyblocks", (*NotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("notifymempoolevents", (*NotifyMempoolEventsCmd)(nil), flags)
 	MustRegisterCmd("notifynewtransactions", (*NotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("notifyreceived", (*NotifyReceivedCmd)(nil), flags)
 	MustRegisterCmd("notifyspent", (*NotifySpentCmd)(nil), flags)
+	MustRegisterCmd("notifyutreexoroots", (*NotifyUtreexoRootsCmd)(nil), flags)
+	MustRegisterCmd("notifywalletevents", (*NotifyWalletEventsCmd)(nil), flags)
 	MustRegisterCmd("session", (*SessionCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyblocks", (*StopNotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("stopnotifymempoolevents", (*StopNotifyMempoolEventsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifynewtransactions", (*StopNotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyspent", (*StopNotifySpentCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyreceived", (*StopNotifyReceivedCmd)(nil), flags)
+	MustRegisterCmd("stopnotifywalletevents", (*StopNotifyWalletEventsCmd)(nil), flags)
+	MustRegisterCmd("stopnotifyutreexoroots", (*StopNotifyUtreexoRootsCmd)(nil), flags)
 	MustRegisterCmd("rescan", (*RescanCmd)(nil), flags)
 	MustRegisterCmd("rescanblocks", (*RescanBlocksCmd)(nil), flags)
 }
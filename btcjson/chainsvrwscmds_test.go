@@ -63,6 +63,72 @@ func TestChainSvrWsCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyblocks","params":[],"id":1}`,
 			unmarshalled: &btcjson.StopNotifyBlocksCmd{},
 		},
+		{
+			name: "notifyutreexoroots",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifyutreexoroots")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyUtreexoRootsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyutreexoroots","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyUtreexoRootsCmd{},
+		},
+		{
+			name: "stopnotifyutreexoroots",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifyutreexoroots")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyUtreexoRootsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyutreexoroots","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyUtreexoRootsCmd{},
+		},
+		{
+			name: "notifymempoolevents",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifymempoolevents")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyMempoolEventsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifymempoolevents","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyMempoolEventsCmd{},
+		},
+		{
+			name: "stopnotifymempoolevents",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifymempoolevents")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyMempoolEventsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifymempoolevents","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyMempoolEventsCmd{},
+		},
+		{
+			name: "notifywalletevents",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifywalletevents")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyWalletEventsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifywalletevents","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyWalletEventsCmd{},
+		},
+		{
+			name: "stopnotifywalletevents",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifywalletevents")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyWalletEventsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifywalletevents","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyWalletEventsCmd{},
+		},
 		{
 			name: "notifynewtransactions",
 			newCmd: func() (interface{}, error) {
@@ -75,6 +75,27 @@ const (
 	// from the chain server that inform a client that a transaction that
 	// matches the loaded filter was accepted by the mempool.
 	RelevantTxAcceptedNtfnMethod = "relevanttxaccepted"
+
+	// UtreexoRootsConnectedNtfnMethod is the method used for notifications
+	// from the chain server that a block has been connected and the utreexo
+	// accumulator has been updated.
+	UtreexoRootsConnectedNtfnMethod = "utreexorootsconnected"
+
+	// UtreexoRootsDisconnectedNtfnMethod is the method used for
+	// notifications from the chain server that a block has been
+	// disconnected and the utreexo accumulator has been rolled back.
+	UtreexoRootsDisconnectedNtfnMethod = "utreexorootsdisconnected"
+
+	// MempoolEventNtfnMethod is the method used for notifications from the
+	// chain server that a transaction was added to, removed from, replaced
+	// in or confirmed out of the mempool.
+	MempoolEventNtfnMethod = "mempoolevent"
+
+	// WalletEventNtfnMethod is the method used for notifications from the
+	// chain server that a bdk wallet received a payment, that one of its
+	// transactions was confirmed or that the fee of one of its
+	// transactions was bumped.
+	WalletEventNtfnMethod = "walletevent"
 )
 
 // BlockConnectedNtfn defines the blockconnected JSON-RPC notification.
@@ -285,6 +306,112 @@ func NewRelevantTxAcceptedNtfn(txHex string) *RelevantTxAcceptedNtfn {
 	return &RelevantTxAcceptedNtfn{Transaction: txHex}
 }
 
+// UtreexoRootsConnectedNtfn defines the utreexorootsconnected JSON-RPC
+// notification.  The roots and the number of leaves are of the accumulator
+// after the block was connected.
+type UtreexoRootsConnectedNtfn struct {
+	Hash      string
+	Height    int32
+	NumLeaves uint64
+	Roots     []string
+}
+
+// NewUtreexoRootsConnectedNtfn returns a new instance which can be used to
+// issue a utreexorootsconnected JSON-RPC notification.
+func NewUtreexoRootsConnectedNtfn(hash string, height int32, numLeaves uint64,
+	roots []string) *UtreexoRootsConnectedNtfn {
+
+	return &UtreexoRootsConnectedNtfn{
+		Hash:      hash,
+		Height:    height,
+		NumLeaves: numLeaves,
+		Roots:     roots,
+	}
+}
+
+// UtreexoRootsDisconnectedNtfn defines the utreexorootsdisconnected JSON-RPC
+// notification.  The hash and the height are of the disconnected block while
+// the roots and the number of leaves are of the accumulator after the block was
+// disconnected.
+type UtreexoRootsDisconnectedNtfn struct {
+	Hash      string
+	Height    int32
+	NumLeaves uint64
+	Roots     []string
+}
+
+// NewUtreexoRootsDisconnectedNtfn returns a new instance which can be used to
+// issue a utreexorootsdisconnected JSON-RPC notification.
+func NewUtreexoRootsDisconnectedNtfn(hash string, height int32, numLeaves uint64,
+	roots []string) *UtreexoRootsDisconnectedNtfn {
+
+	return &UtreexoRootsDisconnectedNtfn{
+		Hash:      hash,
+		Height:    height,
+		NumLeaves: numLeaves,
+		Roots:     roots,
+	}
+}
+
+// MempoolEventDetails describes a transaction that entered or left the mempool.
+// The event is one of added, removed, replaced or confirmed.  The fee and the
+// fee rate in BTC/kvB are the ones the transaction was accepted with.  The
+// replacing transaction is only set for replaced events while the block is only
+// set for confirmed events.
+type MempoolEventDetails struct {
+	Event       string  `json:"event"`
+	TxID        string  `json:"txid"`
+	Fee         float64 `json:"fee"`
+	VSize       int64   `json:"vsize"`
+	FeeRate     float64 `json:"feerate"`
+	ReplacedBy  string  `json:"replacedby,omitempty"`
+	BlockHash   string  `json:"blockhash,omitempty"`
+	BlockHeight int32   `json:"blockheight,omitempty"`
+}
+
+// MempoolEventNtfn defines the mempoolevent JSON-RPC notification.
+type MempoolEventNtfn struct {
+	Event MempoolEventDetails
+}
+
+// NewMempoolEventNtfn returns a new instance which can be used to issue a
+// mempoolevent JSON-RPC notification.
+func NewMempoolEventNtfn(event MempoolEventDetails) *MempoolEventNtfn {
+	return &MempoolEventNtfn{
+		Event: event,
+	}
+}
+
+// WalletEventDetails describes an event of a bdk wallet.  The event is one of
+// received, confirmed or feebumped and the wallet is empty for the default
+// wallet.  The amounts are in BTC.  The bumped transaction, the fees and the
+// method, which is either bumpfee or cpfp, are only set for feebumped events.
+type WalletEventDetails struct {
+	Event         string  `json:"event"`
+	Wallet        string  `json:"wallet"`
+	TxID          string  `json:"txid"`
+	Received      float64 `json:"received"`
+	Spent         float64 `json:"spent"`
+	Confirmations uint    `json:"confirmations"`
+	BumpedTxID    string  `json:"bumpedtxid,omitempty"`
+	Fee           float64 `json:"fee,omitempty"`
+	OriginalFee   float64 `json:"originalfee,omitempty"`
+	Method        string  `json:"method,omitempty"`
+}
+
+// WalletEventNtfn defines the walletevent JSON-RPC notification.
+type WalletEventNtfn struct {
+	Event WalletEventDetails
+}
+
+// NewWalletEventNtfn returns a new instance which can be used to issue a
+// walletevent JSON-RPC notification.
+func NewWalletEventNtfn(event WalletEventDetails) *WalletEventNtfn {
+	return &WalletEventNtfn{
+		Event: event,
+	}
+}
+
 func init() {
 	// The commands in this file are only usable by websockets and are
 	// notifications.
@@ -301,4 +428,8 @@ func init() {
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedVerboseNtfnMethod, (*TxAcceptedVerboseNtfn)(nil), flags)
 	MustRegisterCmd(RelevantTxAcceptedNtfnMethod, (*RelevantTxAcceptedNtfn)(nil), flags)
+	MustRegisterCmd(UtreexoRootsConnectedNtfnMethod, (*UtreexoRootsConnectedNtfn)(nil), flags)
+	MustRegisterCmd(UtreexoRootsDisconnectedNtfnMethod, (*UtreexoRootsDisconnectedNtfn)(nil), flags)
+	MustRegisterCmd(MempoolEventNtfnMethod, (*MempoolEventNtfn)(nil), flags)
+	MustRegisterCmd(WalletEventNtfnMethod, (*WalletEventNtfn)(nil), flags)
 }
@@ -88,6 +88,88 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Header: "header",
 			},
 		},
+		{
+			name: "utreexorootsconnected",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("utreexorootsconnected", "123", 100000, 5, []string{"aa", "bb"})
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewUtreexoRootsConnectedNtfn("123", 100000, 5, []string{"aa", "bb"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"utreexorootsconnected","params":["123",100000,5,["aa","bb"]],"id":null}`,
+			unmarshalled: &btcjson.UtreexoRootsConnectedNtfn{
+				Hash:      "123",
+				Height:    100000,
+				NumLeaves: 5,
+				Roots:     []string{"aa", "bb"},
+			},
+		},
+		{
+			name: "utreexorootsdisconnected",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("utreexorootsdisconnected", "123", 100000, 4, []string{"aa"})
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewUtreexoRootsDisconnectedNtfn("123", 100000, 4, []string{"aa"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"utreexorootsdisconnected","params":["123",100000,4,["aa"]],"id":null}`,
+			unmarshalled: &btcjson.UtreexoRootsDisconnectedNtfn{
+				Hash:      "123",
+				Height:    100000,
+				NumLeaves: 4,
+				Roots:     []string{"aa"},
+			},
+		},
+		{
+			name: "mempoolevent",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("mempoolevent", `{"event":"replaced","txid":"123","fee":0.0001,"vsize":141,"feerate":0.0007092,"replacedby":"456"}`)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewMempoolEventNtfn(btcjson.MempoolEventDetails{
+					Event:      "replaced",
+					TxID:       "123",
+					Fee:        0.0001,
+					VSize:      141,
+					FeeRate:    0.0007092,
+					ReplacedBy: "456",
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"mempoolevent","params":[{"event":"replaced","txid":"123","fee":0.0001,"vsize":141,"feerate":0.0007092,"replacedby":"456"}],"id":null}`,
+			unmarshalled: &btcjson.MempoolEventNtfn{
+				Event: btcjson.MempoolEventDetails{
+					Event:      "replaced",
+					TxID:       "123",
+					Fee:        0.0001,
+					VSize:      141,
+					FeeRate:    0.0007092,
+					ReplacedBy: "456",
+				},
+			},
+		},
+		{
+			name: "walletevent",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("walletevent", `{"event":"received","wallet":"shop","txid":"123","received":0.001,"spent":0,"confirmations":0}`)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewWalletEventNtfn(btcjson.WalletEventDetails{
+					Event:    "received",
+					Wallet:   "shop",
+					TxID:     "123",
+					Received: 0.001,
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"walletevent","params":[{"event":"received","wallet":"shop","txid":"123","received":0.001,"spent":0,"confirmations":0}],"id":null}`,
+			unmarshalled: &btcjson.WalletEventNtfn{
+				Event: btcjson.WalletEventDetails{
+					Event:    "received",
+					Wallet:   "shop",
+					TxID:     "123",
+					Received: 0.001,
+				},
+			},
+		},
 		{
 			name: "recvtx",
 			newNtfn: func() (interface{}, error) {
@@ -183,4 +183,8 @@ const (
 const (
 	ErrRPCNoWallet      RPCErrorCode = -1
 	ErrRPCUnimplemented RPCErrorCode = -1
+
+	// ErrRPCRateLimited indicates that the client made more calls than the
+	// rate limits of the server allow.
+	ErrRPCRateLimited RPCErrorCode = -40
 )
@@ -48,6 +48,30 @@ func NewAddWitnessAddressCmd(address string) *AddWitnessAddressCmd {
 	}
 }
 
+// BumpFeeOpts represents the options of the bumpfee JSON-RPC command.
+type BumpFeeOpts struct {
+	ConfTarget *int64   `json:"conf_target,omitempty"`
+	FeeRate    *float64 `json:"fee_rate,omitempty"` // In sat/vB
+}
+
+// BumpFeeCmd defines the bumpfee JSON-RPC command.
+type BumpFeeCmd struct {
+	TxID    string
+	Options *BumpFeeOpts
+}
+
+// NewBumpFeeCmd returns a new instance which can be used to issue a bumpfee
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewBumpFeeCmd(txID string, options *BumpFeeOpts) *BumpFeeCmd {
+	return &BumpFeeCmd{
+		TxID:    txID,
+		Options: options,
+	}
+}
+
 // CreateMultisigCmd defines the createmultisig JSON-RPC command.
 type CreateMultisigCmd struct {
 	NRequired int
@@ -338,12 +362,39 @@ func NewGetWalletInfoCmd() *GetWalletInfoCmd {
 // BackupWalletCmd defines the backupwallet JSON-RPC command
 type BackupWalletCmd struct {
 	Destination string
+	Passphrase  *string
 }
 
 // NewBackupWalletCmd returns a new instance which can be used to issue a
 // backupwallet JSON-RPC command
-func NewBackupWalletCmd(destination string) *BackupWalletCmd {
-	return &BackupWalletCmd{Destination: destination}
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewBackupWalletCmd(destination string, passphrase *string) *BackupWalletCmd {
+	return &BackupWalletCmd{
+		Destination: destination,
+		Passphrase:  passphrase,
+	}
+}
+
+// RestoreWalletCmd defines the restorewallet JSON-RPC command.
+type RestoreWalletCmd struct {
+	WalletName string
+	BackupFile string
+	Passphrase *string
+}
+
+// NewRestoreWalletCmd returns a new instance which can be used to issue a
+// restorewallet JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewRestoreWalletCmd(walletName, backupFile string, passphrase *string) *RestoreWalletCmd {
+	return &RestoreWalletCmd{
+		WalletName: walletName,
+		BackupFile: backupFile,
+		Passphrase: passphrase,
+	}
 }
 
 // UnloadWalletCmd defines the unloadwallet JSON-RPC command
@@ -520,6 +571,15 @@ func NewListTransactionsCmd(account *string, count, from *int, includeWatchOnly
 	}
 }
 
+// ListWalletsCmd defines the listwallets JSON-RPC command.
+type ListWalletsCmd struct{}
+
+// NewListWalletsCmd returns a new instance which can be used to issue a
+// listwallets JSON-RPC command.
+func NewListWalletsCmd() *ListWalletsCmd {
+	return &ListWalletsCmd{}
+}
+
 // ListUnspentCmd defines the listunspent JSON-RPC command.
 type ListUnspentCmd struct {
 	MinConf   *int `jsonrpcdefault:"1"`
@@ -579,6 +639,32 @@ func NewMoveCmd(fromAccount, toAccount string, amount float64, minConf *int, com
 	}
 }
 
+// SendAllOpts represents the options of the sendall JSON-RPC command.
+type SendAllOpts struct {
+	ConfTarget   *int64              `json:"conf_target,omitempty"`
+	EstimateMode *string             `json:"estimate_mode,omitempty"`
+	FeeRate      *float64            `json:"fee_rate,omitempty"` // In sat/vB
+	Inputs       *[]TransactionInput `json:"inputs,omitempty"`
+}
+
+// SendAllCmd defines the sendall JSON-RPC command.
+type SendAllCmd struct {
+	Recipients []string
+	Options    *SendAllOpts
+}
+
+// NewSendAllCmd returns a new instance which can be used to issue a sendall
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSendAllCmd(recipients []string, options *SendAllOpts) *SendAllCmd {
+	return &SendAllCmd{
+		Recipients: recipients,
+		Options:    options,
+	}
+}
+
 // SendFromCmd defines the sendfrom JSON-RPC command.
 type SendFromCmd struct {
 	FromAccount string
@@ -1035,6 +1121,8 @@ type WalletCreateFundedPsbtOpts struct {
 	Replaceable            *bool       `json:"replaceable,omitempty"`
 	ConfTarget             *int64      `json:"conf_target,omitempty"`
 	EstimateMode           *string     `json:"estimate_mode,omitempty"`
+	CoinSelection          *string     `json:"coinSelection,omitempty"`
+	AddInputs              *bool       `json:"add_inputs,omitempty"`
 }
 
 // WalletCreateFundedPsbtCmd defines the walletcreatefundedpsbt JSON-RPC command.
@@ -1087,6 +1175,7 @@ func init() {
 	MustRegisterCmd("addmultisigaddress", (*AddMultisigAddressCmd)(nil), flags)
 	MustRegisterCmd("addwitnessaddress", (*AddWitnessAddressCmd)(nil), flags)
 	MustRegisterCmd("backupwallet", (*BackupWalletCmd)(nil), flags)
+	MustRegisterCmd("bumpfee", (*BumpFeeCmd)(nil), flags)
 	MustRegisterCmd("createmultisig", (*CreateMultisigCmd)(nil), flags)
 	MustRegisterCmd("createwallet", (*CreateWalletCmd)(nil), flags)
 	MustRegisterCmd("dumpprivkey", (*DumpPrivKeyCmd)(nil), flags)
@@ -1117,9 +1206,12 @@ func init() {
 	MustRegisterCmd("listsinceblock", (*ListSinceBlockCmd)(nil), flags)
 	MustRegisterCmd("listtransactions", (*ListTransactionsCmd)(nil), flags)
 	MustRegisterCmd("listunspent", (*ListUnspentCmd)(nil), flags)
+	MustRegisterCmd("listwallets", (*ListWalletsCmd)(nil), flags)
 	MustRegisterCmd("loadwallet", (*LoadWalletCmd)(nil), flags)
 	MustRegisterCmd("lockunspent", (*LockUnspentCmd)(nil), flags)
 	MustRegisterCmd("move", (*MoveCmd)(nil), flags)
+	MustRegisterCmd("restorewallet", (*RestoreWalletCmd)(nil), flags)
+	MustRegisterCmd("sendall", (*SendAllCmd)(nil), flags)
 	MustRegisterCmd("sendfrom", (*SendFromCmd)(nil), flags)
 	MustRegisterCmd("sendmany", (*SendManyCmd)(nil), flags)
 	MustRegisterCmd("sendtoaddress", (*SendToAddressCmd)(nil), flags)
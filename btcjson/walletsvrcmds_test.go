@@ -136,11 +136,71 @@ func TestWalletSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("backupwallet", "backup.dat")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewBackupWalletCmd("backup.dat")
+				return btcjson.NewBackupWalletCmd("backup.dat", nil)
 			},
 			marshalled:   `{"jsonrpc":"1.0","method":"backupwallet","params":["backup.dat"],"id":1}`,
 			unmarshalled: &btcjson.BackupWalletCmd{Destination: "backup.dat"},
 		},
+		{
+			name: "backupwallet passphrase",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("backupwallet", "backup.dat", "secret")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBackupWalletCmd("backup.dat", btcjson.String("secret"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"backupwallet","params":["backup.dat","secret"],"id":1}`,
+			unmarshalled: &btcjson.BackupWalletCmd{
+				Destination: "backup.dat",
+				Passphrase:  btcjson.String("secret"),
+			},
+		},
+		{
+			name: "restorewallet",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("restorewallet", "savings", "backup.dat", "secret")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewRestoreWalletCmd("savings", "backup.dat", btcjson.String("secret"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"restorewallet","params":["savings","backup.dat","secret"],"id":1}`,
+			unmarshalled: &btcjson.RestoreWalletCmd{
+				WalletName: "savings",
+				BackupFile: "backup.dat",
+				Passphrase: btcjson.String("secret"),
+			},
+		},
+		{
+			name: "bumpfee",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("bumpfee", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBumpFeeCmd("123", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"bumpfee","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.BumpFeeCmd{
+				TxID: "123",
+			},
+		},
+		{
+			name: "bumpfee optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("bumpfee", "123", `{"fee_rate":12.5}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewBumpFeeCmd("123", &btcjson.BumpFeeOpts{
+					FeeRate: btcjson.Float64(12.5),
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"bumpfee","params":["123",{"fee_rate":12.5}],"id":1}`,
+			unmarshalled: &btcjson.BumpFeeCmd{
+				TxID: "123",
+				Options: &btcjson.BumpFeeOpts{
+					FeeRate: btcjson.Float64(12.5),
+				},
+			},
+		},
 		{
 			name: "loadwallet",
 			newCmd: func() (interface{}, error) {
@@ -916,6 +976,17 @@ func TestWalletSvrCmds(t *testing.T) {
 				IncludeWatchOnly: btcjson.Bool(true),
 			},
 		},
+		{
+			name: "listwallets",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listwallets")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListWalletsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"listwallets","params":[],"id":1}`,
+			unmarshalled: &btcjson.ListWalletsCmd{},
+		},
 		{
 			name: "listunspent",
 			newCmd: func() (interface{}, error) {
@@ -1047,6 +1118,40 @@ func TestWalletSvrCmds(t *testing.T) {
 				Comment:     btcjson.String("comment"),
 			},
 		},
+		{
+			name: "sendall",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("sendall", `["1Address"]`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSendAllCmd([]string{"1Address"}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sendall","params":[["1Address"]],"id":1}`,
+			unmarshalled: &btcjson.SendAllCmd{
+				Recipients: []string{"1Address"},
+			},
+		},
+		{
+			name: "sendall optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("sendall", `["1Address"]`,
+					`{"fee_rate":3.5,"inputs":[{"txid":"123","vout":1}]}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSendAllCmd([]string{"1Address"}, &btcjson.SendAllOpts{
+					FeeRate: btcjson.Float64(3.5),
+					Inputs:  &[]btcjson.TransactionInput{{Txid: "123", Vout: 1}},
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sendall","params":[["1Address"],{"fee_rate":3.5,"inputs":[{"txid":"123","vout":1}]}],"id":1}`,
+			unmarshalled: &btcjson.SendAllCmd{
+				Recipients: []string{"1Address"},
+				Options: &btcjson.SendAllOpts{
+					FeeRate: btcjson.Float64(3.5),
+					Inputs:  &[]btcjson.TransactionInput{{Txid: "123", Vout: 1}},
+				},
+			},
+		},
 		{
 			name: "sendfrom",
 			newCmd: func() (interface{}, error) {
@@ -17,6 +17,17 @@ type CreateWalletResult struct {
 	Warning string `json:"warning"`
 }
 
+// RestoreWalletResult models the result of the restorewallet command.
+type RestoreWalletResult struct {
+	Name    string `json:"name"`
+	Warning string `json:"warning"`
+}
+
+// UnloadWalletResult models the result of the unloadwallet command.
+type UnloadWalletResult struct {
+	Warning string `json:"warning"`
+}
+
 // embeddedAddressInfo includes all getaddressinfo output fields, excluding
 // metadata and relation to the wallet.
 //
@@ -275,6 +286,7 @@ type ListReceivedByAddressResult struct {
 	Confirmations     uint64   `json:"confirmations"`
 	TxIDs             []string `json:"txids,omitempty"`
 	InvolvesWatchonly bool     `json:"involvesWatchonly,omitempty"`
+	Label             string   `json:"label,omitempty"`
 }
 
 // ListSinceBlockResult models the data from the listsinceblock command.
@@ -378,6 +390,22 @@ type WalletCreateFundedPsbtResult struct {
 	ChangePos int64   `json:"changepos"`
 }
 
+// BumpFeeResult models the data returned from the bumpfee command.
+type BumpFeeResult struct {
+	TxID    string   `json:"txid"`
+	OrigFee float64  `json:"origfee"`
+	Fee     float64  `json:"fee"`
+	Errors  []string `json:"errors"`
+}
+
+// SendAllResult models the data returned from the sendall command.
+type SendAllResult struct {
+	Complete bool    `json:"complete"`
+	TxID     string  `json:"txid"`
+	Hex      string  `json:"hex"`
+	Fee      float64 `json:"fee"`
+}
+
 // WalletProcessPsbtResult models the data returned from the
 // walletprocesspsbtresult command.
 type WalletProcessPsbtResult struct {
@@ -0,0 +1,260 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// chainstateDirName is the directory under the Bitcoin Core data
+	// directory that houses the utxo set.
+	chainstateDirName = "chainstate"
+
+	// blockIndexDirName is the directory under the Bitcoin Core data
+	// directory that houses the block index.
+	blockIndexDirName = "blocks/index"
+
+	// Prefixes of the keys in the Bitcoin Core databases.
+	coinKeyPrefix       = 'C'
+	bestBlockKeyPrefix  = 'B'
+	blockIndexKeyPrefix = 'b'
+
+	// blockHaveData and blockHaveUndo are the flags in the status of a
+	// Bitcoin Core block index entry that signal that the file positions
+	// of the block and the undo data are serialized in the entry.
+	blockHaveData = 8
+	blockHaveUndo = 16
+)
+
+// obfuscateKey is the key in the chainstate under which the key that all the
+// values are xored with is stored.
+var obfuscateKey = append([]byte{0x0e, 0x00}, []byte("obfuscate_key")...)
+
+// coreChainstate is a read-only view of the chainstate and block index of a
+// Bitcoin Core data directory.
+type coreChainstate struct {
+	chainstate *leveldb.DB
+	blockIndex *leveldb.DB
+	xorKey     []byte
+}
+
+// openCoreChainstate opens the chainstate and the block index under the given
+// Bitcoin Core data directory as read-only.
+func openCoreChainstate(coreDataDir string) (*coreChainstate, error) {
+	readOnly := &opt.Options{ReadOnly: true, ErrorIfMissing: true}
+	chainstate, err := leveldb.OpenFile(
+		filepath.Join(coreDataDir, chainstateDirName), readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	blockIndex, err := leveldb.OpenFile(
+		filepath.Join(coreDataDir, blockIndexDirName), readOnly)
+	if err != nil {
+		chainstate.Close()
+		return nil, err
+	}
+
+	cs := &coreChainstate{chainstate: chainstate, blockIndex: blockIndex}
+
+	// Older versions of Bitcoin Core don't obfuscate the values so the
+	// key is allowed to be missing.  The key is serialized as a compact
+	// size prefixed byte slice.
+	serialized, err := chainstate.Get(obfuscateKey, nil)
+	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+		cs.Close()
+		return nil, err
+	}
+	if len(serialized) > 1 {
+		cs.xorKey = serialized[1:]
+	}
+
+	return cs, nil
+}
+
+// Close closes the underlying databases.
+func (cs *coreChainstate) Close() error {
+	err := cs.chainstate.Close()
+	if err2 := cs.blockIndex.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// deobfuscate returns the value xored with the obfuscation key.
+func (cs *coreChainstate) deobfuscate(value []byte) []byte {
+	plain := make([]byte, len(value))
+	for i := range value {
+		if len(cs.xorKey) == 0 {
+			plain[i] = value[i]
+			continue
+		}
+		plain[i] = value[i] ^ cs.xorKey[i%len(cs.xorKey)]
+	}
+	return plain
+}
+
+// bestBlock returns the hash of the block the chainstate is at.
+func (cs *coreChainstate) bestBlock() (*chainhash.Hash, error) {
+	value, err := cs.chainstate.Get([]byte{bestBlockKeyPrefix}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the chainstate best "+
+			"block: %v", err)
+	}
+
+	return chainhash.NewHash(cs.deobfuscate(value))
+}
+
+// blockIndexEntry is the subset of a Bitcoin Core block index entry that's
+// needed to reconstruct the main chain.
+type blockIndexEntry struct {
+	height   int32
+	prevHash chainhash.Hash
+}
+
+// fetchBlockIndexEntry returns the block index entry for the given hash.
+func (cs *coreChainstate) fetchBlockIndexEntry(hash *chainhash.Hash) (*blockIndexEntry, error) {
+	key := append([]byte{blockIndexKeyPrefix}, hash[:]...)
+	serialized, err := cs.blockIndex.Get(key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the block index entry "+
+			"for %v: %v", hash, err)
+	}
+
+	// The entry is the client version, height, status, the number of
+	// transactions and the optional file positions, all as varints,
+	// followed by the block header.
+	var fields [4]uint64
+	offset := 0
+	for i := range fields {
+		var n int
+		fields[i], n = blockchain.DeserializeVLQ(serialized[offset:])
+		if n == 0 {
+			return nil, fmt.Errorf("malformed block index entry "+
+				"for %v", hash)
+		}
+		offset += n
+	}
+	status := fields[2]
+
+	optional := 0
+	if status&(blockHaveData|blockHaveUndo) != 0 {
+		optional++
+	}
+	if status&blockHaveData != 0 {
+		optional++
+	}
+	if status&blockHaveUndo != 0 {
+		optional++
+	}
+	for i := 0; i < optional; i++ {
+		_, n := blockchain.DeserializeVLQ(serialized[offset:])
+		if n == 0 {
+			return nil, fmt.Errorf("malformed block index entry "+
+				"for %v", hash)
+		}
+		offset += n
+	}
+
+	// Skip the version to get to the previous block hash.
+	offset += 4
+	if len(serialized) < offset+chainhash.HashSize {
+		return nil, fmt.Errorf("malformed block index entry for %v", hash)
+	}
+
+	entry := &blockIndexEntry{height: int32(fields[1])}
+	copy(entry.prevHash[:], serialized[offset:offset+chainhash.HashSize])
+	return entry, nil
+}
+
+// mainChainHashes returns the hashes of the main chain ending at the given tip
+// indexed by their height.
+func (cs *coreChainstate) mainChainHashes(tip *chainhash.Hash) ([]chainhash.Hash, error) {
+	entry, err := cs.fetchBlockIndexEntry(tip)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]chainhash.Hash, entry.height+1)
+	hashes[entry.height] = *tip
+	for height := entry.height; height > 0; height-- {
+		hashes[height-1] = entry.prevHash
+		entry, err = cs.fetchBlockIndexEntry(&entry.prevHash)
+		if err != nil {
+			return nil, err
+		}
+		if entry.height != height-1 {
+			return nil, fmt.Errorf("block %v is at height %d but "+
+				"expected height %d", hashes[height-1],
+				entry.height, height-1)
+		}
+	}
+
+	return hashes, nil
+}
+
+// forEachLeaf calls the given function with the leaf data of every coin in the
+// chainstate.  The block hashes must be the main chain hashes indexed by their
+// height as returned by mainChainHashes.
+func (cs *coreChainstate) forEachLeaf(blockHashes []chainhash.Hash,
+	fn func(wire.LeafData) error) error {
+
+	iter := cs.chainstate.NewIterator(util.BytesPrefix([]byte{coinKeyPrefix}), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		// The key is the prefix followed by the txid and the output
+		// index as a varint.
+		key := iter.Key()
+		if len(key) < 1+chainhash.HashSize+1 {
+			return fmt.Errorf("malformed coin key %x", key)
+		}
+		var op wire.OutPoint
+		copy(op.Hash[:], key[1:1+chainhash.HashSize])
+		index, _ := blockchain.DeserializeVLQ(key[1+chainhash.HashSize:])
+		op.Index = uint32(index)
+
+		entry, err := blockchain.DeserializeUtxoEntry(cs.deobfuscate(iter.Value()))
+		if err != nil {
+			return fmt.Errorf("unable to decode coin %v: %v", op, err)
+		}
+
+		height := entry.BlockHeight()
+		if height < 0 || int(height) >= len(blockHashes) {
+			return fmt.Errorf("coin %v is at height %d which is "+
+				"past the chainstate tip", op, height)
+		}
+
+		// Unspendable outputs are never added to the accumulator.
+		txOut := wire.TxOut{Value: entry.Amount(), PkScript: entry.PkScript()}
+		if blockchain.IsUnspendable(&txOut) {
+			continue
+		}
+
+		err = fn(wire.LeafData{
+			BlockHash:  blockHashes[height],
+			OutPoint:   op,
+			Amount:     entry.Amount(),
+			PkScript:   entry.PkScript(),
+			Height:     height,
+			IsCoinBase: entry.IsCoinBase(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return iter.Error()
+}
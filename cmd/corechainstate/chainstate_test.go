@@ -0,0 +1,523 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// hexToBytes converts the passed hex string into bytes and will panic if there
+// is an error.  This is only provided for the hard-coded constants so errors in
+// the source code can be detected.  It will only (and must only) be called with
+// hard-coded values.
+func hexToBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("invalid hex in source file: " + s)
+	}
+	return b
+}
+
+// appendVarInt appends the passed number to b in the VARINT format Bitcoin Core
+// serializes the numbers in its databases with.
+func appendVarInt(b []byte, n uint64) []byte {
+	var tmp [10]byte
+	i := len(tmp) - 1
+	tmp[i] = byte(n & 0x7f)
+	for n > 0x7f {
+		n = (n >> 7) - 1
+		i--
+		tmp[i] = byte(n&0x7f) | 0x80
+	}
+	return append(b, tmp[i:]...)
+}
+
+// coinKey returns the key Bitcoin Core stores the coin for the passed outpoint
+// under in its chainstate.
+func coinKey(op wire.OutPoint) []byte {
+	key := append([]byte{coinKeyPrefix}, op.Hash[:]...)
+	return appendVarInt(key, uint64(op.Index))
+}
+
+// testLeafHash computes the leaf hash of a coin independently of wire.LeafData
+// so that the fields the leaf data is derived with are checked as well.
+func testLeafHash(blockHash chainhash.Hash, op wire.OutPoint, height int32,
+	isCoinBase bool, amount int64, pkScript []byte) [32]byte {
+
+	var buf bytes.Buffer
+	buf.Write(chainhash.UTREEXO_TAG_V1[:])
+	buf.Write(chainhash.UTREEXO_TAG_V1[:])
+	buf.Write(blockHash[:])
+	buf.Write(op.Hash[:])
+	binary.Write(&buf, binary.LittleEndian, op.Index)
+	code := uint32(height) << 1
+	if isCoinBase {
+		code |= 1
+	}
+	binary.Write(&buf, binary.LittleEndian, code)
+	binary.Write(&buf, binary.LittleEndian, uint64(amount))
+	wire.WriteVarBytes(&buf, 0, pkScript)
+	return sha512.Sum512_256(buf.Bytes())
+}
+
+// TestVarInt ensures that the varints in the Bitcoin Core databases are
+// decoded.
+func TestVarInt(t *testing.T) {
+	tests := []struct {
+		n          uint64
+		serialized []byte
+	}{
+		{0, hexToBytes("00")},
+		{0x7f, hexToBytes("7f")},
+		{0x80, hexToBytes("8000")},
+		{0xff, hexToBytes("807f")},
+		{0x3fff, hexToBytes("fe7f")},
+		{0x4000, hexToBytes("ff00")},
+		{0x407f, hexToBytes("ff7f")},
+		{0xffff, hexToBytes("82fe7f")},
+		{1 << 32, hexToBytes("8efefeff00")},
+	}
+
+	for _, test := range tests {
+		got := appendVarInt(nil, test.n)
+		if !bytes.Equal(got, test.serialized) {
+			t.Errorf("appendVarInt(%d): got %x, want %x", test.n,
+				got, test.serialized)
+			continue
+		}
+
+		n, size := blockchain.DeserializeVLQ(test.serialized)
+		if n != test.n || size != len(test.serialized) {
+			t.Errorf("DeserializeVLQ(%x): got %d (%d bytes), want "+
+				"%d (%d bytes)", test.serialized, n, size, test.n,
+				len(test.serialized))
+		}
+	}
+}
+
+// TestDeobfuscate ensures that the chainstate values are xored with the
+// obfuscation key repeated over their length.
+func TestDeobfuscate(t *testing.T) {
+	tests := []struct {
+		name   string
+		xorKey []byte
+		value  []byte
+		want   []byte
+	}{
+		{
+			name:   "no key",
+			xorKey: nil,
+			value:  hexToBytes("0102030405"),
+			want:   hexToBytes("0102030405"),
+		},
+		{
+			name:   "shorter than the key",
+			xorKey: hexToBytes("ff00ff00ff00ff00"),
+			value:  hexToBytes("0102"),
+			want:   hexToBytes("fe02"),
+		},
+		{
+			name:   "longer than the key",
+			xorKey: hexToBytes("0f1e2d3c4b5a6978"),
+			value:  hexToBytes("00000000000000000000ffff"),
+			want:   hexToBytes("0f1e2d3c4b5a69780f1ed2c3"),
+		},
+		{
+			name:   "empty value",
+			xorKey: hexToBytes("0f1e2d3c4b5a6978"),
+			value:  []byte{},
+			want:   []byte{},
+		},
+	}
+
+	for _, test := range tests {
+		value := append([]byte(nil), test.value...)
+		cs := &coreChainstate{xorKey: test.xorKey}
+		got := cs.deobfuscate(value)
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("%s: got %x, want %x", test.name, got, test.want)
+		}
+		if !bytes.Equal(value, test.value) {
+			t.Errorf("%s: the value was modified", test.name)
+		}
+	}
+}
+
+// TestDecodeCoin ensures that coins serialized by Bitcoin Core are decoded
+// along with their compressed amounts and scripts.  The first three coins are
+// the ones from the coins tests of Bitcoin Core.
+func TestDecodeCoin(t *testing.T) {
+	tests := []struct {
+		name       string
+		serialized []byte
+		height     int32
+		isCoinBase bool
+		amount     int64
+		pkScript   []byte
+	}{
+		{
+			name:       "pay-to-pubkey-hash",
+			serialized: hexToBytes("97f23c835800816115944e077fe7c803cfa57f29b36bf87c1d35"),
+			height:     203998,
+			amount:     60000000000,
+			pkScript:   hexToBytes("76a914816115944e077fe7c803cfa57f29b36bf87c1d3588ac"),
+		},
+		{
+			name:       "coinbase",
+			serialized: hexToBytes("8ddf77bbd123008c988f1a4a4de2161e0f50aac7f17e7f9555caa4"),
+			height:     120891,
+			isCoinBase: true,
+			amount:     110397,
+			pkScript:   hexToBytes("76a9148c988f1a4a4de2161e0f50aac7f17e7f9555caa488ac"),
+		},
+		{
+			name:       "smallest",
+			serialized: hexToBytes("000006"),
+			height:     0,
+			amount:     0,
+			pkScript:   []byte{},
+		},
+		{
+			name:       "pay-to-script-hash",
+			serialized: hexToBytes("0201010101010101010101010101010101010101010101"),
+			height:     1,
+			amount:     1,
+			pkScript:   hexToBytes("a914010101010101010101010101010101010101010187"),
+		},
+		{
+			name: "compressed pay-to-pubkey",
+			serialized: hexToBytes("053202" +
+				"0202020202020202020202020202020202020202020202020202020202020202"),
+			height:     2,
+			isCoinBase: true,
+			amount:     5000000000,
+			pkScript: hexToBytes("2102" +
+				"0202020202020202020202020202020202020202020202020202020202020202ac"),
+		},
+		{
+			name:       "uncompressed script",
+			serialized: hexToBytes("04000c6a04deadbeef"),
+			height:     2,
+			amount:     0,
+			pkScript:   hexToBytes("6a04deadbeef"),
+		},
+	}
+
+	for _, test := range tests {
+		entry, err := blockchain.DeserializeUtxoEntry(test.serialized)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if entry.BlockHeight() != test.height {
+			t.Errorf("%s: got height %d, want %d", test.name,
+				entry.BlockHeight(), test.height)
+		}
+		if entry.IsCoinBase() != test.isCoinBase {
+			t.Errorf("%s: got coinbase %v, want %v", test.name,
+				entry.IsCoinBase(), test.isCoinBase)
+		}
+		if entry.Amount() != test.amount {
+			t.Errorf("%s: got amount %d, want %d", test.name,
+				entry.Amount(), test.amount)
+		}
+		if !bytes.Equal(entry.PkScript(), test.pkScript) {
+			t.Errorf("%s: got script %x, want %x", test.name,
+				entry.PkScript(), test.pkScript)
+		}
+	}
+}
+
+// testCoin is a coin that's written to a test chainstate along with the leaf
+// data it's expected to be reconstructed as.
+type testCoin struct {
+	outPoint    wire.OutPoint
+	serialized  []byte
+	height      int32
+	isCoinBase  bool
+	amount      int64
+	pkScript    []byte
+	unspendable bool
+}
+
+// TestCoreChainstate ensures that the main chain and the leaves are
+// reconstructed from a Bitcoin Core data directory with an obfuscated
+// chainstate.
+func TestCoreChainstate(t *testing.T) {
+	coreDir := t.TempDir()
+	xorKey := hexToBytes("8a3f55c1092be6d4")
+	obfuscator := &coreChainstate{xorKey: xorKey}
+
+	// Write the block index of a chain of three blocks.  The entries of
+	// the blocks have the file positions of both the block and the undo
+	// data, only of the block, and of neither.
+	statuses := []uint64{
+		3 | blockHaveData | blockHaveUndo,
+		3 | blockHaveData,
+		3,
+	}
+	blockIndex, err := leveldb.OpenFile(filepath.Join(coreDir, blockIndexDirName), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blockHashes []chainhash.Hash
+	var prevHash chainhash.Hash
+	for height, status := range statuses {
+		header := wire.BlockHeader{
+			Version:   1,
+			PrevBlock: prevHash,
+			Nonce:     uint32(height),
+		}
+		entry := appendVarInt(nil, 259900)
+		entry = appendVarInt(entry, uint64(height))
+		entry = appendVarInt(entry, status)
+		entry = appendVarInt(entry, 1)
+		if status&(blockHaveData|blockHaveUndo) != 0 {
+			entry = appendVarInt(entry, 0)
+		}
+		if status&blockHaveData != 0 {
+			entry = appendVarInt(entry, 8+uint64(height)*300)
+		}
+		if status&blockHaveUndo != 0 {
+			entry = appendVarInt(entry, 8+uint64(height)*100)
+		}
+		var buf bytes.Buffer
+		if err := header.Serialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+		entry = append(entry, buf.Bytes()...)
+
+		hash := header.BlockHash()
+		key := append([]byte{blockIndexKeyPrefix}, hash[:]...)
+		if err := blockIndex.Put(key, entry, nil); err != nil {
+			t.Fatal(err)
+		}
+		blockHashes = append(blockHashes, hash)
+		prevHash = hash
+	}
+	if err := blockIndex.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The output indexes are picked so that they're serialized in one, two
+	// and three bytes.
+	txHash := chainhash.HashH([]byte("tx"))
+	coinbaseHash := chainhash.HashH([]byte("coinbase"))
+	coins := []testCoin{
+		{
+			outPoint:   wire.OutPoint{Hash: coinbaseHash, Index: 0},
+			serialized: hexToBytes("0132001111111111111111111111111111111111111111"),
+			height:     0,
+			isCoinBase: true,
+			amount:     5000000000,
+			pkScript:   hexToBytes("76a914111111111111111111111111111111111111111188ac"),
+		},
+		{
+			outPoint:   wire.OutPoint{Hash: txHash, Index: 0x80},
+			serialized: hexToBytes("0201012222222222222222222222222222222222222222"),
+			height:     1,
+			amount:     1,
+			pkScript:   hexToBytes("a914222222222222222222222222222222222222222287"),
+		},
+		{
+			outPoint:    wire.OutPoint{Hash: txHash, Index: 0x4000},
+			serialized:  hexToBytes("04000c6a04deadbeef"),
+			height:      2,
+			pkScript:    hexToBytes("6a04deadbeef"),
+			unspendable: true,
+		},
+		{
+			outPoint: wire.OutPoint{Hash: txHash, Index: 0x10000},
+			serialized: hexToBytes("053202" +
+				"3333333333333333333333333333333333333333333333333333333333333333"),
+			height:     2,
+			isCoinBase: true,
+			amount:     5000000000,
+			pkScript: hexToBytes("2102" +
+				"3333333333333333333333333333333333333333333333333333333333333333ac"),
+		},
+	}
+
+	// The obfuscation key is stored as is and so is the prefix of the
+	// coin keys.
+	chainstate, err := leveldb.OpenFile(filepath.Join(coreDir, chainstateDirName), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = chainstate.Put(obfuscateKey, append([]byte{byte(len(xorKey))}, xorKey...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip := blockHashes[len(blockHashes)-1]
+	err = chainstate.Put([]byte{bestBlockKeyPrefix}, obfuscator.deobfuscate(tip[:]), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, coin := range coins {
+		err := chainstate.Put(coinKey(coin.outPoint),
+			obfuscator.deobfuscate(coin.serialized), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := chainstate.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := openCoreChainstate(coreDir)
+	if err != nil {
+		t.Fatalf("openCoreChainstate: unexpected error: %v", err)
+	}
+	defer cs.Close()
+	if !bytes.Equal(cs.xorKey, xorKey) {
+		t.Fatalf("got obfuscation key %x, want %x", cs.xorKey, xorKey)
+	}
+
+	bestHash, err := cs.bestBlock()
+	if err != nil {
+		t.Fatalf("bestBlock: unexpected error: %v", err)
+	}
+	if *bestHash != tip {
+		t.Fatalf("got best block %v, want %v", bestHash, tip)
+	}
+
+	gotHashes, err := cs.mainChainHashes(bestHash)
+	if err != nil {
+		t.Fatalf("mainChainHashes: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(gotHashes, blockHashes) {
+		t.Fatalf("got main chain %v, want %v", gotHashes, blockHashes)
+	}
+
+	leaves := make(map[wire.OutPoint]wire.LeafData)
+	err = cs.forEachLeaf(gotHashes, func(leaf wire.LeafData) error {
+		leaves[leaf.OutPoint] = leaf
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEachLeaf: unexpected error: %v", err)
+	}
+
+	for _, coin := range coins {
+		leaf, found := leaves[coin.outPoint]
+		if coin.unspendable {
+			if found {
+				t.Errorf("got a leaf for the unspendable coin %v",
+					coin.outPoint)
+			}
+			continue
+		}
+		if !found {
+			t.Errorf("no leaf for the coin %v", coin.outPoint)
+			continue
+		}
+		delete(leaves, coin.outPoint)
+
+		want := wire.LeafData{
+			BlockHash:  blockHashes[coin.height],
+			OutPoint:   coin.outPoint,
+			Amount:     coin.amount,
+			PkScript:   coin.pkScript,
+			Height:     coin.height,
+			IsCoinBase: coin.isCoinBase,
+		}
+		if !reflect.DeepEqual(leaf, want) {
+			t.Errorf("coin %v: got leaf %v, want %v", coin.outPoint,
+				leaf, want)
+			continue
+		}
+
+		wantHash := testLeafHash(blockHashes[coin.height], coin.outPoint,
+			coin.height, coin.isCoinBase, coin.amount, coin.pkScript)
+		if leaf.LeafHash() != wantHash {
+			t.Errorf("coin %v: got leaf hash %x, want %x",
+				coin.outPoint, leaf.LeafHash(), wantHash)
+		}
+	}
+	if len(leaves) != 0 {
+		t.Errorf("got %d unexpected leaves", len(leaves))
+	}
+}
+
+// TestCoreChainstateErrors ensures that malformed and inconsistent Bitcoin Core
+// databases are rejected.
+func TestCoreChainstateErrors(t *testing.T) {
+	openMem := func() *leveldb.DB {
+		db, err := leveldb.Open(storage.NewMemStorage(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	// A block index entry that ends before the previous block hash.
+	blockIndex := openMem()
+	truncated := chainhash.HashH([]byte("truncated"))
+	entry := hexToBytes("00010301" + "01000000")
+	err := blockIndex.Put(append([]byte{blockIndexKeyPrefix}, truncated[:]...),
+		entry, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := &coreChainstate{chainstate: openMem(), blockIndex: blockIndex}
+	if _, err := cs.mainChainHashes(&truncated); err == nil {
+		t.Errorf("expected an error for a truncated block index entry")
+	}
+	missing := chainhash.HashH([]byte("missing"))
+	if _, err := cs.mainChainHashes(&missing); err == nil {
+		t.Errorf("expected an error for a missing block index entry")
+	}
+	if _, err := cs.bestBlock(); err == nil {
+		t.Errorf("expected an error for a missing best block")
+	}
+
+	tests := []struct {
+		name  string
+		key   []byte
+		value []byte
+	}{
+		{
+			name:  "short key",
+			key:   []byte{coinKeyPrefix, 0x01},
+			value: hexToBytes("000006"),
+		},
+		{
+			name:  "past the tip",
+			key:   coinKey(wire.OutPoint{Index: 1}),
+			value: hexToBytes("0a0006"),
+		},
+		{
+			name:  "truncated coin",
+			key:   coinKey(wire.OutPoint{Index: 2}),
+			value: hexToBytes("0200"),
+		},
+	}
+	blockHashes := []chainhash.Hash{chainhash.HashH([]byte("genesis"))}
+	for _, test := range tests {
+		cs := &coreChainstate{chainstate: openMem()}
+		if err := cs.chainstate.Put(test.key, test.value, nil); err != nil {
+			t.Fatal(err)
+		}
+		err := cs.forEachLeaf(blockHashes, func(wire.LeafData) error {
+			return nil
+		})
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	defaultProgress = 10
+)
+
+var (
+	utreexodHomeDir = btcutil.AppDataDir("utreexod", false)
+	defaultDataDir  = filepath.Join(utreexodHomeDir, "data")
+	defaultCoreDir  = btcutil.AppDataDir("bitcoin", false)
+	activeNetParams = &chaincfg.MainNetParams
+)
+
+// config defines the configuration options for corechainstate.
+//
+// See loadConfig for details on the configuration load process.
+type config struct {
+	CoreDataDir    string `long:"coredatadir" description:"Location of the Bitcoin Core data directory.  Bitcoin Core must not be running"`
+	DataDir        string `short:"b" long:"datadir" description:"Location of the utreexod data directory.  utreexod must not be running"`
+	FlatIndex      bool   `long:"flatutreexoproofindex" description:"Check the utreexo state of the flat utreexo proof index instead of the utreexo proof index"`
+	Progress       int    `short:"p" long:"progress" description:"Show a progress message each time this number of seconds have passed -- Use 0 to disable progress announcements"`
+	RegressionTest bool   `long:"regtest" description:"Use the regression test network"`
+	SigNet         bool   `long:"signet" description:"Use the signet test network"`
+	TestNet3       bool   `long:"testnet" description:"Use the test network"`
+}
+
+// fileExists reports whether the named file or directory exists.
+func fileExists(name string) bool {
+	if _, err := os.Stat(name); err != nil {
+		if os.IsNotExist(err) {
+			return false
+		}
+	}
+	return true
+}
+
+// netName returns the name used when referring to a bitcoin network by
+// utreexod.  utreexod places testnet version 3 in the "testnet" directory
+// which doesn't match the Name field of the chaincfg parameters.
+func netName(chainParams *chaincfg.Params) string {
+	switch chainParams.Net {
+	case wire.TestNet3:
+		return "testnet"
+	default:
+		return chainParams.Name
+	}
+}
+
+// coreNetDir returns the subdirectory Bitcoin Core uses for the given network.
+// Mainnet data is stored directly in the data directory.
+func coreNetDir(chainParams *chaincfg.Params) string {
+	switch chainParams.Net {
+	case wire.MainNet:
+		return ""
+	case wire.TestNet3:
+		return "testnet3"
+	default:
+		return chainParams.Name
+	}
+}
+
+// loadConfig initializes and parses the config using command line options.
+func loadConfig() (*config, []string, error) {
+	// Default config.
+	cfg := config{
+		CoreDataDir: defaultCoreDir,
+		DataDir:     defaultDataDir,
+		Progress:    defaultProgress,
+	}
+
+	// Parse command line options.
+	parser := flags.NewParser(&cfg, flags.Default)
+	remainingArgs, err := parser.Parse()
+	if err != nil {
+		if e, ok := err.(*flags.Error); !ok || e.Type != flags.ErrHelp {
+			parser.WriteHelp(os.Stderr)
+		}
+		return nil, nil, err
+	}
+
+	// Multiple networks can't be selected simultaneously.
+	funcName := "loadConfig"
+	numNets := 0
+	if cfg.TestNet3 {
+		numNets++
+		activeNetParams = &chaincfg.TestNet3Params
+	}
+	if cfg.RegressionTest {
+		numNets++
+		activeNetParams = &chaincfg.RegressionNetParams
+	}
+	if cfg.SigNet {
+		numNets++
+		activeNetParams = &chaincfg.SigNetParams
+	}
+	if numNets > 1 {
+		str := "%s: The testnet, regtest, and signet params can't be " +
+			"used together -- choose one of the three"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		parser.WriteHelp(os.Stderr)
+		return nil, nil, err
+	}
+
+	// Namespace both of the data directories per network.
+	cfg.DataDir = filepath.Join(cfg.DataDir, netName(activeNetParams))
+	cfg.CoreDataDir = filepath.Join(cfg.CoreDataDir, coreNetDir(activeNetParams))
+
+	// Ensure the Bitcoin Core chainstate exists.
+	if !fileExists(filepath.Join(cfg.CoreDataDir, chainstateDirName)) {
+		str := "%s: The Bitcoin Core chainstate does not exist under [%v]"
+		err := fmt.Errorf(str, funcName, cfg.CoreDataDir)
+		fmt.Fprintln(os.Stderr, err)
+		parser.WriteHelp(os.Stderr)
+		return nil, nil, err
+	}
+
+	return &cfg, remainingArgs, nil
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// The corechainstate utility reconstructs the utreexo leaf set from the
+// chainstate of a Bitcoin Core node and checks it against the utreexo state of
+// a utreexod bridge node.
+//
+// The utreexo accumulator isn't a function of the utxo set alone.  The position
+// of every leaf and the number of leaves both depend on the entire history of
+// additions and deletions so the forest of a bridge can't be created from a utxo
+// snapshot.  What can be done from a snapshot is to make sure that the leaf set
+// the bridge commits to is exactly the utxo set Bitcoin Core has, which is a
+// useful check after an unclean shutdown or when auditing a bridge.  Both nodes
+// must be stopped and at the same block, which is checked before the leaves
+// are.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btclog"
+	"github.com/utreexo/utreexod/blockchain/indexers"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	_ "github.com/utreexo/utreexod/database/ffldb"
+	"github.com/utreexo/utreexod/limits"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// blockDbNamePrefix is the prefix for the utreexod block database name.
+const blockDbNamePrefix = "blocks"
+
+// utreexoStateName is the name of the utreexo state of the utreexo proof index.
+// It's the type of the block database the index is stored in.
+const utreexoStateName = "ffldb"
+
+// flatUtreexoStateName is the name of the utreexo state of the flat utreexo
+// proof index.
+const flatUtreexoStateName = "flat"
+
+var (
+	cfg *config
+	log btclog.Logger
+)
+
+// checkResult houses the statistics about the check.
+type checkResult struct {
+	leaves  int
+	missing int
+	amount  int64
+}
+
+// fetchUtreexoStateTip returns the hash and height of the block the utreexo
+// state of the bridge is at according to its block database.
+func fetchUtreexoStateTip() (*chainhash.Hash, int32, error) {
+	dbPath := filepath.Join(cfg.DataDir, blockDbNamePrefix+"_"+utreexoStateName)
+	db, err := database.Open(utreexoStateName, dbPath, activeNetParams.Net)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to open the block database: %v", err)
+	}
+	defer db.Close()
+
+	return indexers.FetchUtreexoStateTip(db, cfg.FlatIndex)
+}
+
+// checkTip returns an error if the Bitcoin Core chainstate and the utreexo
+// state of the bridge aren't at the same block.  The leaf sets of the two can
+// only be compared when they are.
+func checkTip(coreHash *chainhash.Hash, coreHeight int32,
+	bridgeHash *chainhash.Hash, bridgeHeight int32) error {
+
+	if coreHeight == bridgeHeight && coreHash.IsEqual(bridgeHash) {
+		return nil
+	}
+
+	return fmt.Errorf("the Bitcoin Core chainstate is at block %v (height "+
+		"%d) but the utreexo state is at block %v (height %d) -- sync "+
+		"both nodes to the same block and stop them before checking",
+		coreHash, coreHeight, bridgeHash, bridgeHeight)
+}
+
+// checkLeaves reconstructs every leaf from the Bitcoin Core chainstate at the
+// given best block and checks that it's present in the given utreexo state.
+func checkLeaves(cs *coreChainstate, bestHash *chainhash.Hash,
+	uState *indexers.UtreexoState) (*checkResult, error) {
+
+	log.Infof("Loading the main chain from the Bitcoin Core block index")
+	blockHashes, err := cs.mainChainHashes(bestHash)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Loaded %d block hashes", len(blockHashes))
+
+	result := &checkResult{}
+	lastLog := time.Now()
+	progress := time.Duration(cfg.Progress) * time.Second
+	err = cs.forEachLeaf(blockHashes, func(leaf wire.LeafData) error {
+		result.leaves++
+		result.amount += leaf.Amount
+
+		_, found := uState.LeafPosition(leaf.LeafHash())
+		if !found {
+			result.missing++
+			log.Debugf("Leaf for %v is missing from the utreexo state",
+				leaf.OutPoint)
+		}
+
+		if progress > 0 && time.Since(lastLog) >= progress {
+			log.Infof("Checked %d leaves, %d missing so far",
+				result.leaves, result.missing)
+			lastLog = time.Now()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// realMain is the real main function for the utility.  It is necessary to work
+// around the fact that deferred functions do not run when os.Exit() is called.
+func realMain() error {
+	// Load configuration and parse command line.
+	tcfg, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	cfg = tcfg
+
+	// Setup logging.
+	backendLogger := btclog.NewBackend(os.Stdout)
+	defer os.Stdout.Sync()
+	log = backendLogger.Logger("MAIN")
+	indexers.UseLogger(backendLogger.Logger("INDX"))
+
+	cs, err := openCoreChainstate(cfg.CoreDataDir)
+	if err != nil {
+		log.Errorf("Failed to open the Bitcoin Core chainstate: %v", err)
+		return err
+	}
+	defer cs.Close()
+
+	// Make sure both nodes are at the same block before going through the
+	// leaves.
+	bestHash, err := cs.bestBlock()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	entry, err := cs.fetchBlockIndexEntry(bestHash)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Infof("Bitcoin Core chainstate is at block %v (height %d)",
+		bestHash, entry.height)
+
+	tipHash, tipHeight, err := fetchUtreexoStateTip()
+	if err != nil {
+		log.Errorf("Failed to fetch the tip of the utreexo state: %v", err)
+		return err
+	}
+	err = checkTip(bestHash, entry.height, tipHash, tipHeight)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	name := utreexoStateName
+	if cfg.FlatIndex {
+		name = flatUtreexoStateName
+	}
+	uState, err := indexers.InitUtreexoState(&indexers.UtreexoConfig{
+		DataDir: cfg.DataDir,
+		Name:    name,
+		Params:  activeNetParams,
+	}, 0)
+	if err != nil {
+		log.Errorf("Failed to load the utreexo state: %v", err)
+		return err
+	}
+	defer uState.Close()
+
+	result, err := checkLeaves(cs, bestHash, uState)
+	if err != nil {
+		log.Errorf("%v", err)
+		return err
+	}
+
+	leafCount := uState.LeafCount()
+	log.Infof("Reconstructed %d leaves worth %d satoshis from the Bitcoin "+
+		"Core chainstate", result.leaves, result.amount)
+	log.Infof("The utreexo state has %d leaves out of %d ever added",
+		leafCount, uState.NumLeaves())
+
+	if result.missing != 0 || leafCount != result.leaves {
+		err := fmt.Errorf("the utreexo state doesn't match the Bitcoin "+
+			"Core chainstate: %d leaves are missing and the leaf "+
+			"counts are %d and %d", result.missing, leafCount,
+			result.leaves)
+		log.Error(err)
+		return err
+	}
+
+	log.Infof("The utreexo state matches the Bitcoin Core chainstate")
+	return nil
+}
+
+func main() {
+	// up some limits.
+	if err := limits.SetLimits(); err != nil {
+		os.Exit(1)
+	}
+
+	// Work around defer not working after os.Exit()
+	if err := realMain(); err != nil {
+		os.Exit(1)
+	}
+}
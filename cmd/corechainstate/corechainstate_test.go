@@ -0,0 +1,38 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+)
+
+// TestCheckTip ensures that the leaves are only checked when the Bitcoin Core
+// chainstate and the utreexo state are at the same block.
+func TestCheckTip(t *testing.T) {
+	hash := chainhash.HashH([]byte("tip"))
+	other := chainhash.HashH([]byte("other"))
+
+	tests := []struct {
+		name         string
+		bridgeHash   chainhash.Hash
+		bridgeHeight int32
+		valid        bool
+	}{
+		{"same block", hash, 100, true},
+		{"other height", hash, 99, false},
+		{"other hash", other, 100, false},
+		{"other block", other, 101, false},
+	}
+
+	for _, test := range tests {
+		err := checkTip(&hash, 100, &test.bridgeHash, test.bridgeHeight)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v but got error %v",
+				test.name, test.valid, err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"bufio"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/btcsuite/go-socks/socks"
 	flags "github.com/jessevdk/go-flags"
+	"github.com/utreexo/utreexod/addrmgr"
 	"github.com/utreexo/utreexod/blockchain"
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg"
@@ -30,6 +32,7 @@ import (
 	_ "github.com/utreexo/utreexod/database/ffldb"
 	"github.com/utreexo/utreexod/mempool"
 	"github.com/utreexo/utreexod/peer"
+	"github.com/utreexo/utreexod/zmq"
 )
 
 const (
@@ -39,12 +42,14 @@ const (
 	defaultLogDirname            = "logs"
 	defaultLogFilename           = "utreexod.log"
 	defaultMaxPeers              = 125
+	defaultMinProofPeers         = 2
 	defaultBanDuration           = time.Hour * 24
 	defaultBanThreshold          = 300
 	defaultConnectTimeout        = time.Second * 30
 	defaultMaxRPCClients         = 10
 	defaultMaxRPCWebsockets      = 25
 	defaultMaxRPCConcurrentReqs  = 20
+	defaultRPCUnixSocketMode     = "0600"
 	defaultDbType                = "ffldb"
 	defaultElectrumServerPort    = "50001"
 	defaultTLSElectrumServerPort = "50002"
@@ -60,14 +65,21 @@ const (
 	blockMaxWeightMax            = blockchain.MaxBlockWeight - 4000
 	defaultGenerate              = false
 	defaultMaxOrphanTransactions = 100
+	defaultMaxMempoolMB          = 300
+	defaultMempoolExpiryHours    = 336
 	defaultMaxOrphanTxSize       = 100000
 	defaultSigCacheMaxSize       = 100000
 	defaultUtxoCacheMaxSizeMiB   = 250
+	defaultUtreexoRememberPolicy = blockchain.RememberPolicyTTL
+	defaultUtreexoRememberMaxAge = time.Hour * 24
+	defaultZMQPubHWM             = zmq.DefaultSendHWM
 	defaultCookieFileName        = ".cookie"
 	sampleConfigFilename         = "sample-utreexod.conf"
 	defaultTxIndex               = false
 	defaultTTLIndex              = false
 	defaultAddrIndex             = false
+	defaultBdkBackupKeep         = 10
+	defaultBdkNotifyConfs        = 6
 	pruneMinSize                 = 550
 )
 
@@ -99,20 +111,41 @@ func minUint32(a, b uint32) uint32 {
 // See loadConfig for details on the configuration load process.
 type config struct {
 	// General application behavior.
-	ShowVersion         bool   `short:"V" long:"version" description:"Display version information and exit"`
-	DataDir             string `short:"b" long:"datadir" description:"Directory to store data"`
-	LogDir              string `long:"logdir" description:"Directory to log output."`
-	ConfigFile          string `short:"C" long:"configfile" description:"Path to configuration file"`
-	DebugLevel          string `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
-	DbType              string `long:"dbtype" description:"Database backend to use for the Block Chain"`
-	SigCacheMaxSize     uint   `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
-	UtxoCacheMaxSizeMiB uint   `long:"utxocachemaxsize" description:"The maximum size in MiB of the UTXO cache"`
-	NoUtreexo           bool   `long:"noutreexo" description:"Disable utreexo compact state during block validation"`
-	NoWinService        bool   `long:"nowinservice" description:"Do not start as a background service on Windows -- NOTE: This flag only works on the command line, not in the config file"`
-	Prune               uint64 `long:"prune" description:"Prune already validated blocks from the database. Must specify a target size in MiB (minimum value of 550, default of 550. Set to 0 to disable pruning.)"`
+	ShowVersion         bool     `short:"V" long:"version" description:"Display version information and exit"`
+	DataDir             string   `short:"b" long:"datadir" description:"Directory to store data"`
+	LogDir              string   `long:"logdir" description:"Directory to log output."`
+	ConfigFile          string   `short:"C" long:"configfile" description:"Path to configuration file"`
+	DebugLevel          string   `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
+	DbType              string   `long:"dbtype" description:"Database backend to use for the Block Chain {ffldb, pebble}"`
+	ImportBlocks        []string `long:"importblocks" description:"Import the blocks of a bootstrap.dat file, or of the blk*.dat files of a Bitcoin Core blocks directory, on startup before connecting to peers -- The blocks are fully validated and the already known ones are skipped.  Requires --noutreexo or a utreexo proof index and can be specified multiple times"`
+	SigCacheMaxSize     uint     `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
+	UtxoCacheMaxSizeMiB uint     `long:"utxocachemaxsize" description:"The maximum size in MiB of the UTXO cache"`
+	NoUtreexo           bool     `long:"noutreexo" description:"Disable utreexo compact state during block validation"`
+	NoWinService        bool     `long:"nowinservice" description:"Do not start as a background service on Windows -- NOTE: This flag only works on the command line, not in the config file"`
+	Prune               uint64   `long:"prune" description:"Prune already validated blocks from the database. Must specify a target size in MiB (minimum value of 550, default of 550. Set to 0 to disable pruning.)"`
+
+	// Database maintenance options.
+	DbCompactInterval time.Duration `long:"dbcompactinterval" description:"Compact the block database this often to reclaim the space of deleted data, waiting until the chain is synced and no block came in for a minute -- 0 disables the scheduled compactions, which can still be started with the compactdb RPC.  Valid time units are {s, m, h}"`
+
+	// Database tuning options.  The database gives the ones that are 0 their
+	// defaults, and picks the sizes from the memory of the machine.
+	DbCacheSizeMiB       uint `long:"dbcachesize" description:"The size in MiB of the cache the writes to the block database metadata are collected in before they're written out in one batch -- 0 picks it from the memory of the machine"`
+	DbWriteBufferSizeMiB uint `long:"dbwritebuffersize" description:"The size in MiB of the memtable of the leveldb or Pebble store of the block database metadata -- 0 picks it from the memory of the machine"`
+	DbBlockCacheSizeMiB  uint `long:"dbblockcachesize" description:"The size in MiB of the cache of the table blocks of the leveldb or Pebble store of the block database metadata -- 0 picks it from the memory of the machine"`
+	DbMaxOpenFiles       uint `long:"dbmaxopenfiles" description:"The max number of files of the leveldb or Pebble store of the block database metadata to keep open -- 0 uses the default of 500"`
+	DbMaxOpenBlockFiles  uint `long:"dbmaxopenblockfiles" description:"The max number of flat files of the blocks and of the spend journals each to keep open for reads -- 0 uses the default of 64"`
+	DbFileWriteBufferKiB uint `long:"dbfilewritebuffer" description:"The size in KiB of the buffer the blocks and spend journals are collected in before they're written to their flat files -- 0 uses the default of 1024"`
+
+	// Utreexo accumulator options.
+	UtreexoRememberPolicy      string        `long:"utreexorememberpolicy" description:"The policy that decides which utxos the compact state caches so that they don't need a proof when they're spent {always, age, amount, ttl} -- The ttl policy caches the utxos that the bridge says are spent soon"`
+	UtreexoRememberMaxAge      time.Duration `long:"utreexoremembermaxage" description:"The maximum age of the block a utxo was created in for it to be cached with the age remember policy.  Valid time units are {s, m, h}"`
+	UtreexoRememberMinAmount   float64       `long:"utreexorememberminamount" description:"The minimum amount in BTC of a utxo for it to be cached with the amount remember policy"`
+	UtreexoRememberMaxTTL      int32         `long:"utreexoremembermaxttl" description:"Mark the utxos that are spent at most this many blocks after they're created to be cached in the utreexo proofs served to peers, which compact state nodes with the ttl remember policy follow -- Requires --ttlindex and 0 disables it"`
+	UtreexoRootCheckpoints     string        `long:"utreexorootcheckpoints" description:"Path to a JSON file of known good utreexo accumulator roots by height to verify the accumulator against on startup and while syncing"`
+	UtreexoRootCheckpointsHash string        `long:"utreexorootcheckpointshash" description:"The sha256 of the --utreexorootcheckpoints file in hex as printed by sha256sum.  The file is rejected if it doesn't match"`
 
 	// Profiling options.
-	Profile       string `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
+	Profile       string `long:"profile" description:"Enable HTTP profiling and the metrics endpoint (/debug/vars) on given port -- NOTE port must be between 1024 and 65536"`
 	CPUProfile    string `long:"cpuprofile" description:"Write CPU profile to the specified file"`
 	MemoryProfile string `long:"memprofile" description:"Write memory profile to the specified file"`
 	TraceProfile  string `long:"traceprofile" description:"Write trace profile to the specified file"`
@@ -126,7 +159,8 @@ type config struct {
 
 	// RPC server options and policy.
 	DisableTLS           bool     `long:"notls" description:"Disable TLS for the RPC server -- NOTE: This is only allowed if the RPC server is bound to localhost"`
-	DisableRPC           bool     `long:"norpc" description:"Disable built-in RPC server -- NOTE: The RPC server is disabled by default if no rpcuser/rpcpass or rpclimituser/rpclimitpass is specified"`
+	DisableRPC           bool     `long:"norpc" description:"Disable built-in RPC server"`
+	RPCAccounts          []string `long:"rpcaccount" description:"Add an RPC user in the form <user>:<password>:<permission> where the permission is readonly, wallet or admin -- Can be specified multiple times"`
 	RPCCert              string   `long:"rpccert" description:"File containing the certificate file"`
 	RPCKey               string   `long:"rpckey" description:"File containing the certificate key"`
 	RPCLimitPass         string   `long:"rpclimitpass" default-mask:"-" description:"Password for limited RPC connections"`
@@ -135,19 +169,32 @@ type config struct {
 	RPCMaxClients        int      `long:"rpcmaxclients" description:"Max number of RPC clients for standard connections"`
 	RPCMaxConcurrentReqs int      `long:"rpcmaxconcurrentreqs" description:"Max number of concurrent RPC requests that may be processed concurrently"`
 	RPCMaxWebsockets     int      `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
+	RPCAuditLog          string   `long:"rpcauditlog" description:"File to append a JSON line to for every RPC call with its method, client, duration and result code"`
+	RPCMethodRateLimits  []string `long:"rpcmethodratelimit" description:"Limit the calls per second each RPC client may make to a method in the form <method>:<calls per second> -- Can be specified multiple times"`
+	RPCRateLimit         float64  `long:"rpcratelimit" description:"Max number of calls per second each RPC client may make, 0 for no limit"`
 	RPCQuirks            bool     `long:"rpcquirks" description:"Mirror some JSON-RPC quirks of Bitcoin Core -- NOTE: Discouraged unless interoperability issues need to be worked around"`
 	RPCPass              string   `short:"P" long:"rpcpass" default-mask:"-" description:"Password for RPC connections"`
+	RPCUnixSockets       []string `long:"rpcunixsocket" description:"Add a Unix domain socket path to listen for RPC connections on -- NOTE: Requests without credentials are authenticated as admin, so access is controlled by the permissions of the socket file"`
+	RPCUnixSocketMode    string   `long:"rpcunixsocketmode" description:"File permissions of the RPC Unix domain sockets in octal"`
 	RPCUser              string   `short:"u" long:"rpcuser" description:"Username for RPC connections"`
-
-	// P2P proxy and Tor settings.
-	Proxy          string `long:"proxy" description:"Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
-	ProxyPass      string `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
-	ProxyUser      string `long:"proxyuser" description:"Username for proxy server"`
-	NoOnion        bool   `long:"noonion" description:"Disable connecting to tor hidden services"`
-	OnionProxy     string `long:"onion" description:"Connect to tor hidden services via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
-	OnionProxyPass string `long:"onionpass" default-mask:"-" description:"Password for onion proxy server"`
-	OnionProxyUser string `long:"onionuser" description:"Username for onion proxy server"`
-	TorIsolation   bool   `long:"torisolation" description:"Enable Tor stream isolation by randomizing user credentials for each connection."`
+	REST                 bool     `long:"rest" description:"Accept unauthenticated read-only REST requests on the RPC listeners"`
+
+	// P2P proxy, Tor and I2P settings.
+	Proxy            string        `long:"proxy" description:"Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
+	NetProxies       []string      `long:"netproxy" description:"Connect to the peers on a network via its own SOCKS5 proxy instead of --proxy, in the form network=addr where the network is ipv4 or ipv6 and the address is none to connect directly (eg. ipv6=127.0.0.1:9052 or ipv4=none) -- May be specified once per network"`
+	ProxyPass        string        `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
+	ProxyUser        string        `long:"proxyuser" description:"Username for proxy server"`
+	NoOnion          bool          `long:"noonion" description:"Disable connecting to tor hidden services"`
+	OnionProxy       string        `long:"onion" description:"Connect to tor hidden services via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
+	OnionProxyPass   string        `long:"onionpass" default-mask:"-" description:"Password for onion proxy server"`
+	OnionProxyUser   string        `long:"onionuser" description:"Username for onion proxy server"`
+	TorIsolation     bool          `long:"torisolation" description:"Enable Tor stream isolation by randomizing user credentials for each connection."`
+	TorControl       string        `long:"torcontrol" description:"Tor control port to create a v3 onion service for incoming connections with (eg. 127.0.0.1:9051) -- NOTE: Enables Tor stream isolation when a proxy is set"`
+	TorPassword      string        `long:"torpassword" default-mask:"-" description:"Password for the Tor control port, which uses cookie authentication otherwise"`
+	I2PSAM           string        `long:"i2psam" description:"I2P SAM bridge to connect to I2P destinations and to accept incoming connections over I2P with (eg. 127.0.0.1:7656)"`
+	NoI2PListen      bool          `long:"noi2plisten" description:"Disable accepting incoming connections over I2P"`
+	PrivateBroadcast bool          `long:"privatebroadcast" description:"Only announce the locally submitted transactions to peers on Tor or I2P, so that the peers that learn about them first can't tell the IP address of the node"`
+	BroadcastDelay   time.Duration `long:"broadcastdelay" description:"Announce the locally submitted transactions to peers on Tor or I2P first, and only after this long to the other peers (eg. 30s)"`
 
 	// P2P network options.
 	AddPeers          []string      `short:"a" long:"addpeer" description:"Add a peer to connect with at startup"`
@@ -155,19 +202,25 @@ type config struct {
 	Listeners         []string      `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 8333, testnet: 18333)"`
 	DisableListen     bool          `long:"nolisten" description:"Disable listening for incoming connections -- NOTE: Listening is automatically disabled if the --connect or --proxy options are used without also specifying listen interfaces via --listen"`
 	MaxPeers          int           `long:"maxpeers" description:"Max number of inbound and outbound peers"`
+	MaxUploadTarget   uint64        `long:"maxuploadtarget" description:"Try to keep the bytes sent to peers under this many MiB per 24h by no longer serving the blocks and utreexo proofs older than a week once the rest is needed for new blocks -- Peers with the download permission are still served, and 0 means no target"`
+	MinProofPeers     int           `long:"minproofpeers" description:"Min number of outbound peers serving the utreexo proofs needed by the compact state to keep room for -- The proofs of all blocks are needed until the chain is synced, and only those of new blocks afterwards"`
+	OutboundQuotas    []string      `long:"outboundquota" description:"Keep a number of the automatic outbound slots for peers on a network, in the form network:count where the network is ipv4, ipv6, onion or i2p (eg. onion:2) -- May be specified once per network"`
 	UserAgentComments []string      `long:"uacomment" description:"Comment to add to the user agent -- See BIP 14 for more information."`
 	TrickleInterval   time.Duration `long:"trickleinterval" description:"Minimum time between attempts to send new inventory to a connected peer"`
 
 	// P2P network discovery options.
+	ASMap          string   `long:"asmap" description:"Group peer addresses by the autonomous system of their IP address using the ASN map file, in the format of Bitcoin Core, rather than by their /16 -- Makes it harder for a few hosting providers to control all the peers"`
 	DisableDNSSeed bool     `long:"nodnsseed" description:"Disable DNS seeding for peers"`
 	ExternalIPs    []string `long:"externalip" description:"Add an ip to the list of local addresses we claim to listen on to peers"`
 	SigNetSeedNode []string `long:"signetseednode" description:"Specify a seed node for the signet network instead of using the global default signet network seed nodes"`
+	NATPMP         bool     `long:"natpmp" description:"Use NAT-PMP or PCP to map our listening port outside of NAT"`
 	Upnp           bool     `long:"upnp" description:"Use UPnP to map our listening port outside of NAT"`
 
 	// Banning options.
 	AgentBlacklist []string      `long:"agentblacklist" description:"A comma separated list of user-agent substrings which will cause utreexod to reject any peers whose user-agent contains any of the blacklisted substrings."`
 	AgentWhitelist []string      `long:"agentwhitelist" description:"A comma separated list of user-agent substrings which will cause utreexod to require all peers' user-agents to contain one of the whitelisted substrings. The blacklist is applied before the blacklist, and an empty whitelist will allow all agents that do not fail the blacklist."`
-	Whitelists     []string      `long:"whitelist" description:"Add an IP network or IP that will not be banned. (eg. 192.168.1.0/24 or ::1)"`
+	Whitelists     []string      `long:"whitelist" description:"Add an IP network or IP whose peers are given the permissions, like [perm,...@]network, or noban without any. Permissions are bloomfilter, download, forcerelay, mempool, noban, proof and all. (eg. 192.168.1.0/24 or mempool,noban@::1)"`
+	Whitebinds     []string      `long:"whitebind" description:"Listen for peers on the address and give the peers that connect to it the permissions, like [perm,...@]addr, or noban without any. Takes the same permissions as --whitelist. (eg. noban,proof@127.0.0.1:8335)"`
 	DisableBanning bool          `long:"nobanning" description:"Disable banning of misbehaving peers"`
 	BanDuration    time.Duration `long:"banduration" description:"How long to ban misbehaving peers.  Valid time units are {s, m, h}.  Minimum 1 second"`
 	BanThreshold   uint32        `long:"banthreshold" description:"Maximum allowed ban score before disconnecting and banning misbehaving peers."`
@@ -178,14 +231,23 @@ type config struct {
 	NoAssumeUtreexo    bool     `long:"noassumeutreexo" description:"Disable starting from the assume utreexo point and start the initial block download from the genesis block"`
 
 	// Relay and mempool policy.
-	BlocksOnly        bool    `long:"blocksonly" description:"Do not accept transactions from remote peers."`
-	MaxOrphanTxs      int     `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
-	MinRelayTxFee     float64 `long:"minrelaytxfee" description:"The minimum transaction fee in BTC/kB to be considered a non-zero fee."`
-	NoRelayPriority   bool    `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
-	RelayNonStd       bool    `long:"relaynonstd" description:"Relay non-standard transactions regardless of the default settings for the active network."`
-	RejectNonStd      bool    `long:"rejectnonstd" description:"Reject non-standard transactions regardless of the default settings for the active network."`
-	RejectReplacement bool    `long:"rejectreplacement" description:"Reject transactions that attempt to replace existing transactions within the mempool through the Replace-By-Fee (RBF) signaling policy."`
-	FreeTxRelayLimit  float64 `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
+	BlocksOnly          bool    `long:"blocksonly" description:"Do not accept transactions from remote peers."`
+	DataCarrierSize     int     `long:"datacarriersize" description:"Max number of bytes of data that standard null data (OP_RETURN) outputs may carry"`
+	DustRelayFee        float64 `long:"dustrelayfee" description:"The fee rate in BTC/kB used to decide whether an output is dust, since spending it would cost more than it's worth"`
+	MaxMempool          int     `long:"maxmempool" description:"Max size of the mempool in megabytes.  The transactions paying the lowest fee rates are evicted once it is reached"`
+	MaxOrphanTxs        int     `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
+	MaxStandardTxWeight int     `long:"maxstandardtxweight" description:"Max weight of transactions that are considered standard"`
+	MempoolExpiry       int     `long:"mempoolexpiry" description:"Number of hours after which transactions that weren't mined are removed from the mempool"`
+	MempoolFullRBF      bool    `long:"mempoolfullrbf" description:"Accept transactions that replace existing transactions within the mempool whether or not they signal replacement through the Replace-By-Fee (RBF) signaling policy."`
+	MinRelayTxFee       float64 `long:"minrelaytxfee" description:"The minimum transaction fee in BTC/kB to be considered a non-zero fee."`
+	NoDataCarrier       bool    `long:"nodatacarrier" description:"Do not relay transactions with null data (OP_RETURN) outputs"`
+	NoPersistMempool    bool    `long:"nopersistmempool" description:"Do not save the mempool on shutdown and load it again on startup"`
+	NoRelayPriority     bool    `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
+	RejectBareMultisig  bool    `long:"rejectbaremultisig" description:"Reject transactions with bare (non-P2SH) multi-signature outputs"`
+	RelayNonStd         bool    `long:"relaynonstd" description:"Relay non-standard transactions regardless of the default settings for the active network."`
+	RejectNonStd        bool    `long:"rejectnonstd" description:"Reject non-standard transactions regardless of the default settings for the active network."`
+	RejectReplacement   bool    `long:"rejectreplacement" description:"Reject transactions that attempt to replace existing transactions within the mempool through the Replace-By-Fee (RBF) signaling policy."`
+	FreeTxRelayLimit    float64 `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
 
 	// Mining options and policy.
 	Generate          bool     `long:"generate" description:"Generate (mine) bitcoins using the CPU"`
@@ -195,11 +257,17 @@ type config struct {
 	BlockMaxWeight    uint32   `long:"blockmaxweight" description:"Maximum block weight to be used when creating a block"`
 	BlockMinWeight    uint32   `long:"blockminweight" description:"Mininum block weight to be used when creating a block"`
 	BlockPrioritySize uint32   `long:"blockprioritysize" description:"Size in bytes for high-priority/low-fee transactions when creating a block"`
+	BlockMinTxFee     float64  `long:"blockmintxfee" description:"The minimum transaction fee in BTC/kB for transactions to be included when creating a block"`
 
 	// Indexing options.
 	AddrIndex                  bool  `long:"addrindex" description:"Maintain a full address-based transaction index which makes the searchrawtransactions RPC available"`
 	TxIndex                    bool  `long:"txindex" description:"Maintain a full hash-based transaction index which makes all transactions available via the getrawtransaction RPC"`
 	TTLIndex                   bool  `long:"ttlindex" description:"Maintain a full time to live index for all stxos available via the getttl RPC"`
+	SpendIndex                 bool  `long:"spendindex" description:"Maintain a full index of the inputs spending every spent output which makes the spenders of confirmed outputs available via the gettxspendingprevout RPC"`
+	SilentPaymentIndex         bool  `long:"silentpaymentindex" description:"Maintain a full index of the BIP-352 silent payment tweaks of every block available via the getsilentpaymenttweaks RPC"`
+	BlockTimeIndex             bool  `long:"blocktimeindex" description:"Maintain an index of the blocks by their timestamps which makes the blocks within a time range available via the getblocksbytime RPC"`
+	ScriptBalanceIndex         bool  `long:"scriptbalanceindex" description:"Maintain a full index of the confirmed balance and Electrum history status of every script available via the getscriptbalance RPC"`
+	UtreexoCFIndex             bool  `long:"utreexocfindex" description:"Maintain a chain of compact filter headers that also commit to the utreexo roots of every block available via the getutreexocfheader RPC -- Requires --noutreexo off or a utreexo proof index"`
 	UtreexoProofIndex          bool  `long:"utreexoproofindex" description:"Maintain a utreexo proof for all blocks"`
 	FlatUtreexoProofIndex      bool  `long:"flatutreexoproofindex" description:"Maintain a utreexo proof for all blocks in flat files"`
 	UtreexoProofIndexMaxMemory int64 `long:"utreexoproofindexmaxmemory" description:"The maxmimum memory in mebibytes (MiB) that the utreexo proof indexes will use up. Passing in 0 will make the entire proof index stay on disk. Passing in a negative value will make the entire proof index stay in memory. Default of 250MiB."`
@@ -209,6 +277,11 @@ type config struct {
 	DropCfIndex                bool  `long:"dropcfindex" description:"Deletes the index used for committed filtering (CF) support from the database on start up and then exits."`
 	DropTxIndex                bool  `long:"droptxindex" description:"Deletes the hash-based transaction index from the database on start up and then exits."`
 	DropTTLIndex               bool  `long:"dropttlindex" description:"Deletes the time to live index from the database on start up and then exits."`
+	DropSpendIndex             bool  `long:"dropspendindex" description:"Deletes the spend index from the database on start up and then exits."`
+	DropSilentPaymentIndex     bool  `long:"dropsilentpaymentindex" description:"Deletes the silent payment index from the database on start up and then exits."`
+	DropBlockTimeIndex         bool  `long:"dropblocktimeindex" description:"Deletes the block time index from the database on start up and then exits."`
+	DropScriptBalanceIndex     bool  `long:"dropscriptbalanceindex" description:"Deletes the script balance index from the database on start up and then exits."`
+	DropUtreexoCFIndex         bool  `long:"droputreexocfindex" description:"Deletes the utreexo committed filter header index from the database on start up and then exits."`
 	DropUtreexoProofIndex      bool  `long:"droputreexoproofindex" description:"Deletes the utreexo proof index from the database on start up and then exits."`
 	DropFlatUtreexoProofIndex  bool  `long:"dropflatutreexoproofindex" description:"Deletes the flat utreexo proof index from the database on start up and then exits."`
 
@@ -218,20 +291,60 @@ type config struct {
 	RegisterExtendedPubKeysToWatchOnlyWallet             []string `long:"registerextendedpubkeystowatchonlywallet" description:"Registers extended pubkeys to be watched to the watch only wallet. Must have --watchonlywallet enabled."`
 	RegisterExtendedPubKeysWithAddrTypeToWatchOnlyWallet []string `long:"registerextendedpubkeyswithaddresstypetowatchonlywallet" description:"Registers extended pubkeys to be watched to the watch only wallet and let's the user override the hd type of the extended public key. Must have --watchonlywallet enabled. Format: '<extendedpubkey>:<address type>. Supported address types: '{p2pkh, p2wpkh, p2sh}'"`
 	NoBdkWallet                                          bool     `long:"nobdkwallet" description:"Disable the BDK wallet."`
+	BdkWatchOnly                                         string   `long:"bdkwatchonly" description:"Create the BDK wallet as a watch-only wallet of the extended public key or the ranged wpkh or tr output descriptor. An extended public key is tracked as a BIP86 wallet. Only used when the wallet is created"`
+	BdkWatchOnlyBirthday                                 int32    `long:"bdkwatchonlybirthday" description:"Block height the watch-only BDK wallet is rescanned from when it's created. Must have --bdkwatchonly or --bdksigner set"`
+	BdkSigner                                            string   `long:"bdksigner" description:"HWI compatible command of the external signer, such as a hardware wallet, that signs the transactions of the BDK wallet. The wallet is created as a watch-only wallet of the taproot descriptors of the signer unless --bdkwatchonly is set"`
+
+	BdkBackupPassphrase string        `long:"bdkbackuppassphrase" default-mask:"-" description:"Passphrase the BDK wallet backups are encrypted with. Used by backupwallet and restorewallet when they aren't given one"`
+	BdkBackupInterval   time.Duration `long:"bdkbackupinterval" description:"Interval to back up the loaded BDK wallets at. Requires --bdkbackuppassphrase. Valid time units are {s, m, h}"`
+	BdkBackupDir        string        `long:"bdkbackupdir" description:"Directory to write the automatic BDK wallet backups to (default: bdkwallet/backups in the data directory)"`
+	BdkBackupKeep       int           `long:"bdkbackupkeep" description:"Number of automatic backups of each BDK wallet to keep. The oldest backups are removed; 0 keeps all of them"`
+
+	BdkNotifyConfirmations uint `long:"bdknotifyconfirmations" description:"Number of confirmations at which the wallet event subscribers are notified that a transaction of a BDK wallet is confirmed"`
 
 	// Electrum server options.
 	ElectrumListeners    []string `long:"electrumlisteners" description:"Interface/port for the electrum server to listen to. (default 50001). Electrum server is only enabled when --watchonlywallet is enabled"`
 	TLSElectrumListeners []string `long:"tlselectrumlisteners" description:"Interface/port for the electrum server to listen to with tls. (default 50002). TLS electrum server is only enabled when --watchonlywallet is enabled"`
 	DisableElectrum      bool     `long:"disableelectrum" description:"Disable the electrum server while the --watchonlywallet flag is on"`
 
+	// Proof server options.
+	ProofServerListeners []string `long:"proofserverlisten" description:"Add an interface/port to serve utreexo proofs and roots over HTTP on.  Requires --utreexoproofindex or --flatutreexoproofindex"`
+	ProofServerTLS       bool     `long:"proofservertls" description:"Serve the proof server over HTTPS with the --rpccert and --rpckey key pair"`
+
+	// ZMQ notification options.
+	ZMQPubHashBlock    []string `long:"zmqpubhashblock" description:"Publish the hashes of the connected blocks on a ZMQ endpoint (eg. tcp://127.0.0.1:28332)"`
+	ZMQPubRawBlock     []string `long:"zmqpubrawblock" description:"Publish the connected blocks on a ZMQ endpoint"`
+	ZMQPubHashTx       []string `long:"zmqpubhashtx" description:"Publish the hashes of the transactions in the mempool and in the connected blocks on a ZMQ endpoint"`
+	ZMQPubRawTx        []string `long:"zmqpubrawtx" description:"Publish the transactions in the mempool and in the connected blocks on a ZMQ endpoint"`
+	ZMQPubUtreexoRoots []string `long:"zmqpubutreexoroots" description:"Publish the utreexo accumulator roots after every connected block on a ZMQ endpoint.  Requires the utreexo compact state or a utreexo proof index"`
+	ZMQPubMempoolEvent []string `long:"zmqpubmempoolevent" description:"Publish the transactions that are added to, removed from, replaced in or confirmed out of the mempool with their fees and sizes on a ZMQ endpoint"`
+	ZMQPubWalletEvent  []string `long:"zmqpubwalletevent" description:"Publish the payments received by the BDK wallets, the confirmations of their transactions and their fee bumps on a ZMQ endpoint"`
+	ZMQPubHWM          int      `long:"zmqpubhwm" description:"The number of messages queued for a ZMQ subscriber before new messages to it are dropped"`
+
+	// gRPC options.
+	GRPCListeners []string `long:"grpclisten" description:"Add an interface/port to serve the gRPC API on.  It's always served over TLS with the --rpccert and --rpckey key pair"`
+
 	// Cooked options ready for use.
 	lookup          func(string) ([]net.IP, error)
 	oniondial       func(string, string, time.Duration) (net.Conn, error)
+	i2pSession      *i2pSession
 	dial            func(string, string, time.Duration) (net.Conn, error)
+	netProxies      netProxies
+	netDials        [numPeerNetworks]func(string, string, time.Duration) (net.Conn, error)
 	addCheckpoints  []chaincfg.Checkpoint
 	miningAddrs     []btcutil.Address
 	minRelayTxFee   btcutil.Amount
-	whitelists      []*net.IPNet
+	dustRelayFee    btcutil.Amount
+	blockMinTxFee   btcutil.Amount
+	rememberPolicy  blockchain.RememberPolicy
+	rootCheckpoints []blockchain.UtreexoRootCheckpoint
+	rpcUsers        []rpcUser
+	rpcSocketMode   os.FileMode
+	rpcMethodRates  map[string]float64
+	whitelists      []*whitelist
+	whitebinds      []*whitebind
+	asmap           *addrmgr.ASMap
+	outboundQuotas  outboundQuotas
 	extendedPubkeys map[string]string
 }
 
@@ -432,6 +545,18 @@ func parseCheckpoints(checkpointStrings []string) ([]chaincfg.Checkpoint, error)
 	return checkpoints, nil
 }
 
+// parseFileMode parses the octal permission bits of a file mode such as 0600.
+func parseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	if os.FileMode(mode)&^os.ModePerm != 0 {
+		return 0, fmt.Errorf("%s has bits other than the permissions", s)
+	}
+	return os.FileMode(mode), nil
+}
+
 // filesExists reports whether the named file or directory exists.
 func fileExists(name string) bool {
 	if _, err := os.Stat(name); err != nil {
@@ -469,17 +594,22 @@ func loadConfig() (*config, []string, error) {
 		ConfigFile:                 defaultConfigFile,
 		DebugLevel:                 defaultLogLevel,
 		MaxPeers:                   defaultMaxPeers,
+		MinProofPeers:              defaultMinProofPeers,
 		BanDuration:                defaultBanDuration,
 		BanThreshold:               defaultBanThreshold,
 		RPCMaxClients:              defaultMaxRPCClients,
 		RPCMaxWebsockets:           defaultMaxRPCWebsockets,
 		RPCMaxConcurrentReqs:       defaultMaxRPCConcurrentReqs,
+		RPCUnixSocketMode:          defaultRPCUnixSocketMode,
 		DataDir:                    defaultDataDir,
 		LogDir:                     defaultLogDir,
 		DbType:                     defaultDbType,
 		RPCKey:                     defaultRPCKeyFile,
 		RPCCert:                    defaultRPCCertFile,
 		MinRelayTxFee:              mempool.DefaultMinRelayTxFee.ToBTC(),
+		DustRelayFee:               mempool.DefaultMinRelayTxFee.ToBTC(),
+		DataCarrierSize:            mempool.DefaultMaxDataCarrierSize,
+		MaxStandardTxWeight:        mempool.DefaultMaxStandardTxWeight,
 		FreeTxRelayLimit:           defaultFreeTxRelayLimit,
 		TrickleInterval:            defaultTrickleInterval,
 		BlockMinSize:               defaultBlockMinSize,
@@ -488,14 +618,21 @@ func loadConfig() (*config, []string, error) {
 		BlockMaxWeight:             defaultBlockMaxWeight,
 		BlockPrioritySize:          mempool.DefaultBlockPrioritySize,
 		MaxOrphanTxs:               defaultMaxOrphanTransactions,
+		MaxMempool:                 defaultMaxMempoolMB,
+		MempoolExpiry:              defaultMempoolExpiryHours,
 		SigCacheMaxSize:            defaultSigCacheMaxSize,
 		UtxoCacheMaxSizeMiB:        defaultUtxoCacheMaxSizeMiB,
 		UtreexoProofIndexMaxMemory: defaultUtxoCacheMaxSizeMiB,
+		UtreexoRememberPolicy:      defaultUtreexoRememberPolicy,
+		UtreexoRememberMaxAge:      defaultUtreexoRememberMaxAge,
+		ZMQPubHWM:                  defaultZMQPubHWM,
 		Generate:                   defaultGenerate,
 		TxIndex:                    defaultTxIndex,
 		TTLIndex:                   defaultTTLIndex,
 		AddrIndex:                  defaultAddrIndex,
 		Prune:                      pruneMinSize,
+		BdkBackupKeep:              defaultBdkBackupKeep,
+		BdkNotifyConfirmations:     defaultBdkNotifyConfs,
 	}
 
 	// Service options which are only added on Windows.
@@ -679,6 +816,16 @@ func loadConfig() (*config, []string, error) {
 	}
 	cfg.RelayNonStd = relayNonStd
 
+	// Full replacement only makes sense when replacements are accepted.
+	if cfg.MempoolFullRBF && cfg.RejectReplacement {
+		str := "%s: mempoolfullrbf and rejectreplacement cannot be " +
+			"used together -- choose only one"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Append the network type to the data directory so it is "namespaced"
 	// per network.  In addition to the block database, there are other
 	// pieces of data that are saved to disk such as address manager state.
@@ -742,35 +889,65 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
-	// Validate any given whitelisted IP addresses and networks.
-	if len(cfg.Whitelists) > 0 {
-		var ip net.IP
-		cfg.whitelists = make([]*net.IPNet, 0, len(cfg.Whitelists))
+	// The scheduled compactions of the block database can't be more often
+	// than they're checked for.
+	if cfg.DbCompactInterval != 0 && cfg.DbCompactInterval < dbCompactCheckInterval {
+		str := "%s: The dbcompactinterval option may not be less than " +
+			"%v unless it's 0 -- parsed [%v]"
+		err := fmt.Errorf(str, funcName, dbCompactCheckInterval,
+			cfg.DbCompactInterval)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
 
-		for _, addr := range cfg.Whitelists {
-			_, ipnet, err := net.ParseCIDR(addr)
-			if err != nil {
-				ip = net.ParseIP(addr)
-				if ip == nil {
-					str := "%s: The whitelist value of '%s' is invalid"
-					err = fmt.Errorf(str, funcName, addr)
-					fmt.Fprintln(os.Stderr, err)
-					fmt.Fprintln(os.Stderr, usageMessage)
-					return nil, nil, err
-				}
-				var bits int
-				if ip.To4() == nil {
-					// IPv6
-					bits = 128
-				} else {
-					bits = 32
-				}
-				ipnet = &net.IPNet{
-					IP:   ip,
-					Mask: net.CIDRMask(bits, bits),
-				}
-			}
-			cfg.whitelists = append(cfg.whitelists, ipnet)
+	// Validate any given whitelisted IP addresses and networks along with
+	// their permissions.
+	cfg.whitelists = make([]*whitelist, 0, len(cfg.Whitelists))
+	for _, value := range cfg.Whitelists {
+		list, err := parseWhitelist(value)
+		if err != nil {
+			str := "%s: The whitelist value of '%s' is invalid: %v"
+			err = fmt.Errorf(str, funcName, value, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.whitelists = append(cfg.whitelists, list)
+	}
+
+	// Validate the whitebind listeners along with their permissions.
+	cfg.whitebinds = make([]*whitebind, 0, len(cfg.Whitebinds))
+	for _, value := range cfg.Whitebinds {
+		bind, err := parseWhitebind(value, activeNetParams.DefaultPort)
+		if err != nil {
+			str := "%s: The whitebind value of '%s' is invalid: %v"
+			err = fmt.Errorf(str, funcName, value, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.whitebinds = append(cfg.whitebinds, bind)
+	}
+	if len(cfg.whitebinds) > 0 && cfg.DisableListen {
+		str := "%s: the --whitebind option requires listening for " +
+			"incoming connections"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Load the ASN map to group the peer addresses by.
+	if cfg.ASMap != "" {
+		cfg.ASMap = cleanAndExpandPath(cfg.ASMap)
+		cfg.asmap, err = addrmgr.LoadASMap(cfg.ASMap)
+		if err != nil {
+			str := "%s: failed to load the ASN map: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
 		}
 	}
 
@@ -784,10 +961,37 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
-	// --proxy or --connect without --listen disables listening.
+	// --proxy or --connect without --listen disables listening.  The onion
+	// service of --torcontrol only needs the node to listen on localhost
+	// though.
 	if (cfg.Proxy != "" || len(cfg.ConnectPeers) > 0) &&
-		len(cfg.Listeners) == 0 {
-		cfg.DisableListen = true
+		len(cfg.Listeners) == 0 && len(cfg.whitebinds) == 0 {
+		if cfg.TorControl != "" && !cfg.DisableListen {
+			cfg.Listeners = []string{
+				net.JoinHostPort("127.0.0.1", activeNetParams.DefaultPort),
+			}
+		} else {
+			cfg.DisableListen = true
+		}
+	}
+
+	// The onion service forwards the incoming connections to the listener.
+	if cfg.TorControl != "" {
+		if cfg.DisableListen {
+			str := "%s: the --torcontrol option requires listening " +
+				"for incoming connections"
+			err := fmt.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		if _, _, err := net.SplitHostPort(cfg.TorControl); err != nil {
+			str := "%s: Tor control address '%s' is invalid: %v"
+			err := fmt.Errorf(str, funcName, cfg.TorControl, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
 	}
 
 	// Connect means no DNS seeding.
@@ -797,8 +1001,9 @@ func loadConfig() (*config, []string, error) {
 
 	// Add the default listener if none were specified. The default
 	// listener is all addresses on the listen port for the network
-	// we are to connect to.
-	if len(cfg.Listeners) == 0 {
+	// we are to connect to.  The whitebind listeners replace the default
+	// listener the same way the --listen ones do.
+	if len(cfg.Listeners) == 0 && len(cfg.whitebinds) == 0 {
 		cfg.Listeners = []string{
 			net.JoinHostPort("", activeNetParams.DefaultPort),
 		}
@@ -824,6 +1029,74 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Parse the RPC users.  The --rpcuser and --rpclimituser users are
+	// shorthands for users with the admin and readonly permissions.
+	if cfg.RPCUser != "" && cfg.RPCPass != "" {
+		cfg.rpcUsers = append(cfg.rpcUsers, newRPCUser(cfg.RPCUser,
+			cfg.RPCPass, rpcPermAdmin))
+	}
+	if cfg.RPCLimitUser != "" && cfg.RPCLimitPass != "" {
+		cfg.rpcUsers = append(cfg.rpcUsers, newRPCUser(cfg.RPCLimitUser,
+			cfg.RPCLimitPass, rpcPermReadOnly))
+	}
+	for _, account := range cfg.RPCAccounts {
+		user, err := parseRPCAccount(account)
+		if err != nil {
+			err := fmt.Errorf("%s: %v", funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.rpcUsers = append(cfg.rpcUsers, user)
+	}
+
+	// Check to make sure RPC users don't have the same username, including
+	// the user of the cookie file.
+	usernames := map[string]struct{}{cookieUsername: {}}
+	for _, user := range cfg.rpcUsers {
+		if _, ok := usernames[user.name]; ok {
+			str := "%s: the RPC username '%s' is used more than " +
+				"once or is reserved"
+			err := fmt.Errorf(str, funcName, user.name)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		usernames[user.name] = struct{}{}
+	}
+
+	// Parse the file permissions of the RPC Unix domain sockets.
+	cfg.rpcSocketMode, err = parseFileMode(cfg.RPCUnixSocketMode)
+	if err != nil {
+		str := "%s: the --rpcunixsocketmode option '%s' is not a " +
+			"valid octal file mode"
+		err := fmt.Errorf(str, funcName, cfg.RPCUnixSocketMode)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the RPC rate limits.
+	if cfg.RPCRateLimit < 0 {
+		str := "%s: the rpcratelimit option may not be less than 0 " +
+			"-- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.RPCRateLimit)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	cfg.rpcMethodRates = make(map[string]float64, len(cfg.RPCMethodRateLimits))
+	for _, limit := range cfg.RPCMethodRateLimits {
+		method, rate, err := parseRPCMethodRateLimit(limit)
+		if err != nil {
+			err := fmt.Errorf("%s: %v", funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.rpcMethodRates[method] = rate
+	}
+
 	if cfg.DisableRPC {
 		btcdLog.Infof("RPC service is disabled")
 	}
@@ -860,6 +1133,98 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Validate the dustrelayfee.
+	cfg.dustRelayFee, err = btcutil.NewAmount(cfg.DustRelayFee)
+	if err != nil {
+		str := "%s: invalid dustrelayfee: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the blockmintxfee.
+	cfg.blockMinTxFee, err = btcutil.NewAmount(cfg.BlockMinTxFee)
+	if err != nil || cfg.blockMinTxFee < 0 {
+		str := "%s: invalid blockmintxfee: %v"
+		err := fmt.Errorf(str, funcName, cfg.BlockMinTxFee)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the utreexo remember policy.
+	minRememberAmount, err := btcutil.NewAmount(cfg.UtreexoRememberMinAmount)
+	if err != nil {
+		str := "%s: invalid utreexorememberminamount: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	cfg.rememberPolicy, err = blockchain.NewRememberPolicy(
+		cfg.UtreexoRememberPolicy, cfg.UtreexoRememberMaxAge,
+		int64(minRememberAmount))
+	if err != nil {
+		str := "%s: invalid utreexorememberpolicy: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The remember indexes of the served proofs are derived from the time
+	// to live of the utxos, which only the ttl index knows.
+	if cfg.UtreexoRememberMaxTTL < 0 {
+		str := "%s: the utreexoremembermaxttl option may not be " +
+			"negative -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.UtreexoRememberMaxTTL)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.UtreexoRememberMaxTTL > 0 && !cfg.TTLIndex {
+		err := fmt.Errorf("%s: the --utreexoremembermaxttl option "+
+			"requires the --ttlindex option", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Load the utreexo root checkpoints.
+	if cfg.UtreexoRootCheckpointsHash != "" && cfg.UtreexoRootCheckpoints == "" {
+		str := "%s: the --utreexorootcheckpointshash option requires " +
+			"the --utreexorootcheckpoints option"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.UtreexoRootCheckpoints != "" {
+		var fileHash *[sha256.Size]byte
+		if cfg.UtreexoRootCheckpointsHash != "" {
+			fileHash, err = blockchain.ParseUtreexoRootCheckpointsHash(
+				cfg.UtreexoRootCheckpointsHash)
+			if err != nil {
+				str := "%s: invalid utreexorootcheckpointshash: %v"
+				err := fmt.Errorf(str, funcName, err)
+				fmt.Fprintln(os.Stderr, err)
+				fmt.Fprintln(os.Stderr, usageMessage)
+				return nil, nil, err
+			}
+		}
+
+		cfg.UtreexoRootCheckpoints = cleanAndExpandPath(cfg.UtreexoRootCheckpoints)
+		cfg.rootCheckpoints, err = blockchain.LoadUtreexoRootCheckpoints(
+			cfg.UtreexoRootCheckpoints, fileHash)
+		if err != nil {
+			str := "%s: failed to load the utreexo root checkpoints: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
 	// Limit the max block size to a sane value.
 	if cfg.BlockMaxSize < blockMaxSizeMin || cfg.BlockMaxSize >
 		blockMaxSizeMax {
@@ -887,6 +1252,15 @@ func loadConfig() (*config, []string, error) {
 	}
 
 	// Limit the max orphan count to a sane vlue.
+	if cfg.MinProofPeers < 0 {
+		str := "%s: The minproofpeers option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.MinProofPeers)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	if cfg.MaxOrphanTxs < 0 {
 		str := "%s: The maxorphantx option may not be less than 0 " +
 			"-- parsed [%d]"
@@ -896,6 +1270,45 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Limit the mempool size and transaction age to sane values.
+	if cfg.MaxMempool < 1 {
+		str := "%s: The maxmempool option may not be less than 1 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.MaxMempool)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.MempoolExpiry < 1 {
+		str := "%s: The mempoolexpiry option may not be less than 1 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.MempoolExpiry)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Limit the standardness policy knobs to sane values.
+	if cfg.DataCarrierSize < 0 {
+		str := "%s: The datacarriersize option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.DataCarrierSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.MaxStandardTxWeight < 1 ||
+		cfg.MaxStandardTxWeight > blockchain.MaxBlockWeight {
+
+		str := "%s: The maxstandardtxweight option must be in range " +
+			"[%d, %d] -- parsed [%d]"
+		err := fmt.Errorf(str, funcName, 1, blockchain.MaxBlockWeight,
+			cfg.MaxStandardTxWeight)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Limit the block priority and minimum block sizes to max block size.
 	cfg.BlockPrioritySize = minUint32(cfg.BlockPrioritySize, cfg.BlockMaxSize)
 	cfg.BlockMinSize = minUint32(cfg.BlockMinSize, cfg.BlockMaxSize)
@@ -972,6 +1385,56 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// --spendindex and --dropspendindex do not mix.
+	if cfg.SpendIndex && cfg.DropSpendIndex {
+		err := fmt.Errorf("%s: the --spendindex and --dropspendindex "+
+			"options may not be activated at the same time ",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --silentpaymentindex and --dropsilentpaymentindex do not mix.
+	if cfg.SilentPaymentIndex && cfg.DropSilentPaymentIndex {
+		err := fmt.Errorf("%s: the --silentpaymentindex and "+
+			"--dropsilentpaymentindex options may not be activated "+
+			"at the same time ", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --blocktimeindex and --dropblocktimeindex do not mix.
+	if cfg.BlockTimeIndex && cfg.DropBlockTimeIndex {
+		err := fmt.Errorf("%s: the --blocktimeindex and --dropblocktimeindex "+
+			"options may not be activated at the same time ",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --scriptbalanceindex and --dropscriptbalanceindex do not mix.
+	if cfg.ScriptBalanceIndex && cfg.DropScriptBalanceIndex {
+		err := fmt.Errorf("%s: the --scriptbalanceindex and "+
+			"--dropscriptbalanceindex options may not be activated "+
+			"at the same time ", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --utreexocfindex and --droputreexocfindex do not mix.
+	if cfg.UtreexoCFIndex && cfg.DropUtreexoCFIndex {
+		err := fmt.Errorf("%s: the --utreexocfindex and --droputreexocfindex "+
+			"options may not be activated at the same time ",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// --utreexoproofindex and --droputreexoproofindex do not mix.
 	if cfg.UtreexoProofIndex && cfg.DropUtreexoProofIndex {
 		err := fmt.Errorf("%s: the --utreexoproofindex and --droputreexoproofindex"+
@@ -1026,6 +1489,9 @@ func loadConfig() (*config, []string, error) {
 
 	// Add default port to all listener addresses if needed and remove
 	// duplicate addresses.
+	for _, bind := range cfg.whitebinds {
+		cfg.Listeners = append(cfg.Listeners, bind.addr)
+	}
 	cfg.Listeners = normalizeAddresses(cfg.Listeners,
 		activeNetParams.DefaultPort)
 
@@ -1080,6 +1546,16 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// The peers on IPv4 and IPv6 may be given proxies of their own.
+	cfg.netProxies, err = parseNetProxies(cfg.NetProxies)
+	if err != nil {
+		str := "%s: Invalid --netproxy: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Check the checkpoints for syntax errors.
 	cfg.addCheckpoints, err = parseCheckpoints(cfg.AddCheckpoints)
 	if err != nil {
@@ -1090,10 +1566,18 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Connections made through Tor while it's configured with a control
+	// port get a circuit of their own.
+	anyProxy := cfg.Proxy != "" || cfg.OnionProxy != "" ||
+		cfg.netProxies.proxied()
+	if cfg.TorControl != "" && anyProxy {
+		cfg.TorIsolation = true
+	}
+
 	// Tor stream isolation requires either proxy or onion proxy to be set.
-	if cfg.TorIsolation && cfg.Proxy == "" && cfg.OnionProxy == "" {
-		str := "%s: Tor stream isolation requires either proxy or " +
-			"onionproxy to be set"
+	if cfg.TorIsolation && !anyProxy {
+		str := "%s: Tor stream isolation requires either proxy, " +
+			"netproxy or onionproxy to be set"
 		err := fmt.Errorf(str, funcName)
 		fmt.Fprintln(os.Stderr, err)
 		fmt.Fprintln(os.Stderr, usageMessage)
@@ -1121,11 +1605,8 @@ func loadConfig() (*config, []string, error) {
 		// Tor isolation flag means proxy credentials will be overridden
 		// unless there is also an onion proxy configured in which case
 		// that one will be overridden.
-		torIsolation := false
-		if cfg.TorIsolation && cfg.OnionProxy == "" &&
-			(cfg.ProxyUser != "" || cfg.ProxyPass != "") {
-
-			torIsolation = true
+		torIsolation := cfg.TorIsolation && cfg.OnionProxy == ""
+		if torIsolation && (cfg.ProxyUser != "" || cfg.ProxyPass != "") {
 			fmt.Fprintln(os.Stderr, "Tor isolation set -- "+
 				"overriding specified proxy user credentials")
 		}
@@ -1137,14 +1618,43 @@ func loadConfig() (*config, []string, error) {
 			TorIsolation: torIsolation,
 		}
 		cfg.dial = proxy.DialTimeout
+	}
 
-		// Treat the proxy as tor and perform DNS resolution through it
-		// unless the --noonion flag is set or there is an
-		// onion-specific proxy configured.
-		if !cfg.NoOnion && cfg.OnionProxy == "" {
-			cfg.lookup = func(host string) ([]net.IP, error) {
-				return connmgr.TorLookupIP(host, cfg.Proxy)
+	// The peers on IPv4 and IPv6 are connected to through the proxy given
+	// to their network with --netproxy, or through the dial function
+	// selected above otherwise.
+	for _, network := range []peerNetwork{netIPv4, netIPv6} {
+		switch addr := cfg.netProxies[network]; addr {
+		case "":
+			cfg.netDials[network] = cfg.dial
+		case netProxyNone:
+			cfg.netDials[network] = net.DialTimeout
+		default:
+			proxy := &socks.Proxy{
+				Addr:         addr,
+				TorIsolation: cfg.TorIsolation && cfg.OnionProxy == "",
 			}
+			cfg.netDials[network] = proxy.DialTimeout
+		}
+	}
+
+	// Once the peers on IPv4 or IPv6 are connected to through a proxy,
+	// their host names are resolved through it as well so that the DNS
+	// requests don't leak to the system resolver.  The proxy is treated as
+	// tor unless the --noonion flag is set or there is an onion-specific
+	// proxy configured, in which case that one resolves them instead.
+	clearnetProxy := cfg.netProxies.proxyOf(netIPv4, cfg.Proxy)
+	if clearnetProxy == "" {
+		clearnetProxy = cfg.netProxies.proxyOf(netIPv6, cfg.Proxy)
+	}
+	switch {
+	case clearnetProxy != "" && cfg.OnionProxy != "":
+		cfg.lookup = func(host string) ([]net.IP, error) {
+			return connmgr.TorLookupIP(host, cfg.OnionProxy)
+		}
+	case clearnetProxy != "" && !cfg.NoOnion:
+		cfg.lookup = func(host string) ([]net.IP, error) {
+			return connmgr.TorLookupIP(host, clearnetProxy)
 		}
 	}
 
@@ -1182,16 +1692,6 @@ func loadConfig() (*config, []string, error) {
 			}
 			return proxy.DialTimeout(network, addr, timeout)
 		}
-
-		// When configured in bridge mode (both --onion and --proxy are
-		// configured), it means that the proxy configured by --proxy is
-		// not a tor proxy, so override the DNS resolution to use the
-		// onion-specific proxy.
-		if cfg.Proxy != "" {
-			cfg.lookup = func(host string) ([]net.IP, error) {
-				return connmgr.TorLookupIP(host, cfg.OnionProxy)
-			}
-		}
 	} else {
 		cfg.oniondial = cfg.dial
 	}
@@ -1206,6 +1706,21 @@ func loadConfig() (*config, []string, error) {
 		cfg.NoAssumeUtreexo = true
 	}
 
+	// Compact state nodes need the utreexo proofs of the blocks, which
+	// aren't in the block files.
+	if len(cfg.ImportBlocks) > 0 && !cfg.NoUtreexo {
+		str := "%s: The importblocks option requires --noutreexo or a utreexo proof index since " +
+			"the block files don't have the utreexo proofs a compact " +
+			"state node needs"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	for i, path := range cfg.ImportBlocks {
+		cfg.ImportBlocks[i] = cleanAndExpandPath(path)
+	}
+
 	// Specifying --noonion means the onion address dial function results in
 	// an error.
 	if cfg.NoOnion {
@@ -1214,6 +1729,125 @@ func loadConfig() (*config, []string, error) {
 		}
 	}
 
+	// Connections to I2P destinations are made over the session of the
+	// destination of the node with the SAM bridge.
+	if cfg.I2PSAM != "" {
+		if _, _, err := net.SplitHostPort(cfg.I2PSAM); err != nil {
+			str := "%s: I2P SAM bridge address '%s' is invalid: %v"
+			err := fmt.Errorf(str, funcName, cfg.I2PSAM, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.i2pSession = newI2PSession(cfg.I2PSAM,
+			filepath.Join(cfg.DataDir, i2pKeyFile),
+			activeNetParams.DefaultPort)
+	}
+
+	// The outbound slots kept for the networks have to fit in the
+	// automatic outbound slots, and the networks have to be reachable.
+	cfg.outboundQuotas, err = parseOutboundQuotas(cfg.OutboundQuotas)
+	if err != nil {
+		str := "%s: Invalid --outboundquota: %v"
+		err := fmt.Errorf(str, funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	maxOutbound := defaultTargetOutbound
+	if cfg.MaxPeers < maxOutbound {
+		maxOutbound = cfg.MaxPeers
+	}
+	if total := cfg.outboundQuotas.total(); total > maxOutbound {
+		str := "%s: The outbound quotas add up to %d slots, more " +
+			"than the %d automatic outbound slots"
+		err := fmt.Errorf(str, funcName, total, maxOutbound)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	onionReachable := !cfg.NoOnion && (cfg.Proxy != "" || cfg.OnionProxy != "")
+	if (cfg.outboundQuotas[netOnion] > 0 && !onionReachable) ||
+		(cfg.outboundQuotas[netI2P] > 0 && cfg.i2pSession == nil) {
+
+		str := "%s: Outbound slots can only be kept for onion peers " +
+			"with --proxy or --onion, and for i2p peers with --i2psam"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --privatebroadcast and --broadcastdelay do not mix.
+	if cfg.PrivateBroadcast && cfg.BroadcastDelay > 0 {
+		str := "%s: The --privatebroadcast and --broadcastdelay " +
+			"options may not be used together"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The locally submitted transactions can only be held back from the
+	// peers that aren't on Tor or I2P when there can be peers that are.
+	if (cfg.PrivateBroadcast || cfg.BroadcastDelay > 0) &&
+		!onionReachable && cfg.i2pSession == nil {
+
+		str := "%s: The --privatebroadcast and --broadcastdelay " +
+			"options require --proxy, --onion or --i2psam"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if len(cfg.ProofServerListeners) > 0 && !cfg.UtreexoProofIndex &&
+		!cfg.FlatUtreexoProofIndex {
+
+		err := fmt.Errorf("%s: the --proofserverlisten option requires "+
+			"the --utreexoproofindex or the --flatutreexoproofindex option",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if len(cfg.ZMQPubUtreexoRoots) > 0 && cfg.NoUtreexo &&
+		!cfg.UtreexoProofIndex && !cfg.FlatUtreexoProofIndex {
+
+		err := fmt.Errorf("%s: the --zmqpubutreexoroots option requires "+
+			"the --noutreexo option off or a utreexo proof index", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if len(cfg.ZMQPubWalletEvent) > 0 && cfg.NoBdkWallet {
+		err := fmt.Errorf("%s: the --zmqpubwalletevent option requires "+
+			"the --nobdkwallet option off", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.UtreexoCFIndex && cfg.NoUtreexo &&
+		!cfg.UtreexoProofIndex && !cfg.FlatUtreexoProofIndex {
+
+		err := fmt.Errorf("%s: the --utreexocfindex option requires "+
+			"the --noutreexo option off or a utreexo proof index", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.ZMQPubHWM <= 0 {
+		err := fmt.Errorf("%s: the --zmqpubhwm option must be positive "+
+			"-- parsed [%d]", funcName, cfg.ZMQPubHWM)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	if cfg.WatchOnlyWallet && cfg.NoUtreexo {
 		err := fmt.Errorf("%s: the --watchonlywallet requires the --noutreexo option off", funcName)
 		fmt.Fprintln(os.Stderr, err)
@@ -1245,6 +1879,60 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	if cfg.NoBdkWallet && cfg.BdkWatchOnly != "" {
+		err := fmt.Errorf("%s: the --bdkwatchonly option requires the --nobdkwallet option off", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.NoBdkWallet && cfg.BdkSigner != "" {
+		err := fmt.Errorf("%s: the --bdksigner option requires the --nobdkwallet option off", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.BdkWatchOnly == "" && cfg.BdkSigner == "" && cfg.BdkWatchOnlyBirthday != 0 {
+		err := fmt.Errorf("%s: the --bdkwatchonlybirthday requires the --bdkwatchonly or the "+
+			"--bdksigner option set at the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.NoBdkWallet && cfg.BdkBackupInterval != 0 {
+		err := fmt.Errorf("%s: the --bdkbackupinterval option requires the --nobdkwallet option off", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.BdkBackupInterval < 0 || cfg.BdkBackupKeep < 0 {
+		err := fmt.Errorf("%s: the --bdkbackupinterval and --bdkbackupkeep options may not be negative", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.BdkBackupInterval != 0 && cfg.BdkBackupPassphrase == "" {
+		err := fmt.Errorf("%s: the --bdkbackupinterval option requires the --bdkbackuppassphrase "+
+			"option set to encrypt the backups with", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.BdkBackupDir != "" {
+		cfg.BdkBackupDir = cleanAndExpandPath(cfg.BdkBackupDir)
+	}
+
+	if cfg.BdkWatchOnlyBirthday < 0 {
+		err := fmt.Errorf("%s: the --bdkwatchonlybirthday option may not be negative", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	if len(cfg.RegisterExtendedPubKeysWithAddrTypeToWatchOnlyWallet) > 0 {
 		cfg.extendedPubkeys = make(map[string]string)
 
@@ -1314,6 +2002,46 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	if cfg.Prune != 0 && cfg.SpendIndex {
+		err := fmt.Errorf("%s: the --prune and --spendindex options may "+
+			"not be activated at the same time. Set --prune=0 to disable pruning.", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.Prune != 0 && cfg.SilentPaymentIndex {
+		err := fmt.Errorf("%s: the --prune and --silentpaymentindex options may "+
+			"not be activated at the same time. Set --prune=0 to disable pruning.", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.Prune != 0 && cfg.BlockTimeIndex {
+		err := fmt.Errorf("%s: the --prune and --blocktimeindex options may "+
+			"not be activated at the same time. Set --prune=0 to disable pruning.", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.Prune != 0 && cfg.ScriptBalanceIndex {
+		err := fmt.Errorf("%s: the --prune and --scriptbalanceindex options may "+
+			"not be activated at the same time. Set --prune=0 to disable pruning.", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.Prune != 0 && cfg.UtreexoCFIndex {
+		err := fmt.Errorf("%s: the --prune and --utreexocfindex options may "+
+			"not be activated at the same time. Set --prune=0 to disable pruning.", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Warn about missing config file only after all other configuration is
 	// done.  This prevents the warning on help messages and invalid
 	// options.  Note this should go directly before the return.
@@ -1374,26 +2102,39 @@ func createDefaultConfigFile(destinationPath string) error {
 // dial function depending on the address and configuration options.  For
 // example, .onion addresses will be dialed using the onion specific proxy if
 // one was specified, but will otherwise use the normal dial function (which
-// could itself use a proxy or not).
+// could itself use a proxy or not).  The IPv4 and IPv6 addresses are dialed
+// using the proxy of their network when --netproxy gives it one.
 func btcdDial(addr net.Addr) (net.Conn, error) {
 	if strings.Contains(addr.String(), ".onion:") {
 		return cfg.oniondial(addr.Network(), addr.String(),
 			defaultConnectTimeout)
 	}
-	return cfg.dial(addr.Network(), addr.String(), defaultConnectTimeout)
+	if strings.Contains(addr.String(), ".i2p:") {
+		if cfg.i2pSession == nil {
+			return nil, errors.New("i2p is not enabled")
+		}
+		return cfg.i2pSession.Dial(addr.String(), defaultConnectTimeout)
+	}
+	dial := cfg.netDials[clearnetOf(addr.String())]
+	return dial(addr.Network(), addr.String(), defaultConnectTimeout)
 }
 
 // btcdLookup resolves the IP of the given host using the correct DNS lookup
 // function depending on the configuration options.  For example, addresses will
-// be resolved using tor when the --proxy flag was specified unless --noonion
-// was also specified in which case the normal system DNS resolver will be used.
+// be resolved using tor when the --proxy flag, or a --netproxy proxy, was
+// specified unless --noonion was also specified in which case the normal system
+// DNS resolver will be used.
 //
-// Any attempt to resolve a tor address (.onion) will return an error since they
-// are not intended to be resolved outside of the tor proxy.
+// Any attempt to resolve a tor address (.onion) or an I2P address (.i2p) will
+// return an error since they are not intended to be resolved outside of the tor
+// proxy and the I2P router.
 func btcdLookup(host string) ([]net.IP, error) {
 	if strings.HasSuffix(host, ".onion") {
 		return nil, fmt.Errorf("attempt to resolve tor address %s", host)
 	}
+	if strings.HasSuffix(host, ".i2p") {
+		return nil, fmt.Errorf("attempt to resolve i2p address %s", host)
+	}
 
 	return cfg.lookup(host)
 }
@@ -20,6 +20,12 @@ const (
 	// seen time.
 	secondsIn3Days int32 = 24 * 60 * 60 * 3
 	secondsIn4Days int32 = 24 * 60 * 60 * 4
+
+	// fallbackSeedServices are the services the seeds that support
+	// filtering are queried for when they don't know of any peers with all
+	// of the requested ones, such as those of utreexo bridge nodes that not
+	// every seed tracks.
+	fallbackSeedServices = wire.SFNodeNetwork | wire.SFNodeUtreexo
 )
 
 // OnSeed is the signature of the callback function which is invoked when DNS
@@ -29,28 +35,51 @@ type OnSeed func(addrs []*wire.NetAddress)
 // LookupFunc is the signature of the DNS lookup function.
 type LookupFunc func(string) ([]net.IP, error)
 
+// seedHost returns the host to look up on the seed for peers with the passed
+// services.  The seeds that support filtering only return the peers that have
+// all of the services of the x-prefixed subdomain.
+func seedHost(seed string, services wire.ServiceFlag) string {
+	if services == wire.SFNodeNetwork {
+		return seed
+	}
+	return fmt.Sprintf("x%x.%s", uint64(services), seed)
+}
+
 // SeedFromDNS uses DNS seeding to populate the address manager with peers.
+//
+// The seeds that support filtering are only asked for the peers with the
+// required services, which the addresses they return are given so that they're
+// connected to ahead of the others.  They're asked for the peers with the
+// fallback services instead when they don't know of any.
 func SeedFromDNS(chainParams *chaincfg.Params, reqServices wire.ServiceFlag,
 	lookupFn LookupFunc, seedFn OnSeed) {
 
 	for _, dnsseed := range chainParams.DNSSeeds {
-		var host string
-
 		// Ignore seeds that don't have filtering the reqServices include utreexo bit.
 		if !dnsseed.HasFiltering && reqServices&wire.SFNodeUtreexo == wire.SFNodeUtreexo {
 			continue
 		}
 
-		if !dnsseed.HasFiltering || reqServices == wire.SFNodeNetwork {
-			host = dnsseed.Host
-		} else {
-			host = fmt.Sprintf("x%x.%s", uint64(reqServices), dnsseed.Host)
-		}
-
-		go func(host string) {
+		go func(dnsseed chaincfg.DNSSeed) {
 			randSource := mrand.New(mrand.NewSource(time.Now().UnixNano()))
 
+			host := dnsseed.Host
+			var services wire.ServiceFlag
+			if dnsseed.HasFiltering {
+				host = seedHost(dnsseed.Host, reqServices)
+				services = reqServices
+			}
 			seedpeers, err := lookupFn(host)
+			fallback := reqServices & fallbackSeedServices
+			if dnsseed.HasFiltering && len(seedpeers) == 0 &&
+				fallback != reqServices {
+
+				log.Debugf("No addresses found from DNS seed %s, "+
+					"querying it for services %v", host, fallback)
+				host = seedHost(dnsseed.Host, fallback)
+				services = fallback
+				seedpeers, err = lookupFn(host)
+			}
 			if err != nil {
 				log.Infof("DNS discovery failed on seed %s: %v", host, err)
 				return
@@ -72,10 +101,10 @@ func SeedFromDNS(chainParams *chaincfg.Params, reqServices wire.ServiceFlag,
 					// and 7 days ago.
 					time.Now().Add(-1*time.Second*time.Duration(secondsIn3Days+
 						randSource.Int31n(secondsIn4Days))),
-					0, peer, uint16(intPort))
+					services, peer, uint16(intPort))
 			}
 
 			seedFn(addresses)
-		}(host)
+		}(dnsseed)
 	}
 }
@@ -0,0 +1,70 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package connmgr
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestSeedFromDNSFiltering ensures that the seeds that support filtering are
+// asked for the peers with the required services, that those they return are
+// given the services, and that the fallback services are asked for when there
+// aren't any.
+func TestSeedFromDNSFiltering(t *testing.T) {
+	params := chaincfg.MainNetParams
+	params.DNSSeeds = []chaincfg.DNSSeed{
+		{Host: "bridges.example.com", HasFiltering: true},
+		{Host: "utreexo.example.com", HasFiltering: true},
+		{Host: "unfiltered.example.com", HasFiltering: false},
+	}
+
+	reqServices := wire.SFNodeNetwork | wire.SFNodeUtreexo |
+		wire.SFNodeUtreexoBridge
+	lookup := func(host string) ([]net.IP, error) {
+		switch host {
+		case "x3000001.bridges.example.com":
+			return []net.IP{net.ParseIP("1.2.3.4")}, nil
+		case "x1000001.utreexo.example.com":
+			return []net.IP{net.ParseIP("5.6.7.8")}, nil
+		case "unfiltered.example.com":
+			t.Errorf("unexpected lookup of the unfiltered seed")
+		}
+		return nil, nil
+	}
+
+	seeded := make(chan *wire.NetAddress)
+	SeedFromDNS(&params, reqServices, lookup, func(addrs []*wire.NetAddress) {
+		for _, addr := range addrs {
+			seeded <- addr
+		}
+	})
+
+	want := map[string]wire.ServiceFlag{
+		"1.2.3.4": reqServices,
+		"5.6.7.8": wire.SFNodeNetwork | wire.SFNodeUtreexo,
+	}
+	for len(want) != 0 {
+		select {
+		case addr := <-seeded:
+			services, ok := want[addr.IP.String()]
+			if !ok {
+				t.Fatalf("unexpected address %v", addr.IP)
+			}
+			if addr.Services != services {
+				t.Fatalf("%v: got services %v, want %v", addr.IP,
+					addr.Services, services)
+			}
+			delete(want, addr.IP.String())
+
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for the addresses %v", want)
+		}
+	}
+}
@@ -58,7 +58,7 @@ func (cmd *blockRegionCmd) Execute(args []string) error {
 	}
 
 	// Load the block database.
-	db, err := loadBlockDB()
+	db, err := loadBlockDBReadOnly()
 	if err != nil {
 		return err
 	}
@@ -31,7 +31,7 @@ func (cmd *headersCmd) Execute(args []string) error {
 	}
 
 	// Load the block database.
-	db, err := loadBlockDB()
+	db, err := loadBlockDBReadOnly()
 	if err != nil {
 		return err
 	}
@@ -56,6 +56,23 @@ func loadBlockDB() (database.DB, error) {
 	return db, nil
 }
 
+// loadBlockDBReadOnly opens the block database read-only, which works while it's
+// in use by a running node, and returns a handle to it.
+func loadBlockDBReadOnly() (database.DB, error) {
+	// The database name is based on the database type.
+	dbName := blockDbNamePrefix + "_" + cfg.DbType
+	dbPath := filepath.Join(cfg.DataDir, dbName)
+
+	log.Infof("Loading block database from '%s' read-only", dbPath)
+	db, err := database.OpenReadOnly(cfg.DbType, dbPath, activeNetParams.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Block database loaded")
+	return db, nil
+}
+
 // realMain is the real main function for the utility.  It is necessary to work
 // around the fact that deferred functions do not run when os.Exit() is called.
 func realMain() error {
@@ -34,6 +34,12 @@ obtained via the Create and Open functions which take a database type string
 that identifies the specific database driver (backend) to use as well as
 arguments specific to the specified driver.
 
+Drivers that support it can also open a database read-only with the
+OpenReadOnly function.  A database that is opened read-only may be in use by
+another process, such as a running node, and reads a consistent view of the
+database as of the time it was opened.  All attempts to write to it fail with
+ErrDbReadOnly.
+
 The interface provides facilities for obtaining transactions (the Tx interface)
 that are the basis of all database reads and writes.  Unlike some database
 interfaces that support reading and writing without transactions, this interface
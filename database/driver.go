@@ -27,6 +27,14 @@ type Driver struct {
 	// ErrDbDoesNotExist if the database has not already been created.
 	Open func(args ...interface{}) (DB, error)
 
+	// OpenReadOnly is the function that will be invoked with all
+	// user-specified arguments to open the database read-only.  It must
+	// not take any lock that would keep the database from being opened
+	// for writing by another process, nor modify the database in any way.
+	// It is optional, and drivers that leave it nil don't support
+	// read-only opens.
+	OpenReadOnly func(args ...interface{}) (DB, error)
+
 	// UseLogger uses a specified Logger to output package logging info.
 	UseLogger func(logger btclog.Logger)
 }
@@ -87,3 +95,26 @@ func Open(dbType string, args ...interface{}) (DB, error) {
 
 	return drv.Open(args...)
 }
+
+// OpenReadOnly opens an existing database for the specified type for reading
+// only.  Unlike Open, the database may be in use by another process, such as a
+// running node, for the whole time it is open.  The arguments are specific to
+// the database type driver.  See the documentation for the database driver for
+// further details.
+//
+// ErrDbUnknownType will be returned if the the database type is not registered
+// and ErrDbReadOnly if the driver doesn't support read-only opens.
+func OpenReadOnly(dbType string, args ...interface{}) (DB, error) {
+	drv, exists := drivers[dbType]
+	if !exists {
+		str := fmt.Sprintf("driver %q is not registered", dbType)
+		return nil, makeError(ErrDbUnknownType, str, nil)
+	}
+	if drv.OpenReadOnly == nil {
+		str := fmt.Sprintf("driver %q does not support read-only opens",
+			dbType)
+		return nil, makeError(ErrDbReadOnly, str, nil)
+	}
+
+	return drv.OpenReadOnly(args...)
+}
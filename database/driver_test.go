@@ -133,4 +133,31 @@ func TestCreateOpenUnsupported(t *testing.T) {
 	if !checkDbError(t, testName, err, database.ErrDbUnknownType) {
 		return
 	}
+
+	// Ensure opening a database read-only with an unsupported type fails
+	// with the expected error.
+	testName = "read-only open with unsupported database type"
+	_, err = database.OpenReadOnly(dbType)
+	if !checkDbError(t, testName, err, database.ErrDbUnknownType) {
+		return
+	}
+}
+
+// TestOpenReadOnlyUnsupported ensures that opening a database read-only with a
+// driver that doesn't support read-only opens is handled properly.
+func TestOpenReadOnlyUnsupported(t *testing.T) {
+	dbType := "noreadonly"
+	bogusCreateDB := func(args ...interface{}) (database.DB, error) {
+		return nil, fmt.Errorf("unexpected open for database type "+
+			"[%v]", dbType)
+	}
+	database.RegisterDriver(database.Driver{
+		DbType: dbType,
+		Create: bogusCreateDB,
+		Open:   bogusCreateDB,
+	})
+
+	testName := "read-only open without driver support"
+	_, err := database.OpenReadOnly(dbType)
+	checkDbError(t, testName, err, database.ErrDbReadOnly)
 }
@@ -43,6 +43,11 @@ const (
 	// is already open.
 	ErrDbAlreadyOpen
 
+	// ErrDbReadOnly indicates a write was attempted on a database that
+	// was opened read-only, or that a read-only open was requested from a
+	// driver that doesn't support it.
+	ErrDbReadOnly
+
 	// ErrInvalid indicates the specified database is not valid.
 	ErrInvalid
 
@@ -143,6 +148,7 @@ var errorCodeStrings = map[ErrorCode]string{
 	ErrDbExists:             "ErrDbExists",
 	ErrDbNotOpen:            "ErrDbNotOpen",
 	ErrDbAlreadyOpen:        "ErrDbAlreadyOpen",
+	ErrDbReadOnly:           "ErrDbReadOnly",
 	ErrInvalid:              "ErrInvalid",
 	ErrCorruption:           "ErrCorruption",
 	ErrTxClosed:             "ErrTxClosed",
@@ -23,6 +23,7 @@ func TestErrorCodeStringer(t *testing.T) {
 		{database.ErrDbExists, "ErrDbExists"},
 		{database.ErrDbNotOpen, "ErrDbNotOpen"},
 		{database.ErrDbAlreadyOpen, "ErrDbAlreadyOpen"},
+		{database.ErrDbReadOnly, "ErrDbReadOnly"},
 		{database.ErrInvalid, "ErrInvalid"},
 		{database.ErrCorruption, "ErrCorruption"},
 		{database.ErrTxClosed, "ErrTxClosed"},
@@ -5,6 +5,8 @@
 package ffldb
 
 import (
+	"encoding/binary"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -95,3 +97,115 @@ func BenchmarkBlock(b *testing.B) {
 	// Don't benchmark teardown.
 	b.StopTimer()
 }
+
+// benchDbTypes are the database types the metadata benchmarks are run against.
+var benchDbTypes = []string{dbType, pebbleDbType}
+
+// BenchmarkMetadataWrite benchmarks how long it takes to commit transactions of
+// 100 metadata updates each when every commit is written through to the
+// metadata store of each of the database types.
+func BenchmarkMetadataWrite(b *testing.B) {
+	for _, dbType := range benchDbTypes {
+		dbType := dbType
+		b.Run(dbType, func(b *testing.B) {
+			dbPath := b.TempDir()
+			idb, err := database.Create(dbType, dbPath, blockDataNet)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer idb.Close()
+			idb.(*db).cache.maxSize = 0
+
+			bucketKey := []byte("benchbucket")
+			err = idb.Update(func(tx database.Tx) error {
+				_, err := tx.Metadata().CreateBucket(bucketKey)
+				return err
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			var key [8]byte
+			value := make([]byte, 64)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := idb.Update(func(tx database.Tx) error {
+					bucket := tx.Metadata().Bucket(bucketKey)
+					for j := 0; j < 100; j++ {
+						binary.BigEndian.PutUint64(key[:],
+							uint64(i*100+j))
+						if err := bucket.Put(key[:], value); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			// Don't benchmark teardown.
+			b.StopTimer()
+		})
+	}
+}
+
+// BenchmarkMetadataRead benchmarks how long it takes to look up the keys of a
+// bucket that was flushed to the metadata store of each of the database types.
+func BenchmarkMetadataRead(b *testing.B) {
+	const numKeys = 10000
+	for _, dbType := range benchDbTypes {
+		dbType := dbType
+		b.Run(dbType, func(b *testing.B) {
+			dbPath := b.TempDir()
+			idb, err := database.Create(dbType, dbPath, blockDataNet)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer idb.Close()
+			idb.(*db).cache.maxSize = 0
+
+			bucketKey := []byte("benchbucket")
+			value := make([]byte, 64)
+			err = idb.Update(func(tx database.Tx) error {
+				bucket, err := tx.Metadata().CreateBucket(bucketKey)
+				if err != nil {
+					return err
+				}
+				var key [8]byte
+				for i := 0; i < numKeys; i++ {
+					binary.BigEndian.PutUint64(key[:], uint64(i))
+					if err := bucket.Put(key[:], value); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			err = idb.View(func(tx database.Tx) error {
+				bucket := tx.Metadata().Bucket(bucketKey)
+				var key [8]byte
+				for i := 0; i < b.N; i++ {
+					binary.BigEndian.PutUint64(key[:], uint64(i%numKeys))
+					if bucket.Get(key[:]) == nil {
+						return fmt.Errorf("key %d not found", i%numKeys)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			// Don't benchmark teardown.
+			b.StopTimer()
+		})
+	}
+}
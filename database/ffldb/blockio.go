@@ -48,11 +48,6 @@ const (
 	// The name to be used for spend journals.
 	spendJournalFilenameTemplate = "%09d-undo.fdb"
 
-	// maxOpenFiles is the max number of open files to maintain in the
-	// open blocks cache.  Note that this does not include the current
-	// write file, so there will typically be one more than this value open.
-	maxOpenFiles = 25
-
 	// maxBlockFileSize is the maximum size for each file used to store
 	// blocks.
 	//
@@ -113,6 +108,13 @@ type writeCursor struct {
 	// curOffset is the offset in the current write block file where the
 	// next new block will be written.
 	curOffset uint32
+
+	// pending is the data that's been written to the current write block
+	// file at pendingOffset but that's still in memory.  It's written out
+	// by writePending, which is done before the transaction that wrote it
+	// is committed, so readers never need to look at it.
+	pending       []byte
+	pendingOffset uint32
 }
 
 // blockStore houses information used to handle reading and writing blocks (and
@@ -130,6 +132,15 @@ type blockStore struct {
 	// override the value.
 	maxBlockFileSize uint32
 
+	// maxOpenFiles is the max number of open files to maintain in the
+	// open blocks cache.  Note that this does not include the current
+	// write file, so there will typically be one more than this value open.
+	maxOpenFiles int
+
+	// writeBufferSize is the size of the data that's collected in memory
+	// before it's written to the current write file.
+	writeBufferSize int
+
 	// The following fields are related to the flat files which hold the
 	// actual blocks.   The number of open files is limited by maxOpenFiles.
 	//
@@ -300,7 +311,7 @@ func (s *blockStore) openFile(fileNum uint32) (*lockableFile, error) {
 	// therefore should be closed last.
 	s.lruMutex.Lock()
 	lruList := s.openBlocksLRU
-	if lruList.Len() >= maxOpenFiles {
+	if lruList.Len() >= s.maxOpenFiles {
 		lruFileNum := lruList.Remove(lruList.Back()).(uint32)
 		oldBlockFile := s.openBlockFiles[lruFileNum]
 
@@ -356,6 +367,39 @@ func (s *blockStore) fileStartEndNum() (uint32, uint32, error) {
 	return uint32(first), uint32(last), nil
 }
 
+// backup copies the flat files of the store up to the write cursor to the
+// passed directory.  The files before the current write file are never written
+// to again, so they're hard-linked when possible, while the current write file
+// is copied since it's still appended to.  The files that were deleted by
+// pruning are skipped.  It must not be called while data is being written.
+func (s *blockStore) backup(dir string) error {
+	wc := s.writeCursor
+	wc.RLock()
+	curFileNum, curOffset := wc.curFileNum, wc.curOffset
+	wc.RUnlock()
+
+	for fileNum := uint32(0); fileNum <= curFileNum; fileNum++ {
+		src := s.filePathFunc(s.basePath, fileNum)
+		dst := s.filePathFunc(dir, fileNum)
+		if !fileExists(src) {
+			continue
+		}
+
+		var err error
+		if fileNum < curFileNum {
+			err = linkOrCopyFile(dst, src)
+		} else {
+			err = copyFile(dst, src, int64(curOffset))
+		}
+		if err != nil {
+			str := fmt.Sprintf("failed to back up file %d", fileNum)
+			return makeDbErr(database.ErrDriverSpecific, str, err)
+		}
+	}
+
+	return nil
+}
+
 // blockFile attempts to return an existing file handle for the passed flat file
 // number if it is already open as well as marking it as most recently used.  It
 // will also open the file when it's not already open subject to the rules
@@ -415,8 +459,11 @@ func (s *blockStore) blockFile(fileNum uint32) (*lockableFile, error) {
 
 // writeData is a helper function for writeBlock which writes the provided data
 // at the current write offset and updates the write cursor accordingly.  The
-// field name parameter is only used when there is an error to provide a nicer
-// error message.
+// data is collected in memory with the data written before it and it's written
+// to the file with it once there's at least writeBufferSize of it, which saves
+// the system calls of writing the fields of each block on their own.  The field
+// name parameter is only used when there is an error to provide a nicer error
+// message.
 //
 // The write cursor will be advanced the number of bytes actually written in the
 // event of failure.
@@ -426,18 +473,63 @@ func (s *blockStore) blockFile(fileNum uint32) (*lockableFile, error) {
 // locked for writes.  Also, the write cursor current file must NOT be nil.
 func (s *blockStore) writeData(data []byte, fieldName string) error {
 	wc := s.writeCursor
-	n, err := wc.curFile.file.WriteAt(data, int64(wc.curOffset))
-	wc.curOffset += uint32(n)
-	if err != nil {
-		str := fmt.Sprintf("failed to write %s to file %d at "+
-			"offset %d: %v", fieldName, wc.curFileNum,
-			wc.curOffset-uint32(n), err)
+	if len(wc.pending) == 0 {
+		wc.pendingOffset = wc.curOffset
+	}
+	wc.pending = append(wc.pending, data...)
+	wc.curOffset += uint32(len(data))
+	if len(wc.pending) < s.writeBufferSize {
+		return nil
+	}
+
+	if err := s.writePending(); err != nil {
+		str := fmt.Sprintf("failed to write %s: %v", fieldName, err)
 		return makeDbErr(database.ErrDriverSpecific, str, err)
 	}
+	return nil
+}
+
+// writePending writes the data that writeData has collected in memory to the
+// current write file.  The write cursor is moved back to the end of the data
+// that was actually written in the event of failure.
+//
+// NOTE: This function MUST be called with the write cursor current file lock
+// held and must only be called during a write transaction so it is effectively
+// locked for writes.
+func (s *blockStore) writePending() error {
+	wc := s.writeCursor
+	if len(wc.pending) == 0 {
+		return nil
+	}
+
+	n, err := wc.curFile.file.WriteAt(wc.pending, int64(wc.pendingOffset))
+	wc.pending = wc.pending[:0]
+	if err != nil {
+		wc.curOffset = wc.pendingOffset + uint32(n)
+		return fmt.Errorf("failed to write to file %d at offset %d: %v",
+			wc.curFileNum, wc.pendingOffset, err)
+	}
 
 	return nil
 }
 
+// flushWrites writes the data that's been written to the store but that's still
+// in memory to the current write file.  It must be called before the metadata
+// that refers to the data is committed so that readers find it in the file.
+//
+// This function must only be called during a write transaction so it is
+// effectively locked for writes.
+func (s *blockStore) flushWrites() error {
+	wc := s.writeCursor
+	wc.curFile.Lock()
+	defer wc.curFile.Unlock()
+
+	if err := s.writePending(); err != nil {
+		return makeDbErr(database.ErrDriverSpecific, err.Error(), err)
+	}
+	return nil
+}
+
 // writeBlock appends the specified raw block bytes to the store's write cursor
 // location and increments it accordingly.  When the block would exceed the max
 // file size for the current flat file, this function will close the current
@@ -470,6 +562,12 @@ func (s *blockStore) writeBlock(rawBlock []byte, blockLoc *blockLocation) (block
 	wc := s.writeCursor
 	finalOffset := wc.curOffset + fullLen
 	if finalOffset < wc.curOffset || finalOffset > s.maxBlockFileSize {
+		// Write out the data that's still in memory before the file
+		// it's for is closed.
+		if err := s.flushWrites(); err != nil {
+			return blockLocation{}, err
+		}
+
 		// This is done under the write cursor lock since the curFileNum
 		// field is accessed elsewhere by readers.
 		//
@@ -490,6 +588,10 @@ func (s *blockStore) writeBlock(rawBlock []byte, blockLoc *blockLocation) (block
 		wc.curOffset = 0
 		wc.Unlock()
 	} else if blockLoc != nil {
+		if err := s.flushWrites(); err != nil {
+			return blockLocation{}, err
+		}
+
 		// This is done under the write cursor lock since the curFileNum
 		// field is accessed elsewhere by readers.
 		//
@@ -732,6 +834,12 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 	wc.Lock()
 	defer wc.Unlock()
 
+	// The data that's still in memory was never written to the file, so
+	// it's dropped.
+	wc.curFile.Lock()
+	wc.pending = wc.pending[:0]
+	wc.curFile.Unlock()
+
 	// Nothing to do if the rollback point is the same as the current write
 	// cursor.
 	if wc.curFileNum == oldBlockFileNum && wc.curOffset == oldBlockOffset {
@@ -874,8 +982,10 @@ func scanBlockFiles(dbPath string,
 }
 
 // newBlockStore returns a new block store with the current block file number
-// and offset set and all fields initialized.
-func newBlockStore(basePath string, network wire.BitcoinNet) (*blockStore, error) {
+// and offset set and all fields initialized from the passed options.
+func newBlockStore(basePath string, network wire.BitcoinNet,
+	opts Options) (*blockStore, error) {
+
 	// Look for the end of the latest block to file to determine what the
 	// write cursor position is from the viewpoing of the block files on
 	// disk.
@@ -892,6 +1002,8 @@ func newBlockStore(basePath string, network wire.BitcoinNet) (*blockStore, error
 		network:          network,
 		basePath:         basePath,
 		maxBlockFileSize: maxBlockFileSize,
+		maxOpenFiles:     opts.MaxOpenBlockFiles,
+		writeBufferSize:  opts.FileWriteBufferSize,
 		openBlockFiles:   make(map[uint32]*lockableFile),
 		openBlocksLRU:    list.New(),
 		fileNumToLRUElem: make(map[uint32]*list.Element),
@@ -912,8 +1024,10 @@ func newBlockStore(basePath string, network wire.BitcoinNet) (*blockStore, error
 }
 
 // newSJStore returns a new spend journal store with the current spend journal file
-// number and offset set and all fields initialized.
-func newSJStore(basePath string, network wire.BitcoinNet) (*blockStore, error) {
+// number and offset set and all fields initialized from the passed options.
+func newSJStore(basePath string, network wire.BitcoinNet,
+	opts Options) (*blockStore, error) {
+
 	// Look for the end of the latest block to file to determine what the
 	// write cursor position is from the viewpoing of the block files on
 	// disk.
@@ -930,6 +1044,8 @@ func newSJStore(basePath string, network wire.BitcoinNet) (*blockStore, error) {
 		network:          network,
 		basePath:         basePath,
 		maxBlockFileSize: maxBlockFileSize,
+		maxOpenFiles:     opts.MaxOpenBlockFiles,
+		writeBufferSize:  opts.FileWriteBufferSize,
 		openBlockFiles:   make(map[uint32]*lockableFile),
 		openBlocksLRU:    list.New(),
 		fileNumToLRUElem: make(map[uint32]*list.Element),
@@ -7,6 +7,7 @@ package ffldb
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"os"
@@ -15,12 +16,11 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/cockroachdb/pebble"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/comparer"
 	ldberrors "github.com/syndtr/goleveldb/leveldb/errors"
-	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
-	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
@@ -102,6 +102,10 @@ const (
 	// error code.
 	errDbNotOpenStr = "database is not open"
 
+	// errDbReadOnlyStr is the text to use for the database.ErrDbReadOnly
+	// error code.
+	errDbReadOnlyStr = "database is open read-only"
+
 	// errTxClosedStr is the text to use for the database.ErrTxClosed error
 	// code.
 	errTxClosedStr = "database tx is closed"
@@ -151,9 +155,9 @@ func makeDbErr(c database.ErrorCode, desc string, err error) database.Error {
 	return database.Error{ErrorCode: c, Description: desc, Err: err}
 }
 
-// convertErr converts the passed leveldb error into a database error with an
-// equivalent error code  and the passed description.  It also sets the passed
-// error as the underlying error.
+// convertErr converts the passed leveldb or Pebble error into a database error
+// with an equivalent error code  and the passed description.  It also sets the
+// passed error as the underlying error.
 func convertErr(desc string, ldbErr error) database.Error {
 	// Use the driver-specific error code by default.  The code below will
 	// update this with the converted error if it's recognized.
@@ -163,10 +167,14 @@ func convertErr(desc string, ldbErr error) database.Error {
 	// Database corruption errors.
 	case ldberrors.IsCorrupted(ldbErr):
 		code = database.ErrCorruption
+	case errors.Is(ldbErr, pebble.ErrCorruption):
+		code = database.ErrCorruption
 
 	// Database open/create errors.
 	case ldbErr == leveldb.ErrClosed:
 		code = database.ErrDbNotOpen
+	case errors.Is(ldbErr, pebble.ErrClosed):
+		code = database.ErrDbNotOpen
 
 	// Transaction errors.
 	case ldbErr == leveldb.ErrSnapshotReleased:
@@ -1797,6 +1805,17 @@ func (tx *transaction) writePendingAndCommit() error {
 		}
 	}
 
+	// Write out the data that's still in memory before the metadata that
+	// refers to it is committed.
+	if err := tx.db.blkStore.flushWrites(); err != nil {
+		rollback()
+		return err
+	}
+	if err := tx.db.sjStore.flushWrites(); err != nil {
+		rollback()
+		return err
+	}
+
 	// Update the metadata for the current write block file and offset.
 	blkWriteRow := serializeWriteRow(blkWC.curFileNum, blkWC.curOffset)
 	if err := tx.metaBucket.Put(blkWriteLocKeyName, blkWriteRow); err != nil {
@@ -1978,7 +1997,7 @@ func (tx *transaction) PruneBlocks(targetSize uint64, keepHeight int32) (int32,
 		// If the last height in this file is equal or greater than the
 		// keep height, keep this file but keep looking for other files to
 		// delete.
-		if lastHeight >= keepHeight && keepHeight > 0 {
+		if lastHeight >= keepHeight && keepHeight >= 0 {
 			// Since we're going to keep this file, update the earliest height
 			// if it's earlier than our current one.
 			if firstHeight < earliestHeight {
@@ -2119,7 +2138,14 @@ type db struct {
 	closed    bool         // Is the database closed?
 	blkStore  *blockStore  // Handles read/writing blocks to flat files.
 	sjStore   *blockStore  // Handles read/writing spend journals to flat files.
-	cache     *dbCache     // Cache layer which wraps underlying leveldb DB.
+	cache     *dbCache     // Cache layer which wraps underlying metadata store.
+	dbType    string       // Driver type the database was opened with.
+
+	// readOnly is set when the database was opened read-only, in which
+	// case the metadata is read from the copy at checkpointDir, which is
+	// removed when the database is closed.
+	readOnly      bool
+	checkpointDir string
 }
 
 // Enforce db implements the database.DB interface.
@@ -2130,7 +2156,7 @@ var _ database.DB = (*db)(nil)
 //
 // This function is part of the database.DB interface implementation.
 func (db *db) Type() string {
-	return dbType
+	return db.dbType
 }
 
 // begin is the implementation function for the Begin database method.  See its
@@ -2140,6 +2166,11 @@ func (db *db) Type() string {
 // which is used by the managed transaction code while the database method
 // returns the interface.
 func (db *db) begin(writable bool) (*transaction, error) {
+	if writable && db.readOnly {
+		return nil, makeDbErr(database.ErrDbReadOnly, errDbReadOnlyStr,
+			nil)
+	}
+
 	// Whenever a new writable transaction is started, grab the write lock
 	// to ensure only a single write transaction can be active at the same
 	// time.  This lock will not be released until the transaction is
@@ -2288,6 +2319,134 @@ func (db *db) Update(fn func(database.Tx) error) error {
 	return tx.Commit()
 }
 
+// compactionRanges returns the ranges of keys the metadata is compacted in,
+// which split the keys at the start of each bucket so that the progress of a
+// compaction can be followed bucket by bucket.  The ranges cover all of the
+// keys, including those of the buckets that were deleted.
+func (db *db) compactionRanges() ([]util.Range, error) {
+	snapshot, err := db.cache.store.Snapshot()
+	if err != nil {
+		return nil, convertErr("failed to open metadata snapshot", err)
+	}
+	defer snapshot.Release()
+
+	// The bucket index maps the names of the buckets to their IDs, which
+	// prefix the keys of the buckets.
+	bucketIDs := [][]byte{metadataBucketID[:]}
+	iter := snapshot.NewIterator(util.BytesPrefix(bucketIndexPrefix))
+	for iter.Next() {
+		if len(iter.Value()) == 4 {
+			bucketIDs = append(bucketIDs, copySlice(iter.Value()))
+		}
+	}
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return nil, convertErr("failed to read bucket index", err)
+	}
+	sort.Slice(bucketIDs, func(i, j int) bool {
+		return bytes.Compare(bucketIDs[i], bucketIDs[j]) < 0
+	})
+
+	ranges := make([]util.Range, 0, len(bucketIDs)+1)
+	var start []byte
+	for _, bucketID := range bucketIDs {
+		ranges = append(ranges, util.Range{Start: start, Limit: bucketID})
+		start = bucketID
+	}
+	ranges = append(ranges, util.Range{Start: start})
+	return ranges, nil
+}
+
+// Compact compacts the metadata storage one range of keys at a time while the
+// database stays in use.  The cache is flushed first so that the keys removed
+// in it are compacted away too.
+//
+// This function is part of the database.DB interface implementation.
+func (db *db) Compact(interrupt <-chan struct{}, progress func(done, total int)) error {
+	if db.readOnly {
+		return makeDbErr(database.ErrDbReadOnly, errDbReadOnlyStr, nil)
+	}
+
+	// Closing the database waits for the compaction to finish.
+	db.closeLock.RLock()
+	defer db.closeLock.RUnlock()
+	if db.closed {
+		return makeDbErr(database.ErrDbNotOpen, errDbNotOpenStr, nil)
+	}
+
+	db.writeLock.Lock()
+	err := db.cache.flush()
+	db.writeLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	ranges, err := db.compactionRanges()
+	if err != nil {
+		return err
+	}
+	for i, slice := range ranges {
+		select {
+		case <-interrupt:
+			return nil
+		default:
+		}
+
+		if err := db.cache.store.Compact(slice); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(i+1, len(ranges))
+		}
+	}
+	return nil
+}
+
+// Backup writes a copy of the database to the passed directory, which must not
+// exist, while the database stays open.  Write transactions wait for the copy
+// to be made, which is after the cache is flushed, the metadata store is
+// checkpointed, and the block and spend journal files are hard-linked or
+// copied, so the copy has all of the transactions that were committed before
+// it was called.
+//
+// This function is part of the database.DB interface implementation.
+func (db *db) Backup(dir string) error {
+	// Closing the database waits for the backup to finish.
+	db.closeLock.RLock()
+	defer db.closeLock.RUnlock()
+	if db.closed {
+		return makeDbErr(database.ErrDbNotOpen, errDbNotOpenStr, nil)
+	}
+
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+	if err := db.cache.flush(); err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(dir, 0700); err != nil {
+		str := fmt.Sprintf("unable to create the backup directory %q", dir)
+		return makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+	err := db.cache.store.Checkpoint(filepath.Join(dir, metadataDbName))
+	if err != nil {
+		os.RemoveAll(dir)
+		str := fmt.Sprintf("unable to back up the metadata to %q", dir)
+		return makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+	if err := db.blkStore.backup(dir); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	if err := db.sjStore.backup(dir); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	return nil
+}
+
 // Close cleanly shuts down the database and syncs all data.  It will block
 // until all database transactions have been finalized (rolled back or
 // committed).
@@ -2341,6 +2500,14 @@ func (db *db) Close() error {
 	db.sjStore.openBlocksLRU.Init()
 	db.sjStore.fileNumToLRUElem = nil
 
+	// Remove the copy of the metadata a read-only database was opened
+	// from.
+	if db.checkpointDir != "" {
+		if err := os.RemoveAll(db.checkpointDir); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+
 	return closeErr
 }
 
@@ -2356,48 +2523,62 @@ func fileExists(name string) bool {
 
 // initDB creates the initial buckets and values used by the package.  This is
 // mainly in a separate function for testing purposes.
-func initDB(ldb *leveldb.DB) error {
-	// The starting block file write cursor location is file num 0, offset
-	// 0.
-	batch := new(leveldb.Batch)
-	batch.Put(bucketizedKey(metadataBucketID, blkWriteLocKeyName),
-		serializeWriteRow(0, 0))
-
-	batch.Put(bucketizedKey(metadataBucketID, sjWriteLocKeyName),
-		serializeWriteRow(0, 0))
-
-	// Create block index bucket and set the current bucket id.
-	//
-	// NOTE: Since buckets are virtualized through the use of prefixes,
-	// there is no need to store the bucket index data for the metadata
-	// bucket in the database.  However, the first bucket ID to use does
-	// need to account for it to ensure there are no key collisions.
-	batch.Put(bucketIndexKey(metadataBucketID, blockIdxBucketName),
-		blockIdxBucketID[:])
-	batch.Put(curBucketIDKeyName, blockIdxBucketID[:])
-
-	batch.Put(bucketIndexKey(metadataBucketID, sjIdxBucketName),
-		sjIdxBucketID[:])
-	batch.Put(curBucketIDKeyName, sjIdxBucketID[:])
-
-	// Create a last block height bucket and set the current bucket id.
-	batch.Put(bucketIndexKey(metadataBucketID, blockHeightKeyName),
-		blockHeightBucketID[:])
-	batch.Put(curBucketIDKeyName, blockHeightBucketID[:])
-
+func initDB(store metadataStore) error {
 	// Write everything as a single batch.
-	if err := ldb.Write(batch, nil); err != nil {
-		str := fmt.Sprintf("failed to initialize metadata database: %v",
-			err)
-		return convertErr(str, err)
+	err := store.Write(func(batch metadataBatch) error {
+		// The starting block file write cursor location is file num
+		// 0, offset 0.
+		puts := [][2][]byte{
+			{bucketizedKey(metadataBucketID, blkWriteLocKeyName),
+				serializeWriteRow(0, 0)},
+			{bucketizedKey(metadataBucketID, sjWriteLocKeyName),
+				serializeWriteRow(0, 0)},
+
+			// Create block index bucket and set the current bucket
+			// id.
+			//
+			// NOTE: Since buckets are virtualized through the use
+			// of prefixes, there is no need to store the bucket
+			// index data for the metadata bucket in the database.
+			// However, the first bucket ID to use does need to
+			// account for it to ensure there are no key collisions.
+			{bucketIndexKey(metadataBucketID, blockIdxBucketName),
+				blockIdxBucketID[:]},
+			{curBucketIDKeyName, blockIdxBucketID[:]},
+
+			{bucketIndexKey(metadataBucketID, sjIdxBucketName),
+				sjIdxBucketID[:]},
+			{curBucketIDKeyName, sjIdxBucketID[:]},
+
+			// Create a last block height bucket and set the
+			// current bucket id.
+			{bucketIndexKey(metadataBucketID, blockHeightKeyName),
+				blockHeightBucketID[:]},
+			{curBucketIDKeyName, blockHeightBucketID[:]},
+		}
+		for _, put := range puts {
+			if err := batch.Put(put[0], put[1]); err != nil {
+				str := fmt.Sprintf("failed to initialize "+
+					"metadata database: %v", err)
+				return convertErr(str, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// openDB opens the database at the provided path.  database.ErrDbDoesNotExist
-// is returned if the database doesn't exist and the create flag is not set.
-func openDB(dbPath string, network wire.BitcoinNet, create bool) (database.DB, error) {
+// openDB opens the database at the provided path with its metadata in the store
+// of the passed driver type and with the passed options.
+// database.ErrDbDoesNotExist is returned if the database doesn't exist and the
+// create flag is not set.
+func openDB(dbPath string, network wire.BitcoinNet, create bool,
+	dbType string, opts Options) (database.DB, error) {
+
 	// Error if the database doesn't exist and the create flag is not set.
 	metadataDbPath := filepath.Join(dbPath, metadataDbName)
 	dbExists := fileExists(metadataDbPath)
@@ -2408,35 +2589,37 @@ func openDB(dbPath string, network wire.BitcoinNet, create bool) (database.DB, e
 
 	// Ensure the full path to the database exists.
 	if !dbExists {
-		// The error can be ignored here since the call to
-		// leveldb.OpenFile will fail if the directory couldn't be
-		// created.
+		// The error can be ignored here since opening the metadata
+		// store will fail if the directory couldn't be created.
 		_ = os.MkdirAll(dbPath, 0700)
 	}
 
 	// Open the metadata database (will create it if needed).
-	opts := opt.Options{
-		ErrorIfExist: create,
-		Strict:       opt.DefaultStrict,
-		Compression:  opt.NoCompression,
-		Filter:       filter.NewBloomFilter(10),
-	}
-	ldb, err := leveldb.OpenFile(metadataDbPath, &opts)
+	openStore := openLdbStore
+	if dbType == pebbleDbType {
+		openStore = openPebbleStore
+	}
+	log.Debugf("Opening the database with a %d MiB cache, a %d MiB "+
+		"write buffer and a %d MiB block cache", opts.CacheSize>>20,
+		opts.WriteBufferSize>>20, opts.BlockCacheSize>>20)
+	store, err := openStore(metadataDbPath, create, opts)
 	if err != nil {
-		return nil, convertErr(err.Error(), err)
+		return nil, err
 	}
 
-	blkStore, err := newBlockStore(dbPath, network)
+	blkStore, err := newBlockStore(dbPath, network, opts)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't make a new block store. Err: %v", err)
 	}
-	sjStore, err := newSJStore(dbPath, network)
+	sjStore, err := newSJStore(dbPath, network, opts)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't make a new spend journal store. Err: %v", err)
 	}
 
-	cache := newDbCache(ldb, blkStore, sjStore, defaultCacheSize, defaultFlushSecs)
-	pdb := &db{blkStore: blkStore, sjStore: sjStore, cache: cache}
+	cache := newDbCache(store, blkStore, sjStore, uint64(opts.CacheSize),
+		defaultFlushSecs)
+	pdb := &db{blkStore: blkStore, sjStore: sjStore, cache: cache,
+		dbType: dbType}
 
 	// Perform any reconciliation needed between the block and metadata as
 	// well as database initialization, if needed.
@@ -25,5 +25,48 @@ database path as a string and the block network:
 	if err != nil {
 		// Handle error
 	}
+
+# Read-only
+
+The OpenReadOnly function takes the same parameters and opens a database that
+may be in use by a running node, such as to read its blocks from another
+process.  The metadata store is locked by the node, so it's copied to a
+temporary directory that's removed when the database is closed, and the tables
+of the store are hard-linked rather than copied when possible.  The database
+sees the data as of when it was opened.
+
+	db, err := database.OpenReadOnly("ffldb", "path/to/database", wire.MainNet)
+	if err != nil {
+		// Handle error
+	}
+
+# Pebble
+
+The package also provides the database type of "pebble", which keeps the
+metadata in Pebble rather than leveldb and the blocks in the same flat files.
+Pebble writes less to disk than leveldb when large batches of metadata are
+flushed, as they are while the chain is downloaded.  The two types store the
+metadata in different formats, so a database created with one of them can't be
+opened with the other.
+
+	db, err := database.Create("pebble", "path/to/database", wire.MainNet)
+	if err != nil {
+		// Handle error
+	}
+
+# Options
+
+The Open, Create and OpenReadOnly functions of both types take an optional
+*Options after the block network with the sizes of the caches and buffers and
+the limits of open files.  The options that aren't set are given their
+defaults, and the sizes are picked from the memory of the machine, falling back
+to the sizes that were used before they could be tuned when it can't be
+detected.
+
+	db, err := database.Open("ffldb", "path/to/database", wire.MainNet,
+		&ffldb.Options{CacheSize: 512 * 1024 * 1024})
+	if err != nil {
+		// Handle error
+	}
 */
 package ffldb
@@ -16,51 +16,113 @@ var log = btclog.Disabled
 
 const (
 	dbType = "ffldb"
+
+	// pebbleDbType is the type of the driver that keeps the metadata in
+	// Pebble rather than leveldb.  The blocks and spend journals are kept
+	// in the same flat files as with ffldb.
+	pebbleDbType = "pebble"
 )
 
-// parseArgs parses the arguments from the database Open/Create methods.
-func parseArgs(funcName string, args ...interface{}) (string, wire.BitcoinNet, error) {
-	if len(args) != 2 {
-		return "", 0, fmt.Errorf("invalid arguments to %s.%s -- "+
-			"expected database path and block network", dbType,
-			funcName)
+// parseArgs parses the arguments from the database Open/Create methods.  The
+// options are optional, and the defaults are returned for the options that
+// aren't set.
+func parseArgs(dbType, funcName string, args ...interface{}) (string, wire.BitcoinNet, Options, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", 0, Options{}, fmt.Errorf("invalid arguments to "+
+			"%s.%s -- expected database path, block network and "+
+			"optional options", dbType, funcName)
 	}
 
 	dbPath, ok := args[0].(string)
 	if !ok {
-		return "", 0, fmt.Errorf("first argument to %s.%s is invalid -- "+
-			"expected database path string", dbType, funcName)
+		return "", 0, Options{}, fmt.Errorf("first argument to %s.%s "+
+			"is invalid -- expected database path string", dbType,
+			funcName)
 	}
 
 	network, ok := args[1].(wire.BitcoinNet)
 	if !ok {
-		return "", 0, fmt.Errorf("second argument to %s.%s is invalid -- "+
-			"expected block network", dbType, funcName)
+		return "", 0, Options{}, fmt.Errorf("second argument to %s.%s "+
+			"is invalid -- expected block network", dbType, funcName)
+	}
+
+	var opts *Options
+	if len(args) == 3 {
+		opts, ok = args[2].(*Options)
+		if !ok {
+			return "", 0, Options{}, fmt.Errorf("third argument to "+
+				"%s.%s is invalid -- expected *ffldb.Options",
+				dbType, funcName)
+		}
 	}
 
-	return dbPath, network, nil
+	return dbPath, network, opts.withDefaults(dbType), nil
 }
 
 // openDBDriver is the callback provided during driver registration that opens
 // an existing database for use.
 func openDBDriver(args ...interface{}) (database.DB, error) {
-	dbPath, network, err := parseArgs("Open", args...)
+	dbPath, network, opts, err := parseArgs(dbType, "Open", args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return openDB(dbPath, network, false)
+	return openDB(dbPath, network, false, dbType, opts)
 }
 
 // createDBDriver is the callback provided during driver registration that
 // creates, initializes, and opens a database for use.
 func createDBDriver(args ...interface{}) (database.DB, error) {
-	dbPath, network, err := parseArgs("Create", args...)
+	dbPath, network, opts, err := parseArgs(dbType, "Create", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDB(dbPath, network, true, dbType, opts)
+}
+
+// openReadOnlyDBDriver is the callback provided during driver registration
+// that opens an existing database read-only.
+func openReadOnlyDBDriver(args ...interface{}) (database.DB, error) {
+	dbPath, network, opts, err := parseArgs(dbType, "OpenReadOnly", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openReadOnlyDB(dbPath, network, dbType, opts)
+}
+
+// openPebbleDBDriver is the callback provided during the registration of the
+// pebble driver that opens an existing database for use.
+func openPebbleDBDriver(args ...interface{}) (database.DB, error) {
+	dbPath, network, opts, err := parseArgs(pebbleDbType, "Open", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDB(dbPath, network, false, pebbleDbType, opts)
+}
+
+// createPebbleDBDriver is the callback provided during the registration of the
+// pebble driver that creates, initializes, and opens a database for use.
+func createPebbleDBDriver(args ...interface{}) (database.DB, error) {
+	dbPath, network, opts, err := parseArgs(pebbleDbType, "Create", args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return openDB(dbPath, network, true)
+	return openDB(dbPath, network, true, pebbleDbType, opts)
+}
+
+// openReadOnlyPebbleDBDriver is the callback provided during the registration
+// of the pebble driver that opens an existing database read-only.
+func openReadOnlyPebbleDBDriver(args ...interface{}) (database.DB, error) {
+	dbPath, network, opts, err := parseArgs(pebbleDbType, "OpenReadOnly", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openReadOnlyDB(dbPath, network, pebbleDbType, opts)
 }
 
 // useLogger is the callback provided during driver registration that sets the
@@ -70,15 +132,24 @@ func useLogger(logger btclog.Logger) {
 }
 
 func init() {
-	// Register the driver.
-	driver := database.Driver{
-		DbType:    dbType,
-		Create:    createDBDriver,
-		Open:      openDBDriver,
-		UseLogger: useLogger,
-	}
-	if err := database.RegisterDriver(driver); err != nil {
-		panic(fmt.Sprintf("Failed to regiser database driver '%s': %v",
-			dbType, err))
+	// Register the drivers.
+	drivers := []database.Driver{{
+		DbType:       dbType,
+		Create:       createDBDriver,
+		Open:         openDBDriver,
+		OpenReadOnly: openReadOnlyDBDriver,
+		UseLogger:    useLogger,
+	}, {
+		DbType:       pebbleDbType,
+		Create:       createPebbleDBDriver,
+		Open:         openPebbleDBDriver,
+		OpenReadOnly: openReadOnlyPebbleDBDriver,
+		UseLogger:    useLogger,
+	}}
+	for _, driver := range drivers {
+		if err := database.RegisterDriver(driver); err != nil {
+			panic(fmt.Sprintf("Failed to regiser database driver '%s': %v",
+				driver.DbType, err))
+		}
 	}
 }
@@ -20,6 +20,10 @@ import (
 // dbType is the database type name for this driver.
 const dbType = "ffldb"
 
+// dbTypes are the database types of the drivers in this package, which share
+// the flat files and differ in the store that keeps the metadata.
+var dbTypes = []string{dbType, "pebble"}
+
 // TestCreateOpenFail ensures that errors related to creating and opening a
 // database are handled properly.
 func TestCreateOpenFail(t *testing.T) {
@@ -36,8 +40,8 @@ func TestCreateOpenFail(t *testing.T) {
 	// Ensure that attempting to open a database with the wrong number of
 	// parameters returns the expected error.
 	wantErr := fmt.Errorf("invalid arguments to %s.Open -- expected "+
-		"database path and block network", dbType)
-	_, err = database.Open(dbType, 1, 2, 3)
+		"database path, block network and optional options", dbType)
+	_, err = database.Open(dbType, 1, 2, 3, 4)
 	if err.Error() != wantErr.Error() {
 		t.Errorf("Open: did not receive expected error - got %v, "+
 			"want %v", err, wantErr)
@@ -66,11 +70,22 @@ func TestCreateOpenFail(t *testing.T) {
 		return
 	}
 
+	// Ensure that attempting to open a database with an invalid type for
+	// the third parameter returns the expected error.
+	wantErr = fmt.Errorf("third argument to %s.Open is invalid -- "+
+		"expected *ffldb.Options", dbType)
+	_, err = database.Open(dbType, "noexist", blockDataNet, ffldb.Options{})
+	if err.Error() != wantErr.Error() {
+		t.Errorf("Open: did not receive expected error - got %v, "+
+			"want %v", err, wantErr)
+		return
+	}
+
 	// Ensure that attempting to create a database with the wrong number of
 	// parameters returns the expected error.
 	wantErr = fmt.Errorf("invalid arguments to %s.Create -- expected "+
-		"database path and block network", dbType)
-	_, err = database.Create(dbType, 1, 2, 3)
+		"database path, block network and optional options", dbType)
+	_, err = database.Create(dbType, 1, 2, 3, 4)
 	if err.Error() != wantErr.Error() {
 		t.Errorf("Create: did not receive expected error - got %v, "+
 			"want %v", err, wantErr)
@@ -151,8 +166,20 @@ func TestCreateOpenFail(t *testing.T) {
 func TestPersistence(t *testing.T) {
 	t.Parallel()
 
+	for _, dbType := range dbTypes {
+		dbType := dbType
+		t.Run(dbType, func(t *testing.T) {
+			t.Parallel()
+			testPersistence(t, dbType)
+		})
+	}
+}
+
+// testPersistence performs the persistence tests against a database of the
+// passed type.
+func testPersistence(t *testing.T, dbType string) {
 	// Create a new database to run tests against.
-	dbPath := filepath.Join(os.TempDir(), "ffldb-persistencetest")
+	dbPath := filepath.Join(os.TempDir(), dbType+"-persistencetest")
 	_ = os.RemoveAll(dbPath)
 	db, err := database.Create(dbType, dbPath, blockDataNet)
 	if err != nil {
@@ -259,12 +286,24 @@ func TestPersistence(t *testing.T) {
 	}
 }
 
-// TestInterface performs all interfaces tests for this database driver.
+// TestInterface performs all interfaces tests for the database drivers.
 func TestInterface(t *testing.T) {
 	t.Parallel()
 
+	for _, dbType := range dbTypes {
+		dbType := dbType
+		t.Run(dbType, func(t *testing.T) {
+			t.Parallel()
+			testDriverInterface(t, dbType)
+		})
+	}
+}
+
+// testDriverInterface performs all interfaces tests against a database of the
+// passed type.
+func testDriverInterface(t *testing.T, dbType string) {
 	// Create a new database to run tests against.
-	dbPath := filepath.Join(os.TempDir(), "ffldb-interfacetest")
+	dbPath := filepath.Join(os.TempDir(), dbType+"-interfacetest")
 	_ = os.RemoveAll(dbPath)
 	db, err := database.Create(dbType, dbPath, blockDataNet)
 	if err != nil {
@@ -465,3 +504,457 @@ func TestPrune(t *testing.T) {
 		testfn(t, db)
 	})
 }
+
+// TestCompact ensures that the metadata can be compacted while the database is
+// open for each of the database types, that the progress covers all of the key
+// ranges, and that an interrupted compaction stops without an error.
+func TestCompact(t *testing.T) {
+	t.Parallel()
+
+	for _, dbType := range dbTypes {
+		dbType := dbType
+		t.Run(dbType, func(t *testing.T) {
+			t.Parallel()
+
+			db, err := database.Create(dbType, t.TempDir(), blockDataNet)
+			if err != nil {
+				t.Fatalf("Failed to create test database (%s) %v",
+					dbType, err)
+			}
+			defer db.Close()
+
+			// Fill two buckets and delete one of them so that there
+			// is something to compact away.
+			keepKey, dropKey := []byte("keep"), []byte("drop")
+			err = db.Update(func(tx database.Tx) error {
+				for _, name := range [][]byte{keepKey, dropKey} {
+					bucket, err := tx.Metadata().CreateBucket(name)
+					if err != nil {
+						return err
+					}
+					for i := 0; i < 1000; i++ {
+						key := []byte(fmt.Sprintf("key%04d", i))
+						if err := bucket.Put(key, key); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Update: unexpected error: %v", err)
+			}
+			err = db.Update(func(tx database.Tx) error {
+				return tx.Metadata().DeleteBucket(dropKey)
+			})
+			if err != nil {
+				t.Fatalf("DeleteBucket: unexpected error: %v", err)
+			}
+
+			// An interrupted compaction doesn't compact any range.
+			interrupt := make(chan struct{})
+			close(interrupt)
+			calls := 0
+			err = db.Compact(interrupt, func(done, total int) {
+				calls++
+			})
+			if err != nil || calls != 0 {
+				t.Fatalf("interrupted Compact: got %d calls and "+
+					"error %v, want none", calls, err)
+			}
+
+			var lastDone, lastTotal int
+			err = db.Compact(nil, func(done, total int) {
+				if done != lastDone+1 {
+					t.Errorf("progress: got %d done after %d",
+						done, lastDone)
+				}
+				lastDone, lastTotal = done, total
+			})
+			if err != nil {
+				t.Fatalf("Compact: unexpected error: %v", err)
+			}
+			if lastTotal == 0 || lastDone != lastTotal {
+				t.Fatalf("Compact: got %d of %d ranges done",
+					lastDone, lastTotal)
+			}
+
+			// The data that wasn't deleted is still there.
+			err = db.View(func(tx database.Tx) error {
+				bucket := tx.Metadata().Bucket(keepKey)
+				if bucket == nil {
+					return fmt.Errorf("bucket %s not found", keepKey)
+				}
+				if got := bucket.Get([]byte("key0999")); string(got) != "key0999" {
+					return fmt.Errorf("got value %q, want key0999", got)
+				}
+				if tx.Metadata().Bucket(dropKey) != nil {
+					return fmt.Errorf("deleted bucket %s found", dropKey)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("View: unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestOpenReadOnly ensures that a database can be opened read-only for each of
+// the database types while it's open and being written to, that it sees the
+// data as of when it was opened, that writes to it are refused, and that it
+// doesn't keep the database from being opened for writing.
+func TestOpenReadOnly(t *testing.T) {
+	t.Parallel()
+
+	for _, dbType := range dbTypes {
+		dbType := dbType
+		t.Run(dbType, func(t *testing.T) {
+			t.Parallel()
+			testOpenReadOnly(t, dbType)
+		})
+	}
+}
+
+// testOpenReadOnly performs the read-only open tests against a database of the
+// passed type.
+func testOpenReadOnly(t *testing.T, dbType string) {
+	dbPath := t.TempDir()
+	_, err := database.OpenReadOnly(dbType, dbPath, blockDataNet)
+	if !checkDbError(t, "OpenReadOnly", err, database.ErrDbDoesNotExist) {
+		return
+	}
+
+	db, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer func() { db.Close() }()
+
+	// putKeys stores the passed number of keys in the bucket after the
+	// ones that are already stored and flushes them to the metadata
+	// store.
+	bucketKey := []byte("bucket")
+	numKeys := 0
+	putKeys := func(n int) error {
+		err := db.Update(func(tx database.Tx) error {
+			bucket, err := tx.Metadata().CreateBucketIfNotExists(bucketKey)
+			if err != nil {
+				return err
+			}
+			for i := numKeys; i < numKeys+n; i++ {
+				key := []byte(fmt.Sprintf("key%06d", i))
+				if err := bucket.Put(key, key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		numKeys += n
+		return db.Compact(nil, nil)
+	}
+
+	genesisBlock := btcutil.NewBlock(chaincfg.MainNetParams.GenesisBlock)
+	genesisBlock.SetHeight(0)
+	err = db.Update(func(tx database.Tx) error {
+		if err := tx.StoreBlock(genesisBlock); err != nil {
+			return err
+		}
+		return tx.StoreSpendJournal(genesisBlock.Hash(), []byte{})
+	})
+	if err != nil {
+		t.Fatalf("StoreBlock: unexpected error: %v", err)
+	}
+	if err := putKeys(1000); err != nil {
+		t.Fatalf("putKeys: unexpected error: %v", err)
+	}
+
+	// Keep writing to the database while it's opened read-only.
+	done := make(chan struct{})
+	writeErr := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				writeErr <- nil
+				return
+			default:
+			}
+			if err := putKeys(100); err != nil {
+				writeErr <- err
+				return
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		rodb, err := database.OpenReadOnly(dbType, dbPath, blockDataNet)
+		if err != nil {
+			close(done)
+			t.Fatalf("OpenReadOnly: unexpected error: %v", err)
+		}
+		err = rodb.View(func(tx database.Tx) error {
+			bucket := tx.Metadata().Bucket(bucketKey)
+			if bucket == nil {
+				return fmt.Errorf("bucket %s not found", bucketKey)
+			}
+			if got := bucket.Get([]byte("key000999")); string(got) != "key000999" {
+				return fmt.Errorf("got value %q, want key000999", got)
+			}
+			_, err := tx.FetchBlock(genesisBlock.Hash())
+			return err
+		})
+		rodb.Close()
+		if err != nil {
+			close(done)
+			t.Fatalf("View: unexpected error: %v", err)
+		}
+	}
+	close(done)
+	if err := <-writeErr; err != nil {
+		t.Fatalf("putKeys: unexpected error: %v", err)
+	}
+
+	rodb, err := database.OpenReadOnly(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("OpenReadOnly: unexpected error: %v", err)
+	}
+	defer rodb.Close()
+
+	// Writes are refused.
+	_, err = rodb.Begin(true)
+	if !checkDbError(t, "Begin(true)", err, database.ErrDbReadOnly) {
+		return
+	}
+	err = rodb.Update(func(tx database.Tx) error { return nil })
+	if !checkDbError(t, "Update", err, database.ErrDbReadOnly) {
+		return
+	}
+	err = rodb.Compact(nil, nil)
+	if !checkDbError(t, "Compact", err, database.ErrDbReadOnly) {
+		return
+	}
+
+	// The keys stored after the database was opened read-only aren't seen,
+	// and the database can still be closed and opened for writing.
+	wantKeys := numKeys
+	if err := putKeys(10); err != nil {
+		t.Fatalf("putKeys: unexpected error: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+	db, err = database.Open(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Open: unexpected error: %v", err)
+	}
+	err = rodb.View(func(tx database.Tx) error {
+		gotKeys := 0
+		err := tx.Metadata().Bucket(bucketKey).ForEach(func(k, v []byte) error {
+			gotKeys++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if gotKeys != wantKeys {
+			return fmt.Errorf("got %d keys, want %d", gotKeys, wantKeys)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: unexpected error: %v", err)
+	}
+}
+
+// TestBackup ensures that a backup of the database can be made while it's open
+// for each of the database types and that the backup can be opened with the
+// blocks and metadata that were stored before it was made.
+func TestBackup(t *testing.T) {
+	t.Parallel()
+
+	blocks, err := loadBlocks(t, blockDataFile, blockDataNet)
+	if err != nil {
+		t.Fatalf("Unable to load blocks from test data: %v", err)
+	}
+
+	for _, dbType := range dbTypes {
+		dbType := dbType
+		t.Run(dbType, func(t *testing.T) {
+			t.Parallel()
+			testBackup(t, dbType, blocks[:20])
+		})
+	}
+}
+
+// testBackup performs the backup tests against a database of the passed type.
+func testBackup(t *testing.T, dbType string, blocks []*btcutil.Block) {
+	db, err := database.Create(dbType, t.TempDir(), blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer db.Close()
+
+	// storeBlocks stores the passed blocks along with a key for each of
+	// them.
+	bucketKey := []byte("bucket")
+	storeBlocks := func(blocks []*btcutil.Block) error {
+		return db.Update(func(tx database.Tx) error {
+			bucket, err := tx.Metadata().CreateBucketIfNotExists(bucketKey)
+			if err != nil {
+				return err
+			}
+			for _, block := range blocks {
+				if err := tx.StoreBlock(block); err != nil {
+					return err
+				}
+				err := tx.StoreSpendJournal(block.Hash(), []byte{0x01})
+				if err != nil {
+					return err
+				}
+				if err := bucket.Put(block.Hash()[:], nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	// Store the blocks in several files, some of which are backed up and
+	// some of which are stored after the backup is made.
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	ffldb.TstRunWithMaxBlockFileSize(db, 2048, func() {
+		if err = storeBlocks(blocks[:10]); err != nil {
+			return
+		}
+		if err = db.Backup(backupDir); err != nil {
+			return
+		}
+		err = storeBlocks(blocks[10:])
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.Backup(backupDir); err == nil {
+		t.Fatalf("expected an error for an existing backup directory")
+	}
+
+	backup, err := database.Open(dbType, backupDir, blockDataNet)
+	if err != nil {
+		t.Fatalf("Open: unexpected error: %v", err)
+	}
+	defer backup.Close()
+	err = backup.View(func(tx database.Tx) error {
+		bucket := tx.Metadata().Bucket(bucketKey)
+		for i, block := range blocks {
+			wantStored := i < 10
+			stored := bucket.Get(block.Hash()[:]) != nil
+			if stored != wantStored {
+				return fmt.Errorf("block %d: got key stored %v, "+
+					"want %v", i, stored, wantStored)
+			}
+			if !wantStored {
+				continue
+			}
+
+			gotBytes, err := tx.FetchBlock(block.Hash())
+			if err != nil {
+				return err
+			}
+			wantBytes, _ := block.Bytes()
+			if !reflect.DeepEqual(gotBytes, wantBytes) {
+				return fmt.Errorf("block %d: stored block mismatch", i)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: unexpected error: %v", err)
+	}
+}
+
+// TestOptions ensures that databases opened with options other than the
+// defaults store and fetch blocks across several flat files, including while
+// at most one of them is kept open and while the buffered writes are written
+// out in the middle of blocks.
+func TestOptions(t *testing.T) {
+	t.Parallel()
+
+	blocks, err := loadBlocks(t, blockDataFile, blockDataNet)
+	if err != nil {
+		t.Fatalf("Unable to load blocks from test data: %v", err)
+	}
+
+	for _, dbType := range dbTypes {
+		dbType := dbType
+		t.Run(dbType, func(t *testing.T) {
+			t.Parallel()
+			testOptions(t, dbType, blocks[:20])
+		})
+	}
+}
+
+// testOptions performs the options tests against a database of the passed
+// type.
+func testOptions(t *testing.T, dbType string, blocks []*btcutil.Block) {
+	opts := &ffldb.Options{
+		CacheSize:           1,
+		WriteBufferSize:     1024 * 1024,
+		BlockCacheSize:      1024 * 1024,
+		MaxOpenFiles:        16,
+		MaxOpenBlockFiles:   1,
+		FileWriteBufferSize: 100,
+	}
+	dbPath := t.TempDir()
+	db, err := database.Create(dbType, dbPath, blockDataNet, opts)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+
+	// Store the blocks a few at a time in several files.
+	ffldb.TstRunWithMaxBlockFileSize(db, 2048, func() {
+		for i := 0; i < len(blocks) && err == nil; i += 5 {
+			err = db.Update(func(tx database.Tx) error {
+				for _, block := range blocks[i : i+5] {
+					if err := tx.StoreBlock(block); err != nil {
+						return err
+					}
+					err := tx.StoreSpendJournal(block.Hash(),
+						[]byte{0x01})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	})
+	db.Close()
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	db, err = database.Open(dbType, dbPath, blockDataNet, opts)
+	if err != nil {
+		t.Fatalf("Failed to open test database (%s) %v", dbType, err)
+	}
+	defer db.Close()
+	err = db.View(func(tx database.Tx) error {
+		for i, block := range blocks {
+			gotBytes, err := tx.FetchBlock(block.Hash())
+			if err != nil {
+				return err
+			}
+			wantBytes, _ := block.Bytes()
+			if !reflect.DeepEqual(gotBytes, wantBytes) {
+				return fmt.Errorf("block %d: stored block mismatch", i)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ffldb
+
+import "golang.org/x/sys/unix"
+
+// totalMemory returns the amount of physical memory of the machine in bytes,
+// or 0 when it can't be detected.
+func totalMemory() uint64 {
+	mem, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0
+	}
+	return mem
+}
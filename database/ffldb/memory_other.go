@@ -0,0 +1,14 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package ffldb
+
+// totalMemory returns 0 since the amount of physical memory of the machine
+// isn't detected on this platform.
+func totalMemory() uint64 {
+	return 0
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ffldb
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// metadataStore is the key/value store the metadata of the database is kept
+// in, while the blocks and spend journals are kept in the flat files.  It is
+// implemented with leveldb by the ffldb driver and with Pebble by the pebble
+// driver.
+type metadataStore interface {
+	// Snapshot returns a snapshot of the store at the current point in
+	// time.  The snapshot must be released after use by calling Release.
+	Snapshot() (metadataSnapshot, error)
+
+	// Write atomically applies the puts and deletes made to the batch by
+	// the passed function.  Nothing is applied when the function returns
+	// an error.
+	Write(fn func(batch metadataBatch) error) error
+
+	// Compact compacts the keys in the passed range while the store stays
+	// in use.  A nil start or limit key leaves the range open on that side.
+	Compact(slice util.Range) error
+
+	// Checkpoint writes a copy of the store to the passed directory, which
+	// must not exist, while the store stays in use.  The copy has all of
+	// the writes that were applied before it was called.
+	Checkpoint(dir string) error
+
+	// Close closes the store.
+	Close() error
+}
+
+// metadataSnapshot is a snapshot of a metadata store.
+type metadataSnapshot interface {
+	// Has returns whether or not the passed key exists.
+	Has(key []byte) (bool, error)
+
+	// Get returns the value for the passed key, or nil when the key does
+	// not exist.
+	Get(key []byte) ([]byte, error)
+
+	// NewIterator returns a new iterator over the keys of the snapshot in
+	// the passed range.  The start key is inclusive and the limit key is
+	// exclusive.
+	NewIterator(slice *util.Range) iterator.Iterator
+
+	// Release releases the snapshot.
+	Release()
+}
+
+// metadataBatch collects the updates to apply to a metadata store atomically.
+type metadataBatch interface {
+	// Put sets the value for the passed key.
+	Put(key, value []byte) error
+
+	// Delete removes the passed key.
+	Delete(key []byte) error
+}
+
+// openLdbStore opens the leveldb metadata store at the passed path with the
+// passed options, creating it if needed.  An error is returned if the create
+// flag is set and the store already exists.
+func openLdbStore(path string, create bool, dbOpts Options) (metadataStore, error) {
+	opts := opt.Options{
+		ErrorIfExist:           create,
+		Strict:                 opt.DefaultStrict,
+		Compression:            opt.NoCompression,
+		Filter:                 filter.NewBloomFilter(10),
+		WriteBuffer:            dbOpts.WriteBufferSize,
+		BlockCacheCapacity:     dbOpts.BlockCacheSize,
+		OpenFilesCacheCapacity: dbOpts.MaxOpenFiles,
+	}
+	ldb, err := leveldb.OpenFile(path, &opts)
+	if err != nil {
+		return nil, convertErr(err.Error(), err)
+	}
+	return &ldbStore{ldb: ldb, path: path}, nil
+}
+
+// ldbStore is a metadata store backed by leveldb.
+type ldbStore struct {
+	ldb  *leveldb.DB
+	path string
+}
+
+// Enforce ldbStore implements the metadataStore interface.
+var _ metadataStore = (*ldbStore)(nil)
+
+// Snapshot returns a snapshot of the store at the current point in time.
+//
+// This is part of the metadataStore interface implementation.
+func (s *ldbStore) Snapshot() (metadataSnapshot, error) {
+	snapshot, err := s.ldb.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &ldbSnapshot{snapshot}, nil
+}
+
+// Write atomically applies the updates made to the batch by the passed
+// function in a leveldb transaction, which writes them straight to the tables
+// rather than through the journal.
+//
+// This is part of the metadataStore interface implementation.
+func (s *ldbStore) Write(fn func(batch metadataBatch) error) error {
+	ldbTx, err := s.ldb.OpenTransaction()
+	if err != nil {
+		return convertErr("failed to open ldb transaction", err)
+	}
+
+	if err := fn(&ldbBatch{ldbTx}); err != nil {
+		ldbTx.Discard()
+		return err
+	}
+
+	// Commit the leveldb transaction and convert any errors as needed.
+	if err := ldbTx.Commit(); err != nil {
+		return convertErr("failed to commit leveldb transaction", err)
+	}
+	return nil
+}
+
+// Compact compacts the keys in the passed range of the leveldb database.
+//
+// This is part of the metadataStore interface implementation.
+func (s *ldbStore) Compact(slice util.Range) error {
+	if err := s.ldb.CompactRange(slice); err != nil {
+		return convertErr("failed to compact leveldb range", err)
+	}
+	return nil
+}
+
+// Checkpoint copies the files of the leveldb database to the passed directory,
+// since leveldb has no checkpoints of its own.  The copy is made again when the
+// files change while they're copied, which is only due to the compactions of
+// leveldb when no write is applied meanwhile.
+//
+// This is part of the metadataStore interface implementation.
+func (s *ldbStore) Checkpoint(dir string) error {
+	return retryCheckpoint(s.path, func() error {
+		return checkpointMetadata(s.path, dir)
+	})
+}
+
+// Close closes the underlying leveldb database.
+//
+// This is part of the metadataStore interface implementation.
+func (s *ldbStore) Close() error {
+	return s.ldb.Close()
+}
+
+// ldbSnapshot is a snapshot of a leveldb metadata store.
+type ldbSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+// Has returns whether or not the passed key exists.
+//
+// This is part of the metadataSnapshot interface implementation.
+func (s *ldbSnapshot) Has(key []byte) (bool, error) {
+	return s.snapshot.Has(key, nil)
+}
+
+// Get returns the value for the passed key, or nil when the key does not exist.
+//
+// This is part of the metadataSnapshot interface implementation.
+func (s *ldbSnapshot) Get(key []byte) ([]byte, error) {
+	value, err := s.snapshot.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return value, err
+}
+
+// NewIterator returns a new iterator over the keys in the passed range.
+//
+// This is part of the metadataSnapshot interface implementation.
+func (s *ldbSnapshot) NewIterator(slice *util.Range) iterator.Iterator {
+	return s.snapshot.NewIterator(slice, nil)
+}
+
+// Release releases the snapshot.
+//
+// This is part of the metadataSnapshot interface implementation.
+func (s *ldbSnapshot) Release() {
+	s.snapshot.Release()
+}
+
+// ldbBatch applies the updates to a leveldb transaction.
+type ldbBatch struct {
+	ldbTx *leveldb.Transaction
+}
+
+// Put sets the value for the passed key.
+//
+// This is part of the metadataBatch interface implementation.
+func (b *ldbBatch) Put(key, value []byte) error {
+	return b.ldbTx.Put(key, value, nil)
+}
+
+// Delete removes the passed key.
+//
+// This is part of the metadataBatch interface implementation.
+func (b *ldbBatch) Delete(key []byte) error {
+	return b.ldbTx.Delete(key, nil)
+}
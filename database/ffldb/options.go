@@ -0,0 +1,117 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ffldb
+
+const (
+	// minLdbWriteBufferSize and maxLdbWriteBufferSize bound the default
+	// size of the leveldb write buffer, which is 1/128 of the memory of the
+	// machine.  The minimum is the size leveldb uses by default.
+	minLdbWriteBufferSize = 4 * 1024 * 1024
+	maxLdbWriteBufferSize = 64 * 1024 * 1024
+
+	// minBlockCacheSize and maxBlockCacheSize bound the default size of the
+	// block cache of the metadata store, which is 1/64 of the memory of the
+	// machine.  The minimum is the size leveldb and Pebble use by default.
+	minBlockCacheSize = 8 * 1024 * 1024
+	maxBlockCacheSize = 256 * 1024 * 1024
+
+	// maxCacheSize bounds the default size of the database cache, which is
+	// 1/32 of the memory of the machine and at least defaultCacheSize.
+	maxCacheSize = 512 * 1024 * 1024
+
+	// defaultMaxOpenFiles is the default max number of files of the
+	// metadata store to keep open.
+	defaultMaxOpenFiles = 500
+
+	// defaultMaxOpenBlockFiles is the default max number of flat files of
+	// each of the block and spend journal stores to keep open for reads.
+	defaultMaxOpenBlockFiles = 64
+
+	// defaultFileWriteBufferSize is the default size of the buffer the
+	// writes to the current flat file are collected in.
+	defaultFileWriteBufferSize = 1024 * 1024
+)
+
+// Options houses the tunables of the database.  It's passed to the Open,
+// Create and OpenReadOnly functions after the block network, and the fields
+// that are zero, or all of them when it's not passed, are given defaults that
+// are picked from the memory of the machine.  The defaults are the sizes that
+// were used before they could be tuned when the memory can't be detected.
+type Options struct {
+	// CacheSize is the size in bytes of the cache that the writes to the
+	// metadata are collected in before they're written to the metadata
+	// store in one batch.
+	CacheSize int
+
+	// WriteBufferSize is the size in bytes of the memtable the metadata
+	// store writes to before it writes a table to disk.  It defaults to
+	// pebbleMemTableSize for Pebble.
+	WriteBufferSize int
+
+	// BlockCacheSize is the size in bytes of the cache of the blocks of
+	// the tables of the metadata store.
+	BlockCacheSize int
+
+	// MaxOpenFiles is the max number of files of the metadata store to
+	// keep open.
+	MaxOpenFiles int
+
+	// MaxOpenBlockFiles is the max number of flat files of each of the
+	// block and spend journal stores to keep open for reads.  The files
+	// that are written to aren't counted.
+	MaxOpenBlockFiles int
+
+	// FileWriteBufferSize is the size in bytes of the buffer the blocks
+	// and spend journals of a transaction are collected in before they're
+	// written to the current flat file.  The buffer is written out when
+	// it's full and when the transaction is committed.
+	FileWriteBufferSize int
+}
+
+// clampSize returns the passed size bounded by the passed minimum and maximum.
+func clampSize(size, min, max uint64) int {
+	if size < min {
+		return int(min)
+	}
+	if size > max {
+		return int(max)
+	}
+	return int(size)
+}
+
+// withDefaults returns a copy of the options with the fields that are zero or
+// negative set to their defaults for the passed database type.
+func (o *Options) withDefaults(dbType string) Options {
+	var opts Options
+	if o != nil {
+		opts = *o
+	}
+
+	mem := totalMemory()
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = clampSize(mem/32, defaultCacheSize, maxCacheSize)
+	}
+	if opts.WriteBufferSize <= 0 {
+		opts.WriteBufferSize = clampSize(mem/128,
+			minLdbWriteBufferSize, maxLdbWriteBufferSize)
+		if dbType == pebbleDbType {
+			opts.WriteBufferSize = pebbleMemTableSize
+		}
+	}
+	if opts.BlockCacheSize <= 0 {
+		opts.BlockCacheSize = clampSize(mem/64, minBlockCacheSize,
+			maxBlockCacheSize)
+	}
+	if opts.MaxOpenFiles <= 0 {
+		opts.MaxOpenFiles = defaultMaxOpenFiles
+	}
+	if opts.MaxOpenBlockFiles <= 0 {
+		opts.MaxOpenBlockFiles = defaultMaxOpenBlockFiles
+	}
+	if opts.FileWriteBufferSize <= 0 {
+		opts.FileWriteBufferSize = defaultFileWriteBufferSize
+	}
+	return opts
+}
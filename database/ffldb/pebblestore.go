@@ -0,0 +1,444 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ffldb
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// pebbleMemTableSize is the size of the memtables of the Pebble
+	// metadata store.  The cache flushes its entries in large batches, and
+	// larger memtables let more of them be merged before they're written
+	// out to level 0.
+	pebbleMemTableSize = 64 * 1024 * 1024
+)
+
+// openPebbleStore opens the Pebble metadata store at the passed path with the
+// passed options, creating it if needed.  An error is returned if the create
+// flag is set and the store already exists.
+func openPebbleStore(path string, create bool, dbOpts Options) (metadataStore, error) {
+	cache := pebble.NewCache(int64(dbOpts.BlockCacheSize))
+	defer cache.Unref()
+
+	opts := &pebble.Options{
+		ErrorIfExists: create,
+		MemTableSize:  uint64(dbOpts.WriteBufferSize),
+		Cache:         cache,
+		MaxOpenFiles:  dbOpts.MaxOpenFiles,
+		Levels: []pebble.LevelOptions{{
+			Compression:  pebble.NoCompression,
+			FilterPolicy: bloom.FilterPolicy(10),
+		}},
+		Logger: pebbleLogger{},
+	}
+	pdb, err := pebble.Open(path, opts)
+	if err != nil {
+		return nil, convertErr(err.Error(), err)
+	}
+	return &pebbleStore{pdb: pdb}, nil
+}
+
+// pebbleLogger sends the messages of Pebble to the log of the package.
+type pebbleLogger struct{}
+
+// Infof logs the informational messages of Pebble at the debug level.
+func (pebbleLogger) Infof(format string, args ...interface{}) {
+	log.Debugf("Pebble: "+format, args...)
+}
+
+// Fatalf logs the fatal errors of Pebble, after which it can't go on.
+func (pebbleLogger) Fatalf(format string, args ...interface{}) {
+	log.Criticalf("Pebble: "+format, args...)
+	panic("pebble: fatal error")
+}
+
+// pebbleStore is a metadata store backed by Pebble, which writes less than
+// leveldb while the chain is downloaded.
+type pebbleStore struct {
+	pdb *pebble.DB
+
+	// Pebble panics when it's used once it's closed rather than returning
+	// an error like leveldb, so the store remembers that it's closed.  The
+	// read lock is held while the store is used.
+	mtx    sync.RWMutex
+	closed bool
+
+	// iters houses the iterators that aren't released yet.  The cursors
+	// of the database only release their iterators when they're garbage
+	// collected, and Pebble fails to close while iterators are open, so
+	// the store releases them when it's closed.  It's protected by
+	// itersMtx since iterators are released by the finalizers.
+	itersMtx sync.Mutex
+	iters    map[*pebbleIter]struct{}
+}
+
+// Enforce pebbleStore implements the metadataStore interface.
+var _ metadataStore = (*pebbleStore)(nil)
+
+// Snapshot returns a snapshot of the store at the current point in time.
+//
+// This is part of the metadataStore interface implementation.
+func (s *pebbleStore) Snapshot() (metadataSnapshot, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.closed {
+		return nil, pebble.ErrClosed
+	}
+	return &pebbleSnapshot{store: s, snapshot: s.pdb.NewSnapshot()}, nil
+}
+
+// Write atomically applies the updates made to the batch by the passed
+// function in a Pebble batch, which is synced to disk when it's committed.
+//
+// This is part of the metadataStore interface implementation.
+func (s *pebbleStore) Write(fn func(batch metadataBatch) error) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.closed {
+		return convertErr("failed to open pebble batch", pebble.ErrClosed)
+	}
+
+	batch := s.pdb.NewBatch()
+	defer batch.Close()
+	if err := fn(&pebbleBatch{batch}); err != nil {
+		return err
+	}
+	if err := batch.Commit(pebble.Sync); err != nil {
+		return convertErr("failed to commit pebble batch", err)
+	}
+	return nil
+}
+
+// Compact compacts the keys in the passed range of the Pebble database.
+//
+// This is part of the metadataStore interface implementation.
+func (s *pebbleStore) Compact(slice util.Range) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.closed {
+		return convertErr("failed to compact pebble range", pebble.ErrClosed)
+	}
+
+	// Pebble needs both ends of the range, so an open limit is set past
+	// the last key.  There's nothing to compact when there's no key past
+	// the start.
+	start, limit := slice.Start, slice.Limit
+	if start == nil {
+		start = []byte{}
+	}
+	if limit == nil {
+		iter, err := s.pdb.NewIter(&pebble.IterOptions{LowerBound: start})
+		if err != nil {
+			return convertErr("failed to compact pebble range", err)
+		}
+		if iter.Last() {
+			limit = append(copySlice(iter.Key()), 0x00)
+		}
+		if err := iter.Close(); err != nil {
+			return convertErr("failed to compact pebble range", err)
+		}
+		if limit == nil {
+			return nil
+		}
+	}
+	if bytes.Compare(start, limit) >= 0 {
+		return nil
+	}
+
+	if err := s.pdb.Compact(start, limit, true); err != nil {
+		return convertErr("failed to compact pebble range", err)
+	}
+	return nil
+}
+
+// Checkpoint writes a Pebble checkpoint of the database to the passed
+// directory.
+//
+// This is part of the metadataStore interface implementation.
+func (s *pebbleStore) Checkpoint(dir string) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.closed {
+		return convertErr("failed to checkpoint pebble", pebble.ErrClosed)
+	}
+	if err := s.pdb.Checkpoint(dir); err != nil {
+		return convertErr("failed to checkpoint pebble", err)
+	}
+	return nil
+}
+
+// Close closes the underlying Pebble database.
+//
+// This is part of the metadataStore interface implementation.
+func (s *pebbleStore) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return pebble.ErrClosed
+	}
+	s.closed = true
+
+	s.itersMtx.Lock()
+	for iter := range s.iters {
+		iter.released = true
+		iter.iter.Close()
+	}
+	s.iters = nil
+	s.itersMtx.Unlock()
+
+	return s.pdb.Close()
+}
+
+// pebbleSnapshot is a snapshot of a Pebble metadata store.
+type pebbleSnapshot struct {
+	store    *pebbleStore
+	snapshot *pebble.Snapshot
+}
+
+// Has returns whether or not the passed key exists.
+//
+// This is part of the metadataSnapshot interface implementation.
+func (s *pebbleSnapshot) Has(key []byte) (bool, error) {
+	_, closer, err := s.snapshot.Get(key)
+	if err == pebble.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	closer.Close()
+	return true, nil
+}
+
+// Get returns the value for the passed key, or nil when the key does not exist.
+//
+// This is part of the metadataSnapshot interface implementation.
+func (s *pebbleSnapshot) Get(key []byte) ([]byte, error) {
+	value, closer, err := s.snapshot.Get(key)
+	if err == pebble.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The value is only valid until the closer is closed.
+	value = copySlice(value)
+	closer.Close()
+	return value, nil
+}
+
+// NewIterator returns a new iterator over the keys in the passed range.
+//
+// This is part of the metadataSnapshot interface implementation.
+func (s *pebbleSnapshot) NewIterator(slice *util.Range) iterator.Iterator {
+	var opts pebble.IterOptions
+	if slice != nil {
+		opts.LowerBound = slice.Start
+		opts.UpperBound = slice.Limit
+	}
+	iter, err := s.snapshot.NewIter(&opts)
+	if err != nil {
+		return iterator.NewEmptyIterator(err)
+	}
+
+	pIter := &pebbleIter{store: s.store, iter: iter}
+	s.store.itersMtx.Lock()
+	if s.store.iters == nil {
+		s.store.iters = make(map[*pebbleIter]struct{})
+	}
+	s.store.iters[pIter] = struct{}{}
+	s.store.itersMtx.Unlock()
+	return pIter
+}
+
+// Release releases the snapshot.
+//
+// This is part of the metadataSnapshot interface implementation.
+func (s *pebbleSnapshot) Release() {
+	s.snapshot.Close()
+}
+
+// pebbleIter wraps a Pebble iterator to provide the functionality needed to
+// satisfy the leveldb iterator.Iterator interface.
+type pebbleIter struct {
+	store    *pebbleStore
+	iter     *pebble.Iterator
+	releaser util.Releaser
+
+	// positioned is set once the iterator is moved.  Like leveldb
+	// iterators, an iterator that wasn't moved yet moves to the first
+	// key on Next and to the last key on Prev, and moving a released
+	// iterator sets the error to iterator.ErrIterReleased.
+	positioned bool
+	released   bool
+	err        error
+}
+
+// Enforce pebbleIter implements the leveldb iterator.Iterator interface.
+var _ iterator.Iterator = (*pebbleIter)(nil)
+
+// First moves the iterator to the first key.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) First() bool {
+	if iter.released {
+		iter.err = iterator.ErrIterReleased
+		return false
+	}
+	iter.positioned = true
+	return iter.iter.First()
+}
+
+// Last moves the iterator to the last key.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Last() bool {
+	if iter.released {
+		iter.err = iterator.ErrIterReleased
+		return false
+	}
+	iter.positioned = true
+	return iter.iter.Last()
+}
+
+// Seek moves the iterator to the first key that is greater or equal to the
+// passed key.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Seek(key []byte) bool {
+	if iter.released {
+		iter.err = iterator.ErrIterReleased
+		return false
+	}
+	iter.positioned = true
+	return iter.iter.SeekGE(key)
+}
+
+// Next moves the iterator to the next key.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Next() bool {
+	if !iter.positioned {
+		return iter.First()
+	}
+	if iter.released {
+		iter.err = iterator.ErrIterReleased
+		return false
+	}
+	return iter.iter.Next()
+}
+
+// Prev moves the iterator to the previous key.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Prev() bool {
+	if !iter.positioned {
+		return iter.Last()
+	}
+	if iter.released {
+		iter.err = iterator.ErrIterReleased
+		return false
+	}
+	return iter.iter.Prev()
+}
+
+// Valid returns whether the iterator is at a key.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Valid() bool {
+	return !iter.released && iter.positioned && iter.iter.Valid()
+}
+
+// Key returns the current key, which is only valid until the iterator is
+// moved.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Key() []byte {
+	if !iter.Valid() {
+		return nil
+	}
+	return iter.iter.Key()
+}
+
+// Value returns the current value, which is only valid until the iterator is
+// moved.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Value() []byte {
+	if !iter.Valid() {
+		return nil
+	}
+	return iter.iter.Value()
+}
+
+// Error returns any accumulated error.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Error() error {
+	if iter.released {
+		return iter.err
+	}
+	return iter.iter.Error()
+}
+
+// SetReleaser sets the releaser that is called when the iterator is released.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) SetReleaser(releaser util.Releaser) {
+	iter.releaser = releaser
+}
+
+// Release closes the underlying Pebble iterator.
+//
+// This is part of the leveldb iterator.Iterator interface implementation.
+func (iter *pebbleIter) Release() {
+	// The iterator is already closed when the store was closed first.
+	iter.store.itersMtx.Lock()
+	if iter.released {
+		iter.store.itersMtx.Unlock()
+		return
+	}
+	iter.released = true
+	delete(iter.store.iters, iter)
+	iter.store.itersMtx.Unlock()
+
+	iter.iter.Close()
+	if iter.releaser != nil {
+		iter.releaser.Release()
+		iter.releaser = nil
+	}
+}
+
+// pebbleBatch applies the updates to a Pebble batch.
+type pebbleBatch struct {
+	batch *pebble.Batch
+}
+
+// Put sets the value for the passed key.
+//
+// This is part of the metadataBatch interface implementation.
+func (b *pebbleBatch) Put(key, value []byte) error {
+	return b.batch.Set(key, value, nil)
+}
+
+// Delete removes the passed key.
+//
+// This is part of the metadataBatch interface implementation.
+func (b *pebbleBatch) Delete(key []byte) error {
+	return b.batch.Delete(key, nil)
+}
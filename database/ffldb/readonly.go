@@ -0,0 +1,286 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ffldb
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// maxCheckpointAttempts is the number of times the metadata is copied
+	// for a read-only open before giving up.  The copy is made while the
+	// metadata store may be written to, and it's made again when a file
+	// it needs is removed by a compaction while it's being made.
+	maxCheckpointAttempts = 5
+
+	// checkpointRetryInterval is the time that's waited for before the
+	// metadata is copied again.
+	checkpointRetryInterval = 200 * time.Millisecond
+)
+
+// isTableFile returns whether the file with the passed name is a table of a
+// leveldb or Pebble metadata store.  Tables are never modified once they're
+// written, unlike the manifests and journals that are appended to.
+func isTableFile(name string) bool {
+	return strings.HasSuffix(name, ".ldb") || strings.HasSuffix(name, ".sst")
+}
+
+// isCheckpointFile returns whether the file with the passed name is a part of
+// a metadata store that has to be copied to open it.  The lock and the human
+// readable logs are left out.
+func isCheckpointFile(name string) bool {
+	return name != "LOCK" && name != "LOG" && name != "LOG.old"
+}
+
+// copyFile copies the first size bytes of the file at the passed source path,
+// or all of it when the size is negative, to the destination path.
+func copyFile(dst, src string, size int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		_, err = io.Copy(out, in)
+	} else {
+		_, err = io.CopyN(out, in, size)
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// linkOrCopyFile hard-links the file at the passed source path to the
+// destination path, or copies it when it can't be linked, such as when the
+// paths are on different filesystems.  It's only used for files that are never
+// modified once they're written.
+func linkOrCopyFile(dst, src string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(dst, src, -1)
+}
+
+// listCheckpointFiles returns the names of the files of the metadata store at
+// the passed path that are copied to open it, split into the tables and the
+// other files.
+func listCheckpointFiles(path string) ([]string, []string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tables, others []string
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case !entry.Type().IsRegular() || !isCheckpointFile(name):
+		case isTableFile(name):
+			tables = append(tables, name)
+		default:
+			others = append(others, name)
+		}
+	}
+	return tables, others, nil
+}
+
+// checkpointMetadata copies the leveldb or Pebble metadata store at the passed
+// path to the passed directory, which must not exist, while the store may be in
+// use by this or another process.  The tables are hard-linked when possible
+// since they're never modified, and the manifests and journals are copied.
+//
+// The manifests and journals are copied before the tables are listed so that
+// all of the tables they refer to are found.  An error is returned when a table
+// or another file is removed, or a new manifest or journal is started, while
+// the copy is made, in which case the caller tries again with retryCheckpoint.
+func checkpointMetadata(path, dir string) error {
+	_, others, err := listCheckpointFiles(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return err
+	}
+	err = func() error {
+		for _, name := range others {
+			err := copyFile(filepath.Join(dir, name),
+				filepath.Join(path, name), -1)
+			if err != nil {
+				return err
+			}
+		}
+
+		tables, othersAfter, err := listCheckpointFiles(path)
+		if err != nil {
+			return err
+		}
+		if strings.Join(others, "/") != strings.Join(othersAfter, "/") {
+			return fmt.Errorf("the manifests or journals of %s "+
+				"changed while they were copied", path)
+		}
+		for _, name := range tables {
+			err := linkOrCopyFile(filepath.Join(dir, name),
+				filepath.Join(path, name))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		os.RemoveAll(dir)
+	}
+	return err
+}
+
+// retryCheckpoint calls the passed function, which copies the metadata store at
+// the passed path, until it succeeds or it has failed maxCheckpointAttempts
+// times, in which case the last error is returned.
+func retryCheckpoint(path string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == maxCheckpointAttempts {
+			return err
+		}
+		log.Debugf("Unable to copy the metadata at %q, trying again: %v",
+			path, err)
+		time.Sleep(checkpointRetryInterval)
+	}
+}
+
+// openLdbStoreReadOnly opens the copy of a leveldb metadata store at the passed
+// path read-only with the passed options.  The journal of the copy may end with
+// a record that was only partially written when it was copied, so it's
+// recovered without the strict checks.
+func openLdbStoreReadOnly(path string, dbOpts Options) (metadataStore, error) {
+	opts := opt.Options{
+		ReadOnly:               true,
+		Strict:                 opt.DefaultStrict &^ opt.StrictJournalChecksum,
+		Compression:            opt.NoCompression,
+		Filter:                 filter.NewBloomFilter(10),
+		BlockCacheCapacity:     dbOpts.BlockCacheSize,
+		OpenFilesCacheCapacity: dbOpts.MaxOpenFiles,
+	}
+	ldb, err := leveldb.OpenFile(path, &opts)
+	if err != nil {
+		return nil, convertErr(err.Error(), err)
+	}
+	return &ldbStore{ldb: ldb, path: path}, nil
+}
+
+// openPebbleStoreReadOnly opens the copy of a Pebble metadata store at the
+// passed path read-only with the passed options.
+func openPebbleStoreReadOnly(path string, dbOpts Options) (metadataStore, error) {
+	cache := pebble.NewCache(int64(dbOpts.BlockCacheSize))
+	defer cache.Unref()
+
+	opts := &pebble.Options{
+		ReadOnly:     true,
+		Cache:        cache,
+		MaxOpenFiles: dbOpts.MaxOpenFiles,
+		Levels: []pebble.LevelOptions{{
+			FilterPolicy: bloom.FilterPolicy(10),
+		}},
+		Logger: pebbleLogger{},
+	}
+	pdb, err := pebble.Open(path, opts)
+	if err != nil {
+		return nil, convertErr(err.Error(), err)
+	}
+	return &pebbleStore{pdb: pdb}, nil
+}
+
+// openReadOnlyDB opens the database at the provided path read-only with the
+// passed options.  The
+// metadata store of a database may be locked by the process that has it open,
+// so it's opened from a copy of the metadata store, which is what the database
+// sees for as long as it's open.  The block files are only ever read, which is
+// safe while they're written to by another process since the metadata of the
+// copy only refers to the blocks that were written before it was made.
+// database.ErrDbDoesNotExist is returned if the database doesn't exist.
+func openReadOnlyDB(dbPath string, network wire.BitcoinNet, dbType string,
+	opts Options) (database.DB, error) {
+
+	metadataDbPath := filepath.Join(dbPath, metadataDbName)
+	if !fileExists(metadataDbPath) {
+		str := fmt.Sprintf("database %q does not exist", metadataDbPath)
+		return nil, makeDbErr(database.ErrDbDoesNotExist, str, nil)
+	}
+
+	openStore := openLdbStoreReadOnly
+	if dbType == pebbleDbType {
+		openStore = openPebbleStoreReadOnly
+	}
+	checkpointDir, err := os.MkdirTemp("", "ffldb-readonly-")
+	if err != nil {
+		return nil, err
+	}
+	storePath := filepath.Join(checkpointDir, metadataDbName)
+	var store metadataStore
+	err = retryCheckpoint(metadataDbPath, func() error {
+		err := checkpointMetadata(metadataDbPath, storePath)
+		if err != nil {
+			return err
+		}
+		store, err = openStore(storePath, opts)
+		if err != nil {
+			os.RemoveAll(storePath)
+		}
+		return err
+	})
+	if err != nil {
+		os.RemoveAll(checkpointDir)
+		str := fmt.Sprintf("unable to copy the metadata at %q to open "+
+			"it read-only", metadataDbPath)
+		return nil, makeDbErr(database.ErrDriverSpecific, str, err)
+	}
+
+	blkStore, err := newBlockStore(dbPath, network, opts)
+	if err != nil {
+		store.Close()
+		os.RemoveAll(checkpointDir)
+		return nil, fmt.Errorf("couldn't make a new block store. Err: %v", err)
+	}
+	sjStore, err := newSJStore(dbPath, network, opts)
+	if err != nil {
+		store.Close()
+		os.RemoveAll(checkpointDir)
+		return nil, fmt.Errorf("couldn't make a new spend journal store. Err: %v", err)
+	}
+
+	cache := newDbCache(store, blkStore, sjStore, uint64(opts.CacheSize),
+		defaultFlushSecs)
+	pdb := &db{blkStore: blkStore, sjStore: sjStore, cache: cache,
+		dbType: dbType, readOnly: true, checkpointDir: checkpointDir}
+
+	// Check the block files against the metadata without repairing them.
+	rodb, err := reconcileDB(pdb, false)
+	if err != nil {
+		pdb.Close()
+		return nil, err
+	}
+	return rodb, nil
+}
@@ -53,7 +53,7 @@ func reconcileDB(pdb *db, create bool) (database.DB, error) {
 	// Perform initial internal bucket and value creation during database
 	// creation.
 	if create {
-		if err := initDB(pdb.cache.ldb); err != nil {
+		if err := initDB(pdb.cache.store); err != nil {
 			return nil, err
 		}
 	}
@@ -107,7 +107,9 @@ func reconcileDB(pdb *db, create bool) (database.DB, error) {
 	if blkWC.curFileNum > blkCurFileNum || (blkWC.curFileNum == blkCurFileNum &&
 		blkWC.curOffset > blkCurOffset) {
 
-		log.Info("Detected unclean shutdown for block files- Repairing...")
+		if !pdb.readOnly {
+			log.Info("Detected unclean shutdown for block files- Repairing...")
+		}
 		log.Debugf("Metadata claims file %d, offset %d. Block data is "+
 			"at file %d, offset %d", blkCurFileNum, blkCurOffset,
 			blkWC.curFileNum, blkWC.curOffset)
@@ -138,7 +140,9 @@ func reconcileDB(pdb *db, create bool) (database.DB, error) {
 	if sjWC.curFileNum > sjCurFileNum || (sjWC.curFileNum == sjCurFileNum &&
 		sjWC.curOffset > sjCurOffset) {
 
-		log.Info("Detected unclean shutdown for undo files - Repairing...")
+		if !pdb.readOnly {
+			log.Info("Detected unclean shutdown for undo files - Repairing...")
+		}
 		log.Debugf("Metadata claims file %d, offset %d. Spend journal data is "+
 			"at file %d, offset %d", sjCurFileNum, sjCurOffset,
 			sjWC.curFileNum, sjWC.curOffset)
@@ -154,7 +158,10 @@ func reconcileDB(pdb *db, create bool) (database.DB, error) {
 		return nil, makeDbErr(database.ErrCorruption, str, nil)
 	}
 
-	if needsRollBack {
+	// The files of a database that's opened read-only are ahead of the
+	// metadata when blocks are written to it after the metadata was
+	// copied, which isn't repaired since the files aren't its to modify.
+	if needsRollBack && !pdb.readOnly {
 		pdb.blkStore.handleRollback(blkCurFileNum, blkCurOffset)
 		pdb.sjStore.handleRollback(sjCurFileNum, sjCurOffset)
 		log.Infof("Database sync complete")
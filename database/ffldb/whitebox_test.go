@@ -182,7 +182,8 @@ func TestCornerCases(t *testing.T) {
 	// directory is needed.
 	testName := "openDB: fail due to file at target location"
 	wantErrCode := database.ErrDriverSpecific
-	idb, err := openDB(dbPath, blockDataNet, true)
+	idb, err := openDB(dbPath, blockDataNet, true, dbType,
+		(&Options{}).withDefaults(dbType))
 	if !checkDbError(t, testName, err, wantErrCode) {
 		if err == nil {
 			idb.Close()
@@ -194,7 +195,8 @@ func TestCornerCases(t *testing.T) {
 	// Remove the file and create the database to run tests against.  It
 	// should be successful this time.
 	_ = os.RemoveAll(dbPath)
-	idb, err = openDB(dbPath, blockDataNet, true)
+	idb, err = openDB(dbPath, blockDataNet, true, dbType,
+		(&Options{}).withDefaults(dbType))
 	if err != nil {
 		t.Errorf("openDB: unexpected error: %v", err)
 		return
@@ -218,14 +220,14 @@ func TestCornerCases(t *testing.T) {
 	_ = os.RemoveAll(filePath)
 
 	// Close the underlying leveldb database out from under the database.
-	ldb := idb.(*db).cache.ldb
-	ldb.Close()
+	metaStore := idb.(*db).cache.store
+	metaStore.Close()
 
 	// Ensure initilization errors in the underlying database work as
 	// expected.
 	testName = "initDB: reinitialization"
 	wantErrCode = database.ErrDbNotOpen
-	err = initDB(ldb)
+	err = initDB(metaStore)
 	if !checkDbError(t, testName, err, wantErrCode) {
 		return
 	}
@@ -972,3 +974,56 @@ func TestFailureScenarios(t *testing.T) {
 	// Test various corruption scenarios.
 	testCorruption(tc)
 }
+
+// TestOptionsDefaults ensures that the options that aren't set are given their
+// defaults and that the ones that are set are kept.
+func TestOptionsDefaults(t *testing.T) {
+	t.Parallel()
+
+	opts := (*Options)(nil).withDefaults(dbType)
+	if opts.CacheSize < defaultCacheSize || opts.CacheSize > maxCacheSize {
+		t.Errorf("got cache size %d, want between %d and %d",
+			opts.CacheSize, defaultCacheSize, maxCacheSize)
+	}
+	if opts.WriteBufferSize < minLdbWriteBufferSize ||
+		opts.WriteBufferSize > maxLdbWriteBufferSize {
+
+		t.Errorf("got write buffer size %d, want between %d and %d",
+			opts.WriteBufferSize, minLdbWriteBufferSize,
+			maxLdbWriteBufferSize)
+	}
+	if opts.BlockCacheSize < minBlockCacheSize ||
+		opts.BlockCacheSize > maxBlockCacheSize {
+
+		t.Errorf("got block cache size %d, want between %d and %d",
+			opts.BlockCacheSize, minBlockCacheSize, maxBlockCacheSize)
+	}
+	if opts.MaxOpenFiles != defaultMaxOpenFiles ||
+		opts.MaxOpenBlockFiles != defaultMaxOpenBlockFiles ||
+		opts.FileWriteBufferSize != defaultFileWriteBufferSize {
+
+		t.Errorf("got open files %d, open block files %d and file "+
+			"write buffer size %d, want the defaults",
+			opts.MaxOpenFiles, opts.MaxOpenBlockFiles,
+			opts.FileWriteBufferSize)
+	}
+
+	// Pebble keeps its larger memtables.
+	opts = (&Options{}).withDefaults(pebbleDbType)
+	if opts.WriteBufferSize != pebbleMemTableSize {
+		t.Errorf("got Pebble write buffer size %d, want %d",
+			opts.WriteBufferSize, pebbleMemTableSize)
+	}
+
+	set := Options{
+		CacheSize:           1,
+		WriteBufferSize:     2,
+		BlockCacheSize:      3,
+		MaxOpenFiles:        4,
+		MaxOpenBlockFiles:   5,
+		FileWriteBufferSize: 6,
+	}
+	if opts := set.withDefaults(pebbleDbType); opts != set {
+		t.Errorf("got options %+v, want %+v", opts, set)
+	}
+}
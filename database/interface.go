@@ -504,6 +504,22 @@ type DB interface {
 	// user-supplied function will result in a panic.
 	Update(fn func(tx Tx) error) error
 
+	// Compact compacts the metadata storage while the database stays in
+	// use, which reclaims the space taken by the keys that were deleted or
+	// overwritten, such as those of a dropped index.  The metadata is
+	// compacted one range of keys at a time and the passed progress
+	// function, when it's not nil, is called after each of them with the
+	// number of ranges compacted so far and their total.  The compaction
+	// stops early without an error when the interrupt channel is closed.
+	Compact(interrupt <-chan struct{}, progress func(done, total int)) error
+
+	// Backup writes a copy of the database that can be opened like the
+	// original to the passed directory, which must not exist, while the
+	// database stays in use.  Write transactions wait for the copy to be
+	// made, so it has all of the transactions that were committed before
+	// Backup was called and none of the ones after.
+	Backup(dir string) error
+
 	// Close cleanly shuts down the database and syncs all data.  It will
 	// block until all database transactions have been finalized (rolled
 	// back or committed).
@@ -0,0 +1,202 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/utreexo/utreexod/database"
+)
+
+// dbCompactCheckInterval is how often it's checked whether a scheduled
+// compaction of the block database is due and the node is idle.
+const dbCompactCheckInterval = time.Minute
+
+// errCompactionRunning is returned when a compaction of the block database is
+// requested while one is already running.
+var errCompactionRunning = errors.New("a compaction of the block database " +
+	"is already running")
+
+// dbCompactionStatus is the progress of the running compaction of the block
+// database, or of the last one when none is running.
+type dbCompactionStatus struct {
+	running       bool
+	done          int
+	total         int
+	started       time.Time
+	finished      time.Time
+	err           error
+	nextScheduled time.Time
+}
+
+// dbCompactor compacts the metadata of the block database to reclaim the space
+// of the data that was deleted, such as that of dropped indexes and pruned
+// blocks, without stopping the node.  Compactions are started with the
+// compactdb RPC and, when --dbcompactinterval is set, every interval once the
+// chain is synced and no block was connected since the last check.
+type dbCompactor struct {
+	db       database.DB
+	interval time.Duration
+
+	// isCurrent and bestHeight tell whether the node is idle.
+	isCurrent  func() bool
+	bestHeight func() int32
+
+	mtx    sync.Mutex
+	status dbCompactionStatus
+
+	wg       sync.WaitGroup
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+// newDBCompactor returns a compactor of the passed database that compacts it
+// every interval while the node is idle, or only on request when the interval
+// is 0.
+func newDBCompactor(db database.DB, interval time.Duration,
+	isCurrent func() bool, bestHeight func() int32) *dbCompactor {
+
+	c := &dbCompactor{
+		db:         db,
+		interval:   interval,
+		isCurrent:  isCurrent,
+		bestHeight: bestHeight,
+		quit:       make(chan struct{}),
+	}
+	if interval > 0 {
+		c.status.nextScheduled = time.Now().Add(interval)
+	}
+	return c
+}
+
+// compact starts a compaction of the block database in the background.  It
+// returns errCompactionRunning when one is already running.
+func (c *dbCompactor) compact() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	select {
+	case <-c.quit:
+		return errors.New("the server is shutting down")
+	default:
+	}
+	if c.status.running {
+		return errCompactionRunning
+	}
+	c.status = dbCompactionStatus{
+		running:       true,
+		started:       time.Now(),
+		nextScheduled: c.status.nextScheduled,
+	}
+
+	c.wg.Add(1)
+	go c.run()
+	return nil
+}
+
+// run compacts the block database and records how it went.  It must be run as
+// a goroutine.
+func (c *dbCompactor) run() {
+	defer c.wg.Done()
+
+	srvrLog.Infof("Compacting the block database")
+	err := c.db.Compact(c.quit, func(done, total int) {
+		c.mtx.Lock()
+		c.status.done = done
+		c.status.total = total
+		c.mtx.Unlock()
+	})
+
+	c.mtx.Lock()
+	c.status.running = false
+	c.status.finished = time.Now()
+	c.status.err = err
+	if c.interval > 0 {
+		c.status.nextScheduled = c.status.finished.Add(c.interval)
+	}
+	status := c.status
+	c.mtx.Unlock()
+
+	switch {
+	case err != nil:
+		srvrLog.Errorf("Unable to compact the block database: %v", err)
+	case status.total == 0 || status.done < status.total:
+		srvrLog.Infof("Compaction of the block database interrupted "+
+			"after %d of %d key ranges", status.done, status.total)
+	default:
+		srvrLog.Infof("Compacted the block database in %v",
+			status.finished.Sub(status.started).Round(time.Second))
+	}
+}
+
+// progress returns the progress of the running compaction, or of the last one
+// when none is running.
+func (c *dbCompactor) progress() dbCompactionStatus {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.status
+}
+
+// due returns whether a scheduled compaction is due at the passed time.
+func (c *dbCompactor) due(now time.Time) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.interval > 0 && !c.status.running &&
+		!now.Before(c.status.nextScheduled)
+}
+
+// scheduleHandler starts the scheduled compactions once they're due and the
+// node is idle, which is when the chain is synced and its tip didn't change
+// since the last check.  It must be run as a goroutine.
+func (c *dbCompactor) scheduleHandler() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(dbCompactCheckInterval)
+	defer ticker.Stop()
+
+	lastHeight := c.bestHeight()
+	for {
+		select {
+		case <-ticker.C:
+			height := c.bestHeight()
+			idle := height == lastHeight && c.isCurrent()
+			lastHeight = height
+			if !idle || !c.due(time.Now()) {
+				continue
+			}
+			if err := c.compact(); err != nil {
+				srvrLog.Debugf("Scheduled compaction of the "+
+					"block database not started: %v", err)
+			}
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// Start starts the scheduled compactions if --dbcompactinterval is set.
+func (c *dbCompactor) Start() {
+	if c.interval <= 0 {
+		return
+	}
+	srvrLog.Infof("Compacting the block database every %v while idle",
+		c.interval)
+	c.wg.Add(1)
+	go c.scheduleHandler()
+}
+
+// Stop stops the scheduled compactions and interrupts the running one, and
+// waits for the range of keys being compacted to finish.
+func (c *dbCompactor) Stop() {
+	c.stopOnce.Do(func() {
+		c.mtx.Lock()
+		close(c.quit)
+		c.mtx.Unlock()
+	})
+	c.wg.Wait()
+}
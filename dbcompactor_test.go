@@ -0,0 +1,70 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+	"github.com/utreexo/utreexod/database"
+	_ "github.com/utreexo/utreexod/database/ffldb"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestDBCompactor ensures that a requested compaction of the block database
+// runs to completion in the background and that the scheduled ones are only due
+// once the interval went by.
+func TestDBCompactor(t *testing.T) {
+	// There's no log rotator to write the progress of the compaction to.
+	logger := srvrLog
+	srvrLog = btclog.Disabled
+	defer func() { srvrLog = logger }()
+
+	db, err := database.Create("ffldb", t.TempDir(), wire.SimNet)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	defer db.Close()
+
+	c := newDBCompactor(db, time.Hour, func() bool { return true },
+		func() int32 { return 0 })
+	defer c.Stop()
+
+	now := time.Now()
+	if c.due(now) {
+		t.Fatalf("expected no compaction to be due before the interval")
+	}
+	if !c.due(now.Add(time.Hour)) {
+		t.Fatalf("expected a compaction to be due after the interval")
+	}
+
+	if err := c.compact(); err != nil {
+		t.Fatalf("compact: unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	status := c.progress()
+	for status.running && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		status = c.progress()
+	}
+	if status.running || status.err != nil {
+		t.Fatalf("compaction didn't finish: running %v, error %v",
+			status.running, status.err)
+	}
+	if status.total == 0 || status.done != status.total {
+		t.Fatalf("got %d of %d ranges done", status.done, status.total)
+	}
+	if !status.nextScheduled.After(now.Add(time.Hour)) {
+		t.Fatalf("expected the next compaction to be scheduled an " +
+			"interval after the last one")
+	}
+
+	// No compaction is started once the compactor is stopped.
+	c.Stop()
+	if err := c.compact(); err == nil {
+		t.Fatalf("expected compact to fail once stopped")
+	}
+}
@@ -0,0 +1,634 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/utreexo/utreexod/btcjson"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/txscript"
+)
+
+const (
+	// defaultDescriptorRange is the number of child keys that are derived
+	// for ranged descriptors when no range is given, the same as Bitcoin
+	// Core.
+	defaultDescriptorRange = 1000
+
+	// maxDescriptorRange is the maximum number of child keys that may be
+	// derived for a ranged descriptor.
+	maxDescriptorRange = 1000000
+
+	// pubKeyBytesLenUncompressed is the length of an uncompressed public
+	// key.
+	pubKeyBytesLenUncompressed = 65
+)
+
+// descriptorInputCharset are the characters that may be part of an output
+// descriptor, in the order used by the descriptor checksum.
+const descriptorInputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+	"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+	"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+
+// descriptorChecksumCharset are the characters of a descriptor checksum.
+const descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// descriptorPolyMod computes the BCH code of the descriptor checksum given the
+// code of the preceding characters and the next value.
+func descriptorPolyMod(c uint64, val uint64) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ val
+	if c0&1 != 0 {
+		c ^= 0xf5dee51989
+	}
+	if c0&2 != 0 {
+		c ^= 0xa9fdca3312
+	}
+	if c0&4 != 0 {
+		c ^= 0x1bab10e32d
+	}
+	if c0&8 != 0 {
+		c ^= 0x3706b1677a
+	}
+	if c0&16 != 0 {
+		c ^= 0x644d626ffd
+	}
+	return c
+}
+
+// descriptorChecksum returns the checksum of an output descriptor the same way
+// Bitcoin Core computes it.  An empty string is returned when the descriptor
+// contains characters that may not be part of a descriptor.
+func descriptorChecksum(desc string) string {
+	c := uint64(1)
+	var cls, clsCount uint64
+	for _, ch := range desc {
+		pos := strings.IndexRune(descriptorInputCharset, ch)
+		if pos < 0 {
+			return ""
+		}
+		c = descriptorPolyMod(c, uint64(pos&31))
+		cls = cls*3 + uint64(pos>>5)
+		clsCount++
+		if clsCount == 3 {
+			c = descriptorPolyMod(c, cls)
+			cls = 0
+			clsCount = 0
+		}
+	}
+	if clsCount > 0 {
+		c = descriptorPolyMod(c, cls)
+	}
+	for i := 0; i < 8; i++ {
+		c = descriptorPolyMod(c, 0)
+	}
+	c ^= 1
+
+	checksum := make([]byte, 8)
+	for i := range checksum {
+		checksum[i] = descriptorChecksumCharset[(c>>(5*(7-i)))&31]
+	}
+	return string(checksum)
+}
+
+// descriptorContext is where a script expression of a descriptor appears,
+// which limits the expressions and keys that may be used.
+type descriptorContext int
+
+const (
+	descriptorTop descriptorContext = iota
+	descriptorP2SH
+	descriptorP2WSH
+	descriptorP2TR
+)
+
+// descriptorScript is an output script expanded from a descriptor along with
+// the descriptor of only that script, which has a checksum and uses the
+// derived public keys.
+type descriptorScript struct {
+	script []byte
+	desc   string
+}
+
+// descriptorKey is a public key of a descriptor along with its serialization
+// in the scripts, which is x-only for taproot.
+type descriptorKey struct {
+	pubKey     *btcec.PublicKey
+	serialized []byte
+}
+
+// descriptorExpander expands the scripts of an output descriptor.
+type descriptorExpander struct {
+	params     *chaincfg.Params
+	rangeBegin uint32
+	rangeEnd   uint32
+
+	// ranged, private and unsolvable are set once a ranged key, a private
+	// key or a script that isn't made of keys is parsed.
+	ranged     bool
+	private    bool
+	unsolvable bool
+
+	// publicKeys maps the private keys that were parsed to their public
+	// keys.
+	publicKeys map[string]string
+}
+
+// descriptorInfo describes an output descriptor the same way as the
+// getdescriptorinfo command of Bitcoin Core.
+type descriptorInfo struct {
+	// public is the descriptor with its private keys replaced by their
+	// public keys along with its checksum.
+	public string
+
+	// checksum is the checksum of the descriptor as given.
+	checksum string
+
+	isRange        bool
+	isSolvable     bool
+	hasPrivateKeys bool
+}
+
+// expandDescriptor returns the output scripts described by an output
+// descriptor.  The pk(), pkh(), wpkh(), sh(), wsh(), combo(), tr(), addr() and
+// raw() descriptors are supported, except for taproot script trees.  Keys may
+// be hex-encoded public keys, WIF private keys or extended keys followed by a
+// derivation path, where the children from rangeBegin to rangeEnd are derived
+// for a path ending with "*".  The checksum of the descriptor is optional but
+// has to be valid when given.
+func expandDescriptor(desc string, rangeBegin, rangeEnd uint32,
+	params *chaincfg.Params) ([]descriptorScript, error) {
+
+	scripts, _, err := parseDescriptor(desc, rangeBegin, rangeEnd, params)
+	return scripts, err
+}
+
+// describeDescriptor returns the description of an output descriptor, which
+// is parsed the same way as by expandDescriptor.
+func describeDescriptor(desc string, params *chaincfg.Params) (
+	*descriptorInfo, error) {
+
+	_, e, err := parseDescriptor(desc, 0, 0, params)
+	if err != nil {
+		return nil, err
+	}
+
+	body := desc
+	if i := strings.LastIndexByte(desc, '#'); i >= 0 {
+		body = desc[:i]
+	}
+	public := body
+	for privKey, pubKey := range e.publicKeys {
+		public = strings.ReplaceAll(public, privKey, pubKey)
+	}
+
+	return &descriptorInfo{
+		public:         public + "#" + descriptorChecksum(public),
+		checksum:       descriptorChecksum(body),
+		isRange:        e.ranged,
+		isSolvable:     !e.unsolvable,
+		hasPrivateKeys: e.private,
+	}, nil
+}
+
+// parseDescriptor verifies the checksum of an output descriptor when it's given
+// and expands its scripts.  The expander is returned for what it learned about
+// the descriptor.
+func parseDescriptor(desc string, rangeBegin, rangeEnd uint32,
+	params *chaincfg.Params) ([]descriptorScript, *descriptorExpander, error) {
+
+	body := desc
+	if i := strings.LastIndexByte(desc, '#'); i >= 0 {
+		body = desc[:i]
+		checksum := descriptorChecksum(body)
+		if checksum == "" || checksum != desc[i+1:] {
+			return nil, nil, invalidDescriptorError(desc, fmt.Sprintf(
+				"expected checksum %s", checksum))
+		}
+	}
+
+	e := &descriptorExpander{
+		params:     params,
+		rangeBegin: rangeBegin,
+		rangeEnd:   rangeEnd,
+		publicKeys: make(map[string]string),
+	}
+	scripts, err := e.expand(body, descriptorTop)
+	if err != nil {
+		return nil, nil, invalidDescriptorError(desc, err.Error())
+	}
+	for i := range scripts {
+		scripts[i].desc += "#" + descriptorChecksum(scripts[i].desc)
+	}
+
+	return scripts, e, nil
+}
+
+// invalidDescriptorError returns the RPC error of an invalid descriptor.
+func invalidDescriptorError(desc, str string) error {
+	return btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+		fmt.Sprintf("Invalid descriptor '%s': %s", desc, str))
+}
+
+// splitDescriptorExpr splits an expression of the form name(args) into its
+// name and arguments.
+func splitDescriptorExpr(expr string) (string, string, error) {
+	open := strings.IndexByte(expr, '(')
+	if open <= 0 || !strings.HasSuffix(expr, ")") {
+		return "", "", fmt.Errorf("'%s' is not a valid expression", expr)
+	}
+	return expr[:open], expr[open+1 : len(expr)-1], nil
+}
+
+// expand returns the scripts of a script expression in the given context.
+func (e *descriptorExpander) expand(expr string, ctx descriptorContext) (
+	[]descriptorScript, error) {
+
+	name, arg, err := splitDescriptorExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "addr", "raw", "combo", "tr":
+		if ctx != descriptorTop {
+			return nil, fmt.Errorf("%s() can only be used at the top "+
+				"level", name)
+		}
+	}
+
+	switch name {
+	case "addr":
+		e.unsolvable = true
+		addr, err := btcutil.DecodeAddress(arg, e.params)
+		if err != nil || !addr.IsForNet(e.params) {
+			return nil, fmt.Errorf("invalid address")
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		return []descriptorScript{{script: script, desc: expr}}, nil
+
+	case "raw":
+		e.unsolvable = true
+		script, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid script hex")
+		}
+		return []descriptorScript{{script: script, desc: expr}}, nil
+
+	case "pk", "pkh", "wpkh", "combo", "tr":
+		if name == "tr" && strings.IndexByte(arg, ',') >= 0 {
+			return nil, fmt.Errorf("taproot script trees aren't " +
+				"supported")
+		}
+		if name == "wpkh" && ctx == descriptorP2WSH {
+			return nil, fmt.Errorf("wpkh() can't be used in wsh()")
+		}
+		// The keys of wpkh() have the same restrictions as the keys
+		// in wsh().
+		keyCtx := ctx
+		if name == "tr" {
+			keyCtx = descriptorP2TR
+		} else if name == "wpkh" {
+			keyCtx = descriptorP2WSH
+		}
+		keys, err := e.parseKey(arg, keyCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		var scripts []descriptorScript
+		for _, key := range keys {
+			keyScripts, err := e.keyScripts(name, key)
+			if err != nil {
+				return nil, err
+			}
+			scripts = append(scripts, keyScripts...)
+		}
+		return scripts, nil
+
+	case "sh", "wsh":
+		if ctx == descriptorP2WSH || (name == "sh" && ctx != descriptorTop) {
+			return nil, fmt.Errorf("%s() can't be nested here", name)
+		}
+		innerCtx := descriptorP2SH
+		if name == "wsh" {
+			innerCtx = descriptorP2WSH
+		}
+		inner, err := e.expand(arg, innerCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		scripts := make([]descriptorScript, 0, len(inner))
+		for _, s := range inner {
+			var addr btcutil.Address
+			if name == "sh" {
+				addr, err = btcutil.NewAddressScriptHash(s.script,
+					e.params)
+			} else {
+				witnessProg := sha256.Sum256(s.script)
+				addr, err = btcutil.NewAddressWitnessScriptHash(
+					witnessProg[:], e.params)
+			}
+			if err != nil {
+				return nil, err
+			}
+			script, err := txscript.PayToAddrScript(addr)
+			if err != nil {
+				return nil, err
+			}
+			scripts = append(scripts, descriptorScript{
+				script: script,
+				desc:   name + "(" + s.desc + ")",
+			})
+		}
+		return scripts, nil
+	}
+
+	return nil, fmt.Errorf("%s() isn't supported", name)
+}
+
+// keyScripts returns the scripts of a key expression of the given type.
+func (e *descriptorExpander) keyScripts(name string, key descriptorKey) (
+	[]descriptorScript, error) {
+
+	var scripts []descriptorScript
+	addAddr := func(desc string, addr btcutil.Address, err error) error {
+		if err != nil {
+			return err
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return err
+		}
+		scripts = append(scripts, descriptorScript{
+			script: script,
+			desc:   desc,
+		})
+		return nil
+	}
+
+	keyStr := hex.EncodeToString(key.serialized)
+	pubKeyHash := btcutil.Hash160(key.serialized)
+	if name == "pk" || name == "combo" {
+		script, err := txscript.NewScriptBuilder().
+			AddData(key.serialized).AddOp(txscript.OP_CHECKSIG).Script()
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, descriptorScript{
+			script: script,
+			desc:   "pk(" + keyStr + ")",
+		})
+	}
+	if name == "pkh" || name == "combo" {
+		addr, err := btcutil.NewAddressPubKeyHash(pubKeyHash, e.params)
+		err = addAddr("pkh("+keyStr+")", addr, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only compressed keys have segwit scripts.
+	compressed := len(key.serialized) == btcec.PubKeyBytesLenCompressed
+	if name == "wpkh" || (name == "combo" && compressed) {
+		addr, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash,
+			e.params)
+		err = addAddr("wpkh("+keyStr+")", addr, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if name == "combo" && compressed {
+		witnessScript := scripts[len(scripts)-1].script
+		addr, err := btcutil.NewAddressScriptHash(witnessScript, e.params)
+		err = addAddr("sh(wpkh("+keyStr+"))", addr, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if name == "tr" {
+		outputKey := txscript.ComputeTaprootKeyNoScript(key.pubKey)
+		addr, err := btcutil.NewAddressTaproot(
+			schnorr.SerializePubKey(outputKey), e.params)
+		err = addAddr("tr("+keyStr+")", addr, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return scripts, nil
+}
+
+// parseKey returns the public keys of a key expression in the given context.
+// Extended keys with a path ending with "*" return the keys of all the children
+// in the range of the expander.
+func (e *descriptorExpander) parseKey(expr string, ctx descriptorContext) (
+	[]descriptorKey, error) {
+
+	// The origin of the key is only informational.
+	if strings.HasPrefix(expr, "[") {
+		end := strings.IndexByte(expr, ']')
+		if end < 0 {
+			return nil, fmt.Errorf("key origin start '[' has no " +
+				"matching ']'")
+		}
+		origin := strings.Split(expr[1:end], "/")
+		if _, err := hex.DecodeString(origin[0]); err != nil ||
+			len(origin[0]) != 8 {
+
+			return nil, fmt.Errorf("fingerprint '%s' is not 4 bytes "+
+				"of hex", origin[0])
+		}
+		for _, step := range origin[1:] {
+			if _, err := parseDerivationStep(step); err != nil {
+				return nil, err
+			}
+		}
+		expr = expr[end+1:]
+	}
+
+	path := strings.Split(expr, "/")
+	if len(path) == 1 {
+		key, err := e.parseSingleKey(expr, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []descriptorKey{key}, nil
+	}
+
+	extKey, err := hdkeychain.NewKeyFromString(path[0])
+	if err != nil {
+		return nil, fmt.Errorf("key '%s' is not valid", path[0])
+	}
+	version := extKey.Version()
+	if !bytes.Equal(version, e.params.HDPublicKeyID[:]) &&
+		!bytes.Equal(version, e.params.HDPrivateKeyID[:]) {
+
+		return nil, fmt.Errorf("extended key '%s' is for another "+
+			"network", path[0])
+	}
+	if extKey.IsPrivate() {
+		pubKey, err := extKey.Neuter()
+		if err != nil {
+			return nil, err
+		}
+		e.private = true
+		e.publicKeys[path[0]] = pubKey.String()
+	}
+
+	// Derive the fixed part of the path.
+	steps := path[1:]
+	last := steps[len(steps)-1]
+	ranged := last == "*" || last == "*'" || last == "*h"
+	if ranged {
+		e.ranged = true
+		steps = steps[:len(steps)-1]
+	}
+	for _, step := range steps {
+		index, err := parseDerivationStep(step)
+		if err != nil {
+			return nil, err
+		}
+		extKey, err = deriveDescriptorChild(extKey, index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !ranged {
+		pubKey, err := extKey.ECPubKey()
+		if err != nil {
+			return nil, err
+		}
+		return []descriptorKey{newDescriptorKey(pubKey, ctx)}, nil
+	}
+
+	var hardened uint32
+	if last != "*" {
+		hardened = hdkeychain.HardenedKeyStart
+	}
+	keys := make([]descriptorKey, 0, e.rangeEnd-e.rangeBegin+1)
+	for i := e.rangeBegin; i <= e.rangeEnd; i++ {
+		child, err := deriveDescriptorChild(extKey, i+hardened)
+		if err == hdkeychain.ErrInvalidChild {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		pubKey, err := child.ECPubKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, newDescriptorKey(pubKey, ctx))
+	}
+
+	return keys, nil
+}
+
+// parseSingleKey parses a hex-encoded public key or a WIF private key.  Only
+// taproot allows x-only public keys and segwit doesn't allow uncompressed keys.
+func (e *descriptorExpander) parseSingleKey(expr string,
+	ctx descriptorContext) (descriptorKey, error) {
+
+	serialized, err := hex.DecodeString(expr)
+	if err != nil {
+		wif, err := btcutil.DecodeWIF(expr)
+		if err != nil {
+			return descriptorKey{}, fmt.Errorf("key '%s' is not valid",
+				expr)
+		}
+		if !wif.IsForNet(e.params) {
+			return descriptorKey{}, fmt.Errorf("private key '%s' is "+
+				"for another network", expr)
+		}
+		serialized = wif.SerializePubKey()
+		if ctx == descriptorP2TR {
+			serialized = serialized[1:]
+		}
+		e.private = true
+		e.publicKeys[expr] = hex.EncodeToString(serialized)
+	}
+
+	var pubKey *btcec.PublicKey
+	switch {
+	case len(serialized) == schnorr.PubKeyBytesLen && ctx == descriptorP2TR:
+		pubKey, err = schnorr.ParsePubKey(serialized)
+	case len(serialized) == btcec.PubKeyBytesLenCompressed:
+		pubKey, err = btcec.ParsePubKey(serialized)
+	case len(serialized) == pubKeyBytesLenUncompressed &&
+		(ctx == descriptorTop || ctx == descriptorP2SH):
+
+		pubKey, err = btcec.ParsePubKey(serialized)
+	default:
+		return descriptorKey{}, fmt.Errorf("public key '%s' has an "+
+			"invalid size for this context", expr)
+	}
+	if err != nil {
+		return descriptorKey{}, fmt.Errorf("public key '%s' is not "+
+			"valid", expr)
+	}
+
+	return descriptorKey{pubKey: pubKey, serialized: serialized}, nil
+}
+
+// newDescriptorKey returns the descriptor key of a derived public key, which is
+// written x-only for taproot and compressed otherwise.
+func newDescriptorKey(pubKey *btcec.PublicKey, ctx descriptorContext) descriptorKey {
+	if ctx == descriptorP2TR {
+		return descriptorKey{
+			pubKey:     pubKey,
+			serialized: schnorr.SerializePubKey(pubKey),
+		}
+	}
+	return descriptorKey{
+		pubKey:     pubKey,
+		serialized: pubKey.SerializeCompressed(),
+	}
+}
+
+// parseDerivationStep returns the child index of a step of a derivation path.
+func parseDerivationStep(step string) (uint32, error) {
+	hardened := strings.HasSuffix(step, "'") || strings.HasSuffix(step, "h")
+	number := step
+	if hardened {
+		number = step[:len(step)-1]
+	}
+	index, err := strconv.ParseUint(number, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("key path value '%s' is not a "+
+			"valid uint31", step)
+	}
+	if hardened {
+		index += hdkeychain.HardenedKeyStart
+	}
+	return uint32(index), nil
+}
+
+// deriveDescriptorChild derives the child of an extended key, which has to be a
+// private key for hardened children.
+func deriveDescriptorChild(extKey *hdkeychain.ExtendedKey, index uint32) (
+	*hdkeychain.ExtendedKey, error) {
+
+	if index >= hdkeychain.HardenedKeyStart && !extKey.IsPrivate() {
+		return nil, fmt.Errorf("hardened derivation requires a private " +
+			"key")
+	}
+	return extKey.Derive(index)
+}
@@ -0,0 +1,389 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	btcdcfg "github.com/btcsuite/btcd/chaincfg"
+	"github.com/utreexo/utreexod/btcjson"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/txscript"
+)
+
+// TestExpandDescriptor ensures output descriptors are expanded into their
+// scripts and that their checksums are verified.
+func TestExpandDescriptor(t *testing.T) {
+	// The checksum from the descriptor documentation of Bitcoin Core.
+	desc := "pkh([f34db33f/44'/0'/0']xpub6Cc939fyHvfB9pPLWd3bSyyQFvgKbwhidca49jGCM5Hz5ypEPGf9JVXB4NBuUfPgoHnMjN6oNgdC9KRqM11RZtL8QLW6rFKziNwHDYhZ6Kx/0/*)"
+	if checksum := descriptorChecksum(desc); checksum != "ed7px9nu" {
+		t.Fatalf("expected checksum ed7px9nu, got %s", checksum)
+	}
+
+	// The generator point, of which the public key hash is the witness
+	// program of the P2WPKH example of BIP 173.
+	const g = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	const uncompressedG = "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+	const pkHash = "751e76e8199196d454941c45d1b3a323f1433bd6"
+	p2wpkh := "0014" + pkHash
+	p2shP2wpkh := "a914" + hex.EncodeToString(
+		btcutil.Hash160(hexToBytes(p2wpkh))) + "87"
+
+	// The first receiving addresses of the BIP 44 and BIP 86 test
+	// mnemonic of the accounts with the extended keys.
+	const bip44XPub = "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj"
+	bip44Script := addrScriptHex(t, "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA")
+	const bip86XPub = "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ"
+	const bip86Internal = "cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115"
+	const bip86Script = "5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c"
+
+	tests := []struct {
+		name    string
+		desc    string
+		scripts []string
+		descs   []string
+		valid   bool
+	}{
+		{
+			name:    "address",
+			desc:    "addr(bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4)",
+			scripts: []string{p2wpkh},
+			valid:   true,
+		},
+		{
+			name:    "raw script",
+			desc:    "raw(" + p2wpkh + ")",
+			scripts: []string{p2wpkh},
+			descs:   []string{"raw(" + p2wpkh + ")"},
+			valid:   true,
+		},
+		{
+			name:    "raw script with checksum",
+			desc:    "raw(" + p2wpkh + ")#" + descriptorChecksum("raw("+p2wpkh+")"),
+			scripts: []string{p2wpkh},
+			valid:   true,
+		},
+		{
+			name:    "pk",
+			desc:    "pk(" + g + ")",
+			scripts: []string{"21" + g + "ac"},
+			valid:   true,
+		},
+		{
+			name:    "pkh with an uncompressed key",
+			desc:    "pkh(" + uncompressedG + ")",
+			scripts: []string{"76a91491b24bf9f5288532960ac687abb035127b1d28a588ac"},
+			valid:   true,
+		},
+		{
+			name:    "sh(wpkh)",
+			desc:    "sh(wpkh(" + g + "))",
+			scripts: []string{p2shP2wpkh},
+			descs:   []string{"sh(wpkh(" + g + "))"},
+			valid:   true,
+		},
+		{
+			name: "combo",
+			desc: "combo(" + g + ")",
+			scripts: []string{
+				"21" + g + "ac",
+				"76a914" + pkHash + "88ac",
+				p2wpkh,
+				p2shP2wpkh,
+			},
+			valid: true,
+		},
+		{
+			name:    "pkh of a ranged extended key",
+			desc:    "pkh([73c5da0a/44'/0'/0']" + bip44XPub + "/0/*)",
+			scripts: []string{bip44Script},
+			valid:   true,
+		},
+		{
+			name:    "tr of a ranged extended key",
+			desc:    "tr(" + bip86XPub + "/0/*)",
+			scripts: []string{bip86Script},
+			descs:   []string{"tr(" + bip86Internal + ")"},
+			valid:   true,
+		},
+		{
+			name:    "tr of an x-only key",
+			desc:    "tr(" + bip86Internal + ")",
+			scripts: []string{bip86Script},
+			valid:   true,
+		},
+		{
+			name: "invalid checksum",
+			desc: "raw(" + p2wpkh + ")#qqqqqqqq",
+		},
+		{
+			name: "address of another network",
+			desc: "addr(tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx)",
+		},
+		{
+			name: "invalid hex",
+			desc: "raw(0g)",
+		},
+		{
+			name: "uncompressed key in segwit",
+			desc: "wpkh(" + uncompressedG + ")",
+		},
+		{
+			name: "x-only key outside of taproot",
+			desc: "pkh(" + bip86Internal + ")",
+		},
+		{
+			name: "hardened derivation of a public key",
+			desc: "pkh(" + bip44XPub + "/0'/*)",
+		},
+		{
+			name: "nested sh",
+			desc: "sh(sh(pkh(" + g + ")))",
+		},
+		{
+			name: "wpkh in wsh",
+			desc: "wsh(wpkh(" + g + "))",
+		},
+		{
+			name: "taproot script tree",
+			desc: "tr(" + g + ",pk(" + g + "))",
+		},
+		{
+			name: "unsupported descriptor",
+			desc: "multi(1," + g + ")",
+		},
+	}
+
+	for _, test := range tests {
+		scripts, err := expandDescriptor(test.desc, 0, 0,
+			&chaincfg.MainNetParams)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if len(scripts) != len(test.scripts) {
+			t.Errorf("%s: expected %d scripts, got %d", test.name,
+				len(test.scripts), len(scripts))
+			continue
+		}
+		for i, script := range scripts {
+			if hex.EncodeToString(script.script) != test.scripts[i] {
+				t.Errorf("%s: expected script %s, got %x", test.name,
+					test.scripts[i], script.script)
+			}
+			if i >= len(test.descs) {
+				continue
+			}
+			want := test.descs[i] + "#" + descriptorChecksum(test.descs[i])
+			if script.desc != want {
+				t.Errorf("%s: expected descriptor %s, got %s",
+					test.name, want, script.desc)
+			}
+		}
+	}
+
+	// The children in the range are derived for ranged descriptors.
+	scripts, err := expandDescriptor("pkh("+bip44XPub+"/0/*)", 0, 9,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) != 10 {
+		t.Fatalf("expected 10 scripts, got %d", len(scripts))
+	}
+	if hex.EncodeToString(scripts[0].script) != bip44Script {
+		t.Fatalf("expected the first script %s, got %x", bip44Script,
+			scripts[0].script)
+	}
+}
+
+// hexToBytes decodes a hex string that is known to be valid.
+func hexToBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// addrScriptHex returns the hex-encoded output script of a mainnet address.
+func addrScriptHex(t *testing.T, encoded string) string {
+	addr, err := btcutil.DecodeAddress(encoded, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(script)
+}
+
+// TestDescribeDescriptor ensures descriptors are described the same way as by
+// the getdescriptorinfo command of Bitcoin Core.
+func TestDescribeDescriptor(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	const g = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+	// The private key 1, of which the public key is the generator point.
+	privKey, _ := btcec.PrivKeyFromBytes([]byte{1})
+	wif, err := btcutil.NewWIF(privKey, params, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := hdkeychain.NewMaster(bytes.Repeat([]byte{1}, 32),
+		&btcdcfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xpub, err := master.Neuter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		desc   string
+		public string
+		want   descriptorInfo
+	}{
+		{
+			name:   "public key",
+			desc:   "wpkh(" + g + ")",
+			public: "wpkh(" + g + ")",
+			want:   descriptorInfo{isSolvable: true},
+		},
+		{
+			name:   "private key",
+			desc:   "sh(pkh(" + wif.String() + "))",
+			public: "sh(pkh(" + g + "))",
+			want:   descriptorInfo{isSolvable: true, hasPrivateKeys: true},
+		},
+		{
+			name:   "x-only private key",
+			desc:   "tr(" + wif.String() + ")",
+			public: "tr(" + g[2:] + ")",
+			want:   descriptorInfo{isSolvable: true, hasPrivateKeys: true},
+		},
+		{
+			name:   "ranged extended private key",
+			desc:   "pkh([d34db33f/44h]" + master.String() + "/1h/*)",
+			public: "pkh([d34db33f/44h]" + xpub.String() + "/1h/*)",
+			want: descriptorInfo{isRange: true, isSolvable: true,
+				hasPrivateKeys: true},
+		},
+		{
+			name:   "address",
+			desc:   "addr(bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4)",
+			public: "addr(bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4)",
+		},
+	}
+
+	for _, test := range tests {
+		// The checksum is of the descriptor as given, with or without
+		// its checksum.
+		for _, desc := range []string{test.desc,
+			test.desc + "#" + descriptorChecksum(test.desc)} {
+
+			info, err := describeDescriptor(desc, params)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+				continue
+			}
+			want := test.want
+			want.public = test.public + "#" + descriptorChecksum(test.public)
+			want.checksum = descriptorChecksum(test.desc)
+			if *info != want {
+				t.Errorf("%s: expected %+v, got %+v", test.name,
+					want, *info)
+			}
+		}
+	}
+}
+
+// TestHandleDeriveAddresses ensures addresses are derived from the descriptors
+// that have their checksums and a range only when they're ranged.
+func TestHandleDeriveAddresses(t *testing.T) {
+	s := &rpcServer{cfg: rpcserverConfig{ChainParams: &chaincfg.MainNetParams}}
+	withChecksum := func(desc string) string {
+		return desc + "#" + descriptorChecksum(desc)
+	}
+	const bip44XPub = "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj"
+	const g = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+	tests := []struct {
+		name  string
+		desc  string
+		rng   *btcjson.DescriptorRange
+		addrs []string
+	}{
+		{
+			name:  "single key",
+			desc:  withChecksum("wpkh(" + g + ")"),
+			addrs: []string{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		},
+		{
+			name:  "ranged",
+			desc:  withChecksum("pkh(" + bip44XPub + "/0/*)"),
+			rng:   &btcjson.DescriptorRange{Value: []int{0, 1}},
+			addrs: []string{"1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA", ""},
+		},
+		{
+			name: "missing checksum",
+			desc: "wpkh(" + g + ")",
+		},
+		{
+			name: "missing range",
+			desc: withChecksum("pkh(" + bip44XPub + "/0/*)"),
+		},
+		{
+			name: "unexpected range",
+			desc: withChecksum("wpkh(" + g + ")"),
+			rng:  &btcjson.DescriptorRange{Value: 1},
+		},
+		{
+			name: "no address",
+			desc: withChecksum("pk(" + g + ")"),
+		},
+	}
+
+	for _, test := range tests {
+		cmd := btcjson.NewDeriveAddressesCmd(test.desc, test.rng)
+		result, err := handleDeriveAddresses(s, cmd, nil)
+		if test.addrs == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		addrs := result.(btcjson.DeriveAddressesResult)
+		if len(addrs) != len(test.addrs) {
+			t.Errorf("%s: expected %d addresses, got %d", test.name,
+				len(test.addrs), len(addrs))
+			continue
+		}
+		for i, addr := range addrs {
+			if test.addrs[i] != "" && addr != test.addrs[i] {
+				t.Errorf("%s: expected address %s, got %s",
+					test.name, test.addrs[i], addr)
+			}
+		}
+	}
+}
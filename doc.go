@@ -29,6 +29,9 @@ Application Options:
 	    --addrindex             Maintain a full address-based transaction index
 	                            which makes the searchrawtransactions RPC
 	                            available
+	    --asmap=                Group peer addresses by the autonomous system of
+	                            their IP address using the ASN map file, in the
+	                            format of Bitcoin Core, rather than by their /16
 	    --banduration=          How long to ban misbehaving peers.  Valid time
 	                            units are {s, m, h}.  Minimum 1 second (default:
 	                            24h0m0s)
@@ -42,16 +45,54 @@ Application Options:
 	                            block (default: 3000000)
 	    --blockminweight=       Mininum block weight to be used when creating a
 	                            block
+	    --blockmintxfee=        The minimum transaction fee in BTC/kB for
+	                            transactions to be included when creating a
+	                            block
 	    --blockprioritysize=    Size in bytes for high-priority/low-fee
 	                            transactions when creating a block (default:
 	                            50000)
 	    --blocksonly            Do not accept transactions from remote peers.
+	    --blocktimeindex        Maintain an index of the blocks by their
+	                            timestamps which makes the blocks within a time
+	                            range available via the getblocksbytime RPC
+	    --broadcastdelay=       Announce the locally submitted transactions to
+	                            peers on Tor or I2P first, and only after this
+	                            long to the other peers (eg. 30s)
 	-C, --configfile=           Path to configuration file
 	    --connect=              Connect only to the specified peers at startup
 	    --cpuprofile=           Write CPU profile to the specified file
+	    --datacarriersize=      Max number of bytes of data that standard null
+	                            data (OP_RETURN) outputs may carry (default: 80)
 	-b, --datadir=              Directory to store data
+	    --dbcompactinterval=    Compact the block database this often to reclaim
+	                            the space of deleted data, waiting until the
+	                            chain is synced and no block came in for a
+	                            minute -- 0 disables the scheduled compactions,
+	                            which can still be started with the compactdb
+	                            RPC.  Valid time units are {s, m, h}
+	    --dbblockcachesize=     The size in MiB of the cache of the table blocks
+	                            of the leveldb or Pebble store of the block
+	                            database metadata -- 0 picks it from the memory
+	                            of the machine
+	    --dbcachesize=          The size in MiB of the cache the writes to the
+	                            block database metadata are collected in before
+	                            they're written out in one batch -- 0 picks it
+	                            from the memory of the machine
+	    --dbfilewritebuffer=    The size in KiB of the buffer the blocks and
+	                            spend journals are collected in before they're
+	                            written to their flat files -- 0 uses the
+	                            default of 1024
+	    --dbmaxopenblockfiles=  The max number of flat files of the blocks and
+	                            of the spend journals each to keep open for
+	                            reads -- 0 uses the default of 64
+	    --dbmaxopenfiles=       The max number of files of the leveldb or Pebble
+	                            store of the block database metadata to keep
+	                            open -- 0 uses the default of 500
 	    --dbtype=               Database backend to use for the Block Chain
-	                            (default: ffldb)
+	                            {ffldb, pebble} (default: ffldb)
+	    --dbwritebuffersize=    The size in MiB of the memtable of the leveldb or
+	                            Pebble store of the block database metadata -- 0
+	                            picks it from the memory of the machine
 	-d, --debuglevel=           Logging level for all subsystems {trace, debug,
 	                            info, warn, error, critical} -- You may also
 	                            specify
@@ -66,9 +107,22 @@ Application Options:
 	                            then exits.
 	    --droptxindex           Deletes the hash-based transaction index from the
 	                            database on start up and then exits.
+	    --dustrelayfee=         The fee rate in BTC/kB used to decide whether an
+	                            output is dust, since spending it would cost
+	                            more than it's worth (default: 1e-05)
 	    --externalip=           Add an ip to the list of local addresses we claim
 	                            to listen on to peers
 	    --generate              Generate (mine) bitcoins using the CPU
+	    --i2psam=               I2P SAM bridge to connect to I2P destinations
+	                            and to accept incoming connections over I2P
+	                            with (eg. 127.0.0.1:7656)
+	    --importblocks=         Import the blocks of a bootstrap.dat file, or of
+	                            the blk*.dat files of a Bitcoin Core blocks
+	                            directory, on startup before connecting to peers
+	                            -- The blocks are fully validated and the
+	                            already known ones are skipped.  Requires
+	                            --noutreexo or a utreexo proof index and can be
+	                            specified multiple times
 	    --limitfreerelay=       Limit relay of transactions with no transaction
 	                            fee to the given amount in thousands of bytes per
 	                            minute (default: 15)
@@ -76,27 +130,63 @@ Application Options:
 	                            (default all interfaces port: 8333, testnet:
 	                            18333, signet: 38333)
 	    --logdir=               Directory to log output
+	    --maxmempool=           Max size of the mempool in megabytes.  The
+	                            transactions paying the lowest fee rates are
+	                            evicted once it is reached (default: 300)
 	    --maxorphantx=          Max number of orphan transactions to keep in
 	                            memory (default: 100)
 	    --maxpeers=             Max number of inbound and outbound peers
 	                            (default: 125)
+	    --maxuploadtarget=      Try to keep the bytes sent to peers under this
+	                            many MiB per 24h by no longer serving the blocks
+	                            and utreexo proofs older than a week once the
+	                            rest is needed for new blocks -- Peers with the
+	                            download permission are still served, and 0
+	                            means no target
+	    --maxstandardtxweight=  Max weight of transactions that are considered
+	                            standard (default: 400000)
+	    --mempoolexpiry=        Number of hours after which transactions that
+	                            weren't mined are removed from the mempool
+	                            (default: 336)
+	    --mempoolfullrbf        Accept transactions that replace existing
+	                            transactions within the mempool whether or not
+	                            they signal replacement through the
+	                            Replace-By-Fee (RBF) signaling policy.
 	    --miningaddr=           Add the specified payment address to the list of
 	                            addresses to use for generated blocks -- At least
 	                            one address is required if the generate option is
 	                            set
+	    --minproofpeers=        Min number of outbound peers serving the utreexo
+	                            proofs needed by the compact state to keep room
+	                            for -- The proofs of all blocks are needed until
+	                            the chain is synced, and only those of new
+	                            blocks afterwards (default: 2)
 	    --minrelaytxfee=        The minimum transaction fee in BTC/kB to be
 	                            considered a non-zero fee. (default: 1e-05)
+	    --natpmp                Use NAT-PMP or PCP to map our listening port
+	                            outside of NAT
+	    --netproxy=             Connect to the peers on a network via its own
+	                            SOCKS5 proxy instead of --proxy, in the form
+	                            network=addr where the network is ipv4 or ipv6
+	                            and the address is none to connect directly (eg.
+	                            ipv6=127.0.0.1:9052 or ipv4=none) -- May be
+	                            specified once per network
 	    --nobanning             Disable banning of misbehaving peers
 	    --nocfilters            Disable committed filtering (CF) support
+	    --nodatacarrier         Do not relay transactions with null data
+	                            (OP_RETURN) outputs
 	    --nocheckpoints         Disable built-in checkpoints.  Don't do this
 	                            unless you know what you're doing.
 	    --nodnsseed             Disable DNS seeding for peers
+	    --noi2plisten           Disable accepting incoming connections over I2P
 	    --nolisten              Disable listening for incoming connections --
 	                            NOTE: Listening is automatically disabled if the
 	                            --connect or --proxy options are used without
 	                            also specifying listen interfaces via --listen
 	    --noonion               Disable connecting to tor hidden services
 	    --nopeerbloomfilters    Disable bloom filtering support
+	    --nopersistmempool      Do not save the mempool on shutdown and load it
+	                            again on startup
 	    --norelaypriority       Do not require free or low-fee transactions to
 	                            have high priority for relaying
 	    --norpc                 Disable built-in RPC server -- NOTE: The RPC
@@ -110,16 +200,30 @@ Application Options:
 	                            (eg. 127.0.0.1:9050)
 	    --onionpass=            Password for onion proxy server
 	    --onionuser=            Username for onion proxy server
+	    --outboundquota=        Keep a number of the automatic outbound slots
+	                            for peers on a network, in the form
+	                            network:count where the network is ipv4, ipv6,
+	                            onion or i2p (eg. onion:2) -- May be specified
+	                            once per network
+	    --privatebroadcast      Only announce the locally submitted
+	                            transactions to peers on Tor or I2P, so that the
+	                            peers that learn about them first can't tell the
+	                            IP address of the node
 	    --profile=              Enable HTTP profiling on given port -- NOTE port
 	                            must be between 1024 and 65536
 	    --proxy=                Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)
 	    --proxypass=            Password for proxy server
 	    --proxyuser=            Username for proxy server
 	    --regtest               Use the regression test network
+	    --rejectbaremultisig    Reject transactions with bare (non-P2SH)
+	                            multi-signature outputs
 	    --rejectnonstd          Reject non-standard transactions regardless of
 	                            the default settings for the active network.
 	    --relaynonstd           Relay non-standard transactions regardless of the
 	                            default settings for the active network.
+	    --rpcauditlog=          File to append a JSON line to for every RPC call
+	                            with its method, client, duration and result
+	                            code
 	    --rpccert=              File containing the certificate file
 	    --rpckey=               File containing the certificate key
 	    --rpclimitpass=         Password for limited RPC connections
@@ -132,17 +236,39 @@ Application Options:
 	                            processed concurrently (default: 20)
 	    --rpcmaxwebsockets=     Max number of RPC websocket connections (default:
 	                            25)
+	    --rpcmethodratelimit=   Limit the calls per second each RPC client may
+	                            make to a method in the form
+	                            <method>:<calls per second> -- Can be specified
+	                            multiple times
 	    --rpcquirks             Mirror some JSON-RPC quirks of Bitcoin Core --
 	                            NOTE: Discouraged unless interoperability issues
 	                            need to be worked around
 	-P, --rpcpass=              Password for RPC connections
+	    --rpcratelimit=         Max number of calls per second each RPC client
+	                            may make, 0 for no limit
 	-u, --rpcuser=              Username for RPC connections
+	    --scriptbalanceindex    Maintain a full index of the confirmed balance and
+	                            Electrum history status of every script
+	                            available via the getscriptbalance RPC
 	    --sigcachemaxsize=      The maximum number of entries in the signature
 	                            verification cache (default: 100000)
+	    --silentpaymentindex    Maintain a full index of the BIP-352 silent
+	                            payment tweaks of every block available via the
+	                            getsilentpaymenttweaks RPC
 	    --simnet                Use the simulation test network
+	    --spendindex            Maintain a full index of the inputs spending
+	                            every spent output which makes the spenders of
+	                            confirmed outputs available via the
+	                            gettxspendingprevout RPC
 	    --testnet               Use the test network
+	    --torcontrol=           Tor control port to create a v3 onion service
+	                            for incoming connections with (eg.
+	                            127.0.0.1:9051) -- NOTE: Enables Tor stream
+	                            isolation when a proxy is set
 	    --torisolation          Enable Tor stream isolation by randomizing user
 	                            credentials for each connection.
+	    --torpassword=          Password for the Tor control port, which uses
+	                            cookie authentication otherwise
 	    --trickleinterval=      Minimum time between attempts to send new
 	                            inventory to a connected peer (default: 10s)
 	    --txindex               Maintain a full hash-based transaction index
@@ -151,9 +277,22 @@ Application Options:
 	    --uacomment=            Comment to add to the user agent -- See BIP 14
 	                            for more information.
 	    --upnp                  Use UPnP to map our listening port outside of NAT
+	    --utreexocfindex        Maintain a chain of compact filter headers that
+	                            also commit to the utreexo roots of every block
+	                            available via the getutreexocfheader RPC --
+	                            Requires --noutreexo off or a utreexo proof index
 	-V, --version               Display version information and exit
-	    --whitelist=            Add an IP network or IP that will not be banned.
-	                            (eg. 192.168.1.0/24 or ::1)
+	    --whitebind=            Listen for peers on the address and give the
+	                            peers that connect to it the permissions, like
+	                            [perm,...@]addr, or noban without any.  Takes the
+	                            same permissions as --whitelist.
+	                            (eg. noban,proof@127.0.0.1:8335)
+	    --whitelist=            Add an IP network or IP whose peers are given the
+	                            permissions, like [perm,...@]network, or noban
+	                            without any.  Permissions are bloomfilter,
+	                            download, forcerelay, mempool, noban, proof and
+	                            all.
+	                            (eg. 192.168.1.0/24 or mempool,noban@::1)
 
 Help Options:
 
@@ -0,0 +1,158 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// evictProtectNetGroups is the number of inbound peers protected from
+	// eviction by their network group.  The groups are keyed with a secret
+	// of the node, so an attacker can't tell which groups are protected.
+	evictProtectNetGroups = 4
+
+	// evictProtectPing is the number of inbound peers with the lowest
+	// ping times protected from eviction.
+	evictProtectPing = 8
+
+	// evictProtectBlocks is the number of inbound peers that most recently
+	// sent a new block along with its utreexo proof protected from
+	// eviction.
+	evictProtectBlocks = 4
+)
+
+// evictionCandidate is an inbound peer that may be disconnected to make room
+// for a new one, with what the protection classes look at.
+type evictionCandidate struct {
+	id            int32
+	connTime      time.Time
+	pingMicros    int64
+	lastBlockTime time.Time
+	netGroup      string
+	keyedNetGroup uint64
+
+	// disadvantaged is whether the peer comes from a network that few
+	// peers come from, such as Tor, I2P and localhost.  Those peers would
+	// otherwise be evicted first since they have higher ping times and
+	// share a network group.
+	disadvantaged bool
+}
+
+// newEvictionCandidate returns the eviction candidate of the inbound peer,
+// keying its network group with the key.
+func newEvictionCandidate(sp *serverPeer, key []byte) *evictionCandidate {
+	netGroup := sp.server.addrManager.GroupKey(sp.NA())
+	hash := sha256.Sum256(append(append([]byte{}, key...), netGroup...))
+
+	host, _, err := net.SplitHostPort(sp.Addr())
+	if err != nil {
+		host = sp.Addr()
+	}
+	ip := net.ParseIP(host)
+
+	return &evictionCandidate{
+		id:            sp.ID(),
+		connTime:      sp.TimeConnected(),
+		pingMicros:    sp.LastPingMicros(),
+		lastBlockTime: sp.lastBlockTime(),
+		netGroup:      netGroup,
+		keyedNetGroup: binary.LittleEndian.Uint64(hash[:8]),
+		disadvantaged: strings.HasSuffix(host, ".onion") ||
+			strings.HasSuffix(host, ".i2p") ||
+			(ip != nil && ip.IsLoopback()),
+	}
+}
+
+// protectCandidates sorts the candidates with less and returns them without
+// the last count of them, which are protected from eviction.
+func protectCandidates(candidates []*evictionCandidate, count int,
+	less func(a, b *evictionCandidate) bool) []*evictionCandidate {
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return less(candidates[i], candidates[j])
+	})
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	return candidates[:len(candidates)-count]
+}
+
+// selectPeerToEvict returns the inbound peer to evict out of the candidates
+// the way Bitcoin Core does, or nil when all of them are protected.
+//
+// The peers are protected from eviction by the keyed network group, by the
+// lowest ping time and by the most recent new block sent.  Up to a quarter of
+// the remaining peers are then protected when they come from disadvantaged
+// networks, and half of them in total are protected by the time connected.
+// The newest peer of the network group with the most peers left is evicted.
+func selectPeerToEvict(candidates []*evictionCandidate) *evictionCandidate {
+	candidates = append([]*evictionCandidate{}, candidates...)
+
+	candidates = protectCandidates(candidates, evictProtectNetGroups,
+		func(a, b *evictionCandidate) bool {
+			return a.keyedNetGroup < b.keyedNetGroup
+		})
+	candidates = protectCandidates(candidates, evictProtectPing,
+		func(a, b *evictionCandidate) bool {
+			// Peers that never answered a ping are the slowest.
+			if a.pingMicros == 0 || b.pingMicros == 0 {
+				return b.pingMicros != 0
+			}
+			return a.pingMicros > b.pingMicros
+		})
+	candidates = protectCandidates(candidates, evictProtectBlocks,
+		func(a, b *evictionCandidate) bool {
+			return a.lastBlockTime.Before(b.lastBlockTime)
+		})
+
+	// Newer peers come first, so the oldest peers are protected.
+	byAge := func(a, b *evictionCandidate) bool {
+		return a.connTime.After(b.connTime)
+	}
+	total := len(candidates) / 2
+	var disadvantaged, others []*evictionCandidate
+	for _, c := range candidates {
+		if c.disadvantaged {
+			disadvantaged = append(disadvantaged, c)
+		} else {
+			others = append(others, c)
+		}
+	}
+	protect := len(candidates) / 4
+	if protect > len(disadvantaged) {
+		protect = len(disadvantaged)
+	}
+	disadvantaged = protectCandidates(disadvantaged, protect, byAge)
+	candidates = protectCandidates(append(others, disadvantaged...),
+		total-protect, byAge)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Evict from the network group with the most peers, breaking ties by
+	// the group with the newest peer.
+	groups := make(map[string][]*evictionCandidate)
+	for _, c := range candidates {
+		groups[c.netGroup] = append(groups[c.netGroup], c)
+	}
+	var evictGroup []*evictionCandidate
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return byAge(group[i], group[j])
+		})
+		if len(group) > len(evictGroup) || (len(group) == len(evictGroup) &&
+			group[0].connTime.After(evictGroup[0].connTime)) {
+
+			evictGroup = group
+		}
+	}
+	return evictGroup[0]
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestSelectPeerToEvict ensures that the protection classes keep their peers
+// from being evicted and that the newest peer of the largest network group is
+// evicted otherwise.
+func TestSelectPeerToEvict(t *testing.T) {
+	now := time.Now()
+
+	// Too few peers for any of them to be left after the protections.
+	var candidates []*evictionCandidate
+	for i := 0; i < evictProtectNetGroups+evictProtectPing+evictProtectBlocks; i++ {
+		candidates = append(candidates, &evictionCandidate{
+			id:       int32(i),
+			connTime: now.Add(-time.Duration(i) * time.Minute),
+			netGroup: "1.2.0.0",
+		})
+	}
+	if evict := selectPeerToEvict(candidates); evict != nil {
+		t.Fatalf("evicted peer %d while all of them are protected", evict.id)
+	}
+
+	// Build 40 peers where the protected ones stand out:
+	//   - peers 0-3 have the highest keyed network groups
+	//   - peers 4-11 have the lowest pings
+	//   - peers 12-15 sent the most recent blocks
+	//   - peers 16-19 are old peers connected over Tor
+	//   - peers 20-39 share two network groups, with 30-39 being the
+	//     newest in the larger 5.6.0.0 group
+	candidates = nil
+	for i := 0; i < 40; i++ {
+		c := &evictionCandidate{
+			id:            int32(i),
+			connTime:      now.Add(-time.Duration(100-i) * time.Minute),
+			pingMicros:    int64(100000 + i),
+			keyedNetGroup: uint64(i),
+			netGroup:      fmt.Sprintf("10.%d.0.0", i),
+		}
+		switch {
+		case i < 4:
+			c.keyedNetGroup = uint64(1000 + i)
+		case i < 12:
+			c.pingMicros = int64(i)
+		case i < 16:
+			c.lastBlockTime = now.Add(-time.Duration(i) * time.Second)
+		case i < 20:
+			c.disadvantaged = true
+			c.netGroup = "local"
+			c.connTime = now.Add(-time.Duration(1000-i) * time.Minute)
+		case i < 26:
+			c.netGroup = "1.2.0.0"
+		default:
+			c.netGroup = "5.6.0.0"
+		}
+		candidates = append(candidates, c)
+	}
+
+	evict := selectPeerToEvict(candidates)
+	if evict == nil || evict.id != 39 {
+		t.Fatalf("evicted peer %v, want 39", evict)
+	}
+
+	// The disadvantaged peers are protected even when they're the largest
+	// group and the newest peers.
+	for _, c := range candidates[20:] {
+		c.netGroup = fmt.Sprintf("10.%d.0.0", c.id)
+	}
+	for _, c := range candidates[16:20] {
+		c.connTime = now
+	}
+	evict = selectPeerToEvict(candidates)
+	if evict == nil || evict.disadvantaged {
+		t.Fatalf("evicted peer %v, want a peer that isn't disadvantaged",
+			evict)
+	}
+
+	// The candidates are left in their order.
+	for i, c := range candidates {
+		if c.id != int32(i) {
+			t.Fatalf("candidate %d was moved to %d", c.id, i)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"time"
+
+	"github.com/utreexo/utreexod/addrmgr"
+	"github.com/utreexo/utreexod/peer"
+)
+
+const (
+	// feelerInterval is the interval at which feeler connections are made.
+	feelerInterval = time.Minute * 2
+
+	// maxFeelerTries is the most addresses of the address manager that are
+	// looked at for each feeler connection.
+	maxFeelerTries = 10
+)
+
+// feelerHandler periodically makes short-lived feeler connections, which check
+// that there's a node at the addresses the address manager hasn't connected
+// to yet and test the tried addresses that good new addresses would evict.
+// That keeps the addresses the outbound peers are picked from working, which
+// matters all the more with the few utreexo nodes there are to connect to.
+// It must be run as a goroutine.
+func (s *server) feelerHandler() {
+	ticker := time.NewTicker(feelerInterval)
+	defer ticker.Stop()
+
+	var feeler *serverPeer
+out:
+	for {
+		select {
+		case <-ticker.C:
+			// Only one feeler connection is made at a time.
+			if feeler != nil && feeler.Connected() {
+				continue
+			}
+			feeler = s.connectFeeler()
+
+		case <-s.quit:
+			break out
+		}
+	}
+
+	if feeler != nil {
+		feeler.Disconnect()
+	}
+	s.wg.Done()
+	srvrLog.Tracef("Feeler handler done")
+}
+
+// connectFeeler makes a feeler connection to a tried address that a good new
+// address would evict, or to an address that hasn't been connected to yet when
+// there isn't any to test, and returns its peer.  It returns nil when no
+// connection was made.
+func (s *server) connectFeeler() *serverPeer {
+	s.addrManager.ResolveCollisions()
+
+	for tries := 0; tries < maxFeelerTries; tries++ {
+		var ka *addrmgr.KnownAddress
+		if tries == 0 {
+			ka = s.addrManager.SelectTriedCollision()
+		}
+		if ka == nil {
+			ka = s.addrManager.GetNewAddress()
+		}
+		if ka == nil {
+			return nil
+		}
+
+		// Skip the addresses in the network groups of the outbound
+		// peers, those that were attempted lately and banned ones.
+		na := ka.NetAddress()
+		if s.OutboundGroupCount(s.addrManager.GroupKey(na)) != 0 ||
+			time.Since(ka.LastAttempt()) < 10*time.Minute {
+
+			continue
+		}
+		addrString := addrmgr.NetAddressKey(na)
+		host, _, err := net.SplitHostPort(addrString)
+		if err != nil {
+			continue
+		}
+		if _, banned := s.banManager.isBanned(host); banned {
+			continue
+		}
+		addr, err := addrStringToNetAddr(addrString)
+		if err != nil {
+			continue
+		}
+
+		srvrLog.Debugf("Making a feeler connection to %s", addrString)
+		s.addrManager.Attempt(na)
+		conn, err := btcdDial(addr)
+		if err != nil {
+			srvrLog.Debugf("Feeler connection to %s failed: %v",
+				addrString, err)
+			return nil
+		}
+
+		sp := newServerPeer(s, false)
+		sp.feeler = true
+		p, err := peer.NewOutboundPeer(newPeerConfig(sp), addr.String())
+		if err != nil {
+			srvrLog.Debugf("Cannot create feeler peer %s: %v", addr, err)
+			conn.Close()
+			return nil
+		}
+		sp.Peer = p
+		sp.permissions = connPermissions(conn.RemoteAddr(), nil)
+		sp.AssociateConnection(conn)
+		go s.peerDoneHandler(sp)
+		return sp
+	}
+	return nil
+}
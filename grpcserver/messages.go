@@ -0,0 +1,560 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package grpcserver
+
+import (
+	"errors"
+	"fmt"
+)
+
+// This file implements the protobuf encoding of the messages in utreexod.proto.
+// Only the parts of the encoding that the messages use are implemented.
+
+// Wire types of the protobuf encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errTruncated is returned when a message ends in the middle of a field.
+var errTruncated = errors.New("truncated protobuf message")
+
+// appendVarint appends the base 128 varint encoding of v.
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// appendTag appends the key of a field.
+func appendTag(b []byte, num int, wireType int) []byte {
+	return appendVarint(b, uint64(num)<<3|uint64(wireType))
+}
+
+// appendVarintField appends a varint field.  Like every proto3 scalar, it's
+// left out if it has the default value.
+func appendVarintField(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, num, wireVarint)
+	return appendVarint(b, v)
+}
+
+// appendInt32Field appends an int32 field.  Negative values are sign extended
+// to 64 bits.
+func appendInt32Field(b []byte, num int, v int32) []byte {
+	return appendVarintField(b, num, uint64(int64(v)))
+}
+
+// appendBoolField appends a bool field.
+func appendBoolField(b []byte, num int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendVarintField(b, num, 1)
+}
+
+// appendBytes appends a length-delimited field even if it's empty, which is
+// needed for the elements of repeated fields.
+func appendBytes(b []byte, num int, v []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendBytesField appends a bytes field unless it's empty.
+func appendBytesField(b []byte, num int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	return appendBytes(b, num, v)
+}
+
+// appendPackedField appends a packed repeated varint field.
+func appendPackedField(b []byte, num int, vs []uint64) []byte {
+	if len(vs) == 0 {
+		return b
+	}
+	var packed []byte
+	for _, v := range vs {
+		packed = appendVarint(packed, v)
+	}
+	return appendBytes(b, num, packed)
+}
+
+// consumeVarint decodes a varint from the start of b and returns it with the
+// number of bytes it took.
+func consumeVarint(b []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+	if len(b) < 10 {
+		return 0, 0, errTruncated
+	}
+	return 0, 0, errors.New("varint overflows 64 bits")
+}
+
+// field is a decoded field of a message.  varint is set for varint fields and
+// data for length-delimited fields.
+type field struct {
+	num      int
+	wireType int
+	varint   uint64
+	data     []byte
+}
+
+// parseFields decodes the fields of a message.  Fixed size fields are skipped
+// since none of the messages use them.
+func parseFields(b []byte) ([]field, error) {
+	var fields []field
+	for len(b) > 0 {
+		key, n, err := consumeVarint(b)
+		if err != nil {
+			return nil, err
+		}
+		b = b[n:]
+
+		f := field{num: int(key >> 3), wireType: int(key & 7)}
+		switch f.wireType {
+		case wireVarint:
+			f.varint, n, err = consumeVarint(b)
+			if err != nil {
+				return nil, err
+			}
+			b = b[n:]
+
+		case wireBytes:
+			size, n, err := consumeVarint(b)
+			if err != nil {
+				return nil, err
+			}
+			b = b[n:]
+			if uint64(len(b)) < size {
+				return nil, errTruncated
+			}
+			f.data = b[:size]
+			b = b[size:]
+
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errTruncated
+			}
+			b = b[8:]
+			continue
+
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errTruncated
+			}
+			b = b[4:]
+			continue
+
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", f.wireType)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// checkWireType returns an error if the field doesn't have the wire type.
+func checkWireType(f *field, wireType int) error {
+	if f.wireType != wireType {
+		return fmt.Errorf("field %d has wire type %d but expected %d",
+			f.num, f.wireType, wireType)
+	}
+	return nil
+}
+
+// appendVarints decodes a repeated varint field, which can be either packed
+// or not, and appends the values to vs.
+func appendVarints(vs []uint64, f *field) ([]uint64, error) {
+	if f.wireType == wireVarint {
+		return append(vs, f.varint), nil
+	}
+	if err := checkWireType(f, wireBytes); err != nil {
+		return nil, err
+	}
+
+	data := f.data
+	for len(data) > 0 {
+		v, n, err := consumeVarint(data)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+		data = data[n:]
+	}
+	return vs, nil
+}
+
+// EventType tells whether a block was connected to or disconnected from the
+// main chain.
+type EventType int32
+
+const (
+	// EventConnected is sent for a block that's connected to the main
+	// chain.
+	EventConnected EventType = 0
+
+	// EventDisconnected is sent for a block that was sent as connected
+	// before and which was reorged out of the main chain since.
+	EventDisconnected EventType = 1
+)
+
+// String returns the EventType in human-readable form.
+func (t EventType) String() string {
+	switch t {
+	case EventConnected:
+		return "connected"
+	case EventDisconnected:
+		return "disconnected"
+	}
+	return fmt.Sprintf("unknown event type (%d)", int32(t))
+}
+
+// StreamBlocksRequest is the request of the StreamBlocks method.
+type StreamBlocksRequest struct {
+	StartHeight int32
+}
+
+// Marshal returns the protobuf encoding of the message.
+func (m *StreamBlocksRequest) Marshal() []byte {
+	return appendInt32Field(nil, 1, m.StartHeight)
+}
+
+// Unmarshal decodes the protobuf encoding of the message.
+func (m *StreamBlocksRequest) Unmarshal(b []byte) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		if f.num == 1 {
+			if err := checkWireType(f, wireVarint); err != nil {
+				return err
+			}
+			m.StartHeight = int32(f.varint)
+		}
+	}
+	return nil
+}
+
+// BlockEvent is a message of the StreamBlocks method.
+type BlockEvent struct {
+	Type     EventType
+	Height   int32
+	Hash     []byte
+	RawBlock []byte
+}
+
+// Marshal returns the protobuf encoding of the message.
+func (m *BlockEvent) Marshal() []byte {
+	b := appendVarintField(nil, 1, uint64(m.Type))
+	b = appendInt32Field(b, 2, m.Height)
+	b = appendBytesField(b, 3, m.Hash)
+	return appendBytesField(b, 4, m.RawBlock)
+}
+
+// Unmarshal decodes the protobuf encoding of the message.
+func (m *BlockEvent) Unmarshal(b []byte) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		switch f.num {
+		case 1, 2:
+			if err := checkWireType(f, wireVarint); err != nil {
+				return err
+			}
+			if f.num == 1 {
+				m.Type = EventType(f.varint)
+			} else {
+				m.Height = int32(f.varint)
+			}
+		case 3, 4:
+			if err := checkWireType(f, wireBytes); err != nil {
+				return err
+			}
+			if f.num == 3 {
+				m.Hash = f.data
+			} else {
+				m.RawBlock = f.data
+			}
+		}
+	}
+	return nil
+}
+
+// StreamMempoolRequest is the request of the StreamMempool method.
+type StreamMempoolRequest struct {
+	IncludeExisting bool
+}
+
+// Marshal returns the protobuf encoding of the message.
+func (m *StreamMempoolRequest) Marshal() []byte {
+	return appendBoolField(nil, 1, m.IncludeExisting)
+}
+
+// Unmarshal decodes the protobuf encoding of the message.
+func (m *StreamMempoolRequest) Unmarshal(b []byte) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		if f.num == 1 {
+			if err := checkWireType(f, wireVarint); err != nil {
+				return err
+			}
+			m.IncludeExisting = f.varint != 0
+		}
+	}
+	return nil
+}
+
+// MempoolTx is a message of the StreamMempool method.
+type MempoolTx struct {
+	Txid      []byte
+	RawTx     []byte
+	Fee       int64
+	AddedTime int64
+}
+
+// Marshal returns the protobuf encoding of the message.
+func (m *MempoolTx) Marshal() []byte {
+	b := appendBytesField(nil, 1, m.Txid)
+	b = appendBytesField(b, 2, m.RawTx)
+	b = appendVarintField(b, 3, uint64(m.Fee))
+	return appendVarintField(b, 4, uint64(m.AddedTime))
+}
+
+// Unmarshal decodes the protobuf encoding of the message.
+func (m *MempoolTx) Unmarshal(b []byte) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		switch f.num {
+		case 1, 2:
+			if err := checkWireType(f, wireBytes); err != nil {
+				return err
+			}
+			if f.num == 1 {
+				m.Txid = f.data
+			} else {
+				m.RawTx = f.data
+			}
+		case 3, 4:
+			if err := checkWireType(f, wireVarint); err != nil {
+				return err
+			}
+			if f.num == 3 {
+				m.Fee = int64(f.varint)
+			} else {
+				m.AddedTime = int64(f.varint)
+			}
+		}
+	}
+	return nil
+}
+
+// StreamProofsRequest is the request of the StreamProofs method.
+type StreamProofsRequest struct {
+	StartHeight int32
+}
+
+// Marshal returns the protobuf encoding of the message.
+func (m *StreamProofsRequest) Marshal() []byte {
+	return appendInt32Field(nil, 1, m.StartHeight)
+}
+
+// Unmarshal decodes the protobuf encoding of the message.
+func (m *StreamProofsRequest) Unmarshal(b []byte) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		if f.num == 1 {
+			if err := checkWireType(f, wireVarint); err != nil {
+				return err
+			}
+			m.StartHeight = int32(f.varint)
+		}
+	}
+	return nil
+}
+
+// LeafData is a leaf of the utreexo accumulator that's spent by a block.
+type LeafData struct {
+	BlockHash  []byte
+	Txid       []byte
+	Index      uint32
+	Height     int32
+	IsCoinBase bool
+	Amount     int64
+	PkScript   []byte
+}
+
+// Marshal returns the protobuf encoding of the message.
+func (m *LeafData) Marshal() []byte {
+	b := appendBytesField(nil, 1, m.BlockHash)
+	b = appendBytesField(b, 2, m.Txid)
+	b = appendVarintField(b, 3, uint64(m.Index))
+	b = appendInt32Field(b, 4, m.Height)
+	b = appendBoolField(b, 5, m.IsCoinBase)
+	b = appendVarintField(b, 6, uint64(m.Amount))
+	return appendBytesField(b, 7, m.PkScript)
+}
+
+// Unmarshal decodes the protobuf encoding of the message.
+func (m *LeafData) Unmarshal(b []byte) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		switch f.num {
+		case 1, 2, 7:
+			if err := checkWireType(f, wireBytes); err != nil {
+				return err
+			}
+			switch f.num {
+			case 1:
+				m.BlockHash = f.data
+			case 2:
+				m.Txid = f.data
+			case 7:
+				m.PkScript = f.data
+			}
+		case 3, 4, 5, 6:
+			if err := checkWireType(f, wireVarint); err != nil {
+				return err
+			}
+			switch f.num {
+			case 3:
+				m.Index = uint32(f.varint)
+			case 4:
+				m.Height = int32(f.varint)
+			case 5:
+				m.IsCoinBase = f.varint != 0
+			case 6:
+				m.Amount = int64(f.varint)
+			}
+		}
+	}
+	return nil
+}
+
+// BlockProofEvent is a message of the StreamProofs method.
+type BlockProofEvent struct {
+	Type            EventType
+	Height          int32
+	Hash            []byte
+	Targets         []uint64
+	ProofHashes     [][]byte
+	TargetHashes    [][]byte
+	LeafDatas       []LeafData
+	RememberIndexes []uint32
+}
+
+// Marshal returns the protobuf encoding of the message.
+func (m *BlockProofEvent) Marshal() []byte {
+	b := appendVarintField(nil, 1, uint64(m.Type))
+	b = appendInt32Field(b, 2, m.Height)
+	b = appendBytesField(b, 3, m.Hash)
+	b = appendPackedField(b, 4, m.Targets)
+	for _, hash := range m.ProofHashes {
+		b = appendBytes(b, 5, hash)
+	}
+	for _, hash := range m.TargetHashes {
+		b = appendBytes(b, 6, hash)
+	}
+	for i := range m.LeafDatas {
+		b = appendBytes(b, 7, m.LeafDatas[i].Marshal())
+	}
+	if len(m.RememberIndexes) > 0 {
+		remembers := make([]uint64, 0, len(m.RememberIndexes))
+		for _, idx := range m.RememberIndexes {
+			remembers = append(remembers, uint64(idx))
+		}
+		b = appendPackedField(b, 8, remembers)
+	}
+	return b
+}
+
+// Unmarshal decodes the protobuf encoding of the message.
+func (m *BlockProofEvent) Unmarshal(b []byte) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		switch f.num {
+		case 1, 2:
+			if err := checkWireType(f, wireVarint); err != nil {
+				return err
+			}
+			if f.num == 1 {
+				m.Type = EventType(f.varint)
+			} else {
+				m.Height = int32(f.varint)
+			}
+		case 3, 5, 6, 7:
+			if err := checkWireType(f, wireBytes); err != nil {
+				return err
+			}
+			switch f.num {
+			case 3:
+				m.Hash = f.data
+			case 5:
+				m.ProofHashes = append(m.ProofHashes, f.data)
+			case 6:
+				m.TargetHashes = append(m.TargetHashes, f.data)
+			case 7:
+				var leaf LeafData
+				if err := leaf.Unmarshal(f.data); err != nil {
+					return err
+				}
+				m.LeafDatas = append(m.LeafDatas, leaf)
+			}
+		case 4:
+			m.Targets, err = appendVarints(m.Targets, f)
+			if err != nil {
+				return err
+			}
+		case 8:
+			remembers, err := appendVarints(nil, f)
+			if err != nil {
+				return err
+			}
+			for _, idx := range remembers {
+				m.RememberIndexes = append(m.RememberIndexes, uint32(idx))
+			}
+		}
+	}
+	return nil
+}
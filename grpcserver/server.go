@@ -0,0 +1,666 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/blockchain/indexers"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/mempool"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// serviceName is the fully qualified name of the service in
+	// utreexod.proto.
+	serviceName = "utreexod.v1.Utreexod"
+
+	// maxRequestSize is the largest request message that's accepted.  All
+	// the requests are a few bytes.
+	maxRequestSize = 4096
+
+	// mempoolStreamBuffer is the number of transactions that are queued for
+	// a mempool stream.  The stream is ended once a client falls this far
+	// behind since it would miss transactions otherwise.
+	mempoolStreamBuffer = 1000
+
+	// readHeaderTimeout is the time a client has to send the headers of a
+	// request.
+	readHeaderTimeout = 10 * time.Second
+
+	// shutdownTimeout is the time the streams have to end when the server
+	// is stopped.
+	shutdownTimeout = 5 * time.Second
+)
+
+// gRPC status codes.
+const (
+	codeOK                 = 0
+	codeCanceled           = 1
+	codeInvalidArgument    = 3
+	codeResourceExhausted  = 8
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnavailable        = 14
+)
+
+// statusError is an error that's returned to the client with its gRPC status
+// code.
+type statusError struct {
+	code int
+	msg  string
+}
+
+// Error returns the message of the error.
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// newStatusError returns a statusError with the code and the formatted message.
+func newStatusError(code int, format string, args ...interface{}) error {
+	return &statusError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// errReorged is returned by the callback of followChain when the block was
+// reorged out while its data was fetched.
+var errReorged = errors.New("block was reorged out")
+
+// methodHandler serves a call of a server streaming method.
+type methodHandler func(s *Server, ctx context.Context, req []byte, st *stream) error
+
+// methods maps the paths of the methods to their handlers.
+var methods = map[string]methodHandler{
+	"/" + serviceName + "/StreamBlocks":  (*Server).streamBlocks,
+	"/" + serviceName + "/StreamMempool": (*Server).streamMempool,
+	"/" + serviceName + "/StreamProofs":  (*Server).streamProofs,
+}
+
+// stream writes the response messages of a call.
+type stream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// send writes a length-prefixed message to the client.
+func (st *stream) send(msg []byte) error {
+	var hdr [5]byte
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(msg)))
+
+	_, err := st.w.Write(hdr[:])
+	if err != nil {
+		return err
+	}
+	_, err = st.w.Write(msg)
+	if err != nil {
+		return err
+	}
+	st.flusher.Flush()
+
+	return nil
+}
+
+// readRequest reads the request message of a call.
+func readRequest(r io.Reader) ([]byte, error) {
+	var hdr [5]byte
+	_, err := io.ReadFull(r, hdr[:])
+	if err != nil {
+		return nil, newStatusError(codeInvalidArgument,
+			"unable to read the request: %v", err)
+	}
+	if hdr[0] != 0 {
+		return nil, newStatusError(codeUnimplemented,
+			"compressed requests aren't supported")
+	}
+
+	size := binary.BigEndian.Uint32(hdr[1:])
+	if size > maxRequestSize {
+		return nil, newStatusError(codeResourceExhausted,
+			"request of %d bytes exceeds the maximum of %d bytes",
+			size, maxRequestSize)
+	}
+
+	req := make([]byte, size)
+	_, err = io.ReadFull(r, req)
+	if err != nil {
+		return nil, newStatusError(codeInvalidArgument,
+			"unable to read the request: %v", err)
+	}
+
+	return req, nil
+}
+
+// encodeGrpcMessage percent-encodes the status message like the gRPC protocol
+// requires.
+func encodeGrpcMessage(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// Config is a configuration struct used to initialize a new gRPC server.
+type Config struct {
+	// Listeners are the listeners the server accepts connections on.  The
+	// server takes ownership of them and closes them when it's stopped.
+	// gRPC requires HTTP/2, so they must be TLS listeners that negotiate
+	// h2.
+	Listeners []net.Listener
+
+	// Chain is the chain the blocks and the proofs are streamed from.
+	Chain *blockchain.BlockChain
+
+	// TxMemPool is the mempool whose transactions are streamed.
+	TxMemPool *mempool.TxPool
+
+	// UtreexoProofIndex and FlatUtreexoProofIndex are the utreexo proof
+	// indexes the proofs are streamed from.  The proof stream is only
+	// available if one of them is set.
+	UtreexoProofIndex     *indexers.UtreexoProofIndex
+	FlatUtreexoProofIndex *indexers.FlatUtreexoProofIndex
+}
+
+// mempoolSub is a mempool stream that's waiting for new transactions.
+type mempoolSub struct {
+	txs chan *mempool.TxDesc
+
+	// full is closed once the stream fell too far behind.
+	full chan struct{}
+}
+
+// Server serves the gRPC API of the node.
+type Server struct {
+	started  int32
+	shutdown int32
+
+	cfg        Config
+	httpServer http.Server
+	wg         sync.WaitGroup
+	quit       chan struct{}
+
+	// tipChanged is closed and replaced every time a block is connected or
+	// disconnected.
+	tipMtx     sync.Mutex
+	tipChanged chan struct{}
+
+	mempoolMtx  sync.Mutex
+	mempoolSubs map[*mempoolSub]struct{}
+}
+
+// New returns a new instance of the gRPC server.
+func New(config *Config) *Server {
+	s := &Server{
+		cfg:         *config,
+		quit:        make(chan struct{}),
+		tipChanged:  make(chan struct{}),
+		mempoolSubs: make(map[*mempoolSub]struct{}),
+	}
+	s.httpServer = http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	s.cfg.Chain.Subscribe(s.handleBlockChainNotification)
+
+	return s
+}
+
+// Start begins accepting connections on all the listeners.
+func (s *Server) Start() {
+	if atomic.AddInt32(&s.started, 1) != 1 {
+		return
+	}
+
+	for _, listener := range s.cfg.Listeners {
+		s.wg.Add(1)
+		go func(listener net.Listener) {
+			log.Infof("gRPC server listening on %s", listener.Addr())
+			s.httpServer.Serve(listener)
+			log.Tracef("gRPC server done listening on %s", listener.Addr())
+			s.wg.Done()
+		}(listener)
+	}
+}
+
+// Stop ends all the streams and closes the listeners.
+func (s *Server) Stop() {
+	if atomic.AddInt32(&s.shutdown, 1) != 1 {
+		log.Infof("gRPC server is already in the process of shutting down")
+		return
+	}
+	log.Infof("Stopping gRPC server...")
+
+	// Ending the streams lets the clients know that the server is going
+	// away before the connections are closed.
+	close(s.quit)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	err := s.httpServer.Shutdown(ctx)
+	cancel()
+	if err != nil {
+		s.httpServer.Close()
+	}
+	s.wg.Wait()
+
+	log.Infof("gRPC server stopped")
+}
+
+// ServeHTTP serves a gRPC call.
+//
+// This is part of the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || r.ProtoMajor != 2 || r.Method != http.MethodPost ||
+		!strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+
+		http.Error(w, "only gRPC calls over HTTP/2 are served",
+			http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	err := s.serveCall(r, &stream{w: w, flusher: flusher})
+
+	code, msg := codeOK, ""
+	var statusErr *statusError
+	switch {
+	case err == nil:
+	case errors.As(err, &statusErr):
+		code, msg = statusErr.code, statusErr.msg
+	case s.isShuttingDown():
+		code, msg = codeUnavailable, "the server is shutting down"
+	case errors.Is(err, context.Canceled):
+		code, msg = codeCanceled, "the call was canceled"
+	default:
+		code, msg = codeInternal, err.Error()
+	}
+	if code != codeOK && code != codeCanceled {
+		log.Debugf("gRPC call %s from %s failed: %s", r.URL.Path,
+			r.RemoteAddr, msg)
+	}
+
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeGrpcMessage(msg))
+	}
+}
+
+// isShuttingDown returns whether the server is being stopped.
+func (s *Server) isShuttingDown() bool {
+	select {
+	case <-s.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// serveCall reads the request of the call and passes it to the handler of the
+// method.
+func (s *Server) serveCall(r *http.Request, st *stream) error {
+	handler, ok := methods[r.URL.Path]
+	if !ok {
+		return newStatusError(codeUnimplemented, "unknown method %s",
+			r.URL.Path)
+	}
+
+	req, err := readRequest(r.Body)
+	if err != nil {
+		return err
+	}
+
+	// End the call once the client goes away or the server is stopped.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return handler(s, ctx, req, st)
+}
+
+// handleBlockChainNotification wakes up the streams that follow the chain.
+func (s *Server) handleBlockChainNotification(notification *blockchain.Notification) {
+	switch notification.Type {
+	case blockchain.NTBlockConnected, blockchain.NTBlockDisconnected:
+		s.tipMtx.Lock()
+		close(s.tipChanged)
+		s.tipChanged = make(chan struct{})
+		s.tipMtx.Unlock()
+	}
+}
+
+// tipChangedChan returns a channel that's closed the next time a block is
+// connected or disconnected.
+func (s *Server) tipChangedChan() <-chan struct{} {
+	s.tipMtx.Lock()
+	defer s.tipMtx.Unlock()
+	return s.tipChanged
+}
+
+// followChain calls send for every block of the main chain from the start
+// height onwards and then keeps following the chain tip until the context is
+// done.  Blocks that were sent and are reorged out are sent again as
+// disconnected, from the tip back to the fork point, before the blocks of the
+// new chain are sent.  If send returns errReorged, the block is treated as not
+// sent.
+func (s *Server) followChain(ctx context.Context, start int32,
+	send func(eventType EventType, height int32, hash *chainhash.Hash) error) error {
+
+	// lastHash is the last block that was sent as connected.
+	var lastHash *chainhash.Hash
+	lastHeight := start - 1
+	for {
+		// Fetch the channel before looking at the chain so that a change
+		// in between isn't missed.
+		tipChanged := s.tipChangedChan()
+
+		if lastHash != nil && !s.cfg.Chain.MainChainHasBlock(lastHash) {
+			header, err := s.cfg.Chain.HeaderByHash(lastHash)
+			if err != nil {
+				return err
+			}
+			err = send(EventDisconnected, lastHeight, lastHash)
+			if err != nil {
+				return err
+			}
+
+			lastHash = &header.PrevBlock
+			lastHeight--
+			continue
+		}
+
+		best := s.cfg.Chain.BestSnapshot()
+		if lastHeight < best.Height {
+			height := lastHeight + 1
+			hash, err := s.cfg.Chain.BlockHashByHeight(height)
+			if err != nil {
+				// The block was disconnected since the snapshot.
+				continue
+			}
+			header, err := s.cfg.Chain.HeaderByHash(hash)
+			if err != nil {
+				return err
+			}
+
+			// The block must build on the last one sent.  If it
+			// doesn't, a reorg happened and the loop disconnects
+			// the last block first.
+			if lastHash != nil && header.PrevBlock != *lastHash {
+				continue
+			}
+
+			hashCopy := *hash
+			err = send(EventConnected, height, &hashCopy)
+			if err == errReorged {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			lastHash = &hashCopy
+			lastHeight = height
+			continue
+		}
+
+		select {
+		case <-tipChanged:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// streamBlocks implements the StreamBlocks method.
+func (s *Server) streamBlocks(ctx context.Context, req []byte, st *stream) error {
+	var request StreamBlocksRequest
+	err := request.Unmarshal(req)
+	if err != nil {
+		return newStatusError(codeInvalidArgument, "invalid request: %v", err)
+	}
+	if request.StartHeight < 0 {
+		return newStatusError(codeInvalidArgument,
+			"the start height can't be negative")
+	}
+
+	return s.followChain(ctx, request.StartHeight, func(eventType EventType,
+		height int32, hash *chainhash.Hash) error {
+
+		event := BlockEvent{Type: eventType, Height: height, Hash: hash[:]}
+		if eventType == EventConnected {
+			block, err := s.cfg.Chain.BlockByHash(hash)
+			if err != nil {
+				if !s.cfg.Chain.MainChainHasBlock(hash) {
+					return errReorged
+				}
+				return err
+			}
+			event.RawBlock, err = block.Bytes()
+			if err != nil {
+				return err
+			}
+		}
+
+		return st.send(event.Marshal())
+	})
+}
+
+// fetchProof returns the utreexo proof of the block with its leaf data.
+func (s *Server) fetchProof(height int32, hash *chainhash.Hash) (*wire.UData, []chainhash.Hash, error) {
+	var ud *wire.UData
+	var err error
+	if s.cfg.UtreexoProofIndex != nil {
+		ud, err = s.cfg.UtreexoProofIndex.FetchUtreexoProof(hash)
+	} else {
+		ud, err = s.cfg.FlatUtreexoProofIndex.FetchUtreexoProof(height, false)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The flat index fetches by height, so make sure the proof is of the
+	// block that was asked for.
+	if !s.cfg.Chain.MainChainHasBlock(hash) {
+		return nil, nil, errReorged
+	}
+
+	targetHashes, err := s.cfg.Chain.ReconstructUData(ud, *hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]chainhash.Hash, 0, len(targetHashes))
+	for _, targetHash := range targetHashes {
+		hashes = append(hashes, chainhash.Hash(targetHash))
+	}
+	return ud, hashes, nil
+}
+
+// streamProofs implements the StreamProofs method.
+func (s *Server) streamProofs(ctx context.Context, req []byte, st *stream) error {
+	if s.cfg.UtreexoProofIndex == nil && s.cfg.FlatUtreexoProofIndex == nil {
+		return newStatusError(codeFailedPrecondition, "a utreexo proof "+
+			"index must be enabled. (--utreexoproofindex) or "+
+			"(--flatutreexoproofindex)")
+	}
+
+	var request StreamProofsRequest
+	err := request.Unmarshal(req)
+	if err != nil {
+		return newStatusError(codeInvalidArgument, "invalid request: %v", err)
+	}
+	if request.StartHeight < 0 {
+		return newStatusError(codeInvalidArgument,
+			"the start height can't be negative")
+	}
+
+	return s.followChain(ctx, request.StartHeight, func(eventType EventType,
+		height int32, hash *chainhash.Hash) error {
+
+		event := BlockProofEvent{Type: eventType, Height: height, Hash: hash[:]}
+
+		// The genesis block doesn't spend anything so its proof is
+		// empty.
+		if eventType == EventConnected && height > 0 {
+			ud, targetHashes, err := s.fetchProof(height, hash)
+			if err != nil {
+				return err
+			}
+
+			event.Targets = ud.AccProof.Targets
+			event.RememberIndexes = ud.RememberIdx
+			for i := range ud.AccProof.Proof {
+				event.ProofHashes = append(event.ProofHashes, ud.AccProof.Proof[i][:])
+			}
+			for i := range targetHashes {
+				event.TargetHashes = append(event.TargetHashes, targetHashes[i][:])
+			}
+			for i := range ud.LeafDatas {
+				ld := &ud.LeafDatas[i]
+				event.LeafDatas = append(event.LeafDatas, LeafData{
+					BlockHash:  ld.BlockHash[:],
+					Txid:       ld.OutPoint.Hash[:],
+					Index:      ld.OutPoint.Index,
+					Height:     ld.Height,
+					IsCoinBase: ld.IsCoinBase,
+					Amount:     ld.Amount,
+					PkScript:   ld.PkScript,
+				})
+			}
+		}
+
+		return st.send(event.Marshal())
+	})
+}
+
+// NotifyNewTransactions passes the transactions that were accepted to the
+// mempool on to the mempool streams.
+func (s *Server) NotifyNewTransactions(txns []*mempool.TxDesc) {
+	s.mempoolMtx.Lock()
+	defer s.mempoolMtx.Unlock()
+
+	for sub := range s.mempoolSubs {
+		for _, txD := range txns {
+			select {
+			case sub.txs <- txD:
+			default:
+				close(sub.full)
+				delete(s.mempoolSubs, sub)
+			}
+			if _, ok := s.mempoolSubs[sub]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// mempoolTx returns the message of the mempool transaction.
+func mempoolTx(txD *mempool.TxDesc) (*MempoolTx, error) {
+	var buf bytes.Buffer
+	err := txD.Tx.MsgTx().Serialize(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MempoolTx{
+		Txid:      txD.Tx.Hash()[:],
+		RawTx:     buf.Bytes(),
+		Fee:       txD.Fee,
+		AddedTime: txD.Added.Unix(),
+	}, nil
+}
+
+// streamMempool implements the StreamMempool method.
+func (s *Server) streamMempool(ctx context.Context, req []byte, st *stream) error {
+	var request StreamMempoolRequest
+	err := request.Unmarshal(req)
+	if err != nil {
+		return newStatusError(codeInvalidArgument, "invalid request: %v", err)
+	}
+
+	// Subscribe before the existing transactions are fetched so that none
+	// are missed in between.
+	sub := &mempoolSub{
+		txs:  make(chan *mempool.TxDesc, mempoolStreamBuffer),
+		full: make(chan struct{}),
+	}
+	s.mempoolMtx.Lock()
+	s.mempoolSubs[sub] = struct{}{}
+	s.mempoolMtx.Unlock()
+	defer func() {
+		s.mempoolMtx.Lock()
+		delete(s.mempoolSubs, sub)
+		s.mempoolMtx.Unlock()
+	}()
+
+	// existing are the transactions that were sent before the stream
+	// caught up.  They aren't sent twice if they're notified as well.
+	var existing map[chainhash.Hash]struct{}
+	if request.IncludeExisting && s.cfg.TxMemPool != nil {
+		txDescs := s.cfg.TxMemPool.TxDescs()
+		existing = make(map[chainhash.Hash]struct{}, len(txDescs))
+		for _, txD := range txDescs {
+			msg, err := mempoolTx(txD)
+			if err != nil {
+				return err
+			}
+			err = st.send(msg.Marshal())
+			if err != nil {
+				return err
+			}
+			existing[*txD.Tx.Hash()] = struct{}{}
+		}
+	}
+
+	for {
+		select {
+		case txD := <-sub.txs:
+			if _, ok := existing[*txD.Tx.Hash()]; ok {
+				continue
+			}
+			msg, err := mempoolTx(txD)
+			if err != nil {
+				return err
+			}
+			err = st.send(msg.Marshal())
+			if err != nil {
+				return err
+			}
+
+		case <-sub.full:
+			return newStatusError(codeResourceExhausted,
+				"the stream fell too far behind the mempool")
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package grpcserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/mempool"
+	"github.com/utreexo/utreexod/mining"
+	"github.com/utreexo/utreexod/wire"
+)
+
+func TestMessagesRoundTrip(t *testing.T) {
+	event := BlockProofEvent{
+		Type:         EventConnected,
+		Height:       -1,
+		Hash:         bytes.Repeat([]byte{1}, 32),
+		Targets:      []uint64{0, 300, 1 << 40},
+		ProofHashes:  [][]byte{bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{3}, 32)},
+		TargetHashes: [][]byte{bytes.Repeat([]byte{4}, 32)},
+		LeafDatas: []LeafData{{
+			BlockHash:  bytes.Repeat([]byte{5}, 32),
+			Txid:       bytes.Repeat([]byte{6}, 32),
+			Index:      7,
+			Height:     8,
+			IsCoinBase: true,
+			Amount:     5000000000,
+			PkScript:   []byte{0x51},
+		}},
+		RememberIndexes: []uint32{1, 2},
+	}
+
+	var got BlockProofEvent
+	err := got.Unmarshal(event.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, event) {
+		t.Fatalf("expected %+v but got %+v", event, got)
+	}
+
+	tx := MempoolTx{Txid: []byte{1}, RawTx: []byte{2, 3}, Fee: 1000, AddedTime: 1700000000}
+	var gotTx MempoolTx
+	err = gotTx.Unmarshal(tx.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotTx, tx) {
+		t.Fatalf("expected %+v but got %+v", tx, gotTx)
+	}
+
+	// Truncated messages must be rejected.
+	b := event.Marshal()
+	err = got.Unmarshal(b[:len(b)-1])
+	if err == nil {
+		t.Fatalf("expected an error for a truncated message")
+	}
+}
+
+// readResponse reads a length-prefixed response message.
+func readResponse(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+
+	var hdr [5]byte
+	_, err := io.ReadFull(r, hdr[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := make([]byte, binary.BigEndian.Uint32(hdr[1:]))
+	_, err = io.ReadFull(r, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// newRequest returns a gRPC request of the method with the message.
+func newRequest(t *testing.T, url, method string, msg []byte) *http.Request {
+	t.Helper()
+
+	body := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(msg)))
+	body = append(body, msg...)
+
+	req, err := http.NewRequest(http.MethodPost, url+"/"+serviceName+"/"+method,
+		bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	return req
+}
+
+func TestStreamMempool(t *testing.T) {
+	s := &Server{
+		quit:        make(chan struct{}),
+		tipChanged:  make(chan struct{}),
+		mempoolSubs: make(map[*mempoolSub]struct{}),
+	}
+	ts := httptest.NewUnstartedServer(s)
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	req := newRequest(t, ts.URL, "StreamMempool", (&StreamMempoolRequest{}).Marshal())
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Wait for the stream to subscribe before notifying the transaction.
+	for i := 0; ; i++ {
+		s.mempoolMtx.Lock()
+		subs := len(s.mempoolSubs)
+		s.mempoolMtx.Unlock()
+		if subs > 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("the stream didn't subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	tx := btcutil.NewTx(msgTx)
+	s.NotifyNewTransactions([]*mempool.TxDesc{{
+		TxDesc: mining.TxDesc{Tx: tx, Added: time.Unix(1700000000, 0), Fee: 500},
+	}})
+
+	var got MempoolTx
+	err = got.Unmarshal(readResponse(t, resp.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Txid, tx.Hash()[:]) || got.Fee != 500 ||
+		got.AddedTime != 1700000000 {
+
+		t.Fatalf("unexpected mempool tx %+v", got)
+	}
+
+	// Stopping the server ends the stream with UNAVAILABLE.
+	close(s.quit)
+	_, err = io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := resp.Trailer.Get("Grpc-Status"); status != "14" {
+		t.Fatalf("expected status 14 but got %q", status)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	s := &Server{quit: make(chan struct{})}
+	ts := httptest.NewUnstartedServer(s)
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	resp, err := ts.Client().Do(newRequest(t, ts.URL, "Unknown", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := resp.Trailer.Get("Grpc-Status"); status != "12" {
+		t.Fatalf("expected status 12 but got %q", status)
+	}
+}
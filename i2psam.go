@@ -0,0 +1,479 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// i2pKeyFile is the name of the file in the data directory that the
+	// private key of the I2P destination is kept in, so that the
+	// destination keeps its address across restarts.
+	i2pKeyFile = "i2p_private_key"
+
+	// i2pSAMTimeout is how long connecting to the SAM bridge and creating
+	// the session over it may take.
+	i2pSAMTimeout = 2 * time.Minute
+
+	// i2pSessionRetryInterval is how long to wait before accepting
+	// connections again after the session with the SAM bridge failed.
+	i2pSessionRetryInterval = time.Minute
+
+	// i2pDestinationLen is the length of a destination without its
+	// certificate, which is a 256 byte public key, a 128 byte signing key
+	// and the 3 byte header of the certificate.
+	i2pDestinationLen = 387
+)
+
+var (
+	// i2pBase64 is the base64 encoding of I2P, which uses - and ~ in place
+	// of + and /.
+	i2pBase64 = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-~")
+
+	// i2pBase32 is the base32 encoding of the .b32.i2p names of the
+	// destinations.
+	i2pBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	// errI2PSessionClosed is returned by the I2P session once it's closed.
+	errI2PSessionClosed = errors.New("i2p session closed")
+)
+
+// i2pAddr implements the net.Addr interface and represents an I2P address.
+type i2pAddr struct {
+	addr string
+}
+
+// String returns the I2P address.
+//
+// This is part of the net.Addr interface.
+func (ia *i2pAddr) String() string {
+	return ia.addr
+}
+
+// Network returns "i2p".
+//
+// This is part of the net.Addr interface.
+func (ia *i2pAddr) Network() string {
+	return "i2p"
+}
+
+// Ensure i2pAddr implements the net.Addr interface.
+var _ net.Addr = (*i2pAddr)(nil)
+
+// i2pConn is a stream to another I2P destination over a connection to the SAM
+// bridge.  Whatever the bridge sent after the reply that opened the stream is
+// read before the rest of the connection.
+type i2pConn struct {
+	net.Conn
+	reader *bufio.Reader
+	local  net.Addr
+	remote net.Addr
+}
+
+// Read reads from the stream.
+//
+// This is part of the net.Conn interface.
+func (c *i2pConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// LocalAddr returns the I2P address of the destination of the node.
+//
+// This is part of the net.Conn interface.
+func (c *i2pConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// RemoteAddr returns the I2P address of the destination of the peer.
+//
+// This is part of the net.Conn interface.
+func (c *i2pConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// i2pHost returns the .b32.i2p name of the I2P destination, which is either
+// the base64 encoded destination or a private key starting with it.
+func i2pHost(destination string) (string, error) {
+	raw, err := i2pBase64.DecodeString(destination)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < i2pDestinationLen {
+		return "", fmt.Errorf("i2p destination is %d bytes, want at "+
+			"least %d", len(raw), i2pDestinationLen)
+	}
+	certLen := int(binary.BigEndian.Uint16(raw[i2pDestinationLen-2:]))
+	if len(raw) < i2pDestinationLen+certLen {
+		return "", errors.New("i2p destination certificate is truncated")
+	}
+	hash := sha256.Sum256(raw[:i2pDestinationLen+certLen])
+	return strings.ToLower(i2pBase32.EncodeToString(hash[:])) + ".b32.i2p", nil
+}
+
+// dialI2PSAM connects to the SAM bridge at the address and greets it.  The
+// deadline of the connection is left at the timeout.
+func dialI2PSAM(addr string, timeout time.Duration) (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	reader := bufio.NewReader(conn)
+	_, err = i2pSAMCommand(conn, reader, "HELLO VERSION MIN=3.1 MAX=3.1")
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, reader, nil
+}
+
+// i2pSAMCommand sends the command to the SAM bridge and returns the key=value
+// fields of its reply.  Replies with a result other than OK are turned into
+// errors.
+func i2pSAMCommand(conn net.Conn, reader *bufio.Reader, cmd string) (map[string]string, error) {
+	if _, err := fmt.Fprintf(conn, "%s\n", cmd); err != nil {
+		return nil, err
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	values := torKeyValues(strings.TrimRight(line, "\r\n"))
+	if result, ok := values["RESULT"]; ok && result != "OK" {
+		if message := values["MESSAGE"]; message != "" {
+			result += ": " + message
+		}
+		name := strings.Join(strings.Fields(cmd)[:2], " ")
+		return nil, fmt.Errorf("i2p sam command %s failed: %s", name,
+			result)
+	}
+	return values, nil
+}
+
+// i2pSession is a streaming session of the I2P destination of the node with
+// the SAM bridge of an I2P router.  Outbound connections to other destinations
+// are dialed over it and it accepts the inbound connections as a net.Listener.
+// The session is created when it's first needed and again after the bridge is
+// lost.
+type i2pSession struct {
+	samAddr string
+	keyPath string
+	port    string
+	quit    chan struct{}
+
+	// createMtx makes sure only one session is created at a time.
+	createMtx  sync.Mutex
+	privateKey string
+
+	mtx     sync.Mutex
+	control net.Conn // nil when there's no session
+	id      string
+	host    string
+	pending map[net.Conn]struct{}
+	closed  bool
+}
+
+// newI2PSession returns an I2P session with the SAM bridge at the address,
+// keeping the private key of the destination in the file.  The port is used
+// in the address of the destination, which SAM 3.1 otherwise has no use for.
+func newI2PSession(samAddr, keyPath, port string) *i2pSession {
+	s := &i2pSession{
+		samAddr: samAddr,
+		keyPath: keyPath,
+		port:    port,
+		quit:    make(chan struct{}),
+		pending: make(map[net.Conn]struct{}),
+	}
+	if key, err := os.ReadFile(keyPath); err == nil {
+		s.privateKey = strings.TrimSpace(string(key))
+		s.host, _ = i2pHost(s.privateKey)
+	}
+	return s
+}
+
+// dial connects to the SAM bridge and greets it.  The connection is closed
+// when the session is, up until it's released.
+func (s *i2pSession) dial(timeout time.Duration) (net.Conn, *bufio.Reader, error) {
+	conn, reader, err := dialI2PSAM(s.samAddr, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		conn.Close()
+		return nil, nil, errI2PSessionClosed
+	}
+	s.pending[conn] = struct{}{}
+	return conn, reader, nil
+}
+
+// release stops closing the connection along with the session.
+func (s *i2pSession) release(conn net.Conn) {
+	s.mtx.Lock()
+	delete(s.pending, conn)
+	s.mtx.Unlock()
+}
+
+// session returns the id of the session with the SAM bridge, creating the
+// session when there isn't one.
+func (s *i2pSession) session() (string, error) {
+	s.createMtx.Lock()
+	defer s.createMtx.Unlock()
+
+	s.mtx.Lock()
+	id, closed := s.id, s.closed
+	if s.control == nil {
+		id = ""
+	}
+	s.mtx.Unlock()
+	if closed {
+		return "", errI2PSessionClosed
+	}
+	if id != "" {
+		return id, nil
+	}
+
+	conn, reader, err := s.dial(i2pSAMTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer s.release(conn)
+	id, err = s.create(conn, reader)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	conn.SetDeadline(time.Time{})
+
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		conn.Close()
+		return "", errI2PSessionClosed
+	}
+	s.control, s.id = conn, id
+	s.mtx.Unlock()
+	srvrLog.Infof("I2P session created for %s", s.Addr())
+
+	// The session lasts for as long as the connection it was created over
+	// stays open.
+	go func() {
+		for {
+			if _, err := reader.ReadString('\n'); err != nil {
+				break
+			}
+		}
+		s.mtx.Lock()
+		if s.control == conn {
+			s.control = nil
+		}
+		s.mtx.Unlock()
+		conn.Close()
+	}()
+
+	return id, nil
+}
+
+// create creates a session over the connection to the SAM bridge and returns
+// its id.  The private key of the destination is generated and saved first
+// when there isn't one yet.
+func (s *i2pSession) create(conn net.Conn, reader *bufio.Reader) (string, error) {
+	if s.privateKey == "" {
+		values, err := i2pSAMCommand(conn, reader,
+			"DEST GENERATE SIGNATURE_TYPE=7")
+		if err != nil {
+			return "", err
+		}
+		host, err := i2pHost(values["PRIV"])
+		if err != nil {
+			return "", err
+		}
+		err = os.WriteFile(s.keyPath, []byte(values["PRIV"]+"\n"), 0600)
+		if err != nil {
+			return "", err
+		}
+		s.privateKey = values["PRIV"]
+		s.mtx.Lock()
+		s.host = host
+		s.mtx.Unlock()
+	}
+
+	var nonce [8]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(nonce[:])
+	_, err := i2pSAMCommand(conn, reader, fmt.Sprintf("SESSION CREATE "+
+		"STYLE=STREAM ID=%s DESTINATION=%s SIGNATURE_TYPE=7", id,
+		s.privateKey))
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Dial connects to the I2P destination at the address over the session.
+func (s *i2pSession) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.session()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, reader, err := s.dial(timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer s.release(conn)
+	values, err := i2pSAMCommand(conn, reader, "NAMING LOOKUP NAME="+host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_, err = i2pSAMCommand(conn, reader, fmt.Sprintf("STREAM CONNECT ID=%s "+
+		"DESTINATION=%s SILENT=false", id, values["VALUE"]))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return &i2pConn{
+		Conn:   conn,
+		reader: reader,
+		local:  s.Addr(),
+		remote: &i2pAddr{addr: addr},
+	}, nil
+}
+
+// Accept waits for the next inbound connection to the destination.  Failures
+// of the session are retried until the session is closed.
+//
+// This is part of the net.Listener interface.
+func (s *i2pSession) Accept() (net.Conn, error) {
+	for {
+		conn, err := s.accept()
+		if err == nil {
+			return conn, nil
+		}
+
+		select {
+		case <-s.quit:
+			return nil, errI2PSessionClosed
+		default:
+		}
+		srvrLog.Warnf("Unable to accept connections over I2P: %v", err)
+
+		select {
+		case <-time.After(i2pSessionRetryInterval):
+		case <-s.quit:
+			return nil, errI2PSessionClosed
+		}
+	}
+}
+
+// accept waits for a single inbound connection to the destination.
+func (s *i2pSession) accept() (net.Conn, error) {
+	id, err := s.session()
+	if err != nil {
+		return nil, err
+	}
+	conn, reader, err := s.dial(i2pSAMTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer s.release(conn)
+	_, err = i2pSAMCommand(conn, reader, fmt.Sprintf("STREAM ACCEPT ID=%s "+
+		"SILENT=false", id))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// The bridge sends the destination of the peer once it connects.
+	conn.SetDeadline(time.Time{})
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		conn.Close()
+		return nil, errors.New("i2p sam bridge sent no peer destination")
+	}
+	host, err := i2pHost(fields[0])
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &i2pConn{
+		Conn:   conn,
+		reader: reader,
+		local:  s.Addr(),
+		remote: &i2pAddr{addr: net.JoinHostPort(host, "0")},
+	}, nil
+}
+
+// Close ends the session along with the connections to the SAM bridge that
+// are still being set up or waiting for inbound connections.
+//
+// This is part of the net.Listener interface.
+func (s *i2pSession) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.quit)
+	if s.control != nil {
+		s.control.Close()
+		s.control = nil
+	}
+	for conn := range s.pending {
+		conn.Close()
+	}
+	return nil
+}
+
+// Addr returns the I2P address of the destination of the node.  It's an empty
+// address until the private key of the destination was created.
+//
+// This is part of the net.Listener interface.
+func (s *i2pSession) Addr() net.Addr {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.host == "" {
+		return &i2pAddr{}
+	}
+	return &i2pAddr{addr: net.JoinHostPort(s.host, s.port)}
+}
+
+// Ensure i2pSession implements the net.Listener interface.
+var _ net.Listener = (*i2pSession)(nil)
@@ -0,0 +1,174 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// fakeI2PDestination returns a destination with an empty certificate that's
+// filled with the byte, along with its private key.
+func fakeI2PDestination(b byte) (string, string) {
+	raw := bytes.Repeat([]byte{b}, i2pDestinationLen)
+	raw[i2pDestinationLen-3], raw[i2pDestinationLen-2], raw[i2pDestinationLen-1] = 0, 0, 0
+	private := append(append([]byte{}, raw...), bytes.Repeat([]byte{0xee}, 32)...)
+	return i2pBase64.EncodeToString(raw), i2pBase64.EncodeToString(private)
+}
+
+// fakeSAM answers the commands of the SAM protocol on the connections to the
+// listener the way an I2P router does.  Streams that are connected get the
+// destination of the peer and are then answered with what they send, while
+// waiting for an inbound connection is answered right away by the peer.
+func fakeSAM(listener net.Listener, commands chan<- string) {
+	peer, privateKey := fakeI2PDestination(1)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				cmd := strings.TrimSpace(line)
+				commands <- cmd
+
+				fields := strings.Fields(cmd)
+				var reply string
+				switch fields[0] + " " + fields[1] {
+				case "HELLO VERSION":
+					reply = "HELLO REPLY RESULT=OK VERSION=3.1\n"
+				case "DEST GENERATE":
+					reply = fmt.Sprintf("DEST REPLY PUB=%s PRIV=%s\n",
+						peer, privateKey)
+				case "SESSION CREATE":
+					reply = "SESSION STATUS RESULT=OK DESTINATION=" +
+						privateKey + "\n"
+				case "NAMING LOOKUP":
+					reply = fmt.Sprintf("NAMING REPLY RESULT=OK %s VALUE=%s\n",
+						fields[2], peer)
+				case "STREAM CONNECT":
+					conn.Write([]byte("STREAM STATUS RESULT=OK\n"))
+					io.Copy(conn, reader)
+					return
+				case "STREAM ACCEPT":
+					conn.Write([]byte("STREAM STATUS RESULT=OK\n" +
+						peer + " FROM_PORT=0 TO_PORT=0\nversion"))
+					io.Copy(io.Discard, reader)
+					return
+				default:
+					reply = "SESSION STATUS RESULT=I2P_ERROR MESSAGE=\"unknown command\"\n"
+				}
+				if _, err := conn.Write([]byte(reply)); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// TestI2PSession ensures that the I2P session creates and keeps the private key
+// of the destination, and that it dials and accepts streams over the SAM
+// bridge.
+func TestI2PSession(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	commands := make(chan string, 64)
+	go fakeSAM(listener, commands)
+
+	// There's no log rotator to write the logs of the session to.
+	logger := srvrLog
+	srvrLog = btclog.Disabled
+	defer func() { srvrLog = logger }()
+
+	peer, privateKey := fakeI2PDestination(1)
+	host, err := i2pHost(peer)
+	if err != nil {
+		t.Fatalf("unable to get the name of the destination: %v", err)
+	}
+	if privateHost, err := i2pHost(privateKey); err != nil || privateHost != host {
+		t.Fatalf("got name %s for the private key, want %s", privateHost, host)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), i2pKeyFile)
+	session := newI2PSession(listener.Addr().String(), keyPath, "8333")
+	defer session.Close()
+
+	conn, err := session.Dial(net.JoinHostPort(host, "8333"), time.Minute)
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "ping" {
+		t.Fatalf("got reply %q (%v), want %q", reply, err, "ping")
+	}
+	conn.Close()
+
+	key, err := os.ReadFile(keyPath)
+	if err != nil || strings.TrimSpace(string(key)) != privateKey {
+		t.Fatalf("got private key %q (%v), want %q", key, err, privateKey)
+	}
+	if addr := session.Addr().String(); addr != net.JoinHostPort(host, "8333") {
+		t.Fatalf("got address %s, want %s", addr, net.JoinHostPort(host, "8333"))
+	}
+
+	conn, err = session.Accept()
+	if err != nil {
+		t.Fatalf("unable to accept: %v", err)
+	}
+	if addr := conn.RemoteAddr().String(); addr != net.JoinHostPort(host, "0") {
+		t.Fatalf("got remote address %s, want %s", addr, net.JoinHostPort(host, "0"))
+	}
+	msg := make([]byte, 7)
+	if _, err := io.ReadFull(conn, msg); err != nil || string(msg) != "version" {
+		t.Fatalf("got %q (%v), want %q", msg, err, "version")
+	}
+	conn.Close()
+
+	session.Close()
+	if _, err := session.Accept(); err != errI2PSessionClosed {
+		t.Fatalf("got error %v after closing, want %v", err, errI2PSessionClosed)
+	}
+
+	// The saved private key is used from then on.
+	var generated int
+	for len(commands) > 0 {
+		cmd := <-commands
+		if strings.HasPrefix(cmd, "DEST GENERATE") {
+			generated++
+		}
+		if strings.HasPrefix(cmd, "SESSION CREATE") &&
+			!strings.Contains(cmd, "DESTINATION="+privateKey) {
+			t.Fatalf("session created with another key: %s", cmd)
+		}
+	}
+	if generated != 1 {
+		t.Fatalf("private key generated %d times, want 1", generated)
+	}
+	if session := newI2PSession(listener.Addr().String(), keyPath, "8333"); session.Addr().String() != net.JoinHostPort(host, "8333") {
+		t.Fatalf("saved private key isn't loaded")
+	}
+}
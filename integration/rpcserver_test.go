@@ -10,7 +10,12 @@ package integration
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"runtime/debug"
 	"testing"
@@ -136,11 +141,74 @@ func testBulkClient(r *rpctest.Harness, t *testing.T) {
 
 }
 
+func testRawBatchRequest(r *rpctest.Harness, t *testing.T) {
+	// Send a batch like Bitcoin Core clients do, with a request that
+	// doesn't specify the JSON-RPC version, a request of an unknown method
+	// and an entry that isn't a request object.
+	body := ` [{"id":1,"method":"getblockcount"},` +
+		`{"jsonrpc":"2.0","id":"b","method":"nosuchmethod","params":[]},` +
+		`1,` +
+		`{"jsonrpc":"1.0","id":3,"method":"getblockhash","params":[0]}]`
+
+	rpcConfig := r.RPCConfig()
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(rpcConfig.Certificates)
+	client := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+	req, err := http.NewRequest("POST", "https://"+rpcConfig.Host,
+		bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(rpcConfig.User, rpcConfig.Pass)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+		ID interface{} `json:"id"`
+	}
+	if err := json.Unmarshal(respBytes, &replies); err != nil {
+		t.Fatalf("unable to unmarshal batched response %s: %v",
+			respBytes, err)
+	}
+	if len(replies) != 4 {
+		t.Fatalf("expected 4 replies, got %d: %s", len(replies), respBytes)
+	}
+
+	// The replies are in the order of the requests.
+	wantIDs := []interface{}{1.0, "b", nil, 3.0}
+	wantErrs := []bool{false, true, true, false}
+	for i, reply := range replies {
+		if reply.ID != wantIDs[i] {
+			t.Fatalf("expected reply %d to have id %v, got %v", i,
+				wantIDs[i], reply.ID)
+		}
+		if (reply.Error != nil) != wantErrs[i] {
+			t.Fatalf("unexpected error of reply %d: %s", i, respBytes)
+		}
+	}
+}
+
 var rpcTestCases = []rpctest.HarnessTestCase{
 	testGetBestBlock,
 	testGetBlockCount,
 	testGetBlockHash,
 	testBulkClient,
+	testRawBatchRequest,
 }
 
 var primaryHarness *rpctest.Harness
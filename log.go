@@ -17,6 +17,7 @@ import (
 	"github.com/utreexo/utreexod/connmgr"
 	"github.com/utreexo/utreexod/database"
 	"github.com/utreexo/utreexod/electrum"
+	"github.com/utreexo/utreexod/grpcserver"
 	"github.com/utreexo/utreexod/mempool"
 	"github.com/utreexo/utreexod/mining"
 	"github.com/utreexo/utreexod/mining/cpuminer"
@@ -24,6 +25,7 @@ import (
 	"github.com/utreexo/utreexod/peer"
 	"github.com/utreexo/utreexod/txscript"
 	"github.com/utreexo/utreexod/wallet"
+	"github.com/utreexo/utreexod/zmq"
 
 	"github.com/btcsuite/btclog"
 	"github.com/jrick/logrotate/rotator"
@@ -75,6 +77,8 @@ var (
 	wlltLog = backendLog.Logger("WLLT")
 	elecLog = backendLog.Logger("ELEC")
 	bdkwLog = backendLog.Logger("BDKW")
+	zmqpLog = backendLog.Logger("ZMQP")
+	grpcLog = backendLog.Logger("GRPC")
 )
 
 // Initialize package-global logger variables.
@@ -93,6 +97,8 @@ func init() {
 	wallet.UseLogger(wlltLog)
 	electrum.UseLogger(elecLog)
 	bdkwallet.UseLogger(bdkwLog)
+	zmq.UseLogger(zmqpLog)
+	grpcserver.UseLogger(grpcLog)
 }
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
@@ -115,6 +121,8 @@ var subsystemLoggers = map[string]btclog.Logger{
 	"WLLT": wlltLog,
 	"ELEC": elecLog,
 	"BDKW": bdkwLog,
+	"ZMQP": zmqpLog,
+	"GRPC": grpcLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
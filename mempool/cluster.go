@@ -0,0 +1,319 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/mining"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// MaxClusterCount is the maximum number of transactions in a cluster of the
+// pool.  It bounds the cost of keeping the linearization of a cluster up to
+// date as transactions are added and removed.
+const MaxClusterCount = 64
+
+// txChunk is a set of consecutive transactions in the linearization of a
+// cluster that a miner would include together since the ones among them that
+// pay lower fee rates are paid for by the others.
+type txChunk struct {
+	txs  []*TxDesc
+	fee  int64
+	size int64
+}
+
+// higherFeeRate returns whether the chunk pays a higher fee rate than the
+// other chunk.
+func (c *txChunk) higherFeeRate(other *txChunk) bool {
+	return c.fee*other.size > other.fee*c.size
+}
+
+// feePerKB returns the fee rate of the chunk in Satoshi per 1000 bytes.
+func (c *txChunk) feePerKB() int64 {
+	return c.fee * 1000 / c.size
+}
+
+// txCluster is a set of transactions in the pool that are connected by spending
+// each other's outputs.  It keeps a linearization of its transactions, which is
+// an order that respects their dependencies, split into chunks of
+// non-increasing fee rate.  This is the order a miner maximizing fees would
+// include them in, and what eviction and replacement decisions are based on.
+type txCluster struct {
+	txs      map[chainhash.Hash]*TxDesc
+	chunks   []*txChunk
+	chunkIdx map[chainhash.Hash]int
+}
+
+// newTxCluster returns a cluster of the passed transactions along with their
+// linearization.
+func newTxCluster(txs map[chainhash.Hash]*TxDesc) *txCluster {
+	cluster := &txCluster{
+		txs:      txs,
+		chunks:   linearizeCluster(txs),
+		chunkIdx: make(map[chainhash.Hash]int, len(txs)),
+	}
+	for i, chunk := range cluster.chunks {
+		for _, desc := range chunk.txs {
+			cluster.chunkIdx[*desc.Tx.Hash()] = i
+		}
+	}
+	return cluster
+}
+
+// chunkFeePerKB returns the fee rate of the chunk the passed transaction of the
+// cluster is in.
+func (c *txCluster) chunkFeePerKB(hash chainhash.Hash) int64 {
+	return c.chunks[c.chunkIdx[hash]].feePerKB()
+}
+
+// appendAncestors appends the ancestors of the passed transaction among the
+// remaining transactions that weren't seen yet, followed by the transaction
+// itself, so that parents always come before their children.
+func appendAncestors(desc *TxDesc, remaining map[chainhash.Hash]*TxDesc,
+	seen map[chainhash.Hash]struct{}, order []*TxDesc) []*TxDesc {
+
+	seen[*desc.Tx.Hash()] = struct{}{}
+	for _, txIn := range desc.Tx.MsgTx().TxIn {
+		parent, ok := remaining[txIn.PreviousOutPoint.Hash]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[*parent.Tx.Hash()]; ok {
+			continue
+		}
+		order = appendAncestors(parent, remaining, seen, order)
+	}
+	return append(order, desc)
+}
+
+// linearizeCluster orders the transactions of a cluster by repeatedly picking
+// the remaining transaction whose set of remaining ancestors pays the highest
+// fee rate along with those ancestors.  The order is then split into chunks of
+// non-increasing fee rate by merging each chunk into the one before it while it
+// pays a higher fee rate.
+func linearizeCluster(txs map[chainhash.Hash]*TxDesc) []*txChunk {
+	// Consider the transactions in a fixed order so that ties are always
+	// broken the same way.
+	sorted := make([]*TxDesc, 0, len(txs))
+	sizes := make(map[chainhash.Hash]int64, len(txs))
+	for hash, desc := range txs {
+		sorted = append(sorted, desc)
+		sizes[hash] = GetTxVirtualSize(desc.Tx)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Tx.Hash()[:],
+			sorted[j].Tx.Hash()[:]) < 0
+	})
+
+	remaining := make(map[chainhash.Hash]*TxDesc, len(txs))
+	for hash, desc := range txs {
+		remaining[hash] = desc
+	}
+	order := make([]*TxDesc, 0, len(txs))
+	for len(remaining) > 0 {
+		var best []*TxDesc
+		var bestChunk txChunk
+		for _, desc := range sorted {
+			if _, ok := remaining[*desc.Tx.Hash()]; !ok {
+				continue
+			}
+
+			set := appendAncestors(desc, remaining,
+				make(map[chainhash.Hash]struct{}), nil)
+			var chunk txChunk
+			for _, ancestor := range set {
+				chunk.fee += ancestor.Fee
+				chunk.size += sizes[*ancestor.Tx.Hash()]
+			}
+			if best == nil || chunk.higherFeeRate(&bestChunk) {
+				best = set
+				bestChunk = chunk
+			}
+		}
+
+		for _, desc := range best {
+			delete(remaining, *desc.Tx.Hash())
+		}
+		order = append(order, best...)
+	}
+
+	chunks := make([]*txChunk, 0, len(order))
+	for _, desc := range order {
+		chunk := &txChunk{
+			txs:  []*TxDesc{desc},
+			fee:  desc.Fee,
+			size: sizes[*desc.Tx.Hash()],
+		}
+		for len(chunks) > 0 && chunk.higherFeeRate(chunks[len(chunks)-1]) {
+			prev := chunks[len(chunks)-1]
+			prev.txs = append(prev.txs, chunk.txs...)
+			prev.fee += chunk.fee
+			prev.size += chunk.size
+			chunk = prev
+			chunks = chunks[:len(chunks)-1]
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// connectedClusters returns the clusters of the transactions in the pool that
+// the passed transaction spends the outputs of or that spend its outputs.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) connectedClusters(tx *btcutil.Tx) map[*txCluster]struct{} {
+	clusters := make(map[*txCluster]struct{})
+	for _, txIn := range tx.MsgTx().TxIn {
+		if cluster, ok := mp.clusters[txIn.PreviousOutPoint.Hash]; ok {
+			clusters[cluster] = struct{}{}
+		}
+	}
+	prevOut := wire.OutPoint{Hash: *tx.Hash()}
+	for i := range tx.MsgTx().TxOut {
+		prevOut.Index = uint32(i)
+		spender, ok := mp.outpoints[prevOut]
+		if !ok {
+			continue
+		}
+		if cluster, ok := mp.clusters[*spender.Hash()]; ok {
+			clusters[cluster] = struct{}{}
+		}
+	}
+	return clusters
+}
+
+// setCluster makes the passed cluster the one of each of its transactions.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) setCluster(cluster *txCluster) {
+	for hash := range cluster.txs {
+		mp.clusters[hash] = cluster
+	}
+}
+
+// addToCluster merges the clusters the passed transaction that was just added
+// to the pool is connected to into a single one that includes it.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) addToCluster(txD *TxDesc) {
+	txs := map[chainhash.Hash]*TxDesc{*txD.Tx.Hash(): txD}
+	for cluster := range mp.connectedClusters(txD.Tx) {
+		for hash, desc := range cluster.txs {
+			txs[hash] = desc
+		}
+	}
+	mp.setCluster(newTxCluster(txs))
+}
+
+// removeFromCluster removes the passed transaction that was just removed from
+// the pool from its cluster, which is split into the clusters of the
+// transactions that are still connected to each other.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) removeFromCluster(tx *btcutil.Tx) {
+	cluster, ok := mp.clusters[*tx.Hash()]
+	if !ok {
+		return
+	}
+	delete(mp.clusters, *tx.Hash())
+
+	remaining := make(map[chainhash.Hash]*TxDesc, len(cluster.txs)-1)
+	for hash, desc := range cluster.txs {
+		if hash != *tx.Hash() {
+			remaining[hash] = desc
+		}
+	}
+
+	// Walk the dependencies of each transaction that isn't part of a new
+	// cluster yet to find the transactions that are still connected to it.
+	for len(remaining) > 0 {
+		var stack []*TxDesc
+		for _, desc := range remaining {
+			stack = append(stack, desc)
+			break
+		}
+		txs := make(map[chainhash.Hash]*TxDesc)
+		for len(stack) > 0 {
+			desc := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			hash := *desc.Tx.Hash()
+			if _, ok := remaining[hash]; !ok {
+				continue
+			}
+			delete(remaining, hash)
+			txs[hash] = desc
+
+			for _, txIn := range desc.Tx.MsgTx().TxIn {
+				parent, ok := remaining[txIn.PreviousOutPoint.Hash]
+				if ok {
+					stack = append(stack, parent)
+				}
+			}
+			prevOut := wire.OutPoint{Hash: hash}
+			for i := range desc.Tx.MsgTx().TxOut {
+				prevOut.Index = uint32(i)
+				spender, ok := mp.outpoints[prevOut]
+				if !ok {
+					continue
+				}
+				if child, ok := remaining[*spender.Hash()]; ok {
+					stack = append(stack, child)
+				}
+			}
+		}
+		mp.setCluster(newTxCluster(txs))
+	}
+}
+
+// clusterCount returns the number of transactions in the cluster the passed
+// transaction would be in if it was accepted and its conflicts were replaced.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) clusterCount(tx *btcutil.Tx,
+	conflicts map[chainhash.Hash]*btcutil.Tx) int {
+
+	count := 1
+	for cluster := range mp.connectedClusters(tx) {
+		for hash := range cluster.txs {
+			if _, ok := conflicts[hash]; !ok {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// chunkFeePerKB returns the fee rate of the chunk the passed transaction in the
+// pool is in, which is the fee rate it's expected to be mined at.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) chunkFeePerKB(hash chainhash.Hash) int64 {
+	return mp.clusters[hash].chunkFeePerKB(hash)
+}
+
+// replacementFeePerKB returns the fee rate of the chunk the passed replacement
+// would be in once it's accepted and its conflicts are replaced.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) replacementFeePerKB(tx *btcutil.Tx, txFee int64,
+	conflicts map[chainhash.Hash]*btcutil.Tx) int64 {
+
+	txs := map[chainhash.Hash]*TxDesc{
+		*tx.Hash(): {TxDesc: mining.TxDesc{Tx: tx, Fee: txFee}},
+	}
+	for cluster := range mp.connectedClusters(tx) {
+		for hash, desc := range cluster.txs {
+			if _, ok := conflicts[hash]; !ok {
+				txs[hash] = desc
+			}
+		}
+	}
+	return newTxCluster(txs).chunkFeePerKB(*tx.Hash())
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/mining"
+)
+
+// testDesc returns a descriptor of the passed transaction paying the fee.
+func testDesc(tx *btcutil.Tx, fee int64) *TxDesc {
+	return &TxDesc{TxDesc: mining.TxDesc{Tx: tx, Fee: fee}}
+}
+
+// TestLinearizeCluster ensures that clusters are linearized into chunks of
+// non-increasing fee rates that respect the dependencies of the transactions.
+func TestLinearizeCluster(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+
+	// parent -> child -> grandchild
+	chain, err := harness.CreateTxChain(spendableOuts[0], 3)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	parent, child, grandchild := chain[0], chain[1], chain[2]
+
+	tests := []struct {
+		name   string
+		fees   []int64
+		chunks [][]*btcutil.Tx
+	}{
+		{
+			name:   "decreasing fee rates",
+			fees:   []int64{3000, 2000, 1000},
+			chunks: [][]*btcutil.Tx{{parent}, {child}, {grandchild}},
+		},
+		{
+			name:   "child pays for parent",
+			fees:   []int64{1000, 5000, 2000},
+			chunks: [][]*btcutil.Tx{{parent, child}, {grandchild}},
+		},
+		{
+			name:   "grandchild pays for all",
+			fees:   []int64{1000, 1000, 9000},
+			chunks: [][]*btcutil.Tx{{parent, child, grandchild}},
+		},
+	}
+
+	for _, test := range tests {
+		txs := make(map[chainhash.Hash]*TxDesc)
+		for i, tx := range chain {
+			txs[*tx.Hash()] = testDesc(tx, test.fees[i])
+		}
+		cluster := newTxCluster(txs)
+
+		if len(cluster.chunks) != len(test.chunks) {
+			t.Fatalf("%s: expected %d chunks, got %d", test.name,
+				len(test.chunks), len(cluster.chunks))
+		}
+		for i, chunk := range cluster.chunks {
+			var fee, size int64
+			for j, desc := range chunk.txs {
+				if desc.Tx != test.chunks[i][j] {
+					t.Fatalf("%s: unexpected transaction %d of "+
+						"chunk %d", test.name, j, i)
+				}
+				fee += desc.Fee
+				size += GetTxVirtualSize(desc.Tx)
+			}
+			want := fee * 1000 / size
+			if chunk.feePerKB() != want {
+				t.Fatalf("%s: expected a fee rate of %d for "+
+					"chunk %d, got %d", test.name, want, i,
+					chunk.feePerKB())
+			}
+			if i > 0 && chunk.higherFeeRate(cluster.chunks[i-1]) {
+				t.Fatalf("%s: chunk %d pays a higher fee rate "+
+					"than the one before it", test.name, i)
+			}
+		}
+	}
+}
+
+// TestPoolClusters ensures that the clusters of the pool are merged and split
+// as transactions are added and removed and that they can't grow past the
+// maximum count.
+func TestPoolClusters(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	mp := harness.txPool
+
+	chainedTxns, err := harness.CreateTxChain(spendableOuts[0],
+		MaxClusterCount+1)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for _, tx := range chainedTxns[:MaxClusterCount] {
+		_, err := mp.ProcessTransaction(tx, false, false, 0)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+	cluster := mp.clusters[*chainedTxns[0].Hash()]
+	if len(cluster.txs) != MaxClusterCount {
+		t.Fatalf("expected a cluster of %d transactions, got %d",
+			MaxClusterCount, len(cluster.txs))
+	}
+	for _, tx := range chainedTxns[:MaxClusterCount] {
+		if mp.clusters[*tx.Hash()] != cluster {
+			t.Fatalf("transaction %v isn't in the cluster", tx.Hash())
+		}
+	}
+
+	// The cluster can't grow any further.
+	last := chainedTxns[MaxClusterCount]
+	_, err = mp.ProcessTransaction(last, false, false, 0)
+	if err == nil || !strings.Contains(err.Error(), "cluster") {
+		t.Fatalf("expected the transaction to be rejected for its "+
+			"cluster, got %v", err)
+	}
+
+	// Removing a transaction from the middle of the chain without its
+	// redeemers splits the cluster in two.
+	const split = MaxClusterCount / 2
+	mp.RemoveTransaction(chainedTxns[split], false, false)
+	before := mp.clusters[*chainedTxns[0].Hash()]
+	after := mp.clusters[*chainedTxns[split+1].Hash()]
+	if len(before.txs) != split || len(after.txs) != MaxClusterCount-split-1 {
+		t.Fatalf("expected clusters of %d and %d transactions, got %d "+
+			"and %d", split, MaxClusterCount-split-1,
+			len(before.txs), len(after.txs))
+	}
+	if _, ok := mp.clusters[*chainedTxns[split].Hash()]; ok {
+		t.Fatalf("removed transaction is still in a cluster")
+	}
+
+	// Removing the rest leaves no clusters behind.
+	mp.RemoveTransaction(chainedTxns[0], true, false)
+	mp.RemoveTransaction(chainedTxns[split+1], true, false)
+	if len(mp.clusters) != 0 {
+		t.Fatalf("expected no clusters, got %d", len(mp.clusters))
+	}
+}
+
+// TestChunkFeeRates ensures that the mining descriptors of the pool carry the
+// fee rates of the chunks of the transactions and that replacements are
+// compared against the fee rates of the chunks of their conflicts.
+func TestChunkFeeRates(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+
+	// A parent paid for by its child is mined at the fee rate of both.
+	coinbase := ctx.addCoinbaseTx(1)
+	coinbaseOut := txOutToSpendableOut(coinbase, 0)
+	parent := ctx.addSignedTx(
+		[]spendableOutput{coinbaseOut}, 1, 2000, true, false,
+	)
+	child := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 4000,
+		false, false,
+	)
+	chunkFeePerKB := int64(6000) * 1000 /
+		(GetTxVirtualSize(parent) + GetTxVirtualSize(child))
+	for _, desc := range harness.txPool.MiningDescs() {
+		if desc.ChunkFeePerKB != chunkFeePerKB {
+			t.Fatalf("expected a chunk fee rate of %d for %v, got %d",
+				chunkFeePerKB, desc.Tx.Hash(), desc.ChunkFeePerKB)
+		}
+	}
+
+	// A replacement of the parent that pays a higher fee rate than the
+	// chunk but a lower one than the child on its own replaces both.
+	replacement, err := harness.CreateSignedTx(
+		[]spendableOutput{coinbaseOut}, 5, 6500, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create replacement: %v", err)
+	}
+	feePerKB := int64(6500) * 1000 / GetTxVirtualSize(replacement)
+	childFeePerKB := int64(4000) * 1000 / GetTxVirtualSize(child)
+	if feePerKB <= chunkFeePerKB || feePerKB >= childFeePerKB {
+		t.Fatalf("replacement fee rate %d isn't between the chunk fee "+
+			"rate %d and the child fee rate %d", feePerKB,
+			chunkFeePerKB, childFeePerKB)
+	}
+	_, err = harness.txPool.ProcessTransaction(replacement, false, false, 0)
+	if err != nil {
+		t.Fatalf("unable to accept replacement: %v", err)
+	}
+	testPoolMembership(ctx, replacement, false, true)
+	testPoolMembership(ctx, parent, false, false)
+	testPoolMembership(ctx, child, false, false)
+}
@@ -61,8 +61,12 @@ be an exhaustive list.
   - Most recent block height when the transaction was added to the pool
   - The fee the transaction pays
   - The starting priority for the transaction
+  - The fee rate of the chunk the transaction is expected to be mined in
+    within the linearization of its cluster of dependent transactions
   - Manual control of transaction removal
   - Recursive removal of all dependent transactions
+  - Subscriptions to the transactions that are added, removed, replaced and
+    confirmed along with their fees and sizes
 
 # Errors
 
@@ -0,0 +1,91 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// MaxDustOutputsPerTx is the maximum number of dust outputs a standard
+// transaction may have.  Such ephemeral dust, like the anchors that contracting
+// protocols use to bump the fees of their transactions, may only be relayed by
+// transactions that pay no fee themselves in a package with a child that spends
+// it, so that it never stays in the pool without being spent.
+const MaxDustOutputsPerTx = 1
+
+// dustOutputs returns the indexes of the outputs of the passed transaction that
+// are dust according to the passed dust relay fee.  Null data outputs aren't
+// counted since they can't be spent.
+func dustOutputs(tx *btcutil.Tx, dustRelayFee btcutil.Amount) []uint32 {
+	var dust []uint32
+	for i, txOut := range tx.MsgTx().TxOut {
+		if _, ok := dataCarrierSize(txOut.PkScript); ok {
+			continue
+		}
+		if IsDust(txOut, dustRelayFee) {
+			dust = append(dust, uint32(i))
+		}
+	}
+	return dust
+}
+
+// checkEphemeralTx checks that the passed transaction paying the passed fee
+// pays no fee when it has dust outputs, since a fee would give miners a reason
+// to mine it without the child that spends the dust.  The transaction is
+// rejected for its fee when the check fees flag is set so that it may still be
+// accepted in a package with the child.
+func checkEphemeralTx(tx *btcutil.Tx, txFee int64, dustRelayFee btcutil.Amount,
+	checkFees bool) error {
+
+	dust := dustOutputs(tx, dustRelayFee)
+	if len(dust) == 0 {
+		return nil
+	}
+
+	if txFee != 0 {
+		str := fmt.Sprintf("transaction %v has dust output %d and pays "+
+			"%d fees which must be zero", tx.Hash(), dust[0], txFee)
+		return txRuleError(wire.RejectDust, str)
+	}
+	if checkFees {
+		str := fmt.Sprintf("transaction %v has dust output %d and must be "+
+			"relayed in a package with a child that spends it",
+			tx.Hash(), dust[0])
+		return txRuleError(wire.RejectInsufficientFee, str)
+	}
+	return nil
+}
+
+// checkEphemeralSpends checks that the passed transaction spends all the dust
+// outputs of the unconfirmed parents it spends from, since the parents pay no
+// fee and would otherwise leave the dust in the pool.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) checkEphemeralSpends(tx *btcutil.Tx) error {
+	spent := make(map[wire.OutPoint]struct{}, len(tx.MsgTx().TxIn))
+	for _, txIn := range tx.MsgTx().TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+
+	for _, txIn := range tx.MsgTx().TxIn {
+		parent, ok := mp.pool[txIn.PreviousOutPoint.Hash]
+		if !ok {
+			continue
+		}
+		for _, idx := range dustOutputs(parent.Tx, mp.cfg.Policy.DustRelayFee) {
+			outpoint := wire.OutPoint{Hash: *parent.Tx.Hash(), Index: idx}
+			if _, ok := spent[outpoint]; ok {
+				continue
+			}
+			str := fmt.Sprintf("transaction %v is missing ephemeral "+
+				"spends of dust output %v", tx.Hash(), outpoint)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+	return nil
+}
@@ -13,8 +13,8 @@ import (
 // processing of a transaction failed due to one of the many validation
 // rules.  The caller can use type assertions to determine if a failure was
 // specifically due to a rule violation and use the Err field to access the
-// underlying error, which will be either a TxRuleError or a
-// blockchain.RuleError.
+// underlying error, which will be either a TxRuleError, a UtreexoProofError or
+// a blockchain.RuleError.
 type RuleError struct {
 	Err error
 }
@@ -50,6 +50,38 @@ func txRuleError(c wire.RejectCode, desc string) RuleError {
 	}
 }
 
+// UtreexoProofError identifies a transaction whose inputs couldn't be proven
+// with the utreexo data it was relayed with, either since the data was missing
+// or since it was made against a different state of the accumulator, such as
+// one from before the latest block.  A fresh proof of the inputs may still
+// prove them, so the caller can request one instead of treating the
+// transaction as invalid.
+type UtreexoProofError struct {
+	TxRuleError
+}
+
+// utreexoProofError creates an underlying UtreexoProofError with the given
+// description and returns a RuleError that encapsulates it.
+func utreexoProofError(desc string) RuleError {
+	return RuleError{
+		Err: UtreexoProofError{TxRuleError{
+			RejectCode:  wire.RejectInvalid,
+			Description: desc,
+		}},
+	}
+}
+
+// IsUtreexoProofError returns whether the passed error is a RuleError caused by
+// a transaction whose inputs couldn't be proven with its utreexo data.
+func IsUtreexoProofError(err error) bool {
+	rerr, ok := err.(RuleError)
+	if !ok {
+		return false
+	}
+	_, ok = rerr.Err.(UtreexoProofError)
+	return ok
+}
+
 // chainRuleError returns a RuleError that encapsulates the given
 // blockchain.RuleError.
 func chainRuleError(chainErr blockchain.RuleError) RuleError {
@@ -100,6 +132,9 @@ func extractRejectCode(err error) (wire.RejectCode, bool) {
 	case TxRuleError:
 		return err.RejectCode, true
 
+	case UtreexoProofError:
+		return err.RejectCode, true
+
 	case nil:
 		return wire.RejectInvalid, false
 	}
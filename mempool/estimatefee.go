@@ -9,43 +9,69 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
 	"math"
-	"math/rand"
 	"sort"
-	"strings"
 	"sync"
 
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
-	"github.com/utreexo/utreexod/mining"
 )
 
-// TODO incorporate Alex Morcos' modifications to Gavin's initial model
-// https://lists.linuxfoundation.org/pipermail/bitcoin-dev/2014-October/006824.html
+// The fee estimator follows the design of the fee estimator of Bitcoin Core.
+// Transactions are grouped into buckets by their fee rate, and for each bucket
+// exponentially decaying moving averages are kept of how many transactions
+// were confirmed within each number of blocks and how many left the mempool
+// without being confirmed.  An estimate for a confirmation target is the
+// median fee rate of the cheapest range of buckets whose transactions were
+// confirmed within the target often enough.  The averages are kept over three
+// horizons which decay at different rates so that estimates react quickly to
+// changing conditions while long confirmation targets are still covered.
 
 const (
-	// estimateFeeDepth is the maximum number of blocks before a transaction
-	// is confirmed that we want to track.
-	estimateFeeDepth = 25
-
-	// estimateFeeBinSize is the number of txs stored in each bin.
-	estimateFeeBinSize = 100
-
-	// estimateFeeMaxReplacements is the max number of replacements that
-	// can be made by the txs found in a given block.
-	estimateFeeMaxReplacements = 10
-
-	// DefaultEstimateFeeMaxRollback is the default number of rollbacks
-	// allowed by the fee estimator for orphaned blocks.
-	DefaultEstimateFeeMaxRollback = 2
-
-	// DefaultEstimateFeeMinRegisteredBlocks is the default minimum
-	// number of blocks which must be observed by the fee estimator before
-	// it will provide fee estimations.
-	DefaultEstimateFeeMinRegisteredBlocks = 3
-
-	bytePerKb = 1000
+	// minBucketFeeRate and maxBucketFeeRate are the lowest and highest fee
+	// rates in satoshis per 1000 virtual bytes the buckets are spread
+	// between.  Transactions paying more than the highest share the last
+	// bucket.
+	minBucketFeeRate = 1000
+	maxBucketFeeRate = 1e7
+
+	// feeBucketSpacing is the ratio between the fee rates of consecutive
+	// buckets.
+	feeBucketSpacing = 1.05
+
+	// infFeeRate is the upper bound of the last bucket.
+	infFeeRate = 1e99
+
+	// The number of periods, the number of blocks per period and the decay
+	// of the moving averages per block of the short, medium and long
+	// horizons.  The decays are chosen so that the averages have a half
+	// life of about 18 blocks, 144 blocks and 1008 blocks respectively.
+	shortBlockPeriods = 12
+	shortScale        = 1
+	shortDecay        = .962
+	medBlockPeriods   = 24
+	medScale          = 2
+	medDecay          = .9952
+	longBlockPeriods  = 42
+	longScale         = 24
+	longDecay         = .99931
+
+	// halfSuccessPct, successPct and doubleSuccessPct are the fractions of
+	// transactions that must have been confirmed within half the target,
+	// the target and double the target for estimates.
+	halfSuccessPct   = .6
+	successPct       = .85
+	doubleSuccessPct = .95
+
+	// sufficientFeeTxs is the number of transactions per block a range of
+	// buckets must have on average for its success rate to be trusted.
+	// The short horizon requires more since it decays faster.
+	sufficientFeeTxs   = 0.1
+	sufficientTxsShort = 0.5
+
+	// oldestEstimateHistory is the number of blocks after which the
+	// history restored from an earlier session is too old to be used.
+	oldestEstimateHistory = 6 * 1008
 
 	btcPerSatoshi = 1e-8
 )
@@ -70,7 +96,7 @@ func (rate SatoshiPerByte) ToBtcPerKb() BtcPerKilobyte {
 		return -1.0
 	}
 
-	return BtcPerKilobyte(float64(rate) * bytePerKb * btcPerSatoshi)
+	return BtcPerKilobyte(float64(rate) * 1000 * btcPerSatoshi)
 }
 
 // Fee returns the fee for a transaction of a given size for
@@ -90,597 +116,821 @@ func NewSatoshiPerByte(fee btcutil.Amount, size uint32) SatoshiPerByte {
 	return SatoshiPerByte(float64(fee) / float64(size))
 }
 
-// observedTransaction represents an observed transaction and some
-// additional data required for the fee estimation algorithm.
-type observedTransaction struct {
-	// A transaction hash.
-	hash chainhash.Hash
+// FeeEstimateHorizon identifies one of the horizons the fee estimator keeps
+// its statistics over.
+type FeeEstimateHorizon int
 
-	// The fee per byte of the transaction in satoshis.
-	feeRate SatoshiPerByte
+const (
+	// ShortHorizon tracks confirmations of up to 12 blocks and reacts
+	// quickly to changes.
+	ShortHorizon FeeEstimateHorizon = iota
 
-	// The block height when it was observed.
-	observed int32
+	// MediumHorizon tracks confirmations of up to 48 blocks.
+	MediumHorizon
 
-	// The height of the block in which it was mined.
-	// If the transaction has not yet been mined, it is zero.
-	mined int32
-}
+	// LongHorizon tracks confirmations of up to 1008 blocks and changes
+	// slowly.
+	LongHorizon
+)
 
-func (o *observedTransaction) Serialize(w io.Writer) {
-	binary.Write(w, binary.BigEndian, o.hash)
-	binary.Write(w, binary.BigEndian, o.feeRate)
-	binary.Write(w, binary.BigEndian, o.observed)
-	binary.Write(w, binary.BigEndian, o.mined)
+// Map of FeeEstimateHorizon values back to their constant names for pretty
+// printing.
+var feeEstimateHorizonStrings = map[FeeEstimateHorizon]string{
+	ShortHorizon:  "short",
+	MediumHorizon: "medium",
+	LongHorizon:   "long",
 }
 
-func deserializeObservedTransaction(r io.Reader) (*observedTransaction, error) {
-	ot := observedTransaction{}
-
-	// The first 32 bytes should be a hash.
-	binary.Read(r, binary.BigEndian, &ot.hash)
-
-	// The next 8 are SatoshiPerByte
-	binary.Read(r, binary.BigEndian, &ot.feeRate)
-
-	// And next there are two uint32's.
-	binary.Read(r, binary.BigEndian, &ot.observed)
-	binary.Read(r, binary.BigEndian, &ot.mined)
-
-	return &ot, nil
+// String returns the FeeEstimateHorizon in human-readable form.
+func (h FeeEstimateHorizon) String() string {
+	if s, ok := feeEstimateHorizonStrings[h]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown FeeEstimateHorizon (%d)", int(h))
 }
 
-// registeredBlock has the hash of a block and the list of transactions
-// it mined which had been previously observed by the FeeEstimator. It
-// is used if Rollback is called to reverse the effect of registering
-// a block.
-type registeredBlock struct {
-	hash         chainhash.Hash
-	transactions []*observedTransaction
+// EstimatorBucket houses the statistics of a range of fee rate buckets that
+// were considered for an estimate.  Start and End are -1 when no range was
+// considered.
+type EstimatorBucket struct {
+	// Start and End are the fee rates in satoshis per 1000 virtual bytes
+	// the range of buckets spans.
+	Start float64
+	End   float64
+
+	// WithinTarget is the decayed number of transactions that were
+	// confirmed within the target and TotalConfirmed the decayed number of
+	// transactions that were confirmed at all.
+	WithinTarget   float64
+	TotalConfirmed float64
+
+	// InMempool is the number of transactions in the mempool that haven't
+	// been confirmed within the target and LeftMempool the decayed number
+	// of transactions that left the mempool without being confirmed.
+	InMempool   float64
+	LeftMempool float64
 }
 
-func (rb *registeredBlock) serialize(w io.Writer, txs map[*observedTransaction]uint32) {
-	binary.Write(w, binary.BigEndian, rb.hash)
+// EstimationResult houses the details of how a raw fee estimate was found.
+type EstimationResult struct {
+	// Pass is the range of buckets the estimate was found in and Fail the
+	// range of more expensive buckets that next to it failed the threshold.
+	Pass EstimatorBucket
+	Fail EstimatorBucket
+
+	// Decay and Scale are the decay per block and the number of blocks per
+	// period of the horizon.
+	Decay float64
+	Scale uint32
+}
 
-	binary.Write(w, binary.BigEndian, uint32(len(rb.transactions)))
-	for _, o := range rb.transactions {
-		binary.Write(w, binary.BigEndian, txs[o])
-	}
+// newEstimatorBucket returns an EstimatorBucket which doesn't span any range.
+func newEstimatorBucket() EstimatorBucket {
+	return EstimatorBucket{Start: -1, End: -1}
 }
 
-// FeeEstimator manages the data necessary to create
-// fee estimations. It is safe for concurrent access.
-type FeeEstimator struct {
-	maxRollback uint32
-	binSize     int32
+// txConfirmStats tracks the confirmation statistics of the transactions of
+// each fee rate bucket over one horizon.
+type txConfirmStats struct {
+	// buckets are the upper bounds of the fee rates of the buckets.
+	buckets []float64
+
+	// feeRateAvg is the decayed sum of the fee rates and txCtAvg the
+	// decayed number of the confirmed transactions of each bucket.
+	feeRateAvg []float64
+	txCtAvg    []float64
+
+	// confAvg is the decayed number of transactions of each bucket that
+	// were confirmed within each number of periods, and failAvg the
+	// decayed number of transactions that left the mempool without being
+	// confirmed after each number of periods.
+	confAvg [][]float64
+	failAvg [][]float64
+
+	decay float64
+	scale uint32
+
+	// unconfTxs is the number of transactions of each bucket that are in
+	// the mempool indexed by the height they entered it at modulo the
+	// maximum number of confirmations.  oldUnconfTxs is the number that
+	// have been in the mempool for longer than that.
+	unconfTxs    [][]int
+	oldUnconfTxs []int
+}
 
-	// The maximum number of replacements that can be made in a single
-	// bin per block. Default is estimateFeeMaxReplacements
-	maxReplacements int32
+// newTxConfirmStats returns the confirmation statistics for the buckets over a
+// horizon of the number of periods of scale blocks.
+func newTxConfirmStats(buckets []float64, periods, scale uint32,
+	decay float64) *txConfirmStats {
 
-	// The minimum number of blocks that can be registered with the fee
-	// estimator before it will provide answers.
-	minRegisteredBlocks uint32
+	stats := &txConfirmStats{
+		buckets:    buckets,
+		feeRateAvg: make([]float64, len(buckets)),
+		txCtAvg:    make([]float64, len(buckets)),
+		confAvg:    make([][]float64, periods),
+		failAvg:    make([][]float64, periods),
+		decay:      decay,
+		scale:      scale,
+	}
+	for i := range stats.confAvg {
+		stats.confAvg[i] = make([]float64, len(buckets))
+		stats.failAvg[i] = make([]float64, len(buckets))
+	}
+	stats.resetUnconfirmed()
 
-	// The last known height.
-	lastKnownHeight int32
+	return stats
+}
 
-	// The number of blocks that have been registered.
-	numBlocksRegistered uint32
+// resetUnconfirmed clears the counts of the transactions in the mempool.
+func (stats *txConfirmStats) resetUnconfirmed() {
+	stats.unconfTxs = make([][]int, stats.maxConfirms())
+	for i := range stats.unconfTxs {
+		stats.unconfTxs[i] = make([]int, len(stats.buckets))
+	}
+	stats.oldUnconfTxs = make([]int, len(stats.buckets))
+}
 
-	mtx      sync.RWMutex
-	observed map[chainhash.Hash]*observedTransaction
-	bin      [estimateFeeDepth][]*observedTransaction
+// maxConfirms returns the maximum number of confirmations tracked.
+func (stats *txConfirmStats) maxConfirms() uint32 {
+	return stats.scale * uint32(len(stats.confAvg))
+}
 
-	// The cached estimates.
-	cached []SatoshiPerByte
+// bucketIndex returns the index of the bucket of the fee rate.
+func (stats *txConfirmStats) bucketIndex(feeRate float64) int {
+	return sort.SearchFloat64s(stats.buckets, feeRate)
+}
 
-	// Transactions that have been removed from the bins. This allows us to
-	// revert in case of an orphaned block.
-	dropped []*registeredBlock
+// unconfIndex returns the index into unconfTxs of the height.
+func (stats *txConfirmStats) unconfIndex(height int32) int {
+	bins := int32(len(stats.unconfTxs))
+	return int(((height % bins) + bins) % bins)
 }
 
-// NewFeeEstimator creates a FeeEstimator for which at most maxRollback blocks
-// can be unregistered and which returns an error unless minRegisteredBlocks
-// have been registered with it.
-func NewFeeEstimator(maxRollback, minRegisteredBlocks uint32) *FeeEstimator {
-	return &FeeEstimator{
-		maxRollback:         maxRollback,
-		minRegisteredBlocks: minRegisteredBlocks,
-		lastKnownHeight:     mining.UnminedHeight,
-		binSize:             estimateFeeBinSize,
-		maxReplacements:     estimateFeeMaxReplacements,
-		observed:            make(map[chainhash.Hash]*observedTransaction),
-		dropped:             make([]*registeredBlock, 0, maxRollback),
+// clearCurrent moves the transactions of the mempool that entered it the
+// maximum number of confirmations ago to oldUnconfTxs, freeing their slot for
+// the transactions entering at the height.
+func (stats *txConfirmStats) clearCurrent(height int32) {
+	current := stats.unconfTxs[stats.unconfIndex(height)]
+	for i := range current {
+		stats.oldUnconfTxs[i] += current[i]
+		current[i] = 0
 	}
 }
 
-// ObserveTransaction is called when a new transaction is observed in the mempool.
-func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
-
-	// If we haven't seen a block yet we don't know when this one arrived,
-	// so we ignore it.
-	if ef.lastKnownHeight == mining.UnminedHeight {
+// record records a transaction of the fee rate that was confirmed after the
+// number of blocks.
+func (stats *txConfirmStats) record(blocksToConfirm int32, feeRate float64) {
+	if blocksToConfirm < 1 {
 		return
 	}
+	periodsToConfirm := (int(blocksToConfirm) + int(stats.scale) - 1) /
+		int(stats.scale)
+	bucket := stats.bucketIndex(feeRate)
+	for i := periodsToConfirm; i <= len(stats.confAvg); i++ {
+		stats.confAvg[i-1][bucket]++
+	}
+	stats.txCtAvg[bucket]++
+	stats.feeRateAvg[bucket] += feeRate
+}
 
-	hash := *t.Tx.Hash()
-	if _, ok := ef.observed[hash]; !ok {
-		size := uint32(GetTxVirtualSize(t.Tx))
-
-		ef.observed[hash] = &observedTransaction{
-			hash:     hash,
-			feeRate:  NewSatoshiPerByte(btcutil.Amount(t.Fee), size),
-			observed: t.Height,
-			mined:    mining.UnminedHeight,
+// updateMovingAverages decays the moving averages for a new block.
+func (stats *txConfirmStats) updateMovingAverages() {
+	for i := range stats.buckets {
+		for j := range stats.confAvg {
+			stats.confAvg[j][i] *= stats.decay
+			stats.failAvg[j][i] *= stats.decay
 		}
+		stats.feeRateAvg[i] *= stats.decay
+		stats.txCtAvg[i] *= stats.decay
 	}
 }
 
-// RegisterBlock informs the fee estimator of a new block to take into account.
-func (ef *FeeEstimator) RegisterBlock(block *btcutil.Block) error {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
+// newTx records a transaction of the fee rate that entered the mempool at the
+// height and returns the index of its bucket.
+func (stats *txConfirmStats) newTx(height int32, feeRate float64) int {
+	bucket := stats.bucketIndex(feeRate)
+	stats.unconfTxs[stats.unconfIndex(height)][bucket]++
+	return bucket
+}
 
-	// The previous sorted list is invalid, so delete it.
-	ef.cached = nil
+// removeTx removes a transaction of the bucket that entered the mempool at the
+// entry height from the counts of the mempool.  Transactions that weren't
+// confirmed in a block count as failures for every period they were in the
+// mempool for.
+func (stats *txConfirmStats) removeTx(entryHeight, bestSeenHeight int32,
+	bucket int, inBlock bool) {
 
-	height := block.Height()
-	if height != ef.lastKnownHeight+1 && ef.lastKnownHeight != mining.UnminedHeight {
-		return fmt.Errorf("intermediate block not recorded; current height is %d; new height is %d",
-			ef.lastKnownHeight, height)
+	blocksAgo := bestSeenHeight - entryHeight
+	if bestSeenHeight == 0 {
+		blocksAgo = 0
 	}
-
-	// Update the last known height.
-	ef.lastKnownHeight = height
-	ef.numBlocksRegistered++
-
-	// Randomly order txs in block.
-	transactions := make(map[*btcutil.Tx]struct{})
-	for _, t := range block.Transactions() {
-		transactions[t] = struct{}{}
+	if blocksAgo < 0 {
+		log.Debugf("Fee estimator asked to remove a transaction that " +
+			"entered the mempool in the future")
+		return
 	}
 
-	// Count the number of replacements we make per bin so that we don't
-	// replace too many.
-	var replacementCounts [estimateFeeDepth]int
-
-	// Keep track of which txs were dropped in case of an orphan block.
-	dropped := &registeredBlock{
-		hash:         *block.Hash(),
-		transactions: make([]*observedTransaction, 0, 100),
+	if blocksAgo >= int32(len(stats.unconfTxs)) {
+		if stats.oldUnconfTxs[bucket] > 0 {
+			stats.oldUnconfTxs[bucket]--
+		}
+	} else {
+		unconf := stats.unconfTxs[stats.unconfIndex(entryHeight)]
+		if unconf[bucket] > 0 {
+			unconf[bucket]--
+		}
 	}
 
-	// Go through the txs in the block.
-	for t := range transactions {
-		hash := *t.Hash()
-
-		// Have we observed this tx in the mempool?
-		o, ok := ef.observed[hash]
-		if !ok {
-			continue
+	if !inBlock && uint32(blocksAgo) >= stats.scale {
+		periodsAgo := int(uint32(blocksAgo) / stats.scale)
+		for i := 0; i < periodsAgo && i < len(stats.failAvg); i++ {
+			stats.failAvg[i][bucket]++
 		}
+	}
+}
 
-		// Put the observed tx in the oppropriate bin.
-		blocksToConfirm := height - o.observed - 1
+// estimateMedianVal returns the median fee rate of the cheapest range of
+// buckets of which at least the successBreakPoint fraction of transactions
+// were confirmed within the target, or -1 when there is none.  Ranges are
+// grown from the most expensive bucket down until they have enough
+// transactions for their success rate to be meaningful.
+func (stats *txConfirmStats) estimateMedianVal(confTarget int, sufficientTxVal,
+	successBreakPoint float64, height int32, result *EstimationResult) float64 {
+
+	var nConf, totalNum, failNum float64
+	var extraNum int
+	periodTarget := (confTarget + int(stats.scale) - 1) / int(stats.scale)
+	maxBucket := len(stats.buckets) - 1
+
+	curNearBucket, bestNearBucket := maxBucket, maxBucket
+	curFarBucket, bestFarBucket := maxBucket, maxBucket
+
+	foundAnswer := false
+	newBucketRange := true
+	passing := true
+	passBucket := newEstimatorBucket()
+	failBucket := newEstimatorBucket()
+
+	// bucketRange returns the fee rates the buckets between the two
+	// indices span.
+	bucketRange := func(a, b int) (float64, float64) {
+		minBucket, maxBucket := a, b
+		if minBucket > maxBucket {
+			minBucket, maxBucket = maxBucket, minBucket
+		}
+		var start float64
+		if minBucket > 0 {
+			start = stats.buckets[minBucket-1]
+		}
+		return start, stats.buckets[maxBucket]
+	}
 
-		// This shouldn't happen if the fee estimator works correctly,
-		// but return an error if it does.
-		if o.mined != mining.UnminedHeight {
-			log.Error("Estimate fee: transaction ", hash.String(), " has already been mined")
-			return errors.New("Transaction has already been mined")
+	for bucket := maxBucket; bucket >= 0; bucket-- {
+		if newBucketRange {
+			curNearBucket = bucket
+			newBucketRange = false
 		}
+		curFarBucket = bucket
+		nConf += stats.confAvg[periodTarget-1][bucket]
+		totalNum += stats.txCtAvg[bucket]
+		failNum += stats.failAvg[periodTarget-1][bucket]
+		for confct := uint32(confTarget); confct < stats.maxConfirms(); confct++ {
+			unconf := stats.unconfTxs[stats.unconfIndex(height-int32(confct))]
+			extraNum += unconf[bucket]
+		}
+		extraNum += stats.oldUnconfTxs[bucket]
 
-		// This shouldn't happen but check just in case to avoid
-		// an out-of-bounds array index later.
-		//
-		// Also check that blocksToConfirm is not negative as this causes
-		// the node to crash on reorgs.  A tx that was observed at height X
-		// might be included in heights less than X because of chain reorgs.
-		// Refer to github.com/btcsuite/btcd/issues/1660 for more information.
-		//
-		// TODO(kcalvinalvin) a better design that doesn't just skip over the
-		// transaction would result in a more accurate fee estimator.  Properly
-		// implement this later.
-		if blocksToConfirm >= estimateFeeDepth || blocksToConfirm < 0 {
+		// Only check the success rate once the range has enough data
+		// points.
+		if totalNum < sufficientTxVal/(1-stats.decay) {
 			continue
 		}
 
-		// Make sure we do not replace too many transactions per min.
-		if replacementCounts[blocksToConfirm] == int(ef.maxReplacements) {
+		curPct := nConf / (totalNum + failNum + float64(extraNum))
+		if curPct < successBreakPoint {
+			// Remember the first range that failed so its
+			// statistics can be reported.
+			if passing {
+				failBucket.Start, failBucket.End = bucketRange(
+					curNearBucket, curFarBucket)
+				failBucket.WithinTarget = nConf
+				failBucket.TotalConfirmed = totalNum
+				failBucket.InMempool = float64(extraNum)
+				failBucket.LeftMempool = failNum
+				passing = false
+			}
 			continue
 		}
 
-		o.mined = height
-
-		replacementCounts[blocksToConfirm]++
-
-		bin := ef.bin[blocksToConfirm]
-
-		// Remove a random element and replace it with this new tx.
-		if len(bin) == int(ef.binSize) {
-			// Don't drop transactions we have just added from this same block.
-			l := int(ef.binSize) - replacementCounts[blocksToConfirm]
-			drop := rand.Intn(l)
-			dropped.transactions = append(dropped.transactions, bin[drop])
-
-			bin[drop] = bin[l-1]
-			bin[l-1] = o
-		} else {
-			bin = append(bin, o)
+		// The range passed, so remember it and start a new one at the
+		// next cheaper bucket.
+		failBucket = newEstimatorBucket()
+		foundAnswer = true
+		passing = true
+		passBucket.WithinTarget = nConf
+		passBucket.TotalConfirmed = totalNum
+		passBucket.InMempool = float64(extraNum)
+		passBucket.LeftMempool = failNum
+		nConf, totalNum, failNum, extraNum = 0, 0, 0, 0
+		bestNearBucket = curNearBucket
+		bestFarBucket = curFarBucket
+		newBucketRange = true
+	}
+
+	// Find the bucket of the median transaction of the cheapest passing
+	// range and return the average fee rate of that bucket.
+	median := -1.0
+	minBucket, maxBucket := bestNearBucket, bestFarBucket
+	if minBucket > maxBucket {
+		minBucket, maxBucket = maxBucket, minBucket
+	}
+	var txSum float64
+	for i := minBucket; i <= maxBucket; i++ {
+		txSum += stats.txCtAvg[i]
+	}
+	if foundAnswer && txSum != 0 {
+		txSum /= 2
+		for i := minBucket; i <= maxBucket; i++ {
+			if stats.txCtAvg[i] < txSum {
+				txSum -= stats.txCtAvg[i]
+				continue
+			}
+			median = stats.feeRateAvg[i] / stats.txCtAvg[i]
+			break
 		}
-		ef.bin[blocksToConfirm] = bin
+		passBucket.Start, passBucket.End = bucketRange(minBucket,
+			maxBucket)
 	}
 
-	// Go through the mempool for txs that have been in too long.
-	for hash, o := range ef.observed {
-		if o.mined == mining.UnminedHeight && height-o.observed >= estimateFeeDepth {
-			delete(ef.observed, hash)
-		}
+	// Report the remaining range as failed if it couldn't be checked for
+	// lack of data after all the ranges before it passed.
+	if passing && !newBucketRange {
+		failBucket.Start, failBucket.End = bucketRange(curNearBucket,
+			curFarBucket)
+		failBucket.WithinTarget = nConf
+		failBucket.TotalConfirmed = totalNum
+		failBucket.InMempool = float64(extraNum)
+		failBucket.LeftMempool = failNum
 	}
 
-	// Add dropped list to history.
-	if ef.maxRollback == 0 {
-		return nil
+	if result != nil {
+		result.Pass = passBucket
+		result.Fail = failBucket
+		result.Decay = stats.decay
+		result.Scale = stats.scale
 	}
+	return median
+}
 
-	if uint32(len(ef.dropped)) == ef.maxRollback {
-		ef.dropped = append(ef.dropped[1:], dropped)
-	} else {
-		ef.dropped = append(ef.dropped, dropped)
-	}
+// trackedTx is a transaction in the mempool that is tracked by the fee
+// estimator.
+type trackedTx struct {
+	height  int32
+	bucket  int
+	feeRate float64
+}
 
-	return nil
+// FeeEstimator manages the data necessary to create
+// fee estimations. It is safe for concurrent access.
+type FeeEstimator struct {
+	mtx sync.Mutex
+
+	// bestSeenHeight is the height of the last block registered.
+	bestSeenHeight int32
+
+	// firstRecordedHeight is the height of the first block of this session
+	// that confirmed a tracked transaction.  historicalFirst and
+	// historicalBest are the heights the statistics restored from an
+	// earlier session span.
+	firstRecordedHeight int32
+	historicalFirst     int32
+	historicalBest      int32
+
+	buckets    []float64
+	feeStats   *txConfirmStats
+	shortStats *txConfirmStats
+	longStats  *txConfirmStats
+
+	// mempoolTxs are the tracked transactions in the mempool.
+	mempoolTxs map[chainhash.Hash]trackedTx
 }
 
-// LastKnownHeight returns the height of the last block which was registered.
-func (ef *FeeEstimator) LastKnownHeight() int32 {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
+// feeBuckets returns the upper bounds of the fee rates of the buckets.
+func feeBuckets() []float64 {
+	var buckets []float64
+	for boundary := float64(minBucketFeeRate); boundary <= maxBucketFeeRate; boundary *= feeBucketSpacing {
+		buckets = append(buckets, boundary)
+	}
+	return append(buckets, infFeeRate)
+}
 
-	return ef.lastKnownHeight
+// NewFeeEstimator creates a FeeEstimator that has no statistics yet.
+func NewFeeEstimator() *FeeEstimator {
+	buckets := feeBuckets()
+	return &FeeEstimator{
+		buckets: buckets,
+		feeStats: newTxConfirmStats(buckets, medBlockPeriods, medScale,
+			medDecay),
+		shortStats: newTxConfirmStats(buckets, shortBlockPeriods,
+			shortScale, shortDecay),
+		longStats: newTxConfirmStats(buckets, longBlockPeriods,
+			longScale, longDecay),
+		mempoolTxs: make(map[chainhash.Hash]trackedTx),
+	}
 }
 
-// Rollback unregisters a recently registered block from the FeeEstimator.
-// This can be used to reverse the effect of an orphaned block on the fee
-// estimator. The maximum number of rollbacks allowed is given by
-// maxRollbacks.
-//
-// Note: not everything can be rolled back because some transactions are
-// deleted if they have been observed too long ago. That means the result
-// of Rollback won't always be exactly the same as if the last block had not
-// happened, but it should be close enough.
-func (ef *FeeEstimator) Rollback(hash *chainhash.Hash) error {
+// allStats returns the statistics of every horizon.
+func (ef *FeeEstimator) allStats() []*txConfirmStats {
+	return []*txConfirmStats{ef.feeStats, ef.shortStats, ef.longStats}
+}
+
+// ObserveTransaction is called when a new transaction is observed in the mempool.
+// Transactions that were added while the estimator isn't at the best block are
+// ignored since the number of blocks it takes to confirm them would be wrong.
+func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
-	// Find this block in the stack of recent registered blocks.
-	var n int
-	for n = 1; n <= len(ef.dropped); n++ {
-		if ef.dropped[len(ef.dropped)-n].hash.IsEqual(hash) {
-			break
-		}
+	hash := *t.Tx.Hash()
+	if _, ok := ef.mempoolTxs[hash]; ok {
+		return
 	}
-
-	if n > len(ef.dropped) {
-		return errors.New("no such block was recently registered")
+	if t.Height != ef.bestSeenHeight {
+		return
 	}
 
-	for i := 0; i < n; i++ {
-		ef.rollback()
+	vsize := GetTxVirtualSize(t.Tx)
+	if vsize <= 0 {
+		return
 	}
+	feeRate := float64(t.Fee) * 1000 / float64(vsize)
 
-	return nil
+	var bucket int
+	for _, stats := range ef.allStats() {
+		bucket = stats.newTx(t.Height, feeRate)
+	}
+	ef.mempoolTxs[hash] = trackedTx{
+		height:  t.Height,
+		bucket:  bucket,
+		feeRate: feeRate,
+	}
 }
 
-// rollback rolls back the effect of the last block in the stack
-// of registered blocks.
-func (ef *FeeEstimator) rollback() {
-	// The previous sorted list is invalid, so delete it.
-	ef.cached = nil
+// RemoveTransaction is called when a transaction leaves the mempool without
+// being confirmed, which counts as a failure to confirm at its fee rate.
+// Transactions confirmed by a block must be registered with RegisterBlock
+// before they're removed from the mempool.
+func (ef *FeeEstimator) RemoveTransaction(hash *chainhash.Hash) {
+	ef.mtx.Lock()
+	ef.removeTx(hash, false)
+	ef.mtx.Unlock()
+}
 
-	// pop the last list of dropped txs from the stack.
-	last := len(ef.dropped) - 1
-	if last == -1 {
-		// Cannot really happen because the exported calling function
-		// only rolls back a block already known to be in the list
-		// of dropped transactions.
-		return
+// removeTx stops tracking the transaction and returns whether it was tracked.
+//
+// This function MUST be called with the fee estimator lock held.
+func (ef *FeeEstimator) removeTx(hash *chainhash.Hash, inBlock bool) bool {
+	tx, ok := ef.mempoolTxs[*hash]
+	if !ok {
+		return false
 	}
+	for _, stats := range ef.allStats() {
+		stats.removeTx(tx.height, ef.bestSeenHeight, tx.bucket, inBlock)
+	}
+	delete(ef.mempoolTxs, *hash)
+	return true
+}
 
-	dropped := ef.dropped[last]
-
-	// where we are in each bin as we replace txs?
-	var replacementCounters [estimateFeeDepth]int
-
-	// Go through the txs in the dropped block.
-	for _, o := range dropped.transactions {
-		// Which bin was this tx in?
-		blocksToConfirm := o.mined - o.observed - 1
-
-		bin := ef.bin[blocksToConfirm]
-
-		var counter = replacementCounters[blocksToConfirm]
-
-		// Continue to go through that bin where we left off.
-		for {
-			if counter >= len(bin) {
-				// Panic, as we have entered an unrecoverable invalid state.
-				panic(errors.New("illegal state: cannot rollback dropped transaction"))
-			}
-
-			prev := bin[counter]
-
-			if prev.mined == ef.lastKnownHeight {
-				prev.mined = mining.UnminedHeight
+// RegisterBlock informs the fee estimator of a new block to take into account.
+// Blocks that aren't higher than the last registered block are ignored, which
+// leaves the statistics untouched by reorganizations.
+func (ef *FeeEstimator) RegisterBlock(block *btcutil.Block) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
 
-				bin[counter] = o
+	height := block.Height()
+	if height <= ef.bestSeenHeight {
+		return
+	}
+	ef.bestSeenHeight = height
 
-				counter++
-				break
-			}
+	for _, stats := range ef.allStats() {
+		stats.clearCurrent(height)
+		stats.updateMovingAverages()
+	}
 
-			counter++
+	var counted int
+	for _, tx := range block.Transactions()[1:] {
+		entry, ok := ef.mempoolTxs[*tx.Hash()]
+		if !ok || !ef.removeTx(tx.Hash(), true) {
+			continue
 		}
-
-		replacementCounters[blocksToConfirm] = counter
+		blocksToConfirm := height - entry.height
+		if blocksToConfirm <= 0 {
+			continue
+		}
+		for _, stats := range ef.allStats() {
+			stats.record(blocksToConfirm, entry.feeRate)
+		}
+		counted++
 	}
 
-	// Continue going through bins to find other txs to remove
-	// which did not replace any other when they were entered.
-	for i, j := range replacementCounters {
-		for {
-			l := len(ef.bin[i])
-			if j >= l {
-				break
-			}
-
-			prev := ef.bin[i][j]
-
-			if prev.mined == ef.lastKnownHeight {
-				prev.mined = mining.UnminedHeight
-
-				newBin := append(ef.bin[i][0:j], ef.bin[i][j+1:l]...)
-				// TODO This line should prevent an unintentional memory
-				// leak but it causes a panic when it is uncommented.
-				// ef.bin[i][j] = nil
-				ef.bin[i] = newBin
+	if ef.firstRecordedHeight == 0 && counted > 0 {
+		ef.firstRecordedHeight = height
+	}
 
-				continue
-			}
+	log.Debugf("Fee estimator registered block %d confirming %d of %d "+
+		"tracked transactions", height, counted, counted+len(ef.mempoolTxs))
+}
 
-			j++
-		}
-	}
+// LastKnownHeight returns the height of the last block which was registered.
+func (ef *FeeEstimator) LastKnownHeight() int32 {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
 
-	ef.dropped = ef.dropped[0:last]
+	return ef.bestSeenHeight
+}
 
-	// The number of blocks the fee estimator has seen is decrimented.
-	ef.numBlocksRegistered--
-	ef.lastKnownHeight--
+// horizonStats returns the statistics of the horizon.
+func (ef *FeeEstimator) horizonStats(horizon FeeEstimateHorizon) (*txConfirmStats, float64) {
+	switch horizon {
+	case ShortHorizon:
+		return ef.shortStats, sufficientTxsShort
+	case MediumHorizon:
+		return ef.feeStats, sufficientFeeTxs
+	case LongHorizon:
+		return ef.longStats, sufficientFeeTxs
+	}
+	return nil, 0
 }
 
-// estimateFeeSet is a set of txs that can that is sorted
-// by the fee per kb rate.
-type estimateFeeSet struct {
-	feeRate []SatoshiPerByte
-	bin     [estimateFeeDepth]uint32
+// HighestTargetTracked returns the highest confirmation target the horizon
+// tracks.
+func (ef *FeeEstimator) HighestTargetTracked(horizon FeeEstimateHorizon) uint32 {
+	stats, _ := ef.horizonStats(horizon)
+	if stats == nil {
+		return 0
+	}
+	return stats.maxConfirms()
 }
 
-func (b *estimateFeeSet) Len() int { return len(b.feeRate) }
+// EstimateRawFee returns the lowest fee rate of which at least the threshold
+// fraction of transactions were confirmed within the target according to the
+// statistics of the horizon, along with details of how it was found.  The fee
+// rate is zero when there is no such fee rate or not enough data, and the
+// details are nil when the target isn't tracked by the horizon.
+func (ef *FeeEstimator) EstimateRawFee(confTarget uint32, threshold float64,
+	horizon FeeEstimateHorizon) (BtcPerKilobyte, *EstimationResult) {
 
-func (b *estimateFeeSet) Less(i, j int) bool {
-	return b.feeRate[i] > b.feeRate[j]
-}
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
 
-func (b *estimateFeeSet) Swap(i, j int) {
-	b.feeRate[i], b.feeRate[j] = b.feeRate[j], b.feeRate[i]
-}
+	stats, sufficientTxs := ef.horizonStats(horizon)
+	if stats == nil || confTarget == 0 || confTarget > stats.maxConfirms() ||
+		threshold > 1 {
 
-// estimateFee returns the estimated fee for a transaction
-// to confirm in confirmations blocks from now, given
-// the data set we have collected.
-func (b *estimateFeeSet) estimateFee(confirmations int) SatoshiPerByte {
-	if confirmations <= 0 {
-		return SatoshiPerByte(math.Inf(1))
+		return 0, nil
 	}
 
-	if confirmations > estimateFeeDepth {
-		return 0
+	var result EstimationResult
+	median := stats.estimateMedianVal(int(confTarget), sufficientTxs,
+		threshold, ef.bestSeenHeight, &result)
+	if median < 0 {
+		return 0, &result
 	}
+	return toBtcPerKb(median), &result
+}
 
-	// We don't have any transactions!
-	if len(b.feeRate) == 0 {
+// blockSpan returns the number of blocks the statistics of this session span.
+func (ef *FeeEstimator) blockSpan() int32 {
+	if ef.firstRecordedHeight == 0 {
 		return 0
 	}
+	return ef.bestSeenHeight - ef.firstRecordedHeight
+}
+
+// historicalBlockSpan returns the number of blocks the statistics restored from
+// an earlier session span, or zero when they're too old.
+func (ef *FeeEstimator) historicalBlockSpan() int32 {
+	if ef.historicalFirst == 0 ||
+		ef.bestSeenHeight-ef.historicalBest > oldestEstimateHistory {
 
-	var min, max int = 0, 0
-	for i := 0; i < confirmations-1; i++ {
-		min += int(b.bin[i])
+		return 0
 	}
+	return ef.historicalBest - ef.historicalFirst
+}
 
-	max = min + int(b.bin[confirmations-1]) - 1
-	if max < min {
-		max = min
+// maxUsableEstimate returns the highest confirmation target there's enough
+// history to estimate fees for.
+func (ef *FeeEstimator) maxUsableEstimate() uint32 {
+	span := ef.blockSpan()
+	if historical := ef.historicalBlockSpan(); historical > span {
+		span = historical
 	}
-	feeIndex := (min + max) / 2
-	if feeIndex >= len(b.feeRate) {
-		feeIndex = len(b.feeRate) - 1
+	usable := uint32(span / 2)
+	if maxConfirms := ef.longStats.maxConfirms(); usable > maxConfirms {
+		usable = maxConfirms
 	}
-
-	return b.feeRate[feeIndex]
+	return usable
 }
 
-// newEstimateFeeSet creates a temporary data structure that
-// can be used to find all fee estimates.
-func (ef *FeeEstimator) newEstimateFeeSet() *estimateFeeSet {
-	set := &estimateFeeSet{}
-
-	capacity := 0
-	for i, b := range ef.bin {
-		l := len(b)
-		set.bin[i] = uint32(l)
-		capacity += l
+// estimateCombinedFee returns the estimate for the target from the shortest
+// horizon that tracks it.  When checkShorterHorizon is set, the estimates of
+// the highest targets of shorter horizons are used instead when they're lower,
+// since confirming sooner is fine.
+func (ef *FeeEstimator) estimateCombinedFee(confTarget uint32,
+	successThreshold float64, checkShorterHorizon bool) float64 {
+
+	estimate := -1.0
+	if confTarget < 1 || confTarget > ef.longStats.maxConfirms() {
+		return estimate
+	}
+
+	height := ef.bestSeenHeight
+	switch {
+	case confTarget <= ef.shortStats.maxConfirms():
+		estimate = ef.shortStats.estimateMedianVal(int(confTarget),
+			sufficientTxsShort, successThreshold, height, nil)
+	case confTarget <= ef.feeStats.maxConfirms():
+		estimate = ef.feeStats.estimateMedianVal(int(confTarget),
+			sufficientFeeTxs, successThreshold, height, nil)
+	default:
+		estimate = ef.longStats.estimateMedianVal(int(confTarget),
+			sufficientFeeTxs, successThreshold, height, nil)
+	}
+
+	if checkShorterHorizon {
+		if maxConfirms := ef.feeStats.maxConfirms(); confTarget > maxConfirms {
+			medMax := ef.feeStats.estimateMedianVal(int(maxConfirms),
+				sufficientFeeTxs, successThreshold, height, nil)
+			if medMax > 0 && (estimate == -1 || medMax < estimate) {
+				estimate = medMax
+			}
+		}
+		if maxConfirms := ef.shortStats.maxConfirms(); confTarget > maxConfirms {
+			shortMax := ef.shortStats.estimateMedianVal(int(maxConfirms),
+				sufficientTxsShort, successThreshold, height, nil)
+			if shortMax > 0 && (estimate == -1 || shortMax < estimate) {
+				estimate = shortMax
+			}
+		}
 	}
 
-	set.feeRate = make([]SatoshiPerByte, capacity)
+	return estimate
+}
 
-	i := 0
-	for _, b := range ef.bin {
-		for _, o := range b {
-			set.feeRate[i] = o.feeRate
-			i++
+// estimateConservativeFee returns the highest of the estimates of the medium
+// and long horizons for double the target with the double success threshold.
+func (ef *FeeEstimator) estimateConservativeFee(doubleTarget uint32) float64 {
+	estimate := -1.0
+	height := ef.bestSeenHeight
+	if doubleTarget <= ef.shortStats.maxConfirms() {
+		estimate = ef.feeStats.estimateMedianVal(int(doubleTarget),
+			sufficientFeeTxs, doubleSuccessPct, height, nil)
+	}
+	if doubleTarget <= ef.feeStats.maxConfirms() {
+		longEstimate := ef.longStats.estimateMedianVal(int(doubleTarget),
+			sufficientFeeTxs, doubleSuccessPct, height, nil)
+		if longEstimate > estimate {
+			estimate = longEstimate
 		}
 	}
-
-	sort.Sort(set)
-
-	return set
+	return estimate
 }
 
-// estimates returns the set of all fee estimates from 1 to estimateFeeDepth
-// confirmations from now.
-func (ef *FeeEstimator) estimates() []SatoshiPerByte {
-	set := ef.newEstimateFeeSet()
+// EstimateSmartFee returns the fee rate a transaction needs to be confirmed
+// within the target along with the target the estimate is actually for, which
+// is lower when there isn't enough history for the requested one.  The
+// estimate is the highest of the estimates for half the target, the target and
+// double the target with increasing success thresholds.  Conservative
+// estimates also take the longer horizons into account, which makes them
+// slower to follow drops in fee rates.  The fee rate is zero when there isn't
+// enough data.
+func (ef *FeeEstimator) EstimateSmartFee(confTarget uint32,
+	conservative bool) (BtcPerKilobyte, uint32) {
+
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
 
-	estimates := make([]SatoshiPerByte, estimateFeeDepth)
-	for i := 0; i < estimateFeeDepth; i++ {
-		estimates[i] = set.estimateFee(i + 1)
+	if confTarget == 0 || confTarget > ef.longStats.maxConfirms() {
+		return 0, confTarget
 	}
 
-	return estimates
-}
+	// It's not possible to get reasonable estimates for a target of one
+	// block.
+	if confTarget == 1 {
+		confTarget = 2
+	}
+	if maxUsable := ef.maxUsableEstimate(); confTarget > maxUsable {
+		confTarget = maxUsable
+	}
+	if confTarget <= 1 {
+		return 0, confTarget
+	}
 
-// EstimateFee estimates the fee per byte to have a tx confirmed a given
-// number of blocks from now.
-func (ef *FeeEstimator) EstimateFee(numBlocks uint32) (BtcPerKilobyte, error) {
-	ef.mtx.Lock()
-	defer ef.mtx.Unlock()
+	median := ef.estimateCombinedFee(confTarget/2, halfSuccessPct, true)
+	actualEst := ef.estimateCombinedFee(confTarget, successPct, true)
+	if actualEst > median {
+		median = actualEst
+	}
+	doubleEst := ef.estimateCombinedFee(2*confTarget, doubleSuccessPct,
+		!conservative)
+	if doubleEst > median {
+		median = doubleEst
+	}
+	if conservative || median == -1 {
+		consEst := ef.estimateConservativeFee(2 * confTarget)
+		if consEst > median {
+			median = consEst
+		}
+	}
 
-	// If the number of registered blocks is below the minimum, return
-	// an error.
-	if ef.numBlocksRegistered < ef.minRegisteredBlocks {
-		return -1, errors.New("not enough blocks have been observed")
+	if median < 0 {
+		return 0, confTarget
 	}
+	return toBtcPerKb(median), confTarget
+}
 
+// EstimateFee estimates the fee per kilobyte to have a tx confirmed a given
+// number of blocks from now.  It's the economical estimate of EstimateSmartFee.
+func (ef *FeeEstimator) EstimateFee(numBlocks uint32) (BtcPerKilobyte, error) {
 	if numBlocks == 0 {
 		return -1, errors.New("cannot confirm transaction in zero blocks")
 	}
 
-	if numBlocks > estimateFeeDepth {
+	maxConfirms := ef.HighestTargetTracked(LongHorizon)
+	if numBlocks > maxConfirms {
 		return -1, fmt.Errorf(
 			"can only estimate fees for up to %d blocks from now",
-			estimateFeeDepth)
+			maxConfirms)
 	}
 
-	// If there are no cached results, generate them.
-	if ef.cached == nil {
-		ef.cached = ef.estimates()
+	feeRate, _ := ef.EstimateSmartFee(numBlocks, false)
+	if feeRate <= 0 {
+		return -1, errors.New("not enough transactions have been " +
+			"observed to estimate the fee")
 	}
+	return feeRate, nil
+}
 
-	return ef.cached[int(numBlocks)-1].ToBtcPerKb(), nil
+// toBtcPerKb converts a fee rate in satoshis per 1000 virtual bytes to
+// bitcoins per kilobyte.
+func toBtcPerKb(feeRate float64) BtcPerKilobyte {
+	return BtcPerKilobyte(feeRate * btcPerSatoshi)
 }
 
 // In case the format for the serialized version of the FeeEstimator changes,
 // we use a version number. If the version number changes, it does not make
 // sense to try to upgrade a previous version to a new version. Instead, just
 // start fee estimation over.
-const estimateFeeSaveVersion = 1
-
-func deserializeRegisteredBlock(r io.Reader, txs map[uint32]*observedTransaction) (*registeredBlock, error) {
-	var lenTransactions uint32
-
-	rb := &registeredBlock{}
-	binary.Read(r, binary.BigEndian, &rb.hash)
-	binary.Read(r, binary.BigEndian, &lenTransactions)
+const estimateFeeSaveVersion = 2
 
-	rb.transactions = make([]*observedTransaction, lenTransactions)
-
-	for i := uint32(0); i < lenTransactions; i++ {
-		var index uint32
-		binary.Read(r, binary.BigEndian, &index)
-		rb.transactions[i] = txs[index]
-	}
-
-	return rb, nil
-}
+// maxSavedFeeBuckets is the highest number of buckets a saved fee estimator
+// state may have.
+const maxSavedFeeBuckets = 1000
 
 // FeeEstimatorState represents a saved FeeEstimator that can be
 // restored with data from an earlier session of the program.
 type FeeEstimatorState []byte
 
-// observedTxSet is a set of txs that can that is sorted
-// by hash. It exists for serialization purposes so that
-// a serialized state always comes out the same.
-type observedTxSet []*observedTransaction
-
-func (q observedTxSet) Len() int { return len(q) }
-
-func (q observedTxSet) Less(i, j int) bool {
-	return strings.Compare(q[i].hash.String(), q[j].hash.String()) < 0
-}
-
-func (q observedTxSet) Swap(i, j int) {
-	q[i], q[j] = q[j], q[i]
-}
-
 // Save records the current state of the FeeEstimator to a []byte that
-// can be restored later.
+// can be restored later.  The transactions in the mempool aren't saved since
+// they're gone by the time the state is restored.
 func (ef *FeeEstimator) Save() FeeEstimatorState {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
-	// TODO figure out what the capacity should be.
 	w := bytes.NewBuffer(make([]byte, 0))
 
 	binary.Write(w, binary.BigEndian, uint32(estimateFeeSaveVersion))
+	binary.Write(w, binary.BigEndian, ef.bestSeenHeight)
 
-	// Insert basic parameters.
-	binary.Write(w, binary.BigEndian, &ef.maxRollback)
-	binary.Write(w, binary.BigEndian, &ef.binSize)
-	binary.Write(w, binary.BigEndian, &ef.maxReplacements)
-	binary.Write(w, binary.BigEndian, &ef.minRegisteredBlocks)
-	binary.Write(w, binary.BigEndian, &ef.lastKnownHeight)
-	binary.Write(w, binary.BigEndian, &ef.numBlocksRegistered)
-
-	// Put all the observed transactions in a sorted list.
-	var txCount uint32
-	ots := make([]*observedTransaction, len(ef.observed))
-	for hash := range ef.observed {
-		ots[txCount] = ef.observed[hash]
-		txCount++
-	}
-
-	sort.Sort(observedTxSet(ots))
-
-	txCount = 0
-	observed := make(map[*observedTransaction]uint32)
-	binary.Write(w, binary.BigEndian, uint32(len(ef.observed)))
-	for _, ot := range ots {
-		ot.Serialize(w)
-		observed[ot] = txCount
-		txCount++
-	}
-
-	// Save all the right bins.
-	for _, list := range ef.bin {
-
-		binary.Write(w, binary.BigEndian, uint32(len(list)))
-
-		for _, o := range list {
-			binary.Write(w, binary.BigEndian, observed[o])
+	// Save the span of this session if it spans more than half of the
+	// restored history and the restored history otherwise.
+	if ef.blockSpan() > ef.historicalBlockSpan()/2 {
+		binary.Write(w, binary.BigEndian, ef.firstRecordedHeight)
+		binary.Write(w, binary.BigEndian, ef.bestSeenHeight)
+	} else {
+		binary.Write(w, binary.BigEndian, ef.historicalFirst)
+		binary.Write(w, binary.BigEndian, ef.historicalBest)
+	}
+
+	binary.Write(w, binary.BigEndian, uint32(len(ef.buckets)))
+	binary.Write(w, binary.BigEndian, ef.buckets)
+	for _, stats := range ef.allStats() {
+		binary.Write(w, binary.BigEndian, stats.decay)
+		binary.Write(w, binary.BigEndian, stats.scale)
+		binary.Write(w, binary.BigEndian, uint32(len(stats.confAvg)))
+		binary.Write(w, binary.BigEndian, stats.feeRateAvg)
+		binary.Write(w, binary.BigEndian, stats.txCtAvg)
+		for _, conf := range stats.confAvg {
+			binary.Write(w, binary.BigEndian, conf)
+		}
+		for _, fail := range stats.failAvg {
+			binary.Write(w, binary.BigEndian, fail)
 		}
 	}
 
-	// Dropped transactions.
-	binary.Write(w, binary.BigEndian, uint32(len(ef.dropped)))
-	for _, registered := range ef.dropped {
-		registered.serialize(w, observed)
-	}
-
-	// Commit the tx and return.
 	return FeeEstimatorState(w.Bytes())
 }
 
@@ -700,58 +950,70 @@ func RestoreFeeEstimator(data FeeEstimatorState) (*FeeEstimator, error) {
 	}
 
 	ef := &FeeEstimator{
-		observed: make(map[chainhash.Hash]*observedTransaction),
-	}
-
-	// Read basic parameters.
-	binary.Read(r, binary.BigEndian, &ef.maxRollback)
-	binary.Read(r, binary.BigEndian, &ef.binSize)
-	binary.Read(r, binary.BigEndian, &ef.maxReplacements)
-	binary.Read(r, binary.BigEndian, &ef.minRegisteredBlocks)
-	binary.Read(r, binary.BigEndian, &ef.lastKnownHeight)
-	binary.Read(r, binary.BigEndian, &ef.numBlocksRegistered)
-
-	// Read transactions.
-	var numObserved uint32
-	observed := make(map[uint32]*observedTransaction)
-	binary.Read(r, binary.BigEndian, &numObserved)
-	for i := uint32(0); i < numObserved; i++ {
-		ot, err := deserializeObservedTransaction(r)
-		if err != nil {
+		mempoolTxs: make(map[chainhash.Hash]trackedTx),
+	}
+	var fileFirst, fileBest int32
+	for _, v := range []interface{}{&ef.bestSeenHeight, &fileFirst, &fileBest} {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
 			return nil, err
 		}
-		observed[i] = ot
-		ef.observed[ot.hash] = ot
-	}
-
-	// Read bins.
-	for i := 0; i < estimateFeeDepth; i++ {
-		var numTransactions uint32
-		binary.Read(r, binary.BigEndian, &numTransactions)
-		bin := make([]*observedTransaction, numTransactions)
-		for j := uint32(0); j < numTransactions; j++ {
-			var index uint32
-			binary.Read(r, binary.BigEndian, &index)
-
-			var exists bool
-			bin[j], exists = observed[index]
-			if !exists {
-				return nil, fmt.Errorf("Invalid transaction reference %d", index)
+	}
+	if fileFirst < 0 || fileBest < fileFirst || fileBest > ef.bestSeenHeight {
+		return nil, fmt.Errorf("Invalid estimate history %d-%d at height %d",
+			fileFirst, fileBest, ef.bestSeenHeight)
+	}
+	ef.historicalFirst = fileFirst
+	ef.historicalBest = fileBest
+
+	var numBuckets uint32
+	if err := binary.Read(r, binary.BigEndian, &numBuckets); err != nil {
+		return nil, err
+	}
+	if numBuckets <= 1 || numBuckets > maxSavedFeeBuckets {
+		return nil, fmt.Errorf("Invalid number of fee buckets %d", numBuckets)
+	}
+	ef.buckets = make([]float64, numBuckets)
+	if err := binary.Read(r, binary.BigEndian, ef.buckets); err != nil {
+		return nil, err
+	}
+	if !sort.Float64sAreSorted(ef.buckets) {
+		return nil, errors.New("Fee buckets are not sorted")
+	}
+
+	horizons := []struct {
+		stats   **txConfirmStats
+		periods uint32
+	}{
+		{&ef.feeStats, medBlockPeriods},
+		{&ef.shortStats, shortBlockPeriods},
+		{&ef.longStats, longBlockPeriods},
+	}
+	for _, horizon := range horizons {
+		var decay float64
+		var scale, periods uint32
+		for _, v := range []interface{}{&decay, &scale, &periods} {
+			if err := binary.Read(r, binary.BigEndian, v); err != nil {
+				return nil, err
 			}
 		}
-		ef.bin[i] = bin
-	}
+		if decay <= 0 || decay >= 1 || math.IsNaN(decay) {
+			return nil, fmt.Errorf("Invalid fee estimate decay %v", decay)
+		}
+		if scale == 0 || periods != horizon.periods {
+			return nil, fmt.Errorf("Invalid fee estimate horizon of %d "+
+				"periods of %d blocks", periods, scale)
+		}
 
-	// Read dropped transactions.
-	var numDropped uint32
-	binary.Read(r, binary.BigEndian, &numDropped)
-	ef.dropped = make([]*registeredBlock, numDropped)
-	for i := uint32(0); i < numDropped; i++ {
-		var err error
-		ef.dropped[int(i)], err = deserializeRegisteredBlock(r, observed)
-		if err != nil {
-			return nil, err
+		stats := newTxConfirmStats(ef.buckets, periods, scale, decay)
+		averages := [][]float64{stats.feeRateAvg, stats.txCtAvg}
+		averages = append(averages, stats.confAvg...)
+		averages = append(averages, stats.failAvg...)
+		for _, avg := range averages {
+			if err := binary.Read(r, binary.BigEndian, avg); err != nil {
+				return nil, err
+			}
 		}
+		*horizon.stats = stats
 	}
 
 	return ef, nil
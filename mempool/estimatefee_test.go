@@ -6,36 +6,14 @@ package mempool
 
 import (
 	"bytes"
-	"math/rand"
+	"math"
 	"testing"
 
 	"github.com/utreexo/utreexod/btcutil"
-	"github.com/utreexo/utreexod/chaincfg/chainhash"
 	"github.com/utreexo/utreexod/mining"
 	"github.com/utreexo/utreexod/wire"
 )
 
-// newTestFeeEstimator creates a feeEstimator with some different parameters
-// for testing purposes.
-func newTestFeeEstimator(binSize, maxReplacements, maxRollback uint32) *FeeEstimator {
-	return &FeeEstimator{
-		maxRollback:         maxRollback,
-		lastKnownHeight:     0,
-		binSize:             int32(binSize),
-		minRegisteredBlocks: 0,
-		maxReplacements:     int32(maxReplacements),
-		observed:            make(map[chainhash.Hash]*observedTransaction),
-		dropped:             make([]*registeredBlock, 0, maxRollback),
-	}
-}
-
-// lastBlock is a linked list of the block hashes which have been
-// processed by the test FeeEstimator.
-type lastBlock struct {
-	hash *chainhash.Hash
-	prev *lastBlock
-}
-
 // estimateFeeTester interacts with the FeeEstimator to keep track
 // of its expected state.
 type estimateFeeTester struct {
@@ -43,382 +21,234 @@ type estimateFeeTester struct {
 	t       *testing.T
 	version int32
 	height  int32
-	last    *lastBlock
 }
 
-func (eft *estimateFeeTester) testTx(fee btcutil.Amount) *TxDesc {
+// testTx returns a unique transaction entering the mempool at the current
+// height that pays the fee rate in satoshis per 1000 virtual bytes.
+func (eft *estimateFeeTester) testTx(feeRate float64) *TxDesc {
 	eft.version++
+	tx := btcutil.NewTx(&wire.MsgTx{
+		Version: eft.version,
+	})
 	return &TxDesc{
 		TxDesc: mining.TxDesc{
-			Tx: btcutil.NewTx(&wire.MsgTx{
-				Version: eft.version,
-			}),
+			Tx:     tx,
 			Height: eft.height,
-			Fee:    int64(fee),
+			Fee:    int64(feeRate * float64(GetTxVirtualSize(tx)) / 1000),
 		},
 		StartingPriority: 0,
 	}
 }
 
-func expectedFeePerKilobyte(t *TxDesc) BtcPerKilobyte {
-	size := float64(t.TxDesc.Tx.MsgTx().SerializeSize())
-	fee := float64(t.TxDesc.Fee)
-
-	return SatoshiPerByte(fee / size).ToBtcPerKb()
-}
-
-func (eft *estimateFeeTester) newBlock(txs []*wire.MsgTx) {
+// newBlock registers a block at the next height confirming the transactions.
+func (eft *estimateFeeTester) newBlock(txs []*TxDesc) {
 	eft.height++
 
+	// The first transaction is skipped as the coinbase.
+	eft.version++
+	msgTxs := []*wire.MsgTx{{Version: -eft.version}}
+	for _, tx := range txs {
+		msgTxs = append(msgTxs, tx.Tx.MsgTx())
+	}
 	block := btcutil.NewBlock(&wire.MsgBlock{
-		Transactions: txs,
+		Transactions: msgTxs,
 	})
 	block.SetHeight(eft.height)
 
-	eft.last = &lastBlock{block.Hash(), eft.last}
-
 	eft.ef.RegisterBlock(block)
 }
 
-func (eft *estimateFeeTester) rollback() {
-	if eft.last == nil {
-		return
-	}
-
-	err := eft.ef.Rollback(eft.last.hash)
-
-	if err != nil {
-		eft.t.Errorf("Could not rollback: %v", err)
+// simulate registers the number of blocks, each of which confirms the
+// transactions paying the high fee rate that entered the mempool at the
+// previous block.  Transactions paying the low fee rate are never confirmed
+// and leave the mempool after 20 blocks.
+func (eft *estimateFeeTester) simulate(blocks int, highFeeRate, lowFeeRate float64) {
+	var high, low []*TxDesc
+	for i := 0; i < blocks; i++ {
+		eft.newBlock(high)
+
+		high = high[:0]
+		for j := 0; j < 10; j++ {
+			tx := eft.testTx(highFeeRate)
+			eft.ef.ObserveTransaction(tx)
+			high = append(high, tx)
+
+			tx = eft.testTx(lowFeeRate)
+			eft.ef.ObserveTransaction(tx)
+			low = append(low, tx)
+		}
+
+		if i%20 == 19 {
+			for _, tx := range low {
+				eft.ef.RemoveTransaction(tx.Tx.Hash())
+			}
+			low = low[:0]
+		}
 	}
+}
 
-	eft.height--
-	eft.last = eft.last.prev
+// feeRateEqual returns whether the fee rates are equal within the precision of
+// their computation.
+func feeRateEqual(a, b BtcPerKilobyte) bool {
+	return math.Abs(float64(a-b)) < 1e-12
 }
 
 // TestEstimateFee tests basic functionality in the FeeEstimator.
 func TestEstimateFee(t *testing.T) {
-	ef := newTestFeeEstimator(5, 3, 1)
+	ef := NewFeeEstimator()
 	eft := estimateFeeTester{ef: ef, t: t}
 
-	// Try with no txs and get zero for all queries.
-	expected := BtcPerKilobyte(0.0)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f when estimator is empty; got %f", expected, estimated)
+	// Try with no txs and get no estimates.
+	for _, target := range []uint32{1, 2, 6, 100, 1008} {
+		if feeRate, _ := ef.EstimateSmartFee(target, true); feeRate != 0 {
+			t.Fatalf("expected no estimate for target %d when the "+
+				"estimator is empty, got %v", target, feeRate)
 		}
-	}
-
-	// Now insert a tx.
-	tx := eft.testTx(1000000)
-	ef.ObserveTransaction(tx)
-
-	// Expected should still be zero because this is still in the mempool.
-	expected = BtcPerKilobyte(0.0)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f when estimator has one tx in mempool; got %f", expected, estimated)
+		if _, err := ef.EstimateFee(target); err == nil {
+			t.Fatalf("expected an error for target %d when the "+
+				"estimator is empty", target)
 		}
 	}
-
-	// Change minRegisteredBlocks to make sure that works. Error return
-	// value expected.
-	ef.minRegisteredBlocks = 1
-	expected = BtcPerKilobyte(-1.0)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f before any blocks have been registered; got %f", expected, estimated)
-		}
-	}
-
-	// Record a block with the new tx.
-	eft.newBlock([]*wire.MsgTx{tx.Tx.MsgTx()})
-	expected = expectedFeePerKilobyte(tx)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f when one tx is binned; got %f", expected, estimated)
-		}
-	}
-
-	// Roll back the last block; this was an orphan block.
-	ef.minRegisteredBlocks = 0
-	eft.rollback()
-	expected = BtcPerKilobyte(0.0)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f after rolling back block; got %f", expected, estimated)
-		}
-	}
-
-	// Record an empty block and then a block with the new tx.
-	// This test was made because of a bug that only appeared when there
-	// were no transactions in the first bin.
-	eft.newBlock([]*wire.MsgTx{})
-	eft.newBlock([]*wire.MsgTx{tx.Tx.MsgTx()})
-	expected = expectedFeePerKilobyte(tx)
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f when one tx is binned; got %f", expected, estimated)
-		}
-	}
-
-	// Create some more transactions.
-	txA := eft.testTx(500000)
-	txB := eft.testTx(2000000)
-	txC := eft.testTx(4000000)
-	ef.ObserveTransaction(txA)
-	ef.ObserveTransaction(txB)
-	ef.ObserveTransaction(txC)
-
-	// Record 7 empty blocks.
-	for i := 0; i < 7; i++ {
-		eft.newBlock([]*wire.MsgTx{})
+	if _, err := ef.EstimateFee(0); err == nil {
+		t.Fatal("expected an error for a target of zero blocks")
 	}
 
-	// Mine the first tx.
-	eft.newBlock([]*wire.MsgTx{txA.Tx.MsgTx()})
+	// Transactions paying 50 sat/vbyte are confirmed in the next block
+	// while those paying 2 sat/vbyte never are.
+	eft.simulate(200, 50000, 2000)
+	expected := BtcPerKilobyte(0.0005)
 
-	// Now the estimated amount should depend on the value
-	// of the argument to estimate fee.
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-		if i > 2 {
-			expected = expectedFeePerKilobyte(txA)
-		} else {
-			expected = expectedFeePerKilobyte(tx)
+	for _, conservative := range []bool{false, true} {
+		feeRate, blocks := ef.EstimateSmartFee(1, conservative)
+		if blocks != 2 {
+			t.Fatalf("expected an estimate for 2 blocks, got %d", blocks)
 		}
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f on round %d; got %f", expected, i, estimated)
+		if !feeRateEqual(feeRate, expected) {
+			t.Fatalf("expected an estimate of %v with conservative "+
+				"%v, got %v", expected, conservative, feeRate)
 		}
 	}
-
-	// Record 5 more empty blocks.
-	for i := 0; i < 5; i++ {
-		eft.newBlock([]*wire.MsgTx{})
-	}
-
-	// Mine the next tx.
-	eft.newBlock([]*wire.MsgTx{txB.Tx.MsgTx()})
-
-	// Now the estimated amount should depend on the value
-	// of the argument to estimate fee.
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-		if i <= 2 {
-			expected = expectedFeePerKilobyte(txB)
-		} else if i <= 8 {
-			expected = expectedFeePerKilobyte(tx)
-		} else {
-			expected = expectedFeePerKilobyte(txA)
-		}
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f on round %d; got %f", expected, i, estimated)
-		}
+	feeRate, err := ef.EstimateFee(6)
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	// Record 9 more empty blocks.
-	for i := 0; i < 10; i++ {
-		eft.newBlock([]*wire.MsgTx{})
+	if !feeRateEqual(feeRate, expected) {
+		t.Fatalf("expected an estimate of %v, got %v", expected, feeRate)
 	}
 
-	// Mine txC.
-	eft.newBlock([]*wire.MsgTx{txC.Tx.MsgTx()})
-
-	// This should have no effect on the outcome because too
-	// many blocks have been mined for txC to be recorded.
-	for i := uint32(1); i <= estimateFeeDepth; i++ {
-		estimated, _ := ef.EstimateFee(i)
-		if i <= 2 {
-			expected = expectedFeePerKilobyte(txC)
-		} else if i <= 8 {
-			expected = expectedFeePerKilobyte(txB)
-		} else if i <= 8+6 {
-			expected = expectedFeePerKilobyte(tx)
-		} else {
-			expected = expectedFeePerKilobyte(txA)
-		}
-
-		if estimated != expected {
-			t.Errorf("Estimate fee error: expected %f on round %d; got %f", expected, i, estimated)
-		}
+	// Targets without enough history are lowered to the highest usable
+	// one.
+	maxUsable := uint32(eft.height-1) / 2
+	feeRate, blocks := ef.EstimateSmartFee(1008, false)
+	if blocks != maxUsable || !feeRateEqual(feeRate, expected) {
+		t.Fatalf("expected an estimate of %v for %d blocks, got %v "+
+			"for %d blocks", expected, maxUsable, feeRate, blocks)
 	}
-}
-
-func (eft *estimateFeeTester) estimates() [estimateFeeDepth]BtcPerKilobyte {
-
-	// Generate estimates
-	var estimates [estimateFeeDepth]BtcPerKilobyte
-	for i := 0; i < estimateFeeDepth; i++ {
-		estimates[i], _ = eft.ef.EstimateFee(uint32(i + 1))
+	if feeRate, _ := ef.EstimateSmartFee(1009, false); feeRate != 0 {
+		t.Fatalf("expected no estimate beyond the longest horizon, "+
+			"got %v", feeRate)
 	}
 
-	// Check that all estimated fee results go in descending order.
-	for i := 1; i < estimateFeeDepth; i++ {
-		if estimates[i] > estimates[i-1] {
-			eft.t.Error("Estimates not in descending order; got ",
-				estimates[i], " for estimate ", i, " and ", estimates[i-1], " for ", (i - 1))
-			panic("invalid state.")
-		}
+	// Blocks from reorganizations aren't taken into account.
+	height := ef.LastKnownHeight()
+	eft.height -= 2
+	eft.newBlock(nil)
+	if ef.LastKnownHeight() != height {
+		t.Fatalf("expected the estimator to stay at height %d, got %d",
+			height, ef.LastKnownHeight())
 	}
-
-	return estimates
 }
 
-func (eft *estimateFeeTester) round(txHistory [][]*TxDesc,
-	estimateHistory [][estimateFeeDepth]BtcPerKilobyte,
-	txPerRound, txPerBlock uint32) ([][]*TxDesc, [][estimateFeeDepth]BtcPerKilobyte) {
-
-	// generate new txs.
-	var newTxs []*TxDesc
-	for i := uint32(0); i < txPerRound; i++ {
-		newTx := eft.testTx(btcutil.Amount(rand.Intn(1000000)))
-		eft.ef.ObserveTransaction(newTx)
-		newTxs = append(newTxs, newTx)
-	}
-
-	// Generate mempool.
-	mempool := make(map[*observedTransaction]*TxDesc)
-	for _, h := range txHistory {
-		for _, t := range h {
-			if o, exists := eft.ef.observed[*t.Tx.Hash()]; exists && o.mined == mining.UnminedHeight {
-				mempool[o] = t
-			}
-		}
-	}
-
-	// generate new block, with no duplicates.
-	i := uint32(0)
-	newBlockList := make([]*wire.MsgTx, 0, txPerBlock)
-	for _, t := range mempool {
-		newBlockList = append(newBlockList, t.TxDesc.Tx.MsgTx())
-		i++
-
-		if i == txPerBlock {
-			break
+// TestEstimateRawFee ensures raw estimates are found in the expected buckets of
+// each horizon.
+func TestEstimateRawFee(t *testing.T) {
+	ef := NewFeeEstimator()
+	eft := estimateFeeTester{ef: ef, t: t}
+	eft.simulate(200, 50000, 2000)
+
+	tests := []struct {
+		target   uint32
+		horizon  FeeEstimateHorizon
+		tracked  bool
+		estimate BtcPerKilobyte
+	}{
+		{1, ShortHorizon, true, 0.0005},
+		{12, ShortHorizon, true, 0.0005},
+		{13, ShortHorizon, false, 0},
+		{48, MediumHorizon, true, 0.0005},
+		{49, MediumHorizon, false, 0},
+		{1008, LongHorizon, true, 0.0005},
+		{1009, LongHorizon, false, 0},
+	}
+
+	for _, test := range tests {
+		feeRate, result := ef.EstimateRawFee(test.target, 0.95,
+			test.horizon)
+		if (result != nil) != test.tracked {
+			t.Fatalf("%v horizon target %d: expected tracked %v",
+				test.horizon, test.target, test.tracked)
+		}
+		if !feeRateEqual(feeRate, test.estimate) {
+			t.Fatalf("%v horizon target %d: expected an estimate "+
+				"of %v, got %v", test.horizon, test.target,
+				test.estimate, feeRate)
+		}
+		if result == nil || feeRate == 0 {
+			continue
+		}
+		if result.Pass.Start >= 50000 || result.Pass.End < 50000 {
+			t.Fatalf("%v horizon target %d: unexpected pass "+
+				"bucket %+v", test.horizon, test.target,
+				result.Pass)
+		}
+		if result.Fail.Start != -1 && result.Fail.End > 50000 {
+			t.Fatalf("%v horizon target %d: unexpected fail "+
+				"bucket %+v", test.horizon, test.target,
+				result.Fail)
 		}
 	}
-
-	// Register a new block.
-	eft.newBlock(newBlockList)
-
-	// return results.
-	estimates := eft.estimates()
-
-	// Return results
-	return append(txHistory, newTxs), append(estimateHistory, estimates)
 }
 
-// TestEstimateFeeRollback tests the rollback function, which undoes the
-// effect of a adding a new block.
-func TestEstimateFeeRollback(t *testing.T) {
-	txPerRound := uint32(7)
-	txPerBlock := uint32(5)
-	binSize := uint32(6)
-	maxReplacements := uint32(4)
-	stepsBack := 2
-	rounds := 30
-
-	eft := estimateFeeTester{ef: newTestFeeEstimator(binSize, maxReplacements, uint32(stepsBack)), t: t}
-	var txHistory [][]*TxDesc
-	estimateHistory := [][estimateFeeDepth]BtcPerKilobyte{eft.estimates()}
-
-	for round := 0; round < rounds; round++ {
-		// Go forward a few rounds.
-		for step := 0; step <= stepsBack; step++ {
-			txHistory, estimateHistory =
-				eft.round(txHistory, estimateHistory, txPerRound, txPerBlock)
-		}
-
-		// Now go back.
-		for step := 0; step < stepsBack; step++ {
-			eft.rollback()
-
-			// After rolling back, we should have the same estimated
-			// fees as before.
-			expected := estimateHistory[len(estimateHistory)-step-2]
-			estimates := eft.estimates()
+// TestDatabase tests that the FeeEstimator is persisted and restored wholly.
+func TestDatabase(t *testing.T) {
+	ef := NewFeeEstimator()
+	eft := estimateFeeTester{ef: ef, t: t}
+	eft.simulate(100, 50000, 2000)
 
-			// Ensure that these are both the same.
-			for i := 0; i < estimateFeeDepth; i++ {
-				if expected[i] != estimates[i] {
-					t.Errorf("Rollback value mismatch. Expected %f, got %f. ",
-						expected[i], estimates[i])
-					return
-				}
+	saved := ef.Save()
+	restored, err := RestoreFeeEstimator(saved)
+	if err != nil {
+		t.Fatalf("Could not restore database: %v", err)
+	}
+
+	if restored.LastKnownHeight() != ef.LastKnownHeight() {
+		t.Fatalf("expected the restored estimator at height %d, got %d",
+			ef.LastKnownHeight(), restored.LastKnownHeight())
+	}
+	for _, target := range []uint32{2, 6, 25, 40} {
+		for _, conservative := range []bool{false, true} {
+			want, wantBlocks := ef.EstimateSmartFee(target, conservative)
+			got, gotBlocks := restored.EstimateSmartFee(target,
+				conservative)
+			if got != want || gotBlocks != wantBlocks {
+				t.Fatalf("target %d: expected %v for %d blocks, "+
+					"got %v for %d blocks", target, want,
+					wantBlocks, got, gotBlocks)
 			}
 		}
-
-		// Erase history.
-		txHistory = txHistory[0 : len(txHistory)-stepsBack]
-		estimateHistory = estimateHistory[0 : len(estimateHistory)-stepsBack]
-	}
-}
-
-func (eft *estimateFeeTester) checkSaveAndRestore(
-	previousEstimates [estimateFeeDepth]BtcPerKilobyte) {
-
-	// Get the save state.
-	save := eft.ef.Save()
-
-	// Save and restore database.
-	var err error
-	eft.ef, err = RestoreFeeEstimator(save)
-	if err != nil {
-		eft.t.Fatalf("Could not restore database: %s", err)
-	}
-
-	// Save again and check that it matches the previous one.
-	redo := eft.ef.Save()
-	if !bytes.Equal(save, redo) {
-		eft.t.Fatalf("Restored states do not match: %v %v", save, redo)
 	}
 
-	// Check that the results match.
-	newEstimates := eft.estimates()
-
-	for i, prev := range previousEstimates {
-		if prev != newEstimates[i] {
-			eft.t.Error("Mismatch in estimate ", i, " after restore; got ", newEstimates[i], " but expected ", prev)
-		}
+	// Saving the restored estimator results in the same state.
+	if !bytes.Equal(restored.Save(), saved) {
+		t.Fatal("restored estimator saved a different state")
 	}
-}
 
-// TestSave tests saving and restoring to a []byte.
-func TestDatabase(t *testing.T) {
-
-	txPerRound := uint32(7)
-	txPerBlock := uint32(5)
-	binSize := uint32(6)
-	maxReplacements := uint32(4)
-	rounds := 8
-
-	eft := estimateFeeTester{ef: newTestFeeEstimator(binSize, maxReplacements, uint32(rounds)+1), t: t}
-	var txHistory [][]*TxDesc
-	estimateHistory := [][estimateFeeDepth]BtcPerKilobyte{eft.estimates()}
-
-	for round := 0; round < rounds; round++ {
-		eft.checkSaveAndRestore(estimateHistory[len(estimateHistory)-1])
-
-		// Go forward one step.
-		txHistory, estimateHistory =
-			eft.round(txHistory, estimateHistory, txPerRound, txPerBlock)
+	// Invalid states aren't restored.
+	if _, err := RestoreFeeEstimator(saved[:len(saved)-1]); err == nil {
+		t.Fatal("expected an error restoring a truncated state")
 	}
-
-	// Reverse the process and try again.
-	for round := 1; round <= rounds; round++ {
-		eft.rollback()
-		eft.checkSaveAndRestore(estimateHistory[len(estimateHistory)-round-1])
+	badVersion := append([]byte{0, 0, 0, 1}, saved[4:]...)
+	if _, err := RestoreFeeEstimator(badVersion); err == nil {
+		t.Fatal("expected an error restoring a state of another version")
 	}
 }
@@ -0,0 +1,207 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// rollingMinFeeHalfLife is how long it takes for the minimum fee rate
+	// of a full pool to halve once blocks are mined.  It halves faster the
+	// emptier the pool is.
+	rollingMinFeeHalfLife = 12 * time.Hour
+
+	// rollingMinFeeUpdateInterval is the minimum amount of time in between
+	// updates of the decaying minimum fee rate of the pool.
+	rollingMinFeeUpdateInterval = 10 * time.Second
+
+	// poolExpireScanInterval is the minimum amount of time in between
+	// scans of the pool to remove expired transactions.
+	poolExpireScanInterval = time.Minute
+)
+
+// rollingMinFeeRate returns the fee rate in Satoshi per 1000 bytes that
+// transactions have to pay since ones paying less were evicted for the size of
+// the pool, or zero when there's no such fee rate.  It decays exponentially
+// once blocks are mined after it was last raised and is dropped when it's
+// under half the minimum relay fee.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) rollingMinFeeRate() btcutil.Amount {
+	if mp.rollingMinFee == 0 || mp.cfg.BestHeight() <= mp.rollingMinFeeHeight {
+		return btcutil.Amount(mp.rollingMinFee)
+	}
+
+	now := time.Now()
+	if elapsed := now.Sub(mp.lastRollingFeeUpdate); elapsed > rollingMinFeeUpdateInterval {
+		halfLife := rollingMinFeeHalfLife
+		maxSize := mp.cfg.Policy.MaxPoolSize
+		switch {
+		case mp.poolSize < maxSize/4:
+			halfLife /= 4
+		case mp.poolSize < maxSize/2:
+			halfLife /= 2
+		}
+		mp.rollingMinFee /= math.Pow(2, elapsed.Seconds()/halfLife.Seconds())
+		mp.lastRollingFeeUpdate = now
+
+		if mp.rollingMinFee < float64(mp.cfg.Policy.MinRelayTxFee)/2 {
+			mp.rollingMinFee = 0
+			return 0
+		}
+	}
+
+	return btcutil.Amount(mp.rollingMinFee)
+}
+
+// minFeeRate returns the fee rate in Satoshi per 1000 bytes that transactions
+// have to pay to be accepted to the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) minFeeRate() btcutil.Amount {
+	minFee := mp.rollingMinFeeRate()
+	if minFee < mp.cfg.Policy.MinRelayTxFee {
+		minFee = mp.cfg.Policy.MinRelayTxFee
+	}
+	return minFee
+}
+
+// MinFeeRate returns the fee rate in Satoshi per 1000 bytes that transactions
+// have to pay to be accepted to the pool, which is the minimum relay fee unless
+// transactions were evicted for the size of the pool recently.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MinFeeRate() btcutil.Amount {
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	return mp.minFeeRate()
+}
+
+// Size returns the total serialized size in bytes of the transactions in the
+// pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Size() int64 {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	return mp.poolSize
+}
+
+// expireTransactions removes the transactions that have been in the pool for
+// longer than the maximum age along with the transactions that spend them.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) expireTransactions(now time.Time) {
+	if mp.cfg.Policy.MaxTxAge == 0 || now.Before(mp.nextPoolExpireScan) {
+		return
+	}
+	mp.nextPoolExpireScan = now.Add(poolExpireScanInterval)
+
+	cutoff := now.Add(-mp.cfg.Policy.MaxTxAge)
+	var expired []*btcutil.Tx
+	for _, desc := range mp.pool {
+		if desc.Added.Before(cutoff) {
+			expired = append(expired, desc.Tx)
+		}
+	}
+	for _, tx := range expired {
+		mp.removeTransaction(tx, true, true, removedTx)
+	}
+	if len(expired) > 0 {
+		log.Debugf("Expired %d transactions from the mempool",
+			len(expired))
+	}
+}
+
+// worstChunk returns the chunk of the pool with the lowest fee rate, which is
+// the last chunk of one of the clusters, or nil when the pool is empty.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) worstChunk() *txChunk {
+	var worst *txChunk
+	seen := make(map[*txCluster]struct{})
+	for _, cluster := range mp.clusters {
+		if _, ok := seen[cluster]; ok {
+			continue
+		}
+		seen[cluster] = struct{}{}
+
+		last := cluster.chunks[len(cluster.chunks)-1]
+		if worst == nil || worst.higherFeeRate(last) {
+			worst = last
+		}
+	}
+	return worst
+}
+
+// limitPoolSize removes the expired transactions from the pool and then evicts
+// the chunks with the lowest fee rates until the pool fits in its maximum size.
+// The minimum fee rate of the pool is raised above the fee rate of each evicted
+// chunk by the minimum relay fee so that it doesn't get replaced by one that
+// pays barely more.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) limitPoolSize() {
+	now := time.Now()
+	mp.expireTransactions(now)
+
+	maxSize := mp.cfg.Policy.MaxPoolSize
+	for maxSize > 0 && mp.poolSize > maxSize {
+		chunk := mp.worstChunk()
+		if chunk == nil {
+			break
+		}
+
+		feeRate := float64(chunk.feePerKB() +
+			int64(mp.cfg.Policy.MinRelayTxFee))
+		if feeRate > mp.rollingMinFee {
+			mp.rollingMinFee = feeRate
+			mp.rollingMinFeeHeight = mp.cfg.BestHeight()
+			mp.lastRollingFeeUpdate = now
+		}
+
+		// Remove the children of the chunk first since they come
+		// after their parents.
+		for i := len(chunk.txs) - 1; i >= 0; i-- {
+			mp.removeTransaction(chunk.txs[i].Tx, true, true,
+				removedTx)
+		}
+		log.Debugf("Evicted %d transactions with a fee rate of %d "+
+			"sat/kb for the size of the mempool", len(chunk.txs),
+			chunk.feePerKB())
+	}
+}
+
+// limitAccepted limits the size of the pool after the passed transactions were
+// accepted and returns the ones that are still in the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) limitAccepted(accepted []*TxDesc) []*TxDesc {
+	mp.limitPoolSize()
+
+	remaining := accepted[:0]
+	for _, txD := range accepted {
+		if mp.isTransactionInPool(txD.Tx.Hash()) {
+			remaining = append(remaining, txD)
+		}
+	}
+	return remaining
+}
+
+// errMempoolFull returns the error of a transaction that was evicted right
+// after it was accepted since it didn't pay enough to stay in the full pool.
+func errMempoolFull(tx *btcutil.Tx) error {
+	str := fmt.Sprintf("transaction %v was evicted right away since the "+
+		"mempool is full", tx.Hash())
+	return txRuleError(wire.RejectInsufficientFee, str)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+)
+
+// TestLimitPoolSize ensures that the transactions paying the lowest fee rates
+// are evicted once the pool grows past its maximum size, that the minimum fee
+// rate of the pool is raised above theirs and decays once blocks are mined, and
+// that transactions expire after the maximum age.
+func TestLimitPoolSize(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	mp := harness.txPool
+
+	coinbase := ctx.addCoinbaseTx(3)
+	low := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 1000,
+		false, false,
+	)
+	lowSize := int64(low.MsgTx().SerializeSize())
+	if mp.Size() != lowSize {
+		t.Fatalf("expected a pool size of %d, got %d", lowSize, mp.Size())
+	}
+	if mp.MinFeeRate() != mp.cfg.Policy.MinRelayTxFee {
+		t.Fatalf("expected the minimum fee rate to be the minimum relay "+
+			"fee, got %d", mp.MinFeeRate())
+	}
+
+	// Only one transaction fits in the pool, so the one paying the lowest
+	// fee rate is evicted once another one is accepted.
+	mp.cfg.Policy.MaxPoolSize = lowSize * 3 / 2
+	high := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 5000,
+		false, false,
+	)
+	testPoolMembership(ctx, low, false, false)
+	testPoolMembership(ctx, high, false, true)
+
+	lowFeePerKB := int64(1000) * 1000 / GetTxVirtualSize(low)
+	wantMinFee := btcutil.Amount(lowFeePerKB) + mp.cfg.Policy.MinRelayTxFee
+	if mp.MinFeeRate() != wantMinFee {
+		t.Fatalf("expected a minimum fee rate of %d, got %d", wantMinFee,
+			mp.MinFeeRate())
+	}
+
+	// A transaction paying as little as the evicted one is rejected.
+	out := txOutToSpendableOut(coinbase, 2)
+	tx, err := harness.CreateSignedTx([]spendableOutput{out}, 1, 1000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = mp.ProcessTransaction(tx, false, false, 0)
+	if err == nil || !strings.Contains(err.Error(), "mempool minimum fee") {
+		t.Fatalf("expected the transaction to be rejected for the "+
+			"mempool minimum fee, got %v", err)
+	}
+
+	// A transaction paying more than the minimum fee rate but less than
+	// the one in the pool is evicted right after it's accepted.
+	tx, err = harness.CreateSignedTx([]spendableOutput{out}, 1, 3000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = mp.ProcessTransaction(tx, false, false, 0)
+	if err == nil || !strings.Contains(err.Error(), "mempool is full") {
+		t.Fatalf("expected the transaction to be evicted, got %v", err)
+	}
+	testPoolMembership(ctx, tx, false, false)
+	testPoolMembership(ctx, high, false, true)
+	if mp.MinFeeRate() <= wantMinFee {
+		t.Fatalf("expected the minimum fee rate to be raised above %d, "+
+			"got %d", wantMinFee, mp.MinFeeRate())
+	}
+
+	// The minimum fee rate doesn't decay until a block is mined and it
+	// drops back to the minimum relay fee eventually.
+	raisedMinFee := mp.MinFeeRate()
+	mp.lastRollingFeeUpdate = time.Now().Add(-time.Hour)
+	if mp.MinFeeRate() != raisedMinFee {
+		t.Fatalf("expected the minimum fee rate to stay at %d, got %d",
+			raisedMinFee, mp.MinFeeRate())
+	}
+	harness.chain.SetHeight(harness.chain.BestHeight() + 1)
+	if mp.MinFeeRate() >= raisedMinFee {
+		t.Fatalf("expected the minimum fee rate to decay from %d, got %d",
+			raisedMinFee, mp.MinFeeRate())
+	}
+	mp.lastRollingFeeUpdate = time.Now().Add(-30 * 24 * time.Hour)
+	if mp.MinFeeRate() != mp.cfg.Policy.MinRelayTxFee {
+		t.Fatalf("expected the minimum fee rate to drop to the minimum "+
+			"relay fee, got %d", mp.MinFeeRate())
+	}
+
+	// Transactions that stay in the pool for longer than the maximum age
+	// are removed.
+	mp.cfg.Policy.MaxTxAge = time.Hour
+	mp.expireTransactions(time.Now())
+	testPoolMembership(ctx, high, false, true)
+	mp.nextPoolExpireScan = time.Time{}
+	mp.expireTransactions(time.Now().Add(2 * time.Hour))
+	testPoolMembership(ctx, high, false, false)
+	if mp.Size() != 0 {
+		t.Fatalf("expected an empty pool, got a size of %d", mp.Size())
+	}
+}
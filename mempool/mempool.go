@@ -102,6 +102,11 @@ type Config struct {
 	// PruneFromAccumulator uncaches the given hashes from the accumulator.
 	PruneFromAccumulator func(hashes []wire.LeafData) error
 
+	// GenerateUData defines the function to use to prove the given leaves
+	// against the current utreexo accumulator.  This is only used when the
+	// node is run with the UtreexoView activated.
+	GenerateUData func(dels []wire.LeafData) (*wire.UData, error)
+
 	// SigCache defines a signature cache to use.
 	SigCache *txscript.SigCache
 
@@ -114,7 +119,8 @@ type Config struct {
 	AddrIndex *indexers.AddrIndex
 
 	// FeeEstimatator provides a feeEstimator. If it is not nil, the mempool
-	// records all new transactions it observes into the feeEstimator.
+	// records all new transactions it observes into the feeEstimator, and
+	// informs it of the transactions that leave the pool.
 	FeeEstimator *FeeEstimator
 }
 
@@ -157,10 +163,46 @@ type Policy struct {
 	// considered a non-zero fee.
 	MinRelayTxFee btcutil.Amount
 
+	// DustRelayFee is the fee rate in Satoshi per 1000 bytes that decides
+	// which outputs are dust.  Outputs that cost more than a third of
+	// their value to spend at this fee rate are dust.
+	DustRelayFee btcutil.Amount
+
+	// MaxStandardTxWeight is the maximum weight of a standard transaction.
+	MaxStandardTxWeight int64
+
+	// MaxDataCarrierSize is the maximum number of bytes of data that a
+	// standard null data output may carry.
+	MaxDataCarrierSize int
+
+	// RejectDataCarrier, if true, rejects transactions with null data
+	// outputs as non-standard.
+	RejectDataCarrier bool
+
+	// RejectBareMultisig, if true, rejects transactions with bare
+	// multi-signature outputs as non-standard.
+	RejectBareMultisig bool
+
 	// RejectReplacement, if true, rejects accepting replacement
 	// transactions using the Replace-By-Fee (RBF) signaling policy into
 	// the mempool.
 	RejectReplacement bool
+
+	// FullRBF, if true, accepts replacements of transactions in the
+	// mempool that don't signal replacement through the Replace-By-Fee
+	// (RBF) signaling policy.  It has no effect when RejectReplacement is
+	// set.
+	FullRBF bool
+
+	// MaxPoolSize is the maximum total serialized size in bytes of the
+	// transactions in the mempool.  The transactions with the lowest fee
+	// rates are evicted once it's exceeded.  Zero means the size is not
+	// limited.
+	MaxPoolSize int64
+
+	// MaxTxAge is how long a transaction may stay in the mempool before
+	// it's removed.  Zero means transactions never expire.
+	MaxTxAge time.Duration
 }
 
 // TxDesc is a descriptor containing a transaction in the mempool along with
@@ -196,9 +238,42 @@ type TxPool struct {
 	orphans       map[chainhash.Hash]*orphanTx
 	orphansByPrev map[wire.OutPoint]map[chainhash.Hash]*btcutil.Tx
 	outpoints     map[wire.OutPoint]*btcutil.Tx
+	lowFeeTxs     map[chainhash.Hash]*btcutil.Tx
+	clusters      map[chainhash.Hash]*txCluster
+	poolSize      int64   // total serialized size of the pool transactions.
 	pennyTotal    float64 // exponentially decaying total for penny spends.
 	lastPennyUnix int64   // unix time of last ``penny spend''
 
+	// rollingMinFee is the fee rate in Satoshi per 1000 bytes that
+	// transactions have to pay since ones paying less were evicted for the
+	// size of the pool.  It decays once blocks are mined after the height
+	// it was last raised at.
+	rollingMinFee        float64
+	rollingMinFeeHeight  int32
+	lastRollingFeeUpdate time.Time
+
+	// nextPoolExpireScan is the time after which the pool will be scanned
+	// for transactions that have been in it for too long.
+	nextPoolExpireScan time.Time
+
+	// spentPoolOutputs are the outputs of the transactions in the pool that
+	// are spent by other transactions in the pool.  It's guarded by its own
+	// lock so that the chain can look them up while connecting a block
+	// without taking the pool lock.
+	spentPoolOutputsMtx sync.RWMutex
+	spentPoolOutputs    map[wire.OutPoint]struct{}
+
+	// pendingPrunes are the leaves of the removed transactions that are
+	// uncached from the accumulator at once instead of one transaction at
+	// a time while batchPrunes is set.
+	batchPrunes   bool
+	pendingPrunes []wire.LeafData
+
+	// notifications are the callbacks of the subscribers that are notified
+	// of the transactions that enter and leave the pool.
+	notificationsLock sync.RWMutex
+	notifications     []NotificationCallback
+
 	// nextExpireScan is the time after which the orphan pool will be
 	// scanned in order to evict orphans.  This is NOT a hard deadline as
 	// the scan will only run when an orphan is added to the pool as opposed
@@ -479,15 +554,21 @@ func (mp *TxPool) HaveTransaction(hash *chainhash.Hash) bool {
 // removeTransaction is the internal function which implements the public
 // RemoveTransaction.  See the comment for RemoveTransaction for more details.
 //
+// The passed removal is why the transaction is removed, which the subscribers
+// are notified of.  The redeemers are always notified as removed.
+//
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers, uncacheUtreexo bool) {
+func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers, uncacheUtreexo bool,
+	r *removal) {
+
 	txHash := tx.Hash()
 	if removeRedeemers {
 		// Remove any transactions which rely on this one.
 		for i := uint32(0); i < uint32(len(tx.MsgTx().TxOut)); i++ {
 			prevOut := wire.OutPoint{Hash: *txHash, Index: i}
 			if txRedeemer, exists := mp.outpoints[prevOut]; exists {
-				mp.removeTransaction(txRedeemer, true, uncacheUtreexo)
+				mp.removeTransaction(txRedeemer, true,
+					uncacheUtreexo, removedTx)
 			}
 		}
 	}
@@ -508,6 +589,9 @@ func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers, uncacheUtre
 				log.Debugf("missing the leaf hashes for tx %s from while "+
 					"removing it from the pool",
 					tx.MsgTx().TxHash().String())
+			} else if mp.batchPrunes {
+				delete(mp.poolLeaves, *txHash)
+				mp.pendingPrunes = append(mp.pendingPrunes, leaves...)
 			} else {
 				delete(mp.poolLeaves, *txHash)
 
@@ -519,12 +603,21 @@ func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers, uncacheUtre
 			}
 		}
 
+		// Stop tracking the transaction for fee estimation if enabled.
+		if mp.cfg.FeeEstimator != nil {
+			mp.cfg.FeeEstimator.RemoveTransaction(txHash)
+		}
+
 		// Mark the referenced outpoints as unspent by the pool.
 		for _, txIn := range txDesc.Tx.MsgTx().TxIn {
 			delete(mp.outpoints, txIn.PreviousOutPoint)
 		}
+		mp.unmarkSpentPoolOutputs(txDesc.Tx)
 		delete(mp.pool, *txHash)
+		mp.poolSize -= int64(tx.MsgTx().SerializeSize())
+		mp.removeFromCluster(tx)
 		atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
+		mp.sendNotification(txDesc, r)
 	}
 }
 
@@ -538,7 +631,7 @@ func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers, uncacheUtre
 func (mp *TxPool) RemoveTransaction(tx *btcutil.Tx, removeRedeemers, uncacheUtreexo bool) {
 	// Protect concurrent access.
 	mp.mtx.Lock()
-	mp.removeTransaction(tx, removeRedeemers, uncacheUtreexo)
+	mp.removeTransaction(tx, removeRedeemers, uncacheUtreexo, removedTx)
 	mp.mtx.Unlock()
 }
 
@@ -555,7 +648,8 @@ func (mp *TxPool) RemoveDoubleSpends(tx *btcutil.Tx) {
 	for _, txIn := range tx.MsgTx().TxIn {
 		if txRedeemer, ok := mp.outpoints[txIn.PreviousOutPoint]; ok {
 			if !txRedeemer.Hash().IsEqual(tx.Hash()) {
-				mp.removeTransaction(txRedeemer, true, true)
+				mp.removeTransaction(txRedeemer, true, true,
+					removedTx)
 			}
 		}
 	}
@@ -595,10 +689,23 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *btcutil
 		StartingPriority: mining.CalcPriority(tx.MsgTx(), utxoView, height),
 	}
 
+	// Transactions spending other transactions of the pool aren't used for
+	// fee estimation since their fee rates don't reflect what they're
+	// mined for.
+	observeFee := mp.cfg.FeeEstimator != nil
+	for _, txIn := range tx.MsgTx().TxIn {
+		if _, exists := mp.pool[txIn.PreviousOutPoint.Hash]; exists {
+			observeFee = false
+		}
+	}
+
+	mp.markSpentPoolOutputs(tx)
 	mp.pool[*tx.Hash()] = txD
 	for _, txIn := range tx.MsgTx().TxIn {
 		mp.outpoints[txIn.PreviousOutPoint] = tx
 	}
+	mp.poolSize += int64(tx.MsgTx().SerializeSize())
+	mp.addToCluster(txD)
 	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 
 	// Add unconfirmed address index entries associated with the transaction
@@ -608,20 +715,22 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *btcutil
 	}
 
 	// Record this tx for fee estimation if enabled.
-	if mp.cfg.FeeEstimator != nil {
+	if observeFee {
 		mp.cfg.FeeEstimator.ObserveTransaction(txD)
 	}
 
+	mp.sendNotification(txD, nil)
+
 	return txD, nil
 }
 
 // checkPoolDoubleSpend checks whether or not the passed transaction is
 // attempting to spend coins already spent by other transactions in the pool.
 // If it does, we'll check whether each of those transactions are signaling for
-// replacement. If just one of them isn't, an error is returned. Otherwise, a
-// boolean is returned signaling that the transaction is a replacement. Note it
-// does not check for double spends against transactions already in the main
-// chain.
+// replacement, unless the policy accepts full replacement. If just one of them
+// isn't, an error is returned. Otherwise, a boolean is returned signaling that
+// the transaction is a replacement. Note it does not check for double spends
+// against transactions already in the main chain.
 //
 // This function MUST be called with the mempool lock held (for reads).
 func (mp *TxPool) checkPoolDoubleSpend(tx *btcutil.Tx) (bool, error) {
@@ -633,9 +742,10 @@ func (mp *TxPool) checkPoolDoubleSpend(tx *btcutil.Tx) (bool, error) {
 		}
 
 		// Reject the transaction if we don't accept replacement
-		// transactions or if it doesn't signal replacement.
-		if mp.cfg.Policy.RejectReplacement ||
-			!mp.signalsReplacement(conflict, nil) {
+		// transactions or if it doesn't signal replacement and we
+		// only accept the replacements that are signaled.
+		if mp.cfg.Policy.RejectReplacement || (!mp.cfg.Policy.FullRBF &&
+			!mp.signalsReplacement(conflict, nil)) {
 			str := fmt.Sprintf("output %v already spent by "+
 				"transaction %v in the memory pool",
 				txIn.PreviousOutPoint, conflict.Hash())
@@ -660,6 +770,9 @@ func (mp *TxPool) checkPoolDoubleSpend(tx *btcutil.Tx) (bool, error) {
 // are replaceable under this policy for as long as any one of their ancestors
 // signals replaceability and remains unconfirmed.
 //
+// TRUC signaling: Transactions that opt in to the TRUC policy are always
+// replaceable.
+//
 // The cache is optional and serves as an optimization to avoid visiting
 // transactions we've already determined don't signal replacement.
 //
@@ -673,6 +786,10 @@ func (mp *TxPool) signalsReplacement(tx *btcutil.Tx,
 		cache = make(map[chainhash.Hash]struct{})
 	}
 
+	if isTRUC(tx) {
+		return true
+	}
+
 	for _, txIn := range tx.MsgTx().TxIn {
 		if txIn.Sequence <= MaxRBFSequence {
 			return true
@@ -918,6 +1035,24 @@ func (mp *TxPool) FetchTransaction(txHash *chainhash.Hash) (*btcutil.Tx, error)
 	return nil, fmt.Errorf("transaction is not in the pool")
 }
 
+// FetchTxDesc returns the descriptor of the requested transaction from the
+// transaction pool.  This only fetches from the main transaction pool and does
+// not include orphans.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) FetchTxDesc(txHash *chainhash.Hash) (*TxDesc, error) {
+	// Protect concurrent access.
+	mp.mtx.RLock()
+	txDesc, exists := mp.pool[*txHash]
+	mp.mtx.RUnlock()
+
+	if exists {
+		return txDesc, nil
+	}
+
+	return nil, fmt.Errorf("transaction is not in the pool")
+}
+
 // FetchLeafDatas returns the leafdatas for the given tx.  Returns an error if
 // the leaves for the given tx is not in the pool.
 func (mp *TxPool) FetchLeafDatas(txHash *chainhash.Hash) ([]wire.LeafData, error) {
@@ -934,22 +1069,21 @@ func (mp *TxPool) FetchLeafDatas(txHash *chainhash.Hash) ([]wire.LeafData, error
 }
 
 // validateReplacement determines whether a transaction is deemed as a valid
-// replacement of all of its conflicts according to the RBF policy. If it is
+// replacement of the passed conflicts according to the RBF policy. If it is
 // valid, no error is returned. Otherwise, an error is returned indicating what
 // went wrong.
 //
 // This function MUST be called with the mempool lock held (for reads).
-func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
-	txFee int64) (map[chainhash.Hash]*btcutil.Tx, error) {
+func (mp *TxPool) validateReplacement(tx *btcutil.Tx, txFee int64,
+	conflicts map[chainhash.Hash]*btcutil.Tx) error {
 
 	// First, we'll make sure the set of conflicting transactions doesn't
 	// exceed the maximum allowed.
-	conflicts := mp.txConflicts(tx)
 	if len(conflicts) > MaxReplacementEvictions {
 		str := fmt.Sprintf("replacement transaction %v evicts more "+
 			"transactions than permitted: max is %v, evicts %v",
 			tx.Hash(), MaxReplacementEvictions, len(conflicts))
-		return nil, txRuleError(wire.RejectNonstandard, str)
+		return txRuleError(wire.RejectNonstandard, str)
 	}
 
 	// The set of conflicts (transactions we'll replace) and ancestors
@@ -961,12 +1095,16 @@ func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
 		}
 		str := fmt.Sprintf("replacement transaction %v spends parent "+
 			"transaction %v", tx.Hash(), ancestorHash)
-		return nil, txRuleError(wire.RejectInvalid, str)
+		return txRuleError(wire.RejectInvalid, str)
 	}
 
-	// The replacement should have a higher fee rate than each of the
-	// conflicting transactions and a higher absolute fee than the fee sum
-	// of all the conflicting transactions.
+	// The replacement should be mined at a higher fee rate than each of
+	// the conflicting transactions and have a higher absolute fee than the
+	// fee sum of all the conflicting transactions.  The fee rates compared
+	// are the ones of the chunks of the transactions in the linearizations
+	// of their clusters, so that a conflict paid for by its children can't
+	// be replaced for cheap and a replacement paid for by nothing but its
+	// own fee can't displace better paying ones.
 	//
 	// We usually don't want to accept replacements with lower fee rates
 	// than what they replaced as that would lower the fee rate of the next
@@ -974,17 +1112,17 @@ func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
 	// easy-to-reason about way to prevent DoS attacks via replacements.
 	var (
 		txSize           = GetTxVirtualSize(tx)
-		txFeeRate        = txFee * 1000 / txSize
+		txFeeRate        = mp.replacementFeePerKB(tx, txFee, conflicts)
 		conflictsFee     int64
 		conflictsParents = make(map[chainhash.Hash]struct{})
 	)
 	for hash, conflict := range conflicts {
-		if txFeeRate <= mp.pool[hash].FeePerKB {
+		conflictFeeRate := mp.chunkFeePerKB(hash)
+		if txFeeRate <= conflictFeeRate {
 			str := fmt.Sprintf("replacement transaction %v has an "+
 				"insufficient fee rate: needs more than %v, "+
-				"has %v", tx.Hash(), mp.pool[hash].FeePerKB,
-				txFeeRate)
-			return nil, txRuleError(wire.RejectInsufficientFee, str)
+				"has %v", tx.Hash(), conflictFeeRate, txFeeRate)
+			return txRuleError(wire.RejectInsufficientFee, str)
 		}
 
 		conflictsFee += mp.pool[hash].Fee
@@ -1004,7 +1142,7 @@ func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
 		str := fmt.Sprintf("replacement transaction %v has an "+
 			"insufficient absolute fee: needs %v, has %v",
 			tx.Hash(), conflictsFee+minFee, txFee)
-		return nil, txRuleError(wire.RejectInsufficientFee, str)
+		return txRuleError(wire.RejectInsufficientFee, str)
 	}
 
 	// Finally, it should not spend any new unconfirmed outputs, other than
@@ -1021,18 +1159,49 @@ func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
 		str := fmt.Sprintf("replacement transaction spends new "+
 			"unconfirmed input %v not found in conflicting "+
 			"transactions", txIn.PreviousOutPoint)
-		return nil, txRuleError(wire.RejectInvalid, str)
+		return txRuleError(wire.RejectInvalid, str)
 	}
 
-	return conflicts, nil
+	return nil
 }
 
-// maybeAcceptTransaction is the internal function which implements the public
-// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
-// more details.
+// txAcceptance houses the outcome of checking whether a transaction may be
+// accepted into the pool.
+type txAcceptance struct {
+	// missingParents are the parents of an orphan transaction.  The other
+	// fields aren't set when there are any.
+	missingParents []*chainhash.Hash
+
+	// utxoView contains the outputs spent by the transaction.
+	utxoView *blockchain.UtxoViewpoint
+
+	// conflicts are the transactions in the pool that the transaction
+	// replaces.
+	conflicts map[chainhash.Hash]*btcutil.Tx
+
+	// bestHeight is the height of the main chain the transaction was
+	// checked against.
+	bestHeight int32
+
+	// fee is the fee of the transaction and size is its virtual size.
+	fee  int64
+	size int64
+}
+
+// checkTransactionAcceptance checks whether the passed transaction may be
+// accepted into the pool without modifying the pool.  See the comment for
+// MaybeAcceptTransaction for more details.
+//
+// The minimum fee and priority checks are skipped when the check fees flag
+// isn't set.  This is used by ProcessPackage, which checks the fee rate of the
+// package instead.  The package transactions are treated as if they were in
+// the pool when looking up the outputs the transaction spends.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejectDupOrphans bool) ([]*chainhash.Hash, *TxDesc, error) {
+func (mp *TxPool) checkTransactionAcceptance(tx *btcutil.Tx, isNew, rateLimit,
+	rejectDupOrphans, checkFees bool,
+	pkgTxs map[chainhash.Hash]*btcutil.Tx) (*txAcceptance, error) {
+
 	txHash := tx.Hash()
 
 	// If a transaction has witness data, and segwit isn't active yet, If
@@ -1041,7 +1210,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	if tx.MsgTx().HasWitness() {
 		segwitActive, err := mp.cfg.IsDeploymentActive(chaincfg.DeploymentSegwit)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		if !segwitActive {
@@ -1053,7 +1222,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			}
 			str := fmt.Sprintf("transaction %v has witness data, "+
 				"but segwit isn't active yet%s", txHash, simnetHint)
-			return nil, nil, txRuleError(wire.RejectNonstandard, str)
+			return nil, txRuleError(wire.RejectNonstandard, str)
 		}
 	}
 
@@ -1065,7 +1234,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		mp.isOrphanInPool(txHash)) {
 
 		str := fmt.Sprintf("already have transaction %v", txHash)
-		return nil, nil, txRuleError(wire.RejectDuplicate, str)
+		return nil, txRuleError(wire.RejectDuplicate, str)
 	}
 
 	// Perform preliminary sanity checks on the transaction.  This makes
@@ -1074,16 +1243,16 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	err := blockchain.CheckTransactionSanity(tx)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// A standalone transaction must not be a coinbase transaction.
 	if blockchain.IsCoinBase(tx) {
 		str := fmt.Sprintf("transaction %v is an individual coinbase",
 			txHash)
-		return nil, nil, txRuleError(wire.RejectInvalid, str)
+		return nil, txRuleError(wire.RejectInvalid, str)
 	}
 
 	// Get the current height of the main chain.  A standalone transaction
@@ -1098,8 +1267,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	// forbid their acceptance.
 	if !mp.cfg.Policy.AcceptNonStd {
 		err = checkTransactionStandard(tx, nextBlockHeight,
-			medianTimePast, mp.cfg.Policy.MinRelayTxFee,
-			mp.cfg.Policy.MaxTxVersion)
+			medianTimePast, &mp.cfg.Policy)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -1110,7 +1278,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			}
 			str := fmt.Sprintf("transaction %v is not standard: %v",
 				txHash, err)
-			return nil, nil, txRuleError(rejectCode, str)
+			return nil, txRuleError(rejectCode, str)
 		}
 	}
 
@@ -1125,7 +1293,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	// spend data and prevents double spends.
 	isReplacement, err := mp.checkPoolDoubleSpend(tx)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	var utxoView *blockchain.UtxoViewpoint
@@ -1140,7 +1308,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		if err != nil {
 			str := fmt.Sprintf("transaction %v failed the utreexo data verification. %v",
 				txHash, err)
-			return nil, nil, txRuleError(wire.RejectInvalid, str)
+			return nil, utreexoProofError(str)
 		}
 		log.Debugf("VerifyUData passed for tx %s", txHash.String())
 
@@ -1154,9 +1322,25 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		utxoView, err = mp.fetchInputUtxos(tx)
 		if err != nil {
 			if cerr, ok := err.(blockchain.RuleError); ok {
-				return nil, nil, chainRuleError(cerr)
+				return nil, chainRuleError(cerr)
 			}
-			return nil, nil, err
+			return nil, err
+		}
+	}
+
+	// Attempt to populate any missing inputs from the package.
+	for _, txIn := range tx.MsgTx().TxIn {
+		prevOut := &txIn.PreviousOutPoint
+		entry := utxoView.LookupEntry(*prevOut)
+		if entry != nil && !entry.IsSpent() {
+			continue
+		}
+
+		if pkgTx, exists := pkgTxs[prevOut.Hash]; exists {
+			// AddTxOut ignores out of range index values, so it is
+			// safe to call without bounds checking here.
+			utxoView.AddTxOut(pkgTx, prevOut.Index,
+				mining.UnminedHeight)
 		}
 	}
 
@@ -1167,7 +1351,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		prevOut.Index = uint32(txOutIdx)
 		entry := utxoView.LookupEntry(prevOut)
 		if entry != nil && !entry.IsSpent() {
-			return nil, nil, txRuleError(wire.RejectDuplicate,
+			return nil, txRuleError(wire.RejectDuplicate,
 				"transaction already exists")
 		}
 		utxoView.RemoveEntry(prevOut)
@@ -1189,7 +1373,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		}
 	}
 	if len(missingParents) > 0 {
-		return missingParents, nil, nil
+		return &txAcceptance{missingParents: missingParents}, nil
 	}
 
 	// Don't allow the transaction into the mempool unless its sequence
@@ -1198,13 +1382,13 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	sequenceLock, err := mp.cfg.CalcSequenceLock(tx, utxoView)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 	if !blockchain.SequenceLockActive(sequenceLock, nextBlockHeight,
 		medianTimePast) {
-		return nil, nil, txRuleError(wire.RejectNonstandard,
+		return nil, txRuleError(wire.RejectNonstandard,
 			"transaction's sequence locks on inputs not met")
 	}
 
@@ -1216,9 +1400,9 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		utxoView, mp.cfg.ChainParams)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Don't allow transactions with non-standard inputs if the network
@@ -1235,7 +1419,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			}
 			str := fmt.Sprintf("transaction %v has a non-standard "+
 				"input: %v", txHash, err)
-			return nil, nil, txRuleError(rejectCode, str)
+			return nil, txRuleError(rejectCode, str)
 		}
 	}
 
@@ -1252,14 +1436,28 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	sigOpCost, err := blockchain.GetSigOpCost(tx, false, utxoView, true, true)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 	if sigOpCost > mp.cfg.Policy.MaxSigOpCostPerTx {
 		str := fmt.Sprintf("transaction %v sigop cost is too high: %d > %d",
 			txHash, sigOpCost, mp.cfg.Policy.MaxSigOpCostPerTx)
-		return nil, nil, txRuleError(wire.RejectNonstandard, str)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	// Transactions with dust outputs may only be relayed when they pay no
+	// fee and every transaction spending an unconfirmed parent with dust
+	// outputs has to spend all of them.
+	if !mp.cfg.Policy.AcceptNonStd {
+		err := checkEphemeralTx(tx, txFee, mp.cfg.Policy.DustRelayFee,
+			checkFees)
+		if err != nil {
+			return nil, err
+		}
+		if err := mp.checkEphemeralSpends(tx); err != nil {
+			return nil, err
+		}
 	}
 
 	// Don't allow transactions with fees too low to get into a mined block.
@@ -1276,25 +1474,42 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 	serializedSize := GetTxVirtualSize(tx)
 	minFee := calcMinRequiredTxRelayFee(serializedSize,
 		mp.cfg.Policy.MinRelayTxFee)
-	if serializedSize >= (DefaultBlockPrioritySize-1000) && txFee < minFee {
+	if checkFees && serializedSize >= (DefaultBlockPrioritySize-1000) &&
+		txFee < minFee {
+
 		str := fmt.Sprintf("transaction %v has %d fees which is under "+
 			"the required amount of %d", txHash, txFee,
 			minFee)
-		return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+		return nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
+
+	// Once transactions were evicted for the size of the pool, the ones
+	// that pay less than the evicted ones would just be evicted again.
+	if poolMinFee := mp.rollingMinFeeRate(); checkFees && poolMinFee > 0 {
+		requiredFee := calcMinRequiredTxRelayFee(serializedSize,
+			poolMinFee)
+		if txFee < requiredFee {
+			str := fmt.Sprintf("transaction %v has %d fees which is "+
+				"under the mempool minimum fee of %d", txHash,
+				txFee, requiredFee)
+			return nil, txRuleError(wire.RejectInsufficientFee, str)
+		}
 	}
 
 	// Require that free transactions have sufficient priority to be mined
 	// in the next block.  Transactions which are being added back to the
 	// memory pool from blocks that have been disconnected during a reorg
 	// are exempted.
-	if isNew && !mp.cfg.Policy.DisableRelayPriority && txFee < minFee {
+	if checkFees && isNew && !mp.cfg.Policy.DisableRelayPriority &&
+		txFee < minFee {
+
 		currentPriority := mining.CalcPriority(tx.MsgTx(), utxoView,
 			nextBlockHeight)
 		if currentPriority <= mining.MinHighPriority {
 			str := fmt.Sprintf("transaction %v has insufficient "+
 				"priority (%g <= %g)", txHash,
 				currentPriority, mining.MinHighPriority)
-			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, txRuleError(wire.RejectInsufficientFee, str)
 		}
 	}
 
@@ -1312,7 +1527,7 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		if mp.pennyTotal >= mp.cfg.Policy.FreeTxRelayLimit*10*1000 {
 			str := fmt.Sprintf("transaction %v has been rejected "+
 				"by the rate limiter due to low fees", txHash)
-			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+			return nil, txRuleError(wire.RejectInsufficientFee, str)
 		}
 		oldTotal := mp.pennyTotal
 
@@ -1322,16 +1537,42 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			mp.cfg.Policy.FreeTxRelayLimit*10*1000)
 	}
 
-	// If the transaction has any conflicts and we've made it this far, then
-	// we're processing a potential replacement.
+	// The transaction must follow the TRUC policy once its conflicts are
+	// replaced.  A TRUC transaction spending a parent that already has a
+	// child replaces that child, so it's treated as one of the conflicts.
 	var conflicts map[chainhash.Hash]*btcutil.Tx
 	if isReplacement {
-		conflicts, err = mp.validateReplacement(tx, txFee)
+		conflicts = mp.txConflicts(tx)
+	}
+	sibling, err := mp.checkTRUC(tx, serializedSize, conflicts)
+	if err != nil {
+		return nil, err
+	}
+	if sibling != nil {
+		if conflicts == nil {
+			conflicts = make(map[chainhash.Hash]*btcutil.Tx)
+		}
+		conflicts[*sibling.Hash()] = sibling
+	}
+
+	// If the transaction has any conflicts and we've made it this far, then
+	// we're processing a potential replacement.
+	if len(conflicts) > 0 {
+		err = mp.validateReplacement(tx, txFee, conflicts)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 	}
 
+	// The transaction may not grow its cluster beyond the maximum number
+	// of transactions once its conflicts are replaced.
+	if count := mp.clusterCount(tx, conflicts); count > MaxClusterCount {
+		str := fmt.Sprintf("transaction %v would be in a cluster of %d "+
+			"transactions which is over the maximum of %d", txHash,
+			count, MaxClusterCount)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
 	// Verify crypto signatures for each input and reject the transaction if
 	// any don't verify.
 	err = blockchain.ValidateTransactionScripts(tx, utxoView,
@@ -1339,39 +1580,107 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		mp.cfg.HashCache)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
-			return nil, nil, chainRuleError(cerr)
+			return nil, chainRuleError(cerr)
 		}
+		return nil, err
+	}
+	return &txAcceptance{
+		utxoView:   utxoView,
+		conflicts:  conflicts,
+		bestHeight: bestHeight,
+		fee:        txFee,
+		size:       serializedSize,
+	}, nil
+}
+
+// maybeAcceptTransaction is the internal function which implements the public
+// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
+// more details.
+//
+// The minimum fee and priority checks are skipped when the check fees flag
+// isn't set.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejectDupOrphans, checkFees bool) ([]*chainhash.Hash, *TxDesc, error) {
+	acceptance, err := mp.checkTransactionAcceptance(tx, isNew, rateLimit,
+		rejectDupOrphans, checkFees, nil)
+	if err != nil {
 		return nil, nil, err
 	}
+	if len(acceptance.missingParents) > 0 {
+		return acceptance.missingParents, nil, nil
+	}
 
 	// Now that we've deemed the transaction as valid, we can add it to the
 	// mempool. If it ended up replacing any transactions, we'll remove them
 	// first.
-	for _, conflict := range conflicts {
+	var replacedLeaves []wire.LeafData
+	for _, conflict := range acceptance.conflicts {
 		log.Debugf("Replacing transaction %v (fee_rate=%v sat/kb) "+
 			"with %v (fee_rate=%v sat/kb)\n", conflict.Hash(),
 			mp.pool[*conflict.Hash()].FeePerKB, tx.Hash(),
-			txFee*1000/serializedSize)
+			acceptance.fee*1000/acceptance.size)
 
 		// The conflict set should already include the descendants for
 		// each one, so we don't need to remove the redeemers within
 		// this call as they'll be removed eventually.
 		//
 		// Don't remove the cached utreexo proof either because we'll need
-		// it for the ingestion.
-		mp.removeTransaction(conflict, false, false)
+		// it for the ingestion.  The leaves are uncached once the
+		// replacement is added instead.
+		mp.removeTransaction(conflict, false, false, &removal{
+			typ:        NTTxReplaced,
+			replacedBy: tx,
+		})
+		if leaves, ok := mp.poolLeaves[*conflict.Hash()]; ok {
+			replacedLeaves = append(replacedLeaves, leaves...)
+			delete(mp.poolLeaves, *conflict.Hash())
+		}
 	}
-	txD, err := mp.addTransaction(utxoView, tx, bestHeight, txFee)
+	txD, err := mp.addTransaction(acceptance.utxoView, tx,
+		acceptance.bestHeight, acceptance.fee)
+	mp.pruneReplacedLeaves(tx, replacedLeaves)
 	if err != nil {
 		return nil, txD, err
 	}
 
-	log.Debugf("Accepted transaction %v (pool size: %v)", txHash,
+	log.Debugf("Accepted transaction %v (pool size: %v)", tx.Hash(),
 		len(mp.pool))
 
 	return nil, txD, nil
 }
 
+// pruneReplacedLeaves uncaches the leaves of the transactions replaced by the
+// passed transaction from the accumulator, except for the ones of the outputs
+// the transaction spends as well since its proof still needs them.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) pruneReplacedLeaves(tx *btcutil.Tx, leaves []wire.LeafData) {
+	if len(leaves) == 0 {
+		return
+	}
+
+	spent := make(map[wire.OutPoint]struct{}, len(tx.MsgTx().TxIn))
+	for _, txIn := range tx.MsgTx().TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+	unspent := make([]wire.LeafData, 0, len(leaves))
+	for _, leaf := range leaves {
+		if _, ok := spent[leaf.OutPoint]; !ok {
+			unspent = append(unspent, leaf)
+		}
+	}
+	if len(unspent) == 0 {
+		return
+	}
+
+	err := mp.cfg.PruneFromAccumulator(unspent)
+	if err != nil {
+		log.Infof("err while pruning proof for inputs replaced by tx "+
+			"%s: %v", tx.Hash(), err)
+	}
+}
+
 // MaybeAcceptTransaction is the main workhorse for handling insertion of new
 // free-standing transactions into a memory pool.  It includes functionality
 // such as rejecting duplicate transactions, ensuring transactions follow all
@@ -1386,7 +1695,11 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 func (mp *TxPool) MaybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit bool) ([]*chainhash.Hash, *TxDesc, error) {
 	// Protect concurrent access.
 	mp.mtx.Lock()
-	hashes, txD, err := mp.maybeAcceptTransaction(tx, isNew, rateLimit, true)
+	hashes, txD, err := mp.maybeAcceptTransaction(tx, isNew, rateLimit, true,
+		true)
+	if err == nil && txD != nil && len(mp.limitAccepted([]*TxDesc{txD})) == 0 {
+		txD, err = nil, errMempoolFull(tx)
+	}
 	mp.mtx.Unlock()
 
 	return hashes, txD, err
@@ -1429,7 +1742,7 @@ func (mp *TxPool) processOrphans(acceptedTx *btcutil.Tx) []*TxDesc {
 			// Potentially accept an orphan into the tx pool.
 			for _, tx := range orphans {
 				missing, txD, err := mp.maybeAcceptTransaction(
-					tx, true, true, false)
+					tx, true, true, false, true)
 				if err != nil {
 					// The orphan is now invalid, so there
 					// is no way any other orphans which
@@ -1513,12 +1826,19 @@ func (mp *TxPool) ProcessTransaction(tx *btcutil.Tx, allowOrphan, rateLimit bool
 
 	// Potentially accept the transaction to the memory pool.
 	missingParents, txD, err := mp.maybeAcceptTransaction(tx, true, rateLimit,
-		true)
+		true, true)
 	if err != nil {
+		// Remember the relayed transactions that were only rejected for
+		// their fee since a child may pay for them later on.
+		if allowOrphan {
+			mp.maybeAddLowFeeTx(tx, err)
+		}
 		return nil, err
 	}
 
 	if len(missingParents) == 0 {
+		delete(mp.lowFeeTxs, *tx.Hash())
+
 		// Accept any orphan transactions that depend on this
 		// transaction (they may no longer be orphans if all inputs
 		// are now available) and repeat for those accepted
@@ -1531,11 +1851,27 @@ func (mp *TxPool) ProcessTransaction(tx *btcutil.Tx, allowOrphan, rateLimit bool
 		acceptedTxs[0] = txD
 		copy(acceptedTxs[1:], newTxs)
 
+		// The transaction is evicted right away along with the orphans
+		// that spend it when it doesn't pay enough to stay in the pool.
+		acceptedTxs = mp.limitAccepted(acceptedTxs)
+		if len(acceptedTxs) == 0 {
+			return nil, errMempoolFull(tx)
+		}
+
 		return acceptedTxs, nil
 	}
 
-	// The transaction is an orphan (has inputs missing).  Reject
-	// it if the flag to allow orphans is not set.
+	// The transaction is an orphan (has inputs missing).  It may be
+	// accepted along with its parents when they were only rejected for
+	// their fee and it pays for them.
+	if allowOrphan {
+		acceptedTxs := mp.maybeAcceptLowFeeParents(tx, missingParents)
+		if acceptedTxs != nil {
+			return acceptedTxs, nil
+		}
+	}
+
+	// Reject it if the flag to allow orphans is not set.
 	if !allowOrphan {
 		// Only use the first missing parent transaction in
 		// the error message.
@@ -1557,6 +1893,99 @@ func (mp *TxPool) ProcessTransaction(tx *btcutil.Tx, allowOrphan, rateLimit bool
 	return nil, err
 }
 
+// TestAcceptResult describes whether a transaction passed to
+// TestAcceptTransactions would be accepted into the pool.
+type TestAcceptResult struct {
+	// Tx is the checked transaction.
+	Tx *btcutil.Tx
+
+	// Fee is the fee of the transaction in satoshi and FeePerKB is its fee
+	// rate in satoshi per 1000 bytes.  Both are only set when the
+	// transaction would be accepted.
+	Fee      int64
+	FeePerKB int64
+
+	// Err is set to the reason the transaction would be rejected.
+	Err error
+}
+
+// TestAcceptTransactions checks whether the passed transactions would be
+// accepted into the pool without adding them, relaying them, or otherwise
+// modifying the pool.  A transaction may spend the outputs of the transactions
+// before it that would be accepted, but it may not spend the same outputs as
+// them.  Orphan transactions would be rejected.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) TestAcceptTransactions(txs []*btcutil.Tx) []TestAcceptResult {
+	// Protect concurrent access.
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	results := make([]TestAcceptResult, len(txs))
+	pkgTxs := make(map[chainhash.Hash]*btcutil.Tx, len(txs))
+	pkgSpent := make(map[wire.OutPoint]*btcutil.Tx)
+	for i, tx := range txs {
+		results[i].Tx = tx
+		acceptance, err := mp.testAcceptTransaction(tx, pkgTxs, pkgSpent)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Fee = acceptance.fee
+		results[i].FeePerKB = acceptance.fee * 1000 / acceptance.size
+
+		pkgTxs[*tx.Hash()] = tx
+		for _, txIn := range tx.MsgTx().TxIn {
+			pkgSpent[txIn.PreviousOutPoint] = tx
+		}
+	}
+
+	return results
+}
+
+// testAcceptTransaction checks whether the passed transaction would be
+// accepted into the pool along with the transactions that were checked before
+// it.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) testAcceptTransaction(tx *btcutil.Tx,
+	pkgTxs map[chainhash.Hash]*btcutil.Tx,
+	pkgSpent map[wire.OutPoint]*btcutil.Tx) (*txAcceptance, error) {
+
+	if _, exists := pkgTxs[*tx.Hash()]; exists {
+		str := fmt.Sprintf("already have transaction %v", tx.Hash())
+		return nil, txRuleError(wire.RejectDuplicate, str)
+	}
+	for _, txIn := range tx.MsgTx().TxIn {
+		if conflict, exists := pkgSpent[txIn.PreviousOutPoint]; exists {
+			str := fmt.Sprintf("output %v already spent by "+
+				"transaction %v", txIn.PreviousOutPoint,
+				conflict.Hash())
+			return nil, txRuleError(wire.RejectDuplicate, str)
+		}
+	}
+
+	// The proof of the transaction is verified without being remembered
+	// and the rate limiter isn't used, so checking the transaction doesn't
+	// modify the pool.
+	acceptance, err := mp.checkTransactionAcceptance(tx, true, false, true,
+		true, pkgTxs)
+	if err != nil {
+		return nil, err
+	}
+	if len(acceptance.missingParents) > 0 {
+		// NOTE: RejectDuplicate matches ProcessTransaction.  See the
+		// comment there for more details.
+		str := fmt.Sprintf("orphan transaction %v references "+
+			"outputs of unknown or fully-spent "+
+			"transaction %v", tx.Hash(),
+			acceptance.missingParents[0])
+		return nil, txRuleError(wire.RejectDuplicate, str)
+	}
+
+	return acceptance, nil
+}
+
 // Count returns the number of transactions in the main pool.  It does not
 // include the orphan pool.
 //
@@ -1613,8 +2042,10 @@ func (mp *TxPool) MiningDescs() []*mining.TxDesc {
 	mp.mtx.RLock()
 	descs := make([]*mining.TxDesc, len(mp.pool))
 	i := 0
-	for _, desc := range mp.pool {
-		descs[i] = &desc.TxDesc
+	for hash, desc := range mp.pool {
+		miningDesc := desc.TxDesc
+		miningDesc.ChunkFeePerKB = mp.chunkFeePerKB(hash)
+		descs[i] = &miningDesc
 		i++
 	}
 	mp.mtx.RUnlock()
@@ -1671,6 +2102,158 @@ func (mp *TxPool) RawMempoolVerbose() map[string]*btcjson.GetRawMempoolVerboseRe
 	return result
 }
 
+// mempoolEntry returns the memory pool entry of the passed transaction
+// descriptor, which includes the fees and sizes of its unconfirmed ancestors
+// and descendants.  The caches are optional and may be shared between calls to
+// avoid visiting the same transactions more than once.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) mempoolEntry(desc *TxDesc, ancestorCache,
+	descendantCache map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx) *btcjson.GetMempoolEntryResult {
+
+	tx := desc.Tx
+	vsize := GetTxVirtualSize(tx)
+	fee := btcutil.Amount(desc.Fee).ToBTC()
+	entry := &btcjson.GetMempoolEntryResult{
+		VSize:             int32(vsize),
+		Size:              int32(tx.MsgTx().SerializeSize()),
+		Weight:            blockchain.GetTransactionWeight(tx),
+		Fee:               fee,
+		ModifiedFee:       fee,
+		Time:              desc.Added.Unix(),
+		Height:            int64(desc.Height),
+		DescendantCount:   1,
+		DescendantSize:    vsize,
+		AncestorCount:     1,
+		AncestorSize:      vsize,
+		WTxId:             tx.WitnessHash().String(),
+		Depends:           make([]string, 0),
+		SpentBy:           make([]string, 0),
+		BIP125Replaceable: mp.signalsReplacement(tx, nil),
+	}
+
+	// The ancestor and descendant counts, sizes, and fees include the
+	// transaction itself.
+	ancestorFee := desc.Fee
+	for hash, ancestor := range mp.txAncestors(tx, ancestorCache) {
+		entry.AncestorCount++
+		entry.AncestorSize += GetTxVirtualSize(ancestor)
+		ancestorFee += mp.pool[hash].Fee
+	}
+	descendantFee := desc.Fee
+	for hash, descendant := range mp.txDescendants(tx, descendantCache) {
+		entry.DescendantCount++
+		entry.DescendantSize += GetTxVirtualSize(descendant)
+		descendantFee += mp.pool[hash].Fee
+	}
+	entry.AncestorFees = float64(ancestorFee)
+	entry.DescendantFees = float64(descendantFee)
+	entry.Fees = btcjson.MempoolFees{
+		Base:       fee,
+		Modified:   fee,
+		Ancestor:   btcutil.Amount(ancestorFee).ToBTC(),
+		Descendant: btcutil.Amount(descendantFee).ToBTC(),
+	}
+
+	// Add the transactions in the pool that the transaction spends and that
+	// spend it.
+	seen := make(map[chainhash.Hash]struct{})
+	for _, txIn := range tx.MsgTx().TxIn {
+		hash := txIn.PreviousOutPoint.Hash
+		if _, exists := mp.pool[hash]; !exists {
+			continue
+		}
+		if _, exists := seen[hash]; exists {
+			continue
+		}
+		seen[hash] = struct{}{}
+		entry.Depends = append(entry.Depends, hash.String())
+	}
+	op := wire.OutPoint{Hash: *tx.Hash()}
+	for i := range tx.MsgTx().TxOut {
+		op.Index = uint32(i)
+		child, exists := mp.outpoints[op]
+		if !exists {
+			continue
+		}
+		if _, exists := seen[*child.Hash()]; exists {
+			continue
+		}
+		seen[*child.Hash()] = struct{}{}
+		entry.SpentBy = append(entry.SpentBy, child.Hash().String())
+	}
+
+	return entry
+}
+
+// MempoolEntry returns the memory pool entry of the transaction with the
+// passed hash.  An error is returned if the transaction isn't in the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolEntry(txHash *chainhash.Hash) (*btcjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	desc, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+
+	return mp.mempoolEntry(desc, nil, nil), nil
+}
+
+// MempoolAncestors returns the memory pool entries of all of the unconfirmed
+// ancestors of the transaction with the passed hash, keyed by their hashes.  An
+// error is returned if the transaction isn't in the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolAncestors(txHash *chainhash.Hash) (map[string]*btcjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	desc, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+
+	ancestorCache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	descendantCache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	ancestors := mp.txAncestors(desc.Tx, ancestorCache)
+	result := make(map[string]*btcjson.GetMempoolEntryResult, len(ancestors))
+	for hash := range ancestors {
+		result[hash.String()] = mp.mempoolEntry(mp.pool[hash],
+			ancestorCache, descendantCache)
+	}
+
+	return result, nil
+}
+
+// MempoolDescendants returns the memory pool entries of all of the unconfirmed
+// descendants of the transaction with the passed hash, keyed by their hashes.
+// An error is returned if the transaction isn't in the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolDescendants(txHash *chainhash.Hash) (map[string]*btcjson.GetMempoolEntryResult, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	desc, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+
+	ancestorCache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	descendantCache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	descendants := mp.txDescendants(desc.Tx, descendantCache)
+	result := make(map[string]*btcjson.GetMempoolEntryResult, len(descendants))
+	for hash := range descendants {
+		result[hash.String()] = mp.mempoolEntry(mp.pool[hash],
+			ancestorCache, descendantCache)
+	}
+
+	return result, nil
+}
+
 // LastUpdated returns the last time a transaction was added to or removed from
 // the main pool.  It does not include the orphan pool.
 //
@@ -1688,7 +2271,11 @@ func New(cfg *Config) *TxPool {
 		poolLeaves:     make(map[chainhash.Hash][]wire.LeafData),
 		orphans:        make(map[chainhash.Hash]*orphanTx),
 		orphansByPrev:  make(map[wire.OutPoint]map[chainhash.Hash]*btcutil.Tx),
+		lowFeeTxs:      make(map[chainhash.Hash]*btcutil.Tx),
+		clusters:       make(map[chainhash.Hash]*txCluster),
 		nextExpireScan: time.Now().Add(orphanExpireScanInterval),
 		outpoints:      make(map[wire.OutPoint]*btcutil.Tx),
+
+		spentPoolOutputs: make(map[wire.OutPoint]struct{}),
 	}
 }
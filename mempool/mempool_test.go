@@ -6,6 +6,7 @@ package mempool
 
 import (
 	"encoding/hex"
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
@@ -323,6 +324,9 @@ func newPoolHarness(chainParams *chaincfg.Params) (*poolHarness, []spendableOutp
 				MaxOrphanTxSize:      1000,
 				MaxSigOpCostPerTx:    blockchain.MaxBlockSigOpsCost / 4,
 				MinRelayTxFee:        1000, // 1 Satoshi per byte
+				DustRelayFee:         1000,
+				MaxStandardTxWeight:  DefaultMaxStandardTxWeight,
+				MaxDataCarrierSize:   DefaultMaxDataCarrierSize,
 				MaxTxVersion:         1,
 			},
 			ChainParams:      chainParams,
@@ -1186,6 +1190,31 @@ func TestCheckPoolDoubleSpend(t *testing.T) {
 			},
 			isReplacement: true,
 		},
+		{
+			// Transactions that double spend inputs of
+			// transactions that don't signal replacement are valid
+			// when the mempool's policy accepts full replacement.
+			name: "full replacement policy",
+			setup: func(ctx *testContext) *btcutil.Tx {
+				ctx.harness.txPool.cfg.Policy.FullRBF = true
+
+				coinbase := ctx.addCoinbaseTx(1)
+				coinbaseOut := txOutToSpendableOut(coinbase, 0)
+				outs := []spendableOutput{coinbaseOut}
+				ctx.addSignedTx(outs, 1, 0, false, false)
+
+				tx, err := ctx.harness.CreateSignedTx(
+					outs, 2, 0, false,
+				)
+				if err != nil {
+					ctx.t.Fatalf("unable to create "+
+						"transaction: %v", err)
+				}
+
+				return tx
+			},
+			isReplacement: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -1453,7 +1482,8 @@ func TestAncestorsDescendants(t *testing.T) {
 }
 
 // TestRBF tests the different cases required for a transaction to properly
-// replace its conflicts given that they all signal replacement.
+// replace its conflicts given that they all signal replacement or that the
+// policy accepts full replacement.
 func TestRBF(t *testing.T) {
 	t.Parallel()
 
@@ -1530,37 +1560,51 @@ func TestRBF(t *testing.T) {
 			// replaced.
 			name: "exceeds maximum conflicts",
 			setup: func(ctx *testContext) (*btcutil.Tx, []*btcutil.Tx) {
-				const numDescendants = 100
-				coinbaseOuts := make(
-					[]spendableOutput, numDescendants,
-				)
-				for i := 0; i < numDescendants; i++ {
-					tx := ctx.addCoinbaseTx(1)
-					coinbaseOuts[i] = txOutToSpendableOut(tx, 0)
-				}
-				parent := ctx.addSignedTx(
-					coinbaseOuts, numDescendants,
-					defaultFee, true, false,
+				// The descendants are split among two
+				// parents so that neither of their clusters
+				// exceeds the maximum size.
+				const numParents = 2
+				const numDescendants = 50
+				replacementOuts := make(
+					[]spendableOutput, 0, numParents,
 				)
-
-				// We'll then spend each output of the parent
-				// transaction with a distinct transaction.
-				for i := uint32(0); i < numDescendants; i++ {
-					out := txOutToSpendableOut(parent, i)
-					outs := []spendableOutput{out}
-					ctx.addSignedTx(
-						outs, 1, defaultFee, false, false,
+				for p := 0; p < numParents; p++ {
+					coinbaseOuts := make(
+						[]spendableOutput, numDescendants,
+					)
+					for i := 0; i < numDescendants; i++ {
+						tx := ctx.addCoinbaseTx(1)
+						coinbaseOuts[i] = txOutToSpendableOut(tx, 0)
+					}
+					parent := ctx.addSignedTx(
+						coinbaseOuts, numDescendants,
+						defaultFee, true, false,
 					)
+					replacementOuts = append(
+						replacementOuts, coinbaseOuts[0],
+					)
+
+					// We'll then spend each output of the
+					// parent transaction with a distinct
+					// transaction.
+					for i := uint32(0); i < numDescendants; i++ {
+						out := txOutToSpendableOut(parent, i)
+						outs := []spendableOutput{out}
+						ctx.addSignedTx(
+							outs, 1, defaultFee, false, false,
+						)
+					}
 				}
 
 				// We'll then create a replacement transaction
-				// by spending one of the coinbase outputs.
-				// Replacing the original spender of the
-				// coinbase output would evict the maximum
-				// number of transactions from the mempool,
-				// however, so we should reject it.
+				// by spending one of the coinbase outputs of
+				// each parent.  Replacing the original
+				// spenders of the coinbase outputs would evict
+				// more than the maximum number of transactions
+				// from the mempool, however, so we should
+				// reject it.
 				tx, err := ctx.harness.CreateSignedTx(
-					coinbaseOuts[:1], 1, defaultFee, false,
+					replacementOuts, 1, defaultFee, false,
 				)
 				if err != nil {
 					ctx.t.Fatalf("unable to create "+
@@ -1790,6 +1834,42 @@ func TestRBF(t *testing.T) {
 			},
 			err: "",
 		},
+		{
+			// A transaction that doesn't signal replacement can be
+			// replaced along with its descendants if the policy
+			// accepts full replacement.
+			name: "full replacement",
+			setup: func(ctx *testContext) (*btcutil.Tx, []*btcutil.Tx) {
+				ctx.harness.txPool.cfg.Policy.FullRBF = true
+
+				coinbase := ctx.addCoinbaseTx(1)
+				coinbaseOut := txOutToSpendableOut(coinbase, 0)
+				outs := []spendableOutput{coinbaseOut}
+				parent := ctx.addSignedTx(
+					outs, 1, defaultFee, false, false,
+				)
+
+				parentOut := txOutToSpendableOut(parent, 0)
+				outs = []spendableOutput{parentOut}
+				child := ctx.addSignedTx(
+					outs, 1, defaultFee, false, false,
+				)
+
+				// The replacement still has to pay for the
+				// transactions it replaces.
+				outs = []spendableOutput{coinbaseOut}
+				tx, err := ctx.harness.CreateSignedTx(
+					outs, 1, defaultFee*3, false,
+				)
+				if err != nil {
+					ctx.t.Fatalf("unable to create "+
+						"transaction: %v", err)
+				}
+
+				return tx, []*btcutil.Tx{parent, child}
+			},
+			err: "",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -1851,3 +1931,276 @@ func TestRBF(t *testing.T) {
 		}
 	}
 }
+
+// TestTestAcceptTransactions ensures that checking whether transactions would
+// be accepted reports the right outcome without modifying the pool.
+func TestTestAcceptTransactions(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+
+	chainedTxns, err := harness.CreateTxChain(spendableOuts[0], 2)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	doubleSpend, err := harness.CreateSignedTx(spendableOuts, 1, 1000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		txs     []*btcutil.Tx
+		allowed []bool
+	}{
+		{
+			name:    "parent and child",
+			txs:     chainedTxns,
+			allowed: []bool{true, true},
+		},
+		{
+			name:    "orphan",
+			txs:     chainedTxns[1:],
+			allowed: []bool{false},
+		},
+		{
+			name:    "double spend",
+			txs:     []*btcutil.Tx{chainedTxns[0], doubleSpend},
+			allowed: []bool{true, false},
+		},
+		{
+			name:    "duplicate",
+			txs:     []*btcutil.Tx{doubleSpend, doubleSpend},
+			allowed: []bool{true, false},
+		},
+	}
+
+	for _, test := range tests {
+		results := harness.txPool.TestAcceptTransactions(test.txs)
+		for i, result := range results {
+			if (result.Err == nil) != test.allowed[i] {
+				t.Fatalf("%s: unexpected result for transaction "+
+					"%d: %v", test.name, i, result.Err)
+			}
+		}
+	}
+
+	// None of the transactions may have been added to the pool.
+	for _, tx := range append(chainedTxns, doubleSpend) {
+		testPoolMembership(tc, tx, false, false)
+	}
+}
+
+// TestMempoolEntry ensures that the memory pool entries of transactions include
+// the fees and sizes of their unconfirmed ancestors and descendants.
+func TestMempoolEntry(t *testing.T) {
+	t.Parallel()
+
+	harness, outputs, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+
+	// We'll be creating the same chain of unconfirmed transactions as in
+	// TestAncestorsDescendants, where each transaction pays a fee of 1000:
+	//
+	//       B ----
+	//     /        \
+	//   A            E
+	//     \        /
+	//       C -- D
+	a := ctx.addSignedTx(outputs[:1], 2, 1000, false, false)
+	b := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(a, 0)}, 1, 1000, false,
+		false,
+	)
+	c := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(a, 1)}, 1, 1000, true,
+		false,
+	)
+	d := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(c, 0)}, 1, 1000, false,
+		false,
+	)
+	e := ctx.addSignedTx(
+		[]spendableOutput{
+			txOutToSpendableOut(b, 0), txOutToSpendableOut(d, 0),
+		}, 1, 1000, false, false,
+	)
+
+	// C has A as its ancestor and D and E as its descendants.
+	entry, err := harness.txPool.MempoolEntry(c.Hash())
+	if err != nil {
+		t.Fatalf("unable to fetch mempool entry: %v", err)
+	}
+	vsize := GetTxVirtualSize(c)
+	if entry.VSize != int32(vsize) || entry.WTxId != c.WitnessHash().String() {
+		t.Fatalf("unexpected vsize %d or wtxid %v", entry.VSize,
+			entry.WTxId)
+	}
+	if entry.AncestorCount != 2 ||
+		entry.AncestorSize != vsize+GetTxVirtualSize(a) ||
+		entry.AncestorFees != 2000 {
+
+		t.Fatalf("unexpected ancestor count %d, size %d, or fees %v",
+			entry.AncestorCount, entry.AncestorSize,
+			entry.AncestorFees)
+	}
+	descendantSize := vsize + GetTxVirtualSize(d) + GetTxVirtualSize(e)
+	if entry.DescendantCount != 3 ||
+		entry.DescendantSize != descendantSize ||
+		entry.DescendantFees != 3000 {
+
+		t.Fatalf("unexpected descendant count %d, size %d, or fees %v",
+			entry.DescendantCount, entry.DescendantSize,
+			entry.DescendantFees)
+	}
+	if entry.Fees.Base != 0.00001 || entry.Fees.Ancestor != 0.00002 ||
+		entry.Fees.Descendant != 0.00003 {
+
+		t.Fatalf("unexpected fees %+v", entry.Fees)
+	}
+	if len(entry.Depends) != 1 || entry.Depends[0] != a.Hash().String() {
+		t.Fatalf("unexpected depends %v", entry.Depends)
+	}
+	if len(entry.SpentBy) != 1 || entry.SpentBy[0] != d.Hash().String() {
+		t.Fatalf("unexpected spentby %v", entry.SpentBy)
+	}
+	if !entry.BIP125Replaceable {
+		t.Fatalf("expected the entry to signal replacement")
+	}
+
+	// D signals replacement through C, but B doesn't.
+	entry, err = harness.txPool.MempoolEntry(d.Hash())
+	if err != nil {
+		t.Fatalf("unable to fetch mempool entry: %v", err)
+	}
+	if !entry.BIP125Replaceable {
+		t.Fatalf("expected the entry to inherit replacement signaling")
+	}
+	entry, err = harness.txPool.MempoolEntry(b.Hash())
+	if err != nil {
+		t.Fatalf("unable to fetch mempool entry: %v", err)
+	}
+	if entry.BIP125Replaceable {
+		t.Fatalf("expected the entry not to signal replacement")
+	}
+
+	// The ancestors of E and the descendants of A are all of the other
+	// transactions.
+	ancestors, err := harness.txPool.MempoolAncestors(e.Hash())
+	if err != nil {
+		t.Fatalf("unable to fetch mempool ancestors: %v", err)
+	}
+	descendants, err := harness.txPool.MempoolDescendants(a.Hash())
+	if err != nil {
+		t.Fatalf("unable to fetch mempool descendants: %v", err)
+	}
+	for _, tx := range []*btcutil.Tx{a, b, c, d} {
+		if _, ok := ancestors[tx.Hash().String()]; !ok {
+			t.Fatalf("missing ancestor %v", tx.Hash())
+		}
+	}
+	for _, tx := range []*btcutil.Tx{b, c, d, e} {
+		if _, ok := descendants[tx.Hash().String()]; !ok {
+			t.Fatalf("missing descendant %v", tx.Hash())
+		}
+	}
+	if len(ancestors) != 4 || len(descendants) != 4 {
+		t.Fatalf("expected 4 ancestors and descendants, got %d and %d",
+			len(ancestors), len(descendants))
+	}
+	if ancestors[c.Hash().String()].DescendantCount != 3 {
+		t.Fatalf("unexpected descendant count %d of ancestor",
+			ancestors[c.Hash().String()].DescendantCount)
+	}
+
+	// Transactions that aren't in the pool don't have an entry.
+	_, err = harness.txPool.MempoolEntry(&chainhash.Hash{})
+	if err == nil {
+		t.Fatalf("expected an error for a transaction not in the pool")
+	}
+}
+
+// TestPruneReplacedLeaves ensures the leaves of replaced transactions are
+// uncached from the accumulator unless the replacement spends them as well.
+func TestPruneReplacedLeaves(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	var pruned []wire.LeafData
+	harness.txPool.cfg.PruneFromAccumulator = func(leaves []wire.LeafData) error {
+		pruned = append(pruned, leaves...)
+		return nil
+	}
+
+	kept := wire.OutPoint{Hash: chainhash.Hash{1}, Index: 0}
+	replaced := wire.OutPoint{Hash: chainhash.Hash{2}, Index: 1}
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxIn(wire.NewTxIn(&kept, nil, nil))
+	tx := btcutil.NewTx(msgTx)
+
+	// Nothing is pruned when the replacement spends all the leaves.
+	harness.txPool.pruneReplacedLeaves(tx, []wire.LeafData{{OutPoint: kept}})
+	if len(pruned) != 0 {
+		t.Fatalf("expected no pruned leaves, got %d", len(pruned))
+	}
+
+	harness.txPool.pruneReplacedLeaves(tx, []wire.LeafData{
+		{OutPoint: kept}, {OutPoint: replaced},
+	})
+	if len(pruned) != 1 || pruned[0].OutPoint != replaced {
+		t.Fatalf("expected only the leaf of %v to be pruned, got %v",
+			replaced, pruned)
+	}
+}
+
+// TestUtreexoProofError ensures that transactions whose inputs can't be proven
+// with their utreexo data are rejected with an error that tells them apart from
+// invalid transactions.
+func TestUtreexoProofError(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	harness.txPool.cfg.IsUtreexoViewActive = func() bool { return true }
+	harness.txPool.cfg.VerifyUData = func(ud *wire.UData,
+		txIns []*wire.TxIn, remember bool) error {
+
+		return fmt.Errorf("stale proof")
+	}
+
+	tx, err := harness.CreateSignedTx(spendableOuts, 1, 1000, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = harness.txPool.ProcessTransaction(tx, false, false, 0)
+	if !IsUtreexoProofError(err) {
+		t.Fatalf("expected a utreexo proof error, got %v", err)
+	}
+	if code, _ := ErrToRejectErr(err); code != wire.RejectInvalid {
+		t.Fatalf("expected reject code %v, got %v", wire.RejectInvalid,
+			code)
+	}
+
+	// Errors for other rule violations aren't proof errors.
+	harness.txPool.cfg.IsUtreexoViewActive = nil
+	_, err = harness.txPool.ProcessTransaction(tx, false, false, 0)
+	if err != nil {
+		t.Fatalf("unable to accept transaction: %v", err)
+	}
+	_, err = harness.txPool.ProcessTransaction(tx, false, false, 0)
+	if err == nil || IsUtreexoProofError(err) {
+		t.Fatalf("expected a duplicate transaction error, got %v", err)
+	}
+}
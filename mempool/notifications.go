@@ -0,0 +1,130 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexod/btcutil"
+)
+
+// NotificationType represents the type of a notification message.
+type NotificationType int
+
+// NotificationCallback is used for a caller to provide a callback for
+// notifications about the transactions that enter and leave the pool.
+type NotificationCallback func(*Notification)
+
+// Constants for the type of a notification message.
+const (
+	// NTTxAdded indicates the associated transaction was added to the
+	// pool.
+	NTTxAdded NotificationType = iota
+
+	// NTTxRemoved indicates the associated transaction was removed from
+	// the pool without being mined, such as when it expired, was evicted
+	// for the size of the pool, conflicted with a connected block or spent
+	// an output of another transaction that was removed.
+	NTTxRemoved
+
+	// NTTxReplaced indicates the associated transaction was replaced by
+	// another transaction that spends the same outputs.
+	NTTxReplaced
+
+	// NTTxConfirmed indicates the associated transaction was removed from
+	// the pool because it's in a block that was connected to the main
+	// chain.
+	NTTxConfirmed
+)
+
+// notificationTypeStrings is a map of notification types back to their constant
+// names for pretty printing.
+var notificationTypeStrings = map[NotificationType]string{
+	NTTxAdded:     "NTTxAdded",
+	NTTxRemoved:   "NTTxRemoved",
+	NTTxReplaced:  "NTTxReplaced",
+	NTTxConfirmed: "NTTxConfirmed",
+}
+
+// String returns the NotificationType in human-readable form.
+func (n NotificationType) String() string {
+	if s, ok := notificationTypeStrings[n]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Notification Type (%d)", int(n))
+}
+
+// Notification defines the notification that is sent to the subscribers of the
+// pool whenever a transaction enters or leaves it.  The fee and the sizes are
+// the ones the transaction was accepted with so that subscribers don't need to
+// look them up, which isn't possible anymore once the transaction was removed.
+//
+// ReplacedBy is only set for NTTxReplaced notifications and Block is only set
+// for NTTxConfirmed notifications.
+type Notification struct {
+	Type     NotificationType
+	Tx       *btcutil.Tx
+	Fee      int64
+	VSize    int64
+	FeePerKB int64
+
+	ReplacedBy *btcutil.Tx
+	Block      *btcutil.Block
+}
+
+// removal describes why transactions are removed from the pool so that the
+// notifications of the removed transactions can tell the subscribers.
+type removal struct {
+	typ        NotificationType
+	replacedBy *btcutil.Tx
+	block      *btcutil.Block
+}
+
+// removedTx is the removal of transactions that are neither replaced nor
+// confirmed, which also applies to the descendants of every removed
+// transaction.
+var removedTx = &removal{typ: NTTxRemoved}
+
+// Subscribe to mempool notifications.  Registers a callback to be executed when
+// transactions are added to or removed from the pool.  See the documentation on
+// Notification and NotificationType for details on the types and contents of
+// notifications.
+//
+// The callbacks are executed with the mempool lock held so that they're called
+// in the same order as the pool changes, which means they must not call back
+// into the pool and should hand the notifications off rather than block.
+func (mp *TxPool) Subscribe(callback NotificationCallback) {
+	mp.notificationsLock.Lock()
+	mp.notifications = append(mp.notifications, callback)
+	mp.notificationsLock.Unlock()
+}
+
+// sendNotification sends a notification of the passed type for the passed
+// transaction of the pool to all the subscribers.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) sendNotification(txD *TxDesc, r *removal) {
+	mp.notificationsLock.RLock()
+	defer mp.notificationsLock.RUnlock()
+	if len(mp.notifications) == 0 {
+		return
+	}
+
+	n := Notification{
+		Type:     NTTxAdded,
+		Tx:       txD.Tx,
+		Fee:      txD.Fee,
+		VSize:    GetTxVirtualSize(txD.Tx),
+		FeePerKB: txD.FeePerKB,
+	}
+	if r != nil {
+		n.Type = r.typ
+		n.ReplacedBy = r.replacedBy
+		n.Block = r.block
+	}
+	for _, callback := range mp.notifications {
+		callback(&n)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestNotifications ensures that the subscribers of the pool are notified of
+// the transactions that are added, removed, replaced and confirmed along with
+// their fees and sizes.
+func TestNotifications(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	mp := harness.txPool
+
+	var ntfns []Notification
+	mp.Subscribe(func(n *Notification) {
+		ntfns = append(ntfns, *n)
+	})
+	expectNtfns := func(want ...Notification) {
+		t.Helper()
+
+		if len(ntfns) != len(want) {
+			t.Fatalf("expected %d notifications, got %d", len(want),
+				len(ntfns))
+		}
+		for i, n := range ntfns {
+			if n.Type != want[i].Type || n.Tx != want[i].Tx ||
+				n.ReplacedBy != want[i].ReplacedBy ||
+				n.Block != want[i].Block {
+
+				t.Fatalf("unexpected notification %d: got %v of %v, "+
+					"want %v of %v", i, n.Type, n.Tx.Hash(),
+					want[i].Type, want[i].Tx.Hash())
+			}
+		}
+		ntfns = nil
+	}
+
+	coinbase := ctx.addCoinbaseTx(3)
+	parent := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 1000,
+		true, false,
+	)
+	child := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 1000,
+		false, false,
+	)
+	expectNtfns(
+		Notification{Type: NTTxAdded, Tx: parent},
+		Notification{Type: NTTxAdded, Tx: child},
+	)
+
+	// The metadata is the one the transaction was accepted with.
+	desc := mp.pool[*child.Hash()]
+	mp.RemoveTransaction(child, true, true)
+	if len(ntfns) != 1 || ntfns[0].Fee != desc.Fee ||
+		ntfns[0].FeePerKB != desc.FeePerKB ||
+		ntfns[0].VSize != GetTxVirtualSize(child) {
+
+		t.Fatalf("unexpected removal notification %v", ntfns)
+	}
+	expectNtfns(Notification{Type: NTTxRemoved, Tx: child})
+
+	// Replacing the parent notifies of the replacement.
+	replacement := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 5000,
+		false, false,
+	)
+	expectNtfns(
+		Notification{Type: NTTxReplaced, Tx: parent, ReplacedBy: replacement},
+		Notification{Type: NTTxAdded, Tx: replacement},
+	)
+
+	// Connecting a block with the replacement confirms it and removes the
+	// transactions that double spend the block.
+	doubleSpent := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 1000,
+		false, false,
+	)
+	doubleSpend, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 2000, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	ntfns = nil
+
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			coinbase.MsgTx(), replacement.MsgTx(), doubleSpend.MsgTx(),
+		},
+	})
+	block.SetHeight(harness.chain.BestHeight() + 1)
+	mp.RemoveBlockTransactions(block)
+	expectNtfns(
+		Notification{Type: NTTxConfirmed, Tx: replacement, Block: block},
+		Notification{Type: NTTxRemoved, Tx: doubleSpent},
+	)
+}
@@ -0,0 +1,450 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// MaxPackageCount is the maximum number of transactions allowed in a
+	// package.
+	MaxPackageCount = 25
+
+	// MaxPackageWeight is the maximum total weight of the transactions in
+	// a package.
+	MaxPackageWeight = 404000
+
+	// MaxPackageAncestorSize is the maximum total virtual size of the
+	// transactions of a package along with their unconfirmed ancestors in
+	// the pool, the same as the ancestor size limit of Bitcoin Core.
+	MaxPackageAncestorSize = 101000
+
+	// maxLowFeeTxs is the maximum number of relayed transactions that were
+	// rejected for their fee to remember so that a child that pays for
+	// them may get them accepted as a package.
+	maxLowFeeTxs = 100
+)
+
+// PackageTxResult describes the outcome of validating a transaction of a
+// package passed to ProcessPackage.
+type PackageTxResult struct {
+	// Tx is the transaction of the package.
+	Tx *btcutil.Tx
+
+	// TxDesc is the descriptor of the transaction in the pool.  It is nil
+	// when the transaction isn't in the pool.
+	TxDesc *TxDesc
+
+	// AlreadyInPool is set when the transaction was already in the pool
+	// before the package was processed.
+	AlreadyInPool bool
+
+	// EffectiveFeePerKB is the fee rate in satoshi per 1000 bytes that the
+	// transaction was accepted with.  This is the fee rate of the package
+	// for the transactions that didn't pay the minimum fee on their own.
+	EffectiveFeePerKB int64
+
+	// EffectiveIncludes are the transactions whose fees and sizes make up
+	// the effective fee rate.
+	EffectiveIncludes []*btcutil.Tx
+
+	// Err is set to the reason the transaction was rejected.
+	Err error
+}
+
+// checkPackage checks that the passed transactions form a package that may be
+// processed.  The package has to be a child with all of its unconfirmed
+// parents, sorted so that the child comes last, and the parents may not spend
+// each other.
+func checkPackage(txs []*btcutil.Tx) error {
+	if len(txs) == 0 || len(txs) > MaxPackageCount {
+		str := fmt.Sprintf("package must contain between 1 and %d "+
+			"transactions", MaxPackageCount)
+		return txRuleError(wire.RejectInvalid, str)
+	}
+
+	var weight int64
+	inPackage := make(map[chainhash.Hash]int, len(txs))
+	spent := make(map[wire.OutPoint]struct{})
+	for i, tx := range txs {
+		if _, exists := inPackage[*tx.Hash()]; exists {
+			str := fmt.Sprintf("package contains duplicate "+
+				"transaction %v", tx.Hash())
+			return txRuleError(wire.RejectInvalid, str)
+		}
+		inPackage[*tx.Hash()] = i
+
+		for _, txIn := range tx.MsgTx().TxIn {
+			if _, exists := spent[txIn.PreviousOutPoint]; exists {
+				str := fmt.Sprintf("output %v is spent by more "+
+					"than one transaction of the package",
+					txIn.PreviousOutPoint)
+				return txRuleError(wire.RejectInvalid, str)
+			}
+			spent[txIn.PreviousOutPoint] = struct{}{}
+		}
+
+		weight += blockchain.GetTransactionWeight(tx)
+	}
+	if weight > MaxPackageWeight {
+		str := fmt.Sprintf("package weight of %d is over the maximum "+
+			"of %d", weight, MaxPackageWeight)
+		return txRuleError(wire.RejectInvalid, str)
+	}
+
+	// Only the child may spend other transactions of the package, and it
+	// has to spend all of them.
+	childIdx := len(txs) - 1
+	parents := make(map[int]struct{}, childIdx)
+	for i, tx := range txs {
+		for _, txIn := range tx.MsgTx().TxIn {
+			j, exists := inPackage[txIn.PreviousOutPoint.Hash]
+			if !exists {
+				continue
+			}
+			if j >= i {
+				str := fmt.Sprintf("package isn't sorted: "+
+					"transaction %v spends the later "+
+					"transaction %v", tx.Hash(), txs[j].Hash())
+				return txRuleError(wire.RejectInvalid, str)
+			}
+			if i != childIdx {
+				str := fmt.Sprintf("package isn't a child with "+
+					"its parents: parent %v spends parent %v",
+					tx.Hash(), txs[j].Hash())
+				return txRuleError(wire.RejectInvalid, str)
+			}
+			parents[j] = struct{}{}
+		}
+	}
+	for i := 0; i < childIdx; i++ {
+		if _, exists := parents[i]; !exists {
+			str := fmt.Sprintf("package isn't a child with its "+
+				"parents: transaction %v isn't spent by the "+
+				"last transaction", txs[i].Hash())
+			return txRuleError(wire.RejectInvalid, str)
+		}
+	}
+
+	return nil
+}
+
+// rejectPackage removes the transactions of the package that were added to
+// the pool and marks the results that don't have an error yet as rejected
+// along with the package.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) rejectPackage(results []PackageTxResult, added []*TxDesc) {
+	for i := len(added) - 1; i >= 0; i-- {
+		mp.removeTransaction(added[i].Tx, false, true, removedTx)
+	}
+
+	for i := range results {
+		result := &results[i]
+		if result.AlreadyInPool || result.Err != nil {
+			continue
+		}
+
+		str := fmt.Sprintf("transaction %v not accepted since the "+
+			"package was rejected", result.Tx.Hash())
+		result.Err = txRuleError(wire.RejectInvalid, str)
+		result.TxDesc = nil
+		result.EffectiveFeePerKB = 0
+		result.EffectiveIncludes = nil
+	}
+}
+
+// checkPackageLimits checks that the transactions of the package that aren't in
+// the pool yet together with their unconfirmed ancestors don't exceed the
+// maximum count and size of a package.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) checkPackageLimits(results []PackageTxResult) error {
+	var count, size int64
+	ancestors := make(map[chainhash.Hash]*btcutil.Tx)
+	cache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	for i := range results {
+		if results[i].AlreadyInPool {
+			continue
+		}
+		tx := results[i].Tx
+		count++
+		size += GetTxVirtualSize(tx)
+		for hash, ancestor := range mp.txAncestors(tx, cache) {
+			ancestors[hash] = ancestor
+		}
+	}
+	for _, ancestor := range ancestors {
+		count++
+		size += GetTxVirtualSize(ancestor)
+	}
+
+	if count > MaxPackageCount {
+		str := fmt.Sprintf("package has %d transactions with its "+
+			"unconfirmed ancestors which is over the maximum of %d",
+			count, MaxPackageCount)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+	if size > MaxPackageAncestorSize {
+		str := fmt.Sprintf("package has a virtual size of %d with its "+
+			"unconfirmed ancestors which is over the maximum of %d",
+			size, MaxPackageAncestorSize)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+
+	return nil
+}
+
+// maybeAddLowFeeTx remembers a relayed transaction that was rejected with the
+// passed error when it was only rejected for its fee.  A random one is evicted
+// when there are already too many of them.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) maybeAddLowFeeTx(tx *btcutil.Tx, err error) {
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		return
+	}
+	if _, exists := mp.lowFeeTxs[*tx.Hash()]; exists {
+		return
+	}
+	if tx.MsgTx().SerializeSize() > mp.cfg.Policy.MaxOrphanTxSize {
+		return
+	}
+
+	if len(mp.lowFeeTxs) >= maxLowFeeTxs {
+		// Map iteration is pseudo-random so the first entry is a
+		// random one.
+		for hash := range mp.lowFeeTxs {
+			delete(mp.lowFeeTxs, hash)
+			break
+		}
+	}
+	mp.lowFeeTxs[*tx.Hash()] = tx
+}
+
+// maybeAcceptLowFeeParents accepts an orphan transaction as a package with its
+// missing parents when all of them were relayed but rejected for their fee.
+// The accepted transactions are returned, or nil when the package wasn't
+// accepted.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) maybeAcceptLowFeeParents(tx *btcutil.Tx,
+	missingParents []*chainhash.Hash) []*TxDesc {
+
+	txs := make([]*btcutil.Tx, 0, len(missingParents)+1)
+	seen := make(map[chainhash.Hash]struct{}, len(missingParents))
+	for _, hash := range missingParents {
+		if _, ok := seen[*hash]; ok {
+			continue
+		}
+		seen[*hash] = struct{}{}
+
+		parent, ok := mp.lowFeeTxs[*hash]
+		if !ok {
+			return nil
+		}
+		txs = append(txs, parent)
+	}
+	txs = append(txs, tx)
+	if err := checkPackage(txs); err != nil {
+		return nil
+	}
+
+	results, acceptedTxs := mp.processPackage(txs)
+	for _, result := range results {
+		if result.Err != nil {
+			log.Debugf("Rejected transaction %v with its low fee "+
+				"parents: %v", tx.Hash(), result.Err)
+			return nil
+		}
+	}
+	for _, parent := range txs[:len(txs)-1] {
+		delete(mp.lowFeeTxs, *parent.Hash())
+	}
+
+	return acceptedTxs
+}
+
+// ProcessPackage accepts a package of transactions into the pool as a whole.
+// The package must be a child with all of its parents that aren't confirmed
+// yet, where the child comes last and the parents don't spend each other.
+// Unlike ProcessTransaction, parents that don't pay the minimum relay fee on
+// their own are accepted when the fee rate of them together with the child
+// pays it.  Package transactions may not replace transactions in the pool,
+// and together with their unconfirmed ancestors they may not exceed
+// MaxPackageCount transactions or MaxPackageAncestorSize of virtual size.
+//
+// Either all of the transactions that weren't already in the pool are
+// accepted, or none of them are.  The returned error is only set when the
+// package itself isn't valid, otherwise there is a result for each of the
+// transactions in the order of the package and the ones that were rejected
+// have an error set.  The returned descriptors are the transactions that were
+// added to the pool, including any orphans that were accepted as a result.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) ProcessPackage(txs []*btcutil.Tx) ([]PackageTxResult, []*TxDesc, error) {
+	log.Tracef("Processing package of %d transactions", len(txs))
+
+	err := checkPackage(txs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Protect concurrent access.
+	mp.mtx.Lock()
+	results, acceptedTxs := mp.processPackage(txs)
+	mp.mtx.Unlock()
+
+	return results, acceptedTxs, nil
+}
+
+// processPackage is the internal function which implements the public
+// ProcessPackage for a package that was already checked.  See the comment for
+// ProcessPackage for more details.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) processPackage(txs []*btcutil.Tx) ([]PackageTxResult, []*TxDesc) {
+	results := make([]PackageTxResult, len(txs))
+	for i, tx := range txs {
+		results[i].Tx = tx
+		if txD, exists := mp.pool[*tx.Hash()]; exists {
+			results[i].TxDesc = txD
+			results[i].AlreadyInPool = true
+		}
+	}
+
+	// Replaced transactions couldn't be restored if the package is
+	// rejected, so don't allow any package transaction to conflict with the
+	// pool or to evict the sibling of a TRUC parent in the pool.
+	for i, tx := range txs {
+		if results[i].AlreadyInPool {
+			continue
+		}
+		if siblings := mp.trucSiblings(tx, nil); len(siblings) > 0 {
+			str := fmt.Sprintf("transaction %v would evict TRUC "+
+				"sibling %v in the memory pool", tx.Hash(),
+				siblings[0].Hash())
+			results[i].Err = txRuleError(wire.RejectDuplicate, str)
+			mp.rejectPackage(results, nil)
+			return results, nil
+		}
+		for _, txIn := range tx.MsgTx().TxIn {
+			conflict, exists := mp.outpoints[txIn.PreviousOutPoint]
+			if !exists {
+				continue
+			}
+
+			str := fmt.Sprintf("output %v already spent by "+
+				"transaction %v in the memory pool",
+				txIn.PreviousOutPoint, conflict.Hash())
+			results[i].Err = txRuleError(wire.RejectDuplicate, str)
+			mp.rejectPackage(results, nil)
+			return results, nil
+		}
+	}
+
+	childIdx := len(txs) - 1
+	if err := mp.checkPackageLimits(results); err != nil {
+		results[childIdx].Err = err
+		mp.rejectPackage(results, nil)
+		return results, nil
+	}
+
+	// Accept the transactions in order so that the child can spend its
+	// parents.  A transaction that is rejected for its fee on its own is
+	// accepted without the fee checks and its fee is checked as part of the
+	// package below.
+	added := make([]*TxDesc, 0, len(txs))
+	var lowFeeIdxs []int
+	for i, tx := range txs {
+		if results[i].AlreadyInPool {
+			continue
+		}
+
+		missingParents, txD, err := mp.maybeAcceptTransaction(tx, true,
+			false, true, true)
+		if code, _ := extractRejectCode(err); code == wire.RejectInsufficientFee {
+			missingParents, txD, err = mp.maybeAcceptTransaction(tx,
+				true, false, true, false)
+			lowFeeIdxs = append(lowFeeIdxs, i)
+		}
+		if err == nil && len(missingParents) > 0 {
+			str := fmt.Sprintf("transaction %v references outputs "+
+				"of unknown or fully-spent transaction %v",
+				tx.Hash(), missingParents[0])
+			err = txRuleError(wire.RejectDuplicate, str)
+		}
+		if err != nil {
+			results[i].Err = err
+			mp.rejectPackage(results, added)
+			return results, nil
+		}
+
+		results[i].TxDesc = txD
+		results[i].EffectiveFeePerKB = txD.FeePerKB
+		results[i].EffectiveIncludes = []*btcutil.Tx{tx}
+		added = append(added, txD)
+	}
+
+	// The transactions that didn't pay the minimum fee on their own are
+	// paid for by the child, so the fee rate of them together with the
+	// child has to pay the minimum fee.
+	if len(lowFeeIdxs) > 0 {
+		if lowFeeIdxs[len(lowFeeIdxs)-1] != childIdx {
+			lowFeeIdxs = append(lowFeeIdxs, childIdx)
+		}
+
+		var fee, size int64
+		includes := make([]*btcutil.Tx, 0, len(lowFeeIdxs))
+		for _, i := range lowFeeIdxs {
+			fee += results[i].TxDesc.Fee
+			size += GetTxVirtualSize(txs[i])
+			includes = append(includes, txs[i])
+		}
+
+		minFee := calcMinRequiredTxRelayFee(size, mp.minFeeRate())
+		if fee < minFee {
+			str := fmt.Sprintf("package has %d fees which is under "+
+				"the required amount of %d", fee, minFee)
+			results[childIdx].Err = txRuleError(
+				wire.RejectInsufficientFee, str)
+			mp.rejectPackage(results, added)
+			return results, nil
+		}
+
+		for _, i := range lowFeeIdxs {
+			results[i].EffectiveFeePerKB = fee * 1000 / size
+			results[i].EffectiveIncludes = includes
+		}
+	}
+
+	// Accept any orphans that depend on the package now that all of its
+	// transactions are in the pool.
+	acceptedTxs := make([]*TxDesc, 0, len(added))
+	for _, txD := range added {
+		acceptedTxs = append(acceptedTxs, txD)
+		acceptedTxs = append(acceptedTxs, mp.processOrphans(txD.Tx)...)
+	}
+
+	// The package is evicted right away when it doesn't pay enough to stay
+	// in the pool.
+	acceptedTxs = mp.limitAccepted(acceptedTxs)
+	for i, tx := range txs {
+		if !mp.isTransactionInPool(tx.Hash()) {
+			results[i].Err = errMempoolFull(tx)
+		}
+	}
+
+	log.Debugf("Accepted package of %d transactions (pool size: %v)",
+		len(txs), len(mp.pool))
+
+	return results, acceptedTxs
+}
@@ -0,0 +1,340 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestProcessPackage ensures that packages are accepted or rejected as a whole
+// and that a child can pay for a parent that doesn't pay the minimum fee.
+func TestProcessPackage(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	harness.txPool.cfg.Policy.DisableRelayPriority = false
+	ctx := &testContext{t, harness}
+
+	// Fund the package with outputs that were just confirmed so that
+	// transactions spending them without a fee don't have enough priority
+	// to be accepted.
+	coinbase := ctx.addCoinbaseTx(1)
+	coinbaseOut := txOutToSpendableOut(coinbase, 0)
+	funding := ctx.addSignedTx(
+		[]spendableOutput{coinbaseOut}, 2, 0, false, true,
+	)
+
+	parent, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(funding, 0)}, 1, 0, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create parent transaction: %v", err)
+	}
+	_, err = harness.txPool.ProcessTransaction(parent, false, false, 0)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("expected the parent to be rejected for its fee, got %v",
+			err)
+	}
+
+	// A child without a fee can't pay for its parent, so neither of them
+	// may be accepted.
+	freeChild, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 0, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create child transaction: %v", err)
+	}
+	results, accepted, err := harness.txPool.ProcessPackage(
+		[]*btcutil.Tx{parent, freeChild},
+	)
+	if err != nil {
+		t.Fatalf("unexpected package error: %v", err)
+	}
+	if len(accepted) != 0 {
+		t.Fatalf("expected no accepted transactions, got %d",
+			len(accepted))
+	}
+	for i, result := range results {
+		if result.Err == nil {
+			t.Fatalf("expected transaction %d to be rejected", i)
+		}
+	}
+	testPoolMembership(ctx, parent, false, false)
+	testPoolMembership(ctx, freeChild, false, false)
+
+	// A child with a fee that pays for both transactions gets both of
+	// them accepted at the fee rate of the package.
+	child, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 10000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create child transaction: %v", err)
+	}
+	results, accepted, err = harness.txPool.ProcessPackage(
+		[]*btcutil.Tx{parent, child},
+	)
+	if err != nil {
+		t.Fatalf("unexpected package error: %v", err)
+	}
+	if len(accepted) != 2 {
+		t.Fatalf("expected 2 accepted transactions, got %d",
+			len(accepted))
+	}
+	size := GetTxVirtualSize(parent) + GetTxVirtualSize(child)
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("unexpected error for transaction %d: %v", i,
+				result.Err)
+		}
+		if result.EffectiveFeePerKB != 10000*1000/size {
+			t.Fatalf("expected an effective fee rate of %d for "+
+				"transaction %d, got %d", 10000*1000/size, i,
+				result.EffectiveFeePerKB)
+		}
+		if len(result.EffectiveIncludes) != 2 {
+			t.Fatalf("expected the effective fee rate of "+
+				"transaction %d to include 2 transactions, got %d",
+				i, len(result.EffectiveIncludes))
+		}
+	}
+	testPoolMembership(ctx, parent, false, true)
+	testPoolMembership(ctx, child, false, true)
+
+	// Submitting the package again reports both as already in the pool.
+	results, _, err = harness.txPool.ProcessPackage(
+		[]*btcutil.Tx{parent, child},
+	)
+	if err != nil {
+		t.Fatalf("unexpected package error: %v", err)
+	}
+	for i, result := range results {
+		if !result.AlreadyInPool || result.Err != nil {
+			t.Fatalf("expected transaction %d to already be in "+
+				"the pool", i)
+		}
+	}
+
+	// A package that conflicts with the pool is rejected without
+	// replacing anything.
+	conflict, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(funding, 0)}, 1, 20000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create conflicting transaction: %v", err)
+	}
+	results, _, err = harness.txPool.ProcessPackage(
+		[]*btcutil.Tx{conflict},
+	)
+	if err != nil {
+		t.Fatalf("unexpected package error: %v", err)
+	}
+	if results[0].Err == nil {
+		t.Fatalf("expected the conflicting transaction to be rejected")
+	}
+	testPoolMembership(ctx, conflict, false, false)
+	testPoolMembership(ctx, parent, false, true)
+}
+
+// TestLowFeeParents ensures that a relayed parent that was rejected for its fee
+// is accepted together with a relayed child that pays for it.
+func TestLowFeeParents(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	harness.txPool.cfg.Policy.DisableRelayPriority = false
+	ctx := &testContext{t, harness}
+
+	coinbase := ctx.addCoinbaseTx(1)
+	coinbaseOut := txOutToSpendableOut(coinbase, 0)
+	funding := ctx.addSignedTx(
+		[]spendableOutput{coinbaseOut}, 2, 0, false, true,
+	)
+
+	parent, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(funding, 0)}, 1, 0, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create parent transaction: %v", err)
+	}
+	_, err = harness.txPool.ProcessTransaction(parent, true, false, 0)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("expected the parent to be rejected for its fee, got %v",
+			err)
+	}
+	if _, ok := harness.txPool.lowFeeTxs[*parent.Hash()]; !ok {
+		t.Fatalf("expected the parent to be remembered")
+	}
+
+	// A child without a fee is an orphan since it can't pay for its
+	// parent.
+	freeChild, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 0, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create child transaction: %v", err)
+	}
+	accepted, err := harness.txPool.ProcessTransaction(freeChild, true,
+		false, 0)
+	if err != nil {
+		t.Fatalf("unexpected error for the free child: %v", err)
+	}
+	if len(accepted) != 0 {
+		t.Fatalf("expected no accepted transactions, got %d",
+			len(accepted))
+	}
+	testPoolMembership(ctx, parent, false, false)
+	testPoolMembership(ctx, freeChild, true, false)
+
+	// A child that pays for both gets its parent accepted.
+	child, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 10000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create child transaction: %v", err)
+	}
+	accepted, err = harness.txPool.ProcessTransaction(child, true, false, 0)
+	if err != nil {
+		t.Fatalf("unexpected error for the child: %v", err)
+	}
+	if len(accepted) != 2 {
+		t.Fatalf("expected 2 accepted transactions, got %d",
+			len(accepted))
+	}
+	testPoolMembership(ctx, parent, false, true)
+	testPoolMembership(ctx, child, false, true)
+	if _, ok := harness.txPool.lowFeeTxs[*parent.Hash()]; ok {
+		t.Fatalf("expected the accepted parent to be forgotten")
+	}
+}
+
+// TestPackageLimits ensures that packages which exceed the maximum count of
+// transactions with their unconfirmed ancestors are rejected.
+func TestPackageLimits(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+
+	chainedTxns, err := harness.CreateTxChain(spendableOuts[0],
+		MaxPackageCount+1)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for _, tx := range chainedTxns[:MaxPackageCount] {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, 0)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+
+	last := chainedTxns[MaxPackageCount]
+	results, _, err := harness.txPool.ProcessPackage([]*btcutil.Tx{last})
+	if err != nil {
+		t.Fatalf("unexpected package error: %v", err)
+	}
+	code, _ := extractRejectCode(results[0].Err)
+	if code != wire.RejectNonstandard {
+		t.Fatalf("expected the package to be rejected for its "+
+			"ancestors, got %v", results[0].Err)
+	}
+	testPoolMembership(ctx, last, false, false)
+
+	// The same transaction is accepted on its own since the limits only
+	// apply to packages.
+	_, err = harness.txPool.ProcessTransaction(last, false, false, 0)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+	}
+	testPoolMembership(ctx, last, false, true)
+}
+
+// TestCheckPackage ensures that packages which aren't a child with its parents
+// sorted in order are rejected.
+func TestCheckPackage(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+
+	chain, err := harness.CreateTxChain(spendableOuts[0], 3)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	unrelated, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(chain[0], 0)}, 1, 0, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		txs   []*btcutil.Tx
+		valid bool
+	}{
+		{
+			name:  "single transaction",
+			txs:   chain[:1],
+			valid: true,
+		},
+		{
+			name:  "parent and child",
+			txs:   chain[:2],
+			valid: true,
+		},
+		{
+			name: "empty package",
+		},
+		{
+			name: "child before parent",
+			txs:  []*btcutil.Tx{chain[1], chain[0]},
+		},
+		{
+			name: "parents depend on each other",
+			txs:  chain,
+		},
+		{
+			name: "duplicate transaction",
+			txs:  []*btcutil.Tx{chain[0], chain[0]},
+		},
+		{
+			name: "parents conflict",
+			txs:  []*btcutil.Tx{chain[0], chain[1], unrelated},
+		},
+		{
+			name: "parent not spent by the child",
+			txs:  []*btcutil.Tx{chain[2], chain[0]},
+		},
+	}
+
+	for _, test := range tests {
+		err := checkPackage(test.txs)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
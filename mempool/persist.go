@@ -0,0 +1,172 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// dumpVersion is the version of the serialization format of the
+	// transactions written by Dump.
+	dumpVersion = 1
+
+	// dumpFlagUtreexo is set in the flags of a dump when every transaction
+	// is followed by the utreexo data that proves its inputs.
+	dumpFlagUtreexo = 1 << 0
+)
+
+// -----------------------------------------------------------------------------
+// The transactions in the pool are dumped in the order they were added so that
+// parents always come before their children.
+//
+// The serialized format is:
+//
+//   <version><flags><count>[<tx>...]
+//
+//   Field     Type      Size
+//   version   uint32    4 bytes
+//   flags     uint8     1 byte
+//   count     varint    variable
+//   tx        MsgTx     variable
+//
+// The transactions are serialized with the witness encoding, along with the
+// compact utreexo data of the wire encoding when the utreexo flag is set.
+// -----------------------------------------------------------------------------
+
+// Dump serializes the transactions in the pool to w so that they can be loaded
+// back with Load after a restart.  When the utreexo view is active, each
+// transaction is dumped with a proof of its inputs against the current
+// accumulator since the node has no utxo set to revalidate them with.  The
+// number of dumped transactions is returned.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Dump(w io.Writer) (int, error) {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	descs := make([]*TxDesc, 0, len(mp.pool))
+	for _, desc := range mp.pool {
+		descs = append(descs, desc)
+	}
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].Added.Before(descs[j].Added)
+	})
+
+	var flags uint8
+	enc := wire.WitnessEncoding
+	utreexoActive := mp.cfg.IsUtreexoViewActive != nil &&
+		mp.cfg.IsUtreexoViewActive()
+	if utreexoActive {
+		flags |= dumpFlagUtreexo
+		enc |= wire.UtreexoEncoding
+	}
+
+	var header [5]byte
+	binary.LittleEndian.PutUint32(header[:4], dumpVersion)
+	header[4] = flags
+	if _, err := w.Write(header[:]); err != nil {
+		return 0, err
+	}
+	err := wire.WriteVarInt(w, 0, uint64(len(descs)))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, desc := range descs {
+		// Encode a copy of the transaction so that the utreexo data
+		// isn't attached to the one in the pool.
+		msgTx := *desc.Tx.MsgTx()
+		if utreexoActive {
+			leaves := mp.poolLeaves[*desc.Tx.Hash()]
+			ud, err := mp.cfg.GenerateUData(leaves)
+			if err != nil {
+				return 0, fmt.Errorf("unable to prove the inputs "+
+					"of transaction %v: %v", desc.Tx.Hash(), err)
+			}
+			msgTx.UData = ud
+		}
+
+		err := msgTx.BtcEncode(w, 0, enc)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(descs), nil
+}
+
+// Load reads the transactions dumped by Dump from r and processes them as if
+// they were relayed again so that they're revalidated against the current
+// tip.  It returns the number of them that were accepted to the pool and the
+// number that were rejected.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Load(r io.Reader) (int, int, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, err
+	}
+	version := binary.LittleEndian.Uint32(header[:4])
+	if version != dumpVersion {
+		return 0, 0, fmt.Errorf("unsupported mempool dump version %d",
+			version)
+	}
+	flags := header[4]
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	enc := wire.WitnessEncoding
+	hasUData := flags&dumpFlagUtreexo == dumpFlagUtreexo
+	if hasUData {
+		enc |= wire.UtreexoEncoding
+	}
+	utreexoActive := mp.cfg.IsUtreexoViewActive != nil &&
+		mp.cfg.IsUtreexoViewActive()
+	if utreexoActive && !hasUData && count > 0 {
+		return 0, 0, fmt.Errorf("the mempool was dumped without the " +
+			"utreexo data needed to revalidate it")
+	}
+
+	txs := make([]*btcutil.Tx, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var msgTx wire.MsgTx
+		err := msgTx.BtcDecode(r, 0, enc)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !utreexoActive {
+			msgTx.UData = nil
+		}
+		txs = append(txs, btcutil.NewTx(&msgTx))
+	}
+
+	// Orphans are allowed so that parents which were only accepted along
+	// with their children get accepted as packages again.
+	for _, tx := range txs {
+		_, err := mp.ProcessTransaction(tx, true, false, 0)
+		if err != nil {
+			log.Debugf("Rejected loaded transaction %v: %v",
+				tx.Hash(), err)
+		}
+	}
+
+	var accepted int
+	for _, tx := range txs {
+		if mp.IsTransactionInPool(tx.Hash()) {
+			accepted++
+		}
+	}
+
+	return accepted, len(txs) - accepted, nil
+}
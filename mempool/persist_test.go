@@ -0,0 +1,82 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg"
+)
+
+// TestDumpLoad ensures that the transactions dumped from the pool are loaded
+// back into a new pool and that the ones that are no longer valid are
+// rejected.
+func TestDumpLoad(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+
+	chainedTxns, err := harness.CreateTxChain(spendableOuts[0], 3)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for _, tx := range chainedTxns {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, 0)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+
+	var buf bytes.Buffer
+	count, err := harness.txPool.Dump(&buf)
+	if err != nil {
+		t.Fatalf("Dump: unexpected error: %v", err)
+	}
+	if count != len(chainedTxns) {
+		t.Fatalf("expected %d dumped transactions, got %d",
+			len(chainedTxns), count)
+	}
+	dump := buf.Bytes()
+
+	// The transactions are all accepted again into an empty pool.
+	harness.txPool = New(&harness.txPool.cfg)
+	accepted, rejected, err := harness.txPool.Load(bytes.NewReader(dump))
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if accepted != len(chainedTxns) || rejected != 0 {
+		t.Fatalf("expected %d accepted and 0 rejected transactions, "+
+			"got %d and %d", len(chainedTxns), accepted, rejected)
+	}
+	for _, tx := range chainedTxns {
+		testPoolMembership(ctx, tx, false, true)
+	}
+
+	// None of them are accepted once the output the chain spends is spent
+	// by the chain.
+	harness.txPool = New(&harness.txPool.cfg)
+	harness.chain.utxos.LookupEntry(spendableOuts[0].outPoint).Spend()
+	accepted, rejected, err = harness.txPool.Load(bytes.NewReader(dump))
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if accepted != 0 || rejected != len(chainedTxns) {
+		t.Fatalf("expected 0 accepted and %d rejected transactions, "+
+			"got %d and %d", len(chainedTxns), accepted, rejected)
+	}
+
+	// Dumps of an unknown version are rejected.
+	dump[0]++
+	_, _, err = harness.txPool.Load(bytes.NewReader(dump))
+	if err == nil {
+		t.Fatalf("Load: expected an error for an unknown version")
+	}
+}
@@ -19,9 +19,13 @@ const (
 	// that are considered standard in a pay-to-script-hash script.
 	maxStandardP2SHSigOps = 15
 
-	// maxStandardTxCost is the max weight permitted by any transaction
-	// according to the current default policy.
-	maxStandardTxWeight = 400000
+	// DefaultMaxStandardTxWeight is the default max weight permitted by any
+	// transaction according to the standardness policy.
+	DefaultMaxStandardTxWeight = 400000
+
+	// DefaultMaxDataCarrierSize is the default maximum number of bytes of
+	// data that a standard null data output may carry.
+	DefaultMaxDataCarrierSize = txscript.MaxDataCarrierSize
 
 	// maxStandardSigScriptSize is the maximum size allowed for a
 	// transaction input signature script to be considered standard.  This
@@ -112,6 +116,13 @@ func checkInputsStandard(tx *btcutil.Tx, utxoView *blockchain.UtxoViewpoint) err
 				return txRuleError(wire.RejectNonstandard, str)
 			}
 
+		case txscript.PayToAnchorTy:
+			if len(txIn.Witness) != 0 {
+				str := fmt.Sprintf("transaction input #%d spends "+
+					"an anchor with a non-empty witness", i)
+				return txRuleError(wire.RejectNonstandard, str)
+			}
+
 		case txscript.NonStandardTy:
 			str := fmt.Sprintf("transaction input #%d has a "+
 				"non-standard script form", i)
@@ -127,9 +138,16 @@ func checkInputsStandard(tx *btcutil.Tx, utxoView *blockchain.UtxoViewpoint) err
 // A standard public key script is one that is a recognized form, and for
 // multi-signature scripts, only contains from 1 to maxStandardMultiSigKeys
 // public keys.
-func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass) error {
+func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass,
+	rejectBareMultisig bool) error {
+
 	switch scriptClass {
 	case txscript.MultiSigTy:
+		if rejectBareMultisig {
+			return txRuleError(wire.RejectNonstandard,
+				"bare multi-signature script")
+		}
+
 		numPubKeys, numSigs, err := txscript.CalcMultiSigStats(pkScript)
 		if err != nil {
 			str := fmt.Sprintf("multi-signature script parse "+
@@ -273,13 +291,14 @@ func IsDust(txOut *wire.TxOut, minRelayTxFee btcutil.Amount) bool {
 // conforms to several additional limiting cases over what is considered a
 // "sane" transaction such as having a version in the supported range, being
 // finalized, conforming to more stringent size constraints, having scripts
-// of recognized forms, and not containing "dust" outputs (those that are
-// so small it costs more to process them than they are worth).
+// of recognized forms, and not containing more than MaxDustOutputsPerTx "dust"
+// outputs (those that are so small it costs more to process them than they are
+// worth).  The limits are taken from the passed policy.
 func checkTransactionStandard(tx *btcutil.Tx, height int32,
-	medianTimePast time.Time, minRelayTxFee btcutil.Amount,
-	maxTxVersion int32) error {
+	medianTimePast time.Time, policy *Policy) error {
 
 	// The transaction must be a currently supported version.
+	maxTxVersion := policy.MaxTxVersion
 	msgTx := tx.MsgTx()
 	if msgTx.Version > maxTxVersion || msgTx.Version < 1 {
 		str := fmt.Sprintf("transaction version %d is not in the "+
@@ -300,9 +319,9 @@ func checkTransactionStandard(tx *btcutil.Tx, height int32,
 	// size of a transaction.  This also helps mitigate CPU exhaustion
 	// attacks.
 	txWeight := blockchain.GetTransactionWeight(tx)
-	if txWeight > maxStandardTxWeight {
+	if txWeight > policy.MaxStandardTxWeight {
 		str := fmt.Sprintf("weight of transaction %v is larger than max "+
-			"allowed weight of %v", txWeight, maxStandardTxWeight)
+			"allowed weight of %v", txWeight, policy.MaxStandardTxWeight)
 		return txRuleError(wire.RejectNonstandard, str)
 	}
 
@@ -328,12 +347,33 @@ func checkTransactionStandard(tx *btcutil.Tx, height int32,
 		}
 	}
 
-	// None of the output public key scripts can be a non-standard script or
-	// be "dust" (except when the script is a null data script).
-	numNullDataOutputs := 0
+	// None of the output public key scripts can be a non-standard script and
+	// at most MaxDustOutputsPerTx of them can be "dust" (except when the
+	// script is a null data script).
+	numNullDataOutputs, numDustOutputs := 0, 0
 	for i, txOut := range msgTx.TxOut {
+		// Null data outputs may carry at most the configured amount of
+		// data, if any.
+		if size, ok := dataCarrierSize(txOut.PkScript); ok {
+			if policy.RejectDataCarrier {
+				str := fmt.Sprintf("transaction output %d: null "+
+					"data outputs are not relayed", i)
+				return txRuleError(wire.RejectNonstandard, str)
+			}
+			if size > policy.MaxDataCarrierSize {
+				str := fmt.Sprintf("transaction output %d: null "+
+					"data of %d bytes is more than the max "+
+					"allowed size of %d bytes", i, size,
+					policy.MaxDataCarrierSize)
+				return txRuleError(wire.RejectNonstandard, str)
+			}
+			numNullDataOutputs++
+			continue
+		}
+
 		scriptClass := txscript.GetScriptClass(txOut.PkScript)
-		err := checkPkScriptStandard(txOut.PkScript, scriptClass)
+		err := checkPkScriptStandard(txOut.PkScript, scriptClass,
+			policy.RejectBareMultisig)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -346,15 +386,17 @@ func checkTransactionStandard(tx *btcutil.Tx, height int32,
 			return txRuleError(rejectCode, str)
 		}
 
-		// Accumulate the number of outputs which only carry data.  For
-		// all other script types, ensure the output value is not
-		// "dust".
-		if scriptClass == txscript.NullDataTy {
-			numNullDataOutputs++
-		} else if IsDust(txOut, minRelayTxFee) {
-			str := fmt.Sprintf("transaction output %d: payment "+
-				"of %d is dust", i, txOut.Value)
-			return txRuleError(wire.RejectDust, str)
+		// Count the outputs whose value is "dust".
+		if IsDust(txOut, policy.DustRelayFee) {
+			numDustOutputs++
+			if numDustOutputs > MaxDustOutputsPerTx {
+				str := fmt.Sprintf("transaction output %d: "+
+					"payment of %d is dust and the "+
+					"transaction has more than %d dust "+
+					"outputs", i, txOut.Value,
+					MaxDustOutputsPerTx)
+				return txRuleError(wire.RejectDust, str)
+			}
 		}
 	}
 
@@ -368,6 +410,30 @@ func checkTransactionStandard(tx *btcutil.Tx, height int32,
 	return nil
 }
 
+// dataCarrierSize returns the number of bytes of data that the passed null data
+// script, an OP_RETURN followed by at most one data push, carries.  Unlike the
+// null data scripts recognized by txscript, the size of the data isn't limited
+// so that it can be checked against the configured maximum.  The boolean is
+// false if the script isn't a null data script.
+func dataCarrierSize(pkScript []byte) (int, bool) {
+	if len(pkScript) < 1 || pkScript[0] != txscript.OP_RETURN {
+		return 0, false
+	}
+	if len(pkScript) == 1 {
+		return 0, true
+	}
+
+	tokenizer := txscript.MakeScriptTokenizer(0, pkScript[1:])
+	if !tokenizer.Next() || !tokenizer.Done() {
+		return 0, false
+	}
+	op := tokenizer.Opcode()
+	if op > txscript.OP_PUSHDATA4 && (op < txscript.OP_1 || op > txscript.OP_16) {
+		return 0, false
+	}
+	return len(tokenizer.Data()), true
+}
+
 // GetTxVirtualSize computes the virtual size of a given transaction. A
 // transaction's virtual size is based off its weight, creating a discount for
 // any witness data it contains, proportional to the current
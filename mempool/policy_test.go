@@ -41,13 +41,13 @@ func TestCalcMinRequiredTxRelayFee(t *testing.T) {
 		},
 		{
 			"max standard tx size with default minimum relay fee",
-			maxStandardTxWeight / 4,
+			DefaultMaxStandardTxWeight / 4,
 			DefaultMinRelayTxFee,
 			100000,
 		},
 		{
 			"max standard tx size with max satoshi relay fee",
-			maxStandardTxWeight / 4,
+			DefaultMaxStandardTxWeight / 4,
 			btcutil.MaxSatoshi,
 			btcutil.MaxSatoshi,
 		},
@@ -193,7 +193,7 @@ func TestCheckPkScriptStandard(t *testing.T) {
 			continue
 		}
 		scriptClass := txscript.GetScriptClass(script)
-		got := checkPkScriptStandard(script, scriptClass)
+		got := checkPkScriptStandard(script, scriptClass, false)
 		if (test.isStandard && got != nil) ||
 			(!test.isStandard && got == nil) {
 
@@ -201,6 +201,15 @@ func TestCheckPkScriptStandard(t *testing.T) {
 				test.name)
 			return
 		}
+
+		// Bare multi-signature scripts are nonstandard when rejected
+		// by policy.
+		got = checkPkScriptStandard(script, scriptClass, true)
+		if scriptClass == txscript.MultiSigTy && got == nil {
+			t.Fatalf("TestCheckPkScriptStandard test '%s' failed "+
+				"to reject bare multisig", test.name)
+			return
+		}
 	}
 }
 
@@ -310,6 +319,7 @@ func TestCheckTransactionStandard(t *testing.T) {
 		name       string
 		tx         wire.MsgTx
 		height     int32
+		policy     func(*Policy)
 		isStandard bool
 		code       wire.RejectCode
 	}{
@@ -360,7 +370,7 @@ func TestCheckTransactionStandard(t *testing.T) {
 				TxOut: []*wire.TxOut{{
 					Value: 0,
 					PkScript: bytes.Repeat([]byte{0x00},
-						(maxStandardTxWeight/4)+1),
+						(DefaultMaxStandardTxWeight/4)+1),
 				}},
 				LockTime: 0,
 			},
@@ -436,13 +446,30 @@ func TestCheckTransactionStandard(t *testing.T) {
 			code:       wire.RejectNonstandard,
 		},
 		{
-			name: "Dust output",
+			name: "One dust output (standard)",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    0,
+					PkScript: dummyPkScript,
+				}},
+				LockTime: 0,
+			},
+			height:     300000,
+			isStandard: true,
+		},
+		{
+			name: "More than one dust output",
 			tx: wire.MsgTx{
 				Version: 1,
 				TxIn:    []*wire.TxIn{&dummyTxIn},
 				TxOut: []*wire.TxOut{{
 					Value:    0,
 					PkScript: dummyPkScript,
+				}, {
+					Value:    0,
+					PkScript: dummyPkScript,
 				}},
 				LockTime: 0,
 			},
@@ -464,13 +491,115 @@ func TestCheckTransactionStandard(t *testing.T) {
 			height:     300000,
 			isStandard: true,
 		},
+		{
+			name: "Nulldata output when data carriers are rejected",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    0,
+					PkScript: []byte{txscript.OP_RETURN},
+				}},
+				LockTime: 0,
+			},
+			height: 300000,
+			policy: func(p *Policy) {
+				p.RejectDataCarrier = true
+			},
+			isStandard: false,
+			code:       wire.RejectNonstandard,
+		},
+		{
+			name: "Nulldata output over the data carrier size",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value: 0,
+					PkScript: []byte{txscript.OP_RETURN,
+						txscript.OP_DATA_2, 0x01, 0x02},
+				}},
+				LockTime: 0,
+			},
+			height: 300000,
+			policy: func(p *Policy) {
+				p.MaxDataCarrierSize = 1
+			},
+			isStandard: false,
+			code:       wire.RejectNonstandard,
+		},
+		{
+			name: "Large nulldata output with a raised data carrier size",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value: 0,
+					PkScript: append([]byte{txscript.OP_RETURN,
+						txscript.OP_PUSHDATA2, 0xe8, 0x03},
+						make([]byte, 1000)...),
+				}},
+				LockTime: 0,
+			},
+			height: 300000,
+			policy: func(p *Policy) {
+				p.MaxDataCarrierSize = 1000
+			},
+			isStandard: true,
+		},
+		{
+			name: "Transaction over the max standard weight",
+			tx: wire.MsgTx{
+				Version:  1,
+				TxIn:     []*wire.TxIn{&dummyTxIn},
+				TxOut:    []*wire.TxOut{&dummyTxOut},
+				LockTime: 0,
+			},
+			height: 300000,
+			policy: func(p *Policy) {
+				p.MaxStandardTxWeight = 100
+			},
+			isStandard: false,
+			code:       wire.RejectNonstandard,
+		},
+		{
+			name: "Output under a raised dust relay fee",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn},
+				TxOut: []*wire.TxOut{{
+					Value:    1000,
+					PkScript: dummyPkScript,
+				}, {
+					Value:    1000,
+					PkScript: dummyPkScript,
+				}},
+				LockTime: 0,
+			},
+			height: 300000,
+			policy: func(p *Policy) {
+				p.DustRelayFee = 10000
+			},
+			isStandard: false,
+			code:       wire.RejectDust,
+		},
 	}
 
 	pastMedianTime := time.Now()
 	for _, test := range tests {
+		policy := Policy{
+			MaxTxVersion:        1,
+			DustRelayFee:        DefaultMinRelayTxFee,
+			MaxStandardTxWeight: DefaultMaxStandardTxWeight,
+			MaxDataCarrierSize:  DefaultMaxDataCarrierSize,
+		}
+		if test.policy != nil {
+			test.policy(&policy)
+		}
+
 		// Ensure standardness is as expected.
 		err := checkTransactionStandard(btcutil.NewTx(&test.tx),
-			test.height, pastMedianTime, DefaultMinRelayTxFee, 1)
+			test.height, pastMedianTime, &policy)
 		if err == nil && test.isStandard {
 			// Test passes since function returned standard for a
 			// transaction which is intended to be standard.
@@ -0,0 +1,157 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// TRUCVersion is the version of the transactions that opt in to the
+	// topologically restricted until confirmation (TRUC) policy of BIP 431.
+	// They're always replaceable and may only be in clusters of a parent
+	// with a single child, which keeps replacing them cheap and
+	// predictable for the contracting protocols that rely on it.
+	TRUCVersion = 3
+
+	// TRUCAncestorLimit is the maximum number of unconfirmed transactions a
+	// TRUC transaction may have as ancestors, counting itself.
+	TRUCAncestorLimit = 2
+
+	// TRUCDescendantLimit is the maximum number of unconfirmed transactions
+	// a TRUC transaction may have as descendants, counting itself.
+	TRUCDescendantLimit = 2
+
+	// TRUCMaxVSize is the maximum virtual size of a TRUC transaction.
+	TRUCMaxVSize = 10000
+
+	// TRUCChildMaxVSize is the maximum virtual size of a TRUC transaction
+	// that spends an unconfirmed TRUC transaction.
+	TRUCChildMaxVSize = 1000
+)
+
+// isTRUC returns whether the passed transaction opts in to the TRUC policy.
+func isTRUC(tx *btcutil.Tx) bool {
+	return tx.MsgTx().Version == TRUCVersion
+}
+
+// trucSiblings returns the descendants of the unconfirmed TRUC parents that the
+// passed TRUC transaction spends, which it would have to evict to be accepted
+// since each parent may only have one child.  Descendants that are among the
+// passed conflicts don't count since they're replaced anyway.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) trucSiblings(tx *btcutil.Tx,
+	conflicts map[chainhash.Hash]*btcutil.Tx) []*btcutil.Tx {
+
+	if !isTRUC(tx) {
+		return nil
+	}
+
+	var siblings []*btcutil.Tx
+	seen := make(map[chainhash.Hash]struct{})
+	for _, txIn := range tx.MsgTx().TxIn {
+		parent, ok := mp.pool[txIn.PreviousOutPoint.Hash]
+		if !ok || !isTRUC(parent.Tx) {
+			continue
+		}
+		if _, ok := seen[*parent.Tx.Hash()]; ok {
+			continue
+		}
+		seen[*parent.Tx.Hash()] = struct{}{}
+
+		for hash, child := range mp.txDescendants(parent.Tx, nil) {
+			if _, ok := conflicts[hash]; !ok {
+				siblings = append(siblings, child)
+			}
+		}
+	}
+	return siblings
+}
+
+// checkTRUC checks the passed transaction of the passed virtual size against
+// the TRUC policy once its conflicts are replaced.  TRUC transactions may only
+// spend unconfirmed TRUC transactions and the other way around, may have at
+// most one unconfirmed parent which itself has no unconfirmed parents, and
+// the parent may only have one child.
+//
+// A TRUC transaction that spends a parent that already has a child may still
+// be accepted by replacing that child, which is known as sibling eviction.  The
+// sibling to evict is returned so that the caller can check the transaction as
+// a replacement of it.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) checkTRUC(tx *btcutil.Tx, txSize int64,
+	conflicts map[chainhash.Hash]*btcutil.Tx) (*btcutil.Tx, error) {
+
+	txHash := tx.Hash()
+	ancestors := mp.txAncestors(tx, nil)
+	for hash := range conflicts {
+		delete(ancestors, hash)
+	}
+
+	if !isTRUC(tx) {
+		for hash, ancestor := range ancestors {
+			if isTRUC(ancestor) {
+				str := fmt.Sprintf("non-TRUC transaction %v "+
+					"can't spend unconfirmed TRUC "+
+					"transaction %v", txHash, hash)
+				return nil, txRuleError(wire.RejectNonstandard, str)
+			}
+		}
+		return nil, nil
+	}
+
+	if txSize > TRUCMaxVSize {
+		str := fmt.Sprintf("TRUC transaction %v has a virtual size of %d "+
+			"which is over the maximum of %d", txHash, txSize,
+			TRUCMaxVSize)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+	for hash, ancestor := range ancestors {
+		if !isTRUC(ancestor) {
+			str := fmt.Sprintf("TRUC transaction %v can't spend "+
+				"unconfirmed non-TRUC transaction %v", txHash,
+				hash)
+			return nil, txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+	if len(ancestors)+1 > TRUCAncestorLimit {
+		str := fmt.Sprintf("TRUC transaction %v would have %d "+
+			"unconfirmed ancestors which is over the maximum of %d",
+			txHash, len(ancestors), TRUCAncestorLimit-1)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+	if len(ancestors) == 0 {
+		return nil, nil
+	}
+
+	if txSize > TRUCChildMaxVSize {
+		str := fmt.Sprintf("TRUC child transaction %v has a virtual "+
+			"size of %d which is over the maximum of %d", txHash,
+			txSize, TRUCChildMaxVSize)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	siblings := mp.trucSiblings(tx, conflicts)
+	switch len(siblings) {
+	case 0:
+		return nil, nil
+
+	// The parent is only allowed a single child, which can be evicted in
+	// favor of the transaction as long as it pays enough to replace it.
+	case TRUCDescendantLimit - 1:
+		return siblings[0], nil
+	}
+
+	str := fmt.Sprintf("TRUC transaction %v would give its parent %d "+
+		"unconfirmed descendants which is over the maximum of %d",
+		txHash, len(siblings)+1, TRUCDescendantLimit-1)
+	return nil, txRuleError(wire.RejectNonstandard, str)
+}
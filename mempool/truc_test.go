@@ -0,0 +1,277 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/txscript"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// createTRUCTx creates a signed TRUC transaction like CreateSignedTx that also
+// spends the passed anchor outputs and pays to the passed extra outputs.
+func (p *poolHarness) createTRUCTx(inputs []spendableOutput,
+	numOutputs uint32, fee btcutil.Amount, anchors []wire.OutPoint,
+	extraOuts ...*wire.TxOut) (*btcutil.Tx, error) {
+
+	tx, err := p.CreateSignedTx(inputs, numOutputs, fee, false)
+	if err != nil {
+		return nil, err
+	}
+	msgTx := tx.MsgTx()
+	msgTx.Version = TRUCVersion
+	for _, anchor := range anchors {
+		msgTx.AddTxIn(wire.NewTxIn(&anchor, nil, nil))
+	}
+	for _, txOut := range extraOuts {
+		msgTx.AddTxOut(txOut)
+	}
+
+	for i := range inputs {
+		sigScript, err := txscript.SignatureScript(msgTx, i, p.payScript,
+			txscript.SigHashAll, p.signKey, true)
+		if err != nil {
+			return nil, err
+		}
+		msgTx.TxIn[i].SignatureScript = sigScript
+	}
+
+	return btcutil.NewTx(msgTx), nil
+}
+
+// TestTRUC ensures that TRUC transactions are limited to clusters of a parent
+// with a single child and that a child may evict its sibling.
+func TestTRUC(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	harness.txPool.cfg.Policy.MaxTxVersion = TRUCVersion
+	ctx := &testContext{t, harness}
+	mp := harness.txPool
+
+	expectRejected := func(tx *btcutil.Tx, reason string) {
+		t.Helper()
+
+		_, err := mp.ProcessTransaction(tx, false, false, 0)
+		if err == nil || !strings.Contains(err.Error(), reason) {
+			t.Fatalf("expected the transaction to be rejected with "+
+				"%q, got %v", reason, err)
+		}
+		testPoolMembership(ctx, tx, false, false)
+	}
+	expectAccepted := func(tx *btcutil.Tx) {
+		t.Helper()
+
+		_, err := mp.ProcessTransaction(tx, false, false, 0)
+		if err != nil {
+			t.Fatalf("unable to accept transaction: %v", err)
+		}
+		testPoolMembership(ctx, tx, false, true)
+	}
+
+	coinbase := ctx.addCoinbaseTx(2)
+	parent, err := harness.createTRUCTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 2, 1000, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	expectAccepted(parent)
+
+	// A non-TRUC transaction can't spend the parent.
+	nonTRUCChild, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 1000, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	expectRejected(nonTRUCChild, "non-TRUC transaction")
+
+	// A TRUC transaction can't spend a non-TRUC one either.
+	nonTRUCParent := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 1000,
+		false, false,
+	)
+	trucChild, err := harness.createTRUCTx(
+		[]spendableOutput{txOutToSpendableOut(nonTRUCParent, 0)}, 1,
+		1000, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	expectRejected(trucChild, "unconfirmed non-TRUC transaction")
+
+	// The parent may have a child, but the child can't have children of
+	// its own.
+	child, err := harness.createTRUCTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 1000, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	expectAccepted(child)
+
+	grandchild, err := harness.createTRUCTx(
+		[]spendableOutput{txOutToSpendableOut(child, 0)}, 1, 1000, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	expectRejected(grandchild, "unconfirmed ancestors")
+
+	// Children are limited in size.
+	bigSibling, err := harness.createTRUCTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 1)}, 40, 100000,
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	expectRejected(bigSibling, "TRUC child transaction")
+
+	// A sibling of the child has to pay enough to replace it, in which
+	// case the child is evicted.
+	cheapSibling, err := harness.createTRUCTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 1)}, 1, 1000, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	expectRejected(cheapSibling, "insufficient")
+	testPoolMembership(ctx, child, false, true)
+
+	sibling, err := harness.createTRUCTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 1)}, 1, 5000, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	expectAccepted(sibling)
+	testPoolMembership(ctx, child, false, false)
+	testPoolMembership(ctx, parent, false, true)
+}
+
+// TestEphemeralAnchor ensures that a transaction with a dust anchor output is
+// only accepted without a fee in a package with a child that spends the
+// anchor.
+func TestEphemeralAnchor(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	harness.txPool.cfg.Policy.MaxTxVersion = TRUCVersion
+	ctx := &testContext{t, harness}
+	mp := harness.txPool
+
+	anchorScript := []byte{txscript.OP_1, txscript.OP_DATA_2, 0x4e, 0x73}
+	if !txscript.IsPayToAnchor(anchorScript) {
+		t.Fatalf("expected a pay-to-anchor script")
+	}
+	anchorOut := wire.NewTxOut(0, anchorScript)
+
+	coinbase := ctx.addCoinbaseTx(1)
+	coinbaseOut := txOutToSpendableOut(coinbase, 0)
+
+	// A transaction with a dust output may not pay a fee.
+	feeParent, err := harness.createTRUCTx(
+		[]spendableOutput{coinbaseOut}, 1, 1000, nil, anchorOut,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = mp.ProcessTransaction(feeParent, false, false, 0)
+	if code, _ := extractRejectCode(err); code != wire.RejectDust {
+		t.Fatalf("expected the transaction to be rejected for its "+
+			"dust, got %v", err)
+	}
+
+	// Nor can it be accepted without a child.
+	parent, err := harness.createTRUCTx(
+		[]spendableOutput{coinbaseOut}, 1, 0, nil, anchorOut,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = mp.ProcessTransaction(parent, false, false, 0)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("expected the transaction to be rejected for its "+
+			"fee, got %v", err)
+	}
+	testPoolMembership(ctx, parent, false, false)
+
+	// A child that doesn't spend the anchor is rejected along with the
+	// parent.
+	anchor := wire.OutPoint{Hash: *parent.Hash(), Index: 1}
+	parentOut := txOutToSpendableOut(parent, 0)
+	child, err := harness.createTRUCTx(
+		[]spendableOutput{parentOut}, 1, 5000, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	results, _, err := mp.ProcessPackage([]*btcutil.Tx{parent, child})
+	if err != nil {
+		t.Fatalf("unexpected package error: %v", err)
+	}
+	if err := results[1].Err; err == nil ||
+		!strings.Contains(err.Error(), "missing ephemeral spends") {
+
+		t.Fatalf("expected the child to be rejected for not spending "+
+			"the anchor, got %v", err)
+	}
+	testPoolMembership(ctx, parent, false, false)
+
+	// The anchor has to be spent with an empty witness.
+	mp.cfg.IsDeploymentActive = func(uint32) (bool, error) {
+		return true, nil
+	}
+	child, err = harness.createTRUCTx(
+		[]spendableOutput{parentOut}, 1, 5000, []wire.OutPoint{anchor},
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	child.MsgTx().TxIn[1].Witness = wire.TxWitness{{0x01}}
+	witnessChild := btcutil.NewTx(child.MsgTx())
+	results, _, err = mp.ProcessPackage([]*btcutil.Tx{parent, witnessChild})
+	if err != nil {
+		t.Fatalf("unexpected package error: %v", err)
+	}
+	if err := results[1].Err; err == nil ||
+		!strings.Contains(err.Error(), "non-empty witness") {
+
+		t.Fatalf("expected the child to be rejected for its anchor "+
+			"witness, got %v", err)
+	}
+
+	// A child spending the anchor gets both accepted.
+	child.MsgTx().TxIn[1].Witness = nil
+	child = btcutil.NewTx(child.MsgTx())
+	results, accepted, err := mp.ProcessPackage([]*btcutil.Tx{parent, child})
+	if err != nil {
+		t.Fatalf("unexpected package error: %v", err)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("unexpected error for transaction %d: %v", i,
+				result.Err)
+		}
+	}
+	if len(accepted) != 2 {
+		t.Fatalf("expected 2 accepted transactions, got %d",
+			len(accepted))
+	}
+	testPoolMembership(ctx, parent, false, true)
+	testPoolMembership(ctx, child, false, true)
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// markSpentPoolOutputs records the outputs of transactions in the pool that the
+// passed transaction spends.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) markSpentPoolOutputs(tx *btcutil.Tx) {
+	mp.spentPoolOutputsMtx.Lock()
+	for _, txIn := range tx.MsgTx().TxIn {
+		if _, exists := mp.pool[txIn.PreviousOutPoint.Hash]; exists {
+			mp.spentPoolOutputs[txIn.PreviousOutPoint] = struct{}{}
+		}
+	}
+	mp.spentPoolOutputsMtx.Unlock()
+}
+
+// unmarkSpentPoolOutputs forgets the outputs that the passed transaction that's
+// removed from the pool spends.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) unmarkSpentPoolOutputs(tx *btcutil.Tx) {
+	mp.spentPoolOutputsMtx.Lock()
+	for _, txIn := range tx.MsgTx().TxIn {
+		delete(mp.spentPoolOutputs, txIn.PreviousOutPoint)
+	}
+	mp.spentPoolOutputsMtx.Unlock()
+}
+
+// IsPoolOutputSpent returns whether the passed output of a transaction in the
+// pool is spent by another transaction in the pool.  The compact state uses it
+// to cache the leaves of the outputs that a connected block confirms for the
+// transactions that remain in the pool, so that their proofs are kept up to date
+// by the same accumulator modification that connects the block.
+//
+// This function is safe for concurrent access and doesn't take the mempool lock,
+// so it may be called while the chain is being modified.
+func (mp *TxPool) IsPoolOutputSpent(op *wire.OutPoint) bool {
+	mp.spentPoolOutputsMtx.RLock()
+	_, spent := mp.spentPoolOutputs[*op]
+	mp.spentPoolOutputsMtx.RUnlock()
+
+	return spent
+}
+
+// confirmPoolLeaves updates the leaf datas of the transactions in the pool that
+// spend outputs created by the passed block, which were unconfirmed when the
+// transactions were accepted, to the leaves the block added to the accumulator.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) confirmPoolLeaves(block *btcutil.Block) {
+	var confirmed int
+	for txIdx, tx := range block.Transactions() {
+		for outIdx, txOut := range tx.MsgTx().TxOut {
+			op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(outIdx)}
+			redeemer, exists := mp.outpoints[op]
+			if !exists {
+				continue
+			}
+
+			mp.spentPoolOutputsMtx.Lock()
+			delete(mp.spentPoolOutputs, op)
+			mp.spentPoolOutputsMtx.Unlock()
+
+			leaves := mp.poolLeaves[*redeemer.Hash()]
+			for i, txIn := range redeemer.MsgTx().TxIn {
+				if i >= len(leaves) || txIn.PreviousOutPoint != op ||
+					!leaves[i].IsUnconfirmed() {
+
+					continue
+				}
+				leaves[i] = wire.LeafData{
+					BlockHash:  *block.Hash(),
+					OutPoint:   op,
+					Amount:     txOut.Value,
+					PkScript:   txOut.PkScript,
+					Height:     block.Height(),
+					IsCoinBase: txIdx == 0,
+				}
+				confirmed++
+			}
+		}
+	}
+	if confirmed > 0 {
+		log.Debugf("Updated %d leaves of mempool transactions confirmed "+
+			"by block %v", confirmed, block.Hash())
+	}
+}
+
+// RemoveBlockTransactions removes the transactions of the passed block that was
+// connected to the main chain from the pool along with the transactions that
+// double spend them.  Transactions which depend on a confirmed transaction are
+// NOT removed recursively because they are still valid.
+//
+// When the utreexo view is active, the leaves of the removed transactions are
+// uncached from the accumulator at once rather than one transaction at a time
+// and the leaf datas of the remaining transactions that spend outputs of the
+// block are updated to the confirmed leaves, whose proofs the accumulator keeps
+// up to date itself, so that none of the transactions need to be reproven.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) RemoveBlockTransactions(block *btcutil.Block) {
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	utreexoActive := mp.cfg.IsUtreexoViewActive != nil &&
+		mp.cfg.IsUtreexoViewActive()
+	mp.batchPrunes = utreexoActive
+
+	confirmed := &removal{typ: NTTxConfirmed, block: block}
+	for _, tx := range block.Transactions()[1:] {
+		mp.removeTransaction(tx, false, true, confirmed)
+		for _, txIn := range tx.MsgTx().TxIn {
+			txRedeemer, ok := mp.outpoints[txIn.PreviousOutPoint]
+			if ok && !txRedeemer.Hash().IsEqual(tx.Hash()) {
+				mp.removeTransaction(txRedeemer, true, true,
+					removedTx)
+			}
+		}
+	}
+
+	mp.batchPrunes = false
+	if !utreexoActive {
+		return
+	}
+	mp.confirmPoolLeaves(block)
+
+	if len(mp.pendingPrunes) == 0 {
+		return
+	}
+	err := mp.cfg.PruneFromAccumulator(mp.pendingPrunes)
+	if err != nil {
+		log.Infof("err while pruning proofs for inputs of the txs in "+
+			"block %s: %v", block.Hash(), err)
+	}
+	mp.pendingPrunes = nil
+}
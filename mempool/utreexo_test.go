@@ -0,0 +1,104 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"testing"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestRemoveBlockTransactions ensures that the transactions of a connected block
+// and their double spends are removed from the pool with their leaves uncached
+// at once and that the leaves of the remaining transactions spending outputs of
+// the block are confirmed.
+func TestRemoveBlockTransactions(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	mp := harness.txPool
+
+	coinbase := ctx.addCoinbaseTx(2)
+	parent := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 1000,
+		false, false,
+	)
+	child := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 1000,
+		false, false,
+	)
+	doubleSpent := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 1000,
+		false, false,
+	)
+	doubleSpend, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 2000, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+
+	parentOut := wire.OutPoint{Hash: *parent.Hash(), Index: 0}
+	if !mp.IsPoolOutputSpent(&parentOut) {
+		t.Fatalf("expected the output of the parent to be spent")
+	}
+
+	// Give the transactions the leaves they'd have on a compact state
+	// node, where the input of the child is unconfirmed.
+	unconfirmed := wire.LeafData{OutPoint: parentOut}
+	unconfirmed.SetUnconfirmed()
+	mp.poolLeaves[*parent.Hash()] = []wire.LeafData{
+		{OutPoint: parent.MsgTx().TxIn[0].PreviousOutPoint, Height: 1},
+	}
+	mp.poolLeaves[*child.Hash()] = []wire.LeafData{unconfirmed}
+	mp.poolLeaves[*doubleSpent.Hash()] = []wire.LeafData{
+		{OutPoint: doubleSpent.MsgTx().TxIn[0].PreviousOutPoint, Height: 1},
+	}
+
+	var prunes [][]wire.LeafData
+	mp.cfg.IsUtreexoViewActive = func() bool { return true }
+	mp.cfg.PruneFromAccumulator = func(leaves []wire.LeafData) error {
+		prunes = append(prunes, leaves)
+		return nil
+	}
+
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Transactions: []*wire.MsgTx{
+			coinbase.MsgTx(), parent.MsgTx(), doubleSpend.MsgTx(),
+		},
+	})
+	block.SetHeight(harness.chain.BestHeight() + 1)
+	mp.RemoveBlockTransactions(block)
+
+	testPoolMembership(ctx, parent, false, false)
+	testPoolMembership(ctx, doubleSpent, false, false)
+	testPoolMembership(ctx, child, false, true)
+	if len(prunes) != 1 || len(prunes[0]) != 2 {
+		t.Fatalf("expected the 2 leaves to be pruned at once, got %v",
+			prunes)
+	}
+
+	leaves, err := mp.FetchLeafDatas(child.Hash())
+	if err != nil {
+		t.Fatalf("unable to fetch the leaves of the child: %v", err)
+	}
+	leaf := leaves[0]
+	if leaf.IsUnconfirmed() || leaf.Height != block.Height() ||
+		leaf.BlockHash != *block.Hash() ||
+		leaf.Amount != parent.MsgTx().TxOut[0].Value {
+
+		t.Fatalf("expected the leaf of the child to be confirmed by "+
+			"the block, got %v", leaf)
+	}
+	if mp.IsPoolOutputSpent(&parentOut) {
+		t.Fatalf("expected the confirmed output to not be tracked")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// utreexoMetricsName is the name the utreexo statistics are published under on
+// the metrics endpoint.
+const utreexoMetricsName = "utreexo"
+
+// metricsServer holds the server whose statistics are published.  It's
+// replaced when the server is restarted within the same process.
+var metricsServer atomic.Value
+
+// publishMetrics publishes the statistics of the server so that they're served
+// as JSON at /debug/vars by the profile server (--profile).  The statistics
+// are computed on every request so they're always up to date.
+func publishMetrics(s *server) {
+	metricsServer.Store(s)
+
+	// Publish panics on duplicate names so don't publish again if the
+	// server was restarted within the same process.
+	if expvar.Get(utreexoMetricsName) != nil {
+		return
+	}
+
+	expvar.Publish(utreexoMetricsName, expvar.Func(func() interface{} {
+		s := metricsServer.Load().(*server)
+		stats, ok := s.chain.UtreexoStats()
+		if !ok {
+			return nil
+		}
+		orphanStats := s.syncManager.ProofOrphanStats()
+		return utreexoStatsResult(&stats, &orphanStats)
+	}))
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"reflect"
+	"testing"
+
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcjson"
+	"github.com/utreexo/utreexod/netsync"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestPublishMetrics ensures that the statistics of the accumulator of the
+// utreexo proof index and of the served proofs are published as JSON and that
+// they're up to date on every read.
+func TestPublishMetrics(t *testing.T) {
+	chain, _ := newUtreexoIndexTestChain(t)
+
+	// Publishing again, as a server restarted within the same process
+	// does, must not panic on the duplicate name and must publish the
+	// statistics of the new server.
+	publishMetrics(&server{chain: &blockchain.BlockChain{}})
+	s := &server{chain: chain, syncManager: &netsync.SyncManager{}}
+	publishMetrics(s)
+
+	readMetrics := func() *btcjson.GetUtreexoStatsResult {
+		t.Helper()
+
+		v := expvar.Get(utreexoMetricsName)
+		if v == nil {
+			t.Fatalf("the utreexo statistics weren't published")
+		}
+		var result btcjson.GetUtreexoStatsResult
+		if err := json.Unmarshal([]byte(v.String()), &result); err != nil {
+			t.Fatalf("unable to decode the utreexo statistics %s: %v",
+				v.String(), err)
+		}
+		return &result
+	}
+	wantMetrics := func() *btcjson.GetUtreexoStatsResult {
+		t.Helper()
+
+		stats, ok := chain.UtreexoStats()
+		if !ok {
+			t.Fatalf("the chain doesn't report an accumulator")
+		}
+		orphanStats := s.syncManager.ProofOrphanStats()
+		return utreexoStatsResult(&stats, &orphanStats)
+	}
+
+	got := readMetrics()
+	if got.NumLeaves == 0 || got.NumRoots == 0 || got.StateSize == 0 {
+		t.Fatalf("got empty accumulator statistics %+v", got)
+	}
+	if got.ProofsServed != 0 || got.ProofBytesServed != 0 {
+		t.Fatalf("got %d proofs of %d bytes served before any were",
+			got.ProofsServed, got.ProofBytesServed)
+	}
+	if want := wantMetrics(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got metrics %+v, want %+v", got, want)
+	}
+
+	ud := &wire.UData{AccProof: utreexo.Proof{Targets: []uint64{0}}}
+	chain.RecordProofServed(ud)
+	chain.RecordProofServed(ud)
+	got = readMetrics()
+	wantBytes := 2 * uint64(ud.SerializeSizeCompact(false))
+	if got.ProofsServed != 2 || got.ProofBytesServed != wantBytes {
+		t.Fatalf("got %d proofs of %d bytes served, want 2 of %d bytes",
+			got.ProofsServed, got.ProofBytesServed, wantBytes)
+	}
+	if got.AvgProofSize != float64(wantBytes)/2 {
+		t.Fatalf("got average proof size %v, want %v",
+			got.AvgProofSize, float64(wantBytes)/2)
+	}
+	if want := wantMetrics(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got metrics %+v, want %+v", got, want)
+	}
+}
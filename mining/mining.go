@@ -53,6 +53,13 @@ type TxDesc struct {
 
 	// FeePerKB is the fee the transaction pays in Satoshi per 1000 bytes.
 	FeePerKB int64
+
+	// ChunkFeePerKB is the fee in Satoshi per 1000 bytes of the set of
+	// transactions of the source pool that the transaction is expected to
+	// be mined with, which accounts for the parents it pays for and the
+	// children that pay for it.  Zero when the source pool doesn't track
+	// it, in which case FeePerKB is used instead.
+	ChunkFeePerKB int64
 }
 
 // MarshalJSON implements the json.Marshaler interface for TxDesc.
@@ -501,6 +508,15 @@ func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 //	|  <= policy.BlockMinSize)          |   |
 //	 -----------------------------------  --
 func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcutil.Address) (*BlockTemplate, error) {
+	return g.NewBlockTemplateWithPolicy(g.policy, payToAddress)
+}
+
+// NewBlockTemplateWithPolicy returns a new block template like NewBlockTemplate
+// does, but generated according to the passed policy instead of the policy of
+// the generator.  This allows callers to override it for specific templates.
+func (g *BlkTmplGenerator) NewBlockTemplateWithPolicy(policy *Policy,
+	payToAddress btcutil.Address) (*BlockTemplate, error) {
+
 	// Extend the most recently known best block.
 	best := g.chain.BestSnapshot()
 	nextBlockHeight := best.Height + 1
@@ -532,7 +548,7 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcutil.Address) (*Bloc
 	// choose the initial sort order for the priority queue based on whether
 	// or not there is an area allocated for high-priority transactions.
 	sourceTxns := g.txSource.MiningDescs()
-	sortedByFee := g.policy.BlockPrioritySize == 0
+	sortedByFee := policy.BlockPrioritySize == 0
 	priorityQueue := newTxPriorityQueue(len(sourceTxns), sortedByFee)
 
 	// Create a slice to hold the transactions to be included in the
@@ -635,8 +651,14 @@ mempoolLoop:
 		prioItem.priority = CalcPriority(tx.MsgTx(), utxos,
 			nextBlockHeight)
 
-		// Calculate the fee in Satoshi/kB.
+		// Calculate the fee in Satoshi/kB.  Transactions are sorted by
+		// the fee rate of the chunk they're mined with when the source
+		// pool tracks it so that parents paid for by their children
+		// are included along with them.
 		prioItem.feePerKB = txDesc.FeePerKB
+		if txDesc.ChunkFeePerKB != 0 {
+			prioItem.feePerKB = txDesc.ChunkFeePerKB
+		}
 		prioItem.fee = txDesc.Fee
 
 		// Add the transaction to the priority queue to mark it ready
@@ -727,7 +749,7 @@ mempoolLoop:
 		txWeight := uint32(blockchain.GetTransactionWeight(tx))
 		blockPlusTxWeight := blockWeight + txWeight
 		if blockPlusTxWeight < blockWeight ||
-			blockPlusTxWeight >= g.policy.BlockMaxWeight {
+			blockPlusTxWeight >= policy.BlockMaxWeight {
 
 			log.Tracef("Skipping tx %s because it would exceed "+
 				"the max block weight", tx.Hash())
@@ -735,6 +757,16 @@ mempoolLoop:
 			continue
 		}
 
+		// Skip transactions paying less than the minimum fee rate for
+		// inclusion in blocks.
+		if prioItem.feePerKB < int64(policy.BlockMinTxFee) {
+			log.Tracef("Skipping tx %s with feePerKB %d "+
+				"< BlockMinTxFee %d", tx.Hash(), prioItem.feePerKB,
+				policy.BlockMinTxFee)
+			logSkippedDeps(tx, deps)
+			continue
+		}
+
 		// Enforce maximum signature operation cost per block.  Also
 		// check for overflow.
 		sigOpCost, err := blockchain.GetSigOpCost(tx, false,
@@ -756,14 +788,14 @@ mempoolLoop:
 		// Skip free transactions once the block is larger than the
 		// minimum block size.
 		if sortedByFee &&
-			prioItem.feePerKB < int64(g.policy.TxMinFreeFee) &&
-			blockPlusTxWeight >= g.policy.BlockMinWeight {
+			prioItem.feePerKB < int64(policy.TxMinFreeFee) &&
+			blockPlusTxWeight >= policy.BlockMinWeight {
 
 			log.Tracef("Skipping tx %s with feePerKB %d "+
 				"< TxMinFreeFee %d and block weight %d >= "+
 				"minBlockWeight %d", tx.Hash(), prioItem.feePerKB,
-				g.policy.TxMinFreeFee, blockPlusTxWeight,
-				g.policy.BlockMinWeight)
+				policy.TxMinFreeFee, blockPlusTxWeight,
+				policy.BlockMinWeight)
 			logSkippedDeps(tx, deps)
 			continue
 		}
@@ -771,13 +803,13 @@ mempoolLoop:
 		// Prioritize by fee per kilobyte once the block is larger than
 		// the priority size or there are no more high-priority
 		// transactions.
-		if !sortedByFee && (blockPlusTxWeight >= g.policy.BlockPrioritySize ||
+		if !sortedByFee && (blockPlusTxWeight >= policy.BlockPrioritySize ||
 			prioItem.priority <= MinHighPriority) {
 
 			log.Tracef("Switching to sort by fees per "+
 				"kilobyte blockSize %d >= BlockPrioritySize "+
 				"%d || priority %.2f <= minHighPriority %.2f",
-				blockPlusTxWeight, g.policy.BlockPrioritySize,
+				blockPlusTxWeight, policy.BlockPrioritySize,
 				prioItem.priority, MinHighPriority)
 
 			sortedByFee = true
@@ -789,7 +821,7 @@ mempoolLoop:
 			// is too low.  Otherwise this transaction will be the
 			// final one in the high-priority section, so just fall
 			// though to the code below so it is added now.
-			if blockPlusTxWeight > g.policy.BlockPrioritySize ||
+			if blockPlusTxWeight > policy.BlockPrioritySize ||
 				prioItem.priority < MinHighPriority {
 
 				heap.Push(priorityQueue, prioItem)
@@ -1028,6 +1060,14 @@ func (g *BlkTmplGenerator) BestSnapshot() *blockchain.BestState {
 	return g.chain.BestSnapshot()
 }
 
+// Policy returns a copy of the policy the generator creates block templates
+// with.
+//
+// This function is safe for concurrent access.
+func (g *BlkTmplGenerator) Policy() Policy {
+	return *g.policy
+}
+
 // TxSource returns the associated transaction source.
 //
 // This function is safe for concurrent access.
@@ -45,6 +45,10 @@ type Policy struct {
 	// required for a transaction to be treated as free for mining purposes
 	// (block template generation).
 	TxMinFreeFee btcutil.Amount
+
+	// BlockMinTxFee is the minimum fee in Satoshi/1000 bytes that is
+	// required for a transaction to be included in a block template.
+	BlockMinTxFee btcutil.Amount
 }
 
 // minInt is a helper function to return the minimum of two ints.  This avoids
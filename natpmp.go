@@ -0,0 +1,331 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// natPMPPort is the port the NAT-PMP and PCP servers of routers listen
+	// on.
+	natPMPPort = 5351
+
+	// natPMPVersion and pcpVersion are the versions of the protocols in the
+	// first byte of their messages.  PCP is version 2 of NAT-PMP.
+	natPMPVersion = 0
+	pcpVersion    = 2
+
+	// The opcodes of the requests.  The responses carry the opcode of the
+	// request with the response bit set.
+	natPMPOpExternalAddress = 0
+	natPMPOpMapUDP          = 1
+	natPMPOpMapTCP          = 2
+	pcpOpAnnounce           = 0
+	pcpOpMap                = 1
+	natPMPResponseBit       = 0x80
+
+	// pcpHeaderSize and pcpMapSize are the sizes of the header of the PCP
+	// messages and of the payload of its map opcode.
+	pcpHeaderSize = 24
+	pcpMapSize    = 36
+
+	// natPMPTries is how many times a request is sent, waiting twice as
+	// long for the response each time starting at natPMPInitialTimeout.
+	natPMPTries          = 4
+	natPMPInitialTimeout = 250 * time.Millisecond
+)
+
+// natPMP is a NAT that maps ports with PCP (RFC 6887) when the router supports
+// it, and with NAT-PMP (RFC 6886) otherwise.  Many routers that have UPnP
+// disabled support one of them.
+type natPMP struct {
+	gateway *net.UDPAddr
+	pcp     bool
+
+	// nonce identifies the PCP mappings of the node when they're renewed.
+	nonce [12]byte
+
+	// externalIP and lifetime are the external address and the lifetime of
+	// the last mapping.  The external address is only learned by mapping a
+	// port with PCP.
+	externalIP net.IP
+	lifetime   time.Duration
+}
+
+// Ensure natPMP implements the NAT interface.
+var _ NAT = (*natPMP)(nil)
+
+// DiscoverPMP looks for a router that supports PCP or NAT-PMP at the default
+// gateway, returning a NAT for the network if so.
+func DiscoverPMP() (NAT, error) {
+	gateway, err := defaultGateway()
+	if err != nil {
+		return nil, err
+	}
+	n, err := discoverPMP(&net.UDPAddr{IP: gateway, Port: natPMPPort})
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// discoverPMP looks for a PCP or NAT-PMP server at the address.  The servers
+// that support PCP answer its announce request, and those that only support
+// NAT-PMP answer it with a version they do support.
+func discoverPMP(gateway *net.UDPAddr) (*natPMP, error) {
+	n := &natPMP{gateway: gateway}
+	if _, err := rand.Read(n.nonce[:]); err != nil {
+		return nil, err
+	}
+
+	resp, err := n.exchange(func(clientIP net.IP) []byte {
+		return pcpRequest(pcpOpAnnounce, 0, clientIP)
+	})
+	if err == nil && resp[0] == pcpVersion && resp[3] == 0 {
+		n.pcp = true
+		return n, nil
+	}
+	if _, err := n.GetExternalAddress(); err != nil {
+		return nil, fmt.Errorf("no PCP or NAT-PMP server at %v: %v",
+			gateway, err)
+	}
+	return n, nil
+}
+
+// String returns the protocol the ports are mapped with.
+func (n *natPMP) String() string {
+	if n.pcp {
+		return "PCP"
+	}
+	return "NAT-PMP"
+}
+
+// exchange sends the request built for the local address the gateway is
+// reached at and returns the response to it, resending the request while there
+// is none.
+func (n *natPMP) exchange(build func(clientIP net.IP) []byte) ([]byte, error) {
+	conn, err := net.DialUDP("udp", nil, n.gateway)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	req := build(conn.LocalAddr().(*net.UDPAddr).IP)
+	resp := make([]byte, 1100)
+	timeout := natPMPInitialTimeout
+	for try := 0; try < natPMPTries; try++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+		for {
+			nr, err := conn.Read(resp)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, err
+			}
+
+			// Late responses to requests sent before are skipped.
+			if nr >= 4 && resp[1] == req[1]|natPMPResponseBit {
+				return resp[:nr], nil
+			}
+		}
+		timeout *= 2
+	}
+	return nil, fmt.Errorf("no response from %v", n.gateway)
+}
+
+// pcpRequest returns the header of a PCP request.
+func pcpRequest(opcode byte, lifetime uint32, clientIP net.IP) []byte {
+	req := make([]byte, pcpHeaderSize)
+	req[0] = pcpVersion
+	req[1] = opcode
+	binary.BigEndian.PutUint32(req[4:8], lifetime)
+	copy(req[8:24], clientIP.To16())
+	return req
+}
+
+// GetExternalAddress implements the NAT interface by asking the router for its
+// external address with NAT-PMP, or by returning the external address of the
+// last port mapped with PCP.
+func (n *natPMP) GetExternalAddress() (net.IP, error) {
+	if n.pcp {
+		if n.externalIP == nil {
+			return nil, errors.New("no port mapped with PCP yet")
+		}
+		return n.externalIP, nil
+	}
+
+	resp, err := n.exchange(func(net.IP) []byte {
+		return []byte{natPMPVersion, natPMPOpExternalAddress}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if code := binary.BigEndian.Uint16(resp[2:4]); code != 0 {
+		return nil, fmt.Errorf("NAT-PMP result code %d", code)
+	}
+	if len(resp) < 12 {
+		return nil, errors.New("short NAT-PMP response")
+	}
+	return net.IPv4(resp[8], resp[9], resp[10], resp[11]), nil
+}
+
+// AddPortMapping implements the NAT interface by mapping the external port to
+// the internal port for the timeout in seconds.  The router may map another
+// external port, which is returned, and for a shorter time.
+func (n *natPMP) AddPortMapping(protocol string, externalPort, internalPort int,
+	description string, timeout int) (int, error) {
+
+	port, lifetime, err := n.mapPort(protocol, externalPort, internalPort,
+		uint32(timeout))
+	if err != nil {
+		return 0, err
+	}
+	n.lifetime = time.Duration(lifetime) * time.Second
+	return port, nil
+}
+
+// DeletePortMapping implements the NAT interface by mapping the internal port
+// for no time, which removes its mapping.
+func (n *natPMP) DeletePortMapping(protocol string, externalPort, internalPort int) error {
+	_, _, err := n.mapPort(protocol, 0, internalPort, 0)
+	return err
+}
+
+// leaseLifetime returns how long the last mapping was granted for.
+func (n *natPMP) leaseLifetime() time.Duration {
+	return n.lifetime
+}
+
+// mapPort maps the internal port for the lifetime in seconds and returns the
+// external port and lifetime the router mapped it with.
+func (n *natPMP) mapPort(protocol string, externalPort, internalPort int,
+	lifetime uint32) (int, uint32, error) {
+
+	var pcpProtocol, natPMPOp byte
+	switch strings.ToLower(protocol) {
+	case "tcp":
+		pcpProtocol, natPMPOp = 6, natPMPOpMapTCP
+	case "udp":
+		pcpProtocol, natPMPOp = 17, natPMPOpMapUDP
+	default:
+		return 0, 0, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+
+	if !n.pcp {
+		resp, err := n.exchange(func(net.IP) []byte {
+			req := make([]byte, 12)
+			req[0] = natPMPVersion
+			req[1] = natPMPOp
+			binary.BigEndian.PutUint16(req[4:6], uint16(internalPort))
+			binary.BigEndian.PutUint16(req[6:8], uint16(externalPort))
+			binary.BigEndian.PutUint32(req[8:12], lifetime)
+			return req
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		if code := binary.BigEndian.Uint16(resp[2:4]); code != 0 {
+			return 0, 0, fmt.Errorf("NAT-PMP result code %d", code)
+		}
+		if len(resp) < 16 {
+			return 0, 0, errors.New("short NAT-PMP response")
+		}
+		return int(binary.BigEndian.Uint16(resp[10:12])),
+			binary.BigEndian.Uint32(resp[12:16]), nil
+	}
+
+	resp, err := n.exchange(func(clientIP net.IP) []byte {
+		req := pcpRequest(pcpOpMap, lifetime, clientIP)
+		payload := make([]byte, pcpMapSize)
+		copy(payload[0:12], n.nonce[:])
+		payload[12] = pcpProtocol
+		binary.BigEndian.PutUint16(payload[16:18], uint16(internalPort))
+		binary.BigEndian.PutUint16(payload[18:20], uint16(externalPort))
+		copy(payload[20:36], net.IPv4zero.To16())
+		return append(req, payload...)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if resp[0] != pcpVersion {
+		return 0, 0, fmt.Errorf("unexpected PCP version %d", resp[0])
+	}
+	if code := resp[3]; code != 0 {
+		return 0, 0, fmt.Errorf("PCP result code %d", code)
+	}
+	if len(resp) < pcpHeaderSize+pcpMapSize {
+		return 0, 0, errors.New("short PCP response")
+	}
+	payload := resp[pcpHeaderSize:]
+	if string(payload[0:12]) != string(n.nonce[:]) {
+		return 0, 0, errors.New("PCP response for another mapping")
+	}
+	if lifetime != 0 {
+		n.externalIP = net.IP(append([]byte(nil), payload[20:36]...))
+	}
+	return int(binary.BigEndian.Uint16(payload[18:20])),
+		binary.BigEndian.Uint32(resp[4:8]), nil
+}
+
+// defaultGateway returns the IPv4 default gateway from the routing table of
+// Linux, or the first address of the network of the local address the internet
+// is reached from on other systems, which is where most home routers are.
+func defaultGateway() (net.IP, error) {
+	if f, err := os.Open("/proc/net/route"); err == nil {
+		defer f.Close()
+
+		// The lines are the interface, destination, gateway and flags
+		// followed by other fields, with the addresses in little
+		// endian hex.  The default route has a destination of 0 and the
+		// gateway flag set.
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 || fields[1] != "00000000" {
+				continue
+			}
+			flags, err := strconv.ParseUint(fields[3], 16, 16)
+			if err != nil || flags&0x2 == 0 {
+				continue
+			}
+			gateway, err := strconv.ParseUint(fields[2], 16, 32)
+			if err != nil {
+				continue
+			}
+			ip := make(net.IP, 4)
+			binary.LittleEndian.PutUint32(ip, uint32(gateway))
+			return ip, nil
+		}
+	}
+
+	// No packets are sent by dialing UDP.
+	conn, err := net.Dial("udp4", "198.51.100.1:9")
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the default gateway: %v", err)
+	}
+	defer conn.Close()
+	ip := conn.LocalAddr().(*net.UDPAddr).IP.To4()
+	if ip == nil || !ip.IsPrivate() {
+		return nil, errors.New("unable to find the default gateway")
+	}
+	return net.IPv4(ip[0], ip[1], ip[2], 1), nil
+}
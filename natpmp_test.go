@@ -0,0 +1,111 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeNATPMPServer answers the requests of PCP when pcp is set, and only those
+// of NAT-PMP otherwise, mapping the ports to the external port plus one.
+func fakeNATPMPServer(t *testing.T, pcp bool) *net.UDPAddr {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	externalIP := net.IPv4(203, 0, 113, 5).To4()
+	go func() {
+		req := make([]byte, 1100)
+		for {
+			nr, addr, err := conn.ReadFromUDP(req)
+			if err != nil {
+				return
+			}
+			var resp []byte
+			switch {
+			case req[0] == pcpVersion && !pcp:
+				resp = []byte{natPMPVersion, req[1] | natPMPResponseBit, 0, 1}
+
+			case req[0] == pcpVersion && req[1] == pcpOpAnnounce:
+				resp = make([]byte, pcpHeaderSize)
+				resp[0], resp[1] = pcpVersion, req[1]|natPMPResponseBit
+
+			case req[0] == pcpVersion && req[1] == pcpOpMap && nr == 60:
+				resp = make([]byte, pcpHeaderSize+pcpMapSize)
+				resp[0], resp[1] = pcpVersion, req[1]|natPMPResponseBit
+				lifetime := binary.BigEndian.Uint32(req[4:8]) / 2
+				binary.BigEndian.PutUint32(resp[4:8], lifetime)
+				copy(resp[pcpHeaderSize:], req[pcpHeaderSize:])
+				port := binary.BigEndian.Uint16(req[42:44])
+				binary.BigEndian.PutUint16(resp[42:44], port+1)
+				copy(resp[44:60], externalIP.To16())
+
+			case req[0] == natPMPVersion && req[1] == natPMPOpExternalAddress:
+				resp = make([]byte, 12)
+				resp[1] = req[1] | natPMPResponseBit
+				copy(resp[8:12], externalIP)
+
+			case req[0] == natPMPVersion && req[1] == natPMPOpMapTCP && nr == 12:
+				resp = make([]byte, 16)
+				resp[1] = req[1] | natPMPResponseBit
+				copy(resp[8:10], req[4:6])
+				port := binary.BigEndian.Uint16(req[6:8])
+				binary.BigEndian.PutUint16(resp[10:12], port+1)
+				lifetime := binary.BigEndian.Uint32(req[8:12]) / 2
+				binary.BigEndian.PutUint32(resp[12:16], lifetime)
+
+			default:
+				continue
+			}
+			conn.WriteToUDP(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+// TestNATPMP ensures that PCP is used with the routers that support it and
+// NAT-PMP with those that don't, and that the mapped ports, lifetimes and
+// external addresses they return are used.
+func TestNATPMP(t *testing.T) {
+	t.Parallel()
+
+	for _, pcp := range []bool{true, false} {
+		n, err := discoverPMP(fakeNATPMPServer(t, pcp))
+		if err != nil {
+			t.Fatalf("pcp=%v: unable to discover the server: %v", pcp, err)
+		}
+		if n.pcp != pcp {
+			t.Fatalf("pcp=%v: discovered %v", pcp, n)
+		}
+
+		port, err := n.AddPortMapping("tcp", 8333, 8333, "", 20*60)
+		if err != nil {
+			t.Fatalf("%v: unable to map the port: %v", n, err)
+		}
+		if port != 8334 {
+			t.Fatalf("%v: got external port %d, want 8334", n, port)
+		}
+		if n.leaseLifetime() != 10*time.Minute {
+			t.Fatalf("%v: got lifetime %v, want 10m", n, n.leaseLifetime())
+		}
+		ip, err := n.GetExternalAddress()
+		if err != nil {
+			t.Fatalf("%v: unable to get the external address: %v", n, err)
+		}
+		if !ip.Equal(net.IPv4(203, 0, 113, 5)) {
+			t.Fatalf("%v: got external address %v", n, ip)
+		}
+		if err := n.DeletePortMapping("tcp", 8333, 8333); err != nil {
+			t.Fatalf("%v: unable to delete the mapping: %v", n, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// netProxyNone is the --netproxy address for connecting to the peers on the
+// network directly rather than through --proxy.
+const netProxyNone = "none"
+
+// netProxies are the SOCKS5 proxies given with --netproxy for the peers on IPv4
+// and IPv6, which are used instead of --proxy.  The address is netProxyNone for
+// the networks whose peers are connected to directly, and empty for those that
+// use --proxy.  The peers on Tor and I2P are reached with --onion and --i2psam.
+type netProxies [numPeerNetworks]string
+
+// parseNetProxies parses the --netproxy values of the form network=addr.  The
+// last value given for a network is the one that counts.
+func parseNetProxies(values []string) (netProxies, error) {
+	var proxies netProxies
+	for _, value := range values {
+		name, addr, found := strings.Cut(value, "=")
+		if !found {
+			return proxies, fmt.Errorf("network proxy %q isn't of "+
+				"the form network=addr", value)
+		}
+
+		var network peerNetwork
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case netIPv4.String():
+			network = netIPv4
+		case netIPv6.String():
+			network = netIPv6
+		case netOnion.String():
+			return proxies, fmt.Errorf("the proxy for onion is " +
+				"given with --onion")
+		case netI2P.String():
+			return proxies, fmt.Errorf("the I2P SAM bridge is " +
+				"given with --i2psam")
+		default:
+			return proxies, fmt.Errorf("unknown network %q -- "+
+				"supported networks are %s and %s", name,
+				netIPv4, netIPv6)
+		}
+
+		addr = strings.TrimSpace(addr)
+		if addr != netProxyNone {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				return proxies, fmt.Errorf("proxy address %q "+
+					"for network %s is invalid: %v", addr,
+					network, err)
+			}
+		}
+		proxies[network] = addr
+	}
+	return proxies, nil
+}
+
+// proxyOf returns the proxy the peers on the IPv4 or IPv6 network are connected
+// to through, which is the --proxy one unless the network is given its own, or
+// empty when they're connected to directly.
+func (p *netProxies) proxyOf(network peerNetwork, proxy string) string {
+	switch p[network] {
+	case "":
+		return proxy
+	case netProxyNone:
+		return ""
+	default:
+		return p[network]
+	}
+}
+
+// proxied returns whether any of the networks is given a proxy rather than
+// being connected to directly.
+func (p *netProxies) proxied() bool {
+	for _, addr := range p {
+		if addr != "" && addr != netProxyNone {
+			return true
+		}
+	}
+	return false
+}
+
+// clearnetOf returns the network of the address to dial, which is IPv6 for
+// the IPv6 addresses and IPv4 for everything else, including host names.
+func clearnetOf(addr string) peerNetwork {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return netIPv6
+	}
+	return netIPv4
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+// TestParseNetProxies ensures that the --netproxy values are parsed, that the
+// networks without proxies of their own use --proxy, and that the networks
+// other than IPv4 and IPv6 and invalid addresses are rejected.
+func TestParseNetProxies(t *testing.T) {
+	proxies, err := parseNetProxies([]string{"IPv6=127.0.0.1:9052",
+		"ipv4=127.0.0.1:1080", "ipv4=none"})
+	if err != nil {
+		t.Fatalf("unable to parse the network proxies: %v", err)
+	}
+	want := netProxies{netIPv4: netProxyNone, netIPv6: "127.0.0.1:9052"}
+	if proxies != want {
+		t.Fatalf("got network proxies %v, want %v", proxies, want)
+	}
+	if !proxies.proxied() {
+		t.Fatalf("expected IPv6 to be proxied")
+	}
+	if proxy := proxies.proxyOf(netIPv4, "127.0.0.1:9050"); proxy != "" {
+		t.Fatalf("got proxy %q for ipv4, want none", proxy)
+	}
+	if proxy := proxies.proxyOf(netIPv6, "127.0.0.1:9050"); proxy != "127.0.0.1:9052" {
+		t.Fatalf("got proxy %q for ipv6, want 127.0.0.1:9052", proxy)
+	}
+	var none netProxies
+	if proxy := none.proxyOf(netIPv6, "127.0.0.1:9050"); proxy != "127.0.0.1:9050" {
+		t.Fatalf("got proxy %q for ipv6, want the --proxy one", proxy)
+	}
+
+	for _, value := range []string{"ipv4", "onion=127.0.0.1:9050",
+		"i2p=127.0.0.1:7656", "cjdns=none", "ipv6=127.0.0.1"} {
+
+		if _, err := parseNetProxies([]string{value}); err == nil {
+			t.Errorf("%s: expected the network proxy to be rejected",
+				value)
+		}
+	}
+}
+
+// TestClearnetOf ensures that the addresses are dialed through the proxies of
+// their networks.
+func TestClearnetOf(t *testing.T) {
+	tests := []struct {
+		addr    string
+		network peerNetwork
+	}{
+		{"1.2.3.4:8333", netIPv4},
+		{"[::ffff:1.2.3.4]:8333", netIPv4},
+		{"[2001:db8::1]:8333", netIPv6},
+		{"seed.example.com:8333", netIPv4},
+	}
+	for _, test := range tests {
+		if network := clearnetOf(test.addr); network != test.network {
+			t.Errorf("%s: got network %v, want %v", test.addr,
+				network, test.network)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"time"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	peerpkg "github.com/utreexo/utreexod/peer"
+)
+
+const (
+	// minBlockDownloadWindow and maxBlockDownloadWindow bound the number of
+	// blocks past the next block to connect that may be requested in
+	// headers-first mode.  The window is twice the number of blocks that
+	// may be in flight from the peers, so that the peers are kept busy
+	// while the blocks before the ones they send are still arriving.
+	minBlockDownloadWindow = 32
+	maxBlockDownloadWindow = 1024
+
+	// maxDownloadWindowBytes bounds the window by the size of the blocks.
+	// Blocks that arrive out of order are held in memory until the blocks
+	// before them are connected, so the window bounds how many of them are
+	// held.
+	maxDownloadWindowBytes = 256 * 1024 * 1024
+
+	// blockRequestInterval is the interval at which the blocks of the
+	// download window are requested and stalls are checked for.
+	blockRequestInterval = time.Second
+
+	// minPeerBlocksInFlight is the number of blocks that may always be
+	// requested from a peer, which is what peers start out with before
+	// their throughput is known.
+	minPeerBlocksInFlight = 4
+
+	// maxPeerBlocksInFlight is the maximum number of blocks that may be
+	// requested from a single peer.
+	maxPeerBlocksInFlight = 64
+
+	// blockRequestHorizon is how long the blocks requested from a peer
+	// should take it to send at its throughput.  Faster peers are given
+	// more blocks at once this way.
+	blockRequestHorizon = 10 * time.Second
+
+	// throughputAlpha is the weight of the newest sample in the moving
+	// averages of the throughput of the peers and the size of the blocks.
+	throughputAlpha = 0.2
+
+	// minBlockStallTimeout is the time after which the block holding up
+	// the download window is taken away from the peer it was requested
+	// from.  The timeout doubles with every stall up to
+	// maxBlockStallTimeout, since the blocks, along with their utreexo
+	// proofs, may simply be too large for the peers to send in time.  It
+	// shrinks back towards minBlockStallTimeout as blocks arrive.
+	minBlockStallTimeout = 5 * time.Second
+	maxBlockStallTimeout = 64 * time.Second
+
+	// blockDeadlineSlack is how many times longer than expected a block,
+	// along with its utreexo proof, may take to arrive before it's
+	// requested from another peer.
+	blockDeadlineSlack = 2
+)
+
+// blockRequest is a block requested from a peer.
+type blockRequest struct {
+	peer      *peerpkg.Peer
+	requested time.Time
+
+	// deadline is when the block is requested from another peer if the
+	// peer hasn't sent it yet.
+	deadline time.Time
+}
+
+// blockPeerState is the download state the scheduler keeps for a peer.
+type blockPeerState struct {
+	// inFlight is the blocks requested from the peer.
+	inFlight map[chainhash.Hash]struct{}
+
+	// released is the blocks that were requested from the peer but were
+	// requested from another peer since.  The peer may still send them.
+	released map[chainhash.Hash]struct{}
+
+	// throughput is the moving average of the bytes per second the peer
+	// sends blocks at, or zero when the peer hasn't sent any yet.
+	throughput float64
+
+	// lastReceived is when the peer last sent a block.
+	lastReceived time.Time
+
+	// latency is the round trip time of the last ping of the peer.
+	latency time.Duration
+
+	// stalled is set when the peer held up the download window.  A stalled
+	// peer isn't requested blocks from until it sends one.
+	stalled bool
+}
+
+// blockScheduler spreads the requests of the blocks of the headers-first
+// download over the peers.  The peers are requested blocks in proportion to
+// their throughput and latency, so a single slow peer can't hold up the
+// download, and the block holding up the download window is requested from
+// another peer when the peer it was requested from stalls.  The other blocks
+// are requested from another peer once they're past their deadline.
+type blockScheduler struct {
+	requests map[chainhash.Hash]*blockRequest
+	peers    map[*peerpkg.Peer]*blockPeerState
+
+	// blockSize is the moving average of the size of the blocks.
+	blockSize float64
+
+	// stallTimeout is the current timeout of the block holding up the
+	// download window.
+	stallTimeout time.Duration
+}
+
+// newBlockScheduler returns a new, empty, block scheduler.
+func newBlockScheduler() *blockScheduler {
+	return &blockScheduler{
+		requests:     make(map[chainhash.Hash]*blockRequest),
+		peers:        make(map[*peerpkg.Peer]*blockPeerState),
+		stallTimeout: minBlockStallTimeout,
+	}
+}
+
+// peerState returns the state of the passed peer, creating it if needed.
+func (s *blockScheduler) peerState(peer *peerpkg.Peer) *blockPeerState {
+	state, exists := s.peers[peer]
+	if !exists {
+		state = &blockPeerState{
+			inFlight: make(map[chainhash.Hash]struct{}),
+			released: make(map[chainhash.Hash]struct{}),
+		}
+		s.peers[peer] = state
+	}
+	return state
+}
+
+// rate returns the throughput the peer is expected to send blocks at.  Peers
+// that haven't sent a block yet are expected to be as fast as the average
+// peer.
+func (s *blockScheduler) rate(state *blockPeerState) float64 {
+	if state.throughput > 0 {
+		return state.throughput
+	}
+
+	var total float64
+	var known int
+	for _, other := range s.peers {
+		if other.throughput > 0 {
+			total += other.throughput
+			known++
+		}
+	}
+	if known == 0 {
+		return 1
+	}
+	return total / float64(known)
+}
+
+// capacity returns the number of blocks that may be in flight from the peer.
+// The blocks cover the round trip of the requests on top of the request
+// horizon, so that the peers far away aren't left waiting for requests.
+func (s *blockScheduler) capacity(state *blockPeerState) int {
+	if state.throughput == 0 || s.blockSize == 0 {
+		return minPeerBlocksInFlight
+	}
+
+	horizon := blockRequestHorizon + state.latency
+	blocks := int(state.throughput * horizon.Seconds() / s.blockSize)
+	switch {
+	case blocks < minPeerBlocksInFlight:
+		return minPeerBlocksInFlight
+	case blocks > maxPeerBlocksInFlight:
+		return maxPeerBlocksInFlight
+	}
+	return blocks
+}
+
+// window returns the number of blocks past the next block to connect that may
+// be requested from the passed peers, which follows the number of blocks that
+// may be in flight from them and the size of the blocks.
+func (s *blockScheduler) window(peers []*peerpkg.Peer) int {
+	var inFlight int
+	for _, peer := range peers {
+		inFlight += s.capacity(s.peerState(peer))
+	}
+
+	window := 2 * inFlight
+	if s.blockSize > 0 {
+		if max := int(maxDownloadWindowBytes / s.blockSize); window > max {
+			window = max
+		}
+	}
+	switch {
+	case window < minBlockDownloadWindow:
+		return minBlockDownloadWindow
+	case window > maxBlockDownloadWindow:
+		return maxBlockDownloadWindow
+	}
+	return window
+}
+
+// deadline returns when the block requested from the peer at the passed time is
+// requested from another peer if it hasn't arrived, which is once it's late by
+// blockDeadlineSlack times the time the peer is expected to take to send it
+// after the blocks ahead of it.  It's no sooner than the stall timeout.
+func (s *blockScheduler) deadline(state *blockPeerState, now time.Time) time.Time {
+	expected := state.latency
+	if s.blockSize > 0 {
+		blocks := float64(len(state.inFlight) + 1)
+		seconds := blocks * s.blockSize / s.rate(state)
+		expected += time.Duration(seconds * float64(time.Second))
+	}
+
+	timeout := expected * blockDeadlineSlack
+	switch {
+	case timeout < s.stallTimeout:
+		timeout = s.stallTimeout
+	case timeout > maxBlockStallTimeout:
+		timeout = maxBlockStallTimeout
+	}
+	return now.Add(timeout)
+}
+
+// isRequested returns whether the block is in flight from a peer.
+func (s *blockScheduler) isRequested(hash *chainhash.Hash) bool {
+	_, exists := s.requests[*hash]
+	return exists
+}
+
+// schedule assigns the passed blocks that aren't in flight yet to the peers at
+// the passed time, and returns the blocks to request from each peer.  Each
+// block goes to the peer that is expected to send it the soonest among the
+// peers that have room for more blocks, preferring the peers it wasn't taken
+// away from before.
+func (s *blockScheduler) schedule(hashes []chainhash.Hash, peers []*peerpkg.Peer,
+	now time.Time) map[*peerpkg.Peer][]chainhash.Hash {
+
+	for _, peer := range peers {
+		if ping := peer.LastPingMicros(); ping > 0 {
+			s.peerState(peer).latency = time.Duration(ping) * time.Microsecond
+		}
+	}
+
+	requests := make(map[*peerpkg.Peer][]chainhash.Hash)
+	for _, hash := range hashes {
+		if _, exists := s.requests[hash]; exists {
+			continue
+		}
+
+		best, bestState := s.pick(hash, peers, false)
+		if best == nil {
+			best, bestState = s.pick(hash, peers, true)
+		}
+		if best == nil {
+			continue
+		}
+
+		s.requests[hash] = &blockRequest{
+			peer:      best,
+			requested: now,
+			deadline:  s.deadline(bestState, now),
+		}
+		bestState.inFlight[hash] = struct{}{}
+		delete(bestState.released, hash)
+		requests[best] = append(requests[best], hash)
+	}
+	return requests
+}
+
+// pick returns the peer with room for more blocks that is expected to send the
+// block the soonest, skipping the peers the block was taken away from unless
+// released is set.
+func (s *blockScheduler) pick(hash chainhash.Hash, peers []*peerpkg.Peer,
+	released bool) (*peerpkg.Peer, *blockPeerState) {
+
+	var best *peerpkg.Peer
+	var bestState *blockPeerState
+	var bestTime float64
+	for _, peer := range peers {
+		state := s.peerState(peer)
+		if state.stalled || len(state.inFlight) >= s.capacity(state) {
+			continue
+		}
+		if _, exists := state.released[hash]; exists && !released {
+			continue
+		}
+
+		eta := float64(len(state.inFlight)+1) / s.rate(state)
+		if best == nil || eta < bestTime {
+			best, bestState, bestTime = peer, state, eta
+		}
+	}
+	return best, bestState
+}
+
+// release takes the block away from the peer it is in flight from, and
+// remembers that the peer may still send it.
+func (s *blockScheduler) release(hash chainhash.Hash) {
+	req, exists := s.requests[hash]
+	if !exists {
+		return
+	}
+	delete(s.requests, hash)
+
+	state := s.peerState(req.peer)
+	delete(state.inFlight, hash)
+	limitAdd(state.released, hash, maxRequestedBlocks)
+}
+
+// received records that the peer sent the block of the passed size at the
+// passed time.  It returns whether the block was requested from the peer.  A
+// block that was requested from another peer since is taken away from that
+// peer.
+func (s *blockScheduler) received(peer *peerpkg.Peer, hash *chainhash.Hash,
+	size int, now time.Time) bool {
+
+	state, exists := s.peers[peer]
+	if !exists {
+		return false
+	}
+	_, inFlight := state.inFlight[*hash]
+	_, released := state.released[*hash]
+	if !inFlight && !released {
+		return false
+	}
+
+	// Samples are taken from the later of the request and the previous
+	// block, since the peer sends the blocks requested from it one after
+	// the other.
+	start := state.lastReceived
+	req, exists := s.requests[*hash]
+	if inFlight && req.requested.After(start) {
+		start = req.requested
+	}
+	if !start.IsZero() {
+		elapsed := now.Sub(start).Seconds()
+		if elapsed < 1e-3 {
+			elapsed = 1e-3
+		}
+		sample := float64(size) / elapsed
+		if state.throughput == 0 {
+			state.throughput = sample
+		} else {
+			state.throughput += throughputAlpha * (sample - state.throughput)
+		}
+	}
+	if s.blockSize == 0 {
+		s.blockSize = float64(size)
+	} else {
+		s.blockSize += throughputAlpha * (float64(size) - s.blockSize)
+	}
+	state.lastReceived = now
+	state.stalled = false
+
+	if exists {
+		s.release(*hash)
+	}
+	delete(s.peerState(peer).released, *hash)
+	return true
+}
+
+// notFound records that the peer doesn't have the block, which is then
+// requested from another peer.  The peer isn't requested more blocks until it
+// sends one of those it was already asked for.
+func (s *blockScheduler) notFound(peer *peerpkg.Peer, hash *chainhash.Hash) bool {
+	req, exists := s.requests[*hash]
+	if !exists || req.peer != peer {
+		return false
+	}
+	s.release(*hash)
+	s.peerState(peer).stalled = true
+	return true
+}
+
+// checkStall checks whether the next block to connect, which holds up the
+// download window, has been in flight for longer than the stall timeout at the
+// passed time.  When it has, all the blocks in flight from the peer it was
+// requested from are released and that peer is returned.
+func (s *blockScheduler) checkStall(next *chainhash.Hash, now time.Time) *peerpkg.Peer {
+	req, exists := s.requests[*next]
+	if !exists || now.Sub(req.requested) <= s.stallTimeout {
+		return nil
+	}
+
+	state := s.peerState(req.peer)
+	for hash := range state.inFlight {
+		s.release(hash)
+	}
+	state.stalled = true
+
+	s.stallTimeout *= 2
+	if s.stallTimeout > maxBlockStallTimeout {
+		s.stallTimeout = maxBlockStallTimeout
+	}
+	return req.peer
+}
+
+// expire takes the blocks that are past their deadline at the passed time away
+// from the peers they were requested from, so that they're requested from other
+// peers, and returns them.  Unlike a stalled peer, the peers keep being
+// requested other blocks.
+func (s *blockScheduler) expire(now time.Time) []chainhash.Hash {
+	var expired []chainhash.Hash
+	for hash, req := range s.requests {
+		if now.After(req.deadline) {
+			expired = append(expired, hash)
+		}
+	}
+	for _, hash := range expired {
+		s.release(hash)
+	}
+	return expired
+}
+
+// connected records that the next block was connected without stalling, which
+// shrinks the stall timeout back towards its minimum.
+func (s *blockScheduler) connected() {
+	s.stallTimeout = s.stallTimeout * 85 / 100
+	if s.stallTimeout < minBlockStallTimeout {
+		s.stallTimeout = minBlockStallTimeout
+	}
+}
+
+// removePeer forgets about the passed peer, releasing the blocks in flight
+// from it.
+func (s *blockScheduler) removePeer(peer *peerpkg.Peer) {
+	state, exists := s.peers[peer]
+	if !exists {
+		return
+	}
+	for hash := range state.inFlight {
+		delete(s.requests, hash)
+	}
+	delete(s.peers, peer)
+}
+
+// reset releases all the blocks in flight, such as when the download starts
+// over with a new sync peer.  The peers may still send them.
+func (s *blockScheduler) reset() {
+	for hash := range s.requests {
+		s.release(hash)
+	}
+	s.stallTimeout = minBlockStallTimeout
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	peerpkg "github.com/utreexo/utreexod/peer"
+)
+
+// TestBlockScheduler ensures that the scheduler spreads the blocks over the
+// peers by their throughput, takes the blocks away from a peer that stalls and
+// still accepts the blocks that the stalled peer sends late.
+func TestBlockScheduler(t *testing.T) {
+	t.Parallel()
+
+	fast, slow := &peerpkg.Peer{}, &peerpkg.Peer{}
+	peers := []*peerpkg.Peer{fast, slow}
+	hashes := make([]chainhash.Hash, 200)
+	for i := range hashes {
+		hashes[i] = chainhash.Hash{byte(i), byte(i >> 8)}
+	}
+
+	// Peers start out with the same number of blocks in flight before
+	// their throughput is known.
+	s := newBlockScheduler()
+	start := time.Now()
+	requests := s.schedule(hashes, peers, start)
+	if len(requests[fast]) != minPeerBlocksInFlight ||
+		len(requests[slow]) != minPeerBlocksInFlight {
+		t.Fatalf("expected %d blocks from each peer, got %d and %d",
+			minPeerBlocksInFlight, len(requests[fast]),
+			len(requests[slow]))
+	}
+	if more := s.schedule(hashes, peers, start); len(more) != 0 {
+		t.Fatalf("expected no more blocks to be requested, got %v", more)
+	}
+
+	// The fast peer sends a block every 10ms and the slow peer every
+	// second, so the fast peer is given many more blocks.
+	const size = 100000
+	for i, hash := range requests[fast] {
+		now := start.Add(time.Duration(i+1) * 10 * time.Millisecond)
+		if !s.received(fast, &hash, size, now) {
+			t.Fatalf("expected block %v to be requested", hash)
+		}
+	}
+	slowHash := requests[slow][0]
+	if !s.received(slow, &slowHash, size, start.Add(time.Second)) {
+		t.Fatalf("expected block %v to be requested", slowHash)
+	}
+	if s.received(slow, &slowHash, size, start.Add(time.Second)) {
+		t.Fatalf("expected a block sent twice not to be requested")
+	}
+
+	now := start.Add(time.Second)
+	requests = s.schedule(hashes[2*minPeerBlocksInFlight:], peers, now)
+	if len(requests[fast]) <= len(requests[slow]) {
+		t.Fatalf("expected more blocks from the fast peer, got %d "+
+			"and %d", len(requests[fast]), len(requests[slow]))
+	}
+	if got := len(s.peers[fast].inFlight); got > maxPeerBlocksInFlight {
+		t.Fatalf("expected at most %d blocks in flight, got %d",
+			maxPeerBlocksInFlight, got)
+	}
+
+	// A block that isn't late yet doesn't count as a stall.
+	stallHash := requests[slow][0]
+	if peer := s.checkStall(&stallHash, now.Add(time.Second)); peer != nil {
+		t.Fatalf("expected no stall, got %v", peer)
+	}
+
+	// Once the slow peer holds up the download, all its blocks are taken
+	// away and requested from the fast peer, and the slow peer isn't asked
+	// for any until it sends a block.
+	now = now.Add(minBlockStallTimeout + time.Second)
+	if peer := s.checkStall(&stallHash, now); peer != slow {
+		t.Fatalf("expected the slow peer to stall, got %v", peer)
+	}
+	if s.stallTimeout != 2*minBlockStallTimeout {
+		t.Fatalf("expected the stall timeout to double, got %v",
+			s.stallTimeout)
+	}
+	if len(s.peers[slow].inFlight) != 0 || s.isRequested(&stallHash) {
+		t.Fatalf("expected the blocks of the slow peer to be released")
+	}
+	for _, hash := range requests[fast] {
+		hash := hash
+		s.received(fast, &hash, size, now)
+	}
+	requests = s.schedule([]chainhash.Hash{stallHash}, peers, now)
+	if len(requests[fast]) != 1 || len(requests[slow]) != 0 {
+		t.Fatalf("expected the block to be requested from the fast "+
+			"peer, got %v", requests)
+	}
+
+	// The slow peer may still send the block, after which the fast peer
+	// isn't expected to send it anymore but may do so anyway.
+	if !s.received(slow, &stallHash, size, now.Add(time.Second)) {
+		t.Fatalf("expected the late block to be accepted")
+	}
+	if s.isRequested(&stallHash) || s.peers[slow].stalled {
+		t.Fatalf("expected the block to be received")
+	}
+	if !s.received(fast, &stallHash, size, now.Add(time.Second)) {
+		t.Fatalf("expected the block to be accepted from the fast peer")
+	}
+
+	// The stall timeout shrinks as blocks are connected.
+	for i := 0; i < 10; i++ {
+		s.connected()
+	}
+	if s.stallTimeout != minBlockStallTimeout {
+		t.Fatalf("expected the stall timeout to shrink to %v, got %v",
+			minBlockStallTimeout, s.stallTimeout)
+	}
+
+	// The blocks of a peer that is gone are requested from another peer,
+	// as are the ones a peer doesn't have.
+	requests = s.schedule(hashes[150:], []*peerpkg.Peer{slow}, now)
+	if len(requests[slow]) == 0 {
+		t.Fatalf("expected blocks to be requested from the slow peer")
+	}
+	s.removePeer(slow)
+	for _, hash := range requests[slow] {
+		hash := hash
+		if s.isRequested(&hash) {
+			t.Fatalf("expected block %v to be released", hash)
+		}
+	}
+	requests = s.schedule(hashes[150:151], peers, now)
+	notFoundHash := requests[fast][0]
+	if !s.notFound(fast, &notFoundHash) || s.isRequested(&notFoundHash) {
+		t.Fatalf("expected block %v to be released", notFoundHash)
+	}
+}
+
+// TestBlockSchedulerDeadlines ensures that the blocks past their deadline are
+// requested from another peer while the peer they were taken away from keeps
+// being requested other blocks.
+func TestBlockSchedulerDeadlines(t *testing.T) {
+	t.Parallel()
+
+	first, second := &peerpkg.Peer{}, &peerpkg.Peer{}
+	hashes := make([]chainhash.Hash, 2)
+	for i := range hashes {
+		hashes[i] = chainhash.Hash{byte(i)}
+	}
+
+	s := newBlockScheduler()
+	start := time.Now()
+	requests := s.schedule(hashes[:1], []*peerpkg.Peer{first}, start)
+	if len(requests[first]) != 1 {
+		t.Fatalf("expected the block to be requested from the first "+
+			"peer, got %v", requests)
+	}
+	if expired := s.expire(start.Add(minBlockStallTimeout)); len(expired) != 0 {
+		t.Fatalf("expected no blocks past their deadline, got %v", expired)
+	}
+
+	now := start.Add(minBlockStallTimeout + time.Second)
+	expired := s.expire(now)
+	if len(expired) != 1 || expired[0] != hashes[0] || s.isRequested(&hashes[0]) {
+		t.Fatalf("expected the block to be past its deadline, got %v",
+			expired)
+	}
+	if s.peers[first].stalled {
+		t.Fatalf("expected the first peer not to count as stalled")
+	}
+
+	// The block goes to the other peer, and new blocks still go to the
+	// first peer.
+	peers := []*peerpkg.Peer{first, second}
+	requests = s.schedule(hashes, peers, now)
+	if len(requests[second]) != 1 || requests[second][0] != hashes[0] {
+		t.Fatalf("expected the late block to be requested from the "+
+			"second peer, got %v", requests)
+	}
+	if len(requests[first]) != 1 || requests[first][0] != hashes[1] {
+		t.Fatalf("expected the new block to be requested from the "+
+			"first peer, got %v", requests)
+	}
+}
+
+// TestBlockSchedulerWindow ensures that the download window follows the blocks
+// the peers can have in flight, their latency and the size of the blocks.
+func TestBlockSchedulerWindow(t *testing.T) {
+	t.Parallel()
+
+	peer := &peerpkg.Peer{}
+	peers := []*peerpkg.Peer{peer}
+	s := newBlockScheduler()
+	if window := s.window(peers); window != minBlockDownloadWindow {
+		t.Fatalf("got window %d before the throughput is known, want %d",
+			window, minBlockDownloadWindow)
+	}
+
+	// A peer that sends 1MB blocks at 2MB/s can have 20 of them in flight
+	// over the request horizon, and another 10 to cover a latency of 5s.
+	state := s.peerState(peer)
+	state.throughput = 2e6
+	s.blockSize = 1e6
+	if capacity := s.capacity(state); capacity != 20 {
+		t.Fatalf("got capacity %d, want 20", capacity)
+	}
+	state.latency = 5 * time.Second
+	if capacity := s.capacity(state); capacity != 30 {
+		t.Fatalf("got capacity %d with latency, want 30", capacity)
+	}
+	if window := s.window(peers); window != 60 {
+		t.Fatalf("got window %d, want 60", window)
+	}
+
+	// Small blocks are limited by the blocks a peer may have in flight,
+	// and large ones by the memory the blocks held out of order take.
+	s.blockSize = 1000
+	if window := s.window(peers); window != 2*maxPeerBlocksInFlight {
+		t.Fatalf("got window %d for small blocks, want %d", window,
+			2*maxPeerBlocksInFlight)
+	}
+	state.throughput = 1e9
+	s.blockSize = 8e6
+	want := maxDownloadWindowBytes / 8000000
+	if window := s.window(peers); window != want {
+		t.Fatalf("got window %d for large blocks, want %d", window, want)
+	}
+}
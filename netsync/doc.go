@@ -9,5 +9,10 @@ download, keep the chain and unconfirmed transaction pool in sync, and announce
 new blocks connected to the chain. Currently the sync manager selects a single
 sync peer that it downloads all blocks from until it is up to date with the
 longest chain the sync peer is aware of.
+
+Transactions announced by several peers are requested from one peer at a time.
+Outbound peers are preferred and inbound peers are only asked after a delay, and
+a transaction a peer doesn't send in time is requested from the next peer that
+announced it.
 */
 package netsync
@@ -0,0 +1,411 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	peerpkg "github.com/utreexo/utreexod/peer"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// headerAuditInterval is the interval at which the headers of the best
+	// chains of the peers are synced and compared once the chain is
+	// current.
+	headerAuditInterval = 10 * time.Minute
+
+	// headerAuditTimeout is how long a peer has to send the headers of its
+	// best chain before they're requested again.
+	headerAuditTimeout = 2 * time.Minute
+
+	// maxHeaderAuditPeers is the most peers the headers are synced from at
+	// once for each audit.  Outbound peers are picked first.
+	maxHeaderAuditPeers = 4
+
+	// maxHeaderAuditHeaders is the most headers past the best chain that
+	// are synced from a peer.  It also bounds how far below the tip the
+	// work of the best chain is added up when comparing it.
+	maxHeaderAuditHeaders = 4 * wire.MaxBlockHeadersPerMsg
+
+	// forkAlertBlocks is the number of blocks, at the difficulty of the
+	// best block, that two chains need the work of past the point they
+	// fork at for the fork to be alerted.  Forks of a block or two happen
+	// now and then when blocks are found at about the same time.
+	forkAlertBlocks = 6
+)
+
+// headerChain is the chain of a peer as it was synced, which is the best chain
+// up to the fork height followed by the headers the peer sent, or the best
+// chain itself when peer is nil.
+type headerChain struct {
+	peer   *peerpkg.Peer
+	fork   int32
+	hashes []chainhash.Hash
+	work   []*big.Int
+}
+
+// tipHeight returns the height of the last header of the chain.
+func (c *headerChain) tipHeight() int32 {
+	return c.fork + int32(len(c.hashes))
+}
+
+// tipHash returns the hash of the last header of the chain, or nil when it's
+// the block of the best chain at the fork height.
+func (c *headerChain) tipHash() *chainhash.Hash {
+	if len(c.hashes) == 0 {
+		return nil
+	}
+	return &c.hashes[len(c.hashes)-1]
+}
+
+// String returns the peer of the chain, or "best chain" for the best chain.
+func (c *headerChain) String() string {
+	if c.peer == nil {
+		return "best chain"
+	}
+	return fmt.Sprintf("peer %s", c.peer)
+}
+
+// commonHeight returns the height of the last block the chains have in common
+// and whether they conflict, which is when neither of their tips is in the
+// other chain.
+func commonHeight(a, b *headerChain) (int32, bool) {
+	if a.fork != b.fork {
+		// The chain that leaves the best chain first only conflicts
+		// with the other when it has headers of its own.
+		lower := a
+		if b.fork < a.fork {
+			lower = b
+		}
+		return lower.fork, len(lower.hashes) > 0
+	}
+
+	n := len(a.hashes)
+	if len(b.hashes) < n {
+		n = len(b.hashes)
+	}
+	for i := 0; i < n; i++ {
+		if a.hashes[i] != b.hashes[i] {
+			return a.fork + int32(i), true
+		}
+	}
+	return a.fork + int32(n), false
+}
+
+// branchWork returns the work of the chain past the height, where mainWork
+// returns the work of the best chain past a height up to another, or false
+// when it's too far below the tip to add up.  The work is nil when it's too
+// far below the tip.
+func branchWork(c *headerChain, height int32,
+	mainWork func(from, to int32) (*big.Int, bool)) *big.Int {
+
+	work := new(big.Int)
+	if height < c.fork {
+		main, ok := mainWork(height, c.fork)
+		if !ok {
+			return nil
+		}
+		work.Add(work, main)
+		height = c.fork
+	}
+	for _, headerWork := range c.work[height-c.fork:] {
+		work.Add(work, headerWork)
+	}
+	return work
+}
+
+// forkConflict is a pair of chains that both have at least the work that is
+// alerted past the point they fork at.
+type forkConflict struct {
+	a, b   *headerChain
+	height int32
+}
+
+// String returns a description of the conflict.
+func (f *forkConflict) String() string {
+	describe := func(c *headerChain) string {
+		if hash := c.tipHash(); hash != nil {
+			return fmt.Sprintf("%s (tip %v at height %d)", c, hash,
+				c.tipHeight())
+		}
+		return fmt.Sprintf("%s (tip at height %d of the best chain)", c,
+			c.tipHeight())
+	}
+	return fmt.Sprintf("%s and %s fork at height %d", describe(f.a),
+		describe(f.b), f.height)
+}
+
+// findForkConflicts returns the pairs of the chains that conflict with each
+// other with at least the passed work past the point they fork at.  Chains that
+// branch off too far below the tip for the work of the best chain to be added
+// up are taken to have enough of it.
+func findForkConflicts(chains []*headerChain, minWork *big.Int,
+	mainWork func(from, to int32) (*big.Int, bool)) []*forkConflict {
+
+	enough := func(c *headerChain, height int32) bool {
+		work := branchWork(c, height, mainWork)
+		return work == nil || work.Cmp(minWork) >= 0
+	}
+
+	var conflicts []*forkConflict
+	for i, a := range chains {
+		for _, b := range chains[i+1:] {
+			height, conflict := commonHeight(a, b)
+			if !conflict || !enough(a, height) || !enough(b, height) {
+				continue
+			}
+			conflicts = append(conflicts, &forkConflict{
+				a: a, b: b, height: height,
+			})
+		}
+	}
+	return conflicts
+}
+
+// headerAudit is the state of the sync of the headers of the best chain of a
+// peer, which are compared with those of the other peers.
+type headerAudit struct {
+	requested time.Time
+	started   bool
+	done      bool
+	chain     headerChain
+}
+
+// pushAuditGetHeaders asks the peer for the headers of its best chain past the
+// locator.  The request is queued directly, since the peer filters the
+// getheaders requests that begin at the same block as the last one and the tip
+// is likely unchanged since the last audit.
+func pushAuditGetHeaders(peer *peerpkg.Peer, locator blockchain.BlockLocator) error {
+	msg := wire.NewMsgGetHeaders()
+	for _, hash := range locator {
+		if err := msg.AddBlockLocatorHash(hash); err != nil {
+			return err
+		}
+	}
+	peer.QueueMessage(msg, nil)
+	return nil
+}
+
+// startHeaderAudit syncs the headers of the best chains of several peers once
+// the chain is current, which are compared once they're all in.  Peers that are
+// still sending them from the last audit are left to finish.
+func (sm *SyncManager) startHeaderAudit() {
+	if sm.headersFirstMode || sm.headersBuildMode || !sm.current() {
+		return
+	}
+
+	locator, err := sm.chain.LatestBlockLocator()
+	if err != nil {
+		log.Errorf("Failed to get block locator for the latest block: %v",
+			err)
+		return
+	}
+
+	// Outbound peers are picked before inbound ones, which are easier for
+	// an attacker to make up.
+	now := time.Now()
+	var outbound, inbound []*peerpkg.Peer
+	for peer, state := range sm.peerStates {
+		audit := state.headerAudit
+		if audit != nil && !audit.done &&
+			now.Sub(audit.requested) < headerAuditTimeout {
+
+			continue
+		}
+		if !sm.isSyncCandidate(peer) {
+			continue
+		}
+		if peer.Inbound() {
+			inbound = append(inbound, peer)
+		} else {
+			outbound = append(outbound, peer)
+		}
+	}
+	peers := append(outbound, inbound...)
+	if len(peers) > maxHeaderAuditPeers {
+		peers = peers[:maxHeaderAuditPeers]
+	}
+
+	for _, peer := range peers {
+		if err := pushAuditGetHeaders(peer, locator); err != nil {
+			log.Warnf("Failed to send getheaders message to peer "+
+				"%s: %v", peer.Addr(), err)
+			continue
+		}
+		sm.peerStates[peer].headerAudit = &headerAudit{requested: now}
+	}
+}
+
+// handleAuditHeaders adds the headers the peer sent to the chain of its audit,
+// and asks it for more while it has them.  The chains of the peers are compared
+// once none of them is still being synced.
+func (sm *SyncManager) handleAuditHeaders(peer *peerpkg.Peer,
+	audit *headerAudit, headers []*wire.BlockHeader) {
+
+	chain := &audit.chain
+	chain.peer = peer
+	powLimit := sm.chainParams.PowLimit
+	for i, header := range headers {
+		// The first header builds on the last block of the best chain
+		// the peer has, and the others on the header before them.
+		switch {
+		case !audit.started && i == 0:
+			height, err := sm.chain.BlockHeightByHash(&header.PrevBlock)
+			if err != nil || !sm.chain.MainChainHasBlock(&header.PrevBlock) {
+				log.Warnf("Received block header that does not "+
+					"properly connect to the chain from peer "+
+					"%s -- disconnecting", peer.Addr())
+				peer.Disconnect()
+				audit.done = true
+				return
+			}
+			chain.fork = height
+			audit.started = true
+
+		case header.PrevBlock != *chain.tipHash():
+			log.Warnf("Received block header that does not "+
+				"properly connect to the chain from peer %s "+
+				"-- disconnecting", peer.Addr())
+			peer.Disconnect()
+			audit.done = true
+			return
+		}
+
+		hash := header.BlockHash()
+		target := blockchain.CompactToBig(header.Bits)
+		if target.Sign() <= 0 || target.Cmp(powLimit) > 0 ||
+			blockchain.HashToBig(&hash).Cmp(target) > 0 {
+
+			log.Warnf("Received block header %v without enough "+
+				"proof of work from peer %s -- disconnecting",
+				hash, peer.Addr())
+			peer.Disconnect()
+			audit.done = true
+			return
+		}
+		chain.hashes = append(chain.hashes, hash)
+		chain.work = append(chain.work, blockchain.CalcWork(header.Bits))
+	}
+
+	// A peer without headers past the locator has the best chain.
+	if !audit.started {
+		chain.fork = sm.chain.BestSnapshot().Height
+		audit.started = true
+	}
+
+	// Ask for more headers while the peer sends full messages of them.
+	if len(headers) == wire.MaxBlockHeadersPerMsg &&
+		len(chain.hashes) < maxHeaderAuditHeaders {
+
+		locator := blockchain.BlockLocator([]*chainhash.Hash{chain.tipHash()})
+		if err := pushAuditGetHeaders(peer, locator); err == nil {
+			return
+		}
+	}
+	audit.done = true
+
+	for _, state := range sm.peerStates {
+		if state.headerAudit != nil && !state.headerAudit.done {
+			return
+		}
+	}
+	sm.checkForks()
+}
+
+// mainChainWork returns the work of the blocks of the best chain past the
+// height up to another, or false when the first height is too far below the
+// tip to add it up.
+func (sm *SyncManager) mainChainWork(from, to int32) (*big.Int, bool) {
+	if to-from > maxHeaderAuditHeaders {
+		return nil, false
+	}
+	work := new(big.Int)
+	for height := from + 1; height <= to; height++ {
+		hash, err := sm.chain.BlockHashByHeight(height)
+		if err != nil {
+			return nil, false
+		}
+		header, err := sm.chain.HeaderByHash(hash)
+		if err != nil {
+			return nil, false
+		}
+		work.Add(work, blockchain.CalcWork(header.Bits))
+	}
+	return work, true
+}
+
+// checkForks compares the chains of the audited peers with each other and with
+// the best chain, and warns about those that conflict with the others with a
+// lot of work on both sides, since that's what an eclipse attack or a stale tip
+// look like.  The warning is kept until an audit finds no conflicts.
+func (sm *SyncManager) checkForks() {
+	best := sm.chain.BestSnapshot()
+	chains := []*headerChain{{fork: best.Height}}
+	for _, state := range sm.peerStates {
+		audit := state.headerAudit
+		if audit == nil || !audit.started {
+			continue
+		}
+		chain := audit.chain
+
+		// Drop the headers that made it into the best chain since.
+		for len(chain.hashes) > 0 &&
+			sm.chain.MainChainHasBlock(&chain.hashes[0]) {
+
+			chain.fork++
+			chain.hashes = chain.hashes[1:]
+			chain.work = chain.work[1:]
+		}
+		chains = append(chains, &chain)
+	}
+
+	minWork := blockchain.CalcWork(best.Bits)
+	minWork.Mul(minWork, big.NewInt(forkAlertBlocks))
+	conflicts := findForkConflicts(chains, minWork, sm.mainChainWork)
+
+	var warnings []string
+	for _, conflict := range conflicts {
+		warnings = append(warnings, conflict.String())
+	}
+	warning := ""
+	if len(warnings) > 0 {
+		warning = "Conflicting chains with a lot of work: " +
+			strings.Join(warnings, "; ")
+	}
+
+	sm.forkWarningMtx.Lock()
+	changed := warning != sm.forkWarning
+	sm.forkWarning = warning
+	sm.forkWarningMtx.Unlock()
+	if !changed {
+		return
+	}
+	if warning == "" {
+		log.Infof("The synced chains of the peers no longer conflict")
+		return
+	}
+	for _, conflict := range conflicts {
+		log.Warnf("Peers present conflicting chains with a lot of "+
+			"work: %v -- the node may be eclipsed or partitioned "+
+			"from the network", conflict)
+	}
+}
+
+// ForkWarning returns the warning about the chains of the peers conflicting
+// with each other or with the best chain, or an empty string when the last
+// audit of their headers found no conflicts.
+//
+// This function is safe for concurrent access.
+func (sm *SyncManager) ForkWarning() string {
+	sm.forkWarningMtx.Lock()
+	defer sm.forkWarningMtx.Unlock()
+	return sm.forkWarning
+}
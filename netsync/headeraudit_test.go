@@ -0,0 +1,80 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+)
+
+// testHeaderChain returns a chain that forks off the best chain at the height
+// with a header of a unit of work for each of the bytes.
+func testHeaderChain(fork int32, ids ...byte) *headerChain {
+	c := &headerChain{fork: fork}
+	for _, id := range ids {
+		c.hashes = append(c.hashes, chainhash.Hash{id})
+		c.work = append(c.work, big.NewInt(1))
+	}
+	return c
+}
+
+// TestFindForkConflicts ensures that only the chains that conflict with each
+// other with enough work on both sides past the point they fork at are found.
+func TestFindForkConflicts(t *testing.T) {
+	t.Parallel()
+
+	// The best chain is at height 100 with a unit of work for each block,
+	// and its blocks more than 50 below the tip aren't added up.
+	mainWork := func(from, to int32) (*big.Int, bool) {
+		if to-from > 50 {
+			return nil, false
+		}
+		return big.NewInt(int64(to - from)), true
+	}
+	best := testHeaderChain(100)
+	minWork := big.NewInt(3)
+
+	tests := []struct {
+		name      string
+		chains    []*headerChain
+		conflicts int
+	}{{
+		name:   "peer ahead of the best chain",
+		chains: []*headerChain{best, testHeaderChain(100, 1, 2, 3, 4)},
+	}, {
+		name: "peers on the same chain",
+		chains: []*headerChain{best, testHeaderChain(100, 1, 2),
+			testHeaderChain(100, 1, 2, 3, 4)},
+	}, {
+		name:      "peer on a fork of the best chain",
+		chains:    []*headerChain{best, testHeaderChain(96, 1, 2, 3, 4, 5)},
+		conflicts: 1,
+	}, {
+		name:   "fork without enough work on the best chain",
+		chains: []*headerChain{best, testHeaderChain(98, 1, 2, 3, 4, 5)},
+	}, {
+		name:   "fork without enough work on the peer chain",
+		chains: []*headerChain{best, testHeaderChain(90, 1, 2)},
+	}, {
+		name: "peers on forks of each other",
+		chains: []*headerChain{best, testHeaderChain(100, 1, 2, 3, 4),
+			testHeaderChain(100, 1, 5, 6, 7)},
+		conflicts: 1,
+	}, {
+		name: "peers forking too far below the tip",
+		chains: []*headerChain{best, testHeaderChain(10, 1, 2, 3),
+			testHeaderChain(20, 4, 5, 6)},
+		conflicts: 3,
+	}}
+	for _, test := range tests {
+		conflicts := findForkConflicts(test.chains, minWork, mainWork)
+		if len(conflicts) != test.conflicts {
+			t.Errorf("%s: got %d conflicts, want %d: %v", test.name,
+				len(conflicts), test.conflicts, conflicts)
+		}
+	}
+}
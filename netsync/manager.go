@@ -24,11 +24,6 @@ import (
 )
 
 const (
-	// minInFlightBlocks is the minimum number of blocks that should be
-	// in the request queue for headers-first mode before requesting
-	// more.
-	minInFlightBlocks = 10
-
 	// maxRejectedTxns is the maximum number of rejected transactions
 	// hashes to store in memory.
 	maxRejectedTxns = 1000
@@ -37,10 +32,6 @@ const (
 	// hashes to store in memory.
 	maxRequestedBlocks = wire.MaxInvPerMsg
 
-	// maxRequestedTxns is the maximum number of requested transactions
-	// hashes to store in memory.
-	maxRequestedTxns = wire.MaxInvPerMsg
-
 	// maxStallDuration is the time after which we will disconnect our
 	// current sync peer if we haven't made progress.
 	maxStallDuration = 3 * time.Minute
@@ -151,8 +142,11 @@ type headerNode struct {
 type peerSyncState struct {
 	syncCandidate   bool
 	requestQueue    []*wire.InvVect
-	requestedTxns   map[chainhash.Hash]struct{}
 	requestedBlocks map[chainhash.Hash]struct{}
+
+	// headerAudit is the sync of the headers of the best chain of the
+	// peer, which is compared with those of the other peers.
+	headerAudit *headerAudit
 }
 
 // limitAdd is a helper function for maps that require a maximum limit by
@@ -180,6 +174,10 @@ func limitAdd(m map[chainhash.Hash]struct{}, hash chainhash.Hash, limit int) {
 // chain is in sync, the SyncManager handles incoming block and header
 // notifications and relays announcements of new blocks to peers.
 type SyncManager struct {
+	// The counters must only be used atomically.  Putting them first makes
+	// them 64-bit aligned for 32-bit systems.
+	proofOrphanCounters proofOrphanCounters
+
 	peerNotifier   PeerNotifier
 	started        int32
 	shutdown       int32
@@ -193,23 +191,36 @@ type SyncManager struct {
 
 	// These fields should only be accessed from the blockHandler thread
 	rejectedTxns     map[chainhash.Hash]struct{}
-	requestedTxns    map[chainhash.Hash]struct{}
+	txRequests       *txRequestTracker
 	requestedBlocks  map[chainhash.Hash]struct{}
 	syncPeer         *peerpkg.Peer
 	peerStates       map[*peerpkg.Peer]*peerSyncState
 	lastProgressTime time.Time
 
+	// proofOrphans are the transactions waiting for a fresh utreexo proof.
+	proofOrphans map[chainhash.Hash]*proofOrphan
+
 	// headersBuildMode downloads and builds the entire header index.
 	headersBuildMode bool
 
 	// The following fields are used for headers-first mode.
 	headersFirstMode bool
 	headerList       *list.List
-	startHeader      *list.Element
 	nextCheckpoint   *chaincfg.Checkpoint
 
+	// blockScheduler spreads the block requests of headers-first mode
+	// over the peers, and queuedBlocks holds the blocks that arrived
+	// before the blocks they build on.
+	blockScheduler *blockScheduler
+	queuedBlocks   map[chainhash.Hash]*blockMsg
+
 	// An optional fee estimator.
 	feeEstimator *mempool.FeeEstimator
+
+	// forkWarning is the warning about the conflicting chains found by the
+	// last audit of the headers of the peers.
+	forkWarningMtx sync.Mutex
+	forkWarning    string
 }
 
 // resetHeaderState sets the headers-first mode state to values appropriate for
@@ -217,7 +228,8 @@ type SyncManager struct {
 func (sm *SyncManager) resetHeaderState(newestHash *chainhash.Hash, newestHeight int32) {
 	sm.headersFirstMode = false
 	sm.headerList.Init()
-	sm.startHeader = nil
+	sm.blockScheduler.reset()
+	sm.queuedBlocks = make(map[chainhash.Hash]*blockMsg)
 
 	// When there is a next checkpoint, add an entry for the latest known
 	// block into the header pool.  This allows the next downloaded header
@@ -460,7 +472,6 @@ func (sm *SyncManager) handleNewPeerMsg(peer *peerpkg.Peer) {
 	isSyncCandidate := sm.isSyncCandidate(peer)
 	sm.peerStates[peer] = &peerSyncState{
 		syncCandidate:   isSyncCandidate,
-		requestedTxns:   make(map[chainhash.Hash]struct{}),
 		requestedBlocks: make(map[chainhash.Hash]struct{}),
 	}
 
@@ -540,6 +551,9 @@ func (sm *SyncManager) handleDonePeerMsg(peer *peerpkg.Peer) {
 	log.Infof("Lost peer %s", peer)
 
 	sm.clearRequestedState(state)
+	sm.blockScheduler.removePeer(peer)
+	sm.txRequests.removePeer(peer)
+	sm.removePeerProofOrphans(peer)
 
 	if peer == sm.syncPeer {
 		// Update the sync peer. The server has already disconnected the
@@ -548,16 +562,10 @@ func (sm *SyncManager) handleDonePeerMsg(peer *peerpkg.Peer) {
 	}
 }
 
-// clearRequestedState wipes all expected transactions and blocks from the sync
-// manager's requested maps that were requested under a peer's sync state, This
-// allows them to be rerequested by a subsequent sync peer.
+// clearRequestedState wipes all expected blocks from the sync manager's
+// requested map that were requested under a peer's sync state, This allows them
+// to be rerequested by a subsequent sync peer.
 func (sm *SyncManager) clearRequestedState(state *peerSyncState) {
-	// Remove requested transactions from the global map so that they will
-	// be fetched from elsewhere next time we get an inv.
-	for txHash := range state.requestedTxns {
-		delete(sm.requestedTxns, txHash)
-	}
-
 	// Remove requested blocks from the global map so that they will be
 	// fetched from elsewhere next time we get an inv.
 	// TODO: we could possibly here check which peers have these blocks
@@ -593,7 +601,7 @@ func (sm *SyncManager) updateSyncPeer(dcSyncPeer bool) {
 // handleTxMsg handles transaction messages from all peers.
 func (sm *SyncManager) handleTxMsg(tmsg *txMsg) {
 	peer := tmsg.peer
-	state, exists := sm.peerStates[peer]
+	_, exists := sm.peerStates[peer]
 	if !exists {
 		log.Warnf("Received tx message from unknown peer %s", peer)
 		return
@@ -623,12 +631,21 @@ func (sm *SyncManager) handleTxMsg(tmsg *txMsg) {
 	acceptedTxs, err := sm.txMemPool.ProcessTransaction(tmsg.tx,
 		true, true, mempool.Tag(peer.ID()))
 
-	// Remove transaction from request maps. Either the mempool/chain
+	// Forget the announcements of the transaction. Either the mempool/chain
 	// already knows about it and as such we shouldn't have any more
 	// instances of trying to fetch it, or we failed to insert and thus
 	// we'll retry next time we get an inv.
-	delete(state.requestedTxns, *txHash)
-	delete(sm.requestedTxns, *txHash)
+	sm.txRequests.received(txHash)
+
+	// Compact state nodes can't tell a transaction with a proof that was
+	// made against a different accumulator state apart from an invalid
+	// one, so ask the peer for a fresh proof before rejecting it.
+	if err != nil && sm.isProofOrphan(err) && sm.queueProofOrphan(peer, tmsg.tx) {
+		log.Debugf("Queued transaction %v from %s for a fresh utreexo "+
+			"proof: %v", txHash, peer, err)
+		return
+	}
+	sm.removeProofOrphan(txHash, err != nil)
 
 	if err != nil {
 		// Do not request this transaction again until a new block
@@ -689,7 +706,9 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 
 	// If we didn't ask for this block then the peer is misbehaving.
 	blockHash := bmsg.block.Hash()
-	if _, exists = state.requestedBlocks[*blockHash]; !exists {
+	scheduled := sm.blockScheduler.received(peer, blockHash,
+		blockDownloadSize(bmsg.block), time.Now())
+	if _, exists = state.requestedBlocks[*blockHash]; !exists && !scheduled {
 		// The regression test intentionally sends some blocks twice
 		// to test duplicate block insertion fails.  Don't disconnect
 		// the peer or ignore the block when we're in regression test
@@ -703,15 +722,76 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
+	// Remove block from request maps. Either chain will know about it and
+	// so we shouldn't have any more instances of trying to fetch it, or we
+	// will fail the insert and thus we'll retry next time we get an inv.
+	delete(state.requestedBlocks, *blockHash)
+	delete(sm.requestedBlocks, *blockHash)
+
+	// The blocks of the headers-first download are fetched from several
+	// peers at once, so they may arrive out of order or more than once.
+	// The copies that arrive late are dropped, and the blocks are queued
+	// up to be processed in the order of the header list.
+	if scheduled {
+		_, queued := sm.queuedBlocks[*blockHash]
+		haveBlock, err := sm.chain.HaveBlock(blockHash)
+		if err != nil {
+			log.Warnf("Unexpected failure when checking for "+
+				"existing block %v: %v", blockHash, err)
+		}
+		if queued || haveBlock {
+			return
+		}
+		if sm.headersFirstMode {
+			sm.queuedBlocks[*blockHash] = bmsg
+			sm.processQueuedBlocks()
+			return
+		}
+	}
+
+	sm.processBlock(peer, bmsg.block)
+}
+
+// processQueuedBlocks processes the queued blocks of the headers-first download
+// for as long as the next block of the header list is among them, and then
+// requests the next blocks.
+func (sm *SyncManager) processQueuedBlocks() {
+	for sm.headersFirstMode {
+		firstNodeEl := sm.headerList.Front()
+		if firstNodeEl == nil {
+			break
+		}
+		hash := firstNodeEl.Value.(*headerNode).hash
+		bmsg, exists := sm.queuedBlocks[*hash]
+		if !exists {
+			break
+		}
+		delete(sm.queuedBlocks, *hash)
+
+		sm.processBlock(bmsg.peer, bmsg.block)
+		sm.blockScheduler.connected()
+	}
+
+	if sm.headersFirstMode {
+		sm.fetchHeaderBlocks()
+	}
+}
+
+// processBlock processes the passed block the passed peer sent, and moves the
+// sync on to the next checkpoint once the block is a checkpoint.
+func (sm *SyncManager) processBlock(peer *peerpkg.Peer, block *btcutil.Block) {
 	// When in headers-first mode, if the block matches the hash of the
 	// first header in the list of headers that are being fetched, it's
 	// eligible for less validation since the headers have already been
 	// verified to link together and are valid up to the next checkpoint.
 	// Also, remove the list entry for all blocks except the checkpoint
-	// since it is needed to verify the next round of headers links
-	// properly.
+	// once the block is processed, since it is needed to verify the next
+	// round of headers links properly.  The entry of a block that fails
+	// to process is kept so that the block is requested again.
+	blockHash := block.Hash()
 	isCheckpointBlock := false
 	behaviorFlags := blockchain.BFNone
+	var processedNodeEl *list.Element
 	if sm.headersFirstMode {
 		firstNodeEl := sm.headerList.Front()
 		if firstNodeEl != nil {
@@ -721,21 +801,15 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 				if firstNode.hash.IsEqual(sm.nextCheckpoint.Hash) {
 					isCheckpointBlock = true
 				} else {
-					sm.headerList.Remove(firstNodeEl)
+					processedNodeEl = firstNodeEl
 				}
 			}
 		}
 	}
 
-	// Remove block from request maps. Either chain will know about it and
-	// so we shouldn't have any more instances of trying to fetch it, or we
-	// will fail the insert and thus we'll retry next time we get an inv.
-	delete(state.requestedBlocks, *blockHash)
-	delete(sm.requestedBlocks, *blockHash)
-
 	// Process the block to include validation, best chain selection, orphan
 	// handling, etc.
-	_, isOrphan, err := sm.chain.ProcessBlock(bmsg.block, behaviorFlags)
+	_, isOrphan, err := sm.chain.ProcessBlock(block, behaviorFlags)
 	if err != nil {
 		// When the error is a rule error, it means the block was simply
 		// rejected as opposed to something actually going wrong, so log
@@ -744,6 +818,12 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		if _, ok := err.(blockchain.RuleError); ok {
 			log.Infof("Rejected block %v from %s: %v", blockHash,
 				peer, err)
+
+			// A rejected block with a utreexo proof counts as a
+			// failed proof request as the proof may be invalid.
+			if block.MsgBlock().UData != nil {
+				peer.AddProofRequestFailure()
+			}
 		} else {
 			log.Errorf("Failed to process block %v: %v",
 				blockHash, err)
@@ -759,6 +839,9 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		peer.PushRejectMsg(wire.CmdBlock, code, reason, blockHash, false)
 		return
 	}
+	if processedNodeEl != nil {
+		sm.headerList.Remove(processedNodeEl)
+	}
 
 	// Meta-data about the new block this peer is reporting. We use this
 	// below to update this peer's latest block height and the heights of
@@ -779,9 +862,9 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		// block height from the scriptSig of the coinbase transaction.
 		// Extraction is only attempted if the block's version is
 		// high enough (ver 2+).
-		header := &bmsg.block.MsgBlock().Header
+		header := &block.MsgBlock().Header
 		if blockchain.ShouldHaveSerializedBlockHeight(header) {
-			coinbaseTx := bmsg.block.Transactions()[0]
+			coinbaseTx := block.Transactions()[0]
 			cbHeight, err := blockchain.ExtractCoinbaseHeight(coinbaseTx)
 			if err != nil {
 				log.Warnf("Unable to extract height from "+
@@ -803,13 +886,15 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 			peer.PushGetBlocksMsg(locator, orphanRoot)
 		}
 	} else {
-		if peer == sm.syncPeer {
+		// The blocks of the headers-first download come from any of
+		// the download peers, all of which count as progress.
+		if peer == sm.syncPeer || sm.headersFirstMode {
 			sm.lastProgressTime = time.Now()
 		}
 
 		// When the block is not an orphan, log information about it and
 		// update the chain state.
-		sm.progressLogger.LogBlockHeight(bmsg.block, sm.chain)
+		sm.progressLogger.LogBlockHeight(block, sm.chain)
 
 		// Update this peer's latest block height, for future
 		// potential sync node candidacy.
@@ -819,6 +904,10 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 
 		// Clear the rejected transactions.
 		sm.rejectedTxns = make(map[chainhash.Hash]struct{})
+
+		// The block may have changed the positions the queued
+		// transactions were proven at, so request fresh proofs.
+		sm.requestProofOrphans()
 	}
 
 	// Update the block height for this peer. But only send a message to
@@ -847,17 +936,18 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
-	// This is headers-first mode, so if the block is not a checkpoint
-	// request more blocks using the header list when the request queue is
-	// getting short.
+	// This is headers-first mode, so if the block is not a checkpoint the
+	// next blocks are requested once the queued blocks are processed.
 	if !isCheckpointBlock {
-		if sm.startHeader != nil &&
-			len(state.requestedBlocks) < minInFlightBlocks {
-			sm.fetchHeaderBlocks()
-		}
 		return
 	}
 
+	// The checkpoint block may have come from any of the download peers,
+	// but the sync moves on with the sync peer.
+	if sm.syncPeer != nil {
+		peer = sm.syncPeer
+	}
+
 	// This is headers-first mode and the block is a checkpoint.  When
 	// there is a next checkpoint, get the next round of headers by asking
 	// for headers starting from the block after this one up to the next
@@ -875,7 +965,7 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 		log.Infof("Downloading headers for blocks %d to %d from "+
 			"peer %s", prevHeight+1, sm.nextCheckpoint.Height,
-			sm.syncPeer.Addr())
+			peer.Addr())
 		return
 	}
 
@@ -894,27 +984,37 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	}
 }
 
-// fetchHeaderBlocks creates and sends a request to the syncPeer for the next
-// list of blocks to be downloaded based on the current list of headers.
+// fetchHeaderBlocks requests the blocks of the download window, which are the
+// next blocks of the header list that aren't downloaded yet, from the peers the
+// block scheduler assigns them to.  The next block to process is requested from
+// another peer when the peer it was requested from stalls.
 func (sm *SyncManager) fetchHeaderBlocks() {
-	// Nothing to do if there is no start header.
-	if sm.startHeader == nil {
-		log.Warnf("fetchHeaderBlocks called with no start header")
+	// The blocks are only requested once all the headers up to the next
+	// checkpoint are known to link to it.
+	lastNodeEl := sm.headerList.Back()
+	if lastNodeEl == nil || sm.nextCheckpoint == nil ||
+		!lastNodeEl.Value.(*headerNode).hash.IsEqual(sm.nextCheckpoint.Hash) {
 		return
 	}
 
-	// Build up a getdata request for the list of blocks the headers
-	// describe.  The size hint will be limited to wire.MaxInvPerMsg by
-	// the function, so no need to double check it here.
-	gdmsg := wire.NewMsgGetDataSizeHint(uint(sm.headerList.Len()))
-	numRequested := 0
-	for e := sm.startHeader; e != nil; e = e.Next() {
+	// The window follows the number of blocks the peers can have in flight
+	// and the size of the blocks.
+	peers := sm.blockDownloadPeers()
+	window := sm.blockScheduler.window(peers)
+
+	var needed []chainhash.Hash
+	numHeaders := 0
+	for e := sm.headerList.Front(); e != nil && numHeaders < window; e = e.Next() {
 		node, ok := e.Value.(*headerNode)
 		if !ok {
 			log.Warn("Header list node type is not a headerNode")
 			continue
 		}
+		numHeaders++
 
+		if _, exists := sm.queuedBlocks[*node.hash]; exists {
+			continue
+		}
 		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
 		haveInv, err := sm.haveInventory(iv)
 		if err != nil {
@@ -923,55 +1023,107 @@ func (sm *SyncManager) fetchHeaderBlocks() {
 				"fetch: %v", err)
 		}
 		if !haveInv {
-			syncPeerState := sm.peerStates[sm.syncPeer]
-
-			sm.requestedBlocks[*node.hash] = struct{}{}
-			syncPeerState.requestedBlocks[*node.hash] = struct{}{}
+			needed = append(needed, *node.hash)
+		}
+	}
+	if len(needed) == 0 {
+		return
+	}
 
-			// If we're fetching from a witness enabled peer
-			// post-fork, then ensure that we receive all the
-			// witness data in the blocks.
-			if sm.syncPeer.IsWitnessEnabled() {
-				iv.Type = wire.InvTypeWitnessBlock
+	// Take the next block away from a peer that holds up the download,
+	// and the blocks that are past their deadline away from the peers
+	// they were requested from, as long as there is another peer to
+	// request them from.
+	now := time.Now()
+	if len(peers) > 1 {
+		stalled := sm.blockScheduler.checkStall(&needed[0], now)
+		if stalled != nil {
+			log.Debugf("Peer %s stalled the download of block %v "+
+				"-- requesting its blocks from other peers",
+				stalled, needed[0])
+		}
+		if expired := sm.blockScheduler.expire(now); len(expired) > 0 {
+			log.Debugf("Requesting %d blocks past their deadline "+
+				"from other peers", len(expired))
+		}
+	}
 
-				// If we're syncing from a utreexo enabled peer, also
-				// ask for the proofs.
-				if sm.syncPeer.IsUtreexoEnabled() {
-					iv.Type = wire.InvTypeWitnessUtreexoBlock
-				}
-			} else {
-				// If we're syncing from a utreexo enabled peer, also
-				// ask for the proofs.
-				if sm.syncPeer.IsUtreexoEnabled() {
-					iv.Type = wire.InvTypeUtreexoBlock
-				}
-			}
+	for peer, hashes := range sm.blockScheduler.schedule(needed, peers, now) {
+		gdmsg := wire.NewMsgGetDataSizeHint(uint(len(hashes)))
+		for i := range hashes {
+			gdmsg.AddInvVect(wire.NewInvVect(blockInvType(peer), &hashes[i]))
+		}
+		peer.QueueMessage(gdmsg, nil)
+	}
+}
 
-			gdmsg.AddInvVect(iv)
-			numRequested++
+// blockDownloadPeers returns the peers the blocks of the headers-first download
+// are requested from, which are the sync peer and the other sync candidates
+// that have the blocks up to the next checkpoint.
+func (sm *SyncManager) blockDownloadPeers() []*peerpkg.Peer {
+	var peers []*peerpkg.Peer
+	if sm.syncPeer != nil {
+		peers = append(peers, sm.syncPeer)
+	}
+	for peer, state := range sm.peerStates {
+		if peer == sm.syncPeer || !state.syncCandidate ||
+			peer.LastBlock() < sm.nextCheckpoint.Height {
+			continue
 		}
-		sm.startHeader = e.Next()
-		if numRequested >= wire.MaxInvPerMsg {
-			break
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+// blockInvType returns the inventory type to request blocks from the passed
+// peer with.  Witness enabled peers are asked for the witness data of the
+// blocks, and utreexo enabled peers for their proofs too.
+func blockInvType(peer *peerpkg.Peer) wire.InvType {
+	if peer.IsWitnessEnabled() {
+		if peer.IsUtreexoEnabled() {
+			return wire.InvTypeWitnessUtreexoBlock
 		}
+		return wire.InvTypeWitnessBlock
 	}
-	if len(gdmsg.InvList) > 0 {
-		sm.syncPeer.QueueMessage(gdmsg, nil)
+	if peer.IsUtreexoEnabled() {
+		return wire.InvTypeUtreexoBlock
 	}
+	return wire.InvTypeBlock
+}
+
+// blockDownloadSize returns the number of bytes the block was sent in, which
+// includes its utreexo proof.
+func blockDownloadSize(block *btcutil.Block) int {
+	msgBlock := block.MsgBlock()
+	size := msgBlock.SerializeSize()
+	if msgBlock.UData != nil {
+		size += msgBlock.UData.SerializeSizeCompact(false)
+	}
+	return size
 }
 
 // handleHeadersMsg handles block header messages from all peers.  Headers are
 // requested when performing a headers-first sync.
 func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 	peer := hmsg.peer
-	_, exists := sm.peerStates[peer]
+	state, exists := sm.peerStates[peer]
 	if !exists {
 		log.Warnf("Received headers message from unknown peer %s", peer)
 		return
 	}
 
-	// The remote peer is misbehaving if we didn't request headers.
+	// The headers of the best chain of the peer are being synced to
+	// compare them with those of the other peers.
 	msg := hmsg.headers
+	audit := state.headerAudit
+	if audit != nil && !audit.done && !sm.headersFirstMode &&
+		!sm.headersBuildMode {
+
+		sm.handleAuditHeaders(peer, audit, msg.Headers)
+		return
+	}
+
+	// The remote peer is misbehaving if we didn't request headers.
 	numHeaders := len(msg.Headers)
 	if !sm.headersFirstMode && !sm.headersBuildMode {
 		log.Warnf("Got %d unrequested headers from %s -- "+
@@ -1090,10 +1242,7 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 		prevNode := prevNodeEl.Value.(*headerNode)
 		if prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
 			node.height = prevNode.height + 1
-			e := sm.headerList.PushBack(&node)
-			if sm.startHeader == nil {
-				sm.startHeader = e
-			}
+			sm.headerList.PushBack(&node)
 		} else {
 			log.Warnf("Received block header that does not "+
 				"properly connect to the chain from peer %s "+
@@ -1174,6 +1323,10 @@ func (sm *SyncManager) handleNotFoundMsg(nfmsg *notFoundMsg) {
 				delete(sm.requestedBlocks, inv.Hash)
 			}
 
+			// Blocks of the headers-first download are requested
+			// from another peer.
+			sm.blockScheduler.notFound(peer, &inv.Hash)
+
 		case wire.InvTypeWitnessTx:
 			fallthrough
 		case wire.InvTypeUtreexoTx:
@@ -1181,12 +1334,12 @@ func (sm *SyncManager) handleNotFoundMsg(nfmsg *notFoundMsg) {
 		case wire.InvTypeWitnessUtreexoTx:
 			fallthrough
 		case wire.InvTypeTx:
-			if _, exists := state.requestedTxns[inv.Hash]; exists {
-				delete(state.requestedTxns, inv.Hash)
-				delete(sm.requestedTxns, inv.Hash)
-			}
+			// Request the transaction from the next peer that
+			// announced it.
+			sm.txRequests.completed(peer, &inv.Hash)
 		}
 	}
+	sm.requestTxns()
 }
 
 // haveInventory returns whether or not the inventory represented by the passed
@@ -1372,29 +1525,36 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 				continue
 			}
 
-			// Add it to the request queue.
-			state.requestQueue = append(state.requestQueue, iv)
+			switch iv.Type {
+			case wire.InvTypeTx:
+			case wire.InvTypeWitnessTx:
+			case wire.InvTypeUtreexoTx:
+			case wire.InvTypeWitnessUtreexoTx:
+			default:
+				// Add the block to the request queue.
+				state.requestQueue = append(state.requestQueue, iv)
+				continue
+			}
 
-			// If the inv is for a utreexo tx, then also pop off the utreexo
-			// proof hash invs and add it to the request queue.
+			// If the inv is for a utreexo tx, then also pop off the
+			// utreexo proof hash invs that represent the positions
+			// of its inputs.
+			var proofHashes []chainhash.Hash
 			if peer.IsUtreexoEnabled() {
-				switch iv.Type {
-				case wire.InvTypeTx:
-				case wire.InvTypeWitnessTx:
-				case wire.InvTypeUtreexoTx:
-				case wire.InvTypeWitnessUtreexoTx:
-				default:
-					continue
-				}
-
 				for j := i + 1; j < len(invVects); j++ {
 					if invVects[j].Type != wire.InvTypeUtreexoProofHash {
 						break
 					}
-					state.requestQueue = append(state.requestQueue, invVects[j])
+					proofHashes = append(proofHashes, invVects[j].Hash)
 				}
 			}
 
+			// Leave it to the tracker to decide when and from which
+			// of the peers that announce it the transaction is
+			// requested.
+			sm.txRequests.announce(peer, iv, proofHashes,
+				!peer.Inbound(), time.Now())
+
 			continue
 		}
 
@@ -1475,99 +1635,82 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 				gdmsg.AddInvVect(iv)
 				numRequested++
 			}
+		}
 
-		case wire.InvTypeWitnessTx:
-			fallthrough
-		case wire.InvTypeWitnessUtreexoTx:
-			fallthrough
-		case wire.InvTypeUtreexoTx:
-			fallthrough
-		case wire.InvTypeTx:
-			amUtreexoNode := sm.chain.IsUtreexoViewActive()
-			if !amUtreexoNode {
-				// Request the transaction if there is not already a
-				// pending request.
-				if _, exists := sm.requestedTxns[iv.Hash]; !exists {
-					limitAdd(sm.requestedTxns, iv.Hash, maxRequestedTxns)
-					limitAdd(state.requestedTxns, iv.Hash, maxRequestedTxns)
-
-					// If the peer is capable, request the txn
-					// including all witness data.
-					if peer.IsWitnessEnabled() {
-						iv.Type = wire.InvTypeWitnessTx
-					}
-
-					gdmsg.AddInvVect(iv)
-					numRequested++
-				}
-			} else {
-				// Request the transaction if there is not already a
-				// pending request.
-				if _, exists := sm.requestedTxns[iv.Hash]; !exists {
-					// Pop off all the utreexo proof hash invs that's related to this
-					// tx from the request queue. These represent the positions of the
-					// inputs.
-					var targetPositions []chainhash.Hash
-					for len(requestQueue) > 0 && requestQueue[0].Type == wire.InvTypeUtreexoProofHash {
-						proofInv := requestQueue[0]
-						targetPositions = append(targetPositions, proofInv.Hash)
-
-						requestQueue[0] = nil
-						requestQueue = requestQueue[1:]
-					}
+		if numRequested >= wire.MaxInvPerMsg {
+			break
+		}
+	}
+	state.requestQueue = requestQueue
+	if len(gdmsg.InvList) > 0 {
+		peer.QueueMessage(gdmsg, nil)
+	}
 
-					log.Debugf("for tx %s(%v), got %v packed positions, which are %v",
-						iv.Hash, iv.Type.String(),
-						targetPositions, chainhash.PackedHashesToUint64(targetPositions))
+	// Request the announced transactions that are due right away.
+	sm.requestTxns()
+}
 
-					// Check that the proof invs+the current tx inv and all
-					// other requested invs do not go over the max inv per
-					// message limit.
-					if len(targetPositions)+1+numRequested+1 >= wire.MaxInvPerMsg {
-						break
-					}
+// requestTxns times out the transaction requests that the peers didn't answer
+// in time and requests the transactions that are due from the peers the
+// tracker selects for them.
+func (sm *SyncManager) requestTxns() {
+	now := time.Now()
+	for _, ann := range sm.txRequests.expire(now) {
+		log.Debugf("Request of transaction %v from %s timed out",
+			ann.hash, ann.peer)
+	}
 
-					var neededPositions []chainhash.Hash
-					if len(targetPositions) > 0 {
-						neededPositions = sm.chain.GetNeededPositions(targetPositions)
-					}
+	for peer, anns := range sm.txRequests.schedule(now) {
+		sm.pushTxGetData(peer, anns)
+	}
+}
 
-					log.Debugf("need %v to prove tx %v", chainhash.PackedHashesToUint64(neededPositions), iv.Hash)
+// pushTxGetData requests the transactions of the passed announcements from the
+// passed peer.  Compact state nodes also request the proof hashes needed to
+// prove the inputs of the transactions that aren't cached.
+func (sm *SyncManager) pushTxGetData(peer *peerpkg.Peer, anns []*txAnnouncement) {
+	amUtreexoNode := sm.chain.IsUtreexoViewActive()
+	gdmsg := wire.NewMsgGetData()
+	for _, ann := range anns {
+		// If the peer is capable, request the txn including all witness
+		// data.
+		iv := wire.NewInvVect(ann.invType, &ann.hash)
+		if peer.IsWitnessEnabled() {
+			iv.Type = wire.InvTypeWitnessTx
+		}
 
-					limitAdd(sm.requestedTxns, iv.Hash, maxRequestedTxns)
-					limitAdd(state.requestedTxns, iv.Hash, maxRequestedTxns)
+		var neededPositions []chainhash.Hash
+		if amUtreexoNode {
+			if len(ann.proofHashes) > 0 {
+				neededPositions = sm.chain.GetNeededPositions(
+					ann.proofHashes)
+			}
+			log.Debugf("need %v to prove tx %v",
+				chainhash.PackedHashesToUint64(neededPositions),
+				ann.hash)
 
-					// If the peer is capable, request the txn
-					// including all witness data.
-					if peer.IsWitnessEnabled() {
-						iv.Type = wire.InvTypeWitnessTx
-					}
+			// Add in the utreexo flag to the tx inv.
+			iv.Type |= wire.InvUtreexoFlag
+		}
 
-					// Add in the utreexo flag then add the tx inv.
-					iv.Type |= wire.InvUtreexoFlag
-					gdmsg.AddInvVect(iv)
-					numRequested++
-
-					// Then add all the packed proof positions inv.
-					for i := range neededPositions {
-						gdmsg.AddInvVect(wire.NewInvVect(
-							wire.InvTypeUtreexoProofHash,
-							&neededPositions[i]),
-						)
-						numRequested++
-					}
-				}
-			}
-		case wire.InvTypeUtreexoProofHash:
-			// Purposely left empty. Utreexo proof hash invs are not useful on their own.
+		// A transaction that can't be requested along with the proof
+		// hashes of its inputs in a single message is requested from
+		// another peer instead.
+		if len(neededPositions)+1 > wire.MaxInvPerMsg {
+			sm.txRequests.completed(peer, &ann.hash)
 			continue
 		}
+		if len(gdmsg.InvList)+len(neededPositions)+1 > wire.MaxInvPerMsg {
+			peer.QueueMessage(gdmsg, nil)
+			gdmsg = wire.NewMsgGetData()
+		}
 
-		if numRequested >= wire.MaxInvPerMsg {
-			break
+		gdmsg.AddInvVect(iv)
+		for i := range neededPositions {
+			gdmsg.AddInvVect(wire.NewInvVect(
+				wire.InvTypeUtreexoProofHash, &neededPositions[i]))
 		}
 	}
-	state.requestQueue = requestQueue
 	if len(gdmsg.InvList) > 0 {
 		peer.QueueMessage(gdmsg, nil)
 	}
@@ -1582,6 +1725,12 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 func (sm *SyncManager) blockHandler() {
 	stallTicker := time.NewTicker(stallSampleInterval)
 	defer stallTicker.Stop()
+	txRequestTicker := time.NewTicker(txRequestInterval)
+	defer txRequestTicker.Stop()
+	blockRequestTicker := time.NewTicker(blockRequestInterval)
+	defer blockRequestTicker.Stop()
+	headerAuditTicker := time.NewTicker(headerAuditInterval)
+	defer headerAuditTicker.Stop()
 
 out:
 	for {
@@ -1648,6 +1797,17 @@ out:
 		case <-stallTicker.C:
 			sm.handleStallSample()
 
+		case <-txRequestTicker.C:
+			sm.requestTxns()
+
+		case <-blockRequestTicker.C:
+			if sm.headersFirstMode {
+				sm.fetchHeaderBlocks()
+			}
+
+		case <-headerAuditTicker.C:
+			sm.startHeaderAudit()
+
 		case <-sm.quit:
 			break out
 		}
@@ -1704,36 +1864,29 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 			break
 		}
 
+		// Register block with the fee estimator, if it exists.  This is
+		// done before removing its transactions from the transaction
+		// pool so that the estimator sees them confirmed rather than
+		// leaving the pool.
+		if sm.feeEstimator != nil {
+			sm.feeEstimator.RegisterBlock(block)
+		}
+
 		// Remove all of the transactions (except the coinbase) in the
-		// connected block from the transaction pool.  Secondly, remove any
+		// connected block from the transaction pool along with any
 		// transactions which are now double spends as a result of these
-		// new transactions.  Finally, remove any transaction that is
-		// no longer an orphan. Transactions which depend on a confirmed
-		// transaction are NOT removed recursively because they are still
-		// valid.
+		// new transactions at once.  Then, remove any transaction that
+		// is no longer an orphan. Transactions which depend on a
+		// confirmed transaction are NOT removed recursively because they
+		// are still valid.
+		sm.txMemPool.RemoveBlockTransactions(block)
 		for _, tx := range block.Transactions()[1:] {
-			sm.txMemPool.RemoveTransaction(tx, false, true)
-			sm.txMemPool.RemoveDoubleSpends(tx)
 			sm.txMemPool.RemoveOrphan(tx)
 			sm.peerNotifier.TransactionConfirmed(tx)
 			acceptedTxs := sm.txMemPool.ProcessOrphans(tx)
 			sm.peerNotifier.AnnounceNewTransactions(acceptedTxs)
 		}
 
-		// Register block with the fee estimator, if it exists.
-		if sm.feeEstimator != nil {
-			err := sm.feeEstimator.RegisterBlock(block)
-
-			// If an error is somehow generated then the fee estimator
-			// has entered an invalid state. Since it doesn't know how
-			// to recover, create a new one.
-			if err != nil {
-				sm.feeEstimator = mempool.NewFeeEstimator(
-					mempool.DefaultEstimateFeeMaxRollback,
-					mempool.DefaultEstimateFeeMinRegisteredBlocks)
-			}
-		}
-
 	// A block has been disconnected from the main block chain.
 	case blockchain.NTBlockDisconnected:
 		block, ok := notification.Data.(*btcutil.Block)
@@ -1754,11 +1907,6 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 				sm.txMemPool.RemoveTransaction(tx, true, true)
 			}
 		}
-
-		// Rollback previous block recorded by the fee estimator.
-		if sm.feeEstimator != nil {
-			sm.feeEstimator.Rollback(block.Hash())
-		}
 	}
 }
 
@@ -1912,8 +2060,11 @@ func New(config *Config) (*SyncManager, error) {
 		txMemPool:       config.TxMemPool,
 		chainParams:     config.ChainParams,
 		rejectedTxns:    make(map[chainhash.Hash]struct{}),
-		requestedTxns:   make(map[chainhash.Hash]struct{}),
+		txRequests:      newTxRequestTracker(),
+		proofOrphans:    make(map[chainhash.Hash]*proofOrphan),
 		requestedBlocks: make(map[chainhash.Hash]struct{}),
+		blockScheduler:  newBlockScheduler(),
+		queuedBlocks:    make(map[chainhash.Hash]*blockMsg),
 		peerStates:      make(map[*peerpkg.Peer]*peerSyncState),
 		progressLogger:  newBlockProgressLogger("Processed", log),
 		msgChan:         make(chan interface{}, config.MaxPeers*3),
@@ -0,0 +1,214 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/mempool"
+	peerpkg "github.com/utreexo/utreexod/peer"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// maxProofOrphans is the maximum number of transactions waiting for a
+	// fresh utreexo proof to keep track of.
+	maxProofOrphans = 100
+
+	// maxProofRequests is the maximum number of times a fresh utreexo proof
+	// is requested for a transaction before it's rejected.
+	maxProofRequests = 3
+)
+
+// proofOrphan is a transaction whose inputs couldn't be proven with the utreexo
+// data it was relayed with and that is waiting for a fresh proof from the peer
+// that announced it.
+type proofOrphan struct {
+	peer *peerpkg.Peer
+
+	// targets are the positions of the inputs of the transaction in the
+	// accumulator according to the last proof the peer sent.
+	targets []uint64
+
+	// requests is the number of times a fresh proof was requested.
+	requests int
+}
+
+// ProofOrphanStats are counters of the transactions that were relayed with
+// utreexo proofs that couldn't prove their inputs.
+type ProofOrphanStats struct {
+	// Pending is the number of transactions currently waiting for a fresh
+	// proof.
+	Pending int
+
+	// Orphaned is the number of transactions that were queued for a fresh
+	// proof.
+	Orphaned uint64
+
+	// Requested is the number of fresh proofs that were requested.
+	Requested uint64
+
+	// Resolved is the number of queued transactions that were accepted
+	// once a fresh proof was received.
+	Resolved uint64
+
+	// Dropped is the number of queued transactions that were given up on,
+	// either since too many proofs were requested or since the peer that
+	// announced them disconnected.
+	Dropped uint64
+}
+
+// proofOrphanCounters holds the counters of ProofOrphanStats.  It's accessed
+// atomically so that the statistics can be read outside of the blockHandler
+// thread.
+type proofOrphanCounters struct {
+	pending   int64
+	orphaned  uint64
+	requested uint64
+	resolved  uint64
+	dropped   uint64
+}
+
+// isProofOrphan returns whether the passed error of processing a transaction
+// relayed by a peer means that the transaction should wait for a fresh utreexo
+// proof instead of being rejected.
+func (sm *SyncManager) isProofOrphan(err error) bool {
+	return sm.chain.IsUtreexoViewActive() && mempool.IsUtreexoProofError(err)
+}
+
+// queueProofOrphan queues the passed transaction whose inputs couldn't be proven
+// and requests a fresh proof for it from the peer.  It returns false when the
+// transaction should be rejected instead since too many proofs were requested
+// for it already or there's no room for it.
+func (sm *SyncManager) queueProofOrphan(peer *peerpkg.Peer, tx *btcutil.Tx) bool {
+	orphan, exists := sm.proofOrphans[*tx.Hash()]
+	if !exists {
+		if len(sm.proofOrphans) >= maxProofOrphans {
+			return false
+		}
+		orphan = &proofOrphan{}
+		sm.proofOrphans[*tx.Hash()] = orphan
+		atomic.AddInt64(&sm.proofOrphanCounters.pending, 1)
+		atomic.AddUint64(&sm.proofOrphanCounters.orphaned, 1)
+	}
+	if orphan.requests >= maxProofRequests {
+		sm.removeProofOrphan(tx.Hash(), true)
+		return false
+	}
+
+	// Ask the peer that sent the last proof since it's the one that has
+	// the transaction, and prove the inputs at the positions it sent.
+	orphan.peer = peer
+	orphan.targets = nil
+	if ud := tx.MsgTx().UData; ud != nil {
+		orphan.targets = ud.AccProof.Targets
+	}
+	sm.requestProof(tx.Hash(), orphan)
+	return true
+}
+
+// requestProof requests the passed transaction from the peer of the orphan
+// again along with the proof hashes needed to prove its inputs that aren't
+// cached.
+func (sm *SyncManager) requestProof(txHash *chainhash.Hash, orphan *proofOrphan) {
+	if _, exists := sm.peerStates[orphan.peer]; !exists {
+		return
+	}
+	if sm.txRequests.isRequested(txHash) {
+		return
+	}
+
+	var neededPositions []chainhash.Hash
+	if len(orphan.targets) > 0 {
+		neededPositions = sm.chain.GetNeededPositions(
+			chainhash.Uint64sToPackedHashes(orphan.targets))
+	}
+
+	// Leave room for the transaction itself.
+	if len(neededPositions)+1 > wire.MaxInvPerMsg {
+		return
+	}
+
+	sm.txRequests.requested(orphan.peer, txHash, time.Now())
+
+	invType := wire.InvTypeUtreexoTx
+	if orphan.peer.IsWitnessEnabled() {
+		invType = wire.InvTypeWitnessUtreexoTx
+	}
+	gdmsg := wire.NewMsgGetDataSizeHint(uint(len(neededPositions) + 1))
+	gdmsg.AddInvVect(wire.NewInvVect(invType, txHash))
+	for i := range neededPositions {
+		gdmsg.AddInvVect(wire.NewInvVect(
+			wire.InvTypeUtreexoProofHash, &neededPositions[i]))
+	}
+	orphan.peer.QueueMessage(gdmsg, nil)
+
+	orphan.requests++
+	atomic.AddUint64(&sm.proofOrphanCounters.requested, 1)
+	log.Debugf("Requested a fresh utreexo proof for transaction %v from %s "+
+		"(attempt %d)", txHash, orphan.peer, orphan.requests)
+}
+
+// removeProofOrphan stops waiting for a fresh proof for the passed transaction
+// if it was queued for one.  The transaction is counted as dropped when dropped
+// is true and as resolved otherwise.
+func (sm *SyncManager) removeProofOrphan(txHash *chainhash.Hash, dropped bool) {
+	if _, exists := sm.proofOrphans[*txHash]; !exists {
+		return
+	}
+	delete(sm.proofOrphans, *txHash)
+	atomic.AddInt64(&sm.proofOrphanCounters.pending, -1)
+	if dropped {
+		atomic.AddUint64(&sm.proofOrphanCounters.dropped, 1)
+	} else {
+		atomic.AddUint64(&sm.proofOrphanCounters.resolved, 1)
+	}
+}
+
+// removePeerProofOrphans drops the transactions waiting for a fresh proof from
+// the passed peer that disconnected.
+func (sm *SyncManager) removePeerProofOrphans(peer *peerpkg.Peer) {
+	for txHash, orphan := range sm.proofOrphans {
+		if orphan.peer == peer {
+			sm.removeProofOrphan(&txHash, true)
+		}
+	}
+}
+
+// requestProofOrphans requests fresh proofs for the transactions that are still
+// waiting for one after a block was connected since the proofs received before
+// it may have been made against the accumulator the block changed.
+func (sm *SyncManager) requestProofOrphans() {
+	for txHash, orphan := range sm.proofOrphans {
+		txHash := txHash
+		if sm.txMemPool.HaveTransaction(&txHash) {
+			sm.removeProofOrphan(&txHash, false)
+			continue
+		}
+		if orphan.requests >= maxProofRequests {
+			sm.removeProofOrphan(&txHash, true)
+			continue
+		}
+		sm.requestProof(&txHash, orphan)
+	}
+}
+
+// ProofOrphanStats returns the counters of the transactions that were relayed
+// with utreexo proofs that couldn't prove their inputs.
+//
+// This function is safe for concurrent access.
+func (sm *SyncManager) ProofOrphanStats() ProofOrphanStats {
+	c := &sm.proofOrphanCounters
+	return ProofOrphanStats{
+		Pending:   int(atomic.LoadInt64(&c.pending)),
+		Orphaned:  atomic.LoadUint64(&c.orphaned),
+		Requested: atomic.LoadUint64(&c.requested),
+		Resolved:  atomic.LoadUint64(&c.resolved),
+		Dropped:   atomic.LoadUint64(&c.dropped),
+	}
+}
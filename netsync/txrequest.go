@@ -0,0 +1,337 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"time"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	peerpkg "github.com/utreexo/utreexod/peer"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// txRequestInterval is the interval at which the transactions that are
+	// due are requested from the peers that announced them.
+	txRequestInterval = 500 * time.Millisecond
+
+	// txRequestTimeout is the time after which a transaction that was
+	// requested from a peer that didn't send it is requested from another
+	// peer that announced it instead.
+	txRequestTimeout = time.Minute
+
+	// nonPreferredTxDelay is the delay before a transaction is requested
+	// from an inbound peer.  It gives the outbound peers, which are harder
+	// for an attacker to control, a chance to announce the transaction
+	// first and makes it harder for inbound peers to learn when we heard
+	// of a transaction.
+	nonPreferredTxDelay = 2 * time.Second
+
+	// overloadedTxDelay is the additional delay before a transaction is
+	// requested from a peer that already has maxPeerTxsInFlight requests
+	// in flight.
+	overloadedTxDelay = 2 * time.Second
+
+	// maxPeerTxsInFlight is the number of transactions that may be
+	// requested from a peer before the transactions it announces are
+	// delayed.
+	maxPeerTxsInFlight = 100
+
+	// maxPeerTxAnnouncements is the maximum number of transaction
+	// announcements of a peer that are tracked.  Any further announcements
+	// are ignored until some of them are resolved.
+	maxPeerTxAnnouncements = 5000
+)
+
+// txAnnouncement is the announcement of a transaction by a peer.
+type txAnnouncement struct {
+	hash chainhash.Hash
+	peer *peerpkg.Peer
+
+	// invType is the type of the inventory vector the transaction was
+	// announced with.
+	invType wire.InvType
+
+	// proofHashes are the packed positions of the inputs of the
+	// transaction in the accumulator that a utreexo peer announced along
+	// with the transaction.
+	proofHashes []chainhash.Hash
+
+	// preferred is whether the transaction should be requested from the
+	// peer over the peers that aren't preferred.
+	preferred bool
+
+	// reqTime is the earliest time the transaction may be requested from
+	// the peer.
+	reqTime time.Time
+
+	// seq orders the announcements so that the transaction is requested
+	// from the peer that announced it first among the candidates.
+	seq uint64
+
+	// completed is set once the peer failed to send the transaction, after
+	// which it isn't requested from the peer again.
+	completed bool
+}
+
+// txRequest tracks the announcements of a transaction and the pending request
+// of it.
+type txRequest struct {
+	announcements map[*peerpkg.Peer]*txAnnouncement
+
+	// inFlight is the announcement of the peer the transaction is
+	// currently requested from, if any, and expiry is the time the request
+	// times out.
+	inFlight *txAnnouncement
+	expiry   time.Time
+}
+
+// txPeerCounts are the number of announcements and requests in flight that the
+// tracker keeps for a peer.
+type txPeerCounts struct {
+	announced int
+	inFlight  int
+}
+
+// txRequestTracker schedules the requests of the transactions that peers
+// announce.  Each transaction is requested from only one peer at a time, where
+// the outbound peers are preferred and the inbound peers are only asked after a
+// delay.  A transaction that a peer doesn't send in time or that it reports as
+// not found is requested from the next peer that announced it.
+//
+// The tracker isn't safe for concurrent access and must only be used from the
+// blockHandler thread.
+type txRequestTracker struct {
+	txns  map[chainhash.Hash]*txRequest
+	peers map[*peerpkg.Peer]*txPeerCounts
+	seq   uint64
+}
+
+// newTxRequestTracker returns a new tracker without any announcements.
+func newTxRequestTracker() *txRequestTracker {
+	return &txRequestTracker{
+		txns:  make(map[chainhash.Hash]*txRequest),
+		peers: make(map[*peerpkg.Peer]*txPeerCounts),
+	}
+}
+
+// peerCounts returns the counts of the passed peer, creating them if needed.
+func (t *txRequestTracker) peerCounts(peer *peerpkg.Peer) *txPeerCounts {
+	counts, exists := t.peers[peer]
+	if !exists {
+		counts = &txPeerCounts{}
+		t.peers[peer] = counts
+	}
+	return counts
+}
+
+// announce records the announcement of a transaction by the passed peer at the
+// passed time.  Announcements of transactions the peer already announced are
+// ignored, as are all announcements of peers that have too many of them.
+func (t *txRequestTracker) announce(peer *peerpkg.Peer, iv *wire.InvVect,
+	proofHashes []chainhash.Hash, preferred bool, now time.Time) {
+
+	req, exists := t.txns[iv.Hash]
+	if exists {
+		if _, exists := req.announcements[peer]; exists {
+			return
+		}
+	}
+	counts := t.peerCounts(peer)
+	if counts.announced >= maxPeerTxAnnouncements {
+		return
+	}
+	if !exists {
+		req = &txRequest{
+			announcements: make(map[*peerpkg.Peer]*txAnnouncement),
+		}
+		t.txns[iv.Hash] = req
+	}
+
+	reqTime := now
+	if !preferred {
+		reqTime = reqTime.Add(nonPreferredTxDelay)
+	}
+	if counts.inFlight >= maxPeerTxsInFlight {
+		reqTime = reqTime.Add(overloadedTxDelay)
+	}
+
+	t.seq++
+	req.announcements[peer] = &txAnnouncement{
+		hash:        iv.Hash,
+		peer:        peer,
+		invType:     iv.Type,
+		proofHashes: proofHashes,
+		preferred:   preferred,
+		reqTime:     reqTime,
+		seq:         t.seq,
+	}
+	counts.announced++
+}
+
+// isRequested returns whether the passed transaction is currently requested
+// from a peer.
+func (t *txRequestTracker) isRequested(hash *chainhash.Hash) bool {
+	req, exists := t.txns[*hash]
+	return exists && req.inFlight != nil
+}
+
+// requested records that the passed transaction was requested from the passed
+// peer outside of the schedule of the tracker at the passed time.
+func (t *txRequestTracker) requested(peer *peerpkg.Peer, hash *chainhash.Hash,
+	now time.Time) {
+
+	iv := wire.NewInvVect(wire.InvTypeTx, hash)
+	t.announce(peer, iv, nil, true, now)
+	req, exists := t.txns[*hash]
+	if !exists {
+		return
+	}
+	ann, exists := req.announcements[peer]
+	if !exists {
+		return
+	}
+	if req.inFlight != nil {
+		t.peers[req.inFlight.peer].inFlight--
+	}
+	ann.completed = false
+	t.setInFlight(req, ann, now)
+}
+
+// setInFlight marks the passed announcement of the transaction as the one the
+// transaction is requested from.
+func (t *txRequestTracker) setInFlight(req *txRequest, ann *txAnnouncement,
+	now time.Time) {
+
+	req.inFlight = ann
+	req.expiry = now.Add(txRequestTimeout)
+	t.peers[ann.peer].inFlight++
+}
+
+// completed records that the passed peer didn't send the passed transaction,
+// such as when it replied that it doesn't have it, so that the transaction is
+// requested from another peer that announced it.
+func (t *txRequestTracker) completed(peer *peerpkg.Peer, hash *chainhash.Hash) {
+	req, exists := t.txns[*hash]
+	if !exists {
+		return
+	}
+	ann, exists := req.announcements[peer]
+	if !exists {
+		return
+	}
+	ann.completed = true
+	if req.inFlight == ann {
+		req.inFlight = nil
+		t.peers[peer].inFlight--
+	}
+	t.forgetCompleted(hash, req)
+}
+
+// forgetCompleted forgets the passed transaction when there is no peer left
+// that it may be requested from.
+func (t *txRequestTracker) forgetCompleted(hash *chainhash.Hash, req *txRequest) {
+	for _, ann := range req.announcements {
+		if !ann.completed {
+			return
+		}
+	}
+	t.received(hash)
+}
+
+// received forgets all the announcements of the passed transaction once it was
+// received from any peer, or when it's no longer needed.
+func (t *txRequestTracker) received(hash *chainhash.Hash) {
+	req, exists := t.txns[*hash]
+	if !exists {
+		return
+	}
+	for peer := range req.announcements {
+		t.peers[peer].announced--
+	}
+	if req.inFlight != nil {
+		t.peers[req.inFlight.peer].inFlight--
+	}
+	delete(t.txns, *hash)
+}
+
+// removePeer forgets all the announcements of the passed peer that
+// disconnected.  The transactions that were requested from it become
+// requestable from the other peers that announced them.
+func (t *txRequestTracker) removePeer(peer *peerpkg.Peer) {
+	if _, exists := t.peers[peer]; !exists {
+		return
+	}
+	for hash, req := range t.txns {
+		ann, exists := req.announcements[peer]
+		if !exists {
+			continue
+		}
+		delete(req.announcements, peer)
+		if req.inFlight == ann {
+			req.inFlight = nil
+		}
+		if len(req.announcements) == 0 {
+			delete(t.txns, hash)
+			continue
+		}
+		hash := hash
+		t.forgetCompleted(&hash, req)
+	}
+	delete(t.peers, peer)
+}
+
+// expire times out the requests that weren't answered by the passed time and
+// returns the announcements of the peers that stalled them.  The transactions
+// are requested from another peer on the next schedule.
+func (t *txRequestTracker) expire(now time.Time) []*txAnnouncement {
+	var stalled []*txAnnouncement
+	for hash, req := range t.txns {
+		if req.inFlight == nil || now.Before(req.expiry) {
+			continue
+		}
+		stalled = append(stalled, req.inFlight)
+
+		hash := hash
+		t.completed(req.inFlight.peer, &hash)
+	}
+	return stalled
+}
+
+// schedule selects the peers to request the transactions that are due at the
+// passed time from and marks the requests as in flight.  A transaction is
+// requested from the preferred peer that announced it first when there is one
+// and from the peer that announced it first otherwise.  The returned
+// announcements are grouped by the peer they are to be requested from.
+func (t *txRequestTracker) schedule(now time.Time) map[*peerpkg.Peer][]*txAnnouncement {
+	var requests map[*peerpkg.Peer][]*txAnnouncement
+	for _, req := range t.txns {
+		if req.inFlight != nil {
+			continue
+		}
+
+		var best *txAnnouncement
+		for _, ann := range req.announcements {
+			if ann.completed || now.Before(ann.reqTime) {
+				continue
+			}
+			if best == nil || (ann.preferred && !best.preferred) ||
+				(ann.preferred == best.preferred && ann.seq < best.seq) {
+
+				best = ann
+			}
+		}
+		if best == nil {
+			continue
+		}
+
+		t.setInFlight(req, best, now)
+		if requests == nil {
+			requests = make(map[*peerpkg.Peer][]*txAnnouncement)
+		}
+		requests[best.peer] = append(requests[best.peer], best)
+	}
+	return requests
+}
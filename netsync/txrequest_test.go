@@ -0,0 +1,102 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	peerpkg "github.com/utreexo/utreexod/peer"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestTxRequestTracker ensures that the tracker requests each announced
+// transaction from one peer at a time, prefers the outbound peers, delays the
+// requests from inbound peers and moves on to the next peer when a peer doesn't
+// send the transaction.
+func TestTxRequestTracker(t *testing.T) {
+	t.Parallel()
+
+	inbound1, inbound2 := &peerpkg.Peer{}, &peerpkg.Peer{}
+	outbound := &peerpkg.Peer{}
+	hash := chainhash.Hash{0x01}
+	iv := wire.NewInvVect(wire.InvTypeTx, &hash)
+
+	// expectRequest ensures that the scheduled requests at the passed time
+	// are the transaction from the passed peer, or nothing for a nil peer.
+	tracker := newTxRequestTracker()
+	expectRequest := func(now time.Time, peer *peerpkg.Peer) {
+		t.Helper()
+
+		requests := tracker.schedule(now)
+		if peer == nil {
+			if len(requests) != 0 {
+				t.Fatalf("expected no requests, got %v", requests)
+			}
+			return
+		}
+		anns := requests[peer]
+		if len(requests) != 1 || len(anns) != 1 || anns[0].hash != hash {
+			t.Fatalf("expected the transaction to be requested from "+
+				"the peer, got %v", requests)
+		}
+	}
+
+	// Inbound peers are only asked after a delay even when they announce
+	// the transaction first, which gives an outbound peer the chance to be
+	// preferred.
+	start := time.Now()
+	tracker.announce(inbound1, iv, nil, false, start)
+	tracker.announce(inbound2, iv, nil, false, start.Add(time.Second))
+	expectRequest(start, nil)
+	tracker.announce(outbound, iv, nil, true, start.Add(time.Second))
+	expectRequest(start.Add(time.Second), outbound)
+	if !tracker.isRequested(&hash) {
+		t.Fatalf("expected the transaction to be requested")
+	}
+
+	// Nothing else is requested while the request is in flight, and the
+	// first inbound peer is asked once the outbound peer reports that it
+	// doesn't have the transaction.
+	now := start.Add(3 * time.Second)
+	expectRequest(now, nil)
+	tracker.completed(outbound, &hash)
+	expectRequest(now, inbound1)
+
+	// The request times out when the peer stalls, which leaves the last
+	// peer to request the transaction from.
+	stalled := tracker.expire(now.Add(txRequestTimeout))
+	if len(stalled) != 1 || stalled[0].peer != inbound1 {
+		t.Fatalf("expected the request from the inbound peer to time "+
+			"out, got %v", stalled)
+	}
+	expectRequest(now.Add(txRequestTimeout), inbound2)
+
+	// The transaction is forgotten once the last peer that announced it
+	// disconnects.
+	tracker.removePeer(inbound2)
+	if len(tracker.txns) != 0 {
+		t.Fatalf("expected the transaction to be forgotten")
+	}
+	for peer, counts := range tracker.peers {
+		if counts.announced != 0 || counts.inFlight != 0 {
+			t.Fatalf("unexpected counts %v of peer %p", counts, peer)
+		}
+	}
+
+	// A received transaction is forgotten along with its announcements.
+	tracker.announce(outbound, iv, nil, true, now)
+	expectRequest(now, outbound)
+	tracker.received(&hash)
+	if tracker.isRequested(&hash) || len(tracker.txns) != 0 {
+		t.Fatalf("expected the received transaction to be forgotten")
+	}
+	if counts := tracker.peers[outbound]; counts.announced != 0 ||
+		counts.inFlight != 0 {
+
+		t.Fatalf("unexpected counts %v of the outbound peer", counts)
+	}
+}
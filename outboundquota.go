@@ -0,0 +1,151 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/utreexo/utreexod/addrmgr"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// peerNetwork is a network that the outbound slots can be kept for with the
+// --outboundquota option.
+type peerNetwork int
+
+const (
+	netIPv4 peerNetwork = iota
+	netIPv6
+	netOnion
+	netI2P
+
+	// numPeerNetworks is the number of networks.
+	numPeerNetworks
+)
+
+// peerNetworkNames are the names the networks are given by in the
+// --outboundquota option.
+var peerNetworkNames = [numPeerNetworks]string{
+	netIPv4:  "ipv4",
+	netIPv6:  "ipv6",
+	netOnion: "onion",
+	netI2P:   "i2p",
+}
+
+// String returns the name of the network.
+func (n peerNetwork) String() string {
+	if n < 0 || n >= numPeerNetworks {
+		return fmt.Sprintf("Unknown peerNetwork (%d)", int(n))
+	}
+	return peerNetworkNames[n]
+}
+
+// networkOf returns the network of the address.  The IPv4 addresses mapped into
+// IPv6 are on IPv4.
+func networkOf(na *wire.NetAddress) peerNetwork {
+	switch {
+	case addrmgr.IsI2P(na):
+		return netI2P
+	case addrmgr.IsOnionCatTor(na):
+		return netOnion
+	case addrmgr.IsIPv4(na):
+		return netIPv4
+	default:
+		return netIPv6
+	}
+}
+
+// outboundQuotas are the numbers of automatic outbound slots that are kept for
+// the peers on each of the networks.
+type outboundQuotas [numPeerNetworks]int
+
+// parseOutboundQuotas parses the --outboundquota values of the form
+// network:count.  The last value given for a network is the one that counts.
+func parseOutboundQuotas(values []string) (outboundQuotas, error) {
+	var quotas outboundQuotas
+	for _, value := range values {
+		name, count, found := strings.Cut(value, ":")
+		if !found {
+			return quotas, fmt.Errorf("outbound quota %q isn't of "+
+				"the form network:count", value)
+		}
+
+		network := numPeerNetworks
+		name = strings.TrimSpace(strings.ToLower(name))
+		for n, networkName := range peerNetworkNames {
+			if networkName == name {
+				network = peerNetwork(n)
+				break
+			}
+		}
+		if network == numPeerNetworks {
+			return quotas, fmt.Errorf("unknown network %q -- "+
+				"supported networks are %s", name,
+				strings.Join(peerNetworkNames[:], ", "))
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || n < 0 {
+			return quotas, fmt.Errorf("invalid outbound quota %q for "+
+				"network %s", count, network)
+		}
+		quotas[network] = n
+	}
+	return quotas, nil
+}
+
+// total returns the number of slots kept for all of the networks.
+func (q *outboundQuotas) total() int {
+	var total int
+	for _, quota := range q {
+		total += quota
+	}
+	return total
+}
+
+// missing returns the networks that have fewer of the counted peers than their
+// quotas.
+func (q *outboundQuotas) missing(counts *[numPeerNetworks]int) []peerNetwork {
+	var missing []peerNetwork
+	for n, quota := range q {
+		if counts[n] < quota {
+			missing = append(missing, peerNetwork(n))
+		}
+	}
+	return missing
+}
+
+// allows returns whether an automatic outbound peer on the network can take a
+// slot when there are already the counted peers on each network, out of the
+// target number of automatic outbound peers.  A peer on a network that hasn't
+// met its quota always can, while other peers can't take the slots kept for
+// the networks that haven't.
+func (q *outboundQuotas) allows(network peerNetwork,
+	counts *[numPeerNetworks]int, target int) bool {
+
+	if counts[network] < q[network] {
+		return true
+	}
+
+	var overQuota int
+	for n, quota := range q {
+		if counts[n] > quota {
+			overQuota += counts[n] - quota
+		}
+	}
+	return overQuota < target-q.total()
+}
+
+// containsNetwork returns whether the network is one of the networks.
+func containsNetwork(networks []peerNetwork, network peerNetwork) bool {
+	for _, n := range networks {
+		if n == network {
+			return true
+		}
+	}
+	return false
+}
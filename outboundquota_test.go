@@ -0,0 +1,89 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+// TestParseOutboundQuotas ensures that the --outboundquota values are parsed
+// and that unknown networks and invalid counts are rejected.
+func TestParseOutboundQuotas(t *testing.T) {
+	quotas, err := parseOutboundQuotas([]string{"onion:2", "IPv6:1",
+		"i2p:3", "i2p:1"})
+	if err != nil {
+		t.Fatalf("unable to parse the outbound quotas: %v", err)
+	}
+	want := outboundQuotas{netIPv6: 1, netOnion: 2, netI2P: 1}
+	if quotas != want {
+		t.Fatalf("got outbound quotas %v, want %v", quotas, want)
+	}
+
+	for _, value := range []string{"onion", "cjdns:1", "ipv4:-1", "ipv4:x"} {
+		if _, err := parseOutboundQuotas([]string{value}); err == nil {
+			t.Errorf("%s: expected the outbound quota to be rejected",
+				value)
+		}
+	}
+}
+
+// TestOutboundQuotasAllows ensures that the outbound slots kept for the
+// networks that haven't met their quotas only go to peers on them.
+func TestOutboundQuotasAllows(t *testing.T) {
+	quotas := outboundQuotas{netOnion: 2, netI2P: 1}
+	const target = 8
+
+	tests := []struct {
+		name    string
+		network peerNetwork
+		counts  [numPeerNetworks]int
+		allowed bool
+	}{
+		{
+			name:    "free slot",
+			network: netIPv4,
+			counts:  [numPeerNetworks]int{netIPv4: 4},
+			allowed: true,
+		},
+		{
+			name:    "kept slots",
+			network: netIPv4,
+			counts:  [numPeerNetworks]int{netIPv4: 4, netIPv6: 1},
+			allowed: false,
+		},
+		{
+			name:    "network under its quota",
+			network: netOnion,
+			counts:  [numPeerNetworks]int{netIPv4: 5, netOnion: 1},
+			allowed: true,
+		},
+		{
+			name:    "network over its quota",
+			network: netOnion,
+			counts:  [numPeerNetworks]int{netIPv4: 3, netOnion: 4},
+			allowed: false,
+		},
+		{
+			name:    "quotas met",
+			network: netIPv6,
+			counts: [numPeerNetworks]int{netIPv4: 2, netOnion: 2,
+				netI2P: 1},
+			allowed: true,
+		},
+	}
+
+	for _, test := range tests {
+		got := quotas.allows(test.network, &test.counts, target)
+		if got != test.allowed {
+			t.Errorf("%s: got allowed %v, want %v", test.name, got,
+				test.allowed)
+		}
+	}
+
+	missing := quotas.missing(&[numPeerNetworks]int{netOnion: 2})
+	if len(missing) != 1 || missing[0] != netI2P {
+		t.Fatalf("got missing networks %v, want [i2p]", missing)
+	}
+}
@@ -289,8 +289,11 @@ func minUint32(a, b uint32) uint32 {
 
 // newNetAddress attempts to extract the IP address and port from the passed
 // net.Addr interface and create a bitcoin NetAddress structure using that
-// information.
-func newNetAddress(addr net.Addr, services wire.ServiceFlag) (*wire.NetAddress, error) {
+// information.  Hosts that aren't IP addresses, such as I2P destinations, are
+// turned into a NetAddress with hostToNetAddress when it's not nil.
+func newNetAddress(addr net.Addr, services wire.ServiceFlag,
+	hostToNetAddress HostToNetAddrFunc) (*wire.NetAddress, error) {
+
 	// addr will be a net.TCPAddr when not using a proxy.
 	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
 		ip := tcpAddr.IP
@@ -322,6 +325,9 @@ func newNetAddress(addr net.Addr, services wire.ServiceFlag) (*wire.NetAddress,
 	if err != nil {
 		return nil, err
 	}
+	if ip == nil && hostToNetAddress != nil {
+		return hostToNetAddress(host, uint16(port), services)
+	}
 	na := wire.NewNetAddressIPPort(ip, uint16(port), services)
 	return na, nil
 }
@@ -380,6 +386,16 @@ type StatsSnap struct {
 	LastPingNonce  uint64
 	LastPingTime   time.Time
 	LastPingMicros int64
+
+	// ProofBytesSent and ProofBytesRecv are the bytes of the utreexo
+	// proofs sent with blocks and transactions, ProofVersion is the
+	// version of the proofs exchanged with the peer and
+	// ProofRequestFailures is the number of requests for proofs the peer
+	// failed to serve.
+	ProofBytesSent       uint64
+	ProofBytesRecv       uint64
+	ProofVersion         uint32
+	ProofRequestFailures uint64
 }
 
 // HashFunc is a function which returns a block hash, height and error
@@ -422,12 +438,15 @@ type HostToNetAddrFunc func(host string, port uint16,
 // provided as a convenience.
 type Peer struct {
 	// The following variables must only be used atomically.
-	bytesReceived uint64
-	bytesSent     uint64
-	lastRecv      int64
-	lastSend      int64
-	connected     int32
-	disconnect    int32
+	bytesReceived        uint64
+	bytesSent            uint64
+	proofBytesReceived   uint64
+	proofBytesSent       uint64
+	proofRequestFailures uint64
+	lastRecv             int64
+	lastSend             int64
+	connected            int32
+	disconnect           int32
 
 	conn net.Conn
 
@@ -559,6 +578,11 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 		LastPingNonce:  p.lastPingNonce,
 		LastPingMicros: p.lastPingMicros,
 		LastPingTime:   p.lastPingTime,
+
+		ProofBytesSent:       atomic.LoadUint64(&p.proofBytesSent),
+		ProofBytesRecv:       atomic.LoadUint64(&p.proofBytesReceived),
+		ProofVersion:         p.UtreexoProofVersion(),
+		ProofRequestFailures: atomic.LoadUint64(&p.proofRequestFailures),
 	}
 
 	p.statsMtx.RUnlock()
@@ -826,6 +850,29 @@ func (p *Peer) IsUtreexoEnabled() bool {
 	return utreexoEnabled
 }
 
+// UtreexoProofVersion returns the version of the utreexo proofs exchanged with
+// the peer, which is 0 when either the peer or the local node doesn't support
+// the utreexo protocol.
+//
+// This function is safe for concurrent access.
+func (p *Peer) UtreexoProofVersion() uint32 {
+	if !p.IsUtreexoEnabled() ||
+		p.cfg.Services&wire.SFNodeUtreexo != wire.SFNodeUtreexo {
+
+		return 0
+	}
+
+	return wire.UtreexoProofVersion
+}
+
+// AddProofRequestFailure records that the peer failed to serve a request for a
+// block or a transaction with its utreexo proof.
+//
+// This function is safe for concurrent access.
+func (p *Peer) AddProofRequestFailure() {
+	atomic.AddUint64(&p.proofRequestFailures, 1)
+}
+
 // PushAddrMsg sends an addr message to the connected peer using the provided
 // addresses.  This function is useful over manually sending the message via
 // QueueMessage since it automatically limits the addresses to the maximum
@@ -1027,6 +1074,10 @@ func (p *Peer) readMessage(encoding wire.MessageEncoding) (wire.Message, []byte,
 	n, msg, buf, err := wire.ReadMessageWithEncodingN(p.conn,
 		p.ProtocolVersion(), p.cfg.ChainParams.Net, encoding)
 	atomic.AddUint64(&p.bytesReceived, uint64(n))
+	if err == nil {
+		atomic.AddUint64(&p.proofBytesReceived,
+			utreexoProofSize(msg, encoding))
+	}
 	if p.cfg.Listeners.OnRead != nil {
 		p.cfg.Listeners.OnRead(p, n, msg, err)
 	}
@@ -1055,6 +1106,27 @@ func (p *Peer) readMessage(encoding wire.MessageEncoding) (wire.Message, []byte,
 	return msg, buf, nil
 }
 
+// utreexoProofSize returns the serialized size of the utreexo proof of a block
+// or a transaction message encoded with the given encoding.
+func utreexoProofSize(msg wire.Message, enc wire.MessageEncoding) uint64 {
+	if enc&wire.UtreexoEncoding != wire.UtreexoEncoding {
+		return 0
+	}
+
+	switch m := msg.(type) {
+	case *wire.MsgBlock:
+		if m.UData != nil {
+			return uint64(m.UData.SerializeSizeCompact(false))
+		}
+	case *wire.MsgTx:
+		if m.UData != nil {
+			return uint64(m.UData.SerializeSizeCompact(true))
+		}
+	}
+
+	return 0
+}
+
 // writeMessage sends a bitcoin message to the peer with logging.
 func (p *Peer) writeMessage(msg wire.Message, enc wire.MessageEncoding) error {
 	// Don't do anything if we're disconnecting.
@@ -1090,6 +1162,9 @@ func (p *Peer) writeMessage(msg wire.Message, enc wire.MessageEncoding) error {
 	n, err := wire.WriteMessageWithEncodingN(p.conn, msg,
 		p.ProtocolVersion(), p.cfg.ChainParams.Net, enc)
 	atomic.AddUint64(&p.bytesSent, uint64(n))
+	if err == nil {
+		atomic.AddUint64(&p.proofBytesSent, utreexoProofSize(msg, enc))
+	}
 	if p.cfg.Listeners.OnWrite != nil {
 		p.cfg.Listeners.OnWrite(p, n, msg, err)
 	}
@@ -2288,7 +2363,8 @@ func (p *Peer) AssociateConnection(conn net.Conn) {
 		// Set up a NetAddress for the peer to be used with AddrManager.  We
 		// only do this inbound because outbound set this up at connection time
 		// and no point recomputing.
-		na, err := newNetAddress(p.conn.RemoteAddr(), p.services)
+		na, err := newNetAddress(p.conn.RemoteAddr(), p.services,
+			p.cfg.HostToNetAddress)
 		if err != nil {
 			log.Errorf("Cannot create remote net address: %v", err)
 			p.Disconnect()
@@ -0,0 +1,226 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// peerPermissions is the set of permissions given to a peer by the whitelisted
+// networks it's in and the whitebind listener it connected to.
+type peerPermissions uint32
+
+const (
+	// permNoBan keeps the peer from being banned or disconnected for
+	// misbehaving and protects it from eviction.  Bans of its address
+	// don't apply to it either.
+	permNoBan peerPermissions = 1 << iota
+
+	// permForceRelay relays the transactions of the peer even when
+	// transaction relay is disabled with --blocksonly, and announces them
+	// again when they're already in the memory pool.
+	permForceRelay
+
+	// permMempool allows the peer to request the contents of the memory
+	// pool even when bloom filters are disabled.
+	permMempool
+
+	// permBloomFilter allows the peer to load bloom filters even when
+	// they're disabled with --nopeerbloomfilters.
+	permBloomFilter
+
+	// permProof allows the peer to request as many blocks and transactions
+	// along with their utreexo proofs as it wants without adding to its ban
+	// score, such as for a local compact state node syncing from the node.
+	permProof
+
+	// permDownload allows the peer to download historical blocks and
+	// proofs even when the upload target leaves no room for them.
+	permDownload
+
+	// permAll is all of the permissions.
+	permAll = permNoBan | permForceRelay | permMempool | permBloomFilter |
+		permProof | permDownload
+
+	// permDefault is the permissions of the whitelisted networks and the
+	// whitebind listeners that are given without any.
+	permDefault = permNoBan
+)
+
+// permissionNames maps the permissions to the names they're given by in the
+// --whitelist and --whitebind options, in the order they're listed in.
+var permissionNames = []struct {
+	perm peerPermissions
+	name string
+}{
+	{permBloomFilter, "bloomfilter"},
+	{permDownload, "download"},
+	{permForceRelay, "forcerelay"},
+	{permMempool, "mempool"},
+	{permNoBan, "noban"},
+	{permProof, "proof"},
+}
+
+// has returns whether all of the passed permissions are in the set.
+func (p peerPermissions) has(perm peerPermissions) bool {
+	return p&perm == perm
+}
+
+// names returns the names of the permissions in the set.
+func (p peerPermissions) names() []string {
+	names := make([]string, 0, len(permissionNames))
+	for _, perm := range permissionNames {
+		if p.has(perm.perm) {
+			names = append(names, perm.name)
+		}
+	}
+	return names
+}
+
+// parsePermissions splits the permissions off a --whitelist or --whitebind
+// value of the form [perm,...@]address, and returns them along with the
+// address.  Values without any permissions are given the default ones.
+func parsePermissions(value string) (peerPermissions, string, error) {
+	names, addr, found := strings.Cut(value, "@")
+	if !found {
+		return permDefault, value, nil
+	}
+
+	var perms peerPermissions
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "all" {
+			perms |= permAll
+			continue
+		}
+
+		var known bool
+		for _, perm := range permissionNames {
+			if perm.name == name {
+				perms |= perm.perm
+				known = true
+				break
+			}
+		}
+		if !known {
+			return 0, "", fmt.Errorf("unknown permission %q", name)
+		}
+	}
+	return perms, addr, nil
+}
+
+// whitelist is a whitelisted network along with the permissions of its peers.
+type whitelist struct {
+	ipNet *net.IPNet
+	perms peerPermissions
+}
+
+// parseWhitelist parses a --whitelist value of the form [perm,...@]network,
+// where the network is an IP address or a network in CIDR notation.
+func parseWhitelist(value string) (*whitelist, error) {
+	perms, addr, err := parsePermissions(value)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ipNet, err := net.ParseCIDR(addr)
+	if err != nil {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid network %q", addr)
+		}
+		bits := 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	return &whitelist{ipNet: ipNet, perms: perms}, nil
+}
+
+// whitebind is a listen address along with the permissions of the inbound
+// peers that connect to it.
+type whitebind struct {
+	addr  string
+	ip    net.IP
+	port  string
+	perms peerPermissions
+}
+
+// parseWhitebind parses a --whitebind value of the form [perm,...@]address,
+// where the address is an IP address with an optional port, or :port to listen
+// on all the interfaces.
+func parseWhitebind(value, defaultPort string) (*whitebind, error) {
+	perms, addr, err := parsePermissions(value)
+	if err != nil {
+		return nil, err
+	}
+
+	addr = normalizeAddress(addr, defaultPort)
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	var ip net.IP
+	if host != "" {
+		if ip = net.ParseIP(host); ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", host)
+		}
+	}
+	return &whitebind{addr: addr, ip: ip, port: port, perms: perms}, nil
+}
+
+// matches returns whether the local address of an inbound connection is the
+// address of the whitebind listener.  Connections over I2P never match since
+// their local address isn't an IP address.
+func (w *whitebind) matches(local net.Addr) bool {
+	host, port, err := net.SplitHostPort(local.String())
+	if err != nil || port != w.port {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return w.ip == nil || w.ip.IsUnspecified() || ip.Equal(w.ip)
+}
+
+// connPermissions returns the permissions of a connection from the remote
+// address.  The permissions of all the whitelisted networks the address is in
+// are combined, along with those of the whitebind listener the connection was
+// accepted on for inbound connections, for which local is the local address.
+func connPermissions(remote, local net.Addr) peerPermissions {
+	var perms peerPermissions
+	if local != nil {
+		for _, bind := range cfg.whitebinds {
+			if bind.matches(local) {
+				perms |= bind.perms
+			}
+		}
+	}
+	if len(cfg.whitelists) == 0 {
+		return perms
+	}
+
+	host, _, err := net.SplitHostPort(remote.String())
+	if err != nil {
+		srvrLog.Warnf("Unable to SplitHostPort on '%s': %v", remote, err)
+		return perms
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		// Peers on Tor and I2P aren't whitelisted by their address.
+		return perms
+	}
+
+	for _, list := range cfg.whitelists {
+		if list.ipNet.Contains(ip) {
+			perms |= list.perms
+		}
+	}
+	return perms
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+// TestParseWhitelist ensures that the permissions and the networks of the
+// --whitelist values are parsed.
+func TestParseWhitelist(t *testing.T) {
+	tests := []struct {
+		value string
+		net   string
+		perms []string
+		valid bool
+	}{
+		{
+			value: "192.168.1.0/24",
+			net:   "192.168.1.0/24",
+			perms: []string{"noban"},
+			valid: true,
+		},
+		{
+			value: "mempool,Proof@::1",
+			net:   "::1/128",
+			perms: []string{"mempool", "proof"},
+			valid: true,
+		},
+		{
+			value: "all@10.1.2.3",
+			net:   "10.1.2.3/32",
+			perms: []string{"bloomfilter", "download", "forcerelay",
+				"mempool", "noban", "proof"},
+			valid: true,
+		},
+		{
+			value: "@10.1.2.3",
+		},
+		{
+			value: "relay@10.1.2.3",
+		},
+		{
+			value: "noban@example.com",
+		},
+	}
+
+	for _, test := range tests {
+		list, err := parseWhitelist(test.value)
+		if test.valid != (err == nil) {
+			t.Errorf("%s: unexpected error %v", test.value, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if list.ipNet.String() != test.net {
+			t.Errorf("%s: got network %v, want %v", test.value,
+				list.ipNet, test.net)
+		}
+		if names := list.perms.names(); !reflect.DeepEqual(names, test.perms) {
+			t.Errorf("%s: got permissions %v, want %v", test.value,
+				names, test.perms)
+		}
+	}
+}
+
+// TestWhitebindMatches ensures that the whitebind listeners only match the
+// connections accepted on their address.
+func TestWhitebindMatches(t *testing.T) {
+	tcpAddr := func(addr string) net.Addr {
+		tcp, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			t.Fatalf("unable to resolve %s: %v", addr, err)
+		}
+		return tcp
+	}
+
+	tests := []struct {
+		bind  string
+		local net.Addr
+		match bool
+	}{
+		{bind: "noban@127.0.0.1:18335", local: tcpAddr("127.0.0.1:18335"), match: true},
+		{bind: "noban@127.0.0.1", local: tcpAddr("127.0.0.1:8333"), match: true},
+		{bind: "noban@127.0.0.1:18335", local: tcpAddr("127.0.0.1:8333"), match: false},
+		{bind: "noban@127.0.0.1:18335", local: tcpAddr("10.0.0.1:18335"), match: false},
+		{bind: "noban@:18335", local: tcpAddr("10.0.0.1:18335"), match: true},
+		{bind: "noban@[::]:18335", local: tcpAddr("[::1]:18335"), match: true},
+		{bind: "noban@[::1]:18335", local: tcpAddr("[::1]:18335"), match: true},
+		{bind: "noban@:18335", local: &i2pAddr{addr: "example.b32.i2p:18335"}, match: false},
+	}
+
+	for _, test := range tests {
+		bind, err := parseWhitebind(test.bind, "8333")
+		if err != nil {
+			t.Fatalf("%s: unable to parse: %v", test.bind, err)
+		}
+		if got := bind.matches(test.local); got != test.match {
+			t.Errorf("%s: got match %v for %v, want %v", test.bind,
+				got, test.local, test.match)
+		}
+	}
+
+	if _, err := parseWhitebind("noban@localhost:18335", "8333"); err == nil {
+		t.Fatalf("expected a whitebind host name to be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+)
+
+// localTxBroadcast keeps the locally submitted transactions from being
+// announced to the peers that aren't on Tor or I2P, so that the peers that
+// learn about them first can't tell the IP address of the node they came from.
+// They're held back for good with --privatebroadcast, and for the
+// --broadcastdelay otherwise.
+type localTxBroadcast struct {
+	// delay is how long the transactions are held back for, or 0 when
+	// they're held back until they're mined or leave the mempool.
+	delay time.Duration
+
+	mtx sync.Mutex
+	txs map[chainhash.Hash]time.Time
+}
+
+// newLocalTxBroadcast returns a localTxBroadcast that holds the transactions
+// back for the delay, or for good when it's 0.
+func newLocalTxBroadcast(delay time.Duration) *localTxBroadcast {
+	return &localTxBroadcast{
+		delay: delay,
+		txs:   make(map[chainhash.Hash]time.Time),
+	}
+}
+
+// add starts holding back the locally submitted transaction.
+func (b *localTxBroadcast) add(hash *chainhash.Hash, now time.Time) {
+	b.mtx.Lock()
+	if _, ok := b.txs[*hash]; !ok {
+		b.txs[*hash] = now
+	}
+	b.mtx.Unlock()
+}
+
+// remove stops holding back the transaction.
+func (b *localTxBroadcast) remove(hash *chainhash.Hash) {
+	b.mtx.Lock()
+	delete(b.txs, *hash)
+	b.mtx.Unlock()
+}
+
+// held returns whether the transaction is a locally submitted one that is still
+// held back from the peers that aren't on Tor or I2P.
+func (b *localTxBroadcast) held(hash *chainhash.Hash, now time.Time) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	added, ok := b.txs[*hash]
+	if !ok {
+		return false
+	}
+	if b.delay > 0 && now.Sub(added) >= b.delay {
+		delete(b.txs, *hash)
+		return false
+	}
+	return true
+}
+
+// isPrivatePeer returns whether the peer is connected over Tor or I2P, which
+// the locally submitted transactions can be announced to while they're held
+// back from the others.
+func isPrivatePeer(sp *serverPeer) bool {
+	network := networkOf(sp.NA())
+	return network == netOnion || network == netI2P
+}
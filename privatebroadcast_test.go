@@ -0,0 +1,45 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+)
+
+// TestLocalTxBroadcast ensures that the locally submitted transactions are held
+// back for the delay, or until they're removed when there is none.
+func TestLocalTxBroadcast(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	local, remote := chainhash.Hash{1}, chainhash.Hash{2}
+
+	b := newLocalTxBroadcast(0)
+	b.add(&local, now)
+	if b.held(&remote, now) {
+		t.Fatalf("expected the remote transaction not to be held back")
+	}
+	if !b.held(&local, now.Add(24*time.Hour)) {
+		t.Fatalf("expected the local transaction to be held back")
+	}
+	b.remove(&local)
+	if b.held(&local, now) {
+		t.Fatalf("expected the removed transaction not to be held back")
+	}
+
+	b = newLocalTxBroadcast(time.Minute)
+	b.add(&local, now)
+	if !b.held(&local, now.Add(30*time.Second)) {
+		t.Fatalf("expected the local transaction to be held back " +
+			"before the delay is up")
+	}
+	if b.held(&local, now.Add(time.Minute)) {
+		t.Fatalf("expected the local transaction not to be held back " +
+			"once the delay is up")
+	}
+}
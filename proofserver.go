@@ -0,0 +1,458 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/blockchain/indexers"
+	"github.com/utreexo/utreexod/btcjson"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// proofServerReadTimeout is how long the proof server waits for a
+	// client to send its request.
+	proofServerReadTimeout = 10 * time.Second
+
+	// proofServerWriteTimeout is how long the proof server takes at most to
+	// write the response of a request.
+	proofServerWriteTimeout = time.Minute
+
+	// proofServerMaxOutPoints is the maximum number of outpoints that can be
+	// proven in a single leaf proof request.
+	proofServerMaxOutPoints = 1000
+
+	// contentTypeJSON and contentTypeBinary are the content types of the
+	// responses of the proof server.
+	contentTypeJSON   = "application/json"
+	contentTypeBinary = "application/octet-stream"
+)
+
+// errProofServerNotFound is returned when the requested data doesn't exist.
+var errProofServerNotFound = errors.New("not found")
+
+// proofServerConfig is a descriptor containing the proof server configuration.
+type proofServerConfig struct {
+	// Listeners defines a slice of listeners for which the proof server
+	// will take ownership of and accept connections.
+	Listeners []net.Listener
+
+	// Chain is the chain the served data is for.
+	Chain *blockchain.BlockChain
+
+	// UtreexoProofIndex and FlatUtreexoProofIndex are the indexes the
+	// proofs are served from.  One of them must be set.
+	UtreexoProofIndex     *indexers.UtreexoProofIndex
+	FlatUtreexoProofIndex *indexers.FlatUtreexoProofIndex
+
+	// UploadTarget is the budget the bytes of the responses count toward,
+	// which stops the proofs of historical blocks from being served once it
+	// leaves no room for them.  It's nil when there is no budget.
+	UploadTarget *uploadTarget
+}
+
+// proofServer serves the utreexo data of a bridge node over HTTP so that light
+// wallets can use it without speaking the P2P protocol.  The endpoints and the
+// formats are documented in docs/proof_server.md.
+type proofServer struct {
+	started  int32
+	shutdown int32
+
+	cfg        proofServerConfig
+	httpServer http.Server
+	wg         sync.WaitGroup
+}
+
+// proofServerError is the body of a response to a failed request.
+type proofServerError struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes the value as the JSON body of the response.
+func (s *proofServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		rpcsLog.Debugf("Failed to write proof server response: %v", err)
+	}
+}
+
+// writeError writes the error as the body of the response.
+func (s *proofServer) writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errProofServerNotFound) {
+		status = http.StatusNotFound
+	}
+	s.writeJSON(w, status, &proofServerError{Error: err.Error()})
+}
+
+// writeResponse writes the passed in serialized data if the binary format was
+// requested and the JSON encoding of v otherwise.
+func (s *proofServer) writeResponse(w http.ResponseWriter, r *http.Request,
+	serialize func() ([]byte, error), v interface{}) {
+
+	if !wantsBinary(r) {
+		s.writeJSON(w, http.StatusOK, v)
+		return
+	}
+
+	serialized, err := serialize()
+	if err != nil {
+		s.writeJSON(w, http.StatusInternalServerError,
+			&proofServerError{Error: err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeBinary)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(serialized)
+	if err != nil {
+		rpcsLog.Debugf("Failed to write proof server response: %v", err)
+	}
+}
+
+// wantsBinary returns true if the request asks for the binary format either
+// with the format query parameter or with the Accept header.
+func wantsBinary(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "bin":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), contentTypeBinary)
+}
+
+// blockByHeightOrHash resolves the passed in height or block hash to a block
+// in the main chain.
+func (s *proofServer) blockByHeightOrHash(str string) (int32, *chainhash.Hash, error) {
+	if len(str) == chainhash.MaxHashStringSize {
+		hash, err := chainhash.NewHashFromStr(str)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid block hash %q", str)
+		}
+		height, err := s.cfg.Chain.BlockHeightByHash(hash)
+		if err != nil {
+			return 0, nil, fmt.Errorf("block %v: %w", hash,
+				errProofServerNotFound)
+		}
+		return height, hash, nil
+	}
+
+	height, err := strconv.ParseInt(str, 10, 32)
+	if err != nil || height < 0 {
+		return 0, nil, fmt.Errorf("invalid block height %q", str)
+	}
+	hash, err := s.cfg.Chain.BlockHashByHeight(int32(height))
+	if err != nil {
+		return 0, nil, fmt.Errorf("block at height %d: %w", height,
+			errProofServerNotFound)
+	}
+	return int32(height), hash, nil
+}
+
+// proofServerRootsResult is the JSON response of the roots endpoint.
+type proofServerRootsResult struct {
+	Height    int32    `json:"height"`
+	Hash      string   `json:"hash"`
+	NumLeaves uint64   `json:"numleaves"`
+	Roots     []string `json:"roots"`
+}
+
+// handleRoots serves the roots of the accumulator after the requested block.
+func (s *proofServer) handleRoots(w http.ResponseWriter, r *http.Request) {
+	height, hash, err := s.blockByHeightOrHash(strings.TrimPrefix(r.URL.Path, "/v1/roots/"))
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	roots, numLeaves, found, err := s.cfg.Chain.FetchUtreexoRoots(height)
+	if err == nil && !found {
+		err = fmt.Errorf("roots at height %d: %w", height, errProofServerNotFound)
+	}
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	result := &proofServerRootsResult{
+		Height:    height,
+		Hash:      hash.String(),
+		NumLeaves: numLeaves,
+		Roots:     make([]string, 0, len(roots)),
+	}
+	for _, root := range roots {
+		result.Roots = append(result.Roots, hex.EncodeToString(root[:]))
+	}
+
+	s.writeResponse(w, r, func() ([]byte, error) {
+		uRoots := make([]utreexo.Hash, len(roots))
+		for i, root := range roots {
+			uRoots[i] = utreexo.Hash(*root)
+		}
+		return blockchain.SerializeUtreexoRoots(numLeaves, uRoots)
+	}, result)
+}
+
+// handleBlockProof serves the proof for the requested block.
+func (s *proofServer) handleBlockProof(w http.ResponseWriter, r *http.Request) {
+	height, hash, err := s.blockByHeightOrHash(strings.TrimPrefix(r.URL.Path, "/v1/blockproof/"))
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if height == 0 {
+		s.writeError(w, fmt.Errorf("the genesis block doesn't have a proof"))
+		return
+	}
+	if s.cfg.UploadTarget != nil &&
+		s.cfg.Chain.BestSnapshot().Height-height >= historicalBlockDepth &&
+		!s.cfg.UploadTarget.servesHistorical(time.Now()) {
+
+		s.writeJSON(w, http.StatusServiceUnavailable,
+			&proofServerError{Error: errUploadTargetReached.Error()})
+		return
+	}
+
+	var ud *wire.UData
+	if s.cfg.UtreexoProofIndex != nil {
+		ud, err = s.cfg.UtreexoProofIndex.FetchUtreexoProof(hash)
+	} else {
+		ud, err = s.cfg.FlatUtreexoProofIndex.FetchUtreexoProof(height, false)
+	}
+	if err != nil {
+		s.writeError(w, fmt.Errorf("proof for block %v: %w", hash,
+			errProofServerNotFound))
+		return
+	}
+
+	targetHashes, err := s.cfg.Chain.ReconstructUData(ud, *hash)
+	if err != nil {
+		s.writeJSON(w, http.StatusInternalServerError,
+			&proofServerError{Error: err.Error()})
+		return
+	}
+
+	result := &btcjson.GetUtreexoProofVerboseResult{
+		ProofHashes:     make([]string, 0, len(ud.AccProof.Proof)),
+		RememberIndexes: ud.RememberIdx,
+		TargetHashes:    make([]string, 0, len(targetHashes)),
+		TargetPreimages: make([]string, 0, len(ud.LeafDatas)),
+		ProofTargets:    ud.AccProof.Targets,
+	}
+	for _, proofHash := range ud.AccProof.Proof {
+		result.ProofHashes = append(result.ProofHashes, hex.EncodeToString(proofHash[:]))
+	}
+	for _, targetHash := range targetHashes {
+		result.TargetHashes = append(result.TargetHashes, hex.EncodeToString(targetHash[:]))
+	}
+	for _, ld := range ud.LeafDatas {
+		var buf bytes.Buffer
+		err := ld.Serialize(&buf)
+		if err != nil {
+			s.writeJSON(w, http.StatusInternalServerError,
+				&proofServerError{Error: err.Error()})
+			return
+		}
+		result.TargetPreimages = append(result.TargetPreimages, hex.EncodeToString(buf.Bytes()))
+	}
+
+	s.cfg.Chain.RecordProofServed(ud)
+	s.writeResponse(w, r, func() ([]byte, error) {
+		var buf bytes.Buffer
+		err := ud.Serialize(&buf)
+		return buf.Bytes(), err
+	}, result)
+}
+
+// parseOutPoint parses an outpoint in the txid:vout format.
+func parseOutPoint(str string) (*wire.OutPoint, error) {
+	txid, vout, ok := strings.Cut(str, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid outpoint %q", str)
+	}
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid txid in outpoint %q", str)
+	}
+	index, err := strconv.ParseUint(vout, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vout in outpoint %q", str)
+	}
+	return wire.NewOutPoint(hash, uint32(index)), nil
+}
+
+// handleLeafProof serves a proof for the requested outpoints against the
+// accumulator at the chain tip.
+func (s *proofServer) handleLeafProof(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()["outpoint"]
+	if len(params) == 0 {
+		s.writeError(w, fmt.Errorf("no outpoints were given"))
+		return
+	}
+	if len(params) > proofServerMaxOutPoints {
+		s.writeError(w, fmt.Errorf("at most %d outpoints can be proven "+
+			"at once", proofServerMaxOutPoints))
+		return
+	}
+
+	outpoints := make([]wire.OutPoint, 0, len(params))
+	utxos := make([]*blockchain.UtxoEntry, 0, len(params))
+	for _, param := range params {
+		outpoint, err := parseOutPoint(param)
+		if err != nil {
+			s.writeError(w, err)
+			return
+		}
+
+		utxo, err := s.cfg.Chain.FetchUtxoEntry(*outpoint)
+		if err != nil || utxo == nil || utxo.IsSpent() {
+			s.writeError(w, fmt.Errorf("utxo %v: %w", outpoint,
+				errProofServerNotFound))
+			return
+		}
+
+		outpoints = append(outpoints, *outpoint)
+		utxos = append(utxos, utxo)
+	}
+
+	var proof *blockchain.ChainTipProof
+	var err error
+	if s.cfg.UtreexoProofIndex != nil {
+		proof, err = s.cfg.UtreexoProofIndex.ProveUtxos(utxos, &outpoints)
+	} else {
+		proof, err = s.cfg.FlatUtreexoProofIndex.ProveUtxos(utxos, &outpoints)
+	}
+	if err != nil {
+		s.writeJSON(w, http.StatusInternalServerError,
+			&proofServerError{Error: err.Error()})
+		return
+	}
+
+	result := &btcjson.ProveUtxoChainTipInclusionVerboseResult{
+		ProvedAtHash: proof.ProvedAtHash.String(),
+		ProofHashes:  make([]string, 0, len(proof.AccProof.Proof)),
+		ProofTargets: proof.AccProof.Targets,
+		HashesProven: make([]string, 0, len(proof.HashesProven)),
+		Hex:          proof.String(),
+	}
+	for _, proofHash := range proof.AccProof.Proof {
+		result.ProofHashes = append(result.ProofHashes, hex.EncodeToString(proofHash[:]))
+	}
+	for _, hashProven := range proof.HashesProven {
+		result.HashesProven = append(result.HashesProven, hex.EncodeToString(hashProven[:]))
+	}
+
+	s.writeResponse(w, r, func() ([]byte, error) {
+		var buf bytes.Buffer
+		err := proof.Serialize(&buf)
+		return buf.Bytes(), err
+	}, result)
+}
+
+// onlyGet wraps the handler so that it only accepts GET requests.
+func onlyGet(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// uploadCountingWriter counts the bytes of the responses toward the upload
+// target.
+type uploadCountingWriter struct {
+	http.ResponseWriter
+	target *uploadTarget
+}
+
+// Write writes the bytes and counts them toward the upload target.
+func (w *uploadCountingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.target.add(uint64(n), time.Now())
+	return n, err
+}
+
+// countUpload wraps the handler so that the bytes of its responses count toward
+// the upload target when there is one.
+func (s *proofServer) countUpload(handler http.HandlerFunc) http.HandlerFunc {
+	if s.cfg.UploadTarget == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(&uploadCountingWriter{w, s.cfg.UploadTarget}, r)
+	}
+}
+
+// Start is used by server.go to start the proof server listener.
+func (s *proofServer) Start() {
+	if atomic.AddInt32(&s.started, 1) != 1 {
+		return
+	}
+
+	rpcsLog.Trace("Starting proof server")
+	for _, listener := range s.cfg.Listeners {
+		s.wg.Add(1)
+		go func(listener net.Listener) {
+			rpcsLog.Infof("Proof server listening on %s", listener.Addr())
+			s.httpServer.Serve(listener)
+			rpcsLog.Tracef("Proof server done listening on %s", listener.Addr())
+			s.wg.Done()
+		}(listener)
+	}
+}
+
+// Stop is used by server.go to stop the proof server listener.
+func (s *proofServer) Stop() error {
+	if atomic.AddInt32(&s.shutdown, 1) != 1 {
+		rpcsLog.Infof("Proof server is already in the process of shutting down")
+		return nil
+	}
+	rpcsLog.Warnf("Proof server shutting down")
+	err := s.httpServer.Close()
+	s.wg.Wait()
+	rpcsLog.Infof("Proof server shutdown complete")
+	return err
+}
+
+// newProofServer returns a new instance of the proofServer struct.
+func newProofServer(config *proofServerConfig) (*proofServer, error) {
+	if config.UtreexoProofIndex == nil && config.FlatUtreexoProofIndex == nil {
+		return nil, errors.New("the proof server requires a utreexo proof index")
+	}
+
+	s := proofServer{cfg: *config}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/roots/", onlyGet(s.countUpload(s.handleRoots)))
+	mux.HandleFunc("/v1/blockproof/", onlyGet(s.countUpload(s.handleBlockProof)))
+	mux.HandleFunc("/v1/leafproof", onlyGet(s.countUpload(s.handleLeafProof)))
+	s.httpServer = http.Server{
+		Handler:      mux,
+		ReadTimeout:  proofServerReadTimeout,
+		WriteTimeout: proofServerWriteTimeout,
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,357 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btclog"
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/blockchain/indexers"
+	"github.com/utreexo/utreexod/btcjson"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// newUtreexoIndexTestChain returns a chain of the five import test blocks with
+// a utreexo proof index and the indexes created by the passed functions.  The
+// logs are disabled until the test is done.
+func newUtreexoIndexTestChain(t *testing.T,
+	newIndexes ...func(database.DB) indexers.Indexer) (
+	*blockchain.BlockChain, *indexers.UtreexoProofIndex) {
+
+	t.Helper()
+
+	oldLog := srvrLog
+	srvrLog = btclog.Disabled
+	blockchain.UseLogger(btclog.Disabled)
+	indexers.UseLogger(btclog.Disabled)
+	t.Cleanup(func() {
+		srvrLog = oldLog
+		blockchain.UseLogger(chanLog)
+		indexers.UseLogger(indxLog)
+	})
+
+	dir := t.TempDir()
+	writeImportTestFile(t, filepath.Join(dir, "blk00000.dat"), nil,
+		loadImportTestBlocks(t), nil)
+
+	// The test blocks spend the coinbases of the blocks before them.
+	params := chaincfg.MainNetParams
+	params.CoinbaseMaturity = 1
+
+	dataDir := t.TempDir()
+	db, err := database.Create("ffldb", filepath.Join(dataDir, "db"),
+		wire.MainNet)
+	if err != nil {
+		t.Fatalf("unable to create the database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	utreexoProofIndex, err := indexers.NewUtreexoProofIndex(db, false,
+		50*1024*1024, &params, dataDir)
+	if err != nil {
+		t.Fatalf("unable to create the utreexo proof index: %v", err)
+	}
+	t.Cleanup(func() { utreexoProofIndex.FlushUtreexoState() })
+	indexes := []indexers.Indexer{utreexoProofIndex}
+	for _, newIndex := range newIndexes {
+		indexes = append(indexes, newIndex(db))
+	}
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:           db,
+		ChainParams:  &params,
+		TimeSource:   blockchain.NewMedianTime(),
+		IndexManager: indexers.NewManager(db, indexes),
+	})
+	if err != nil {
+		t.Fatalf("unable to create the chain: %v", err)
+	}
+	if err := importBlockFiles(chain, wire.MainNet, []string{dir}, nil); err != nil {
+		t.Fatalf("importBlockFiles: unexpected error: %v", err)
+	}
+
+	return chain, utreexoProofIndex
+}
+
+// decodeAccumulatorHashes decodes the hex encoded accumulator hashes of a proof
+// server response.  They're in byte order unlike block hashes and txids.
+func decodeAccumulatorHashes(t *testing.T, strs []string) []utreexo.Hash {
+	t.Helper()
+
+	hashes := make([]utreexo.Hash, 0, len(strs))
+	for _, str := range strs {
+		b, err := hex.DecodeString(str)
+		if err != nil || len(b) != len(utreexo.Hash{}) {
+			t.Fatalf("invalid accumulator hash %q", str)
+		}
+		hashes = append(hashes, *(*utreexo.Hash)(b))
+	}
+	return hashes
+}
+
+// testStump returns the accumulator of the chain after the block at the passed
+// height.
+func testStump(t *testing.T, chain *blockchain.BlockChain, height int32) utreexo.Stump {
+	t.Helper()
+
+	roots, numLeaves, found, err := chain.FetchUtreexoRoots(height)
+	if err != nil || !found {
+		t.Fatalf("unable to fetch the roots at height %d: %v", height, err)
+	}
+	stump := utreexo.Stump{NumLeaves: numLeaves}
+	for _, root := range roots {
+		stump.Roots = append(stump.Roots, utreexo.Hash(*root))
+	}
+	return stump
+}
+
+// TestProofServer ensures that the proof server serves the roots and the proofs
+// of the index in both formats, that the accumulator hashes of the JSON
+// responses are in byte order, and that bad requests are rejected.
+func TestProofServer(t *testing.T) {
+	chain, utreexoProofIndex := newUtreexoIndexTestChain(t)
+
+	if _, err := newProofServer(&proofServerConfig{Chain: chain}); err == nil {
+		t.Fatalf("expected an error for a proof server without an index")
+	}
+	s, err := newProofServer(&proofServerConfig{
+		Chain:             chain,
+		UtreexoProofIndex: utreexoProofIndex,
+	})
+	if err != nil {
+		t.Fatalf("newProofServer: unexpected error: %v", err)
+	}
+
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, r)
+		return w
+	}
+	getJSON := func(target string, v interface{}) {
+		t.Helper()
+
+		w := get(target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: got status %d (%s), want %d", target,
+				w.Code, w.Body, http.StatusOK)
+		}
+		if got := w.Header().Get("Content-Type"); got != contentTypeJSON {
+			t.Fatalf("GET %s: got content type %q, want %q", target,
+				got, contentTypeJSON)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: unable to decode %s: %v", target,
+				w.Body, err)
+		}
+	}
+	getBinary := func(target string, header http.Header) []byte {
+		t.Helper()
+
+		w := get(target, header)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: got status %d (%s), want %d", target,
+				w.Code, w.Body, http.StatusOK)
+		}
+		if got := w.Header().Get("Content-Type"); got != contentTypeBinary {
+			t.Fatalf("GET %s: got content type %q, want %q", target,
+				got, contentTypeBinary)
+		}
+		return w.Body.Bytes()
+	}
+
+	best := chain.BestSnapshot()
+	tipStump := testStump(t, chain, best.Height)
+
+	// The roots by height and by hash, as JSON and in binary.
+	for _, block := range []string{fmt.Sprint(best.Height), best.Hash.String()} {
+		var roots proofServerRootsResult
+		getJSON("/v1/roots/"+block, &roots)
+		if roots.Height != best.Height || roots.Hash != best.Hash.String() ||
+			roots.NumLeaves != tipStump.NumLeaves {
+
+			t.Fatalf("got roots at %s height %d with %d leaves, want "+
+				"%v height %d with %d leaves", roots.Hash,
+				roots.Height, roots.NumLeaves, best.Hash,
+				best.Height, tipStump.NumLeaves)
+		}
+		gotRoots := decodeAccumulatorHashes(t, roots.Roots)
+		if !reflect.DeepEqual(gotRoots, tipStump.Roots) {
+			t.Fatalf("got roots %v, want %v", gotRoots, tipStump.Roots)
+		}
+
+		binaryHeaders := []http.Header{
+			nil,
+			{"Accept": []string{contentTypeBinary}},
+		}
+		targets := []string{"/v1/roots/" + block + "?format=bin", "/v1/roots/" + block}
+		for i, header := range binaryHeaders {
+			serialized := getBinary(targets[i], header)
+			numLeaves, gotRoots, err := blockchain.DeserializeUtreexoRoots(serialized)
+			if err != nil {
+				t.Fatalf("unable to decode the binary roots: %v", err)
+			}
+			if numLeaves != tipStump.NumLeaves ||
+				!reflect.DeepEqual(gotRoots, tipStump.Roots) {
+
+				t.Fatalf("got binary roots %v with %d leaves, want "+
+					"%v with %d leaves", gotRoots, numLeaves,
+					tipStump.Roots, tipStump.NumLeaves)
+			}
+		}
+	}
+
+	// format=json takes precedence over the Accept header.
+	w := get("/v1/roots/1?format=json", http.Header{"Accept": []string{contentTypeBinary}})
+	if got := w.Header().Get("Content-Type"); w.Code != http.StatusOK || got != contentTypeJSON {
+		t.Fatalf("got status %d and content type %q, want %d and %q",
+			w.Code, got, http.StatusOK, contentTypeJSON)
+	}
+
+	// The proofs of the blocks verify against the accumulator before them.
+	for height := int32(1); height <= best.Height; height++ {
+		var proof btcjson.GetUtreexoProofVerboseResult
+		getJSON(fmt.Sprintf("/v1/blockproof/%d", height), &proof)
+		accProof := utreexo.Proof{
+			Targets: proof.ProofTargets,
+			Proof:   decodeAccumulatorHashes(t, proof.ProofHashes),
+		}
+		targetHashes := decodeAccumulatorHashes(t, proof.TargetHashes)
+		_, err := utreexo.Verify(testStump(t, chain, height-1), targetHashes, accProof)
+		if err != nil {
+			t.Fatalf("the proof of block %d doesn't verify: %v", height, err)
+		}
+
+		var ud wire.UData
+		serialized := getBinary(fmt.Sprintf("/v1/blockproof/%d?format=bin", height), nil)
+		if err := ud.Deserialize(bytes.NewReader(serialized)); err != nil {
+			t.Fatalf("unable to decode the binary proof of block %d: %v",
+				height, err)
+		}
+		if !reflect.DeepEqual(ud.AccProof.Targets, accProof.Targets) {
+			t.Fatalf("got binary proof targets %v for block %d, want %v",
+				ud.AccProof.Targets, height, accProof.Targets)
+		}
+	}
+
+	// A proof for every output of the tip block, which are all unspent.
+	tipBlock, err := chain.BlockByHash(&best.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var query []string
+	for _, tx := range tipBlock.Transactions() {
+		for i := range tx.MsgTx().TxOut {
+			query = append(query, fmt.Sprintf("outpoint=%v:%d", tx.Hash(), i))
+		}
+	}
+	leafProofTarget := "/v1/leafproof?" + strings.Join(query, "&")
+	var leafProof btcjson.ProveUtxoChainTipInclusionVerboseResult
+	getJSON(leafProofTarget, &leafProof)
+	if leafProof.ProvedAtHash != best.Hash.String() {
+		t.Fatalf("got a proof at %s, want %v", leafProof.ProvedAtHash,
+			best.Hash)
+	}
+	hashesProven := decodeAccumulatorHashes(t, leafProof.HashesProven)
+	if len(hashesProven) != len(query) {
+		t.Fatalf("got %d hashes proven, want %d", len(hashesProven),
+			len(query))
+	}
+	accProof := utreexo.Proof{
+		Targets: leafProof.ProofTargets,
+		Proof:   decodeAccumulatorHashes(t, leafProof.ProofHashes),
+	}
+	if _, err := utreexo.Verify(tipStump, hashesProven, accProof); err != nil {
+		t.Fatalf("the leaf proof doesn't verify: %v", err)
+	}
+
+	var chainTipProof blockchain.ChainTipProof
+	serialized := getBinary(leafProofTarget+"&format=bin", nil)
+	if err := chainTipProof.Deserialize(bytes.NewReader(serialized)); err != nil {
+		t.Fatalf("unable to decode the binary leaf proof: %v", err)
+	}
+	if *chainTipProof.ProvedAtHash != best.Hash ||
+		!reflect.DeepEqual(chainTipProof.HashesProven, hashesProven) {
+
+		t.Fatalf("got binary leaf proof at %v proving %v, want %v "+
+			"proving %v", chainTipProof.ProvedAtHash,
+			chainTipProof.HashesProven, best.Hash, hashesProven)
+	}
+
+	tooMany := make([]string, proofServerMaxOutPoints+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("outpoint=%v:%d", tipBlock.Transactions()[0].Hash(), i)
+	}
+	// The coinbase of the first block is spent by the second one.
+	firstBlock, err := chain.BlockByHeight(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spent := firstBlock.Transactions()[0].Hash()
+	unknownHash := chainhash.HashH([]byte("unknown"))
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"roots of an invalid height", "/v1/roots/abc", http.StatusBadRequest},
+		{"roots of a negative height", "/v1/roots/-1", http.StatusBadRequest},
+		{"roots past the tip", "/v1/roots/100", http.StatusNotFound},
+		{"roots of an invalid hash", "/v1/roots/" + strings.Repeat("z", 64), http.StatusBadRequest},
+		{"roots of an unknown block", "/v1/roots/" + unknownHash.String(), http.StatusNotFound},
+		{"proof of the genesis block", "/v1/blockproof/0", http.StatusBadRequest},
+		{"proof past the tip", "/v1/blockproof/100", http.StatusNotFound},
+		{"proof of an unknown block", "/v1/blockproof/" + unknownHash.String(), http.StatusNotFound},
+		{"leaf proof without outpoints", "/v1/leafproof", http.StatusBadRequest},
+		{"leaf proof of too many outpoints", "/v1/leafproof?" + strings.Join(tooMany, "&"), http.StatusBadRequest},
+		{"leaf proof without a vout", "/v1/leafproof?outpoint=" + unknownHash.String(), http.StatusBadRequest},
+		{"leaf proof of an invalid txid", "/v1/leafproof?outpoint=zz:0", http.StatusBadRequest},
+		{"leaf proof of an invalid vout", "/v1/leafproof?outpoint=" + unknownHash.String() + ":x", http.StatusBadRequest},
+		{"leaf proof of an unknown utxo", "/v1/leafproof?outpoint=" + unknownHash.String() + ":0", http.StatusNotFound},
+		{"leaf proof of a spent utxo", fmt.Sprintf("/v1/leafproof?outpoint=%v:0", spent), http.StatusNotFound},
+	}
+	for _, test := range tests {
+		w := get(test.target, nil)
+		if w.Code != test.status {
+			t.Errorf("%s: got status %d (%s), want %d", test.name,
+				w.Code, w.Body, test.status)
+			continue
+		}
+
+		var proofErr proofServerError
+		err := json.Unmarshal(w.Body.Bytes(), &proofErr)
+		if err != nil || proofErr.Error == "" {
+			t.Errorf("%s: got error body %s, want a reason",
+				test.name, w.Body)
+		}
+	}
+
+	// Only GET requests are accepted.
+	r := httptest.NewRequest(http.MethodPost, "/v1/roots/1", nil)
+	w = httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodGet {
+		t.Errorf("POST: got status %d allowing %q, want %d allowing %q",
+			w.Code, w.Header().Get("Allow"),
+			http.StatusMethodNotAllowed, http.MethodGet)
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/utreexo/utreexod/btcjson"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+)
+
+const (
+	// restDefaultHeaders and restMaxHeaders are the default and the
+	// maximum number of headers that are returned by the headers endpoint.
+	restDefaultHeaders = 5
+	restMaxHeaders     = 2000
+
+	// restImmutableCache is the Cache-Control header of the responses that
+	// never change, like the serialization of a block by its hash.
+	restImmutableCache = "public, max-age=31536000, immutable"
+
+	// contentTypeText is the content type of the hex responses.
+	contentTypeText = "text/plain"
+)
+
+// restFormat is the output format of a REST response.  It's given as the
+// extension of the path.
+type restFormat int
+
+const (
+	restFormatBinary restFormat = iota
+	restFormatHex
+	restFormatJSON
+)
+
+// restFormats maps the extensions of the paths to the formats.
+var restFormats = map[string]restFormat{
+	"bin":  restFormatBinary,
+	"hex":  restFormatHex,
+	"json": restFormatJSON,
+}
+
+// errRESTNotFound is returned when the requested data doesn't exist.
+var errRESTNotFound = errors.New("not found")
+
+// restHandler serves the unauthenticated read-only REST interface on the RPC
+// listeners.  The endpoints are compatible with the REST interface of Bitcoin
+// Core and are documented in docs/rest.md.
+type restHandler struct {
+	server *rpcServer
+	mux    *http.ServeMux
+}
+
+// newRESTHandler returns a handler for the REST interface of the RPC server.
+func newRESTHandler(s *rpcServer) *restHandler {
+	h := &restHandler{server: s, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/rest/block/notxdetails/", h.handleBlockNoTxDetails)
+	h.mux.HandleFunc("/rest/block/", h.handleBlock)
+	h.mux.HandleFunc("/rest/headers/", h.handleHeaders)
+	h.mux.HandleFunc("/rest/blockhashbyheight/", h.handleBlockHashByHeight)
+	h.mux.HandleFunc("/rest/tx/", h.handleTx)
+	h.mux.HandleFunc("/rest/chaininfo.json", h.handleChainInfo)
+	h.mux.HandleFunc("/rest/mempool/info.json", h.handleMempoolInfo)
+	h.mux.HandleFunc("/rest/mempool/contents.json", h.handleMempoolContents)
+	h.mux.HandleFunc("/rest/utreexoproof/", h.handleUtreexoProof)
+	return h
+}
+
+// ServeHTTP serves a REST request.
+//
+// This is part of the http.Handler interface.
+func (h *restHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Limit the number of connections to max allowed like for the RPC
+	// requests.
+	if h.server.limitConnections(w, r.RemoteAddr) {
+		return
+	}
+	h.server.incrementClients()
+	defer h.server.decrementClients()
+
+	h.mux.ServeHTTP(w, r)
+}
+
+// writeError writes the error as the plain text body of the response.  RPC
+// errors are mapped to the matching HTTP status.
+func (h *restHandler) writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var rpcErr *btcjson.RPCError
+	switch {
+	case errors.Is(err, errRESTNotFound):
+		status = http.StatusNotFound
+	case errors.As(err, &rpcErr):
+		switch rpcErr.Code {
+		case btcjson.ErrRPCBlockNotFound:
+			status = http.StatusNotFound
+		case btcjson.ErrRPCInternal.Code:
+			status = http.StatusInternalServerError
+		}
+		err = errors.New(rpcErr.Message)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// write writes the body of a successful response.
+func (h *restHandler) write(w http.ResponseWriter, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(body)
+	if err != nil {
+		rpcsLog.Debugf("Failed to write REST response: %v", err)
+	}
+}
+
+// writeJSON writes the value as the JSON body of the response.
+func (h *restHandler) writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		h.writeError(w, internalRPCError(err.Error(), "Failed to marshal REST response"))
+		return
+	}
+	h.write(w, contentTypeJSON, append(body, '\n'))
+}
+
+// writeHex writes the hex encoded data in the requested format.  The data is
+// decoded for the binary format.
+func (h *restHandler) writeHex(w http.ResponseWriter, format restFormat, hexStr string) {
+	if format == restFormatHex {
+		h.write(w, contentTypeText, []byte(hexStr+"\n"))
+		return
+	}
+
+	body, err := hex.DecodeString(hexStr)
+	if err != nil {
+		h.writeError(w, internalRPCError(err.Error(), "Failed to decode REST response"))
+		return
+	}
+	h.write(w, contentTypeBinary, body)
+}
+
+// parsePath returns the parameter of the path after the prefix with its
+// format extension.
+func parsePath(r *http.Request, prefix string) (string, restFormat, error) {
+	param := strings.TrimPrefix(r.URL.Path, prefix)
+	param, ext, ok := cutLast(param, ".")
+	if !ok {
+		return "", 0, fmt.Errorf("output format not found (available: bin, hex, json)")
+	}
+	format, ok := restFormats[ext]
+	if !ok {
+		return "", 0, fmt.Errorf("unknown output format %q (available: "+
+			"bin, hex, json)", ext)
+	}
+	return param, format, nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// serveBlock serves the block of the path at the verbosity that's used for the
+// JSON format.
+func (h *restHandler) serveBlock(w http.ResponseWriter, r *http.Request,
+	prefix string, jsonVerbosity int) {
+
+	hash, format, err := parsePath(r, prefix)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	verbosity := 0
+	if format == restFormatJSON {
+		verbosity = jsonVerbosity
+	}
+	result, err := handleGetBlock(h.server, &btcjson.GetBlockCmd{
+		Hash:      hash,
+		Verbosity: &verbosity,
+	}, nil)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if format == restFormatJSON {
+		h.writeJSON(w, result)
+		return
+	}
+
+	// The serialization of a block never changes, so it can be cached
+	// forever.  The JSON isn't since it has the number of confirmations.
+	w.Header().Set("Cache-Control", restImmutableCache)
+	h.writeHex(w, format, result.(string))
+}
+
+// handleBlock serves a block with the details of its transactions.
+func (h *restHandler) handleBlock(w http.ResponseWriter, r *http.Request) {
+	h.serveBlock(w, r, "/rest/block/", 2)
+}
+
+// handleBlockNoTxDetails serves a block with only the txids of its
+// transactions.
+func (h *restHandler) handleBlockNoTxDetails(w http.ResponseWriter, r *http.Request) {
+	h.serveBlock(w, r, "/rest/block/notxdetails/", 1)
+}
+
+// handleHeaders serves the headers of the main chain from the block of the
+// path onwards.  The number of headers is given with the count query
+// parameter, or before the hash in the deprecated /rest/headers/<count>/<hash>
+// form.
+func (h *restHandler) handleHeaders(w http.ResponseWriter, r *http.Request) {
+	param, format, err := parsePath(r, "/rest/headers/")
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	countStr := r.URL.Query().Get("count")
+	if countParam, hashParam, ok := strings.Cut(param, "/"); ok {
+		countStr, param = countParam, hashParam
+	}
+	count := restDefaultHeaders
+	if countStr != "" {
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 1 || count > restMaxHeaders {
+			h.writeError(w, fmt.Errorf("header count is invalid or out "+
+				"of acceptable range (1-%d): %s", restMaxHeaders, countStr))
+			return
+		}
+	}
+
+	hash, err := chainhash.NewHashFromStr(param)
+	if err != nil {
+		h.writeError(w, rpcDecodeHexError(param))
+		return
+	}
+
+	// Headers that aren't in the main chain are returned on their own.
+	chain := h.server.cfg.Chain
+	hashes := []*chainhash.Hash{hash}
+	if height, err := chain.BlockHeightByHash(hash); err == nil {
+		best := chain.BestSnapshot()
+		for i := height + 1; i <= best.Height && len(hashes) < count; i++ {
+			hash, err := chain.BlockHashByHeight(i)
+			if err != nil {
+				break
+			}
+			hashes = append(hashes, hash)
+		}
+	}
+
+	verbose := format == restFormatJSON
+	results := make([]interface{}, 0, len(hashes))
+	var hexStr strings.Builder
+	for _, hash := range hashes {
+		result, err := handleGetBlockHeader(h.server, &btcjson.GetBlockHeaderCmd{
+			Hash:    hash.String(),
+			Verbose: &verbose,
+		}, nil)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		if verbose {
+			results = append(results, result)
+		} else {
+			hexStr.WriteString(result.(string))
+		}
+	}
+
+	if verbose {
+		h.writeJSON(w, results)
+		return
+	}
+	h.writeHex(w, format, hexStr.String())
+}
+
+// restBlockHashResult is the JSON response of the blockhashbyheight endpoint.
+type restBlockHashResult struct {
+	BlockHash string `json:"blockhash"`
+}
+
+// handleBlockHashByHeight serves the hash of the main chain block at the height
+// of the path.
+func (h *restHandler) handleBlockHashByHeight(w http.ResponseWriter, r *http.Request) {
+	param, format, err := parsePath(r, "/rest/blockhashbyheight/")
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	height, err := strconv.ParseInt(param, 10, 32)
+	if err != nil || height < 0 {
+		h.writeError(w, fmt.Errorf("invalid height: %s", param))
+		return
+	}
+	hash, err := h.server.cfg.Chain.BlockHashByHeight(int32(height))
+	if err != nil {
+		h.writeError(w, fmt.Errorf("block height out of range: %w", errRESTNotFound))
+		return
+	}
+
+	switch format {
+	case restFormatJSON:
+		h.writeJSON(w, &restBlockHashResult{BlockHash: hash.String()})
+	case restFormatHex:
+		h.writeHex(w, format, hex.EncodeToString(hash[:]))
+	default:
+		h.write(w, contentTypeBinary, hash[:])
+	}
+}
+
+// handleTx serves the transaction of the path from the mempool or, with the
+// transaction index, from the chain.
+func (h *restHandler) handleTx(w http.ResponseWriter, r *http.Request) {
+	txid, format, err := parsePath(r, "/rest/tx/")
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	verbose := 0
+	if format == restFormatJSON {
+		verbose = 1
+	}
+	result, err := handleGetRawTransaction(h.server, &btcjson.GetRawTransactionCmd{
+		Txid:    txid,
+		Verbose: &verbose,
+	}, nil)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if format == restFormatJSON {
+		h.writeJSON(w, result)
+		return
+	}
+	h.writeHex(w, format, result.(string))
+}
+
+// handleChainInfo serves the getblockchaininfo result.
+func (h *restHandler) handleChainInfo(w http.ResponseWriter, r *http.Request) {
+	result, err := handleGetBlockChainInfo(h.server, &btcjson.GetBlockChainInfoCmd{}, nil)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, result)
+}
+
+// handleMempoolInfo serves the getmempoolinfo result.
+func (h *restHandler) handleMempoolInfo(w http.ResponseWriter, r *http.Request) {
+	result, err := handleGetMempoolInfo(h.server, &btcjson.GetMempoolInfoCmd{}, nil)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, result)
+}
+
+// handleMempoolContents serves the verbose getrawmempool result.
+func (h *restHandler) handleMempoolContents(w http.ResponseWriter, r *http.Request) {
+	result, err := handleGetRawMempool(h.server, &btcjson.GetRawMempoolCmd{
+		Verbose: btcjson.Bool(true),
+	}, nil)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, result)
+}
+
+// handleUtreexoProof serves the utreexo proof of the block of the path.
+func (h *restHandler) handleUtreexoProof(w http.ResponseWriter, r *http.Request) {
+	hashStr, format, err := parsePath(r, "/rest/utreexoproof/")
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	hash, err := chainhash.NewHashFromStr(hashStr)
+	if err != nil {
+		h.writeError(w, rpcDecodeHexError(hashStr))
+		return
+	}
+	if _, err := h.server.cfg.Chain.BlockHeightByHash(hash); err != nil {
+		h.writeError(w, fmt.Errorf("block %v: %w", hash, errRESTNotFound))
+		return
+	}
+
+	verbosity := 0
+	if format == restFormatJSON {
+		verbosity = 1
+	}
+	result, err := handleGetUtreexoProof(h.server, &btcjson.GetUtreexoProofCmd{
+		BlockHash: hashStr,
+		Verbosity: &verbosity,
+	}, nil)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	if format == restFormatJSON {
+		h.writeJSON(w, result)
+		return
+	}
+
+	// The proof of a block is always against the accumulator before it,
+	// so it never changes.
+	w.Header().Set("Cache-Control", restImmutableCache)
+	h.writeHex(w, format, result.(string))
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/blockchain/indexers"
+	"github.com/utreexo/utreexod/btcjson"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/mempool"
+)
+
+// restTestSyncManager is a sync manager that reports no fork warnings.  The
+// other methods of the rpcserverSyncManager interface aren't implemented.
+type restTestSyncManager struct {
+	rpcserverSyncManager
+}
+
+// ForkWarning returns no warning.
+//
+// This is part of the rpcserverSyncManager interface.
+func (restTestSyncManager) ForkWarning() string {
+	return ""
+}
+
+// TestRESTHandler ensures that the REST endpoints serve the data of the chain
+// in every format, that the immutable responses are marked as cacheable, and
+// that bad requests are rejected with the matching status.
+func TestRESTHandler(t *testing.T) {
+	oldCfg := cfg
+	cfg = &config{RPCMaxClients: 10}
+	oldLog := rpcsLog
+	rpcsLog = btclog.Disabled
+	defer func() {
+		cfg = oldCfg
+		rpcsLog = oldLog
+	}()
+
+	var db database.DB
+	var txIndex *indexers.TxIndex
+	chain, utreexoProofIndex := newUtreexoIndexTestChain(t,
+		func(indexDB database.DB) indexers.Indexer {
+			db = indexDB
+			txIndex = indexers.NewTxIndex(indexDB)
+			return txIndex
+		})
+	params := &chaincfg.MainNetParams
+	txMemPool := mempool.New(&mempool.Config{
+		ChainParams:    params,
+		FetchUtxoView:  chain.FetchUtxoView,
+		BestHeight:     func() int32 { return chain.BestSnapshot().Height },
+		MedianTimePast: func() time.Time { return chain.BestSnapshot().MedianTime },
+	})
+	s := &rpcServer{cfg: rpcserverConfig{
+		ChainParams:       params,
+		Chain:             chain,
+		DB:                db,
+		TimeSource:        blockchain.NewMedianTime(),
+		TxMemPool:         txMemPool,
+		TxIndex:           txIndex,
+		SyncMgr:           restTestSyncManager{},
+		UtreexoProofIndex: utreexoProofIndex,
+	}}
+	h := newRESTHandler(s)
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	get := func(target, contentType string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		w := do(http.MethodGet, target)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: got status %d (%s), want %d", target,
+				w.Code, w.Body, http.StatusOK)
+		}
+		if got := w.Header().Get("Content-Type"); got != contentType {
+			t.Fatalf("GET %s: got content type %q, want %q", target,
+				got, contentType)
+		}
+		return w
+	}
+	getJSON := func(target string, v interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+
+		w := get(target, contentTypeJSON)
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: unable to decode %s: %v", target, w.Body, err)
+		}
+		return w
+	}
+	// checkBinaryAndHex checks that the bin and hex formats of the endpoint
+	// serve the passed serialized data and returns the responses.
+	checkBinaryAndHex := func(path, query string, serialized []byte) (
+		*httptest.ResponseRecorder, *httptest.ResponseRecorder) {
+
+		t.Helper()
+
+		bin := get(path+".bin"+query, contentTypeBinary)
+		if !bytes.Equal(bin.Body.Bytes(), serialized) {
+			t.Fatalf("GET %s.bin%s: got %x, want %x", path, query,
+				bin.Body.Bytes(), serialized)
+		}
+		hexResp := get(path+".hex"+query, contentTypeText)
+		if want := hex.EncodeToString(serialized) + "\n"; hexResp.Body.String() != want {
+			t.Fatalf("GET %s.hex%s: got %q, want %q", path, query,
+				hexResp.Body.String(), want)
+		}
+		return bin, hexResp
+	}
+
+	best := chain.BestSnapshot()
+	blocks := make([]*btcutil.Block, 0, best.Height+1)
+	for height := int32(0); height <= best.Height; height++ {
+		block, err := chain.BlockByHeight(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
+	serializedHeaders := func(from, to int) []byte {
+		var buf bytes.Buffer
+		for _, block := range blocks[from:to] {
+			if err := block.MsgBlock().Header.Serialize(&buf); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return buf.Bytes()
+	}
+
+	// Blocks, with and without the details of their transactions.
+	tipBlock := blocks[best.Height]
+	tipTx := tipBlock.Transactions()[len(tipBlock.Transactions())-1]
+	serializedBlock, err := tipBlock.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{"/rest/block/", "/rest/block/notxdetails/"} {
+		bin, hexResp := checkBinaryAndHex(prefix+best.Hash.String(), "",
+			serializedBlock)
+		for _, w := range []*httptest.ResponseRecorder{bin, hexResp} {
+			if got := w.Header().Get("Cache-Control"); got != restImmutableCache {
+				t.Fatalf("GET %s: got Cache-Control %q, want %q",
+					prefix, got, restImmutableCache)
+			}
+		}
+	}
+	var verboseBlock btcjson.GetBlockVerboseTxResult
+	w := getJSON("/rest/block/"+best.Hash.String()+".json", &verboseBlock)
+	if w.Header().Get("Cache-Control") != "" {
+		t.Fatalf("got a cacheable JSON block")
+	}
+	if verboseBlock.Hash != best.Hash.String() ||
+		len(verboseBlock.RawTx) != len(tipBlock.Transactions()) ||
+		verboseBlock.RawTx[0].Txid != tipBlock.Transactions()[0].Hash().String() {
+
+		t.Fatalf("got block %s with %d transactions, want %v with %d",
+			verboseBlock.Hash, len(verboseBlock.RawTx), best.Hash,
+			len(tipBlock.Transactions()))
+	}
+	var noTxDetails btcjson.GetBlockVerboseResult
+	getJSON("/rest/block/notxdetails/"+best.Hash.String()+".json", &noTxDetails)
+	if len(noTxDetails.Tx) != len(tipBlock.Transactions()) ||
+		noTxDetails.Tx[0] != tipBlock.Transactions()[0].Hash().String() {
+
+		t.Fatalf("got txids %v, want the ones of block %v",
+			noTxDetails.Tx, best.Hash)
+	}
+
+	// Headers of the main chain from a block, with the count as a query
+	// parameter, in the deprecated path and by default.  The count is
+	// capped by the tip.
+	first := blocks[1].Hash().String()
+	checkBinaryAndHex("/rest/headers/"+first, "?count=3", serializedHeaders(1, 4))
+	checkBinaryAndHex("/rest/headers/2/"+first, "", serializedHeaders(1, 3))
+	checkBinaryAndHex("/rest/headers/"+blocks[0].Hash().String(), "",
+		serializedHeaders(0, restDefaultHeaders))
+	checkBinaryAndHex("/rest/headers/"+first, "?count=2000",
+		serializedHeaders(1, len(blocks)))
+	var headers []btcjson.GetBlockHeaderVerboseResult
+	getJSON("/rest/headers/"+first+".json?count=2", &headers)
+	if len(headers) != 2 || headers[0].Hash != first ||
+		headers[1].Hash != blocks[2].Hash().String() {
+
+		t.Fatalf("got headers %+v, want the ones of blocks 1 and 2", headers)
+	}
+
+	// Block hashes by height.  The bin and hex formats are of the hash in
+	// byte order, while the JSON one has the usual reversed hash.
+	tipHash := best.Hash
+	checkBinaryAndHex(fmt.Sprintf("/rest/blockhashbyheight/%d", best.Height),
+		"", tipHash[:])
+	var blockHash restBlockHashResult
+	getJSON(fmt.Sprintf("/rest/blockhashbyheight/%d.json", best.Height), &blockHash)
+	if blockHash.BlockHash != best.Hash.String() {
+		t.Fatalf("got block hash %s, want %v", blockHash.BlockHash, best.Hash)
+	}
+
+	// Transactions of the chain are found with the transaction index, which
+	// is caught up in the background.
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		region, err := txIndex.TxBlockRegion(tipTx.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if region != nil {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("the transaction index didn't catch up")
+		}
+	}
+	var serializedTx bytes.Buffer
+	if err := tipTx.MsgTx().Serialize(&serializedTx); err != nil {
+		t.Fatal(err)
+	}
+	checkBinaryAndHex("/rest/tx/"+tipTx.Hash().String(), "", serializedTx.Bytes())
+	var rawTx btcjson.TxRawResult
+	getJSON("/rest/tx/"+tipTx.Hash().String()+".json", &rawTx)
+	if rawTx.Txid != tipTx.Hash().String() || rawTx.BlockHash != best.Hash.String() {
+		t.Fatalf("got transaction %s in block %s, want %v in block %v",
+			rawTx.Txid, rawTx.BlockHash, tipTx.Hash(), best.Hash)
+	}
+
+	var chainInfo btcjson.GetBlockChainInfoResult
+	getJSON("/rest/chaininfo.json", &chainInfo)
+	if chainInfo.Blocks != best.Height || chainInfo.BestBlockHash != best.Hash.String() {
+		t.Fatalf("got chain info at block %s height %d, want %v height %d",
+			chainInfo.BestBlockHash, chainInfo.Blocks, best.Hash,
+			best.Height)
+	}
+
+	var mempoolInfo btcjson.GetMempoolInfoResult
+	getJSON("/rest/mempool/info.json", &mempoolInfo)
+	if mempoolInfo.Size != 0 {
+		t.Fatalf("got %d transactions in the empty mempool", mempoolInfo.Size)
+	}
+	var mempoolContents map[string]btcjson.GetRawMempoolVerboseResult
+	getJSON("/rest/mempool/contents.json", &mempoolContents)
+	if len(mempoolContents) != 0 {
+		t.Fatalf("got %d transactions in the empty mempool",
+			len(mempoolContents))
+	}
+
+	// The utreexo proofs are the ones of getutreexoproof.
+	proofHex, err := handleGetUtreexoProof(s, &btcjson.GetUtreexoProofCmd{
+		BlockHash: best.Hash.String(),
+		Verbosity: btcjson.Int(0),
+	}, nil)
+	if err != nil {
+		t.Fatalf("handleGetUtreexoProof: unexpected error: %v", err)
+	}
+	serializedProof, err := hex.DecodeString(proofHex.(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, hexResp := checkBinaryAndHex("/rest/utreexoproof/"+best.Hash.String(),
+		"", serializedProof)
+	for _, w := range []*httptest.ResponseRecorder{bin, hexResp} {
+		if got := w.Header().Get("Cache-Control"); got != restImmutableCache {
+			t.Fatalf("got Cache-Control %q for a proof, want %q", got,
+				restImmutableCache)
+		}
+	}
+	var verboseProof btcjson.GetUtreexoProofVerboseResult
+	getJSON("/rest/utreexoproof/"+best.Hash.String()+".json", &verboseProof)
+	if len(verboseProof.TargetHashes) != len(verboseProof.ProofTargets) {
+		t.Fatalf("got %d target hashes for %d targets",
+			len(verboseProof.TargetHashes), len(verboseProof.ProofTargets))
+	}
+
+	// HEAD requests are served like GET requests.
+	if w := do(http.MethodHead, "/rest/chaininfo.json"); w.Code != http.StatusOK {
+		t.Fatalf("HEAD: got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	unknownHash := chainhash.HashH([]byte("unknown")).String()
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{"no format", http.MethodGet, "/rest/block/" + first, http.StatusBadRequest},
+		{"unknown format", http.MethodGet, "/rest/block/" + first + ".xml", http.StatusBadRequest},
+		{"invalid block hash", http.MethodGet, "/rest/block/zz.bin", http.StatusBadRequest},
+		{"unknown block", http.MethodGet, "/rest/block/" + unknownHash + ".bin", http.StatusNotFound},
+		{"unknown block without details", http.MethodGet, "/rest/block/notxdetails/" + unknownHash + ".json", http.StatusNotFound},
+		{"zero headers", http.MethodGet, "/rest/headers/" + first + ".bin?count=0", http.StatusBadRequest},
+		{"too many headers", http.MethodGet, "/rest/headers/" + first + ".bin?count=2001", http.StatusBadRequest},
+		{"invalid header count", http.MethodGet, "/rest/headers/x/" + first + ".bin", http.StatusBadRequest},
+		{"invalid header hash", http.MethodGet, "/rest/headers/zz.bin", http.StatusBadRequest},
+		{"unknown header", http.MethodGet, "/rest/headers/" + unknownHash + ".bin", http.StatusNotFound},
+		{"invalid height", http.MethodGet, "/rest/blockhashbyheight/x.bin", http.StatusBadRequest},
+		{"negative height", http.MethodGet, "/rest/blockhashbyheight/-1.bin", http.StatusBadRequest},
+		{"height past the tip", http.MethodGet, "/rest/blockhashbyheight/100.bin", http.StatusNotFound},
+		{"invalid txid", http.MethodGet, "/rest/tx/zz.bin", http.StatusBadRequest},
+		{"unknown transaction", http.MethodGet, "/rest/tx/" + unknownHash + ".bin", http.StatusNotFound},
+		{"invalid proof hash", http.MethodGet, "/rest/utreexoproof/zz.bin", http.StatusBadRequest},
+		{"unknown proof", http.MethodGet, "/rest/utreexoproof/" + unknownHash + ".bin", http.StatusNotFound},
+		{"unknown endpoint", http.MethodGet, "/rest/unknown.json", http.StatusNotFound},
+		{"post", http.MethodPost, "/rest/chaininfo.json", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		w := do(test.method, test.target)
+		if w.Code != test.status {
+			t.Errorf("%s: got status %d (%s), want %d", test.name,
+				w.Code, strings.TrimSpace(w.Body.String()), test.status)
+		}
+	}
+
+	// A transaction of the chain can't be found without the index.
+	s.cfg.TxIndex = nil
+	if w := do(http.MethodGet, "/rest/tx/"+tipTx.Hash().String()+".bin"); w.Code != http.StatusNotFound {
+		t.Errorf("got status %d for a transaction without the index, "+
+			"want %d", w.Code, http.StatusNotFound)
+	}
+
+	// The requests count towards the max number of RPC clients.
+	cfg.RPCMaxClients = 0
+	if w := do(http.MethodGet, "/rest/chaininfo.json"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d past the max number of clients, want %d",
+			w.Code, http.StatusServiceUnavailable)
+	}
+}
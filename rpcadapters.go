@@ -5,8 +5,11 @@
 package main
 
 import (
+	"net"
 	"sync/atomic"
+	"time"
 
+	"github.com/utreexo/utreexod/addrmgr"
 	"github.com/utreexo/utreexod/blockchain"
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
@@ -43,6 +46,14 @@ func (p *rpcPeer) IsTxRelayDisabled() bool {
 	return (*serverPeer)(p).disableRelayTx
 }
 
+// IsBlockRelayOnly returns whether or not the peer is only used to relay blocks.
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) IsBlockRelayOnly() bool {
+	return (*serverPeer)(p).blockRelayOnly
+}
+
 // BanScore returns the current integer value that represents how close the peer
 // is to being banned.
 //
@@ -61,6 +72,15 @@ func (p *rpcPeer) FeeFilter() int64 {
 	return atomic.LoadInt64(&(*serverPeer)(p).feeFilter)
 }
 
+// Permissions returns the names of the permissions the peer was given by the
+// whitelisted networks and the whitebind listeners.
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) Permissions() []string {
+	return (*serverPeer)(p).permissions.names()
+}
+
 // rpcConnManager provides a connection manager for use with the RPC server and
 // implements the rpcserverConnManager interface.
 type rpcConnManager struct {
@@ -147,6 +167,64 @@ func (cm *rpcConnManager) DisconnectByAddr(addr string) error {
 	return <-replyChan
 }
 
+// Ban bans the IP address, subnet, or Tor or I2P host until the passed time
+// and disconnects the connected peers it bans.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) Ban(subnet string, until time.Time) error {
+	err := cm.server.banManager.ban(subnet, until, banReasonManual)
+	if err != nil {
+		return err
+	}
+
+	canonical, ipNet, err := parseBanSubnet(subnet)
+	if err != nil {
+		return err
+	}
+	entry := &banEntry{Subnet: canonical, ipNet: ipNet}
+	for {
+		replyChan := make(chan error)
+		cm.server.query <- disconnectNodeMsg{
+			cmp: func(sp *serverPeer) bool {
+				host, _, err := net.SplitHostPort(sp.Addr())
+				if err != nil {
+					host = sp.Addr()
+				}
+				return entry.matches(host)
+			},
+			reply: replyChan,
+		}
+		if <-replyChan != nil {
+			return nil
+		}
+	}
+}
+
+// Unban removes the ban of the IP address, subnet, or Tor or I2P host.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) Unban(subnet string) error {
+	return cm.server.banManager.unban(subnet)
+}
+
+// ListBanned returns the bans that haven't expired yet.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) ListBanned() []banEntry {
+	return cm.server.banManager.list()
+}
+
+// ClearBanned removes all the bans.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) ClearBanned() error {
+	return cm.server.banManager.clear()
+}
+
 // ConnectedCount returns the number of currently connected peers.
 //
 // This function is safe for concurrent access and is part of the
@@ -227,10 +305,22 @@ func (cm *rpcConnManager) AddRebroadcastInventory(iv *wire.InvVect, data interfa
 	cm.server.AddRebroadcastInventory(iv, data)
 }
 
+// AbandonTransaction stops rebroadcasting the locally submitted transaction
+// with the provided hash and removes it from the mempool along with the
+// transactions that spend it.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) AbandonTransaction(hash *chainhash.Hash) error {
+	return cm.server.AbandonTransaction(hash)
+}
+
 // RelayTransactions generates and relays inventory vectors for all of the
-// passed transactions to all connected peers.
+// passed transactions to all connected peers.  The transactions are submitted
+// locally, so they're held back from the peers that aren't on Tor or I2P with
+// --privatebroadcast or --broadcastdelay.
 func (cm *rpcConnManager) RelayTransactions(txns []*mempool.TxDesc) {
-	cm.server.relayTransactions(txns)
+	cm.server.relayLocalTransactions(txns)
 }
 
 // NodeAddresses returns an array consisting node addresses which can
@@ -242,6 +332,40 @@ func (cm *rpcConnManager) NodeAddresses() []*wire.NetAddress {
 	return cm.server.addrManager.AddressCache()
 }
 
+// LocalAddresses returns the local addresses that are advertised to peers.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) LocalAddresses() []addrmgr.LocalAddress {
+	return cm.server.addrManager.LocalAddresses()
+}
+
+// Services returns the services the node advertises to peers.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) Services() wire.ServiceFlag {
+	return cm.server.services
+}
+
+// PortMapping returns the state of the mapping of the listening port outside
+// of the NAT, or nil when no UPnP, NAT-PMP or PCP router was found.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) PortMapping() *portMappingStatus {
+	return cm.server.PortMapping()
+}
+
+// UploadTarget returns the budget of the bytes sent to peers, or nil when
+// --maxuploadtarget isn't set.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *rpcConnManager) UploadTarget() *uploadTarget {
+	return cm.server.uploadTarget
+}
+
 // rpcSyncMgr provides a block manager for use with the RPC server and
 // implements the rpcserverSyncManager interface.
 type rpcSyncMgr struct {
@@ -296,3 +420,21 @@ func (b *rpcSyncMgr) SyncPeerID() int32 {
 func (b *rpcSyncMgr) LocateHeaders(locators []*chainhash.Hash, hashStop *chainhash.Hash) []wire.BlockHeader {
 	return b.server.chain.LocateHeaders(locators, hashStop)
 }
+
+// ProofOrphanStats returns the counters of the transactions that were relayed
+// with utreexo proofs that couldn't prove their inputs.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverSyncManager interface implementation.
+func (b *rpcSyncMgr) ProofOrphanStats() netsync.ProofOrphanStats {
+	return b.syncMgr.ProofOrphanStats()
+}
+
+// ForkWarning returns the warning about the peers presenting conflicting
+// chains, or an empty string when there is none.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverSyncManager interface implementation.
+func (b *rpcSyncMgr) ForkWarning() string {
+	return b.syncMgr.ForkWarning()
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// cookieUsername is the username of the RPC user whose password is written to
+// the cookie file at startup.
+const cookieUsername = "__cookie__"
+
+// rpcPermission is the set of RPC commands an RPC user is authorized to call.
+type rpcPermission uint8
+
+const (
+	// rpcPermReadOnly authorizes the commands of rpcLimited, which don't
+	// change the configuration of the server or touch its wallets.
+	rpcPermReadOnly rpcPermission = iota
+
+	// rpcPermWallet authorizes the commands of rpcWallet in addition to the
+	// read-only commands.
+	rpcPermWallet
+
+	// rpcPermAdmin authorizes every command.
+	rpcPermAdmin
+)
+
+// Map of rpcPermission values back to their constant names for pretty
+// printing.
+var rpcPermissionStrings = map[rpcPermission]string{
+	rpcPermReadOnly: "readonly",
+	rpcPermWallet:   "wallet",
+	rpcPermAdmin:    "admin",
+}
+
+// String returns the rpcPermission in human-readable form.
+func (p rpcPermission) String() string {
+	if s, ok := rpcPermissionStrings[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown rpcPermission (%d)", uint8(p))
+}
+
+// authorized returns whether a user with the permission may call the method.
+func (p rpcPermission) authorized(method string) bool {
+	if p == rpcPermAdmin {
+		return true
+	}
+	if _, ok := rpcLimited[method]; ok {
+		return true
+	}
+	if p == rpcPermWallet {
+		_, ok := rpcWallet[method]
+		return ok
+	}
+	return false
+}
+
+// unauthorizedError returns the error message for calling a method the
+// permission doesn't authorize.
+func (p rpcPermission) unauthorizedError() string {
+	return fmt.Sprintf("%s user not authorized for this method", p)
+}
+
+// parseRPCPermission returns the rpcPermission of its name.
+func parseRPCPermission(s string) (rpcPermission, error) {
+	for perm, name := range rpcPermissionStrings {
+		if s == name {
+			return perm, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown RPC permission '%s' -- must be one "+
+		"of readonly, wallet or admin", s)
+}
+
+// rpcUser is a user that may authenticate to the RPC server.
+type rpcUser struct {
+	name    string
+	authsha [sha256.Size]byte
+	perm    rpcPermission
+}
+
+// newRPCUser returns an RPC user with the credentials and permission.
+func newRPCUser(name, pass string, perm rpcPermission) rpcUser {
+	return rpcUser{
+		name:    name,
+		authsha: sha256.Sum256([]byte(basicAuth(name, pass))),
+		perm:    perm,
+	}
+}
+
+// parseRPCAccount parses an RPC account of the form
+// <user>:<password>:<permission>.  The password may contain colons.
+func parseRPCAccount(account string) (rpcUser, error) {
+	userEnd := strings.Index(account, ":")
+	passEnd := strings.LastIndex(account, ":")
+	if userEnd <= 0 || passEnd == userEnd || passEnd == userEnd+1 {
+		return rpcUser{}, fmt.Errorf("RPC account '%s' is not of the "+
+			"form <user>:<password>:<permission>", account)
+	}
+	perm, err := parseRPCPermission(account[passEnd+1:])
+	if err != nil {
+		return rpcUser{}, err
+	}
+	name := account[:userEnd]
+	return newRPCUser(name, account[userEnd+1:passEnd], perm), nil
+}
+
+// basicAuth returns the value of the Authorization header of HTTP basic
+// authentication with the credentials.
+func basicAuth(user, pass string) string {
+	login := user + ":" + pass
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
+}
+
+// authenticate returns the user of the Authorization header value, or false
+// when it doesn't match the credentials of any user.
+//
+// This check is time-constant for a given number of users.
+func (s *rpcServer) authenticate(auth string) (*rpcUser, bool) {
+	authsha := sha256.Sum256([]byte(auth))
+
+	var match *rpcUser
+	for i := range s.users {
+		cmp := subtle.ConstantTimeCompare(authsha[:], s.users[i].authsha[:])
+		if cmp == 1 {
+			match = &s.users[i]
+		}
+	}
+	return match, match != nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+// TestParseRPCAccount ensures RPC accounts are parsed into users that the RPC
+// server authenticates with their permissions.
+func TestParseRPCAccount(t *testing.T) {
+	tests := []struct {
+		account string
+		name    string
+		pass    string
+		perm    rpcPermission
+		valid   bool
+	}{
+		{
+			account: "alice:secret:readonly",
+			name:    "alice",
+			pass:    "secret",
+			perm:    rpcPermReadOnly,
+			valid:   true,
+		},
+		{
+			account: "bob:se:cr:et:wallet",
+			name:    "bob",
+			pass:    "se:cr:et",
+			perm:    rpcPermWallet,
+			valid:   true,
+		},
+		{
+			account: "carol:secret:admin",
+			name:    "carol",
+			pass:    "secret",
+			perm:    rpcPermAdmin,
+			valid:   true,
+		},
+		{account: "dave:secret"},
+		{account: "dave::admin"},
+		{account: ":secret:admin"},
+		{account: "dave:secret:root"},
+	}
+
+	s := &rpcServer{}
+	for _, test := range tests {
+		user, err := parseRPCAccount(test.account)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.account)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.account, err)
+			continue
+		}
+		if user.name != test.name || user.perm != test.perm {
+			t.Errorf("%s: unexpected user %s with permission %v",
+				test.account, user.name, user.perm)
+			continue
+		}
+		s.users = append(s.users, user)
+
+		got, ok := s.authenticate(basicAuth(test.name, test.pass))
+		if !ok || got.name != test.name {
+			t.Errorf("%s: failed to authenticate", test.account)
+		}
+	}
+
+	if _, ok := s.authenticate(basicAuth("alice", "wrong")); ok {
+		t.Fatal("authenticated a user with the wrong password")
+	}
+}
+
+// TestRPCPermissionAuthorized ensures the permissions authorize their sets of
+// commands.
+func TestRPCPermissionAuthorized(t *testing.T) {
+	tests := []struct {
+		method string
+		perm   rpcPermission
+		want   bool
+	}{
+		{"getblockcount", rpcPermReadOnly, true},
+		{"balance", rpcPermReadOnly, false},
+		{"stop", rpcPermReadOnly, false},
+		{"getblockcount", rpcPermWallet, true},
+		{"balance", rpcPermWallet, true},
+		{"getmnemonicwords", rpcPermWallet, false},
+		{"stop", rpcPermWallet, false},
+		{"backupwallet", rpcPermWallet, false},
+		{"restorewallet", rpcPermWallet, false},
+		{"backupwallet", rpcPermAdmin, true},
+		{"getmnemonicwords", rpcPermAdmin, true},
+		{"stop", rpcPermAdmin, true},
+	}
+
+	for _, test := range tests {
+		if got := test.perm.authorized(test.method); got != test.want {
+			t.Errorf("%v user calling %s: expected %v, got %v",
+				test.perm, test.method, test.want, got)
+		}
+	}
+}
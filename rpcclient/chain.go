@@ -762,6 +762,106 @@ func (c *Client) GetMempoolEntry(txHash string) (*btcjson.GetMempoolEntryResult,
 	return c.GetMempoolEntryAsync(txHash).Receive()
 }
 
+// FutureGetMempoolEntriesResult is a future promise to deliver the result of
+// a GetMempoolAncestorsVerboseAsync or GetMempoolDescendantsVerboseAsync RPC
+// invocation (or an applicable error).
+type FutureGetMempoolEntriesResult chan *Response
+
+// Receive waits for the Response promised by the future and returns a map of
+// transaction hashes to an associated data structure with information about the
+// transaction in the memory pool.
+func (r FutureGetMempoolEntriesResult) Receive() (map[string]btcjson.GetMempoolEntryResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the result as a map of strings (tx shas) to their detailed
+	// results.
+	var mempoolEntries map[string]btcjson.GetMempoolEntryResult
+	err = json.Unmarshal(res, &mempoolEntries)
+	if err != nil {
+		return nil, err
+	}
+	return mempoolEntries, nil
+}
+
+// GetMempoolAncestorsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolAncestors for the blocking version and more details.
+func (c *Client) GetMempoolAncestorsAsync(txHash string) FutureGetRawMempoolResult {
+	cmd := btcjson.NewGetMempoolAncestorsCmd(txHash, btcjson.Bool(false))
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolAncestors returns the hashes of all unconfirmed ancestors of the
+// transaction in the memory pool given its hash.
+//
+// See GetMempoolAncestorsVerbose to retrieve data structures with information
+// about the ancestors instead.
+func (c *Client) GetMempoolAncestors(txHash string) ([]*chainhash.Hash, error) {
+	return c.GetMempoolAncestorsAsync(txHash).Receive()
+}
+
+// GetMempoolAncestorsVerboseAsync returns an instance of a type that can be
+// used to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolAncestorsVerbose for the blocking version and more details.
+func (c *Client) GetMempoolAncestorsVerboseAsync(txHash string) FutureGetMempoolEntriesResult {
+	cmd := btcjson.NewGetMempoolAncestorsCmd(txHash, btcjson.Bool(true))
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolAncestorsVerbose returns a map of transaction hashes to an
+// associated data structure with information about the transaction for all
+// unconfirmed ancestors of the transaction in the memory pool given its hash.
+//
+// See GetMempoolAncestors to retrieve only the transaction hashes instead.
+func (c *Client) GetMempoolAncestorsVerbose(txHash string) (map[string]btcjson.GetMempoolEntryResult, error) {
+	return c.GetMempoolAncestorsVerboseAsync(txHash).Receive()
+}
+
+// GetMempoolDescendantsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolDescendants for the blocking version and more details.
+func (c *Client) GetMempoolDescendantsAsync(txHash string) FutureGetRawMempoolResult {
+	cmd := btcjson.NewGetMempoolDescendantsCmd(txHash, btcjson.Bool(false))
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolDescendants returns the hashes of all unconfirmed descendants of
+// the transaction in the memory pool given its hash.
+//
+// See GetMempoolDescendantsVerbose to retrieve data structures with information
+// about the descendants instead.
+func (c *Client) GetMempoolDescendants(txHash string) ([]*chainhash.Hash, error) {
+	return c.GetMempoolDescendantsAsync(txHash).Receive()
+}
+
+// GetMempoolDescendantsVerboseAsync returns an instance of a type that can be
+// used to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMempoolDescendantsVerbose for the blocking version and more details.
+func (c *Client) GetMempoolDescendantsVerboseAsync(txHash string) FutureGetMempoolEntriesResult {
+	cmd := btcjson.NewGetMempoolDescendantsCmd(txHash, btcjson.Bool(true))
+	return c.SendCmd(cmd)
+}
+
+// GetMempoolDescendantsVerbose returns a map of transaction hashes to an
+// associated data structure with information about the transaction for all
+// unconfirmed descendants of the transaction in the memory pool given its hash.
+//
+// See GetMempoolDescendants to retrieve only the transaction hashes instead.
+func (c *Client) GetMempoolDescendantsVerbose(txHash string) (map[string]btcjson.GetMempoolEntryResult, error) {
+	return c.GetMempoolDescendantsVerboseAsync(txHash).Receive()
+}
+
 // FutureGetRawMempoolResult is a future promise to deliver the result of a
 // GetRawMempoolAsync RPC invocation (or an applicable error).
 type FutureGetRawMempoolResult chan *Response
@@ -926,6 +1026,43 @@ func (c *Client) EstimateSmartFee(confTarget int64, mode *btcjson.EstimateSmartF
 	return c.EstimateSmartFeeAsync(confTarget, mode).Receive()
 }
 
+// FutureEstimateRawFeeResult is a future promise to deliver the result of a
+// EstimateRawFeeAsync RPC invocation (or an applicable error).
+type FutureEstimateRawFeeResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// estimates of each horizon.
+func (r FutureEstimateRawFeeResult) Receive() (*btcjson.EstimateRawFeeResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var verified btcjson.EstimateRawFeeResult
+	err = json.Unmarshal(res, &verified)
+	if err != nil {
+		return nil, err
+	}
+	return &verified, nil
+}
+
+// EstimateRawFeeAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See EstimateRawFee for the blocking version and more details.
+func (c *Client) EstimateRawFeeAsync(confTarget int64, threshold *float64) FutureEstimateRawFeeResult {
+	cmd := btcjson.NewEstimateRawFeeCmd(confTarget, threshold)
+	return c.SendCmd(cmd)
+}
+
+// EstimateRawFee requests the server to estimate the fee rate to be confirmed
+// within the target with the success threshold for each of the horizons of its
+// fee estimator.
+func (c *Client) EstimateRawFee(confTarget int64, threshold *float64) (*btcjson.EstimateRawFeeResult, error) {
+	return c.EstimateRawFeeAsync(confTarget, threshold).Receive()
+}
+
 // FutureVerifyChainResult is a future promise to deliver the result of a
 // VerifyChainAsync, VerifyChainLevelAsyncRPC, or VerifyChainBlocksAsync
 // invocation (or an applicable error).
@@ -1102,6 +1239,120 @@ func (c *Client) GetTxOutSetInfo() (*btcjson.GetTxOutSetInfoResult, error) {
 	return c.GetTxOutSetInfoAsync().Receive()
 }
 
+// FutureGetIndexInfoResult is a future promise to deliver the result of a
+// GetIndexInfoAsync RPC invocation (or an applicable error).
+type FutureGetIndexInfoResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// statuses of the indexes keyed by their names.
+func (r FutureGetIndexInfoResult) Receive() (map[string]btcjson.GetIndexInfoResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a map of getindexinfo result objects.
+	var indexInfo map[string]btcjson.GetIndexInfoResult
+	err = json.Unmarshal(res, &indexInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return indexInfo, nil
+}
+
+// GetIndexInfoAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See GetIndexInfo for the blocking version and more details.
+func (c *Client) GetIndexInfoAsync(indexName *string) FutureGetIndexInfoResult {
+	cmd := btcjson.NewGetIndexInfoCmd(indexName)
+	return c.SendCmd(cmd)
+}
+
+// GetIndexInfo returns the statuses of the enabled indexes, or only of the
+// named index when indexName isn't nil.
+func (c *Client) GetIndexInfo(indexName *string) (map[string]btcjson.GetIndexInfoResult, error) {
+	return c.GetIndexInfoAsync(indexName).Receive()
+}
+
+// FutureDumpTxOutSetResult is a future promise to deliver the result of a
+// DumpTxOutSetAsync RPC invocation (or an applicable error).
+type FutureDumpTxOutSetResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// results of DumpTxOutSetAsync RPC invocation.
+func (r FutureDumpTxOutSetResult) Receive() (*btcjson.DumpTxOutSetResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a dumptxoutset result object.
+	var dumpResult *btcjson.DumpTxOutSetResult
+	err = json.Unmarshal(res, &dumpResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return dumpResult, nil
+}
+
+// DumpTxOutSetAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See DumpTxOutSet for the blocking version and more details.
+func (c *Client) DumpTxOutSetAsync(path string) FutureDumpTxOutSetResult {
+	cmd := btcjson.NewDumpTxOutSetCmd(path)
+	return c.SendCmd(cmd)
+}
+
+// DumpTxOutSet writes a snapshot of the unspent transaction output set to the
+// given path on the node.
+func (c *Client) DumpTxOutSet(path string) (*btcjson.DumpTxOutSetResult, error) {
+	return c.DumpTxOutSetAsync(path).Receive()
+}
+
+// FutureLoadTxOutSetResult is a future promise to deliver the result of a
+// LoadTxOutSetAsync RPC invocation (or an applicable error).
+type FutureLoadTxOutSetResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// results of LoadTxOutSetAsync RPC invocation.
+func (r FutureLoadTxOutSetResult) Receive() (*btcjson.LoadTxOutSetResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a loadtxoutset result object.
+	var loadResult *btcjson.LoadTxOutSetResult
+	err = json.Unmarshal(res, &loadResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadResult, nil
+}
+
+// LoadTxOutSetAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See LoadTxOutSet for the blocking version and more details.
+func (c *Client) LoadTxOutSetAsync(path string) FutureLoadTxOutSetResult {
+	cmd := btcjson.NewLoadTxOutSetCmd(path)
+	return c.SendCmd(cmd)
+}
+
+// LoadTxOutSet loads the snapshot of the unspent transaction output set at the
+// given path on the node.
+func (c *Client) LoadTxOutSet(path string) (*btcjson.LoadTxOutSetResult, error) {
+	return c.LoadTxOutSetAsync(path).Receive()
+}
+
 // FutureRescanBlocksResult is a future promise to deliver the result of a
 // RescanBlocksAsync RPC invocation (or an applicable error).
 //
@@ -1298,6 +1549,251 @@ func (c *Client) GetCFilterHeader(blockHash *chainhash.Hash,
 	return c.GetCFilterHeaderAsync(blockHash, filterType).Receive()
 }
 
+// FutureScanBlocksResult is a future promise to deliver the result of a
+// ScanBlocksAsync RPC invocation (or an applicable error).
+type FutureScanBlocksResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// blocks that match the scan objects.
+func (r FutureScanBlocksResult) Receive() (*btcjson.ScanBlocksResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a scanblocks result object.
+	var scanResult *btcjson.ScanBlocksResult
+	err = json.Unmarshal(res, &scanResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanResult, nil
+}
+
+// ScanBlocksAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See ScanBlocks for the blocking version and more details.
+func (c *Client) ScanBlocksAsync(scanObjects []string, startHeight,
+	stopHeight *int32, options *btcjson.ScanBlocksOptions) FutureScanBlocksResult {
+
+	cmd := btcjson.NewScanBlocksCmd(btcjson.ScanBlocksActionStart,
+		&scanObjects, startHeight, stopHeight, nil, options)
+	return c.SendCmd(cmd)
+}
+
+// ScanBlocks returns the hashes of the blocks between the start and stop
+// heights whose compact filters match any of the descriptors.  Passing nil for
+// the heights scans from the genesis block to the best block.
+func (c *Client) ScanBlocks(scanObjects []string, startHeight, stopHeight *int32,
+	options *btcjson.ScanBlocksOptions) (*btcjson.ScanBlocksResult, error) {
+
+	return c.ScanBlocksAsync(scanObjects, startHeight, stopHeight,
+		options).Receive()
+}
+
+// FutureScanBlocksStatusResult is a future promise to deliver the result of a
+// ScanBlocksStatusAsync RPC invocation (or an applicable error).
+type FutureScanBlocksStatusResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// progress of the scan in progress, or nil when there is none.
+func (r FutureScanBlocksStatusResult) Receive() (*btcjson.ScanBlocksStatusResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a scanblocks status result object.
+	var status *btcjson.ScanBlocksStatusResult
+	err = json.Unmarshal(res, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// ScanBlocksStatusAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See ScanBlocksStatus for the blocking version and more details.
+func (c *Client) ScanBlocksStatusAsync() FutureScanBlocksStatusResult {
+	cmd := btcjson.NewScanBlocksCmd(btcjson.ScanBlocksActionStatus, nil,
+		nil, nil, nil, nil)
+	return c.SendCmd(cmd)
+}
+
+// ScanBlocksStatus returns the progress of the scan in progress, or nil when
+// there is none.
+func (c *Client) ScanBlocksStatus() (*btcjson.ScanBlocksStatusResult, error) {
+	return c.ScanBlocksStatusAsync().Receive()
+}
+
+// FutureScanBlocksAbortResult is a future promise to deliver the result of a
+// ScanBlocksAbortAsync RPC invocation (or an applicable error).
+type FutureScanBlocksAbortResult chan *Response
+
+// Receive waits for the Response promised by the future and returns whether
+// the scan in progress was aborted.
+func (r FutureScanBlocksAbortResult) Receive() (bool, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return false, err
+	}
+
+	// Unmarshal result as a boolean.
+	var aborted bool
+	err = json.Unmarshal(res, &aborted)
+	if err != nil {
+		return false, err
+	}
+
+	return aborted, nil
+}
+
+// ScanBlocksAbortAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See ScanBlocksAbort for the blocking version and more details.
+func (c *Client) ScanBlocksAbortAsync() FutureScanBlocksAbortResult {
+	cmd := btcjson.NewScanBlocksCmd(btcjson.ScanBlocksActionAbort, nil,
+		nil, nil, nil, nil)
+	return c.SendCmd(cmd)
+}
+
+// ScanBlocksAbort aborts the scan in progress and returns whether there was
+// one.
+func (c *Client) ScanBlocksAbort() (bool, error) {
+	return c.ScanBlocksAbortAsync().Receive()
+}
+
+// FutureScanTxOutSetResult is a future promise to deliver the result of a
+// ScanTxOutSetAsync RPC invocation (or an applicable error).
+type FutureScanTxOutSetResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// unspent outputs that pay to the scan objects.
+func (r FutureScanTxOutSetResult) Receive() (*btcjson.ScanTxOutSetResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a scantxoutset result object.
+	var scanResult *btcjson.ScanTxOutSetResult
+	err = json.Unmarshal(res, &scanResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanResult, nil
+}
+
+// ScanTxOutSetAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See ScanTxOutSet for the blocking version and more details.
+func (c *Client) ScanTxOutSetAsync(scanObjects []btcjson.ScanObject,
+	includeProof bool) FutureScanTxOutSetResult {
+
+	cmd := btcjson.NewScanTxOutSetCmd(btcjson.ScanTxOutSetActionStart,
+		&scanObjects, &includeProof)
+	return c.SendCmd(cmd)
+}
+
+// ScanTxOutSet returns the unspent outputs of the utxo set that pay to any of
+// the descriptors, along with a utreexo proof of them when includeProof is
+// true.
+func (c *Client) ScanTxOutSet(scanObjects []btcjson.ScanObject,
+	includeProof bool) (*btcjson.ScanTxOutSetResult, error) {
+
+	return c.ScanTxOutSetAsync(scanObjects, includeProof).Receive()
+}
+
+// FutureScanTxOutSetStatusResult is a future promise to deliver the result of
+// a ScanTxOutSetStatusAsync RPC invocation (or an applicable error).
+type FutureScanTxOutSetStatusResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// progress of the scan in progress, or nil when there is none.
+func (r FutureScanTxOutSetStatusResult) Receive() (*btcjson.ScanTxOutSetStatusResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a scantxoutset status result object.
+	var status *btcjson.ScanTxOutSetStatusResult
+	err = json.Unmarshal(res, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// ScanTxOutSetStatusAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See ScanTxOutSetStatus for the blocking version and more details.
+func (c *Client) ScanTxOutSetStatusAsync() FutureScanTxOutSetStatusResult {
+	cmd := btcjson.NewScanTxOutSetCmd(btcjson.ScanTxOutSetActionStatus,
+		nil, nil)
+	return c.SendCmd(cmd)
+}
+
+// ScanTxOutSetStatus returns the progress of the scan in progress, or nil when
+// there is none.
+func (c *Client) ScanTxOutSetStatus() (*btcjson.ScanTxOutSetStatusResult, error) {
+	return c.ScanTxOutSetStatusAsync().Receive()
+}
+
+// FutureScanTxOutSetAbortResult is a future promise to deliver the result of a
+// ScanTxOutSetAbortAsync RPC invocation (or an applicable error).
+type FutureScanTxOutSetAbortResult chan *Response
+
+// Receive waits for the Response promised by the future and returns whether
+// the scan in progress was aborted.
+func (r FutureScanTxOutSetAbortResult) Receive() (bool, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return false, err
+	}
+
+	// Unmarshal result as a boolean.
+	var aborted bool
+	err = json.Unmarshal(res, &aborted)
+	if err != nil {
+		return false, err
+	}
+
+	return aborted, nil
+}
+
+// ScanTxOutSetAbortAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See ScanTxOutSetAbort for the blocking version and more details.
+func (c *Client) ScanTxOutSetAbortAsync() FutureScanTxOutSetAbortResult {
+	cmd := btcjson.NewScanTxOutSetCmd(btcjson.ScanTxOutSetActionAbort,
+		nil, nil)
+	return c.SendCmd(cmd)
+}
+
+// ScanTxOutSetAbort aborts the scan in progress and returns whether there was
+// one.
+func (c *Client) ScanTxOutSetAbort() (bool, error) {
+	return c.ScanTxOutSetAbortAsync().Receive()
+}
+
 // FutureGetBlockStatsResult is a future promise to deliver the result of a
 // GetBlockStatsAsync RPC invocation (or an applicable error).
 type FutureGetBlockStatsResult chan *Response
@@ -1517,20 +2013,37 @@ func (r FutureGetUtreexoRootsResult) Receive() (*btcjson.GetUtreexoRootsResult,
 //
 // See GetUtreexoRoots for the blocking version and more details.
 func (c *Client) GetUtreexoRootsAsync(blockHash *chainhash.Hash) FutureGetUtreexoRootsResult {
-	hash := ""
+	var hashOrHeight *btcjson.HashOrHeight
 	if blockHash != nil {
-		hash = blockHash.String()
+		hashOrHeight = &btcjson.HashOrHeight{Value: blockHash.String()}
 	}
 
-	cmd := btcjson.NewGetUtreexoRootsCmd(hash)
+	cmd := btcjson.NewGetUtreexoRootsCmd(hashOrHeight)
 	return c.SendCmd(cmd)
 }
 
-// GetUtreexoRoots reconsiders a specific block and the branch that the block is included in.
+// GetUtreexoRoots returns the utreexo accumulator state after the given block
+// was connected.  The state at the chain tip is returned if the hash is nil.
 func (c *Client) GetUtreexoRoots(blockHash *chainhash.Hash) (*btcjson.GetUtreexoRootsResult, error) {
 	return c.GetUtreexoRootsAsync(blockHash).Receive()
 }
 
+// GetUtreexoRootsByHeightAsync returns an instance of a type that can be used
+// to get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetUtreexoRootsByHeight for the blocking version and more details.
+func (c *Client) GetUtreexoRootsByHeightAsync(height int32) FutureGetUtreexoRootsResult {
+	cmd := btcjson.NewGetUtreexoRootsCmd(&btcjson.HashOrHeight{Value: int(height)})
+	return c.SendCmd(cmd)
+}
+
+// GetUtreexoRootsByHeight returns the utreexo accumulator state after the block
+// at the given height was connected.
+func (c *Client) GetUtreexoRootsByHeight(height int32) (*btcjson.GetUtreexoRootsResult, error) {
+	return c.GetUtreexoRootsByHeightAsync(height).Receive()
+}
+
 // FutureProveWatchOnlyChainTipInclusion is a future promise to deliver the result of a
 // ProveWatchOnlyChainTipInclusionAsync RPC invocation (or an applicable error).
 type FutureProveWatchOnlyChainTipInclusion chan *Response
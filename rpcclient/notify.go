@@ -33,6 +33,7 @@ var (
 // reconnect.
 type notificationState struct {
 	notifyBlocks       bool
+	notifyUtreexoRoots bool
 	notifyNewTx        bool
 	notifyNewTxVerbose bool
 	notifyReceived     map[string]struct{}
@@ -43,6 +44,7 @@ type notificationState struct {
 func (s *notificationState) Copy() *notificationState {
 	var stateCopy notificationState
 	stateCopy.notifyBlocks = s.notifyBlocks
+	stateCopy.notifyUtreexoRoots = s.notifyUtreexoRoots
 	stateCopy.notifyNewTx = s.notifyNewTx
 	stateCopy.notifyNewTxVerbose = s.notifyNewTxVerbose
 	stateCopy.notifyReceived = make(map[string]struct{})
@@ -121,6 +123,24 @@ type NotificationHandlers struct {
 	// OnBlockDisconnected: it receives the block's height and header.
 	OnFilteredBlockDisconnected func(height int32, header *wire.BlockHeader)
 
+	// OnUtreexoRootsConnected is invoked when a block is connected to the
+	// longest (best) chain with the roots and the number of leaves of the
+	// utreexo accumulator after the block.  It will only be invoked if a
+	// preceding call to NotifyUtreexoRoots has been made to register for
+	// the notification and the function is non-nil.  The roots are in the
+	// byte order of the accumulator.
+	OnUtreexoRootsConnected func(hash *chainhash.Hash, height int32,
+		numLeaves uint64, roots []chainhash.Hash)
+
+	// OnUtreexoRootsDisconnected is invoked when a block is disconnected
+	// from the longest (best) chain with the roots and the number of leaves
+	// of the utreexo accumulator after the block was disconnected.  It
+	// will only be invoked if a preceding call to NotifyUtreexoRoots has
+	// been made to register for the notification and the function is
+	// non-nil.
+	OnUtreexoRootsDisconnected func(hash *chainhash.Hash, height int32,
+		numLeaves uint64, roots []chainhash.Hash)
+
 	// OnRecvTx is invoked when a transaction that receives funds to a
 	// registered address is received into the memory pool and also
 	// connected to the longest (best) chain.  It will only be invoked if a
@@ -290,6 +310,44 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 		c.ntfnHandlers.OnFilteredBlockDisconnected(blockHeight,
 			blockHeader)
 
+	// OnUtreexoRootsConnected
+	case btcjson.UtreexoRootsConnectedNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnUtreexoRootsConnected == nil {
+			return
+		}
+
+		blockHash, blockHeight, numLeaves, roots, err :=
+			parseUtreexoRootsNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid utreexo roots connected "+
+				"notification: %v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnUtreexoRootsConnected(blockHash, blockHeight,
+			numLeaves, roots)
+
+	// OnUtreexoRootsDisconnected
+	case btcjson.UtreexoRootsDisconnectedNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnUtreexoRootsDisconnected == nil {
+			return
+		}
+
+		blockHash, blockHeight, numLeaves, roots, err :=
+			parseUtreexoRootsNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid utreexo roots disconnected "+
+				"notification: %v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnUtreexoRootsDisconnected(blockHash, blockHeight,
+			numLeaves, roots)
+
 	// OnRecvTx
 	case btcjson.RecvTxNtfnMethod:
 		// Ignore the notification if the client is not interested in
@@ -525,6 +583,64 @@ func parseChainNtfnParams(params []json.RawMessage) (*chainhash.Hash,
 	return blockHash, blockHeight, blockTime, nil
 }
 
+// parseUtreexoRootsNtfnParams parses out the block hash, the height, the number
+// of leaves and the roots from the parameters of a utreexorootsconnected or a
+// utreexorootsdisconnected notification.
+func parseUtreexoRootsNtfnParams(params []json.RawMessage) (*chainhash.Hash,
+	int32, uint64, []chainhash.Hash, error) {
+
+	if len(params) != 4 {
+		return nil, 0, 0, nil, wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as a string.
+	var blockHashStr string
+	err := json.Unmarshal(params[0], &blockHashStr)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+	blockHash, err := chainhash.NewHashFromStr(blockHashStr)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+
+	// Unmarshal second parameter as an integer.
+	var blockHeight int32
+	err = json.Unmarshal(params[1], &blockHeight)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+
+	// Unmarshal third parameter as an integer.
+	var numLeaves uint64
+	err = json.Unmarshal(params[2], &numLeaves)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+
+	// Unmarshal fourth parameter as a slice of hex-encoded roots.  The
+	// roots aren't byte reversed like block hashes are.
+	var rootStrs []string
+	err = json.Unmarshal(params[3], &rootStrs)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+	roots := make([]chainhash.Hash, len(rootStrs))
+	for i, rootStr := range rootStrs {
+		root, err := hex.DecodeString(rootStr)
+		if err != nil {
+			return nil, 0, 0, nil, err
+		}
+		if len(root) != chainhash.HashSize {
+			return nil, 0, 0, nil, fmt.Errorf("invalid root length "+
+				"of %d bytes", len(root))
+		}
+		copy(roots[i][:], root)
+	}
+
+	return blockHash, blockHeight, numLeaves, roots, nil
+}
+
 // parseFilteredBlockConnectedParams parses out the parameters included in a
 // filteredblockconnected notification.
 //
@@ -902,6 +1018,55 @@ func (c *Client) NotifyBlocks() error {
 	return c.NotifyBlocksAsync().Receive()
 }
 
+// FutureNotifyUtreexoRootsResult is a future promise to deliver the result of
+// a NotifyUtreexoRootsAsync RPC invocation (or an applicable error).
+type FutureNotifyUtreexoRootsResult chan *Response
+
+// Receive waits for the Response promised by the future and returns an error
+// if the registration was not successful.
+func (r FutureNotifyUtreexoRootsResult) Receive() error {
+	_, err := ReceiveFuture(r)
+	return err
+}
+
+// NotifyUtreexoRootsAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See NotifyUtreexoRoots for the blocking version and more details.
+//
+// NOTE: This is a utreexod extension and requires a websocket connection.
+func (c *Client) NotifyUtreexoRootsAsync() FutureNotifyUtreexoRootsResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return newFutureError(ErrWebsocketsRequired)
+	}
+
+	// Ignore the notification if the client is not interested in
+	// notifications.
+	if c.ntfnHandlers == nil {
+		return newNilFutureResult()
+	}
+
+	cmd := btcjson.NewNotifyUtreexoRootsCmd()
+	return c.SendCmd(cmd)
+}
+
+// NotifyUtreexoRoots registers the client to receive notifications with the
+// utreexo roots when blocks are connected and disconnected from the main
+// chain.  The notifications are delivered to the notification handlers
+// associated with the client.  Calling this function has no effect if there
+// are no notification handlers and will result in an error if the client is
+// configured to run in HTTP POST mode.
+//
+// The notifications delivered as a result of this call will be via one of
+// OnUtreexoRootsConnected or OnUtreexoRootsDisconnected.
+//
+// NOTE: This is a utreexod extension and requires a websocket connection.
+func (c *Client) NotifyUtreexoRoots() error {
+	return c.NotifyUtreexoRootsAsync().Receive()
+}
+
 // FutureNotifySpentResult is a future promise to deliver the result of a
 // NotifySpentAsync RPC invocation (or an applicable error).
 //
@@ -383,6 +383,104 @@ func (c *Client) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainh
 	return c.SendRawTransactionAsync(tx, allowHighFees).Receive()
 }
 
+// FutureSubmitPackageResult is a future promise to deliver the result of a
+// SubmitPackageAsync RPC invocation (or an applicable error).
+type FutureSubmitPackageResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// results of submitting the package of transactions.
+func (r FutureSubmitPackageResult) Receive() (*btcjson.SubmitPackageResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a submitpackage result object.
+	var submitPackageResult btcjson.SubmitPackageResult
+	err = json.Unmarshal(res, &submitPackageResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return &submitPackageResult, nil
+}
+
+// SubmitPackageAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on the
+// returned instance.
+//
+// See SubmitPackage for the blocking version and more details.
+func (c *Client) SubmitPackageAsync(txs []*wire.MsgTx) FutureSubmitPackageResult {
+	rawTxs := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+		rawTxs = append(rawTxs, hex.EncodeToString(buf.Bytes()))
+	}
+
+	cmd := btcjson.NewSubmitPackageCmd(rawTxs)
+	return c.SendCmd(cmd)
+}
+
+// SubmitPackage submits a package of transactions, which is a child with all
+// of its unconfirmed parents where the child comes last, to the server which
+// will then relay them to the network.
+func (c *Client) SubmitPackage(txs []*wire.MsgTx) (*btcjson.SubmitPackageResult, error) {
+	return c.SubmitPackageAsync(txs).Receive()
+}
+
+// FutureTestMempoolAcceptResult is a future promise to deliver the result of a
+// TestMempoolAcceptAsync RPC invocation (or an applicable error).
+type FutureTestMempoolAcceptResult chan *Response
+
+// Receive waits for the Response promised by the future and returns whether
+// each of the transactions would be accepted to the memory pool.
+func (r FutureTestMempoolAcceptResult) Receive() ([]btcjson.TestMempoolAcceptResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as an array of testmempoolaccept result objects.
+	var results []btcjson.TestMempoolAcceptResult
+	err = json.Unmarshal(res, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// TestMempoolAcceptAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See TestMempoolAccept for the blocking version and more details.
+func (c *Client) TestMempoolAcceptAsync(txs []*wire.MsgTx, maxFeeRate float64) FutureTestMempoolAcceptResult {
+	rawTxs := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+		rawTxs = append(rawTxs, hex.EncodeToString(buf.Bytes()))
+	}
+
+	cmd := btcjson.NewTestMempoolAcceptCmd(rawTxs, &maxFeeRate)
+	return c.SendCmd(cmd)
+}
+
+// TestMempoolAccept returns whether each of the transactions would be accepted
+// to the memory pool of the server without submitting them.  Transactions with
+// a fee rate in BTC/kvB above maxFeeRate are rejected unless it is 0.
+func (c *Client) TestMempoolAccept(txs []*wire.MsgTx, maxFeeRate float64) ([]btcjson.TestMempoolAcceptResult, error) {
+	return c.TestMempoolAcceptAsync(txs, maxFeeRate).Receive()
+}
+
 // FutureSignRawTransactionResult is a future promise to deliver the result
 // of one of the SignRawTransactionAsync family of RPC invocations (or an
 // applicable error).
@@ -774,7 +872,7 @@ func (c *Client) SearchRawTransactionsAsync(address btcutil.Address, skip, count
 	addr := address.EncodeAddress()
 	verbose := btcjson.Int(0)
 	cmd := btcjson.NewSearchRawTransactionsCmd(addr, verbose, &skip, &count,
-		nil, &reverse, &filterAddrs)
+		nil, &reverse, &filterAddrs, nil, nil)
 	return c.SendCmd(cmd)
 }
 
@@ -827,7 +925,7 @@ func (c *Client) SearchRawTransactionsVerboseAsync(address btcutil.Address, skip
 		prevOut = btcjson.Int(1)
 	}
 	cmd := btcjson.NewSearchRawTransactionsCmd(addr, verbose, &skip, &count,
-		prevOut, &reverse, filterAddrs)
+		prevOut, &reverse, filterAddrs, nil, nil)
 	return c.SendCmd(cmd)
 }
 
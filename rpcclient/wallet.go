@@ -2709,7 +2709,7 @@ func (r FutureBackupWalletResult) Receive() error {
 //
 // See BackupWallet for the blocking version and more details.
 func (c *Client) BackupWalletAsync(destination string) FutureBackupWalletResult {
-	return c.SendCmd(btcjson.NewBackupWalletCmd(destination))
+	return c.SendCmd(btcjson.NewBackupWalletCmd(destination, nil))
 }
 
 // BackupWallet safely copies current wallet file to destination, which can
@@ -0,0 +1,277 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/utreexo/utreexod/btcjson"
+)
+
+// rateLimitSweepInterval is how often the rate limiter forgets the clients
+// that have stopped calling.
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket allows calls at a steady rate with bursts of up to a second of
+// calls.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time passed since the last call at the rate
+// and takes a token when there's one.
+func (b *tokenBucket) take(rate float64, now time.Time) bool {
+	burst := burstSize(rate)
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// burstSize returns the number of calls that may be made at once at the rate.
+func burstSize(rate float64) float64 {
+	if rate < 1 {
+		return 1
+	}
+	return rate
+}
+
+// rpcRateLimiter limits the calls per second each RPC client may make in total
+// and to each method.
+type rpcRateLimiter struct {
+	mtx sync.Mutex
+
+	// rate is the limit of calls per second of a client to any method.  A
+	// rate of zero doesn't limit the calls.
+	rate float64
+
+	// methodRates are the limits of calls per second of a client to each of
+	// the methods.
+	methodRates map[string]float64
+
+	// clients and methods are the buckets of the clients and of the
+	// methods of each client.
+	clients   map[string]*tokenBucket
+	methods   map[string]map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// newRPCRateLimiter returns a rate limiter for the limits, or nil when none of
+// them limit the calls.
+func newRPCRateLimiter(rate float64, methodRates map[string]float64) *rpcRateLimiter {
+	if rate == 0 && len(methodRates) == 0 {
+		return nil
+	}
+	return &rpcRateLimiter{
+		rate:        rate,
+		methodRates: methodRates,
+		clients:     make(map[string]*tokenBucket),
+		methods:     make(map[string]map[string]*tokenBucket),
+		lastSweep:   time.Now(),
+	}
+}
+
+// allow returns whether the client may call the method now and counts the call
+// when it may.
+func (l *rpcRateLimiter) allow(client, method string) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	// The method limit is checked first so that the calls it rejects
+	// don't use up the tokens of the client.
+	if methodRate, ok := l.methodRates[method]; ok {
+		buckets := l.methods[client]
+		if buckets == nil {
+			buckets = make(map[string]*tokenBucket)
+			l.methods[client] = buckets
+		}
+		bucket := buckets[method]
+		if bucket == nil {
+			bucket = &tokenBucket{tokens: burstSize(methodRate), last: now}
+			buckets[method] = bucket
+		}
+		if !bucket.take(methodRate, now) {
+			return false
+		}
+	}
+
+	if l.rate == 0 {
+		return true
+	}
+	bucket := l.clients[client]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: burstSize(l.rate), last: now}
+		l.clients[client] = bucket
+	}
+	return bucket.take(l.rate, now)
+}
+
+// sweep forgets the buckets that have refilled completely since they'd be
+// recreated in the same state.
+//
+// This function MUST be called with the rate limiter lock held.
+func (l *rpcRateLimiter) sweep(now time.Time) {
+	full := func(b *tokenBucket, rate float64) bool {
+		return b.tokens+now.Sub(b.last).Seconds()*rate >= burstSize(rate)
+	}
+	for client, bucket := range l.clients {
+		if full(bucket, l.rate) {
+			delete(l.clients, client)
+		}
+	}
+	for client, buckets := range l.methods {
+		for method, bucket := range buckets {
+			if full(bucket, l.methodRates[method]) {
+				delete(buckets, method)
+			}
+		}
+		if len(buckets) == 0 {
+			delete(l.methods, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// parseRPCMethodRateLimit parses a method rate limit of the form
+// <method>:<calls per second>.
+func parseRPCMethodRateLimit(limit string) (string, float64, error) {
+	sep := strings.LastIndex(limit, ":")
+	if sep <= 0 {
+		return "", 0, fmt.Errorf("RPC method rate limit '%s' is not of "+
+			"the form <method>:<calls per second>", limit)
+	}
+	method := limit[:sep]
+	_, ok := rpcHandlers[method]
+	if _, wsOk := wsHandlers[method]; !ok && !wsOk {
+		return "", 0, fmt.Errorf("RPC method rate limit '%s' is for "+
+			"the unknown method '%s'", limit, method)
+	}
+	rate, err := strconv.ParseFloat(limit[sep+1:], 64)
+	if err != nil || rate <= 0 {
+		return "", 0, fmt.Errorf("RPC method rate limit '%s' must "+
+			"allow a positive number of calls per second", limit)
+	}
+	return method, rate, nil
+}
+
+// rpcClientHost returns the host of the remote address of an RPC client so that
+// the clients that open a new connection for every request are limited as one.
+func rpcClientHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// rpcAuditEntry is a line of the audit log written for every RPC call.
+type rpcAuditEntry struct {
+	Time     string  `json:"time"`
+	Client   string  `json:"client"`
+	Method   string  `json:"method"`
+	Duration float64 `json:"duration_ms"`
+	Code     int     `json:"code"`
+}
+
+// rpcAuditLog appends a JSON line for every RPC call to a file.
+type rpcAuditLog struct {
+	mtx  sync.Mutex
+	file *os.File
+}
+
+// openRPCAuditLog opens the audit log file at the path for appending.
+func openRPCAuditLog(path string) (*rpcAuditLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND,
+		0600)
+	if err != nil {
+		return nil, err
+	}
+	return &rpcAuditLog{file: file}, nil
+}
+
+// record appends the entry of a call that started at the time to the log.  The
+// result code is zero for calls that succeeded and the RPC error code of the
+// others.
+func (a *rpcAuditLog) record(client, method string, start time.Time, err error) {
+	entry := rpcAuditEntry{
+		Time:     start.UTC().Format(time.RFC3339Nano),
+		Client:   client,
+		Method:   method,
+		Duration: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		entry.Code = int(btcjson.ErrRPCInternal.Code)
+		if rpcErr, ok := err.(*btcjson.RPCError); ok {
+			entry.Code = int(rpcErr.Code)
+		}
+	}
+
+	line, err := json.Marshal(&entry)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal RPC audit log entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if _, err := a.file.Write(line); err != nil {
+		rpcsLog.Errorf("Failed to write RPC audit log entry: %v", err)
+	}
+}
+
+// Close closes the audit log file.
+func (a *rpcAuditLog) Close() error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.file.Close()
+}
+
+// callCmd checks the rate limits of the client at the remote address, runs the
+// handler of the parsed command and records the call to the audit log.
+func (s *rpcServer) callCmd(remoteAddr string, cmd *parsedRPCCmd,
+	handler func() (interface{}, error)) (interface{}, error) {
+
+	start := time.Now()
+	var result interface{}
+	var err error
+	if s.rateLimiter != nil &&
+		!s.rateLimiter.allow(rpcClientHost(remoteAddr), cmd.method) {
+
+		rpcsLog.Debugf("Rate limited command <%s> from %s", cmd.method,
+			remoteAddr)
+		err = &btcjson.RPCError{
+			Code:    btcjson.ErrRPCRateLimited,
+			Message: "Rate limit exceeded",
+		}
+	} else {
+		result, err = handler()
+	}
+
+	if s.auditLog != nil {
+		s.auditLog.record(remoteAddr, cmd.method, start, err)
+	}
+	return result, err
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRPCRateLimiter ensures the rate limiter allows bursts of up to a second
+// of calls for each client and method and refills at the rate.
+func TestRPCRateLimiter(t *testing.T) {
+	if l := newRPCRateLimiter(0, nil); l != nil {
+		t.Fatal("expected no rate limiter without limits")
+	}
+
+	l := newRPCRateLimiter(3, map[string]float64{"getblocktemplate": 1})
+	tests := []struct {
+		client string
+		method string
+		want   bool
+	}{
+		// The method limit only allows a single call at once.
+		{"10.0.0.1", "getblocktemplate", true},
+		{"10.0.0.1", "getblocktemplate", false},
+
+		// The rejected call didn't use up the tokens of the client.
+		{"10.0.0.1", "getblockcount", true},
+		{"10.0.0.1", "getblockcount", true},
+		{"10.0.0.1", "getblockcount", false},
+
+		// Clients are limited separately.
+		{"10.0.0.2", "getblocktemplate", true},
+		{"10.0.0.2", "getblockcount", true},
+	}
+	for i, test := range tests {
+		if got := l.allow(test.client, test.method); got != test.want {
+			t.Fatalf("#%d: %s calling %s: expected %v, got %v", i,
+				test.client, test.method, test.want, got)
+		}
+	}
+
+	// Buckets refill at the rate and never beyond a second of calls.
+	var b tokenBucket
+	now := time.Now()
+	b.last = now
+	if b.take(2, now) {
+		t.Fatal("took a token from an empty bucket")
+	}
+	if !b.take(2, now.Add(500*time.Millisecond)) {
+		t.Fatal("bucket didn't refill at the rate")
+	}
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if !b.take(2, now) {
+			t.Fatalf("bucket didn't allow a burst of %d calls", i+1)
+		}
+	}
+	if b.take(2, now) {
+		t.Fatal("bucket allowed a burst of more than a second of calls")
+	}
+
+	// Full buckets are forgotten.
+	l.sweep(time.Now().Add(time.Hour))
+	if len(l.clients) != 0 || len(l.methods) != 0 {
+		t.Fatalf("expected the idle clients to be forgotten, got %d "+
+			"clients and %d method clients", len(l.clients),
+			len(l.methods))
+	}
+}
+
+// TestParseRPCMethodRateLimit ensures method rate limits are parsed.
+func TestParseRPCMethodRateLimit(t *testing.T) {
+	tests := []struct {
+		limit  string
+		method string
+		rate   float64
+		valid  bool
+	}{
+		{"getblocktemplate:1", "getblocktemplate", 1, true},
+		{"scantxoutset:0.1", "scantxoutset", 0.1, true},
+		{"notifyblocks:5", "notifyblocks", 5, true},
+		{limit: "getblocktemplate"},
+		{limit: ":1"},
+		{limit: "getblocktemplate:0"},
+		{limit: "getblocktemplate:-1"},
+		{limit: "getblocktemplate:fast"},
+		{limit: "nosuchmethod:1"},
+	}
+
+	for _, test := range tests {
+		method, rate, err := parseRPCMethodRateLimit(test.limit)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.limit)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.limit, err)
+			continue
+		}
+		if method != test.method || rate != test.rate {
+			t.Errorf("%s: expected %s at %v, got %s at %v",
+				test.limit, test.method, test.rate, method, rate)
+		}
+	}
+}
@@ -7,8 +7,6 @@ package main
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -16,12 +14,14 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"math/big"
 	"math/rand"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,19 +30,24 @@ import (
 
 	cryptorand "crypto/rand"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
 	"github.com/btcsuite/websocket"
+	"github.com/utreexo/utreexod/addrmgr"
 	"github.com/utreexo/utreexod/bdkwallet"
 	"github.com/utreexo/utreexod/blockchain"
 	"github.com/utreexo/utreexod/blockchain/indexers"
 	"github.com/utreexo/utreexod/btcjson"
 	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/btcutil/gcs"
+	"github.com/utreexo/utreexod/btcutil/gcs/builder"
 	"github.com/utreexo/utreexod/chaincfg"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
 	"github.com/utreexo/utreexod/database"
 	"github.com/utreexo/utreexod/mempool"
 	"github.com/utreexo/utreexod/mining"
 	"github.com/utreexo/utreexod/mining/cpuminer"
+	"github.com/utreexo/utreexod/netsync"
 	"github.com/utreexo/utreexod/peer"
 	"github.com/utreexo/utreexod/txscript"
 	"github.com/utreexo/utreexod/wallet"
@@ -107,6 +112,11 @@ var (
 	// invocation for constant data.
 	gbtCapabilities = []string{"proposal"}
 
+	// gbtUtreexoCapabilities describes the capabilities returned with a
+	// block template by nodes that are able to prove the inputs of its
+	// transactions.
+	gbtUtreexoCapabilities = []string{"proposal", "utreexoproof"}
+
 	// JSON 2.0 batched request prefix
 	batchedRequestPrefix = []byte("[")
 )
@@ -135,15 +145,21 @@ type commandHandler func(*rpcServer, interface{}, <-chan struct{}) (interface{},
 // a dependency loop.
 var rpcHandlers map[string]commandHandler
 var rpcHandlersBeforeInit = map[string]commandHandler{
+	"abandontransaction":                 handleAbandonTransaction,
 	"addnode":                            handleAddNode,
-	"balance":                            handleBalance,
-	"createtransactionfrombdkwallet":     handleCreateTransactionFromBDKWallet,
+	"backupdatadir":                      handleBackupDataDir,
+	"clearbanned":                        handleClearBanned,
+	"compactdb":                          handleCompactDB,
 	"createrawtransaction":               handleCreateRawTransaction,
+	"createwallet":                       handleCreateWallet,
 	"debuglevel":                         handleDebugLevel,
 	"decoderawtransaction":               handleDecodeRawTransaction,
 	"decodescript":                       handleDecodeScript,
+	"deriveaddresses":                    handleDeriveAddresses,
+	"dumptxoutset":                       handleDumpTxOutSet,
 	"estimatefee":                        handleEstimateFee,
-	"freshaddress":                       handleFreshAddress,
+	"estimaterawfee":                     handleEstimateRawFee,
+	"estimatesmartfee":                   handleEstimateSmartFee,
 	"generate":                           handleGenerate,
 	"getaddednodeinfo":                   handleGetAddedNodeInfo,
 	"getbestblock":                       handleGetBestBlock,
@@ -154,51 +170,72 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"getblockcount":                      handleGetBlockCount,
 	"getblockhash":                       handleGetBlockHash,
 	"getblockheader":                     handleGetBlockHeader,
+	"getblocksbytime":                    handleGetBlocksByTime,
+	"getblockttls":                       handleGetBlockTTLs,
 	"getblocktemplate":                   handleGetBlockTemplate,
 	"getchaintips":                       handleGetChainTips,
+	"getchaintxstats":                    handleGetChainTxStats,
 	"getcfilter":                         handleGetCFilter,
 	"getcfilterheader":                   handleGetCFilterHeader,
 	"getconnectioncount":                 handleGetConnectionCount,
 	"getcurrentnet":                      handleGetCurrentNet,
+	"getdbcompactioninfo":                handleGetDBCompactionInfo,
+	"getdescriptorinfo":                  handleGetDescriptorInfo,
 	"getdifficulty":                      handleGetDifficulty,
 	"getgenerate":                        handleGetGenerate,
 	"gethashespersec":                    handleGetHashesPerSec,
 	"getheaders":                         handleGetHeaders,
+	"getindexinfo":                       handleGetIndexInfo,
 	"getinfo":                            handleGetInfo,
+	"getmempoolancestors":                handleGetMempoolAncestors,
+	"getmempooldescendants":              handleGetMempoolDescendants,
+	"getmempoolentry":                    handleGetMempoolEntry,
 	"getmempoolinfo":                     handleGetMempoolInfo,
 	"getmininginfo":                      handleGetMiningInfo,
-	"getmnemonicwords":                   handleGetMnemonicWords,
 	"getnettotals":                       handleGetNetTotals,
 	"gettxtotals":                        handleGetTxTotals,
 	"getnetworkhashps":                   handleGetNetworkHashPS,
+	"getnetworkinfo":                     handleGetNetworkInfo,
 	"getnodeaddresses":                   handleGetNodeAddresses,
 	"getpeerinfo":                        handleGetPeerInfo,
 	"getrawmempool":                      handleGetRawMempool,
 	"getrawtransaction":                  handleGetRawTransaction,
+	"getscriptbalance":                   handleGetScriptBalance,
+	"getsilentpaymenttweaks":             handleGetSilentPaymentTweaks,
 	"getttl":                             handleGetTTL,
 	"gettxout":                           handleGetTxOut,
+	"gettxoutsetinfo":                    handleGetTxOutSetInfo,
+	"gettxspendingprevout":               handleGetTxSpendingPrevOut,
+	"getutreexocfheader":                 handleGetUtreexoCFHeader,
 	"getutreexoproof":                    handleGetUtreexoProof,
 	"getutreexoroots":                    handleGetUtreexoRoots,
+	"getutreexostats":                    handleGetUtreexoStats,
+	"getutxoproof":                       handleGetUtxoProof,
 	"getwatchonlybalance":                handleGetWatchOnlyBalance,
 	"invalidateblock":                    handleInvalidateBlock,
 	"help":                               handleHelp,
-	"listbdktransactions":                handleListBDKTransactions,
-	"listbdkutxos":                       handleListBDKUTXOs,
+	"listbanned":                         handleListBanned,
+	"listwallets":                        handleListWallets,
+	"loadtxoutset":                       handleLoadTxOutSet,
+	"loadwallet":                         handleLoadWallet,
 	"node":                               handleNode,
-	"peekaddress":                        handlePeekAddress,
 	"ping":                               handlePing,
 	"proveutxochaintipinclusion":         handleProveUtxoChainTipInclusion,
 	"provewatchonlychaintipinclusion":    handleProveWatchOnlyChainTipInclusion,
-	"rebroadcastunconfirmedbdktxs":       handleRebroadcastUnconfirmedBDKTxs,
 	"reconsiderblock":                    handleReconsiderBlock,
+	"restorewallet":                      handleRestoreWallet,
 	"registeraddressestowatchonlywallet": handleRegisterAddressesToWatchOnlyWallet,
+	"scanblocks":                         handleScanBlocks,
+	"scantxoutset":                       handleScanTxOutSet,
 	"searchrawtransactions":              handleSearchRawTransactions,
 	"sendrawtransaction":                 handleSendRawTransaction,
+	"setban":                             handleSetBan,
 	"setgenerate":                        handleSetGenerate,
 	"signmessagewithprivkey":             handleSignMessageWithPrivKey,
 	"stop":                               handleStop,
 	"submitblock":                        handleSubmitBlock,
-	"unusedaddress":                      handleUnusedAddress,
+	"submitpackage":                      handleSubmitPackage,
+	"testmempoolaccept":                  handleTestMempoolAccept,
 	"uptime":                             handleUptime,
 	"validateaddress":                    handleValidateAddress,
 	"verifychain":                        handleVerifyChain,
@@ -207,12 +244,47 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"version":                            handleVersion,
 }
 
+type bdkWalletHandler func(*rpcServer, *bdkwallet.Manager, interface{}, <-chan struct{}) (interface{}, error)
+
+// rpcBDKWalletHandlers maps the RPC command strings of the bdk wallet to their
+// handler functions.  The commands are run with the wallet of the endpoint the
+// request is sent to, /wallet/<name> for a named wallet, and with the default
+// wallet otherwise.
+var rpcBDKWalletHandlers = map[string]bdkWalletHandler{
+	"backupwallet":                   handleBackupWallet,
+	"balance":                        handleBalance,
+	"bumpfee":                        handleBumpFee,
+	"cpfpbdktransaction":             handleCpfpBDKTransaction,
+	"createtransactionfrombdkwallet": handleCreateTransactionFromBDKWallet,
+	"enumeratesigners":               handleEnumerateSigners,
+	"exportbdktransactions":          handleExportBDKTransactions,
+	"finalizepsbt":                   handleFinalizePsbt,
+	"freshaddress":                   handleFreshAddress,
+	"getmnemonicwords":               handleGetMnemonicWords,
+	"importbdkdescriptor":            handleImportBDKDescriptor,
+	"importsilentpaymentkey":         handleImportSilentPaymentKey,
+	"listbdkdescriptors":             handleListBDKDescriptors,
+	"listbdktransactions":            handleListBDKTransactions,
+	"listbdkutxos":                   handleListBDKUTXOs,
+	"listlockunspent":                handleListLockUnspent,
+	"listreceivedbyaddress":          handleListReceivedByAddress,
+	"lockunspent":                    handleLockUnspent,
+	"peekaddress":                    handlePeekAddress,
+	"rebroadcastunconfirmedbdktxs":   handleRebroadcastUnconfirmedBDKTxs,
+	"sendall":                        handleSendAll,
+	"setlabel":                       handleSetLabel,
+	"unloadwallet":                   handleUnloadWallet,
+	"unusedaddress":                  handleUnusedAddress,
+	"utxoupdatepsbt":                 handleUtxoUpdatePsbt,
+	"walletcreatefundedpsbt":         handleWalletCreateFundedPsbt,
+	"walletprocesspsbt":              handleWalletProcessPsbt,
+}
+
 // list of commands that we recognize, but for which btcd has no support because
 // it lacks support for wallet functionality. For these commands the user
 // should ask a connected instance of btcwallet.
 var rpcAskWallet = map[string]struct{}{
 	"addmultisigaddress":     {},
-	"backupwallet":           {},
 	"createencryptedwallet":  {},
 	"createmultisig":         {},
 	"dumpprivkey":            {},
@@ -227,7 +299,6 @@ var rpcAskWallet = map[string]struct{}{
 	"getreceivedbyaccount":   {},
 	"getreceivedbyaddress":   {},
 	"gettransaction":         {},
-	"gettxoutsetinfo":        {},
 	"getunconfirmedbalance":  {},
 	"getwalletinfo":          {},
 	"importprivkey":          {},
@@ -235,13 +306,10 @@ var rpcAskWallet = map[string]struct{}{
 	"keypoolrefill":          {},
 	"listaccounts":           {},
 	"listaddressgroupings":   {},
-	"listlockunspent":        {},
 	"listreceivedbyaccount":  {},
-	"listreceivedbyaddress":  {},
 	"listsinceblock":         {},
 	"listtransactions":       {},
 	"listunspent":            {},
-	"lockunspent":            {},
 	"move":                   {},
 	"sendfrom":               {},
 	"sendmany":               {},
@@ -258,13 +326,12 @@ var rpcAskWallet = map[string]struct{}{
 // Commands that are currently unimplemented, but should ultimately be.
 var rpcUnimplemented = map[string]struct{}{
 	"estimatepriority": {},
-	"getmempoolentry":  {},
-	"getnetworkinfo":   {},
 	"getwork":          {},
 	"preciousblock":    {},
 }
 
-// Commands that are available to a limited user
+// Commands that are available to a limited user, which are the commands of
+// the readonly RPC permission.
 var rpcLimited = map[string]struct{}{
 	// Websockets commands
 	"loadtxfilter":          {},
@@ -283,7 +350,10 @@ var rpcLimited = map[string]struct{}{
 	"createrawtransaction":       {},
 	"decoderawtransaction":       {},
 	"decodescript":               {},
+	"deriveaddresses":            {},
 	"estimatefee":                {},
+	"estimaterawfee":             {},
+	"estimatesmartfee":           {},
 	"getbestblock":               {},
 	"getbestblockhash":           {},
 	"getbeststate":               {},
@@ -291,33 +361,90 @@ var rpcLimited = map[string]struct{}{
 	"getblockcount":              {},
 	"getblockhash":               {},
 	"getblockheader":             {},
+	"getblocksbytime":            {},
+	"getblockttls":               {},
 	"getchaintips":               {},
+	"getchaintxstats":            {},
 	"getcfilter":                 {},
 	"getcfilterheader":           {},
 	"getcurrentnet":              {},
+	"getdescriptorinfo":          {},
 	"getdifficulty":              {},
 	"getheaders":                 {},
+	"getindexinfo":               {},
 	"getinfo":                    {},
+	"getmempoolancestors":        {},
+	"getmempooldescendants":      {},
+	"getmempoolentry":            {},
 	"getnettotals":               {},
 	"gettxtotals":                {},
 	"getnetworkhashps":           {},
+	"getnetworkinfo":             {},
 	"getrawmempool":              {},
 	"getrawtransaction":          {},
+	"getscriptbalance":           {},
+	"getsilentpaymenttweaks":     {},
 	"gettxout":                   {},
+	"gettxspendingprevout":       {},
+	"getutreexocfheader":         {},
 	"getutreexoproof":            {},
 	"getutreexoroots":            {},
+	"getutreexostats":            {},
+	"getutxoproof":               {},
 	"invalidateblock":            {},
 	"proveutxochaintipinclusion": {},
 	"reconsiderblock":            {},
 	"searchrawtransactions":      {},
 	"sendrawtransaction":         {},
 	"submitblock":                {},
+	"submitpackage":              {},
+	"testmempoolaccept":          {},
 	"uptime":                     {},
+	"utxoupdatepsbt":             {},
 	"validateaddress":            {},
 	"verifymessage":              {},
 	"version":                    {},
 }
 
+// Commands that are available to a user with the wallet RPC permission in
+// addition to the commands of a limited user.  The mnemonic and the private
+// descriptors of the wallet are purposely only revealed to admin users.  The
+// backups hold the mnemonic and are written to and read from any path the node
+// can access, so backupwallet and restorewallet are only available to admin
+// users as well.
+var rpcWallet = map[string]struct{}{
+	"balance":                            {},
+	"bumpfee":                            {},
+	"cpfpbdktransaction":                 {},
+	"createtransactionfrombdkwallet":     {},
+	"createwallet":                       {},
+	"enumeratesigners":                   {},
+	"exportbdktransactions":              {},
+	"freshaddress":                       {},
+	"getwatchonlybalance":                {},
+	"importbdkdescriptor":                {},
+	"importsilentpaymentkey":             {},
+	"listbdktransactions":                {},
+	"listbdkutxos":                       {},
+	"listlockunspent":                    {},
+	"listreceivedbyaddress":              {},
+	"listwallets":                        {},
+	"loadwallet":                         {},
+	"lockunspent":                        {},
+	"notifywalletevents":                 {},
+	"peekaddress":                        {},
+	"provewatchonlychaintipinclusion":    {},
+	"rebroadcastunconfirmedbdktxs":       {},
+	"registeraddressestowatchonlywallet": {},
+	"sendall":                            {},
+	"setlabel":                           {},
+	"stopnotifywalletevents":             {},
+	"unloadwallet":                       {},
+	"unusedaddress":                      {},
+	"walletcreatefundedpsbt":             {},
+	"walletprocesspsbt":                  {},
+}
+
 // builderScript is a convenience function which is used for hard-coded scripts
 // built with the script builder.   Any errors are converted to a panic since it
 // is only, and must only, be used with hard-coded, and therefore, known good,
@@ -352,6 +479,27 @@ func rpcDecodeHexError(gotHex string) *btcjson.RPCError {
 			gotHex))
 }
 
+// decodeOutPoints returns the outpoints of the transaction inputs of a wallet
+// command.
+func decodeOutPoints(inputs []btcjson.TransactionInput) ([]wire.OutPoint, error) {
+	outpoints := make([]wire.OutPoint, 0, len(inputs))
+	for _, input := range inputs {
+		txHash, err := chainhash.NewHashFromStr(input.Txid)
+		if err != nil {
+			return nil, rpcDecodeHexError(input.Txid)
+		}
+		outpoints = append(outpoints, *wire.NewOutPoint(txHash, input.Vout))
+	}
+	return outpoints, nil
+}
+
+// rpcNotInMempoolError is a convenience function for returning a nicely
+// formatted RPC error which indicates a transaction isn't in the memory pool.
+func rpcNotInMempoolError() *btcjson.RPCError {
+	return btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+		"Transaction not in mempool")
+}
+
 // rpcNoTxInfoError is a convenience function for returning a nicely formatted
 // RPC error which indicates there is no information available for the provided
 // transaction hash.
@@ -372,6 +520,48 @@ type gbtWorkState struct {
 	template      *mining.BlockTemplate
 	notifyMap     map[chainhash.Hash]map[int64]chan struct{}
 	timeSource    blockchain.MedianTimeSource
+
+	// utreexoProof is the hex-encoded utreexo data proving the inputs of
+	// proofTemplate.  It's generated on request since it's only needed by
+	// utreexo-aware miners.
+	utreexoProof  string
+	proofTemplate *mining.BlockTemplate
+
+	// policy is the mining policy template was generated with.  Template
+	// requests overriding it with a different policy regenerate the
+	// template.
+	policy mining.Policy
+
+	// prevTemplate is the template that template replaced and
+	// prevTemplateID its ID.  It's kept to return templates as a delta
+	// against it to callers that were working on it.
+	prevTemplate   *mining.BlockTemplate
+	prevTemplateID string
+}
+
+// gbtOptions houses the options of a getblocktemplate request that determine
+// how its block template is generated and returned.
+type gbtOptions struct {
+	// useCoinbaseValue is whether the caller creates its own coinbase from
+	// the coinbase value rather than being returned a coinbase transaction.
+	useCoinbaseValue bool
+
+	// useUtreexoProof is whether the utreexo proof of the inputs of the
+	// template transactions is returned.
+	useUtreexoProof bool
+
+	// canProveUtreexo is whether the node is able to prove the inputs of
+	// the template transactions, which is advertised with the utreexoproof
+	// capability.
+	canProveUtreexo bool
+
+	// policy is the mining policy the template is generated with.
+	policy mining.Policy
+
+	// baseTemplate is the ID of a previously returned template.  When it's
+	// the current or previous template, the data of the transactions that
+	// are in both templates is omitted from the result.
+	baseTemplate string
 }
 
 // newGbtWorkState returns a new instance of a gbtWorkState with all internal
@@ -396,6 +586,27 @@ func handleAskWallet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (
 	return nil, ErrRPCNoWallet
 }
 
+// handleAbandonTransaction handles abandontransaction commands.
+func handleAbandonTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.AbandonTransactionCmd)
+
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	err = s.cfg.ConnMgr.AbandonTransaction(txHash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: err.Error(),
+		}
+	}
+
+	// no data returned unless an error.
+	return nil, nil
+}
+
 // handleAddNode handles addnode commands.
 func handleAddNode(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.AddNodeCmd)
@@ -427,17 +638,83 @@ func handleAddNode(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 	return nil, nil
 }
 
-// handleBalance handles the balance command.
-func handleBalance(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
+// backupPath returns the path of a backup for the backupwallet, restorewallet
+// and backupdatadir commands.  Relative paths are relative to the data
+// directory.
+func backupPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(cfg.DataDir, path)
+}
+
+// handleClearBanned implements the clearbanned command.
+func handleClearBanned(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if err := s.cfg.ConnMgr.ClearBanned(); err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
+			Message: err.Error(),
+		}
+	}
+
+	// no data returned unless an error.
+	return nil, nil
+}
+
+// handleCompactDB implements the compactdb command.
+func handleCompactDB(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if err := s.cfg.DBCompactor.compact(); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: err.Error(),
+		}
+	}
+
+	// no data returned unless an error.
+	return nil, nil
+}
+
+// handleBackupDataDir implements the backupdatadir command.
+func handleBackupDataDir(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.BackupDataDirCmd)
+
+	dir := backupPath(c.Destination)
+	backup, err := backupDataDir(s.cfg.Chain, s.cfg.DB,
+		s.cfg.UtreexoProofIndex, s.cfg.FlatUtreexoProofIndex, dir)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: err.Error(),
 		}
 	}
 
-	balance := s.cfg.BDKWallet.Wallet.Balance()
+	return btcjson.BackupDataDirResult{
+		Destination: dir,
+		Height:      backup.height,
+		Hash:        backup.hash.String(),
+	}, nil
+}
+
+// handleBackupWallet implements the backupwallet command.
+func handleBackupWallet(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.BackupWalletCmd)
+
+	var passphrase string
+	if c.Passphrase != nil {
+		passphrase = *c.Passphrase
+	}
+	err := bdkWallet.Backup(backupPath(c.Destination), passphrase)
+	if err != nil {
+		return nil, bdkWalletsError(err)
+	}
+
+	// no data returned unless an error.
+	return nil, nil
+}
+
+// handleBalance handles the balance command.
+func handleBalance(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	balance := bdkWallet.Balance()
 	return btcjson.BalanceResult{
 		Immature:         int64(balance.Immature),
 		TrustedPending:   int64(balance.TrustedPending),
@@ -552,20 +829,234 @@ func peerExists(connMgr rpcserverConnManager, addr string, nodeID int32) bool {
 	return false
 }
 
-// handleCreateTransactionFromBDKWallet handles createtransactionfrombdkwallet command.
-func handleCreateTransactionFromBDKWallet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
+// bdkFeeRate returns the fee rate in sat/vB that a transaction of the bdk
+// wallet pays or that the fee of one is bumped to.  The fee rate that confirms
+// the transaction within the target number of blocks is estimated when none is
+// given, conservatively when conservative is set.
+func bdkFeeRate(s *rpcServer, feeRate *float64, confTarget int64, conservative bool) (float32, error) {
+	if feeRate != nil {
+		if *feeRate <= 0 {
+			return 0, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Fee rate must be positive",
+			}
+		}
+		return float32(*feeRate), nil
+	}
+
+	if s.cfg.FeeEstimator == nil {
+		return 0, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Fee estimation is disabled, the fee rate must be given",
+		}
+	}
+	if err := checkConfTarget(s, confTarget); err != nil {
+		return 0, err
+	}
+	estimate, _ := s.cfg.FeeEstimator.EstimateSmartFee(uint32(confTarget), conservative)
+	if estimate <= 0 {
+		return 0, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Insufficient data to estimate the fee rate, the " +
+				"fee rate must be given",
+		}
+	}
+
+	// The estimate is in BTC/kB and never below the minimum relay fee.
+	rate := math.Max(float64(estimate), cfg.minRelayTxFee.ToBTC())
+	return float32(rate * btcutil.SatoshiPerBitcoin / 1000), nil
+}
+
+// bdkFeeBumpUData regenerates the utreexo proof of a transaction of the bdk
+// wallet that bumps the fee of the transaction with the hash.  The leaves of
+// the inputs are taken from the ones the mempool cached for the transaction
+// whose fee is bumped, which the accumulator keeps cached until the
+// transaction is replaced or confirmed.  The inputs spending the outputs of transactions in
+// the mempool are marked as unconfirmed.
+func bdkFeeBumpUData(s *rpcServer, tx *btcutil.Tx, bumped *chainhash.Hash) (*wire.UData, error) {
+	cached := make(map[wire.OutPoint]wire.LeafData)
+	if leaves, err := s.cfg.TxMemPool.FetchLeafDatas(bumped); err == nil {
+		for _, leaf := range leaves {
+			cached[leaf.OutPoint] = leaf
+		}
+	}
+
+	leaves := make([]wire.LeafData, 0, len(tx.MsgTx().TxIn))
+	for _, txIn := range tx.MsgTx().TxIn {
+		prevOut := txIn.PreviousOutPoint
+		if s.cfg.TxMemPool.HaveTransaction(&prevOut.Hash) {
+			leaf := wire.LeafData{}
+			leaf.SetUnconfirmed()
+			leaves = append(leaves, leaf)
+			continue
+		}
+
+		leaf, ok := cached[prevOut]
+		if !ok || leaf.IsUnconfirmed() {
+			return nil, fmt.Errorf("no cached leaf for input %v", prevOut)
+		}
+		leaves = append(leaves, leaf)
+	}
+
+	return s.cfg.Chain.GenerateUData(leaves)
+}
+
+// broadcastBDKFeeBump submits a transaction of the bdk wallet that bumps the
+// fee of the transaction with the hash.  Compact state nodes attach the
+// utreexo proof regenerated for its inputs and fall back to mempool.space
+// when the inputs can't be proven, like for the rest of the transactions of
+// the wallet.
+func broadcastBDKFeeBump(s *rpcServer, tx *btcutil.Tx, bumped *chainhash.Hash) error {
+	if !s.cfg.Chain.IsUtreexoViewActive() {
+		return s.rpcProcessTx(tx, true, false)
+	}
+
+	ud, err := bdkFeeBumpUData(s, tx, bumped)
+	if err == nil {
+		tx.MsgTx().UData = ud
+		return s.rpcProcessTx(tx, true, false)
+	}
+	rpcsLog.Debugf("Unable to prove tx %v, sending it to mempool.space: %v",
+		tx.Hash(), err)
+
+	_, err = sendTxToMempoolSpace(txHexString(tx.MsgTx()), s.cfg.ChainParams)
+	if err != nil {
+		return &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Failed to broadcast transaction to mempool.space. %v", err),
+		}
+	}
+	txD := &mempool.TxDesc{
+		TxDesc: mining.TxDesc{Tx: tx},
+	}
+	// Notify bdkwallet and other listeners.
+	s.NotifyNewTransactions([]*mempool.TxDesc{txD})
+	return nil
+}
+
+// handleBumpFee implements the bumpfee command.
+func handleBumpFee(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.BumpFeeCmd)
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	var feeRate *float64
+	confTarget := int64(6)
+	if c.Options != nil {
+		feeRate = c.Options.FeeRate
+		if c.Options.ConfTarget != nil {
+			confTarget = *c.Options.ConfTarget
+		}
+	}
+	rate, err := bdkFeeRate(s, feeRate, confTarget, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, bump, err := bdkWallet.BumpFee(*txHash, rate)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: fmt.Sprintf("Failed to bump the fee of tx %v. %v", txHash, err),
+		}
+	}
+	if err := broadcastBDKFeeBump(s, tx, txHash); err != nil {
+		return nil, err
+	}
+	bdkWallet.NotifyFeeBump(tx, *txHash, bump, false)
+
+	return btcjson.BumpFeeResult{
+		TxID:    tx.Hash().String(),
+		OrigFee: bump.OriginalFee.ToBTC(),
+		Fee:     bump.Fee.ToBTC(),
+		Errors:  []string{},
+	}, nil
+}
+
+// handleCpfpBDKTransaction implements the cpfpbdktransaction command.
+func handleCpfpBDKTransaction(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.CpfpBDKTransactionCmd)
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+	rate, err := bdkFeeRate(s, c.FeeRate, 6, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, bump, err := bdkWallet.CreateCpfp(*txHash, rate)
+	if err != nil {
 		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: fmt.Sprintf("Failed to bump the fee of tx %v. %v", txHash, err),
+		}
+	}
+	if err := broadcastBDKFeeBump(s, tx, txHash); err != nil {
+		return nil, err
+	}
+	bdkWallet.NotifyFeeBump(tx, *txHash, bump, true)
+
+	return btcjson.CpfpBDKTransactionResult{
+		TxHash:    tx.Hash().String(),
+		RawBytes:  txHexString(tx.MsgTx()),
+		ParentFee: int64(bump.OriginalFee),
+		Fee:       int64(bump.Fee),
+	}, nil
+}
+
+// broadcastBDKTx broadcasts the transaction created by the bdk wallet.
+func broadcastBDKTx(s *rpcServer, tx *btcutil.Tx) error {
+	if !s.cfg.Chain.IsUtreexoViewActive() {
+		return s.rpcProcessTx(tx, true, false)
+	}
+
+	// This is not ideal but since bdk doesn't handle utreexo proofs yet, there's
+	// no way for us to broadcast it normally unless we make some undesired changes
+	// to the mempool code. Just send it off to mempool.space until we can do things
+	// properly.
+	_, err := sendTxToMempoolSpace(txHexString(tx.MsgTx()), s.cfg.ChainParams)
+	if err != nil {
+		return &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
+			Message: fmt.Sprintf("Failed to broadcast transaction to mempool.space. %v", err),
 		}
 	}
+	txD := &mempool.TxDesc{
+		TxDesc: mining.TxDesc{Tx: tx},
+	}
+	// Notify bdkwallet and other listeners.
+	s.NotifyNewTransactions([]*mempool.TxDesc{txD})
+	return nil
+}
 
+// handleCreateTransactionFromBDKWallet handles createtransactionfrombdkwallet command.
+func handleCreateTransactionFromBDKWallet(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.CreateTransactionFromBDKWalletCmd)
 
 	recipients := make([]bdkwallet.Recipient, len(c.Recipients))
 	for i := range recipients {
+		recipients[i].Amount = btcutil.Amount(c.Recipients[i].Amount)
+		recipients[i].SubtractFee = c.Recipients[i].SubtractFee
+
+		// Silent payment addresses are passed on as they are since
+		// their outputs are derived by the wallet.
+		if bdkwallet.IsSilentPaymentAddress(c.Recipients[i].Address, s.cfg.ChainParams) {
+			_, err := bdkwallet.DecodeSilentPaymentAddress(
+				c.Recipients[i].Address, s.cfg.ChainParams)
+			if err != nil {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCMisc,
+					Message: fmt.Sprintf("Couldn't decode silent payment address %v. %v",
+						c.Recipients[i].Address, err),
+				}
+			}
+			recipients[i].Address = c.Recipients[i].Address
+			continue
+		}
+
 		addr, err := btcutil.DecodeAddress(c.Recipients[i].Address, s.cfg.ChainParams)
 		if err != nil {
 			return nil, &btcjson.RPCError{
@@ -576,10 +1067,37 @@ func handleCreateTransactionFromBDKWallet(s *rpcServer, cmd interface{}, closeCh
 		}
 
 		recipients[i].Address = addr.String()
-		recipients[i].Amount = btcutil.Amount(c.Recipients[i].Amount)
 	}
 
-	bytes, err := s.cfg.BDKWallet.Wallet.CreateTx(c.FeeRate, recipients)
+	coinSelection, err := bdkwallet.ParseCoinSelection(*c.CoinSelection)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: err.Error(),
+		}
+	}
+
+	var inputs []wire.OutPoint
+	if c.Inputs != nil {
+		inputs, err = decodeOutPoints(*c.Inputs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A zero fee rate has the fee rate estimated for the confirmation
+	// target.
+	var feeRate *float64
+	if c.FeeRate != 0 {
+		rate := float64(c.FeeRate)
+		feeRate = &rate
+	}
+	rate, err := bdkFeeRate(s, feeRate, *c.ConfTarget, true)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := bdkWallet.CreateTx(rate, recipients, inputs, coinSelection)
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
@@ -595,28 +1113,8 @@ func handleCreateTransactionFromBDKWallet(s *rpcServer, cmd interface{}, closeCh
 		}
 	}
 
-	if s.cfg.Chain.IsUtreexoViewActive() {
-		// This is not ideal but since bdk doesn't handle utreexo proofs yet, there's
-		// no way for us to broadcast it normally unless we make some undesired changes
-		// to the mempool code. Just send it off to mempool.space until we can do things
-		// properly.
-		_, err = sendTxToMempoolSpace(txHexString(tx.MsgTx()), s.cfg.ChainParams)
-		if err != nil {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCMisc,
-				Message: fmt.Sprintf("Failed to broadcast transaction to mempool.space. %v", err),
-			}
-		}
-		txD := &mempool.TxDesc{
-			TxDesc: mining.TxDesc{Tx: tx},
-		}
-		// Notify bdkwallet and other listeners.
-		s.NotifyNewTransactions([]*mempool.TxDesc{txD})
-	} else {
-		err = s.rpcProcessTx(tx, true, false)
-		if err != nil {
-			return nil, err
-		}
+	if err := broadcastBDKTx(s, tx); err != nil {
+		return nil, err
 	}
 
 	res := btcjson.CreateTransactionFromBDKWalletResult{
@@ -744,6 +1242,63 @@ func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 	return mtxHex, nil
 }
 
+// bdkWalletsError converts an error of creating, loading, unloading, backing up
+// or restoring a bdk wallet to the RPC error Bitcoin Core returns for it.
+func bdkWalletsError(err error) *btcjson.RPCError {
+	code := btcjson.ErrRPCWallet
+	switch {
+	case errors.Is(err, bdkwallet.ErrWalletNotFound),
+		errors.Is(err, bdkwallet.ErrWalletNotLoaded):
+		code = btcjson.ErrRPCWalletNotFound
+	case errors.Is(err, bdkwallet.ErrInvalidWalletName),
+		errors.Is(err, bdkwallet.ErrNoBackupPassphrase):
+		code = btcjson.ErrRPCInvalidParameter
+	}
+	return &btcjson.RPCError{
+		Code:    code,
+		Message: err.Error(),
+	}
+}
+
+// handleCreateWallet implements the createwallet command.
+func handleCreateWallet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Before doing anything, check that the bdk wallet is active.
+	if s.cfg.BDKWallets == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "BDK wallet must be enabled. (--bdkwallet)",
+		}
+	}
+
+	c := cmd.(*btcjson.CreateWalletCmd)
+	if *c.Blank {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Blank wallets are not supported",
+		}
+	}
+	if *c.Passphrase != "" {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Encrypted wallets are not supported",
+		}
+	}
+
+	bdkWallet, err := s.cfg.BDKWallets.Create(c.WalletName, *c.DisablePrivateKeys)
+	if err != nil {
+		return nil, bdkWalletsError(err)
+	}
+
+	var warning string
+	if *c.AvoidReuse {
+		warning = "avoid_reuse is not supported and was ignored"
+	}
+	return btcjson.CreateWalletResult{
+		Name:    bdkWallet.Name(),
+		Warning: warning,
+	}, nil
+}
+
 // handleDebugLevel handles debuglevel commands.
 func handleDebugLevel(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.DebugLevelCmd)
@@ -986,45 +1541,461 @@ func handleDecodeScript(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	return reply, nil
 }
 
-// handleEstimateFee handles estimatefee commands.
-func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	c := cmd.(*btcjson.EstimateFeeCmd)
+// handleDeriveAddresses implements the deriveaddresses command.
+func handleDeriveAddresses(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DeriveAddressesCmd)
 
-	if s.cfg.FeeEstimator == nil {
-		return nil, errors.New("Fee estimation disabled")
+	// Like Bitcoin Core, the checksum is required so that typos in the
+	// descriptor don't derive addresses nobody has the keys of.
+	if strings.IndexByte(c.Descriptor, '#') < 0 {
+		return nil, invalidDescriptorError(c.Descriptor, "missing checksum")
 	}
-
-	if c.NumBlocks <= 0 {
-		return -1.0, errors.New("Parameter NumBlocks must be positive")
+	info, err := describeDescriptor(c.Descriptor, s.cfg.ChainParams)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case info.isRange && c.Range == nil:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Range must be specified for a ranged descriptor")
+	case !info.isRange && c.Range != nil:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Range should not be specified for an un-ranged descriptor")
 	}
 
-	feeRate, err := s.cfg.FeeEstimator.EstimateFee(uint32(c.NumBlocks))
-
+	var rangeBegin, rangeEnd uint32
+	if c.Range != nil {
+		rangeBegin, rangeEnd, err = descriptorRange(c.Range)
+		if err != nil {
+			return nil, err
+		}
+	}
+	expanded, err := expandDescriptor(c.Descriptor, rangeBegin, rangeEnd,
+		s.cfg.ChainParams)
 	if err != nil {
-		return -1.0, err
+		return nil, err
 	}
 
-	// Convert to satoshis per kb.
-	return float64(feeRate), nil
-}
+	addresses := make(btcjson.DeriveAddressesResult, 0, len(expanded))
+	for _, script := range expanded {
+		class, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			script.script, s.cfg.ChainParams)
+		if err != nil || len(addrs) != 1 ||
+			class == txscript.PubKeyTy || class == txscript.MultiSigTy {
 
-// handleFreshAddress implements the freshaddress command.
-func handleFreshAddress(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+				"Descriptor does not have a corresponding address")
+		}
+		addresses = append(addresses, addrs[0].EncodeAddress())
+	}
+
+	return addresses, nil
+}
+
+// handleGetDBCompactionInfo implements the getdbcompactioninfo command.
+func handleGetDBCompactionInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	status := s.cfg.DBCompactor.progress()
+	result := btcjson.GetDBCompactionInfoResult{
+		Running:     status.running,
+		RangesDone:  status.done,
+		RangesTotal: status.total,
+	}
+	if status.total > 0 {
+		result.Progress = float64(status.done) / float64(status.total)
+	}
+	if !status.started.IsZero() {
+		result.LastStarted = status.started.Unix()
+	}
+	if !status.finished.IsZero() {
+		result.LastFinished = status.finished.Unix()
+	}
+	if status.err != nil {
+		result.LastError = status.err.Error()
+	}
+	if !status.nextScheduled.IsZero() {
+		result.NextScheduled = status.nextScheduled.Unix()
+	}
+	return result, nil
+}
+
+// handleGetDescriptorInfo implements the getdescriptorinfo command.
+func handleGetDescriptorInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetDescriptorInfoCmd)
+
+	info, err := describeDescriptor(c.Descriptor, s.cfg.ChainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return &btcjson.GetDescriptorInfoResult{
+		Descriptor:     info.public,
+		Checksum:       info.checksum,
+		IsRange:        info.isRange,
+		IsSolvable:     info.isSolvable,
+		HasPrivateKeys: info.hasPrivateKeys,
+	}, nil
+}
+
+// txOutSetPath returns the path of a utxo set snapshot for the dumptxoutset and
+// loadtxoutset commands.  Relative paths are relative to the data directory.
+func txOutSetPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(cfg.DataDir, path)
+}
+
+// handleDumpTxOutSet implements the dumptxoutset command.
+func handleDumpTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DumpTxOutSetCmd)
+
+	if s.cfg.Chain.IsUtreexoViewActive() {
 		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
+			Code: btcjson.ErrRPCMisc,
+			Message: "The utxo set isn't kept by compact state " +
+				"nodes (--noutreexo)",
+		}
+	}
+
+	// Write the snapshot to a temporary file first so that an incomplete
+	// snapshot is never at the requested path.
+	path := txOutSetPath(c.Path)
+	if fileExists(path) {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("%s already exists. If you are sure this is "+
+				"what you want, move it out of the way first", path))
+	}
+	tmpPath := path + ".incomplete"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		context := "Failed to create the snapshot file"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	info, err := s.cfg.Chain.DumpUtxoSnapshot(file, closeChan)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		context := "Failed to dump the utxo set"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return &btcjson.DumpTxOutSetResult{
+		CoinsWritten:   info.Coins,
+		BaseHash:       info.Hash.String(),
+		BaseHeight:     info.Height,
+		Path:           path,
+		HashSerialized: info.HashSerialized.String(),
+	}, nil
+}
+
+// handleEnumerateSigners implements the enumeratesigners command.
+func handleEnumerateSigners(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	signers, err := bdkWallet.EnumerateSigners()
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: fmt.Sprintf("Failed to enumerate the external signers. %v", err),
+		}
+	}
+
+	res := btcjson.EnumerateSignersResult{
+		Signers: make([]btcjson.SignerResult, len(signers)),
+	}
+	for i, signer := range signers {
+		res.Signers[i] = btcjson.SignerResult{
+			Fingerprint: signer.Fingerprint,
+			Name:        signer.Name,
+		}
+	}
+	return res, nil
+}
+
+// handleExportBDKTransactions implements the exportbdktransactions command.
+func handleExportBDKTransactions(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ExportBDKTransactionsCmd)
+	if *c.Format != "json" && *c.Format != "csv" {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Format must be \"json\" or \"csv\"",
+		}
+	}
+
+	entries, err := bdkWallet.History()
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: "Unable to fetch transactions: " + err.Error(),
+		}
+	}
+
+	if *c.Format == "csv" {
+		var buf bytes.Buffer
+		if err := bdkwallet.WriteHistoryCSV(&buf, entries); err != nil {
+			return nil, internalRPCError(err.Error(), "Failed to write CSV")
+		}
+		return buf.String(), nil
+	}
+
+	res := make([]btcjson.ExportBDKTransactionsResult, len(entries))
+	for i := range res {
+		entry := &entries[i]
+		res[i] = btcjson.ExportBDKTransactionsResult{
+			Txid:          entry.Txid.String(),
+			Height:        entry.Height,
+			Confirmations: entry.Confirmations,
+			Received:      int64(entry.Received),
+			Spent:         int64(entry.Spent),
+			Net:           int64(entry.Net()),
+			Label:         entry.Label,
+			ReceivedBy:    entry.ReceivedBy,
+			SentTo:        entry.SentTo,
+		}
+		if !entry.Time.IsZero() {
+			res[i].Time = entry.Time.Unix()
+		}
+		if entry.FeeKnown {
+			fee := int64(entry.Fee)
+			res[i].Fee = &fee
+		}
+	}
+
+	return res, nil
+}
+
+// handleEstimateFee handles estimatefee commands.
+func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateFeeCmd)
+
+	if s.cfg.FeeEstimator == nil {
+		return nil, errors.New("Fee estimation disabled")
+	}
+
+	if c.NumBlocks <= 0 {
+		return -1.0, errors.New("Parameter NumBlocks must be positive")
+	}
+
+	feeRate, err := s.cfg.FeeEstimator.EstimateFee(uint32(c.NumBlocks))
+
+	if err != nil {
+		return -1.0, err
+	}
+
+	// Convert to satoshis per kb.
+	return float64(feeRate), nil
+}
+
+// checkConfTarget returns an error when the confirmation target of a fee
+// estimate isn't tracked by the fee estimator.
+func checkConfTarget(s *rpcServer, confTarget int64) error {
+	maxTarget := s.cfg.FeeEstimator.HighestTargetTracked(mempool.LongHorizon)
+	if confTarget < 1 || confTarget > int64(maxTarget) {
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Invalid conf_target, must be "+
+				"between 1 and %d", maxTarget),
+		}
+	}
+	return nil
+}
+
+// estimateModeConservative returns whether the fee rate is estimated
+// conservatively in the estimate mode.
+func estimateModeConservative(mode btcjson.EstimateSmartFeeMode) (bool, error) {
+	switch mode {
+	case btcjson.EstimateModeUnset, btcjson.EstimateModeConservative:
+		return true, nil
+	case btcjson.EstimateModeEconomical:
+		return false, nil
+	default:
+		return false, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid estimate_mode parameter",
+		}
+	}
+}
+
+// handleEstimateSmartFee handles estimatesmartfee commands.
+func handleEstimateSmartFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateSmartFeeCmd)
+
+	if s.cfg.FeeEstimator == nil {
+		return nil, errors.New("Fee estimation disabled")
+	}
+	if err := checkConfTarget(s, c.ConfTarget); err != nil {
+		return nil, err
+	}
+
+	conservative := true
+	if c.EstimateMode != nil {
+		var err error
+		conservative, err = estimateModeConservative(*c.EstimateMode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	feeRate, blocks := s.cfg.FeeEstimator.EstimateSmartFee(
+		uint32(c.ConfTarget), conservative)
+	result := &btcjson.EstimateSmartFeeResult{
+		Blocks: int64(blocks),
+	}
+	if feeRate <= 0 {
+		result.Errors = []string{"Insufficient data or no feerate found"}
+		return result, nil
+	}
+
+	// Transactions paying less than the minimum relay fee wouldn't be
+	// relayed, so never estimate less than it.
+	rate := math.Max(float64(feeRate), cfg.minRelayTxFee.ToBTC())
+	result.FeeRate = &rate
+	return result, nil
+}
+
+// estimatorBucketResult converts the statistics of a range of fee rate buckets
+// to the result of the estimaterawfee command.
+func estimatorBucketResult(bucket *mempool.EstimatorBucket) *btcjson.EstimateRawFeeBucketResult {
+	return &btcjson.EstimateRawFeeBucketResult{
+		StartRange:     math.Round(bucket.Start),
+		EndRange:       math.Round(bucket.End),
+		WithinTarget:   math.Round(bucket.WithinTarget*100) / 100,
+		TotalConfirmed: math.Round(bucket.TotalConfirmed*100) / 100,
+		InMempool:      math.Round(bucket.InMempool*100) / 100,
+		LeftMempool:    math.Round(bucket.LeftMempool*100) / 100,
+	}
+}
+
+// handleEstimateRawFee handles estimaterawfee commands.
+func handleEstimateRawFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateRawFeeCmd)
+
+	if s.cfg.FeeEstimator == nil {
+		return nil, errors.New("Fee estimation disabled")
+	}
+	if err := checkConfTarget(s, c.ConfTarget); err != nil {
+		return nil, err
+	}
+
+	threshold := 0.95
+	if c.Threshold != nil {
+		threshold = *c.Threshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid threshold",
+		}
+	}
+
+	var result btcjson.EstimateRawFeeResult
+	horizons := []struct {
+		horizon mempool.FeeEstimateHorizon
+		result  **btcjson.EstimateRawFeeHorizonResult
+	}{
+		{mempool.ShortHorizon, &result.Short},
+		{mempool.MediumHorizon, &result.Medium},
+		{mempool.LongHorizon, &result.Long},
+	}
+	for _, h := range horizons {
+		feeRate, estimate := s.cfg.FeeEstimator.EstimateRawFee(
+			uint32(c.ConfTarget), threshold, h.horizon)
+		if estimate == nil {
+			continue
+		}
+
+		horizonResult := &btcjson.EstimateRawFeeHorizonResult{
+			Decay: estimate.Decay,
+			Scale: estimate.Scale,
+		}
+		if feeRate > 0 {
+			rate := float64(feeRate)
+			horizonResult.FeeRate = &rate
+			horizonResult.Pass = estimatorBucketResult(&estimate.Pass)
+		} else {
+			horizonResult.Errors = []string{"Insufficient data or " +
+				"no feerate found which meets threshold"}
+		}
+		if estimate.Fail.Start != -1 {
+			horizonResult.Fail = estimatorBucketResult(&estimate.Fail)
+		}
+		*h.result = horizonResult
+	}
+
+	return &result, nil
+}
+
+// decodePsbt decodes a base64 encoded psbt.
+func decodePsbt(encoded string) ([]byte, error) {
+	psbt, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "PSBT decode failed: " + err.Error(),
+		}
+	}
+	return psbt, nil
+}
+
+// handleFinalizePsbt implements the finalizepsbt command.
+func handleFinalizePsbt(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.FinalizePsbtCmd)
+	psbt, err := decodePsbt(c.Psbt)
+	if err != nil {
+		return nil, err
+	}
+
+	finalized, tx, err := bdkWallet.Wallet.FinalizePsbt(psbt)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: fmt.Sprintf("Failed to finalize psbt. %v", err),
 		}
 	}
 
-	index, address, err := s.cfg.BDKWallet.Wallet.FreshAddress()
+	// The psbt is returned as is unless the transaction can be extracted
+	// from it and it was asked for.
+	if tx == nil || !*c.Extract {
+		return btcjson.FinalizePsbtResult{
+			Psbt:     base64.StdEncoding.EncodeToString(finalized),
+			Complete: tx != nil,
+		}, nil
+	}
+
+	txHex, err := messageToHex(tx.MsgTx())
+	if err != nil {
+		return nil, err
+	}
+	return btcjson.FinalizePsbtResult{
+		Hex:      txHex,
+		Complete: true,
+	}, nil
+}
+
+// handleFreshAddress implements the freshaddress command.
+func handleFreshAddress(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.FreshAddressCmd)
+	index, address, err := bdkWallet.Wallet.FreshAddress()
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
 			Message: "Failed to retrieve new address: " + err.Error(),
 		}
 	}
+	if c.Label != nil && *c.Label != "" {
+		if err := bdkWallet.SetAddressLabel(address, *c.Label); err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCWallet,
+				Message: "Unable to label address: " + err.Error(),
+			}
+		}
+	}
 
 	result := btcjson.BDKAddressResult{Index: int(index), Address: address.String()}
 	return result, nil
@@ -1314,6 +2285,23 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 
 		blockReply.Tx = txNames
 	} else {
+		// Verbosity 3 also includes the outputs spent by each input.
+		// These come from the spend journal, which on utreexo nodes is
+		// built from the leaf data of the block, so no txindex is
+		// needed.
+		var stxos []blockchain.SpentTxOut
+		if *c.Verbosity >= 3 {
+			stxos, err = s.cfg.Chain.FetchSpendJournal(blk)
+			if err != nil {
+				context := "Failed to fetch spent outputs"
+				return nil, internalRPCError(err.Error(), context)
+			}
+			if len(stxos) != countSpentOutputs(blk) {
+				return nil, internalRPCError("Spend journal "+
+					"doesn't match the block", "")
+			}
+		}
+
 		txns := blk.Transactions()
 		rawTxns := make([]btcjson.TxRawResult, len(txns))
 		for i, tx := range txns {
@@ -1323,6 +2311,16 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 			if err != nil {
 				return nil, err
 			}
+
+			// The spend journal has an entry for every input of
+			// every transaction except the coinbase, in order.
+			if stxos != nil && i > 0 {
+				for j := range rawTxn.Vin {
+					rawTxn.Vin[j].PrevOut = createPrevOutResult(
+						&stxos[0], params)
+					stxos = stxos[1:]
+				}
+			}
 			rawTxns[i] = *rawTxn
 		}
 		blockReply.RawTx = rawTxns
@@ -1331,6 +2329,46 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return blockReply, nil
 }
 
+// countSpentOutputs returns the number of outputs spent by the block, which is
+// the number of inputs of all of its transactions except the coinbase.
+func countSpentOutputs(block *btcutil.Block) int {
+	var numSpent int
+	for _, tx := range block.Transactions()[1:] {
+		numSpent += len(tx.MsgTx().TxIn)
+	}
+	return numSpent
+}
+
+// createPrevOutResult converts the passed spent output to the JSON object
+// returned for an input by getblock with verbosity 3.
+func createPrevOutResult(stxo *blockchain.SpentTxOut, chainParams *chaincfg.Params) *btcjson.PrevOutResult {
+	// The disassembled string will contain [error] inline if the script
+	// doesn't fully parse, so ignore the error here.
+	disbuf, _ := txscript.DisasmString(stxo.PkScript)
+
+	// Ignore the error here since an error means the script couldn't parse
+	// and there is no additional information about it anyways.
+	scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(
+		stxo.PkScript, chainParams)
+	encodedAddrs := make([]string, len(addrs))
+	for i, addr := range addrs {
+		encodedAddrs[i] = addr.EncodeAddress()
+	}
+
+	return &btcjson.PrevOutResult{
+		Generated: stxo.IsCoinBase,
+		Height:    stxo.Height,
+		Value:     btcutil.Amount(stxo.Amount).ToBTC(),
+		ScriptPubKey: btcjson.ScriptPubKeyResult{
+			Asm:       disbuf,
+			Hex:       hex.EncodeToString(stxo.PkScript),
+			ReqSigs:   int32(reqSigs),
+			Type:      scriptClass.String(),
+			Addresses: encodedAddrs,
+		},
+	}
+}
+
 // softForkStatus converts a ThresholdState state into a human readable string
 // corresponding to the particular state.
 func softForkStatus(state blockchain.ThresholdState) (string, error) {
@@ -1366,6 +2404,7 @@ func handleGetBlockChainInfo(s *rpcServer, cmd interface{}, closeChan <-chan str
 		Difficulty:    getDifficultyRatio(chainSnapshot.Bits, params),
 		MedianTime:    chainSnapshot.MedianTime.Unix(),
 		Pruned:        cfg.Prune != 0,
+		Warnings:      s.cfg.SyncMgr.ForkWarning(),
 		SoftForks: &btcjson.SoftForks{
 			Bip9SoftForks: make(map[string]*btcjson.Bip9SoftForkDescription),
 		},
@@ -1564,6 +2603,44 @@ func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	return blockHeaderReply, nil
 }
 
+// handleGetBlocksByTime implements the getblocksbytime command.
+func handleGetBlocksByTime(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the block time index is not enabled.
+	if s.cfg.BlockTimeIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "block time index must be enabled (--blocktimeindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetBlocksByTimeCmd)
+	if c.EndTime < c.StartTime {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("End time %d is before start time %d",
+				c.EndTime, c.StartTime),
+		}
+	}
+
+	entries, err := s.cfg.BlockTimeIndex.FetchBlocksByTime(
+		time.Unix(c.StartTime, 0), time.Unix(c.EndTime, 0))
+	if err != nil {
+		context := "Failed to fetch the blocks by time"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	results := make([]btcjson.GetBlocksByTimeResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, btcjson.GetBlocksByTimeResult{
+			Hash:   entry.Hash.String(),
+			Height: entry.Height,
+			Time:   entry.Timestamp.Unix(),
+		})
+	}
+
+	return results, nil
+}
+
 // encodeTemplateID encodes the passed details into an ID that can be used to
 // uniquely identify a block template.
 func encodeTemplateID(prevHash *chainhash.Hash, lastGenerated time.Time) string {
@@ -1709,14 +2786,17 @@ func (state *gbtWorkState) templateUpdateChan(prevHash *chainhash.Hash, lastGene
 // changed or the transactions in the memory pool have been updated and it has
 // been long enough since the last template was generated.  Otherwise, the
 // timestamp for the existing block template is updated (and possibly the
-// difficulty on testnet per the consesus rules).  Finally, if the
-// useCoinbaseValue flag is false and the existing block template does not
-// already contain a valid payment address, the block template will be updated
-// with a randomly selected payment address from the list of configured
-// addresses.
+// difficulty on testnet per the consesus rules).  A new block template is also
+// generated when the requested mining policy differs from the one the current
+// template was generated with.  Finally, if the useCoinbaseValue option is
+// false and the existing block template does not already contain a valid
+// payment address, the block template will be updated with a randomly selected
+// payment address from the list of configured addresses.  The utreexo proof of
+// the template is generated when the useUtreexoProof option is set and it
+// isn't already.
 //
 // This function MUST be called with the state locked.
-func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bool) error {
+func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, opts *gbtOptions) error {
 	generator := s.cfg.Generator
 	lastTxUpdate := generator.TxSource().LastUpdated()
 	if lastTxUpdate.IsZero() {
@@ -1733,10 +2813,19 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 	template := state.template
 	if template == nil || state.prevHash == nil ||
 		!state.prevHash.IsEqual(latestHash) ||
+		state.policy != opts.policy ||
 		(state.lastTxUpdate != lastTxUpdate &&
 			time.Now().After(state.lastGenerated.Add(time.Second*
 				gbtRegenerateSeconds))) {
 
+		// Remember the template that's being replaced so the new one
+		// can be returned as a delta against it.
+		var prevTemplateID string
+		if template != nil && state.prevHash != nil {
+			prevTemplateID = encodeTemplateID(state.prevHash,
+				state.lastGenerated)
+		}
+
 		// Reset the previous best hash the block template was generated
 		// against so any errors below cause the next invocation to try
 		// again.
@@ -1746,7 +2835,7 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		// full coinbase as opposed to only the pertinent details needed
 		// to create their own coinbase.
 		var payAddr btcutil.Address
-		if !useCoinbaseValue {
+		if !opts.useCoinbaseValue {
 			payAddr = cfg.miningAddrs[rand.Intn(len(cfg.miningAddrs))]
 		}
 
@@ -1755,7 +2844,8 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		// block template doesn't include the coinbase, so the caller
 		// will ultimately create their own coinbase which pays to the
 		// appropriate address(es).
-		blkTemplate, err := generator.NewBlockTemplate(payAddr)
+		blkTemplate, err := generator.NewBlockTemplateWithPolicy(
+			&opts.policy, payAddr)
 		if err != nil {
 			return internalRPCError("Failed to create new block "+
 				"template: "+err.Error(), "")
@@ -1771,10 +2861,21 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		best := s.cfg.Chain.BestSnapshot()
 		minTimestamp := mining.MinimumMedianTime(best)
 
+		// Template IDs only have a resolution of a second, so make
+		// sure the new template doesn't reuse the ID of the one it
+		// replaces.
+		lastGenerated := time.Now()
+		if lastGenerated.Unix() <= state.lastGenerated.Unix() {
+			lastGenerated = state.lastGenerated.Add(time.Second)
+		}
+
 		// Update work state to ensure another block template isn't
 		// generated until needed.
+		state.prevTemplate = state.template
+		state.prevTemplateID = prevTemplateID
 		state.template = template
-		state.lastGenerated = time.Now()
+		state.policy = opts.policy
+		state.lastGenerated = lastGenerated
 		state.lastTxUpdate = lastTxUpdate
 		state.prevHash = latestHash
 		state.minTimestamp = minTimestamp
@@ -1800,7 +2901,7 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		// template if it doesn't already have one.  Since this requires
 		// mining addresses to be specified via the config, an error is
 		// returned if none have been specified.
-		if !useCoinbaseValue && !template.ValidPayAddress {
+		if !opts.useCoinbaseValue && !template.ValidPayAddress {
 			// Choose a payment address at random.
 			payToAddr := cfg.miningAddrs[rand.Intn(len(cfg.miningAddrs))]
 
@@ -1836,15 +2937,108 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 			targetDifficulty)
 	}
 
+	if opts.useUtreexoProof && state.proofTemplate != template {
+		proof, err := templateUtreexoProof(s, template)
+		if err != nil {
+			return err
+		}
+		state.utreexoProof = proof
+		state.proofTemplate = template
+	}
+
 	return nil
 }
 
-// blockTemplateResult returns the current block template associated with the
-// state as a btcjson.GetBlockTemplateResult that is ready to be encoded to JSON
-// and returned to the caller.
-//
+// templateUtreexoProof returns the hex-encoded utreexo data that proves the
+// inputs of the transactions of the block template against the accumulator as
+// of the block the template builds on.  Inputs that spend outputs created in
+// the template aren't proven since those outputs are never added to the
+// accumulator.
+func templateUtreexoProof(s *rpcServer, template *mining.BlockTemplate) (string, error) {
+	if !canProveTemplates(s) {
+		return "", &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "A utreexo proof index must be enabled to prove " +
+				"block templates. (--utreexoproofindex) or " +
+				"(--flatutreexoproofindex).",
+		}
+	}
+
+	// The accumulator must be at the block the template builds on or the
+	// proof won't be valid for it.
+	best := s.cfg.Chain.BestSnapshot()
+	if template.Block.Header.PrevBlock != best.Hash {
+		return "", &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "The block template doesn't build on the " +
+				"best block. Try again.",
+		}
+	}
+
+	var leaves []wire.LeafData
+	for _, tx := range template.Block.Transactions[1:] {
+		var txLeaves []wire.LeafData
+		var err error
+		if cfg.NoUtreexo {
+			txLeaves, err = blockchain.TxToDelLeaves(
+				btcutil.NewTx(tx), s.cfg.Chain)
+		} else {
+			txHash := tx.TxHash()
+			txLeaves, err = s.cfg.TxMemPool.FetchLeafDatas(&txHash)
+		}
+		if err != nil {
+			context := "Failed to fetch the leaves of transaction"
+			return "", internalRPCError(err.Error(), context)
+		}
+
+		for _, leaf := range txLeaves {
+			if !leaf.IsUnconfirmed() {
+				leaves = append(leaves, leaf)
+			}
+		}
+	}
+
+	var ud *wire.UData
+	var err error
+	switch {
+	case s.cfg.UtreexoProofIndex != nil:
+		ud, err = s.cfg.UtreexoProofIndex.GenerateUData(leaves)
+	case s.cfg.FlatUtreexoProofIndex != nil:
+		ud, err = s.cfg.FlatUtreexoProofIndex.GenerateUData(leaves)
+	default:
+		ud, err = s.cfg.Chain.GenerateUData(leaves)
+	}
+	if err != nil {
+		context := "Failed to prove the block template"
+		return "", internalRPCError(err.Error(), context)
+	}
+
+	var buf bytes.Buffer
+	if err := ud.Serialize(&buf); err != nil {
+		context := "Failed to serialize the utreexo proof"
+		return "", internalRPCError(err.Error(), context)
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// canProveTemplates returns whether the node keeps an accumulator or a utreexo
+// proof index that the inputs of block templates can be proven with.
+func canProveTemplates(s *rpcServer) bool {
+	return !cfg.NoUtreexo || s.cfg.UtreexoProofIndex != nil ||
+		s.cfg.FlatUtreexoProofIndex != nil
+}
+
+// blockTemplateResult returns the current block template associated with the
+// state as a btcjson.GetBlockTemplateResult that is ready to be encoded to JSON
+// and returned to the caller.  The utreexo proof of the template is included
+// when the useUtreexoProof option is set and the utreexoproof capability when
+// the canProveUtreexo option is.  When the baseTemplate option is the
+// ID of the current or previous template, the result is a delta against it
+// which omits the data of the transactions the caller already has.
+//
 // This function MUST be called with the state locked.
-func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld *bool) (*btcjson.GetBlockTemplateResult, error) {
+func (state *gbtWorkState) blockTemplateResult(opts *gbtOptions,
+	submitOld *bool) (*btcjson.GetBlockTemplateResult, error) {
 	// Ensure the timestamps are still in valid range for the template.
 	// This should really only ever happen if the local clock is changed
 	// after the template is generated, but it's important to avoid serving
@@ -1864,6 +3058,25 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 		}
 	}
 
+	// Find the transactions of the template the result is a delta against.
+	templateID := encodeTemplateID(state.prevHash, state.lastGenerated)
+	var baseTemplate *mining.BlockTemplate
+	switch opts.baseTemplate {
+	case "":
+	case templateID:
+		baseTemplate = template
+	case state.prevTemplateID:
+		baseTemplate = state.prevTemplate
+	}
+	var baseTxns map[chainhash.Hash]struct{}
+	if baseTemplate != nil {
+		baseTxns = make(map[chainhash.Hash]struct{},
+			len(baseTemplate.Block.Transactions))
+		for _, tx := range baseTemplate.Block.Transactions[1:] {
+			baseTxns[tx.TxHash()] = struct{}{}
+		}
+	}
+
 	// Convert each transaction in the block template to a template result
 	// transaction.  The result does not include the coinbase, so notice
 	// the adjustments to the various lengths and indices.
@@ -1896,16 +3109,21 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 			depends = append(depends, idx)
 		}
 
-		// Serialize the transaction for later conversion to hex.
-		txBuf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
-		if err := tx.Serialize(txBuf); err != nil {
-			context := "Failed to serialize transaction"
-			return nil, internalRPCError(err.Error(), context)
+		// Serialize the transaction for later conversion to hex unless
+		// the caller already has it from the base template.
+		var data string
+		if _, ok := baseTxns[txID]; !ok {
+			txBuf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+			if err := tx.Serialize(txBuf); err != nil {
+				context := "Failed to serialize transaction"
+				return nil, internalRPCError(err.Error(), context)
+			}
+			data = hex.EncodeToString(txBuf.Bytes())
 		}
 
 		bTx := btcutil.NewTx(tx)
 		resultTx := btcjson.GetBlockTemplateResultTx{
-			Data:    hex.EncodeToString(txBuf.Bytes()),
+			Data:    data,
 			TxID:    txID.String(),
 			Hash:    tx.WitnessHash().String(),
 			Depends: depends,
@@ -1921,7 +3139,6 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 	//  Including MinTime -> time/decrement
 	//  Omitting CoinbaseTxn -> coinbase, generation
 	targetDifficulty := fmt.Sprintf("%064x", blockchain.CompactToBig(header.Bits))
-	templateID := encodeTemplateID(state.prevHash, state.lastGenerated)
 	reply := btcjson.GetBlockTemplateResult{
 		Bits:         strconv.FormatInt(int64(header.Bits), 16),
 		CurTime:      header.Timestamp.Unix(),
@@ -1947,7 +3164,18 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 		reply.DefaultWitnessCommitment = hex.EncodeToString(template.WitnessCommitment)
 	}
 
-	if useCoinbaseValue {
+	if opts.canProveUtreexo {
+		reply.Capabilities = gbtUtreexoCapabilities
+	}
+	if opts.useUtreexoProof && state.proofTemplate == template {
+		reply.UtreexoProof = state.utreexoProof
+	}
+
+	if baseTemplate != nil {
+		reply.BaseTemplate = opts.baseTemplate
+	}
+
+	if opts.useCoinbaseValue {
 		reply.CoinbaseAux = gbtCoinbaseAux
 		reply.CoinbaseValue = &msgBlock.Transactions[0].TxOut[0].Value
 	} else {
@@ -1995,14 +3223,15 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 // has passed without finding a solution.
 //
 // See https://en.bitcoin.it/wiki/BIP_0022 for more details.
-func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbaseValue bool, closeChan <-chan struct{}) (interface{}, error) {
+func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, opts *gbtOptions,
+	closeChan <-chan struct{}) (interface{}, error) {
 	state := s.gbtWorkState
 	state.Lock()
 	// The state unlock is intentionally not deferred here since it needs to
 	// be manually unlocked before waiting for a notification about block
 	// template changes.
 
-	if err := state.updateBlockTemplate(s, useCoinbaseValue); err != nil {
+	if err := state.updateBlockTemplate(s, opts); err != nil {
 		state.Unlock()
 		return nil, err
 	}
@@ -2011,7 +3240,7 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	// the caller is invalid.
 	prevHash, lastGenerated, err := decodeTemplateID(longPollID)
 	if err != nil {
-		result, err := state.blockTemplateResult(useCoinbaseValue, nil)
+		result, err := state.blockTemplateResult(opts, nil)
 		if err != nil {
 			state.Unlock()
 			return nil, err
@@ -2032,8 +3261,7 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 		// old block template depending on whether or not a solution has
 		// already been found and added to the block chain.
 		submitOld := prevHash.IsEqual(prevTemplateHash)
-		result, err := state.blockTemplateResult(useCoinbaseValue,
-			&submitOld)
+		result, err := state.blockTemplateResult(opts, &submitOld)
 		if err != nil {
 			state.Unlock()
 			return nil, err
@@ -2065,7 +3293,7 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	state.Lock()
 	defer state.Unlock()
 
-	if err := state.updateBlockTemplate(s, useCoinbaseValue); err != nil {
+	if err := state.updateBlockTemplate(s, opts); err != nil {
 		return nil, err
 	}
 
@@ -2073,7 +3301,7 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 	// block template depending on whether or not a solution has already
 	// been found and added to the block chain.
 	submitOld := prevHash.IsEqual(&state.template.Block.Header.PrevBlock)
-	result, err := state.blockTemplateResult(useCoinbaseValue, &submitOld)
+	result, err := state.blockTemplateResult(opts, &submitOld)
 	if err != nil {
 		return nil, err
 	}
@@ -2087,12 +3315,18 @@ func handleGetBlockTemplateLongPoll(s *rpcServer, longPollID string, useCoinbase
 // requests.  In addition, it detects the capabilities reported by the caller
 // in regards to whether or not it supports creating its own coinbase (the
 // coinbasetxn and coinbasevalue capabilities) and modifies the returned block
-// template accordingly.
+// template accordingly.  Callers reporting the utreexoproof capability are
+// also returned the utreexo proof of the inputs of the template.  The
+// blockmintxfee and blockmaxweight fields of the request override the mining
+// policy of the template.
 func handleGetBlockTemplateRequest(s *rpcServer, request *btcjson.TemplateRequest, closeChan <-chan struct{}) (interface{}, error) {
 	// Extract the relevant passed capabilities and restrict the result to
 	// either a coinbase value or a coinbase transaction object depending on
 	// the request.  Default to only providing a coinbase value.
 	useCoinbaseValue := true
+	var useUtreexoProof bool
+	policy := s.cfg.Generator.Policy()
+	var baseTemplate string
 	if request != nil {
 		var hasCoinbaseValue, hasCoinbaseTxn bool
 		for _, capability := range request.Capabilities {
@@ -2101,12 +3335,51 @@ func handleGetBlockTemplateRequest(s *rpcServer, request *btcjson.TemplateReques
 				hasCoinbaseTxn = true
 			case "coinbasevalue":
 				hasCoinbaseValue = true
+			case "utreexoproof":
+				useUtreexoProof = true
 			}
 		}
 
 		if hasCoinbaseTxn && !hasCoinbaseValue {
 			useCoinbaseValue = false
 		}
+
+		if request.BlockMinTxFee != nil {
+			fee, err := btcutil.NewAmount(*request.BlockMinTxFee)
+			if err != nil || fee < 0 {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: fmt.Sprintf("Invalid blockmintxfee "+
+						"%v", *request.BlockMinTxFee),
+				}
+			}
+			policy.BlockMinTxFee = fee
+		}
+
+		if request.BlockMaxWeight != nil {
+			weight := *request.BlockMaxWeight
+			if weight < blockMaxWeightMin || weight > blockMaxWeightMax {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: fmt.Sprintf("The blockmaxweight "+
+						"must be in between %d and %d",
+						blockMaxWeightMin,
+						blockMaxWeightMax),
+				}
+			}
+			policy.BlockMaxWeight = weight
+			policy.BlockMinWeight = minUint32(policy.BlockMinWeight,
+				weight)
+		}
+
+		baseTemplate = request.BaseTemplate
+	}
+	opts := &gbtOptions{
+		useCoinbaseValue: useCoinbaseValue,
+		useUtreexoProof:  useUtreexoProof,
+		canProveUtreexo:  canProveTemplates(s),
+		policy:           policy,
+		baseTemplate:     baseTemplate,
 	}
 
 	// When a coinbase transaction has been requested, respond with an error
@@ -2147,7 +3420,7 @@ func handleGetBlockTemplateRequest(s *rpcServer, request *btcjson.TemplateReques
 	// be replaced with a new one.
 	if request != nil && request.LongPollID != "" {
 		return handleGetBlockTemplateLongPoll(s, request.LongPollID,
-			useCoinbaseValue, closeChan)
+			opts, closeChan)
 	}
 
 	// Protect concurrent access when updating block templates.
@@ -2161,10 +3434,10 @@ func handleGetBlockTemplateRequest(s *rpcServer, request *btcjson.TemplateReques
 	// seconds since the last template was generated.  Otherwise, the
 	// timestamp for the existing block template is updated (and possibly
 	// the difficulty on testnet per the consesus rules).
-	if err := state.updateBlockTemplate(s, useCoinbaseValue); err != nil {
+	if err := state.updateBlockTemplate(s, opts); err != nil {
 		return nil, err
 	}
-	return state.blockTemplateResult(useCoinbaseValue, nil)
+	return state.blockTemplateResult(opts, nil)
 }
 
 // chainErrToGBTErrString converts an error returned from btcchain to a string
@@ -2379,6 +3652,108 @@ func handleGetChainTips(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	return ret, nil
 }
 
+// handleGetChainTxStats implements the getchaintxstats command.
+func handleGetChainTxStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetChainTxStatsCmd)
+
+	// Default to the window ending at the best block.
+	var hash *chainhash.Hash
+	if c.BlockHash != nil {
+		var err error
+		hash, err = chainhash.NewHashFromStr(*c.BlockHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.BlockHash)
+		}
+	} else {
+		hash = &s.cfg.Chain.BestSnapshot().Hash
+	}
+	height, err := s.cfg.Chain.BlockHeightByHash(hash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Block is not in main chain",
+		}
+	}
+
+	// Default to a window of about a month of blocks.
+	var nBlocks int32
+	if c.NBlocks != nil {
+		nBlocks = *c.NBlocks
+		if nBlocks < 0 || (nBlocks > 0 && nBlocks >= height) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid block count: should be between 0 " +
+					"and the block's height - 1",
+			}
+		}
+	} else {
+		month := 30 * 24 * time.Hour
+		nBlocks = int32(month / s.cfg.ChainParams.TargetTimePerBlock)
+		if nBlocks >= height {
+			nBlocks = height - 1
+		}
+		if nBlocks < 0 {
+			nBlocks = 0
+		}
+	}
+
+	medianTime, err := s.cfg.Chain.MedianTimeByHeight(height)
+	if err != nil {
+		context := "Failed to calculate median time"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	txCount, ok, err := s.cfg.Chain.ChainTxCount(height)
+	if err != nil {
+		context := "Failed to fetch chain transaction count"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Transaction count of the block is unknown",
+		}
+	}
+
+	result := &btcjson.GetChainTxStatsResult{
+		Time:                   medianTime.Unix(),
+		TxCount:                int64(txCount),
+		WindowFinalBlockHash:   hash.String(),
+		WindowFinalBlockHeight: height,
+		WindowBlockCount:       nBlocks,
+	}
+	if nBlocks == 0 {
+		return result, nil
+	}
+
+	// Compute the statistics of the window from the block before it.
+	pastMedianTime, err := s.cfg.Chain.MedianTimeByHeight(height - nBlocks)
+	if err != nil {
+		context := "Failed to calculate median time"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	pastTxCount, ok, err := s.cfg.Chain.ChainTxCount(height - nBlocks)
+	if err != nil {
+		context := "Failed to fetch chain transaction count"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "Transaction count of the first block of the " +
+				"window is unknown",
+		}
+	}
+
+	interval := medianTime.Unix() - pastMedianTime.Unix()
+	result.WindowTxCount = int32(txCount - pastTxCount)
+	result.WindowInterval = int32(interval)
+	if interval > 0 {
+		result.TxRate = float64(result.WindowTxCount) / float64(interval)
+	}
+
+	return result, nil
+}
+
 // handleGetCFilter implements the getcfilter command.
 func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	if s.cfg.CfIndex == nil {
@@ -2506,6 +3881,71 @@ func handleGetHeaders(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return hexBlockHeaders, nil
 }
 
+// handleGetIndexInfo implements the getindexinfo command.
+func handleGetIndexInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetIndexInfoCmd)
+
+	// The indexes are named after the options that drop them.
+	indexes := make(map[string]indexers.Indexer)
+	if s.cfg.TxIndex != nil {
+		indexes["txindex"] = s.cfg.TxIndex
+	}
+	if s.cfg.AddrIndex != nil {
+		indexes["addrindex"] = s.cfg.AddrIndex
+	}
+	if s.cfg.CfIndex != nil {
+		indexes["cfindex"] = s.cfg.CfIndex
+	}
+	if s.cfg.TTLIndex != nil {
+		indexes["ttlindex"] = s.cfg.TTLIndex
+	}
+	if s.cfg.SpendIndex != nil {
+		indexes["spendindex"] = s.cfg.SpendIndex
+	}
+	if s.cfg.SilentPaymentIndex != nil {
+		indexes["silentpaymentindex"] = s.cfg.SilentPaymentIndex
+	}
+	if s.cfg.BlockTimeIndex != nil {
+		indexes["blocktimeindex"] = s.cfg.BlockTimeIndex
+	}
+	if s.cfg.ScriptBalanceIndex != nil {
+		indexes["scriptbalanceindex"] = s.cfg.ScriptBalanceIndex
+	}
+	if s.cfg.UtreexoProofIndex != nil {
+		indexes["utreexoproofindex"] = s.cfg.UtreexoProofIndex
+	}
+	if s.cfg.FlatUtreexoProofIndex != nil {
+		indexes["flatutreexoproofindex"] = s.cfg.FlatUtreexoProofIndex
+	}
+	if s.cfg.UtreexoCFIndex != nil {
+		indexes["utreexocfindex"] = s.cfg.UtreexoCFIndex
+	}
+
+	best := s.cfg.Chain.BestSnapshot()
+	result := make(map[string]btcjson.GetIndexInfoResult, len(indexes))
+	for name, indexer := range indexes {
+		if c.IndexName != nil && *c.IndexName != name {
+			continue
+		}
+
+		info, err := indexers.FetchIndexInfo(s.cfg.DB, indexer)
+		if err != nil {
+			context := fmt.Sprintf("Failed to fetch the info of "+
+				"the %s", indexer.Name())
+			return nil, internalRPCError(err.Error(), context)
+		}
+		result[name] = btcjson.GetIndexInfoResult{
+			Synced: info.BestHeight == best.Height &&
+				info.BestHash == best.Hash,
+			BestBlockHeight: info.BestHeight,
+			BestBlockHash:   info.BestHash.String(),
+			SizeOnDisk:      info.SizeOnDisk,
+		}
+	}
+
+	return result, nil
+}
+
 // handleGetInfo implements the getinfo command. We only return the fields
 // that are not related to wallet functionality.
 func handleGetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -2525,18 +3965,85 @@ func handleGetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 	return ret, nil
 }
 
-// handleGetMempoolInfo implements the getmempoolinfo command.
-func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	mempoolTxns := s.cfg.TxMemPool.TxDescs()
+// handleGetMempoolAncestors implements the getmempoolancestors command.
+func handleGetMempoolAncestors(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolAncestorsCmd)
+
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	ancestors, err := s.cfg.TxMemPool.MempoolAncestors(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError()
+	}
+
+	if c.Verbose != nil && *c.Verbose {
+		return ancestors, nil
+	}
+	return mempoolEntryHashes(ancestors), nil
+}
+
+// handleGetMempoolDescendants implements the getmempooldescendants command.
+func handleGetMempoolDescendants(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolDescendantsCmd)
 
-	var numBytes int64
-	for _, txD := range mempoolTxns {
-		numBytes += int64(txD.Tx.MsgTx().SerializeSize())
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	descendants, err := s.cfg.TxMemPool.MempoolDescendants(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError()
+	}
+
+	if c.Verbose != nil && *c.Verbose {
+		return descendants, nil
+	}
+	return mempoolEntryHashes(descendants), nil
+}
+
+// handleGetMempoolEntry implements the getmempoolentry command.
+func handleGetMempoolEntry(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolEntryCmd)
+
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	entry, err := s.cfg.TxMemPool.MempoolEntry(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError()
+	}
+
+	return entry, nil
+}
+
+// mempoolEntryHashes returns the sorted hashes of the passed memory pool
+// entries for the non-verbose results of the getmempoolancestors and
+// getmempooldescendants commands.
+func mempoolEntryHashes(entries map[string]*btcjson.GetMempoolEntryResult) []string {
+	hashStrings := make([]string, 0, len(entries))
+	for hash := range entries {
+		hashStrings = append(hashStrings, hash)
 	}
+	sort.Strings(hashStrings)
+
+	return hashStrings
+}
 
+// handleGetMempoolInfo implements the getmempoolinfo command.
+func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	ret := &btcjson.GetMempoolInfoResult{
-		Size:  int64(len(mempoolTxns)),
-		Bytes: numBytes,
+		Size:          int64(s.cfg.TxMemPool.Count()),
+		Bytes:         s.cfg.TxMemPool.Size(),
+		MaxMempool:    int64(cfg.MaxMempool) * 1000 * 1000,
+		MempoolMinFee: s.cfg.TxMemPool.MinFeeRate().ToBTC(),
+		MinRelayTxFee: cfg.minRelayTxFee.ToBTC(),
+		MempoolExpiry: int64(cfg.MempoolExpiry),
 	}
 
 	return ret, nil
@@ -2579,16 +4086,15 @@ func handleGetMiningInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 }
 
 // handleGetMnemonicWords implements the getmnemonicwords command.
-func handleGetMnemonicWords(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
+func handleGetMnemonicWords(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if bdkWallet.Wallet.WatchOnly() {
 		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
+			Code:    btcjson.ErrRPCWallet,
+			Message: "Watch-only wallet has no mnemonic words",
 		}
 	}
 
-	words := s.cfg.BDKWallet.Wallet.MnemonicWords()
+	words := bdkWallet.Wallet.MnemonicWords()
 	return words, nil
 }
 
@@ -2600,6 +4106,18 @@ func handleGetNetTotals(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 		TotalBytesSent: totalBytesSent,
 		TimeMillis:     time.Now().UTC().UnixNano() / int64(time.Millisecond),
 	}
+
+	if target := s.cfg.ConnMgr.UploadTarget(); target != nil {
+		now := time.Now()
+		reply.UploadTarget = &btcjson.UploadTargetResult{
+			TimeFrame:             int64(uploadTargetCycle / time.Second),
+			Target:                target.limit,
+			TargetReached:         target.reached(now),
+			ServeHistoricalBlocks: target.servesHistorical(now),
+			BytesLeftInCycle:      target.bytesLeft(now),
+			TimeLeftInCycle:       int64(target.cycleTimeLeft(now) / time.Second),
+		}
+	}
 	return reply, nil
 }
 
@@ -2712,6 +4230,100 @@ func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	return hashesPerSec.Int64(), nil
 }
 
+// handleGetNetworkInfo implements the getnetworkinfo command.
+func handleGetNetworkInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	userAgent := &wire.MsgVersion{UserAgent: wire.DefaultUserAgent}
+	if err := userAgent.AddUserAgent(userAgentName, userAgentVersion,
+		cfg.UserAgentComments...); err != nil {
+
+		return nil, internalRPCError(err.Error(), "")
+	}
+
+	// The networks other than IPv4 and IPv6 are reachable through their
+	// proxies.
+	onionProxy := cfg.OnionProxy
+	if onionProxy == "" {
+		onionProxy = cfg.Proxy
+	}
+	onionReachable := !cfg.NoOnion && onionProxy != ""
+	i2pReachable := cfg.i2pSession != nil
+	networks := make([]btcjson.NetworksResult, 0, numPeerNetworks)
+	for n := peerNetwork(0); n < numPeerNetworks; n++ {
+		network := btcjson.NetworksResult{
+			Name:                      n.String(),
+			Reachable:                 true,
+			Proxy:                     cfg.netProxies.proxyOf(n, cfg.Proxy),
+			ProxyRandomizeCredentials: cfg.TorIsolation,
+		}
+		switch n {
+		case netOnion:
+			network.Reachable = onionReachable
+			network.Proxy = onionProxy
+		case netI2P:
+			network.Reachable = i2pReachable
+			network.Proxy = cfg.I2PSAM
+			network.ProxyRandomizeCredentials = false
+		}
+		if !network.Reachable || network.Proxy == "" {
+			network.Proxy = ""
+			network.ProxyRandomizeCredentials = false
+		}
+		network.Limited = !network.Reachable
+		networks = append(networks, network)
+	}
+
+	localAddrs := s.cfg.ConnMgr.LocalAddresses()
+	localResults := make([]btcjson.LocalAddressesResult, 0, len(localAddrs))
+	for _, la := range localAddrs {
+		host, _, err := net.SplitHostPort(addrmgr.NetAddressKey(la.NA))
+		if err != nil {
+			continue
+		}
+		localResults = append(localResults, btcjson.LocalAddressesResult{
+			Address: host,
+			Port:    la.NA.Port,
+			Score:   int32(la.Score),
+		})
+	}
+
+	reply := &btcjson.GetNetworkInfoResult{
+		Version:         int32(1000000*appMajor + 10000*appMinor + 100*appPatch),
+		SubVersion:      userAgent.UserAgent,
+		ProtocolVersion: int32(maxProtocolVersion),
+		LocalServices:   fmt.Sprintf("%016x", uint64(s.cfg.ConnMgr.Services())),
+		LocalRelay:      !cfg.BlocksOnly,
+		TimeOffset:      int64(s.cfg.TimeSource.Offset().Seconds()),
+		Connections:     s.cfg.ConnMgr.ConnectedCount(),
+		NetworkActive:   true,
+		Networks:        networks,
+		RelayFee:        cfg.minRelayTxFee.ToBTC(),
+
+		// Replacements have to pay for their own relay at the minimum
+		// relay fee on top of the fees of the transactions they replace.
+		IncrementalFee: cfg.minRelayTxFee.ToBTC(),
+		LocalAddresses: localResults,
+		Warnings:       s.cfg.SyncMgr.ForkWarning(),
+	}
+
+	if status := s.cfg.ConnMgr.PortMapping(); status != nil {
+		reply.PortMapping = &btcjson.PortMappingResult{
+			Protocol: status.protocol,
+		}
+		if status.external != nil {
+			reply.PortMapping.ExternalAddress = status.external.IP.String()
+			reply.PortMapping.ExternalPort = status.external.Port
+		}
+		if !status.renewed.IsZero() {
+			reply.PortMapping.LastRenewal = status.renewed.Unix()
+		}
+		if status.err != nil {
+			reply.PortMapping.Error = status.err.Error()
+		}
+	}
+
+	return reply, nil
+}
+
 // handleGetNodeAddresses implements the getnodeaddresses command.
 func handleGetNodeAddresses(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetNodeAddressesCmd)
@@ -2774,12 +4386,27 @@ func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 			BanScore:       int32(p.BanScore()),
 			FeeFilter:      p.FeeFilter(),
 			SyncNode:       statsSnap.ID == syncPeerID,
+			BlockRelayOnly: p.IsBlockRelayOnly(),
+			Permissions:    p.Permissions(),
 		}
 		if p.ToPeer().LastPingNonce() != 0 {
 			wait := float64(time.Since(statsSnap.LastPingTime).Nanoseconds())
 			// We actually want microseconds.
 			info.PingWait = wait / 1000
 		}
+		if statsSnap.Services&wire.SFNodeUtreexo == wire.SFNodeUtreexo {
+			nodeType := "csn"
+			if statsSnap.Services&wire.SFNodeUtreexoBridge == wire.SFNodeUtreexoBridge {
+				nodeType = "bridge"
+			}
+			info.Utreexo = &btcjson.GetPeerInfoUtreexoResult{
+				NodeType:             nodeType,
+				ProofVersion:         statsSnap.ProofVersion,
+				ProofBytesSent:       statsSnap.ProofBytesSent,
+				ProofBytesRecv:       statsSnap.ProofBytesRecv,
+				ProofRequestFailures: statsSnap.ProofRequestFailures,
+			}
+		}
 		infos = append(infos, info)
 	}
 	return infos, nil
@@ -2924,54 +4551,235 @@ func handleGetRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan str
 	return *rawTxn, nil
 }
 
-// handleGetTTL handles getttl commands
-func handleGetTTL(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Respond with an error if the ttl index is not enabled.
-	ttlIndex := s.cfg.TTLIndex
-	if ttlIndex == nil {
+// handleGetScriptBalance implements the getscriptbalance command.
+func handleGetScriptBalance(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the script balance index is not enabled.
+	balanceIndex := s.cfg.ScriptBalanceIndex
+	if balanceIndex == nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
-			Message: "ttl index must be enabled (--ttlindex)",
+			Message: "script balance index must be enabled (--scriptbalanceindex)",
 		}
 	}
 
-	c := cmd.(*btcjson.GetTTLCmd)
-
-	// Convert the provided transaction hash hex to a Hash.
-	txHash, err := chainhash.NewHashFromStr(c.Txid)
+	// The script hash is byte reversed the same way as the other hashes.
+	c := cmd.(*btcjson.GetScriptBalanceCmd)
+	scriptHash, err := chainhash.NewHashFromStr(c.ScriptHash)
 	if err != nil {
-		return nil, rpcDecodeHexError(c.Txid)
+		return nil, rpcDecodeHexError(c.ScriptHash)
 	}
 
-	op := wire.NewOutPoint(txHash, c.Vout)
-	ttlRes := ttlIndex.GetTTL(op)
-
-	// If the txo is not in the database, it means either the txo never existed
-	// or that the txo is a utxo. Mark them as -1.
-	ttl := int32(-1)
-	if ttlRes != nil {
-		// Only set if we found a result in the database.
-		ttl = *ttlRes
+	balance, err := balanceIndex.FetchScriptBalance(scriptHash)
+	if err != nil {
+		context := "Failed to fetch the script balance"
+		return nil, internalRPCError(err.Error(), context)
 	}
-	ttlReply := &btcjson.GetTTLResult{
-		TTL: ttl,
+
+	// Scripts that aren't involved in any transaction of the main chain
+	// don't have a balance nor a status.
+	result := &btcjson.GetScriptBalanceResult{}
+	if balance != nil {
+		result.Confirmed = balance.Balance
+		result.TxCount = balance.TxCount
+		result.Status = hex.EncodeToString(balance.Status[:])
 	}
-	return ttlReply, nil
+	return result, nil
 }
 
-// handleGetTxOut handles gettxout commands.
-func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	c := cmd.(*btcjson.GetTxOutCmd)
+// handleGetSilentPaymentTweaks implements the getsilentpaymenttweaks command.
+func handleGetSilentPaymentTweaks(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the silent payment index is not enabled.
+	spIndex := s.cfg.SilentPaymentIndex
+	if spIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "silent payment index must be enabled (--silentpaymentindex)",
+		}
+	}
 
-	// Convert the provided transaction hash hex to a Hash.
-	txHash, err := chainhash.NewHashFromStr(c.Txid)
+	c := cmd.(*btcjson.GetSilentPaymentTweaksCmd)
+	hash, err := chainhash.NewHashFromStr(c.BlockHash)
 	if err != nil {
-		return nil, rpcDecodeHexError(c.Txid)
+		return nil, rpcDecodeHexError(c.BlockHash)
 	}
 
-	// If requested and the tx is available in the mempool try to fetch it
-	// from there, otherwise attempt to fetch from the block database.
-	var bestBlockHash string
+	tweaks, err := spIndex.FetchBlockTweaks(hash)
+	if err != nil {
+		context := "Failed to fetch the silent payment tweaks"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	// Only blocks in the main chain are indexed.
+	if tweaks == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+
+	results := make([]btcjson.GetSilentPaymentTweakResult, 0, len(tweaks))
+	for _, tweak := range tweaks {
+		results = append(results, btcjson.GetSilentPaymentTweakResult{
+			Txid:  tweak.TxHash.String(),
+			Tweak: hex.EncodeToString(tweak.Tweak[:]),
+		})
+	}
+	return results, nil
+}
+
+// handleGetUtreexoCFHeader implements the getutreexocfheader command.
+func handleGetUtreexoCFHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the utreexo committed filter header index
+	// is not enabled.
+	cfIndex := s.cfg.UtreexoCFIndex
+	if cfIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "utreexo committed filter header index must be " +
+				"enabled (--utreexocfindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetUtreexoCFHeaderCmd)
+	hash, err := chainhash.NewHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+
+	entry, err := cfIndex.FetchHeader(hash)
+	if err != nil {
+		context := "Failed to fetch the utreexo committed filter header"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	// Only blocks in the main chain are indexed.
+	if entry == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+
+	// The previous header is all zeros for the genesis block.
+	var prevHeader chainhash.Hash
+	blockHeader, err := s.cfg.Chain.HeaderByHash(hash)
+	if err != nil {
+		context := "Failed to fetch the block header"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if blockHeader.PrevBlock != (chainhash.Hash{}) {
+		prevEntry, err := cfIndex.FetchHeader(&blockHeader.PrevBlock)
+		if err != nil || prevEntry == nil {
+			context := "Failed to fetch the previous utreexo committed " +
+				"filter header"
+			if err == nil {
+				err = fmt.Errorf("missing entry for %v", blockHeader.PrevBlock)
+			}
+			return nil, internalRPCError(err.Error(), context)
+		}
+		prevHeader = prevEntry.Header
+	}
+
+	return &btcjson.GetUtreexoCFHeaderResult{
+		Header:     entry.Header.String(),
+		PrevHeader: prevHeader.String(),
+		FilterHash: entry.FilterHash.String(),
+		RootsHash:  entry.RootsHash.String(),
+	}, nil
+}
+
+// handleGetBlockTTLs implements the getblockttls command.
+func handleGetBlockTTLs(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the ttl index is not enabled.
+	ttlIndex := s.cfg.TTLIndex
+	if ttlIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "ttl index must be enabled (--ttlindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetBlockTTLsCmd)
+	hash, err := chainhash.NewHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+
+	// Only the blocks in the main chain are indexed.
+	block, err := s.cfg.Chain.BlockByHash(hash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+
+	leafTTLs := ttlIndex.FetchLeafTTLs(block)
+	results := make([]btcjson.GetBlockTTLResult, 0, len(leafTTLs))
+	for _, leafTTL := range leafTTLs {
+		result := btcjson.GetBlockTTLResult{
+			Txid:  leafTTL.OutPoint.Hash.String(),
+			Vout:  leafTTL.OutPoint.Index,
+			Index: leafTTL.Index,
+			TTL:   leafTTL.TTL,
+		}
+		if leafTTL.TTL >= 0 {
+			spendHeight := block.Height() + leafTTL.TTL
+			result.SpendHeight = &spendHeight
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// handleGetTTL handles getttl commands
+func handleGetTTL(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the ttl index is not enabled.
+	ttlIndex := s.cfg.TTLIndex
+	if ttlIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "ttl index must be enabled (--ttlindex)",
+		}
+	}
+
+	c := cmd.(*btcjson.GetTTLCmd)
+
+	// Convert the provided transaction hash hex to a Hash.
+	txHash, err := chainhash.NewHashFromStr(c.Txid)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Txid)
+	}
+
+	op := wire.NewOutPoint(txHash, c.Vout)
+	ttlRes := ttlIndex.GetTTL(op)
+
+	// If the txo is not in the database, it means either the txo never existed
+	// or that the txo is a utxo. Mark them as -1.
+	ttl := int32(-1)
+	if ttlRes != nil {
+		// Only set if we found a result in the database.
+		ttl = *ttlRes
+	}
+	ttlReply := &btcjson.GetTTLResult{
+		TTL: ttl,
+	}
+	return ttlReply, nil
+}
+
+// handleGetTxOut handles gettxout commands.
+func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetTxOutCmd)
+
+	// Convert the provided transaction hash hex to a Hash.
+	txHash, err := chainhash.NewHashFromStr(c.Txid)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Txid)
+	}
+
+	// If requested and the tx is available in the mempool try to fetch it
+	// from there, otherwise attempt to fetch from the block database.
+	var bestBlockHash string
 	var confirmations int32
 	var value int64
 	var pkScript []byte
@@ -3065,6 +4873,104 @@ func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return txOutReply, nil
 }
 
+// handleGetTxOutSetInfo implements the gettxoutsetinfo command.
+func handleGetTxOutSetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Compact state nodes don't keep the utxo set, so only the utreexo
+	// accumulator is available.
+	var reply *btcjson.GetTxOutSetInfoResult
+	if s.cfg.Chain.IsUtreexoViewActive() {
+		best := s.cfg.Chain.BestSnapshot()
+		reply = &btcjson.GetTxOutSetInfoResult{
+			Height:    int64(best.Height),
+			BestBlock: best.Hash,
+			Mode:      btcjson.TxOutSetModeAccumulator,
+		}
+	} else {
+		stats, err := s.cfg.Chain.FetchUtxoSetStats(closeChan)
+		if err != nil {
+			context := "Failed to fetch the utxo set statistics"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		reply = &btcjson.GetTxOutSetInfoResult{
+			Height:         int64(stats.Height),
+			BestBlock:      stats.Hash,
+			Mode:           btcjson.TxOutSetModeUtxoSet,
+			Transactions:   stats.Transactions,
+			TxOuts:         stats.TxOuts,
+			BogoSize:       stats.BogoSize,
+			HashSerialized: stats.HashSerialized,
+			DiskSize:       stats.DiskSize,
+			TotalAmount:    btcutil.Amount(stats.TotalAmount),
+		}
+	}
+
+	// Add the utreexo accumulator at the same block for bridge nodes and
+	// compact state nodes.
+	roots, numLeaves, ok, err := s.cfg.Chain.FetchUtreexoRoots(int32(reply.Height))
+	if err != nil {
+		context := "Failed to fetch the utreexo roots"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if ok {
+		reply.Utreexo = &btcjson.GetTxOutSetInfoUtreexoResult{
+			NumLeaves: numLeaves,
+			Roots:     make([]string, 0, len(roots)),
+		}
+		for _, root := range roots {
+			reply.Utreexo.Roots = append(reply.Utreexo.Roots,
+				hex.EncodeToString(root[:]))
+		}
+	}
+
+	return reply, nil
+}
+
+// handleGetTxSpendingPrevOut implements the gettxspendingprevout command.
+func handleGetTxSpendingPrevOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetTxSpendingPrevOutCmd)
+
+	results := make([]btcjson.GetTxSpendingPrevOutResult, 0, len(c.Outputs))
+	for _, output := range c.Outputs {
+		txHash, err := chainhash.NewHashFromStr(output.Txid)
+		if err != nil {
+			return nil, rpcDecodeHexError(output.Txid)
+		}
+		result := btcjson.GetTxSpendingPrevOutResult{
+			Txid: output.Txid,
+			Vout: output.Vout,
+		}
+
+		// Check the memory pool first since its spenders aren't in the
+		// spend index yet.
+		op := wire.OutPoint{Hash: *txHash, Index: output.Vout}
+		if spend := s.cfg.TxMemPool.CheckSpend(op); spend != nil {
+			result.SpendingTxid = spend.Hash().String()
+			results = append(results, result)
+			continue
+		}
+
+		if s.cfg.SpendIndex != nil {
+			spender, err := s.cfg.SpendIndex.FetchSpender(&op)
+			if err != nil {
+				context := "Failed to fetch the spender"
+				return nil, internalRPCError(err.Error(), context)
+			}
+			if spender != nil {
+				blockHash, err := s.cfg.Chain.BlockHashByHeight(spender.Height)
+				if err != nil {
+					context := "Failed to fetch the block hash"
+					return nil, internalRPCError(err.Error(), context)
+				}
+				result.SpendingTxid = spender.TxHash.String()
+				result.BlockHash = blockHash.String()
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
 // handleGetWatchOnlyBalance implements the getwatchonlybalance command.
 func handleGetWatchOnlyBalance(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	if s.cfg.WatchOnlyWallet == nil {
@@ -3133,16 +5039,8 @@ func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 }
 
 // handleListBDKTransactions handles listbdktransactions commands.
-func handleListBDKTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
-		}
-	}
-
-	txs, err := s.cfg.BDKWallet.Wallet.Transactions()
+func handleListBDKTransactions(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	txs, err := bdkWallet.Transactions()
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
@@ -3167,114 +5065,325 @@ func handleListBDKTransactions(s *rpcServer, cmd interface{}, closeChan <-chan s
 			Spent:         int64(txs[i].Spent),
 			Received:      int64(txs[i].Received),
 			Confirmations: txs[i].Confirmations,
+			Label:         bdkWallet.TxLabel(txs[i].Txid),
 		}
 	}
 
 	return res, nil
 }
 
-// handleListBDKUTXOs handles handlelistbdkutxos commands.
-func handleListBDKUTXOs(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
+// handleImportBDKDescriptor implements the importbdkdescriptor command.
+func handleImportBDKDescriptor(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ImportBDKDescriptorCmd)
+	if *c.Birthday < 0 {
 		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Birthday must not be negative",
 		}
 	}
 
-	utxos := s.cfg.BDKWallet.Wallet.UTXOs()
-
-	res := make([]btcjson.ListBDKUTXOsResult, len(utxos))
-	for i := range res {
-		res[i] = btcjson.ListBDKUTXOsResult{
-			Txid:            utxos[i].Txid.String(),
-			Vout:            utxos[i].Vout,
-			Amount:          int64(utxos[i].Amount),
-			ScriptPubKey:    hex.EncodeToString(utxos[i].ScriptPubKey),
-			IsChange:        utxos[i].IsChange,
-			DerivationIndex: utxos[i].DerivationIndex,
-			Confirmations:   utxos[i].Confirmations,
+	err := bdkWallet.ImportDescriptor(c.Descriptor, *c.Birthday)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Unable to import descriptor: " + err.Error(),
 		}
 	}
 
-	return res, nil
+	return nil, nil
 }
 
-// handlePeekAddress implements the peekaddress command.
-func handlePeekAddress(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
+// handleImportSilentPaymentKey implements the importsilentpaymentkey command.
+func handleImportSilentPaymentKey(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ImportSilentPaymentKeyCmd)
+	if *c.Birthday < 0 {
 		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Birthday must not be negative",
 		}
 	}
 
-	c := cmd.(*btcjson.PeekAddressCmd)
+	scanKey, err := btcutil.DecodeWIF(c.ScanKey)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid scan key: " + err.Error(),
+		}
+	}
+	if !scanKey.IsForNet(s.cfg.ChainParams) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Scan key for wrong network",
+		}
+	}
+	serializedSpendKey, err := hex.DecodeString(c.SpendKey)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.SpendKey)
+	}
+	spendKey, err := btcec.ParsePubKey(serializedSpendKey)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid spend key: " + err.Error(),
+		}
+	}
 
-	index, address, err := s.cfg.BDKWallet.Wallet.PeekAddress(c.Index)
+	addr, err := bdkWallet.ImportSilentPaymentKeys(scanKey.PrivKey, spendKey,
+		*c.Birthday)
 	if err != nil {
 		return nil, &btcjson.RPCError{
-			Code: btcjson.ErrRPCMisc,
-			Message: fmt.Sprintf("Failed to retrieve address at index %v: %v",
-				c.Index, err),
+			Code:    btcjson.ErrRPCWallet,
+			Message: "Unable to import silent payment keys: " + err.Error(),
+		}
+	}
+	encoded, err := addr.EncodeAddress(s.cfg.ChainParams)
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "Failed to encode address")
+	}
+	return encoded, nil
+}
+
+// handleListBDKDescriptors implements the listbdkdescriptors command.
+func handleListBDKDescriptors(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ListBDKDescriptorsCmd)
+	descriptors := bdkWallet.Wallet.Descriptors(*c.IncludePrivate)
+
+	res := make([]btcjson.ListBDKDescriptorsResult, len(descriptors))
+	for i := range res {
+		res[i] = btcjson.ListBDKDescriptorsResult{
+			Descriptor: descriptors[i].Descriptor,
+			Internal:   descriptors[i].Internal,
+			Birthday:   descriptors[i].Birthday,
+			NextIndex:  descriptors[i].NextIndex,
 		}
 	}
 
-	result := btcjson.BDKAddressResult{Index: int(index), Address: address.String()}
-	return result, nil
+	return res, nil
 }
 
-// handlePing implements the ping command.
-func handlePing(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Ask server to ping \o_
-	nonce, err := wire.RandomUint64()
-	if err != nil {
-		return nil, internalRPCError("Not sending ping - failed to "+
-			"generate nonce: "+err.Error(), "")
+// handleListBDKUTXOs handles handlelistbdkutxos commands.
+func handleListBDKUTXOs(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	utxos := bdkWallet.UTXOs()
+
+	res := make([]btcjson.ListBDKUTXOsResult, len(utxos))
+	for i := range res {
+		res[i] = btcjson.ListBDKUTXOsResult{
+			Txid:               utxos[i].Txid.String(),
+			Vout:               utxos[i].Vout,
+			Amount:             int64(utxos[i].Amount),
+			ScriptPubKey:       hex.EncodeToString(utxos[i].ScriptPubKey),
+			IsChange:           utxos[i].IsChange,
+			DerivationIndex:    utxos[i].DerivationIndex,
+			Confirmations:      utxos[i].Confirmations,
+			SilentPaymentTweak: hex.EncodeToString(utxos[i].SilentPaymentTweak),
+			Label:              bdkWallet.ScriptLabel(utxos[i].ScriptPubKey),
+		}
 	}
-	s.cfg.ConnMgr.BroadcastMessage(wire.NewMsgPing(nonce))
 
-	return nil, nil
+	return res, nil
 }
 
-// handleProveUtxoChainTipInclusion implements the proveutxochaintipinclusion command.
-func handleProveUtxoChainTipInclusion(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (
-	interface{}, error) {
-	// Before doing anything, check that one of the indexes are active.
-	if s.cfg.UtreexoProofIndex == nil && s.cfg.FlatUtreexoProofIndex == nil {
-		return nil, &btcjson.RPCError{
-			Code: btcjson.ErrRPCMisc,
-			Message: "A utreexo proof index must be enabled. " +
-				"(--utreexoproofindex) or (--flatutreexoproofindex).",
+// handleListLockUnspent implements the listlockunspent command.
+func handleListLockUnspent(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	locked := bdkWallet.LockedUnspent()
+
+	res := make([]btcjson.TransactionInput, len(locked))
+	for i := range res {
+		res[i] = btcjson.TransactionInput{
+			Txid: locked[i].Hash.String(),
+			Vout: locked[i].Index,
 		}
 	}
-	c := cmd.(*btcjson.ProveUtxoChainTipInclusionCmd)
 
-	if len(c.Txids) != len(c.Vouts) {
+	return res, nil
+}
+
+// handleListReceivedByAddress implements the listreceivedbyaddress command.
+func handleListReceivedByAddress(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ListReceivedByAddressCmd)
+	if *c.MinConf < 0 {
 		return nil, &btcjson.RPCError{
-			Code: btcjson.ErrRPCMisc,
-			Message: fmt.Sprintf("Must give same number of txids as vouts. "+
-				"Given %d txids but %d vouts", len(c.Txids), len(c.Vouts)),
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Minconf must not be negative",
 		}
 	}
 
-	// Prepare the outpoints from the given txid and vouts.
-	outpoints := make([]wire.OutPoint, 0, len(c.Txids))
-	for i, txid := range c.Txids {
-		// Convert the provided transaction hash hex to a Hash.
-		txHash, err := chainhash.NewHashFromStr(txid)
+	received, err := bdkWallet.ReceivedByAddress(uint(*c.MinConf), *c.IncludeEmpty)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: "Unable to fetch received amounts: " + err.Error(),
+		}
+	}
+
+	res := make([]btcjson.ListReceivedByAddressResult, len(received))
+	for i := range res {
+		txids := make([]string, len(received[i].Txids))
+		for j := range txids {
+			txids[j] = received[i].Txids[j].String()
+		}
+		res[i] = btcjson.ListReceivedByAddressResult{
+			Address:       received[i].Address.EncodeAddress(),
+			Amount:        received[i].Amount.ToBTC(),
+			Confirmations: uint64(received[i].Confirmations),
+			TxIDs:         txids,
+			Label:         received[i].Label,
+		}
+	}
+
+	return res, nil
+}
+
+// handleLockUnspent implements the lockunspent command.
+func handleLockUnspent(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.LockUnspentCmd)
+
+	// Unlocking without any outputs unlocks all of them.
+	var err error
+	if c.Unlock && len(c.Transactions) == 0 {
+		err = bdkWallet.UnlockAllUnspent()
+	} else {
+		var outpoints []wire.OutPoint
+		outpoints, err = decodeOutPoints(c.Transactions)
 		if err != nil {
-			return nil, rpcDecodeHexError(txid)
+			return nil, err
 		}
-		op := wire.NewOutPoint(txHash, c.Vouts[i])
+		err = bdkWallet.LockUnspent(c.Unlock, outpoints)
+	}
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: err.Error(),
+		}
+	}
 
-		outpoints = append(outpoints, *op)
+	return true, nil
+}
+
+// handleListWallets implements the listwallets command.
+func handleListWallets(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Before doing anything, check that the bdk wallet is active.
+	if s.cfg.BDKWallets == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "BDK wallet must be enabled. (--bdkwallet)",
+		}
+	}
+
+	return s.cfg.BDKWallets.List(), nil
+}
+
+// handleListBanned implements the listbanned command.
+func handleListBanned(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	now := time.Now().Unix()
+	bans := s.cfg.ConnMgr.ListBanned()
+	results := make([]btcjson.ListBannedResult, 0, len(bans))
+	for _, ban := range bans {
+		results = append(results, btcjson.ListBannedResult{
+			Address:       ban.Subnet,
+			BanCreated:    ban.Created,
+			BannedUntil:   ban.Until,
+			BanDuration:   ban.Until - ban.Created,
+			TimeRemaining: ban.Until - now,
+			BanReason:     ban.Reason,
+		})
+	}
+	return results, nil
+}
+
+// handleLoadTxOutSet implements the loadtxoutset command.
+func handleLoadTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.LoadTxOutSetCmd)
+
+	if s.cfg.Chain.IsUtreexoViewActive() {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "The utxo set isn't kept by compact state " +
+				"nodes (--noutreexo)",
+		}
+	}
+
+	path := txOutSetPath(c.Path)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("Couldn't open the snapshot file %s: %v",
+				path, err))
+	}
+	defer file.Close()
+
+	info, err := s.cfg.Chain.LoadUtxoSnapshot(file, closeChan)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Unable to load the snapshot: %v", err),
+		}
+	}
+
+	return &btcjson.LoadTxOutSetResult{
+		CoinsLoaded:    info.Coins,
+		TipHash:        info.Hash.String(),
+		BaseHeight:     info.Height,
+		Path:           path,
+		HashSerialized: info.HashSerialized.String(),
+	}, nil
+}
+
+// handleLoadWallet implements the loadwallet command.
+func handleLoadWallet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Before doing anything, check that the bdk wallet is active.
+	if s.cfg.BDKWallets == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "BDK wallet must be enabled. (--bdkwallet)",
+		}
+	}
+
+	c := cmd.(*btcjson.LoadWalletCmd)
+	bdkWallet, err := s.cfg.BDKWallets.Load(c.WalletName)
+	if err != nil {
+		return nil, bdkWalletsError(err)
+	}
+
+	return btcjson.LoadWalletResult{Name: bdkWallet.Name()}, nil
+}
+
+// handlePeekAddress implements the peekaddress command.
+func handlePeekAddress(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.PeekAddressCmd)
+
+	index, address, err := bdkWallet.Wallet.PeekAddress(c.Index)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Failed to retrieve address at index %v: %v",
+				c.Index, err),
+		}
+	}
+
+	result := btcjson.BDKAddressResult{Index: int(index), Address: address.String()}
+	return result, nil
+}
+
+// handlePing implements the ping command.
+func handlePing(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Ask server to ping \o_
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		return nil, internalRPCError("Not sending ping - failed to "+
+			"generate nonce: "+err.Error(), "")
 	}
+	s.cfg.ConnMgr.BroadcastMessage(wire.NewMsgPing(nonce))
+
+	return nil, nil
+}
 
+// proveOutPoints returns an accumulator proof for the given outpoints against
+// the utreexo state at the chain tip.  One of the utreexo proof indexes must be
+// enabled.
+func proveOutPoints(s *rpcServer, outpoints []wire.OutPoint) (*blockchain.ChainTipProof, error) {
 	// Fetch the utxos that we'll need to prove the outpoints.
-	utxos := make([]*blockchain.UtxoEntry, 0, len(c.Txids))
+	utxos := make([]*blockchain.UtxoEntry, 0, len(outpoints))
 	for _, outpoint := range outpoints {
 		utxo, err := s.cfg.Chain.FetchUtxoEntry(outpoint)
 		if err != nil {
@@ -3300,21 +5409,60 @@ func handleProveUtxoChainTipInclusion(s *rpcServer, cmd interface{}, closeChan <
 		utxos = append(utxos, utxo)
 	}
 
-	// We already checked that at least one index is active.  Pick one and
+	return proveUtxoEntries(s, utxos, outpoints)
+}
+
+// proveUtxoEntries returns an accumulator proof for the given utxos and their
+// outpoints against the utreexo state at the chain tip.  One of the utreexo
+// proof indexes must be enabled.
+func proveUtxoEntries(s *rpcServer, utxos []*blockchain.UtxoEntry,
+	outpoints []wire.OutPoint) (*blockchain.ChainTipProof, error) {
+
+	// The caller checked that at least one index is active.  Pick one and
 	// generate the inclusion proof.
-	var proof *blockchain.ChainTipProof
 	if s.cfg.UtreexoProofIndex != nil {
-		var err error
-		proof, err = s.cfg.UtreexoProofIndex.ProveUtxos(utxos, &outpoints)
-		if err != nil {
-			return nil, err
+		return s.cfg.UtreexoProofIndex.ProveUtxos(utxos, &outpoints)
+	}
+	return s.cfg.FlatUtreexoProofIndex.ProveUtxos(utxos, &outpoints)
+}
+
+// handleProveUtxoChainTipInclusion implements the proveutxochaintipinclusion command.
+func handleProveUtxoChainTipInclusion(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (
+	interface{}, error) {
+	// Before doing anything, check that one of the indexes are active.
+	if s.cfg.UtreexoProofIndex == nil && s.cfg.FlatUtreexoProofIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "A utreexo proof index must be enabled. " +
+				"(--utreexoproofindex) or (--flatutreexoproofindex).",
 		}
-	} else {
-		var err error
-		proof, err = s.cfg.FlatUtreexoProofIndex.ProveUtxos(utxos, &outpoints)
+	}
+	c := cmd.(*btcjson.ProveUtxoChainTipInclusionCmd)
+
+	if len(c.Txids) != len(c.Vouts) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Must give same number of txids as vouts. "+
+				"Given %d txids but %d vouts", len(c.Txids), len(c.Vouts)),
+		}
+	}
+
+	// Prepare the outpoints from the given txid and vouts.
+	outpoints := make([]wire.OutPoint, 0, len(c.Txids))
+	for i, txid := range c.Txids {
+		// Convert the provided transaction hash hex to a Hash.
+		txHash, err := chainhash.NewHashFromStr(txid)
 		if err != nil {
-			return nil, err
+			return nil, rpcDecodeHexError(txid)
 		}
+		op := wire.NewOutPoint(txHash, c.Vouts[i])
+
+		outpoints = append(outpoints, *op)
+	}
+
+	proof, err := proveOutPoints(s, outpoints)
+	if err != nil {
+		return nil, err
 	}
 
 	if *c.Verbosity == 0 {
@@ -3478,166 +5626,248 @@ func handleGetUtreexoRoots(s *rpcServer, cmd interface{}, closeChan <-chan struc
 	}
 	c := cmd.(*btcjson.GetUtreexoRootsCmd)
 
-	// Convert the provided blockhash hex to a Hash.
-	blockHash, err := chainhash.NewHashFromStr(c.BlockHash)
-	if err != nil {
-		return nil, rpcDecodeHexError(c.BlockHash)
-	}
-
-	getReply := &btcjson.GetUtreexoRootsResult{}
-	if s.cfg.Chain.IsUtreexoViewActive() {
-		view, err := s.cfg.Chain.FetchUtreexoViewpoint(blockHash)
-		if err != nil {
-			return nil, &btcjson.RPCError{
-				Code: btcjson.ErrRPCMisc,
-				Message: fmt.Sprintf("Couldn't fetch the utreexoviewpoint for blockhash %s from "+
-					"the database. Error: %v", c.BlockHash, err),
-			}
-		}
-		for _, root := range view.GetRoots() {
-			getReply.Roots = append(getReply.Roots, hex.EncodeToString(root[:]))
-		}
-		getReply.NumLeaves = view.NumLeaves()
-	} else if s.cfg.UtreexoProofIndex != nil {
-		// NOTE (kcalvinalvin): so this is an ugly quirk I didn't bother to fix because
-		// I didn't realize we'd have a need for fetching utreexo roots at arbitrary heights.
-		//
-		// For bridges, the roots that are saved BEFORE the modification. So the current
-		// accumulator state is not saved and it only gets saved when a block gets confirmed.
-		// The key:value here would be "block X":"Utreexo roots BEFORE modification of block X".
-		//
-		// I did this because it made reorgs for bridges easier to handle. But now we have this
-		// weird thing. I know I know. I'll fix it. But the below code is correct in that it returns
-		// the roots that the caller wants.
-		if s.cfg.Chain.BestSnapshot().Hash == *blockHash {
-			roots, numLeaves := s.cfg.UtreexoProofIndex.FetchCurrentUtreexoState()
-
-			for _, root := range roots {
-				getReply.Roots = append(getReply.Roots, hex.EncodeToString(root[:]))
-			}
-			getReply.NumLeaves = numLeaves
-		} else {
-			height, err := s.cfg.Chain.BlockHeightByHash(blockHash)
-			if err != nil {
+	// Default to the chain tip if no block was given.
+	best := s.cfg.Chain.BestSnapshot()
+	height := best.Height
+	if c.HashOrHeight != nil {
+		switch v := c.HashOrHeight.Value.(type) {
+		case int:
+			if v < 0 || v > int(best.Height) {
 				return nil, &btcjson.RPCError{
-					Code: btcjson.ErrRPCMisc,
-					Message: fmt.Sprintf("Couldn't fetch the block height for blockhash %s from "+
-						"the blockindex. Error: %v", c.BlockHash, err),
+					Code:    btcjson.ErrRPCOutOfRange,
+					Message: fmt.Sprintf("Block height %d out of range", v),
 				}
 			}
+			height = int32(v)
 
-			// NOTE (kcalvinalvin): +1 here because the roots for bridges are saved BEFORE the modification.
-			hash, err := s.cfg.Chain.BlockHashByHeight(height + 1)
+		case string:
+			// Convert the provided blockhash hex to a Hash.
+			blockHash, err := chainhash.NewHashFromStr(v)
 			if err != nil {
-				return nil, &btcjson.RPCError{
-					Code: btcjson.ErrRPCMisc,
-					// I'm too ashamed to admit that height+1 is the problem. So I'm gonna lie
-					// to the user and just say it's the view we can't fetch. It's not wrong right?
-					Message: fmt.Sprintf("Couldn't fetch the utreexoview for blockhash %s from "+
-						"Error: %v", c.BlockHash, err),
-				}
+				return nil, rpcDecodeHexError(v)
 			}
-			var roots []*chainhash.Hash
-			var numLeaves uint64
-			err = s.cfg.DB.View(func(dbTx database.Tx) error {
-				roots, numLeaves, err = s.cfg.UtreexoProofIndex.FetchUtreexoState(dbTx, hash)
-				if err != nil {
-					return err
-				}
-
-				return nil
-			})
+			height, err = s.cfg.Chain.BlockHeightByHash(blockHash)
 			if err != nil {
 				return nil, &btcjson.RPCError{
-					Code: btcjson.ErrRPCMisc,
-					Message: fmt.Sprintf("Couldn't fetch the utreexoview for blockhash %s from "+
-						"Error: %v", c.BlockHash, err),
+					Code: btcjson.ErrRPCBlockNotFound,
+					Message: fmt.Sprintf("Couldn't fetch the block height for blockhash %s from "+
+						"the blockindex. Error: %v", v, err),
 				}
 			}
 
-			for _, root := range roots {
-				getReply.Roots = append(getReply.Roots, hex.EncodeToString(root[:]))
+		default:
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "The block must be given as a hash or a height",
 			}
-			getReply.NumLeaves = numLeaves
 		}
-	} else {
-		if s.cfg.Chain.BestSnapshot().Hash == *blockHash {
-			roots, numLeaves := s.cfg.FlatUtreexoProofIndex.FetchCurrentUtreexoState()
-
-			for _, root := range roots {
-				getReply.Roots = append(getReply.Roots, hex.EncodeToString(root[:]))
-			}
-			getReply.NumLeaves = numLeaves
-		} else {
-			height, err := s.cfg.Chain.BlockHeightByHash(blockHash)
-			if err != nil {
-				return nil, &btcjson.RPCError{
-					Code: btcjson.ErrRPCMisc,
-					Message: fmt.Sprintf("Couldn't fetch the block height for blockhash %s from "+
-						"the blockindex. Error: %v", c.BlockHash, err),
-				}
-			}
+	}
 
-			// NOTE (kcalvinalvin): +1 here because the roots for bridges are saved BEFORE the modification.
-			roots, numLeaves, err := s.cfg.FlatUtreexoProofIndex.FetchUtreexoState(height + 1)
-			if err != nil {
-				return nil, &btcjson.RPCError{
-					Code: btcjson.ErrRPCMisc,
-					// I'm too ashamed to admit that height+1 is the problem. So I'm gonna lie
-					// to the user and just say it's the view we can't fetch. It's not wrong right?
-					Message: fmt.Sprintf("Couldn't fetch the utreexoview for blockhash %s from "+
-						"Error: %v", c.BlockHash, err),
-				}
-			}
+	roots, numLeaves, ok, err := s.cfg.Chain.FetchUtreexoRoots(height)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Couldn't fetch the utreexo roots at height %d. "+
+				"Error: %v", height, err),
+		}
+	}
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("The utreexo roots at height %d aren't stored",
+				height),
+		}
+	}
 
-			for _, root := range roots {
-				getReply.Roots = append(getReply.Roots, hex.EncodeToString(root[:]))
-			}
-			getReply.NumLeaves = numLeaves
+	blockHash, err := s.cfg.Chain.BlockHashByHeight(height)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: fmt.Sprintf("Couldn't fetch the block at height %d", height),
 		}
 	}
 
+	getReply := &btcjson.GetUtreexoRootsResult{
+		BlockHash: blockHash.String(),
+		Height:    height,
+		Roots:     make([]string, 0, len(roots)),
+		NumLeaves: numLeaves,
+	}
+	for _, root := range roots {
+		getReply.Roots = append(getReply.Roots, hex.EncodeToString(root[:]))
+	}
+
 	return getReply, nil
 }
 
-// handleProveWatchOnlyChainTipInclusion implements the handleprovewatchonly command.
-func handleProveWatchOnlyChainTipInclusion(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (
+// handleGetUtreexoStats implements the getutreexostats command.
+func handleGetUtreexoStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (
 	interface{}, error) {
 
-	c := cmd.(*btcjson.ProveWatchOnlyChainTipInclusionCmd)
-
-	if s.cfg.WatchOnlyWallet == nil {
+	stats, ok := s.cfg.Chain.UtreexoStats()
+	if !ok {
 		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "Watch only wallet must be enabled (--watchonlywallet)",
+			Code: btcjson.ErrRPCMisc,
+			Message: "A utreexo proof index or utreexo must be enabled. " +
+				"(--utreexoproofindex) or (--flatutreexoproofindex) or (--utreexo)",
 		}
 	}
 
-	proof := s.cfg.WatchOnlyWallet.GetProof()
+	orphanStats := s.cfg.SyncMgr.ProofOrphanStats()
+	return utreexoStatsResult(&stats, &orphanStats), nil
+}
 
-	if *c.Verbosity == 0 {
-		return proof.String(), nil
+// utreexoStatsResult converts the utreexo statistics of the chain and the
+// counters of the transactions that waited for fresh proofs to the result of
+// the getutreexostats command.
+func utreexoStatsResult(stats *blockchain.UtreexoStats,
+	orphanStats *netsync.ProofOrphanStats) *btcjson.GetUtreexoStatsResult {
+
+	return &btcjson.GetUtreexoStatsResult{
+		NumLeaves:                stats.NumLeaves,
+		NumRoots:                 stats.NumRoots,
+		StateSize:                stats.StateSize,
+		NodesCacheHits:           stats.NodesCacheHits,
+		NodesCacheMisses:         stats.NodesCacheMisses,
+		NodesCacheHitRate:        stats.NodesCacheHitRate(),
+		CachedLeavesCacheHits:    stats.CachedLeavesCacheHits,
+		CachedLeavesCacheMisses:  stats.CachedLeavesCacheMisses,
+		CachedLeavesCacheHitRate: stats.CachedLeavesCacheHitRate(),
+		ProofsServed:             stats.ProofsServed,
+		ProofBytesServed:         stats.ProofBytesServed,
+		AvgProofSize:             stats.AvgProofSize(),
+		ProofOrphans:             orphanStats.Pending,
+		ProofOrphansTotal:        orphanStats.Orphaned,
+		ProofRequests:            orphanStats.Requested,
+		ProofOrphansResolved:     orphanStats.Resolved,
+		ProofOrphansDropped:      orphanStats.Dropped,
 	}
+}
 
-	// Convert the hashes to string.
-	proofString := make([]string, 0, len(proof.AccProof.Proof))
-	for _, singleProof := range proof.AccProof.Proof {
-		// Convert to chainhash.Hash to access the String() method.
-		chainHash := chainhash.Hash(singleProof)
-		proofString = append(proofString, chainHash.String())
+// handleGetUtxoProof implements the getutxoproof command.
+func handleGetUtxoProof(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (
+	interface{}, error) {
+	// Before doing anything, check that one of the indexes are active.
+	if s.cfg.UtreexoProofIndex == nil && s.cfg.FlatUtreexoProofIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "A utreexo proof index must be enabled. " +
+				"(--utreexoproofindex) or (--flatutreexoproofindex).",
+		}
 	}
+	c := cmd.(*btcjson.GetUtxoProofCmd)
 
-	hashesProvenString := make([]string, 0, len(proof.HashesProven))
-	for _, singleHash := range proof.HashesProven {
-		// Convert to chainhash.Hash to access the String() method.
-		chainHash := chainhash.Hash(singleHash)
-		hashesProvenString = append(hashesProvenString, chainHash.String())
+	if len(c.Outpoints) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "At least one outpoint must be given",
+		}
 	}
 
-	proveReply := &btcjson.ProveUtxoChainTipInclusionVerboseResult{
-		ProvedAtHash: proof.ProvedAtHash.String(),
-		ProofHashes:  proofString,
-		ProofTargets: proof.AccProof.Targets,
+	// Only the forest at the chain tip is kept so a proof can't be generated
+	// against an older state.  The requested state is checked before any
+	// work is done so that the caller doesn't get a proof that's invalid
+	// against the roots it is expecting.
+	var blockHash *chainhash.Hash
+	if c.BlockHash != nil {
+		var err error
+		blockHash, err = chainhash.NewHashFromStr(*c.BlockHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.BlockHash)
+		}
+
+		tip := s.cfg.Chain.BestSnapshot().Hash
+		if !blockHash.IsEqual(&tip) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Only the current tip %s is "+
+					"supported, not %s", tip, blockHash),
+			}
+		}
+	}
+
+	outpoints := make([]wire.OutPoint, 0, len(c.Outpoints))
+	for _, input := range c.Outpoints {
+		txHash, err := chainhash.NewHashFromStr(input.Txid)
+		if err != nil {
+			return nil, rpcDecodeHexError(input.Txid)
+		}
+		outpoints = append(outpoints, *wire.NewOutPoint(txHash, input.Vout))
+	}
+
+	proof, err := proveOutPoints(s, outpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tip may have moved while the proof was generated.
+	if blockHash != nil && !blockHash.IsEqual(proof.ProvedAtHash) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("The chain tip moved to %s while the "+
+				"proof for %s was generated", proof.ProvedAtHash,
+				blockHash),
+		}
+	}
+
+	// Convert the hashes to string.
+	proofString := make([]string, 0, len(proof.AccProof.Proof))
+	for _, singleProof := range proof.AccProof.Proof {
+		proofString = append(proofString, chainhash.Hash(singleProof).String())
+	}
+
+	hashesProvenString := make([]string, 0, len(proof.HashesProven))
+	for _, singleHash := range proof.HashesProven {
+		hashesProvenString = append(hashesProvenString, chainhash.Hash(singleHash).String())
+	}
+
+	return &btcjson.GetUtxoProofResult{
+		ProvedAtHash: proof.ProvedAtHash.String(),
+		ProofHashes:  proofString,
+		ProofTargets: proof.AccProof.Targets,
+		HashesProven: hashesProvenString,
+		Hex:          proof.String(),
+	}, nil
+}
+
+// handleProveWatchOnlyChainTipInclusion implements the handleprovewatchonly command.
+func handleProveWatchOnlyChainTipInclusion(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (
+	interface{}, error) {
+
+	c := cmd.(*btcjson.ProveWatchOnlyChainTipInclusionCmd)
+
+	if s.cfg.WatchOnlyWallet == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Watch only wallet must be enabled (--watchonlywallet)",
+		}
+	}
+
+	proof := s.cfg.WatchOnlyWallet.GetProof()
+
+	if *c.Verbosity == 0 {
+		return proof.String(), nil
+	}
+
+	// Convert the hashes to string.
+	proofString := make([]string, 0, len(proof.AccProof.Proof))
+	for _, singleProof := range proof.AccProof.Proof {
+		// Convert to chainhash.Hash to access the String() method.
+		chainHash := chainhash.Hash(singleProof)
+		proofString = append(proofString, chainHash.String())
+	}
+
+	hashesProvenString := make([]string, 0, len(proof.HashesProven))
+	for _, singleHash := range proof.HashesProven {
+		// Convert to chainhash.Hash to access the String() method.
+		chainHash := chainhash.Hash(singleHash)
+		hashesProvenString = append(hashesProvenString, chainHash.String())
+	}
+
+	proveReply := &btcjson.ProveUtxoChainTipInclusionVerboseResult{
+		ProvedAtHash: proof.ProvedAtHash.String(),
+		ProofHashes:  proofString,
+		ProofTargets: proof.AccProof.Targets,
 		HashesProven: hashesProvenString,
 		Hex:          proof.String(),
 	}
@@ -3900,16 +6130,8 @@ func sendTxToMempoolSpace(rawTx string, chainParams *chaincfg.Params) (string, e
 }
 
 // handleRebroadcastUnconfirmedBDKTxs implements the rebroadcastunconfirmedbdktxs command.
-func handleRebroadcastUnconfirmedBDKTxs(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
-		}
-	}
-
-	allTxs, err := s.cfg.BDKWallet.Wallet.Transactions()
+func handleRebroadcastUnconfirmedBDKTxs(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	allTxs, err := bdkWallet.Wallet.Transactions()
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
@@ -4002,181 +6224,694 @@ func handleRegisterAddressesToWatchOnlyWallet(s *rpcServer, cmd interface{}, clo
 	return nil, nil
 }
 
-// handleSearchRawTransactions implements the searchrawtransactions command.
-func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Respond with an error if the address index is not enabled.
-	addrIndex := s.cfg.AddrIndex
-	if addrIndex == nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "Address index must be enabled (--addrindex)",
-		}
-	}
+// scanBlocksState houses the state of the scan of the scanblocks command, of
+// which only one may be in progress at a time.
+type scanBlocksState struct {
+	sync.Mutex
+	active        bool
+	abort         bool
+	startHeight   int32
+	stopHeight    int32
+	currentHeight int32
+}
 
-	// Override the flag for including extra previous output information in
-	// each input if needed.
-	c := cmd.(*btcjson.SearchRawTransactionsCmd)
-	vinExtra := false
-	if c.VinExtra != nil {
-		vinExtra = *c.VinExtra != 0
-	}
+// blockHasScripts returns whether any of the outputs of the block or any of
+// the outputs spent by the block pay to one of the scripts.
+func blockHasScripts(s *rpcServer, hash *chainhash.Hash,
+	scripts map[string]struct{}) (bool, error) {
 
-	// Including the extra previous output information requires the
-	// transaction index.  Currently the address index relies on the
-	// transaction index, so this check is redundant, but it's better to be
-	// safe in case the address index is ever changed to not rely on it.
-	if vinExtra && s.cfg.TxIndex == nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "Transaction index must be enabled (--txindex)",
+	block, err := s.cfg.Chain.BlockByHash(hash)
+	if err != nil {
+		return false, err
+	}
+	for _, tx := range block.MsgBlock().Transactions {
+		for _, txOut := range tx.TxOut {
+			if _, ok := scripts[string(txOut.PkScript)]; ok {
+				return true, nil
+			}
 		}
 	}
 
-	// Attempt to decode the supplied address.
-	params := s.cfg.ChainParams
-	addr, err := btcutil.DecodeAddress(c.Address, params)
+	stxos, err := s.cfg.Chain.FetchSpendJournal(block)
 	if err != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidAddressOrKey,
-			Message: "Invalid address or key: " + err.Error(),
-		}
+		return false, err
 	}
-
-	// Override the default number of requested entries if needed.  Also,
-	// just return now if the number of requested entries is zero to avoid
-	// extra work.
-	numRequested := 100
-	if c.Count != nil {
-		numRequested = *c.Count
-		if numRequested < 0 {
-			numRequested = 1
+	for _, stxo := range stxos {
+		if _, ok := scripts[string(stxo.PkScript)]; ok {
+			return true, nil
 		}
 	}
-	if numRequested == 0 {
-		return nil, nil
-	}
 
-	// Override the default number of entries to skip if needed.
-	var numToSkip int
-	if c.Skip != nil {
-		numToSkip = *c.Skip
-		if numToSkip < 0 {
-			numToSkip = 0
+	return false, nil
+}
+
+// handleRestoreWallet implements the restorewallet command.
+func handleRestoreWallet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Before doing anything, check that the bdk wallet is active.
+	if s.cfg.BDKWallets == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "BDK wallet must be enabled. (--bdkwallet)",
 		}
 	}
 
-	// Override the reverse flag if needed.
-	var reverse bool
-	if c.Reverse != nil {
-		reverse = *c.Reverse
-	}
+	c := cmd.(*btcjson.RestoreWalletCmd)
 
-	// Add transactions from mempool first if client asked for reverse
-	// order.  Otherwise, they will be added last (as needed depending on
-	// the requested counts).
-	//
-	// NOTE: This code doesn't sort by dependency.  This might be something
-	// to do in the future for the client's convenience, or leave it to the
-	// client.
-	numSkipped := uint32(0)
-	addressTxns := make([]retrievedTx, 0, numRequested)
-	if reverse {
-		// Transactions in the mempool are not in a block header yet,
-		// so the block header field in the retieved transaction struct
-		// is left nil.
-		mpTxns, mpSkipped := fetchMempoolTxnsForAddress(s, addr,
-			uint32(numToSkip), uint32(numRequested))
-		numSkipped += mpSkipped
-		for _, tx := range mpTxns {
-			addressTxns = append(addressTxns, retrievedTx{tx: tx})
-		}
+	var passphrase string
+	if c.Passphrase != nil {
+		passphrase = *c.Passphrase
+	}
+	bdkWallet, err := s.cfg.BDKWallets.Restore(c.WalletName,
+		backupPath(c.BackupFile), passphrase)
+	if err != nil {
+		return nil, bdkWalletsError(err)
 	}
 
-	// Fetch transactions from the database in the desired order if more are
-	// needed.
-	if len(addressTxns) < numRequested {
-		err = s.cfg.DB.View(func(dbTx database.Tx) error {
-			regions, dbSkipped, err := addrIndex.TxRegionsForAddress(
-				dbTx, addr, uint32(numToSkip)-numSkipped,
-				uint32(numRequested-len(addressTxns)), reverse)
-			if err != nil {
-				return err
-			}
-
-			// Load the raw transaction bytes from the database.
-			serializedTxns, err := dbTx.FetchBlockRegions(regions)
-			if err != nil {
-				return err
-			}
+	return btcjson.RestoreWalletResult{Name: bdkWallet.Name()}, nil
+}
 
-			// Add the transaction and the hash of the block it is
-			// contained in to the list.  Note that the transaction
-			// is left serialized here since the caller might have
-			// requested non-verbose output and hence there would be
-			// no point in deserializing it just to reserialize it
-			// later.
-			for i, serializedTx := range serializedTxns {
-				addressTxns = append(addressTxns, retrievedTx{
-					txBytes: serializedTx,
-					blkHash: regions[i].Hash,
-				})
-			}
-			numSkipped += dbSkipped
+// handleScanBlocks implements the scanblocks command.
+func handleScanBlocks(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ScanBlocksCmd)
 
-			return nil
-		})
-		if err != nil {
-			context := "Failed to load address index entries"
-			return nil, internalRPCError(err.Error(), context)
+	state := &s.scanBlocks
+	switch c.Action {
+	case btcjson.ScanBlocksActionStatus:
+		state.Lock()
+		defer state.Unlock()
+		if !state.active {
+			return nil, nil
 		}
 
-	}
+		var progress int
+		if state.stopHeight > state.startHeight {
+			progress = int(100 * int64(state.currentHeight-state.startHeight) /
+				int64(state.stopHeight-state.startHeight))
+		}
+		return &btcjson.ScanBlocksStatusResult{
+			Progress:      progress,
+			CurrentHeight: state.currentHeight,
+		}, nil
 
-	// Add transactions from mempool last if client did not request reverse
-	// order and the number of results is still under the number requested.
-	if !reverse && len(addressTxns) < numRequested {
-		// Transactions in the mempool are not in a block header yet,
-		// so the block header field in the retieved transaction struct
-		// is left nil.
-		mpTxns, mpSkipped := fetchMempoolTxnsForAddress(s, addr,
-			uint32(numToSkip)-numSkipped, uint32(numRequested-
-				len(addressTxns)))
-		numSkipped += mpSkipped
-		for _, tx := range mpTxns {
-			addressTxns = append(addressTxns, retrievedTx{tx: tx})
+	case btcjson.ScanBlocksActionAbort:
+		state.Lock()
+		defer state.Unlock()
+		if !state.active {
+			return false, nil
 		}
+		state.abort = true
+		return true, nil
+
+	case btcjson.ScanBlocksActionStart:
+
+	default:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("Invalid action '%s'", c.Action))
 	}
 
-	// Address has never been used if neither source yielded any results.
-	if len(addressTxns) == 0 {
+	if s.cfg.CfIndex == nil {
 		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCNoTxInfo,
-			Message: "No information available about address",
+			Code:    btcjson.ErrRPCNoCFIndex,
+			Message: "The CF index must be enabled for this command",
 		}
 	}
+	if c.FilterType != nil && *c.FilterType != btcjson.FilterTypeBasic {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("Unknown filtertype '%s'", *c.FilterType))
+	}
+	if c.ScanObjects == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"scanobjects argument is required for the start action")
+	}
 
-	// Serialize all of the transactions to hex.
-	hexTxns := make([]string, len(addressTxns))
-	for i := range addressTxns {
-		// Simply encode the raw bytes to hex when the retrieved
-		// transaction is already in serialized form.
-		rtx := &addressTxns[i]
-		if rtx.txBytes != nil {
-			hexTxns[i] = hex.EncodeToString(rtx.txBytes)
-			continue
-		}
-
-		// Serialize the transaction first and convert to hex when the
-		// retrieved transaction is the deserialized structure.
-		hexTxns[i], err = messageToHex(rtx.tx.MsgTx())
+	var scripts [][]byte
+	scriptSet := make(map[string]struct{}, len(*c.ScanObjects))
+	for _, desc := range *c.ScanObjects {
+		expanded, err := expandDescriptor(desc, 0,
+			defaultDescriptorRange-1, s.cfg.ChainParams)
 		if err != nil {
 			return nil, err
 		}
+		for _, script := range expanded {
+			scripts = append(scripts, script.script)
+			scriptSet[string(script.script)] = struct{}{}
+		}
 	}
 
-	// When not in verbose mode, simply return a list of serialized txns.
-	if c.Verbose != nil && *c.Verbose == 0 {
-		return hexTxns, nil
+	best := s.cfg.Chain.BestSnapshot()
+	startHeight := *c.StartHeight
+	if startHeight < 0 || startHeight > best.Height {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Invalid start_height")
+	}
+	stopHeight := best.Height
+	if c.StopHeight != nil {
+		stopHeight = *c.StopHeight
+		if stopHeight < startHeight || stopHeight > best.Height {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Invalid stop_height")
+		}
+	}
+	filterFalsePositives := c.Options != nil && c.Options.FilterFalsePositives
+
+	state.Lock()
+	if state.active {
+		state.Unlock()
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "Scan already in progress, use action \"abort\" " +
+				"or \"status\"",
+		}
+	}
+	state.active = true
+	state.abort = false
+	state.startHeight = startHeight
+	state.stopHeight = stopHeight
+	state.currentHeight = startHeight
+	state.Unlock()
+	defer func() {
+		state.Lock()
+		state.active = false
+		state.Unlock()
+	}()
+
+	// Match the filters of the blocks in batches so that the scan can be
+	// aborted in between.
+	const batchSize = 1000
+	reply := &btcjson.ScanBlocksResult{
+		FromHeight:     startHeight,
+		ToHeight:       startHeight,
+		RelevantBlocks: []string{},
+		Completed:      true,
+	}
+	for height := startHeight; height <= stopHeight; height += batchSize {
+		state.Lock()
+		aborted := state.abort
+		state.currentHeight = height
+		state.Unlock()
+		select {
+		case <-closeChan:
+			aborted = true
+		default:
+		}
+		if aborted {
+			reply.Completed = false
+			break
+		}
+
+		endHeight := height + batchSize
+		if endHeight > stopHeight+1 {
+			endHeight = stopHeight + 1
+		}
+		hashes, err := s.cfg.Chain.HeightRange(height, endHeight)
+		if err != nil {
+			context := "Failed to fetch block hashes"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		hashPtrs := make([]*chainhash.Hash, len(hashes))
+		for i := range hashes {
+			hashPtrs[i] = &hashes[i]
+		}
+		filters, err := s.cfg.CfIndex.FiltersByBlockHashes(hashPtrs,
+			wire.GCSFilterRegular)
+		if err != nil {
+			context := "Failed to fetch block filters"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		for i, filterBytes := range filters {
+			if len(filterBytes) == 0 {
+				return nil, internalRPCError("Filter not found "+
+					"for block "+hashes[i].String(), "")
+			}
+			filter, err := gcs.FromNBytes(builder.DefaultP,
+				builder.DefaultM, filterBytes)
+			if err != nil {
+				context := "Failed to deserialize block filter"
+				return nil, internalRPCError(err.Error(), context)
+			}
+			if filter.N() == 0 {
+				continue
+			}
+
+			key := builder.DeriveKey(&hashes[i])
+			match, err := filter.MatchAny(key, scripts)
+			if err != nil {
+				context := "Failed to match block filter"
+				return nil, internalRPCError(err.Error(), context)
+			}
+			if match && filterFalsePositives {
+				match, err = blockHasScripts(s, &hashes[i], scriptSet)
+				if err != nil {
+					context := "Failed to check block scripts"
+					return nil, internalRPCError(err.Error(), context)
+				}
+			}
+			if match {
+				reply.RelevantBlocks = append(reply.RelevantBlocks,
+					hashes[i].String())
+			}
+		}
+		reply.ToHeight = endHeight - 1
+	}
+
+	return reply, nil
+}
+
+// scanTxOutSetState houses the state of the scan of the scantxoutset command,
+// of which only one may be in progress at a time.
+type scanTxOutSetState struct {
+	sync.Mutex
+	active    bool
+	progress  int
+	interrupt chan struct{}
+}
+
+// stop interrupts the scan in progress and returns whether there was one.
+func (state *scanTxOutSetState) stop() bool {
+	state.Lock()
+	defer state.Unlock()
+	if !state.active || state.interrupt == nil {
+		return false
+	}
+	close(state.interrupt)
+	state.interrupt = nil
+	return true
+}
+
+// descriptorRange returns the range of child indexes to derive for a ranged
+// descriptor, which defaults to the first defaultDescriptorRange children.
+func descriptorRange(r *btcjson.DescriptorRange) (uint32, uint32, error) {
+	if r == nil {
+		return 0, defaultDescriptorRange - 1, nil
+	}
+
+	var begin, end int
+	switch v := r.Value.(type) {
+	case int:
+		end = v
+	case []int:
+		begin, end = v[0], v[1]
+	default:
+		return 0, 0, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Range must be specified as end or as [begin,end]")
+	}
+
+	switch {
+	case begin < 0:
+		return 0, 0, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Range should be greater or equal than 0")
+	case end < begin:
+		return 0, 0, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Range specified as [begin,end] must not have begin after end")
+	case end >= math.MaxInt32:
+		return 0, 0, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"End of range is too high")
+	case end-begin >= maxDescriptorRange:
+		return 0, 0, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Range is too large")
+	}
+
+	return uint32(begin), uint32(end), nil
+}
+
+// handleScanTxOutSet implements the scantxoutset command.
+func handleScanTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ScanTxOutSetCmd)
+
+	state := &s.scanTxOutSet
+	switch c.Action {
+	case btcjson.ScanTxOutSetActionStatus:
+		state.Lock()
+		defer state.Unlock()
+		if !state.active {
+			return nil, nil
+		}
+		return &btcjson.ScanTxOutSetStatusResult{
+			Progress: state.progress,
+		}, nil
+
+	case btcjson.ScanTxOutSetActionAbort:
+		return state.stop(), nil
+
+	case btcjson.ScanTxOutSetActionStart:
+
+	default:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("Invalid action '%s'", c.Action))
+	}
+
+	if s.cfg.Chain.IsUtreexoViewActive() {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "The utxo set isn't kept by compact state " +
+				"nodes (--noutreexo)",
+		}
+	}
+	if *c.IncludeProof && s.cfg.UtreexoProofIndex == nil &&
+		s.cfg.FlatUtreexoProofIndex == nil {
+
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "A utreexo proof index must be enabled. " +
+				"(--utreexoproofindex) or (--flatutreexoproofindex).",
+		}
+	}
+	if c.ScanObjects == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"scanobjects argument is required for the start action")
+	}
+
+	// Expand the descriptors into the scripts to scan for and remember
+	// the descriptors of the scripts for the result.
+	descs := make(map[string]string)
+	for _, obj := range *c.ScanObjects {
+		rangeBegin, rangeEnd, err := descriptorRange(obj.Range)
+		if err != nil {
+			return nil, err
+		}
+		expanded, err := expandDescriptor(obj.Desc, rangeBegin,
+			rangeEnd, s.cfg.ChainParams)
+		if err != nil {
+			return nil, err
+		}
+		for _, script := range expanded {
+			descs[string(script.script)] = script.desc
+		}
+	}
+	scripts := make(map[string]struct{}, len(descs))
+	for script := range descs {
+		scripts[script] = struct{}{}
+	}
+
+	state.Lock()
+	if state.active {
+		state.Unlock()
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "Scan already in progress, use action \"abort\" " +
+				"or \"status\"",
+		}
+	}
+	interrupt := make(chan struct{})
+	state.active = true
+	state.progress = 0
+	state.interrupt = interrupt
+	state.Unlock()
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		state.Lock()
+		state.active = false
+		state.interrupt = nil
+		state.Unlock()
+	}()
+
+	// Interrupt the scan when the client goes away.
+	go func() {
+		select {
+		case <-closeChan:
+			state.stop()
+		case <-done:
+		}
+	}()
+
+	scan, err := s.cfg.Chain.ScanUtxoSet(scripts, func(progress int) {
+		state.Lock()
+		state.progress = progress
+		state.Unlock()
+	}, interrupt)
+	if err != nil {
+		select {
+		case <-interrupt:
+			return &btcjson.ScanTxOutSetResult{
+				Success:  false,
+				Unspents: []btcjson.ScanTxOutSetUnspent{},
+			}, nil
+		default:
+		}
+		context := "Failed to scan the utxo set"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	reply := &btcjson.ScanTxOutSetResult{
+		Success:   true,
+		TxOuts:    scan.TxOuts,
+		Height:    scan.Height,
+		BestBlock: scan.Hash.String(),
+		Unspents:  make([]btcjson.ScanTxOutSetUnspent, 0, len(scan.Utxos)),
+	}
+	var totalAmount int64
+	for _, utxo := range scan.Utxos {
+		entry := utxo.Entry
+		blockHash, err := s.cfg.Chain.BlockHashByHeight(entry.BlockHeight())
+		if err != nil {
+			context := "Failed to fetch the block hash"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		reply.Unspents = append(reply.Unspents, btcjson.ScanTxOutSetUnspent{
+			TxID:          utxo.OutPoint.Hash.String(),
+			Vout:          utxo.OutPoint.Index,
+			ScriptPubKey:  hex.EncodeToString(entry.PkScript()),
+			Desc:          descs[string(entry.PkScript())],
+			Amount:        btcutil.Amount(entry.Amount()).ToBTC(),
+			Coinbase:      entry.IsCoinBase(),
+			Height:        entry.BlockHeight(),
+			BlockHash:     blockHash.String(),
+			Confirmations: int64(scan.Height-entry.BlockHeight()) + 1,
+		})
+		totalAmount += entry.Amount()
+	}
+	reply.TotalAmount = btcutil.Amount(totalAmount).ToBTC()
+
+	// The proof is against the accumulator at the chain tip, so it's only
+	// valid for the scanned outputs if no blocks were connected since.
+	if *c.IncludeProof && len(scan.Utxos) > 0 {
+		utxos := make([]*blockchain.UtxoEntry, 0, len(scan.Utxos))
+		outpoints := make([]wire.OutPoint, 0, len(scan.Utxos))
+		for _, utxo := range scan.Utxos {
+			utxos = append(utxos, utxo.Entry)
+			outpoints = append(outpoints, utxo.OutPoint)
+		}
+		proof, err := proveUtxoEntries(s, utxos, outpoints)
+		if err == nil && !proof.ProvedAtHash.IsEqual(&scan.Hash) {
+			err = fmt.Errorf("the chain tip changed during the scan")
+		}
+		if err != nil {
+			context := "Failed to prove the unspent outputs"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		reply.Proof = proof.String()
+	}
+
+	return reply, nil
+}
+
+// handleSearchRawTransactions implements the searchrawtransactions command.
+func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the address index is not enabled.
+	addrIndex := s.cfg.AddrIndex
+	if addrIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Address index must be enabled (--addrindex)",
+		}
+	}
+
+	// Override the flag for including extra previous output information in
+	// each input if needed.
+	c := cmd.(*btcjson.SearchRawTransactionsCmd)
+	vinExtra := false
+	if c.VinExtra != nil {
+		vinExtra = *c.VinExtra != 0
+	}
+
+	// Including the extra previous output information requires the
+	// transaction index.  Currently the address index relies on the
+	// transaction index, so this check is redundant, but it's better to be
+	// safe in case the address index is ever changed to not rely on it.
+	if vinExtra && s.cfg.TxIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Transaction index must be enabled (--txindex)",
+		}
+	}
+
+	// Attempt to decode the supplied address.
+	params := s.cfg.ChainParams
+	addr, err := btcutil.DecodeAddress(c.Address, params)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid address or key: " + err.Error(),
+		}
+	}
+
+	// Override the default number of requested entries if needed.  Also,
+	// just return now if the number of requested entries is zero to avoid
+	// extra work.
+	numRequested := 100
+	if c.Count != nil {
+		numRequested = *c.Count
+		if numRequested < 0 {
+			numRequested = 1
+		}
+	}
+	if numRequested == 0 {
+		return nil, nil
+	}
+
+	// Override the default number of entries to skip if needed.
+	var numToSkip int
+	if c.Skip != nil {
+		numToSkip = *c.Skip
+		if numToSkip < 0 {
+			numToSkip = 0
+		}
+	}
+
+	// Override the reverse flag if needed.
+	var reverse bool
+	if c.Reverse != nil {
+		reverse = *c.Reverse
+	}
+
+	// Restrict the transactions to the requested range of block heights
+	// if needed.  Transactions in the mempool are considered to be above
+	// every block, so they're only included when there's no maximum height.
+	minHeight, maxHeight := int32(0), int32(math.MaxInt32)
+	if c.MinHeight != nil {
+		minHeight = *c.MinHeight
+	}
+	if c.MaxHeight != nil {
+		maxHeight = *c.MaxHeight
+	}
+	if minHeight < 0 || maxHeight < minHeight {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Invalid height range %d to %d",
+				minHeight, maxHeight),
+		}
+	}
+	heightFiltered := c.MinHeight != nil || c.MaxHeight != nil
+	includeMempool := c.MaxHeight == nil
+
+	// Add transactions from mempool first if client asked for reverse
+	// order.  Otherwise, they will be added last (as needed depending on
+	// the requested counts).
+	//
+	// NOTE: This code doesn't sort by dependency.  This might be something
+	// to do in the future for the client's convenience, or leave it to the
+	// client.
+	numSkipped := uint32(0)
+	addressTxns := make([]retrievedTx, 0, numRequested)
+	if reverse && includeMempool {
+		// Transactions in the mempool are not in a block header yet,
+		// so the block header field in the retieved transaction struct
+		// is left nil.
+		mpTxns, mpSkipped := fetchMempoolTxnsForAddress(s, addr,
+			uint32(numToSkip), uint32(numRequested))
+		numSkipped += mpSkipped
+		for _, tx := range mpTxns {
+			addressTxns = append(addressTxns, retrievedTx{tx: tx})
+		}
+	}
+
+	// Fetch transactions from the database in the desired order if more are
+	// needed.
+	if len(addressTxns) < numRequested {
+		err = s.cfg.DB.View(func(dbTx database.Tx) error {
+			var regions []database.BlockRegion
+			var dbSkipped uint32
+			var err error
+			if heightFiltered {
+				regions, dbSkipped, err = addrIndex.TxRegionsForAddressInRange(
+					dbTx, addr, minHeight, maxHeight,
+					uint32(numToSkip)-numSkipped,
+					uint32(numRequested-len(addressTxns)), reverse)
+			} else {
+				regions, dbSkipped, err = addrIndex.TxRegionsForAddress(
+					dbTx, addr, uint32(numToSkip)-numSkipped,
+					uint32(numRequested-len(addressTxns)), reverse)
+			}
+			if err != nil {
+				return err
+			}
+
+			// Load the raw transaction bytes from the database.
+			serializedTxns, err := dbTx.FetchBlockRegions(regions)
+			if err != nil {
+				return err
+			}
+
+			// Add the transaction and the hash of the block it is
+			// contained in to the list.  Note that the transaction
+			// is left serialized here since the caller might have
+			// requested non-verbose output and hence there would be
+			// no point in deserializing it just to reserialize it
+			// later.
+			for i, serializedTx := range serializedTxns {
+				addressTxns = append(addressTxns, retrievedTx{
+					txBytes: serializedTx,
+					blkHash: regions[i].Hash,
+				})
+			}
+			numSkipped += dbSkipped
+
+			return nil
+		})
+		if err != nil {
+			context := "Failed to load address index entries"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+	}
+
+	// Add transactions from mempool last if client did not request reverse
+	// order and the number of results is still under the number requested.
+	if !reverse && includeMempool && len(addressTxns) < numRequested {
+		// Transactions in the mempool are not in a block header yet,
+		// so the block header field in the retieved transaction struct
+		// is left nil.
+		mpTxns, mpSkipped := fetchMempoolTxnsForAddress(s, addr,
+			uint32(numToSkip)-numSkipped, uint32(numRequested-
+				len(addressTxns)))
+		numSkipped += mpSkipped
+		for _, tx := range mpTxns {
+			addressTxns = append(addressTxns, retrievedTx{tx: tx})
+		}
+	}
+
+	// Address has never been used if neither source yielded any results.
+	if len(addressTxns) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoTxInfo,
+			Message: "No information available about address",
+		}
+	}
+
+	// Serialize all of the transactions to hex.
+	hexTxns := make([]string, len(addressTxns))
+	for i := range addressTxns {
+		// Simply encode the raw bytes to hex when the retrieved
+		// transaction is already in serialized form.
+		rtx := &addressTxns[i]
+		if rtx.txBytes != nil {
+			hexTxns[i] = hex.EncodeToString(rtx.txBytes)
+			continue
+		}
+
+		// Serialize the transaction first and convert to hex when the
+		// retrieved transaction is the deserialized structure.
+		hexTxns[i], err = messageToHex(rtx.tx.MsgTx())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// When not in verbose mode, simply return a list of serialized txns.
+	if c.Verbose != nil && *c.Verbose == 0 {
+		return hexTxns, nil
 	}
 
 	// Normalize the provided filter addresses (if any) to ensure there are
@@ -4330,22 +7065,100 @@ func (s *rpcServer) rpcProcessTx(tx *btcutil.Tx, allowOrphan, rateLimit bool) er
 		return internalRPCError(errStr, "")
 	}
 
-	// Generate and relay inventory vectors for all newly accepted
-	// transactions into the memory pool due to the original being
-	// accepted.
-	s.cfg.ConnMgr.RelayTransactions(acceptedTxs)
-
-	// Notify both websocket and getblocktemplate long poll clients of all
-	// newly accepted transactions.
-	s.NotifyNewTransactions(acceptedTxs)
-
-	// Keep track of all the txns so that they can be rebroadcast if they
-	// don't make their way into a block.
-	txD := acceptedTxs[0]
-	iv := wire.NewInvVect(wire.InvTypeTx, txD.Tx.Hash())
-	s.cfg.ConnMgr.AddRebroadcastInventory(iv, txD)
+	// Generate and relay inventory vectors for all newly accepted
+	// transactions into the memory pool due to the original being
+	// accepted.
+	s.cfg.ConnMgr.RelayTransactions(acceptedTxs)
+
+	// Notify both websocket and getblocktemplate long poll clients of all
+	// newly accepted transactions.
+	s.NotifyNewTransactions(acceptedTxs)
+
+	// Keep track of all the txns so that they can be rebroadcast if they
+	// don't make their way into a block.
+	txD := acceptedTxs[0]
+	iv := wire.NewInvVect(wire.InvTypeTx, txD.Tx.Hash())
+	s.cfg.ConnMgr.AddRebroadcastInventory(iv, txD)
+
+	return nil
+}
+
+// handleSendAll implements the sendall command.
+func handleSendAll(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SendAllCmd)
+	if len(c.Recipients) != 1 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Exactly one recipient must be given",
+		}
+	}
+	if bdkwallet.IsSilentPaymentAddress(c.Recipients[0], s.cfg.ChainParams) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Sweeping to silent payment addresses is not supported",
+		}
+	}
+	addr, err := btcutil.DecodeAddress(c.Recipients[0], s.cfg.ChainParams)
+	if err != nil || !addr.IsForNet(s.cfg.ChainParams) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid address: " + c.Recipients[0],
+		}
+	}
+
+	var feeRate *float64
+	var inputs []wire.OutPoint
+	confTarget := int64(6)
+	conservative := true
+	if opts := c.Options; opts != nil {
+		if opts.FeeRate != nil && (opts.ConfTarget != nil || opts.EstimateMode != nil) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "Cannot specify both conf_target or " +
+					"estimate_mode and fee_rate",
+			}
+		}
+		feeRate = opts.FeeRate
+		if opts.ConfTarget != nil {
+			confTarget = *opts.ConfTarget
+		}
+		if opts.EstimateMode != nil {
+			mode := strings.ToUpper(*opts.EstimateMode)
+			conservative, err = estimateModeConservative(
+				btcjson.EstimateSmartFeeMode(mode))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if opts.Inputs != nil {
+			inputs, err = decodeOutPoints(*opts.Inputs)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	rate, err := bdkFeeRate(s, feeRate, confTarget, conservative)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, fee, err := bdkWallet.SendAll(rate, addr, inputs)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: fmt.Sprintf("Failed to sweep the wallet. %v", err),
+		}
+	}
+	if err := broadcastBDKTx(s, tx); err != nil {
+		return nil, err
+	}
 
-	return nil
+	return btcjson.SendAllResult{
+		Complete: true,
+		TxID:     tx.Hash().String(),
+		Hex:      txHexString(tx.MsgTx()),
+		Fee:      fee.ToBTC(),
+	}, nil
 }
 
 // handleSendRawTransaction implements the sendrawtransaction command.
@@ -4379,6 +7192,57 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 	return tx.Hash().String(), nil
 }
 
+// handleSetBan implements the setban command.
+func handleSetBan(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SetBanCmd)
+
+	var err error
+	switch c.SubCmd {
+	case btcjson.SBAdd:
+		var banTime int64
+		if c.BanTime != nil {
+			banTime = *c.BanTime
+		}
+		absolute := c.Absolute != nil && *c.Absolute
+
+		// Without a ban time, the peer is banned for as long as
+		// misbehaving peers are.
+		until := time.Now().Add(cfg.BanDuration)
+		switch {
+		case banTime > 0 && absolute:
+			until = time.Unix(banTime, 0)
+		case banTime > 0:
+			until = time.Now().Add(time.Duration(banTime) * time.Second)
+		}
+		if !until.After(time.Now()) {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "the absolute ban time is in the past",
+			}
+		}
+		err = s.cfg.ConnMgr.Ban(c.Subnet, until)
+
+	case btcjson.SBRemove:
+		err = s.cfg.ConnMgr.Unban(c.Subnet)
+
+	default:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "invalid subcommand for setban",
+		}
+	}
+
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCClientInvalidIPOrSubnet,
+			Message: err.Error(),
+		}
+	}
+
+	// no data returned unless an error.
+	return nil, nil
+}
+
 // handleSetGenerate implements the setgenerate command.
 func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SetGenerateCmd)
@@ -4419,6 +7283,40 @@ func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 // inadvertently signing a transaction.
 const messageSignatureHeader = "Bitcoin Signed Message:\n"
 
+// handleSetLabel implements the setlabel command.
+func handleSetLabel(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SetLabelCmd)
+
+	// A reference that is a hash is the txid of a transaction and is
+	// otherwise an address.
+	var err error
+	if len(c.Reference) == chainhash.MaxHashStringSize {
+		var txid *chainhash.Hash
+		txid, err = chainhash.NewHashFromStr(c.Reference)
+		if err != nil {
+			return nil, rpcDecodeHexError(c.Reference)
+		}
+		err = bdkWallet.SetTxLabel(*txid, c.Label)
+	} else {
+		addr, decodeErr := btcutil.DecodeAddress(c.Reference, s.cfg.ChainParams)
+		if decodeErr != nil || !addr.IsForNet(s.cfg.ChainParams) {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid address or txid: " + c.Reference,
+			}
+		}
+		err = bdkWallet.SetAddressLabel(addr, c.Label)
+	}
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: "Unable to set label: " + err.Error(),
+		}
+	}
+
+	return nil, nil
+}
+
 // handleSignMessageWithPrivKey implements the signmessagewithprivkey command.
 func handleSignMessageWithPrivKey(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SignMessageWithPrivKeyCmd)
@@ -4449,84 +7347,346 @@ func handleSignMessageWithPrivKey(s *rpcServer, cmd interface{}, closeChan <-cha
 	wire.WriteVarString(&buf, 0, c.Message)
 	messageHash := chainhash.DoubleHashB(buf.Bytes())
 
-	sig, err := ecdsa.SignCompact(wif.PrivKey, messageHash, wif.CompressPubKey)
-	if err != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidAddressOrKey,
-			Message: "Sign failed",
+	sig, err := ecdsa.SignCompact(wif.PrivKey, messageHash, wif.CompressPubKey)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Sign failed",
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// handleStop implements the stop command.
+func handleStop(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	select {
+	case s.requestProcessShutdown <- struct{}{}:
+	default:
+	}
+	return "btcd stopping.", nil
+}
+
+// handleSubmitBlock implements the submitblock command.
+func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SubmitBlockCmd)
+
+	// Deserialize the submitted block.
+	hexStr := c.HexBlock
+	if len(hexStr)%2 != 0 {
+		hexStr = "0" + c.HexBlock
+	}
+	serializedBlock, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+
+	block, err := btcutil.NewBlockFromBytes(serializedBlock)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "Block decode failed: " + err.Error(),
+		}
+	}
+
+	// Process this block using the same rules as blocks coming from other
+	// nodes.  This will in turn relay it to the network like normal.
+	_, err = s.cfg.SyncMgr.SubmitBlock(block, blockchain.BFNone)
+	if err != nil {
+		return fmt.Sprintf("rejected: %s", err.Error()), nil
+	}
+
+	rpcsLog.Infof("Accepted block %s via submitblock", block.Hash())
+	return nil, nil
+}
+
+// handleSubmitPackage implements the submitpackage command.
+func handleSubmitPackage(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SubmitPackageCmd)
+
+	// Deserialize the transactions of the package.
+	txs := make([]*btcutil.Tx, 0, len(c.RawTxs))
+	for _, hexStr := range c.RawTxs {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		serializedTx, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		var msgTx wire.MsgTx
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDeserialization,
+				Message: "TX decode failed: " + err.Error(),
+			}
+		}
+		txs = append(txs, btcutil.NewTx(&msgTx))
+	}
+
+	results, acceptedTxs, err := s.cfg.TxMemPool.ProcessPackage(txs)
+	if err != nil {
+		if _, ok := err.(mempool.RuleError); !ok {
+			rpcsLog.Errorf("Failed to process package: %v", err)
+		}
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Package rejected: " + err.Error(),
+		}
+	}
+
+	reply := btcjson.SubmitPackageResult{
+		PackageMsg:           "success",
+		TxResults:            make(map[string]btcjson.SubmitPackageTxResult, len(results)),
+		ReplacedTransactions: []string{},
+	}
+	for _, result := range results {
+		txResult := btcjson.SubmitPackageTxResult{
+			Txid: result.Tx.Hash().String(),
+		}
+		if result.Err != nil {
+			rpcsLog.Debugf("Rejected transaction %v of package: %v",
+				result.Tx.Hash(), result.Err)
+
+			reply.PackageMsg = "transaction failed"
+			txResult.Error = result.Err.Error()
+			reply.TxResults[result.Tx.WitnessHash().String()] = txResult
+			continue
+		}
+
+		txResult.Vsize = mempool.GetTxVirtualSize(result.Tx)
+		txResult.Fees = &btcjson.SubmitPackageFeesResult{
+			Base: btcutil.Amount(result.TxDesc.Fee).ToBTC(),
+		}
+		if !result.AlreadyInPool {
+			feeRate := btcutil.Amount(result.EffectiveFeePerKB).ToBTC()
+			txResult.Fees.EffectiveFeeRate = &feeRate
+			for _, tx := range result.EffectiveIncludes {
+				txResult.Fees.EffectiveIncludes = append(
+					txResult.Fees.EffectiveIncludes,
+					tx.WitnessHash().String())
+			}
+
+			// Keep track of the transaction so that it can be
+			// rebroadcast if it doesn't make its way into a block.
+			iv := wire.NewInvVect(wire.InvTypeTx, result.Tx.Hash())
+			s.cfg.ConnMgr.AddRebroadcastInventory(iv, result.TxDesc)
+		}
+		reply.TxResults[result.Tx.WitnessHash().String()] = txResult
+	}
+
+	if len(acceptedTxs) > 0 {
+		// Generate and relay inventory vectors for all newly accepted
+		// transactions into the memory pool.
+		s.cfg.ConnMgr.RelayTransactions(acceptedTxs)
+
+		// Notify both websocket and getblocktemplate long poll clients
+		// of all newly accepted transactions.
+		s.NotifyNewTransactions(acceptedTxs)
+	}
+
+	return reply, nil
+}
+
+// handleTestMempoolAccept implements the testmempoolaccept command.
+func handleTestMempoolAccept(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.TestMempoolAcceptCmd)
+
+	if len(c.RawTxs) == 0 || len(c.RawTxs) > mempool.MaxPackageCount {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Array must contain between 1 and "+
+				"%d transactions.", mempool.MaxPackageCount),
+		}
+	}
+
+	// Deserialize the transactions to check.
+	txs := make([]*btcutil.Tx, 0, len(c.RawTxs))
+	for _, hexStr := range c.RawTxs {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		serializedTx, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		var msgTx wire.MsgTx
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDeserialization,
+				Message: "TX decode failed: " + err.Error(),
+			}
+		}
+		txs = append(txs, btcutil.NewTx(&msgTx))
+	}
+
+	var maxFeePerKB btcutil.Amount
+	if c.MaxFeeRate != nil && *c.MaxFeeRate > 0 {
+		var err error
+		maxFeePerKB, err = btcutil.NewAmount(*c.MaxFeeRate)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid maxfeerate: " + err.Error(),
+			}
+		}
+	}
+
+	results := s.cfg.TxMemPool.TestAcceptTransactions(txs)
+	reply := make([]btcjson.TestMempoolAcceptResult, 0, len(results))
+	for _, result := range results {
+		txResult := btcjson.TestMempoolAcceptResult{
+			Txid:  result.Tx.Hash().String(),
+			Wtxid: result.Tx.WitnessHash().String(),
+		}
+		switch {
+		case result.Err != nil:
+			txResult.RejectReason = result.Err.Error()
+
+		case maxFeePerKB > 0 && result.FeePerKB > int64(maxFeePerKB):
+			txResult.RejectReason = "max-fee-exceeded"
+
+		default:
+			txResult.Allowed = true
+			txResult.Vsize = mempool.GetTxVirtualSize(result.Tx)
+			txResult.Fees = &btcjson.TestMempoolAcceptFeesResult{
+				Base:              btcutil.Amount(result.Fee).ToBTC(),
+				EffectiveFeeRate:  btcutil.Amount(result.FeePerKB).ToBTC(),
+				EffectiveIncludes: []string{txResult.Wtxid},
+			}
+		}
+		reply = append(reply, txResult)
+	}
+
+	return reply, nil
+}
+
+// handleUnloadWallet implements the unloadwallet command.  The wallet of the
+// endpoint is unloaded when no wallet name is given.
+func handleUnloadWallet(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.UnloadWalletCmd)
+	name := bdkWallet.Name()
+	if c.WalletName != nil {
+		if name != "" && *c.WalletName != name {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "RPC endpoint wallet and wallet_name " +
+					"parameter specify different wallets",
+			}
+		}
+		name = *c.WalletName
+	}
+
+	if err := s.cfg.BDKWallets.Unload(name); err != nil {
+		return nil, bdkWalletsError(err)
+	}
+	return btcjson.UnloadWalletResult{}, nil
+}
+
+// handleUnusedAddress implements the unusedaddress command.
+func handleUnusedAddress(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	index, address, err := bdkWallet.Wallet.UnusedAddress()
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Failed to retrieve unused address: " + err.Error(),
+		}
+	}
+
+	result := btcjson.BDKAddressResult{Index: int(index), Address: address.String()}
+	return result, nil
+}
+
+// handleUptime implements the uptime command.
+func handleUptime(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return time.Now().Unix() - s.cfg.StartupTime, nil
+}
+
+// fetchPsbtPrevTxs fetches the transactions of the passed outpoints from the
+// mempool and, when it's enabled, the transaction index.  The transactions
+// that can't be found are left out.
+func fetchPsbtPrevTxs(s *rpcServer, outpoints []wire.OutPoint) ([]*btcutil.Tx, error) {
+	var prevTxs []*btcutil.Tx
+	seen := make(map[chainhash.Hash]struct{}, len(outpoints))
+	for _, op := range outpoints {
+		if _, ok := seen[op.Hash]; ok {
+			continue
+		}
+		seen[op.Hash] = struct{}{}
+
+		tx, err := s.cfg.TxMemPool.FetchTransaction(&op.Hash)
+		if err == nil {
+			prevTxs = append(prevTxs, tx)
+			continue
+		}
+		if s.cfg.TxIndex == nil {
+			continue
+		}
+
+		// Look up the location of the transaction.
+		blockRegion, err := s.cfg.TxIndex.TxBlockRegion(&op.Hash)
+		if err != nil {
+			context := "Failed to retrieve transaction location"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		if blockRegion == nil {
+			continue
+		}
+
+		// Load the raw transaction bytes from the database.
+		var txBytes []byte
+		err = s.cfg.DB.View(func(dbTx database.Tx) error {
+			var err error
+			txBytes, err = dbTx.FetchBlockRegion(blockRegion)
+			return err
+		})
+		if err != nil {
+			context := "Failed to load transaction"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		tx, err = btcutil.NewTxFromBytes(txBytes)
+		if err != nil {
+			context := "Failed to deserialize transaction"
+			return nil, internalRPCError(err.Error(), context)
 		}
+		prevTxs = append(prevTxs, tx)
 	}
 
-	return base64.StdEncoding.EncodeToString(sig), nil
-}
-
-// handleStop implements the stop command.
-func handleStop(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	select {
-	case s.requestProcessShutdown <- struct{}{}:
-	default:
-	}
-	return "btcd stopping.", nil
+	return prevTxs, nil
 }
 
-// handleSubmitBlock implements the submitblock command.
-func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	c := cmd.(*btcjson.SubmitBlockCmd)
-
-	// Deserialize the submitted block.
-	hexStr := c.HexBlock
-	if len(hexStr)%2 != 0 {
-		hexStr = "0" + c.HexBlock
-	}
-	serializedBlock, err := hex.DecodeString(hexStr)
+// handleUtxoUpdatePsbt implements the utxoupdatepsbt command.
+func handleUtxoUpdatePsbt(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.UtxoUpdatePsbtCmd)
+	psbt, err := decodePsbt(c.Psbt)
 	if err != nil {
-		return nil, rpcDecodeHexError(hexStr)
+		return nil, err
 	}
 
-	block, err := btcutil.NewBlockFromBytes(serializedBlock)
+	outpoints, err := bdkWallet.Wallet.PsbtInputs(psbt)
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCDeserialization,
-			Message: "Block decode failed: " + err.Error(),
+			Message: fmt.Sprintf("Failed to update psbt. %v", err),
 		}
 	}
-
-	// Process this block using the same rules as blocks coming from other
-	// nodes.  This will in turn relay it to the network like normal.
-	_, err = s.cfg.SyncMgr.SubmitBlock(block, blockchain.BFNone)
+	prevTxs, err := fetchPsbtPrevTxs(s, outpoints)
 	if err != nil {
-		return fmt.Sprintf("rejected: %s", err.Error()), nil
-	}
-
-	rpcsLog.Infof("Accepted block %s via submitblock", block.Hash())
-	return nil, nil
-}
-
-// handleUnusedAddress implements the unusedaddress command.
-func handleUnusedAddress(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Before doing anything, check that the bdk wallet is active.
-	if s.cfg.BDKWallet == nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCMisc,
-			Message: "BDK wallet must be enabled. (--bdkwallet)",
-		}
+		return nil, err
 	}
 
-	index, address, err := s.cfg.BDKWallet.Wallet.UnusedAddress()
+	updated, err := bdkWallet.Wallet.UpdatePsbt(psbt, prevTxs)
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
-			Message: "Failed to retrieve unused address: " + err.Error(),
+			Message: fmt.Sprintf("Failed to update psbt. %v", err),
 		}
 	}
 
-	result := btcjson.BDKAddressResult{Index: int(index), Address: address.String()}
-	return result, nil
-}
-
-// handleUptime implements the uptime command.
-func handleUptime(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	return time.Now().Unix() - s.cfg.StartupTime, nil
+	return base64.StdEncoding.EncodeToString(updated), nil
 }
 
 // handleValidateAddress implements the validateaddress command.
@@ -4764,13 +7924,282 @@ func handleVerifyUtxoChainTipInclusionProof(s *rpcServer, cmd interface{}, close
 	return true, nil
 }
 
+// psbtTxOut returns the output described by an entry of an output of the
+// walletcreatefundedpsbt command.  The entry is either an address along with
+// the amount in BTC to pay to it or "data" along with the hex encoded data of
+// an OP_RETURN output.  The silent payment address is returned along with the
+// output, which has no script, when the address is one.
+func psbtTxOut(params *chaincfg.Params, key string,
+	value interface{}) (*wire.TxOut, *bdkwallet.SilentPaymentAddress, error) {
+
+	if key == "data" {
+		hexStr, ok := value.(string)
+		if !ok {
+			return nil, nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCType,
+				Message: "Data must be a hex string",
+			}
+		}
+		data, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, nil, rpcDecodeHexError(hexStr)
+		}
+		pkScript, err := txscript.NullDataScript(data)
+		if err != nil {
+			return nil, nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid data: " + err.Error(),
+			}
+		}
+		return wire.NewTxOut(0, pkScript), nil, nil
+	}
+
+	// Ensure amount is in the valid range for monetary amounts.
+	amount, ok := value.(float64)
+	if !ok || amount <= 0 || amount*btcutil.SatoshiPerBitcoin > btcutil.MaxSatoshi {
+		return nil, nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCType,
+			Message: "Invalid amount",
+		}
+	}
+
+	satoshi, err := btcutil.NewAmount(amount)
+	if err != nil {
+		context := "Failed to convert amount"
+		return nil, nil, internalRPCError(err.Error(), context)
+	}
+
+	if bdkwallet.IsSilentPaymentAddress(key, params) {
+		spAddr, err := bdkwallet.DecodeSilentPaymentAddress(key, params)
+		if err != nil {
+			return nil, nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid silent payment address: " + err.Error(),
+			}
+		}
+		return wire.NewTxOut(int64(satoshi), nil), spAddr, nil
+	}
+
+	addr, err := btcutil.DecodeAddress(key, params)
+	if err != nil {
+		return nil, nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid address or key: " + err.Error(),
+		}
+	}
+	if !addr.IsForNet(params) {
+		return nil, nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid address: " + key +
+				" is for the wrong network",
+		}
+	}
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		context := "Failed to generate pay-to-address script"
+		return nil, nil, internalRPCError(err.Error(), context)
+	}
+
+	return wire.NewTxOut(int64(satoshi), pkScript), nil, nil
+}
+
+// handleWalletCreateFundedPsbt implements the walletcreatefundedpsbt command.
+func handleWalletCreateFundedPsbt(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.WalletCreateFundedPsbtCmd)
+
+	inputs := make([]wire.OutPoint, 0, len(c.Inputs))
+	for _, input := range c.Inputs {
+		txHash, err := chainhash.NewHashFromStr(input.Txid)
+		if err != nil {
+			return nil, rpcDecodeHexError(input.Txid)
+		}
+		inputs = append(inputs, *wire.NewOutPoint(txHash, input.Vout))
+	}
+
+	// More inputs are only added by default when none are given.
+	addInputs := len(inputs) == 0
+
+	// The entries of each output are added ordered by their keys so that
+	// the outputs of the psbt don't depend on the map iteration order.
+	var outputs []*wire.TxOut
+	spAddrs := make(map[int]*bdkwallet.SilentPaymentAddress)
+	for _, output := range c.Outputs {
+		keys := make([]string, 0, len(output))
+		for key := range output {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			txOut, spAddr, err := psbtTxOut(s.cfg.ChainParams, key, output[key])
+			if err != nil {
+				return nil, err
+			}
+			if spAddr != nil {
+				spAddrs[len(outputs)] = spAddr
+			}
+			outputs = append(outputs, txOut)
+		}
+	}
+
+	var locktime uint32
+	if c.Locktime != nil {
+		locktime = *c.Locktime
+	}
+
+	// The fee rate defaults to the minimum relay fee and is given in BTC/kB
+	// while the wallet takes it in sat/vB.
+	feeRate := float32(cfg.minRelayTxFee) / 1000
+	replaceable := true
+	lockUnspents := false
+	coinSelection := bdkwallet.CoinSelectionBranchAndBound
+	var subtractFeeFrom []int
+	if opts := c.Options; opts != nil {
+		if opts.ChangeAddress != nil || opts.ChangePosition != nil ||
+			opts.ChangeType != nil || opts.IncludeWatching != nil {
+
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "The changeAddress, changePosition, change_type " +
+					"and includeWatching options are not supported",
+			}
+		}
+		if opts.FeeRate != nil && (opts.ConfTarget != nil || opts.EstimateMode != nil) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "Cannot specify both conf_target or " +
+					"estimate_mode and feeRate",
+			}
+		}
+		if opts.SubtractFeeFromOutputs != nil {
+			for _, index := range *opts.SubtractFeeFromOutputs {
+				subtractFeeFrom = append(subtractFeeFrom, int(index))
+			}
+		}
+		if opts.AddInputs != nil {
+			addInputs = *opts.AddInputs
+		}
+		if opts.LockUnspents != nil {
+			lockUnspents = *opts.LockUnspents
+		}
+		if opts.FeeRate != nil {
+			if *opts.FeeRate < 0 {
+				return nil, &btcjson.RPCError{
+					Code:    btcjson.ErrRPCInvalidParameter,
+					Message: "Fee rate must not be negative",
+				}
+			}
+			feeRate = float32(*opts.FeeRate * btcutil.SatoshiPerBitcoin / 1000)
+		}
+
+		// The fee rate is estimated instead when a confirmation target
+		// or an estimate mode is given.
+		if opts.ConfTarget != nil || opts.EstimateMode != nil {
+			confTarget := int64(6)
+			if opts.ConfTarget != nil {
+				confTarget = *opts.ConfTarget
+			}
+			conservative := true
+			if opts.EstimateMode != nil {
+				var err error
+				mode := strings.ToUpper(*opts.EstimateMode)
+				conservative, err = estimateModeConservative(
+					btcjson.EstimateSmartFeeMode(mode))
+				if err != nil {
+					return nil, err
+				}
+			}
+			var err error
+			feeRate, err = bdkFeeRate(s, nil, confTarget, conservative)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if opts.Replaceable != nil {
+			replaceable = *opts.Replaceable
+		}
+		if opts.CoinSelection != nil {
+			var err error
+			coinSelection, err = bdkwallet.ParseCoinSelection(*opts.CoinSelection)
+			if err != nil {
+				return nil, &btcjson.RPCError{
+					Code:    btcjson.ErrRPCInvalidParameter,
+					Message: err.Error(),
+				}
+			}
+		}
+	}
+
+	funded, err := bdkWallet.CreatePsbt(inputs, outputs, spAddrs,
+		subtractFeeFrom, locktime, feeRate, replaceable, addInputs,
+		coinSelection)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Failed to create psbt. %v", err),
+		}
+	}
+
+	if lockUnspents {
+		spent, err := bdkWallet.Wallet.PsbtInputs(funded.Psbt)
+		if err == nil {
+			err = bdkWallet.LockInputs(spent)
+		}
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCWallet,
+				Message: "Unable to lock the inputs: " + err.Error(),
+			}
+		}
+	}
+
+	return btcjson.WalletCreateFundedPsbtResult{
+		Psbt:      base64.StdEncoding.EncodeToString(funded.Psbt),
+		Fee:       funded.Fee.ToBTC(),
+		ChangePos: int64(funded.ChangePos),
+	}, nil
+}
+
+// handleWalletProcessPsbt implements the walletprocesspsbt command.
+func handleWalletProcessPsbt(s *rpcServer, bdkWallet *bdkwallet.Manager, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// The wallet always signs with SIGHASH_ALL, or SIGHASH_DEFAULT for
+	// taproot inputs.
+	c := cmd.(*btcjson.WalletProcessPsbtCmd)
+	if *c.SighashType != "ALL" && *c.SighashType != "DEFAULT" {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Only the ALL and DEFAULT sighash types are supported",
+		}
+	}
+
+	psbt, err := decodePsbt(c.Psbt)
+	if err != nil {
+		return nil, err
+	}
+
+	processed, complete, err := bdkWallet.ProcessPsbt(psbt, *c.Sign)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Failed to process psbt. %v", err),
+		}
+	}
+
+	return btcjson.WalletProcessPsbtResult{
+		Psbt:     base64.StdEncoding.EncodeToString(processed),
+		Complete: complete,
+	}, nil
+}
+
 // rpcServer provides a concurrent safe RPC server to a chain server.
 type rpcServer struct {
 	started                int32
 	shutdown               int32
 	cfg                    rpcserverConfig
-	authsha                [sha256.Size]byte
-	limitauthsha           [sha256.Size]byte
+	users                  []rpcUser
+	rateLimiter            *rpcRateLimiter
+	auditLog               *rpcAuditLog
 	ntfnMgr                *wsNotificationManager
 	numClients             int32
 	statusLines            map[int]string
@@ -4778,6 +8207,8 @@ type rpcServer struct {
 	wg                     sync.WaitGroup
 	gbtWorkState           *gbtWorkState
 	helpCacher             *helpCacher
+	scanBlocks             scanBlocksState
+	scanTxOutSet           scanTxOutSetState
 	requestProcessShutdown chan struct{}
 	quit                   chan int
 }
@@ -4863,6 +8294,11 @@ func (s *rpcServer) Stop() error {
 	s.ntfnMgr.WaitForShutdown()
 	close(s.quit)
 	s.wg.Wait()
+	if s.auditLog != nil {
+		if err := s.auditLog.Close(); err != nil {
+			rpcsLog.Errorf("Problem closing the RPC audit log: %v", err)
+		}
+	}
 	rpcsLog.Infof("RPC server shutdown complete")
 	return nil
 }
@@ -4886,8 +8322,8 @@ func (s *rpcServer) NotifyNewTransactions(txns []*mempool.TxDesc) {
 		// about stale block templates due to the new transaction.
 		s.gbtWorkState.NotifyMempoolTx(s.cfg.TxMemPool.LastUpdated())
 
-		if s.cfg.BDKWallet != nil {
-			s.cfg.BDKWallet.NotifyNewTransactions(txns)
+		if s.cfg.BDKWallets != nil {
+			s.cfg.BDKWallets.NotifyNewTransactions(txns)
 		}
 
 		if s.cfg.WatchOnlyWallet != nil {
@@ -4937,40 +8373,43 @@ func (s *rpcServer) decrementClients() {
 //
 // This check is time-constant.
 //
-// The first bool return value signifies auth success (true if successful) and
-// the second bool return value specifies whether the user can change the state
-// of the server (true) or whether the user is limited (false). The second is
-// always false if the first is.
-func (s *rpcServer) checkAuth(r *http.Request, require bool) (bool, bool, error) {
+// Requests without the header that are received on a Unix domain socket are
+// authenticated as an admin, since access to the socket is controlled by its
+// file permissions.
+//
+// The bool return value signifies auth success (true if successful) and the
+// rpcPermission return value specifies the set of commands the user may call.
+// The permission is always rpcPermReadOnly if the auth isn't successful.
+func (s *rpcServer) checkAuth(r *http.Request, require bool) (bool, rpcPermission, error) {
 	authhdr := r.Header["Authorization"]
 	if len(authhdr) <= 0 {
+		if isUnixSocketRequest(r) {
+			return true, rpcPermAdmin, nil
+		}
 		if require {
 			rpcsLog.Warnf("RPC authentication failure from %s",
 				r.RemoteAddr)
-			return false, false, errors.New("auth failure")
+			return false, rpcPermReadOnly, errors.New("auth failure")
 		}
 
-		return false, false, nil
+		return false, rpcPermReadOnly, nil
 	}
 
-	authsha := sha256.Sum256([]byte(authhdr[0]))
-
-	// Check for limited auth first as in environments with limited users, those
-	// are probably expected to have a higher volume of calls
-	limitcmp := subtle.ConstantTimeCompare(authsha[:], s.limitauthsha[:])
-	if limitcmp == 1 {
-		return true, false, nil
+	user, ok := s.authenticate(authhdr[0])
+	if !ok {
+		// Request's auth doesn't match any user
+		rpcsLog.Warnf("RPC authentication failure from %s", r.RemoteAddr)
+		return false, rpcPermReadOnly, errors.New("auth failure")
 	}
 
-	// Check for admin-level auth
-	cmp := subtle.ConstantTimeCompare(authsha[:], s.authsha[:])
-	if cmp == 1 {
-		return true, true, nil
-	}
+	return true, user.perm, nil
+}
 
-	// Request's auth doesn't match either user
-	rpcsLog.Warnf("RPC authentication failure from %s", r.RemoteAddr)
-	return false, false, errors.New("auth failure")
+// isUnixSocketRequest returns whether the request was received on a Unix domain
+// socket listener.
+func isUnixSocketRequest(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
 }
 
 // parsedRPCCmd represents a JSON-RPC request object that has been parsed into
@@ -4982,6 +8421,10 @@ type parsedRPCCmd struct {
 	method  string
 	cmd     interface{}
 	err     *btcjson.RPCError
+
+	// wallet is the name of the bdk wallet of the endpoint the command
+	// was sent to.  It's empty for the default wallet.
+	wallet string
 }
 
 // standardCmdResult checks that a parsed command is a standard Bitcoin JSON-RPC
@@ -4989,6 +8432,11 @@ type parsedRPCCmd struct {
 // commands which are not recognized or not implemented will return an error
 // suitable for use in replies.
 func (s *rpcServer) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct{}) (interface{}, error) {
+	if walletHandler, ok := rpcBDKWalletHandlers[cmd.method]; ok {
+		return s.bdkWalletCmdResult(walletHandler, cmd.wallet, cmd.cmd,
+			closeChan)
+	}
+
 	handler, ok := rpcHandlers[cmd.method]
 	if ok {
 		goto handled
@@ -5009,6 +8457,34 @@ handled:
 	return handler(s, cmd.cmd, closeChan)
 }
 
+// bdkWalletCmdResult runs the handler of a bdk wallet command with the loaded
+// wallet of the passed name.  The default wallet has an empty name.
+func (s *rpcServer) bdkWalletCmdResult(handler bdkWalletHandler, walletName string,
+	cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+
+	// Before doing anything, check that the bdk wallet is active.
+	if s.cfg.BDKWallets == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "BDK wallet must be enabled. (--bdkwallet)",
+		}
+	}
+
+	bdkWallet, err := s.cfg.BDKWallets.Get(walletName)
+	if err != nil {
+		return nil, bdkWalletsError(err)
+	}
+	return handler(s, bdkWallet, cmd, closeChan)
+}
+
+// defaultBDKWalletHandler returns a command handler that runs the handler of a
+// bdk wallet command with the default wallet.
+func defaultBDKWalletHandler(handler bdkWalletHandler) commandHandler {
+	return func(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+		return s.bdkWalletCmdResult(handler, "", cmd, closeChan)
+	}
+}
+
 // parseCmd parses a JSON-RPC request object into known concrete command.  The
 // err field of the returned parsedRPCCmd struct will contain an RPC error that
 // is suitable for use in replies if the command is invalid in some way such as
@@ -5046,6 +8522,12 @@ func parseCmd(request *btcjson.Request) *parsedRPCCmd {
 // passed parameters.  It will automatically convert errors that are not of
 // the type *btcjson.RPCError to the appropriate type as needed.
 func createMarshalledReply(rpcVersion btcjson.RPCVersion, id interface{}, result interface{}, replyErr error) ([]byte, error) {
+	// Requests that don't specify the JSON-RPC version are replied to with
+	// a JSON-RPC 1.0 response as Bitcoin Core does.
+	if !rpcVersion.IsValid() {
+		rpcVersion = btcjson.RpcVersion1
+	}
+
 	var jsonErr *btcjson.RPCError
 	if replyErr != nil {
 		if jErr, ok := replyErr.(*btcjson.RPCError); ok {
@@ -5059,17 +8541,17 @@ func createMarshalledReply(rpcVersion btcjson.RPCVersion, id interface{}, result
 }
 
 // processRequest determines the incoming request type (single or batched),
-// parses it and returns a marshalled response.
-func (s *rpcServer) processRequest(request *btcjson.Request, isAdmin bool, closeChan <-chan struct{}) []byte {
+// parses it and returns a marshalled response.  The bdk wallet commands are
+// run with the named wallet, or the default wallet when the name is empty.
+func (s *rpcServer) processRequest(request *btcjson.Request, remoteAddr string,
+	walletName string, perm rpcPermission, closeChan <-chan struct{}) []byte {
+
 	var result interface{}
 	var err error
 	var jsonErr *btcjson.RPCError
 
-	if !isAdmin {
-		if _, ok := rpcLimited[request.Method]; !ok {
-			jsonErr = internalRPCError("limited user not "+
-				"authorized for this method", "")
-		}
+	if !perm.authorized(request.Method) {
+		jsonErr = internalRPCError(perm.unauthorizedError(), "")
 	}
 
 	if jsonErr == nil {
@@ -5095,11 +8577,15 @@ func (s *rpcServer) processRequest(request *btcjson.Request, isAdmin bool, close
 		// Attempt to parse the JSON-RPC request into a known
 		// concrete command.
 		parsedCmd := parseCmd(request)
+		parsedCmd.wallet = walletName
 		if parsedCmd.err != nil {
 			jsonErr = parsedCmd.err
 		} else {
-			result, err = s.standardCmdResult(parsedCmd,
-				closeChan)
+			result, err = s.callCmd(remoteAddr, parsedCmd,
+				func() (interface{}, error) {
+					return s.standardCmdResult(parsedCmd,
+						closeChan)
+				})
 			if err != nil {
 				if rpcErr, ok := err.(*btcjson.RPCError); ok {
 					jsonErr = rpcErr
@@ -5123,11 +8609,18 @@ func (s *rpcServer) processRequest(request *btcjson.Request, isAdmin bool, close
 }
 
 // jsonRPCRead handles reading and responding to RPC messages.
-func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin bool) {
+func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, perm rpcPermission) {
 	if atomic.LoadInt32(&s.shutdown) != 0 {
 		return
 	}
 
+	// Requests sent to the /wallet/<name> endpoint are run with the named
+	// bdk wallet.
+	var walletName string
+	if strings.HasPrefix(r.URL.Path, "/wallet/") {
+		walletName = strings.TrimPrefix(r.URL.Path, "/wallet/")
+	}
+
 	// Read and close the JSON-RPC request body from the caller.
 	body, err := ioutil.ReadAll(r.Body)
 	r.Body.Close()
@@ -5178,8 +8671,9 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 	var batchSize int
 	var batchedRequest bool
 
-	// Determine request type
-	if bytes.HasPrefix(body, batchedRequestPrefix) {
+	// Determine request type.  Whitespace before the JSON array of a
+	// batched request is allowed.
+	if bytes.HasPrefix(bytes.TrimSpace(body), batchedRequestPrefix) {
 		batchedRequest = true
 	}
 
@@ -5222,7 +8716,8 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 			if req.ID == nil && !(cfg.RPCQuirks && req.Jsonrpc == "") {
 				return
 			}
-			resp = s.processRequest(&req, isAdmin, closeChan)
+			resp = s.processRequest(&req, r.RemoteAddr, walletName,
+				perm, closeChan)
 		}
 
 		if resp != nil {
@@ -5292,15 +8787,17 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 						continue
 					}
 
+					// Entries that aren't request objects are
+					// replied to with an error in their place
+					// of the batch.
 					var req btcjson.Request
 					err := json.Unmarshal(reqBytes, &req)
 					if err != nil {
 						jsonErr := &btcjson.RPCError{
-							Code: btcjson.ErrRPCInvalidRequest.Code,
-							Message: fmt.Sprintf("Invalid request: %v",
-								err),
+							Code:    btcjson.ErrRPCInvalidRequest.Code,
+							Message: "Invalid request: malformed",
 						}
-						resp, err = btcjson.MarshalResponse("", nil, nil, jsonErr)
+						resp, err = btcjson.MarshalResponse(btcjson.RpcVersion2, nil, nil, jsonErr)
 						if err != nil {
 							rpcsLog.Errorf("Failed to create reply: %v", err)
 						}
@@ -5311,7 +8808,8 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 						continue
 					}
 
-					resp = s.processRequest(&req, isAdmin, closeChan)
+					resp = s.processRequest(&req, r.RemoteAddr,
+						walletName, perm, closeChan)
 					if resp != nil {
 						results = append(results, resp)
 					}
@@ -5396,19 +8894,19 @@ func (s *rpcServer) Start() {
 		// Keep track of the number of connected clients.
 		s.incrementClients()
 		defer s.decrementClients()
-		_, isAdmin, err := s.checkAuth(r, true)
+		_, perm, err := s.checkAuth(r, true)
 		if err != nil {
 			jsonAuthFail(w)
 			return
 		}
 
 		// Read and respond to the request.
-		s.jsonRPCRead(w, r, isAdmin)
+		s.jsonRPCRead(w, r, perm)
 	})
 
 	// Websocket endpoint.
 	rpcServeMux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		authenticated, isAdmin, err := s.checkAuth(r, false)
+		authenticated, perm, err := s.checkAuth(r, false)
 		if err != nil {
 			jsonAuthFail(w)
 			return
@@ -5425,9 +8923,14 @@ func (s *rpcServer) Start() {
 			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
 			return
 		}
-		s.WebsocketHandler(ws, r.RemoteAddr, authenticated, isAdmin)
+		s.WebsocketHandler(ws, r.RemoteAddr, authenticated, perm)
 	})
 
+	// REST endpoint.
+	if cfg.REST {
+		rpcServeMux.Handle("/rest/", newRESTHandler(s))
+	}
+
 	for _, listener := range s.cfg.Listeners {
 		s.wg.Add(1)
 		go func(listener net.Listener) {
@@ -5465,27 +8968,29 @@ func genCertPair(certFile, keyFile string) error {
 	return nil
 }
 
-// makeCookie creates a user and login for the rpcserver at the given path.
-func makeCookie(path string) (string, error) {
+// makeCookie creates an admin user for the rpcserver and writes its login to
+// the cookie file at the given path.
+func makeCookie(path string) (rpcUser, error) {
 	randomBytes := make([]byte, 32)
 	_, err := cryptorand.Read(randomBytes)
 	if err != nil {
-		return "", err
+		return rpcUser{}, err
 	}
-	login := "__cookie__" + ":" + hex.EncodeToString(randomBytes)
+	pass := hex.EncodeToString(randomBytes)
 
 	f, err := os.OpenFile(path,
 		os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
-		return "", err
+		return rpcUser{}, err
 	}
+	defer f.Close()
 
-	_, err = f.WriteString(login)
+	_, err = f.WriteString(cookieUsername + ":" + pass)
 	if err != nil {
-		return "", err
+		return rpcUser{}, err
 	}
 
-	return login, err
+	return newRPCUser(cookieUsername, pass, rpcPermAdmin), nil
 }
 
 // rpcserverPeer represents a peer for use with the RPC server.
@@ -5500,6 +9005,10 @@ type rpcserverPeer interface {
 	// transaction relay.
 	IsTxRelayDisabled() bool
 
+	// IsBlockRelayOnly returns whether or not the peer is only used to
+	// relay blocks.
+	IsBlockRelayOnly() bool
+
 	// BanScore returns the current integer value that represents how close
 	// the peer is to being banned.
 	BanScore() uint32
@@ -5507,6 +9016,10 @@ type rpcserverPeer interface {
 	// FeeFilter returns the requested current minimum fee rate for which
 	// transactions should be announced.
 	FeeFilter() int64
+
+	// Permissions returns the names of the permissions the peer was given
+	// by the whitelisted networks and the whitebind listeners.
+	Permissions() []string
 }
 
 // rpcserverConnManager represents a connection manager for use with the RPC
@@ -5542,6 +9055,21 @@ type rpcserverConnManager interface {
 	// error.
 	DisconnectByAddr(addr string) error
 
+	// Ban bans the IP address, subnet, or Tor or I2P host until the
+	// passed time and disconnects the connected peers it bans.
+	Ban(subnet string, until time.Time) error
+
+	// Unban removes the ban of the IP address, subnet, or Tor or I2P
+	// host.  Attempting to unban one that isn't banned will return an
+	// error.
+	Unban(subnet string) error
+
+	// ListBanned returns the bans that haven't expired yet.
+	ListBanned() []banEntry
+
+	// ClearBanned removes all the bans.
+	ClearBanned() error
+
 	// ConnectedCount returns the number of currently connected peers.
 	ConnectedCount() int32
 
@@ -5569,6 +9097,11 @@ type rpcserverConnManager interface {
 	// in a block.
 	AddRebroadcastInventory(iv *wire.InvVect, data interface{})
 
+	// AbandonTransaction stops rebroadcasting the locally submitted
+	// transaction with the provided hash and removes it from the mempool
+	// along with the transactions that spend it.
+	AbandonTransaction(hash *chainhash.Hash) error
+
 	// RelayTransactions generates and relays inventory vectors for all of
 	// the passed transactions to all connected peers.
 	RelayTransactions(txns []*mempool.TxDesc)
@@ -5576,6 +9109,22 @@ type rpcserverConnManager interface {
 	// NodeAddresses returns an array consisting node addresses which can
 	// potentially be used to find new nodes in the network.
 	NodeAddresses() []*wire.NetAddress
+
+	// LocalAddresses returns the local addresses that are advertised to
+	// peers.
+	LocalAddresses() []addrmgr.LocalAddress
+
+	// Services returns the services the node advertises to peers.
+	Services() wire.ServiceFlag
+
+	// PortMapping returns the state of the mapping of the listening port
+	// outside of the NAT, or nil when no UPnP, NAT-PMP or PCP router was
+	// found.
+	PortMapping() *portMappingStatus
+
+	// UploadTarget returns the budget of the bytes sent to peers, or nil
+	// when --maxuploadtarget isn't set.
+	UploadTarget() *uploadTarget
 }
 
 // rpcserverSyncManager represents a sync manager for use with the RPC server.
@@ -5603,6 +9152,14 @@ type rpcserverSyncManager interface {
 	// current tip is reached, up to a max of wire.MaxBlockHeadersPerMsg
 	// hashes.
 	LocateHeaders(locators []*chainhash.Hash, hashStop *chainhash.Hash) []wire.BlockHeader
+
+	// ProofOrphanStats returns the counters of the transactions that were
+	// relayed with utreexo proofs that couldn't prove their inputs.
+	ProofOrphanStats() netsync.ProofOrphanStats
+
+	// ForkWarning returns the warning about the peers presenting
+	// conflicting chains, or an empty string when there is none.
+	ForkWarning() string
 }
 
 // rpcserverConfig is a descriptor containing the RPC server configuration.
@@ -5650,8 +9207,13 @@ type rpcserverConfig struct {
 	AddrIndex             *indexers.AddrIndex
 	CfIndex               *indexers.CfIndex
 	TTLIndex              *indexers.TTLIndex
+	SpendIndex            *indexers.SpendIndex
+	SilentPaymentIndex    *indexers.SilentPaymentIndex
+	BlockTimeIndex        *indexers.BlockTimeIndex
+	ScriptBalanceIndex    *indexers.ScriptBalanceIndex
 	UtreexoProofIndex     *indexers.UtreexoProofIndex
 	FlatUtreexoProofIndex *indexers.FlatUtreexoProofIndex
+	UtreexoCFIndex        *indexers.UtreexoCFIndex
 
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
@@ -5661,8 +9223,12 @@ type rpcserverConfig struct {
 	// for the given addresses and xpubs.
 	WatchOnlyWallet *wallet.WatchOnlyWalletManager
 
-	// BDKWallet is the underlying bdk wallet that is a part of this node.
-	BDKWallet *bdkwallet.Manager
+	// BDKWallets are the underlying bdk wallets that are a part of this
+	// node, the default wallet and the named wallets that are loaded.
+	BDKWallets *bdkwallet.Wallets
+
+	// DBCompactor compacts the block database in the background.
+	DBCompactor *dbCompactor
 }
 
 // newRPCServer returns a new instance of the rpcServer struct.
@@ -5675,27 +9241,30 @@ func newRPCServer(config *rpcserverConfig) (*rpcServer, error) {
 		requestProcessShutdown: make(chan struct{}),
 		quit:                   make(chan int),
 	}
-	if cfg.RPCUser != "" && cfg.RPCPass != "" {
-		login := cfg.RPCUser + ":" + cfg.RPCPass
-		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-		rpc.authsha = sha256.Sum256([]byte(auth))
-	} else {
-		cookiePath := filepath.Join(cfg.DataDir, defaultCookieFileName)
-		rpcsLog.Infof("RPCUser or RPCPassword not set. Making cookiefile at %v", cookiePath)
-		login, err := makeCookie(cookiePath)
+
+	// The cookie file is always made so that local clients can authenticate
+	// as an admin without any configured credentials.
+	cookiePath := filepath.Join(cfg.DataDir, defaultCookieFileName)
+	rpcsLog.Infof("Making cookiefile at %v", cookiePath)
+	cookieUser, err := makeCookie(cookiePath)
+	if err != nil {
+		return nil, err
+	}
+	rpc.users = append(rpc.users, cookieUser)
+	rpc.users = append(rpc.users, cfg.rpcUsers...)
+	rpc.rateLimiter = newRPCRateLimiter(cfg.RPCRateLimit, cfg.rpcMethodRates)
+	if cfg.RPCAuditLog != "" {
+		rpc.auditLog, err = openRPCAuditLog(cfg.RPCAuditLog)
 		if err != nil {
 			return nil, err
 		}
-		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-		rpc.authsha = sha256.Sum256([]byte(auth))
-	}
-	if cfg.RPCLimitUser != "" && cfg.RPCLimitPass != "" {
-		login := cfg.RPCLimitUser + ":" + cfg.RPCLimitPass
-		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-		rpc.limitauthsha = sha256.Sum256([]byte(auth))
 	}
 	rpc.ntfnMgr = newWsNotificationManager(&rpc)
 	rpc.cfg.Chain.Subscribe(rpc.handleBlockchainNotification)
+	rpc.cfg.TxMemPool.Subscribe(rpc.ntfnMgr.NotifyMempoolEvent)
+	if rpc.cfg.BDKWallets != nil {
+		rpc.cfg.BDKWallets.Subscribe(rpc.ntfnMgr.NotifyWalletEvent)
+	}
 
 	return &rpc, nil
 }
@@ -5740,5 +9309,8 @@ func (s *rpcServer) handleBlockchainNotification(notification *blockchain.Notifi
 
 func init() {
 	rpcHandlers = rpcHandlersBeforeInit
+	for method, handler := range rpcBDKWalletHandlers {
+		rpcHandlers[method] = defaultBDKWalletHandler(handler)
+	}
 	rand.Seed(time.Now().UnixNano())
 }
@@ -0,0 +1,315 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcjson"
+	"github.com/utreexo/utreexod/chaincfg"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/mining"
+	"github.com/utreexo/utreexod/wire"
+)
+
+// TestHandleGetUtxoProof ensures that getutxoproof proves the utxos against the
+// accumulator at the tip, and that a block other than the tip is rejected before
+// the utxos are looked up.
+func TestHandleGetUtxoProof(t *testing.T) {
+	chain, utreexoProofIndex := newUtreexoIndexTestChain(t)
+	s := &rpcServer{cfg: rpcserverConfig{
+		ChainParams:       &chaincfg.MainNetParams,
+		Chain:             chain,
+		UtreexoProofIndex: utreexoProofIndex,
+	}}
+
+	best := chain.BestSnapshot()
+	tip := best.Hash.String()
+	prevHash, err := chain.BlockHashByHeight(best.Height - 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := prevHash.String()
+	tipBlock, err := chain.BlockByHash(&best.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unspent := btcjson.TransactionInput{
+		Txid: tipBlock.Transactions()[0].Hash().String(),
+		Vout: 0,
+	}
+	unknown := btcjson.TransactionInput{
+		Txid: chainhash.HashH([]byte("unknown")).String(),
+		Vout: 0,
+	}
+	invalid := "zz"
+
+	// The proof verifies against the roots at the tip.
+	for _, blockHash := range []*string{nil, &tip} {
+		cmd := btcjson.NewGetUtxoProofCmd(
+			[]btcjson.TransactionInput{unspent}, blockHash)
+		reply, err := handleGetUtxoProof(s, cmd, nil)
+		if err != nil {
+			t.Fatalf("handleGetUtxoProof: unexpected error: %v", err)
+		}
+		result := reply.(*btcjson.GetUtxoProofResult)
+		if result.ProvedAtHash != tip {
+			t.Fatalf("got a proof at %s, want %s", result.ProvedAtHash, tip)
+		}
+
+		// The hashes are in the reversed byte order of the RPC server.
+		decode := func(strs []string) []utreexo.Hash {
+			hashes := make([]utreexo.Hash, 0, len(strs))
+			for _, str := range strs {
+				hash, err := chainhash.NewHashFromStr(str)
+				if err != nil {
+					t.Fatalf("invalid hash %q", str)
+				}
+				hashes = append(hashes, utreexo.Hash(*hash))
+			}
+			return hashes
+		}
+		accProof := utreexo.Proof{
+			Targets: result.ProofTargets,
+			Proof:   decode(result.ProofHashes),
+		}
+		_, err = utreexo.Verify(testStump(t, chain, best.Height),
+			decode(result.HashesProven), accProof)
+		if err != nil {
+			t.Fatalf("the proof doesn't verify: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		s         *rpcServer
+		outpoints []btcjson.TransactionInput
+		blockHash *string
+		code      btcjson.RPCErrorCode
+		message   string
+	}{
+		{
+			name:      "no index",
+			s:         &rpcServer{cfg: rpcserverConfig{Chain: chain}},
+			outpoints: []btcjson.TransactionInput{unspent},
+			code:      btcjson.ErrRPCMisc,
+			message:   "A utreexo proof index must be enabled",
+		},
+		{
+			name:    "no outpoints",
+			s:       s,
+			code:    btcjson.ErrRPCInvalidParameter,
+			message: "At least one outpoint must be given",
+		},
+		{
+			name:      "invalid block hash",
+			s:         s,
+			outpoints: []btcjson.TransactionInput{unspent},
+			blockHash: &invalid,
+			code:      btcjson.ErrRPCDecodeHexString,
+		},
+		{
+			name:      "block before the tip",
+			s:         s,
+			outpoints: []btcjson.TransactionInput{unspent},
+			blockHash: &prev,
+			code:      btcjson.ErrRPCInvalidParameter,
+			message:   "Only the current tip " + tip + " is supported",
+		},
+		{
+			// The block is checked before the utxos are.
+			name:      "block before the tip and an unknown utxo",
+			s:         s,
+			outpoints: []btcjson.TransactionInput{unknown},
+			blockHash: &prev,
+			code:      btcjson.ErrRPCInvalidParameter,
+			message:   "Only the current tip " + tip + " is supported",
+		},
+		{
+			name:      "unknown utxo",
+			s:         s,
+			outpoints: []btcjson.TransactionInput{unspent, unknown},
+			blockHash: &tip,
+			code:      btcjson.ErrRPCMisc,
+			message:   "does not exist in the UTXO set",
+		},
+		{
+			name:      "invalid txid",
+			s:         s,
+			outpoints: []btcjson.TransactionInput{{Txid: invalid}},
+			code:      btcjson.ErrRPCDecodeHexString,
+		},
+	}
+	for _, test := range tests {
+		cmd := btcjson.NewGetUtxoProofCmd(test.outpoints, test.blockHash)
+		_, err := handleGetUtxoProof(test.s, cmd, nil)
+		var rpcErr *btcjson.RPCError
+		if !errors.As(err, &rpcErr) {
+			t.Errorf("%s: got error %v, want an RPC error", test.name, err)
+			continue
+		}
+		if rpcErr.Code != test.code || !strings.Contains(rpcErr.Message, test.message) {
+			t.Errorf("%s: got error %d %q, want %d containing %q",
+				test.name, rpcErr.Code, rpcErr.Message, test.code,
+				test.message)
+		}
+	}
+}
+
+// TestTemplateUtreexoProof ensures that the utreexo proof of a block template
+// proves the inputs of its transactions against the accumulator at the tip,
+// that the inputs spending outputs of the template are left out, and that the
+// utreexoproof capability is advertised whenever the inputs can be proven.
+func TestTemplateUtreexoProof(t *testing.T) {
+	oldCfg := cfg
+	cfg = &config{NoUtreexo: true}
+	defer func() { cfg = oldCfg }()
+
+	chain, utreexoProofIndex := newUtreexoIndexTestChain(t)
+	s := &rpcServer{cfg: rpcserverConfig{
+		ChainParams:       &chaincfg.MainNetParams,
+		Chain:             chain,
+		UtreexoProofIndex: utreexoProofIndex,
+	}}
+
+	best := chain.BestSnapshot()
+	tipBlock, err := chain.BlockByHash(&best.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coinbase := tipBlock.Transactions()[0].MsgTx()
+
+	// A transaction spending the coinbase of the tip and a transaction
+	// spending the output of that one, which isn't in the accumulator.
+	spendTx := wire.NewMsgTx(wire.TxVersion)
+	spendTx.AddTxIn(wire.NewTxIn(
+		wire.NewOutPoint(tipBlock.Transactions()[0].Hash(), 0), nil, nil))
+	spendTx.AddTxOut(wire.NewTxOut(coinbase.TxOut[0].Value, []byte{0x51}))
+	spendHash := spendTx.TxHash()
+	childTx := wire.NewMsgTx(wire.TxVersion)
+	childTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&spendHash, 0), nil, nil))
+	childTx.AddTxOut(wire.NewTxOut(spendTx.TxOut[0].Value, []byte{0x51}))
+
+	newTemplate := func(prevBlock chainhash.Hash) *mining.BlockTemplate {
+		return &mining.BlockTemplate{
+			Block: &wire.MsgBlock{
+				Header: wire.BlockHeader{
+					PrevBlock: prevBlock,
+					Timestamp: time.Unix(time.Now().Unix(), 0),
+					Bits:      chaincfg.MainNetParams.PowLimitBits,
+				},
+				Transactions: []*wire.MsgTx{coinbase, spendTx, childTx},
+			},
+			Fees:       []int64{0, 0, 0},
+			SigOpCosts: []int64{0, 0, 0},
+		}
+	}
+	template := newTemplate(best.Hash)
+
+	proofHex, err := templateUtreexoProof(s, template)
+	if err != nil {
+		t.Fatalf("templateUtreexoProof: unexpected error: %v", err)
+	}
+	serialized, err := hex.DecodeString(proofHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ud wire.UData
+	if err := ud.Deserialize(bytes.NewReader(serialized)); err != nil {
+		t.Fatalf("unable to decode the proof: %v", err)
+	}
+	if len(ud.LeafDatas) != 1 ||
+		ud.LeafDatas[0].OutPoint != spendTx.TxIn[0].PreviousOutPoint {
+
+		t.Fatalf("got the leaves %v, want only the one of %v",
+			ud.LeafDatas, spendTx.TxIn[0].PreviousOutPoint)
+	}
+	leafHash := utreexo.Hash(ud.LeafDatas[0].LeafHash())
+	_, err = utreexo.Verify(testStump(t, chain, best.Height),
+		[]utreexo.Hash{leafHash}, ud.AccProof)
+	if err != nil {
+		t.Fatalf("the proof doesn't verify: %v", err)
+	}
+
+	// The proof is returned when it's requested and the capability is
+	// advertised as long as the inputs can be proven.
+	state := newGbtWorkState(blockchain.NewMedianTime())
+	state.template = template
+	state.prevHash = &best.Hash
+	state.utreexoProof = proofHex
+	state.proofTemplate = template
+	resultTests := []struct {
+		name         string
+		opts         gbtOptions
+		capabilities []string
+		utreexoProof string
+	}{
+		{
+			name: "proof requested",
+			opts: gbtOptions{
+				useCoinbaseValue: true,
+				useUtreexoProof:  true,
+				canProveUtreexo:  true,
+			},
+			capabilities: gbtUtreexoCapabilities,
+			utreexoProof: proofHex,
+		},
+		{
+			name: "proof not requested",
+			opts: gbtOptions{
+				useCoinbaseValue: true,
+				canProveUtreexo:  true,
+			},
+			capabilities: gbtUtreexoCapabilities,
+		},
+		{
+			name:         "unable to prove",
+			opts:         gbtOptions{useCoinbaseValue: true},
+			capabilities: gbtCapabilities,
+		},
+	}
+	for _, test := range resultTests {
+		result, err := state.blockTemplateResult(&test.opts, nil)
+		if err != nil {
+			t.Fatalf("%s: blockTemplateResult: unexpected error: %v",
+				test.name, err)
+		}
+		if !reflect.DeepEqual(result.Capabilities, test.capabilities) {
+			t.Errorf("%s: got capabilities %v, want %v", test.name,
+				result.Capabilities, test.capabilities)
+		}
+		if result.UtreexoProof != test.utreexoProof {
+			t.Errorf("%s: got utreexo proof %q, want %q", test.name,
+				result.UtreexoProof, test.utreexoProof)
+		}
+	}
+
+	// A template that doesn't build on the tip can't be proven and neither
+	// can templates without an accumulator to prove them with.
+	oldTemplate := newTemplate(tipBlock.MsgBlock().Header.PrevBlock)
+	if _, err := templateUtreexoProof(s, oldTemplate); err == nil {
+		t.Errorf("proved a template that doesn't build on the tip")
+	}
+	noIndex := &rpcServer{cfg: rpcserverConfig{Chain: chain}}
+	if canProveTemplates(noIndex) {
+		t.Errorf("got able to prove templates without an index")
+	}
+	if _, err := templateUtreexoProof(noIndex, template); err == nil {
+		t.Errorf("proved a template without an index")
+	}
+	cfg.NoUtreexo = false
+	if !canProveTemplates(noIndex) {
+		t.Errorf("got unable to prove templates with the accumulator of " +
+			"the chain")
+	}
+}
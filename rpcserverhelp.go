@@ -29,11 +29,35 @@ var helpDescsEnUS = map[string]string{
 	"debuglevel--result0":    "The string 'Done.'",
 	"debuglevel--result1":    "The list of subsystems",
 
+	// AbandonTransactionCmd help.
+	"abandontransaction--synopsis": "Stops rebroadcasting a transaction that was submitted to this node and removes it from the mempool along with the transactions that spend it, so that its inputs can be spent by another transaction.",
+	"abandontransaction-txid":      "The hash of the transaction to abandon",
+
 	// AddNodeCmd help.
 	"addnode--synopsis": "Attempts to add or remove a persistent peer.",
 	"addnode-addr":      "IP address and port of the peer to operate on",
 	"addnode-subcmd":    "'add' to add a persistent peer, 'remove' to remove a persistent peer, or 'onetry' to try a single connection to a peer",
 
+	// ClearBannedCmd help.
+	"clearbanned--synopsis": "Removes all the bans of IP addresses and subnets.",
+
+	// CompactDBCmd help.
+	"compactdb--synopsis": "Starts compacting the block database in the background to reclaim the space of deleted data, such as that of dropped indexes and pruned blocks. The node keeps running meanwhile and the progress is returned by getdbcompactioninfo.",
+
+	// BackupDataDirCmd help.
+	"backupdatadir--synopsis":   "Writes a copy of the block database and of the state of the utreexo proof indexes to the destination without stopping the node. Blocks are held back while the copy is made, so all of it is at the same block, and the node can be started with the copy as its data directory. The tables and block files that are never modified are hard-linked when the destination is on the same filesystem. The peer addresses and the wallets aren't copied.",
+	"backupdatadir-destination": "The directory of the backup, which must not exist. Relative paths are relative to the data directory",
+
+	// BackupDataDirResult help.
+	"backupdatadirresult-destination": "The directory of the backup",
+	"backupdatadirresult-height":      "The height of the block the backup is at",
+	"backupdatadirresult-hash":        "The hash of the block the backup is at",
+
+	// BackupWalletCmd help.
+	"backupwallet--synopsis":   "Writes a backup of the bdk wallet encrypted with the passphrase to the destination. Restore it with restorewallet.",
+	"backupwallet-destination": "The path of the backup. Relative paths are relative to the data directory",
+	"backupwallet-passphrase":  "The passphrase the backup is encrypted with (default: the --bdkbackuppassphrase passphrase)",
+
 	// BalanceCmd help.
 	"balance--synopsis": "Retrieves the balance from the underlying bdkwallet.",
 
@@ -43,14 +67,46 @@ var helpDescsEnUS = map[string]string{
 	"balanceresult-untrustedpending": "The balance that's part of our public keychain.",
 	"balanceresult-confirmed":        "The confirmed balance.",
 
+	// BumpFeeCmd help.
+	"bumpfee--synopsis": "Replaces an unconfirmed transaction of the bdk wallet with one paying a higher fee as BIP125 allows and broadcasts it.\n" +
+		"The fee increase is taken out of the change output, with more confirmed outputs of the wallet added when needed.",
+	"bumpfee-txid":    "The txid of the transaction to replace",
+	"bumpfee-options": "The options of the replacement",
+
+	// BumpFeeOpts help.
+	"bumpfeeopts-conf_target": "The number of blocks the replacement is estimated to confirm within when no fee rate is given (default: 6)",
+	"bumpfeeopts-fee_rate":    "The fee rate of the replacement in sat/vB (default: the estimated fee rate)",
+
+	// BumpFeeResult help.
+	"bumpfeeresult-txid":    "The txid of the replacement",
+	"bumpfeeresult-origfee": "The fee of the replaced transaction in BTC",
+	"bumpfeeresult-fee":     "The fee of the replacement in BTC",
+	"bumpfeeresult-errors":  "Errors encountered while bumping the fee",
+
+	// CpfpBDKTransactionCmd help.
+	"cpfpbdktransaction--synopsis": "Bumps the fee of an unconfirmed transaction of the bdk wallet by broadcasting a child that spends its change output back to the wallet.\n" +
+		"The child pays enough for both transactions to pay the fee rate together.",
+	"cpfpbdktransaction-txid":    "The txid of the transaction to bump the fee of",
+	"cpfpbdktransaction-feerate": "The fee rate of the transaction and its child together in sat/vB (default: the fee rate estimated to confirm within 6 blocks)",
+
+	// CpfpBDKTransactionResult help.
+	"cpfpbdktransactionresult-txhash":    "Txid of the child transaction",
+	"cpfpbdktransactionresult-rawbytes":  "Hex-encoded bytes of the serialized child transaction",
+	"cpfpbdktransactionresult-parentfee": "The fee of the transaction in satoshis",
+	"cpfpbdktransactionresult-fee":       "The fee of the child transaction in satoshis",
+
 	// Recipient help.
-	"recipient-amount":  "The amount in satoshis to send to the recipient.",
-	"recipient-address": "The address of the recipient.",
+	"recipient-amount":      "The amount in satoshis to send to the recipient.",
+	"recipient-address":     "The address of the recipient, which may be a BIP352 silent payment address.",
+	"recipient-subtractfee": "Whether the fee is taken out of the amount, split evenly between the recipients that have it set (default: false)",
 
 	// CreateTransactionFromBDKWalletCmd help.
-	"createtransactionfrombdkwallet--synopsis":  "Creates and returns a hex encoded transaction from the underlying bdk walllet that's ready to broadcast",
-	"createtransactionfrombdkwallet-feerate":    "Feerate in satoshis that this tx will be paying",
-	"createtransactionfrombdkwallet-recipients": "List of recipients that this tx will be paying",
+	"createtransactionfrombdkwallet--synopsis":     "Creates and returns a hex encoded transaction from the underlying bdk walllet that's ready to broadcast",
+	"createtransactionfrombdkwallet-feerate":       "Feerate in satoshis per vbyte that this tx will be paying, or 0 to estimate the feerate that confirms it within conftarget blocks",
+	"createtransactionfrombdkwallet-recipients":    "List of recipients that this tx will be paying",
+	"createtransactionfrombdkwallet-coinselection": "Coin selection strategy used to fund the tx: \"branchandbound\" looks for inputs that need no change, \"knapsack\" picks the inputs closest to the amount and \"avoidpartialspends\" spends all the outputs of an address together",
+	"createtransactionfrombdkwallet-inputs":        "The exact outputs of the wallet the tx spends, which may be locked. The coin selection picks from the unlocked outputs when they aren't given",
+	"createtransactionfrombdkwallet-conftarget":    "The number of blocks the tx should confirm within when the feerate is estimated",
 
 	// CreateTransactionFromBDKWalletResult help.
 	"createtransactionfrombdkwalletresult-txhash":   "Txid of the transaction",
@@ -78,6 +134,18 @@ var helpDescsEnUS = map[string]string{
 	"createrawtransaction-locktime":       "Locktime value; a non-zero value will also locktime-activate the inputs",
 	"createrawtransaction--result0":       "Hex-encoded bytes of the serialized transaction",
 
+	// CreateWalletCmd help.
+	"createwallet--synopsis":          "Creates a named bdk wallet and loads it. The commands of the wallet are sent to the /wallet/<walletname> endpoint.",
+	"createwallet-walletname":         "The name of the wallet, which may only have letters, digits, '.', '-' and '_'",
+	"createwallet-disableprivatekeys": "Create a watch-only wallet of the external signer configured with --bdksigner",
+	"createwallet-blank":              "Not supported, must be false",
+	"createwallet-passphrase":         "Not supported, must be empty",
+	"createwallet-avoidreuse":         "Not supported and ignored",
+
+	// CreateWalletResult help.
+	"createwalletresult-name":    "The name of the wallet",
+	"createwalletresult-warning": "Warning message if the wallet was not created as requested",
+
 	// ScriptSig help.
 	"scriptsig-asm": "Disassembly of the script",
 	"scriptsig-hex": "Hex-encoded bytes of the script",
@@ -102,6 +170,13 @@ var helpDescsEnUS = map[string]string{
 	"vin-scriptSig":   "The signature script used to redeem the origin transaction as a JSON object (non-coinbase txns only)",
 	"vin-txinwitness": "The witness used to redeem the input encoded as a string array of its items",
 	"vin-sequence":    "The script sequence number",
+	"vin-prevout":     "The output spent by the input (only when verbosity=3 in getblock)",
+
+	// PrevOutResult help.
+	"prevoutresult-generated":    "Whether the spent output was created by a coinbase transaction",
+	"prevoutresult-height":       "The height of the block that created the spent output",
+	"prevoutresult-value":        "The value of the spent output in bitcoin",
+	"prevoutresult-scriptPubKey": "The public key script of the spent output",
 
 	// ScriptPubKeyResult help.
 	"scriptpubkeyresult-asm":       "Disassembly of the script",
@@ -141,6 +216,57 @@ var helpDescsEnUS = map[string]string{
 	"decodescript--synopsis": "Returns a JSON object with information about the provided hex-encoded script.",
 	"decodescript-hexscript": "Hex-encoded script",
 
+	// DeriveAddressesCmd help.
+	"deriveaddresses--synopsis":  "Returns the addresses of the scripts of an output descriptor, which must have its checksum.",
+	"deriveaddresses-descriptor": "The output descriptor",
+	"deriveaddresses-range":      "The range of child indexes to derive for a ranged descriptor, either as the end or as [begin,end]",
+	"deriveaddresses--result0":   "The derived addresses",
+
+	// DescriptorRange help.
+	"descriptorrange-value": "The end of the range, or the range as [begin,end]",
+
+	// DumpTxOutSetCmd help.
+	"dumptxoutset--synopsis": "Writes a snapshot of the unspent transaction output set at the best block to a file, in the format of the dumptxoutset RPC of Bitcoin Core.\n" +
+		"The node must keep the utxo set, which compact state nodes don't.",
+	"dumptxoutset-path": "The path to write the snapshot to, which must not exist yet. Relative paths are relative to the data directory",
+
+	// DumpTxOutSetResult help.
+	"dumptxoutsetresult-coins_written":     "The number of unspent transaction outputs in the snapshot",
+	"dumptxoutsetresult-base_hash":         "The hash of the block the snapshot is for",
+	"dumptxoutsetresult-base_height":       "The height of the block the snapshot is for",
+	"dumptxoutsetresult-path":              "The absolute path of the snapshot",
+	"dumptxoutsetresult-hash_serialized_2": "The hash of the serialized utxo set, the same as the one of gettxoutsetinfo",
+
+	// EnumerateSignersCmd help.
+	"enumeratesigners--synopsis": "Returns the external signers found by the command configured with --bdksigner.",
+
+	// EnumerateSignersResult help.
+	"enumeratesignersresult-signers": "The external signers",
+
+	// SignerResult help.
+	"signerresult-fingerprint": "The fingerprint of the master key of the signer",
+	"signerresult-name":        "The model of the signer",
+
+	// ExportBDKTransactionsCmd help.
+	"exportbdktransactions--synopsis":   "Exports the transaction history of the bdk wallet for accounting tools, oldest first and ending with the unconfirmed transactions. The amounts are in satoshis.",
+	"exportbdktransactions-format":      "The format of the export, \"json\" or \"csv\"",
+	"exportbdktransactions--condition0": "format=\"json\"",
+	"exportbdktransactions--condition1": "format=\"csv\"",
+	"exportbdktransactions--result1":    "The transactions as CSV with a header row. The times are in RFC 3339 format in UTC and the addresses of a transaction are separated by spaces",
+
+	// ExportBDKTransactionsResult help.
+	"exportbdktransactionsresult-txid":          "The hash of the transaction",
+	"exportbdktransactionsresult-height":        "The height of the block of the transaction, omitted while unconfirmed",
+	"exportbdktransactionsresult-time":          "The time of the block of the transaction in seconds since 1 Jan 1970 GMT, omitted while unconfirmed",
+	"exportbdktransactionsresult-confirmations": "The number of confirmations of the transaction",
+	"exportbdktransactionsresult-received":      "The sum of the outputs of the transaction paying to the wallet",
+	"exportbdktransactionsresult-spent":         "The sum of the outputs of the wallet spent by the transaction",
+	"exportbdktransactionsresult-net":           "The change of the balance of the wallet, received minus spent",
+	"exportbdktransactionsresult-fee":           "The fee of the transaction, omitted when the wallet doesn't know all of its inputs",
+	"exportbdktransactionsresult-label":         "The label of the transaction set with setlabel",
+	"exportbdktransactionsresult-receivedby":    "The addresses of the wallet the transaction pays to",
+	"exportbdktransactionsresult-sentto":        "The addresses outside of the wallet the transaction pays to",
+
 	// EstimateFeeCmd help.
 	"estimatefee--synopsis": "Estimate the fee per kilobyte in satoshis " +
 		"required for a transaction to be mined before a certain number of " +
@@ -150,9 +276,59 @@ var helpDescsEnUS = map[string]string{
 	"estimatefee--result0": "Estimated fee per kilobyte in satoshis for a block to " +
 		"be mined in the next NumBlocks blocks.",
 
+	// EstimateSmartFeeCmd help.
+	"estimatesmartfee--synopsis": "Estimate the fee rate in BTC/kB a transaction needs to be confirmed within a number of blocks.\n" +
+		"The estimate is for a lower number of blocks when there isn't enough history yet for the requested one.",
+	"estimatesmartfee-conftarget":   "Confirmation target in blocks (1 - 1008)",
+	"estimatesmartfee-estimatemode": "ECONOMICAL for estimates that respond quicker to drops in fee rates, or CONSERVATIVE for estimates which are more likely to be sufficient",
+
+	// EstimateSmartFeeResult help.
+	"estimatesmartfeeresult-feerate": "Estimated fee rate in BTC/kB, never less than the minimum relay fee (omitted when there is no estimate)",
+	"estimatesmartfeeresult-errors":  "Errors encountered while estimating",
+	"estimatesmartfeeresult-blocks":  "Number of blocks the estimate is for",
+
+	// EstimateRawFeeCmd help.
+	"estimaterawfee--synopsis": "Return the fee rate in BTC/kB needed for the threshold fraction of transactions to be confirmed within a number of blocks for each horizon of the fee estimator.\n" +
+		"The short, medium and long horizons track up to 12, 48 and 1008 blocks and are omitted when they don't track the target.",
+	"estimaterawfee-conftarget": "Confirmation target in blocks (1 - 1008)",
+	"estimaterawfee-threshold":  "Fraction of transactions that must have been confirmed within the target",
+
+	// EstimateRawFeeResult help.
+	"estimaterawfeeresult-short":  "Estimate of the short horizon",
+	"estimaterawfeeresult-medium": "Estimate of the medium horizon",
+	"estimaterawfeeresult-long":   "Estimate of the long horizon",
+
+	// EstimateRawFeeHorizonResult help.
+	"estimaterawfeehorizonresult-feerate": "Estimated fee rate in BTC/kB (omitted when there is no estimate)",
+	"estimaterawfeehorizonresult-decay":   "Exponential decay per block of the statistics",
+	"estimaterawfeehorizonresult-scale":   "Number of blocks per period of the statistics",
+	"estimaterawfeehorizonresult-pass":    "Statistics of the range of fee rates the estimate was found in",
+	"estimaterawfeehorizonresult-fail":    "Statistics of the range of fee rates just above it that failed the threshold",
+	"estimaterawfeehorizonresult-errors":  "Errors encountered while estimating",
+
+	// EstimateRawFeeBucketResult help.
+	"estimaterawfeebucketresult-startrange":     "Lowest fee rate of the range in satoshis per kB",
+	"estimaterawfeebucketresult-endrange":       "Highest fee rate of the range in satoshis per kB",
+	"estimaterawfeebucketresult-withintarget":   "Decayed number of transactions of the range confirmed within the target",
+	"estimaterawfeebucketresult-totalconfirmed": "Decayed number of transactions of the range confirmed at all",
+	"estimaterawfeebucketresult-inmempool":      "Number of transactions of the range in the mempool that weren't confirmed within the target",
+	"estimaterawfeebucketresult-leftmempool":    "Decayed number of transactions of the range that left the mempool without being confirmed",
+
+	// FinalizePsbtCmd help.
+	"finalizepsbt--synopsis": "Finalizes the inputs of a psbt that have all of their signatures.\n" +
+		"The transaction is extracted from the psbt when all of its inputs are finalized.",
+	"finalizepsbt-psbt":    "The base64 encoded psbt",
+	"finalizepsbt-extract": "Whether to return the extracted transaction instead of the psbt when it's complete",
+
+	// FinalizePsbtResult help.
+	"finalizepsbtresult-psbt":     "The base64 encoded psbt (only when the transaction wasn't extracted)",
+	"finalizepsbtresult-hex":      "Hex-encoded bytes of the extracted transaction (only when it was extracted)",
+	"finalizepsbtresult-complete": "Whether all of the inputs of the psbt are finalized",
+
 	// FreshAddressCmd help.
 	"freshaddress--synopsis": "Returns an address of the next derivation index regardless of if the " +
 		"preivous derivation address has received funds or not.",
+	"freshaddress-label": "The label to attach to the address",
 
 	// GenerateCmd help
 	"generate--synopsis": "Generates a set number of blocks (simnet or regtest only) and returns a JSON\n" +
@@ -205,7 +381,7 @@ var helpDescsEnUS = map[string]string{
 	// GetBlockCmd help.
 	"getblock--synopsis":   "Returns information about a block given its hash.",
 	"getblock-hash":        "The hash of the block",
-	"getblock-verbosity":   "Specifies whether the block data should be returned as a hex-encoded string (0), as parsed data with a slice of TXIDs (1), or as parsed data with parsed transaction data (2) ",
+	"getblock-verbosity":   "Specifies whether the block data should be returned as a hex-encoded string (0), as parsed data with a slice of TXIDs (1), as parsed data with parsed transaction data (2), or as parsed data with parsed transaction data and the outputs spent by each input (3)",
 	"getblock--condition0": "verbosity=0",
 	"getblock--condition1": "verbosity=1",
 	"getblock--result0":    "Hex-encoded bytes of the serialized block",
@@ -225,6 +401,7 @@ var helpDescsEnUS = map[string]string{
 	"getblockchaininforesult-pruneheight":          "The lowest block retained in the current pruned chain",
 	"getblockchaininforesult-chainwork":            "The total cumulative work in the best chain",
 	"getblockchaininforesult-size_on_disk":         "The estimated size of the block and undo files on disk",
+	"getblockchaininforesult-warnings":             "Warnings about the peers presenting conflicting chains with a lot of work, which may mean the node is eclipsed or its tip is stale",
 	"getblockchaininforesult-initialblockdownload": "Estimate of whether this node is in Initial Block Download mode",
 	"getblockchaininforesult-softforks":            "The status of the super-majority soft-forks",
 	"getblockchaininforesult-unifiedsoftforks":     "The status of the super-majority soft-forks used by bitcoind on or after v0.19.0",
@@ -289,7 +466,7 @@ var helpDescsEnUS = map[string]string{
 	"getblockverboseresult-versionHex":        "The block version in hexadecimal",
 	"getblockverboseresult-merkleroot":        "Root hash of the merkle tree",
 	"getblockverboseresult-tx":                "The transaction hashes (only when verbosity=1)",
-	"getblockverboseresult-rawtx":             "The transactions as JSON objects (only when verbosity=2)",
+	"getblockverboseresult-rawtx":             "The transactions as JSON objects (only when verbosity=2 or verbosity=3)",
 	"getblockverboseresult-time":              "The block time in seconds since 1 Jan 1970 GMT",
 	"getblockverboseresult-nonce":             "The block nonce",
 	"getblockverboseresult-bits":              "The bits which represent the block difficulty",
@@ -330,9 +507,20 @@ var helpDescsEnUS = map[string]string{
 	"getblockheaderverboseresult-previousblockhash": "The hash of the previous block",
 	"getblockheaderverboseresult-nextblockhash":     "The hash of the next block (only if there is one)",
 
+	// GetBlocksByTimeCmd help.
+	"getblocksbytime--synopsis": "Returns the blocks in the main chain with a timestamp at or after the start time and before the end time, ordered by timestamp and then by height.\n" +
+		"Requires the block time index to be enabled (--blocktimeindex).",
+	"getblocksbytime-starttime": "The earliest block timestamp to return in seconds since 1 Jan 1970 GMT",
+	"getblocksbytime-endtime":   "The block timestamp to stop before in seconds since 1 Jan 1970 GMT",
+
+	// GetBlocksByTimeResult help.
+	"getblocksbytimeresult-hash":   "The hash of the block",
+	"getblocksbytimeresult-height": "The height of the block",
+	"getblocksbytimeresult-time":   "The timestamp of the block in seconds since 1 Jan 1970 GMT",
+
 	// TemplateRequest help.
 	"templaterequest-mode":         "This is 'template', 'proposal', or omitted",
-	"templaterequest-capabilities": "List of capabilities",
+	"templaterequest-capabilities": "List of capabilities; 'utreexoproof' requests the utreexo proof of the transaction inputs",
 	"templaterequest-longpollid":   "The long poll ID of a job to monitor for expiration; required and valid only for long poll requests ",
 	"templaterequest-sigoplimit":   "Number of signature operations allowed in blocks (this parameter is ignored)",
 	"templaterequest-sizelimit":    "Number of bytes allowed in blocks (this parameter is ignored)",
@@ -342,8 +530,12 @@ var helpDescsEnUS = map[string]string{
 	"templaterequest-workid":       "The server provided workid if provided in block template (not applicable)",
 	"templaterequest-rules":        "Specific block rules that are to be enforced e.g. '[\"segwit\"]",
 
+	"templaterequest-blockmintxfee":  "Minimum fee rate in BTC/kB of the transactions of the template, overriding --blockmintxfee",
+	"templaterequest-blockmaxweight": "Maximum weight of the template, overriding --blockmaxweight",
+	"templaterequest-basetemplate":   "Long poll ID of a previously returned template to return the template as a delta against",
+
 	// GetBlockTemplateResultTx help.
-	"getblocktemplateresulttx-data":    "Hex-encoded transaction data (byte-for-byte)",
+	"getblocktemplateresulttx-data":    "Hex-encoded transaction data (byte-for-byte); omitted for the transactions of the base template",
 	"getblocktemplateresulttx-hash":    "Hex-encoded transaction hash (little endian if treated as a 256-bit number)",
 	"getblocktemplateresulttx-depends": "Other transactions before this one (by 1-based index in the 'transactions'  list) that must be present in the final block if this one is",
 	"getblocktemplateresulttx-fee":     "Difference in value between transaction inputs and outputs (in Satoshi)",
@@ -376,10 +568,25 @@ var helpDescsEnUS = map[string]string{
 	"getblocktemplateresult-mintime":                    "Minimum allowed time",
 	"getblocktemplateresult-mutable":                    "List of mutations the server explicitly allows",
 	"getblocktemplateresult-noncerange":                 "Two concatenated hex-encoded big-endian 32-bit integers which represent the valid ranges of nonces the miner may scan",
-	"getblocktemplateresult-capabilities":               "List of server capabilities including 'proposal' to indicate support for block proposals",
+	"getblocktemplateresult-capabilities":               "List of server capabilities including 'proposal' to indicate support for block proposals and 'utreexoproof' to indicate support for proving the transaction inputs",
 	"getblocktemplateresult-reject-reason":              "Reason the proposal was invalid as-is (only applies to proposal responses)",
 	"getblocktemplateresult-default_witness_commitment": "The witness commitment itself. Will be populated if the block has witness data",
 	"getblocktemplateresult-weightlimit":                "The current limit on the max allowed weight of a block",
+	"getblocktemplateresult-basetemplate":               "Long poll ID of the template the result is a delta against; the data of its transactions is omitted",
+	"getblocktemplateresult-utreexoproof":               "The hex-encoded utreexo proof of the transaction inputs (only with the 'utreexoproof' capability)",
+
+	// GetBlockTTLsCmd help.
+	"getblockttls--synopsis": "Returns the time to live of every leaf a block added to the utreexo accumulator, which is the number of blocks after which it's spent.\n" +
+		"The outputs spent in the same block and the unspendable outputs aren't leaves and are left out.\n" +
+		"Requires the ttl index to be enabled (--ttlindex).",
+	"getblockttls-blockhash": "The hash of the block",
+
+	// GetBlockTTLResult help.
+	"getblockttlresult-txid":        "The hash of the transaction of the leaf",
+	"getblockttlresult-vout":        "The index of the output of the leaf",
+	"getblockttlresult-index":       "The index of the output among all the outputs of the block, which is how the remember indexes of utreexo proofs refer to the leaf",
+	"getblockttlresult-ttl":         "The number of blocks after which the leaf is spent or -1 if it's unspent",
+	"getblockttlresult-spendheight": "The height of the block that spends the leaf (only for spent leaves)",
 
 	// GetBlockTemplateCmd help.
 	"getblocktemplate--synopsis": "Returns a JSON object with information necessary to construct a block to mine or accepts a proposal to validate.\n" +
@@ -399,6 +606,21 @@ var helpDescsEnUS = map[string]string{
 	// GetChainTipsCmd help.
 	"getchaintips--synopsis": "Returns information about all known tips in the block tree, including the main chain as well as orphaned branches.",
 
+	// GetChainTxStatsCmd help.
+	"getchaintxstats--synopsis": "Returns statistics about the total number and rate of transactions in the main chain.",
+	"getchaintxstats-nblocks":   "Size of the window in number of blocks (default: one month)",
+	"getchaintxstats-blockhash": "The hash of the block that ends the window (default: the best block)",
+
+	// GetChainTxStatsResult help.
+	"getchaintxstatsresult-time":                      "The median time of the final block of the window",
+	"getchaintxstatsresult-txcount":                   "The total number of transactions in the chain up to that point",
+	"getchaintxstatsresult-window_final_block_hash":   "The hash of the final block of the window",
+	"getchaintxstatsresult-window_final_block_height": "The height of the final block of the window",
+	"getchaintxstatsresult-window_block_count":        "Size of the window in number of blocks",
+	"getchaintxstatsresult-window_tx_count":           "The number of transactions in the window (zero when the window is empty)",
+	"getchaintxstatsresult-window_interval":           "The elapsed time in the window in seconds (zero when the window is empty)",
+	"getchaintxstatsresult-txrate":                    "The average number of transactions per second in the window (zero when the interval is zero)",
+
 	// GetCFilterCmd help.
 	"getcfilter--synopsis":  "Returns a block's committed filter given its hash.",
 	"getcfilter-filtertype": "The type of filter to return (0=regular)",
@@ -419,6 +641,30 @@ var helpDescsEnUS = map[string]string{
 	"getcurrentnet--synopsis": "Get bitcoin network the server is running on.",
 	"getcurrentnet--result0":  "The network identifer",
 
+	// GetDBCompactionInfoCmd help.
+	"getdbcompactioninfo--synopsis": "Returns the progress of the running compaction of the block database, or how the last one went when none is running.",
+
+	// GetDBCompactionInfoResult help.
+	"getdbcompactioninforesult-running":        "Whether a compaction is running",
+	"getdbcompactioninforesult-progress":       "The fraction of the key ranges compacted so far, from 0 to 1",
+	"getdbcompactioninforesult-ranges_done":    "The number of key ranges compacted so far",
+	"getdbcompactioninforesult-ranges_total":   "The number of key ranges the database is compacted in",
+	"getdbcompactioninforesult-last_started":   "The time the last compaction started in seconds since 1 Jan 1970 GMT",
+	"getdbcompactioninforesult-last_finished":  "The time the last compaction finished in seconds since 1 Jan 1970 GMT",
+	"getdbcompactioninforesult-last_error":     "The error the last compaction failed with",
+	"getdbcompactioninforesult-next_scheduled": "The time the next scheduled compaction is due in seconds since 1 Jan 1970 GMT, when --dbcompactinterval is set",
+
+	// GetDescriptorInfoCmd help.
+	"getdescriptorinfo--synopsis":  "Returns information about an output descriptor.",
+	"getdescriptorinfo-descriptor": "The output descriptor",
+
+	// GetDescriptorInfoResult help.
+	"getdescriptorinforesult-descriptor":     "The descriptor with its private keys replaced by their public keys, along with its checksum",
+	"getdescriptorinforesult-checksum":       "The checksum of the descriptor as given",
+	"getdescriptorinforesult-isrange":        "Whether the descriptor has keys derived from a range of child indexes",
+	"getdescriptorinforesult-issolvable":     "Whether the descriptor has the keys needed to spend its scripts, which is the case for all but addr() and raw()",
+	"getdescriptorinforesult-hasprivatekeys": "Whether the descriptor has at least one private key",
+
 	// GetDifficultyCmd help.
 	"getdifficulty--synopsis": "Returns the proof-of-work difficulty as a multiple of the minimum difficulty.",
 	"getdifficulty--result0":  "The difficulty",
@@ -467,15 +713,78 @@ var helpDescsEnUS = map[string]string{
 	"getheaders-hashstop":      "Block hash to stop including block headers for; if not found, all headers to the latest known block are returned.",
 	"getheaders--result0":      "Serialized block headers of all located blocks, limited to some arbitrary maximum number of hashes (currently 2000, which matches the wire protocol headers message, but this is not guaranteed)",
 
+	// GetIndexInfoCmd help.
+	"getindexinfo--synopsis":       "Returns the status of the enabled indexes.",
+	"getindexinfo-indexname":       "Only return the status of the index with this name",
+	"getindexinfo--result0--desc":  "Index statuses keyed by the name of the index",
+	"getindexinfo--result0--key":   "Name of the index (txindex, addrindex, cfindex, ttlindex, utreexoproofindex or flatutreexoproofindex)",
+	"getindexinfo--result0--value": "Object containing the status of the index",
+
+	// GetIndexInfoResult help.
+	"getindexinforesult-synced":            "Whether the index is caught up to the best block of the chain",
+	"getindexinforesult-best_block_height": "The height of the block the index is caught up to",
+	"getindexinforesult-best_block_hash":   "The hash of the block the index is caught up to",
+	"getindexinforesult-size_on_disk":      "The size of the data of the index in bytes",
+
 	// GetInfoCmd help.
 	"getinfo--synopsis": "Returns a JSON object containing various state info.",
 
+	// GetMempoolAncestorsCmd help.
+	"getmempoolancestors--synopsis":   "Returns all of the unconfirmed ancestors of a transaction in the memory pool.",
+	"getmempoolancestors-txid":        "The hash of the transaction",
+	"getmempoolancestors-verbose":     "Returns JSON objects keyed by the transaction hashes when true or an array of transaction hashes when false",
+	"getmempoolancestors--condition0": "verbose=false",
+	"getmempoolancestors--condition1": "verbose=true",
+	"getmempoolancestors--result0":    "Array of the hashes of the ancestors",
+
+	// GetMempoolDescendantsCmd help.
+	"getmempooldescendants--synopsis":   "Returns all of the unconfirmed descendants of a transaction in the memory pool.",
+	"getmempooldescendants-txid":        "The hash of the transaction",
+	"getmempooldescendants-verbose":     "Returns JSON objects keyed by the transaction hashes when true or an array of transaction hashes when false",
+	"getmempooldescendants--condition0": "verbose=false",
+	"getmempooldescendants--condition1": "verbose=true",
+	"getmempooldescendants--result0":    "Array of the hashes of the descendants",
+
+	// GetMempoolEntryCmd help.
+	"getmempoolentry--synopsis": "Returns information about a transaction in the memory pool.",
+	"getmempoolentry-txid":      "The hash of the transaction",
+
+	// GetMempoolEntryResult help.
+	"getmempoolentryresult-vsize":              "The virtual size of the transaction",
+	"getmempoolentryresult-size":               "The size of the transaction in bytes",
+	"getmempoolentryresult-weight":             "The weight of the transaction",
+	"getmempoolentryresult-fee":                "The fee of the transaction in bitcoins (deprecated, use fees-base)",
+	"getmempoolentryresult-modifiedfee":        "The fee of the transaction in bitcoins, which is the same as fee since fees can't be modified (deprecated, use fees-modified)",
+	"getmempoolentryresult-time":               "Local time the transaction entered the pool in seconds since 1 Jan 1970 GMT",
+	"getmempoolentryresult-height":             "Block height when the transaction entered the pool",
+	"getmempoolentryresult-descendantcount":    "The number of descendants of the transaction in the pool, including the transaction itself",
+	"getmempoolentryresult-descendantsize":     "The virtual size of the descendants of the transaction in the pool, including the transaction itself",
+	"getmempoolentryresult-descendantfees":     "The fees of the descendants of the transaction in the pool in satoshi, including the transaction itself (deprecated, use fees-descendant)",
+	"getmempoolentryresult-ancestorcount":      "The number of ancestors of the transaction in the pool, including the transaction itself",
+	"getmempoolentryresult-ancestorsize":       "The virtual size of the ancestors of the transaction in the pool, including the transaction itself",
+	"getmempoolentryresult-ancestorfees":       "The fees of the ancestors of the transaction in the pool in satoshi, including the transaction itself (deprecated, use fees-ancestor)",
+	"getmempoolentryresult-wtxid":              "The witness hash of the transaction",
+	"getmempoolentryresult-fees":               "The fees of the transaction and its ancestors and descendants in bitcoins",
+	"getmempoolentryresult-depends":            "Unconfirmed transactions used as inputs for this transaction",
+	"getmempoolentryresult-spentby":            "Unconfirmed transactions spending outputs of this transaction",
+	"getmempoolentryresult-bip125-replaceable": "Whether the transaction or one of its unconfirmed ancestors signals replaceability through BIP 125",
+
+	// MempoolFees help.
+	"mempoolfees-base":       "The fee of the transaction",
+	"mempoolfees-modified":   "The fee of the transaction, which is the same as base since fees can't be modified",
+	"mempoolfees-ancestor":   "The fees of the ancestors of the transaction in the pool, including the transaction itself",
+	"mempoolfees-descendant": "The fees of the descendants of the transaction in the pool, including the transaction itself",
+
 	// GetMempoolInfoCmd help.
 	"getmempoolinfo--synopsis": "Returns memory pool information",
 
 	// GetMempoolInfoResult help.
-	"getmempoolinforesult-bytes": "Size in bytes of the mempool",
-	"getmempoolinforesult-size":  "Number of transactions in the mempool",
+	"getmempoolinforesult-bytes":         "Size in bytes of the mempool",
+	"getmempoolinforesult-size":          "Number of transactions in the mempool",
+	"getmempoolinforesult-maxmempool":    "Maximum size in bytes of the mempool, after which the transactions paying the lowest fee rates are evicted",
+	"getmempoolinforesult-mempoolminfee": "Minimum fee rate in BTC/kB for transactions to be accepted, which is raised above minrelaytxfee while the mempool is full",
+	"getmempoolinforesult-minrelaytxfee": "Minimum fee rate in BTC/kB for transactions to be relayed",
+	"getmempoolinforesult-mempoolexpiry": "Number of hours after which transactions that weren't mined are removed from the mempool",
 
 	// GetMiningInfoResult help.
 	"getmininginforesult-blocks":             "Height of the latest best block",
@@ -504,6 +813,44 @@ var helpDescsEnUS = map[string]string{
 	"getnetworkhashps-height":    "Perform estimate ending with this height or -1 for current best chain block height",
 	"getnetworkhashps--result0":  "Estimated hashes per second",
 
+	// GetNetworkInfoCmd help.
+	"getnetworkinfo--synopsis": "Returns a JSON object containing information about the P2P networking of the node.",
+
+	// GetNetworkInfoResult help.
+	"getnetworkinforesult-version":         "The version of the server",
+	"getnetworkinforesult-subversion":      "The user agent the server sends to peers",
+	"getnetworkinforesult-protocolversion": "The latest supported protocol version",
+	"getnetworkinforesult-localservices":   "The services the server advertises to peers in hex",
+	"getnetworkinforesult-localrelay":      "Whether transactions are relayed from peers",
+	"getnetworkinforesult-timeoffset":      "The time offset",
+	"getnetworkinforesult-connections":     "The number of connected peers",
+	"getnetworkinforesult-networkactive":   "Whether the P2P networking is enabled",
+	"getnetworkinforesult-networks":        "Information about each of the networks",
+	"getnetworkinforesult-relayfee":        "Minimum fee rate in BTC/kB for transactions to be relayed",
+	"getnetworkinforesult-incrementalfee":  "Minimum fee rate increase in BTC/kB for replacements of transactions",
+	"getnetworkinforesult-localaddresses":  "The local addresses advertised to peers",
+	"getnetworkinforesult-portmapping":     "The state of the mapping of the listening port outside of NAT (only when a UPnP, NAT-PMP or PCP router was found)",
+	"getnetworkinforesult-warnings":        "Warnings about the peers presenting conflicting chains with a lot of work",
+
+	// NetworksResult help.
+	"networksresult-name":                        "The network, which is ipv4, ipv6, onion or i2p",
+	"networksresult-limited":                     "Whether the peers on the network are out of reach",
+	"networksresult-reachable":                   "Whether peers on the network can be connected to",
+	"networksresult-proxy":                       "The proxy or I2P SAM bridge peers on the network are connected through",
+	"networksresult-proxy_randomize_credentials": "Whether the proxy credentials are randomized for Tor stream isolation",
+
+	// LocalAddressesResult help.
+	"localaddressesresult-address": "The local address",
+	"localaddressesresult-port":    "The port of the local address",
+	"localaddressesresult-score":   "The priority of the local address",
+
+	// PortMappingResult help.
+	"portmappingresult-protocol":        "The protocol the port is mapped with, which is UPnP, NAT-PMP or PCP",
+	"portmappingresult-externaladdress": "The external address of the router",
+	"portmappingresult-externalport":    "The external port the listening port is mapped to",
+	"portmappingresult-lastrenewal":     "The time the mapping was last added or renewed in seconds since 1 Jan 1970 GMT",
+	"portmappingresult-error":           "The error of the last attempt to add or renew the mapping, if it failed",
+
 	// GetNetTotalsCmd help.
 	"getnettotals--synopsis": "Returns a JSON object containing network traffic statistics.",
 
@@ -514,6 +861,15 @@ var helpDescsEnUS = map[string]string{
 	"getnettotalsresult-totalbytesrecv": "Total bytes received",
 	"getnettotalsresult-totalbytessent": "Total bytes sent",
 	"getnettotalsresult-timemillis":     "Number of milliseconds since 1 Jan 1970 GMT",
+	"getnettotalsresult-uploadtarget":   "The upload target, which is only set with --maxuploadtarget",
+
+	// UploadTargetResult help.
+	"uploadtargetresult-timeframe":               "The length of the cycles of the upload target in seconds",
+	"uploadtargetresult-target":                  "The bytes that may be sent to peers per cycle",
+	"uploadtargetresult-target_reached":          "Whether the bytes sent in the current cycle reached the target",
+	"uploadtargetresult-serve_historical_blocks": "Whether the blocks and utreexo proofs older than a week are still served to the peers without the download permission",
+	"uploadtargetresult-bytes_left_in_cycle":     "The bytes left in the target for the current cycle",
+	"uploadtargetresult-time_left_in_cycle":      "The seconds left in the current cycle",
 
 	// GetTxTotalsResult help.
 	"gettxtotalsresult-totaltxbytesrecv":    "Total tx bytes received",
@@ -557,6 +913,16 @@ var helpDescsEnUS = map[string]string{
 	"getpeerinforesult-banscore":       "The ban score",
 	"getpeerinforesult-feefilter":      "The requested minimum fee a transaction must have to be announced to the peer",
 	"getpeerinforesult-syncnode":       "Whether or not the peer is the sync peer",
+	"getpeerinforesult-blockrelayonly": "Whether or not the peer is only used to relay blocks, which the anchors saved on shutdown are",
+	"getpeerinforesult-permissions":    "The permissions the peer was given by --whitelist and --whitebind",
+	"getpeerinforesult-utreexo":        "The utreexo data of the peer, only present for peers that serve utreexo proofs",
+
+	// GetPeerInfoUtreexoResult help.
+	"getpeerinfoutreexoresult-nodetype":             "The type of utreexo node of the peer (bridge or csn)",
+	"getpeerinfoutreexoresult-proofversion":         "The utreexo proof version used with the peer, 0 if proofs aren't exchanged",
+	"getpeerinfoutreexoresult-proofbytessent":       "Total bytes of utreexo proofs sent",
+	"getpeerinfoutreexoresult-proofbytesrecv":       "Total bytes of utreexo proofs received",
+	"getpeerinfoutreexoresult-proofrequestfailures": "Number of utreexo proofs requested from the peer that were not found or invalid",
 
 	// GetPeerInfoCmd help.
 	"getpeerinfo--synopsis": "Returns data about each connected network peer as an array of json objects.",
@@ -587,6 +953,26 @@ var helpDescsEnUS = map[string]string{
 	"getrawtransaction--condition1": "verbose=true",
 	"getrawtransaction--result0":    "Hex-encoded bytes of the serialized transaction",
 
+	// GetScriptBalanceCmd help.
+	"getscriptbalance--synopsis": "Returns the confirmed balance and the Electrum status of the confirmed history of a script.\n" +
+		"The status is the hex-encoded sha256 of the concatenation of 'txid:height:' for every transaction in the main chain involving the script.\n" +
+		"Requires the script balance index to be enabled (--scriptbalanceindex).",
+	"getscriptbalance-scripthash": "The Electrum script hash of the script, the byte-reversed hex-encoded sha256 of the script",
+
+	// GetScriptBalanceResult help.
+	"getscriptbalanceresult-confirmed": "The sum of the values of the confirmed unspent outputs paying to the script in satoshis",
+	"getscriptbalanceresult-txcount":   "The number of transactions in the main chain involving the script",
+	"getscriptbalanceresult-status":    "The Electrum status of the confirmed history of the script (only when it has any transactions)",
+
+	// GetSilentPaymentTweaksCmd help.
+	"getsilentpaymenttweaks--synopsis": "Returns the BIP-352 silent payment tweaks of the transactions in a block that may contain silent payments.\n" +
+		"Requires the silent payment index to be enabled (--silentpaymentindex).",
+	"getsilentpaymenttweaks-blockhash": "The hash of the block",
+
+	// GetSilentPaymentTweakResult help.
+	"getsilentpaymenttweakresult-txid":  "The hash of the transaction",
+	"getsilentpaymenttweakresult-tweak": "The hex-encoded compressed tweak of the transaction, the sum of the public keys of its eligible inputs multiplied by the hash of its inputs",
+
 	// GetTTLCmd help.
 	"getttl--synopsis": "Returns the time to live value for a spent transaction output.",
 	"getttl-txid":      "The hash of the transaction",
@@ -609,6 +995,50 @@ var helpDescsEnUS = map[string]string{
 	"gettxout-vout":           "The index of the output",
 	"gettxout-includemempool": "Include the mempool when true",
 
+	// GetTxOutSetInfoCmd help.
+	"gettxoutsetinfo--synopsis": "Returns statistics about the unspent transaction output set.\n" +
+		"Nodes that keep the utxo set, which includes bridge nodes, scan it and return all of the fields.\n" +
+		"Compact state nodes only keep the utreexo accumulator and return the height, bestblock, mode, and utreexo fields.",
+
+	// GetTxOutSetInfoResult help.
+	"gettxoutsetinforesult-height":            "The height of the block the statistics are for",
+	"gettxoutsetinforesult-bestblock":         "The hash of the block the statistics are for",
+	"gettxoutsetinforesult-mode":              "'utxoset' when the node keeps the utxo set or 'accumulator' when it only keeps the utreexo accumulator",
+	"gettxoutsetinforesult-transactions":      "The number of transactions with unspent outputs (only in utxoset mode)",
+	"gettxoutsetinforesult-txouts":            "The number of unspent transaction outputs (only in utxoset mode)",
+	"gettxoutsetinforesult-bogosize":          "A database-independent metric for the size of the utxo set (only in utxoset mode)",
+	"gettxoutsetinforesult-hash_serialized_2": "The hash of the serialized utxo set, computed the same way as Bitcoin Core (only in utxoset mode)",
+	"gettxoutsetinforesult-disk_size":         "The size of the utxo set in the database in bytes (only in utxoset mode)",
+	"gettxoutsetinforesult-total_amount":      "The total amount of the unspent transaction outputs in BTC (only in utxoset mode)",
+	"gettxoutsetinforesult-utreexo":           "The utreexo accumulator at the block (only when the node keeps the accumulator at the block)",
+
+	// GetTxOutSetInfoUtreexoResult help.
+	"gettxoutsetinfoutreexoresult-numleaves": "The number of leaves added to the accumulator",
+	"gettxoutsetinfoutreexoresult-roots":     "The roots of the accumulator",
+
+	// GetTxSpendingPrevOutCmd help.
+	"gettxspendingprevout--synopsis": "Returns the transactions spending the passed outputs.\n" +
+		"The memory pool is checked first, and the outputs spent in the main chain are looked up in the spend index when it's enabled (--spendindex).",
+	"gettxspendingprevout-outputs": "The outputs to find the spending transactions of",
+
+	// GetTxSpendingPrevOutResult help.
+	"gettxspendingprevoutresult-txid":         "The hash of the transaction of the output",
+	"gettxspendingprevoutresult-vout":         "The index of the output",
+	"gettxspendingprevoutresult-spendingtxid": "The hash of the transaction spending the output (only when a spender was found)",
+	"gettxspendingprevoutresult-blockhash":    "The hash of the block of the spending transaction (only when it's in the main chain)",
+
+	// GetUtreexoCFHeaderCmd help.
+	"getutreexocfheader--synopsis": "Returns the filter header of a block that commits to both its BIP-158 basic filter and the utreexo roots after it.\n" +
+		"The header is the double-SHA256 of the filter hash, the roots hash and the previous header.\n" +
+		"Requires the utreexo committed filter header index to be enabled (--utreexocfindex).",
+	"getutreexocfheader-blockhash": "The hash of the block",
+
+	// GetUtreexoCFHeaderResult help.
+	"getutreexocfheaderresult-header":     "The filter header of the block",
+	"getutreexocfheaderresult-prevheader": "The filter header of the previous block (all zeros for the genesis block)",
+	"getutreexocfheaderresult-filterhash": "The hash of the BIP-158 basic filter of the block",
+	"getutreexocfheaderresult-rootshash":  "The double-SHA256 of the 8 bytes little-endian number of leaves followed by the utreexo roots after the block",
+
 	// GetUtreexoProof help.
 	"getutreexoproof--synopsis": "Returns an utreexo accumulator proof and the leaf preimages for the desired block",
 	"getutreexoproof-blockhash": "The block hash where the utreexo proof was created",
@@ -624,13 +1054,54 @@ var helpDescsEnUS = map[string]string{
 		"The locations of the given UTXOs in the accumulator.",
 
 	// GetUtreexoRoots help.
-	"getutreexoroots--synopsis": "Returns an utreexo accumulator roots and the number of leaves at the desired block",
-	"getutreexoroots-blockhash": "The block in which to fetch the accumulator state",
+	"getutreexoroots--synopsis":    "Returns an utreexo accumulator roots and the number of leaves after the desired block was connected",
+	"getutreexoroots-hashorheight": "The hash or the height of the block in which to fetch the accumulator state. Defaults to the chain tip",
+
+	// HashOrHeight help.
+	"hashorheight-value": "The hash as a string or the height as a number of the block",
 
 	// GetUtreexoRootsResult help.
+	"getutreexorootsresult-blockhash": "The hash of the block the accumulator state is of",
+	"getutreexorootsresult-height":    "The height of the block the accumulator state is of",
 	"getutreexorootsresult-numleaves": "The number of leaves committed in the accumulator at the given block",
 	"getutreexorootsresult-roots":     "The roots of the accumulator at the given block",
 
+	// GetUtreexoStatsCmd help.
+	"getutreexostats--synopsis": "Returns statistics about the utreexo accumulator of the node and the proofs served to peers",
+
+	// GetUtreexoStatsResult help.
+	"getutreexostatsresult-numleaves":                "The number of leaves ever added to the accumulator",
+	"getutreexostatsresult-numroots":                 "The number of roots the accumulator currently has",
+	"getutreexostatsresult-statesize":                "The size in bytes of the files the accumulator is stored in (0 for compact state nodes)",
+	"getutreexostatsresult-nodescachehits":           "The number of accumulator node lookups served from the cache",
+	"getutreexostatsresult-nodescachemisses":         "The number of accumulator node lookups not served from the cache",
+	"getutreexostatsresult-nodescachehitrate":        "The ratio of accumulator node lookups served from the cache",
+	"getutreexostatsresult-cachedleavescachehits":    "The number of leaf position lookups served from the cache",
+	"getutreexostatsresult-cachedleavescachemisses":  "The number of leaf position lookups not served from the cache",
+	"getutreexostatsresult-cachedleavescachehitrate": "The ratio of leaf position lookups served from the cache",
+	"getutreexostatsresult-proofsserved":             "The number of proofs served to peers",
+	"getutreexostatsresult-proofbytesserved":         "The total size in bytes of the proofs served to peers",
+	"getutreexostatsresult-avgproofsize":             "The average size in bytes of the proofs served to peers",
+	"getutreexostatsresult-prooforphans":             "The number of relayed transactions currently waiting for a fresh proof since theirs couldn't prove their inputs",
+	"getutreexostatsresult-prooforphanstotal":        "The number of relayed transactions that were queued for a fresh proof",
+	"getutreexostatsresult-proofrequests":            "The number of fresh proofs requested from peers",
+	"getutreexostatsresult-prooforphansresolved":     "The number of queued transactions accepted once a fresh proof was received",
+	"getutreexostatsresult-prooforphansdropped":      "The number of queued transactions rejected after too many requests or since their peer disconnected",
+
+	// GetUtxoProofCmd help.
+	"getutxoproof--synopsis": "Returns an utreexo accumulator proof for the given UTXOs against the utreexo state at the chain tip",
+	"getutxoproof-outpoints": "The outpoints of the UTXOs to prove",
+	"getutxoproof-blockhash": "The block of the accumulator state the proof is expected to be against. An error is returned if it's not the chain tip",
+
+	// GetUtxoProofResult help.
+	"getutxoproofresult-provedathash": "The blockhash at which the proof was generated at. The proof will not verify if the blockhash is different",
+	"getutxoproofresult-proofhashes": "One half of the utreexo accumulator proof (the other half being prooftargets).\n" +
+		"The proof hashes for the utreexo accumulator proof of the given UTXOs.",
+	"getutxoproofresult-prooftargets": "One half of the utreexo accumulator proof (the other half being proofhashes).\n" +
+		"The locations of the given UTXOs in the accumulator.",
+	"getutxoproofresult-hashesproven": "The hashes of the UTXOs that are committed in the accumulator",
+	"getutxoproofresult-hex":          "The serialized proof as a hex string",
+
 	// GetWatchOnlyBalanceCmd help.
 	"getwatchonlybalance--synopsis": "Returns the total balance of the watch only wallet",
 	"getwatchonlybalance--result0":  "The total balance of the watch only wallet in satoshis",
@@ -647,6 +1118,33 @@ var helpDescsEnUS = map[string]string{
 	"help--result0":    "List of commands",
 	"help--result1":    "Help for specified command",
 
+	// ImportBDKDescriptorCmd help.
+	"importbdkdescriptor--synopsis": "Imports a ranged wpkh or tr output descriptor into the bdk wallet and rescans the blocks from its birthday.\n" +
+		"A tr descriptor may have a script tree such as tr(xpub/0/*,{pk(xpub/0/*),and_v(v:pk(xpub/0/*),older(144))}), whose outputs are spent through the leaves the wallet has the keys of when it doesn't have the internal key.\n" +
+		"A multi-path descriptor such as wpkh(xpub/<0;1>/*) imports its second path as the change keychain.\n" +
+		"When the compact filter index is enabled (--cfilters), only the blocks whose filters match the scripts of the wallet are rescanned.",
+	"importbdkdescriptor-descriptor": "The output descriptor to import, with public or private keys",
+	"importbdkdescriptor-birthday":   "The height of the first block that may hold outputs of the descriptor",
+
+	// ImportSilentPaymentKeyCmd help.
+	"importsilentpaymentkey--synopsis": "Registers the keys of a BIP352 silent payment address with the bdk wallet and scans the blocks from its birthday for the outputs paying to it.\n" +
+		"The blocks are scanned with the tweaks of the silent payment index when it's enabled (--silentpaymentindex) and with the tweaks computed from their spent outputs otherwise.\n" +
+		"The outputs are included in the balance, listbdktransactions and listbdkutxos once they're confirmed. A wallet has a single pair of silent payment keys.",
+	"importsilentpaymentkey-scankey":  "The WIF encoded private scan key",
+	"importsilentpaymentkey-spendkey": "The hex encoded compressed public spend key",
+	"importsilentpaymentkey-birthday": "The height of the first block that may hold outputs paying to the keys",
+	"importsilentpaymentkey--result0": "The silent payment address of the keys",
+
+	// ListBDKDescriptorsCmd help.
+	"listbdkdescriptors--synopsis":      "Returns the output descriptors of the bdk wallet, including the imported ones, so that they can be backed up.",
+	"listbdkdescriptors-includeprivate": "Whether to include the private keys in the descriptors",
+
+	// ListBDKDescriptorsResult help.
+	"listbdkdescriptorsresult-descriptor": "The output descriptor.",
+	"listbdkdescriptorsresult-internal":   "Whether the descriptor is used for change outputs.",
+	"listbdkdescriptorsresult-birthday":   "The height of the first block scanned for the descriptor.",
+	"listbdkdescriptorsresult-nextindex":  "The next derivation index that hasn't been revealed yet.",
+
 	// ListBDKTransactionsCmd help.
 	"listbdktransactions--synopsis": "Returns a list of all the relevant transactions the bdk wallet is holding onto",
 
@@ -656,18 +1154,82 @@ var helpDescsEnUS = map[string]string{
 	"listbdktransactionsresult-spent":         "The sum of satoshis that was spent in this tx.",
 	"listbdktransactionsresult-received":      "The sum of satoshis that was received in this tx.",
 	"listbdktransactionsresult-confirmations": "The amount of blockchain confirmations for this tx.",
+	"listbdktransactionsresult-label":         "The label of the tx set with setlabel.",
 
 	// ListBDKUTXOsCmd help.
 	"listbdkutxos--synopsis": "Returns a list of all the relevant utxos the bdk wallet is holding onto",
 
 	// ListBDKUTXOsResult help.
-	"listbdkutxosresult-txid":            "The txid of the relevant utxo.",
-	"listbdkutxosresult-vout":            "The output index of the relevant utxo.",
-	"listbdkutxosresult-amount":          "The amount in satoshis this utxo is worth.",
-	"listbdkutxosresult-scriptpubkey":    "The script pubkey of the utxo.",
-	"listbdkutxosresult-ischange":        "Whether or not this utxo is a change output.",
-	"listbdkutxosresult-derivationindex": "The derivation index of the wallet this utxo is located at.",
-	"listbdkutxosresult-confirmations":   "The total amount of blockchain confirmations this utxo has.",
+	"listbdkutxosresult-txid":               "The txid of the relevant utxo.",
+	"listbdkutxosresult-vout":               "The output index of the relevant utxo.",
+	"listbdkutxosresult-amount":             "The amount in satoshis this utxo is worth.",
+	"listbdkutxosresult-scriptpubkey":       "The script pubkey of the utxo.",
+	"listbdkutxosresult-ischange":           "Whether or not this utxo is a change output.",
+	"listbdkutxosresult-derivationindex":    "The derivation index of the wallet this utxo is located at.",
+	"listbdkutxosresult-confirmations":      "The total amount of blockchain confirmations this utxo has.",
+	"listbdkutxosresult-silentpaymenttweak": "The hex encoded tweak that is added to the spend key to spend a silent payment. Only set for silent payments.",
+	"listbdkutxosresult-label":              "The label of the address the utxo pays to.",
+
+	// ListLockUnspentCmd help.
+	"listlockunspent--synopsis": "Returns the outputs of the bdk wallet that are locked with lockunspent.",
+	"listlockunspent--result0":  "The locked outputs",
+
+	// ListReceivedByAddressCmd help.
+	"listreceivedbyaddress--synopsis":        "Returns the amounts received by the revealed receive addresses of the bdk wallet along with their labels.",
+	"listreceivedbyaddress-minconf":          "The minimum number of confirmations of the transactions counted",
+	"listreceivedbyaddress-includeempty":     "Whether to include the addresses that haven't received anything",
+	"listreceivedbyaddress-includewatchonly": "Unused, the outputs of watch-only wallets are always included",
+
+	// ListReceivedByAddressResult help.
+	"listreceivedbyaddressresult-account":           "Unused, always empty",
+	"listreceivedbyaddressresult-address":           "The receive address",
+	"listreceivedbyaddressresult-amount":            "The total amount in BTC received by the address",
+	"listreceivedbyaddressresult-confirmations":     "The number of confirmations of the most recent transaction paying to the address",
+	"listreceivedbyaddressresult-txids":             "The transactions paying to the address",
+	"listreceivedbyaddressresult-involvesWatchonly": "Unused, always false",
+	"listreceivedbyaddressresult-label":             "The label of the address",
+
+	// ListBannedCmd help.
+	"listbanned--synopsis": "Returns the banned IP addresses and subnets.",
+
+	// ListBannedResult help.
+	"listbannedresult-address":        "The banned IP address or subnet, or Tor or I2P host",
+	"listbannedresult-ban_created":    "The time the ban was created in seconds since 1 Jan 1970 GMT",
+	"listbannedresult-banned_until":   "The time the ban ends in seconds since 1 Jan 1970 GMT",
+	"listbannedresult-ban_duration":   "The duration of the ban in seconds",
+	"listbannedresult-time_remaining": "The time left before the ban ends in seconds",
+	"listbannedresult-ban_reason":     "Why the IP address or subnet was banned",
+
+	// ListWalletsCmd help.
+	"listwallets--synopsis": "Returns the names of the loaded bdk wallets. The default wallet has an empty name.",
+	"listwallets--result0":  "The names of the loaded wallets",
+
+	// LockUnspentCmd help.
+	"lockunspent--synopsis":    "Locks or unlocks outputs of the bdk wallet. Locked outputs are never picked by the coin selection and are only spent when they're given as inputs. The locks are kept across restarts and are included in the backups of the wallet.",
+	"lockunspent-unlock":       "True to unlock the outputs, false to lock them",
+	"lockunspent-transactions": "The outputs to lock or unlock. Unlocking without any outputs unlocks all of them",
+	"lockunspent--result0":     "Always true",
+
+	// LoadWalletCmd help.
+	"loadwallet--synopsis":  "Loads a named bdk wallet that was created with createwallet.",
+	"loadwallet-walletname": "The name of the wallet",
+
+	// LoadWalletResult help.
+	"loadwalletresult-name":    "The name of the wallet",
+	"loadwalletresult-warning": "Warning message if the wallet was not loaded cleanly",
+
+	// LoadTxOutSetCmd help.
+	"loadtxoutset--synopsis": "Loads a snapshot of the unspent transaction output set written by dumptxoutset and makes its block the best block.\n" +
+		"The blocks up to the snapshot block are assumed to be valid and aren't downloaded, so the snapshot has to come from a trusted source.\n" +
+		"The header of the snapshot block must already be known and the snapshot can only be loaded before any blocks are connected, by nodes that keep the utxo set and have no indexes enabled.",
+	"loadtxoutset-path": "The path of the snapshot. Relative paths are relative to the data directory",
+
+	// LoadTxOutSetResult help.
+	"loadtxoutsetresult-coins_loaded":      "The number of unspent transaction outputs that were loaded",
+	"loadtxoutsetresult-tip_hash":          "The hash of the block the snapshot is for, which is now the best block",
+	"loadtxoutsetresult-base_height":       "The height of the block the snapshot is for",
+	"loadtxoutsetresult-path":              "The absolute path of the snapshot",
+	"loadtxoutsetresult-hash_serialized_2": "The hash of the serialized utxo set, to compare against gettxoutsetinfo of a trusted node",
 
 	// PeekAddressCmd help.
 	"peekaddress--synopsis": "Returns an address of the desired derivation index",
@@ -712,6 +1274,75 @@ var helpDescsEnUS = map[string]string{
 	"rebroadcastunconfirmedbdktxs--synopsis": "Rebroadcasts the unconfirmed txs in the bdk wallet to the network. Won't rebroadcast the txs already in this node's mempool.",
 	"rebroadcastunconfirmedbdktxs--result0":  "List of txids of the rebroadcasted txs",
 
+	// ScanBlocksCmd help.
+	"scanblocks--synopsis": "Returns the hashes of the blocks whose compact filters match any of the scan objects in a range of heights.\n" +
+		"The blocks may send to or spend from the scripts of the scan objects, or only match the filters by chance when false positives aren't filtered.\n" +
+		"Requires the compact filter index (--cfilters).",
+	"scanblocks-action":      "'start' to start a scan, 'status' to get the progress of the scan in progress, or 'abort' to abort it",
+	"scanblocks-scanobjects": "The descriptors of the scripts to scan for, which are required for the 'start' action. Ranged descriptors are expanded for their first 1000 child indexes",
+	"scanblocks-startheight": "The height to start scanning from",
+	"scanblocks-stopheight":  "The height to stop scanning at (default: the best block)",
+	"scanblocks-filtertype":  "The type of the filters to match, only 'basic' is supported",
+	"scanblocks-options":     "Options of the scan",
+	"scanblocks--condition0": "action=start",
+	"scanblocks--condition1": "action=status",
+	"scanblocks--condition2": "action=abort",
+	"scanblocks--result0":    "Null when no scan is in progress",
+	"scanblocks--result2":    "Whether the scan in progress was aborted",
+
+	// ScanBlocksOptions help.
+	"scanblocksoptions-filter_false_positives": "Remove the blocks that only match the filters by chance by checking their scripts, which requires their block data",
+
+	// ScanBlocksResult help.
+	"scanblocksresult-from_height":     "The height the scan started at",
+	"scanblocksresult-to_height":       "The height of the last block that was scanned",
+	"scanblocksresult-relevant_blocks": "The hashes of the blocks that match the scan objects",
+	"scanblocksresult-completed":       "Whether the scan was completed instead of aborted",
+
+	// ScanBlocksStatusResult help.
+	"scanblocksstatusresult-progress":       "The percentage of the blocks that were scanned",
+	"scanblocksstatusresult-current_height": "The height of the block being scanned",
+
+	// ScanTxOutSetCmd help.
+	"scantxoutset--synopsis": "Returns the unspent transaction outputs of the utxo set that pay to any of the scripts of the output descriptors.\n" +
+		"The pk(), pkh(), wpkh(), sh(), wsh(), combo(), tr(), addr() and raw() descriptors are supported, except for taproot script trees.\n" +
+		"Requires the utxo set of a bridge node (--noutreexo).",
+	"scantxoutset-action":       "'start' to start a scan, 'status' to get the progress of the scan in progress, or 'abort' to abort it",
+	"scantxoutset-scanobjects":  "The descriptors to scan for, which are required for the 'start' action. Each is either a descriptor or an object with the descriptor and its range",
+	"scantxoutset-includeproof": "Include a utreexo proof of the unspent outputs at the best block, which requires a utreexo proof index",
+	"scantxoutset--condition0":  "action=start",
+	"scantxoutset--condition1":  "action=status",
+	"scantxoutset--condition2":  "action=abort",
+	"scantxoutset--result0":     "Null when no scan is in progress",
+	"scantxoutset--result2":     "Whether the scan in progress was aborted",
+
+	// ScanObject help.
+	"scanobject-desc":  "The output descriptor",
+	"scanobject-range": "The range of child indexes to derive for a ranged descriptor, either as the end or as [begin,end] (default: 999)",
+
+	// ScanTxOutSetResult help.
+	"scantxoutsetresult-success":      "Whether the scan was completed instead of aborted",
+	"scantxoutsetresult-txouts":       "The number of unspent outputs that were scanned",
+	"scantxoutsetresult-height":       "The height of the block the utxo set was scanned at",
+	"scantxoutsetresult-bestblock":    "The hash of the block the utxo set was scanned at",
+	"scantxoutsetresult-unspents":     "The unspent outputs that pay to the descriptors",
+	"scantxoutsetresult-total_amount": "The total amount of the unspent outputs in BTC",
+	"scantxoutsetresult-proof":        "The hex-encoded utreexo proof of the unspent outputs at the scanned block, only set when includeproof is true and outputs were found",
+
+	// ScanTxOutSetUnspent help.
+	"scantxoutsetunspent-txid":          "The hash of the transaction of the output",
+	"scantxoutsetunspent-vout":          "The index of the output",
+	"scantxoutsetunspent-scriptPubKey":  "The hex-encoded script of the output",
+	"scantxoutsetunspent-desc":          "The descriptor of the script of the output",
+	"scantxoutsetunspent-amount":        "The amount of the output in BTC",
+	"scantxoutsetunspent-coinbase":      "Whether the output is from a coinbase transaction",
+	"scantxoutsetunspent-height":        "The height of the block of the output",
+	"scantxoutsetunspent-blockhash":     "The hash of the block of the output",
+	"scantxoutsetunspent-confirmations": "The number of confirmations of the output",
+
+	// ScanTxOutSetStatusResult help.
+	"scantxoutsetstatusresult-progress": "The approximate percentage of the utxo set that was scanned",
+
 	// SearchRawTransactionsCmd help.
 	"searchrawtransactions--synopsis": "Returns raw data for transactions involving the passed address.\n" +
 		"Returned transactions are pulled from both the database, and transactions currently in the mempool.\n" +
@@ -727,8 +1358,25 @@ var helpDescsEnUS = map[string]string{
 	"searchrawtransactions-vinextra":    "Specify that extra data from previous output will be returned in vin",
 	"searchrawtransactions-reverse":     "Specifies that the transactions should be returned in reverse chronological order",
 	"searchrawtransactions-filteraddrs": "Address list.  Only inputs or outputs with matching address will be returned",
+	"searchrawtransactions-minheight":   "Only return transactions confirmed at or above this block height",
+	"searchrawtransactions-maxheight":   "Only return transactions confirmed at or below this block height, which also leaves out the transactions in the mempool",
 	"searchrawtransactions--result0":    "Hex-encoded serialized transaction",
 
+	// SendAllCmd help.
+	"sendall--synopsis":  "Sweeps the unspent outputs of the bdk wallet to an address and broadcasts the transaction. The transaction has no change output, so the address receives everything the outputs hold minus the fee. The outputs received through silent payments aren't swept.",
+	"sendall-recipients": "The address to sweep the outputs to, which must be the only recipient",
+	"sendall-options":    "The options of the sweep",
+
+	"sendallopts-conf_target":   "The number of blocks the transaction is estimated to confirm within when no fee rate is given (default: 6)",
+	"sendallopts-estimate_mode": "The fee estimate mode, one of \"unset\", \"economical\" or \"conservative\" (default: \"conservative\")",
+	"sendallopts-fee_rate":      "The fee rate in sat/vB (default: the estimated fee rate)",
+	"sendallopts-inputs":        "The exact outputs of the wallet to sweep, which may be locked (default: all of the unlocked outputs)",
+
+	"sendallresult-complete": "Whether the transaction was signed and broadcast",
+	"sendallresult-txid":     "The txid of the transaction",
+	"sendallresult-hex":      "The hex-encoded serialized transaction",
+	"sendallresult-fee":      "The fee of the transaction in BTC",
+
 	// SendRawTransactionCmd help.
 	"sendrawtransaction--synopsis":    "Submits the serialized, hex-encoded transaction to the local peer and relays it to the network.",
 	"sendrawtransaction-hextx":        "Serialized, hex-encoded signed transaction",
@@ -736,11 +1384,23 @@ var helpDescsEnUS = map[string]string{
 	"sendrawtransaction--result0":     "The hash of the transaction",
 	"allowhighfeesormaxfeerate-value": "Either the boolean value for the allowhighfees parameter in bitcoind < v0.19.0 or the numerical value for the maxfeerate field in bitcoind v0.19.0 and later",
 
+	// SetBanCmd help.
+	"setban--synopsis": "Bans or unbans an IP address, a subnet, or a Tor or I2P host. Bans are kept across restarts and the connected peers that a new ban covers are disconnected.",
+	"setban-subnet":    "The IP address, the subnet in CIDR notation (e.g. 192.168.0.0/24), or the Tor or I2P host to operate on",
+	"setban-subcmd":    "'add' to ban or 'remove' to unban",
+	"setban-bantime":   "The number of seconds to ban for, or 0 for the --banduration duration",
+	"setban-absolute":  "Whether bantime is the time the ban ends in seconds since 1 Jan 1970 GMT instead",
+
 	// SetGenerateCmd help.
 	"setgenerate--synopsis":    "Set the server to generate coins (mine) or not.",
 	"setgenerate-generate":     "Use true to enable generation, false to disable it",
 	"setgenerate-genproclimit": "The number of processors (cores) to limit generation to or -1 for default",
 
+	// SetLabelCmd help.
+	"setlabel--synopsis": "Attaches a label to an address or transaction of the bdk wallet. The labels are kept in the BIP329 format and are included in the backups of the wallet.",
+	"setlabel-reference": "The address or the txid to label",
+	"setlabel-label":     "The label, or an empty string to remove the label",
+
 	// SignMessageWithPrivKeyCmd help.
 	"signmessagewithprivkey--synopsis": "Sign a message with the private key of an address",
 	"signmessagewithprivkey-privkey":   "The private key to sign the message with",
@@ -762,6 +1422,40 @@ var helpDescsEnUS = map[string]string{
 	"submitblock--condition1": "Block rejected",
 	"submitblock--result1":    "The reason the block was rejected",
 
+	// SubmitPackageCmd help.
+	"submitpackage--synopsis": "Submits a package of raw transactions to the memory pool as a whole.\n" +
+		"The package must be a child with all of its unconfirmed parents, sorted so that the child comes last, where the parents don't spend each other.\n" +
+		"Parents that don't pay the minimum relay fee on their own are accepted when the child pays for them.\n" +
+		"Either all of the transactions are accepted or none of them are, and they may not replace transactions in the memory pool.",
+	"submitpackage-rawtxs": "The hex-encoded transactions of the package",
+
+	// SubmitPackageResult help.
+	"submitpackageresult-package_msg":           "The result of the package, 'success' when all of the transactions were accepted or were already in the memory pool",
+	"submitpackageresult-tx-results":            "The results of the transactions",
+	"submitpackageresult-tx-results--key":       "wtxid",
+	"submitpackageresult-tx-results--value":     "{\"txid\": \"txid\", \"vsize\": n, \"fees\": {\"base\": n.nnn, \"effective-feerate\": n.nnn, \"effective-includes\": [\"wtxid\", ...]}, \"error\": \"reason\"}",
+	"submitpackageresult-tx-results--desc":      "The result of the transaction with the wtxid, where the error is only set when it was rejected and the effective fee rate in BTC/kvB is only set when it was newly accepted",
+	"submitpackageresult-replaced-transactions": "The transactions replaced in the memory pool, which is always empty since packages may not replace transactions",
+
+	// TestMempoolAcceptCmd help.
+	"testmempoolaccept--synopsis": "Checks whether raw transactions would be accepted to the memory pool without submitting or relaying them.\n" +
+		"A transaction may spend the outputs of the transactions before it in the array, but transactions that spend unknown outputs are rejected.",
+	"testmempoolaccept-rawtxs":     "The hex-encoded transactions to check",
+	"testmempoolaccept-maxfeerate": "Reject transactions whose fee rate is higher than this in BTC/kvB, or 0 to accept any fee rate",
+
+	// TestMempoolAcceptResult help.
+	"testmempoolacceptresult-txid":          "The hash of the transaction",
+	"testmempoolacceptresult-wtxid":         "The witness hash of the transaction",
+	"testmempoolacceptresult-allowed":       "Whether the transaction would be accepted to the memory pool",
+	"testmempoolacceptresult-vsize":         "The virtual size of the transaction (only when allowed)",
+	"testmempoolacceptresult-fees":          "The fees of the transaction (only when allowed)",
+	"testmempoolacceptresult-reject-reason": "The reason the transaction would be rejected (only when not allowed)",
+
+	// TestMempoolAcceptFeesResult help.
+	"testmempoolacceptfeesresult-base":               "The fee of the transaction in BTC",
+	"testmempoolacceptfeesresult-effective-feerate":  "The fee rate of the transaction in BTC/kvB",
+	"testmempoolacceptfeesresult-effective-includes": "The witness hashes of the transactions that make up the effective fee rate",
+
 	// ValidateAddressResult help.
 	"validateaddresschainresult-isvalid":         "Whether or not the address is valid",
 	"validateaddresschainresult-address":         "The bitcoin address (only when isvalid is true)",
@@ -808,6 +1502,24 @@ var helpDescsEnUS = map[string]string{
 	// StopNotifyBlocksCmd help.
 	"stopnotifyblocks--synopsis": "Cancel registered notifications for whenever a block is connected or disconnected from the main (best) chain.",
 
+	// NotifyUtreexoRootsCmd help.
+	"notifyutreexoroots--synopsis": "Send a utreexorootsconnected or a utreexorootsdisconnected notification with the new utreexo roots and number of leaves whenever a block is connected or disconnected from the main (best) chain.",
+
+	// StopNotifyUtreexoRootsCmd help.
+	"stopnotifyutreexoroots--synopsis": "Cancel registered notifications for whenever the utreexo roots change.",
+
+	// NotifyMempoolEventsCmd help.
+	"notifymempoolevents--synopsis": "Send a mempoolevent notification with the fee and the virtual size of the transaction whenever a transaction is added to, removed from, replaced in or confirmed out of the mempool.",
+
+	// StopNotifyMempoolEventsCmd help.
+	"stopnotifymempoolevents--synopsis": "Cancel registered notifications for whenever transactions enter or leave the mempool.",
+
+	// NotifyWalletEventsCmd help.
+	"notifywalletevents--synopsis": "Send a walletevent notification whenever a bdk wallet receives a payment, one of its transactions reaches the confirmations of --bdknotifyconfirmations or the fee of one of its transactions is bumped with bumpfee or cpfpbdktransaction.",
+
+	// StopNotifyWalletEventsCmd help.
+	"stopnotifywalletevents--synopsis": "Cancel registered notifications for the events of the bdk wallets.",
+
 	// NotifyNewTransactionsCmd help.
 	"notifynewtransactions--synopsis": "Send either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
 	"notifynewtransactions-verbose":   "Specifies which type of notification to receive. If verbose is true, then the caller receives txacceptedverbose, otherwise the caller receives txaccepted",
@@ -845,6 +1557,16 @@ var helpDescsEnUS = map[string]string{
 	"registeraddressestowatchonlywallet--synopsis": "Registers a list of addresses to the watch only wallet.",
 	"registeraddressestowatchonlywallet-addresses": "Addresses to keep track of",
 
+	// RestoreWalletCmd help.
+	"restorewallet--synopsis":  "Restores a backup written by backupwallet as a named bdk wallet and loads it.",
+	"restorewallet-walletname": "The name of the restored wallet, which must not be taken by an existing wallet",
+	"restorewallet-backupfile": "The path of the backup. Relative paths are relative to the data directory",
+	"restorewallet-passphrase": "The passphrase the backup was encrypted with (default: the --bdkbackuppassphrase passphrase)",
+
+	// RestoreWalletResult help.
+	"restorewalletresult-name":    "The name of the wallet",
+	"restorewalletresult-warning": "Warning message if the wallet was not restored cleanly",
+
 	// ReconsiderBlockCmd help.
 	"reconsiderblock--synopsis": "Reconsiders the block of the given block hash. Can be used to re-validate blocks invalidated with invalidateblock",
 	"reconsiderblock-blockhash": "The block hash of the block to reconsider",
@@ -868,6 +1590,13 @@ var helpDescsEnUS = map[string]string{
 	"rescannedblock-hash":         "Hash of the matching block.",
 	"rescannedblock-transactions": "List of matching transactions, serialized and hex-encoded.",
 
+	// UnloadWalletCmd help.
+	"unloadwallet--synopsis":  "Unloads a named bdk wallet. The default wallet can't be unloaded.",
+	"unloadwallet-walletname": "The name of the wallet (default: the wallet of the endpoint)",
+
+	// UnloadWalletResult help.
+	"unloadwalletresult-warning": "Warning message if the wallet was not unloaded cleanly",
+
 	// UnusedAddressCmd help.
 	"unusedaddress--synopsis": "Returns an address that never received funds from the bdkwallet.",
 
@@ -875,6 +1604,12 @@ var helpDescsEnUS = map[string]string{
 	"uptime--synopsis": "Returns the total uptime of the server.",
 	"uptime--result0":  "The number of seconds that the server has been running",
 
+	// UtxoUpdatePsbtCmd help.
+	"utxoupdatepsbt--synopsis": "Adds the outputs spent by a psbt, and the transactions of the outputs that aren't taproot ones, to its inputs.\n" +
+		"The transactions are looked up in the mempool and the transaction index (--txindex) and the inputs spending from transactions that can't be found are left as they are.",
+	"utxoupdatepsbt-psbt":     "The base64 encoded psbt",
+	"utxoupdatepsbt--result0": "The base64 encoded updated psbt",
+
 	// Version help.
 	"version--synopsis":       "Returns the JSON-RPC API version (semver)",
 	"version--result0--desc":  "Version objects keyed by the program or API name",
@@ -888,20 +1623,78 @@ var helpDescsEnUS = map[string]string{
 	"versionresult-patch":         "The patch component of the JSON-RPC API version",
 	"versionresult-prerelease":    "Prerelease info about the current build",
 	"versionresult-buildmetadata": "Metadata about the current build",
+
+	// WalletCreateFundedPsbtCmd help.
+	"walletcreatefundedpsbt--synopsis": "Creates a psbt paying to the outputs that is funded by the bdk wallet.\n" +
+		"The inputs are always spent and more unspent outputs of the wallet are added as needed.\n" +
+		"The outputs paying to silent payment addresses are derived once every eligible input has its BIP375 ECDH shares, which the wallet adds for the inputs it has the keys of.",
+	"walletcreatefundedpsbt-inputs":                     "The unspent outputs of the wallet or of its imported descriptors to spend",
+	"walletcreatefundedpsbt-outputs":                    "JSON objects with the destination addresses, which may be silent payment addresses, as keys and the amounts in BTC as values, or \"data\" as the key and hex-encoded data of an OP_RETURN output as the value",
+	"walletcreatefundedpsbt-locktime":                   "Locktime of the transaction; the wallet picks it when it's zero",
+	"walletcreatefundedpsbt-options":                    "Options for funding the psbt; only feeRate, replaceable and coinSelection are supported",
+	"walletcreatefundedpsbt-bip32derivs":                "Unused; the bip32 derivation paths are always included",
+	"psbtinput-txid":                                    "The hash of the transaction of the output",
+	"psbtinput-vout":                                    "The index of the output",
+	"psbtinput-sequence":                                "Unused; the wallet picks the sequence numbers",
+	"walletcreatefundedpsbtopts-changeAddress":          "Unsupported",
+	"walletcreatefundedpsbtopts-changePosition":         "Unsupported",
+	"walletcreatefundedpsbtopts-change_type":            "Unsupported",
+	"walletcreatefundedpsbtopts-includeWatching":        "Unsupported",
+	"walletcreatefundedpsbtopts-lockUnspents":           "Whether to lock the outputs of the wallet the psbt spends (default: false)",
+	"walletcreatefundedpsbtopts-feeRate":                "The fee rate in BTC/kB (default: the minimum relay fee unless conf_target or estimate_mode is given)",
+	"walletcreatefundedpsbtopts-subtractFeeFromOutputs": "The indexes of the outputs the fee is taken out of, split evenly between them. The wallet still needs to hold the fee on top of the amounts",
+	"walletcreatefundedpsbtopts-replaceable":            "Whether the transaction signals BIP125 replaceability (default: true)",
+	"walletcreatefundedpsbtopts-conf_target":            "The number of blocks the transaction should confirm within, which the fee rate is estimated for (default: 6 when estimate_mode is given)",
+	"walletcreatefundedpsbtopts-estimate_mode":          "The fee estimate mode, one of \"unset\", \"economical\" or \"conservative\" (default: \"conservative\")",
+	"walletcreatefundedpsbtopts-coinSelection":          "The coin selection strategy, one of \"branchandbound\", \"knapsack\" or \"avoidpartialspends\" (default: \"branchandbound\")",
+	"walletcreatefundedpsbtopts-add_inputs":             "Whether to add unlocked outputs of the wallet to the inputs when they don't fund the outputs (default: true when no inputs are given, false otherwise)",
+
+	// WalletCreateFundedPsbtResult help.
+	"walletcreatefundedpsbtresult-psbt":      "The base64 encoded psbt",
+	"walletcreatefundedpsbtresult-fee":       "The fee paid by the psbt in BTC",
+	"walletcreatefundedpsbtresult-changepos": "The index of the change output, or -1 if there is none",
+
+	// WalletProcessPsbtCmd help.
+	"walletprocesspsbt--synopsis": "Adds what the bdk wallet knows of the inputs of a psbt to it and signs the inputs it can.\n" +
+		"The inputs that have all of their signatures are finalized.\n" +
+		"The ECDH shares of the inputs are added for the silent payment outputs, which have to be derived from the shares of all of the eligible inputs before the psbt is signed.",
+	"walletprocesspsbt-psbt":        "The base64 encoded psbt",
+	"walletprocesspsbt-sign":        "Whether to sign the inputs",
+	"walletprocesspsbt-sighashtype": "The signature hash type; only ALL and DEFAULT are supported",
+	"walletprocesspsbt-bip32derivs": "Unused; the bip32 derivation paths are always included",
+
+	// WalletProcessPsbtResult help.
+	"walletprocesspsbtresult-psbt":     "The base64 encoded psbt",
+	"walletprocesspsbtresult-complete": "Whether all of the inputs of the psbt are finalized",
 }
 
 // rpcResultTypes specifies the result types that each RPC command can return.
 // This information is used to generate the help.  Each result type must be a
 // pointer to the type (or nil to indicate no return value).
 var rpcResultTypes = map[string][]interface{}{
+	"abandontransaction":                 nil,
 	"addnode":                            nil,
+	"backupdatadir":                      {(*btcjson.BackupDataDirResult)(nil)},
+	"backupwallet":                       nil,
+	"clearbanned":                        nil,
+	"compactdb":                          nil,
 	"balance":                            {(*btcjson.BalanceResult)(nil)},
+	"bumpfee":                            {(*btcjson.BumpFeeResult)(nil)},
+	"cpfpbdktransaction":                 {(*btcjson.CpfpBDKTransactionResult)(nil)},
 	"createrawtransaction":               {(*string)(nil)},
+	"createwallet":                       {(*btcjson.CreateWalletResult)(nil)},
 	"createtransactionfrombdkwallet":     {(*btcjson.CreateTransactionFromBDKWalletResult)(nil)},
 	"debuglevel":                         {(*string)(nil), (*string)(nil)},
 	"decoderawtransaction":               {(*btcjson.TxRawDecodeResult)(nil)},
 	"decodescript":                       {(*btcjson.DecodeScriptResult)(nil)},
+	"deriveaddresses":                    {(*[]string)(nil)},
+	"dumptxoutset":                       {(*btcjson.DumpTxOutSetResult)(nil)},
+	"enumeratesigners":                   {(*btcjson.EnumerateSignersResult)(nil)},
+	"exportbdktransactions":              {(*[]btcjson.ExportBDKTransactionsResult)(nil), (*string)(nil)},
 	"estimatefee":                        {(*float64)(nil)},
+	"estimaterawfee":                     {(*btcjson.EstimateRawFeeResult)(nil)},
+	"estimatesmartfee":                   {(*btcjson.EstimateSmartFeeResult)(nil)},
+	"finalizepsbt":                       {(*btcjson.FinalizePsbtResult)(nil)},
 	"freshaddress":                       {(*btcjson.BDKAddressResult)(nil)},
 	"generate":                           {(*[]string)(nil)},
 	"getaddednodeinfo":                   {(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
@@ -912,38 +1705,65 @@ var rpcResultTypes = map[string][]interface{}{
 	"getblockcount":                      {(*int64)(nil)},
 	"getblockhash":                       {(*string)(nil)},
 	"getblockheader":                     {(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
+	"getblocksbytime":                    {(*[]btcjson.GetBlocksByTimeResult)(nil)},
+	"getblockttls":                       {(*[]btcjson.GetBlockTTLResult)(nil)},
 	"getblocktemplate":                   {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
 	"getblockchaininfo":                  {(*btcjson.GetBlockChainInfoResult)(nil)},
 	"getchaintips":                       {(*[]btcjson.GetChainTipsResult)(nil)},
+	"getchaintxstats":                    {(*btcjson.GetChainTxStatsResult)(nil)},
 	"getcfilter":                         {(*string)(nil)},
 	"getcfilterheader":                   {(*string)(nil)},
 	"getconnectioncount":                 {(*int32)(nil)},
 	"getcurrentnet":                      {(*uint32)(nil)},
+	"getdbcompactioninfo":                {(*btcjson.GetDBCompactionInfoResult)(nil)},
+	"getdescriptorinfo":                  {(*btcjson.GetDescriptorInfoResult)(nil)},
 	"getdifficulty":                      {(*float64)(nil)},
 	"getgenerate":                        {(*bool)(nil)},
 	"gethashespersec":                    {(*float64)(nil)},
 	"getheaders":                         {(*[]string)(nil)},
+	"getindexinfo":                       {(*map[string]btcjson.GetIndexInfoResult)(nil)},
 	"getinfo":                            {(*btcjson.InfoChainResult)(nil)},
+	"getmempoolancestors":                {(*[]string)(nil), (*btcjson.GetMempoolEntryResult)(nil)},
+	"getmempooldescendants":              {(*[]string)(nil), (*btcjson.GetMempoolEntryResult)(nil)},
+	"getmempoolentry":                    {(*btcjson.GetMempoolEntryResult)(nil)},
 	"getmempoolinfo":                     {(*btcjson.GetMempoolInfoResult)(nil)},
 	"getmininginfo":                      {(*btcjson.GetMiningInfoResult)(nil)},
 	"getmnemonicwords":                   {(*[]string)(nil)},
 	"getnettotals":                       {(*btcjson.GetNetTotalsResult)(nil)},
 	"gettxtotals":                        {(*btcjson.GetTxTotalsResult)(nil)},
+	"getutreexocfheader":                 {(*btcjson.GetUtreexoCFHeaderResult)(nil)},
 	"getutreexoproof":                    {(*btcjson.GetUtreexoProofVerboseResult)(nil)},
 	"getutreexoroots":                    {(*btcjson.GetUtreexoRootsResult)(nil)},
+	"getutreexostats":                    {(*btcjson.GetUtreexoStatsResult)(nil)},
+	"getutxoproof":                       {(*btcjson.GetUtxoProofResult)(nil)},
 	"getwatchonlybalance":                {(*int64)(nil)},
 	"getnetworkhashps":                   {(*int64)(nil)},
+	"getnetworkinfo":                     {(*btcjson.GetNetworkInfoResult)(nil)},
 	"getnodeaddresses":                   {(*[]btcjson.GetNodeAddressesResult)(nil)},
 	"getpeerinfo":                        {(*[]btcjson.GetPeerInfoResult)(nil)},
 	"getrawmempool":                      {(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
 	"getrawtransaction":                  {(*string)(nil), (*btcjson.TxRawResult)(nil)},
+	"getscriptbalance":                   {(*btcjson.GetScriptBalanceResult)(nil)},
+	"getsilentpaymenttweaks":             {(*[]btcjson.GetSilentPaymentTweakResult)(nil)},
 	"getttl":                             {(*btcjson.GetTTLResult)(nil)},
 	"gettxout":                           {(*btcjson.GetTxOutResult)(nil)},
+	"gettxoutsetinfo":                    {(*btcjson.GetTxOutSetInfoResult)(nil)},
+	"gettxspendingprevout":               {(*[]btcjson.GetTxSpendingPrevOutResult)(nil)},
 	"node":                               nil,
 	"help":                               {(*string)(nil), (*string)(nil)},
 	"invalidateblock":                    nil,
+	"importbdkdescriptor":                nil,
+	"importsilentpaymentkey":             {(*string)(nil)},
+	"listbdkdescriptors":                 {(*[]btcjson.ListBDKDescriptorsResult)(nil)},
 	"listbdktransactions":                {(*[]btcjson.ListBDKTransactionsResult)(nil)},
 	"listbdkutxos":                       {(*[]btcjson.ListBDKUTXOsResult)(nil)},
+	"listlockunspent":                    {(*[]btcjson.TransactionInput)(nil)},
+	"listreceivedbyaddress":              {(*[]btcjson.ListReceivedByAddressResult)(nil)},
+	"listbanned":                         {(*[]btcjson.ListBannedResult)(nil)},
+	"listwallets":                        {(*[]string)(nil)},
+	"loadtxoutset":                       {(*btcjson.LoadTxOutSetResult)(nil)},
+	"loadwallet":                         {(*btcjson.LoadWalletResult)(nil)},
+	"lockunspent":                        {(*bool)(nil)},
 	"peekaddress":                        {(*btcjson.BDKAddressResult)(nil)},
 	"ping":                               nil,
 	"proveutxochaintipinclusion":         {(*btcjson.ProveUtxoChainTipInclusionVerboseResult)(nil)},
@@ -951,31 +1771,49 @@ var rpcResultTypes = map[string][]interface{}{
 	"rebroadcastunconfirmedbdktxs":       {(*[]string)(nil)},
 	"registeraddressestowatchonlywallet": nil,
 	"reconsiderblock":                    nil,
+	"restorewallet":                      {(*btcjson.RestoreWalletResult)(nil)},
+	"scanblocks":                         {(*btcjson.ScanBlocksResult)(nil), (*btcjson.ScanBlocksStatusResult)(nil), (*bool)(nil)},
+	"scantxoutset":                       {(*btcjson.ScanTxOutSetResult)(nil), (*btcjson.ScanTxOutSetStatusResult)(nil), (*bool)(nil)},
 	"searchrawtransactions":              {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
+	"sendall":                            {(*btcjson.SendAllResult)(nil)},
 	"sendrawtransaction":                 {(*string)(nil)},
+	"setban":                             nil,
 	"setgenerate":                        nil,
+	"setlabel":                           nil,
 	"signmessagewithprivkey":             {(*string)(nil)},
 	"stop":                               {(*string)(nil)},
 	"submitblock":                        {nil, (*string)(nil)},
+	"submitpackage":                      {(*btcjson.SubmitPackageResult)(nil)},
+	"testmempoolaccept":                  {(*[]btcjson.TestMempoolAcceptResult)(nil)},
+	"unloadwallet":                       {(*btcjson.UnloadWalletResult)(nil)},
 	"unusedaddress":                      {(*btcjson.BDKAddressResult)(nil)},
 	"uptime":                             {(*int64)(nil)},
+	"utxoupdatepsbt":                     {(*string)(nil)},
 	"validateaddress":                    {(*btcjson.ValidateAddressChainResult)(nil)},
 	"verifychain":                        {(*bool)(nil)},
 	"verifymessage":                      {(*bool)(nil)},
 	"verifyutxochaintipinclusionproof":   {(*bool)(nil)},
 	"version":                            {(*map[string]btcjson.VersionResult)(nil)},
+	"walletcreatefundedpsbt":             {(*btcjson.WalletCreateFundedPsbtResult)(nil)},
+	"walletprocesspsbt":                  {(*btcjson.WalletProcessPsbtResult)(nil)},
 
 	// Websocket commands.
 	"loadtxfilter":              nil,
 	"session":                   {(*btcjson.SessionResult)(nil)},
 	"notifyblocks":              nil,
 	"stopnotifyblocks":          nil,
+	"notifymempoolevents":       nil,
+	"stopnotifymempoolevents":   nil,
+	"notifywalletevents":        nil,
+	"stopnotifywalletevents":    nil,
 	"notifynewtransactions":     nil,
 	"stopnotifynewtransactions": nil,
 	"notifyreceived":            nil,
 	"stopnotifyreceived":        nil,
 	"notifyspent":               nil,
 	"stopnotifyspent":           nil,
+	"notifyutreexoroots":        nil,
+	"stopnotifyutreexoroots":    nil,
 	"rescan":                    nil,
 	"rescanblocks":              {(*[]btcjson.RescannedBlock)(nil)},
 }
@@ -0,0 +1,254 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btclog"
+)
+
+// unixSocketTestDir returns a temporary directory for Unix domain sockets.  The
+// test temporary directories may be too long for the paths of sockets.
+func unixSocketTestDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "rpcsock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// TestParseFileMode ensures that only the octal permission bits are accepted as
+// the file mode of the RPC Unix domain sockets.
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		s     string
+		mode  os.FileMode
+		valid bool
+	}{
+		{s: "0600", mode: 0600, valid: true},
+		{s: "660", mode: 0660, valid: true},
+		{s: "0777", mode: 0777, valid: true},
+		{s: "0", mode: 0, valid: true},
+		{s: "01777"},
+		{s: "0800"},
+		{s: "rw"},
+		{s: ""},
+	}
+	for _, test := range tests {
+		mode, err := parseFileMode(test.s)
+		if test.valid != (err == nil) {
+			t.Errorf("%q: unexpected error %v", test.s, err)
+			continue
+		}
+		if mode != test.mode {
+			t.Errorf("%q: got mode %v, want %v", test.s, mode, test.mode)
+		}
+	}
+}
+
+// TestSetupUnixListeners ensures that the RPC Unix domain socket listeners are
+// given the file permissions of the mode, that stale sockets are replaced and
+// that the paths that can't be listened on are skipped without removing other
+// files.
+func TestSetupUnixListeners(t *testing.T) {
+	oldLog := rpcsLog
+	rpcsLog = btclog.Disabled
+	defer func() { rpcsLog = oldLog }()
+
+	dir := unixSocketTestDir(t)
+
+	// A socket left behind by an unclean shutdown.
+	stale := filepath.Join(dir, "stale.sock")
+	listener, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	fresh := filepath.Join(dir, "fresh.sock")
+	regular := filepath.Join(dir, "regular")
+	if err := os.WriteFile(regular, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing", "rpc.sock")
+
+	const mode = 0660
+	listeners, err := setupUnixListeners(
+		[]string{stale, regular, fresh, missing}, mode)
+	if err != nil {
+		t.Fatalf("setupUnixListeners: unexpected error: %v", err)
+	}
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+
+	if len(listeners) != 2 {
+		t.Fatalf("got %d listeners, want 2", len(listeners))
+	}
+	for i, path := range []string{stale, fresh} {
+		if got := listeners[i].Addr().String(); got != path {
+			t.Fatalf("got a listener on %s, want %s", got, path)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != mode {
+			t.Fatalf("got %s with mode %v, want a socket with %v",
+				path, fi.Mode(), os.FileMode(mode))
+		}
+	}
+
+	data, err := os.ReadFile(regular)
+	if err != nil || string(data) != "data" {
+		t.Fatalf("the regular file in the way of a socket was changed "+
+			"(%q, %v)", data, err)
+	}
+}
+
+// TestListenUnixMode ensures that the RPC Unix domain sockets are created with
+// the file permissions of the mode instead of the ones of the process umask.
+func TestListenUnixMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the socket permissions are not set from a umask on windows")
+	}
+
+	path := filepath.Join(unixSocketTestDir(t), "rpc.sock")
+	const mode = 0600
+	listener, err := listenUnix(path, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != mode {
+		t.Fatalf("got a socket with mode %v, want %v", fi.Mode().Perm(),
+			os.FileMode(mode))
+	}
+}
+
+// TestUnixSocketAuth ensures that the requests without credentials received on
+// a Unix domain socket are authenticated as admin while the ones received on
+// TCP aren't, and that the credentials given on either are checked.
+func TestUnixSocketAuth(t *testing.T) {
+	oldLog := rpcsLog
+	rpcsLog = btclog.Disabled
+	defer func() { rpcsLog = oldLog }()
+
+	user, err := parseRPCAccount("alice:secret:readonly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &rpcServer{users: []rpcUser{user}}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, perm, err := s.checkAuth(r, true)
+		fmt.Fprintf(w, "%v %v %v", ok, perm, err != nil)
+	})
+
+	path := filepath.Join(unixSocketTestDir(t), "rpc.sock")
+	listeners, err := setupUnixListeners([]string{path}, 0600)
+	if err != nil || len(listeners) != 1 {
+		t.Fatalf("unable to listen on %s: %v", path, err)
+	}
+	unixServer := httptest.NewUnstartedServer(handler)
+	unixServer.Listener.Close()
+	unixServer.Listener = listeners[0]
+	unixServer.Start()
+	defer unixServer.Close()
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+
+	tcpServer := httptest.NewServer(handler)
+	defer tcpServer.Close()
+
+	tests := []struct {
+		name   string
+		client *http.Client
+		url    string
+		auth   string
+		want   string
+	}{
+		{
+			name:   "unix socket without credentials",
+			client: unixClient,
+			url:    "http://unix",
+			want:   "true admin false",
+		},
+		{
+			name:   "unix socket with credentials",
+			client: unixClient,
+			url:    "http://unix",
+			auth:   basicAuth("alice", "secret"),
+			want:   "true readonly false",
+		},
+		{
+			name:   "unix socket with the wrong credentials",
+			client: unixClient,
+			url:    "http://unix",
+			auth:   basicAuth("alice", "wrong"),
+			want:   "false readonly true",
+		},
+		{
+			name:   "tcp without credentials",
+			client: tcpServer.Client(),
+			url:    tcpServer.URL,
+			want:   "false readonly true",
+		},
+		{
+			name:   "tcp with credentials",
+			client: tcpServer.Client(),
+			url:    tcpServer.URL,
+			auth:   basicAuth("alice", "secret"),
+			want:   "true readonly false",
+		},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		resp, err := test.client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(body)); got != test.want {
+			t.Errorf("%s: got auth %q, want %q", test.name, got,
+				test.want)
+		}
+	}
+}
@@ -8,9 +8,6 @@ package main
 import (
 	"bytes"
 	"container/list"
-	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -21,12 +18,14 @@ import (
 	"time"
 
 	"github.com/btcsuite/websocket"
+	"github.com/utreexo/utreexod/bdkwallet"
 	"github.com/utreexo/utreexod/blockchain"
 	"github.com/utreexo/utreexod/btcjson"
 	"github.com/utreexo/utreexod/btcutil"
 	"github.com/utreexo/utreexod/chaincfg"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
 	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/mempool"
 	"github.com/utreexo/utreexod/txscript"
 	"github.com/utreexo/utreexod/wire"
 	"golang.org/x/crypto/ripemd160"
@@ -66,14 +65,20 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 	"loadtxfilter":              handleLoadTxFilter,
 	"help":                      handleWebsocketHelp,
 	"notifyblocks":              handleNotifyBlocks,
+	"notifymempoolevents":       handleNotifyMempoolEvents,
 	"notifynewtransactions":     handleNotifyNewTransactions,
 	"notifyreceived":            handleNotifyReceived,
 	"notifyspent":               handleNotifySpent,
+	"notifyutreexoroots":        handleNotifyUtreexoRoots,
+	"notifywalletevents":        handleNotifyWalletEvents,
 	"session":                   handleSession,
 	"stopnotifyblocks":          handleStopNotifyBlocks,
+	"stopnotifymempoolevents":   handleStopNotifyMempoolEvents,
 	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
 	"stopnotifyspent":           handleStopNotifySpent,
 	"stopnotifyreceived":        handleStopNotifyReceived,
+	"stopnotifyutreexoroots":    handleStopNotifyUtreexoRoots,
+	"stopnotifywalletevents":    handleStopNotifyWalletEvents,
 	"rescan":                    handleRescan,
 	"rescanblocks":              handleRescanBlocks,
 }
@@ -84,7 +89,7 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 // server handler which runs each new connection in a new goroutine thereby
 // satisfying the requirement.
 func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
-	authenticated bool, isAdmin bool) {
+	authenticated bool, perm rpcPermission) {
 
 	// Clear the read deadline that was set before the websocket hijacked
 	// the connection.
@@ -103,7 +108,7 @@ func (s *rpcServer) WebsocketHandler(conn *websocket.Conn, remoteAddr string,
 	// Create a new websocket client to handle the new websocket connection
 	// and wait for it to shutdown.  Once it has shutdown (and hence
 	// disconnected), remove it and any notifications it registered for.
-	client, err := newWebsocketClient(s, conn, remoteAddr, authenticated, isAdmin)
+	client, err := newWebsocketClient(s, conn, remoteAddr, authenticated, perm)
 	if err != nil {
 		rpcsLog.Errorf("Failed to serve client %s: %v", remoteAddr, err)
 		conn.Close()
@@ -246,6 +251,32 @@ func (m *wsNotificationManager) NotifyMempoolTx(tx *btcutil.Tx, isNew bool) {
 	}
 }
 
+// NotifyMempoolEvent passes a transaction that entered or left the mempool to
+// the notification manager for mempool event notification processing.
+func (m *wsNotificationManager) NotifyMempoolEvent(n *mempool.Notification) {
+	// As NotifyMempoolEvent will be called by mempool and the RPC server
+	// may no longer be running, use a select statement to unblock
+	// enqueuing the notification once the RPC server has begun
+	// shutting down.
+	select {
+	case m.queueNotification <- (*notificationMempoolEvent)(n):
+	case <-m.quit:
+	}
+}
+
+// NotifyWalletEvent passes an event of a bdk wallet to the notification
+// manager for wallet event notification processing.
+func (m *wsNotificationManager) NotifyWalletEvent(n *bdkwallet.Notification) {
+	// As NotifyWalletEvent will be called by the wallets and the RPC
+	// server may no longer be running, use a select statement to unblock
+	// enqueuing the notification once the RPC server has begun shutting
+	// down.
+	select {
+	case m.queueNotification <- (*notificationWalletEvent)(n):
+	case <-m.quit:
+	}
+}
+
 // wsClientFilter tracks relevant addresses for each websocket client for
 // the `rescanblocks` extension. It is modified by the `loadtxfilter` command.
 //
@@ -451,6 +482,8 @@ type notificationTxAcceptedByMempool struct {
 	isNew bool
 	tx    *btcutil.Tx
 }
+type notificationMempoolEvent mempool.Notification
+type notificationWalletEvent bdkwallet.Notification
 
 // Notification control requests
 type notificationRegisterClient wsClient
@@ -459,6 +492,12 @@ type notificationRegisterBlocks wsClient
 type notificationUnregisterBlocks wsClient
 type notificationRegisterNewMempoolTxs wsClient
 type notificationUnregisterNewMempoolTxs wsClient
+type notificationRegisterMempoolEvents wsClient
+type notificationUnregisterMempoolEvents wsClient
+type notificationRegisterWalletEvents wsClient
+type notificationUnregisterWalletEvents wsClient
+type notificationRegisterUtreexoRoots wsClient
+type notificationUnregisterUtreexoRoots wsClient
 type notificationRegisterSpent struct {
 	wsc *wsClient
 	ops []*wire.OutPoint
@@ -491,6 +530,9 @@ func (m *wsNotificationManager) notificationHandler() {
 	// since it is quite a bit more efficient than using the entire struct.
 	blockNotifications := make(map[chan struct{}]*wsClient)
 	txNotifications := make(map[chan struct{}]*wsClient)
+	mempoolEventNotifications := make(map[chan struct{}]*wsClient)
+	walletEventNotifications := make(map[chan struct{}]*wsClient)
+	utreexoRootsNotifications := make(map[chan struct{}]*wsClient)
 	watchedOutPoints := make(map[wire.OutPoint]map[chan struct{}]*wsClient)
 	watchedAddrs := make(map[string]map[chan struct{}]*wsClient)
 
@@ -522,6 +564,11 @@ out:
 						block)
 				}
 
+				if len(utreexoRootsNotifications) != 0 {
+					m.notifyUtreexoRootsConnected(
+						utreexoRootsNotifications, block)
+				}
+
 			case *notificationBlockDisconnected:
 				block := (*btcutil.Block)(n)
 
@@ -532,6 +579,11 @@ out:
 						block)
 				}
 
+				if len(utreexoRootsNotifications) != 0 {
+					m.notifyUtreexoRootsDisconnected(
+						utreexoRootsNotifications, block)
+				}
+
 			case *notificationTxAcceptedByMempool:
 				if n.isNew && len(txNotifications) != 0 {
 					m.notifyForNewTx(txNotifications, n.tx)
@@ -539,6 +591,18 @@ out:
 				m.notifyForTx(watchedOutPoints, watchedAddrs, n.tx, nil)
 				m.notifyRelevantTxAccepted(n.tx, clients)
 
+			case *notificationMempoolEvent:
+				if len(mempoolEventNotifications) != 0 {
+					m.notifyMempoolEvent(mempoolEventNotifications,
+						(*mempool.Notification)(n))
+				}
+
+			case *notificationWalletEvent:
+				if len(walletEventNotifications) != 0 {
+					m.notifyWalletEvent(walletEventNotifications,
+						(*bdkwallet.Notification)(n))
+				}
+
 			case *notificationRegisterBlocks:
 				wsc := (*wsClient)(n)
 				blockNotifications[wsc.quit] = wsc
@@ -557,6 +621,9 @@ out:
 				// the client itself.
 				delete(blockNotifications, wsc.quit)
 				delete(txNotifications, wsc.quit)
+				delete(mempoolEventNotifications, wsc.quit)
+				delete(walletEventNotifications, wsc.quit)
+				delete(utreexoRootsNotifications, wsc.quit)
 				for k := range wsc.spentRequests {
 					op := k
 					m.removeSpentRequest(watchedOutPoints, wsc, &op)
@@ -586,6 +653,30 @@ out:
 				wsc := (*wsClient)(n)
 				delete(txNotifications, wsc.quit)
 
+			case *notificationRegisterMempoolEvents:
+				wsc := (*wsClient)(n)
+				mempoolEventNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterMempoolEvents:
+				wsc := (*wsClient)(n)
+				delete(mempoolEventNotifications, wsc.quit)
+
+			case *notificationRegisterWalletEvents:
+				wsc := (*wsClient)(n)
+				walletEventNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterWalletEvents:
+				wsc := (*wsClient)(n)
+				delete(walletEventNotifications, wsc.quit)
+
+			case *notificationRegisterUtreexoRoots:
+				wsc := (*wsClient)(n)
+				utreexoRootsNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterUtreexoRoots:
+				wsc := (*wsClient)(n)
+				delete(utreexoRootsNotifications, wsc.quit)
+
 			default:
 				rpcsLog.Warn("Unhandled notification type")
 			}
@@ -625,6 +716,18 @@ func (m *wsNotificationManager) UnregisterBlockUpdates(wsc *wsClient) {
 	m.queueNotification <- (*notificationUnregisterBlocks)(wsc)
 }
 
+// RegisterUtreexoRootsUpdates requests utreexo roots update notifications to
+// the passed websocket client.
+func (m *wsNotificationManager) RegisterUtreexoRootsUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterUtreexoRoots)(wsc)
+}
+
+// UnregisterUtreexoRootsUpdates removes utreexo roots update notifications for
+// the passed websocket client.
+func (m *wsNotificationManager) UnregisterUtreexoRootsUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterUtreexoRoots)(wsc)
+}
+
 // subscribedClients returns the set of all websocket client quit channels that
 // are registered to receive notifications regarding tx, either due to tx
 // spending a watched output or outputting to a watched address.  Matching
@@ -730,6 +833,87 @@ func (*wsNotificationManager) notifyBlockDisconnected(clients map[chan struct{}]
 	}
 }
 
+// fetchUtreexoRootStrings returns the hex encoded roots and the number of leaves
+// of the accumulator after the main chain block at the height.  The boolean is
+// false if the block at the height isn't the passed in block anymore, which
+// happens when the chain was reorganized before the notification was
+// processed.  The notification of the reorganization follows in that case.
+func (m *wsNotificationManager) fetchUtreexoRootStrings(height int32,
+	hash *chainhash.Hash) ([]string, uint64, bool) {
+
+	chain := m.server.cfg.Chain
+	roots, numLeaves, ok, err := chain.FetchUtreexoRoots(height)
+	if err != nil || !ok {
+		if err != nil {
+			rpcsLog.Debugf("Failed to fetch the utreexo roots at "+
+				"height %d: %v", height, err)
+		}
+		return nil, 0, false
+	}
+
+	// Make sure the roots are of the block by checking that it's still in
+	// the main chain after the roots were fetched.
+	mainHash, err := chain.BlockHashByHeight(height)
+	if err != nil || !mainHash.IsEqual(hash) {
+		return nil, 0, false
+	}
+
+	rootStrings := make([]string, 0, len(roots))
+	for _, root := range roots {
+		rootStrings = append(rootStrings, hex.EncodeToString(root[:]))
+	}
+	return rootStrings, numLeaves, true
+}
+
+// notifyUtreexoRootsConnected notifies websocket clients that have registered
+// for utreexo roots updates when a block is connected to the main chain.
+func (m *wsNotificationManager) notifyUtreexoRootsConnected(clients map[chan struct{}]*wsClient,
+	block *btcutil.Block) {
+
+	roots, numLeaves, ok := m.fetchUtreexoRootStrings(block.Height(), block.Hash())
+	if !ok {
+		return
+	}
+
+	ntfn := btcjson.NewUtreexoRootsConnectedNtfn(block.Hash().String(),
+		block.Height(), numLeaves, roots)
+	marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal utreexo roots connected "+
+			"notification: %v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// notifyUtreexoRootsDisconnected notifies websocket clients that have
+// registered for utreexo roots updates when a block is disconnected from the
+// main chain (due to a reorganize).  The roots are the ones of the parent of
+// the block.
+func (m *wsNotificationManager) notifyUtreexoRootsDisconnected(clients map[chan struct{}]*wsClient,
+	block *btcutil.Block) {
+
+	prevHash := &block.MsgBlock().Header.PrevBlock
+	roots, numLeaves, ok := m.fetchUtreexoRootStrings(block.Height()-1, prevHash)
+	if !ok {
+		return
+	}
+
+	ntfn := btcjson.NewUtreexoRootsDisconnectedNtfn(block.Hash().String(),
+		block.Height(), numLeaves, roots)
+	marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal utreexo roots disconnected "+
+			"notification: %v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
 // notifyFilteredBlockConnected notifies websocket clients that have registered for
 // block updates when a block is connected to the main chain.
 func (m *wsNotificationManager) notifyFilteredBlockConnected(clients map[chan struct{}]*wsClient,
@@ -868,6 +1052,115 @@ func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClie
 	}
 }
 
+// RegisterMempoolEventUpdates requests notifications to the passed websocket
+// client when transactions enter or leave the memory pool.
+func (m *wsNotificationManager) RegisterMempoolEventUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterMempoolEvents)(wsc)
+}
+
+// UnregisterMempoolEventUpdates removes notifications to the passed websocket
+// client when transactions enter or leave the memory pool.
+func (m *wsNotificationManager) UnregisterMempoolEventUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterMempoolEvents)(wsc)
+}
+
+// mempoolEventNames maps the types of the mempool notifications to the events
+// of the mempoolevent notifications.
+var mempoolEventNames = map[mempool.NotificationType]string{
+	mempool.NTTxAdded:     "added",
+	mempool.NTTxRemoved:   "removed",
+	mempool.NTTxReplaced:  "replaced",
+	mempool.NTTxConfirmed: "confirmed",
+}
+
+// notifyMempoolEvent notifies websocket clients that have registered for
+// mempool events when a transaction enters or leaves the memory pool.
+func (m *wsNotificationManager) notifyMempoolEvent(clients map[chan struct{}]*wsClient,
+	n *mempool.Notification) {
+
+	event := btcjson.MempoolEventDetails{
+		Event:   mempoolEventNames[n.Type],
+		TxID:    n.Tx.Hash().String(),
+		Fee:     btcutil.Amount(n.Fee).ToBTC(),
+		VSize:   n.VSize,
+		FeeRate: btcutil.Amount(n.FeePerKB).ToBTC(),
+	}
+	if n.ReplacedBy != nil {
+		event.ReplacedBy = n.ReplacedBy.Hash().String()
+	}
+	if n.Block != nil {
+		event.BlockHash = n.Block.Hash().String()
+		event.BlockHeight = n.Block.Height()
+	}
+
+	ntfn := btcjson.NewMempoolEventNtfn(event)
+	marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal mempool event notification: "+
+			"%v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
+// RegisterWalletEventUpdates requests notifications to the passed websocket
+// client on the events of the bdk wallets.
+func (m *wsNotificationManager) RegisterWalletEventUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterWalletEvents)(wsc)
+}
+
+// UnregisterWalletEventUpdates removes notifications to the passed websocket
+// client on the events of the bdk wallets.
+func (m *wsNotificationManager) UnregisterWalletEventUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterWalletEvents)(wsc)
+}
+
+// walletEventNames maps the types of the wallet notifications to the events of
+// the walletevent notifications.
+var walletEventNames = map[bdkwallet.NotificationType]string{
+	bdkwallet.NTPaymentReceived: "received",
+	bdkwallet.NTTxConfirmed:     "confirmed",
+	bdkwallet.NTFeeBumped:       "feebumped",
+}
+
+// notifyWalletEvent notifies websocket clients that have registered for wallet
+// events when a bdk wallet receives a payment, when one of its transactions is
+// confirmed and when the fee of one of its transactions is bumped.
+func (m *wsNotificationManager) notifyWalletEvent(clients map[chan struct{}]*wsClient,
+	n *bdkwallet.Notification) {
+
+	event := btcjson.WalletEventDetails{
+		Event:         walletEventNames[n.Type],
+		Wallet:        n.Wallet,
+		TxID:          n.Txid.String(),
+		Received:      n.Received.ToBTC(),
+		Spent:         n.Spent.ToBTC(),
+		Confirmations: n.Confirmations,
+	}
+	if n.Type == bdkwallet.NTFeeBumped {
+		event.BumpedTxID = n.BumpedTxid.String()
+		event.Fee = n.Fee.ToBTC()
+		event.OriginalFee = n.OriginalFee.ToBTC()
+		event.Method = "bumpfee"
+		if n.Cpfp {
+			event.Method = "cpfp"
+		}
+	}
+
+	ntfn := btcjson.NewWalletEventNtfn(event)
+	marshalledJSON, err := btcjson.MarshalCmd(btcjson.RpcVersion1, nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal wallet event notification: "+
+			"%v", err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
 // RegisterSpentRequests requests a notification when each of the passed
 // outpoints is confirmed spent (contained in a block connected to the main
 // chain) for the passed websocket client.  The request is automatically
@@ -1264,9 +1557,8 @@ type wsClient struct {
 	// and therefore is allowed to communicated over the websocket.
 	authenticated bool
 
-	// isAdmin specifies whether a client may change the state of the server;
-	// false means its access is only to the limited set of RPC calls.
-	isAdmin bool
+	// perm specifies the set of RPC calls the client may make.
+	perm rpcPermission
 
 	// sessionID is a random ID generated for each client when connected.
 	// These IDs may be queried by a client using the session RPC.  A change
@@ -1410,17 +1702,14 @@ out:
 				break out
 			case !c.authenticated:
 				// Check credentials.
-				login := authCmd.Username + ":" + authCmd.Passphrase
-				auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-				authSha := sha256.Sum256([]byte(auth))
-				cmp := subtle.ConstantTimeCompare(authSha[:], c.server.authsha[:])
-				limitcmp := subtle.ConstantTimeCompare(authSha[:], c.server.limitauthsha[:])
-				if cmp != 1 && limitcmp != 1 {
+				auth := basicAuth(authCmd.Username, authCmd.Passphrase)
+				user, ok := c.server.authenticate(auth)
+				if !ok {
 					rpcsLog.Warnf("Auth failure.")
 					break out
 				}
 				c.authenticated = true
-				c.isAdmin = cmp == 1
+				c.perm = user.perm
 
 				// Marshal and send response.
 				reply, err = createMarshalledReply(cmd.jsonrpc, cmd.id, nil, nil)
@@ -1433,24 +1722,22 @@ out:
 				continue
 			}
 
-			// Check if the client is using limited RPC credentials and
-			// error when not authorized to call the supplied RPC.
-			if !c.isAdmin {
-				if _, ok := rpcLimited[req.Method]; !ok {
-					jsonErr := &btcjson.RPCError{
-						Code:    btcjson.ErrRPCInvalidParams.Code,
-						Message: "limited user not authorized for this method",
-					}
-					// Marshal and send response.
-					reply, err = createMarshalledReply("", req.ID, nil, jsonErr)
-					if err != nil {
-						rpcsLog.Errorf("Failed to marshal parse failure "+
-							"reply: %v", err)
-						continue
-					}
-					c.SendMessage(reply, nil)
+			// Check if the client's RPC credentials authorize it to call
+			// the supplied RPC and error when they don't.
+			if !c.perm.authorized(req.Method) {
+				jsonErr := &btcjson.RPCError{
+					Code:    btcjson.ErrRPCInvalidParams.Code,
+					Message: c.perm.unauthorizedError(),
+				}
+				// Marshal and send response.
+				reply, err = createMarshalledReply("", req.ID, nil, jsonErr)
+				if err != nil {
+					rpcsLog.Errorf("Failed to marshal parse failure "+
+						"reply: %v", err)
 					continue
 				}
+				c.SendMessage(reply, nil)
+				continue
 			}
 
 			// Asynchronously handle the request.  A semaphore is used to
@@ -1647,18 +1934,15 @@ out:
 							break out
 						case !c.authenticated:
 							// Check credentials.
-							login := authCmd.Username + ":" + authCmd.Passphrase
-							auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-							authSha := sha256.Sum256([]byte(auth))
-							cmp := subtle.ConstantTimeCompare(authSha[:], c.server.authsha[:])
-							limitcmp := subtle.ConstantTimeCompare(authSha[:], c.server.limitauthsha[:])
-							if cmp != 1 && limitcmp != 1 {
+							auth := basicAuth(authCmd.Username, authCmd.Passphrase)
+							user, ok := c.server.authenticate(auth)
+							if !ok {
 								rpcsLog.Warnf("Auth failure.")
 								break out
 							}
 
 							c.authenticated = true
-							c.isAdmin = cmp == 1
+							c.perm = user.perm
 
 							// Marshal and send response.
 							reply, err = createMarshalledReply(cmd.jsonrpc, cmd.id, nil, nil)
@@ -1674,38 +1958,30 @@ out:
 							continue
 						}
 
-						// Check if the client is using limited RPC credentials and
-						// error when not authorized to call the supplied RPC.
-						if !c.isAdmin {
-							if _, ok := rpcLimited[req.Method]; !ok {
-								jsonErr := &btcjson.RPCError{
-									Code:    btcjson.ErrRPCInvalidParams.Code,
-									Message: "limited user not authorized for this method",
-								}
-								// Marshal and send response.
-								reply, err = createMarshalledReply(req.Jsonrpc, req.ID, nil, jsonErr)
-								if err != nil {
-									rpcsLog.Errorf("Failed to marshal parse failure "+
-										"reply: %v", err)
-									continue
-								}
-
-								if reply != nil {
-									results = append(results, reply)
-								}
+						// Check if the client's RPC credentials authorize it to call
+						// the supplied RPC and error when they don't.
+						if !c.perm.authorized(req.Method) {
+							jsonErr := &btcjson.RPCError{
+								Code:    btcjson.ErrRPCInvalidParams.Code,
+								Message: c.perm.unauthorizedError(),
+							}
+							// Marshal and send response.
+							reply, err = createMarshalledReply(req.Jsonrpc, req.ID, nil, jsonErr)
+							if err != nil {
+								rpcsLog.Errorf("Failed to marshal parse failure "+
+									"reply: %v", err)
 								continue
 							}
+
+							if reply != nil {
+								results = append(results, reply)
+							}
+							continue
 						}
 
 						// Lookup the websocket extension for the command, if it doesn't
 						// exist fallback to handling the command as a standard command.
-						var resp interface{}
-						wsHandler, ok := wsHandlers[cmd.method]
-						if ok {
-							resp, err = wsHandler(c, cmd.cmd)
-						} else {
-							resp, err = c.server.standardCmdResult(cmd, nil)
-						}
+						resp, err := c.callCmd(cmd)
 
 						// Marshal request output.
 						reply, err := createMarshalledReply(cmd.jsonrpc, cmd.id, resp, err)
@@ -1764,19 +2040,7 @@ out:
 // appropriate RPC handler.  The response is marshalled and sent to the
 // websocket client.
 func (c *wsClient) serviceRequest(r *parsedRPCCmd) {
-	var (
-		result interface{}
-		err    error
-	)
-
-	// Lookup the websocket extension for the command and if it doesn't
-	// exist fallback to handling the command as a standard command.
-	wsHandler, ok := wsHandlers[r.method]
-	if ok {
-		result, err = wsHandler(c, r.cmd)
-	} else {
-		result, err = c.server.standardCmdResult(r, nil)
-	}
+	result, err := c.callCmd(r)
 	reply, err := createMarshalledReply(r.jsonrpc, r.id, result, err)
 	if err != nil {
 		rpcsLog.Errorf("Failed to marshal reply for <%s> "+
@@ -1786,6 +2050,22 @@ func (c *wsClient) serviceRequest(r *parsedRPCCmd) {
 	c.SendMessage(reply, nil)
 }
 
+// callCmd runs the websocket extension handler of the parsed command, or its
+// standard handler when it isn't a websocket extension, subject to the rate
+// limits of the client.
+func (c *wsClient) callCmd(r *parsedRPCCmd) (interface{}, error) {
+	return c.server.callCmd(c.addr, r, func() (interface{}, error) {
+		// Lookup the websocket extension for the command and if it
+		// doesn't exist fallback to handling the command as a standard
+		// command.
+		wsHandler, ok := wsHandlers[r.method]
+		if ok {
+			return wsHandler(c, r.cmd)
+		}
+		return c.server.standardCmdResult(r, nil)
+	})
+}
+
 // notificationQueueHandler handles the queuing of outgoing notifications for
 // the websocket client.  This runs as a muxer for various sources of input to
 // ensure that queuing up notifications to be sent will not block.  Otherwise,
@@ -1991,7 +2271,7 @@ func (c *wsClient) WaitForShutdown() {
 // incoming and outgoing messages in separate goroutines complete with queuing
 // and asynchrous handling for long-running operations.
 func newWebsocketClient(server *rpcServer, conn *websocket.Conn,
-	remoteAddr string, authenticated bool, isAdmin bool) (*wsClient, error) {
+	remoteAddr string, authenticated bool, perm rpcPermission) (*wsClient, error) {
 
 	sessionID, err := wire.RandomUint64()
 	if err != nil {
@@ -2002,7 +2282,7 @@ func newWebsocketClient(server *rpcServer, conn *websocket.Conn,
 		conn:              conn,
 		addr:              remoteAddr,
 		authenticated:     authenticated,
-		isAdmin:           isAdmin,
+		perm:              perm,
 		sessionID:         sessionID,
 		server:            server,
 		addrRequests:      make(map[string]struct{}),
@@ -2114,6 +2394,33 @@ func handleNotifyBlocks(wsc *wsClient, icmd interface{}) (interface{}, error) {
 	return nil, nil
 }
 
+// handleNotifyUtreexoRoots implements the notifyutreexoroots command extension
+// for websocket connections.
+func handleNotifyUtreexoRoots(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	// Only nodes that keep an accumulator have roots to notify about.
+	chain := wsc.server.cfg.Chain
+	_, _, ok, err := chain.FetchUtreexoRoots(chain.BestSnapshot().Height)
+	if err != nil || !ok {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "The utreexo roots aren't available. They require " +
+				"the utreexo compact state (--noutreexo off) or a " +
+				"utreexo proof index (--utreexoproofindex) or " +
+				"(--flatutreexoproofindex).",
+		}
+	}
+
+	wsc.server.ntfnMgr.RegisterUtreexoRootsUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyUtreexoRoots implements the stopnotifyutreexoroots command
+// extension for websocket connections.
+func handleStopNotifyUtreexoRoots(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterUtreexoRootsUpdates(wsc)
+	return nil, nil
+}
+
 // handleSession implements the session command extension for websocket
 // connections.
 func handleSession(wsc *wsClient, icmd interface{}) (interface{}, error) {
@@ -2164,6 +2471,40 @@ func handleStopNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface
 	return nil, nil
 }
 
+// handleNotifyMempoolEvents implements the notifymempoolevents command extension
+// for websocket connections.
+func handleNotifyMempoolEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterMempoolEventUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyMempoolEvents implements the stopnotifymempoolevents command
+// extension for websocket connections.
+func handleStopNotifyMempoolEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterMempoolEventUpdates(wsc)
+	return nil, nil
+}
+
+// handleNotifyWalletEvents implements the notifywalletevents command extension
+// for websocket connections.
+func handleNotifyWalletEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	if wsc.server.cfg.BDKWallets == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "BDK wallet must be enabled. (--bdkwallet)",
+		}
+	}
+	wsc.server.ntfnMgr.RegisterWalletEventUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyWalletEvents implements the stopnotifywalletevents command
+// extension for websocket connections.
+func handleStopNotifyWalletEvents(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterWalletEventUpdates(wsc)
+	return nil, nil
+}
+
 // handleNotifyReceived implements the notifyreceived command extension for
 // websocket connections.
 func handleNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
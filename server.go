@@ -6,6 +6,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/rand"
 	"crypto/tls"
@@ -14,6 +15,8 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
@@ -33,6 +36,7 @@ import (
 	"github.com/utreexo/utreexod/connmgr"
 	"github.com/utreexo/utreexod/database"
 	"github.com/utreexo/utreexod/electrum"
+	"github.com/utreexo/utreexod/grpcserver"
 	"github.com/utreexo/utreexod/mempool"
 	"github.com/utreexo/utreexod/mining"
 	"github.com/utreexo/utreexod/mining/cpuminer"
@@ -41,6 +45,7 @@ import (
 	"github.com/utreexo/utreexod/txscript"
 	"github.com/utreexo/utreexod/wallet"
 	"github.com/utreexo/utreexod/wire"
+	"github.com/utreexo/utreexod/zmq"
 )
 
 const (
@@ -60,6 +65,10 @@ const (
 	// retries when connecting to persistent peers.  It is adjusted by the
 	// number of retries such that there is a retry backoff.
 	connectionRetryInterval = time.Second * 5
+
+	// mempoolFileName is the name of the file in the data directory that
+	// the mempool is saved to on shutdown.
+	mempoolFileName = "mempool.dat"
 )
 
 var (
@@ -134,6 +143,14 @@ type broadcastInventoryAdd relayMsg
 // needs to be removed from the rebroadcast map
 type broadcastInventoryDel *wire.InvVect
 
+// broadcastInventoryAbandon is a type used to declare that the InvVect it
+// contains needs to be removed from the rebroadcast map because it was
+// abandoned.  Whether it was in the map is sent on the reply channel.
+type broadcastInventoryAbandon struct {
+	invVect *wire.InvVect
+	reply   chan bool
+}
+
 // relayMsg packages an inventory vector along with the newly discovered
 // inventory so the relay has access to that information.
 type relayMsg struct {
@@ -159,8 +176,11 @@ type peerState struct {
 	inboundPeers    map[int32]*serverPeer
 	outboundPeers   map[int32]*serverPeer
 	persistentPeers map[int32]*serverPeer
-	banned          map[string]time.Time
 	outboundGroups  map[string]int
+
+	// evictionKey keys the network groups of the inbound peers when
+	// picking the ones that are protected from eviction.
+	evictionKey [32]byte
 }
 
 // Count returns the count of all known peers.
@@ -180,6 +200,31 @@ func (ps *peerState) forAllOutboundPeers(closure func(sp *serverPeer)) {
 	}
 }
 
+// proofPeerCounts returns the number of outbound peers that have the passed
+// services along with the number of automatic outbound peers that don't.
+func (ps *peerState) proofPeerCounts(services wire.ServiceFlag) (int, int) {
+	var proofPeers, otherPeers int
+	ps.forAllOutboundPeers(func(sp *serverPeer) {
+		switch {
+		case hasServices(sp.Services(), services):
+			proofPeers++
+		case !sp.persistent:
+			otherPeers++
+		}
+	})
+	return proofPeers, otherPeers
+}
+
+// networkCounts returns the number of automatic outbound peers on each of the
+// networks.
+func (ps *peerState) networkCounts() [numPeerNetworks]int {
+	var counts [numPeerNetworks]int
+	for _, sp := range ps.outboundPeers {
+		counts[networkOf(sp.NA())]++
+	}
+	return counts
+}
+
 // forAllPeers is a helper function that runs closure on all peers known to
 // peerState.
 func (ps *peerState) forAllPeers(closure func(sp *serverPeer)) {
@@ -212,16 +257,18 @@ type txByteStats struct {
 type server struct {
 	// The following variables must only be used atomically.
 	// Putting the uint64s first makes them 64-bit aligned for 32-bit systems.
-	bytesReceived uint64 // Total bytes received from all peers since start.
-	bytesSent     uint64 // Total bytes sent by all peers since start.
-	txBytes       txByteStats
-	started       int32
-	shutdown      int32
-	shutdownSched int32
-	startupTime   int64
+	bytesReceived   uint64 // Total bytes received from all peers since start.
+	bytesSent       uint64 // Total bytes sent by all peers since start.
+	txBytes         txByteStats
+	started         int32
+	shutdown        int32
+	shutdownSched   int32
+	startupTime     int64
+	blockRelayPeers int32 // Number of block-relay-only peers.
 
 	chainParams          *chaincfg.Params
 	addrManager          *addrmgr.AddrManager
+	banManager           *banManager
 	connManager          *connmgr.ConnManager
 	sigCache             *txscript.SigCache
 	hashCache            *txscript.HashCache
@@ -241,10 +288,25 @@ type server struct {
 	wg                   sync.WaitGroup
 	quit                 chan struct{}
 	nat                  NAT
+	portMappingMtx       sync.Mutex
+	portMapping          portMappingStatus
 	db                   database.DB
 	timeSource           blockchain.MedianTimeSource
 	services             wire.ServiceFlag
 
+	// localTxs holds the locally submitted transactions back from the
+	// peers that aren't on Tor or I2P.  It's nil unless --privatebroadcast
+	// or --broadcastdelay is set.
+	localTxs *localTxBroadcast
+
+	// uploadTarget is the budget of the bytes sent to peers, which is nil
+	// unless --maxuploadtarget is set.
+	uploadTarget *uploadTarget
+
+	// dbCompactor compacts the block database on request and every
+	// --dbcompactinterval while the node is idle.
+	dbCompactor *dbCompactor
+
 	// The following fields are used for optional indexes.  They will be nil
 	// if the associated index is not enabled.  These fields are set during
 	// initial creation of the server and never changed afterwards, so they
@@ -253,8 +315,13 @@ type server struct {
 	addrIndex             *indexers.AddrIndex
 	cfIndex               *indexers.CfIndex
 	ttlIndex              *indexers.TTLIndex
+	spendIndex            *indexers.SpendIndex
+	silentPaymentIndex    *indexers.SilentPaymentIndex
+	blockTimeIndex        *indexers.BlockTimeIndex
+	scriptBalanceIndex    *indexers.ScriptBalanceIndex
 	utreexoProofIndex     *indexers.UtreexoProofIndex
 	flatUtreexoProofIndex *indexers.FlatUtreexoProofIndex
+	utreexoCFIndex        *indexers.UtreexoCFIndex
 
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
@@ -264,12 +331,25 @@ type server struct {
 	// a watch-only wallet functionality.
 	watchOnlyWallet *wallet.WatchOnlyWalletManager
 
+	// proofServer serves utreexo proofs and roots over HTTP.  It's nil if
+	// it isn't enabled.
+	proofServer *proofServer
+
+	// zmqPublisher publishes the notifications of the node to ZMQ
+	// subscribers.  It's nil if no ZMQ endpoints are configured.
+	zmqPublisher *zmq.Publisher
+
+	// grpcServer serves the gRPC API.  It's nil if no gRPC listeners are
+	// configured.
+	grpcServer *grpcserver.Server
+
 	// electrumServer is a stateless personal electrum server and it fetches data from
 	// the database and the watch only wallet and serves them to the connected client.
 	electrumServer *electrum.ElectrumServer
 
-	// bdkWallet keeps track of a wallet
-	bdkWallet *bdkwallet.Manager
+	// bdkWallets keeps track of the default wallet and the named wallets
+	// that are loaded.
+	bdkWallets *bdkwallet.Wallets
 
 	// cfCheckptCaches stores a cached slice of filter headers for cfcheckpt
 	// messages for each filter type.
@@ -283,6 +363,26 @@ type server struct {
 	// agentWhitelist is a list of whitelisted user agent substrings, no
 	// whitelisting will be applied if the list is empty or nil.
 	agentWhitelist []string
+
+	// maxBlockRelay is the most block-relay-only peers.  Those peers are
+	// the first automatic outbound peers and their addresses are saved as
+	// the anchors of the node on shutdown.
+	maxBlockRelay int32
+
+	// maxOtherOutbound is the most automatic outbound peers that don't
+	// serve the utreexo proofs the compact state needs.  The rest of the
+	// outbound slots are kept for peers that do.
+	maxOtherOutbound int
+
+	// targetOutbound is the number of automatic outbound peers, some of
+	// whose slots are kept for the networks of the --outboundquota option.
+	targetOutbound int
+
+	// anchors are the addresses of the block-relay-only peers of the last
+	// run that are still to be connected to.  They're connected to before
+	// any other address of the address manager.
+	anchors    []string
+	anchorsMtx sync.Mutex
 }
 
 // serverPeer extends the peer to maintain state shared by the server and
@@ -290,17 +390,20 @@ type server struct {
 type serverPeer struct {
 	// The following variables must only be used atomically
 	feeFilter int64
+	lastBlock int64 // Unix time in nanoseconds of the last new tip sent.
 
 	*peer.Peer
 
 	connReq        *connmgr.ConnReq
 	server         *server
 	persistent     bool
+	blockRelayOnly bool
+	feeler         bool
 	continueHash   *chainhash.Hash
 	relayMtx       sync.Mutex
 	disableRelayTx bool
 	sentAddrs      bool
-	isWhitelisted  bool
+	permissions    peerPermissions
 	filter         *bloom.Filter
 	addressesMtx   sync.RWMutex
 	knownAddresses map[string]struct{}
@@ -359,11 +462,11 @@ func (sp *serverPeer) setDisableRelayTx(disable bool) {
 }
 
 // relayTxDisabled returns whether or not relaying of transactions for the given
-// peer is disabled.
+// peer is disabled, which it always is for block-relay-only peers.
 // It is safe for concurrent access.
 func (sp *serverPeer) relayTxDisabled() bool {
 	sp.relayMtx.Lock()
-	isDisabled := sp.disableRelayTx
+	isDisabled := sp.disableRelayTx || sp.blockRelayOnly
 	sp.relayMtx.Unlock()
 
 	return isDisabled
@@ -398,7 +501,7 @@ func (sp *serverPeer) addBanScore(persistent, transient uint32, reason string) b
 	if cfg.DisableBanning {
 		return false
 	}
-	if sp.isWhitelisted {
+	if sp.permissions.has(permNoBan) {
 		peerLog.Debugf("Misbehaving whitelisted peer %s: %s", sp, reason)
 		return false
 	}
@@ -462,6 +565,15 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 		return nil
 	}
 
+	// Feeler connections are only made to check that there's a node at
+	// the address, which they're done with once it sent its version.
+	if sp.feeler {
+		peerLog.Debugf("Feeler connection to %s succeeded", sp)
+		addrManager.Good(remoteAddr)
+		sp.Disconnect()
+		return nil
+	}
+
 	// Reject outbound peers that are not full nodes.
 	wantServices := wire.SFNodeNetwork
 
@@ -521,6 +633,10 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 // OnVerAck is invoked when a peer receives a verack bitcoin message and is used
 // to kick start communication with them.
 func (sp *serverPeer) OnVerAck(_ *peer.Peer, _ *wire.MsgVerAck) {
+	// Feeler connections are being disconnected already.
+	if sp.feeler {
+		return
+	}
 	sp.server.AddPeer(sp)
 }
 
@@ -530,8 +646,9 @@ func (sp *serverPeer) OnVerAck(_ *peer.Peer, _ *wire.MsgVerAck) {
 // bloom filter loaded, the contents are filtered accordingly.
 func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 	// Only allow mempool requests if the server has bloom filtering
-	// enabled.
-	if sp.server.services&wire.SFNodeBloom != wire.SFNodeBloom {
+	// enabled, unless the peer is allowed to make them regardless.
+	allowed := sp.permissions.has(permMempool)
+	if sp.server.services&wire.SFNodeBloom != wire.SFNodeBloom && !allowed {
 		peerLog.Debugf("peer %v sent mempool request with bloom "+
 			"filtering disabled -- disconnecting", sp)
 		sp.Disconnect()
@@ -542,7 +659,7 @@ func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 	// The ban score accumulates and passes the ban threshold if a burst of
 	// mempool messages comes from a peer. The score decays each minute to
 	// half of its value.
-	if sp.addBanScore(0, 33, "mempool") {
+	if !allowed && sp.addBanScore(0, 33, "mempool") {
 		return
 	}
 
@@ -555,7 +672,16 @@ func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 	txDescs := txMemPool.TxDescs()
 	invMsg := wire.NewMsgInvSizeHint(uint(len(txDescs)))
 
+	localTxs := sp.server.localTxs
+	hideLocal := localTxs != nil && !isPrivatePeer(sp)
+	now := time.Now()
 	for _, txDesc := range txDescs {
+		// The locally submitted transactions that are held back
+		// from the peer aren't given away here either.
+		if hideLocal && localTxs.held(txDesc.Tx.Hash(), now) {
+			continue
+		}
+
 		// Either add all transactions when there is no bloom filter,
 		// or only the transactions that match the filter when there is
 		// one.
@@ -579,9 +705,10 @@ func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 // handler this does not serialize all transactions through a single thread
 // transactions don't rely on the previous one in a linear fashion like blocks.
 func (sp *serverPeer) OnTx(_ *peer.Peer, msg *wire.MsgTx) {
-	if cfg.BlocksOnly {
-		peerLog.Tracef("Ignoring tx %v from %v - blocksonly enabled",
-			msg.TxHash(), sp)
+	forceRelay := sp.permissions.has(permForceRelay)
+	if (cfg.BlocksOnly && !forceRelay) || sp.blockRelayOnly {
+		peerLog.Tracef("Ignoring tx %v from %v - transaction relay "+
+			"disabled", msg.TxHash(), sp)
 		return
 	}
 
@@ -592,6 +719,18 @@ func (sp *serverPeer) OnTx(_ *peer.Peer, msg *wire.MsgTx) {
 	iv := wire.NewInvVect(wire.InvTypeTx, tx.Hash())
 	sp.AddKnownInventory(iv)
 
+	// The transactions of peers allowed to force their relay are announced
+	// again to the peers that don't know about them yet when they're
+	// already in the memory pool.
+	if forceRelay {
+		txD, err := sp.server.txMemPool.FetchTxDesc(tx.Hash())
+		if err == nil {
+			peerLog.Debugf("Force relaying tx %v from %v", tx.Hash(), sp)
+			sp.server.relayTransactions([]*mempool.TxDesc{txD})
+			return
+		}
+	}
+
 	// Queue the transaction up to be handled by the sync manager and
 	// intentionally block further receives until the transaction is fully
 	// processed and known good or bad.  This helps prevent a malicious peer
@@ -625,6 +764,22 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 	// the bitcoin block has been fully processed.
 	sp.server.syncManager.QueueBlock(block, sp.Peer, sp.blockProcessed)
 	<-sp.blockProcessed
+
+	// Remember the peers that extend the chain, which are protected from
+	// being evicted.
+	if sp.server.chain.BestSnapshot().Hash == *block.Hash() {
+		atomic.StoreInt64(&sp.lastBlock, time.Now().UnixNano())
+	}
+}
+
+// lastBlockTime returns when the peer last sent a block that became the tip of
+// the chain, or the zero time if it never did.
+func (sp *serverPeer) lastBlockTime() time.Time {
+	lastBlock := atomic.LoadInt64(&sp.lastBlock)
+	if lastBlock == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, lastBlock)
 }
 
 // OnInv is invoked when a peer receives an inv bitcoin message and is
@@ -632,7 +787,7 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 // accordingly.  We pass the message down to blockmanager which will call
 // QueueMessage with any appropriate responses.
 func (sp *serverPeer) OnInv(_ *peer.Peer, msg *wire.MsgInv) {
-	if !cfg.BlocksOnly {
+	if !cfg.BlocksOnly && !sp.blockRelayOnly {
 		if len(msg.InvList) > 0 {
 			sp.server.syncManager.QueueInv(msg, sp.Peer)
 		}
@@ -643,7 +798,7 @@ func (sp *serverPeer) OnInv(_ *peer.Peer, msg *wire.MsgInv) {
 	for _, invVect := range msg.InvList {
 		if invVect.Type == wire.InvTypeTx {
 			peerLog.Tracef("Ignoring tx %v in inv from %v -- "+
-				"blocksonly enabled", invVect.Hash, sp)
+				"transaction relay disabled", invVect.Hash, sp)
 			if sp.ProtocolVersion() >= wire.BIP0037Version {
 				peerLog.Infof("Peer %v is announcing "+
 					"transactions -- disconnecting", sp)
@@ -684,7 +839,12 @@ func (sp *serverPeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
 	// bursts of small requests are not penalized as that would potentially ban
 	// peers performing IBD.
 	// This incremental score decays each minute to half of its value.
-	if sp.addBanScore(0, uint32(length)*99/wire.MaxInvPerMsg, "getdata") {
+	// Peers allowed to request proofs aren't limited, since the blocks and
+	// transactions they sync along with their utreexo proofs are requested
+	// in large batches.
+	if !sp.permissions.has(permProof) &&
+		sp.addBanScore(0, uint32(length)*99/wire.MaxInvPerMsg, "getdata") {
+
 		return
 	}
 
@@ -1185,7 +1345,9 @@ func (sp *serverPeer) OnGetCFCheckpt(_ *peer.Peer, msg *wire.MsgGetCFCheckpt) {
 // version  that is high enough to observe the bloom filter service support bit,
 // it will be banned since it is intentionally violating the protocol.
 func (sp *serverPeer) enforceNodeBloomFlag(cmd string) bool {
-	if sp.server.services&wire.SFNodeBloom != wire.SFNodeBloom {
+	if sp.server.services&wire.SFNodeBloom != wire.SFNodeBloom &&
+		!sp.permissions.has(permBloomFilter) {
+
 		// Ban the peer if the protocol version is high enough that the
 		// peer is knowingly violating the protocol and banning is
 		// enabled.
@@ -1337,6 +1499,11 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 		return
 	}
 
+	// Block-relay-only peers don't take part in relaying addresses.
+	if sp.blockRelayOnly {
+		return
+	}
+
 	// Ignore old style addresses which don't include a timestamp.
 	if sp.ProtocolVersion() < wire.NetAddressTimeVersion {
 		return
@@ -1423,16 +1590,20 @@ func (sp *serverPeer) OnNotFound(p *peer.Peer, msg *wire.MsgNotFound) {
 			numBlocks++
 		case wire.InvTypeUtreexoBlock:
 			numBlocks++
+			p.AddProofRequestFailure()
 		case wire.InvTypeWitnessUtreexoBlock:
 			numBlocks++
+			p.AddProofRequestFailure()
 		case wire.InvTypeTx:
 			numTxns++
 		case wire.InvTypeWitnessTx:
 			numTxns++
 		case wire.InvTypeWitnessUtreexoTx:
 			numTxns++
+			p.AddProofRequestFailure()
 		case wire.InvTypeUtreexoTx:
 			numTxns++
+			p.AddProofRequestFailure()
 		default:
 			peerLog.Debugf("Invalid inv type '%d' in notfound message from %s",
 				inv.Type, sp)
@@ -1498,6 +1669,35 @@ func (s *server) RemoveRebroadcastInventory(iv *wire.InvVect) {
 	s.modifyRebroadcastInv <- broadcastInventoryDel(iv)
 }
 
+// AbandonTransaction stops rebroadcasting the locally submitted transaction
+// with the passed hash and removes it from the mempool along with the
+// transactions that spend it, so that a transaction paying the same outputs
+// differently can be submitted in its place.  An error is returned when the
+// transaction isn't one that's being rebroadcast.
+func (s *server) AbandonTransaction(hash *chainhash.Hash) error {
+	reply := make(chan bool, 1)
+	select {
+	case s.modifyRebroadcastInv <- broadcastInventoryAbandon{
+		invVect: wire.NewInvVect(wire.InvTypeTx, hash),
+		reply:   reply,
+	}:
+	case <-s.quit:
+		return errors.New("server is shutting down")
+	}
+	if !<-reply {
+		return fmt.Errorf("transaction %v is not a locally submitted "+
+			"transaction that's being rebroadcast", hash)
+	}
+
+	tx, err := s.txMemPool.FetchTransaction(hash)
+	if err == nil {
+		s.txMemPool.RemoveTransaction(tx, true, true)
+	}
+	srvrLog.Infof("Abandoned transaction %v", hash)
+
+	return nil
+}
+
 // relayTransactions generates and relays inventory vectors for all of the
 // passed transactions to all connected peers.
 func (s *server) relayTransactions(txns []*mempool.TxDesc) {
@@ -1507,6 +1707,35 @@ func (s *server) relayTransactions(txns []*mempool.TxDesc) {
 	}
 }
 
+// relayLocalTransactions generates and relays inventory vectors for the passed
+// transactions that were submitted locally.  They're only announced to the
+// peers on Tor or I2P when the local transactions are held back from the
+// others, and to the others as well once the --broadcastdelay is up.
+func (s *server) relayLocalTransactions(txns []*mempool.TxDesc) {
+	if s.localTxs != nil {
+		now := time.Now()
+		for _, txD := range txns {
+			s.localTxs.add(txD.Tx.Hash(), now)
+		}
+	}
+	s.relayTransactions(txns)
+
+	if s.localTxs == nil || s.localTxs.delay == 0 {
+		return
+	}
+	time.AfterFunc(s.localTxs.delay, func() {
+		if atomic.LoadInt32(&s.shutdown) != 0 {
+			return
+		}
+		for _, txD := range txns {
+			if s.txMemPool.HaveTransaction(txD.Tx.Hash()) {
+				iv := wire.NewInvVect(wire.InvTypeTx, txD.Tx.Hash())
+				s.RelayInventory(iv, txD)
+			}
+		}
+	})
+}
+
 // AnnounceNewTransactions generates and relays inventory vectors and notifies
 // both websocket and getblocktemplate long poll clients of the passed
 // transactions.  This function should be called whenever new transactions
@@ -1522,13 +1751,21 @@ func (s *server) AnnounceNewTransactions(txns []*mempool.TxDesc) {
 		s.rpcServer.NotifyNewTransactions(txns)
 	}
 
-	if s.bdkWallet != nil {
-		s.bdkWallet.NotifyNewTransactions(txns)
+	if s.bdkWallets != nil {
+		s.bdkWallets.NotifyNewTransactions(txns)
 	}
 
 	if s.watchOnlyWallet != nil {
 		s.watchOnlyWallet.NotifyNewTransactions(txns)
 	}
+
+	if s.zmqPublisher != nil {
+		s.zmqPublisher.NotifyNewTransactions(txns)
+	}
+
+	if s.grpcServer != nil {
+		s.grpcServer.NotifyNewTransactions(txns)
+	}
 }
 
 // Transaction has one confirmation on the main chain. Now we can mark it as no
@@ -1606,6 +1843,7 @@ func (s *server) pushTxMsg(sp *serverPeer, hash *chainhash.Hash, packedPositions
 				}
 
 				tx.MsgTx().UData = ud
+				s.chain.RecordProofServed(ud)
 			}
 		}
 		// For bridge nodes.
@@ -1631,6 +1869,7 @@ func (s *server) pushTxMsg(sp *serverPeer, hash *chainhash.Hash, packedPositions
 				}
 
 				tx.MsgTx().UData = ud
+				s.chain.RecordProofServed(ud)
 			}
 		} else if s.flatUtreexoProofIndex != nil {
 			if packedPositions != nil || len(packedPositions) == 0 {
@@ -1653,6 +1892,7 @@ func (s *server) pushTxMsg(sp *serverPeer, hash *chainhash.Hash, packedPositions
 				}
 
 				tx.MsgTx().UData = ud
+				s.chain.RecordProofServed(ud)
 			}
 		}
 	}
@@ -1672,6 +1912,13 @@ func (s *server) pushTxMsg(sp *serverPeer, hash *chainhash.Hash, packedPositions
 func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan chan<- struct{},
 	waitChan <-chan struct{}, encoding wire.MessageEncoding) error {
 
+	if !s.servesBlock(sp, hash) {
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return errUploadTargetReached
+	}
+
 	// Early check to see if Utreexo proof index is there if UtreexoEncoding is given.
 	doUtreexo := encoding&wire.UtreexoEncoding == wire.UtreexoEncoding
 	if doUtreexo && s.utreexoProofIndex == nil && s.flatUtreexoProofIndex == nil && cfg.NoUtreexo {
@@ -1754,7 +2001,22 @@ func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan cha
 			}
 		}
 
+		// Mark the leaves that are spent soon to be remembered since
+		// the ttl index knows when they're spent.
+		if s.ttlIndex != nil && cfg.UtreexoRememberMaxTTL > 0 &&
+			len(ud.RememberIdx) == 0 {
+
+			height, err := s.chain.BlockHeightByHash(hash)
+			if err == nil {
+				block := btcutil.NewBlock(&msgBlock)
+				block.SetHeight(height)
+				ud.RememberIdx = s.ttlIndex.Remembers(block,
+					cfg.UtreexoRememberMaxTTL)
+			}
+		}
+
 		msgBlock.UData = ud
+		s.chain.RecordProofServed(ud)
 	}
 
 	// Once we have fetched data wait for any previous operation to finish.
@@ -1788,6 +2050,26 @@ func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan cha
 	return nil
 }
 
+// servesBlock returns whether the block is served to the peer, which historical
+// blocks aren't once the upload target leaves no room for them unless the peer
+// has the download permission.  The peer is told they're not found instead of
+// being disconnected, so that it can get them from other peers.
+func (s *server) servesBlock(sp *serverPeer, hash *chainhash.Hash) bool {
+	if s.uploadTarget == nil || sp.permissions.has(permDownload) {
+		return true
+	}
+	height, err := s.chain.BlockHeightByHash(hash)
+	if err != nil || s.chain.BestSnapshot().Height-height < historicalBlockDepth {
+		return true
+	}
+	if s.uploadTarget.servesHistorical(time.Now()) {
+		return true
+	}
+	peerLog.Debugf("Not serving historical block %v to %v: %v", hash, sp,
+		errUploadTargetReached)
+	return false
+}
+
 // pushMerkleBlockMsg sends a merkleblock message for the provided block hash to
 // the connected peer.  Since a merkle block requires the peer to have a filter
 // loaded, this call will simply be ignored if there is no filter loaded.  An
@@ -1803,6 +2085,13 @@ func (s *server) pushMerkleBlockMsg(sp *serverPeer, hash *chainhash.Hash,
 		return nil
 	}
 
+	if !s.servesBlock(sp, hash) {
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return errUploadTargetReached
+	}
+
 	// Fetch the raw block bytes from the database.
 	blk, err := sp.server.chain.BlockByHash(hash)
 	if err != nil {
@@ -1877,6 +2166,30 @@ func (s *server) handleUpdatePeerHeights(state *peerState, umsg updatePeerHeight
 	})
 }
 
+// evictInboundPeer disconnects one of the inbound peers that aren't protected
+// from eviction.  It returns false when all of them are protected.  It is
+// invoked from the peerHandler goroutine.
+func (s *server) evictInboundPeer(state *peerState) bool {
+	var candidates []*evictionCandidate
+	for _, sp := range state.inboundPeers {
+		if sp.permissions.has(permNoBan) || !sp.Connected() {
+			continue
+		}
+		candidates = append(candidates,
+			newEvictionCandidate(sp, state.evictionKey[:]))
+	}
+
+	evict := selectPeerToEvict(candidates)
+	if evict == nil {
+		return false
+	}
+	sp := state.inboundPeers[evict.id]
+	srvrLog.Infof("Max peers reached [%d] - evicting inbound peer %s",
+		cfg.MaxPeers, sp)
+	sp.Disconnect()
+	return true
+}
+
 // handleAddPeerMsg deals with adding new peers.  It is invoked from the
 // peerHandler goroutine.
 func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
@@ -1904,22 +2217,21 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		sp.Disconnect()
 		return false
 	}
-	if banEnd, ok := state.banned[host]; ok {
-		if time.Now().Before(banEnd) {
-			srvrLog.Debugf("Peer %s is banned for another %v - disconnecting",
-				host, time.Until(banEnd))
-			sp.Disconnect()
-			return false
-		}
-
-		srvrLog.Infof("Peer %s is no longer banned", host)
-		delete(state.banned, host)
+	banEnd, banned := s.banManager.isBanned(host)
+	if banned && !sp.permissions.has(permNoBan) {
+		srvrLog.Debugf("Peer %s is banned for another %v - disconnecting",
+			host, time.Until(banEnd))
+		sp.Disconnect()
+		return false
 	}
 
 	// TODO: Check for max peers from a single IP.
 
-	// Limit max number of total peers.
-	if state.Count() >= cfg.MaxPeers {
+	// Limit max number of total peers.  An inbound peer is made room for by
+	// evicting another inbound peer when one isn't protected.
+	if state.Count() >= cfg.MaxPeers &&
+		!(sp.Inbound() && s.evictInboundPeer(state)) {
+
 		srvrLog.Infof("Max peers reached [%d] - disconnecting peer %s",
 			cfg.MaxPeers, sp)
 		sp.Disconnect()
@@ -1928,12 +2240,43 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		return false
 	}
 
+	// Keep room among the automatic outbound peers for the peers that
+	// serve the utreexo proofs the compact state needs.
+	if !sp.Inbound() && !sp.persistent {
+		services := s.proofPeerServices()
+		_, otherPeers := state.proofPeerCounts(services)
+		if services != 0 && !hasServices(sp.Services(), services) &&
+			otherPeers >= s.maxOtherOutbound {
+
+			srvrLog.Debugf("Disconnecting peer %s with services %v, "+
+				"keeping its slot for a peer serving utreexo "+
+				"proofs", sp, sp.Services())
+			sp.Disconnect()
+			return false
+		}
+
+		// Keep the slots of the networks that haven't met their
+		// outbound quotas for peers on them.
+		counts := state.networkCounts()
+		network := networkOf(sp.NA())
+		quotas := &cfg.outboundQuotas
+		if quotas.total() > 0 &&
+			!quotas.allows(network, &counts, s.targetOutbound) {
+
+			srvrLog.Debugf("Disconnecting peer %s on %s, keeping "+
+				"its slot for a peer on %v", sp, network,
+				quotas.missing(&counts))
+			sp.Disconnect()
+			return false
+		}
+	}
+
 	// Add the new peer and start it.
 	srvrLog.Debugf("New peer %s", sp)
 	if sp.Inbound() {
 		state.inboundPeers[sp.ID()] = sp
 	} else {
-		state.outboundGroups[addrmgr.GroupKey(sp.NA())]++
+		state.outboundGroups[s.addrManager.GroupKey(sp.NA())]++
 		if sp.persistent {
 			state.persistentPeers[sp.ID()] = sp
 		} else {
@@ -1959,7 +2302,9 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		// Advertise the local address when the server accepts incoming
 		// connections and it believes itself to be close to the best
 		// known tip.
-		if !cfg.DisableListen && s.syncManager.IsCurrent() {
+		if !cfg.DisableListen && !sp.blockRelayOnly &&
+			s.syncManager.IsCurrent() {
+
 			// Get address that best matches.
 			lna := s.addrManager.GetBestLocalAddress(sp.NA())
 			if addrmgr.IsRoutable(lna) {
@@ -1973,10 +2318,18 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 		// more and the peer has a protocol version new enough to
 		// include a timestamp with addresses.
 		hasTimestamp := sp.ProtocolVersion() >= wire.NetAddressTimeVersion
-		if s.addrManager.NeedMoreAddresses() && hasTimestamp {
+		if s.addrManager.NeedMoreAddresses() && hasTimestamp &&
+			!sp.blockRelayOnly {
+
 			sp.QueueMessage(wire.NewMsgGetAddr(), nil)
 		}
 
+		// Remember the I2P peers that were connected to, since their
+		// addresses are never received from other peers.
+		if addrmgr.IsI2P(sp.NA()) {
+			s.addrManager.AddAddress(sp.NA(), sp.NA())
+		}
+
 		// Mark the address as a known good address.
 		s.addrManager.Good(sp.NA())
 	}
@@ -1998,8 +2351,9 @@ func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
 
 	// Regardless of whether the peer was found in our list, we'll inform
 	// our connection manager about the disconnection. This can happen if we
-	// process a peer's `done` message before its `add`.
-	if !sp.Inbound() {
+	// process a peer's `done` message before its `add`.  Feeler connections
+	// aren't made by the connection manager.
+	if !sp.Inbound() && !sp.feeler {
 		if sp.persistent {
 			s.connManager.Disconnect(sp.connReq.ID())
 		} else {
@@ -2010,7 +2364,7 @@ func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
 
 	if _, ok := list[sp.ID()]; ok {
 		if !sp.Inbound() && sp.VersionKnown() {
-			state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+			state.outboundGroups[s.addrManager.GroupKey(sp.NA())]--
 		}
 		delete(list, sp.ID())
 		srvrLog.Debugf("Removed peer %s", sp)
@@ -2029,7 +2383,11 @@ func (s *server) handleBanPeerMsg(state *peerState, sp *serverPeer) {
 	direction := directionString(sp.Inbound())
 	srvrLog.Infof("Banned peer %s (%s) for %v", host, direction,
 		cfg.BanDuration)
-	state.banned[host] = time.Now().Add(cfg.BanDuration)
+	err = s.banManager.ban(host, time.Now().Add(cfg.BanDuration),
+		banReasonMisbehaving)
+	if err != nil {
+		srvrLog.Errorf("Unable to ban peer %s: %v", host, err)
+	}
 }
 
 // relayUtreexoInv queues tx invs for utreexo invs with the proof positions appended
@@ -2120,6 +2478,7 @@ func (s *server) relayUtreexoTxInv(sp *serverPeer, msg relayMsg) {
 // handleRelayInvMsg deals with relaying inventory to peers that are not already
 // known to have it.  It is invoked from the peerHandler goroutine.
 func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
+	now := time.Now()
 	state.forAllPeers(func(sp *serverPeer) {
 		if !sp.Connected() {
 			return
@@ -2152,6 +2511,15 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 				return
 			}
 
+			// Don't relay the locally submitted transactions that
+			// are held back from the peers that aren't on Tor or
+			// I2P to them.
+			if s.localTxs != nil && !isPrivatePeer(sp) &&
+				s.localTxs.held(&msg.invVect.Hash, now) {
+
+				return
+			}
+
 			txD, ok := msg.data.(*mempool.TxDesc)
 			if !ok {
 				peerLog.Warnf("Underlying data for tx inv "+
@@ -2226,6 +2594,15 @@ type getOutboundGroup struct {
 	reply chan int
 }
 
+type getProofPeerCountMsg struct {
+	services wire.ServiceFlag
+	reply    chan int
+}
+
+type getNetworkCountsMsg struct {
+	reply chan [numPeerNetworks]int
+}
+
 type getAddedNodesMsg struct {
 	reply chan []*serverPeer
 }
@@ -2303,7 +2680,7 @@ func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 		found := disconnectPeer(state.persistentPeers, msg.cmp, func(sp *serverPeer) {
 			// Keep group counts ok since we remove from
 			// the list now.
-			state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+			state.outboundGroups[s.addrManager.GroupKey(sp.NA())]--
 		})
 
 		if found {
@@ -2318,6 +2695,13 @@ func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 		} else {
 			msg.reply <- 0
 		}
+
+	case getProofPeerCountMsg:
+		proofPeers, _ := state.proofPeerCounts(msg.services)
+		msg.reply <- proofPeers
+
+	case getNetworkCountsMsg:
+		msg.reply <- state.networkCounts()
 	// Request a list of the persistent (added) peers.
 	case getAddedNodesMsg:
 		// Respond with a slice of the relevant peers.
@@ -2339,7 +2723,7 @@ func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 		found = disconnectPeer(state.outboundPeers, msg.cmp, func(sp *serverPeer) {
 			// Keep group counts ok since we remove from
 			// the list now.
-			state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+			state.outboundGroups[s.addrManager.GroupKey(sp.NA())]--
 		})
 		if found {
 			// If there are multiple outbound connections to the same
@@ -2347,7 +2731,7 @@ func (s *server) handleQuery(state *peerState, querymsg interface{}) {
 			// peers are found.
 			for found {
 				found = disconnectPeer(state.outboundPeers, msg.cmp, func(sp *serverPeer) {
-					state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+					state.outboundGroups[s.addrManager.GroupKey(sp.NA())]--
 				})
 			}
 			msg.reply <- nil
@@ -2423,7 +2807,7 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 		UserAgentComments: cfg.UserAgentComments,
 		ChainParams:       sp.server.chainParams,
 		Services:          sp.server.services,
-		DisableRelayTx:    cfg.BlocksOnly,
+		DisableRelayTx:    cfg.BlocksOnly || sp.blockRelayOnly,
 		ProtocolVersion:   peer.MaxProtocolVersion,
 		TrickleInterval:   cfg.TrickleInterval,
 	}
@@ -2435,7 +2819,7 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 // for disconnection.
 func (s *server) inboundPeerConnected(conn net.Conn) {
 	sp := newServerPeer(s, false)
-	sp.isWhitelisted = isWhitelisted(conn.RemoteAddr())
+	sp.permissions = connPermissions(conn.RemoteAddr(), conn.LocalAddr())
 	sp.Peer = peer.NewInboundPeer(newPeerConfig(sp))
 	sp.AssociateConnection(conn)
 	go s.peerDoneHandler(sp)
@@ -2448,9 +2832,15 @@ func (s *server) inboundPeerConnected(conn net.Conn) {
 // manager of the attempt.
 func (s *server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 	sp := newServerPeer(s, c.Permanent)
+	if !c.Permanent {
+		sp.blockRelayOnly = s.reserveBlockRelayPeer()
+	}
 	p, err := peer.NewOutboundPeer(newPeerConfig(sp), c.Addr.String())
 	if err != nil {
 		srvrLog.Debugf("Cannot create outbound peer %s: %v", c.Addr, err)
+		if sp.blockRelayOnly {
+			atomic.AddInt32(&s.blockRelayPeers, -1)
+		}
 		if c.Permanent {
 			s.connManager.Disconnect(c.ID())
 		} else {
@@ -2461,19 +2851,81 @@ func (s *server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 	}
 	sp.Peer = p
 	sp.connReq = c
-	sp.isWhitelisted = isWhitelisted(conn.RemoteAddr())
+	sp.permissions = connPermissions(conn.RemoteAddr(), nil)
 	sp.AssociateConnection(conn)
 	go s.peerDoneHandler(sp)
 }
 
+// reserveBlockRelayPeer returns whether or not an outbound peer that is about
+// to be connected to is to be a block-relay-only peer, counting it as one if
+// so.
+func (s *server) reserveBlockRelayPeer() bool {
+	for {
+		count := atomic.LoadInt32(&s.blockRelayPeers)
+		if count >= s.maxBlockRelay {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&s.blockRelayPeers, count, count+1) {
+			return true
+		}
+	}
+}
+
+// nextAnchor returns the address of the next anchor to connect to, or nil
+// when all of them were connected to already.
+func (s *server) nextAnchor() net.Addr {
+	s.anchorsMtx.Lock()
+	defer s.anchorsMtx.Unlock()
+
+	for len(s.anchors) > 0 {
+		addrString := s.anchors[0]
+		s.anchors = s.anchors[1:]
+
+		addr, err := addrStringToNetAddr(addrString)
+		if err != nil {
+			srvrLog.Debugf("Skipping anchor %s: %v", addrString, err)
+			continue
+		}
+		srvrLog.Infof("Connecting to anchor %s", addrString)
+		return addr
+	}
+	return nil
+}
+
+// saveAnchors saves the addresses of the block-relay-only peers that the
+// handshake was completed with to the anchors file in the data directory.
+func (s *server) saveAnchors(state *peerState) {
+	var anchors []string
+	for _, sp := range state.outboundPeers {
+		if sp.blockRelayOnly && sp.VerAckReceived() &&
+			len(anchors) < maxBlockRelayPeers {
+
+			anchors = append(anchors, sp.Addr())
+		}
+	}
+	if len(anchors) == 0 {
+		return
+	}
+
+	path := filepath.Join(cfg.DataDir, anchorsFileName)
+	if err := saveAnchors(path, anchors); err != nil {
+		srvrLog.Errorf("Unable to save the anchors: %v", err)
+		return
+	}
+	srvrLog.Infof("Saved %d anchors to %s", len(anchors), path)
+}
+
 // peerDoneHandler handles peer disconnects by notifiying the server that it's
 // done along with other performing other desirable cleanup.
 func (s *server) peerDoneHandler(sp *serverPeer) {
 	sp.WaitForDisconnect()
 	s.donePeers <- sp
+	if sp.blockRelayOnly {
+		atomic.AddInt32(&s.blockRelayPeers, -1)
+	}
 
 	// Only tell sync manager we are gone if we ever told it we existed.
-	if sp.VerAckReceived() {
+	if sp.VerAckReceived() && !sp.feeler {
 		s.syncManager.DonePeer(sp.Peer)
 
 		// Evict any remaining orphans that were sent by the peer.
@@ -2505,15 +2957,23 @@ func (s *server) peerHandler() {
 		inboundPeers:    make(map[int32]*serverPeer),
 		persistentPeers: make(map[int32]*serverPeer),
 		outboundPeers:   make(map[int32]*serverPeer),
-		banned:          make(map[string]time.Time),
 		outboundGroups:  make(map[string]int),
 	}
+	if _, err := rand.Read(state.evictionKey[:]); err != nil {
+		srvrLog.Errorf("Unable to create the eviction key: %v", err)
+	}
 
 	if !cfg.DisableDNSSeed {
 		requiredServices := defaultRequiredServices
 		if !cfg.NoUtreexo {
 			requiredServices |= wire.SFNodeUtreexo
 		}
+
+		// Compact state nodes ask the seeds for the peers that serve
+		// the utreexo proofs they need, so that they don't have to go
+		// through the other utreexo nodes to find them.
+		requiredServices |= s.proofPeerServices()
+
 		// Add peers discovered through DNS to the address manager.
 		connmgr.SeedFromDNS(activeNetParams.Params, requiredServices,
 			btcdLookup, func(addrs []*wire.NetAddress) {
@@ -2559,6 +3019,12 @@ out:
 			s.handleQuery(state, qmsg)
 
 		case <-s.quit:
+			// Save the block-relay-only peers as the anchors to
+			// connect to first on the next start.
+			if s.maxBlockRelay > 0 {
+				s.saveAnchors(state)
+			}
+
 			// Disconnect all peers on server shutdown.
 			state.forAllPeers(func(sp *serverPeer) {
 				srvrLog.Tracef("Shutdown peer %s", sp)
@@ -2572,6 +3038,12 @@ out:
 	s.syncManager.Stop()
 	s.addrManager.Stop()
 
+	// The I2P session is only closed along with the listeners when it
+	// accepts incoming connections.
+	if cfg.i2pSession != nil {
+		cfg.i2pSession.Close()
+	}
+
 	// If utreexoProofIndex option is on, flush it after closing down syncManager.
 	if s.utreexoProofIndex != nil {
 		err := s.utreexoProofIndex.FlushUtreexoState()
@@ -2649,10 +3121,44 @@ func (s *server) OutboundGroupCount(key string) int {
 	return <-replyChan
 }
 
+// ProofPeerCount returns the number of outbound peers that have the passed
+// services.
+func (s *server) ProofPeerCount(services wire.ServiceFlag) int {
+	replyChan := make(chan int)
+	s.query <- getProofPeerCountMsg{services: services, reply: replyChan}
+	return <-replyChan
+}
+
+// NetworkCounts returns the number of automatic outbound peers on each of the
+// networks.
+func (s *server) NetworkCounts() [numPeerNetworks]int {
+	replyChan := make(chan [numPeerNetworks]int)
+	s.query <- getNetworkCountsMsg{reply: replyChan}
+	return <-replyChan
+}
+
+// proofPeerServices returns the services an outbound peer needs to serve the
+// utreexo proofs that the compact state needs, or zero when it doesn't need
+// any.  The proofs of all the blocks are needed until the chain is current,
+// and only those of the new blocks afterwards.
+func (s *server) proofPeerServices() wire.ServiceFlag {
+	if !s.chain.IsUtreexoViewActive() || cfg.MinProofPeers == 0 {
+		return 0
+	}
+	services := wire.ServiceFlag(wire.SFNodeUtreexo | wire.SFNodeUtreexoBridge)
+	if !s.syncManager.IsCurrent() {
+		services |= wire.SFNodeUtreexoArchive
+	}
+	return services
+}
+
 // AddBytesSent adds the passed number of bytes to the total bytes sent counter
 // for the server.  It is safe for concurrent access.
 func (s *server) AddBytesSent(bytesSent uint64) {
 	atomic.AddUint64(&s.bytesSent, bytesSent)
+	if s.uploadTarget != nil {
+		s.uploadTarget.add(bytesSent, time.Now())
+	}
 }
 
 // AddBytesReceived adds the passed number of bytes to the total bytes received
@@ -2814,12 +3320,41 @@ out:
 			// now remove it, if it was present.
 			case broadcastInventoryDel:
 				delete(pendingInvs, *msg)
+				if s.localTxs != nil {
+					s.localTxs.remove(&msg.Hash)
+				}
+
+			// Abandoned InvVects are no longer rebroadcast.
+			case broadcastInventoryAbandon:
+				_, ok := pendingInvs[*msg.invVect]
+				delete(pendingInvs, *msg.invVect)
+				if s.localTxs != nil {
+					s.localTxs.remove(&msg.invVect.Hash)
+				}
+				msg.reply <- ok
 			}
 
 		case <-timer.C:
 			// Any inventory we have has not made it into a block
 			// yet. We periodically resubmit them until they have.
+			// Transactions that left the mempool without being
+			// mined, such as when they were replaced, evicted or
+			// spent a transaction that was, can't be relayed
+			// anymore so they're forgotten.
 			for iv, data := range pendingInvs {
+				if iv.Type == wire.InvTypeTx &&
+					!s.txMemPool.HaveTransaction(&iv.Hash) {
+
+					srvrLog.Debugf("Not rebroadcasting "+
+						"transaction %v that left the "+
+						"mempool", iv.Hash)
+					delete(pendingInvs, iv)
+					if s.localTxs != nil {
+						s.localTxs.remove(&iv.Hash)
+					}
+					continue
+				}
+
 				ivCopy := iv
 				s.RelayInventory(&ivCopy, data)
 			}
@@ -2841,7 +3376,10 @@ out:
 cleanup:
 	for {
 		select {
-		case <-s.modifyRebroadcastInv:
+		case riv := <-s.modifyRebroadcastInv:
+			if msg, ok := riv.(broadcastInventoryAbandon); ok {
+				msg.reply <- false
+			}
 		default:
 			break cleanup
 		}
@@ -2858,6 +3396,15 @@ func (s *server) Start() {
 
 	srvrLog.Trace("Starting server")
 
+	// Load the transactions that were in the mempool on shutdown before
+	// any peers connect.
+	if !cfg.NoPersistMempool {
+		err := s.loadMempool()
+		if err != nil {
+			srvrLog.Errorf("Unable to load the mempool: %v", err)
+		}
+	}
+
 	// Server startup time. Used for the uptime command for uptime calculation.
 	s.startupTime = time.Now().Unix()
 
@@ -2868,7 +3415,19 @@ func (s *server) Start() {
 
 	if s.nat != nil {
 		s.wg.Add(1)
-		go s.upnpUpdateThread()
+		go s.natUpdateThread()
+	}
+
+	if cfg.TorControl != "" {
+		s.wg.Add(1)
+		go s.torControlHandler()
+	}
+
+	// Feeler connections are only made along with the automatic outbound
+	// connections to the addresses of the address manager.
+	if !cfg.SimNet && len(cfg.ConnectPeers) == 0 {
+		s.wg.Add(1)
+		go s.feelerHandler()
 	}
 
 	if !cfg.DisableRPC {
@@ -2886,11 +3445,30 @@ func (s *server) Start() {
 		s.cpuMiner.Start()
 	}
 
+	if s.proofServer != nil {
+		s.proofServer.Start()
+	}
+
+	if s.zmqPublisher != nil {
+		s.zmqPublisher.Start()
+	}
+
+	if s.grpcServer != nil {
+		s.grpcServer.Start()
+	}
+
 	// Start the watch only wallet if it's enabled.
 	if cfg.WatchOnlyWallet {
 		s.watchOnlyWallet.Start()
 		s.electrumServer.Start()
 	}
+
+	// Start the automatic backups of the bdk wallets if they're enabled.
+	if s.bdkWallets != nil {
+		s.bdkWallets.Start()
+	}
+
+	s.dbCompactor.Start()
 }
 
 // Stop gracefully shuts down the server by stopping and disconnecting all
@@ -2912,12 +3490,39 @@ func (s *server) Stop() error {
 		s.rpcServer.Stop()
 	}
 
+	if s.proofServer != nil {
+		s.proofServer.Stop()
+	}
+
+	if s.zmqPublisher != nil {
+		s.zmqPublisher.Stop()
+	}
+
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+
 	// Stop the watch only wallet if it's enabled.
 	if cfg.WatchOnlyWallet {
 		s.watchOnlyWallet.Stop()
 		s.electrumServer.Stop()
 	}
 
+	if s.bdkWallets != nil {
+		s.bdkWallets.Stop()
+	}
+
+	// Interrupt the compaction of the block database if one is running.
+	s.dbCompactor.Stop()
+
+	// Save the mempool so that it's loaded again on the next start.
+	if !cfg.NoPersistMempool {
+		err := s.saveMempool()
+		if err != nil {
+			srvrLog.Errorf("Unable to save the mempool: %v", err)
+		}
+	}
+
 	// Save fee estimator state in the database.
 	s.db.Update(func(tx database.Tx) error {
 		metadata := tx.Metadata()
@@ -2931,6 +3536,63 @@ func (s *server) Stop() error {
 	return nil
 }
 
+// saveMempool writes the transactions in the mempool to the mempool file in the
+// data directory.  The transactions are written to a temporary file first so
+// that an interrupted save doesn't replace the previous file.
+func (s *server) saveMempool() error {
+	path := filepath.Join(cfg.DataDir, mempoolFileName)
+	tmpPath := path + ".new"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	count, err := s.txMemPool.Dump(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	srvrLog.Infof("Saved %d mempool transactions to %s", count, path)
+	return nil
+}
+
+// loadMempool revalidates the transactions saved to the mempool file in the
+// data directory and adds the ones that are still valid to the mempool.
+func (s *server) loadMempool() error {
+	path := filepath.Join(cfg.DataDir, mempoolFileName)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	accepted, rejected, err := s.txMemPool.Load(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+
+	srvrLog.Infof("Loaded %d mempool transactions from %s (%d no longer "+
+		"valid)", accepted, path, rejected)
+	return nil
+}
+
 // WaitForShutdown blocks until the main listener and peer handlers are stopped.
 func (s *server) WaitForShutdown() {
 	s.wg.Wait()
@@ -3020,16 +3682,146 @@ func parseListeners(addrs []string) ([]net.Addr, error) {
 	return netAddrs, nil
 }
 
-func (s *server) upnpUpdateThread() {
+// torControlHandler keeps an onion service forwarding incoming connections to
+// the listener of the server up through the Tor control port for as long as the
+// server runs.  The service is created again whenever the connection to the
+// control port is lost.  It must be run as a goroutine.
+func (s *server) torControlHandler() {
+	defer s.wg.Done()
+
+	for {
+		err := s.runOnionService()
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		srvrLog.Warnf("Onion service through the Tor control port %s "+
+			"is down: %v", cfg.TorControl, err)
+
+		select {
+		case <-time.After(torControlRetryInterval):
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// runOnionService creates the onion service of the server through the Tor
+// control port and returns once the connection to the control port is closed.
+// The private key of the service is kept in the data directory so that its
+// address stays the same across restarts.
+func (s *server) runOnionService() error {
+	// Tor reaches the listener over loopback unless it only listens on
+	// some other interface.
+	host, port, err := net.SplitHostPort(cfg.Listeners[0])
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	virtPort, err := strconv.ParseUint(activeNetParams.DefaultPort, 10, 16)
+	if err != nil {
+		return err
+	}
+
+	control, err := dialTorControl(cfg.TorControl)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.quit:
+		case <-done:
+		}
+		control.Close()
+	}()
+
+	if err := control.authenticate(cfg.TorPassword); err != nil {
+		return err
+	}
+	keyPath := filepath.Join(cfg.DataDir, torOnionKeyFile)
+	key, err := os.ReadFile(keyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	serviceID, privateKey, err := control.addOnion(
+		strings.TrimSpace(string(key)), uint16(virtPort),
+		net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+	if privateKey != strings.TrimSpace(string(key)) {
+		err := os.WriteFile(keyPath, []byte(privateKey+"\n"), 0600)
+		if err != nil {
+			return err
+		}
+	}
+	srvrLog.Infof("Onion service listening at %s.onion:%d", serviceID,
+		virtPort)
+
+	return control.wait()
+}
+
+// portMappingStatus is the state of the mapping of the listening port outside
+// of the NAT, which is reported by the getnetworkinfo RPC.
+type portMappingStatus struct {
+	protocol string
+	external *wire.NetAddress
+	renewed  time.Time
+	err      error
+}
+
+// natLeaser is implemented by the NATs that may map the ports for less time
+// than asked for.
+type natLeaser interface {
+	// leaseLifetime returns how long the last mapping was granted for.
+	leaseLifetime() time.Duration
+}
+
+// setPortMappingStatus records the outcome of the last attempt to map the
+// listening port.
+func (s *server) setPortMappingStatus(external *wire.NetAddress, err error) {
+	s.portMappingMtx.Lock()
+	s.portMapping.external = external
+	s.portMapping.err = err
+	if err == nil {
+		s.portMapping.renewed = time.Now()
+	}
+	s.portMappingMtx.Unlock()
+}
+
+// PortMapping returns the state of the mapping of the listening port outside
+// of the NAT, or nil when no UPnP, NAT-PMP or PCP router was found.
+//
+// This function is safe for concurrent access.
+func (s *server) PortMapping() *portMappingStatus {
+	if s.nat == nil {
+		return nil
+	}
+	s.portMappingMtx.Lock()
+	status := s.portMapping
+	s.portMappingMtx.Unlock()
+	status.protocol = s.nat.String()
+	return &status
+}
+
+func (s *server) natUpdateThread() {
 	// Go off immediately to prevent code duplication, thereafter we renew
-	// lease every 15 minutes.
+	// lease every 15 minutes, or at half of the lifetime the mapping was
+	// granted for when the NAT grants it for less.
 	timer := time.NewTimer(0 * time.Second)
 	lport, _ := strconv.ParseInt(activeNetParams.DefaultPort, 10, 16)
-	first := true
+	var bound *wire.NetAddress
 out:
 	for {
 		select {
 		case <-timer.C:
+			renewal := time.Minute * 15
+
 			// TODO: pick external port  more cleverly
 			// TODO: know which ports we are listening to on an external net.
 			// TODO: if specific listen port doesn't work then ask for wildcard
@@ -3038,26 +3830,43 @@ out:
 			listenPort, err := s.nat.AddPortMapping("tcp", int(lport), int(lport),
 				"btcd listen port", 20*60)
 			if err != nil {
-				srvrLog.Warnf("can't add UPnP port mapping: %v", err)
+				srvrLog.Warnf("can't add %v port mapping: %v", s.nat, err)
+				s.setPortMappingStatus(nil, err)
+				timer.Reset(renewal)
+				continue
 			}
-			if first && err == nil {
-				// TODO: look this up periodically to see if upnp domain changed
-				// and so did ip.
-				externalip, err := s.nat.GetExternalAddress()
-				if err != nil {
-					srvrLog.Warnf("UPnP can't get external address: %v", err)
-					continue out
+			if leaser, ok := s.nat.(natLeaser); ok {
+				lifetime := leaser.leaseLifetime()
+				if lifetime > 0 && lifetime/2 < renewal {
+					renewal = lifetime / 2
 				}
-				na := wire.NewNetAddressIPPort(externalip, uint16(listenPort),
-					s.services)
+			}
+
+			// The external address is looked up on each renewal since
+			// the router may have been given a new one.
+			externalip, err := s.nat.GetExternalAddress()
+			if err != nil {
+				srvrLog.Warnf("%v can't get external address: %v",
+					s.nat, err)
+				s.setPortMappingStatus(nil, err)
+				timer.Reset(renewal)
+				continue
+			}
+			na := wire.NewNetAddressIPPort(externalip, uint16(listenPort),
+				s.services)
+			s.setPortMappingStatus(na, nil)
+			if bound == nil || !bound.IP.Equal(na.IP) || bound.Port != na.Port {
 				err = s.addrManager.AddLocalAddress(na, addrmgr.UpnpPrio)
 				if err != nil {
-					// XXX DeletePortMapping?
+					srvrLog.Warnf("Skipping %v external address: %v",
+						s.nat, err)
+				} else {
+					srvrLog.Infof("Successfully bound via %v to %s",
+						s.nat, addrmgr.NetAddressKey(na))
 				}
-				srvrLog.Warnf("Successfully bound via UPnP to %s", addrmgr.NetAddressKey(na))
-				first = false
+				bound = na
 			}
-			timer.Reset(time.Minute * 15)
+			timer.Reset(renewal)
 		case <-s.quit:
 			break out
 		}
@@ -3066,14 +3875,36 @@ out:
 	timer.Stop()
 
 	if err := s.nat.DeletePortMapping("tcp", int(lport), int(lport)); err != nil {
-		srvrLog.Warnf("unable to remove UPnP port mapping: %v", err)
+		srvrLog.Warnf("unable to remove %v port mapping: %v", s.nat, err)
 	} else {
-		srvrLog.Debugf("successfully disestablished UPnP port mapping")
+		srvrLog.Debugf("successfully disestablished %v port mapping", s.nat)
 	}
 
 	s.wg.Done()
 }
 
+// rpcTLSConfig returns the TLS configuration for the --rpccert and --rpckey key
+// pair.  The key pair is generated if neither file exists.
+func rpcTLSConfig() (*tls.Config, error) {
+	// Generate the TLS cert and key file if both don't already
+	// exist.
+	if !fileExists(cfg.RPCKey) && !fileExists(cfg.RPCCert) {
+		err := genCertPair(cfg.RPCCert, cfg.RPCKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	keypair, err := tls.LoadX509KeyPair(cfg.RPCCert, cfg.RPCKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{keypair},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
 // setupListeners returns a slice of listeners that are configured for use
 // with the RPC server depending on the configuration settings for listen
 // addresses and TLS.
@@ -3081,27 +3912,14 @@ func setupListeners(rpcListeners []string, tlsOn bool) ([]net.Listener, error) {
 	// Setup TLS if not disabled.
 	listenFunc := net.Listen
 	if tlsOn {
-		// Generate the TLS cert and key file if both don't already
-		// exist.
-		if !fileExists(cfg.RPCKey) && !fileExists(cfg.RPCCert) {
-			err := genCertPair(cfg.RPCCert, cfg.RPCKey)
-			if err != nil {
-				return nil, err
-			}
-		}
-		keypair, err := tls.LoadX509KeyPair(cfg.RPCCert, cfg.RPCKey)
+		tlsConfig, err := rpcTLSConfig()
 		if err != nil {
 			return nil, err
 		}
 
-		tlsConfig := tls.Config{
-			Certificates: []tls.Certificate{keypair},
-			MinVersion:   tls.VersionTLS12,
-		}
-
 		// Change the standard net.Listen function to the tls one.
 		listenFunc = func(net string, laddr string) (net.Listener, error) {
-			return tls.Listen(net, laddr, &tlsConfig)
+			return tls.Listen(net, laddr, tlsConfig)
 		}
 	}
 
@@ -3123,6 +3941,43 @@ func setupListeners(rpcListeners []string, tlsOn bool) ([]net.Listener, error) {
 	return listeners, nil
 }
 
+// listenUnix listens on the Unix domain socket path.  The platforms with a
+// umask replace it with one that creates the socket with the file permissions
+// of mode so it's never accessible with the looser permissions of the process
+// umask.
+var listenUnix = func(path string, mode os.FileMode) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
+
+// setupUnixListeners returns a slice of listeners on the Unix domain socket
+// paths for use with the RPC server.  Stale sockets left by an unclean
+// shutdown are removed and the sockets are given the file permissions of mode.
+func setupUnixListeners(paths []string, mode os.FileMode) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(paths))
+	for _, path := range paths {
+		if fi, err := os.Lstat(path); err == nil &&
+			fi.Mode()&os.ModeSocket != 0 {
+
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+
+		listener, err := listenUnix(path, mode)
+		if err != nil {
+			rpcsLog.Warnf("Can't listen on %s: %v", path, err)
+			continue
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
 // newServer returns a new btcd server configured to listen on addr for the
 // bitcoin network type specified by chainParams.  Use start to begin accepting
 // connections from peers.
@@ -3151,8 +4006,29 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	if !cfg.NoUtreexo || cfg.UtreexoProofIndex || cfg.FlatUtreexoProofIndex {
 		services |= wire.SFNodeUtreexo
 	}
+	if cfg.UtreexoProofIndex || cfg.FlatUtreexoProofIndex {
+		services |= wire.SFNodeUtreexoBridge
+
+		// Pruned bridges don't keep the proofs of the old blocks.
+		if cfg.Prune == 0 {
+			services |= wire.SFNodeUtreexoArchive
+		}
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, btcdLookup)
+	if cfg.asmap != nil {
+		version := cfg.asmap.Version()
+		srvrLog.Infof("Grouping peer addresses with the ASN map %s "+
+			"(version %x)", cfg.ASMap, version[:8])
+		amgr.SetASMap(cfg.asmap)
+	}
+
+	banPath := filepath.Join(cfg.DataDir, banListFileName)
+	bm, banErr := newBanManager(banPath)
+	if banErr != nil {
+		srvrLog.Warnf("Unable to load the ban list, starting with "+
+			"no bans: %v", banErr)
+	}
 
 	var listeners []net.Listener
 	var nat NAT
@@ -3166,6 +4042,9 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			return nil, errors.New("no valid listen address")
 		}
 	}
+	if cfg.i2pSession != nil && !cfg.NoI2PListen {
+		listeners = append(listeners, cfg.i2pSession)
+	}
 
 	if len(agentBlacklist) > 0 {
 		srvrLog.Infof("User-agent blacklist %s", agentBlacklist)
@@ -3177,6 +4056,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	s := server{
 		chainParams:          chainParams,
 		addrManager:          amgr,
+		banManager:           bm,
 		newPeers:             make(chan *serverPeer, cfg.MaxPeers),
 		donePeers:            make(chan *serverPeer, cfg.MaxPeers),
 		banPeers:             make(chan *serverPeer, cfg.MaxPeers),
@@ -3196,6 +4076,16 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		agentBlacklist:       agentBlacklist,
 		agentWhitelist:       agentWhitelist,
 	}
+	if cfg.PrivateBroadcast || cfg.BroadcastDelay > 0 {
+		s.localTxs = newLocalTxBroadcast(cfg.BroadcastDelay)
+	}
+	if cfg.MaxUploadTarget > 0 {
+		s.uploadTarget = newUploadTarget(cfg.MaxUploadTarget*1024*1024,
+			time.Now())
+	}
+	s.dbCompactor = newDBCompactor(db, cfg.DbCompactInterval,
+		func() bool { return s.syncManager.IsCurrent() },
+		func() int32 { return s.chain.BestSnapshot().Height })
 
 	// Create the transaction and address indexes if needed.
 	//
@@ -3233,6 +4123,26 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		s.ttlIndex = indexers.NewTTLIndex(db, chainParams)
 		indexes = append(indexes, s.ttlIndex)
 	}
+	if cfg.SpendIndex {
+		indxLog.Info("Spend index is enabled")
+		s.spendIndex = indexers.NewSpendIndex(db)
+		indexes = append(indexes, s.spendIndex)
+	}
+	if cfg.SilentPaymentIndex {
+		indxLog.Info("Silent payment index is enabled")
+		s.silentPaymentIndex = indexers.NewSilentPaymentIndex(db)
+		indexes = append(indexes, s.silentPaymentIndex)
+	}
+	if cfg.BlockTimeIndex {
+		indxLog.Info("Block time index is enabled")
+		s.blockTimeIndex = indexers.NewBlockTimeIndex(db)
+		indexes = append(indexes, s.blockTimeIndex)
+	}
+	if cfg.ScriptBalanceIndex {
+		indxLog.Info("Script balance index is enabled")
+		s.scriptBalanceIndex = indexers.NewScriptBalanceIndex(db)
+		indexes = append(indexes, s.scriptBalanceIndex)
+	}
 	if cfg.UtreexoProofIndex {
 		indxLog.Info("Utreexo Proof index is enabled")
 
@@ -3262,6 +4172,14 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		}
 		indexes = append(indexes, s.flatUtreexoProofIndex)
 	}
+	// The utreexo committed filter header index fetches the roots from
+	// the utreexo proof indexes so it must come after them.
+	if cfg.UtreexoCFIndex {
+		indxLog.Info("Utreexo committed filter header index is enabled")
+		s.utreexoCFIndex = indexers.NewUtreexoCFIndex(db,
+			s.utreexoProofIndex, s.flatUtreexoProofIndex)
+		indexes = append(indexes, s.utreexoCFIndex)
+	}
 
 	// Create an index manager if any of the optional indexes are enabled.
 	var indexManager blockchain.IndexManager
@@ -3287,26 +4205,53 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		assumeUtreexoPoint = chaincfg.AssumeUtreexo{}
 	}
 
+	// Compact state nodes also cache the outputs that a connected block
+	// creates for the mempool transactions spending them so that the
+	// proofs of those transactions remain valid.
+	rememberPolicy := cfg.rememberPolicy
+	if !cfg.NoUtreexo {
+		rememberPolicy = blockchain.SpentRememberPolicy{
+			RememberPolicy: cfg.rememberPolicy,
+			IsSpent: func(op *wire.OutPoint) bool {
+				return s.txMemPool != nil &&
+					s.txMemPool.IsPoolOutputSpent(op)
+			},
+		}
+	}
+
 	// Create a new block chain instance with the appropriate configuration.
 	var err error
 	s.chain, err = blockchain.New(&blockchain.Config{
-		DB:                 s.db,
-		Interrupt:          interrupt,
-		ChainParams:        s.chainParams,
-		Checkpoints:        checkpoints,
-		TimeSource:         s.timeSource,
-		SigCache:           s.sigCache,
-		IndexManager:       indexManager,
-		HashCache:          s.hashCache,
-		UtxoCacheMaxSize:   uint64(cfg.UtxoCacheMaxSizeMiB) * 1024 * 1024,
-		UtreexoView:        utreexo,
-		Prune:              cfg.Prune * 1024 * 1024,
-		AssumeUtreexoPoint: assumeUtreexoPoint,
+		DB:                     s.db,
+		Interrupt:              interrupt,
+		ChainParams:            s.chainParams,
+		Checkpoints:            checkpoints,
+		TimeSource:             s.timeSource,
+		SigCache:               s.sigCache,
+		IndexManager:           indexManager,
+		HashCache:              s.hashCache,
+		UtxoCacheMaxSize:       uint64(cfg.UtxoCacheMaxSizeMiB) * 1024 * 1024,
+		UtreexoView:            utreexo,
+		RememberPolicy:         rememberPolicy,
+		UtreexoRootCheckpoints: cfg.rootCheckpoints,
+		Prune:                  cfg.Prune * 1024 * 1024,
+		AssumeUtreexoPoint:     assumeUtreexoPoint,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// Import the blocks of the files passed with --importblocks before
+	// anything subscribes to the notifications of the chain and before any
+	// peer is connected, so that the blocks aren't downloaded as well.
+	if len(cfg.ImportBlocks) > 0 {
+		err := importBlockFiles(s.chain, s.chainParams.Net,
+			cfg.ImportBlocks, interrupt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Search for a FeeEstimator state in the database. If none can be found
 	// or if it cannot be loaded, create a new one.
 	db.Update(func(tx database.Tx) error {
@@ -3330,11 +4275,10 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	})
 
 	// If no feeEstimator has been found, or if the one that has been found
-	// is behind somehow, create a new one and start over.
-	if s.feeEstimator == nil || s.feeEstimator.LastKnownHeight() != s.chain.BestSnapshot().Height {
-		s.feeEstimator = mempool.NewFeeEstimator(
-			mempool.DefaultEstimateFeeMaxRollback,
-			mempool.DefaultEstimateFeeMinRegisteredBlocks)
+	// is ahead of the chain somehow, create a new one and start over.  One
+	// that is behind catches up as the chain advances.
+	if s.feeEstimator == nil || s.feeEstimator.LastKnownHeight() > s.chain.BestSnapshot().Height {
+		s.feeEstimator = mempool.NewFeeEstimator()
 	}
 
 	txC := mempool.Config{
@@ -3346,8 +4290,16 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			MaxOrphanTxSize:      defaultMaxOrphanTxSize,
 			MaxSigOpCostPerTx:    blockchain.MaxBlockSigOpsCost / 4,
 			MinRelayTxFee:        cfg.minRelayTxFee,
-			MaxTxVersion:         2,
+			DustRelayFee:         cfg.dustRelayFee,
+			MaxStandardTxWeight:  int64(cfg.MaxStandardTxWeight),
+			MaxDataCarrierSize:   cfg.DataCarrierSize,
+			RejectDataCarrier:    cfg.NoDataCarrier,
+			RejectBareMultisig:   cfg.RejectBareMultisig,
+			MaxTxVersion:         3,
 			RejectReplacement:    cfg.RejectReplacement,
+			FullRBF:              cfg.MempoolFullRBF,
+			MaxPoolSize:          int64(cfg.MaxMempool) * 1000 * 1000,
+			MaxTxAge:             time.Duration(cfg.MempoolExpiry) * time.Hour,
 		},
 		ChainParams:    chainParams,
 		FetchUtxoView:  s.chain.FetchUtxoView,
@@ -3360,6 +4312,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		IsUtreexoViewActive:  s.chain.IsUtreexoViewActive,
 		VerifyUData:          s.chain.VerifyUData,
 		PruneFromAccumulator: s.chain.PruneFromAccumulator,
+		GenerateUData:        s.chain.GenerateUData,
 		SigCache:             s.sigCache,
 		HashCache:            s.hashCache,
 		AddrIndex:            s.addrIndex,
@@ -3392,6 +4345,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		BlockMaxSize:      cfg.BlockMaxSize,
 		BlockPrioritySize: cfg.BlockPrioritySize,
 		TxMinFreeFee:      cfg.minRelayTxFee,
+		BlockMinTxFee:     cfg.blockMinTxFee,
 	}
 	blockTemplateGenerator := mining.NewBlkTmplGenerator(&policy,
 		s.chainParams, s.txMemPool, s.chain, s.timeSource,
@@ -3414,6 +4368,29 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	var newAddressFunc func() (net.Addr, error)
 	if !cfg.SimNet && len(cfg.ConnectPeers) == 0 {
 		newAddressFunc = func() (net.Addr, error) {
+			if addr := s.nextAnchor(); addr != nil {
+				return addr, nil
+			}
+
+			// Only try the addresses of peers that serve the
+			// utreexo proofs the compact state needs while there
+			// are too few of them, as long as some are known.
+			proofServices := s.proofPeerServices()
+			if proofServices != 0 &&
+				s.ProofPeerCount(proofServices) >= cfg.MinProofPeers {
+
+				proofServices = 0
+			}
+
+			// Likewise, only try the addresses on the networks
+			// that haven't met their outbound quotas while there
+			// are any.
+			var wantNetworks []peerNetwork
+			if cfg.outboundQuotas.total() > 0 {
+				counts := s.NetworkCounts()
+				wantNetworks = cfg.outboundQuotas.missing(&counts)
+			}
+
 			for tries := 0; tries < 100; tries++ {
 				addr := s.addrManager.GetAddress()
 				if addr == nil {
@@ -3426,11 +4403,30 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 				// in the same group so that we are not connecting
 				// to the same network segment at the expense of
 				// others.
-				key := addrmgr.GroupKey(addr.NetAddress())
+				key := s.addrManager.GroupKey(addr.NetAddress())
 				if s.OutboundGroupCount(key) != 0 {
 					continue
 				}
 
+				// Don't bother connecting to banned peers.
+				addrString := addrmgr.NetAddressKey(addr.NetAddress())
+				host, _, err := net.SplitHostPort(addrString)
+				if err != nil {
+					continue
+				}
+				if _, banned := s.banManager.isBanned(host); banned {
+					continue
+				}
+
+				if tries < 70 && !hasServices(addr.Services(), proofServices) {
+					continue
+				}
+				if tries < 70 && len(wantNetworks) > 0 &&
+					!containsNetwork(wantNetworks, networkOf(addr.NetAddress())) {
+
+					continue
+				}
+
 				// only allow recent nodes (10mins) after we failed 30
 				// times
 				if tries < 30 && time.Since(addr.LastAttempt()) < 10*time.Minute {
@@ -3446,7 +4442,6 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 				// Mark an attempt for the valid address.
 				s.addrManager.Attempt(addr.NetAddress())
 
-				addrString := addrmgr.NetAddressKey(addr.NetAddress())
 				return addrStringToNetAddr(addrString)
 			}
 
@@ -3459,6 +4454,28 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	if cfg.MaxPeers < targetOutbound {
 		targetOutbound = cfg.MaxPeers
 	}
+
+	// A quarter of the automatic outbound peers at most are only used to
+	// relay blocks, and those of the last run are connected to first.
+	if newAddressFunc != nil {
+		s.maxBlockRelay = int32(targetOutbound / 4)
+		if s.maxBlockRelay > maxBlockRelayPeers {
+			s.maxBlockRelay = maxBlockRelayPeers
+		}
+	}
+	s.targetOutbound = targetOutbound
+	s.maxOtherOutbound = targetOutbound - cfg.MinProofPeers
+	if s.maxOtherOutbound < 0 {
+		s.maxOtherOutbound = 0
+	}
+	if s.maxBlockRelay > 0 {
+		anchors, err := loadAnchors(filepath.Join(cfg.DataDir,
+			anchorsFileName))
+		if err != nil {
+			srvrLog.Warnf("Unable to load the anchors: %v", err)
+		}
+		s.anchors = anchors
+	}
 	cmgr, err := connmgr.New(&connmgr.Config{
 		Listeners:      listeners,
 		OnAccept:       s.inboundPeerConnected,
@@ -3564,15 +4581,29 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 
 	if !cfg.NoBdkWallet {
 		// Setup BDK wallet if it is enabled.
-		s.bdkWallet, err = bdkwallet.NewManager(bdkwallet.ManagerConfig{
+		s.bdkWallets, err = bdkwallet.NewWallets(bdkwallet.ManagerConfig{
 			Chain:       s.chain,
 			TxMemPool:   s.txMemPool,
 			ChainParams: chainParams,
 			DataDir:     cfg.DataDir,
+
+			SilentPaymentIndex: s.silentPaymentIndex,
+			CfIndex:            s.cfIndex,
+
+			WatchOnlyDescriptor: cfg.BdkWatchOnly,
+			WatchOnlyBirthday:   cfg.BdkWatchOnlyBirthday,
+			SignerCommand:       cfg.BdkSigner,
+
+			BackupPassphrase: cfg.BdkBackupPassphrase,
+			BackupInterval:   cfg.BdkBackupInterval,
+			BackupDir:        cfg.BdkBackupDir,
+			BackupKeep:       cfg.BdkBackupKeep,
+
+			NotifyConfirmations: cfg.BdkNotifyConfirmations,
 		})
 		if err != nil {
 			if err == bdkwallet.ErrNoBDK {
-				s.bdkWallet = nil
+				s.bdkWallets = nil
 				cfg.NoBdkWallet = true
 				btcdLog.Infof("Unable to enable bkdwallet as utreexod wasn't built with bdkwallet. " +
 					"Starting node without bdkwallet.")
@@ -3589,6 +4620,12 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		if err != nil {
 			return nil, err
 		}
+		unixListeners, err := setupUnixListeners(cfg.RPCUnixSockets,
+			cfg.rpcSocketMode)
+		if err != nil {
+			return nil, err
+		}
+		rpcListeners = append(rpcListeners, unixListeners...)
 		if len(rpcListeners) == 0 {
 			return nil, errors.New("RPCS: No valid listen address")
 		}
@@ -3609,11 +4646,17 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			AddrIndex:             s.addrIndex,
 			CfIndex:               s.cfIndex,
 			TTLIndex:              s.ttlIndex,
+			SpendIndex:            s.spendIndex,
+			SilentPaymentIndex:    s.silentPaymentIndex,
+			BlockTimeIndex:        s.blockTimeIndex,
+			ScriptBalanceIndex:    s.scriptBalanceIndex,
 			UtreexoProofIndex:     s.utreexoProofIndex,
 			FlatUtreexoProofIndex: s.flatUtreexoProofIndex,
+			UtreexoCFIndex:        s.utreexoCFIndex,
 			FeeEstimator:          s.feeEstimator,
 			WatchOnlyWallet:       s.watchOnlyWallet,
-			BDKWallet:             s.bdkWallet,
+			BDKWallets:            s.bdkWallets,
+			DBCompactor:           s.dbCompactor,
 		})
 		if err != nil {
 			return nil, err
@@ -3626,6 +4669,83 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		}()
 	}
 
+	if len(cfg.ProofServerListeners) > 0 {
+		listeners, err := setupListeners(cfg.ProofServerListeners, cfg.ProofServerTLS)
+		if err != nil {
+			return nil, err
+		}
+		if len(listeners) == 0 {
+			return nil, errors.New("no valid listen address for the proof server")
+		}
+
+		s.proofServer, err = newProofServer(&proofServerConfig{
+			Listeners:             listeners,
+			Chain:                 s.chain,
+			UtreexoProofIndex:     s.utreexoProofIndex,
+			FlatUtreexoProofIndex: s.flatUtreexoProofIndex,
+			UploadTarget:          s.uploadTarget,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.GRPCListeners) > 0 {
+		tlsConfig, err := rpcTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		// gRPC requires HTTP/2, which is negotiated with ALPN.
+		tlsConfig.NextProtos = []string{"h2"}
+
+		listeners, err := setupListeners(cfg.GRPCListeners, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(listeners) == 0 {
+			return nil, errors.New("no valid listen address for the gRPC server")
+		}
+		for i := range listeners {
+			listeners[i] = tls.NewListener(listeners[i], tlsConfig)
+		}
+
+		s.grpcServer = grpcserver.New(&grpcserver.Config{
+			Listeners:             listeners,
+			Chain:                 s.chain,
+			TxMemPool:             s.txMemPool,
+			UtreexoProofIndex:     s.utreexoProofIndex,
+			FlatUtreexoProofIndex: s.flatUtreexoProofIndex,
+		})
+	}
+
+	zmqEndpoints := map[string][]string{
+		zmq.TopicHashBlock:    cfg.ZMQPubHashBlock,
+		zmq.TopicRawBlock:     cfg.ZMQPubRawBlock,
+		zmq.TopicHashTx:       cfg.ZMQPubHashTx,
+		zmq.TopicRawTx:        cfg.ZMQPubRawTx,
+		zmq.TopicUtreexoRoots: cfg.ZMQPubUtreexoRoots,
+		zmq.TopicMempoolEvent: cfg.ZMQPubMempoolEvent,
+		zmq.TopicWalletEvent:  cfg.ZMQPubWalletEvent,
+	}
+	for topic, endpoints := range zmqEndpoints {
+		if len(endpoints) == 0 {
+			delete(zmqEndpoints, topic)
+		}
+	}
+	if len(zmqEndpoints) > 0 {
+		s.zmqPublisher, err = zmq.New(&zmq.Config{
+			Endpoints: zmqEndpoints,
+			SendHWM:   cfg.ZMQPubHWM,
+			Chain:     s.chain,
+			TxMemPool: s.txMemPool,
+			Wallets:   s.bdkWallets,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if cfg.WatchOnlyWallet && !cfg.DisableElectrum {
 		listener, err := setupListeners(cfg.ElectrumListeners, false)
 		if err != nil {
@@ -3659,7 +4779,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			Mempool:                 s.txMemPool,
 			MinRelayFee:             cfg.minRelayTxFee,
 			AddRebroadcastInventory: s.AddRebroadcastInventory,
-			RelayTransactions:       s.relayTransactions,
+			RelayTransactions:       s.relayLocalTransactions,
 			AnnounceNewTransactions: s.AnnounceNewTransactions,
 		})
 		if err != nil {
@@ -3726,7 +4846,16 @@ func initListeners(amgr *addrmgr.AddrManager, listenAddrs []string, services wir
 			}
 		}
 	} else {
-		if cfg.Upnp {
+		// NAT-PMP and PCP are tried first when UPnP is enabled as
+		// well.
+		if cfg.NATPMP {
+			var err error
+			nat, err = DiscoverPMP()
+			if err != nil {
+				srvrLog.Warnf("Can't discover NAT-PMP or PCP: %v", err)
+			}
+		}
+		if nat == nil && cfg.Upnp {
 			var err error
 			nat, err = Discover()
 			if err != nil {
@@ -3781,6 +4910,15 @@ func addrStringToNetAddr(addr string) (net.Addr, error) {
 		return &onionAddr{addr: addr}, nil
 	}
 
+	// Neither can I2P addresses, which are dialed over the SAM bridge.
+	if strings.HasSuffix(host, ".i2p") {
+		if cfg.i2pSession == nil {
+			return nil, errors.New("i2p is not enabled")
+		}
+
+		return &i2pAddr{addr: addr}, nil
+	}
+
 	// Attempt to look up an IP address associated with the parsed host.
 	ips, err := btcdLookup(host)
 	if err != nil {
@@ -3864,32 +5002,6 @@ func dynamicTickDuration(remaining time.Duration) time.Duration {
 	return time.Hour
 }
 
-// isWhitelisted returns whether the IP address is included in the whitelisted
-// networks and IPs.
-func isWhitelisted(addr net.Addr) bool {
-	if len(cfg.whitelists) == 0 {
-		return false
-	}
-
-	host, _, err := net.SplitHostPort(addr.String())
-	if err != nil {
-		srvrLog.Warnf("Unable to SplitHostPort on '%s': %v", addr, err)
-		return false
-	}
-	ip := net.ParseIP(host)
-	if ip == nil {
-		srvrLog.Warnf("Unable to parse IP '%s'", addr)
-		return false
-	}
-
-	for _, ipnet := range cfg.whitelists {
-		if ipnet.Contains(ip) {
-			return true
-		}
-	}
-	return false
-}
-
 // checkpointSorter implements sort.Interface to allow a slice of checkpoints to
 // be sorted.
 type checkpointSorter []chaincfg.Checkpoint
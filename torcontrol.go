@@ -0,0 +1,309 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// torOnionKeyFile is the name of the file in the data directory that
+	// the private key of the onion service is kept in, so that the service
+	// keeps its address across restarts.
+	torOnionKeyFile = "onion_v3_private_key"
+
+	// torControlTimeout is how long connecting to the Tor control port may
+	// take.
+	torControlTimeout = 10 * time.Second
+
+	// torControlRetryInterval is how long to wait before connecting to the
+	// Tor control port again after the connection to it failed or was
+	// lost.
+	torControlRetryInterval = time.Minute
+
+	// The keys of the HMACs that the controller and Tor prove they know
+	// the authentication cookie with in SAFECOOKIE authentication.
+	torSafeCookieServerKey = "Tor safe cookie authentication server-to-controller hash"
+	torSafeCookieClientKey = "Tor safe cookie authentication controller-to-server hash"
+)
+
+// torControl is a connection to the control port of a Tor daemon.  It speaks
+// just enough of the Tor control protocol to authenticate and to create an
+// onion service, which lasts for as long as the connection stays open.
+type torControl struct {
+	conn   net.Conn
+	reader *textproto.Reader
+}
+
+// newTorControl returns a torControl speaking the Tor control protocol over
+// the connection.
+func newTorControl(conn net.Conn) *torControl {
+	return &torControl{
+		conn:   conn,
+		reader: textproto.NewReader(bufio.NewReader(conn)),
+	}
+}
+
+// dialTorControl connects to the Tor control port at the address.
+func dialTorControl(addr string) (*torControl, error) {
+	conn, err := net.DialTimeout("tcp", addr, torControlTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newTorControl(conn), nil
+}
+
+// Close closes the connection to the control port, which removes the onion
+// services created over it.
+func (c *torControl) Close() error {
+	return c.conn.Close()
+}
+
+// command sends the command to Tor and returns the lines of its reply without
+// their status codes.  Replies that don't have the 250 status code are turned
+// into errors.
+func (c *torControl) command(cmd string) ([]string, error) {
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", cmd); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for {
+		line, err := c.reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) < 4 {
+			return nil, fmt.Errorf("malformed tor control reply %q", line)
+		}
+		if line[:3] != "250" {
+			return nil, fmt.Errorf("tor control command failed: %s", line)
+		}
+
+		switch text := line[4:]; line[3] {
+		case ' ':
+			return append(lines, text), nil
+		case '-':
+			lines = append(lines, text)
+		case '+':
+			data, err := c.reader.ReadDotLines()
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, text+strings.Join(data, "\n"))
+		default:
+			return nil, fmt.Errorf("malformed tor control reply %q", line)
+		}
+	}
+}
+
+// torFields splits the line of a reply into its space separated fields,
+// keeping the quoted strings in them whole.
+func torFields(line string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+		field.WriteRune(r)
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// torKeyValues returns the values of the key=value fields of the line.
+func torKeyValues(line string) map[string]string {
+	values := make(map[string]string)
+	for _, field := range torFields(line) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// authenticate authenticates to Tor with the password when one is given and
+// Tor accepts it.  The authentication cookie of Tor is used otherwise, or no
+// authentication at all if Tor doesn't require any.
+func (c *torControl) authenticate(password string) error {
+	lines, err := c.command("PROTOCOLINFO 1")
+	if err != nil {
+		return err
+	}
+	methods := make(map[string]struct{})
+	var cookieFile string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "AUTH ") {
+			continue
+		}
+		values := torKeyValues(line[len("AUTH "):])
+		for _, method := range strings.Split(values["METHODS"], ",") {
+			methods[method] = struct{}{}
+		}
+		cookieFile = values["COOKIEFILE"]
+	}
+	has := func(method string) bool {
+		_, ok := methods[method]
+		return ok
+	}
+
+	switch {
+	case password != "" && has("HASHEDPASSWORD"):
+		_, err = c.command("AUTHENTICATE " + strconv.Quote(password))
+		return err
+
+	case has("SAFECOOKIE") && cookieFile != "":
+		return c.authenticateSafeCookie(cookieFile)
+
+	case has("COOKIE") && cookieFile != "":
+		cookie, err := os.ReadFile(cookieFile)
+		if err != nil {
+			return err
+		}
+		_, err = c.command("AUTHENTICATE " + hex.EncodeToString(cookie))
+		return err
+
+	case has("NULL"):
+		_, err = c.command("AUTHENTICATE")
+		return err
+	}
+
+	if password == "" && has("HASHEDPASSWORD") {
+		return errors.New("tor control port requires a password " +
+			"(--torpassword)")
+	}
+	return fmt.Errorf("no supported tor control authentication "+
+		"method in %q", lines)
+}
+
+// authenticateSafeCookie authenticates to Tor with the authentication cookie
+// in the file without sending the cookie itself, after making sure that the
+// other end of the connection knows the cookie too.
+func (c *torControl) authenticateSafeCookie(cookieFile string) error {
+	cookie, err := os.ReadFile(cookieFile)
+	if err != nil {
+		return err
+	}
+	if len(cookie) != 32 {
+		return fmt.Errorf("tor authentication cookie %s is %d bytes "+
+			"instead of 32", cookieFile, len(cookie))
+	}
+	clientNonce := make([]byte, 32)
+	if _, err := rand.Read(clientNonce); err != nil {
+		return err
+	}
+
+	lines, err := c.command("AUTHCHALLENGE SAFECOOKIE " +
+		hex.EncodeToString(clientNonce))
+	if err != nil {
+		return err
+	}
+	values := torKeyValues(strings.TrimPrefix(lines[0], "AUTHCHALLENGE "))
+	serverHash, err := hex.DecodeString(values["SERVERHASH"])
+	if err != nil {
+		return fmt.Errorf("malformed tor server hash: %v", err)
+	}
+	serverNonce, err := hex.DecodeString(values["SERVERNONCE"])
+	if err != nil {
+		return fmt.Errorf("malformed tor server nonce: %v", err)
+	}
+
+	msg := make([]byte, 0, len(cookie)+len(clientNonce)+len(serverNonce))
+	msg = append(msg, cookie...)
+	msg = append(msg, clientNonce...)
+	msg = append(msg, serverNonce...)
+	if !hmac.Equal(torSafeCookieHash(torSafeCookieServerKey, msg), serverHash) {
+		return errors.New("tor control port doesn't know the " +
+			"authentication cookie")
+	}
+	clientHash := torSafeCookieHash(torSafeCookieClientKey, msg)
+	_, err = c.command("AUTHENTICATE " + hex.EncodeToString(clientHash))
+	return err
+}
+
+// torSafeCookieHash returns the HMAC-SHA256 of the message with the key.
+func torSafeCookieHash(key string, msg []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+// addOnion creates a v3 onion service forwarding the virtual port to the
+// target address.  The service is created with the private key when one is
+// given and with a new key otherwise.  It returns the service id, which is
+// the onion address without the .onion suffix, along with the private key.
+func (c *torControl) addOnion(privateKey string, port uint16, target string) (string, string, error) {
+	key := privateKey
+	if key == "" {
+		key = "NEW:ED25519-V3"
+	}
+	lines, err := c.command(fmt.Sprintf("ADD_ONION %s Port=%d,%s",
+		key, port, target))
+	if err != nil {
+		return "", "", err
+	}
+
+	var serviceID string
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "ServiceID":
+			serviceID = value
+		case "PrivateKey":
+			privateKey = value
+		}
+	}
+	if serviceID == "" || privateKey == "" {
+		return "", "", fmt.Errorf("malformed ADD_ONION reply %q", lines)
+	}
+	return serviceID, privateKey, nil
+}
+
+// wait blocks until the connection to the control port is closed, which is
+// when the onion services created over it are gone.
+func (c *torControl) wait() error {
+	for {
+		// Tor only sends asynchronous events that were asked for, so
+		// whatever arrives is dropped.
+		if _, err := c.reader.ReadLine(); err != nil {
+			return err
+		}
+	}
+}
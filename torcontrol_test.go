@@ -0,0 +1,135 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTor answers the commands of the Tor control protocol read from the
+// connection the way a Tor daemon with cookie authentication does.  The
+// commands it got are sent on the channel.
+func fakeTor(conn net.Conn, cookieFile string, cookie []byte, commands chan<- string) {
+	defer close(commands)
+	serverNonce := bytes.Repeat([]byte{7}, 32)
+	var clientNonce []byte
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		cmd := scanner.Text()
+		commands <- cmd
+
+		var reply string
+		fields := strings.Fields(cmd)
+		switch fields[0] {
+		case "PROTOCOLINFO":
+			reply = fmt.Sprintf("250-PROTOCOLINFO 1\r\n"+
+				"250-AUTH METHODS=COOKIE,SAFECOOKIE COOKIEFILE=%q\r\n"+
+				"250-VERSION Tor=\"0.4.8.10\"\r\n250 OK\r\n", cookieFile)
+		case "AUTHCHALLENGE":
+			clientNonce, _ = hex.DecodeString(fields[2])
+			msg := append(append(append([]byte{}, cookie...), clientNonce...), serverNonce...)
+			reply = fmt.Sprintf("250 AUTHCHALLENGE SERVERHASH=%x SERVERNONCE=%x\r\n",
+				torSafeCookieHash(torSafeCookieServerKey, msg), serverNonce)
+		case "AUTHENTICATE":
+			msg := append(append(append([]byte{}, cookie...), clientNonce...), serverNonce...)
+			if fields[1] != hex.EncodeToString(torSafeCookieHash(torSafeCookieClientKey, msg)) {
+				reply = "515 Authentication failed: Wrong length on authentication cookie.\r\n"
+				break
+			}
+			reply = "250 OK\r\n"
+		case "ADD_ONION":
+			reply = "250-ServiceID=abcdefghijklmnopqrstuvwxyz234567abcdefghijklmnopqrstuvwx\r\n"
+			if fields[1] == "NEW:ED25519-V3" {
+				reply += "250-PrivateKey=ED25519-V3:secret\r\n"
+			}
+			reply += "250 OK\r\n"
+		default:
+			reply = "510 Unrecognized command\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// TestTorControl ensures that the Tor control port is authenticated to with
+// the safe cookie and that onion services are created with new and existing
+// keys.
+func TestTorControl(t *testing.T) {
+	cookie := bytes.Repeat([]byte{1}, 32)
+	cookieFile := filepath.Join(t.TempDir(), "control auth cookie")
+	if err := os.WriteFile(cookieFile, cookie, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, server := net.Pipe()
+	commands := make(chan string, 16)
+	go fakeTor(server, cookieFile, cookie, commands)
+	control := newTorControl(client)
+	defer control.Close()
+
+	if err := control.authenticate(""); err != nil {
+		t.Fatalf("unable to authenticate: %v", err)
+	}
+	serviceID, key, err := control.addOnion("", 8333, "127.0.0.1:8333")
+	if err != nil {
+		t.Fatalf("unable to add onion service: %v", err)
+	}
+	wantID := "abcdefghijklmnopqrstuvwxyz234567abcdefghijklmnopqrstuvwx"
+	if serviceID != wantID || key != "ED25519-V3:secret" {
+		t.Fatalf("got service %q with key %q, want %q with key %q",
+			serviceID, key, wantID, "ED25519-V3:secret")
+	}
+
+	// Tor doesn't send the key back when it's given one.
+	serviceID, key, err = control.addOnion("ED25519-V3:secret", 8333, "127.0.0.1:8333")
+	if err != nil {
+		t.Fatalf("unable to add onion service: %v", err)
+	}
+	if serviceID != wantID || key != "ED25519-V3:secret" {
+		t.Fatalf("got service %q with key %q, want %q with key %q",
+			serviceID, key, wantID, "ED25519-V3:secret")
+	}
+
+	if _, err := control.command("GETINFO version"); err == nil {
+		t.Fatal("expected an error for a command tor rejects")
+	}
+	control.Close()
+
+	var got []string
+	for cmd := range commands {
+		got = append(got, strings.Fields(cmd)[0])
+	}
+	want := []string{"PROTOCOLINFO", "AUTHCHALLENGE", "AUTHENTICATE",
+		"ADD_ONION", "ADD_ONION", "GETINFO"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got commands %v, want %v", got, want)
+	}
+}
+
+// TestTorFields ensures that the quoted strings of the replies of Tor are kept
+// whole when splitting them into their fields.
+func TestTorFields(t *testing.T) {
+	values := torKeyValues(`METHODS=COOKIE,SAFECOOKIE COOKIEFILE="/var/lib/tor dir/control_auth_cookie" VERSION="0.4 \"alpha\""`)
+	want := map[string]string{
+		"METHODS":    "COOKIE,SAFECOOKIE",
+		"COOKIEFILE": "/var/lib/tor dir/control_auth_cookie",
+		"VERSION":    `0.4 "alpha"`,
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Fatalf("got %s=%q, want %q", key, values[key], value)
+		}
+	}
+}
@@ -729,6 +729,16 @@ func (vm *Engine) verifyWitnessProgram(witness wire.TxWitness) error {
 			vm.SetStack(witness[:len(witness)-2])
 		}
 
+	// Pay-to-anchor outputs are keyless anchors rather than the witness
+	// program of a future soft fork, so their spends aren't discouraged.
+	// Like those, they can be spent by anyone, so only a true item is left
+	// on the stack for the spend to succeed.
+	case vm.isWitnessVersionActive(TaprootWitnessVersion) &&
+		bytes.Equal(vm.witnessProgram, payToAnchorProgram) && !vm.bip16:
+
+		vm.witnessProgram = nil
+		vm.SetStack([][]byte{{1}})
+
 	case vm.hasFlag(ScriptVerifyDiscourageUpgradeableWitnessProgram):
 		errStr := fmt.Sprintf("new witness program versions "+
 			"invalid: %v", vm.witnessProgram)
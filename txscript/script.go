@@ -92,6 +92,12 @@ func IsPayToTaproot(script []byte) bool {
 	return isWitnessTaprootScript(script)
 }
 
+// IsPayToAnchor returns true if the passed script is a standard pay-to-anchor
+// (P2A) script.
+func IsPayToAnchor(script []byte) bool {
+	return isPayToAnchorScript(script)
+}
+
 // IsWitnessProgram returns true if the passed script is a valid witness
 // program which is encoded according to the passed witness program version. A
 // witness program must be a small integer (from 0-16), followed by 2-40 bytes
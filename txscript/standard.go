@@ -67,6 +67,7 @@ const (
 	NullDataTy                               // Empty data-only (provably prunable).
 	WitnessV1TaprootTy                       // Taproot output
 	WitnessUnknownTy                         // Witness unknown
+	PayToAnchorTy                            // Pay to anchor
 )
 
 // scriptClassToName houses the human-readable strings which describe each
@@ -82,6 +83,7 @@ var scriptClassToName = []string{
 	NullDataTy:            "nulldata",
 	WitnessV1TaprootTy:    "witness_v1_taproot",
 	WitnessUnknownTy:      "witness_unknown",
+	PayToAnchorTy:         "anchor",
 }
 
 // String implements the Stringer interface by returning the name of
@@ -470,6 +472,20 @@ func isWitnessTaprootScript(script []byte) bool {
 	return extractWitnessV1KeyBytes(script) != nil
 }
 
+// payToAnchorProgram is the witness program of a pay-to-anchor output, which
+// is a keyless anchor that anyone can spend with an empty witness.
+var payToAnchorProgram = []byte{0x4e, 0x73}
+
+// isPayToAnchorScript returns whether or not the passed script is a
+// pay-to-anchor script of the form:
+//
+//	OP_1 OP_DATA_2 0x4e73
+func isPayToAnchorScript(script []byte) bool {
+	return len(script) == 2+len(payToAnchorProgram) &&
+		script[0] == OP_1 && script[1] == OP_DATA_2 &&
+		bytes.Equal(script[2:], payToAnchorProgram)
+}
+
 // isAnnexedWitness returns true if the passed witness has a final push
 // that is a witness annex.
 func isAnnexedWitness(witness wire.TxWitness) bool {
@@ -553,6 +569,8 @@ func typeOfScript(scriptVersion uint16, script []byte) ScriptClass {
 		switch {
 		case isWitnessTaprootScript(script):
 			return WitnessV1TaprootTy
+		case isPayToAnchorScript(script):
+			return PayToAnchorTy
 		}
 	}
 
@@ -1040,6 +1058,11 @@ func ExtractPkScriptAddrs(pkScript []byte,
 		return WitnessV1TaprootTy, addrs, 1, nil
 	}
 
+	// Pay-to-anchor outputs have no addresses and need no signatures.
+	if isPayToAnchorScript(pkScript) {
+		return PayToAnchorTy, nil, 0, nil
+	}
+
 	// If none of the above passed, then the address must be non-standard.
 	return NonStandardTy, nil, 0, nil
 }
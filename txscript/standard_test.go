@@ -1107,6 +1107,19 @@ var scriptClassTests = []struct {
 		script: "0 DATA_32 0x9f96ade4b41d5433f4eda31e1738ec2b36f6e7d1420d94a6af99801a88f7f7ff",
 		class:  WitnessV0ScriptHashTy,
 	},
+	{
+		// A pay to anchor pk script.
+		name:   "Pay To Anchor",
+		script: "1 DATA_2 0x4e73",
+		class:  PayToAnchorTy,
+	},
+	{
+		// A witness v1 program of the size of an anchor that isn't
+		// the anchor program.
+		name:   "witness v1 2-byte program",
+		script: "1 DATA_2 0x4e74",
+		class:  NonStandardTy,
+	},
 }
 
 // TestScriptClass ensures all the scripts in scriptClassTests have the expected
@@ -1175,6 +1188,11 @@ func TestStringifyClass(t *testing.T) {
 			class:    NullDataTy,
 			stringed: "nulldata",
 		},
+		{
+			name:     "anchor",
+			class:    PayToAnchorTy,
+			stringed: "anchor",
+		},
 		{
 			name:     "broken",
 			class:    ScriptClass(255),
@@ -0,0 +1,25 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+func init() {
+	listenUnix = func(path string, mode os.FileMode) (net.Listener, error) {
+		// The umask is process wide so it's only tightened for as long
+		// as it takes to create the socket.
+		oldMask := syscall.Umask(int(^mode & os.ModePerm))
+		defer syscall.Umask(oldMask)
+
+		return net.Listen("unix", path)
+	}
+}
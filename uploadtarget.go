@@ -0,0 +1,120 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/utreexo/utreexod/wire"
+)
+
+const (
+	// uploadTargetCycle is the time the --maxuploadtarget budget is for.
+	uploadTargetCycle = 24 * time.Hour
+
+	// historicalBlockDepth is how many blocks below the tip the blocks and
+	// their proofs are counted as historical, which is about a week of
+	// blocks.  Only the historical ones stop being served when the upload
+	// target is close to being reached, so that the peers that are in sync
+	// still get the new blocks.
+	historicalBlockDepth = 7 * 24 * 6
+
+	// uploadTargetBlockReserve is the room kept in the upload target for
+	// each of the blocks expected to be found in the rest of the cycle,
+	// which is a block of the largest size along with its utreexo proof.
+	uploadTargetBlockReserve = 2 * wire.MaxBlockPayload
+)
+
+// errUploadTargetReached is returned when a historical block or proof isn't
+// served since the upload target leaves no room for it.
+var errUploadTargetReached = errors.New("the upload target leaves no room " +
+	"for historical blocks and proofs")
+
+// uploadTarget is the --maxuploadtarget budget of the bytes sent to peers in a
+// cycle.  The historical blocks and proofs stop being served once the rest of
+// the budget is needed for the blocks found in the rest of the cycle, while the
+// new blocks and everything else keep being served.
+type uploadTarget struct {
+	limit uint64
+
+	mtx        sync.Mutex
+	cycleStart time.Time
+	sent       uint64
+}
+
+// newUploadTarget returns an upload target of the limit in bytes per cycle.
+func newUploadTarget(limit uint64, now time.Time) *uploadTarget {
+	return &uploadTarget{
+		limit:      limit,
+		cycleStart: now,
+	}
+}
+
+// roll starts a new cycle once the current one is over.  The mutex must be
+// held.
+func (u *uploadTarget) roll(now time.Time) {
+	if now.Sub(u.cycleStart) >= uploadTargetCycle {
+		u.cycleStart = now
+		u.sent = 0
+	}
+}
+
+// add counts the bytes sent to a peer.
+func (u *uploadTarget) add(bytes uint64, now time.Time) {
+	u.mtx.Lock()
+	u.roll(now)
+	u.sent += bytes
+	u.mtx.Unlock()
+}
+
+// timeLeft returns the time left in the cycle.  The mutex must be held.
+func (u *uploadTarget) timeLeft(now time.Time) time.Duration {
+	return u.cycleStart.Add(uploadTargetCycle).Sub(now)
+}
+
+// reached returns whether the bytes sent in the cycle reached the target.
+func (u *uploadTarget) reached(now time.Time) bool {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.roll(now)
+	return u.sent >= u.limit
+}
+
+// servesHistorical returns whether historical blocks and proofs are still
+// served, which is while the rest of the budget is more than the room kept for
+// the blocks expected in the rest of the cycle.
+func (u *uploadTarget) servesHistorical(now time.Time) bool {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.roll(now)
+	blocks := uint64(u.timeLeft(now)/(10*time.Minute)) + 1
+	reserve := blocks * uploadTargetBlockReserve
+	return reserve < u.limit && u.sent < u.limit-reserve
+}
+
+// bytesLeft returns the bytes left in the budget of the cycle.
+func (u *uploadTarget) bytesLeft(now time.Time) uint64 {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.roll(now)
+	if u.sent >= u.limit {
+		return 0
+	}
+	return u.limit - u.sent
+}
+
+// cycleTimeLeft returns the time left in the cycle.
+func (u *uploadTarget) cycleTimeLeft(now time.Time) time.Duration {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.roll(now)
+	return u.timeLeft(now)
+}
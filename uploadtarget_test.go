@@ -0,0 +1,61 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestUploadTarget ensures that the historical blocks stop being served once
+// the rest of the upload target is needed for the new blocks, that the target
+// is reported as reached once the bytes sent reach it, and that it starts over
+// with a new cycle.
+func TestUploadTarget(t *testing.T) {
+	t.Parallel()
+
+	// The room kept at the start of a cycle is for 145 blocks, and for one
+	// block in its last 10 minutes.
+	now := time.Now()
+	limit := uint64(200 * uploadTargetBlockReserve)
+	u := newUploadTarget(limit, now)
+	if !u.servesHistorical(now) {
+		t.Fatalf("expected historical blocks to be served at the start")
+	}
+
+	u.add(60*uploadTargetBlockReserve, now)
+	if u.servesHistorical(now) {
+		t.Fatalf("expected historical blocks not to be served once the " +
+			"rest is needed for new blocks")
+	}
+	end := now.Add(uploadTargetCycle - time.Minute)
+	if !u.servesHistorical(end) {
+		t.Fatalf("expected historical blocks to be served at the end " +
+			"of the cycle")
+	}
+	if u.reached(end) {
+		t.Fatalf("expected the target not to be reached")
+	}
+
+	u.add(140*uploadTargetBlockReserve, end)
+	if !u.reached(end) || u.bytesLeft(end) != 0 {
+		t.Fatalf("expected the target to be reached")
+	}
+	if u.cycleTimeLeft(end) != time.Minute {
+		t.Fatalf("got %v left in the cycle, want 1m", u.cycleTimeLeft(end))
+	}
+
+	next := now.Add(uploadTargetCycle)
+	if u.reached(next) || u.bytesLeft(next) != limit {
+		t.Fatalf("expected a new cycle to start")
+	}
+
+	// A target that is too small for the new blocks never serves the
+	// historical ones.
+	u = newUploadTarget(uploadTargetBlockReserve, now)
+	if u.servesHistorical(end) {
+		t.Fatalf("expected a small target not to serve historical blocks")
+	}
+}
@@ -57,6 +57,8 @@ type NAT interface {
 	// Remove a previously added port mapping from external port to
 	// internal port.
 	DeletePortMapping(protocol string, externalPort, internalPort int) (err error)
+	// Get the name of the protocol the ports are mapped with.
+	String() string
 }
 
 type upnpNAT struct {
@@ -332,6 +334,11 @@ type getExternalIPAddressResponse struct {
 	ExternalIPAddress string   `xml:"NewExternalIPAddress"`
 }
 
+// String implements the NAT interface by returning the name of UPnP.
+func (n *upnpNAT) String() string {
+	return "UPnP"
+}
+
 // GetExternalAddress implements the NAT interface by fetching the external IP
 // from the UPnP router.
 func (n *upnpNAT) GetExternalAddress() (addr net.IP, err error) {
@@ -20,6 +20,7 @@ import (
 	"github.com/utreexo/utreexod/blockchain"
 	"github.com/utreexo/utreexod/blockchain/indexers"
 	"github.com/utreexo/utreexod/database"
+	"github.com/utreexo/utreexod/database/ffldb"
 	"github.com/utreexo/utreexod/limits"
 )
 
@@ -72,6 +73,37 @@ func pruneChecks(db database.DB) error {
 			"previously pruned. You must delete the files in the datadir: \"%s\" "+
 			"and sync from the beginning to enable the desired index", cfg.DataDir)
 	}
+	// No way to sync up the spend index if the node has already been pruned.
+	if beenPruned && cfg.SpendIndex {
+		return fmt.Errorf("--spendindex cannot be enabled as the node has been "+
+			"previously pruned. You must delete the files in the datadir: \"%s\" "+
+			"and sync from the beginning to enable the desired index", cfg.DataDir)
+	}
+	// No way to sync up the silent payment index if the node has already been pruned.
+	if beenPruned && cfg.SilentPaymentIndex {
+		return fmt.Errorf("--silentpaymentindex cannot be enabled as the node has been "+
+			"previously pruned. You must delete the files in the datadir: \"%s\" "+
+			"and sync from the beginning to enable the desired index", cfg.DataDir)
+	}
+	// No way to sync up the block time index if the node has already been pruned.
+	if beenPruned && cfg.BlockTimeIndex {
+		return fmt.Errorf("--blocktimeindex cannot be enabled as the node has been "+
+			"previously pruned. You must delete the files in the datadir: \"%s\" "+
+			"and sync from the beginning to enable the desired index", cfg.DataDir)
+	}
+	// No way to sync up the script balance index if the node has already been pruned.
+	if beenPruned && cfg.ScriptBalanceIndex {
+		return fmt.Errorf("--scriptbalanceindex cannot be enabled as the node has been "+
+			"previously pruned. You must delete the files in the datadir: \"%s\" "+
+			"and sync from the beginning to enable the desired index", cfg.DataDir)
+	}
+	// No way to sync up the utreexo committed filter header index if the node has already
+	// been pruned.
+	if beenPruned && cfg.UtreexoCFIndex {
+		return fmt.Errorf("--utreexocfindex cannot be enabled as the node has been "+
+			"previously pruned. You must delete the files in the datadir: \"%s\" "+
+			"and sync from the beginning to enable the desired index", cfg.DataDir)
+	}
 	// If we've previously been pruned and the utreexoproofindex isn't present, it means that
 	// theh user wants to enable the index after the node has already synced up while being pruned.
 	if beenPruned && !indexers.UtreexoProofIndexInitialized(db) && cfg.UtreexoProofIndex {
@@ -295,6 +327,46 @@ func btcdMain(serverChan chan<- *server) error {
 
 		return nil
 	}
+	if cfg.DropSpendIndex {
+		if err := indexers.DropSpendIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
+	if cfg.DropSilentPaymentIndex {
+		if err := indexers.DropSilentPaymentIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
+	if cfg.DropBlockTimeIndex {
+		if err := indexers.DropBlockTimeIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
+	if cfg.DropScriptBalanceIndex {
+		if err := indexers.DropScriptBalanceIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
+	if cfg.DropUtreexoCFIndex {
+		if err := indexers.DropUtreexoCFIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
 	if cfg.DropUtreexoProofIndex {
 		if err := indexers.DropUtreexoProofIndex(db, cfg.DataDir, interrupt); err != nil {
 			btcdLog.Errorf("%v", err)
@@ -384,6 +456,7 @@ func btcdMain(serverChan chan<- *server) error {
 		server.WaitForShutdown()
 		srvrLog.Infof("Server shutdown complete")
 	}()
+	publishMetrics(server)
 	server.Start()
 	if serverChan != nil {
 		serverChan <- server
@@ -424,14 +497,20 @@ func removeRegressionDB(dbPath string) error {
 	return nil
 }
 
-// dbPath returns the path to the block database given a database type.
-func blockDbPath(dbType string) string {
+// blockDbName returns the name of the block database in the data directory
+// given a database type.
+func blockDbName(dbType string) string {
 	// The database name is based on the database type.
 	dbName := blockDbNamePrefix + "_" + dbType
 	if dbType == "sqlite" {
 		dbName = dbName + ".db"
 	}
-	dbPath := filepath.Join(cfg.DataDir, dbName)
+	return dbName
+}
+
+// dbPath returns the path to the block database given a database type.
+func blockDbPath(dbType string) string {
+	dbPath := filepath.Join(cfg.DataDir, blockDbName(dbType))
 	return dbPath
 }
 
@@ -442,7 +521,7 @@ func warnMultipleDBs() {
 	// This is intentionally not using the known db types which depend
 	// on the database types compiled into the binary since we want to
 	// detect legacy db types as well.
-	dbTypes := []string{"ffldb", "leveldb", "sqlite"}
+	dbTypes := []string{"ffldb", "pebble", "leveldb", "sqlite"}
 	duplicateDbPaths := make([]string, 0, len(dbTypes)-1)
 	for _, dbType := range dbTypes {
 		if dbType == cfg.DbType {
@@ -468,6 +547,19 @@ func warnMultipleDBs() {
 	}
 }
 
+// blockDbOptions returns the options of the ffldb and pebble block databases
+// from the configuration.  The options that aren't set are picked by ffldb.
+func blockDbOptions() *ffldb.Options {
+	return &ffldb.Options{
+		CacheSize:           int(cfg.DbCacheSizeMiB) * 1024 * 1024,
+		WriteBufferSize:     int(cfg.DbWriteBufferSizeMiB) * 1024 * 1024,
+		BlockCacheSize:      int(cfg.DbBlockCacheSizeMiB) * 1024 * 1024,
+		MaxOpenFiles:        int(cfg.DbMaxOpenFiles),
+		MaxOpenBlockFiles:   int(cfg.DbMaxOpenBlockFiles),
+		FileWriteBufferSize: int(cfg.DbFileWriteBufferKiB) * 1024,
+	}
+}
+
 // loadBlockDB loads (or creates when needed) the block database taking into
 // account the selected database backend and returns a handle to it.  It also
 // contains additional logic such warning the user if there are multiple
@@ -496,7 +588,8 @@ func loadBlockDB() (database.DB, error) {
 	removeRegressionDB(dbPath)
 
 	btcdLog.Infof("Loading block database from '%s'", dbPath)
-	db, err := database.Open(cfg.DbType, dbPath, activeNetParams.Net)
+	dbOpts := blockDbOptions()
+	db, err := database.Open(cfg.DbType, dbPath, activeNetParams.Net, dbOpts)
 	if err != nil {
 		// Return the error if it's not because the database doesn't
 		// exist.
@@ -511,7 +604,8 @@ func loadBlockDB() (database.DB, error) {
 		if err != nil {
 			return nil, err
 		}
-		db, err = database.Create(cfg.DbType, dbPath, activeNetParams.Net)
+		db, err = database.Create(cfg.DbType, dbPath, activeNetParams.Net,
+			dbOpts)
 		if err != nil {
 			return nil, err
 		}
@@ -12,8 +12,10 @@ import (
 	"fmt"
 	"hash"
 	"io"
+	"runtime"
 	"sync"
 
+	"github.com/utreexo/utreexo"
 	"github.com/utreexo/utreexod/chaincfg/chainhash"
 )
 
@@ -133,6 +135,48 @@ func (l *LeafData) LeafHash() [32]byte {
 	return *(*[32]byte)(digest.Sum(nil))
 }
 
+// parallelLeafHashThreshold is the number of leaves below which LeafHashes
+// hashes the leaves on the calling goroutine as spinning up workers costs more
+// than it saves.
+const parallelLeafHashThreshold = 128
+
+// LeafHashes returns the leaf hashes of all the passed in leaves in the same
+// order.  Large batches, such as all the additions or deletions of a block
+// with thousands of inputs and outputs, are split up and hashed concurrently.
+func LeafHashes(leaves []LeafData) []utreexo.Hash {
+	hashes := make([]utreexo.Hash, len(leaves))
+
+	workers := runtime.NumCPU()
+	if len(leaves) < parallelLeafHashThreshold || workers == 1 {
+		for i := range leaves {
+			hashes[i] = leaves[i].LeafHash()
+		}
+		return hashes
+	}
+
+	// Each worker hashes a contiguous chunk of the leaves.  The chunks
+	// don't overlap so the results can be written without locking.
+	chunkSize := (len(leaves) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(leaves); start += chunkSize {
+		end := start + chunkSize
+		if end > len(leaves) {
+			end = len(leaves)
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				hashes[i] = leaves[i].LeafHash()
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	return hashes
+}
+
 // String turns a LeafData into a string for logging.
 func (l *LeafData) String() (s string) {
 	s += fmt.Sprintf("BlockHash:%s,", hex.EncodeToString(l.BlockHash[:]))
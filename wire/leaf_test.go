@@ -695,3 +695,37 @@ func TestLeafHash(t *testing.T) {
 		}
 	}
 }
+
+func TestLeafHashes(t *testing.T) {
+	t.Parallel()
+
+	// Test both below and above the threshold for hashing concurrently.
+	for _, count := range []int{0, 1, parallelLeafHashThreshold - 1, 5000} {
+		leaves := make([]LeafData, count)
+		for i := range leaves {
+			leaves[i] = LeafData{
+				BlockHash: *newHashFromStr("000000000000000000278eb9386b4e70b850a4ec21907af3a27f50330b7325aa"),
+				OutPoint: OutPoint{
+					Hash:  *newHashFromStr("fa201b650eef761f5701afbb610e4a211b86985da4745aec3ac0f4b7a8e2c8d2"),
+					Index: uint32(i),
+				},
+				Amount:   int64(i),
+				PkScript: hexToBytes("76a9142cc2b87a28c8a097f48fcc1d468ced6e7d39958d88ac"),
+				Height:   573123,
+			}
+		}
+
+		hashes := LeafHashes(leaves)
+		if len(hashes) != len(leaves) {
+			t.Fatalf("expected %d hashes but got %d", len(leaves), len(hashes))
+		}
+		for i := range leaves {
+			expect := leaves[i].LeafHash()
+			if hashes[i] != expect {
+				t.Fatalf("leaf %d of %d: expect %s but got %s", i, count,
+					hex.EncodeToString(expect[:]),
+					hex.EncodeToString(hashes[i][:]))
+			}
+		}
+	}
+}
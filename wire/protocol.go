@@ -55,6 +55,13 @@ const (
 	// FeeFilterVersion is the protocol version which added a new
 	// feefilter message.
 	FeeFilterVersion uint32 = 70013
+
+	// UtreexoProofVersion is the version of the utreexo proofs that are
+	// sent with blocks and transactions to the peers that advertise
+	// SFNodeUtreexo.  There's only one version of the proofs so far, so
+	// it's implied by the service flag instead of being negotiated in the
+	// version message.
+	UtreexoProofVersion uint32 = 1
 )
 
 // ServiceFlag identifies services supported by a bitcoin peer.
@@ -101,6 +108,22 @@ const (
 	// TODO: Using bit 24 at the moment as bits 24-31 are reserved for
 	// experiments.  The bit used will definitely change in the future.
 	SFNodeUtreexo = 1 << 24
+
+	// SFNodeUtreexoBridge is a flag used to indicate a utreexo peer is a
+	// bridge node that generates the utreexo proofs from the full utxo set
+	// instead of being a compact state node.
+	//
+	// TODO: Like SFNodeUtreexo, this uses a bit that's reserved for
+	// experiments and will change in the future.
+	SFNodeUtreexoBridge = 1 << 25
+
+	// SFNodeUtreexoArchive is a flag used to indicate a utreexo bridge node
+	// keeps the utreexo proofs of all the blocks and is able to serve them
+	// for the whole chain, as opposed to only those of the recent blocks.
+	//
+	// TODO: Like SFNodeUtreexo, this uses a bit that's reserved for
+	// experiments and will change in the future.
+	SFNodeUtreexoArchive = 1 << 26
 )
 
 // Map of service flags back to their constant names for pretty printing.
@@ -115,6 +138,8 @@ var sfStrings = map[ServiceFlag]string{
 	SFNodeCF:             "SFNodeCF",
 	SFNode2X:             "SFNode2X",
 	SFNodeUtreexo:        "SFNodeUtreexo",
+	SFNodeUtreexoBridge:  "SFNodeUtreexoBridge",
+	SFNodeUtreexoArchive: "SFNodeUtreexoArchive",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -130,6 +155,8 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeCF,
 	SFNode2X,
 	SFNodeUtreexo,
+	SFNodeUtreexoBridge,
+	SFNodeUtreexoArchive,
 }
 
 // String returns the ServiceFlag in human-readable form.
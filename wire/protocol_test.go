@@ -23,7 +23,9 @@ func TestServiceFlagStringer(t *testing.T) {
 		{SFNodeCF, "SFNodeCF"},
 		{SFNode2X, "SFNode2X"},
 		{SFNodeUtreexo, "SFNodeUtreexo"},
-		{0xffffffff, "SFNodeNetwork|SFNodeNetworkLimited|SFNodeGetUTXO|SFNodeBloom|SFNodeWitness|SFNodeXthin|SFNodeBit5|SFNodeCF|SFNode2X|SFNodeUtreexo|0xfefffb00"},
+		{SFNodeUtreexoBridge, "SFNodeUtreexoBridge"},
+		{SFNodeUtreexoArchive, "SFNodeUtreexoArchive"},
+		{0xffffffff, "SFNodeNetwork|SFNodeNetworkLimited|SFNodeGetUTXO|SFNodeBloom|SFNodeWitness|SFNodeXthin|SFNodeBit5|SFNodeCF|SFNode2X|SFNodeUtreexo|SFNodeUtreexoBridge|SFNodeUtreexoArchive|0xf8fffb00"},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -29,12 +29,7 @@ type UData struct {
 // StxosHashes returns the hash of all stxos in this UData.  The hashes returned
 // here represent the hash commitments of the stxos.
 func (ud *UData) StxoHashes() []utreexo.Hash {
-	leafHashes := make([]utreexo.Hash, len(ud.LeafDatas))
-	for i, stxo := range ud.LeafDatas {
-		leafHashes[i] = stxo.LeafHash()
-	}
-
-	return leafHashes
+	return LeafHashes(ud.LeafDatas)
 }
 
 // SerializeUtxoDataSize returns the number of bytes it would take to serialize the
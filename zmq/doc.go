@@ -0,0 +1,36 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package zmq implements a ZMQ publisher for the notifications of the node.
+
+The publisher speaks ZMTP 3.0, the wire protocol of ZMQ, directly so that it
+doesn't depend on libzmq.  Any ZMQ SUB socket can connect to it over the tcp or
+the ipc transport with the NULL security mechanism.
+
+The topics and the messages are the same as the ones of Bitcoin Core so that
+existing consumers can be used with the node:
+
+	hashblock     the hash of a connected block
+	rawblock      the serialized connected block
+	hashtx        the hash of a transaction in the mempool or a connected block
+	rawtx         the serialized transaction
+	utreexoroots  the block hash, followed by the number of leaves as a little
+	              endian uint64 and the roots of the utreexo accumulator after
+	              the block was connected
+	mempoolevent  the hash of a transaction that entered or left the mempool,
+	              followed by the label of the event, the fee and the virtual
+	              size and, for replacements and confirmations, the hash of
+	              the replacing transaction or of the block
+	walletevent   the hash of a transaction of a bdk wallet that received a
+	              payment, was confirmed or bumped the fee of another one,
+	              followed by the label of the event, the amounts and the
+	              confirmations and ending with the name of the wallet
+
+Every message has three parts: the topic, the body and a 4-byte little endian
+sequence number of the topic.  Block and transaction hashes are in the byte
+order they're displayed in while the roots are in the same byte order as the
+getutreexoroots RPC returns them in.
+*/
+package zmq
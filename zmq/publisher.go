@@ -0,0 +1,363 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/utreexo/utreexo"
+	"github.com/utreexo/utreexod/bdkwallet"
+	"github.com/utreexo/utreexod/blockchain"
+	"github.com/utreexo/utreexod/btcutil"
+	"github.com/utreexo/utreexod/chaincfg/chainhash"
+	"github.com/utreexo/utreexod/mempool"
+)
+
+// Topics that are published.
+const (
+	// TopicHashBlock is the hash of every block that's connected to the
+	// main chain.
+	TopicHashBlock = "hashblock"
+
+	// TopicRawBlock is every block that's connected to the main chain.
+	TopicRawBlock = "rawblock"
+
+	// TopicHashTx is the hash of every transaction that's accepted to the
+	// mempool or that's in a block connected to the main chain.
+	TopicHashTx = "hashtx"
+
+	// TopicRawTx is every transaction that's accepted to the mempool or
+	// that's in a block connected to the main chain.
+	TopicRawTx = "rawtx"
+
+	// TopicUtreexoRoots is the utreexo accumulator state after every block
+	// that's connected to the main chain.
+	TopicUtreexoRoots = "utreexoroots"
+
+	// TopicMempoolEvent is every transaction that's added to, removed
+	// from, replaced in or confirmed out of the mempool along with its fee
+	// and virtual size.
+	TopicMempoolEvent = "mempoolevent"
+
+	// TopicWalletEvent is every payment received by the bdk wallets, every
+	// transaction of theirs that's confirmed and every fee bump of their
+	// transactions.
+	TopicWalletEvent = "walletevent"
+)
+
+// mempoolEventLabels are the labels of the mempool events in the bodies of the
+// mempoolevent messages.
+var mempoolEventLabels = map[mempool.NotificationType]byte{
+	mempool.NTTxAdded:     'A',
+	mempool.NTTxRemoved:   'R',
+	mempool.NTTxReplaced:  'X',
+	mempool.NTTxConfirmed: 'C',
+}
+
+// walletEventLabels are the labels of the wallet events in the bodies of the
+// walletevent messages.
+var walletEventLabels = map[bdkwallet.NotificationType]byte{
+	bdkwallet.NTPaymentReceived: 'R',
+	bdkwallet.NTTxConfirmed:     'C',
+	bdkwallet.NTFeeBumped:       'B',
+}
+
+// blockTopics are the topics that are published when a block is connected.
+var blockTopics = []string{
+	TopicHashBlock, TopicRawBlock, TopicHashTx, TopicRawTx, TopicUtreexoRoots,
+}
+
+// Config is a configuration struct used to initialize a new publisher.
+type Config struct {
+	// Endpoints maps each topic to the endpoints it's published on.  The
+	// topics that are published on the same endpoint share a socket.
+	Endpoints map[string][]string
+
+	// SendHWM is the number of messages that are queued for a subscriber
+	// before new messages to it are dropped.
+	SendHWM int
+
+	// Chain is the chain the blocks and the utreexo roots are published
+	// from.
+	Chain *blockchain.BlockChain
+
+	// TxMemPool is the mempool the mempool events are published from.
+	TxMemPool *mempool.TxPool
+
+	// Wallets are the bdk wallets the wallet events are published from.
+	// The wallet events aren't published when it's nil.
+	Wallets *bdkwallet.Wallets
+}
+
+// Publisher publishes the notifications of the node to ZMQ subscribers.  The
+// messages are compatible with the ones of Bitcoin Core and consist of three
+// parts: the topic, the body and a 4-byte little endian sequence number that's
+// incremented for every message of the topic.
+type Publisher struct {
+	started  int32
+	shutdown int32
+
+	cfg     Config
+	sockets []*pubSocket
+	topics  map[string][]*pubSocket
+
+	mtx       sync.Mutex
+	sequences map[string]uint32
+}
+
+// New returns a publisher that's bound to all the configured endpoints.
+func New(config *Config) (*Publisher, error) {
+	p := Publisher{
+		cfg:       *config,
+		topics:    make(map[string][]*pubSocket),
+		sequences: make(map[string]uint32),
+	}
+	if p.cfg.SendHWM <= 0 {
+		p.cfg.SendHWM = DefaultSendHWM
+	}
+
+	// Sort the topics so that the sockets are created in the same order
+	// every time.
+	topics := make([]string, 0, len(p.cfg.Endpoints))
+	for topic := range p.cfg.Endpoints {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	sockets := make(map[string]*pubSocket)
+	for _, topic := range topics {
+		for _, endpoint := range p.cfg.Endpoints[topic] {
+			socket, ok := sockets[endpoint]
+			if !ok {
+				var err error
+				socket, err = newPubSocket(endpoint, p.cfg.SendHWM)
+				if err != nil {
+					for _, socket := range p.sockets {
+						socket.listener.Close()
+					}
+					return nil, fmt.Errorf("unable to bind the ZMQ "+
+						"endpoint %s: %v", endpoint, err)
+				}
+				sockets[endpoint] = socket
+				p.sockets = append(p.sockets, socket)
+			}
+			p.topics[topic] = append(p.topics[topic], socket)
+		}
+	}
+
+	for _, topic := range blockTopics {
+		if len(p.topics[topic]) > 0 {
+			p.cfg.Chain.Subscribe(p.handleBlockChainNotification)
+			break
+		}
+	}
+	if len(p.topics[TopicMempoolEvent]) > 0 {
+		p.cfg.TxMemPool.Subscribe(p.handleMempoolNotification)
+	}
+	if len(p.topics[TopicWalletEvent]) > 0 && p.cfg.Wallets != nil {
+		p.cfg.Wallets.Subscribe(p.handleWalletNotification)
+	}
+
+	return &p, nil
+}
+
+// Start begins accepting subscribers on all the endpoints.
+func (p *Publisher) Start() {
+	if atomic.AddInt32(&p.started, 1) != 1 {
+		return
+	}
+
+	for _, socket := range p.sockets {
+		log.Infof("ZMQ publisher listening on %s", socket.endpoint)
+		socket.start()
+	}
+}
+
+// Stop disconnects all the subscribers and closes all the endpoints.
+func (p *Publisher) Stop() {
+	if atomic.AddInt32(&p.shutdown, 1) != 1 {
+		log.Infof("ZMQ publisher is already in the process of shutting down")
+		return
+	}
+	log.Infof("Stopping ZMQ publisher...")
+
+	for _, socket := range p.sockets {
+		socket.stop()
+	}
+
+	log.Infof("ZMQ publisher stopped")
+}
+
+// publish sends the body to all the sockets of the topic.
+func (p *Publisher) publish(topic string, body []byte) {
+	sockets := p.topics[topic]
+	if len(sockets) == 0 {
+		return
+	}
+
+	p.mtx.Lock()
+	var seq [4]byte
+	binary.LittleEndian.PutUint32(seq[:], p.sequences[topic])
+	p.sequences[topic]++
+	p.mtx.Unlock()
+
+	msg := encodeMessage([]byte(topic), body, seq[:])
+	for _, socket := range sockets {
+		socket.send(topic, msg)
+	}
+}
+
+// hashBytes returns the hash in the byte order it's displayed in, which is the
+// byte order Bitcoin Core publishes hashes in.
+func hashBytes(hash *chainhash.Hash) []byte {
+	b := make([]byte, chainhash.HashSize)
+	for i := range hash {
+		b[i] = hash[chainhash.HashSize-1-i]
+	}
+	return b
+}
+
+// publishTx publishes the hash and the serialization of the transaction.
+func (p *Publisher) publishTx(tx *btcutil.Tx) {
+	p.publish(TopicHashTx, hashBytes(tx.Hash()))
+
+	if len(p.topics[TopicRawTx]) > 0 {
+		var buf bytes.Buffer
+		err := tx.MsgTx().Serialize(&buf)
+		if err != nil {
+			log.Errorf("Unable to serialize tx %v: %v", tx.Hash(), err)
+			return
+		}
+		p.publish(TopicRawTx, buf.Bytes())
+	}
+}
+
+// NotifyNewTransactions publishes the transactions that were accepted to the
+// mempool.
+func (p *Publisher) NotifyNewTransactions(txns []*mempool.TxDesc) {
+	for _, txD := range txns {
+		p.publishTx(txD.Tx)
+	}
+}
+
+// handleMempoolNotification publishes the transactions that enter and leave the
+// mempool.  The body is the hash of the transaction followed by the label of the
+// event, the fee as a little endian int64 and the virtual size as a little
+// endian uint64.  Replaced events are followed by the hash of the replacing
+// transaction and confirmed events by the hash of the block.
+func (p *Publisher) handleMempoolNotification(n *mempool.Notification) {
+	var sizes [16]byte
+	binary.LittleEndian.PutUint64(sizes[:8], uint64(n.Fee))
+	binary.LittleEndian.PutUint64(sizes[8:], uint64(n.VSize))
+
+	body := make([]byte, 0, chainhash.HashSize*2+len(sizes)+1)
+	body = append(body, hashBytes(n.Tx.Hash())...)
+	body = append(body, mempoolEventLabels[n.Type])
+	body = append(body, sizes[:]...)
+	switch {
+	case n.ReplacedBy != nil:
+		body = append(body, hashBytes(n.ReplacedBy.Hash())...)
+	case n.Block != nil:
+		body = append(body, hashBytes(n.Block.Hash())...)
+	}
+
+	p.publish(TopicMempoolEvent, body)
+}
+
+// handleWalletNotification publishes the events of the bdk wallets.  The body
+// is the hash of the transaction followed by the label of the event, the amount
+// the transaction received as a little endian int64, the amount it spent as a
+// little endian int64 and its confirmations as a little endian uint32.  Fee
+// bumped events are followed by the hash of the bumped transaction and the fee
+// of the transaction as a little endian int64.  The body ends with the name of
+// the wallet, which is empty for the default wallet.
+func (p *Publisher) handleWalletNotification(n *bdkwallet.Notification) {
+	var amounts [20]byte
+	binary.LittleEndian.PutUint64(amounts[:8], uint64(n.Received))
+	binary.LittleEndian.PutUint64(amounts[8:16], uint64(n.Spent))
+	binary.LittleEndian.PutUint32(amounts[16:], uint32(n.Confirmations))
+
+	body := make([]byte, 0, chainhash.HashSize*2+len(amounts)+9+len(n.Wallet))
+	body = append(body, hashBytes(&n.Txid)...)
+	body = append(body, walletEventLabels[n.Type])
+	body = append(body, amounts[:]...)
+	if n.Type == bdkwallet.NTFeeBumped {
+		var fee [8]byte
+		binary.LittleEndian.PutUint64(fee[:], uint64(n.Fee))
+		body = append(body, hashBytes(&n.BumpedTxid)...)
+		body = append(body, fee[:]...)
+	}
+	body = append(body, n.Wallet...)
+
+	p.publish(TopicWalletEvent, body)
+}
+
+// publishUtreexoRoots publishes the utreexo accumulator state after the block
+// was connected.  The body is the hash of the block followed by the number of
+// leaves as a little endian uint64 and the roots.
+func (p *Publisher) publishUtreexoRoots(block *btcutil.Block) {
+	if len(p.topics[TopicUtreexoRoots]) == 0 {
+		return
+	}
+
+	roots, numLeaves, ok, err := p.cfg.Chain.FetchUtreexoRoots(block.Height())
+	if err != nil {
+		log.Errorf("Unable to fetch the utreexo roots at height %d: %v",
+			block.Height(), err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	utreexoRoots := make([]utreexo.Hash, 0, len(roots))
+	for _, root := range roots {
+		utreexoRoots = append(utreexoRoots, utreexo.Hash(*root))
+	}
+	serialized, err := blockchain.SerializeUtreexoRoots(numLeaves, utreexoRoots)
+	if err != nil {
+		log.Errorf("Unable to serialize the utreexo roots at height %d: %v",
+			block.Height(), err)
+		return
+	}
+
+	p.publish(TopicUtreexoRoots, append(hashBytes(block.Hash()), serialized...))
+}
+
+// handleBlockChainNotification publishes the blocks that are connected to the
+// main chain.
+func (p *Publisher) handleBlockChainNotification(notification *blockchain.Notification) {
+	if notification.Type != blockchain.NTBlockConnected {
+		return
+	}
+
+	block, ok := notification.Data.(*btcutil.Block)
+	if !ok {
+		log.Warnf("Chain connected notification is not a block.")
+		return
+	}
+
+	p.publish(TopicHashBlock, hashBytes(block.Hash()))
+
+	if len(p.topics[TopicRawBlock]) > 0 {
+		serialized, err := block.Bytes()
+		if err != nil {
+			log.Errorf("Unable to serialize block %v: %v", block.Hash(), err)
+		} else {
+			p.publish(TopicRawBlock, serialized)
+		}
+	}
+
+	for _, tx := range block.Transactions() {
+		p.publishTx(tx)
+	}
+
+	p.publishUtreexoRoots(block)
+}
@@ -0,0 +1,358 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmq
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSendHWM is the default number of messages that are queued for
+	// a subscriber before new messages to it are dropped.  It's the same as
+	// the default ZMQ_SNDHWM of libzmq.
+	DefaultSendHWM = 1000
+
+	// handshakeTimeout is the time a subscriber has to complete the ZMTP
+	// handshake after connecting.
+	handshakeTimeout = 10 * time.Second
+)
+
+// listen creates a listener for the ZMQ endpoint.  Only the tcp and the ipc
+// transports are supported.
+func listen(endpoint string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(endpoint, "tcp://"):
+		addr := strings.TrimPrefix(endpoint, "tcp://")
+
+		// libzmq uses * for all the interfaces.
+		if strings.HasPrefix(addr, "*:") {
+			addr = addr[1:]
+		}
+		return net.Listen("tcp", addr)
+
+	case strings.HasPrefix(endpoint, "ipc://"):
+		return net.Listen("unix", strings.TrimPrefix(endpoint, "ipc://"))
+	}
+
+	return nil, fmt.Errorf("unsupported ZMQ endpoint %q.  Only tcp:// "+
+		"and ipc:// endpoints are supported", endpoint)
+}
+
+// pubPeer is a subscriber that's connected to a pubSocket.
+type pubPeer struct {
+	conn net.Conn
+
+	// sendQueue holds the encoded messages that are waiting to be written
+	// to the subscriber.
+	sendQueue chan []byte
+
+	// quit is closed once the subscriber disconnected.
+	quit chan struct{}
+
+	mtx           sync.Mutex
+	subscriptions map[string]int
+}
+
+// subscribe adds the topic prefix to the subscriptions of the peer.
+func (p *pubPeer) subscribe(prefix []byte) {
+	p.mtx.Lock()
+	p.subscriptions[string(prefix)]++
+	p.mtx.Unlock()
+}
+
+// unsubscribe removes the topic prefix from the subscriptions of the peer.
+func (p *pubPeer) unsubscribe(prefix []byte) {
+	p.mtx.Lock()
+	if p.subscriptions[string(prefix)] <= 1 {
+		delete(p.subscriptions, string(prefix))
+	} else {
+		p.subscriptions[string(prefix)]--
+	}
+	p.mtx.Unlock()
+}
+
+// subscribed returns whether the peer subscribed to a prefix of the topic.
+func (p *pubPeer) subscribed(topic string) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for prefix := range p.subscriptions {
+		if strings.HasPrefix(topic, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// pubSocket is a ZMQ PUB socket that speaks ZMTP 3.0 with the NULL security
+// mechanism on a single endpoint.  Messages are only sent to the subscribers
+// whose subscriptions match the topic and they're dropped for subscribers that
+// have sendHWM messages queued already, just like libzmq does.
+type pubSocket struct {
+	endpoint string
+	listener net.Listener
+	sendHWM  int
+
+	mtx   sync.Mutex
+	peers map[*pubPeer]struct{}
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// newPubSocket returns a PUB socket that's bound to the endpoint.
+func newPubSocket(endpoint string, sendHWM int) (*pubSocket, error) {
+	listener, err := listen(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pubSocket{
+		endpoint: endpoint,
+		listener: listener,
+		sendHWM:  sendHWM,
+		peers:    make(map[*pubPeer]struct{}),
+		quit:     make(chan struct{}),
+	}, nil
+}
+
+// start begins accepting subscribers.
+func (s *pubSocket) start() {
+	s.wg.Add(1)
+	go s.acceptHandler()
+}
+
+// stop disconnects all the subscribers and closes the listener.
+func (s *pubSocket) stop() {
+	close(s.quit)
+	s.listener.Close()
+
+	s.mtx.Lock()
+	for peer := range s.peers {
+		peer.conn.Close()
+	}
+	s.mtx.Unlock()
+
+	s.wg.Wait()
+}
+
+// acceptHandler accepts the subscribers that connect to the socket.
+//
+// This must be run as a goroutine.
+func (s *pubSocket) acceptHandler() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+
+			log.Errorf("Can't accept ZMQ subscriber on %s: %v",
+				s.endpoint, err)
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.handlePeer(conn)
+	}
+}
+
+// handshake performs the ZMTP handshake with the subscriber.
+func (s *pubSocket) handshake(conn net.Conn) error {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	ready := encodeCommand("READY", encodeProperties(
+		[]string{"Socket-Type"}, map[string]string{"Socket-Type": "PUB"}))
+	_, err := conn.Write(append(greeting(), ready...))
+	if err != nil {
+		return err
+	}
+
+	err = readGreeting(conn)
+	if err != nil {
+		return err
+	}
+
+	flags, body, err := readFrame(conn, maxInboundFrameSize)
+	if err != nil {
+		return err
+	}
+	if flags&flagCommand == 0 {
+		return fmt.Errorf("expected a READY command")
+	}
+	name, data, err := parseCommand(body)
+	if err != nil {
+		return err
+	}
+	if name != "READY" {
+		return fmt.Errorf("expected a READY command but got %q", name)
+	}
+	props, err := parseProperties(data)
+	if err != nil {
+		return err
+	}
+
+	socketType := props["socket-type"]
+	if socketType != "SUB" && socketType != "XSUB" {
+		return fmt.Errorf("socket type %q can't connect to a PUB socket",
+			socketType)
+	}
+
+	return nil
+}
+
+// handlePeer performs the handshake with the subscriber and serves it until it
+// disconnects.
+//
+// This must be run as a goroutine.
+func (s *pubSocket) handlePeer(conn net.Conn) {
+	defer s.wg.Done()
+
+	err := s.handshake(conn)
+	if err != nil {
+		log.Debugf("ZMQ handshake with %s on %s failed: %v",
+			conn.RemoteAddr(), s.endpoint, err)
+		conn.Close()
+		return
+	}
+
+	peer := &pubPeer{
+		conn:          conn,
+		sendQueue:     make(chan []byte, s.sendHWM),
+		quit:          make(chan struct{}),
+		subscriptions: make(map[string]int),
+	}
+
+	s.mtx.Lock()
+	select {
+	case <-s.quit:
+		s.mtx.Unlock()
+		conn.Close()
+		return
+	default:
+	}
+	s.peers[peer] = struct{}{}
+	s.mtx.Unlock()
+
+	log.Debugf("New ZMQ subscriber %s on %s", conn.RemoteAddr(), s.endpoint)
+
+	s.wg.Add(1)
+	go s.peerWriter(peer)
+	s.peerReader(peer)
+
+	s.mtx.Lock()
+	delete(s.peers, peer)
+	s.mtx.Unlock()
+
+	close(peer.quit)
+	conn.Close()
+
+	log.Debugf("ZMQ subscriber %s on %s disconnected", conn.RemoteAddr(),
+		s.endpoint)
+}
+
+// peerReader reads the subscriptions of the peer until it disconnects.
+func (s *pubSocket) peerReader(peer *pubPeer) {
+	// more is set while the frames of a multi-part message are read.  Only
+	// single-part messages are subscriptions.
+	more := false
+	for {
+		flags, body, err := readFrame(peer.conn, maxInboundFrameSize)
+		if err != nil {
+			return
+		}
+
+		if flags&flagCommand != 0 {
+			name, data, err := parseCommand(body)
+			if err != nil {
+				return
+			}
+
+			switch name {
+			// ZMTP 3.1 peers send their subscriptions as commands.
+			case "SUBSCRIBE":
+				peer.subscribe(data)
+			case "CANCEL":
+				peer.unsubscribe(data)
+
+			// Reply to heartbeats with the context of the ping.
+			case "PING":
+				if len(data) < 2 {
+					return
+				}
+				s.queue(peer, encodeCommand("PONG", data[2:]))
+			}
+			continue
+		}
+
+		isPart := more || flags&flagMore != 0
+		more = flags&flagMore != 0
+		if isPart || len(body) == 0 {
+			continue
+		}
+
+		switch body[0] {
+		case 1:
+			peer.subscribe(body[1:])
+		case 0:
+			peer.unsubscribe(body[1:])
+		}
+	}
+}
+
+// peerWriter writes the queued messages to the peer until it disconnects.
+//
+// This must be run as a goroutine.
+func (s *pubSocket) peerWriter(peer *pubPeer) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case msg := <-peer.sendQueue:
+			_, err := peer.conn.Write(msg)
+			if err != nil {
+				// Closing the connection makes the reader
+				// return and clean up the peer.
+				peer.conn.Close()
+				return
+			}
+
+		case <-peer.quit:
+			return
+		}
+	}
+}
+
+// queue adds the encoded message to the send queue of the peer.  The message
+// is dropped if the queue is full.
+func (s *pubSocket) queue(peer *pubPeer, msg []byte) {
+	select {
+	case peer.sendQueue <- msg:
+	default:
+		log.Debugf("Dropping message to ZMQ subscriber %s on %s since "+
+			"its send queue is full", peer.conn.RemoteAddr(), s.endpoint)
+	}
+}
+
+// send queues the encoded message for every subscriber of the topic.
+func (s *pubSocket) send(topic string, msg []byte) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for peer := range s.peers {
+		if peer.subscribed(topic) {
+			s.queue(peer, msg)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmq
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// dialSubscriber connects a SUB socket to the endpoint and performs the ZMTP
+// handshake.
+func dialSubscriber(t *testing.T, addr string) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	ready := encodeCommand("READY", encodeProperties(
+		[]string{"Socket-Type"}, map[string]string{"Socket-Type": "SUB"}))
+	_, err = conn.Write(append(greeting(), ready...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = readGreeting(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags, body, err := readFrame(conn, maxInboundFrameSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, data, err := parseCommand(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props, err := parseProperties(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags&flagCommand == 0 || name != "READY" || props["socket-type"] != "PUB" {
+		t.Fatalf("unexpected READY command %q with properties %v", name, props)
+	}
+
+	return conn
+}
+
+// readMessage reads a multi-part message from the connection.
+func readMessage(t *testing.T, conn net.Conn) [][]byte {
+	t.Helper()
+
+	var parts [][]byte
+	for {
+		flags, body, err := readFrame(conn, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, body)
+		if flags&flagMore == 0 {
+			return parts
+		}
+	}
+}
+
+// waitForSubscription waits until a subscriber of the socket subscribed to
+// the topic.
+func waitForSubscription(t *testing.T, s *pubSocket, topic string) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		s.mtx.Lock()
+		for peer := range s.peers {
+			if peer.subscribed(topic) {
+				s.mtx.Unlock()
+				return
+			}
+		}
+		s.mtx.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no subscriber subscribed to %s", topic)
+}
+
+func TestPubSocket(t *testing.T) {
+	s, err := newPubSocket("tcp://127.0.0.1:0", DefaultSendHWM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.start()
+	defer s.stop()
+
+	conn := dialSubscriber(t, s.listener.Addr().String())
+	defer conn.Close()
+
+	// Subscribe to the hash topics with a ZMTP 3.0 subscription message.
+	_, err = conn.Write(encodeMessage([]byte("\x01hash")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForSubscription(t, s, TopicHashTx)
+
+	// The raw tx must be filtered out and only the hash tx must be sent.
+	// The long body makes sure multi-byte frame sizes are encoded right.
+	longBody := bytes.Repeat([]byte{0xaa}, 300)
+	s.send(TopicRawTx, encodeMessage([]byte(TopicRawTx), []byte{1}))
+	s.send(TopicHashTx, encodeMessage([]byte(TopicHashTx), longBody, []byte{0, 0, 0, 0}))
+
+	parts := readMessage(t, conn)
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts but got %d", len(parts))
+	}
+	if string(parts[0]) != TopicHashTx {
+		t.Fatalf("expected topic %s but got %s", TopicHashTx, parts[0])
+	}
+	if !bytes.Equal(parts[1], longBody) {
+		t.Fatalf("unexpected body %x", parts[1])
+	}
+
+	// Pings must be answered with their context.
+	_, err = conn.Write(encodeCommand("PING", []byte{0, 0, 'c', 't', 'x'}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags, body, err := readFrame(conn, maxInboundFrameSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, data, err := parseCommand(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags&flagCommand == 0 || name != "PONG" || string(data) != "ctx" {
+		t.Fatalf("unexpected reply %q %q to the ping", name, data)
+	}
+
+	// Nothing is sent anymore once the subscription is cancelled.
+	_, err = conn.Write(encodeCommand("CANCEL", []byte("hash")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		s.mtx.Lock()
+		subscribed := false
+		for peer := range s.peers {
+			subscribed = subscribed || peer.subscribed(TopicHashTx)
+		}
+		s.mtx.Unlock()
+		if !subscribed {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("subscription wasn't cancelled")
+}
+
+func TestPubSocketRejectsPeers(t *testing.T) {
+	s, err := newPubSocket("tcp://127.0.0.1:0", DefaultSendHWM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.start()
+	defer s.stop()
+
+	// A PUB socket can't connect to a PUB socket.
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	ready := encodeCommand("READY", encodeProperties(
+		[]string{"Socket-Type"}, map[string]string{"Socket-Type": "PUB"}))
+	_, err = conn.Write(append(greeting(), ready...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read the greeting and the READY of the socket.  The connection must
+	// be closed afterwards.
+	err = readGreeting(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = readFrame(conn, maxInboundFrameSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = readFrame(conn, maxInboundFrameSize)
+	if err == nil {
+		t.Fatalf("expected the connection to be closed")
+	}
+}
+
+func TestListen(t *testing.T) {
+	_, err := listen("udp://127.0.0.1:28332")
+	if err == nil {
+		t.Fatalf("expected an error for an unsupported transport")
+	}
+
+	l, err := listen("tcp://*:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+}
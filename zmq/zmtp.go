@@ -0,0 +1,203 @@
+// Copyright (c) 2024 The utreexo developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// Flags of the first byte of a ZMTP frame.
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+
+	// greetingSize is the size of the greeting both peers send when the
+	// connection is opened.
+	greetingSize = 64
+
+	// maxShortFrameSize is the largest frame body that's sent with a one
+	// byte size.
+	maxShortFrameSize = 255
+
+	// maxInboundFrameSize is the largest frame accepted from a subscriber.
+	// Subscribers only send commands and subscriptions so anything bigger
+	// is a misbehaving peer.
+	maxInboundFrameSize = 64 * 1024
+)
+
+// greeting returns the ZMTP 3.0 greeting for the NULL security mechanism.
+func greeting() []byte {
+	g := make([]byte, greetingSize)
+
+	// Signature.
+	g[0] = 0xff
+	g[9] = 0x7f
+
+	// Version.
+	g[10] = 3
+	g[11] = 0
+
+	// Mechanism.  The as-server flag and the filler that follow are all
+	// zeros.
+	copy(g[12:32], "NULL")
+
+	return g
+}
+
+// readGreeting reads the greeting of the peer and checks that it speaks ZMTP 3
+// or later with the NULL security mechanism.
+func readGreeting(r io.Reader) error {
+	var g [greetingSize]byte
+	_, err := io.ReadFull(r, g[:])
+	if err != nil {
+		return err
+	}
+
+	if g[0] != 0xff || g[9]&0x01 == 0 {
+		return fmt.Errorf("invalid greeting signature")
+	}
+	if g[10] < 3 {
+		return fmt.Errorf("unsupported ZMTP version %d.%d", g[10], g[11])
+	}
+	mechanism := string(bytes.TrimRight(g[12:32], "\x00"))
+	if mechanism != "NULL" {
+		return fmt.Errorf("unsupported security mechanism %q", mechanism)
+	}
+
+	return nil
+}
+
+// appendFrame appends a frame with the given flags and body to buf.
+func appendFrame(buf []byte, flags byte, body []byte) []byte {
+	if len(body) > maxShortFrameSize {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(body)))
+		buf = append(buf, flags|flagLong)
+		buf = append(buf, size[:]...)
+	} else {
+		buf = append(buf, flags, byte(len(body)))
+	}
+
+	return append(buf, body...)
+}
+
+// encodeMessage returns the frames of a multi-part message.
+func encodeMessage(parts ...[]byte) []byte {
+	size := 0
+	for _, part := range parts {
+		size += 9 + len(part)
+	}
+
+	buf := make([]byte, 0, size)
+	for i, part := range parts {
+		var flags byte
+		if i != len(parts)-1 {
+			flags = flagMore
+		}
+		buf = appendFrame(buf, flags, part)
+	}
+
+	return buf
+}
+
+// encodeCommand returns the frame of a command with the given name and data.
+func encodeCommand(name string, data []byte) []byte {
+	body := make([]byte, 0, 1+len(name)+len(data))
+	body = append(body, byte(len(name)))
+	body = append(body, name...)
+	body = append(body, data...)
+
+	return appendFrame(nil, flagCommand, body)
+}
+
+// readFrame reads a single frame and returns its flags and body.  An error is
+// returned if the body is bigger than maxSize.
+func readFrame(r io.Reader, maxSize uint64) (byte, []byte, error) {
+	var hdr [9]byte
+	_, err := io.ReadFull(r, hdr[:2])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	flags := hdr[0]
+	size := uint64(hdr[1])
+	if flags&flagLong != 0 {
+		_, err = io.ReadFull(r, hdr[2:])
+		if err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(hdr[1:])
+	}
+	if size > maxSize {
+		return 0, nil, fmt.Errorf("frame of %d bytes exceeds the maximum "+
+			"of %d bytes", size, maxSize)
+	}
+
+	body := make([]byte, size)
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return flags, body, nil
+}
+
+// parseCommand splits the body of a command frame into its name and data.
+func parseCommand(body []byte) (string, []byte, error) {
+	if len(body) == 0 || int(body[0]) > len(body)-1 {
+		return "", nil, fmt.Errorf("malformed command")
+	}
+
+	nameLen := int(body[0])
+	return string(body[1 : 1+nameLen]), body[1+nameLen:], nil
+}
+
+// encodeProperties returns the metadata of a READY command.  The properties are
+// written in the order of the keys given.
+func encodeProperties(keys []string, props map[string]string) []byte {
+	var buf []byte
+	for _, key := range keys {
+		value := props[key]
+
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(value)))
+
+		buf = append(buf, byte(len(key)))
+		buf = append(buf, key...)
+		buf = append(buf, size[:]...)
+		buf = append(buf, value...)
+	}
+
+	return buf
+}
+
+// parseProperties parses the metadata of a READY command.  The property names
+// are case-insensitive so they're returned in lower case.
+func parseProperties(data []byte) (map[string]string, error) {
+	props := make(map[string]string)
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		data = data[1:]
+		if len(data) < nameLen+4 {
+			return nil, fmt.Errorf("malformed property")
+		}
+		name := string(bytes.ToLower(data[:nameLen]))
+		data = data[nameLen:]
+
+		valueLen := binary.BigEndian.Uint32(data[:4])
+		data = data[4:]
+		if uint64(len(data)) < uint64(valueLen) {
+			return nil, fmt.Errorf("malformed property %q", name)
+		}
+		props[name] = string(data[:valueLen])
+		data = data[valueLen:]
+	}
+
+	return props, nil
+}